@@ -2,14 +2,23 @@ package cmd
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
 	"sort"
 	"strings"
 
 	"github.com/charmbracelet/huh"
 	"github.com/spf13/cobra"
 
+	"github.com/maiko/sdbx/internal/backup"
 	"github.com/maiko/sdbx/internal/config"
+	"github.com/maiko/sdbx/internal/docker"
+	"github.com/maiko/sdbx/internal/generator"
 	"github.com/maiko/sdbx/internal/registry"
 	"github.com/maiko/sdbx/internal/tui"
 )
@@ -28,7 +37,8 @@ Examples:
   sdbx addon search media          # Search for media-related addons
   sdbx addon info overseerr        # Show addon details
   sdbx addon enable overseerr      # Enable an addon
-  sdbx addon disable overseerr     # Disable an addon`,
+  sdbx addon disable overseerr     # Disable an addon
+  sdbx addon remove overseerr      # Disable and clean up an addon`,
 }
 
 var addonListCmd = &cobra.Command{
@@ -52,8 +62,12 @@ Examples:
 var addonInfoCmd = &cobra.Command{
 	Use:   "info <addon>",
 	Short: "Show detailed addon information",
-	Args:  cobra.ExactArgs(1),
-	RunE:  runAddonInfo,
+	Long: `Show detailed information about an addon.
+
+With --full, the README.md shipped next to the addon's service.yaml is
+rendered below the details (post-install steps, required tokens, ...).`,
+	Args: cobra.ExactArgs(1),
+	RunE: runAddonInfo,
 }
 
 var addonEnableCmd = &cobra.Command{
@@ -61,7 +75,20 @@ var addonEnableCmd = &cobra.Command{
 	Short: "Enable an addon",
 	Long: `Enable an optional addon service.
 
-After enabling, run 'sdbx up' to start the addon.`,
+With --instance, a named copy of the addon is added instead, e.g. a second
+Sonarr for 4K releases. The instance gets its own container, configs/
+directory, subdomain and integration entries; media and download paths stay
+shared and host ports are not published. Instances are listed under
+'instances' in .sdbx.yaml and removed with 'sdbx addon disable <instance>'.
+Only addons whose definition has 'instancing: {allowed: true}' can be
+instanced; --var sets the instancing variables it declares.
+
+After enabling, run 'sdbx up' to start the addon.
+
+Examples:
+  sdbx addon enable overseerr
+  sdbx addon enable sonarr --instance sonarr4k
+  sdbx addon enable sonarr@sonarr4k --var quality=2160p`,
 	Args: cobra.ExactArgs(1),
 	RunE: runAddonEnable,
 }
@@ -76,10 +103,42 @@ After disabling, run 'sdbx down && sdbx up' to apply changes.`,
 	RunE: runAddonDisable,
 }
 
+var addonRemoveCmd = &cobra.Command{
+	Use:   "remove <addon>",
+	Short: "Disable an addon and clean up after it",
+	Long: `Disable an addon and remove what it left behind.
+
+This command:
+  • Disables the addon and regenerates project files, dropping its
+    Traefik routes, Authelia rules and Homepage entries
+  • Removes the addon's container
+  • Deletes its secrets/<addon>.env file and post-install checklist state
+
+With --purge-config, the addon's configs/ directories, its secrets and its
+service overrides are archived to backups/ (restorable with 'sdbx backup
+restore') and then deleted. With --purge-data, its data/ directories are
+deleted without an archive. Directories shared with another service are
+never touched.
+
+Examples:
+  sdbx addon remove overseerr                 # Keep config and data
+  sdbx addon remove overseerr --purge-config  # Archive and delete its config
+  sdbx addon remove overseerr --purge-data -y # Also delete its data
+  sdbx addon remove sonarr4k --purge-config   # Remove a named instance`,
+	Args: cobra.ExactArgs(1),
+	RunE: runAddonRemove,
+}
+
 // Flags
 var (
-	addonListAll  bool
-	addonCategory string
+	addonListAll      bool
+	addonCategory     string
+	addonInfoFull     bool
+	addonPurgeConfig  bool
+	addonPurgeData    bool
+	addonRemoveYes    bool
+	addonInstance     string
+	addonInstanceVars []string
 )
 
 var addonBrowseCmd = &cobra.Command{
@@ -101,20 +160,27 @@ func init() {
 	addonCmd.AddCommand(addonInfoCmd)
 	addonCmd.AddCommand(addonEnableCmd)
 	addonCmd.AddCommand(addonDisableCmd)
+	addonCmd.AddCommand(addonRemoveCmd)
 	addonCmd.AddCommand(addonBrowseCmd)
 
 	// Flags
 	addonListCmd.Flags().BoolVarP(&addonListAll, "all", "a", false, "Show all available addons")
 	addonSearchCmd.Flags().StringVarP(&addonCategory, "category", "c", "", "Filter by category")
+	addonInfoCmd.Flags().BoolVar(&addonInfoFull, "full", false, "Also render the addon's README")
+	addonEnableCmd.Flags().StringVar(&addonInstance, "instance", "", "Add a named instance of the addon instead (e.g. sonarr4k)")
+	addonEnableCmd.Flags().StringArrayVar(&addonInstanceVars, "var", nil, "Set an instancing variable of the instance (KEY=VALUE, repeatable)")
+	addonRemoveCmd.Flags().BoolVar(&addonPurgeConfig, "purge-config", false, "Archive and delete the addon's config directories and secrets")
+	addonRemoveCmd.Flags().BoolVar(&addonPurgeData, "purge-data", false, "Delete the addon's data directories (no archive)")
+	addonRemoveCmd.Flags().BoolVarP(&addonRemoveYes, "yes", "y", false, "Skip the confirmation prompt for --purge-data")
 }
 
-func runAddonList(_ *cobra.Command, _ []string) error {
+func runAddonList(cmd *cobra.Command, _ []string) error {
 	cfg, err := config.Load()
 	if err != nil {
 		cfg = config.DefaultConfig()
 	}
 
-	ctx := context.Background()
+	ctx := commandContext(cmd)
 
 	// Get addons from registry
 	reg, err := getRegistry()
@@ -148,6 +214,7 @@ func runAddonList(_ *cobra.Command, _ []string) error {
 				"category":    addon.Category,
 				"source":      addon.Source,
 				"enabled":     cfg.IsAddonEnabled(addon.Name),
+				"instances":   cfg.InstancesOf(addon.Name),
 			})
 		}
 		return OutputJSON(result)
@@ -192,6 +259,11 @@ func runAddonList(_ *cobra.Command, _ []string) error {
 		fmt.Printf("Use '%s' to see available addons\n", tui.CommandStyle.Render("sdbx addon list --all"))
 	} else {
 		fmt.Println(table.Render())
+		for _, addon := range addons {
+			for _, instance := range cfg.InstancesOf(addon.Name) {
+				fmt.Printf("  %s %s\n", tui.IconArrow, cfg.ServiceRef(instance))
+			}
+		}
 		fmt.Printf("%s %d enabled, %d available\n",
 			tui.IconPackage,
 			enabled,
@@ -203,13 +275,13 @@ func runAddonList(_ *cobra.Command, _ []string) error {
 	return nil
 }
 
-func runAddonSearch(_ *cobra.Command, args []string) error {
+func runAddonSearch(cmd *cobra.Command, args []string) error {
 	query := ""
 	if len(args) > 0 {
 		query = args[0]
 	}
 
-	ctx := context.Background()
+	ctx := commandContext(cmd)
 
 	reg, err := getRegistry()
 	if err != nil {
@@ -275,10 +347,11 @@ func runAddonSearch(_ *cobra.Command, args []string) error {
 	return nil
 }
 
-func runAddonInfo(_ *cobra.Command, args []string) error {
-	addonName := args[0]
+func runAddonInfo(cmd *cobra.Command, args []string) error {
+	// Instances share their service's definition
+	addonName, _ := config.SplitServiceRef(args[0])
 
-	ctx := context.Background()
+	ctx := commandContext(cmd)
 
 	reg, err := getRegistry()
 	if err != nil {
@@ -294,12 +367,23 @@ func runAddonInfo(_ *cobra.Command, args []string) error {
 		return fmt.Errorf("%s is a core service, not an addon", addonName)
 	}
 
-	cfg, _ := config.Load()
-	isEnabled := cfg != nil && cfg.IsAddonEnabled(addonName)
+	cfg, err := config.Load()
+	if err != nil {
+		cfg = config.DefaultConfig()
+	}
+	isEnabled := cfg.IsAddonEnabled(addonName)
+	instances := cfg.InstancesOf(addonName)
+
+	var readme string
+	if addonInfoFull {
+		if readme, err = reg.GetServiceReadme(ctx, addonName); err != nil {
+			return err
+		}
+	}
 
 	// JSON output
 	if IsJSONOutput() {
-		return OutputJSON(map[string]interface{}{
+		info := map[string]interface{}{
 			"name":        def.Metadata.Name,
 			"version":     def.Metadata.Version,
 			"description": def.Metadata.Description,
@@ -309,7 +393,13 @@ func runAddonInfo(_ *cobra.Command, args []string) error {
 			"image":       def.Spec.Image.Repository + ":" + def.Spec.Image.Tag,
 			"port":        def.Routing.Port,
 			"enabled":     isEnabled,
-		})
+			"instancing":  def.Instancing,
+			"instances":   instances,
+		}
+		if addonInfoFull {
+			info["readme"] = readme
+		}
+		return OutputJSON(info)
 	}
 
 	fmt.Println(tui.TitleStyle.Render(tui.IconPackage + " " + def.Metadata.Name))
@@ -357,6 +447,22 @@ func runAddonInfo(_ *cobra.Command, args []string) error {
 		fmt.Println()
 	}
 
+	if def.Instancing != nil && def.Instancing.Allowed {
+		fmt.Println(tui.RenderSection("  Instances"))
+		limit := "unlimited"
+		if def.Instancing.Max > 0 {
+			limit = fmt.Sprintf("%d", def.Instancing.Max)
+		}
+		fmt.Printf("  %s\n", tui.RenderKeyValue("Max", limit))
+		for _, key := range slices.Sorted(maps.Keys(def.Instancing.Variables)) {
+			fmt.Printf("  %s\n", tui.RenderKeyValue("Variable "+key, def.Instancing.Variables[key]))
+		}
+		for _, name := range instances {
+			fmt.Printf("  %s %s\n", tui.IconArrow, cfg.ServiceRef(name))
+		}
+		fmt.Println()
+	}
+
 	if def.Metadata.Homepage != "" {
 		fmt.Println(tui.RenderSection("  Links"))
 		fmt.Printf("  %s\n", tui.RenderKeyValue("Homepage", def.Metadata.Homepage))
@@ -366,6 +472,20 @@ func runAddonInfo(_ *cobra.Command, args []string) error {
 		fmt.Println()
 	}
 
+	if addonInfoFull {
+		fmt.Println(tui.RenderSection("  README"))
+		if readme == "" {
+			fmt.Println(tui.MutedStyle.Render("  This addon has no README"))
+			fmt.Println()
+		} else {
+			rendered, err := tui.RenderMarkdown(readme, 80, IsTUIEnabled())
+			if err != nil {
+				return fmt.Errorf("failed to render README: %w", err)
+			}
+			fmt.Print(rendered)
+		}
+	}
+
 	fmt.Println(tui.RenderDivider(50))
 	if !isEnabled {
 		fmt.Printf("  %s Enable with: %s\n", tui.IconArrow, tui.CommandStyle.Render("sdbx addon enable "+addonName))
@@ -376,10 +496,16 @@ func runAddonInfo(_ *cobra.Command, args []string) error {
 	return nil
 }
 
-func runAddonEnable(_ *cobra.Command, args []string) error {
-	addonName := args[0]
+func runAddonEnable(cmd *cobra.Command, args []string) error {
+	// "sonarr@sonarr4k" is shorthand for --instance sonarr4k
+	addonName, instance := config.SplitServiceRef(args[0])
+	if instance == "" {
+		instance = addonInstance
+	} else if addonInstance != "" && addonInstance != instance {
+		return fmt.Errorf("conflicting instance names %s and %s", instance, addonInstance)
+	}
 
-	ctx := context.Background()
+	ctx := commandContext(cmd)
 
 	// Validate addon exists in registry
 	reg, err := getRegistry()
@@ -401,6 +527,13 @@ func runAddonEnable(_ *cobra.Command, args []string) error {
 		cfg = config.DefaultConfig()
 	}
 
+	if instance != "" {
+		return enableAddonInstance(ctx, cfg, reg, def, instance)
+	}
+	if len(addonInstanceVars) > 0 {
+		return fmt.Errorf("--var only applies to named instances\n\n  Try: sdbx addon enable %s --instance NAME --var KEY=VALUE", addonName)
+	}
+
 	if cfg.IsAddonEnabled(addonName) {
 		fmt.Printf("%s Addon '%s' is already enabled\n", tui.IconInfo, addonName)
 		return nil
@@ -422,14 +555,107 @@ func runAddonEnable(_ *cobra.Command, args []string) error {
 	return nil
 }
 
-func runAddonDisable(_ *cobra.Command, args []string) error {
-	addonName := args[0]
+// enableAddonInstance declares a named instance of an addon, within the
+// limits of its instancing block, and records its --var values
+func enableAddonInstance(ctx context.Context, cfg *config.Config, reg *registry.Registry, def *registry.ServiceDefinition, instance string) error {
+	addonName := def.Metadata.Name
+	inst := def.Instancing
+	if inst == nil || !inst.Allowed {
+		return fmt.Errorf("%s does not support named instances", addonName)
+	}
+	if base := cfg.InstanceOf(instance); base != "" && base != addonName {
+		return fmt.Errorf("%s is already an instance of %s", instance, base)
+	}
+	if cfg.InstanceOf(instance) == "" && inst.Max > 0 && len(cfg.InstancesOf(addonName)) >= inst.Max {
+		return fmt.Errorf("%s allows at most %d instance(s): %s",
+			addonName, inst.Max, strings.Join(cfg.InstancesOf(addonName), ", "))
+	}
+	if _, _, err := reg.GetService(ctx, instance); err == nil {
+		return fmt.Errorf("%s is already the name of a service\n\n  Try: sdbx addon enable %s --instance %s-2", instance, addonName, addonName)
+	}
+	if slices.ContainsFunc(cfg.ExtraServices, func(e config.ExtraServiceConfig) bool { return e.Name == instance }) {
+		return fmt.Errorf("%s is already the name of an extra service", instance)
+	}
+
+	variables := make(map[string]string)
+	for _, kv := range addonInstanceVars {
+		key, value, ok := strings.Cut(kv, "=")
+		if !ok {
+			return fmt.Errorf("invalid --var %q, expected KEY=VALUE", kv)
+		}
+		if _, declared := inst.Variables[key]; !declared {
+			return fmt.Errorf("%s has no instancing variable %q (available: %s)",
+				addonName, key, strings.Join(slices.Sorted(maps.Keys(inst.Variables)), ", "))
+		}
+		variables[key] = value
+	}
+
+	existing := cfg.InstanceOf(instance) != ""
+	if existing && len(variables) == 0 {
+		fmt.Printf("%s Instance '%s' already exists\n", tui.IconInfo, cfg.ServiceRef(instance))
+		return nil
+	}
+
+	cfg.AddInstance(instance, addonName)
+	if err := cfg.ValidateInstances(); err != nil {
+		return err
+	}
+	if len(variables) > 0 {
+		if cfg.Services == nil {
+			cfg.Services = make(map[string]config.ServiceOverride)
+		}
+		override := cfg.Services[instance]
+		if override.Variables == nil {
+			override.Variables = make(map[string]string)
+		}
+		maps.Copy(override.Variables, variables)
+		cfg.Services[instance] = override
+	}
+	if err := cfg.Save(".sdbx.yaml"); err != nil {
+		return fmt.Errorf("failed to save config: %w", err)
+	}
+
+	if existing {
+		fmt.Println(tui.SuccessStyle.Render(fmt.Sprintf("%s Updated instance: %s", tui.IconSuccess, cfg.ServiceRef(instance))))
+	} else {
+		fmt.Println(tui.SuccessStyle.Render(fmt.Sprintf("%s Added instance: %s", tui.IconSuccess, cfg.ServiceRef(instance))))
+	}
+	for _, key := range slices.Sorted(maps.Keys(variables)) {
+		fmt.Printf("  %s %s = %s\n", tui.IconArrow, key, variables[key])
+	}
+	fmt.Println()
+	fmt.Printf("  %s Run %s to start the service\n",
+		tui.IconArrow,
+		tui.CommandStyle.Render("sdbx up"))
+
+	return nil
+}
 
+func runAddonDisable(_ *cobra.Command, args []string) error {
 	cfg, err := config.Load()
 	if err != nil {
 		cfg = config.DefaultConfig()
 	}
 
+	addonName, err := cfg.ServiceName(args[0])
+	if err != nil {
+		return err
+	}
+
+	if base := cfg.InstanceOf(addonName); base != "" {
+		cfg.RemoveInstance(addonName)
+		if err := cfg.Save(".sdbx.yaml"); err != nil {
+			return fmt.Errorf("failed to save config: %w", err)
+		}
+
+		fmt.Println(tui.SuccessStyle.Render(fmt.Sprintf("%s Removed instance: %s", tui.IconSuccess, base+config.InstanceSeparator+addonName)))
+		fmt.Println()
+		fmt.Printf("  %s Run %s to apply changes\n",
+			tui.IconArrow,
+			tui.CommandStyle.Render("sdbx down && sdbx up"))
+		return nil
+	}
+
 	if !cfg.IsAddonEnabled(addonName) {
 		fmt.Printf("%s Addon '%s' is not enabled\n", tui.IconInfo, addonName)
 		return nil
@@ -451,7 +677,248 @@ func runAddonDisable(_ *cobra.Command, args []string) error {
 	return nil
 }
 
-func runAddonBrowse(_ *cobra.Command, _ []string) error {
+func runAddonRemove(cmd *cobra.Command, args []string) error {
+	projectDir, err := config.ProjectDir()
+	if err != nil {
+		return err
+	}
+
+	// Must not fall back to defaults before saving
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w\n\n  Try: sdbx doctor", err)
+	}
+	addonName, err := cfg.ServiceName(args[0])
+	if err != nil {
+		return err
+	}
+
+	ctx := commandContext(cmd)
+	reg, err := getRegistry()
+	if err != nil {
+		return err
+	}
+
+	// Named instances are removed like addons but live under instances:
+	instanceOf := cfg.InstanceOf(addonName)
+	if instanceOf == "" {
+		def, _, err := reg.GetService(ctx, addonName)
+		if err != nil {
+			return fmt.Errorf("addon not found: %s\nRun 'sdbx addon search' to see available addons", addonName)
+		}
+		if !def.Conditions.RequireAddon {
+			return fmt.Errorf("%s is a core service, not an addon", addonName)
+		}
+		cfg.EnableAddon(addonName)
+	}
+
+	// Compare the project with and without the addon to find what it owns
+	before, beforeGraph, err := expectedCompose(ctx, cfg, reg)
+	if err != nil {
+		return err
+	}
+	if instanceOf != "" {
+		cfg.RemoveInstance(addonName)
+	} else {
+		cfg.DisableAddon(addonName)
+	}
+	if addonPurgeConfig {
+		delete(cfg.Services, addonName)
+	}
+	after, afterGraph, err := expectedCompose(ctx, cfg, reg)
+	if err != nil {
+		return err
+	}
+	configPaths, dataPaths := addonHostPaths(before.Services[addonName], after)
+	configPaths = append(configPaths, addonSecretFiles(projectDir, beforeGraph, afterGraph)...)
+
+	if addonPurgeData && len(dataPaths) > 0 && !addonRemoveYes {
+		if !IsTUIEnabled() {
+			return fmt.Errorf("refusing to delete %s without confirmation\n\n  Try: sdbx addon remove %s --purge-data --yes",
+				strings.Join(dataPaths, ", "), addonName)
+		}
+		var confirm bool
+		if err := huh.NewConfirm().
+			Title(fmt.Sprintf("Permanently delete %s?", strings.Join(dataPaths, ", "))).
+			Value(&confirm).
+			Run(); err != nil {
+			return fmt.Errorf("confirmation prompt failed: %w", err)
+		}
+		if !confirm {
+			fmt.Println(tui.MutedStyle.Render("Aborted."))
+			return nil
+		}
+	}
+
+	result := map[string]interface{}{"addon": addonName}
+
+	// Archive before anything is deleted
+	if addonPurgeConfig && len(configPaths) > 0 {
+		archive, err := backup.NewManager(projectDir).Archive(ctx, "addon-"+addonName, configPaths)
+		if err != nil {
+			return fmt.Errorf("failed to archive %s config: %w", addonName, err)
+		}
+		result["archive"] = archive.Name
+	}
+
+	if err := cfg.Save(filepath.Join(projectDir, ".sdbx.yaml")); err != nil {
+		return fmt.Errorf("failed to save config: %w", err)
+	}
+	gen := generator.NewGeneratorWithRegistry(cfg, projectDir, reg)
+	if err := gen.GenerateContext(ctx); err != nil {
+		return fmt.Errorf("failed to regenerate project: %w\n\n  Try: sdbx doctor", err)
+	}
+
+	// Best effort from here: the addon is already gone from the project
+	var warnings []string
+	for _, e := range gen.Skipped {
+		warnings = append(warnings, "skipped "+e.Error())
+	}
+	compose := projectCompose(projectDir)
+	if resources, err := compose.ProjectResources(ctx); err != nil {
+		warnings = append(warnings, fmt.Sprintf("could not list containers: %v", err))
+	} else {
+		for _, r := range resources {
+			if r.Kind != docker.KindContainer || r.Key != addonName {
+				continue
+			}
+			if err := compose.RemoveResource(ctx, r); err != nil {
+				warnings = append(warnings, fmt.Sprintf("could not remove container %s: %v", r.Name, err))
+				continue
+			}
+			result["container"] = r.Name
+		}
+	}
+
+	envFile := filepath.Join(projectDir, "secrets", addonName+".env")
+	if err := os.Remove(envFile); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		warnings = append(warnings, err.Error())
+	}
+	if err := registry.ForgetChecklistService(projectDir, addonName); err != nil {
+		warnings = append(warnings, err.Error())
+	}
+
+	var toPurge, kept, purged []string
+	if addonPurgeConfig {
+		toPurge = append(toPurge, configPaths...)
+	} else {
+		kept = append(kept, configPaths...)
+	}
+	if addonPurgeData {
+		toPurge = append(toPurge, dataPaths...)
+	} else {
+		kept = append(kept, dataPaths...)
+	}
+	for _, rel := range toPurge {
+		if err := os.RemoveAll(filepath.Join(projectDir, rel)); err != nil {
+			warnings = append(warnings, fmt.Sprintf("could not delete %s: %v", rel, err))
+			continue
+		}
+		purged = append(purged, rel)
+	}
+	result["purged"] = purged
+	result["warnings"] = warnings
+
+	if IsJSONOutput() {
+		return OutputJSON(result)
+	}
+
+	fmt.Println(tui.SuccessStyle.Render(fmt.Sprintf("%s Removed: %s", tui.IconSuccess, addonName)))
+	if name, ok := result["container"]; ok {
+		fmt.Printf("  %s Container %s removed\n", tui.IconArrow, name)
+	}
+	if name, ok := result["archive"]; ok {
+		fmt.Printf("  %s Config archived to backups/%s\n", tui.IconArrow, name)
+	}
+	for _, rel := range purged {
+		fmt.Printf("  %s Deleted %s\n", tui.IconArrow, rel)
+	}
+	if len(kept) > 0 {
+		fmt.Printf("  %s Kept %s (use --purge-config / --purge-data to delete)\n", tui.IconInfo, strings.Join(kept, ", "))
+	}
+	for _, warning := range warnings {
+		fmt.Println(tui.WarningStyle.Render(fmt.Sprintf("%s %s", tui.IconWarning, warning)))
+	}
+	return nil
+}
+
+// expectedCompose resolves cfg and builds the compose file sdbx would write
+func expectedCompose(ctx context.Context, cfg *config.Config, reg *registry.Registry) (*generator.ComposeFile, *registry.ResolutionGraph, error) {
+	graph, err := reg.Resolve(ctx, cfg)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to resolve services: %w", err)
+	}
+	compose, err := generator.NewComposeGenerator(cfg, reg, nil).Generate(graph)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to build compose file: %w", err)
+	}
+	return compose, graph, nil
+}
+
+// addonHostPaths returns the configs/ and data/ bind mounts of an addon that
+// no remaining service mounts (or mounts a parent or child of)
+func addonHostPaths(addon generator.ComposeService, remaining *generator.ComposeFile) (configPaths, dataPaths []string) {
+	var used []string
+	for _, svc := range remaining.Services {
+		for _, volume := range svc.Volumes {
+			used = append(used, volumeHostPath(volume))
+		}
+	}
+	overlaps := func(path string) bool {
+		return slices.ContainsFunc(used, func(u string) bool {
+			return u == path || strings.HasPrefix(u, path+"/") || strings.HasPrefix(path, u+"/")
+		})
+	}
+
+	for _, volume := range addon.Volumes {
+		path := volumeHostPath(volume)
+		top, rest, _ := strings.Cut(path, "/")
+		if rest == "" || overlaps(path) {
+			continue
+		}
+		switch top {
+		case "configs":
+			configPaths = append(configPaths, path)
+		case "data":
+			dataPaths = append(dataPaths, path)
+		}
+	}
+	slices.Sort(configPaths)
+	slices.Sort(dataPaths)
+	return slices.Compact(configPaths), slices.Compact(dataPaths)
+}
+
+// volumeHostPath returns the cleaned, slash-separated host side of a
+// "host:container[:ro]" mount
+func volumeHostPath(volume string) string {
+	host, _, _ := strings.Cut(volume, ":")
+	return filepath.ToSlash(filepath.Clean(host))
+}
+
+// addonSecretFiles returns the secret files (with companions) declared by
+// services that are no longer resolved
+func addonSecretFiles(projectDir string, before, after *registry.ResolutionGraph) []string {
+	remaining := make(map[string]bool)
+	for _, spec := range after.SecretSpecs() {
+		remaining[spec.Name] = true
+	}
+
+	var files []string
+	for _, spec := range before.SecretSpecs() {
+		if remaining[spec.Name] {
+			continue
+		}
+		for _, suffix := range []string{".txt", ".pub", ".password"} {
+			rel := "secrets/" + spec.Name + suffix
+			if _, err := os.Stat(filepath.Join(projectDir, rel)); err == nil {
+				files = append(files, rel)
+			}
+		}
+	}
+	return files
+}
+
+func runAddonBrowse(cmd *cobra.Command, _ []string) error {
 	if !IsTUIEnabled() {
 		return fmt.Errorf("addon browse requires interactive mode (remove --no-tui flag)")
 	}
@@ -461,7 +928,7 @@ func runAddonBrowse(_ *cobra.Command, _ []string) error {
 		cfg = config.DefaultConfig()
 	}
 
-	ctx := context.Background()
+	ctx := commandContext(cmd)
 
 	reg, err := getRegistry()
 	if err != nil {
@@ -596,3 +1063,15 @@ func getRegistry() (*registry.Registry, error) {
 	return registryProvider()
 }
 
+// serviceArg maps a service argument to the compose service it names,
+// accepting "service@instance" references to named instances
+func serviceArg(ref string) (string, error) {
+	if !strings.Contains(ref, config.InstanceSeparator) {
+		return ref, nil
+	}
+	cfg, err := config.Load()
+	if err != nil {
+		return "", fmt.Errorf("failed to load configuration: %w\n\n  Try: sdbx doctor", err)
+	}
+	return cfg.ServiceName(ref)
+}
@@ -10,6 +10,7 @@ import (
 	"testing"
 
 	"github.com/maiko/sdbx/internal/config"
+	"github.com/maiko/sdbx/internal/generator"
 	"github.com/maiko/sdbx/internal/registry"
 )
 
@@ -445,3 +446,188 @@ func TestAddonDisableNotEnabled(t *testing.T) {
 		t.Errorf("Output should mention not enabled: %s", output)
 	}
 }
+
+func TestAddonEnableInstance(t *testing.T) {
+	addons := defaultTestAddons()
+	addons["lidarr"] += `instancing:
+  allowed: true
+  max: 1
+  variables:
+    quality: lossless
+`
+	cleanup := setupTestRegistry(t, addons)
+	defer cleanup()
+
+	tmpDir := t.TempDir()
+	oldCwd, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(oldCwd)
+
+	cfg := config.DefaultConfig()
+	if err := cfg.Save(".sdbx.yaml"); err != nil {
+		t.Fatalf("Failed to save test config: %v", err)
+	}
+
+	oldInstance, oldVars := addonInstance, addonInstanceVars
+	defer func() { addonInstance, addonInstanceVars = oldInstance, oldVars }()
+
+	oldStdout := os.Stdout
+	defer func() { os.Stdout = oldStdout }()
+	_, w, _ := os.Pipe()
+	os.Stdout = w
+	defer w.Close()
+
+	// Instance names may not reuse a registry service name
+	addonInstance = "overseerr"
+	if err := runAddonEnable(addonEnableCmd, []string{"lidarr"}); err == nil {
+		t.Error("expected an error for an instance named after a service")
+	}
+
+	// Only addons with an instancing block can be copied
+	addonInstance = "tautulli2"
+	if err := runAddonEnable(addonEnableCmd, []string{"tautulli"}); err == nil {
+		t.Error("expected an error for an addon without instancing")
+	}
+
+	// Undeclared variables are rejected
+	addonInstance, addonInstanceVars = "lidarr-hifi", []string{"bitrate=320"}
+	if err := runAddonEnable(addonEnableCmd, []string{"lidarr"}); err == nil {
+		t.Error("expected an error for an undeclared variable")
+	}
+
+	// name@instance is shorthand for --instance
+	addonInstance, addonInstanceVars = "", []string{"quality=hires"}
+	if err := runAddonEnable(addonEnableCmd, []string{"lidarr@lidarr-hifi"}); err != nil {
+		t.Fatalf("runAddonEnable lidarr@lidarr-hifi failed: %v", err)
+	}
+
+	loadedCfg, err := config.Load()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if got := loadedCfg.InstanceOf("lidarr-hifi"); got != "lidarr" {
+		t.Errorf("InstanceOf(lidarr-hifi) = %q, want lidarr", got)
+	}
+	if loadedCfg.IsAddonEnabled("lidarr") {
+		t.Error("adding an instance should not enable the original addon")
+	}
+	if got := loadedCfg.Services["lidarr-hifi"].Variables["quality"]; got != "hires" {
+		t.Errorf("quality variable = %q, want hires", got)
+	}
+
+	// Instances cannot be copied again
+	addonInstance, addonInstanceVars = "lidarr-copy", nil
+	if err := runAddonEnable(addonEnableCmd, []string{"lidarr-hifi"}); err == nil {
+		t.Error("expected an error for an instance of an instance")
+	}
+
+	// instancing.max is 1
+	if err := runAddonEnable(addonEnableCmd, []string{"lidarr"}); err == nil {
+		t.Error("expected an error beyond instancing.max")
+	}
+
+	if err := runAddonDisable(addonDisableCmd, []string{"lidarr@lidarr-hifi"}); err != nil {
+		t.Fatalf("runAddonDisable failed: %v", err)
+	}
+	loadedCfg, err = config.Load()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if len(loadedCfg.Instances) != 0 {
+		t.Errorf("instance should have been removed, got %v", loadedCfg.Instances)
+	}
+}
+
+func TestAddonRemovePurge(t *testing.T) {
+	addon := strings.Replace(testAddonYAML("lidarr", "media", "Music automation"), "routing:", `  volumes:
+    - name: config
+      hostPath: "./configs/lidarr"
+      containerPath: /config
+    - name: data
+      hostPath: "./data/lidarr"
+      containerPath: /data
+routing:`, 1)
+	cleanup := setupTestRegistry(t, map[string]string{"lidarr": addon})
+	defer cleanup()
+
+	tmpDir := t.TempDir()
+	oldCwd, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(oldCwd)
+
+	cfg := config.DefaultConfig()
+	cfg.EnableAddon("lidarr")
+	if err := cfg.Save(".sdbx.yaml"); err != nil {
+		t.Fatalf("Failed to save test config: %v", err)
+	}
+	for _, dir := range []string{"configs/lidarr", "data/lidarr", "secrets"} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	os.WriteFile("configs/lidarr/config.xml", []byte("<Config/>"), 0644)
+	os.WriteFile("data/lidarr/library.db", []byte("db"), 0644)
+	os.WriteFile("secrets/lidarr.env", []byte("API_KEY=x\n"), 0600)
+
+	oldPurgeConfig, oldPurgeData, oldYes := addonPurgeConfig, addonPurgeData, addonRemoveYes
+	addonPurgeConfig, addonPurgeData, addonRemoveYes = true, true, true
+	defer func() { addonPurgeConfig, addonPurgeData, addonRemoveYes = oldPurgeConfig, oldPurgeData, oldYes }()
+
+	oldStdout := os.Stdout
+	r, w, _ := os.Pipe()
+	os.Stdout = w
+	err := runAddonRemove(addonRemoveCmd, []string{"lidarr"})
+	w.Close()
+	os.Stdout = oldStdout
+	var buf bytes.Buffer
+	io.Copy(&buf, r)
+	if err != nil {
+		t.Fatalf("runAddonRemove failed: %v\n%s", err, buf.String())
+	}
+
+	loadedCfg, err := config.Load()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if loadedCfg.IsAddonEnabled("lidarr") {
+		t.Error("lidarr should be disabled in saved config")
+	}
+	for _, path := range []string{"configs/lidarr", "data/lidarr", "secrets/lidarr.env"} {
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("%s should have been deleted", path)
+		}
+	}
+	compose, err := os.ReadFile("compose.yaml")
+	if err != nil {
+		t.Fatalf("compose.yaml should have been regenerated: %v", err)
+	}
+	if strings.Contains(string(compose), "lidarr") {
+		t.Error("compose.yaml should no longer contain lidarr")
+	}
+
+	archives, _ := filepath.Glob("backups/sdbx-addon-lidarr-*.tar.gz")
+	if len(archives) != 1 {
+		t.Fatalf("expected one config archive, got %v\n%s", archives, buf.String())
+	}
+}
+
+func TestAddonHostPaths(t *testing.T) {
+	addon := generator.ComposeService{Volumes: []string{
+		"./configs/overseerr:/config",
+		"./data/overseerr/cache:/cache",
+		"./configs/traefik/dynamic:/dynamic:ro",
+		"/srv/media:/media",
+		"./configs:/all",
+	}}
+	remaining := &generator.ComposeFile{Services: map[string]generator.ComposeService{
+		"traefik": {Volumes: []string{"./configs/traefik/dynamic:/etc/traefik/dynamic"}},
+	}}
+
+	configPaths, dataPaths := addonHostPaths(addon, remaining)
+	if strings.Join(configPaths, ",") != "configs/overseerr" {
+		t.Errorf("configPaths = %v, want [configs/overseerr]", configPaths)
+	}
+	if strings.Join(dataPaths, ",") != "data/overseerr/cache" {
+		t.Errorf("dataPaths = %v, want [data/overseerr/cache]", dataPaths)
+	}
+}
@@ -0,0 +1,428 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/spf13/cobra"
+
+	"github.com/maiko/sdbx/internal/agent"
+	"github.com/maiko/sdbx/internal/config"
+	"github.com/maiko/sdbx/internal/docker"
+	"github.com/maiko/sdbx/internal/tui"
+)
+
+var agentCmd = &cobra.Command{
+	Use:   "agent",
+	Short: "Manage seedboxes remotely through sdbx agents",
+	Long: `Run an agent on a seedbox, or manage several seedboxes from one place.
+
+'sdbx agent serve' runs on the seedbox, in the project directory: a
+lightweight API for compose operations, log streaming and file sync,
+authenticated with the project's API tokens (sdbx token create). Read
+tokens may list services, read logs and fetch files; write tokens may
+also run compose actions and write files. Secrets, tokens and share links
+are never served.
+
+The other commands are the control plane: agents are named in
+~/.config/sdbx/agents.yaml with their URL and token, and commands taking
+an agent accept a comma-separated list, or "all".
+
+Examples:
+  sdbx agent serve --listen :7070                  # On the seedbox
+  sdbx agent add box1 https://box1.lan:7070 --token sdbx_...
+  sdbx agent list                                  # Agents and their reachability
+  sdbx agent status all                            # Services of every agent
+  sdbx agent compose box1,box2 restart sonarr
+  sdbx agent logs box1 qbittorrent -f
+  sdbx agent sync box1 && sdbx agent compose box1 up`,
+}
+
+var agentServeCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Serve the agent API of this project",
+	Args:  cobra.NoArgs,
+	RunE:  runAgentServe,
+}
+
+var agentAddCmd = &cobra.Command{
+	Use:   "add <name> <url>",
+	Short: "Add or update an agent",
+	Args:  cobra.ExactArgs(2),
+	RunE:  runAgentAdd,
+}
+
+var agentListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List agents and whether they are reachable",
+	Args:  cobra.NoArgs,
+	RunE:  runAgentList,
+}
+
+var agentRemoveCmd = &cobra.Command{
+	Use:     "remove <name>",
+	Aliases: []string{"rm"},
+	Short:   "Remove an agent",
+	Args:    cobra.ExactArgs(1),
+	RunE:    runAgentRemove,
+}
+
+var agentStatusCmd = &cobra.Command{
+	Use:   "status [agents]",
+	Short: "Show the services of agents (all by default)",
+	Args:  cobra.MaximumNArgs(1),
+	RunE:  runAgentStatus,
+}
+
+var agentComposeCmd = &cobra.Command{
+	Use:   "compose <agents> <action> [services...]",
+	Short: "Run a compose action on agents: " + strings.Join(agent.Actions, ", "),
+	Long: `Run a compose action on agents. up and pull apply to every service
+without a list; down without a list removes the project, with one it stops
+the listed services. start, stop and restart need services.`,
+	Args: cobra.MinimumNArgs(2),
+	RunE: runAgentCompose,
+}
+
+var agentLogsCmd = &cobra.Command{
+	Use:   "logs <agent> <service>",
+	Short: "Show or follow the logs of a service of an agent",
+	Args:  cobra.ExactArgs(2),
+	RunE:  runAgentLogs,
+}
+
+var agentSyncCmd = &cobra.Command{
+	Use:   "sync <agents> [paths...]",
+	Short: "Push this project's files that differ to agents",
+	Long: `Push the files of this project that differ on agents, by default the
+configuration and generated files: ` + strings.Join(agent.SyncPaths, ", ") + `.
+Files only the agent has are kept. Run 'sdbx agent compose <agent> up'
+afterwards to apply them.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: runAgentSync,
+}
+
+var (
+	agentListen  string
+	agentName    string
+	agentTLSCert string
+	agentTLSKey  string
+	agentToken   string
+	agentFollow  bool
+	agentLines   int
+	agentDryRun  bool
+)
+
+func init() {
+	rootCmd.AddCommand(agentCmd)
+	agentCmd.AddCommand(agentServeCmd)
+	agentCmd.AddCommand(agentAddCmd)
+	agentCmd.AddCommand(agentListCmd)
+	agentCmd.AddCommand(agentRemoveCmd)
+	agentCmd.AddCommand(agentStatusCmd)
+	agentCmd.AddCommand(agentComposeCmd)
+	agentCmd.AddCommand(agentLogsCmd)
+	agentCmd.AddCommand(agentSyncCmd)
+
+	agentServeCmd.Flags().StringVar(&agentListen, "listen", ":"+strconv.Itoa(agent.DefaultPort), "Address to listen on")
+	agentServeCmd.Flags().StringVar(&agentName, "name", "", "Name the agent reports (default: the hostname)")
+	agentServeCmd.Flags().StringVar(&agentTLSCert, "tls-cert", "", "TLS certificate file, to serve HTTPS")
+	agentServeCmd.Flags().StringVar(&agentTLSKey, "tls-key", "", "TLS key file, to serve HTTPS")
+	agentServeCmd.MarkFlagsRequiredTogether("tls-cert", "tls-key")
+	agentAddCmd.Flags().StringVar(&agentToken, "token", "", "API token of the agent's project (default: $SDBX_AGENT_TOKEN)")
+	agentLogsCmd.Flags().BoolVarP(&agentFollow, "follow", "f", false, "Follow new log lines")
+	agentLogsCmd.Flags().IntVarP(&agentLines, "lines", "n", 100, "Number of lines to show")
+	agentSyncCmd.Flags().BoolVar(&agentDryRun, "dry-run", false, "List the files that differ without pushing them")
+}
+
+func runAgentServe(cmd *cobra.Command, _ []string) error {
+	projectDir, err := config.ProjectDir()
+	if err != nil {
+		return err
+	}
+	name := agentName
+	if name == "" {
+		name, _ = os.Hostname()
+	}
+
+	server := agent.NewServer(name, projectDir, Version)
+	tokens, err := server.Tokens.List()
+	if err != nil {
+		return err
+	}
+	if len(tokens) == 0 {
+		fmt.Println(tui.WarningStyle.Render(tui.IconWarning + " No API token yet, every request will be refused. Create one with: sdbx token create <name> --scope write"))
+	}
+
+	scheme := "http"
+	if agentTLSCert != "" {
+		scheme = "https"
+	} else if host, _, _ := net.SplitHostPort(agentListen); host == "" || !net.ParseIP(host).IsLoopback() {
+		fmt.Println(tui.WarningStyle.Render(tui.IconWarning + " Serving plain HTTP: tokens cross the network in clear. Use --tls-cert and --tls-key, or a VPN."))
+	}
+	fmt.Printf("%s Agent %s serving %s on %s://%s\n", tui.IconInfo, name, projectDir, scheme, agentListen)
+	return server.ListenAndServe(commandContext(cmd), agentListen, agentTLSCert, agentTLSKey)
+}
+
+func runAgentAdd(cmd *cobra.Command, args []string) error {
+	token := agentToken
+	if token == "" {
+		token = os.Getenv("SDBX_AGENT_TOKEN")
+	}
+	a := agent.Agent{Name: args[0], URL: args[1], Token: token}
+
+	path := agent.AgentsPath()
+	agents, err := agent.LoadAgents(path)
+	if err != nil {
+		return err
+	}
+	if err := agents.Add(a); err != nil {
+		return err
+	}
+	info, err := agent.NewClient(a).Info(commandContext(cmd))
+	if err != nil {
+		return fmt.Errorf("%w\n\n  Try: sdbx agent serve, on the seedbox", err)
+	}
+	if err := agents.Save(path); err != nil {
+		return err
+	}
+
+	fmt.Println(tui.SuccessStyle.Render(fmt.Sprintf("%s Added agent %s: %s (sdbx %s) serving %s", tui.IconSuccess, a.Name, info.Hostname, info.Version, info.ProjectDir)))
+	return nil
+}
+
+// agentEntry is an agent with its reachability, for 'sdbx agent list'
+type agentEntry struct {
+	agent.Agent
+	Info  *agent.Info `json:"info,omitempty"`
+	Error string      `json:"error,omitempty"`
+}
+
+func runAgentList(cmd *cobra.Command, _ []string) error {
+	agents, err := agent.LoadAgents(agent.AgentsPath())
+	if err != nil {
+		return err
+	}
+
+	entries := make([]agentEntry, len(agents.Agents))
+	forEachAgent(agents.Agents, func(i int, c *agent.Client) {
+		info, err := c.Info(commandContext(cmd))
+		entries[i] = agentEntry{Agent: c.Agent(), Info: info}
+		if err != nil {
+			entries[i].Error = err.Error()
+		}
+	})
+
+	if IsJSONOutput() {
+		return OutputJSON(entries)
+	}
+	if len(entries) == 0 {
+		fmt.Println(tui.MutedStyle.Render("No agents. Add one with: sdbx agent add <name> <url> --token <token>"))
+		return nil
+	}
+	table := tui.NewTable("Name", "URL", "Host", "Version", "Domain")
+	for _, e := range entries {
+		if e.Info == nil {
+			table.AddRow(e.Name, e.URL, tui.ErrorStyle.Render("unreachable"), "", "")
+			continue
+		}
+		table.AddRow(e.Name, e.URL, e.Info.Hostname, e.Info.Version, e.Info.Domain)
+	}
+	fmt.Println(table.Render())
+	return nil
+}
+
+func runAgentRemove(_ *cobra.Command, args []string) error {
+	path := agent.AgentsPath()
+	agents, err := agent.LoadAgents(path)
+	if err != nil {
+		return err
+	}
+	if err := agents.Remove(args[0]); err != nil {
+		if errors.Is(err, agent.ErrAgentNotFound) {
+			return fmt.Errorf("%w\n\n  Try: sdbx agent list", err)
+		}
+		return err
+	}
+	if err := agents.Save(path); err != nil {
+		return err
+	}
+	fmt.Println(tui.SuccessStyle.Render(fmt.Sprintf("✓ Removed agent %s", args[0])))
+	return nil
+}
+
+func runAgentStatus(cmd *cobra.Command, args []string) error {
+	selector := "all"
+	if len(args) > 0 {
+		selector = args[0]
+	}
+	selected, err := selectAgents(selector)
+	if err != nil {
+		return err
+	}
+
+	ctx := commandContext(cmd)
+	type agentStatus struct {
+		Agent    string           `json:"agent"`
+		Services []docker.Service `json:"services,omitempty"`
+		Error    string           `json:"error,omitempty"`
+	}
+	statuses := make([]agentStatus, len(selected))
+	forEachAgent(selected, func(i int, c *agent.Client) {
+		statuses[i].Agent = c.Agent().Name
+		services, err := c.Services(ctx)
+		if err != nil {
+			statuses[i].Error = err.Error()
+			return
+		}
+		statuses[i].Services = services
+	})
+
+	if IsJSONOutput() {
+		return OutputJSON(statuses)
+	}
+	table := tui.NewTable("Agent", "Service", "Status", "Health", "Image")
+	failed := 0
+	for _, s := range statuses {
+		if s.Error != "" {
+			failed++
+			table.AddRow(s.Agent, "", tui.ErrorStyle.Render(s.Error), "", "")
+			continue
+		}
+		for _, svc := range s.Services {
+			status := tui.SuccessStyle.Render(svc.Status)
+			if !svc.Running {
+				status = tui.ErrorStyle.Render(svc.Status)
+			}
+			table.AddRow(s.Agent, svc.Service, status, svc.Health, svc.Image)
+		}
+	}
+	fmt.Println(table.Render())
+	if failed > 0 {
+		return fmt.Errorf("%d of %d agents unreachable", failed, len(statuses))
+	}
+	return nil
+}
+
+func runAgentCompose(cmd *cobra.Command, args []string) error {
+	action, services := args[1], args[2:]
+	if !slices.Contains(agent.Actions, action) {
+		return fmt.Errorf("unknown action %q (must be one of %s)", action, strings.Join(agent.Actions, ", "))
+	}
+	selected, err := selectAgents(args[0])
+	if err != nil {
+		return err
+	}
+
+	ctx := commandContext(cmd)
+	errs := make([]error, len(selected))
+	forEachAgent(selected, func(i int, c *agent.Client) {
+		errs[i] = c.Compose(ctx, action, services...)
+	})
+
+	target := "all services"
+	if len(services) > 0 {
+		target = strings.Join(services, ", ")
+	}
+	failed := 0
+	for i, a := range selected {
+		if errs[i] != nil {
+			failed++
+			fmt.Printf("%s %s\n", tui.ErrorStyle.Render(tui.IconError), errs[i])
+			continue
+		}
+		fmt.Printf("%s %s: %s %s\n", tui.SuccessStyle.Render(tui.IconSuccess), a.Name, action, target)
+	}
+	if failed > 0 {
+		return fmt.Errorf("%s failed on %d of %d agents", action, failed, len(selected))
+	}
+	return nil
+}
+
+func runAgentLogs(cmd *cobra.Command, args []string) error {
+	selected, err := selectAgents(args[0])
+	if err != nil {
+		return err
+	}
+	if len(selected) != 1 {
+		return fmt.Errorf("logs are shown for one agent at a time")
+	}
+	return agent.NewClient(selected[0]).Logs(commandContext(cmd), args[1], agentLines, agentFollow, os.Stdout)
+}
+
+func runAgentSync(cmd *cobra.Command, args []string) error {
+	projectDir, err := config.ProjectDir()
+	if err != nil {
+		return err
+	}
+	selected, err := selectAgents(args[0])
+	if err != nil {
+		return err
+	}
+	paths := agent.SyncPaths
+	if len(args) > 1 {
+		paths = args[1:]
+	}
+
+	ctx := commandContext(cmd)
+	failed := 0
+	for _, a := range selected {
+		changed, err := agent.NewClient(a).Sync(ctx, projectDir, paths, agentDryRun)
+		for _, f := range changed {
+			fmt.Printf("  %s %s\n", a.Name, f.Path)
+		}
+		switch {
+		case err != nil:
+			failed++
+			fmt.Printf("%s %s\n", tui.ErrorStyle.Render(tui.IconError), err)
+		case agentDryRun:
+			fmt.Printf("%s %s: %d files differ\n", tui.IconInfo, a.Name, len(changed))
+		default:
+			fmt.Printf("%s %s: %d files pushed\n", tui.SuccessStyle.Render(tui.IconSuccess), a.Name, len(changed))
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("sync failed on %d of %d agents", failed, len(selected))
+	}
+	return nil
+}
+
+// selectAgents returns the agents named by a comma-separated list, or
+// every agent for "all"
+func selectAgents(selector string) ([]agent.Agent, error) {
+	agents, err := agent.LoadAgents(agent.AgentsPath())
+	if err != nil {
+		return nil, err
+	}
+	if selector == "all" {
+		if len(agents.Agents) == 0 {
+			return nil, fmt.Errorf("no agents\n\n  Try: sdbx agent add <name> <url> --token <token>")
+		}
+		return agents.Agents, nil
+	}
+
+	var selected []agent.Agent
+	for _, name := range strings.Split(selector, ",") {
+		a, err := agents.Get(strings.TrimSpace(name))
+		if err != nil {
+			return nil, fmt.Errorf("%w\n\n  Try: sdbx agent list", err)
+		}
+		selected = append(selected, a)
+	}
+	return selected, nil
+}
+
+// forEachAgent calls fn with a client of each agent, concurrently
+func forEachAgent(agents []agent.Agent, fn func(int, *agent.Client)) {
+	var wg sync.WaitGroup
+	for i, a := range agents {
+		wg.Go(func() { fn(i, agent.NewClient(a)) })
+	}
+	wg.Wait()
+}
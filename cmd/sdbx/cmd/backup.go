@@ -1,7 +1,6 @@
 package cmd
 
 import (
-	"context"
 	"fmt"
 	"os"
 
@@ -77,7 +76,7 @@ func init() {
 	backupCreateCmd.Flags().StringVarP(&backupOutput, "output", "o", "", "Custom backup output directory")
 }
 
-func runBackupCreate(_ *cobra.Command, _ []string) error {
+func runBackupCreate(cmd *cobra.Command, _ []string) error {
 	// Get project directory
 	projectDir, err := config.ProjectDir()
 	if err != nil {
@@ -92,7 +91,7 @@ func runBackupCreate(_ *cobra.Command, _ []string) error {
 	// Create backup manager
 	manager := backup.NewManager(projectDir)
 
-	ctx := context.Background()
+	ctx := commandContext(cmd)
 
 	if !IsJSONOutput() {
 		fmt.Println(tui.TitleStyle.Render("Creating Backup"))
@@ -130,7 +129,7 @@ func runBackupCreate(_ *cobra.Command, _ []string) error {
 	return nil
 }
 
-func runBackupList(_ *cobra.Command, _ []string) error {
+func runBackupList(cmd *cobra.Command, _ []string) error {
 	// Get project directory
 	projectDir, err := config.ProjectDir()
 	if err != nil {
@@ -140,7 +139,7 @@ func runBackupList(_ *cobra.Command, _ []string) error {
 	// Create backup manager
 	manager := backup.NewManager(projectDir)
 
-	ctx := context.Background()
+	ctx := commandContext(cmd)
 
 	// List backups
 	backups, err := manager.List(ctx)
@@ -186,7 +185,7 @@ func runBackupList(_ *cobra.Command, _ []string) error {
 	return nil
 }
 
-func runBackupRestore(_ *cobra.Command, args []string) error {
+func runBackupRestore(cmd *cobra.Command, args []string) error {
 	backupName := args[0]
 
 	// Get project directory
@@ -203,7 +202,7 @@ func runBackupRestore(_ *cobra.Command, args []string) error {
 	// Create backup manager
 	manager := backup.NewManager(projectDir)
 
-	ctx := context.Background()
+	ctx := commandContext(cmd)
 
 	if !IsJSONOutput() {
 		fmt.Println(tui.TitleStyle.Render("Restoring Backup"))
@@ -233,7 +232,7 @@ func runBackupRestore(_ *cobra.Command, args []string) error {
 	return nil
 }
 
-func runBackupDelete(_ *cobra.Command, args []string) error {
+func runBackupDelete(cmd *cobra.Command, args []string) error {
 	backupName := args[0]
 
 	// Get project directory
@@ -245,7 +244,7 @@ func runBackupDelete(_ *cobra.Command, args []string) error {
 	// Create backup manager
 	manager := backup.NewManager(projectDir)
 
-	ctx := context.Background()
+	ctx := commandContext(cmd)
 
 	// Delete backup
 	if err := manager.Delete(ctx, backupName); err != nil {
@@ -0,0 +1,230 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"runtime/pprof"
+	"slices"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/maiko/sdbx/internal/config"
+	"github.com/maiko/sdbx/internal/generator"
+	"github.com/maiko/sdbx/internal/registry"
+	"github.com/maiko/sdbx/internal/tui"
+)
+
+var benchCmd = &cobra.Command{
+	Use:    "bench",
+	Short:  "Time source loading, resolution and generation",
+	Hidden: true,
+	Long: `Time each phase of a generation of the current project, to find what is
+slow in large multi-source setups:
+
+  load        Create the registry and read every definition of every source
+  resolve     Resolve the services enabled by .sdbx.yaml
+  templates   Render compose.yaml from the resolved services
+  generate    Full generation (resolution included) into a temporary directory
+
+Each phase runs --iterations times with a fresh registry. The project files
+are not modified.
+
+Examples:
+  sdbx bench
+  sdbx bench -n 20 --cpuprofile cpu.out   # then: go tool pprof cpu.out
+  sdbx bench --memprofile mem.out --json`,
+	Args: cobra.NoArgs,
+	RunE: runBench,
+}
+
+var (
+	benchIterations int
+	benchCPUProfile string
+	benchMemProfile string
+)
+
+func init() {
+	rootCmd.AddCommand(benchCmd)
+
+	benchCmd.Flags().IntVarP(&benchIterations, "iterations", "n", 5, "Number of runs of each phase")
+	benchCmd.Flags().StringVar(&benchCPUProfile, "cpuprofile", "", "Write a CPU profile of the runs to this file")
+	benchCmd.Flags().StringVar(&benchMemProfile, "memprofile", "", "Write a heap profile after the runs to this file")
+}
+
+// Benchmark phases, in run order
+var benchPhases = []string{"load", "resolve", "templates", "generate"}
+
+// benchPhase summarizes the run times of a phase
+type benchPhase struct {
+	Name string        `json:"name"`
+	Min  time.Duration `json:"min_ns"`
+	Avg  time.Duration `json:"avg_ns"`
+	Max  time.Duration `json:"max_ns"`
+}
+
+// summarizeRuns returns the min, average and max of a phase's run times
+func summarizeRuns(name string, runs []time.Duration) benchPhase {
+	phase := benchPhase{Name: name}
+	if len(runs) == 0 {
+		return phase
+	}
+	var total time.Duration
+	for _, d := range runs {
+		total += d
+	}
+	phase.Min = slices.Min(runs)
+	phase.Max = slices.Max(runs)
+	phase.Avg = total / time.Duration(len(runs))
+	return phase
+}
+
+func runBench(cmd *cobra.Command, _ []string) error {
+	if benchIterations < 1 {
+		return fmt.Errorf("--iterations must be at least 1")
+	}
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	if benchCPUProfile != "" {
+		f, err := os.Create(benchCPUProfile)
+		if err != nil {
+			return fmt.Errorf("failed to create CPU profile: %w", err)
+		}
+		defer f.Close()
+		if err := pprof.StartCPUProfile(f); err != nil {
+			return fmt.Errorf("failed to start CPU profile: %w", err)
+		}
+		defer pprof.StopCPUProfile()
+	}
+
+	runs := make(map[string][]time.Duration, len(benchPhases))
+	var sources, services, resolved int
+	bench := func() error {
+		ctx := commandContext(cmd)
+		timed := func(phase string, fn func() error) error {
+			start := time.Now()
+			err := fn()
+			runs[phase] = append(runs[phase], time.Since(start))
+			if err != nil {
+				return fmt.Errorf("%s: %w", phase, err)
+			}
+			return nil
+		}
+
+		var reg *registry.Registry
+		if err := timed("load", func() error {
+			var err error
+			if reg, err = registry.NewWithDefaults(); err != nil {
+				return err
+			}
+			list, err := reg.ListServices(ctx)
+			sources, services = len(reg.Sources()), len(list)
+			return err
+		}); err != nil {
+			return err
+		}
+
+		var graph *registry.ResolutionGraph
+		if err := timed("resolve", func() error {
+			var err error
+			graph, err = reg.Resolve(ctx, cfg)
+			if err == nil {
+				resolved = len(graph.Order)
+			}
+			return err
+		}); err != nil {
+			return err
+		}
+
+		if err := timed("templates", func() error {
+			_, err := generator.NewComposeGenerator(cfg, reg, map[string]string{}).Generate(graph)
+			return err
+		}); err != nil {
+			return err
+		}
+
+		dir, err := os.MkdirTemp("", "sdbx-bench-")
+		if err != nil {
+			return err
+		}
+		defer os.RemoveAll(dir)
+		return timed("generate", func() error {
+			return generator.NewGeneratorWithRegistry(cfg, dir, reg).GenerateContext(ctx)
+		})
+	}
+
+	run := func() error {
+		for i := 0; i < benchIterations; i++ {
+			if err := bench(); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if IsTUIEnabled() && !IsJSONOutput() {
+		err = tui.RunWithSpinner(fmt.Sprintf("Running %d iterations...", benchIterations), run)
+	} else {
+		err = run()
+	}
+	if err != nil {
+		return fmt.Errorf("benchmark failed: %w", err)
+	}
+
+	if benchMemProfile != "" {
+		f, err := os.Create(benchMemProfile)
+		if err != nil {
+			return fmt.Errorf("failed to create heap profile: %w", err)
+		}
+		defer f.Close()
+		runtime.GC()
+		if err := pprof.WriteHeapProfile(f); err != nil {
+			return fmt.Errorf("failed to write heap profile: %w", err)
+		}
+	}
+
+	phases := make([]benchPhase, 0, len(benchPhases))
+	for _, name := range benchPhases {
+		phases = append(phases, summarizeRuns(name, runs[name]))
+	}
+
+	if IsJSONOutput() {
+		return OutputJSON(map[string]interface{}{
+			"iterations": benchIterations,
+			"sources":    sources,
+			"services":   services,
+			"resolved":   resolved,
+			"phases":     phases,
+		})
+	}
+
+	fmt.Println()
+	fmt.Println(tui.TitleStyle.Render("Generation Benchmark"))
+	fmt.Println()
+	fmt.Printf("  %s\n", tui.RenderKeyValue("Iterations", fmt.Sprintf("%d", benchIterations)))
+	fmt.Printf("  %s\n", tui.RenderKeyValue("Sources", fmt.Sprintf("%d", sources)))
+	fmt.Printf("  %s\n", tui.RenderKeyValue("Services", fmt.Sprintf("%d available, %d resolved", services, resolved)))
+	fmt.Println()
+
+	table := tui.NewTable("Phase", "Min", "Avg", "Max")
+	for _, phase := range phases {
+		table.AddRow(phase.Name, benchDuration(phase.Min), benchDuration(phase.Avg), benchDuration(phase.Max))
+	}
+	fmt.Println(table.Render())
+
+	for _, path := range []string{benchCPUProfile, benchMemProfile} {
+		if path != "" {
+			fmt.Printf("Profile written to %s (inspect with '%s')\n", path, tui.CommandStyle.Render("go tool pprof "+path))
+		}
+	}
+	fmt.Println()
+	return nil
+}
+
+// benchDuration formats a phase duration in milliseconds
+func benchDuration(d time.Duration) string {
+	return fmt.Sprintf("%.1f ms", float64(d.Microseconds())/1000)
+}
@@ -0,0 +1,16 @@
+package cmd
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSummarizeRuns(t *testing.T) {
+	phase := summarizeRuns("resolve", []time.Duration{30 * time.Millisecond, 10 * time.Millisecond, 20 * time.Millisecond})
+	if phase.Min != 10*time.Millisecond || phase.Avg != 20*time.Millisecond || phase.Max != 30*time.Millisecond {
+		t.Errorf("summarizeRuns() = %+v, want 10ms/20ms/30ms", phase)
+	}
+	if empty := summarizeRuns("load", nil); empty.Min != 0 || empty.Avg != 0 {
+		t.Errorf("summarizeRuns(nil) = %+v", empty)
+	}
+}
@@ -0,0 +1,241 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/maiko/sdbx/internal/backup"
+	"github.com/maiko/sdbx/internal/registry"
+	"github.com/maiko/sdbx/internal/tui"
+)
+
+var cacheCmd = &cobra.Command{
+	Use:   "cache",
+	Short: "Manage the source cache",
+	Long: `Manage the cache of service sources (~/.cache/sdbx/sources by default):
+clones of Git sources, extracted archive sources and refreshed embedded
+definitions.
+
+A cached source is used without contacting its remote until it is older
+than the cache TTL (cache.ttl in sources.yaml, default 24h).
+
+Examples:
+  sdbx cache status             # Size and age of each cached source
+  sdbx cache gc                 # Remove clones of sources no longer configured
+  sdbx cache clear official     # Drop a source; it is fetched again on next use`,
+}
+
+var cacheStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show the size and age of cached sources",
+	Args:  cobra.NoArgs,
+	RunE:  runCacheStatus,
+}
+
+var cacheClearCmd = &cobra.Command{
+	Use:   "clear [source]",
+	Short: "Remove cached sources",
+	Long: `Remove a cached source, or the whole cache when no source is given.
+Sources are fetched again the next time they are used; refreshed embedded
+definitions are removed with the whole cache.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runCacheClear,
+}
+
+var cacheGCCmd = &cobra.Command{
+	Use:   "gc",
+	Short: "Remove cached sources that are no longer configured",
+	Long: `Remove the clones of sources no longer in sources.yaml, and directories
+left behind by interrupted downloads.`,
+	Args: cobra.NoArgs,
+	RunE: runCacheGC,
+}
+
+var (
+	cacheClearYes bool
+	cacheGCDryRun bool
+)
+
+func init() {
+	rootCmd.AddCommand(cacheCmd)
+	cacheCmd.AddCommand(cacheStatusCmd)
+	cacheCmd.AddCommand(cacheClearCmd)
+	cacheCmd.AddCommand(cacheGCCmd)
+
+	cacheClearCmd.Flags().BoolVarP(&cacheClearYes, "yes", "y", false, "Skip the confirmation prompt")
+	cacheGCCmd.Flags().BoolVar(&cacheGCDryRun, "dry-run", false, "List what would be removed without removing it")
+}
+
+// loadSourceCache returns the configured source cache and the names of the
+// configured sources
+func loadSourceCache() (*registry.Cache, []string, error) {
+	cfg := loadSourceConfig()
+	cache, err := registry.NewCacheFromConfig(cfg.Cache)
+	if err != nil {
+		return nil, nil, err
+	}
+	names := make([]string, 0, len(cfg.Sources))
+	for _, src := range cfg.Sources {
+		names = append(names, src.Name)
+	}
+	return cache, names, nil
+}
+
+func runCacheStatus(_ *cobra.Command, _ []string) error {
+	cache, sources, err := loadSourceCache()
+	if err != nil {
+		return err
+	}
+	entries, err := cache.Entries(sources)
+	if err != nil {
+		return err
+	}
+
+	var total int64
+	for _, entry := range entries {
+		total += entry.Size
+	}
+
+	if IsJSONOutput() {
+		return OutputJSON(map[string]interface{}{
+			"directory":   cache.Dir(),
+			"ttl":         cache.TTL().String(),
+			"entries":     entries,
+			"total_bytes": total,
+		})
+	}
+
+	fmt.Println()
+	fmt.Println(tui.TitleStyle.Render("Source Cache"))
+	fmt.Println()
+	fmt.Printf("  %s\n", tui.RenderKeyValue("Directory", cache.Dir()))
+	fmt.Printf("  %s\n", tui.RenderKeyValue("TTL", cache.TTL().String()))
+	fmt.Printf("  %s\n", tui.RenderKeyValue("Size", backup.FormatBytes(total)))
+	fmt.Println()
+
+	if len(entries) == 0 {
+		fmt.Println(tui.MutedStyle.Render("  The cache is empty"))
+		fmt.Println()
+		return nil
+	}
+
+	table := tui.NewTable("Source", "Size", "Updated", "Status")
+	orphaned := 0
+	for _, entry := range entries {
+		updated := "-"
+		if !entry.LastUpdated.IsZero() {
+			updated = backup.FormatAge(entry.LastUpdated)
+		}
+		status := tui.SuccessStyle.Render("fresh") + tui.MutedStyle.Render(" ("+ttlLeft(entry.LastUpdated, cache.TTL())+")")
+		switch {
+		case entry.Orphaned:
+			status = tui.WarningStyle.Render("orphaned")
+			orphaned++
+		case entry.Expired:
+			status = tui.MutedStyle.Render("expired")
+		case entry.LastUpdated.IsZero():
+			status = tui.MutedStyle.Render("-")
+		}
+		table.AddRow(entry.Name, backup.FormatBytes(entry.Size), updated, status)
+	}
+	fmt.Println(table.Render())
+
+	if orphaned > 0 {
+		fmt.Printf("%d orphaned. Run '%s' to remove them\n", orphaned, tui.CommandStyle.Render("sdbx cache gc"))
+	}
+	fmt.Println()
+	return nil
+}
+
+func runCacheClear(_ *cobra.Command, args []string) error {
+	cache, _, err := loadSourceCache()
+	if err != nil {
+		return err
+	}
+
+	if len(args) == 1 {
+		name := args[0]
+		if name != filepath.Base(name) || !cache.Exists(name) {
+			return fmt.Errorf("source %s is not cached", name)
+		}
+		if err := cache.Clear(name); err != nil {
+			return fmt.Errorf("failed to clear %s: %w", name, err)
+		}
+		fmt.Println(tui.SuccessStyle.Render(fmt.Sprintf("✓ Cleared cached source: %s", name)))
+		return nil
+	}
+
+	if !cacheClearYes {
+		if !IsTUIEnabled() {
+			return fmt.Errorf("refusing to clear the cache without confirmation\n\n  Try: sdbx cache clear --yes")
+		}
+		fmt.Printf("Remove every cached source in %s? [y/N] ", cache.Dir())
+		var response string
+		_, _ = fmt.Scanln(&response)
+		if response != "y" && response != "Y" {
+			return fmt.Errorf("cancelled")
+		}
+	}
+
+	size, _ := cache.GetSize()
+	if err := cache.ClearAll(); err != nil {
+		return fmt.Errorf("failed to clear cache: %w", err)
+	}
+	fmt.Println(tui.SuccessStyle.Render(fmt.Sprintf("✓ Cleared source cache, freed %s", backup.FormatBytes(size))))
+	return nil
+}
+
+func runCacheGC(_ *cobra.Command, _ []string) error {
+	cache, sources, err := loadSourceCache()
+	if err != nil {
+		return err
+	}
+	removed, err := cache.GC(sources, cacheGCDryRun)
+	if err != nil {
+		return err
+	}
+
+	var freed int64
+	for _, entry := range removed {
+		freed += entry.Size
+	}
+
+	if IsJSONOutput() {
+		return OutputJSON(map[string]interface{}{
+			"dry_run":     cacheGCDryRun,
+			"removed":     removed,
+			"freed_bytes": freed,
+		})
+	}
+
+	if len(removed) == 0 {
+		fmt.Println(tui.SuccessStyle.Render("✓ Nothing to collect"))
+		return nil
+	}
+	for _, entry := range removed {
+		age := "never updated"
+		if !entry.LastUpdated.IsZero() {
+			age = "updated " + backup.FormatAge(entry.LastUpdated)
+		}
+		fmt.Printf("  %s %s %s\n", tui.IconPackage, entry.Name,
+			tui.MutedStyle.Render(fmt.Sprintf("(%s, %s)", backup.FormatBytes(entry.Size), age)))
+	}
+	if cacheGCDryRun {
+		fmt.Println(tui.MutedStyle.Render(fmt.Sprintf("Dry run: %d entries (%s) would be removed.", len(removed), backup.FormatBytes(freed))))
+		return nil
+	}
+	fmt.Println(tui.SuccessStyle.Render(fmt.Sprintf("✓ Removed %d entries, freed %s", len(removed), backup.FormatBytes(freed))))
+	return nil
+}
+
+// ttlLeft formats how long a cached source is used before its next update
+func ttlLeft(updated time.Time, ttl time.Duration) string {
+	left := ttl - time.Since(updated)
+	if left < time.Hour {
+		return fmt.Sprintf("%dm left", int(left.Minutes()))
+	}
+	return fmt.Sprintf("%dh left", int(left.Hours()))
+}
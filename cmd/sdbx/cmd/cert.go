@@ -0,0 +1,154 @@
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/maiko/sdbx/internal/certs"
+	"github.com/maiko/sdbx/internal/config"
+	"github.com/maiko/sdbx/internal/docker"
+	"github.com/maiko/sdbx/internal/tui"
+)
+
+var certCmd = &cobra.Command{
+	Use:   "cert",
+	Short: "Inspect the TLS certificates of the project",
+	Long: `Inspect the Let's Encrypt certificates Traefik obtains in direct mode,
+stored in configs/traefik/acme.json.
+
+Traefik renews a certificate 30 days before it expires. A certificate
+closer to expiry has failed to renew: 'sdbx cert status' shows why, from
+the ACME errors of Traefik's logs. 'sdbx doctor' and cert_expiry alert
+rules report certificates 14 days before expiry.
+
+Examples:
+  sdbx cert status
+  sdbx cert status --days 21
+  sdbx cert status --json`,
+}
+
+var certStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "List certificates with their expiry and renewal errors",
+	Args:  cobra.NoArgs,
+	RunE:  runCertStatus,
+}
+
+var certWarnDays int
+
+func init() {
+	rootCmd.AddCommand(certCmd)
+	certCmd.AddCommand(certStatusCmd)
+
+	certStatusCmd.Flags().IntVar(&certWarnDays, "days", certs.DefaultWarnDays, "Warn about certificates expiring within this many days")
+}
+
+// certStatusReport is the JSON output of sdbx cert status
+type certStatusReport struct {
+	Certificates []certStatus           `json:"certificates"`
+	Failures     []certs.RenewalFailure `json:"renewalFailures"`
+	LogsError    string                 `json:"logsError,omitempty"`
+}
+
+// certStatus is a certificate with its state
+type certStatus struct {
+	certs.Certificate
+	DaysLeft int    `json:"daysLeft"`
+	Status   string `json:"status"`
+}
+
+func runCertStatus(cmd *cobra.Command, _ []string) error {
+	projectDir, err := config.ProjectDir()
+	if err != nil {
+		return err
+	}
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w\n\n  Try: sdbx doctor", err)
+	}
+	if cfg.Expose.Mode != config.ExposeModeDirect {
+		return fmt.Errorf("certificates are only obtained in direct mode, expose.mode is %s", cfg.Expose.Mode)
+	}
+	if !cfg.Expose.TLS.UsesACME() {
+		return fmt.Errorf("certificates come from expose.tls.provider %s, not Let's Encrypt\n\n  Try: sdbx doctor, which verifies custom certificates", cfg.Expose.TLS.Provider)
+	}
+
+	all, err := certs.Read(projectDir)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	report := certStatusReport{Certificates: []certStatus{}, Failures: []certs.RenewalFailure{}}
+	for _, c := range all {
+		report.Certificates = append(report.Certificates, certStatus{Certificate: c, DaysLeft: c.DaysLeft(now), Status: c.Status(now, certWarnDays)})
+	}
+	logs, err := docker.NewCompose(projectDir).Logs(commandContext(cmd), "traefik", 5000, false)
+	if err != nil {
+		report.LogsError = err.Error()
+	} else if failures := certs.RenewalFailures(logs); len(failures) > 0 {
+		report.Failures = failures
+	}
+
+	if IsJSONOutput() {
+		return OutputJSON(report)
+	}
+	printCertStatus(report)
+	return nil
+}
+
+// printCertStatus lists the certificates, then the renewal errors
+func printCertStatus(report certStatusReport) {
+	fmt.Println()
+	fmt.Println(tui.TitleStyle.Render("Certificates"))
+	fmt.Println()
+	if len(report.Certificates) == 0 {
+		fmt.Println(tui.MutedStyle.Render("  No certificate in " + certs.ACMEFile + " yet: Traefik requests them when a routed domain is first served"))
+	} else {
+		table := tui.NewTable("Domain", "Issuer", "Expires", "Days left", "Status")
+		for _, c := range report.Certificates {
+			status := c.Status
+			switch c.Status {
+			case certs.StatusValid:
+				status = tui.SuccessStyle.Render(status)
+			case certs.StatusRenewing:
+				status = tui.WarningStyle.Render(status)
+			default:
+				status = tui.ErrorStyle.Render(status)
+			}
+			table.AddRow(strings.Join(c.Names(), ", "), c.Issuer, c.NotAfter.Local().Format("2006-01-02"), strconv.Itoa(c.DaysLeft), status)
+		}
+		fmt.Println(table.Render())
+	}
+
+	var overdue []string
+	for _, c := range report.Certificates {
+		if c.Status != certs.StatusValid {
+			overdue = append(overdue, c.Domain)
+		}
+	}
+	if len(overdue) > 0 {
+		fmt.Println(tui.WarningStyle.Render(fmt.Sprintf("%s Renewal has been failing for %s: Traefik renews certificates 30 days before expiry", tui.IconWarning, strings.Join(overdue, ", "))))
+	}
+
+	switch {
+	case report.LogsError != "":
+		fmt.Println(tui.MutedStyle.Render("Traefik logs unavailable, renewal errors not checked: " + report.LogsError))
+	case len(report.Failures) > 0:
+		fmt.Println()
+		fmt.Println(tui.TitleStyle.Render("ACME errors in Traefik's logs"))
+		fmt.Println()
+		for _, f := range report.Failures {
+			when := ""
+			if !f.Time.IsZero() {
+				when = " " + tui.MutedStyle.Render("("+f.Time.Local().Format("2006-01-02 15:04")+")")
+			}
+			fmt.Printf("  %s %s%s\n", tui.ErrorStyle.Render(tui.IconError), strings.Join(f.Domains, ", "), when)
+			fmt.Printf("      %s %s\n", tui.IconArrow, f.Reason)
+		}
+	}
+	fmt.Println()
+}
@@ -0,0 +1,187 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/maiko/sdbx/internal/config"
+	"github.com/maiko/sdbx/internal/registry"
+	"github.com/maiko/sdbx/internal/tui"
+)
+
+var checklistCmd = &cobra.Command{
+	Use:   "checklist",
+	Short: "Show the post-install checklist of enabled services",
+	Long: `Show what is left to do after services first start.
+
+Service definitions list post-install steps (e.g. "add your media
+libraries"), manual secrets to fill in and useful links. Secrets are
+ticked off once their file under secrets/ has content; steps are ticked
+off with 'sdbx checklist done'. Completed steps are recorded in
+.sdbx.checklist.yaml.
+
+Examples:
+  sdbx checklist                      # Show every item
+  sdbx checklist done plex/libraries  # Mark a step as done
+  sdbx checklist undo plex/libraries  # Mark it as not done`,
+	Args: cobra.NoArgs,
+	RunE: runChecklist,
+}
+
+var checklistDoneCmd = &cobra.Command{
+	Use:   "done <id>",
+	Short: "Mark a post-install step as done",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return setChecklistStep(commandContext(cmd), args[0], true)
+	},
+}
+
+var checklistUndoCmd = &cobra.Command{
+	Use:   "undo <id>",
+	Short: "Mark a post-install step as not done",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return setChecklistStep(commandContext(cmd), args[0], false)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(checklistCmd)
+	checklistCmd.AddCommand(checklistDoneCmd)
+	checklistCmd.AddCommand(checklistUndoCmd)
+}
+
+func runChecklist(cmd *cobra.Command, _ []string) error {
+	projectDir, err := config.ProjectDir()
+	if err != nil {
+		return err
+	}
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w\n\n  Try: sdbx doctor", err)
+	}
+
+	checklist, err := loadChecklist(commandContext(cmd), cfg, projectDir)
+	if err != nil {
+		return err
+	}
+
+	if IsJSONOutput() {
+		return OutputJSON(checklist)
+	}
+
+	fmt.Println()
+	fmt.Println(tui.TitleStyle.Render("Post-install Checklist"))
+	fmt.Println()
+
+	if len(checklist.Services) == 0 {
+		fmt.Println(tui.MutedStyle.Render("  No enabled service has post-install steps."))
+		fmt.Println()
+		return nil
+	}
+
+	for _, svc := range checklist.Services {
+		fmt.Println(tui.SubtitleStyle.Render(svc.Service))
+		for _, item := range svc.Items {
+			printChecklistItem(item)
+		}
+		for _, link := range svc.Links {
+			fmt.Printf("    %s %s: %s\n", tui.IconArrow, link.Title, tui.InfoStyle.Render(link.URL))
+		}
+		fmt.Println()
+	}
+
+	if outstanding := len(checklist.Outstanding()); outstanding > 0 {
+		fmt.Println(tui.InfoStyle.Render(fmt.Sprintf("%d item(s) outstanding", outstanding)))
+	} else {
+		fmt.Println(tui.SuccessStyle.Render("✓ All done"))
+	}
+	fmt.Println()
+	return nil
+}
+
+// printChecklistItem prints an item with its state and how to complete it
+func printChecklistItem(item registry.ChecklistItem) {
+	if item.Done {
+		fmt.Printf("  %s %s\n", tui.SuccessStyle.Render(tui.IconSuccess), tui.MutedStyle.Render(item.Title))
+		return
+	}
+
+	fmt.Printf("  %s %s %s\n", tui.WarningStyle.Render("○"), item.Title, tui.MutedStyle.Render("("+item.ID+")"))
+	if item.Description != "" {
+		fmt.Printf("      %s\n", tui.MutedStyle.Render(item.Description))
+	}
+	if item.URL != "" {
+		fmt.Printf("      %s\n", tui.InfoStyle.Render(item.URL))
+	}
+}
+
+// setChecklistStep marks a step as done or not done after checking that an
+// enabled service declares it
+func setChecklistStep(ctx context.Context, id string, done bool) error {
+	projectDir, err := config.ProjectDir()
+	if err != nil {
+		return err
+	}
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w\n\n  Try: sdbx doctor", err)
+	}
+
+	checklist, err := loadChecklist(ctx, cfg, projectDir)
+	if err != nil {
+		return err
+	}
+	item, ok := checklist.Find(id)
+	if !ok {
+		return fmt.Errorf("no checklist item %q\n\n  Try: sdbx checklist", id)
+	}
+	if item.Secret != "" {
+		return fmt.Errorf("%s is completed by filling in secrets/%s.txt", id, item.Secret)
+	}
+
+	if err := registry.SetChecklistStep(projectDir, id, done); err != nil {
+		return err
+	}
+
+	if IsJSONOutput() {
+		return OutputJSON(map[string]interface{}{"id": id, "done": done})
+	}
+	if done {
+		fmt.Println(tui.SuccessStyle.Render(fmt.Sprintf("✓ Marked %s as done", id)))
+	} else {
+		fmt.Println(tui.SuccessStyle.Render(fmt.Sprintf("✓ Marked %s as not done", id)))
+	}
+	return nil
+}
+
+// loadChecklist resolves the enabled services and builds their checklist
+func loadChecklist(ctx context.Context, cfg *config.Config, projectDir string) (*registry.Checklist, error) {
+	reg, err := getRegistry()
+	if err != nil {
+		return nil, err
+	}
+	graph, err := reg.Resolve(ctx, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve services: %w\n\n  Try: sdbx doctor", err)
+	}
+	return graph.Checklist(projectDir)
+}
+
+// printOutstandingChecklist lists outstanding post-install items, if any
+func printOutstandingChecklist(checklist *registry.Checklist) {
+	outstanding := checklist.Outstanding()
+	if len(outstanding) == 0 {
+		return
+	}
+
+	fmt.Println(tui.SubtitleStyle.Render("Next steps"))
+	for _, item := range outstanding {
+		printChecklistItem(item)
+	}
+	fmt.Printf("  %s\n", tui.MutedStyle.Render("Run 'sdbx checklist' for links, 'sdbx checklist done <id>' to tick steps off"))
+	fmt.Println()
+}
@@ -0,0 +1,89 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/maiko/sdbx/internal/doctor"
+	"github.com/maiko/sdbx/internal/tui"
+)
+
+var completionDoctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Check PATH, Docker Compose, shell completion and locale",
+	Long: `Check the shell environment sdbx runs in:
+
+  • Another sdbx binary earlier on PATH, or an alias or function named sdbx
+    in the shell profiles
+  • The docker compose v2 plugin, and a leftover docker-compose v1
+  • Shell completion installed for the shell of $SHELL (bash, zsh or fish)
+  • A UTF-8 locale, without which the TUI icons and borders are garbled
+
+With --fix, the completion is loaded from the shell profile (~/.bashrc,
+~/.zshrc or ~/.config/fish/config.fish); open a new shell to use it.
+
+Examples:
+  sdbx completion doctor
+  sdbx completion doctor --fix`,
+	Args: cobra.NoArgs,
+	RunE: runCompletionDoctor,
+}
+
+var completionDoctorFix bool
+
+func init() {
+	// Cobra adds its completion command (bash, zsh, fish, powershell) when
+	// the CLI runs; create it now to add the doctor subcommand to it
+	rootCmd.InitDefaultCompletionCmd()
+	if completionCmd, _, err := rootCmd.Find([]string{"completion"}); err == nil && completionCmd != rootCmd {
+		completionCmd.AddCommand(completionDoctorCmd)
+	}
+
+	completionDoctorCmd.Flags().BoolVar(&completionDoctorFix, "fix", false, "Install the shell completion into the shell profile")
+}
+
+func runCompletionDoctor(cmd *cobra.Command, _ []string) error {
+	ctx := commandContext(cmd)
+	shell := doctor.NewShell()
+
+	if completionDoctorFix {
+		completion, err := shell.Completion()
+		if err != nil {
+			return err
+		}
+		if !completion.Installed {
+			if err := shell.InstallCompletion(completion); err != nil {
+				return fmt.Errorf("failed to install the completion: %w", err)
+			}
+			if !IsJSONOutput() {
+				fmt.Println(tui.SuccessStyle.Render(fmt.Sprintf("%s Added %s completion to %s (open a new shell to use it)", tui.IconSuccess, completion.Shell, completion.Profile)))
+			}
+		}
+	}
+
+	checks := shell.Check(ctx)
+	if IsJSONOutput() {
+		return OutputJSON(checks)
+	}
+
+	checklist := tui.NewCheckList()
+	failed := 0
+	for _, check := range checks {
+		idx := checklist.Add(check.Name)
+		status := "success"
+		switch check.Status {
+		case doctor.StatusWarning:
+			status = "warning"
+		case doctor.StatusFailed:
+			status = "error"
+			failed++
+		}
+		checklist.SetStatus(idx, status, check.Message)
+	}
+	fmt.Println(checklist.Render())
+	if failed > 0 {
+		return fmt.Errorf("%d environment check(s) failed", failed)
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/maiko/sdbx/internal/config"
+	"github.com/maiko/sdbx/internal/generator"
+	"github.com/maiko/sdbx/internal/tui"
+)
+
+var docsCmd = &cobra.Command{
+	Use:   "docs",
+	Short: "Generate documentation for the project",
+	Long: `Generate documentation describing this SDBX project.
+
+Examples:
+  sdbx docs generate                 # Write docs/ in the project
+  sdbx docs generate -o wiki/sdbx    # Write somewhere else`,
+}
+
+var docsGenerateCmd = &cobra.Command{
+	Use:   "generate",
+	Short: "Write a docs folder generated from the resolved services",
+	Long: `Write Markdown documentation generated from the resolved services, so
+homelab wikis stay in sync with the project:
+
+  README.md        Overview and links
+  services.md      Every service with its URL, auth policy and image
+  architecture.md  Mermaid diagram of dependencies and networks
+  secrets.md       Secrets the services need, who provides them and
+                   whether they are set (values are never included)
+  .env.example     The generated .env with sensitive values redacted
+
+Files are overwritten on every run.`,
+	Args: cobra.NoArgs,
+	RunE: runDocsGenerate,
+}
+
+var docsOutput string
+
+func init() {
+	rootCmd.AddCommand(docsCmd)
+	docsCmd.AddCommand(docsGenerateCmd)
+
+	docsGenerateCmd.Flags().StringVarP(&docsOutput, "output", "o", generator.DocsDir, "Output directory, relative to the project")
+}
+
+func runDocsGenerate(cmd *cobra.Command, _ []string) error {
+	projectDir, err := config.ProjectDir()
+	if err != nil {
+		return err
+	}
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w\n\n  Try: sdbx doctor", err)
+	}
+
+	reg, err := getRegistry()
+	if err != nil {
+		return err
+	}
+
+	files, err := generator.NewGeneratorWithRegistry(cfg, projectDir, reg).GenerateDocs(commandContext(cmd), docsOutput)
+	if err != nil {
+		return err
+	}
+
+	if IsJSONOutput() {
+		return OutputJSON(map[string]interface{}{"files": files})
+	}
+
+	fmt.Println(tui.SuccessStyle.Render(fmt.Sprintf("%s Documentation written to %s/", tui.IconSuccess, docsOutput)))
+	for _, file := range files {
+		fmt.Printf("  %s %s\n", tui.IconArrow, file)
+	}
+	return nil
+}
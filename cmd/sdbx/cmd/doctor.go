@@ -3,12 +3,14 @@ package cmd
 import (
 	"context"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/spf13/cobra"
 
 	"github.com/maiko/sdbx/internal/config"
 	"github.com/maiko/sdbx/internal/doctor"
+	"github.com/maiko/sdbx/internal/registry"
 	"github.com/maiko/sdbx/internal/tui"
 )
 
@@ -24,15 +26,49 @@ Checks include:
   • Port availability
   • Project file integrity
   • Secrets configuration
-  • VPN connectivity (if services running)`,
+  • VPN connectivity (if services running)
+  • Traefik access log rotation (if enabled)
+  • Services stuck in a restart loop, with their last log lines and
+    recognised causes (bad permissions, port in use, missing secret, ...)
+  • External dependencies declared by enabled services (NAS shares,
+    databases, APIs) are reachable
+  • rclone and mergerfs storage mounts are mounted and answering (if configured)`,
 	RunE: runDoctor,
 }
 
+var doctorTranscodeCmd = &cobra.Command{
+	Use:   "transcode",
+	Short: "Benchmark hardware transcoding and configure the media servers",
+	Long: `Detect the GPUs of the host (/dev/dri render nodes and NVIDIA devices) and
+encode a short 1080p clip with their H.264 and HEVC encoders in a throwaway
+container (` + doctor.TranscodeProbeImage + `).
+
+The best working method, NVIDIA then VA-API (Intel Quick Sync, AMD), is saved
+as services.<name>.transcode of the enabled media servers supporting
+hardware transcoding (Plex, Jellyfin, ...): vaapi passes /dev/dri, nvidia
+reserves the GPUs and exposes their video encoders, and none falls back to
+software transcoding. Run 'sdbx up' afterwards to apply it.
+
+Examples:
+  sdbx doctor transcode            # Probe and configure the media servers
+  sdbx doctor transcode --dry-run  # Only report what the GPUs can do`,
+	Args: cobra.NoArgs,
+	RunE: runDoctorTranscode,
+}
+
+var (
+	doctorLogLines        int
+	doctorTranscodeDryRun bool
+)
+
 func init() {
 	rootCmd.AddCommand(doctorCmd)
+	doctorCmd.AddCommand(doctorTranscodeCmd)
+	doctorCmd.Flags().IntVar(&doctorLogLines, "log-lines", doctor.DefaultLogLines, "Log lines shown for crash looping services")
+	doctorTranscodeCmd.Flags().BoolVar(&doctorTranscodeDryRun, "dry-run", false, "Report without changing .sdbx.yaml")
 }
 
-func runDoctor(_ *cobra.Command, args []string) error {
+func runDoctor(cmd *cobra.Command, args []string) error {
 	// Find project directory
 	projectDir, err := config.ProjectDir()
 	if err != nil {
@@ -40,8 +76,12 @@ func runDoctor(_ *cobra.Command, args []string) error {
 		projectDir = "."
 	}
 
-	ctx := context.Background()
+	ctx := commandContext(cmd)
 	doc := doctor.NewDoctor(projectDir)
+	doc.LogLines = doctorLogLines
+	if cfg, err := config.Load(); err == nil {
+		doc.External, doc.ExternalErr = externalDependencies(ctx, cfg)
+	}
 
 	// JSON output - run all at once
 	if IsJSONOutput() {
@@ -92,6 +132,7 @@ func runDoctor(_ *cobra.Command, args []string) error {
 	}
 
 	fmt.Println(checklist.Render())
+	printCrashLoops(doc.Loops)
 
 	// Summary box
 	fmt.Println()
@@ -109,3 +150,151 @@ func runDoctor(_ *cobra.Command, args []string) error {
 
 	return nil
 }
+
+// printCrashLoops shows the last log lines and diagnosed causes of each
+// crash looping service
+func printCrashLoops(loops []doctor.CrashLoop) {
+	for _, loop := range loops {
+		fmt.Println()
+		fmt.Println(tui.ErrorStyle.Render(fmt.Sprintf("%s %s is crash looping (%d restarts, exit code %d)",
+			tui.IconError, loop.Service, loop.RestartCount, loop.ExitCode)))
+		for _, line := range loop.Logs {
+			fmt.Println(tui.MutedStyle.Render("    " + line))
+		}
+		if len(loop.Causes) == 0 {
+			fmt.Printf("  %s No known failure pattern found - run 'sdbx logs %s' for more\n", tui.IconInfo, loop.Service)
+			continue
+		}
+		for _, cause := range loop.Causes {
+			fmt.Printf("  %s %s: %s\n", tui.WarningStyle.Render(tui.IconWarning), cause.Name, tui.MutedStyle.Render(cause.Line))
+			fmt.Printf("    Try: %s\n", cause.Hint)
+		}
+	}
+}
+
+// externalDependencies returns the external dependencies of enabled services
+func externalDependencies(ctx context.Context, cfg *config.Config) ([]registry.ServiceExternalDependency, error) {
+	reg, err := getRegistry()
+	if err != nil {
+		return nil, err
+	}
+	graph, err := reg.Resolve(ctx, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve services: %w", err)
+	}
+	return graph.ExternalDependencies(cfg)
+}
+
+// transcodeResult is the JSON output of sdbx doctor transcode
+type transcodeResult struct {
+	doctor.TranscodeReport
+	Services []string `json:"services"` // Media servers configured with the method
+	DryRun   bool     `json:"dry_run"`
+}
+
+func runDoctorTranscode(cmd *cobra.Command, _ []string) error {
+	ctx := commandContext(cmd)
+
+	if _, err := config.ProjectDir(); err != nil {
+		return err
+	}
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	services, err := transcodeServices(ctx, cfg)
+	if err != nil {
+		return err
+	}
+
+	devices := doctor.DetectTranscodeDevices()
+	if !IsJSONOutput() {
+		fmt.Println()
+		fmt.Println(tui.TitleStyle.Render("Hardware Transcoding"))
+		if len(devices) == 0 {
+			fmt.Println(tui.MutedStyle.Render("  No /dev/dri render node or NVIDIA GPU found"))
+		} else {
+			fmt.Println(tui.MutedStyle.Render("  Encoding a 1080p test clip on each GPU...\n"))
+		}
+	}
+	result := transcodeResult{TranscodeReport: doctor.ProbeTranscode(ctx, devices), Services: services, DryRun: doctorTranscodeDryRun}
+
+	changed := false
+	for _, name := range services {
+		if cfg.ServiceTranscode(name) == result.Method {
+			continue
+		}
+		if cfg.Services == nil {
+			cfg.Services = make(map[string]config.ServiceOverride)
+		}
+		override := cfg.Services[name]
+		override.Transcode = result.Method
+		cfg.Services[name] = override
+		changed = true
+	}
+	if changed && !doctorTranscodeDryRun {
+		if err := cfg.Save(".sdbx.yaml"); err != nil {
+			return fmt.Errorf("failed to save config: %w", err)
+		}
+	}
+
+	if IsJSONOutput() {
+		return OutputJSON(result)
+	}
+
+	if len(result.Probes) > 0 {
+		table := tui.NewTable("Device", "Encoder", "Result")
+		for _, p := range result.Probes {
+			outcome := tui.SuccessStyle.Render(fmt.Sprintf("%.1fx real time", p.Speed))
+			if !p.OK {
+				outcome = tui.ErrorStyle.Render(p.Error)
+				if p.Hint != "" {
+					outcome += " " + tui.MutedStyle.Render("- "+p.Hint)
+				}
+			}
+			table.AddRow(p.Device, p.Encoder, outcome)
+		}
+		fmt.Println(table.Render())
+		fmt.Println()
+	}
+
+	if result.Method == config.TranscodeNone {
+		fmt.Printf("%s No working hardware encoder: media servers transcode in software\n", tui.IconWarning)
+	} else {
+		fmt.Printf("%s %s transcoding, about %d simultaneous 1080p H.264 transcodes\n",
+			tui.SuccessStyle.Render(tui.IconSuccess), result.Method, result.Streams)
+	}
+	switch {
+	case len(services) == 0:
+		fmt.Println(tui.MutedStyle.Render("  No enabled media server supports hardware transcoding"))
+	case !changed:
+		fmt.Printf("  %s %s already configured\n", tui.IconArrow, strings.Join(services, ", "))
+	case doctorTranscodeDryRun:
+		fmt.Printf("  %s Would set transcode: %s for %s\n", tui.IconArrow, result.Method, strings.Join(services, ", "))
+	default:
+		fmt.Printf("  %s Set transcode: %s for %s\n", tui.IconArrow, result.Method, strings.Join(services, ", "))
+		fmt.Printf("  %s Run %s to apply it\n", tui.IconArrow, tui.CommandStyle.Render("sdbx up"))
+	}
+	return nil
+}
+
+// transcodeServices returns the enabled services whose definition supports
+// hardware transcoding
+func transcodeServices(ctx context.Context, cfg *config.Config) ([]string, error) {
+	reg, err := getRegistry()
+	if err != nil {
+		return nil, err
+	}
+	graph, err := reg.Resolve(ctx, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve services: %w", err)
+	}
+	var services []string
+	for _, name := range graph.Order {
+		svc := graph.Services[name]
+		if svc.Enabled && svc.FinalDefinition != nil && svc.FinalDefinition.Spec.Container.HardwareTranscode {
+			services = append(services, name)
+		}
+	}
+	return services, nil
+}
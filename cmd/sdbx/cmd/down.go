@@ -1,13 +1,11 @@
 package cmd
 
 import (
-	"context"
 	"fmt"
 
 	"github.com/spf13/cobra"
 
 	"github.com/maiko/sdbx/internal/config"
-	"github.com/maiko/sdbx/internal/docker"
 	"github.com/maiko/sdbx/internal/tui"
 )
 
@@ -25,7 +23,7 @@ func init() {
 	downCmd.Flags().BoolVar(&downDryRun, "dry-run", false, "Show what would be done without stopping services")
 }
 
-func runDown(_ *cobra.Command, args []string) error {
+func runDown(cmd *cobra.Command, args []string) error {
 	// Find project directory
 	projectDir, err := config.ProjectDir()
 	if err != nil {
@@ -43,8 +41,8 @@ func runDown(_ *cobra.Command, args []string) error {
 		return nil
 	}
 
-	compose := docker.NewCompose(projectDir)
-	ctx := context.Background()
+	compose := projectCompose(projectDir)
+	ctx := commandContext(cmd)
 
 	if IsTUIEnabled() {
 		err = tui.RunWithSpinner("Stopping SDBX services...", func() error {
@@ -60,6 +58,8 @@ func runDown(_ *cobra.Command, args []string) error {
 		}
 	}
 
+	recordEvent(projectDir, "down")
+
 	fmt.Println()
 	fmt.Println(tui.SuccessStyle.Render("✓ All services stopped"))
 
@@ -0,0 +1,189 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/maiko/sdbx/internal/config"
+	"github.com/maiko/sdbx/internal/generator"
+	"github.com/maiko/sdbx/internal/registry"
+)
+
+var execCmd = &cobra.Command{
+	Use:   "exec <service> [--] <command> [args...]",
+	Short: "Run a command in a service container",
+	Long: `Run a command in the running container of a service.
+
+The container is found from the service name, so you do not need to know
+its container name (sdbx-sonarr4k for sonarr@sonarr4k, ...). A TTY is
+allocated when the terminal is interactive. Put '--' before commands that
+take flags of their own.
+
+Examples:
+  sdbx exec qbittorrent ls /downloads
+  sdbx exec sonarr@sonarr4k -- cat /config/config.xml
+  sdbx exec -u abc radarr -- ls -la /movies`,
+	Args: cobra.MinimumNArgs(2),
+	RunE: runExec,
+}
+
+var shellCmd = &cobra.Command{
+	Use:   "shell <service>",
+	Short: "Open a shell in a service container",
+	Long: `Open an interactive shell in the running container of a service.
+
+bash is used when the image has it, sh otherwise.
+
+Examples:
+  sdbx shell plex
+  sdbx shell -u abc sonarr`,
+	Args: cobra.ExactArgs(1),
+	RunE: runShell,
+}
+
+var (
+	execUser    string
+	execWorkdir string
+	execNoTTY   bool
+)
+
+// shellCommand starts bash when available and falls back to sh
+var shellCommand = []string{"sh", "-c", "command -v bash >/dev/null 2>&1 && exec bash || exec sh"}
+
+func init() {
+	rootCmd.AddCommand(execCmd)
+	rootCmd.AddCommand(shellCmd)
+
+	// Flags after the service belong to the command being run
+	execCmd.Flags().SetInterspersed(false)
+	for _, c := range []*cobra.Command{execCmd, shellCmd} {
+		c.Flags().StringVarP(&execUser, "user", "u", "", "User to run as (name or uid[:gid])")
+		c.Flags().StringVarP(&execWorkdir, "workdir", "w", "", "Working directory inside the container")
+	}
+	execCmd.Flags().BoolVarP(&execNoTTY, "no-tty", "T", false, "Do not allocate a TTY")
+}
+
+func runExec(cmd *cobra.Command, args []string) error {
+	command := args[1:]
+	if command[0] == "--" {
+		command = command[1:]
+	}
+	if len(command) == 0 {
+		return fmt.Errorf("no command given\n\n  Example: sdbx exec %s -- ls /config", args[0])
+	}
+	return execInService(commandContext(cmd), args[0], command, !execNoTTY && stdinIsTerminal())
+}
+
+func runShell(cmd *cobra.Command, args []string) error {
+	if !stdinIsTerminal() {
+		return fmt.Errorf("sdbx shell needs an interactive terminal\n\n  Try: sdbx exec %s -- <command>", args[0])
+	}
+	return execInService(commandContext(cmd), args[0], shellCommand, true)
+}
+
+// execInService runs docker exec in the container of a service, attached to
+// the terminal, and exits with the command's status when it fails
+func execInService(ctx context.Context, ref string, command []string, tty bool) error {
+	projectDir, err := config.ProjectDir()
+	if err != nil {
+		return err
+	}
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w\n\n  Try: sdbx doctor", err)
+	}
+
+	container, err := serviceContainer(ctx, cfg, projectDir, ref)
+	if err != nil {
+		return err
+	}
+
+	cmd := exec.Command("docker", dockerExecArgs(container, execUser, execWorkdir, tty, command)...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			// docker already printed the error; keep the status for scripts
+			os.Exit(exitErr.ExitCode())
+		}
+		return fmt.Errorf("failed to run docker exec: %w", err)
+	}
+	return nil
+}
+
+// dockerExecArgs builds the docker exec arguments; stdin is always kept
+// open so commands can be piped into
+func dockerExecArgs(container, user, workdir string, tty bool, command []string) []string {
+	args := []string{"exec", "-i"}
+	if tty {
+		args = append(args, "-t")
+	}
+	if user != "" {
+		args = append(args, "--user", user)
+	}
+	if workdir != "" {
+		args = append(args, "--workdir", workdir)
+	}
+	args = append(args, container)
+	return append(args, command...)
+}
+
+// serviceContainer resolves the container of a service from the resolution
+// graph. Containers generated outside the registry (static, rclone, ...) are
+// looked up in compose.yaml.
+func serviceContainer(ctx context.Context, cfg *config.Config, projectDir, ref string) (string, error) {
+	reg, err := getRegistry()
+	if err != nil {
+		return "", err
+	}
+	graph, err := reg.Resolve(ctx, cfg)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve services: %w", err)
+	}
+
+	var compose *generator.ComposeFile
+	if data, err := os.ReadFile(filepath.Join(projectDir, "compose.yaml")); err == nil {
+		compose, _ = generator.ParseComposeFile(data)
+	}
+	return resolveContainer(cfg, graph, compose, ref)
+}
+
+// resolveContainer maps a service reference to its container name. It accepts
+// service@instance references and container names (sdbx-plex).
+func resolveContainer(cfg *config.Config, graph *registry.ResolutionGraph, compose *generator.ComposeFile, ref string) (string, error) {
+	name, err := cfg.ServiceName(ref)
+	if err != nil {
+		return "", err
+	}
+
+	candidates := []string{name}
+	if trimmed, ok := strings.CutPrefix(name, "sdbx-"); ok {
+		candidates = append(candidates, trimmed)
+	}
+	for _, candidate := range candidates {
+		if svc, ok := graph.Services[candidate]; ok && svc.Enabled {
+			return generator.ContainerName(cfg, svc.FinalDefinition), nil
+		}
+		if compose != nil {
+			if svc, ok := compose.Services[candidate]; ok && svc.ContainerName != "" {
+				return svc.ContainerName, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("service %q is not enabled\n\n  Try: sdbx status", ref)
+}
+
+// stdinIsTerminal reports whether stdin is an interactive terminal
+func stdinIsTerminal() bool {
+	fileInfo, err := os.Stdin.Stat()
+	return err == nil && (fileInfo.Mode()&os.ModeCharDevice) != 0
+}
@@ -0,0 +1,71 @@
+package cmd
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/maiko/sdbx/internal/config"
+	"github.com/maiko/sdbx/internal/generator"
+	"github.com/maiko/sdbx/internal/registry"
+)
+
+func TestResolveContainer(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Instances = map[string]string{"sonarr4k": "sonarr"}
+
+	service := func(name, nameTemplate string, enabled bool) *registry.ResolvedService {
+		def := &registry.ServiceDefinition{
+			Metadata: registry.ServiceMetadata{Name: name},
+			Spec:     registry.ServiceSpec{Container: registry.ContainerSpec{NameTemplate: nameTemplate}},
+		}
+		return &registry.ResolvedService{Name: name, Enabled: enabled, FinalDefinition: def}
+	}
+	graph := &registry.ResolutionGraph{Services: map[string]*registry.ResolvedService{
+		"sonarr":   service("sonarr", "sdbx-{{ .Name }}", true),
+		"sonarr4k": service("sonarr4k", "sdbx-sonarr4k", true),
+		"plex":     service("plex", "media-plex", true),
+		"lidarr":   service("lidarr", "sdbx-{{ .Name }}", false),
+	}}
+	compose := &generator.ComposeFile{Services: map[string]generator.ComposeService{
+		"rclone": {ContainerName: "sdbx-rclone"},
+	}}
+
+	tests := []struct {
+		ref     string
+		want    string
+		wantErr bool
+	}{
+		{"sonarr", "sdbx-sonarr", false},
+		{"sdbx-sonarr", "sdbx-sonarr", false},
+		{"sonarr@sonarr4k", "sdbx-sonarr4k", false},
+		{"plex", "media-plex", false},
+		{"rclone", "sdbx-rclone", false},
+		{"lidarr", "", true},
+		{"radarr@sonarr4k", "", true},
+		{"unknown", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.ref, func(t *testing.T) {
+			got, err := resolveContainer(cfg, graph, compose, tt.ref)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("resolveContainer(%q) error = %v, wantErr %v", tt.ref, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("resolveContainer(%q) = %q, want %q", tt.ref, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDockerExecArgs(t *testing.T) {
+	got := dockerExecArgs("sdbx-sonarr", "abc", "/config", true, []string{"ls", "-la"})
+	want := []string{"exec", "-i", "-t", "--user", "abc", "--workdir", "/config", "sdbx-sonarr", "ls", "-la"}
+	if !slices.Equal(got, want) {
+		t.Errorf("dockerExecArgs() = %v, want %v", got, want)
+	}
+
+	got = dockerExecArgs("sdbx-plex", "", "", false, []string{"id"})
+	if !slices.Equal(got, []string{"exec", "-i", "sdbx-plex", "id"}) {
+		t.Errorf("dockerExecArgs() without TTY = %v", got)
+	}
+}
@@ -0,0 +1,134 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/maiko/sdbx/internal/backup"
+	"github.com/maiko/sdbx/internal/config"
+	"github.com/maiko/sdbx/internal/geoip"
+	"github.com/maiko/sdbx/internal/registry"
+	"github.com/maiko/sdbx/internal/tui"
+)
+
+var geoipCmd = &cobra.Command{
+	Use:   "geoip",
+	Short: "Download the MaxMind GeoLite databases",
+	Long: `Manage the MaxMind GeoLite databases of the geoip section of .sdbx.yaml.
+
+The databases are downloaded to data/geoip and mounted read-only at /geoip
+into the services whose definition declares requires: geoip. 'sdbx monitor'
+and the web UI (server mode) download them again once older than
+geoip.refresh (72h by default). The license key of your free MaxMind account
+is read from secrets/maxmind_license_key.txt.
+
+Example configuration:
+  geoip:
+    account_id: "123456"
+    editions: [GeoLite2-City, GeoLite2-ASN]
+
+Examples:
+  sdbx geoip update          # Download the missing and stale databases
+  sdbx geoip update --force  # Download all of them again
+  sdbx geoip status          # Show when each database was downloaded`,
+}
+
+var geoipUpdateCmd = &cobra.Command{
+	Use:   "update",
+	Short: "Download the missing and stale databases",
+	Args:  cobra.NoArgs,
+	RunE:  runGeoIPUpdate,
+}
+
+var geoipStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show the downloaded databases",
+	Args:  cobra.NoArgs,
+	RunE:  runGeoIPStatus,
+}
+
+var geoipForce bool
+
+func init() {
+	rootCmd.AddCommand(geoipCmd)
+	geoipCmd.AddCommand(geoipUpdateCmd)
+	geoipCmd.AddCommand(geoipStatusCmd)
+
+	geoipUpdateCmd.Flags().BoolVar(&geoipForce, "force", false, "Download the databases even if they are fresh")
+}
+
+// loadGeoIPUpdater loads the configuration and fails without a geoip section
+func loadGeoIPUpdater() (*geoip.Updater, error) {
+	projectDir, err := config.ProjectDir()
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config: %w", err)
+	}
+	if !cfg.GeoIP.IsEnabled() {
+		return nil, fmt.Errorf("no MaxMind account configured\n\n  Add the geoip section to .sdbx.yaml")
+	}
+	return geoip.NewUpdater(projectDir, cfg), nil
+}
+
+func runGeoIPUpdate(cmd *cobra.Command, _ []string) error {
+	ctx := commandContext(cmd)
+	if err := registry.RequireNetwork("downloading GeoIP databases"); err != nil {
+		return err
+	}
+
+	u, err := loadGeoIPUpdater()
+	if err != nil {
+		return err
+	}
+	updated, updateErr := u.Update(ctx, geoipForce)
+
+	if IsJSONOutput() {
+		if err := OutputJSON(updated); err != nil {
+			return err
+		}
+		return updateErr
+	}
+
+	if len(updated) == 0 && updateErr == nil {
+		fmt.Println(tui.MutedStyle.Render("GeoIP databases are up to date"))
+		return nil
+	}
+	for _, db := range updated {
+		if db.Error != "" {
+			fmt.Printf("%s %s\n", tui.ErrorStyle.Render(tui.IconError+" "+db.Edition), db.Error)
+			continue
+		}
+		fmt.Println(tui.SuccessStyle.Render(fmt.Sprintf("%s Downloaded %s (%s)", tui.IconSuccess, db.Path, backup.FormatBytes(db.Size))))
+	}
+	if updateErr != nil {
+		return fmt.Errorf("failed to update the GeoIP databases: %w", updateErr)
+	}
+	return nil
+}
+
+func runGeoIPStatus(_ *cobra.Command, _ []string) error {
+	u, err := loadGeoIPUpdater()
+	if err != nil {
+		return err
+	}
+	databases := u.Status()
+
+	if IsJSONOutput() {
+		return OutputJSON(databases)
+	}
+
+	table := tui.NewTable("Edition", "Path", "Size", "Downloaded")
+	for _, db := range databases {
+		if db.Updated.IsZero() {
+			table.AddRow(db.Edition, db.Path, "-", tui.WarningStyle.Render("never - run sdbx geoip update"))
+			continue
+		}
+		table.AddRow(db.Edition, db.Path, backup.FormatBytes(db.Size), backup.FormatAge(db.Updated))
+	}
+	fmt.Println(table.Render())
+	return nil
+}
@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/maiko/sdbx/internal/config"
+)
+
+var graphCmd = &cobra.Command{
+	Use:   "graph",
+	Short: "Render the service dependency graph",
+	Long: `Render the resolved service graph for documentation and debugging.
+
+Every known service is shown with the reason it was or wasn't included
+(core service, addon enabled, dependency of another service, conditions
+not met, ...). Edges are required, optional and conditional dependencies;
+inactive ones are dotted. Networks each service joins are shown as ellipses.
+
+Examples:
+  sdbx graph | dot -Tsvg > graph.svg   # Render with Graphviz
+  sdbx graph --format mermaid          # Paste into Markdown
+  sdbx graph --json                    # Machine-readable nodes and edges`,
+	RunE: runGraph,
+}
+
+var graphFormat string
+
+func init() {
+	rootCmd.AddCommand(graphCmd)
+
+	graphCmd.Flags().StringVar(&graphFormat, "format", "dot", "Output format: dot or mermaid")
+}
+
+func runGraph(cmd *cobra.Command, _ []string) error {
+	if graphFormat != "dot" && graphFormat != "mermaid" {
+		return fmt.Errorf("unknown format %q (must be dot or mermaid)", graphFormat)
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w\n\n  Try: sdbx doctor", err)
+	}
+
+	reg, err := getRegistry()
+	if err != nil {
+		return err
+	}
+
+	graph, err := reg.DependencyGraph(commandContext(cmd), cfg)
+	if err != nil {
+		return fmt.Errorf("failed to resolve services: %w", err)
+	}
+
+	if IsJSONOutput() {
+		return OutputJSON(graph)
+	}
+
+	if graphFormat == "mermaid" {
+		fmt.Print(graph.RenderMermaid())
+	} else {
+		fmt.Print(graph.RenderDOT())
+	}
+	return nil
+}
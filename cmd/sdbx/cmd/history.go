@@ -0,0 +1,130 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/maiko/sdbx/internal/config"
+	"github.com/maiko/sdbx/internal/events"
+	"github.com/maiko/sdbx/internal/tui"
+)
+
+var historyCmd = &cobra.Command{
+	Use:   "history",
+	Short: "Show who changed what",
+	Long: `Show the changes made to the project and who made them, newest first.
+
+Every POST, PUT, PATCH and DELETE request to the web UI and its API is
+recorded with its Authelia user (or token:<name> for API tokens), as are
+commands such as 'sdbx up', 'sdbx down' and 'sdbx restart' with the OS user
+running them, and the changes made through the agent API of 'sdbx agent
+serve' with their token. Events are kept in .sdbx.events.log.
+
+Examples:
+  sdbx history                     # Latest 50 changes
+  sdbx history --web               # Changes from the web UI and API only
+  sdbx history --user alice        # Changes made by alice
+  sdbx history --since 24h -n 0    # Every change of the last day`,
+	Args: cobra.NoArgs,
+	RunE: runHistory,
+}
+
+var (
+	historyWeb   bool
+	historyCLI   bool
+	historyAgent bool
+	historyUser  string
+	historySince time.Duration
+	historyLimit int
+)
+
+func init() {
+	rootCmd.AddCommand(historyCmd)
+	historyCmd.Flags().BoolVar(&historyWeb, "web", false, "Only show changes made from the web UI and API")
+	historyCmd.Flags().BoolVar(&historyCLI, "cli", false, "Only show changes made from the CLI")
+	historyCmd.Flags().BoolVar(&historyAgent, "agent", false, "Only show changes made through the agent API")
+	historyCmd.Flags().StringVar(&historyUser, "user", "", "Only show changes made by a user (token:<name> for API tokens)")
+	historyCmd.Flags().DurationVar(&historySince, "since", 0, "Only show changes made within this duration (e.g. 24h)")
+	historyCmd.Flags().IntVarP(&historyLimit, "limit", "n", 50, "Number of changes shown (0 for all)")
+	historyCmd.MarkFlagsMutuallyExclusive("web", "cli", "agent")
+}
+
+func runHistory(_ *cobra.Command, _ []string) error {
+	projectDir, err := config.ProjectDir()
+	if err != nil {
+		return err
+	}
+
+	filter := events.Filter{User: historyUser, Limit: historyLimit}
+	switch {
+	case historyWeb:
+		filter.Source = events.SourceWeb
+	case historyCLI:
+		filter.Source = events.SourceCLI
+	case historyAgent:
+		filter.Source = events.SourceAgent
+	}
+	if historySince > 0 {
+		filter.Since = time.Now().Add(-historySince)
+	}
+
+	log := events.NewLog(projectDir)
+	recorded, err := log.Read(filter)
+	if err != nil {
+		return err
+	}
+
+	if IsJSONOutput() {
+		return OutputJSON(recorded)
+	}
+
+	if len(recorded) == 0 {
+		fmt.Println(tui.MutedStyle.Render("No changes recorded in " + log.Path()))
+		return nil
+	}
+
+	table := tui.NewTable("Time", "User", "Source", "Action", "Status")
+	for _, e := range recorded {
+		who := e.User
+		if who == "" {
+			who = "-"
+		}
+		status := ""
+		if e.Status != 0 {
+			status = strconv.Itoa(e.Status)
+			if e.Status >= 400 {
+				status = tui.ErrorStyle.Render(status)
+			}
+		}
+		table.AddRow(e.Time.Local().Format("2006-01-02 15:04:05"), who, e.Source, e.Action, status)
+	}
+	fmt.Println(table.Render())
+	return nil
+}
+
+// recordEvent adds a CLI change to the events log, such as "sdbx up".
+// History is informational: failing to record is only a warning.
+func recordEvent(projectDir string, args ...string) {
+	e := events.Event{
+		Source: events.SourceCLI,
+		User:   osUser(),
+		Action: strings.Join(append([]string{"sdbx"}, args...), " "),
+	}
+	if err := events.NewLog(projectDir).Record(e); err != nil {
+		fmt.Fprintln(os.Stderr, tui.WarningStyle.Render("Warning: "+err.Error()))
+	}
+}
+
+// osUser returns the name of the user running sdbx, or "" if unknown
+func osUser() string {
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+	return os.Getenv("USER")
+}
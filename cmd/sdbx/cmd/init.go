@@ -19,6 +19,7 @@ import (
 
 	"github.com/maiko/sdbx/internal/config"
 	"github.com/maiko/sdbx/internal/generator"
+	"github.com/maiko/sdbx/internal/i18n"
 	"github.com/maiko/sdbx/internal/registry"
 	"github.com/maiko/sdbx/internal/tui"
 )
@@ -50,6 +51,12 @@ var (
 	initAdminPassword     string
 	initPlexAdvertiseURLs string
 	initJellyfinEnabled   bool
+	initPreset            string
+	initPresetURL         string
+	initPresetChecksum    string
+
+	// initPresetApplied is the name of the preset applied before the wizard
+	initPresetApplied string
 )
 
 var initCmd = &cobra.Command{
@@ -63,7 +70,20 @@ This command will:
   • Create secrets for Authelia authentication
   • Set up directory structure for media and downloads
 
-Use --skip-wizard with flags to run non-interactively.`,
+Use --skip-wizard with flags to run non-interactively.
+
+Use --preset to start from a bundle tuned for the host:
+  nas           Standard stack with hardware transcoding
+  raspberry-pi  Essential addons with conservative memory limits
+  dedicated     Full media automation stack
+
+Use --preset-url to start from a preset shared by the community. It is
+verified against --preset-checksum, or the sha256sum file at the same URL
+with a .sha256 suffix, and reviewed before it is applied. That file only
+proves the download is intact, not who published the preset, so
+--preset-checksum is required with --skip-wizard or without a terminal:
+  sdbx init --preset-url https://example.com/sdbx-preset.yaml \
+    --preset-checksum sha256:<hex>`,
 	RunE: runInit,
 }
 
@@ -87,6 +107,12 @@ func init() {
 	initCmd.Flags().BoolVar(&initJellyfinEnabled, "jellyfin", false, "Enable Jellyfin media server")
 	initCmd.Flags().StringVar(&initPlexAdvertiseURLs, "plex-advertise-urls", "",
 		"Comma-separated URLs where Plex can be reached (e.g., https://plex.domain.com:443,http://192.168.1.100:32400)")
+	initCmd.Flags().StringVar(&initPreset, "preset", "",
+		"Host preset: "+strings.Join(registry.PresetNames(), ", "))
+	initCmd.Flags().StringVar(&initPresetURL, "preset-url", "", "HTTPS URL of a shared preset to start from")
+	initCmd.Flags().StringVar(&initPresetChecksum, "preset-checksum", "",
+		"Expected sha256:<hex> checksum of --preset-url (default: read from <url>.sha256)")
+	initCmd.MarkFlagsMutuallyExclusive("preset", "preset-url")
 }
 
 // detectLocalIP attempts to find the primary local IP address
@@ -133,6 +159,32 @@ func runInit(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to initialize registry: %w\n\n  Try: sdbx source update", err)
 	}
 
+	// Apply the host preset before the wizard so it shows up as the defaults
+	if initPreset != "" {
+		preset, err := reg.ResolvePreset(commandContext(cmd), initPreset)
+		if err != nil {
+			return err
+		}
+		preset.Apply(cfg)
+		initPresetApplied = preset.Name
+		if len(preset.Missing) > 0 {
+			fmt.Println(tui.WarningStyle.Render(fmt.Sprintf("Preset %s: addons not found in any source: %s",
+				preset.Name, strings.Join(preset.Missing, ", "))))
+		}
+	}
+
+	// A shared preset is reviewed before it prefills the wizard
+	if initPresetURL != "" {
+		preset, err := importPreset(cmd, reg, cfg)
+		if err != nil {
+			return err
+		}
+		if preset == nil {
+			return nil
+		}
+		initPresetApplied = preset.Name
+	}
+
 	// If not skipping wizard and TUI is enabled, run wizard
 	if !initSkipWizard && IsTUIEnabled() {
 		// Show logo with style
@@ -143,34 +195,34 @@ func runInit(cmd *cobra.Command, args []string) error {
 		tagline := lipgloss.NewStyle().
 			Foreground(tui.ColorMuted).
 			Italic(true).
-			Render("Seedbox in a Box — Setup Wizard")
+			Render(i18n.T("Seedbox in a Box — Setup Wizard"))
 		fmt.Println(tagline)
 		fmt.Println()
 
 		if hasExisting {
 			var confirm bool
 			if err := huh.NewConfirm().
-				Title("Existing project detected. Overwrite?").
-				Description("This will regenerate all configuration files").
+				Title(i18n.T("Existing project detected. Overwrite?")).
+				Description(i18n.T("This will regenerate all configuration files")).
 				Value(&confirm).
 				Run(); err != nil {
 				return fmt.Errorf("confirmation prompt failed: %w", err)
 			}
 			if !confirm {
-				fmt.Println(tui.MutedStyle.Render("Aborted."))
+				fmt.Println(tui.MutedStyle.Render(i18n.T("Aborted.")))
 				return nil
 			}
 		}
 
 		// Run interactive wizard in a loop to support "start over"
 		for {
-			err := runWizard(cfg, reg)
+			err := runWizard(commandContext(cmd), cfg, reg)
 			if errors.Is(err, errStartOver) {
 				continue
 			}
 			if errors.Is(err, huh.ErrUserAborted) {
 				fmt.Println()
-				fmt.Println(tui.MutedStyle.Render("Setup canceled. Run 'sdbx init' to try again."))
+				fmt.Println(tui.MutedStyle.Render(i18n.T("Setup canceled. Run 'sdbx init' to try again.")))
 				return nil
 			}
 			if err != nil {
@@ -242,12 +294,13 @@ func runInit(cmd *cobra.Command, args []string) error {
 
 	// Generate project using registry-based generator
 	fmt.Println()
-	fmt.Printf("  %s Generating project files...\n", tui.InfoStyle.Render(tui.IconSpinner))
+	fmt.Printf("  %s %s\n", tui.InfoStyle.Render(tui.IconSpinner), i18n.T("Generating project files..."))
 
 	gen := generator.NewGeneratorWithRegistry(cfg, cwd, reg)
-	if err := gen.Generate(); err != nil {
+	if err := gen.GenerateContext(commandContext(cmd)); err != nil {
 		return fmt.Errorf("failed to generate project: %w\n\n  Try: sdbx doctor", err)
 	}
+	printSkipped(gen.Skipped)
 
 	// Create data directories if paths are relative
 	if !filepath.IsAbs(cfg.MediaPath) {
@@ -263,17 +316,17 @@ func runInit(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
-func runWizard(cfg *config.Config, reg *registry.Registry) error {
+func runWizard(ctx context.Context, cfg *config.Config, reg *registry.Registry) error {
 	// Define wizard steps for progress indicator
 	wizardSteps := []string{
-		"Domain & Routing",
-		"Admin Credentials",
-		"Media Server",
-		"Storage Paths",
-		"VPN Configuration",
-		"System Settings",
-		"Addons",
-		"Confirmation",
+		i18n.T("Domain & Routing"),
+		i18n.T("Admin Credentials"),
+		i18n.T("Media Server"),
+		i18n.T("Storage Paths"),
+		i18n.T("VPN Configuration"),
+		i18n.T("System Settings"),
+		i18n.T("Addons"),
+		i18n.T("Confirmation"),
 	}
 	progress := tui.NewStepProgress(wizardSteps...)
 
@@ -281,7 +334,7 @@ func runWizard(cfg *config.Config, reg *registry.Registry) error {
 	renderStep := func() {
 		fmt.Print("\033[H\033[2J") // Clear screen
 		fmt.Println()
-		fmt.Println(tui.TitleStyle.Render("SDBX Setup Wizard"))
+		fmt.Println(tui.TitleStyle.Render(i18n.T("SDBX Setup Wizard")))
 		fmt.Println()
 		fmt.Println(progress.Render())
 		fmt.Println()
@@ -292,36 +345,36 @@ func runWizard(cfg *config.Config, reg *registry.Registry) error {
 	form1 := huh.NewForm(
 		huh.NewGroup(
 			huh.NewInput().
-				Title("Base Domain").
-				Description("Your root domain for all services").
+				Title(i18n.T("Base Domain")).
+				Description(i18n.T("Your root domain for all services")).
 				Placeholder("box.sdbx.one").
 				Value(&cfg.Domain).
 				Validate(func(s string) error {
 					if s == "" {
-						return fmt.Errorf("domain is required")
+						return errors.New(i18n.T("domain is required"))
 					}
 					return nil
 				}),
 
 			huh.NewSelect[string]().
-				Title("Exposure Mode").
-				Description("How should services be accessible?").
+				Title(i18n.T("Exposure Mode")).
+				Description(i18n.T("How should services be accessible?")).
 				Options(
-					huh.NewOption("Cloudflare Tunnel (recommended, zero open ports)", "cloudflared"),
-					huh.NewOption("Direct HTTPS (Let's Encrypt, ports 80/443)", "direct"),
-					huh.NewOption("LAN Only (HTTP, no TLS, for home lab)", "lan"),
+					huh.NewOption(i18n.T("Cloudflare Tunnel (recommended, zero open ports)"), "cloudflared"),
+					huh.NewOption(i18n.T("Direct HTTPS (Let's Encrypt, ports 80/443)"), "direct"),
+					huh.NewOption(i18n.T("LAN Only (HTTP, no TLS, for home lab)"), "lan"),
 				).
 				Value(&cfg.Expose.Mode),
 
 			huh.NewSelect[string]().
-				Title("Routing Strategy").
-				Description("How should services be accessed?").
+				Title(i18n.T("Routing Strategy")).
+				Description(i18n.T("How should services be accessed?")).
 				Options(
-					huh.NewOption("Subdomain (radarr.domain.tld, sonarr.domain.tld)", "subdomain"),
-					huh.NewOption("Path (sdbx.domain.tld/radarr, sdbx.domain.tld/sonarr)", "path"),
+					huh.NewOption(i18n.T("Subdomain (radarr.domain.tld, sonarr.domain.tld)"), "subdomain"),
+					huh.NewOption(i18n.T("Path (sdbx.domain.tld/radarr, sdbx.domain.tld/sonarr)"), "path"),
 				).
 				Value(&cfg.Routing.Strategy),
-		).Title("Domain Configuration"),
+		).Title(i18n.T("Domain Configuration")),
 	)
 
 	if err := form1.Run(); err != nil {
@@ -333,11 +386,11 @@ func runWizard(cfg *config.Config, reg *registry.Registry) error {
 		formBaseDomain := huh.NewForm(
 			huh.NewGroup(
 				huh.NewInput().
-					Title("Base Subdomain").
-					Description("Subdomain for path-based access (e.g., 'sdbx' → sdbx.domain.tld/...)").
+					Title(i18n.T("Base Subdomain")).
+					Description(i18n.T("Subdomain for path-based access (e.g., 'sdbx' → sdbx.domain.tld/...)")).
 					Placeholder("sdbx").
 					Value(&cfg.Routing.BaseDomain),
-			).Title("Path Routing Configuration"),
+			).Title(i18n.T("Path Routing Configuration")),
 		)
 		if err := formBaseDomain.Run(); err != nil {
 			return err
@@ -358,19 +411,19 @@ func runWizard(cfg *config.Config, reg *registry.Registry) error {
 	formAuth := huh.NewForm(
 		huh.NewGroup(
 			huh.NewInput().
-				Title("Admin Username").
-				Description("Username for Authelia SSO").
+				Title(i18n.T("Admin Username")).
+				Description(i18n.T("Username for Authelia SSO")).
 				Placeholder("admin").
 				Value(&cfg.AdminUser),
 
 			huh.NewInput().
-				Title("Admin Password").
-				Description("Password for Authelia (will be hashed securely)").
+				Title(i18n.T("Admin Password")).
+				Description(i18n.T("Password for Authelia (will be hashed securely)")).
 				Placeholder("secure_password").
 				EchoMode(huh.EchoModePassword).
 				Value(&adminPassword).
 				Validate(validateAdminPassword),
-		).Title("Admin Configuration"),
+		).Title(i18n.T("Admin Configuration")),
 	)
 
 	if err := formAuth.Run(); err != nil {
@@ -391,15 +444,15 @@ func runWizard(cfg *config.Config, reg *registry.Registry) error {
 	formMedia := huh.NewForm(
 		huh.NewGroup(
 			huh.NewSelect[string]().
-				Title("Media Server").
-				Description("Choose your media server (or both)").
+				Title(i18n.T("Media Server")).
+				Description(i18n.T("Choose your media server (or both)")).
 				Options(
-					huh.NewOption("Plex (popular, polished UI)", "plex"),
-					huh.NewOption("Jellyfin (free & open source)", "jellyfin"),
-					huh.NewOption("Both (Plex + Jellyfin)", "both"),
+					huh.NewOption(i18n.T("Plex (popular, polished UI)"), "plex"),
+					huh.NewOption(i18n.T("Jellyfin (free & open source)"), "jellyfin"),
+					huh.NewOption(i18n.T("Both (Plex + Jellyfin)"), "both"),
 				).
 				Value(&mediaServer),
-		).Title("Media Server"),
+		).Title(i18n.T("Media Server")),
 	)
 
 	if err := formMedia.Run(); err != nil {
@@ -419,23 +472,23 @@ func runWizard(cfg *config.Config, reg *registry.Registry) error {
 	form2 := huh.NewForm(
 		huh.NewGroup(
 			huh.NewInput().
-				Title("Media Path").
-				Description("Where to store movies, TV shows, music").
+				Title(i18n.T("Media Path")).
+				Description(i18n.T("Where to store movies, TV shows, music")).
 				Placeholder("./data/media").
 				Value(&cfg.MediaPath),
 
 			huh.NewInput().
-				Title("Downloads Path").
-				Description("Where torrent client stores downloads").
+				Title(i18n.T("Downloads Path")).
+				Description(i18n.T("Where torrent client stores downloads")).
 				Placeholder("./data/downloads").
 				Value(&cfg.DownloadsPath),
 
 			huh.NewInput().
-				Title("Config Path").
-				Description("Where service configs are stored").
+				Title(i18n.T("Config Path")).
+				Description(i18n.T("Where service configs are stored")).
 				Placeholder("./config").
 				Value(&cfg.ConfigPath),
-		).Title("Storage Configuration"),
+		).Title(i18n.T("Storage Configuration")),
 	)
 
 	if err := form2.Run(); err != nil {
@@ -449,10 +502,10 @@ func runWizard(cfg *config.Config, reg *registry.Registry) error {
 	formVPN := huh.NewForm(
 		huh.NewGroup(
 			huh.NewConfirm().
-				Title("Enable VPN for downloads?").
-				Description("Routes torrent traffic through VPN with kill-switch. Recommended for privacy.").
+				Title(i18n.T("Enable VPN for downloads?")).
+				Description(i18n.T("Routes torrent traffic through VPN with kill-switch. Recommended for privacy.")).
 				Value(&wantVPN),
-		).Title("VPN Configuration"),
+		).Title(i18n.T("VPN Configuration")),
 	)
 
 	if err := formVPN.Run(); err != nil {
@@ -473,17 +526,17 @@ func runWizard(cfg *config.Config, reg *registry.Registry) error {
 		form3 := huh.NewForm(
 			huh.NewGroup(
 				huh.NewSelect[string]().
-					Title("VPN Provider").
-					Description("Select your VPN service").
+					Title(i18n.T("VPN Provider")).
+					Description(i18n.T("Select your VPN service")).
 					Options(providerOpts...).
 					Value(&cfg.VPNProvider),
 
 				huh.NewInput().
-					Title("VPN Server Country").
-					Description("Preferred VPN exit location (e.g., Netherlands, United States)").
+					Title(i18n.T("VPN Server Country")).
+					Description(i18n.T("Preferred VPN exit location (e.g., Netherlands, United States)")).
 					Placeholder("Netherlands").
 					Value(&cfg.VPNCountry),
-			).Title("VPN Provider"),
+			).Title(i18n.T("VPN Provider")),
 		)
 
 		if err := form3.Run(); err != nil {
@@ -499,7 +552,7 @@ func runWizard(cfg *config.Config, reg *registry.Registry) error {
 		// Build VPN type options based on provider support
 		var vpnTypeOpts []huh.Option[string]
 		if provider.SupportsWG {
-			vpnTypeOpts = append(vpnTypeOpts, huh.NewOption("Wireguard (Recommended)", "wireguard"))
+			vpnTypeOpts = append(vpnTypeOpts, huh.NewOption(i18n.T("Wireguard (Recommended)"), "wireguard"))
 		}
 		if provider.SupportsOpenVPN {
 			vpnTypeOpts = append(vpnTypeOpts, huh.NewOption("OpenVPN", "openvpn"))
@@ -509,11 +562,11 @@ func runWizard(cfg *config.Config, reg *registry.Registry) error {
 		formType := huh.NewForm(
 			huh.NewGroup(
 				huh.NewSelect[string]().
-					Title("VPN Protocol").
-					Description("Wireguard is faster and more reliable, OpenVPN has wider compatibility").
+					Title(i18n.T("VPN Protocol")).
+					Description(i18n.T("Wireguard is faster and more reliable, OpenVPN has wider compatibility")).
 					Options(vpnTypeOpts...).
 					Value(&cfg.VPNType),
-			).Title("VPN Protocol"),
+			).Title(i18n.T("VPN Protocol")),
 		)
 
 		if err := formType.Run(); err != nil {
@@ -522,9 +575,9 @@ func runWizard(cfg *config.Config, reg *registry.Registry) error {
 
 		// Show credentials link
 		if provider.CredDocsURL != "" {
-			fmt.Printf("\n  Get your credentials from: %s\n", provider.CredDocsURL)
+			fmt.Printf("\n  %s\n", i18n.T("Get your credentials from: %s", provider.CredDocsURL))
 			if provider.Notes != "" {
-				fmt.Printf("   Note: %s\n\n", provider.Notes)
+				fmt.Printf("   %s\n\n", i18n.T("Note: %s", provider.Notes))
 			}
 		}
 
@@ -540,11 +593,11 @@ func runWizard(cfg *config.Config, reg *registry.Registry) error {
 	form4 := huh.NewForm(
 		huh.NewGroup(
 			huh.NewInput().
-				Title("Timezone").
-				Description("System timezone for all services").
+				Title(i18n.T("Timezone")).
+				Description(i18n.T("System timezone for all services")).
 				Placeholder("Europe/Paris").
 				Value(&cfg.Timezone),
-		).Title("System Configuration"),
+		).Title(i18n.T("System Configuration")),
 	)
 
 	if err := form4.Run(); err != nil {
@@ -554,25 +607,32 @@ func runWizard(cfg *config.Config, reg *registry.Registry) error {
 	// Step 6: Addons - Preset profiles then optional custom picker
 	progress.Next()
 	renderStep()
-	addonOptions, err := getAddonOptions(reg)
+	addonOptions, err := getAddonOptions(ctx, reg)
 	if err != nil {
 		return fmt.Errorf("failed to load addons: %w", err)
 	}
 
 	var addonPreset string
+	profileOptions := []huh.Option[string]{
+		huh.NewOption(i18n.T("Minimal (core only)"), "minimal"),
+		huh.NewOption(i18n.T("Standard (recommended)"), "standard"),
+		huh.NewOption(i18n.T("Full (all media)"), "full"),
+		huh.NewOption(i18n.T("Custom (pick your own)"), "custom"),
+	}
+	if initPresetApplied != "" {
+		addonPreset = "preset"
+		profileOptions = append([]huh.Option[string]{
+			huh.NewOption(i18n.T("Preset: %s (%s)", initPresetApplied, strings.Join(cfg.Addons, ", ")), "preset"),
+		}, profileOptions...)
+	}
 	presetForm := huh.NewForm(
 		huh.NewGroup(
 			huh.NewSelect[string]().
-				Title("Addon Profile").
-				Description("Choose a preset or pick addons individually").
-				Options(
-					huh.NewOption("Minimal (core only)", "minimal"),
-					huh.NewOption("Standard (recommended)", "standard"),
-					huh.NewOption("Full (all media)", "full"),
-					huh.NewOption("Custom (pick your own)", "custom"),
-				).
+				Title(i18n.T("Addon Profile")).
+				Description(i18n.T("Choose a preset or pick addons individually")).
+				Options(profileOptions...).
 				Value(&addonPreset),
-		).Title("Addons"),
+		).Title(i18n.T("Addons")),
 	)
 
 	if err := presetForm.Run(); err != nil {
@@ -581,6 +641,9 @@ func runWizard(cfg *config.Config, reg *registry.Registry) error {
 
 	var selectedAddons []string
 	switch addonPreset {
+	case "preset":
+		// Addons already selected by --preset or --preset-url
+		selectedAddons = cfg.Addons
 	case "minimal":
 		// No addons — core services only
 	case "standard":
@@ -591,11 +654,11 @@ func runWizard(cfg *config.Config, reg *registry.Registry) error {
 		form5 := huh.NewForm(
 			huh.NewGroup(
 				huh.NewMultiSelect[string]().
-					Title("Optional Addons").
-					Description("Select additional services to enable").
+					Title(i18n.T("Optional Addons")).
+					Description(i18n.T("Select additional services to enable")).
 					Options(addonOptions...).
 					Value(&selectedAddons),
-			).Title("Addons"),
+			).Title(i18n.T("Addons")),
 		)
 
 		if err := form5.Run(); err != nil {
@@ -613,12 +676,12 @@ func runWizard(cfg *config.Config, reg *registry.Registry) error {
 		formPlexQuestion := huh.NewForm(
 			huh.NewGroup(
 				huh.NewConfirm().
-					Title("Configure Plex direct connections?").
-					Description("Recommended for high-quality streaming. Skip if unsure.").
+					Title(i18n.T("Configure Plex direct connections?")).
+					Description(i18n.T("Recommended for high-quality streaming. Skip if unsure.")).
 					Value(&configurePlex).
-					Affirmative("Yes, configure").
-					Negative("Skip for now"),
-			).Title("Advanced: Plex Direct Access"),
+					Affirmative(i18n.T("Yes, configure")).
+					Negative(i18n.T("Skip for now")),
+			).Title(i18n.T("Advanced: Plex Direct Access")),
 		)
 
 		if err := formPlexQuestion.Run(); err != nil {
@@ -635,37 +698,37 @@ func runWizard(cfg *config.Config, reg *registry.Registry) error {
 
 			if cfg.Expose.Mode == config.ExposeModeCloudflared {
 				suggestion = fmt.Sprintf("https://plex.%s:443", cfg.Domain)
-				helpText = "For Cloudflare Tunnel deployments (recommended):\n" +
-					"  • Use your Cloudflare Tunnel URL: https://plex.domain.com:443\n" +
-					"  • Modern Plex supports HTTPS streaming through tunnels\n" +
-					"  • All traffic stays within Cloudflare network\n" +
-					"  • Optionally add local IP for LAN: ,http://" + localIP + ":32400\n" +
-					"  • Format: https://plex.domain.com:443,http://local-ip:32400"
+				helpText = i18n.T("For Cloudflare Tunnel deployments (recommended):\n"+
+					"  • Use your Cloudflare Tunnel URL: https://plex.domain.com:443\n"+
+					"  • Modern Plex supports HTTPS streaming through tunnels\n"+
+					"  • All traffic stays within Cloudflare network\n"+
+					"  • Optionally add local IP for LAN: ,http://%s:32400\n"+
+					"  • Format: https://plex.domain.com:443,http://local-ip:32400", localIP)
 			} else if cfg.Expose.Mode == config.ExposeModeDirect {
 				suggestion = fmt.Sprintf("https://plex.%s:443", cfg.Domain)
-				helpText = "For Direct mode:\n" +
+				helpText = i18n.T("For Direct mode:\n" +
 					"  • Use your public domain with HTTPS\n" +
 					"  • Optionally add local IP for LAN access\n" +
-					"  • Format: https://plex.domain.com:443,http://local-ip:32400"
+					"  • Format: https://plex.domain.com:443,http://local-ip:32400")
 			} else {
 				// LAN mode
 				suggestion = fmt.Sprintf("http://%s:32400", localIP)
-				helpText = "For LAN mode:\n" +
+				helpText = i18n.T("For LAN mode:\n" +
 					"  • Use your local IP address\n" +
-					"  • Format: http://192.168.x.x:32400"
+					"  • Format: http://192.168.x.x:32400")
 			}
 
 			formPlexURLs := huh.NewForm(
 				huh.NewGroup(
 					huh.NewNote().
-						Title("Plex Advertise URLs").
+						Title(i18n.T("Plex Advertise URLs")).
 						Description(helpText),
 					huh.NewInput().
-						Title("Advertise URLs (comma-separated)").
-						Description("Addresses where Plex can be reached for direct connections").
+						Title(i18n.T("Advertise URLs (comma-separated)")).
+						Description(i18n.T("Addresses where Plex can be reached for direct connections")).
 						Placeholder(suggestion).
 						Value(&cfg.PlexAdvertiseURLs),
-				).Title("Configure Plex Direct Access"),
+				).Title(i18n.T("Configure Plex Direct Access")),
 			)
 
 			if err := formPlexURLs.Run(); err != nil {
@@ -681,11 +744,11 @@ func runWizard(cfg *config.Config, reg *registry.Registry) error {
 
 	var confirmChoice string
 	if err := huh.NewSelect[string]().
-		Title("Generate project with these settings?").
+		Title(i18n.T("Generate project with these settings?")).
 		Options(
-			huh.NewOption("Generate project", "generate"),
-			huh.NewOption("Start over (review settings)", "restart"),
-			huh.NewOption("Cancel", "cancel"),
+			huh.NewOption(i18n.T("Generate project"), "generate"),
+			huh.NewOption(i18n.T("Start over (review settings)"), "restart"),
+			huh.NewOption(i18n.T("Cancel"), "cancel"),
 		).
 		Value(&confirmChoice).
 		Run(); err != nil {
@@ -731,8 +794,7 @@ func filterAvailableAddons(available []huh.Option[string], preset []string) []st
 }
 
 // getAddonOptions loads addon options from the registry
-func getAddonOptions(reg *registry.Registry) ([]huh.Option[string], error) {
-	ctx := context.Background()
+func getAddonOptions(ctx context.Context, reg *registry.Registry) ([]huh.Option[string], error) {
 	services, err := reg.ListServices(ctx)
 	if err != nil {
 		return nil, err
@@ -773,42 +835,42 @@ func printConfigSummary(cfg *config.Config) {
 	valueStyle := lipgloss.NewStyle().
 		Foreground(tui.ColorWhite)
 
-	fmt.Println(headerStyle.Render("Configuration Summary"))
+	fmt.Println(headerStyle.Render(i18n.T("Configuration Summary")))
 
 	printRow := func(label, value string) {
 		fmt.Printf("  %s %s\n", labelStyle.Render(label+":"), valueStyle.Render(value))
 	}
 
-	printRow("Domain", cfg.Domain)
-	printRow("Admin User", cfg.AdminUser)
-	printRow("Expose Mode", cfg.Expose.Mode)
-	printRow("Routing", cfg.Routing.Strategy)
+	printRow(i18n.T("Domain"), cfg.Domain)
+	printRow(i18n.T("Admin User"), cfg.AdminUser)
+	printRow(i18n.T("Expose Mode"), cfg.Expose.Mode)
+	printRow(i18n.T("Routing"), cfg.Routing.Strategy)
 
 	if cfg.Routing.Strategy == config.RoutingStrategyPath {
-		printRow("Base Domain", fmt.Sprintf("%s.%s", cfg.Routing.BaseDomain, cfg.Domain))
+		printRow(i18n.T("Base Domain"), fmt.Sprintf("%s.%s", cfg.Routing.BaseDomain, cfg.Domain))
 	}
 
 	if cfg.JellyfinEnabled {
-		printRow("Media Server", "Plex + Jellyfin")
+		printRow(i18n.T("Media Server"), "Plex + Jellyfin")
 	} else {
-		printRow("Media Server", "Plex")
+		printRow(i18n.T("Media Server"), "Plex")
 	}
-	printRow("Media Path", cfg.MediaPath)
+	printRow(i18n.T("Media Path"), cfg.MediaPath)
 
 	if cfg.VPNEnabled {
-		vpnInfo := fmt.Sprintf("%s via %s", cfg.VPNProvider, cfg.VPNType)
+		vpnInfo := i18n.T("%s via %s", cfg.VPNProvider, cfg.VPNType)
 		if cfg.VPNCountry != "" {
 			vpnInfo += fmt.Sprintf(" (%s)", cfg.VPNCountry)
 		}
 		printRow("VPN", vpnInfo)
 	} else {
-		printRow("VPN", tui.MutedStyle.Render("disabled"))
+		printRow("VPN", tui.MutedStyle.Render(i18n.T("disabled")))
 	}
 
-	printRow("Timezone", cfg.Timezone)
+	printRow(i18n.T("Timezone"), cfg.Timezone)
 
 	if len(cfg.Addons) > 0 {
-		printRow("Addons", strings.Join(cfg.Addons, ", "))
+		printRow(i18n.T("Addons"), strings.Join(cfg.Addons, ", "))
 	}
 
 	fmt.Println()
@@ -831,30 +893,30 @@ func printSuccessMessage(cfg *config.Config) {
 		autheliaURL = fmt.Sprintf("https://auth.%s", cfg.Domain)
 	}
 
-	steps = append(steps, fmt.Sprintf("%d. Review and edit %s file", step, tui.CommandStyle.Render(".env")))
+	steps = append(steps, i18n.T("%d. Review and edit %s file", step, tui.CommandStyle.Render(".env")))
 	step++
 
 	// Only show Cloudflare token instruction if token wasn't collected
 	if cfg.Expose.Mode == config.ExposeModeCloudflared && cfg.CloudflareTunnelToken == "" {
-		steps = append(steps, fmt.Sprintf("%d. Add tunnel token to %s", step, tui.CommandStyle.Render("secrets/cloudflared_tunnel_token.txt")))
+		steps = append(steps, i18n.T("%d. Add tunnel token to %s", step, tui.CommandStyle.Render("secrets/cloudflared_tunnel_token.txt")))
 		step++
 	}
 
 	// Always mention Plex claiming happens during sdbx up
 	if slices.Contains(cfg.Addons, "plex") {
-		steps = append(steps, fmt.Sprintf("%d. Run %s - you'll be prompted for Plex claim token before containers start", step, tui.CommandStyle.Render("sdbx up")))
+		steps = append(steps, i18n.T("%d. Run %s - you'll be prompted for Plex claim token before containers start", step, tui.CommandStyle.Render("sdbx up")))
 	} else {
-		steps = append(steps, fmt.Sprintf("%d. Run %s to start services", step, tui.CommandStyle.Render("sdbx up")))
+		steps = append(steps, i18n.T("%d. Run %s to start services", step, tui.CommandStyle.Render("sdbx up")))
 	}
 	step++
 
-	steps = append(steps, fmt.Sprintf("%d. Login at %s (User: %s)", step, tui.CommandStyle.Render(autheliaURL), cfg.AdminUser))
+	steps = append(steps, i18n.T("%d. Login at %s (User: %s)", step, tui.CommandStyle.Render(autheliaURL), cfg.AdminUser))
 	step++
 
-	steps = append(steps, fmt.Sprintf("%d. Run %s to verify setup", step, tui.CommandStyle.Render("sdbx doctor")))
+	steps = append(steps, i18n.T("%d. Run %s to verify setup", step, tui.CommandStyle.Render("sdbx doctor")))
 
 	message := strings.Join(steps, "\n")
-	fmt.Print(tui.RenderSuccessBox("Project initialized successfully!", message))
+	fmt.Print(tui.RenderSuccessBox(i18n.T("Project initialized successfully!"), message))
 	fmt.Println()
 }
 
@@ -890,9 +952,9 @@ func collectVPNCredentials(cfg *config.Config, provider config.VPNProvider) erro
 		return collectWireguardCredentials(cfg, provider)
 	case config.VPNAuthConfig:
 		// Custom config - just inform user
-		fmt.Println("\n  Custom VPN configuration:")
-		fmt.Println("   Place your .ovpn file in configs/gluetun/")
-		fmt.Println("   Edit configs/gluetun/gluetun.env with your settings")
+		fmt.Println("\n  " + i18n.T("Custom VPN configuration:"))
+		fmt.Println("   " + i18n.T("Place your .ovpn file in configs/gluetun/"))
+		fmt.Println("   " + i18n.T("Edit configs/gluetun/gluetun.env with your settings"))
 		return nil
 	default:
 		return fmt.Errorf("unsupported auth type: %s", provider.AuthType)
@@ -903,11 +965,11 @@ func collectVPNCredentials(cfg *config.Config, provider config.VPNProvider) erro
 func collectUserPassCredentials(cfg *config.Config, provider config.VPNProvider) error {
 	usernameLabel := provider.UsernameLabel
 	if usernameLabel == "" {
-		usernameLabel = "Username"
+		usernameLabel = i18n.T("Username")
 	}
 	passwordLabel := provider.PasswordLabel
 	if passwordLabel == "" {
-		passwordLabel = "Password"
+		passwordLabel = i18n.T("Password")
 	}
 
 	form := huh.NewForm(
@@ -920,7 +982,7 @@ func collectUserPassCredentials(cfg *config.Config, provider config.VPNProvider)
 				Title(passwordLabel).
 				EchoMode(huh.EchoModePassword).
 				Value(&cfg.VPNPassword),
-		).Title("VPN Credentials"),
+		).Title(i18n.T("VPN Credentials")),
 	)
 
 	return form.Run()
@@ -930,16 +992,16 @@ func collectUserPassCredentials(cfg *config.Config, provider config.VPNProvider)
 func collectTokenCredentials(cfg *config.Config, provider config.VPNProvider) error {
 	tokenLabel := provider.TokenLabel
 	if tokenLabel == "" {
-		tokenLabel = "Account Token"
+		tokenLabel = i18n.T("Account Token")
 	}
 
 	form := huh.NewForm(
 		huh.NewGroup(
 			huh.NewInput().
 				Title(tokenLabel).
-				Description("This will be stored securely in your gluetun.env file").
+				Description(i18n.T("This will be stored securely in your gluetun.env file")).
 				Value(&cfg.VPNToken),
-		).Title("VPN Credentials"),
+		).Title(i18n.T("VPN Credentials")),
 	)
 
 	return form.Run()
@@ -952,16 +1014,16 @@ func collectWireguardCredentials(cfg *config.Config, provider config.VPNProvider
 	if cfg.VPNType == "wireguard" {
 		keyLabel := provider.TokenLabel
 		if keyLabel == "" {
-			keyLabel = "Wireguard Private Key"
+			keyLabel = i18n.T("Wireguard Private Key")
 		}
 
 		form := huh.NewForm(
 			huh.NewGroup(
 				huh.NewInput().
 					Title(keyLabel).
-					Description("Your Wireguard private key from the provider's setup page").
+					Description(i18n.T("Your Wireguard private key from the provider's setup page")).
 					Value(&cfg.VPNWireguardKey),
-			).Title("Wireguard Credentials"),
+			).Title(i18n.T("Wireguard Credentials")),
 		)
 
 		return form.Run()
@@ -973,7 +1035,7 @@ func collectWireguardCredentials(cfg *config.Config, provider config.VPNProvider
 
 // collectCloudflareToken collects Cloudflare tunnel token
 func collectCloudflareToken(cfg *config.Config) error {
-	instructions := fmt.Sprintf(
+	instructions := i18n.T(
 		"Get your tunnel token from Cloudflare Zero Trust Dashboard:\n" +
 			"1. Go to https://one.dash.cloudflare.com/\n" +
 			"2. Navigate to Networks > Tunnels\n" +
@@ -986,13 +1048,13 @@ func collectCloudflareToken(cfg *config.Config) error {
 	form := huh.NewForm(
 		huh.NewGroup(
 			huh.NewNote().
-				Title("Cloudflare Tunnel Setup").
+				Title(i18n.T("Cloudflare Tunnel Setup")).
 				Description(instructions),
 			huh.NewConfirm().
-				Title("Do you have your Cloudflare tunnel token ready?").
+				Title(i18n.T("Do you have your Cloudflare tunnel token ready?")).
 				Value(&skipToken).
-				Affirmative("Yes, I have it").
-				Negative("Skip for now"),
+				Affirmative(i18n.T("Yes, I have it")).
+				Negative(i18n.T("Skip for now")),
 		),
 	)
 
@@ -1007,11 +1069,11 @@ func collectCloudflareToken(cfg *config.Config) error {
 	form = huh.NewForm(
 		huh.NewGroup(
 			huh.NewInput().
-				Title("Cloudflare Tunnel Token").
-				Description("Paste your tunnel token here").
+				Title(i18n.T("Cloudflare Tunnel Token")).
+				Description(i18n.T("Paste your tunnel token here")).
 				Value(&cfg.CloudflareTunnelToken).
 				Placeholder("eyJhIjoi..."),
-		).Title("Cloudflare Credentials"),
+		).Title(i18n.T("Cloudflare Credentials")),
 	)
 
 	return form.Run()
@@ -0,0 +1,189 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/charmbracelet/huh"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+
+	"github.com/maiko/sdbx/internal/config"
+	"github.com/maiko/sdbx/internal/registry"
+	"github.com/maiko/sdbx/internal/tui"
+)
+
+// presetChange is a line of the review of a preset
+type presetChange struct {
+	Text string
+	Warn bool // Needs a careful look, e.g. raw compose fields
+}
+
+// errPresetChecksumRequired is returned when a shared preset would be
+// applied without confirmation and its checksum is not pinned
+var errPresetChecksumRequired = errors.New("--preset-checksum is required with --skip-wizard or without a terminal: " +
+	"the .sha256 file next to a preset proves it downloaded intact, not who published it. " +
+	"Review the preset and pass --preset-checksum sha256:<hex>")
+
+// importPreset downloads the preset of --preset-url, shows what it changes
+// in cfg and applies it once confirmed. Without the wizard nobody confirms
+// it, so its checksum must be given. It returns nil when the user declined
+// it.
+func importPreset(cmd *cobra.Command, reg *registry.Registry, cfg *config.Config) (*registry.Preset, error) {
+	interactive := !initSkipWizard && IsTUIEnabled()
+	if !interactive && initPresetChecksum == "" {
+		return nil, errPresetChecksumRequired
+	}
+
+	ctx := commandContext(cmd)
+	var remote *registry.RemotePreset
+	fetch := func() error {
+		var err error
+		remote, err = registry.FetchPreset(ctx, initPresetURL, initPresetChecksum, loadSourceConfig().Proxy)
+		return err
+	}
+	var err error
+	if IsTUIEnabled() {
+		err = tui.RunWithSpinner("Downloading preset...", fetch)
+	} else {
+		err = fetch()
+	}
+	if err != nil {
+		return nil, err
+	}
+	preset, err := reg.ResolvePresetDefinition(ctx, remote.Definition)
+	if err != nil {
+		return nil, err
+	}
+
+	fmt.Println()
+	fmt.Println(tui.TitleStyle.Render("Preset " + preset.Name))
+	if preset.Description != "" {
+		fmt.Println(tui.MutedStyle.Render(preset.Description))
+	}
+	fmt.Println(tui.MutedStyle.Render(fmt.Sprintf("%s (sha256:%s)", remote.URL, remote.SHA256)))
+	if !remote.Pinned {
+		fmt.Printf("%s %s\n", tui.WarningStyle.Render(tui.IconWarning),
+			"Checksum read from "+registry.PresetChecksumSuffix+" next to the preset: it proves the download is intact, not who published it")
+	}
+	fmt.Println()
+	for _, change := range presetReview(preset, cfg) {
+		if change.Warn {
+			fmt.Printf("  %s %s\n", tui.WarningStyle.Render(tui.IconWarning), change.Text)
+		} else {
+			fmt.Printf("  %s %s\n", tui.IconArrow, change.Text)
+		}
+	}
+	fmt.Println()
+
+	if interactive {
+		var confirm bool
+		if err := huh.NewConfirm().
+			Title("Apply this preset?").
+			Description("The wizard starts from it, and every answer can still be changed").
+			Value(&confirm).
+			Run(); err != nil {
+			return nil, fmt.Errorf("confirmation prompt failed: %w", err)
+		}
+		if !confirm {
+			fmt.Println(tui.MutedStyle.Render("Aborted."))
+			return nil, nil
+		}
+	}
+	preset.Apply(cfg)
+	return preset, nil
+}
+
+// presetReview lists what applying a preset changes in a configuration
+func presetReview(p *registry.Preset, cfg *config.Config) []presetChange {
+	var changes []presetChange
+	var added, removed []string
+	for _, addon := range p.Addons {
+		if !slices.Contains(cfg.Addons, addon) && !slices.Contains(p.Disable, addon) {
+			added = append(added, addon)
+		}
+	}
+	for _, addon := range p.Disable {
+		if slices.Contains(cfg.Addons, addon) {
+			removed = append(removed, addon)
+		}
+	}
+	if len(added) > 0 {
+		changes = append(changes, presetChange{Text: "Enable addons: " + strings.Join(added, ", ")})
+	}
+	if len(removed) > 0 {
+		changes = append(changes, presetChange{Text: "Disable addons: " + strings.Join(removed, ", ")})
+	}
+	if len(p.Missing) > 0 {
+		changes = append(changes, presetChange{Text: "Skip addons not found in any source: " + strings.Join(p.Missing, ", "), Warn: true})
+	}
+
+	if p.HardwareTranscode != cfg.HardwareTranscode {
+		state := "off"
+		if p.HardwareTranscode {
+			state = "on"
+		}
+		changes = append(changes, presetChange{Text: "Hardware transcoding: " + state})
+	}
+	if p.Routing.Strategy != "" && p.Routing.Strategy != cfg.Routing.Strategy {
+		changes = append(changes, presetChange{Text: "Routing strategy: " + p.Routing.Strategy})
+	}
+	if p.Routing.BaseDomain != "" && p.Routing.BaseDomain != cfg.Routing.BaseDomain {
+		changes = append(changes, presetChange{Text: "Path routing subdomain: " + p.Routing.BaseDomain})
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(p.Services)) {
+		if _, ok := cfg.Services[name]; ok {
+			changes = append(changes, presetChange{Text: fmt.Sprintf("Keep the existing overrides of %s", name)})
+			continue
+		}
+		override := p.Services[name]
+		changes = append(changes, presetChange{Text: fmt.Sprintf("Override %s: %s", name, strings.Join(overrideFields(override), ", "))})
+		if len(override.ComposeExtra) > 0 {
+			changes = append(changes, presetChange{
+				Text: fmt.Sprintf("%s gets raw compose fields (%s): check them before starting it", name, strings.Join(slices.Sorted(maps.Keys(override.ComposeExtra)), ", ")),
+				Warn: true,
+			})
+		}
+	}
+	for _, name := range slices.Sorted(maps.Keys(p.Resources)) {
+		override, ok := cfg.Services[name]
+		if !ok {
+			override = p.Services[name]
+		}
+		if override.Resources != nil {
+			continue
+		}
+		limits := p.Resources[name]
+		var caps []string
+		if limits.CPUs != "" {
+			caps = append(caps, limits.CPUs+" CPUs")
+		}
+		if limits.Memory != "" {
+			caps = append(caps, limits.Memory+" memory")
+		}
+		changes = append(changes, presetChange{Text: fmt.Sprintf("Limit %s to %s", name, strings.Join(caps, ", "))})
+	}
+
+	if len(changes) == 0 {
+		changes = append(changes, presetChange{Text: "No changes to the current configuration"})
+	}
+	return changes
+}
+
+// overrideFields returns the fields a service override sets, by their
+// .sdbx.yaml names
+func overrideFields(override config.ServiceOverride) []string {
+	data, err := yaml.Marshal(override)
+	if err != nil {
+		return nil
+	}
+	var fields map[string]any
+	if err := yaml.Unmarshal(data, &fields); err != nil {
+		return nil
+	}
+	return slices.Sorted(maps.Keys(fields))
+}
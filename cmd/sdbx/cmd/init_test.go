@@ -2,7 +2,13 @@ package cmd
 
 import (
 	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
+
+	"github.com/maiko/sdbx/internal/config"
+	"github.com/maiko/sdbx/internal/registry"
 )
 
 func TestCapitalizeFirst(t *testing.T) {
@@ -57,3 +63,81 @@ func TestErrStartOver(t *testing.T) {
 		t.Error("errStartOver should match itself with errors.Is")
 	}
 }
+
+// TestImportPresetNeedsChecksum verifies a shared preset applied without
+// confirmation is refused before download unless its checksum is pinned
+func TestImportPresetNeedsChecksum(t *testing.T) {
+	var downloads int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		downloads++
+	}))
+	defer server.Close()
+
+	oldURL, oldChecksum, oldSkip, oldNoTUI := initPresetURL, initPresetChecksum, initSkipWizard, noTUI
+	defer func() {
+		initPresetURL, initPresetChecksum, initSkipWizard, noTUI = oldURL, oldChecksum, oldSkip, oldNoTUI
+	}()
+	initPresetURL, initPresetChecksum = server.URL+"/preset.yaml", ""
+
+	for _, tt := range []struct {
+		name       string
+		skipWizard bool
+		noTUI      bool
+	}{
+		{"skip wizard", true, false},
+		{"no TUI", false, true},
+	} {
+		initSkipWizard, noTUI = tt.skipWizard, tt.noTUI
+		_, err := importPreset(initCmd, nil, config.DefaultConfig())
+		if !errors.Is(err, errPresetChecksumRequired) {
+			t.Errorf("%s: importPreset() error = %v, want errPresetChecksumRequired", tt.name, err)
+		}
+	}
+	if downloads != 0 {
+		t.Errorf("preset downloaded %d times before the checksum was checked", downloads)
+	}
+}
+
+func TestPresetReview(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Addons = []string{"sonarr", "tdarr"}
+	cfg.Services = map[string]config.ServiceOverride{"radarr": {Subdomain: "movies"}}
+
+	preset := &registry.Preset{
+		Name:    "shared",
+		Addons:  []string{"sonarr", "radarr"},
+		Missing: []string{"unknown"},
+		Disable: []string{"tdarr"},
+		Routing: registry.PresetRouting{Strategy: config.RoutingStrategyPath, BaseDomain: "media"},
+		Services: map[string]config.ServiceOverride{
+			"radarr": {Subdomain: "films"},
+			"sonarr": {Subdomain: "tv", ComposeExtra: map[string]interface{}{"privileged": true}},
+		},
+		Resources: map[string]config.ResourceLimits{"plex": {Memory: "2g"}},
+	}
+
+	var lines, warnings []string
+	for _, change := range presetReview(preset, cfg) {
+		lines = append(lines, change.Text)
+		if change.Warn {
+			warnings = append(warnings, change.Text)
+		}
+	}
+	review := strings.Join(lines, "\n")
+	for _, want := range []string{
+		"Enable addons: radarr",
+		"Disable addons: tdarr",
+		"Routing strategy: path",
+		"Path routing subdomain: media",
+		"Keep the existing overrides of radarr",
+		"Override sonarr: compose_extra, subdomain",
+		"Limit plex to 2g memory",
+	} {
+		if !strings.Contains(review, want) {
+			t.Errorf("review is missing %q:\n%s", want, review)
+		}
+	}
+	if len(warnings) != 2 {
+		t.Errorf("warnings = %v, want the missing addon and the compose fields", warnings)
+	}
+}
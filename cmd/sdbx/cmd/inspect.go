@@ -0,0 +1,78 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+
+	"github.com/maiko/sdbx/internal/config"
+	"github.com/maiko/sdbx/internal/generator"
+)
+
+var inspectCmd = &cobra.Command{
+	Use:   "inspect <service>",
+	Short: "Show everything sdbx generates for a service",
+	Long: `Print the rendered model of a service: its final definition (after
+overrides and instance variables), its compose.yaml block, the labels
+generated for it, the secrets it declares or references, and its entries
+in the integration configs (Homepage, Traefik middlewares, Cloudflare
+Tunnel, Authelia).
+
+Nothing is written and no secret is created; secret values are never
+shown. Useful to see why a service ended up the way it did before
+running sdbx regenerate.
+
+Examples:
+  sdbx inspect sonarr                # YAML
+  sdbx inspect sonarr@4k --format json`,
+	Args: cobra.ExactArgs(1),
+	RunE: runInspect,
+}
+
+var inspectFormat string
+
+func init() {
+	rootCmd.AddCommand(inspectCmd)
+
+	inspectCmd.Flags().StringVar(&inspectFormat, "format", "yaml", "Output format: yaml or json")
+}
+
+func runInspect(cmd *cobra.Command, args []string) error {
+	if inspectFormat != "yaml" && inspectFormat != "json" {
+		return fmt.Errorf("unknown format %q (must be yaml or json)", inspectFormat)
+	}
+
+	projectDir, err := config.ProjectDir()
+	if err != nil {
+		return err
+	}
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w\n\n  Try: sdbx doctor", err)
+	}
+	serviceName, err := cfg.ServiceName(args[0])
+	if err != nil {
+		return err
+	}
+
+	reg, err := getRegistry()
+	if err != nil {
+		return err
+	}
+	gen := generator.NewGeneratorWithRegistry(cfg, projectDir, reg)
+	insp, err := gen.Inspect(commandContext(cmd), serviceName)
+	if err != nil {
+		return fmt.Errorf("failed to inspect %s: %w\n\n  Try: sdbx addon info %s", serviceName, err, args[0])
+	}
+
+	if IsJSONOutput() || inspectFormat == "json" {
+		return OutputJSON(insp)
+	}
+	out, err := yaml.Marshal(insp)
+	if err != nil {
+		return fmt.Errorf("failed to encode inspection: %w", err)
+	}
+	fmt.Print(string(out))
+	return nil
+}
@@ -1,9 +1,12 @@
 package cmd
 
 import (
-	"context"
+	"errors"
 	"fmt"
+	"io/fs"
+	"maps"
 	"os"
+	"slices"
 
 	"github.com/spf13/cobra"
 
@@ -26,7 +29,8 @@ Examples:
   sdbx lock generate           # Generate/update lock file
   sdbx lock verify             # Verify lock file integrity
   sdbx lock diff               # Show differences from lock
-  sdbx lock update [service]   # Update specific service in lock`,
+  sdbx lock update [service]   # Update specific service in lock
+  sdbx lock fix                # Re-sign after editing the lock by hand`,
 }
 
 var lockGenerateCmd = &cobra.Command{
@@ -65,16 +69,45 @@ Examples:
 	RunE: runLockUpdate,
 }
 
+var lockFixCmd = &cobra.Command{
+	Use:   "fix",
+	Short: "Re-sign the lock file after intentional edits",
+	Long: `Record a new checksum for .sdbx.lock.
+
+A checksum is stored alongside the lock file (.sdbx.lock.sha256) and
+checked by 'sdbx up' and 'sdbx lock verify', so hand edits and corruption
+are detected (see lock_integrity). Run this after editing the lock file on
+purpose to accept the edits; run 'sdbx lock generate' instead to resolve
+the services again (recorded host ports and generated files are kept).`,
+	RunE: runLockFix,
+}
+
 func init() {
 	rootCmd.AddCommand(lockCmd)
 	lockCmd.AddCommand(lockGenerateCmd)
 	lockCmd.AddCommand(lockVerifyCmd)
 	lockCmd.AddCommand(lockDiffCmd)
 	lockCmd.AddCommand(lockUpdateCmd)
+	lockCmd.AddCommand(lockFixCmd)
+}
+
+// lockFilePath returns the lock file of the current project, or .sdbx.lock in
+// the working directory when run outside a project
+func lockFilePath() string {
+	projectDir, err := config.ProjectDir()
+	if err != nil {
+		return ".sdbx.lock"
+	}
+	return registry.GetLockFilePath(projectDir)
+}
+
+// lockLoadError adds recovery hints to lock file load errors
+func lockLoadError(err error) error {
+	return fmt.Errorf("failed to load lock file: %w\n\n  Try: sdbx lock generate", err)
 }
 
-func runLockGenerate(_ *cobra.Command, _ []string) error {
-	ctx := context.Background()
+func runLockGenerate(cmd *cobra.Command, _ []string) error {
+	ctx := commandContext(cmd)
 
 	cfg, err := config.Load()
 	if err != nil {
@@ -86,18 +119,12 @@ func runLockGenerate(_ *cobra.Command, _ []string) error {
 		return err
 	}
 
-	// Generate lock file
-	lockFile, err := reg.GenerateLockFile(ctx, cfg)
+	// Generate and save lock file
+	lockFile, err := registry.NewLockManager(reg, Version).GenerateLockFile(ctx, cfg, lockFilePath())
 	if err != nil {
 		return fmt.Errorf("failed to generate lock file: %w", err)
 	}
 
-	// Save lock file
-	loader := registry.NewLoader()
-	if err := loader.SaveLockFile(".sdbx.lock", lockFile); err != nil {
-		return fmt.Errorf("failed to save lock file: %w", err)
-	}
-
 	// JSON output
 	if IsJSONOutput() {
 		return OutputJSON(lockFile)
@@ -113,8 +140,8 @@ func runLockGenerate(_ *cobra.Command, _ []string) error {
 	return nil
 }
 
-func runLockVerify(_ *cobra.Command, _ []string) error {
-	ctx := context.Background()
+func runLockVerify(cmd *cobra.Command, _ []string) error {
+	ctx := commandContext(cmd)
 
 	cfg, err := config.Load()
 	if err != nil {
@@ -128,15 +155,32 @@ func runLockVerify(_ *cobra.Command, _ []string) error {
 
 	// Load existing lock file
 	loader := registry.NewLoader()
-	existing, err := loader.LoadLockFile(".sdbx.lock")
+	existing, err := loader.LoadLockFile(lockFilePath())
 	if err != nil {
-		if os.IsNotExist(err) {
+		if errors.Is(err, fs.ErrNotExist) {
 			fmt.Println(tui.WarningStyle.Render("No lock file found"))
 			fmt.Println()
 			fmt.Printf("Run '%s' to generate one\n", tui.CommandStyle.Render("sdbx lock generate"))
 			return nil
 		}
-		return fmt.Errorf("failed to load lock file: %w\n\n  Try: sdbx lock generate", err)
+		return lockLoadError(err)
+	}
+	integrity := registry.VerifyLockChecksum(lockFilePath())
+	if cfg.LockIntegrity == config.LockIntegrityOff {
+		integrity = nil
+	}
+	if errors.Is(integrity, registry.ErrLockChecksumMissing) && !IsJSONOutput() {
+		fmt.Println(tui.WarningStyle.Render("Lock file has no checksum"))
+		fmt.Printf("Run '%s' to add one\n\n", tui.CommandStyle.Render("sdbx lock fix"))
+	}
+	if errors.Is(integrity, registry.ErrLockChecksumMismatch) {
+		if cfg.LockIntegrity == config.LockIntegrityFail {
+			return fmt.Errorf("%w (edited or corrupted)\n\n  Try: sdbx lock fix (or sdbx lock generate to resolve the services again)", integrity)
+		}
+		if !IsJSONOutput() {
+			fmt.Println(tui.WarningStyle.Render("Lock file was edited or corrupted since it was generated"))
+			fmt.Printf("Run '%s' to accept the edits\n\n", tui.CommandStyle.Render("sdbx lock fix"))
+		}
 	}
 
 	// Generate current lock file
@@ -179,8 +223,8 @@ func runLockVerify(_ *cobra.Command, _ []string) error {
 	return fmt.Errorf("lock file has %d difference(s)", len(diffs))
 }
 
-func runLockDiff(_ *cobra.Command, _ []string) error {
-	ctx := context.Background()
+func runLockDiff(cmd *cobra.Command, _ []string) error {
+	ctx := commandContext(cmd)
 
 	cfg, err := config.Load()
 	if err != nil {
@@ -194,9 +238,9 @@ func runLockDiff(_ *cobra.Command, _ []string) error {
 
 	// Load existing lock file
 	loader := registry.NewLoader()
-	existing, err := loader.LoadLockFile(".sdbx.lock")
+	existing, err := loader.LoadLockFile(lockFilePath())
 	if err != nil {
-		if os.IsNotExist(err) {
+		if errors.Is(err, fs.ErrNotExist) {
 			fmt.Println(tui.MutedStyle.Render("No lock file found - showing what would be generated"))
 			fmt.Println()
 
@@ -206,15 +250,15 @@ func runLockDiff(_ *cobra.Command, _ []string) error {
 				return fmt.Errorf("failed to generate lock file: %w", err)
 			}
 
-			for sourceName := range current.Sources {
+			for _, sourceName := range slices.Sorted(maps.Keys(current.Sources)) {
 				fmt.Printf("  %s source: %s\n", tui.SuccessStyle.Render("+"), sourceName)
 			}
-			for serviceName := range current.Services {
+			for _, serviceName := range slices.Sorted(maps.Keys(current.Services)) {
 				fmt.Printf("  %s service: %s\n", tui.SuccessStyle.Render("+"), serviceName)
 			}
 			return nil
 		}
-		return fmt.Errorf("failed to load lock file: %w", err)
+		return lockLoadError(err)
 	}
 
 	// Generate current lock file
@@ -257,8 +301,8 @@ func runLockDiff(_ *cobra.Command, _ []string) error {
 	return nil
 }
 
-func runLockUpdate(_ *cobra.Command, args []string) error {
-	ctx := context.Background()
+func runLockUpdate(cmd *cobra.Command, args []string) error {
+	ctx := commandContext(cmd)
 
 	cfg, err := config.Load()
 	if err != nil {
@@ -272,13 +316,13 @@ func runLockUpdate(_ *cobra.Command, args []string) error {
 
 	// Load existing lock file
 	loader := registry.NewLoader()
-	existing, err := loader.LoadLockFile(".sdbx.lock")
+	existing, err := loader.LoadLockFile(lockFilePath())
 	if err != nil {
-		if os.IsNotExist(err) {
+		if errors.Is(err, fs.ErrNotExist) {
 			// No existing lock file, generate new one
 			return runLockGenerate(nil, nil)
 		}
-		return fmt.Errorf("failed to load lock file: %w", err)
+		return lockLoadError(err)
 	}
 
 	var servicesToUpdate []string
@@ -293,7 +337,8 @@ func runLockUpdate(_ *cobra.Command, args []string) error {
 	}
 
 	// Save updated lock file
-	if err := loader.SaveLockFile(".sdbx.lock", updated); err != nil {
+	updated.Metadata.CLIVersion = Version
+	if err := loader.SaveLockFile(lockFilePath(), updated); err != nil {
 		return fmt.Errorf("failed to save lock file: %w", err)
 	}
 
@@ -310,3 +355,39 @@ func runLockUpdate(_ *cobra.Command, args []string) error {
 
 	return nil
 }
+
+func runLockFix(_ *cobra.Command, _ []string) error {
+	lockPath := lockFilePath()
+
+	data, err := os.ReadFile(lockPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("no lock file found\n\n  Try: sdbx lock generate")
+		}
+		return fmt.Errorf("failed to read lock file: %w", err)
+	}
+
+	// Only sign lock files that still parse
+	if _, err := registry.NewLoader().ParseLockFile(data); err != nil {
+		return fmt.Errorf("lock file is invalid: %w\n\n  Try: sdbx lock generate", err)
+	}
+
+	previous := registry.VerifyLockChecksum(lockPath)
+	if err := registry.SignLockFile(lockPath); err != nil {
+		return fmt.Errorf("failed to sign lock file: %w", err)
+	}
+
+	if IsJSONOutput() {
+		return OutputJSON(map[string]interface{}{
+			"checksum": registry.LockChecksum(data),
+			"changed":  previous != nil,
+		})
+	}
+
+	if previous == nil {
+		fmt.Println(tui.MutedStyle.Render("Lock file already matches its checksum"))
+		return nil
+	}
+	fmt.Println(tui.SuccessStyle.Render("✓ Re-signed .sdbx.lock"))
+	return nil
+}
@@ -1,7 +1,6 @@
 package cmd
 
 import (
-	"context"
 	"fmt"
 	"os"
 	"os/exec"
@@ -36,7 +35,7 @@ func init() {
 	logsCmd.Flags().BoolVarP(&logsFollow, "follow", "f", false, "Follow log output")
 }
 
-func runLogs(_ *cobra.Command, args []string) error {
+func runLogs(cmd *cobra.Command, args []string) error {
 	// Find project directory
 	projectDir, err := config.ProjectDir()
 	if err != nil {
@@ -45,7 +44,9 @@ func runLogs(_ *cobra.Command, args []string) error {
 
 	service := ""
 	if len(args) > 0 {
-		service = args[0]
+		if service, err = serviceArg(args[0]); err != nil {
+			return err
+		}
 	}
 
 	// For follow mode, use exec directly for better UX
@@ -67,7 +68,7 @@ func runLogs(_ *cobra.Command, args []string) error {
 
 	// Non-follow mode
 	compose := docker.NewCompose(projectDir)
-	ctx := context.Background()
+	ctx := commandContext(cmd)
 
 	output, err := compose.Logs(ctx, service, logsTail, false)
 	if err != nil {
@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"fmt"
+	"net/netip"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+
+	"github.com/maiko/sdbx/internal/config"
+	"github.com/maiko/sdbx/internal/mdns"
+	"github.com/maiko/sdbx/internal/tui"
+)
+
+var mdnsCmd = &cobra.Command{
+	Use:   "mdns",
+	Short: "Advertise services as .local names over multicast DNS",
+	Long: `Advertise the routed services as <subdomain>.local names over multicast
+DNS, for LAN mode without DNS records for the domain.
+
+With mdns.enabled in .sdbx.yaml (expose.mode lan), generation adds the
+.local names to the Traefik routers, lists them in configs/mdns/names and
+adds the sdbx-mdns container, which runs 'sdbx mdns serve' on the host
+network. Run it on the host instead when another responder owns the
+container's network.
+
+Examples:
+  sdbx mdns serve
+  sdbx mdns serve --address 192.168.1.10`,
+}
+
+var mdnsServeCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Answer mDNS queries for the .local names of the services",
+	Args:  cobra.NoArgs,
+	RunE:  runMDNSServe,
+}
+
+var (
+	mdnsNamesPath string
+	mdnsAddress   string
+)
+
+func init() {
+	rootCmd.AddCommand(mdnsCmd)
+	mdnsCmd.AddCommand(mdnsServeCmd)
+
+	mdnsServeCmd.Flags().StringVar(&mdnsNamesPath, "names", "", "Names file (default: configs/mdns/names of the project)")
+	mdnsServeCmd.Flags().StringVar(&mdnsAddress, "address", "", "IPv4 address answered (default: the host's address on the interface of each query)")
+}
+
+func runMDNSServe(cmd *cobra.Command, _ []string) error {
+	responder := &mdns.Responder{NamesPath: mdnsNamesPath}
+	if responder.NamesPath == "" {
+		projectDir, err := config.ProjectDir()
+		if err != nil {
+			return err
+		}
+		responder.NamesPath = filepath.Join(projectDir, mdns.NamesFile)
+	}
+	if mdnsAddress != "" {
+		addr, err := netip.ParseAddr(mdnsAddress)
+		if err != nil || !addr.Is4() {
+			return fmt.Errorf("invalid IPv4 address %q", mdnsAddress)
+		}
+		responder.Address = addr
+	}
+
+	names := responder.Names()
+	if len(names) == 0 {
+		fmt.Println(tui.WarningStyle.Render(fmt.Sprintf("%s No name in %s yet, enable mdns in .sdbx.yaml and run: sdbx regenerate", tui.IconWarning, responder.NamesPath)))
+	}
+	fmt.Printf("%s Answering %d .local name(s) from %s\n", tui.IconInfo, len(names), responder.NamesPath)
+	return responder.Serve(commandContext(cmd))
+}
@@ -0,0 +1,127 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/maiko/sdbx/internal/alert"
+	"github.com/maiko/sdbx/internal/backup"
+	"github.com/maiko/sdbx/internal/config"
+	"github.com/maiko/sdbx/internal/diskguard"
+	"github.com/maiko/sdbx/internal/geoip"
+	"github.com/maiko/sdbx/internal/health"
+	"github.com/maiko/sdbx/internal/notify"
+	"github.com/maiko/sdbx/internal/scheduler"
+	"github.com/maiko/sdbx/internal/seeding"
+	"github.com/maiko/sdbx/internal/statuspage"
+	"github.com/maiko/sdbx/internal/tui"
+)
+
+var monitorCmd = &cobra.Command{
+	Use:   "monitor",
+	Short: "Record container health history and evaluate alert rules",
+	Long: `Sample the health of every container periodically into .sdbx.health.db,
+the history behind 'sdbx status --history' and the dashboard's uptime table,
+and evaluate the alert rules of .sdbx.yaml after each sample. Alerts are
+sent to the configured notification channels when they start, repeat and
+resolve. The seeding rules, the disk guard and the backup schedule of
+.sdbx.yaml run on their own intervals, and the public status page is
+re-rendered after each sample when status_page is enabled.
+
+Runs in the foreground until interrupted. The web UI samples on its own
+when running as the sdbx-webui service (server mode), so only run this
+when the web UI is not deployed.
+
+Examples:
+  sdbx monitor                    # Sample every minute, keep 7 days
+  sdbx monitor --interval 30s     # Sample more often
+  sdbx monitor --once             # Sample and check alerts once (e.g. from cron)`,
+	Args: cobra.NoArgs,
+	RunE: runMonitor,
+}
+
+var (
+	monitorInterval  time.Duration
+	monitorRetention time.Duration
+	monitorOnce      bool
+)
+
+func init() {
+	rootCmd.AddCommand(monitorCmd)
+
+	monitorCmd.Flags().DurationVar(&monitorInterval, "interval", health.DefaultInterval, "Time between samples")
+	monitorCmd.Flags().DurationVar(&monitorRetention, "retention", health.DefaultRetention, "How long samples are kept")
+	monitorCmd.Flags().BoolVar(&monitorOnce, "once", false, "Take a single sample, evaluate alert rules and exit")
+}
+
+func runMonitor(cmd *cobra.Command, _ []string) error {
+	if monitorInterval <= 0 || monitorRetention <= 0 {
+		return fmt.Errorf("--interval and --retention must be positive")
+	}
+
+	projectDir, err := config.ProjectDir()
+	if err != nil {
+		return err
+	}
+
+	monitor := health.NewMonitor(projectDir)
+	monitor.Interval = monitorInterval
+	monitor.Retention = monitorRetention
+
+	if monitorOnce {
+		return runMonitorOnce(commandContext(cmd), projectDir, monitor)
+	}
+
+	// Canceled on Ctrl+C or SIGTERM
+	ctx := commandContext(cmd)
+
+	fmt.Printf("%s Recording health every %s to %s (Ctrl+C to stop)\n", tui.IconInfo, monitorInterval, health.DBFile)
+	seedingInterval, guardInterval := config.DefaultSeedingInterval, config.DefaultDiskGuardInterval
+	if cfg, err := config.Load(); err == nil {
+		seedingInterval, guardInterval = cfg.Seeding.EnforceInterval(), cfg.DiskGuard.CheckInterval()
+	}
+	scheduler.New(
+		monitor.Job(),
+		alert.Job(projectDir, monitorInterval),
+		seeding.Job(projectDir, seedingInterval, false),
+		diskguard.Job(projectDir, guardInterval, false),
+		backup.Job(projectDir),
+		geoip.Job(projectDir),
+		statuspage.Job(projectDir),
+	).Run(ctx)
+	return nil
+}
+
+// runMonitorOnce takes one sample, evaluates the alert rules and prints the
+// firing alerts
+func runMonitorOnce(ctx context.Context, projectDir string, monitor *health.Monitor) error {
+	services, err := monitor.Sample(ctx)
+	if err != nil {
+		return fmt.Errorf("%w\n\n  Try: sdbx doctor", err)
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+	alerts, err := alert.NewEngine(projectDir, cfg).Run(ctx, notify.New(projectDir, cfg))
+	if err != nil {
+		return err
+	}
+
+	if IsJSONOutput() {
+		return OutputJSON(map[string]interface{}{
+			"sampled": len(services),
+			"alerts":  alerts,
+		})
+	}
+
+	fmt.Println(tui.SuccessStyle.Render(fmt.Sprintf("%s Recorded health of %d container(s)", tui.IconSuccess, len(services))))
+	for _, a := range alerts {
+		fmt.Printf("%s %s\n", tui.ErrorStyle.Render(tui.IconWarning+" "+a.Rule), a.Message)
+	}
+	return nil
+}
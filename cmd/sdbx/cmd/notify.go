@@ -0,0 +1,178 @@
+package cmd
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/maiko/sdbx/internal/config"
+	"github.com/maiko/sdbx/internal/notify"
+	"github.com/maiko/sdbx/internal/tui"
+)
+
+var notifyCmd = &cobra.Command{
+	Use:   "notify",
+	Short: "Test notification channels and set up *arr email notifications",
+	Long: `Work with the notification channels of .sdbx.yaml.
+
+Alerts, the seeding rules and the disk guard notify the channels under
+notifications.channels: ntfy topics, webhooks and email recipients. Email
+goes through the mail server of the smtp section, also used by Authelia;
+its password is read from secrets/smtp_password.txt.
+
+Example configuration:
+  smtp:
+    host: smtp.example.com
+    port: 587
+    user: sdbx@example.com
+    sender: "SDBX <sdbx@example.com>"
+  notifications:
+    channels:
+      - name: mail
+        type: email
+        url: mailto:admin@example.com
+
+Examples:
+  sdbx notify test               # Send a test message to every channel
+  sdbx notify arr                # Email the *arr notifications to the email channels
+  sdbx notify arr sonarr radarr  # Only these services`,
+}
+
+var notifyTestCmd = &cobra.Command{
+	Use:   "test",
+	Short: "Send a test message through every configured channel",
+	Args:  cobra.NoArgs,
+	RunE:  runNotifyTest,
+}
+
+var notifyArrCmd = &cobra.Command{
+	Use:   "arr [service...]",
+	Short: "Create the email connection of the *arr services",
+	Long: `Create or update the "sdbx" email connection of the enabled *arr services
+(Sonarr, Radarr, Lidarr, Readarr and Prowlarr) through their API. The
+connection sends grabs, imports and health issues to the recipients of the
+email channels through the smtp section's mail server. Run it again after
+changing the smtp section or the recipients.
+
+The services must be running and have started once, so that their API key
+exists in configs/<service>/config.xml.`,
+	RunE: runNotifyArr,
+}
+
+func init() {
+	rootCmd.AddCommand(notifyCmd)
+	notifyCmd.AddCommand(notifyTestCmd)
+	notifyCmd.AddCommand(notifyArrCmd)
+}
+
+// notifyResult is the outcome of a channel or service in JSON output
+type notifyResult struct {
+	Name  string `json:"name"`
+	Error string `json:"error,omitempty"`
+}
+
+func runNotifyTest(cmd *cobra.Command, _ []string) error {
+	ctx := commandContext(cmd)
+
+	projectDir, err := config.ProjectDir()
+	if err != nil {
+		return err
+	}
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	if len(cfg.Notifications.Channels) == 0 {
+		return fmt.Errorf("no notification channels configured\n\n  Add channels under notifications.channels in .sdbx.yaml")
+	}
+
+	n := notify.New(projectDir, cfg)
+	msg := notify.Message{
+		Title:    "sdbx test notification",
+		Body:     fmt.Sprintf("Notifications from %s reach this channel.", cfg.Domain),
+		Severity: notify.SeverityInfo,
+		Source:   "notify:test",
+	}
+	var results []notifyResult
+	for _, ch := range n.Channels {
+		result := notifyResult{Name: ch.Name}
+		if err := n.SendTo(ctx, ch, msg); err != nil {
+			result.Error = err.Error()
+		}
+		results = append(results, result)
+	}
+	return reportNotifyResults(results, "Sent a test message to %s", "channel(s) failed")
+}
+
+func runNotifyArr(cmd *cobra.Command, args []string) error {
+	ctx := commandContext(cmd)
+
+	projectDir, err := config.ProjectDir()
+	if err != nil {
+		return err
+	}
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	if !cfg.SMTP.IsSet() {
+		return fmt.Errorf("no mail server configured\n\n  Add the smtp section to .sdbx.yaml")
+	}
+
+	enabled := notify.ArrServices(cfg)
+	services := enabled
+	if len(args) > 0 {
+		for _, name := range args {
+			if !slices.Contains(enabled, name) {
+				return fmt.Errorf("%s is not an enabled *arr service (enabled: %s)", name, strings.Join(enabled, ", "))
+			}
+		}
+		services = args
+	}
+	if len(services) == 0 {
+		return fmt.Errorf("no *arr service enabled")
+	}
+
+	n := notify.New(projectDir, cfg)
+	compose := projectCompose(projectDir)
+	var results []notifyResult
+	for _, service := range services {
+		result := notifyResult{Name: service}
+		if err := n.ConfigureArr(ctx, compose, service); err != nil {
+			result.Error = err.Error()
+		}
+		results = append(results, result)
+	}
+	return reportNotifyResults(results, "Email notifications set up in %s", "service(s) failed")
+}
+
+// reportNotifyResults prints the outcome of each channel or service and
+// fails when one of them failed
+func reportNotifyResults(results []notifyResult, success, failure string) error {
+	var failed int
+	for _, r := range results {
+		if r.Error != "" {
+			failed++
+		}
+	}
+
+	if IsJSONOutput() {
+		if err := OutputJSON(results); err != nil {
+			return err
+		}
+	} else {
+		for _, r := range results {
+			if r.Error != "" {
+				fmt.Printf("%s %s\n", tui.ErrorStyle.Render(tui.IconError+" "+r.Name), r.Error)
+				continue
+			}
+			fmt.Println(tui.SuccessStyle.Render(tui.IconSuccess + " " + fmt.Sprintf(success, r.Name)))
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d %s", failed, failure)
+	}
+	return nil
+}
@@ -0,0 +1,162 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/maiko/sdbx/internal/config"
+	"github.com/maiko/sdbx/internal/doctor"
+	"github.com/maiko/sdbx/internal/generator"
+	"github.com/maiko/sdbx/internal/tui"
+)
+
+var onboardCmd = &cobra.Command{
+	Use:   "onboard",
+	Short: "Run the first-boot onboarding checks",
+	Long: `Check what a fresh deployment still needs before it is usable:
+
+  • The login answers and protects the services, and has a user
+  • The Cloudflare tunnel is connected, or Traefik serves a trusted
+    certificate issued through ACME or by your CA (direct mode)
+  • Plex is claimed by your account
+  • The qBittorrent Web UI has a permanent password, not a temporary one
+
+Each step that needs you prints targeted instructions. With --fix, the
+steps the service APIs allow are completed: Plex is claimed with the token
+of secrets/plex_claim_token.txt, and the qBittorrent Web UI gets a
+generated password stored in secrets/qbittorrent_password.txt.
+
+sdbx up runs onboarding after the first start of a project. The last
+report is recorded in .sdbx.onboarding.yaml.
+
+Examples:
+  sdbx onboard
+  sdbx onboard --fix
+  sdbx onboard --wait 0   # Do not wait for services still starting`,
+	Args: cobra.NoArgs,
+	RunE: runOnboard,
+}
+
+var (
+	onboardFix  bool
+	onboardWait time.Duration
+)
+
+func init() {
+	rootCmd.AddCommand(onboardCmd)
+
+	onboardCmd.Flags().BoolVar(&onboardFix, "fix", false, "Complete the steps the service APIs allow")
+	onboardCmd.Flags().DurationVar(&onboardWait, "wait", doctor.DefaultOnboardingWait, "How long to wait for services still starting")
+}
+
+func runOnboard(cmd *cobra.Command, _ []string) error {
+	projectDir, err := config.ProjectDir()
+	if err != nil {
+		return err
+	}
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w\n\n  Try: sdbx doctor", err)
+	}
+
+	ctx := commandContext(cmd)
+	onboarding, err := newOnboarding(ctx, cfg, projectDir)
+	if err != nil {
+		return err
+	}
+	onboarding.Fix = onboardFix
+	onboarding.Wait = onboardWait
+
+	report, err := runOnboarding(ctx, onboarding)
+	if err != nil {
+		return err
+	}
+	if IsJSONOutput() {
+		return OutputJSON(report)
+	}
+	printOnboardingReport(report)
+	return nil
+}
+
+// newOnboarding creates the onboarding of a project: its enabled services,
+// the login portal and a service behind the login
+func newOnboarding(ctx context.Context, cfg *config.Config, projectDir string) (*doctor.Onboarding, error) {
+	reg, err := getRegistry()
+	if err != nil {
+		return nil, err
+	}
+	graph, err := reg.Resolve(ctx, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve services: %w\n\n  Try: sdbx doctor", err)
+	}
+	var services []string
+	for _, name := range graph.Order {
+		if graph.Services[name].Enabled {
+			services = append(services, name)
+		}
+	}
+
+	onboarding := doctor.NewOnboarding(cfg, projectDir, services)
+	urls, err := generator.NewGeneratorWithRegistry(cfg, projectDir, reg).URLs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, u := range urls {
+		if u.Service == "authelia" {
+			onboarding.PortalURL = u.URL
+		} else if u.Auth != generator.AuthNone && onboarding.ProtectedURL == "" {
+			onboarding.ProtectedURL = u.URL
+		}
+	}
+	return onboarding, nil
+}
+
+// runOnboarding runs the onboarding steps and records the report
+func runOnboarding(ctx context.Context, onboarding *doctor.Onboarding) (*doctor.OnboardingReport, error) {
+	var report *doctor.OnboardingReport
+	if IsTUIEnabled() && !IsJSONOutput() {
+		if err := tui.RunWithSpinner("Running onboarding checks...", func() error {
+			report = onboarding.Run(ctx)
+			return nil
+		}); err != nil {
+			return nil, err
+		}
+	} else {
+		report = onboarding.Run(ctx)
+	}
+	if err := doctor.WriteOnboarding(onboarding.ProjectDir, report); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// printOnboardingReport lists the onboarding steps, with the instructions
+// of the ones that need the user
+func printOnboardingReport(report *doctor.OnboardingReport) {
+	fmt.Println()
+	fmt.Println(tui.TitleStyle.Render("Onboarding"))
+	fmt.Println()
+	for _, step := range report.Steps {
+		switch step.Status {
+		case doctor.StepDone, doctor.StepFixed:
+			fmt.Printf("  %s %s: %s\n", tui.SuccessStyle.Render(tui.IconSuccess), step.Title, step.Message)
+		case doctor.StepSkipped:
+			fmt.Printf("  %s %s\n", tui.MutedStyle.Render("-"), tui.MutedStyle.Render(step.Title+": "+step.Message))
+		default:
+			fmt.Printf("  %s %s: %s\n", tui.WarningStyle.Render("○"), step.Title, step.Message)
+			for _, instruction := range step.Instructions {
+				fmt.Printf("      %s %s\n", tui.IconArrow, instruction)
+			}
+		}
+	}
+	fmt.Println()
+	if outstanding := len(report.Outstanding()); outstanding > 0 {
+		fmt.Println(tui.InfoStyle.Render(fmt.Sprintf("%d step(s) outstanding, run 'sdbx onboard' again once done", outstanding)))
+	} else {
+		fmt.Println(tui.SuccessStyle.Render("✓ Onboarding complete"))
+	}
+	fmt.Println()
+}
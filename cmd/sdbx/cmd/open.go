@@ -39,7 +39,7 @@ type serviceURLInfo struct {
 	Category string
 }
 
-func runOpen(_ *cobra.Command, args []string) error {
+func runOpen(cmd *cobra.Command, args []string) error {
 	cfg, err := config.Load()
 	if err != nil {
 		cfg = config.DefaultConfig()
@@ -53,7 +53,7 @@ func runOpen(_ *cobra.Command, args []string) error {
 		return fmt.Errorf("no domain configured. Run 'sdbx init' first")
 	}
 
-	ctx := context.Background()
+	ctx := commandContext(cmd)
 
 	// Get enabled services from registry
 	services, err := getEnabledServicesWithRouting(ctx, cfg)
@@ -142,7 +142,10 @@ func runOpen(_ *cobra.Command, args []string) error {
 	}
 
 	// Open specific service
-	service := strings.ToLower(args[0])
+	service, err := cfg.ServiceName(strings.ToLower(args[0]))
+	if err != nil {
+		return err
+	}
 	info, ok := urlMap[service]
 	if !ok {
 		return fmt.Errorf("unknown or not enabled service: %s\nRun 'sdbx open' to see available services", service)
@@ -167,12 +170,13 @@ func getEnabledServicesWithRouting(ctx context.Context, cfg *config.Config) ([]r
 	var result []registry.ServiceInfo
 	for _, name := range graph.Order {
 		svc := graph.Services[name]
-		if svc != nil && svc.Definition.Routing.Enabled {
+		// Final definitions carry the names of named instances
+		if svc != nil && svc.FinalDefinition.Routing.Enabled {
 			result = append(result, registry.ServiceInfo{
-				Name:        svc.Definition.Metadata.Name,
-				Description: svc.Definition.Metadata.Description,
-				Category:    svc.Definition.Metadata.Category,
-				IsAddon:     svc.Definition.Conditions.RequireAddon,
+				Name:        svc.FinalDefinition.Metadata.Name,
+				Description: svc.FinalDefinition.Metadata.Description,
+				Category:    svc.FinalDefinition.Metadata.Category,
+				IsAddon:     svc.FinalDefinition.Conditions.RequireAddon,
 			})
 		}
 	}
@@ -0,0 +1,167 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+
+	"github.com/maiko/sdbx/internal/config"
+	"github.com/maiko/sdbx/internal/tui"
+)
+
+var projectsCmd = &cobra.Command{
+	Use:   "projects",
+	Short: "Manage the projects of your workspace",
+	Long: `Name the sdbx projects you manage (home, a remote seedbox mount, your
+parents' box...) to run any command on one of them from anywhere.
+
+Projects are kept in ~/.config/sdbx/projects.yaml. --project (or the
+SDBX_PROJECT environment variable) selects one by name or by path; the
+command then runs as if started in its directory, reading only its
+.sdbx.yaml.
+
+Examples:
+  sdbx projects add home                   # The current project
+  sdbx projects add parents ~/sdbx/parents
+  sdbx projects list
+  sdbx --project parents status
+  SDBX_PROJECT=home sdbx logs sonarr`,
+}
+
+var projectsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the projects of the workspace",
+	Args:  cobra.NoArgs,
+	RunE:  runProjectsList,
+}
+
+var projectsAddCmd = &cobra.Command{
+	Use:   "add <name> [path]",
+	Short: "Add or move a project (the current one by default)",
+	Args:  cobra.RangeArgs(1, 2),
+	RunE:  runProjectsAdd,
+}
+
+var projectsRemoveCmd = &cobra.Command{
+	Use:     "remove <name>",
+	Aliases: []string{"rm"},
+	Short:   "Remove a project from the workspace, keeping its files",
+	Args:    cobra.ExactArgs(1),
+	RunE:    runProjectsRemove,
+}
+
+func init() {
+	rootCmd.AddCommand(projectsCmd)
+	projectsCmd.AddCommand(projectsListCmd)
+	projectsCmd.AddCommand(projectsAddCmd)
+	projectsCmd.AddCommand(projectsRemoveCmd)
+}
+
+// projectEntry is a project of the workspace with its state
+type projectEntry struct {
+	config.Project
+	Domain  string `json:"domain,omitempty"`
+	Current bool   `json:"current"`
+	Error   string `json:"error,omitempty"` // Why the project could not be read
+}
+
+func runProjectsList(_ *cobra.Command, _ []string) error {
+	workspace, err := config.LoadWorkspace(config.WorkspacePath())
+	if err != nil {
+		return err
+	}
+	current, _ := config.ProjectDir()
+
+	entries := make([]projectEntry, len(workspace.Projects))
+	for i, p := range workspace.Projects {
+		entries[i] = projectEntry{Project: p, Current: p.Path == current}
+		data, err := os.ReadFile(filepath.Join(p.Path, ".sdbx.yaml"))
+		if err != nil {
+			if !config.IsProjectDir(p.Path) {
+				entries[i].Error = "not a sdbx project"
+			}
+			continue
+		}
+		if cfg, err := config.Parse(data); err != nil {
+			entries[i].Error = err.Error()
+		} else {
+			entries[i].Domain = cfg.Domain
+		}
+	}
+
+	if IsJSONOutput() {
+		return OutputJSON(entries)
+	}
+	if len(entries) == 0 {
+		fmt.Println(tui.MutedStyle.Render("No projects. Add one with: sdbx projects add <name> [path]"))
+		return nil
+	}
+	table := tui.NewTable("", "Name", "Path", "Domain")
+	for _, e := range entries {
+		marker := ""
+		if e.Current {
+			marker = tui.SuccessStyle.Render("*")
+		}
+		domain := e.Domain
+		if e.Error != "" {
+			domain = tui.ErrorStyle.Render(e.Error)
+		}
+		table.AddRow(marker, e.Name, e.Path, domain)
+	}
+	fmt.Println(table.Render())
+	return nil
+}
+
+func runProjectsAdd(_ *cobra.Command, args []string) error {
+	var dir string
+	if len(args) == 2 {
+		dir = args[1]
+	} else {
+		var err error
+		if dir, err = config.ProjectDir(); err != nil {
+			return fmt.Errorf("%w\n\n  Try: sdbx projects add %s <path>", err, args[0])
+		}
+	}
+
+	path := config.WorkspacePath()
+	workspace, err := config.LoadWorkspace(path)
+	if err != nil {
+		return err
+	}
+	project, err := workspace.Add(args[0], dir)
+	if err != nil {
+		return err
+	}
+	if err := workspace.Save(path); err != nil {
+		return err
+	}
+
+	if IsJSONOutput() {
+		return OutputJSON(project)
+	}
+	fmt.Println(tui.SuccessStyle.Render(fmt.Sprintf("✓ Added project %s (%s)", project.Name, project.Path)))
+	fmt.Println(tui.MutedStyle.Render(fmt.Sprintf("  Run commands on it with: sdbx --project %s <command>", project.Name)))
+	return nil
+}
+
+func runProjectsRemove(_ *cobra.Command, args []string) error {
+	path := config.WorkspacePath()
+	workspace, err := config.LoadWorkspace(path)
+	if err != nil {
+		return err
+	}
+	if err := workspace.Remove(args[0]); err != nil {
+		if errors.Is(err, config.ErrUnknownProject) {
+			return fmt.Errorf("%w\n\n  Try: sdbx projects list", err)
+		}
+		return err
+	}
+	if err := workspace.Save(path); err != nil {
+		return err
+	}
+	fmt.Println(tui.SuccessStyle.Render(fmt.Sprintf("✓ Removed project %s", args[0])))
+	return nil
+}
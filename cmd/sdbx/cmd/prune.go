@@ -0,0 +1,240 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"slices"
+
+	"github.com/charmbracelet/huh"
+	"github.com/spf13/cobra"
+
+	"github.com/maiko/sdbx/internal/backup"
+	"github.com/maiko/sdbx/internal/config"
+	"github.com/maiko/sdbx/internal/docker"
+	"github.com/maiko/sdbx/internal/generator"
+	"github.com/maiko/sdbx/internal/tui"
+)
+
+var pruneCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "Remove Docker resources left behind by disabled services",
+	Long: `Remove containers, networks, volumes and images that belonged to services
+no longer in the resolved service graph (e.g. after 'sdbx addon disable').
+
+Only objects created by the sdbx compose project are considered; other
+containers on the host are never touched. Named volumes and images are only
+removed when --volumes or --images is given.
+
+Examples:
+  sdbx prune --dry-run          # List what would be removed
+  sdbx prune                    # Remove orphaned containers and networks
+  sdbx prune --images --yes     # Also remove their images, without prompting`,
+	RunE: runPrune,
+}
+
+var (
+	pruneDryRun  bool
+	pruneVolumes bool
+	pruneImages  bool
+	pruneYes     bool
+)
+
+func init() {
+	rootCmd.AddCommand(pruneCmd)
+
+	pruneCmd.Flags().BoolVar(&pruneDryRun, "dry-run", false, "List orphaned resources without removing them")
+	pruneCmd.Flags().BoolVar(&pruneVolumes, "volumes", false, "Also remove orphaned named volumes (data loss!)")
+	pruneCmd.Flags().BoolVar(&pruneImages, "images", false, "Also remove images only used by orphaned containers")
+	pruneCmd.Flags().BoolVarP(&pruneYes, "yes", "y", false, "Skip the confirmation prompt")
+}
+
+func runPrune(cmd *cobra.Command, _ []string) error {
+	projectDir, err := config.ProjectDir()
+	if err != nil {
+		return err
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w\n\n  Try: sdbx doctor", err)
+	}
+
+	ctx := commandContext(cmd)
+
+	reg, err := getRegistry()
+	if err != nil {
+		return err
+	}
+	graph, err := reg.Resolve(ctx, cfg)
+	if err != nil {
+		return fmt.Errorf("failed to resolve services: %w", err)
+	}
+
+	// The compose file SDBX would generate now is the source of truth
+	expected, err := generator.NewComposeGenerator(cfg, reg, nil).Generate(graph)
+	if err != nil {
+		return fmt.Errorf("failed to build expected services: %w", err)
+	}
+
+	compose := projectCompose(projectDir)
+	resources, err := compose.ProjectResources(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list Docker resources: %w\n\n  Try: sdbx doctor", err)
+	}
+
+	orphans := selectOrphans(resources, expected, pruneVolumes)
+	if pruneImages {
+		for _, image := range orphanImages(resources, orphans) {
+			if size, err := compose.ImageSize(ctx, image.ID); err == nil {
+				image.Size = size
+			}
+			orphans = append(orphans, image)
+		}
+	}
+
+	var total int64
+	for _, r := range orphans {
+		total += r.Size
+	}
+
+	if IsJSONOutput() && (pruneDryRun || len(orphans) == 0) {
+		return OutputJSON(map[string]interface{}{
+			"dry_run":     pruneDryRun,
+			"resources":   orphans,
+			"total_bytes": total,
+		})
+	}
+
+	if len(orphans) == 0 {
+		fmt.Println(tui.SuccessStyle.Render("✓ Nothing to prune"))
+		return nil
+	}
+
+	if !IsJSONOutput() {
+		printPrunePlan(orphans, total)
+	}
+
+	if pruneDryRun {
+		fmt.Println(tui.MutedStyle.Render("Dry run: nothing was removed. Re-run without --dry-run to prune."))
+		return nil
+	}
+
+	if !pruneYes {
+		if !IsTUIEnabled() {
+			return fmt.Errorf("refusing to prune without confirmation\n\n  Try: sdbx prune --yes")
+		}
+		var confirm bool
+		if err := huh.NewConfirm().
+			Title(fmt.Sprintf("Remove %d resources?", len(orphans))).
+			Value(&confirm).
+			Run(); err != nil {
+			return fmt.Errorf("confirmation prompt failed: %w", err)
+		}
+		if !confirm {
+			fmt.Println(tui.MutedStyle.Render("Aborted."))
+			return nil
+		}
+	}
+
+	// Containers first, so networks, volumes and images are no longer in use
+	var removed []docker.Resource
+	var failed int
+	for _, r := range orphans {
+		if err := compose.RemoveResource(ctx, r); err != nil {
+			failed++
+			fmt.Fprintf(os.Stderr, "  Failed to remove %s %s: %v\n", r.Kind, r.Name, err)
+			continue
+		}
+		removed = append(removed, r)
+	}
+
+	var freed int64
+	for _, r := range removed {
+		freed += r.Size
+	}
+
+	if IsJSONOutput() {
+		return OutputJSON(map[string]interface{}{
+			"dry_run":     false,
+			"resources":   removed,
+			"failed":      failed,
+			"freed_bytes": freed,
+		})
+	}
+
+	fmt.Println(tui.SuccessStyle.Render(fmt.Sprintf("✓ Removed %d resources, freed %s", len(removed), backup.FormatBytes(freed))))
+	if failed > 0 {
+		return fmt.Errorf("%d resources could not be removed", failed)
+	}
+	return nil
+}
+
+// selectOrphans returns project resources that the expected compose file no
+// longer declares, ordered containers, networks, then volumes
+func selectOrphans(resources []docker.Resource, expected *generator.ComposeFile, includeVolumes bool) []docker.Resource {
+	var containers, networks, volumes []docker.Resource
+	for _, r := range resources {
+		switch r.Kind {
+		case docker.KindContainer:
+			if _, ok := expected.Services[r.Key]; !ok {
+				containers = append(containers, r)
+			}
+		case docker.KindNetwork:
+			if _, ok := expected.Networks[r.Key]; !ok {
+				networks = append(networks, r)
+			}
+		case docker.KindVolume:
+			// Named volumes of the volumes section (NFS, SMB, local) are in use
+			if _, ok := expected.Volumes[r.Key]; !ok && includeVolumes {
+				volumes = append(volumes, r)
+			}
+		}
+	}
+	return slices.Concat(containers, networks, volumes)
+}
+
+// orphanImages returns the images of orphaned containers that no remaining
+// project container still uses
+func orphanImages(resources, orphans []docker.Resource) []docker.Resource {
+	inUse := make(map[string]bool)
+	for _, r := range resources {
+		if r.Kind == docker.KindContainer && !slices.Contains(orphans, r) {
+			inUse[r.Image] = true
+		}
+	}
+
+	var images []docker.Resource
+	seen := make(map[string]bool)
+	for _, r := range orphans {
+		if r.Kind != docker.KindContainer || r.Image == "" || inUse[r.Image] || seen[r.Image] {
+			continue
+		}
+		seen[r.Image] = true
+		images = append(images, docker.Resource{Kind: docker.KindImage, ID: r.Image, Name: r.Image, Key: r.Image})
+	}
+	return images
+}
+
+// printPrunePlan lists the resources that will be removed
+func printPrunePlan(orphans []docker.Resource, total int64) {
+	fmt.Println()
+	fmt.Println(tui.TitleStyle.Render("SDBX Prune"))
+	fmt.Println()
+
+	table := tui.NewTable("Type", "Name", "Service", "Size")
+	for _, r := range orphans {
+		size := "-"
+		if r.Size > 0 {
+			size = backup.FormatBytes(r.Size)
+		}
+		service := r.Key
+		if r.Kind == docker.KindImage {
+			service = "-"
+		}
+		table.AddRow(r.Kind, r.Name, service, size)
+	}
+	fmt.Println(table.Render())
+	fmt.Println()
+	fmt.Println(tui.InfoStyle.Render(fmt.Sprintf("%d resources, %s reclaimable", len(orphans), backup.FormatBytes(total))))
+	fmt.Println()
+}
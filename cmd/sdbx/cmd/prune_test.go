@@ -0,0 +1,64 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/maiko/sdbx/internal/docker"
+	"github.com/maiko/sdbx/internal/generator"
+)
+
+func TestSelectOrphans(t *testing.T) {
+	expected := &generator.ComposeFile{
+		Services: map[string]generator.ComposeService{"sonarr": {}, "traefik": {}},
+		Networks: map[string]generator.ComposeNetwork{"proxy": {}},
+	}
+	resources := []docker.Resource{
+		{Kind: docker.KindVolume, ID: "sdbx_cache", Key: "cache"},
+		{Kind: docker.KindNetwork, ID: "n1", Key: "proxy"},
+		{Kind: docker.KindNetwork, ID: "n2", Key: "legacy"},
+		{Kind: docker.KindContainer, ID: "c1", Key: "sonarr", Image: "sonarr"},
+		{Kind: docker.KindContainer, ID: "c2", Key: "lidarr", Image: "lidarr"},
+	}
+
+	orphans := selectOrphans(resources, expected, false)
+	if len(orphans) != 2 || orphans[0].ID != "c2" || orphans[1].ID != "n2" {
+		t.Errorf("orphans = %+v, want lidarr container then legacy network", orphans)
+	}
+
+	orphans = selectOrphans(resources, expected, true)
+	if len(orphans) != 3 || orphans[2].ID != "sdbx_cache" {
+		t.Errorf("orphans with volumes = %+v", orphans)
+	}
+}
+
+func TestSelectOrphansKeepsConfiguredVolumes(t *testing.T) {
+	expected := &generator.ComposeFile{
+		Services: map[string]generator.ComposeService{"plex": {}},
+		Volumes: map[string]generator.ComposeVolume{
+			"media-nfs": {Driver: "local", DriverOpts: map[string]string{"type": "nfs"}},
+		},
+	}
+	resources := []docker.Resource{
+		{Kind: docker.KindVolume, ID: "sdbx_media-nfs", Key: "media-nfs"},
+		{Kind: docker.KindVolume, ID: "sdbx_cache", Key: "cache"},
+	}
+
+	orphans := selectOrphans(resources, expected, true)
+	if len(orphans) != 1 || orphans[0].ID != "sdbx_cache" {
+		t.Errorf("orphans = %+v, want only the unconfigured cache volume", orphans)
+	}
+}
+
+func TestOrphanImages(t *testing.T) {
+	resources := []docker.Resource{
+		{Kind: docker.KindContainer, ID: "c1", Key: "sonarr", Image: "shared:latest"},
+		{Kind: docker.KindContainer, ID: "c2", Key: "lidarr", Image: "lidarr:latest"},
+		{Kind: docker.KindContainer, ID: "c3", Key: "old", Image: "shared:latest"},
+	}
+	orphans := []docker.Resource{resources[1], resources[2]}
+
+	images := orphanImages(resources, orphans)
+	if len(images) != 1 || images[0].ID != "lidarr:latest" || images[0].Kind != docker.KindImage {
+		t.Errorf("images = %+v, want only lidarr:latest (shared image is still in use)", images)
+	}
+}
@@ -0,0 +1,415 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/maiko/sdbx/internal/backup"
+	"github.com/maiko/sdbx/internal/config"
+	"github.com/maiko/sdbx/internal/docker"
+	"github.com/maiko/sdbx/internal/generator"
+	"github.com/maiko/sdbx/internal/registry"
+	"github.com/maiko/sdbx/internal/tui"
+)
+
+var pullCmd = &cobra.Command{
+	Use:   "pull",
+	Short: "Pre-fetch the locked images of enabled services",
+	Long: `Pull the images pinned in .sdbx.lock for enabled services, several at a
+time, so that a later 'sdbx up' or 'sdbx update' only has to recreate
+containers.
+
+Images are pulled even when present, to fetch new builds of their tag.
+When the lock pins a digest, images already at that digest are skipped and
+pulled images must match it.
+
+Examples:
+  sdbx pull                 # Pull 3 images at a time
+  sdbx pull --parallel 6    # Pull 6 images at a time
+  sdbx pull --json          # Progress as JSON events`,
+	Args: cobra.NoArgs,
+	RunE: runPull,
+}
+
+var (
+	pullParallel int
+	pullQuiet    bool
+)
+
+// defaultPullParallel is how many images are pulled at once
+const defaultPullParallel = 3
+
+func init() {
+	rootCmd.AddCommand(pullCmd)
+	pullCmd.Flags().IntVarP(&pullParallel, "parallel", "p", defaultPullParallel, "Number of images pulled at once")
+	pullCmd.Flags().BoolVarP(&pullQuiet, "quiet", "q", false, "Do not show pull progress")
+}
+
+// How image pull progress is reported
+type pullOutput int
+
+const (
+	pullOutputLive  pullOutput = iota // Progress bars redrawn in place
+	pullOutputPlain                   // One line per finished image
+	pullOutputJSON                    // PullEvent JSON lines, then the summary
+	pullOutputQuiet                   // Failures only
+)
+
+// pullRedrawInterval throttles progress bar redraws
+const pullRedrawInterval = 100 * time.Millisecond
+
+// pullTarget is an image to pull, with the digest it must have when the
+// lock file pins one
+type pullTarget struct {
+	Image  string
+	Digest string
+}
+
+// pullSummary is the outcome of pulling a set of images
+type pullSummary struct {
+	Pulled     []string          `json:"pulled"`
+	UpToDate   []string          `json:"up_to_date,omitempty"`
+	Failed     map[string]string `json:"failed,omitempty"`
+	Downloaded int64             `json:"downloaded_bytes"`
+	Duration   time.Duration     `json:"duration_ns"`
+}
+
+// pullReporter renders the progress of image pulls. It is safe for
+// concurrent pulls: each active image keeps its own bar.
+type pullReporter struct {
+	mode pullOutput
+	out  io.Writer
+
+	mu       sync.Mutex
+	start    time.Time
+	progress map[string]*docker.PullProgress
+	active   []string
+	finished []string // Lines printed above the bars on the next redraw
+	lines    int      // Bars currently drawn
+	drawn    time.Time
+	summary  pullSummary
+}
+
+// newPullReporter picks the output from the global flags: JSON events with
+// --json, nothing but failures with quiet, bars in interactive terminals
+func newPullReporter(quiet bool) *pullReporter {
+	mode := pullOutputPlain
+	switch {
+	case IsJSONOutput():
+		mode = pullOutputJSON
+	case quiet:
+		mode = pullOutputQuiet
+	case IsTUIEnabled():
+		mode = pullOutputLive
+	}
+	return &pullReporter{
+		mode:     mode,
+		out:      os.Stdout,
+		start:    time.Now(),
+		progress: make(map[string]*docker.PullProgress),
+		summary:  pullSummary{Failed: make(map[string]string)},
+	}
+}
+
+// Start registers an image whose pull begins
+func (r *pullReporter) Start(image string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.progress[image] = docker.NewPullProgress(image)
+	r.active = append(r.active, image)
+	if r.mode == pullOutputLive {
+		r.redraw()
+	}
+}
+
+// Skip records an image already at its locked digest
+func (r *pullReporter) Skip(image string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.summary.UpToDate = append(r.summary.UpToDate, image)
+}
+
+// Event applies a progress message of a running pull
+func (r *pullReporter) Event(ev docker.PullEvent) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if p, ok := r.progress[ev.Image]; ok {
+		p.Update(ev)
+	}
+	switch r.mode {
+	case pullOutputJSON:
+		r.writeJSON(ev)
+	case pullOutputLive:
+		if time.Since(r.drawn) >= pullRedrawInterval {
+			r.redraw()
+		}
+	}
+}
+
+// Finish records the outcome of an image pull
+func (r *pullReporter) Finish(image string, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.active = slices.DeleteFunc(r.active, func(s string) bool { return s == image })
+
+	var line string
+	if err != nil {
+		r.summary.Failed[image] = err.Error()
+		line = tui.WarningStyle.Render(fmt.Sprintf("  %s %s: %v", tui.IconWarning, image, err))
+	} else {
+		downloaded, _ := r.progress[image].Downloaded()
+		r.summary.Pulled = append(r.summary.Pulled, image)
+		r.summary.Downloaded += downloaded
+		line = fmt.Sprintf("  %s %s %s", tui.SuccessStyle.Render(tui.IconSuccess), image,
+			tui.MutedStyle.Render(backup.FormatBytes(downloaded)))
+	}
+
+	switch r.mode {
+	case pullOutputLive:
+		r.finished = append(r.finished, line)
+		r.redraw()
+	case pullOutputPlain:
+		fmt.Fprintln(r.out, line)
+	case pullOutputQuiet:
+		if err != nil {
+			fmt.Fprintln(r.out, line)
+		}
+	}
+}
+
+// Summary returns the outcome of all pulls and prints it
+func (r *pullReporter) Summary() pullSummary {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.summary.Duration = time.Since(r.start)
+	slices.Sort(r.summary.Pulled)
+	slices.Sort(r.summary.UpToDate)
+
+	switch r.mode {
+	case pullOutputJSON:
+		r.writeJSON(map[string]pullSummary{"summary": r.summary})
+	case pullOutputLive, pullOutputPlain:
+		if len(r.summary.Pulled) > 0 {
+			fmt.Fprintln(r.out, tui.SuccessStyle.Render(fmt.Sprintf("✓ Pulled %d images (%s downloaded) in %s",
+				len(r.summary.Pulled), backup.FormatBytes(r.summary.Downloaded), r.summary.Duration.Round(time.Second))))
+		}
+		if len(r.summary.UpToDate) > 0 {
+			fmt.Fprintln(r.out, tui.MutedStyle.Render(fmt.Sprintf("  %d images already at their locked digest", len(r.summary.UpToDate))))
+		}
+	}
+	return r.summary
+}
+
+// redraw prints finished lines above the bars, then the bar of each active pull
+func (r *pullReporter) redraw() {
+	if r.lines > 0 {
+		fmt.Fprintf(r.out, "\033[%dA", r.lines)
+	}
+	for _, line := range r.finished {
+		fmt.Fprintf(r.out, "\r\033[K%s\n", line)
+	}
+	r.finished = nil
+	for _, image := range r.active {
+		fmt.Fprintf(r.out, "\r\033[K%s\n", renderPullBar(r.progress[image]))
+	}
+	fmt.Fprint(r.out, "\033[J")
+	r.lines = len(r.active)
+	r.drawn = time.Now()
+}
+
+func (r *pullReporter) writeJSON(v interface{}) {
+	if data, err := json.Marshal(v); err == nil {
+		fmt.Fprintln(r.out, string(data))
+	}
+}
+
+// renderPullBar renders one image pull: bar, bytes and layers
+func renderPullBar(p *docker.PullProgress) string {
+	downloaded, total := p.Downloaded()
+	done, layers := p.Layers()
+	detail := fmt.Sprintf("%s / %s  %d/%d layers", backup.FormatBytes(downloaded), backup.FormatBytes(total), done, layers)
+	return fmt.Sprintf("  %s %s %s %s", tui.IconArrow, p.Image,
+		tui.ProgressBar(p.Percent()/100, 30), tui.MutedStyle.Render(detail))
+}
+
+// composeImages returns the images of compose.yaml, sorted and deduplicated
+func composeImages(projectDir string) ([]string, error) {
+	data, err := os.ReadFile(filepath.Join(projectDir, "compose.yaml"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read compose.yaml: %w\n\n  Try: sdbx regenerate", err)
+	}
+	compose, err := generator.ParseComposeFile(data)
+	if err != nil {
+		return nil, err
+	}
+	var images []string
+	for _, svc := range compose.Services {
+		if svc.Image != "" && !slices.Contains(images, svc.Image) {
+			images = append(images, svc.Image)
+		}
+	}
+	slices.Sort(images)
+	return images, nil
+}
+
+// lockedImages returns the images of enabled services in the lock file,
+// sorted and deduplicated
+func lockedImages(lock *registry.LockFile) []pullTarget {
+	seen := make(map[string]bool)
+	var targets []pullTarget
+	for _, svc := range lock.Services {
+		if !svc.Enabled || svc.Image.Repository == "" {
+			continue
+		}
+		tag := svc.Image.Tag
+		if tag == "" {
+			tag = "latest"
+		}
+		image := svc.Image.Repository + ":" + tag
+		if seen[image] {
+			continue
+		}
+		seen[image] = true
+		targets = append(targets, pullTarget{Image: image, Digest: svc.Image.Digest})
+	}
+	slices.SortFunc(targets, func(a, b pullTarget) int { return strings.Compare(a.Image, b.Image) })
+	return targets
+}
+
+// pullImages pulls images with at most parallel pulls at once. Images
+// pinned to a digest are skipped when already at it, and fail when the
+// pulled image does not match it.
+func pullImages(ctx context.Context, engine *docker.Engine, targets []pullTarget, parallel int, reporter *pullReporter) pullSummary {
+	if parallel < 1 {
+		parallel = 1
+	}
+	sem := make(chan struct{}, parallel)
+	var wg sync.WaitGroup
+	for _, target := range targets {
+		wg.Add(1)
+		go func(target pullTarget) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			if target.Digest != "" {
+				if info, err := engine.InspectImage(ctx, target.Image); err == nil && info != nil && info.HasDigest(target.Digest) {
+					reporter.Skip(target.Image)
+					return
+				}
+			}
+			reporter.Start(target.Image)
+			err := engine.PullImage(ctx, target.Image, reporter.Event)
+			if err == nil && target.Digest != "" {
+				err = verifyDigest(ctx, engine, target)
+			}
+			reporter.Finish(target.Image, err)
+		}(target)
+	}
+	wg.Wait()
+	return reporter.Summary()
+}
+
+// verifyDigest checks that a pulled image has its locked digest
+func verifyDigest(ctx context.Context, engine *docker.Engine, target pullTarget) error {
+	info, err := engine.InspectImage(ctx, target.Image)
+	if err != nil {
+		return err
+	}
+	if info == nil || !info.HasDigest(target.Digest) {
+		return fmt.Errorf("digest does not match lock (%s)", target.Digest)
+	}
+	return nil
+}
+
+func runPull(cmd *cobra.Command, _ []string) error {
+	ctx := commandContext(cmd)
+	if err := registry.RequireNetwork("pulling images"); err != nil {
+		return err
+	}
+
+	projectDir, err := config.ProjectDir()
+	if err != nil {
+		return err
+	}
+	lock, err := registry.NewLoader().LoadLockFile(registry.GetLockFilePath(projectDir))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("no lock file found\n\n  Try: sdbx lock generate")
+		}
+		return lockLoadError(err)
+	}
+	targets := lockedImages(lock)
+	if len(targets) == 0 {
+		if IsJSONOutput() {
+			return OutputJSON(pullSummary{Pulled: []string{}})
+		}
+		fmt.Println(tui.MutedStyle.Render("No locked images to pull"))
+		return nil
+	}
+
+	engine := docker.NewEngine()
+	if !engine.Available(ctx) {
+		return fmt.Errorf("cannot reach the Docker Engine API (%s)\n\n  Try: sdbx doctor", docker.DefaultSocket)
+	}
+
+	reporter := newPullReporter(pullQuiet)
+	if reporter.mode == pullOutputLive || reporter.mode == pullOutputPlain {
+		fmt.Println(tui.InfoStyle.Render(fmt.Sprintf("Pulling %d images (%d at a time)...", len(targets), max(pullParallel, 1))))
+	}
+	summary := pullImages(ctx, engine, targets, pullParallel, reporter)
+	if len(summary.Failed) > 0 {
+		return fmt.Errorf("failed to pull %d images", len(summary.Failed))
+	}
+	return nil
+}
+
+// pullMissingImages pulls the compose images not present locally with
+// progress reporting. Without access to the Engine API it does nothing and
+// compose pulls them itself; failed pulls are left for compose to report.
+func pullMissingImages(ctx context.Context, projectDir string, quiet bool) error {
+	engine := docker.NewEngine()
+	if !engine.Available(ctx) {
+		return nil
+	}
+	images, err := composeImages(projectDir)
+	if err != nil {
+		return err
+	}
+
+	var missing []pullTarget
+	for _, image := range images {
+		if exists, err := engine.ImageExists(ctx, image); err == nil && !exists {
+			missing = append(missing, pullTarget{Image: image})
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+	if registry.Offline() {
+		names := make([]string, len(missing))
+		for i, target := range missing {
+			names[i] = target.Image
+		}
+		return registry.RequireNetwork("pulling the missing images " + strings.Join(names, ", "))
+	}
+
+	reporter := newPullReporter(quiet)
+	if reporter.mode == pullOutputLive || reporter.mode == pullOutputPlain {
+		fmt.Println(tui.InfoStyle.Render(fmt.Sprintf("Pulling %d images...", len(missing))))
+	}
+	pullImages(ctx, engine, missing, defaultPullParallel, reporter)
+	return nil
+}
@@ -0,0 +1,168 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/maiko/sdbx/internal/docker"
+	"github.com/maiko/sdbx/internal/registry"
+)
+
+func TestComposeImages(t *testing.T) {
+	dir := t.TempDir()
+	compose := `services:
+  sonarr:
+    image: lscr.io/linuxserver/sonarr:latest
+  sonarr4k:
+    image: lscr.io/linuxserver/sonarr:latest
+  plex:
+    image: plexinc/pms-docker:latest
+  built:
+    build: .
+`
+	if err := os.WriteFile(filepath.Join(dir, "compose.yaml"), []byte(compose), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	images, err := composeImages(dir)
+	if err != nil {
+		t.Fatalf("composeImages() error = %v", err)
+	}
+	want := []string{"lscr.io/linuxserver/sonarr:latest", "plexinc/pms-docker:latest"}
+	if !slices.Equal(images, want) {
+		t.Errorf("composeImages() = %v, want %v", images, want)
+	}
+}
+
+func TestPullReporterJSON(t *testing.T) {
+	var out bytes.Buffer
+	r := newPullReporter(false)
+	r.mode = pullOutputJSON
+	r.out = &out
+
+	r.Start("a:1")
+	r.Event(docker.PullEvent{Image: "a:1", Layer: "l1", Status: docker.LayerDownloading, Current: 10, Total: 40})
+	r.Event(docker.PullEvent{Image: "a:1", Layer: "l1", Status: docker.LayerPullComplete})
+	r.Finish("a:1", nil)
+	r.Start("b:1")
+	r.Finish("b:1", errors.New("denied"))
+	summary := r.Summary()
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("got %d JSON lines, want 3:\n%s", len(lines), out.String())
+	}
+	var ev docker.PullEvent
+	if err := json.Unmarshal([]byte(lines[0]), &ev); err != nil || ev.Current != 10 {
+		t.Errorf("first line = %s (%v)", lines[0], err)
+	}
+	if !strings.HasPrefix(lines[2], `{"summary":`) {
+		t.Errorf("last line is not the summary: %s", lines[2])
+	}
+	if summary.Downloaded != 40 || !slices.Equal(summary.Pulled, []string{"a:1"}) || summary.Failed["b:1"] != "denied" {
+		t.Errorf("summary = %+v", summary)
+	}
+}
+
+func TestPullReporterQuiet(t *testing.T) {
+	var out bytes.Buffer
+	r := newPullReporter(true)
+	r.mode = pullOutputQuiet
+	r.out = &out
+
+	r.Start("a:1")
+	r.Finish("a:1", nil)
+	r.Summary()
+	if out.Len() != 0 {
+		t.Errorf("quiet output = %q, want nothing", out.String())
+	}
+
+	r.Start("b:1")
+	r.Finish("b:1", errors.New("denied"))
+	if !strings.Contains(out.String(), "denied") {
+		t.Errorf("quiet output should report failures, got %q", out.String())
+	}
+}
+
+func TestLockedImages(t *testing.T) {
+	lock := &registry.LockFile{Services: map[string]registry.LockedService{
+		"sonarr":   {Enabled: true, Image: registry.LockedImage{Repository: "lscr.io/linuxserver/sonarr", Tag: "4.0"}},
+		"sonarr4k": {Enabled: true, Image: registry.LockedImage{Repository: "lscr.io/linuxserver/sonarr", Tag: "4.0"}},
+		"plex":     {Enabled: true, Image: registry.LockedImage{Repository: "plexinc/pms-docker", Digest: "sha256:abc"}},
+		"lidarr":   {Enabled: false, Image: registry.LockedImage{Repository: "lscr.io/linuxserver/lidarr", Tag: "latest"}},
+		"no-image": {Enabled: true},
+	}}
+	want := []pullTarget{
+		{Image: "lscr.io/linuxserver/sonarr:4.0"},
+		{Image: "plexinc/pms-docker:latest", Digest: "sha256:abc"},
+	}
+	if got := lockedImages(lock); !slices.Equal(got, want) {
+		t.Errorf("lockedImages() = %v, want %v", got, want)
+	}
+}
+
+func TestPullImages(t *testing.T) {
+	var mu sync.Mutex
+	var running, maxRunning int
+	var pulled []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/images/create":
+			image := r.URL.Query().Get("fromImage")
+			mu.Lock()
+			running++
+			maxRunning = max(maxRunning, running)
+			pulled = append(pulled, image)
+			mu.Unlock()
+			time.Sleep(20 * time.Millisecond)
+			mu.Lock()
+			running--
+			mu.Unlock()
+			fmt.Fprint(w, `{"status":"Pull complete","id":"l1"}`)
+		case strings.HasPrefix(r.URL.Path, "/images/current"):
+			fmt.Fprint(w, `{"RepoDigests":["current@sha256:aaa"]}`)
+		case strings.HasPrefix(r.URL.Path, "/images/stale"):
+			fmt.Fprint(w, `{"RepoDigests":["stale@sha256:old"]}`)
+		default:
+			fmt.Fprint(w, `{}`)
+		}
+	}))
+	defer srv.Close()
+	engine := &docker.Engine{Client: srv.Client(), BaseURL: srv.URL}
+
+	targets := []pullTarget{
+		{Image: "a:1"}, {Image: "b:1"}, {Image: "c:1"}, {Image: "d:1"},
+		{Image: "current:1", Digest: "sha256:aaa"},
+		{Image: "stale:1", Digest: "sha256:new"},
+	}
+	r := newPullReporter(true)
+	r.out = &bytes.Buffer{}
+	summary := pullImages(context.Background(), engine, targets, 2, r)
+
+	if maxRunning > 2 {
+		t.Errorf("%d pulls ran at once, want at most 2", maxRunning)
+	}
+	if slices.Contains(pulled, "current:1") {
+		t.Error("image already at its locked digest should not be pulled")
+	}
+	if !slices.Equal(summary.UpToDate, []string{"current:1"}) {
+		t.Errorf("UpToDate = %v", summary.UpToDate)
+	}
+	if !slices.Equal(summary.Pulled, []string{"a:1", "b:1", "c:1", "d:1"}) {
+		t.Errorf("Pulled = %v", summary.Pulled)
+	}
+	if !strings.Contains(summary.Failed["stale:1"], "digest") {
+		t.Errorf("stale:1 should fail digest verification, got %v", summary.Failed)
+	}
+}
@@ -8,6 +8,7 @@ import (
 
 	"github.com/maiko/sdbx/internal/config"
 	"github.com/maiko/sdbx/internal/generator"
+	"github.com/maiko/sdbx/internal/registry"
 	"github.com/maiko/sdbx/internal/tui"
 )
 
@@ -35,7 +36,7 @@ func init() {
 	rootCmd.AddCommand(regenerateCmd)
 }
 
-func runRegenerate(_ *cobra.Command, _ []string) error {
+func runRegenerate(cmd *cobra.Command, _ []string) error {
 	// Load existing configuration
 	cfg, err := config.Load()
 	if err != nil {
@@ -60,11 +61,12 @@ func runRegenerate(_ *cobra.Command, _ []string) error {
 	if err != nil {
 		return fmt.Errorf("failed to get working directory: %w", err)
 	}
+	ctx := commandContext(cmd)
 
 	// JSON output mode
 	if IsJSONOutput() {
 		gen := generator.NewGenerator(cfg, outputDir)
-		if err := gen.Generate(); err != nil {
+		if err := gen.GenerateContext(ctx); err != nil {
 			return OutputJSON(map[string]interface{}{
 				"success": false,
 				"error":   err.Error(),
@@ -73,14 +75,15 @@ func runRegenerate(_ *cobra.Command, _ []string) error {
 		return OutputJSON(map[string]interface{}{
 			"success": true,
 			"message": "Project files regenerated successfully",
+			"skipped": skippedJSON(gen.Skipped),
 		})
 	}
 
 	// TUI mode with spinner
 	if IsTUIEnabled() {
+		gen := generator.NewGenerator(cfg, outputDir)
 		genErr := tui.RunWithSpinner("Regenerating project files...", func() error {
-			gen := generator.NewGenerator(cfg, outputDir)
-			return gen.Generate()
+			return gen.GenerateContext(ctx)
 		})
 
 		if genErr != nil {
@@ -89,6 +92,7 @@ func runRegenerate(_ *cobra.Command, _ []string) error {
 		}
 
 		fmt.Println(tui.IconSuccess + " Project files regenerated successfully")
+		printSkipped(gen.Skipped)
 		fmt.Println()
 		fmt.Println(tui.IconInfo + " Run 'sdbx up' to apply changes")
 		return nil
@@ -97,11 +101,39 @@ func runRegenerate(_ *cobra.Command, _ []string) error {
 	// Plain text mode
 	fmt.Println("Regenerating project files...")
 	gen := generator.NewGenerator(cfg, outputDir)
-	if err := gen.Generate(); err != nil {
+	if err := gen.GenerateContext(ctx); err != nil {
 		return fmt.Errorf("regeneration failed: %w", err)
 	}
 
 	fmt.Println("Project files regenerated successfully.")
+	printSkipped(gen.Skipped)
 	fmt.Println("Run 'sdbx up' to apply changes.")
 	return nil
 }
+
+// printSkipped warns about the services a generation left out, and why
+func printSkipped(skipped []registry.ResolutionError) {
+	for _, e := range skipped {
+		message := fmt.Sprintf("%s Skipped %s", tui.IconWarning, e.Error())
+		if e.Kept {
+			message += " (previous configuration kept)"
+		}
+		fmt.Println(tui.WarningStyle.Render(message))
+	}
+}
+
+// skippedJSON returns the services a generation left out, for --json output
+func skippedJSON(skipped []registry.ResolutionError) []map[string]string {
+	result := make([]map[string]string, 0, len(skipped))
+	for _, e := range skipped {
+		reason := e.Message
+		if e.Cause != nil {
+			reason += ": " + e.Cause.Error()
+		}
+		if e.Kept {
+			reason += " (previous configuration kept)"
+		}
+		result = append(result, map[string]string{"service": e.Service, "reason": reason})
+	}
+	return result
+}
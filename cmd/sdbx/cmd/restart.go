@@ -1,14 +1,12 @@
 package cmd
 
 import (
-	"context"
 	"fmt"
 	"time"
 
 	"github.com/spf13/cobra"
 
 	"github.com/maiko/sdbx/internal/config"
-	"github.com/maiko/sdbx/internal/docker"
 	"github.com/maiko/sdbx/internal/tui"
 )
 
@@ -28,15 +26,16 @@ func init() {
 	rootCmd.AddCommand(restartCmd)
 }
 
-func runRestart(_ *cobra.Command, args []string) error {
+func runRestart(cmd *cobra.Command, args []string) error {
 	// Find project directory
 	projectDir, err := config.ProjectDir()
 	if err != nil {
 		return err
 	}
 
-	compose := docker.NewCompose(projectDir)
-	ctx := context.Background()
+	compose := projectCompose(projectDir)
+	ctx := commandContext(cmd)
+	recordEvent(projectDir, append([]string{"restart"}, args...)...)
 
 	if len(args) == 0 {
 		fmt.Println(tui.InfoStyle.Render("Restarting all services..."))
@@ -49,7 +48,11 @@ func runRestart(_ *cobra.Command, args []string) error {
 		fmt.Println()
 		fmt.Println(tui.SuccessStyle.Render(fmt.Sprintf("✓ All services restarted in %s", time.Since(start).Round(time.Millisecond))))
 	} else {
-		for _, service := range args {
+		for _, arg := range args {
+			service, err := serviceArg(arg)
+			if err != nil {
+				return err
+			}
 			fmt.Printf("Restarting %s...\n", service)
 			if err := compose.Restart(ctx, service); err != nil {
 				fmt.Println(tui.ErrorStyle.Render(fmt.Sprintf("  ✗ Failed to restart %s: %v", service, err)))
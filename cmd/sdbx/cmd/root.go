@@ -2,18 +2,38 @@
 package cmd
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
+
+	"github.com/maiko/sdbx/internal/config"
+	"github.com/maiko/sdbx/internal/docker"
+	"github.com/maiko/sdbx/internal/i18n"
+	"github.com/maiko/sdbx/internal/problem"
+	"github.com/maiko/sdbx/internal/registry"
 )
 
 var (
-	cfgFile string
-	noTUI   bool
-	jsonOut bool
+	cfgFile      string
+	noTUI        bool
+	jsonOut      bool
+	ignoreCompat bool
+	lang         string
+	offline      bool
+	project      string
+
+	// projectErr is why the project of --project could not be selected,
+	// returned before any command runs
+	projectErr error
 )
 
 // rootCmd represents the base command when called without any subcommands
@@ -36,11 +56,75 @@ Get started:
   sdbx up       Start all services
   sdbx status   View live dashboard
   sdbx doctor   Run diagnostic checks`,
+	// Errors are printed by Execute, with the hint of their problem kind
+	SilenceErrors: true,
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		if projectErr != nil {
+			cmd.SilenceUsage = true
+		}
+		return projectErr
+	},
 }
 
 // Execute adds all child commands to the root command and sets flags appropriately.
 func Execute() error {
-	return rootCmd.Execute()
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// The first Ctrl+C cancels the command, which stops its docker and git
+	// processes and returns; a second one kills sdbx
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+
+	start := time.Now()
+	cmd, err := rootCmd.ExecuteContextC(ctx)
+	recordTelemetry(cmd, start, err)
+	switch {
+	case err == nil:
+	case ctx.Err() != nil && errors.Is(err, context.Canceled):
+		fmt.Fprintln(os.Stderr, "Interrupted")
+	default:
+		printError(os.Stderr, err)
+	}
+	return err
+}
+
+// printError prints the error a command failed with, followed by the hint
+// and documentation link of its problem kind. With --json the problem is
+// printed to stdout instead.
+func printError(w io.Writer, err error) {
+	if IsJSONOutput() {
+		_ = OutputJSON(map[string]interface{}{"error": problem.From(err, 0)})
+		return
+	}
+	fmt.Fprintln(w, "Error:", err)
+	if kind := problem.KindOf(err); kind != nil {
+		fmt.Fprintf(w, "\n  Hint: %s\n  Docs: %s\n", kind.Hint, kind.Doc())
+	}
+}
+
+// commandContext returns the context of a command, canceled on Ctrl+C, or a
+// background context when the command is not run through Execute
+func commandContext(cmd *cobra.Command) context.Context {
+	if cmd != nil && cmd.Context() != nil {
+		return cmd.Context()
+	}
+	return context.Background()
+}
+
+// projectCompose returns the compose project of projectDir, each command
+// limited by timeouts.compose (the default one when the configuration does
+// not load, so a broken project can still be stopped)
+func projectCompose(projectDir string) *docker.Compose {
+	compose := docker.NewCompose(projectDir)
+	compose.Timeout = config.DefaultComposeTimeout
+	compose.Offline = registry.Offline()
+	if cfg, err := config.Load(); err == nil {
+		compose.Timeout = cfg.Timeouts.ComposeTimeout()
+	}
+	return compose
 }
 
 func init() {
@@ -50,6 +134,10 @@ func init() {
 	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is .sdbx.yaml)")
 	rootCmd.PersistentFlags().BoolVar(&noTUI, "no-tui", false, "disable TUI, use plain text output")
 	rootCmd.PersistentFlags().BoolVar(&jsonOut, "json", false, "output in JSON format")
+	rootCmd.PersistentFlags().BoolVar(&ignoreCompat, "ignore-compat", false, "generate services whose definitions require a newer sdbx (minCliVersion)")
+	rootCmd.PersistentFlags().StringVar(&lang, "lang", "", "language of prompts and output: en, fr, de (default from SDBX_LANG or LANG)")
+	rootCmd.PersistentFlags().StringVarP(&project, "project", "P", "", "project to run the command on, by name (sdbx projects list) or path (default from SDBX_PROJECT, or the current directory)")
+	rootCmd.PersistentFlags().BoolVar(&offline, "offline", false, "never access the network: use cached sources and local images (also offline: true in .sdbx.yaml)")
 
 	// Bind flags to viper (panic on error as this indicates a programming bug)
 	if err := viper.BindPFlag("no-tui", rootCmd.PersistentFlags().Lookup("no-tui")); err != nil {
@@ -58,10 +146,15 @@ func init() {
 	if err := viper.BindPFlag("json", rootCmd.PersistentFlags().Lookup("json")); err != nil {
 		panic(fmt.Sprintf("failed to bind json flag: %v", err))
 	}
+	if err := viper.BindPFlag("lang", rootCmd.PersistentFlags().Lookup("lang")); err != nil {
+		panic(fmt.Sprintf("failed to bind lang flag: %v", err))
+	}
 }
 
 // initConfig reads in config file and ENV variables if set.
 func initConfig() {
+	projectErr = selectProject()
+
 	if cfgFile != "" {
 		viper.SetConfigFile(cfgFile)
 	} else {
@@ -77,6 +170,47 @@ func initConfig() {
 
 	// Read config file if it exists (errors are silently ignored)
 	_ = viper.ReadInConfig()
+
+	registry.SetIgnoreCompat(ignoreCompat)
+	// Not bound to the flag, so --offline is never saved to .sdbx.yaml
+	registry.SetOffline(offline || viper.GetBool("offline"))
+	setLanguage(viper.GetString("lang"))
+}
+
+// selectProject makes the project of --project or SDBX_PROJECT the working
+// directory, so commands and viper only see its .sdbx.yaml
+func selectProject() error {
+	value := project
+	if value == "" {
+		value = os.Getenv(config.ProjectEnv)
+	}
+	if value == "" {
+		return nil
+	}
+	workspace, err := config.LoadWorkspace(config.WorkspacePath())
+	if err != nil {
+		return err
+	}
+	dir, err := workspace.Resolve(value)
+	if errors.Is(err, config.ErrUnknownProject) {
+		return fmt.Errorf("%w\n\n  Try: sdbx projects list", err)
+	}
+	if err != nil {
+		return err
+	}
+	return os.Chdir(dir)
+}
+
+// setLanguage selects the language of --lang or SDBX_LANG, or the one of
+// the locale environment. An unsupported language falls back to English.
+func setLanguage(value string) {
+	if value == "" {
+		value = i18n.Detect()
+	}
+	if err := i18n.SetLanguage(value); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		_ = i18n.SetLanguage(i18n.DefaultLanguage)
+	}
 }
 
 // IsTUIEnabled returns true if TUI mode is enabled
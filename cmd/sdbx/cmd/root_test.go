@@ -0,0 +1,28 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/maiko/sdbx/internal/problem"
+)
+
+func TestPrintError(t *testing.T) {
+	var buf bytes.Buffer
+	printError(&buf, errors.New("failed to load config"))
+	if got := buf.String(); got != "Error: failed to load config\n" {
+		t.Errorf("printError() = %q", got)
+	}
+
+	buf.Reset()
+	err := fmt.Errorf("failed to start services: %w", problem.Wrap(problem.ErrDockerDaemon, nil, "docker compose up failed"))
+	printError(&buf, err)
+	for _, want := range []string{"Error: failed to start services", "Hint: " + problem.ErrDockerDaemon.Hint, "Docs: " + problem.ErrDockerDaemon.Doc()} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("printError() = %q, want %q", buf.String(), want)
+		}
+	}
+}
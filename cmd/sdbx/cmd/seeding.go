@@ -0,0 +1,157 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/maiko/sdbx/internal/backup"
+	"github.com/maiko/sdbx/internal/config"
+	"github.com/maiko/sdbx/internal/seeding"
+	"github.com/maiko/sdbx/internal/tui"
+)
+
+var seedingCmd = &cobra.Command{
+	Use:   "seeding",
+	Short: "Enforce per-category seeding rules on qBittorrent",
+	Long: `Apply the seeding rules of .sdbx.yaml to qBittorrent.
+
+Completed torrents whose category rule reached its ratio or seed time
+limit are paused, removed, or removed with their files. 'sdbx monitor' and
+the web UI (server mode) enforce the rules every seeding.interval (15m by
+default) and notify the torrents cleaned up; these commands run them on
+demand and show what was done.
+
+Example rules:
+  seeding:
+    rules:
+      - category: movies
+        ratio: 2.0
+        seed_time: 336h
+        action: remove
+      - category: "*"
+        seed_time: 168h
+
+Examples:
+  sdbx seeding run --dry-run   # Show which torrents the rules apply to
+  sdbx seeding run             # Apply the rules now
+  sdbx seeding report          # Torrents cleaned up so far`,
+}
+
+var seedingRunCmd = &cobra.Command{
+	Use:   "run",
+	Short: "Apply the seeding rules now",
+	Args:  cobra.NoArgs,
+	RunE:  runSeedingRun,
+}
+
+var seedingReportCmd = &cobra.Command{
+	Use:   "report",
+	Short: "Show the torrents cleaned up by the seeding rules",
+	Args:  cobra.NoArgs,
+	RunE:  runSeedingReport,
+}
+
+var (
+	seedingDryRun bool
+	seedingLimit  int
+)
+
+func init() {
+	rootCmd.AddCommand(seedingCmd)
+	seedingCmd.AddCommand(seedingRunCmd)
+	seedingCmd.AddCommand(seedingReportCmd)
+
+	seedingRunCmd.Flags().BoolVar(&seedingDryRun, "dry-run", false, "Show the actions without applying them")
+	seedingReportCmd.Flags().IntVarP(&seedingLimit, "limit", "n", 20, "Number of actions shown")
+}
+
+func runSeedingRun(cmd *cobra.Command, _ []string) error {
+	ctx := commandContext(cmd)
+
+	projectDir, err := config.ProjectDir()
+	if err != nil {
+		return err
+	}
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	if !cfg.Seeding.IsEnabled() {
+		return fmt.Errorf("no seeding rules configured\n\n  Add rules under seeding.rules in .sdbx.yaml")
+	}
+
+	client, err := seeding.NewClient(projectDir, cfg, false)
+	if err != nil {
+		return err
+	}
+	actions, runErr := seeding.NewEnforcer(projectDir, cfg, client).Run(ctx, seedingDryRun)
+
+	if IsJSONOutput() {
+		if err := OutputJSON(map[string]interface{}{"dry_run": seedingDryRun, "actions": actions}); err != nil {
+			return err
+		}
+		return runErr
+	}
+
+	if len(actions) == 0 && runErr == nil {
+		fmt.Println(tui.MutedStyle.Render("No torrent reached its seeding limits"))
+		return nil
+	}
+	if len(actions) > 0 {
+		fmt.Println(renderSeedingActions(actions))
+		if seedingDryRun {
+			fmt.Println(tui.MutedStyle.Render(fmt.Sprintf("Dry run: %d torrents would be cleaned up", len(actions))))
+		} else {
+			fmt.Println(tui.SuccessStyle.Render(seeding.Summary(actions).Title))
+		}
+	}
+	if runErr != nil {
+		return fmt.Errorf("%w\n\n  Try: sdbx status", runErr)
+	}
+	return nil
+}
+
+func runSeedingReport(_ *cobra.Command, _ []string) error {
+	projectDir, err := config.ProjectDir()
+	if err != nil {
+		return err
+	}
+	report, err := seeding.LoadReport(projectDir)
+	if err != nil {
+		return err
+	}
+	if seedingLimit > 0 && len(report.Actions) > seedingLimit {
+		report.Actions = report.Actions[:seedingLimit]
+	}
+
+	if IsJSONOutput() {
+		return OutputJSON(report)
+	}
+
+	if report.LastRun.IsZero() {
+		fmt.Println(tui.MutedStyle.Render("Seeding rules have not run yet"))
+		return nil
+	}
+	fmt.Printf("Last run: %s\n\n", backup.FormatAge(report.LastRun))
+	if len(report.Actions) == 0 {
+		fmt.Println(tui.MutedStyle.Render("No torrent cleaned up yet"))
+		return nil
+	}
+	fmt.Println(renderSeedingActions(report.Actions))
+	return nil
+}
+
+// renderSeedingActions renders actions as a table
+func renderSeedingActions(actions []seeding.Action) string {
+	table := tui.NewTable("Torrent", "Category", "Action", "Reason", "Size", "When")
+	for _, a := range actions {
+		action := a.Action
+		if a.Error != "" {
+			action = tui.ErrorStyle.Render(action + " failed")
+		}
+		table.AddRow(a.Name, a.Category, action, a.Reason, backup.FormatBytes(a.Size), a.At.Local().Format(time.DateTime))
+	}
+	return table.Render()
+}
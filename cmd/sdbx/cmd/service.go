@@ -0,0 +1,378 @@
+package cmd
+
+import (
+	"cmp"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/maiko/sdbx/internal/config"
+	"github.com/maiko/sdbx/internal/generator"
+	"github.com/maiko/sdbx/internal/registry"
+	"github.com/maiko/sdbx/internal/tui"
+)
+
+var serviceCmd = &cobra.Command{
+	Use:   "service",
+	Short: "Manage individual SDBX services",
+	Long: `Manage individual SDBX services.
+
+Examples:
+  sdbx service maintenance sonarr on          # Show a maintenance page for Sonarr
+  sdbx service maintenance sonarr on --stop   # Also stop the container
+  sdbx service maintenance sonarr off         # Route traffic back to Sonarr
+  sdbx service test ./addons/sonarr           # Run a definition's test fixtures
+  sdbx service publish sonarr --source my-fork --pr`,
+}
+
+var serviceMaintenanceCmd = &cobra.Command{
+	Use:   "maintenance <service> on|off",
+	Short: "Toggle maintenance mode for a service",
+	Long: `Toggle maintenance mode for a routed service.
+
+While in maintenance, Traefik answers the service's URL with a generated
+"service under maintenance" page (HTTP 503). The state is stored in
+.sdbx.yaml, so it survives 'sdbx regenerate'.
+
+With --stop, the container is stopped when maintenance starts. Turning
+maintenance off always starts the container again.`,
+	Args:      cobra.ExactArgs(2),
+	ValidArgs: []string{"on", "off"},
+	RunE:      runServiceMaintenance,
+}
+
+var serviceTestCmd = &cobra.Command{
+	Use:   "test [path]",
+	Short: "Run the test fixtures of service definitions",
+	Long: `Run the test fixtures of the service definitions under path (default:
+the current directory), a service directory or a source repository.
+
+Each YAML file in a definition's tests/ directory is a fixture: a
+.sdbx.yaml snippet under config, and under compose the properties the
+service's compose block must have with it. Only the listed properties are
+compared; the definition hash label is left out. Services the definition
+depends on come from the source under test, then the embedded ones.
+
+With --update, failing fixtures are rewritten with the rendered
+properties (all of them when compose is empty), keeping comments.
+
+Examples:
+  sdbx service test                    # Every fixture of the repository
+  sdbx service test addons/sonarr      # One service
+  sdbx service test --update           # Accept the rendered output`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runServiceTest,
+}
+
+var servicePublishCmd = &cobra.Command{
+	Use:   "publish <service|path> --source <name>",
+	Short: "Contribute a local service definition to a git source",
+	Long: `Copy a service definition from a local source (or a directory holding a
+service.yaml) into the checkout of a git source and commit it on a new
+branch, sdbx/<service>-<version>.
+
+The definition must pass validation and its test fixtures, if it has a
+tests/ directory. Its metadata.version is bumped past the source's: the
+local version is kept when newer, otherwise the source's patch number is
+incremented. Hidden files are left out.
+
+With --push the branch is pushed to the source's remote. With --pr it is
+pushed and a pull request is opened through the GitHub API, against the
+repository the source was forked from if any, using the token in
+GITHUB_TOKEN or GH_TOKEN.
+
+Examples:
+  sdbx service publish sonarr --source my-fork          # Commit locally
+  sdbx service publish ./sonarr --source my-fork --push
+  sdbx service publish sonarr --source my-fork --pr`,
+	Args: cobra.ExactArgs(1),
+	RunE: runServicePublish,
+}
+
+var (
+	serviceMaintenanceStop bool
+	serviceTestUpdate      bool
+	servicePublishSource   string
+	servicePublishPush     bool
+	servicePublishPR       bool
+)
+
+func init() {
+	rootCmd.AddCommand(serviceCmd)
+	serviceCmd.AddCommand(serviceMaintenanceCmd)
+	serviceCmd.AddCommand(serviceTestCmd)
+	serviceCmd.AddCommand(servicePublishCmd)
+
+	serviceMaintenanceCmd.Flags().BoolVar(&serviceMaintenanceStop, "stop", false, "Stop the container while in maintenance")
+	serviceTestCmd.Flags().BoolVar(&serviceTestUpdate, "update", false, "Rewrite failing fixtures with the rendered properties")
+	servicePublishCmd.Flags().StringVar(&servicePublishSource, "source", "", "Git source to publish to (required)")
+	servicePublishCmd.Flags().BoolVar(&servicePublishPush, "push", false, "Push the branch to the source's remote")
+	servicePublishCmd.Flags().BoolVar(&servicePublishPR, "pr", false, "Push the branch and open a GitHub pull request")
+	_ = servicePublishCmd.MarkFlagRequired("source")
+}
+
+func runServiceMaintenance(cmd *cobra.Command, args []string) error {
+	var enable bool
+	switch args[1] {
+	case "on":
+		enable = true
+	case "off":
+		enable = false
+	default:
+		return fmt.Errorf("invalid state %q: must be 'on' or 'off'", args[1])
+	}
+
+	projectDir, err := config.ProjectDir()
+	if err != nil {
+		return err
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w\n\n  Try: sdbx doctor", err)
+	}
+	serviceName, err := cfg.ServiceName(args[0])
+	if err != nil {
+		return err
+	}
+
+	ctx := commandContext(cmd)
+
+	// Only routed services have a URL to replace
+	reg, err := getRegistry()
+	if err != nil {
+		return err
+	}
+	// Named instances are routed like the service they copy
+	def, _, err := reg.GetService(ctx, cmp.Or(cfg.InstanceOf(serviceName), serviceName))
+	if err != nil {
+		return fmt.Errorf("service not found: %s\n\n  Try: sdbx addon search", serviceName)
+	}
+	if !def.Routing.Enabled {
+		return fmt.Errorf("%s has no web route, maintenance mode only applies to routed services", serviceName)
+	}
+
+	if cfg.IsInMaintenance(serviceName) == enable {
+		if IsJSONOutput() {
+			return OutputJSON(map[string]interface{}{
+				"service":     serviceName,
+				"maintenance": enable,
+				"changed":     false,
+			})
+		}
+		fmt.Printf("%s %s is already %s\n", tui.IconInfo, serviceName, maintenanceLabel(enable))
+		return nil
+	}
+
+	cfg.SetMaintenance(serviceName, enable)
+	if err := cfg.Save(filepath.Join(projectDir, ".sdbx.yaml")); err != nil {
+		return fmt.Errorf("failed to save config: %w", err)
+	}
+
+	// Regenerate so Traefik picks up the maintenance router via the file provider
+	gen := generator.NewGeneratorWithRegistry(cfg, projectDir, reg)
+	if err := gen.GenerateContext(ctx); err != nil {
+		return fmt.Errorf("failed to regenerate project files: %w\n\n  Try: sdbx regenerate", err)
+	}
+	printSkipped(gen.Skipped)
+
+	compose := projectCompose(projectDir)
+	if enable {
+		if err := compose.UpService(ctx, "static"); err != nil {
+			return fmt.Errorf("failed to start maintenance page server: %w\n\n  Try: sdbx up", err)
+		}
+		if serviceMaintenanceStop {
+			if err := compose.Stop(ctx, serviceName); err != nil {
+				return fmt.Errorf("failed to stop %s: %w", serviceName, err)
+			}
+		}
+	} else {
+		if err := compose.Start(ctx, serviceName); err != nil {
+			return fmt.Errorf("failed to start %s: %w", serviceName, err)
+		}
+	}
+
+	if IsJSONOutput() {
+		return OutputJSON(map[string]interface{}{
+			"service":     serviceName,
+			"maintenance": enable,
+			"changed":     true,
+		})
+	}
+
+	fmt.Println(tui.SuccessStyle.Render(fmt.Sprintf("%s %s is now %s", tui.IconSuccess, serviceName, maintenanceLabel(enable))))
+	if enable {
+		fmt.Printf("  %s %s shows the maintenance page\n", tui.IconArrow, cfg.GetServiceURL(serviceName))
+	}
+	return nil
+}
+
+func runServiceTest(cmd *cobra.Command, args []string) error {
+	path := "."
+	if len(args) > 0 {
+		path = args[0]
+	}
+	if info, err := os.Stat(path); err != nil {
+		return err
+	} else if !info.IsDir() {
+		path = filepath.Dir(path)
+	}
+
+	results, err := generator.RunFixtures(commandContext(cmd), path, loadSourceConfig().Cache, serviceTestUpdate)
+	if err != nil {
+		return fmt.Errorf("failed to run fixtures: %w", err)
+	}
+
+	failed := 0
+	for _, r := range results {
+		if !r.Passed() {
+			failed++
+		}
+	}
+
+	if IsJSONOutput() {
+		if err := OutputJSON(results); err != nil {
+			return err
+		}
+	} else {
+		if len(results) == 0 {
+			fmt.Printf("%s No fixtures found: add YAML files to the %s/ directory next to a service.yaml\n", tui.IconInfo, generator.FixturesDir)
+			return nil
+		}
+		for _, r := range results {
+			name := r.Service + "/" + strings.TrimSuffix(filepath.Base(r.Fixture), ".yaml")
+			switch {
+			case r.Updated:
+				fmt.Printf("%s %s %s\n", tui.WarningStyle.Render(tui.IconWarning), name, tui.MutedStyle.Render("(updated)"))
+			case r.Passed():
+				fmt.Printf("%s %s\n", tui.SuccessStyle.Render(tui.IconSuccess), name)
+			case r.Error != "":
+				fmt.Printf("%s %s: %s\n", tui.ErrorStyle.Render(tui.IconError), name, r.Error)
+			default:
+				fmt.Printf("%s %s\n%s", tui.ErrorStyle.Render(tui.IconError), name, r.Diff)
+			}
+		}
+		fmt.Println()
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d fixtures failed\n\n  Try: sdbx service test --update, if the new output is intended", failed, len(results))
+	}
+	if !IsJSONOutput() {
+		fmt.Println(tui.SuccessStyle.Render(fmt.Sprintf("%s %d fixtures passed", tui.IconSuccess, len(results))))
+	}
+	return nil
+}
+
+func runServicePublish(cmd *cobra.Command, args []string) error {
+	ctx := commandContext(cmd)
+	token := cmp.Or(os.Getenv("GITHUB_TOKEN"), os.Getenv("GH_TOKEN"))
+	if servicePublishPR && token == "" {
+		return fmt.Errorf("--pr needs a GitHub token in GITHUB_TOKEN or GH_TOKEN")
+	}
+
+	reg, err := getRegistry()
+	if err != nil {
+		return err
+	}
+	provider, err := reg.GetSource(servicePublishSource)
+	if err != nil {
+		return fmt.Errorf("%w\n\n  Try: sdbx source list", err)
+	}
+	source, ok := provider.(*registry.GitSource)
+	if !ok {
+		return fmt.Errorf("source %s is not a git source", servicePublishSource)
+	}
+	dir, err := publishServiceDir(reg, args[0])
+	if err != nil {
+		return err
+	}
+
+	// Lint: the definition must validate and pass its fixtures
+	def, err := registry.NewLoader().LoadServiceDefinition(filepath.Join(dir, "service.yaml"))
+	if err != nil {
+		return err
+	}
+	problems := reg.Validate(def)
+	if !IsJSONOutput() {
+		for _, w := range registry.FilterBySeverity(problems, "warning") {
+			fmt.Printf("%s %s\n", tui.WarningStyle.Render(tui.IconWarning), w.Error())
+		}
+	}
+	if registry.HasErrors(problems) {
+		var msgs []string
+		for _, e := range registry.FilterBySeverity(problems, "error") {
+			msgs = append(msgs, "  "+e.Error())
+		}
+		return fmt.Errorf("%s is not valid:\n%s", def.Metadata.Name, strings.Join(msgs, "\n"))
+	}
+	results, err := generator.RunFixtures(ctx, dir, loadSourceConfig().Cache, false)
+	if err != nil {
+		return fmt.Errorf("failed to run fixtures: %w", err)
+	}
+	for _, r := range results {
+		if !r.Passed() {
+			return fmt.Errorf("fixture %s of %s fails\n\n  Try: sdbx service test %s", filepath.Base(r.Fixture), r.Service, dir)
+		}
+	}
+
+	pub, err := source.Publish(ctx, dir, servicePublishPush || servicePublishPR)
+	if err != nil {
+		return fmt.Errorf("failed to publish %s: %w", def.Metadata.Name, err)
+	}
+	var prURL string
+	if servicePublishPR {
+		if prURL, err = source.OpenPullRequest(ctx, pub, token); err != nil {
+			return fmt.Errorf("pushed branch %s but failed to open a pull request: %w", pub.Branch, err)
+		}
+	}
+
+	if IsJSONOutput() {
+		return OutputJSON(struct {
+			*registry.Publication
+			PullRequest string `json:"pull_request,omitempty"`
+		}{pub, prURL})
+	}
+	version := pub.Version
+	if pub.Previous != "" {
+		version = pub.Previous + " → " + pub.Version
+	}
+	fmt.Println(tui.SuccessStyle.Render(fmt.Sprintf("%s Committed %s %s to %s on branch %s", tui.IconSuccess, pub.Service, version, pub.Source, pub.Branch)))
+	fmt.Println(tui.MutedStyle.Render(fmt.Sprintf("  %s (%s, %d fixtures passed)", pub.Dir, pub.Commit[:min(12, len(pub.Commit))], len(results))))
+	switch {
+	case prURL != "":
+		fmt.Printf("%s Pull request: %s\n", tui.IconInfo, prURL)
+	case pub.Pushed:
+		fmt.Printf("%s Pushed %s\n", tui.IconInfo, pub.Branch)
+	default:
+		fmt.Println(tui.MutedStyle.Render(fmt.Sprintf("Push it with: git -C %s push origin %s", pub.Repo, pub.Branch)))
+	}
+	return nil
+}
+
+// publishServiceDir returns the directory of the definition to publish: arg
+// when it is a service directory, otherwise the service's directory in a
+// local source
+func publishServiceDir(reg *registry.Registry, arg string) (string, error) {
+	if _, err := os.Stat(filepath.Join(arg, "service.yaml")); err == nil {
+		return arg, nil
+	}
+	for _, src := range reg.Sources() {
+		local, ok := src.(*registry.LocalSource)
+		if ok && local.HasService(arg) {
+			return filepath.Dir(local.GetServicePath(arg)), nil
+		}
+	}
+	return "", fmt.Errorf("no local source has a definition of %s\n\n  Try: sdbx service publish ./path/to/%s --source %s", arg, arg, servicePublishSource)
+}
+
+// maintenanceLabel describes a maintenance state for user-facing messages
+func maintenanceLabel(enabled bool) string {
+	if enabled {
+		return "in maintenance"
+	}
+	return "out of maintenance"
+}
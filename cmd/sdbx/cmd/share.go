@@ -0,0 +1,195 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/maiko/sdbx/internal/auth"
+	"github.com/maiko/sdbx/internal/config"
+	"github.com/maiko/sdbx/internal/generator"
+	"github.com/maiko/sdbx/internal/registry"
+	"github.com/maiko/sdbx/internal/tui"
+)
+
+var shareCmd = &cobra.Command{
+	Use:   "share",
+	Short: "Share a service temporarily without creating an account",
+	Long: `Create time-limited links that open a service without logging in.
+
+Opening a link stores its token in a cookie on the service's URL; until
+the link expires, requests carrying the cookie skip Authelia (or basic
+auth) and are checked by the web UI instead. Tokens are signed with
+secrets/share_key.txt and their records kept in .sdbx.shares.yaml, so a
+link can be revoked before it expires. Needs the web UI (sdbx-webui).
+
+Examples:
+  sdbx share create overseerr --expires 72h   # Print a link valid for 3 days
+  sdbx share list                              # List links
+  sdbx share revoke 3f9a1c0b7d2e               # Revoke a link`,
+}
+
+var shareCreateCmd = &cobra.Command{
+	Use:   "create <service>",
+	Short: "Create a share link and print it",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runShareCreate,
+}
+
+var shareListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List share links",
+	Args:  cobra.NoArgs,
+	RunE:  runShareList,
+}
+
+var shareRevokeCmd = &cobra.Command{
+	Use:     "revoke <id>",
+	Aliases: []string{"rm"},
+	Short:   "Revoke a share link",
+	Args:    cobra.ExactArgs(1),
+	RunE:    runShareRevoke,
+}
+
+var shareExpires string
+
+func init() {
+	rootCmd.AddCommand(shareCmd)
+	shareCmd.AddCommand(shareCreateCmd)
+	shareCmd.AddCommand(shareListCmd)
+	shareCmd.AddCommand(shareRevokeCmd)
+
+	shareCreateCmd.Flags().StringVar(&shareExpires, "expires", "72h", "Lifetime of the link (e.g. 72h or 7d)")
+}
+
+func runShareCreate(cmd *cobra.Command, args []string) error {
+	expiry, err := auth.ParseExpiry(shareExpires)
+	if err != nil {
+		return err
+	}
+	if expiry == 0 {
+		return fmt.Errorf("share links must expire (e.g. --expires 72h)")
+	}
+
+	projectDir, err := config.ProjectDir()
+	if err != nil {
+		return err
+	}
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w\n\n  Try: sdbx doctor", err)
+	}
+	serviceName, err := cfg.ServiceName(args[0])
+	if err != nil {
+		return err
+	}
+
+	ctx := commandContext(cmd)
+	reg, err := getRegistry()
+	if err != nil {
+		return err
+	}
+	graph, err := reg.Resolve(ctx, cfg)
+	if err != nil {
+		return fmt.Errorf("failed to resolve services: %w", err)
+	}
+	resolved, ok := graph.Services[serviceName]
+	switch {
+	case !ok || !resolved.Enabled:
+		return fmt.Errorf("service %s is not enabled\n\n  Try: sdbx addon list", serviceName)
+	case !resolved.FinalDefinition.Routing.Enabled:
+		return fmt.Errorf("%s has no web route to share", serviceName)
+	case resolved.FinalDefinition.Routing.Auth.Bypass || !resolved.FinalDefinition.Routing.Auth.Required:
+		return fmt.Errorf("%s does not require a login, share %s directly", serviceName, cfg.GetServiceURL(serviceName))
+	}
+
+	store := auth.NewShareStore(projectDir)
+	token, share, err := store.Create(serviceName, expiry)
+	if err != nil {
+		return err
+	}
+	if err := regenerateShares(ctx, cfg, projectDir, reg); err != nil {
+		return err
+	}
+
+	link := cfg.GetServiceURL(serviceName) + auth.SharePath + token
+	if IsJSONOutput() {
+		return OutputJSON(struct {
+			auth.Share
+			URL string `json:"url"`
+		}{share, link})
+	}
+
+	fmt.Println(tui.SuccessStyle.Render(fmt.Sprintf("%s Shared %s until %s (id %s)", tui.IconSuccess, serviceName, share.Expires.Local().Format("2006-01-02 15:04"), share.ID)))
+	fmt.Println()
+	fmt.Println("  " + link)
+	fmt.Println()
+	fmt.Println(tui.MutedStyle.Render("Anyone with this link can use " + serviceName + " until it expires. Revoke it with: sdbx share revoke " + share.ID))
+	return nil
+}
+
+func runShareList(_ *cobra.Command, _ []string) error {
+	projectDir, err := config.ProjectDir()
+	if err != nil {
+		return err
+	}
+	shares, err := auth.NewShareStore(projectDir).List()
+	if err != nil {
+		return err
+	}
+
+	if IsJSONOutput() {
+		return OutputJSON(shares)
+	}
+
+	table := tui.NewTable("ID", "Service", "Created", "Expires")
+	now := time.Now()
+	for _, s := range shares {
+		expires := s.Expires.Local().Format("2006-01-02 15:04")
+		if s.Expired(now) {
+			expires = tui.ErrorStyle.Render(expires + " (expired)")
+		}
+		table.AddRow(s.ID, s.Service, s.Created.Local().Format("2006-01-02 15:04"), expires)
+	}
+	fmt.Println(table.Render())
+	return nil
+}
+
+func runShareRevoke(cmd *cobra.Command, args []string) error {
+	projectDir, err := config.ProjectDir()
+	if err != nil {
+		return err
+	}
+	if err := auth.NewShareStore(projectDir).Revoke(args[0]); err != nil {
+		if errors.Is(err, auth.ErrShareNotFound) {
+			return fmt.Errorf("share link %s not found\n\n  Try: sdbx share list", args[0])
+		}
+		return err
+	}
+	fmt.Println(tui.SuccessStyle.Render(fmt.Sprintf("✓ Revoked share link %s", args[0])))
+
+	// The web UI refuses the link already; drop its router too
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w\n\n  Try: sdbx regenerate", err)
+	}
+	reg, err := getRegistry()
+	if err != nil {
+		return err
+	}
+	return regenerateShares(commandContext(cmd), cfg, projectDir, reg)
+}
+
+// regenerateShares regenerates the project so Traefik picks up the share
+// routers via the file provider
+func regenerateShares(ctx context.Context, cfg *config.Config, projectDir string, reg *registry.Registry) error {
+	gen := generator.NewGeneratorWithRegistry(cfg, projectDir, reg)
+	if err := gen.GenerateContext(ctx); err != nil {
+		return fmt.Errorf("failed to regenerate project files: %w\n\n  Try: sdbx regenerate", err)
+	}
+	printSkipped(gen.Skipped)
+	return nil
+}
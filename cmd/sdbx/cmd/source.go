@@ -3,11 +3,16 @@ package cmd
 import (
 	"context"
 	"fmt"
+	"maps"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
 
 	"github.com/spf13/cobra"
 
+	"github.com/maiko/sdbx/internal/config"
+	"github.com/maiko/sdbx/internal/netproxy"
 	"github.com/maiko/sdbx/internal/registry"
 	"github.com/maiko/sdbx/internal/tui"
 )
@@ -24,6 +29,8 @@ Examples:
   sdbx source list                           # List all configured sources
   sdbx source add community https://github.com/sdbx-community/services.git
   sdbx source update                         # Update all sources
+  sdbx source checkout official v1.4.0       # Track a release tag
+  sdbx source refresh-embedded               # Refresh the built-in definitions
   sdbx source remove community               # Remove a source`,
 }
 
@@ -35,13 +42,21 @@ var sourceListCmd = &cobra.Command{
 
 var sourceAddCmd = &cobra.Command{
 	Use:   "add <name> <url>",
-	Short: "Add a new Git source",
+	Short: "Add a new Git or archive source",
 	Long: `Add a new Git repository as a service definition source.
 
+A URL or path ending in .tar.gz, .tgz or .zip adds an archive source
+instead, for definitions published as a bundle (e.g. by CI) rather than a
+Git repository. Archives are downloaded into the source cache and
+revalidated with their ETag on update; --sha256 pins the archive's checksum.
+
 Examples:
   sdbx source add community https://github.com/sdbx-community/services.git
   sdbx source add mycompany git@github.com:mycompany/sdbx-services.git --priority 50
-  sdbx source add internal https://internal.example.com/services.git --branch develop`,
+  sdbx source add internal https://internal.example.com/services.git --branch develop
+  sdbx source add stable https://github.com/sdbx-community/services.git --ref v2.0.0
+  sdbx source add ci https://ci.example.com/artifacts/services.tar.gz --sha256 9f86d081...
+  sdbx source add offline ~/Downloads/services.zip`,
 	Args: cobra.ExactArgs(2),
 	RunE: runSourceAdd,
 }
@@ -58,6 +73,11 @@ var sourceUpdateCmd = &cobra.Command{
 	Short: "Update sources from remote",
 	Long: `Update Git sources by pulling latest changes.
 
+In a project, the services whose definitions changed since .sdbx.lock
+(or since the previous update without a lock file) are listed with the
+lines added to their CHANGELOG.md and the commits touching them, before
+offering to refresh them in .sdbx.lock.
+
 Examples:
   sdbx source update          # Update all sources
   sdbx source update official # Update specific source`,
@@ -72,11 +92,53 @@ var sourceInfoCmd = &cobra.Command{
 	RunE:  runSourceInfo,
 }
 
+var sourceCheckoutCmd = &cobra.Command{
+	Use:   "checkout <name> <ref>",
+	Short: "Track a tag, commit or branch of a Git source",
+	Long: `Switch a Git source to a tag, a commit or a branch of its repository.
+
+Tags and commits are checked out as they are: 'sdbx source update' keeps
+them and only follows a tag that was moved. Production boxes can track
+release tags of a service repository this way instead of its main branch.
+Checking out a branch of the remote follows it again.
+
+The choice is saved as the source's ref (or branch) in sources.yaml.
+
+Examples:
+  sdbx source checkout official v1.4.0     # Release tag
+  sdbx source checkout official 3f2a9c1    # Specific commit
+  sdbx source checkout official main       # Follow the main branch again`,
+	Args: cobra.ExactArgs(2),
+	RunE: runSourceCheckout,
+}
+
+var sourceRefreshEmbeddedCmd = &cobra.Command{
+	Use:   "refresh-embedded",
+	Short: "Refresh the built-in service definitions from this version's release",
+	Long: `Download the service definitions released with this version of sdbx.
+
+The embedded source is the fallback used when Git sources are unreachable,
+and is compiled into the binary. This command downloads sdbx-services.tar.gz
+from the GitHub release of the running version, verifies it against the
+release's checksums.txt, and keeps it in the source cache, where it is
+preferred over the compiled-in copy. Upgrading sdbx goes back to the
+compiled-in definitions until the command is run again.
+
+Examples:
+  sdbx source refresh-embedded
+  sdbx source info embedded     # Show which copy is in use`,
+	Args: cobra.NoArgs,
+	RunE: runSourceRefreshEmbedded,
+}
+
 // Flags
 var (
 	sourcePriority int
 	sourceBranch   string
+	sourceRef      string
+	sourceSHA256   string
 	sourceSSHKey   string
+	sourceProxy    string
 )
 
 func init() {
@@ -86,11 +148,16 @@ func init() {
 	sourceCmd.AddCommand(sourceRemoveCmd)
 	sourceCmd.AddCommand(sourceUpdateCmd)
 	sourceCmd.AddCommand(sourceInfoCmd)
+	sourceCmd.AddCommand(sourceCheckoutCmd)
+	sourceCmd.AddCommand(sourceRefreshEmbeddedCmd)
 
 	// Add flags
 	sourceAddCmd.Flags().IntVarP(&sourcePriority, "priority", "p", 10, "Source priority (higher = checked first)")
 	sourceAddCmd.Flags().StringVarP(&sourceBranch, "branch", "b", "main", "Git branch to use")
+	sourceAddCmd.Flags().StringVar(&sourceRef, "ref", "", "Tag or commit to check out instead of following the branch")
+	sourceAddCmd.Flags().StringVar(&sourceSHA256, "sha256", "", "SHA-256 the archive of an archive source must match")
 	sourceAddCmd.Flags().StringVar(&sourceSSHKey, "ssh-key", "", "Path to SSH key for private repos")
+	sourceAddCmd.Flags().StringVar(&sourceProxy, "proxy", "", "Proxy URL to fetch the source through (http, https, socks5), or 'direct'")
 }
 
 func runSourceList(_ *cobra.Command, _ []string) error {
@@ -137,6 +204,10 @@ func runSourceAdd(_ *cobra.Command, args []string) error {
 	name := args[0]
 	url := args[1]
 
+	if err := netproxy.Validate(sourceProxy); err != nil {
+		return err
+	}
+
 	cfg := loadSourceConfig()
 
 	// Check for duplicate
@@ -171,10 +242,25 @@ func runSourceAdd(_ *cobra.Command, args []string) error {
 		Type:     "git",
 		URL:      url,
 		Branch:   sourceBranch,
+		Ref:      sourceRef,
 		SSHKey:   sourceSSHKey,
+		Proxy:    sourceProxy,
 		Priority: sourcePriority,
 		Enabled:  true,
 	}
+	if registry.IsArchiveURL(url) {
+		newSource = registry.Source{
+			Name:     name,
+			Type:     "archive",
+			URL:      url,
+			SHA256:   sourceSHA256,
+			Proxy:    sourceProxy,
+			Priority: sourcePriority,
+			Enabled:  true,
+		}
+	} else if sourceSHA256 != "" {
+		return fmt.Errorf("--sha256 only applies to .tar.gz, .tgz and .zip archive sources")
+	}
 
 	cfg.Sources = append(cfg.Sources, newSource)
 
@@ -227,13 +313,24 @@ func runSourceRemove(_ *cobra.Command, args []string) error {
 	return nil
 }
 
-func runSourceUpdate(_ *cobra.Command, args []string) error {
+func runSourceUpdate(cmd *cobra.Command, args []string) error {
+	if err := registry.RequireNetwork("updating sources"); err != nil {
+		return err
+	}
 	reg, err := registry.NewWithDefaults()
 	if err != nil {
 		return fmt.Errorf("failed to initialize registry: %w", err)
 	}
 
-	ctx := context.Background()
+	ctx := commandContext(cmd)
+
+	// Commits of the Git sources before updating, to show what changed
+	previous := make(map[string]string)
+	for _, src := range reg.Sources() {
+		if gitSrc, ok := src.(*registry.GitSource); ok {
+			previous[src.Name()] = gitSrc.HeadCommit(ctx)
+		}
+	}
 
 	if len(args) == 1 {
 		// Update specific source
@@ -296,10 +393,202 @@ func runSourceUpdate(_ *cobra.Command, args []string) error {
 		fmt.Println()
 	}
 
+	return showDefinitionChanges(ctx, reg, previous)
+}
+
+// showDefinitionChanges lists the enabled services of the project whose
+// definitions changed in an updated Git source, then offers to refresh them
+// in the lock file. Services are compared with the commit of their source
+// in .sdbx.lock, or with the commit before the update.
+func showDefinitionChanges(ctx context.Context, reg *registry.Registry, previous map[string]string) error {
+	projectDir, err := config.ProjectDir()
+	if err != nil {
+		return nil // Not in a project: no enabled services
+	}
+	cfg, err := config.Load()
+	if err != nil {
+		return nil
+	}
+	lockPath := registry.GetLockFilePath(projectDir)
+	lock, lockErr := registry.NewLoader().LoadLockFile(lockPath)
+
+	graph, err := reg.Resolve(ctx, cfg)
+	if err != nil {
+		return fmt.Errorf("failed to resolve services: %w", err)
+	}
+
+	var changed []string
+	for _, src := range reg.Sources() {
+		gitSrc, ok := src.(*registry.GitSource)
+		if !ok {
+			continue
+		}
+		from := previous[src.Name()]
+		if lockErr == nil && lock.Sources[src.Name()].Commit != "" {
+			from = lock.Sources[src.Name()].Commit
+		}
+		to := gitSrc.GetCommit()
+		if from == "" || from == to {
+			continue
+		}
+
+		// Named instances share the definition of their base service
+		var names []string
+		for _, name := range slices.Sorted(maps.Keys(graph.Services)) {
+			if graph.Services[name].Source != src.Name() {
+				continue
+			}
+			if base := cfg.InstanceOf(name); base != "" {
+				name = base
+			}
+			if !slices.Contains(names, name) {
+				names = append(names, name)
+			}
+		}
+
+		changes, err := gitSrc.DefinitionChanges(ctx, from, to, names)
+		if err != nil {
+			fmt.Println(tui.WarningStyle.Render(fmt.Sprintf("Could not compare %s definitions: %v", src.Name(), err)))
+			continue
+		}
+		if len(changes) == 0 {
+			continue
+		}
+
+		fmt.Println(tui.RenderSection(fmt.Sprintf("  Definition changes in %s (%s..%s)", src.Name(), truncate(from, 7), truncate(to, 7))))
+		for _, change := range changes {
+			fmt.Printf("  %s %s\n", tui.IconPackage, change.Service)
+			for _, line := range change.Changelog {
+				fmt.Printf("      %s\n", line)
+			}
+			if len(change.Changelog) == 0 {
+				for _, subject := range change.Commits {
+					fmt.Printf("      %s\n", tui.MutedStyle.Render("• "+subject))
+				}
+			}
+			changed = append(changed, change.Service)
+		}
+		fmt.Println()
+	}
+
+	if len(changed) == 0 || lockErr != nil {
+		return nil
+	}
+
+	// Lock entries of named instances follow their base definition
+	for _, name := range slices.Sorted(maps.Keys(graph.Services)) {
+		if slices.Contains(changed, cfg.InstanceOf(name)) {
+			changed = append(changed, name)
+		}
+	}
+	if IsTUIEnabled() {
+		fmt.Print("Refresh these services in .sdbx.lock? [y/N] ")
+		var response string
+		_, _ = fmt.Scanln(&response)
+		if response == "y" || response == "Y" {
+			return runLockUpdate(nil, changed)
+		}
+	}
+	fmt.Printf("Run '%s' to accept the changes\n", tui.CommandStyle.Render("sdbx lock update "+strings.Join(changed, " ")))
 	return nil
 }
 
-func runSourceInfo(_ *cobra.Command, args []string) error {
+func runSourceCheckout(cmd *cobra.Command, args []string) error {
+	name, ref := args[0], args[1]
+
+	cfg := loadSourceConfig()
+	idx := slices.IndexFunc(cfg.Sources, func(src registry.Source) bool { return src.Name == name })
+	if idx < 0 {
+		return fmt.Errorf("source %s not found\n\n  Try: sdbx source list", name)
+	}
+	if cfg.Sources[idx].Type != "git" {
+		return fmt.Errorf("source %s is a %s source; only Git sources can check out a ref", name, cfg.Sources[idx].Type)
+	}
+
+	cache, err := registry.NewCacheFromConfig(cfg.Cache)
+	if err != nil {
+		return err
+	}
+	source := cfg.Sources[idx]
+	source.Proxy = cfg.SourceProxy(source)
+	src := registry.NewGitSource(source, cache)
+
+	var isBranch bool
+	checkout := func() error {
+		var err error
+		isBranch, err = src.Checkout(commandContext(cmd), ref)
+		return err
+	}
+	if IsTUIEnabled() {
+		err = tui.RunWithSpinner(fmt.Sprintf("Checking out %s of %s...", ref, name), checkout)
+	} else {
+		err = checkout()
+	}
+	if err != nil {
+		return fmt.Errorf("failed to check out %s: %w", ref, err)
+	}
+
+	if isBranch {
+		cfg.Sources[idx].Branch = ref
+		cfg.Sources[idx].Ref = ""
+	} else {
+		cfg.Sources[idx].Ref = ref
+	}
+	if err := saveSourceConfig(cfg); err != nil {
+		return err
+	}
+
+	if IsJSONOutput() {
+		return OutputJSON(map[string]interface{}{
+			"source": name,
+			"ref":    ref,
+			"branch": isBranch,
+			"commit": src.GetCommit(),
+		})
+	}
+	if isBranch {
+		fmt.Println(tui.SuccessStyle.Render(fmt.Sprintf("✓ %s follows branch %s (at %s)", name, ref, truncate(src.GetCommit(), 12))))
+	} else {
+		fmt.Println(tui.SuccessStyle.Render(fmt.Sprintf("✓ %s is checked out at %s (%s)", name, ref, truncate(src.GetCommit(), 12))))
+	}
+	fmt.Printf("Run '%s' to apply the definitions to the project\n", tui.CommandStyle.Render("sdbx lock update && sdbx regenerate"))
+	return nil
+}
+
+func runSourceRefreshEmbedded(cmd *cobra.Command, _ []string) error {
+	sources := loadSourceConfig()
+	cache, err := registry.NewCacheFromConfig(sources.Cache)
+	if err != nil {
+		return err
+	}
+	ctx := commandContext(cmd)
+
+	var bundle *registry.EmbeddedBundle
+	refresh := func() error {
+		var err error
+		bundle, err = registry.RefreshEmbeddedBundle(ctx, cache.Dir(), registry.DefaultReleaseURL, Version, sources.Proxy)
+		return err
+	}
+	if IsTUIEnabled() {
+		err = tui.RunWithSpinner(fmt.Sprintf("Downloading service definitions of sdbx %s...", Version), refresh)
+	} else {
+		err = refresh()
+	}
+	if err != nil {
+		return fmt.Errorf("failed to refresh embedded source: %w\n\n  Try: check your internet connection, or keep using the compiled-in definitions", err)
+	}
+
+	if IsJSONOutput() {
+		return OutputJSON(bundle)
+	}
+	fmt.Println(tui.SuccessStyle.Render(fmt.Sprintf("%s Embedded source refreshed from sdbx v%s", tui.IconSuccess, bundle.Version)))
+	fmt.Printf("  %s\n", tui.RenderKeyValue("Services", fmt.Sprintf("%d", bundle.Services)))
+	fmt.Printf("  %s\n", tui.RenderKeyValue("SHA-256", bundle.SHA256))
+	fmt.Printf("  %s\n", tui.RenderKeyValue("Path", bundle.Dir))
+	return nil
+}
+
+func runSourceInfo(cmd *cobra.Command, args []string) error {
 	name := args[0]
 
 	reg, err := registry.NewWithDefaults()
@@ -312,7 +601,7 @@ func runSourceInfo(_ *cobra.Command, args []string) error {
 		return err
 	}
 
-	ctx := context.Background()
+	ctx := commandContext(cmd)
 
 	fmt.Println()
 	fmt.Println(tui.TitleStyle.Render(tui.IconNetwork + " " + name))
@@ -332,7 +621,11 @@ func runSourceInfo(_ *cobra.Command, args []string) error {
 
 	if gitSrc, ok := src.(*registry.GitSource); ok {
 		fmt.Printf("  %s\n", tui.RenderKeyValue("URL", gitSrc.GetURL()))
-		fmt.Printf("  %s\n", tui.RenderKeyValue("Branch", gitSrc.GetBranch()))
+		if ref := gitSrc.GetRef(); ref != "" {
+			fmt.Printf("  %s\n", tui.RenderKeyValue("Ref", ref))
+		} else {
+			fmt.Printf("  %s\n", tui.RenderKeyValue("Branch", gitSrc.GetBranch()))
+		}
 		if commit := gitSrc.GetCommit(); commit != "" {
 			fmt.Printf("  %s\n", tui.RenderKeyValue("Commit", truncate(commit, 12)))
 		}
@@ -340,6 +633,26 @@ func runSourceInfo(_ *cobra.Command, args []string) error {
 			fmt.Printf("  %s\n", tui.RenderKeyValue("Updated", gitSrc.GetLastUpdated().Format("2006-01-02 15:04:05")))
 		}
 	}
+	if archive, ok := src.(*registry.ArchiveSource); ok {
+		fmt.Printf("  %s\n", tui.RenderKeyValue("URL", archive.GetURL()))
+		if pin := archive.GetSHA256(); pin != "" {
+			fmt.Printf("  %s\n", tui.RenderKeyValue("Pinned", "sha256:"+truncate(pin, 12)))
+		}
+		if commit := archive.GetCommit(); commit != "" {
+			fmt.Printf("  %s\n", tui.RenderKeyValue("Checksum", truncate(commit, 19)))
+		}
+		if !archive.GetLastUpdated().IsZero() {
+			fmt.Printf("  %s\n", tui.RenderKeyValue("Updated", archive.GetLastUpdated().Format("2006-01-02 15:04:05")))
+		}
+	}
+	if embedded, ok := src.(*registry.EmbeddedSource); ok {
+		if bundle := embedded.Bundle(); bundle != nil {
+			fmt.Printf("  %s\n", tui.RenderKeyValue("Bundle", "v"+bundle.Version+" (refreshed)"))
+			fmt.Printf("  %s\n", tui.RenderKeyValue("Updated", bundle.Downloaded.Local().Format("2006-01-02 15:04:05")))
+		} else {
+			fmt.Printf("  %s\n", tui.RenderKeyValue("Bundle", "compiled-in"))
+		}
+	}
 	fmt.Println()
 
 	// List services from this source
@@ -416,8 +729,7 @@ func saveSourceConfig(cfg *registry.SourceConfig) error {
 
 // getSourceConfigPath returns the path to sources.yaml
 func getSourceConfigPath() string {
-	home, _ := os.UserHomeDir()
-	return filepath.Join(home, ".config", "sdbx", "sources.yaml")
+	return registry.SourceConfigPath()
 }
 
 // truncate truncates a string to maxLen
@@ -2,13 +2,20 @@ package cmd
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io/fs"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/spf13/cobra"
 
 	"github.com/maiko/sdbx/internal/config"
 	"github.com/maiko/sdbx/internal/docker"
+	"github.com/maiko/sdbx/internal/doctor"
+	"github.com/maiko/sdbx/internal/health"
+	"github.com/maiko/sdbx/internal/i18n"
 	"github.com/maiko/sdbx/internal/registry"
 	"github.com/maiko/sdbx/internal/tui"
 )
@@ -21,22 +28,49 @@ var statusCmd = &cobra.Command{
 Shows:
   • Service name and health status
   • Container state (running/stopped)
-  • Service URLs
-  • VPN connection status`,
+  • Image tag, compared against .sdbx.lock when present
+  • Service URLs, probed over HTTP through Traefik (or the tunnel)
+  • Maintenance mode
+  • Services stuck in a restart loop
+  • VPN connection status
+
+Probes catch "container up but 502 via domain" problems. Use --no-probe
+to skip them.
+
+With --history, shows uptime, last failure and flapping services from the
+health history recorded by 'sdbx monitor' or the web UI in server mode.
+
+Examples:
+  sdbx status                     # Live status
+  sdbx status --history           # Uptime over the last 24 hours
+  sdbx status --history --since 168h`,
 	RunE: runStatus,
 }
 
+var (
+	statusNoProbe bool
+	statusHistory bool
+	statusSince   time.Duration
+)
+
 func init() {
 	rootCmd.AddCommand(statusCmd)
+	statusCmd.Flags().BoolVar(&statusNoProbe, "no-probe", false, "Skip HTTP probes of service URLs")
+	statusCmd.Flags().BoolVar(&statusHistory, "history", false, "Show uptime and flapping from the health history")
+	statusCmd.Flags().DurationVar(&statusSince, "since", 24*time.Hour, "History window for --history")
 }
 
-func runStatus(_ *cobra.Command, args []string) error {
+func runStatus(cmd *cobra.Command, args []string) error {
 	// Find project directory
 	projectDir, err := config.ProjectDir()
 	if err != nil {
 		return err
 	}
 
+	if statusHistory {
+		return runStatusHistory(projectDir)
+	}
+
 	// Load config
 	cfg, err := config.Load()
 	if err != nil {
@@ -44,7 +78,7 @@ func runStatus(_ *cobra.Command, args []string) error {
 	}
 
 	compose := docker.NewCompose(projectDir)
-	ctx := context.Background()
+	ctx := commandContext(cmd)
 
 	// Get service status
 	services, err := compose.PS(ctx)
@@ -52,6 +86,13 @@ func runStatus(_ *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to get service status: %w\n\n  Try: sdbx doctor", err)
 	}
 
+	// Restart loops (container state is best effort)
+	crashLoops, _ := compose.CrashLoops(ctx)
+	looping := make(map[string]docker.RestartState, len(crashLoops))
+	for _, loop := range crashLoops {
+		looping[loop.Service] = loop
+	}
+
 	// Get registry for service info
 	reg, _ := registry.NewWithDefaults()
 	serviceInfo := make(map[string]registry.ServiceInfo)
@@ -63,26 +104,65 @@ func runStatus(_ *cobra.Command, args []string) error {
 		}
 	}
 
+	// Locked images, if the project has a lock file
+	var lock *registry.LockFile
+	if registry.LockFileExists(projectDir) {
+		lock, _ = registry.NewLoader().LoadLockFile(registry.GetLockFilePath(projectDir))
+	}
+
+	// Per-service image and URL checks
+	images := make(map[string]imageStatus)
+	routed := make(map[string]string)
+	for _, svc := range services {
+		name := serviceName(svc)
+		images[name] = checkImage(ctx, compose, svc.Image, lock, name)
+		if info, ok := serviceInfo[name]; ok && info.HasWebUI && svc.Running {
+			routed[name] = cfg.GetServiceURL(name)
+		}
+	}
+	var probes map[string]doctor.ProbeResult
+	if !statusNoProbe {
+		probes = probeServices(ctx, cfg, routed)
+	}
+
+	// Outstanding post-install steps
+	var outstanding []registry.ChecklistItem
+	if checklist, err := loadChecklist(ctx, cfg, projectDir); err == nil {
+		outstanding = checklist.Outstanding()
+	}
+
 	// JSON output mode
 	if IsJSONOutput() {
-		// Enhance service data with hostnames
+		// Enhance service data with hostnames, lock state and probes
 		type ServiceWithHostname struct {
 			docker.Service
-			Hostname string `json:"hostname"`
+			Hostname    string              `json:"hostname"`
+			URL         string              `json:"url,omitempty"`
+			ImageStatus imageStatus         `json:"image_status"`
+			Probe       *doctor.ProbeResult `json:"probe,omitempty"`
+			Maintenance bool                `json:"maintenance,omitempty"`
 		}
 
 		enriched := make([]ServiceWithHostname, len(services))
 		for i, svc := range services {
-			name := extractServiceName(svc.Name)
+			name := serviceName(svc)
 			enriched[i] = ServiceWithHostname{
-				Service:  svc,
-				Hostname: fmt.Sprintf("sdbx-%s", name),
+				Service:     svc,
+				Hostname:    fmt.Sprintf("sdbx-%s", name),
+				URL:         routed[name],
+				ImageStatus: images[name],
+				Maintenance: cfg.IsInMaintenance(name),
+			}
+			if probe, ok := probes[name]; ok {
+				enriched[i].Probe = &probe
 			}
 		}
 
 		return OutputJSON(map[string]interface{}{
-			"domain":   cfg.Domain,
-			"services": enriched,
+			"domain":      cfg.Domain,
+			"services":    enriched,
+			"checklist":   outstanding,
+			"crash_loops": crashLoops,
 		})
 	}
 
@@ -95,53 +175,103 @@ func runStatus(_ *cobra.Command, args []string) error {
 	}
 
 	fmt.Println()
-	fmt.Println(tui.TitleStyle.Render("SDBX Status"))
-	fmt.Printf("  %s %s\n", tui.MutedStyle.Render("Domain:"), cfg.Domain)
-	fmt.Printf("  %s %s\n", tui.MutedStyle.Render("Mode:"), cfg.Expose.Mode)
+	fmt.Println(tui.TitleStyle.Render(i18n.T("SDBX Status")))
+	fmt.Printf("  %s %s\n", tui.MutedStyle.Render(i18n.T("Domain:")), cfg.Domain)
+	fmt.Printf("  %s %s\n", tui.MutedStyle.Render(i18n.T("Mode:")), cfg.Expose.Mode)
 	if cfg.VPNEnabled {
-		fmt.Printf("  %s %s\n", tui.MutedStyle.Render("VPN:"), tui.SuccessStyle.Render(cfg.VPNProvider+" (enabled)"))
+		fmt.Printf("  %s %s\n", tui.MutedStyle.Render("VPN:"), tui.SuccessStyle.Render(i18n.T("%s (enabled)", cfg.VPNProvider)))
 	}
 	fmt.Println()
 
+	// Crash loops first: the table only shows a restarting container between two attempts
+	for _, loop := range crashLoops {
+		fmt.Println(tui.ErrorStyle.Render(fmt.Sprintf("  %s %s", tui.IconError, i18n.T("%s is crash looping: %d restarts, last exit code %d",
+			loop.Service, loop.RestartCount, loop.ExitCode))))
+	}
+	if len(crashLoops) > 0 {
+		fmt.Println(tui.MutedStyle.Render("    " + i18n.T("Run 'sdbx doctor' for their logs and likely causes")))
+		fmt.Println()
+	}
+
 	// Services table
 	if len(services) == 0 {
-		fmt.Println(tui.MutedStyle.Render("  No services running. Run 'sdbx up' to start."))
+		fmt.Println(tui.MutedStyle.Render("  " + i18n.T("No services running. Run 'sdbx up' to start.")))
 		return nil
 	}
 
 	// Create table
-	table := tui.NewTable("Service", "Hostname", "Status", "Health", "URL")
+	table := tui.NewTable(i18n.T("Service"), i18n.T("Hostname"), i18n.T("Status"), i18n.T("Health"), i18n.T("Image"), "URL", i18n.T("Probe"))
 
+	failedProbes := 0
 	for _, svc := range services {
-		name := extractServiceName(svc.Name)
+		name := serviceName(svc)
 
 		// Hostname
 		hostname := tui.MutedStyle.Render(fmt.Sprintf("sdbx-%s", name))
 
 		// Status badge
 		status := tui.StatusBadge(svc.Running)
+		if cfg.IsInMaintenance(name) {
+			status += " " + tui.WarningStyle.Render(i18n.T("maintenance"))
+		}
+		if _, ok := looping[name]; ok {
+			status += " " + tui.ErrorStyle.Render(i18n.T("crash loop"))
+		}
 
 		// Health badge
 		health := tui.HealthBadge(svc.Health)
 
-		// URL
+		// Image tag and lock state
+		image := renderImageStatus(images[name])
+
+		// URL and probe
 		url := tui.MutedStyle.Render("—")
-		if info, ok := serviceInfo[name]; ok && info.HasWebUI && svc.Running {
-			url = tui.InfoStyle.Render(cfg.GetServiceURL(name))
+		probe := tui.MutedStyle.Render("—")
+		if u, ok := routed[name]; ok {
+			url = tui.InfoStyle.Render(u)
+		}
+		if res, ok := probes[name]; ok {
+			if res.OK {
+				probe = tui.SuccessStyle.Render(tui.IconSuccess + " " + res.Message)
+			} else {
+				probe = tui.ErrorStyle.Render(tui.IconError + " " + res.Message)
+				failedProbes++
+			}
 		}
 
-		table.AddRow(name, hostname, status, health, url)
+		table.AddRow(name, hostname, status, health, image, url, probe)
 	}
 
 	fmt.Println(table.Render())
 
+	// Stopped services in maintenance are not listed by compose ps
+	listed := make(map[string]bool, len(services))
+	for _, svc := range services {
+		listed[serviceName(svc)] = true
+	}
+	for _, name := range cfg.MaintenanceServices() {
+		if listed[name] {
+			continue
+		}
+		fmt.Printf("%s %s\n", tui.WarningStyle.Render(tui.IconWarning),
+			tui.MutedStyle.Render(i18n.T("%s is in maintenance (sdbx service maintenance %s off)", name, name)))
+	}
+
 	// Summary
 	summaryStyle := tui.MutedStyle
 	if running == len(services) {
-		msg := summaryStyle.Render(fmt.Sprintf("All %d services running", running))
+		msg := summaryStyle.Render(i18n.T("All %d services running", running))
 		fmt.Printf("%s %s\n", tui.SuccessStyle.Render(tui.IconSuccess), msg)
 	} else {
-		msg := summaryStyle.Render(fmt.Sprintf("%d/%d services running", running, len(services)))
+		msg := summaryStyle.Render(i18n.T("%d/%d services running", running, len(services)))
+		fmt.Printf("%s %s\n", tui.WarningStyle.Render(tui.IconWarning), msg)
+	}
+	if failedProbes > 0 {
+		msg := summaryStyle.Render(i18n.T("%d service URL(s) failed their probe - run 'sdbx logs traefik' to investigate", failedProbes))
+		fmt.Printf("%s %s\n", tui.ErrorStyle.Render(tui.IconError), msg)
+	}
+	if len(outstanding) > 0 {
+		msg := summaryStyle.Render(i18n.T("%d post-install step(s) outstanding - run 'sdbx checklist'", len(outstanding)))
 		fmt.Printf("%s %s\n", tui.WarningStyle.Render(tui.IconWarning), msg)
 	}
 	fmt.Println()
@@ -149,6 +279,186 @@ func runStatus(_ *cobra.Command, args []string) error {
 	return nil
 }
 
+// runStatusHistory shows per-service uptime statistics from the health history
+func runStatusHistory(projectDir string) error {
+	store, err := health.OpenReadOnly(projectDir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("no health history recorded yet\n\n  Try: sdbx monitor")
+	}
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	history, err := store.History(time.Now().Add(-statusSince))
+	if err != nil {
+		return fmt.Errorf("failed to read health history: %w", err)
+	}
+	stats := health.SummarizeAll(history)
+
+	if IsJSONOutput() {
+		return OutputJSON(map[string]interface{}{
+			"since":    statusSince.String(),
+			"services": stats,
+		})
+	}
+
+	fmt.Println()
+	fmt.Println(tui.TitleStyle.Render(i18n.T("SDBX Health History")))
+	fmt.Printf("  %s %s\n", tui.MutedStyle.Render(i18n.T("Window:")), i18n.T("last %s", statusSince))
+	fmt.Println()
+
+	if len(stats) == 0 {
+		fmt.Println(tui.MutedStyle.Render("  " + i18n.T("No samples in this window. Is 'sdbx monitor' running?")))
+		return nil
+	}
+
+	table := tui.NewTable(i18n.T("Service"), i18n.T("Now"), i18n.T("Uptime"), i18n.T("Last failure"), i18n.T("Samples"), i18n.T("Flapping"))
+	flapping := 0
+	for _, s := range stats {
+		uptime := fmt.Sprintf("%.1f%%", s.Uptime)
+		switch {
+		case s.Uptime >= 99:
+			uptime = tui.SuccessStyle.Render(uptime)
+		case s.Uptime >= 90:
+			uptime = tui.WarningStyle.Render(uptime)
+		default:
+			uptime = tui.ErrorStyle.Render(uptime)
+		}
+
+		lastFailure := tui.MutedStyle.Render("—")
+		if s.LastFailure != nil {
+			lastFailure = s.LastFailure.Local().Format("2006-01-02 15:04")
+		}
+
+		now := tui.ErrorStyle.Render(tui.IconError + " " + i18n.T("Down"))
+		if s.Healthy {
+			now = tui.SuccessStyle.Render(tui.IconSuccess + " " + i18n.T("Up"))
+		}
+
+		flap := tui.MutedStyle.Render("—")
+		if s.Flapping {
+			flap = tui.ErrorStyle.Render(fmt.Sprintf("%s %s", tui.IconWarning, i18n.T("%d changes", s.Changes)))
+			flapping++
+		}
+
+		table.AddRow(s.Service, now, uptime, lastFailure, fmt.Sprintf("%d", s.Samples), flap)
+	}
+	fmt.Println(table.Render())
+
+	if flapping > 0 {
+		msg := tui.MutedStyle.Render(i18n.T("%d service(s) flapping - run 'sdbx logs <service>' to investigate", flapping))
+		fmt.Printf("%s %s\n", tui.ErrorStyle.Render(tui.IconError), msg)
+	}
+	fmt.Println()
+
+	return nil
+}
+
+// imageStatus describes a running image and how it compares to the lock file
+type imageStatus struct {
+	Image   string `json:"image"`
+	Tag     string `json:"tag"`
+	Locked  bool   `json:"locked"`            // A lock entry exists for the service
+	Matches bool   `json:"matches,omitempty"` // Running image matches the lock entry
+	Detail  string `json:"detail,omitempty"`
+}
+
+// checkImage compares a running image against the service's lock entry
+func checkImage(ctx context.Context, compose *docker.Compose, image string, lock *registry.LockFile, service string) imageStatus {
+	status := imageStatus{Image: image, Tag: imageTag(image)}
+	if lock == nil {
+		return status
+	}
+	locked, ok := lock.Services[service]
+	if !ok {
+		return status
+	}
+	status.Locked = true
+
+	lockedRef := locked.Image.Repository + ":" + locked.Image.Tag
+	if normalizeImage(image) != normalizeImage(lockedRef) || imageTag(image) != imageTag(lockedRef) {
+		status.Detail = fmt.Sprintf("lock has %s", locked.Image.Tag)
+		return status
+	}
+
+	if locked.Image.Digest != "" {
+		digests, err := compose.ImageRepoDigests(ctx, image)
+		if err == nil && !hasDigest(digests, locked.Image.Digest) {
+			status.Detail = "digest differs from lock"
+			return status
+		}
+	}
+
+	status.Matches = true
+	return status
+}
+
+// renderImageStatus formats an image status for the status table
+func renderImageStatus(s imageStatus) string {
+	switch {
+	case !s.Locked:
+		return s.Tag
+	case s.Matches:
+		return s.Tag + " " + tui.SuccessStyle.Render(tui.IconCheck)
+	default:
+		return s.Tag + " " + tui.WarningStyle.Render(tui.IconWarning+" "+s.Detail)
+	}
+}
+
+// imageTag returns the tag of an image reference, or "latest" if none is set
+func imageTag(image string) string {
+	name := image[strings.LastIndex(image, "/")+1:]
+	if i := strings.Index(name, "@"); i >= 0 {
+		name = name[:i]
+	}
+	if i := strings.LastIndex(name, ":"); i >= 0 {
+		return name[i+1:]
+	}
+	return "latest"
+}
+
+// hasDigest reports whether any repo digest (repo@sha256:...) ends with digest
+func hasDigest(repoDigests []string, digest string) bool {
+	for _, d := range repoDigests {
+		if strings.HasSuffix(d, "@"+digest) {
+			return true
+		}
+	}
+	return false
+}
+
+// probeServices probes all routed URLs concurrently
+func probeServices(ctx context.Context, cfg *config.Config, urls map[string]string) map[string]doctor.ProbeResult {
+	prober := doctor.NewProber(cfg, doctor.DefaultProbeTimeout)
+	results := make(map[string]doctor.ProbeResult, len(urls))
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for name, url := range urls {
+		wg.Add(1)
+		go func(name, url string) {
+			defer wg.Done()
+			res := prober.Probe(ctx, url)
+			mu.Lock()
+			results[name] = res
+			mu.Unlock()
+		}(name, url)
+	}
+	wg.Wait()
+
+	return results
+}
+
+// serviceName returns the SDBX service a container belongs to, preferring
+// the ownership labels over the container name
+func serviceName(svc docker.Service) string {
+	if svc.Service != "" {
+		return svc.Service
+	}
+	return extractServiceName(svc.Name)
+}
+
 // extractServiceName gets the service name from container name (removes project prefix)
 func extractServiceName(containerName string) string {
 	parts := strings.Split(containerName, "-")
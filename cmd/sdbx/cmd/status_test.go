@@ -1,7 +1,10 @@
 package cmd
 
 import (
+	"context"
 	"testing"
+
+	"github.com/maiko/sdbx/internal/registry"
 )
 
 func TestExtractServiceName(t *testing.T) {
@@ -25,3 +28,54 @@ func TestExtractServiceName(t *testing.T) {
 		}
 	}
 }
+
+func TestImageTag(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"lscr.io/linuxserver/sonarr:4.0.0", "4.0.0"},
+		{"traefik:v2.11", "v2.11"},
+		{"nginx", "latest"},
+		{"registry.local:5000/app", "latest"},
+		{"registry.local:5000/app:1.2", "1.2"},
+		{"ghcr.io/org/app:1.0@sha256:abc", "1.0"},
+	}
+
+	for _, tt := range tests {
+		if got := imageTag(tt.input); got != tt.expected {
+			t.Errorf("imageTag(%q) = %q, want %q", tt.input, got, tt.expected)
+		}
+	}
+}
+
+func TestCheckImage(t *testing.T) {
+	lock := &registry.LockFile{
+		Services: map[string]registry.LockedService{
+			"sonarr": {Image: registry.LockedImage{Repository: "lscr.io/linuxserver/sonarr", Tag: "4.0.0"}},
+		},
+	}
+
+	if got := checkImage(context.Background(), nil, "lscr.io/linuxserver/sonarr:4.0.0", lock, "sonarr"); !got.Locked || !got.Matches {
+		t.Errorf("matching image = %+v, want locked and matching", got)
+	}
+	if got := checkImage(context.Background(), nil, "lscr.io/linuxserver/sonarr:latest", lock, "sonarr"); got.Matches || got.Detail == "" {
+		t.Errorf("drifted tag = %+v, want mismatch with detail", got)
+	}
+	if got := checkImage(context.Background(), nil, "traefik:v2.11", lock, "traefik"); got.Locked {
+		t.Errorf("unlocked service = %+v, want Locked=false", got)
+	}
+	if got := checkImage(context.Background(), nil, "traefik:v2.11", nil, "traefik"); got.Tag != "v2.11" {
+		t.Errorf("no lock file: tag = %q, want v2.11", got.Tag)
+	}
+}
+
+func TestHasDigest(t *testing.T) {
+	digests := []string{"lscr.io/linuxserver/sonarr@sha256:abc123"}
+	if !hasDigest(digests, "sha256:abc123") {
+		t.Error("expected digest match")
+	}
+	if hasDigest(digests, "sha256:def456") {
+		t.Error("unexpected digest match")
+	}
+}
@@ -0,0 +1,115 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/maiko/sdbx/internal/config"
+	"github.com/maiko/sdbx/internal/doctor"
+	"github.com/maiko/sdbx/internal/support"
+	"github.com/maiko/sdbx/internal/tui"
+)
+
+var supportBundleCmd = &cobra.Command{
+	Use:   "support-bundle",
+	Short: "Collect redacted diagnostics into an archive for bug reports",
+	Long: `Collect the diagnostics of the project into a single archive to attach
+to a bug report:
+
+  • .sdbx.yaml, .sdbx.lock, the generation state, .env, compose.yaml and
+    the generated Traefik and Authelia configuration
+  • The doctor checks and the services stuck in a restart loop
+  • The state of every container and the last log lines of each
+  • The last 24 hours of the web UI server and job logs (logs/)
+
+Secrets are scrubbed from every file: the values of secrets/ wherever they
+appear, values of keys such as password, token or api_key, credentials in
+URLs, e-mail addresses and the domain. Secret files, the users database,
+acme.json and backups are never collected. Review the archive before
+sharing it.
+
+Examples:
+  sdbx support-bundle
+  sdbx support-bundle --output /tmp/sdbx-support.tar.gz
+  sdbx support-bundle --log-lines 2000
+  sdbx support-bundle --keep-domain   # Routing issues`,
+	Args: cobra.NoArgs,
+	RunE: runSupportBundle,
+}
+
+var (
+	supportOutput     string
+	supportLogLines   int
+	supportKeepDomain bool
+	supportNoDoctor   bool
+)
+
+func init() {
+	rootCmd.AddCommand(supportBundleCmd)
+
+	supportBundleCmd.Flags().StringVarP(&supportOutput, "output", "o", "", "Archive path (default: sdbx-support-<time>.tar.gz)")
+	supportBundleCmd.Flags().IntVar(&supportLogLines, "log-lines", support.DefaultLogLines, "Last log lines collected per service, 0 for none")
+	supportBundleCmd.Flags().BoolVar(&supportKeepDomain, "keep-domain", false, "Do not replace the domain")
+	supportBundleCmd.Flags().BoolVar(&supportNoDoctor, "no-doctor", false, "Skip the doctor checks")
+}
+
+func runSupportBundle(cmd *cobra.Command, _ []string) error {
+	projectDir, err := config.ProjectDir()
+	if err != nil {
+		return err
+	}
+	if supportLogLines < 0 {
+		return fmt.Errorf("--log-lines must not be negative")
+	}
+	path := supportOutput
+	if path == "" {
+		path = fmt.Sprintf("sdbx-support-%s.tar.gz", time.Now().Format("2006-01-02-150405"))
+	}
+
+	ctx := commandContext(cmd)
+	domain := ""
+	cfg, cfgErr := config.Load()
+	if cfgErr == nil && !supportKeepDomain {
+		domain = cfg.Domain
+	}
+	bundle := &support.Bundle{
+		ProjectDir: projectDir,
+		Version:    Version,
+		LogLines:   supportLogLines,
+		Compose:    projectCompose(projectDir),
+		Scrubber:   support.NewScrubber(projectDir, domain),
+	}
+	if !supportNoDoctor {
+		bundle.Doctor = doctor.NewDoctor(projectDir)
+		if cfgErr == nil {
+			bundle.Doctor.External, bundle.Doctor.ExternalErr = externalDependencies(ctx, cfg)
+		}
+	}
+
+	var report *support.Report
+	collect := func() error {
+		var err error
+		report, err = bundle.Write(ctx, path)
+		return err
+	}
+	if IsTUIEnabled() && !IsJSONOutput() {
+		err = tui.RunWithSpinner("Collecting diagnostics...", collect)
+	} else {
+		err = collect()
+	}
+	if err != nil {
+		return err
+	}
+
+	if IsJSONOutput() {
+		return OutputJSON(report)
+	}
+	fmt.Println(tui.SuccessStyle.Render(fmt.Sprintf("%s Support bundle written to %s (%d files)", tui.IconSuccess, report.Path, len(report.Files))))
+	for _, skipped := range report.Skipped {
+		fmt.Printf("  %s %s\n", tui.WarningStyle.Render(tui.IconWarning), tui.MutedStyle.Render("Not collected: "+skipped))
+	}
+	fmt.Println(tui.MutedStyle.Render("  Secrets are scrubbed; review the archive before attaching it to an issue."))
+	return nil
+}
@@ -0,0 +1,198 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/maiko/sdbx/internal/config"
+	"github.com/maiko/sdbx/internal/registry"
+	"github.com/maiko/sdbx/internal/telemetry"
+	"github.com/maiko/sdbx/internal/tui"
+)
+
+var telemetryCmd = &cobra.Command{
+	Use:   "telemetry",
+	Short: "Manage anonymous usage reporting (off unless you opt in)",
+	Long: `Anonymous usage reporting helps find slow commands and common errors.
+It is off until you run 'sdbx telemetry on', and DO_NOT_TRACK=1 or
+SDBX_TELEMETRY=off turn it off whatever the settings say.
+
+Each command run records only:
+  • The command path (e.g. "addon enable"), never its arguments
+  • How long it took
+  • The class of its error (e.g. port-conflict, validation), never the message
+  • The number of enabled addons
+  • The sdbx version, OS, architecture, day, and a random installation ID
+
+Reports are kept in ~/.cache/sdbx/telemetry.jsonl and sent at most once a
+day to the endpoint given when opting in; without one they stay local.
+'sdbx telemetry show' prints exactly what would be sent.
+
+Examples:
+  sdbx telemetry status
+  sdbx telemetry on --endpoint https://telemetry.example.com/v1/reports
+  sdbx telemetry show
+  sdbx telemetry off`,
+}
+
+var telemetryStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show whether usage reporting is on",
+	Args:  cobra.NoArgs,
+	RunE:  runTelemetryStatus,
+}
+
+var telemetryOnCmd = &cobra.Command{
+	Use:   "on",
+	Short: "Opt in to anonymous usage reporting",
+	Args:  cobra.NoArgs,
+	RunE:  runTelemetryOn,
+}
+
+var telemetryOffCmd = &cobra.Command{
+	Use:   "off",
+	Short: "Opt out and delete the reports not sent",
+	Args:  cobra.NoArgs,
+	RunE:  runTelemetryOff,
+}
+
+var telemetryShowCmd = &cobra.Command{
+	Use:   "show",
+	Short: "Print the reports exactly as they would be sent",
+	Args:  cobra.NoArgs,
+	RunE:  runTelemetryShow,
+}
+
+var telemetryEndpoint string
+
+func init() {
+	rootCmd.AddCommand(telemetryCmd)
+	telemetryCmd.AddCommand(telemetryStatusCmd, telemetryOnCmd, telemetryOffCmd, telemetryShowCmd)
+
+	telemetryOnCmd.Flags().StringVar(&telemetryEndpoint, "endpoint", "", "URL reports are sent to (default: keep them local)")
+}
+
+// telemetryStatus is the JSON output of sdbx telemetry status
+type telemetryStatus struct {
+	telemetry.Settings
+	DisabledByEnv bool `json:"disabledByEnv"`
+	Pending       int  `json:"pending"`
+}
+
+func runTelemetryStatus(_ *cobra.Command, _ []string) error {
+	store := telemetry.NewStore()
+	settings, err := store.Settings()
+	if err != nil {
+		return err
+	}
+	pending, err := store.Pending()
+	if err != nil {
+		return err
+	}
+	status := telemetryStatus{Settings: settings, DisabledByEnv: telemetry.DisabledByEnv(), Pending: len(pending)}
+	if IsJSONOutput() {
+		return OutputJSON(status)
+	}
+
+	switch {
+	case status.DisabledByEnv:
+		fmt.Printf("Telemetry: %s\n", tui.MutedStyle.Render("off (DO_NOT_TRACK or SDBX_TELEMETRY=off)"))
+	case settings.Enabled:
+		fmt.Printf("Telemetry: %s\n", tui.SuccessStyle.Render("on"))
+	default:
+		fmt.Printf("Telemetry: %s\n", tui.MutedStyle.Render("off"))
+	}
+	if settings.Enabled {
+		endpoint := settings.Endpoint
+		if endpoint == "" {
+			endpoint = "none, reports stay local"
+		}
+		fmt.Printf("  Installation ID: %s\n", settings.ID)
+		fmt.Printf("  Endpoint:        %s\n", endpoint)
+		fmt.Printf("  Pending reports: %d\n", status.Pending)
+		if !settings.LastSent.IsZero() {
+			fmt.Printf("  Last sent:       %s\n", settings.LastSent.Local().Format("2006-01-02 15:04"))
+		}
+	}
+	return nil
+}
+
+func runTelemetryOn(_ *cobra.Command, _ []string) error {
+	if telemetryEndpoint != "" && !strings.HasPrefix(telemetryEndpoint, "https://") && !strings.HasPrefix(telemetryEndpoint, "http://") {
+		return fmt.Errorf("invalid endpoint %q: must be an http(s) URL", telemetryEndpoint)
+	}
+	settings, err := telemetry.NewStore().Enable(telemetryEndpoint)
+	if err != nil {
+		return err
+	}
+	if IsJSONOutput() {
+		return OutputJSON(settings)
+	}
+	fmt.Println(tui.SuccessStyle.Render(tui.IconSuccess + " Telemetry on, thank you"))
+	if settings.Endpoint == "" {
+		fmt.Println(tui.MutedStyle.Render("  No endpoint set: reports stay in ~/.cache/sdbx/telemetry.jsonl"))
+	}
+	fmt.Println(tui.MutedStyle.Render("  See what is recorded with 'sdbx telemetry show', opt out with 'sdbx telemetry off'"))
+	return nil
+}
+
+func runTelemetryOff(_ *cobra.Command, _ []string) error {
+	if err := telemetry.NewStore().Disable(); err != nil {
+		return err
+	}
+	if IsJSONOutput() {
+		return OutputJSON(map[string]bool{"enabled": false})
+	}
+	fmt.Println(tui.SuccessStyle.Render(tui.IconSuccess + " Telemetry off, pending reports deleted"))
+	return nil
+}
+
+func runTelemetryShow(_ *cobra.Command, _ []string) error {
+	pending, err := telemetry.NewStore().Pending()
+	if err != nil {
+		return err
+	}
+	payload, err := telemetry.Payload(pending)
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(payload))
+	return nil
+}
+
+// recordTelemetry records the run of a command when telemetry is on, and
+// sends the pending reports when due. It never fails the command.
+func recordTelemetry(cmd *cobra.Command, start time.Time, err error) {
+	if cmd == nil || cmd == rootCmd || telemetry.DisabledByEnv() {
+		return
+	}
+	for c := cmd; c != nil; c = c.Parent() {
+		if c == telemetryCmd || c.Name() == "completion" || strings.HasPrefix(c.Name(), "__") {
+			return
+		}
+	}
+	store := telemetry.NewStore()
+	settings, settingsErr := store.Settings()
+	if settingsErr != nil || !settings.Enabled {
+		return
+	}
+
+	var addons *int
+	if _, projectErr := config.ProjectDir(); projectErr == nil {
+		if cfg, loadErr := config.Load(); loadErr == nil {
+			n := len(cfg.Addons)
+			addons = &n
+		}
+	}
+	command := strings.TrimPrefix(cmd.CommandPath(), rootCmd.Name()+" ")
+	if store.Record(telemetry.NewReport(settings, Version, command, time.Since(start), err, addons)) != nil {
+		return
+	}
+	if !registry.Offline() {
+		_ = store.SendDue(context.Background(), time.Now())
+	}
+}
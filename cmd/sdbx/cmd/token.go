@@ -0,0 +1,159 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/maiko/sdbx/internal/auth"
+	"github.com/maiko/sdbx/internal/config"
+	"github.com/maiko/sdbx/internal/tui"
+)
+
+var tokenCmd = &cobra.Command{
+	Use:   "token",
+	Short: "Manage API tokens for scripts and monitoring",
+	Long: `Manage the API tokens that let scripts, Homepage widgets and monitoring
+systems call the web UI API without an Authelia browser session.
+
+Tokens are sent as "Authorization: Bearer <token>" to /api/ routes. Read
+tokens may only make GET requests; write tokens may also change things
+(start services, create backups, ...). Only a hash of each token is kept,
+in .sdbx.tokens.yaml, so a token is shown once when created.
+
+Examples:
+  sdbx token create homepage                          # Read-only, expires in 90 days
+  sdbx token create ci --scope write --expiry 30d     # Write access for 30 days
+  sdbx token list                                     # List tokens
+  sdbx token revoke ci                                # Revoke a token
+
+  curl -H "Authorization: Bearer $TOKEN" https://sdbx.example.com/api/services`,
+}
+
+var tokenCreateCmd = &cobra.Command{
+	Use:   "create <name>",
+	Short: "Create a token and print it once",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runTokenCreate,
+}
+
+var tokenListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List tokens",
+	Args:  cobra.NoArgs,
+	RunE:  runTokenList,
+}
+
+var tokenRevokeCmd = &cobra.Command{
+	Use:     "revoke <name>",
+	Aliases: []string{"rm"},
+	Short:   "Revoke a token",
+	Args:    cobra.ExactArgs(1),
+	RunE:    runTokenRevoke,
+}
+
+var (
+	tokenScope  string
+	tokenExpiry string
+)
+
+func init() {
+	rootCmd.AddCommand(tokenCmd)
+	tokenCmd.AddCommand(tokenCreateCmd)
+	tokenCmd.AddCommand(tokenListCmd)
+	tokenCmd.AddCommand(tokenRevokeCmd)
+
+	tokenCreateCmd.Flags().StringVar(&tokenScope, "scope", auth.ScopeRead, "Access granted: read or write")
+	tokenCreateCmd.Flags().StringVar(&tokenExpiry, "expiry", "90d", "Lifetime (e.g. 30d, 12h) or never")
+}
+
+// tokenStore opens the token store of the current project
+func tokenStore() (*auth.TokenStore, error) {
+	projectDir, err := config.ProjectDir()
+	if err != nil {
+		return nil, err
+	}
+	return auth.NewTokenStore(projectDir), nil
+}
+
+func runTokenCreate(_ *cobra.Command, args []string) error {
+	expiry, err := auth.ParseExpiry(tokenExpiry)
+	if err != nil {
+		return err
+	}
+	store, err := tokenStore()
+	if err != nil {
+		return err
+	}
+	secret, token, err := store.Create(args[0], tokenScope, expiry)
+	if err != nil {
+		return err
+	}
+
+	if IsJSONOutput() {
+		return OutputJSON(struct {
+			auth.Token
+			Secret string `json:"token"`
+		}{token, secret})
+	}
+
+	fmt.Println(tui.SuccessStyle.Render(fmt.Sprintf("✓ Created %s token %s (expires %s)", token.Scope, token.Name, formatTokenExpiry(token))))
+	fmt.Println()
+	fmt.Println("  " + secret)
+	fmt.Println()
+	fmt.Println(tui.MutedStyle.Render("Copy it now, it cannot be shown again. Send it as \"Authorization: Bearer <token>\"."))
+	return nil
+}
+
+func runTokenList(_ *cobra.Command, _ []string) error {
+	store, err := tokenStore()
+	if err != nil {
+		return err
+	}
+	tokens, err := store.List()
+	if err != nil {
+		return err
+	}
+
+	if IsJSONOutput() {
+		return OutputJSON(tokens)
+	}
+
+	table := tui.NewTable("Name", "Scope", "Created", "Expires")
+	now := time.Now()
+	for _, t := range tokens {
+		expires := formatTokenExpiry(t)
+		if t.Expired(now) {
+			expires = tui.ErrorStyle.Render(expires + " (expired)")
+		}
+		table.AddRow(t.Name, t.Scope, t.Created.Local().Format("2006-01-02"), expires)
+	}
+	fmt.Println(table.Render())
+	fmt.Println(tui.MutedStyle.Render(fmt.Sprintf("%d tokens in %s", len(tokens), store.Path())))
+	return nil
+}
+
+func runTokenRevoke(_ *cobra.Command, args []string) error {
+	store, err := tokenStore()
+	if err != nil {
+		return err
+	}
+	if err := store.Revoke(args[0]); err != nil {
+		if errors.Is(err, auth.ErrTokenNotFound) {
+			return fmt.Errorf("token %s not found\n\n  Try: sdbx token list", args[0])
+		}
+		return err
+	}
+	fmt.Println(tui.SuccessStyle.Render(fmt.Sprintf("✓ Revoked token %s", args[0])))
+	return nil
+}
+
+// formatTokenExpiry returns the expiry date of a token, or "never"
+func formatTokenExpiry(t auth.Token) string {
+	if t.Expires.IsZero() {
+		return "never"
+	}
+	return t.Expires.Local().Format("2006-01-02")
+}
@@ -3,9 +3,12 @@ package cmd
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"maps"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"time"
 
@@ -14,6 +17,10 @@ import (
 
 	"github.com/maiko/sdbx/internal/config"
 	"github.com/maiko/sdbx/internal/docker"
+	"github.com/maiko/sdbx/internal/doctor"
+	"github.com/maiko/sdbx/internal/qbittorrent"
+	"github.com/maiko/sdbx/internal/registry"
+	"github.com/maiko/sdbx/internal/secrets"
 	"github.com/maiko/sdbx/internal/tui"
 )
 
@@ -23,20 +30,31 @@ var upCmd = &cobra.Command{
 	Long: `Start all configured SDBX services using Docker Compose.
 
 This command will:
-  • Pull latest images if needed
+  • Pull missing images, with per-image progress
   • Start all enabled services
-  • Wait for health checks to pass`,
+  • Wait for health checks to pass
+  • Replace the temporary qBittorrent Web UI password with a managed one
+  • On the first start, run the onboarding checks (see sdbx onboard)
+
+With --json, image pull progress is printed as JSON events (one per line)
+followed by a summary. --quiet only reports failed pulls.`,
 	RunE: runUp,
 }
 
-var upDryRun bool
+var (
+	upDryRun       bool
+	upQuiet        bool
+	upNoOnboarding bool
+)
 
 func init() {
 	rootCmd.AddCommand(upCmd)
 	upCmd.Flags().BoolVar(&upDryRun, "dry-run", false, "Show what would be done without starting services")
+	upCmd.Flags().BoolVarP(&upQuiet, "quiet", "q", false, "Do not show image pull progress")
+	upCmd.Flags().BoolVar(&upNoOnboarding, "no-onboarding", false, "Do not run the onboarding checks after the first start")
 }
 
-func runUp(_ *cobra.Command, args []string) error {
+func runUp(cmd *cobra.Command, args []string) error {
 	// Find project directory
 	projectDir, err := config.ProjectDir()
 	if err != nil {
@@ -53,7 +71,7 @@ func runUp(_ *cobra.Command, args []string) error {
 	if upDryRun {
 		fmt.Println(tui.TitleStyle.Render("Dry Run: sdbx up"))
 		fmt.Println()
-		fmt.Printf("  %s Pull latest images\n", tui.IconArrow)
+		fmt.Printf("  %s Pull missing images\n", tui.IconArrow)
 		fmt.Printf("  %s Start all services via docker compose up -d\n", tui.IconArrow)
 		fmt.Printf("  %s Project directory: %s\n", tui.IconArrow, projectDir)
 		fmt.Printf("  %s Domain: %s\n", tui.IconArrow, cfg.Domain)
@@ -65,14 +83,30 @@ func runUp(_ *cobra.Command, args []string) error {
 		return nil
 	}
 
-	compose := docker.NewCompose(projectDir)
-	ctx := context.Background()
+	if err := checkProjectState(projectDir); err != nil {
+		return err
+	}
+	if err := checkLockIntegrity(cfg, projectDir); err != nil {
+		return err
+	}
+
+	compose := projectCompose(projectDir)
+	ctx := commandContext(cmd)
 
 	// Prompt for Plex claim token if needed (before starting containers)
 	if err := promptPlexClaimToken(cfg, projectDir); err != nil {
 		return fmt.Errorf("failed to handle Plex claim token: %w", err)
 	}
 
+	if err := ensureServiceSecrets(ctx, cfg, projectDir); err != nil {
+		return err
+	}
+
+	// Pull missing images first, so progress is shown instead of compose output
+	if err := pullMissingImages(ctx, projectDir, upQuiet); err != nil {
+		return err
+	}
+
 	// Start services
 	start := time.Now()
 	if IsTUIEnabled() {
@@ -89,16 +123,175 @@ func runUp(_ *cobra.Command, args []string) error {
 		}
 	}
 
+	recordEvent(projectDir, "up")
+
 	elapsed := time.Since(start)
 	fmt.Println()
 	fmt.Println(tui.SuccessStyle.Render(fmt.Sprintf("✓ Services started in %s", elapsed.Round(time.Millisecond))))
 	fmt.Println()
+
+	bootstrapQBittorrent(ctx, cfg, projectDir, compose)
+
+	if !upNoOnboarding && !IsJSONOutput() {
+		onboardAfterUp(ctx, cfg, projectDir)
+	}
+
+	// Post-install steps are informational; a broken source must not fail up
+	if checklist, err := loadChecklist(ctx, cfg, projectDir); err == nil {
+		printOutstandingChecklist(checklist)
+	}
 	fmt.Println("Run 'sdbx status' to view service health")
 	fmt.Println("Run 'sdbx doctor' to verify configuration")
 
 	return nil
 }
 
+// qbittorrentBootstrapWait bounds how long sdbx up waits for qBittorrent to
+// announce its Web UI login in its logs
+const qbittorrentBootstrapWait = time.Minute
+
+// bootstrapQBittorrent replaces the temporary password qBittorrent gives
+// its Web UI when none is set with the password sdbx manages, so the
+// integrations can log in. It never fails up.
+func bootstrapQBittorrent(ctx context.Context, cfg *config.Config, projectDir string, compose *docker.Compose) {
+	if qbittorrent.HasPassword(projectDir, cfg) {
+		return
+	}
+	deadline := time.Now().Add(qbittorrentBootstrapWait)
+	for {
+		logs, err := compose.Logs(ctx, "qbittorrent", 1000, false)
+		if err != nil {
+			return // Not enabled
+		}
+		temp, started := qbittorrent.TempPassword(logs)
+		if started && temp == "" {
+			return
+		}
+		if started {
+			if err = qbittorrent.Bootstrap(ctx, projectDir, cfg, temp); err == nil {
+				fmt.Println(tui.SuccessStyle.Render(fmt.Sprintf("✓ qBittorrent Web UI password set: log in as %s with secrets/%s.txt",
+					cfg.DownloadClients.QBittorrent.Username, qbittorrent.PasswordSecret)))
+				fmt.Println()
+				return
+			}
+		}
+		if !time.Now().Before(deadline) {
+			msg := "qBittorrent did not start its Web UI in time"
+			if err != nil {
+				msg = err.Error()
+			}
+			fmt.Println(tui.WarningStyle.Render(fmt.Sprintf("%s %s - run 'sdbx onboard --fix' once it is up", tui.IconWarning, msg)))
+			fmt.Println()
+			return
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(2 * time.Second):
+		}
+	}
+}
+
+// onboardAfterUp runs the onboarding checks after the first start of a
+// project, and reminds of outstanding steps afterwards. Onboarding is
+// informational: it never fails up.
+func onboardAfterUp(ctx context.Context, cfg *config.Config, projectDir string) {
+	report, err := doctor.ReadOnboarding(projectDir)
+	if err != nil {
+		return
+	}
+	if report != nil {
+		if outstanding := len(report.Outstanding()); outstanding > 0 {
+			fmt.Println(tui.InfoStyle.Render(fmt.Sprintf("%d onboarding step(s) outstanding: run 'sdbx onboard'", outstanding)))
+			fmt.Println()
+		}
+		return
+	}
+
+	onboarding, err := newOnboarding(ctx, cfg, projectDir)
+	if err != nil {
+		return
+	}
+	if report, err = runOnboarding(ctx, onboarding); err != nil {
+		return
+	}
+	printOnboardingReport(report)
+}
+
+// checkProjectState refuses to start a project whose last generation failed
+// or was interrupted, since its files may be partially written
+func checkProjectState(projectDir string) error {
+	state, err := config.ReadState(projectDir)
+	if err != nil {
+		return err
+	}
+	switch state.State {
+	case config.StateDegraded:
+		return fmt.Errorf("project files are incomplete, the last generation failed: %s\n\n  Try: sdbx regenerate", state.Error)
+	case config.StateGenerating:
+		return fmt.Errorf("project files are incomplete, the last generation was interrupted\n\n  Try: sdbx regenerate")
+	}
+	if dropped := state.Dropped(); len(dropped) > 0 {
+		fmt.Println(tui.WarningStyle.Render(fmt.Sprintf("%s The last generation skipped %s; containers of removed services are kept until it succeeds",
+			tui.IconWarning, strings.Join(dropped, ", "))))
+		fmt.Printf("  Fix the definitions and run '%s'\n\n", tui.CommandStyle.Render("sdbx regenerate"))
+	}
+	return nil
+}
+
+// checkLockIntegrity verifies .sdbx.lock against its checksum, warning or
+// failing according to the lock_integrity setting
+func checkLockIntegrity(cfg *config.Config, projectDir string) error {
+	if cfg.LockIntegrity == config.LockIntegrityOff || !registry.LockFileExists(projectDir) {
+		return nil
+	}
+
+	err := registry.VerifyLockChecksum(registry.GetLockFilePath(projectDir))
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, registry.ErrLockChecksumMissing):
+		fmt.Println(tui.WarningStyle.Render(fmt.Sprintf("%s .sdbx.lock has no checksum; run 'sdbx lock fix' to add one", tui.IconWarning)))
+		return nil
+	case errors.Is(err, registry.ErrLockChecksumMismatch) && cfg.LockIntegrity != config.LockIntegrityFail:
+		fmt.Println(tui.WarningStyle.Render(fmt.Sprintf("%s .sdbx.lock was edited or corrupted since it was generated", tui.IconWarning)))
+		fmt.Printf("  Run '%s' to accept the edits or '%s' to resolve the services again\n\n",
+			tui.CommandStyle.Render("sdbx lock fix"), tui.CommandStyle.Render("sdbx lock generate"))
+		return nil
+	default:
+		return fmt.Errorf("lock file check failed: %w\n\n  Try: sdbx lock fix (or sdbx lock generate to resolve the services again)", err)
+	}
+}
+
+// ensureServiceSecrets generates any secret declared by an enabled service
+// that does not exist yet, and warns about manual or malformed secrets
+func ensureServiceSecrets(ctx context.Context, cfg *config.Config, projectDir string) error {
+	reg, err := getRegistry()
+	if err != nil {
+		return err
+	}
+	graph, err := reg.Resolve(ctx, cfg)
+	if err != nil {
+		return fmt.Errorf("failed to resolve services: %w\n\n  Try: sdbx doctor", err)
+	}
+
+	result, err := secrets.EnsureSecrets(filepath.Join(projectDir, "secrets"), append(graph.SecretSpecs(), registry.ConfigSecretSpecs(cfg)...))
+	if err != nil {
+		return err
+	}
+
+	for _, name := range result.Generated {
+		fmt.Println(tui.SuccessStyle.Render(fmt.Sprintf("✓ Generated secret %s", name)))
+	}
+	for _, name := range result.Missing {
+		fmt.Println(tui.WarningStyle.Render(fmt.Sprintf("%s Secret %s is empty; set it in secrets/%s.txt", tui.IconWarning, name, name)))
+	}
+	for _, name := range slices.Sorted(maps.Keys(result.Invalid)) {
+		fmt.Println(tui.WarningStyle.Render(fmt.Sprintf("%s %s", tui.IconWarning, result.Invalid[name])))
+	}
+	return nil
+}
+
 // promptPlexClaimToken checks if Plex addon is enabled and prompts for claim token
 func promptPlexClaimToken(cfg *config.Config, projectDir string) error {
 	// Check if Plex addon is enabled
@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"os"
+	"testing"
+
+	"github.com/maiko/sdbx/internal/config"
+	"github.com/maiko/sdbx/internal/registry"
+)
+
+func TestCheckLockIntegrity(t *testing.T) {
+	projectDir := t.TempDir()
+	cfg := config.DefaultConfig()
+
+	// No lock file
+	if err := checkLockIntegrity(cfg, projectDir); err != nil {
+		t.Fatalf("checkLockIntegrity() without lock = %v", err)
+	}
+
+	lockPath := registry.GetLockFilePath(projectDir)
+	lock := &registry.LockFile{APIVersion: registry.APIVersion, Kind: registry.KindLockFile}
+	if err := registry.NewLoader().SaveLockFile(lockPath, lock); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(lockPath, []byte("tampered: true\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg.LockIntegrity = config.LockIntegrityWarn
+	if err := checkLockIntegrity(cfg, projectDir); err != nil {
+		t.Errorf("warn mode should not fail: %v", err)
+	}
+	cfg.LockIntegrity = config.LockIntegrityOff
+	if err := checkLockIntegrity(cfg, projectDir); err != nil {
+		t.Errorf("off mode should not fail: %v", err)
+	}
+	cfg.LockIntegrity = config.LockIntegrityFail
+	if err := checkLockIntegrity(cfg, projectDir); err == nil {
+		t.Error("fail mode should reject a tampered lock file")
+	}
+}
+
+func TestCheckProjectState(t *testing.T) {
+	projectDir := t.TempDir()
+
+	if err := checkProjectState(projectDir); err != nil {
+		t.Fatalf("checkProjectState() without state = %v", err)
+	}
+	if err := config.WriteState(projectDir, config.StateDegraded, os.ErrPermission); err != nil {
+		t.Fatal(err)
+	}
+	if err := checkProjectState(projectDir); err == nil {
+		t.Error("checkProjectState() should reject a degraded project")
+	}
+	if err := config.WriteState(projectDir, config.StateReady, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := checkProjectState(projectDir); err != nil {
+		t.Errorf("checkProjectState() of a ready project = %v", err)
+	}
+}
@@ -9,7 +9,6 @@ import (
 	"github.com/spf13/cobra"
 
 	"github.com/maiko/sdbx/internal/config"
-	"github.com/maiko/sdbx/internal/docker"
 	"github.com/maiko/sdbx/internal/registry"
 	"github.com/maiko/sdbx/internal/tui"
 )
@@ -27,6 +26,9 @@ var updateCmd = &cobra.Command{
 By default, services are updated one at a time with health checks.
 Use --all to update all services simultaneously (faster but riskier).
 
+Services with update_policy "pinned" or "notify-only" in .sdbx.yaml
+are skipped.
+
 Examples:
   sdbx update          # Safe update (one at a time)
   sdbx update --all    # Update all at once
@@ -40,30 +42,57 @@ func init() {
 	updateCmd.Flags().BoolVar(&updateAll, "all", false, "Update all services at once (faster)")
 }
 
-func runUpdate(_ *cobra.Command, args []string) error {
+func runUpdate(cmd *cobra.Command, args []string) error {
 	// Find project directory
 	projectDir, err := config.ProjectDir()
 	if err != nil {
 		return err
 	}
 
-	compose := docker.NewCompose(projectDir)
-	ctx := context.Background()
+	compose := projectCompose(projectDir)
+	ctx := commandContext(cmd)
+
+	cfg, err := config.Load()
+	if err != nil {
+		cfg = config.DefaultConfig()
+	}
+
+	if err := registry.RequireNetwork("pulling new images"); err != nil {
+		return err
+	}
 
 	fmt.Println(tui.TitleStyle.Render("SDBX Update"))
 	fmt.Println()
 
+	// Get enabled services from registry in dependency order
+	services, err := getEnabledServicesOrdered(ctx, cfg)
+	if err != nil {
+		return fmt.Errorf("failed to get enabled services: %w", err)
+	}
+
+	updatable, held := splitByUpdatePolicy(cfg, services)
+	for _, svc := range held {
+		fmt.Println(tui.MutedStyle.Render(fmt.Sprintf("  %s skipped (%s)", svc, cfg.UpdatePolicy(svc))))
+	}
+	if len(held) > 0 {
+		fmt.Println()
+	}
+	if len(updatable) == 0 {
+		fmt.Println(tui.InfoStyle.Render("No services with update_policy \"auto\" to update"))
+		return nil
+	}
+
 	// Step 1: Pull images
 	start := time.Now()
 	if IsTUIEnabled() {
 		if err := tui.RunWithSpinner("Pulling latest images...", func() error {
-			return compose.Pull(ctx)
+			return compose.Pull(ctx, updatable...)
 		}); err != nil {
 			return fmt.Errorf("failed to pull images: %w\n\n  Try: Check internet connection or run 'docker login'", err)
 		}
 	} else {
 		fmt.Println(tui.InfoStyle.Render("Pulling latest images..."))
-		if err := compose.Pull(ctx); err != nil {
+		if err := compose.Pull(ctx, updatable...); err != nil {
 			return fmt.Errorf("failed to pull images: %w\n\n  Try: Check internet connection or run 'docker login'", err)
 		}
 	}
@@ -94,16 +123,11 @@ func runUpdate(_ *cobra.Command, args []string) error {
 	} else {
 		fmt.Println(tui.InfoStyle.Render("Restarting services (ordered)..."))
 
-		// Get enabled services from registry in dependency order
-		services, err := getEnabledServicesOrdered(ctx, projectDir)
-		if err != nil {
-			return fmt.Errorf("failed to get enabled services: %w", err)
-		}
-
-		for _, svc := range services {
+		for _, svc := range updatable {
 			fmt.Printf("  %s %s...", tui.IconRunning, svc)
 
-			if err := compose.Restart(ctx, svc); err != nil {
+			// Recreates the container only when its image changed
+			if err := compose.UpService(ctx, svc); err != nil {
 				fmt.Printf("%s\n", tui.WarningStyle.Render(" skipped"))
 				fmt.Fprintf(os.Stderr, "  Failed to restart %s: %v\n", svc, err)
 				continue
@@ -113,8 +137,8 @@ func runUpdate(_ *cobra.Command, args []string) error {
 			if updateSafe {
 				healthy, err := compose.IsHealthy(ctx, svc)
 				if err != nil || !healthy {
-					// Wait up to 30 seconds for service to become healthy
-					if err := compose.WaitHealthy(ctx, svc, 30*time.Second); err != nil {
+					// Wait up to timeouts.health for service to become healthy
+					if err := compose.WaitHealthy(ctx, svc, cfg.Timeouts.HealthTimeout()); err != nil {
 						fmt.Fprintf(os.Stderr, "\nWarning: %s may not be fully healthy: %v\n", svc, err)
 					}
 				}
@@ -133,13 +157,7 @@ func runUpdate(_ *cobra.Command, args []string) error {
 }
 
 // getEnabledServicesOrdered returns enabled services in dependency order
-func getEnabledServicesOrdered(ctx context.Context, _ string) ([]string, error) {
-	// Load config
-	cfg, err := config.Load()
-	if err != nil {
-		cfg = config.DefaultConfig()
-	}
-
+func getEnabledServicesOrdered(ctx context.Context, cfg *config.Config) ([]string, error) {
 	// Get registry
 	reg, err := registry.NewWithDefaults()
 	if err != nil {
@@ -154,3 +172,16 @@ func getEnabledServicesOrdered(ctx context.Context, _ string) ([]string, error)
 
 	return graph.Order, nil
 }
+
+// splitByUpdatePolicy separates services that may be updated from those held
+// back by a pinned or notify-only update policy, preserving order
+func splitByUpdatePolicy(cfg *config.Config, services []string) (updatable, held []string) {
+	for _, svc := range services {
+		if cfg.UpdatePolicy(svc) == config.UpdatePolicyAuto {
+			updatable = append(updatable, svc)
+		} else {
+			held = append(held, svc)
+		}
+	}
+	return updatable, held
+}
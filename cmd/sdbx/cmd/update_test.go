@@ -0,0 +1,26 @@
+package cmd
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/maiko/sdbx/internal/config"
+)
+
+func TestSplitByUpdatePolicy(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Services = map[string]config.ServiceOverride{
+		"plex":   {UpdatePolicy: config.UpdatePolicyPinned},
+		"sonarr": {UpdatePolicy: config.UpdatePolicyNotifyOnly},
+		"radarr": {UpdatePolicy: config.UpdatePolicyAuto},
+	}
+
+	updatable, held := splitByUpdatePolicy(cfg, []string{"traefik", "plex", "sonarr", "radarr"})
+
+	if !slices.Equal(updatable, []string{"traefik", "radarr"}) {
+		t.Errorf("updatable = %v", updatable)
+	}
+	if !slices.Equal(held, []string{"plex", "sonarr"}) {
+		t.Errorf("held = %v", held)
+	}
+}
@@ -0,0 +1,244 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+
+	"github.com/charmbracelet/huh"
+	"github.com/spf13/cobra"
+
+	"github.com/maiko/sdbx/internal/config"
+	"github.com/maiko/sdbx/internal/generator"
+	"github.com/maiko/sdbx/internal/registry"
+	"github.com/maiko/sdbx/internal/tui"
+)
+
+var upgradeProjectCmd = &cobra.Command{
+	Use:   "upgrade-project",
+	Short: "Migrate a project created by an older SDBX version",
+	Long: `Bring a project up to date with the installed CLI.
+
+This command:
+  • Detects .sdbx.yaml and lock file schema versions older than this CLI
+  • Applies config migrations (e.g. expose_mode → expose.mode)
+  • Re-renders project files and shows a diff for review
+  • Lists breaking changes and service definition updates encountered
+
+Nothing is written until the diff is confirmed. Containers are not
+restarted; run 'sdbx up' afterwards.
+
+Examples:
+  sdbx upgrade-project --dry-run   # Show the plan and diff only
+  sdbx upgrade-project --yes       # Apply without prompting`,
+	RunE: runUpgradeProject,
+}
+
+var (
+	upgradeDryRun bool
+	upgradeYes    bool
+)
+
+func init() {
+	rootCmd.AddCommand(upgradeProjectCmd)
+
+	upgradeProjectCmd.Flags().BoolVar(&upgradeDryRun, "dry-run", false, "Show the upgrade plan without writing files")
+	upgradeProjectCmd.Flags().BoolVarP(&upgradeYes, "yes", "y", false, "Skip the confirmation prompt")
+}
+
+// upgradeNotes documents breaking changes introduced by each config schema version
+var upgradeNotes = map[int][]string{
+	2: {
+		"expose_mode was replaced by expose.mode",
+		"'sdbx lock' and 'sdbx backup' require a subcommand (e.g. 'sdbx lock generate')",
+		"'sdbx integrate' and 'sdbx secrets' were removed",
+	},
+}
+
+// upgradePlan describes what upgrade-project will change
+type upgradePlan struct {
+	ConfigVersion   int               `json:"config_version"`
+	TargetVersion   int               `json:"target_version"`
+	Migrations      []string          `json:"migrations,omitempty"`
+	BreakingChanges []string          `json:"breaking_changes,omitempty"`
+	LockVersion     int               `json:"lock_version,omitempty"`
+	LockCLIVersion  string            `json:"lock_cli_version,omitempty"`
+	RegistryChanges []string          `json:"registry_changes,omitempty"`
+	Files           map[string]string `json:"files,omitempty"` // Path → unified diff
+}
+
+// upToDate reports whether the plan changes nothing
+func (p *upgradePlan) upToDate() bool {
+	return p.ConfigVersion >= p.TargetVersion && len(p.Migrations) == 0 &&
+		p.LockVersion <= registry.LockFileVersion && len(p.Files) == 0
+}
+
+func runUpgradeProject(cmd *cobra.Command, _ []string) error {
+	projectDir, err := config.ProjectDir()
+	if err != nil {
+		return err
+	}
+
+	configVersion, err := config.FileSchemaVersion(filepath.Join(projectDir, ".sdbx.yaml"))
+	if err != nil {
+		return fmt.Errorf("failed to read .sdbx.yaml: %w\n\n  Try: sdbx doctor", err)
+	}
+	if configVersion > config.SchemaVersion {
+		return fmt.Errorf(".sdbx.yaml uses schema v%d but this CLI only supports v%d\n\n  Try: upgrade sdbx",
+			configVersion, config.SchemaVersion)
+	}
+
+	// Load applies the migrations in memory
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w\n\n  Try: sdbx doctor", err)
+	}
+	if err := cfg.Validate(); err != nil {
+		return fmt.Errorf("migrated configuration is invalid: %w", err)
+	}
+
+	plan := &upgradePlan{
+		ConfigVersion: configVersion,
+		TargetVersion: config.SchemaVersion,
+		Migrations:    cfg.AppliedMigrations,
+	}
+	for v := configVersion + 1; v <= config.SchemaVersion; v++ {
+		plan.BreakingChanges = append(plan.BreakingChanges, upgradeNotes[v]...)
+	}
+
+	ctx := commandContext(cmd)
+	reg, err := getRegistry()
+	if err != nil {
+		return err
+	}
+
+	lockPath := registry.GetLockFilePath(projectDir)
+	lock, lockErr := registry.NewLoader().LoadLockFile(lockPath)
+	if errors.Is(lockErr, registry.ErrLockChecksumMismatch) {
+		return lockLoadError(lockErr)
+	}
+	if lockErr == nil {
+		if lock.Metadata.Version > registry.LockFileVersion {
+			return fmt.Errorf(".sdbx.lock uses schema v%d but this CLI only supports v%d\n\n  Try: upgrade sdbx",
+				lock.Metadata.Version, registry.LockFileVersion)
+		}
+		plan.LockVersion = lock.Metadata.Version
+		plan.LockCLIVersion = lock.Metadata.CLIVersion
+		current, err := reg.GenerateLockFile(ctx, cfg)
+		if err != nil {
+			return fmt.Errorf("failed to resolve services: %w", err)
+		}
+		for _, d := range reg.DiffLockFiles(lock, current) {
+			plan.RegistryChanges = append(plan.RegistryChanges, d.Description)
+		}
+		sort.Strings(plan.RegistryChanges)
+	}
+
+	plan.Files, err = generator.RenderDiff(cfg, reg, projectDir)
+	if err != nil {
+		return err
+	}
+
+	if IsJSONOutput() && (upgradeDryRun || plan.upToDate()) {
+		return OutputJSON(plan)
+	}
+
+	if plan.upToDate() {
+		fmt.Println(tui.SuccessStyle.Render(fmt.Sprintf("✓ Project is up to date (schema v%d)", config.SchemaVersion)))
+		return nil
+	}
+
+	if !IsJSONOutput() {
+		printUpgradePlan(plan)
+	}
+
+	if upgradeDryRun {
+		fmt.Println(tui.MutedStyle.Render("Dry run: nothing was written. Re-run without --dry-run to upgrade."))
+		return nil
+	}
+
+	if !upgradeYes {
+		if !IsTUIEnabled() {
+			return fmt.Errorf("refusing to upgrade without confirmation\n\n  Try: sdbx upgrade-project --yes")
+		}
+		var confirm bool
+		if err := huh.NewConfirm().
+			Title(fmt.Sprintf("Apply the upgrade to %d files?", len(plan.Files))).
+			Value(&confirm).
+			Run(); err != nil {
+			return fmt.Errorf("confirmation prompt failed: %w", err)
+		}
+		if !confirm {
+			fmt.Println(tui.MutedStyle.Render("Aborted."))
+			return nil
+		}
+	}
+
+	gen := generator.NewGeneratorWithRegistry(cfg, projectDir, reg)
+	if err := gen.GenerateContext(ctx); err != nil {
+		return fmt.Errorf("failed to regenerate project: %w\n\n  Try: sdbx doctor", err)
+	}
+	if lockErr == nil {
+		if _, err := registry.NewLockManager(reg, Version).GenerateLockFile(ctx, cfg, lockPath); err != nil {
+			return fmt.Errorf("failed to update lock file: %w", err)
+		}
+	}
+
+	if IsJSONOutput() {
+		return OutputJSON(plan)
+	}
+
+	fmt.Println(tui.SuccessStyle.Render(fmt.Sprintf("✓ Project upgraded to schema v%d", config.SchemaVersion)))
+	printSkipped(gen.Skipped)
+	fmt.Println()
+	fmt.Println(tui.IconInfo + " Run 'sdbx up' to apply changes")
+	return nil
+}
+
+// printUpgradePlan shows the detected versions, migrations and file diffs
+func printUpgradePlan(plan *upgradePlan) {
+	fmt.Println()
+	fmt.Println(tui.TitleStyle.Render("SDBX Project Upgrade"))
+	fmt.Println()
+
+	fmt.Printf("  %s\n", tui.RenderKeyValue("Config schema", fmt.Sprintf("v%d → v%d", plan.ConfigVersion, plan.TargetVersion)))
+	if plan.LockVersion > 0 {
+		lockCLI := plan.LockCLIVersion
+		if lockCLI == "" {
+			lockCLI = "unknown"
+		}
+		fmt.Printf("  %s\n", tui.RenderKeyValue("Lock file", fmt.Sprintf("v%d (written by CLI %s, running %s)",
+			plan.LockVersion, lockCLI, Version)))
+	}
+	fmt.Println()
+
+	printUpgradeSection("Config migrations", plan.Migrations)
+	printUpgradeSection("Breaking changes", plan.BreakingChanges)
+	printUpgradeSection("Service definition updates", plan.RegistryChanges)
+
+	paths := make([]string, 0, len(plan.Files))
+	for path := range plan.Files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		fmt.Println(tui.SubtitleStyle.Render(path))
+		fmt.Print(plan.Files[path])
+		fmt.Println()
+	}
+	fmt.Println(tui.InfoStyle.Render(fmt.Sprintf("%d files will change", len(paths))))
+	fmt.Println()
+}
+
+// printUpgradeSection prints a titled bullet list, skipping empty lists
+func printUpgradeSection(title string, items []string) {
+	if len(items) == 0 {
+		return
+	}
+	fmt.Println(tui.SubtitleStyle.Render(title))
+	for _, item := range items {
+		fmt.Printf("  • %s\n", item)
+	}
+	fmt.Println()
+}
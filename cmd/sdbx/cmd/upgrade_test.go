@@ -0,0 +1,26 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/maiko/sdbx/internal/config"
+	"github.com/maiko/sdbx/internal/registry"
+)
+
+func TestUpgradePlanUpToDate(t *testing.T) {
+	plan := &upgradePlan{ConfigVersion: config.SchemaVersion, TargetVersion: config.SchemaVersion, LockVersion: registry.LockFileVersion}
+	if !plan.upToDate() {
+		t.Error("current plan should be up to date")
+	}
+
+	plan.ConfigVersion = 1
+	if plan.upToDate() {
+		t.Error("older config schema should need an upgrade")
+	}
+
+	plan.ConfigVersion = config.SchemaVersion
+	plan.Files = map[string]string{"compose.yaml": "@@ -1 +1 @@\n"}
+	if plan.upToDate() {
+		t.Error("pending file changes should need an upgrade")
+	}
+}
@@ -0,0 +1,190 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/maiko/sdbx/internal/auth"
+	"github.com/maiko/sdbx/internal/config"
+	"github.com/maiko/sdbx/internal/generator"
+	"github.com/maiko/sdbx/internal/qrcode"
+	"github.com/maiko/sdbx/internal/tui"
+)
+
+var urlsCmd = &cobra.Command{
+	Use:   "urls [services...]",
+	Short: "List every routed service with its URL, auth and internal address",
+	Long: `Print the inventory of the services Traefik exposes: their final URL,
+the authentication in front of them, their address on the Docker network
+and the ports published on the host. A credentials summary follows: where
+to log in, the users of the authentication backend and the API tokens.
+Passwords and token secrets are never shown.
+
+With --qr, a QR code of each URL is printed, to open the services from a
+phone right after setup.
+
+Examples:
+  sdbx urls
+  sdbx urls plex overseerr --qr`,
+	RunE: runURLs,
+}
+
+var urlsQR bool
+
+func init() {
+	rootCmd.AddCommand(urlsCmd)
+
+	urlsCmd.Flags().BoolVar(&urlsQR, "qr", false, "Print a QR code of each URL")
+}
+
+// urlsReport is the output of sdbx urls
+type urlsReport struct {
+	Domain      string                 `json:"domain"`
+	Services    []generator.ServiceURL `json:"services"`
+	Credentials credentialsSummary     `json:"credentials"`
+}
+
+// credentialsSummary tells how to log in, without any secret
+type credentialsSummary struct {
+	AuthMode  string   `json:"auth_mode"`
+	LoginURL  string   `json:"login_url,omitempty"` // Authelia portal
+	Users     []string `json:"users"`
+	UsersFile string   `json:"users_file"`
+	APITokens int      `json:"api_tokens"` // Unexpired tokens
+}
+
+func runURLs(cmd *cobra.Command, args []string) error {
+	projectDir, err := config.ProjectDir()
+	if err != nil {
+		return err
+	}
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w\n\n  Try: sdbx doctor", err)
+	}
+
+	reg, err := getRegistry()
+	if err != nil {
+		return err
+	}
+	urls, err := generator.NewGeneratorWithRegistry(cfg, projectDir, reg).URLs(commandContext(cmd))
+	if err != nil {
+		return err
+	}
+	// The login portal is found among every URL, before selecting services
+	report := urlsReport{
+		Domain:      cfg.Domain,
+		Services:    urls,
+		Credentials: summarizeCredentials(projectDir, cfg, urls),
+	}
+	if len(args) > 0 {
+		if urls, err = selectURLs(cfg, urls, args); err != nil {
+			return err
+		}
+		report.Services = urls
+	}
+	if IsJSONOutput() {
+		return OutputJSON(report)
+	}
+
+	fmt.Println(tui.TitleStyle.Render("Service URLs"))
+	fmt.Println()
+	table := tui.NewTable("Service", "URL", "Auth", "Internal", "Host Ports")
+	for _, u := range urls {
+		authLabel := u.Auth
+		if u.APITokens {
+			authLabel += " (API tokens)"
+		}
+		table.AddRow(u.Service, u.URL, authLabel, u.Internal, strings.Join(u.Published, ", "))
+	}
+	fmt.Println(table.Render())
+
+	if urlsQR {
+		for _, u := range urls {
+			code, err := qrcode.Encode(u.URL)
+			if err != nil {
+				fmt.Println(tui.WarningStyle.Render(fmt.Sprintf("%s: %v", u.Service, err)))
+				continue
+			}
+			fmt.Println()
+			fmt.Println(tui.InfoStyle.Render(u.Service) + "  " + u.URL)
+			fmt.Print(code.Terminal())
+		}
+	}
+
+	printCredentials(report.Credentials)
+	return nil
+}
+
+// selectURLs keeps the URLs of the services named in args
+func selectURLs(cfg *config.Config, urls []generator.ServiceURL, args []string) ([]generator.ServiceURL, error) {
+	var selected []generator.ServiceURL
+	for _, arg := range args {
+		name, err := cfg.ServiceName(strings.ToLower(arg))
+		if err != nil {
+			return nil, err
+		}
+		ref := cfg.ServiceRef(name)
+		found := false
+		for _, u := range urls {
+			if u.Service == ref {
+				selected = append(selected, u)
+				found = true
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("service %s is not routed\n\n  Try: sdbx urls", arg)
+		}
+	}
+	return selected, nil
+}
+
+// summarizeCredentials reads the users and API tokens of the project, and
+// finds the login portal among urls. Users that cannot be read are left
+// out, the project may not be generated yet.
+func summarizeCredentials(projectDir string, cfg *config.Config, urls []generator.ServiceURL) credentialsSummary {
+	store := auth.NewStore(projectDir, cfg)
+	summary := credentialsSummary{AuthMode: cfg.AuthMode(), UsersFile: store.Path(), Users: []string{}}
+	if !cfg.IsBasicAuth() {
+		for _, u := range urls {
+			if u.Service == "authelia" {
+				summary.LoginURL = u.URL
+			}
+		}
+	}
+	if users, err := store.List(); err == nil {
+		for _, u := range users {
+			summary.Users = append(summary.Users, u.Name)
+		}
+	}
+	if tokens, err := auth.NewTokenStore(projectDir).List(); err == nil {
+		now := time.Now()
+		for _, t := range tokens {
+			if !t.Expired(now) {
+				summary.APITokens++
+			}
+		}
+	}
+	return summary
+}
+
+func printCredentials(c credentialsSummary) {
+	fmt.Println()
+	fmt.Println(tui.TitleStyle.Render("Credentials"))
+	fmt.Println()
+	if c.LoginURL != "" {
+		fmt.Printf("  Login:       %s (Authelia, 2FA)\n", c.LoginURL)
+	} else {
+		fmt.Println("  Login:       Traefik basic auth, prompted by the browser")
+	}
+	if len(c.Users) == 0 {
+		fmt.Printf("  Users:       %s\n", tui.WarningStyle.Render("none - sdbx user add <name>"))
+	} else {
+		fmt.Printf("  Users:       %s\n", strings.Join(c.Users, ", "))
+	}
+	fmt.Printf("  API tokens:  %d active (sdbx token list)\n", c.APITokens)
+	fmt.Println(tui.MutedStyle.Render(fmt.Sprintf("  Passwords are never shown; users live in %s (sdbx user passwd <name> resets one)", c.UsersFile)))
+}
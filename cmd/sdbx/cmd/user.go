@@ -0,0 +1,359 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"slices"
+	"strings"
+
+	"github.com/charmbracelet/huh"
+	"github.com/spf13/cobra"
+
+	"github.com/maiko/sdbx/internal/auth"
+	"github.com/maiko/sdbx/internal/config"
+	"github.com/maiko/sdbx/internal/docker"
+	"github.com/maiko/sdbx/internal/tui"
+)
+
+var userCmd = &cobra.Command{
+	Use:   "user",
+	Short: "Manage the users allowed through authentication",
+	Long: `Manage the users allowed to log in to protected services.
+
+With auth.mode: authelia (default) users live in the Authelia users
+database (configs/authelia/users_database.yml). With auth.mode: basic
+they live in the htpasswd secret Traefik checks
+(secrets/basic_auth_users.txt). The service reading the file is restarted
+after a change when it is running.
+
+Examples:
+  sdbx user list               # List users
+  sdbx user add alice          # Add a user (prompts for the password)
+  sdbx user passwd admin       # Change a password
+  sdbx user remove alice       # Remove a user
+  sdbx user group add bob friends  # Restrict bob to the friends profile`,
+}
+
+var userListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List users",
+	Args:  cobra.NoArgs,
+	RunE:  runUserList,
+}
+
+var userAddCmd = &cobra.Command{
+	Use:   "add <name>",
+	Short: "Add a user",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runUserAdd,
+}
+
+var userPasswdCmd = &cobra.Command{
+	Use:   "passwd <name>",
+	Short: "Change a user's password",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runUserPasswd,
+}
+
+var userRemoveCmd = &cobra.Command{
+	Use:     "remove <name>",
+	Aliases: []string{"rm"},
+	Short:   "Remove a user",
+	Args:    cobra.ExactArgs(1),
+	RunE:    runUserRemove,
+}
+
+var userGroupCmd = &cobra.Command{
+	Use:   "group",
+	Short: "Manage the groups of users and the access profiles",
+	Long: `Manage the Authelia groups of users.
+
+Members of admins and users reach every protected service. Members of an
+access profile's group (access_profiles in .sdbx.yaml) only reach the
+profile's services, even when also in admins or users:
+
+  access_profiles:
+    - name: friends
+      services: [overseerr, plex]
+
+Run 'sdbx regenerate' after changing access_profiles. Groups need
+auth.mode: authelia.
+
+Examples:
+  sdbx user group list                # Groups, their services and members
+  sdbx user group add bob friends     # Restrict bob to overseerr and plex
+  sdbx user group remove bob users    # Take bob out of a group`,
+}
+
+var userGroupListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List groups with their services and members",
+	Args:  cobra.NoArgs,
+	RunE:  runUserGroupList,
+}
+
+var userGroupAddCmd = &cobra.Command{
+	Use:   "add <user> <group>",
+	Short: "Add a user to a group",
+	Args:  cobra.ExactArgs(2),
+	RunE:  runUserGroupAdd,
+}
+
+var userGroupRemoveCmd = &cobra.Command{
+	Use:     "remove <user> <group>",
+	Aliases: []string{"rm"},
+	Short:   "Remove a user from a group",
+	Args:    cobra.ExactArgs(2),
+	RunE:    runUserGroupRemove,
+}
+
+var userPasswordFlag string
+
+func init() {
+	rootCmd.AddCommand(userCmd)
+	userCmd.AddCommand(userListCmd)
+	userCmd.AddCommand(userAddCmd)
+	userCmd.AddCommand(userPasswdCmd)
+	userCmd.AddCommand(userRemoveCmd)
+	userCmd.AddCommand(userGroupCmd)
+	userGroupCmd.AddCommand(userGroupListCmd)
+	userGroupCmd.AddCommand(userGroupAddCmd)
+	userGroupCmd.AddCommand(userGroupRemoveCmd)
+
+	for _, c := range []*cobra.Command{userAddCmd, userPasswdCmd} {
+		c.Flags().StringVar(&userPasswordFlag, "password", "", "Password (prompted when omitted)")
+	}
+}
+
+// userStore opens the user store of the current project
+func userStore() (auth.Store, string, error) {
+	projectDir, err := config.ProjectDir()
+	if err != nil {
+		return nil, "", err
+	}
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to load config: %w", err)
+	}
+	return auth.NewStore(projectDir, cfg), projectDir, nil
+}
+
+func runUserList(_ *cobra.Command, _ []string) error {
+	store, _, err := userStore()
+	if err != nil {
+		return err
+	}
+	users, err := store.List()
+	if err != nil {
+		return fmt.Errorf("failed to read users: %w\n\n  Try: sdbx regenerate", err)
+	}
+
+	if IsJSONOutput() {
+		return OutputJSON(users)
+	}
+
+	table := tui.NewTable("Name", "Display Name", "Email", "Groups")
+	for _, u := range users {
+		table.AddRow(u.Name, u.DisplayName, u.Email, strings.Join(u.Groups, ", "))
+	}
+	fmt.Println(table.Render())
+	fmt.Println(tui.MutedStyle.Render(fmt.Sprintf("%d users in %s", len(users), store.Path())))
+	return nil
+}
+
+func runUserAdd(cmd *cobra.Command, args []string) error {
+	return setUserPassword(commandContext(cmd), args[0], true)
+}
+
+func runUserPasswd(cmd *cobra.Command, args []string) error {
+	return setUserPassword(commandContext(cmd), args[0], false)
+}
+
+// setUserPassword adds a user or changes an existing user's password
+func setUserPassword(ctx context.Context, name string, add bool) error {
+	if err := auth.ValidateUsername(name); err != nil {
+		return err
+	}
+	store, projectDir, err := userStore()
+	if err != nil {
+		return err
+	}
+
+	users, err := store.List()
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to read users: %w", err)
+	}
+	exists := false
+	for _, u := range users {
+		exists = exists || u.Name == name
+	}
+	switch {
+	case add && exists:
+		return fmt.Errorf("user %s already exists\n\n  Try: sdbx user passwd %s", name, name)
+	case !add && !exists:
+		return fmt.Errorf("user %s not found\n\n  Try: sdbx user add %s", name, name)
+	}
+
+	password := userPasswordFlag
+	if password == "" {
+		if err := huh.NewInput().
+			Title(fmt.Sprintf("Password for %s", name)).
+			EchoMode(huh.EchoModePassword).
+			Value(&password).
+			Validate(auth.ValidatePassword).
+			Run(); err != nil {
+			return err
+		}
+	}
+
+	if _, err := store.SetPassword(name, password); err != nil {
+		return err
+	}
+	if add {
+		fmt.Println(tui.SuccessStyle.Render(fmt.Sprintf("✓ Added user %s", name)))
+	} else {
+		fmt.Println(tui.SuccessStyle.Render(fmt.Sprintf("✓ Changed password of %s", name)))
+	}
+	return reloadUsers(ctx, projectDir, store)
+}
+
+func runUserRemove(cmd *cobra.Command, args []string) error {
+	store, projectDir, err := userStore()
+	if err != nil {
+		return err
+	}
+	if err := store.Remove(args[0]); err != nil {
+		return err
+	}
+	fmt.Println(tui.SuccessStyle.Render(fmt.Sprintf("✓ Removed user %s", args[0])))
+	return reloadUsers(commandContext(cmd), projectDir, store)
+}
+
+// userGroup is a group with the services its members reach
+type userGroup struct {
+	Name     string   `json:"name"`
+	Services []string `json:"services"` // Empty for every service
+	Members  []string `json:"members"`
+}
+
+func runUserGroupList(_ *cobra.Command, _ []string) error {
+	projectDir, err := config.ProjectDir()
+	if err != nil {
+		return err
+	}
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	users, err := auth.NewStore(projectDir, cfg).List()
+	if err != nil {
+		return fmt.Errorf("failed to read users: %w\n\n  Try: sdbx regenerate", err)
+	}
+
+	groups := make([]userGroup, 0, len(cfg.AccessGroups()))
+	for _, name := range cfg.AccessGroups() {
+		group := userGroup{Name: name, Services: []string{}, Members: []string{}}
+		if profile, ok := cfg.AccessProfile(name); ok {
+			group.Services = profile.Services
+		}
+		for _, u := range users {
+			if slices.Contains(u.Groups, name) {
+				group.Members = append(group.Members, u.Name)
+			}
+		}
+		groups = append(groups, group)
+	}
+
+	if IsJSONOutput() {
+		return OutputJSON(groups)
+	}
+
+	table := tui.NewTable("Group", "Services", "Members")
+	for _, g := range groups {
+		services := "all"
+		if len(g.Services) > 0 {
+			services = strings.Join(g.Services, ", ")
+		}
+		table.AddRow(g.Name, services, strings.Join(g.Members, ", "))
+	}
+	fmt.Println(table.Render())
+	return nil
+}
+
+func runUserGroupAdd(cmd *cobra.Command, args []string) error {
+	return setUserGroup(commandContext(cmd), args[0], args[1], true)
+}
+
+func runUserGroupRemove(cmd *cobra.Command, args []string) error {
+	return setUserGroup(commandContext(cmd), args[0], args[1], false)
+}
+
+// setUserGroup adds a user to a group or removes it
+func setUserGroup(ctx context.Context, name, group string, add bool) error {
+	projectDir, err := config.ProjectDir()
+	if err != nil {
+		return err
+	}
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	if add && !slices.Contains(cfg.AccessGroups(), group) {
+		return fmt.Errorf("unknown group %s (groups: %s)\n\n  Try: add it to access_profiles in .sdbx.yaml", group, strings.Join(cfg.AccessGroups(), ", "))
+	}
+
+	store := auth.NewStore(projectDir, cfg)
+	users, err := store.List()
+	if err != nil {
+		return fmt.Errorf("failed to read users: %w", err)
+	}
+	i := slices.IndexFunc(users, func(u auth.User) bool { return u.Name == name })
+	if i < 0 {
+		return fmt.Errorf("user %s not found\n\n  Try: sdbx user add %s", name, name)
+	}
+	groups := users[i].Groups
+	switch {
+	case add && slices.Contains(groups, group):
+		fmt.Println(tui.MutedStyle.Render(fmt.Sprintf("%s is already in %s", name, group)))
+		return nil
+	case !add && !slices.Contains(groups, group):
+		return fmt.Errorf("user %s is not in group %s", name, group)
+	case add:
+		groups = append(groups, group)
+	default:
+		groups = slices.DeleteFunc(slices.Clone(groups), func(g string) bool { return g == group })
+	}
+
+	if err := store.SetGroups(name, groups); err != nil {
+		return err
+	}
+	if add {
+		fmt.Println(tui.SuccessStyle.Render(fmt.Sprintf("✓ Added %s to %s", name, group)))
+		if profile, ok := cfg.AccessProfile(group); ok {
+			fmt.Println(tui.MutedStyle.Render(fmt.Sprintf("Members of %s only reach %s", group, strings.Join(profile.Services, ", "))))
+		}
+	} else {
+		fmt.Println(tui.SuccessStyle.Render(fmt.Sprintf("✓ Removed %s from %s", name, group)))
+	}
+	return reloadUsers(ctx, projectDir, store)
+}
+
+// reloadUsers restarts the service reading the users file when it is running
+func reloadUsers(ctx context.Context, projectDir string, store auth.Store) error {
+	compose := docker.NewCompose(projectDir)
+	services, err := compose.PS(ctx)
+	if err != nil {
+		return nil
+	}
+	for _, s := range services {
+		if s.Service == store.Service() && s.Running {
+			if err := compose.Restart(ctx, store.Service()); err != nil {
+				return fmt.Errorf("failed to restart %s: %w\n\n  Try: sdbx restart %s", store.Service(), err, store.Service())
+			}
+			fmt.Println(tui.MutedStyle.Render(fmt.Sprintf("Restarted %s", store.Service())))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,168 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/maiko/sdbx/internal/config"
+	"github.com/maiko/sdbx/internal/doctor"
+	"github.com/maiko/sdbx/internal/tui"
+)
+
+var verifyCmd = &cobra.Command{
+	Use:   "verify",
+	Short: "Run end-to-end smoke tests against the running stack",
+	Long: `Verify a deployment from the outside in.
+
+Checks include:
+  • Every routed service answers through Traefik (or the tunnel)
+  • Cloudflare tunnel has registered edge connections (cloudflared mode)
+  • Torrent traffic exits through the VPN, not the host IP (if enabled)
+  • Prowlarr is linked to, and can reach, each enabled *arr
+  • External dependencies declared by enabled services are reachable
+
+Protected services normally redirect to Authelia. Pass an Authelia session
+cookie with --session (or SDBX_AUTHELIA_SESSION) to check that they serve
+content, or --header to send a bypass header configured in Authelia.
+
+Exits with a non-zero status if any check fails, so it can gate scripts:
+  sdbx up && sdbx verify`,
+	RunE: runVerify,
+}
+
+var (
+	verifySession string
+	verifyHeaders []string
+)
+
+func init() {
+	rootCmd.AddCommand(verifyCmd)
+
+	verifyCmd.Flags().StringVar(&verifySession, "session", "", "Authelia session cookie value for authenticated checks")
+	verifyCmd.Flags().StringArrayVar(&verifyHeaders, "header", nil, "Extra header sent with route checks (\"Name: value\", repeatable)")
+}
+
+func runVerify(cmd *cobra.Command, _ []string) error {
+	projectDir, err := config.ProjectDir()
+	if err != nil {
+		return err
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w\n\n  Try: sdbx doctor", err)
+	}
+
+	ctx := commandContext(cmd)
+
+	reg, err := getRegistry()
+	if err != nil {
+		return err
+	}
+	graph, err := reg.Resolve(ctx, cfg)
+	if err != nil {
+		return fmt.Errorf("failed to resolve services: %w", err)
+	}
+
+	var routed []string
+	for _, name := range graph.Order {
+		resolved := graph.Services[name]
+		if resolved.Enabled && resolved.FinalDefinition.Routing.Enabled {
+			routed = append(routed, name)
+		}
+	}
+
+	external, err := graph.ExternalDependencies(cfg)
+	if err != nil {
+		return err
+	}
+
+	verifier := doctor.NewVerifier(cfg, projectDir, routed)
+	verifier.External = external
+	if verifySession == "" {
+		verifySession = os.Getenv("SDBX_AUTHELIA_SESSION")
+	}
+	if verifySession != "" {
+		verifier.SetSession(verifySession)
+	}
+	for _, header := range verifyHeaders {
+		name, value, ok := strings.Cut(header, ":")
+		if !ok {
+			return fmt.Errorf("invalid header %q: expected \"Name: value\"", header)
+		}
+		verifier.SetHeader(strings.TrimSpace(name), strings.TrimSpace(value))
+	}
+
+	var checks []doctor.Check
+	if IsTUIEnabled() && !IsJSONOutput() {
+		err = tui.RunWithSpinner("Verifying deployment...", func() error {
+			checks = verifier.Run(ctx)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	} else {
+		checks = verifier.Run(ctx)
+	}
+
+	failed := 0
+	for _, check := range checks {
+		if check.Status == doctor.StatusFailed {
+			failed++
+		}
+	}
+
+	if IsJSONOutput() {
+		if err := OutputJSON(map[string]interface{}{
+			"passed": failed == 0,
+			"failed": failed,
+			"checks": checks,
+		}); err != nil {
+			return err
+		}
+	} else {
+		printVerifyReport(checks, failed)
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("verification failed: %d of %d checks failed", failed, len(checks))
+	}
+	return nil
+}
+
+// printVerifyReport renders verification results as a checklist with a summary box
+func printVerifyReport(checks []doctor.Check, failed int) {
+	fmt.Println()
+	fmt.Println(tui.TitleStyle.Render("SDBX Verify"))
+	fmt.Println()
+
+	checklist := tui.NewCheckList()
+	for _, check := range checks {
+		idx := checklist.Add(check.Name)
+		status := "success"
+		if check.Status == doctor.StatusFailed {
+			status = "error"
+		}
+		detail := check.Message
+		if check.Duration > 0 {
+			detail += fmt.Sprintf(" (%s)", check.Duration.Round(time.Millisecond))
+		}
+		checklist.SetStatus(idx, status, detail)
+	}
+	fmt.Println(checklist.Render())
+
+	fmt.Println()
+	if failed == 0 {
+		fmt.Print(tui.RenderSuccessBox("Deployment verified",
+			fmt.Sprintf("%d checks passed", len(checks))))
+	} else {
+		fmt.Print(tui.RenderErrorBox("Verification failed",
+			fmt.Sprintf("%d passed, %d failed\n\nRun 'sdbx status' and 'sdbx logs <service>' to investigate.", len(checks)-failed, failed)))
+	}
+	fmt.Println()
+}
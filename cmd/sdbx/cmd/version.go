@@ -5,6 +5,8 @@ import (
 	"runtime"
 
 	"github.com/spf13/cobra"
+
+	"github.com/maiko/sdbx/internal/registry"
 )
 
 var (
@@ -18,6 +20,7 @@ func SetVersionInfo(version, commit, date string) {
 	Version = version
 	Commit = commit
 	BuildDate = date
+	registry.SetCLIVersion(version)
 }
 
 // VersionInfo holds version details for JSON output
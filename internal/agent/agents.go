@@ -0,0 +1,107 @@
+package agent
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/maiko/sdbx/internal/auth"
+)
+
+// ErrAgentNotFound is returned for agents missing from the agents file
+var ErrAgentNotFound = errors.New("agent not found")
+
+// Agent is a named agent the control plane manages
+type Agent struct {
+	Name  string `yaml:"name" json:"name"`
+	URL   string `yaml:"url" json:"url"` // e.g. https://seedbox.lan:7070
+	Token string `yaml:"token" json:"-"` // API token of the agent's project
+}
+
+// Agents is the agents file of the control plane, agents.yaml next to
+// sources.yaml
+type Agents struct {
+	Agents []Agent `yaml:"agents"`
+}
+
+// AgentsPath returns the path of the user's agents.yaml
+func AgentsPath() string {
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".config", "sdbx", "agents.yaml")
+}
+
+// LoadAgents reads an agents file; a missing file has no agents
+func LoadAgents(path string) (*Agents, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return &Agents{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	var agents Agents
+	if err := yaml.Unmarshal(data, &agents); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return &agents, nil
+}
+
+// Save writes the agents file, only readable by its owner since it holds
+// the agents' tokens
+func (a *Agents) Save(path string) error {
+	data, err := yaml.Marshal(a)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o600)
+}
+
+// Get returns an agent by name
+func (a *Agents) Get(name string) (Agent, error) {
+	for _, agent := range a.Agents {
+		if agent.Name == name {
+			return agent, nil
+		}
+	}
+	return Agent{}, fmt.Errorf("%w: %s", ErrAgentNotFound, name)
+}
+
+// Add adds an agent, or replaces the one of the same name
+func (a *Agents) Add(agent Agent) error {
+	if err := auth.ValidateUsername(agent.Name); err != nil {
+		return fmt.Errorf("invalid agent name %q (letters, digits, dots, dashes and underscores)", agent.Name)
+	}
+	u, err := url.Parse(agent.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid agent URL %q - use http(s)://host:port", agent.URL)
+	}
+	if agent.Token == "" {
+		return errors.New("agents need an API token of their project (sdbx token create on the agent)")
+	}
+
+	if i := slices.IndexFunc(a.Agents, func(x Agent) bool { return x.Name == agent.Name }); i >= 0 {
+		a.Agents[i] = agent
+		return nil
+	}
+	a.Agents = append(a.Agents, agent)
+	return nil
+}
+
+// Remove removes an agent by name
+func (a *Agents) Remove(name string) error {
+	i := slices.IndexFunc(a.Agents, func(x Agent) bool { return x.Name == name })
+	if i < 0 {
+		return fmt.Errorf("%w: %s", ErrAgentNotFound, name)
+	}
+	a.Agents = slices.Delete(a.Agents, i, i+1)
+	return nil
+}
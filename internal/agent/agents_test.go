@@ -0,0 +1,78 @@
+package agent
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestAgents(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sdbx", "agents.yaml")
+	agents, err := LoadAgents(path)
+	if err != nil || len(agents.Agents) != 0 {
+		t.Fatalf("LoadAgents(missing) = %+v, %v", agents, err)
+	}
+
+	for _, invalid := range []Agent{
+		{Name: "bad name", URL: "http://box:7070", Token: "sdbx_x"},
+		{Name: "box", URL: "box:7070", Token: "sdbx_x"},
+		{Name: "box", URL: "http://box:7070"},
+	} {
+		if err := agents.Add(invalid); err == nil {
+			t.Errorf("Add(%+v) should fail", invalid)
+		}
+	}
+	if err := agents.Add(Agent{Name: "box", URL: "http://box:7070", Token: "sdbx_a"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := agents.Add(Agent{Name: "box", URL: "https://box:7070", Token: "sdbx_b"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := agents.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0o600 {
+		t.Errorf("agents file mode = %v, %v", info.Mode(), err)
+	}
+
+	loaded, err := LoadAgents(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := loaded.Get("box"); err != nil || got.URL != "https://box:7070" || got.Token != "sdbx_b" || len(loaded.Agents) != 1 {
+		t.Errorf("Get(box) = %+v, %v", got, err)
+	}
+	if err := loaded.Remove("box"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loaded.Get("box"); !errors.Is(err, ErrAgentNotFound) {
+		t.Errorf("Get(removed) error = %v", err)
+	}
+	if err := loaded.Remove("box"); !errors.Is(err, ErrAgentNotFound) {
+		t.Errorf("Remove(removed) error = %v", err)
+	}
+}
+
+func TestProjectPath(t *testing.T) {
+	for path, ok := range map[string]bool{
+		"configs/traefik/dynamic.yml": true,
+		".sdbx.yaml":                  true,
+		"/compose.yaml":               true,
+		"":                            false,
+		"../outside":                  false,
+		"configs/../../outside":       false,
+		"secrets/vpn.txt":             false,
+		".git/config":                 false,
+		".sdbx.tokens.yaml":           false,
+		".sdbx.events.log.1":          false,
+		"./configs/app.yml":           true,
+		"./secrets/vpn.txt":           false,
+		"configs/../secrets/vpn.txt":  false,
+		"./.sdbx.tokens.yaml":         false,
+	} {
+		if _, err := ProjectPath(path); (err == nil) != ok {
+			t.Errorf("ProjectPath(%q) error = %v, want ok %v", path, err, ok)
+		}
+	}
+}
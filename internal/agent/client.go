@@ -0,0 +1,228 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/maiko/sdbx/internal/docker"
+)
+
+// ErrNotFound is returned for files and directories the agent does not have
+var ErrNotFound = errors.New("not found on the agent")
+
+// Client calls the API of an agent
+type Client struct {
+	agent Agent
+	http  *http.Client
+}
+
+// NewClient creates a client of an agent. Requests are bounded by their
+// context only, as compose actions and log streams run for long.
+func NewClient(agent Agent) *Client {
+	return &Client{agent: agent, http: &http.Client{}}
+}
+
+// Agent returns the agent the client calls
+func (c *Client) Agent() Agent { return c.agent }
+
+// Info describes the agent and its project
+func (c *Client) Info(ctx context.Context) (*Info, error) {
+	var info Info
+	if err := c.call(ctx, http.MethodGet, "/info", nil, &info); err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
+
+// Services lists the service containers of the agent's project
+func (c *Client) Services(ctx context.Context) ([]docker.Service, error) {
+	var services []docker.Service
+	if err := c.call(ctx, http.MethodGet, "/services", nil, &services); err != nil {
+		return nil, err
+	}
+	return services, nil
+}
+
+// Compose runs a compose action (see Actions) on services of the agent
+func (c *Client) Compose(ctx context.Context, action string, services ...string) error {
+	return c.call(ctx, http.MethodPost, "/compose/"+url.PathEscape(action), ComposeRequest{Services: services}, nil)
+}
+
+// Logs copies the last tail log lines of a service to w, then the new ones
+// until ctx is done with follow
+func (c *Client) Logs(ctx context.Context, service string, tail int, follow bool, w io.Writer) error {
+	query := url.Values{"tail": {strconv.Itoa(tail)}}
+	if follow {
+		query.Set("follow", "true")
+	}
+	resp, err := c.do(ctx, http.MethodGet, "/logs/"+url.PathEscape(service)+"?"+query.Encode(), nil, "")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if _, err := io.Copy(w, resp.Body); err != nil && ctx.Err() == nil {
+		return err
+	}
+	return nil
+}
+
+// ListFiles describes the files under dir of the agent's project, all of
+// them for ""
+func (c *Client) ListFiles(ctx context.Context, dir string) ([]FileInfo, error) {
+	path := "/files"
+	if dir != "" {
+		path += "?" + url.Values{"dir": {dir}}.Encode()
+	}
+	var files []FileInfo
+	if err := c.call(ctx, http.MethodGet, path, nil, &files); err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+// GetFile returns a file of the agent's project
+func (c *Client) GetFile(ctx context.Context, path string) ([]byte, error) {
+	resp, err := c.do(ctx, http.MethodGet, filesPath(path), nil, "")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	return io.ReadAll(resp.Body)
+}
+
+// PutFile writes a file of the agent's project
+func (c *Client) PutFile(ctx context.Context, path string, data []byte) (*FileInfo, error) {
+	resp, err := c.do(ctx, http.MethodPut, filesPath(path), bytes.NewReader(data), "application/octet-stream")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	var info FileInfo
+	if err := json.NewDecoder(resp.Body).Decode(&info); err != nil {
+		return nil, fmt.Errorf("invalid response from agent %s: %w", c.agent.Name, err)
+	}
+	return &info, nil
+}
+
+// Sync pushes the files under paths of a local project (see SyncPaths)
+// that differ on the agent, and returns them. Files only the agent has are
+// kept. With dryRun nothing is written.
+func (c *Client) Sync(ctx context.Context, projectDir string, paths []string, dryRun bool) ([]FileInfo, error) {
+	var changed []FileInfo
+	for _, path := range paths {
+		rel, err := ProjectPath(path)
+		if err != nil {
+			return nil, err
+		}
+		local, err := ListFiles(projectDir, filepath.Join(projectDir, rel))
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		remote, err := c.ListFiles(ctx, filepath.ToSlash(rel))
+		if err != nil && !errors.Is(err, ErrNotFound) {
+			return nil, err
+		}
+		hashes := make(map[string]string, len(remote))
+		for _, f := range remote {
+			hashes[f.Path] = f.SHA256
+		}
+
+		for _, f := range local {
+			if hashes[f.Path] == f.SHA256 {
+				continue
+			}
+			changed = append(changed, f)
+			if dryRun {
+				continue
+			}
+			data, err := os.ReadFile(filepath.Join(projectDir, filepath.FromSlash(f.Path)))
+			if err != nil {
+				return changed, err
+			}
+			if _, err := c.PutFile(ctx, f.Path, data); err != nil {
+				return changed, err
+			}
+		}
+	}
+	return changed, nil
+}
+
+// filesPath returns the API path of a project file
+func filesPath(path string) string {
+	segments := strings.Split(strings.TrimPrefix(filepath.ToSlash(path), "/"), "/")
+	for i, s := range segments {
+		segments[i] = url.PathEscape(s)
+	}
+	return "/files/" + strings.Join(segments, "/")
+}
+
+// call sends a JSON request and decodes the JSON response into out
+func (c *Client) call(ctx context.Context, method, path string, in, out any) error {
+	var body io.Reader
+	contentType := ""
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body, contentType = bytes.NewReader(data), "application/json"
+	}
+	resp, err := c.do(ctx, method, path, body, contentType)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("invalid response from agent %s: %w", c.agent.Name, err)
+	}
+	return nil
+}
+
+// do sends a request to the agent and returns its response when
+// successful, or the error the agent reported
+func (c *Client) do(ctx context.Context, method, path string, body io.Reader, contentType string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimSuffix(c.agent.URL, "/")+APIPrefix+path, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.agent.Token)
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("agent %s unreachable: %w", c.agent.Name, err)
+	}
+	if resp.StatusCode/100 == 2 {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+
+	var apiErr ErrorResponse
+	_ = json.NewDecoder(io.LimitReader(resp.Body, 64<<10)).Decode(&apiErr)
+	if apiErr.Error == "" {
+		apiErr.Error = resp.Status
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("agent %s: %s: %w", c.agent.Name, apiErr.Error, ErrNotFound)
+	}
+	return nil, fmt.Errorf("agent %s: %s", c.agent.Name, apiErr.Error)
+}
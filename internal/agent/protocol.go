@@ -0,0 +1,114 @@
+// Package agent implements remote agent mode. A lightweight agent runs on
+// each seedbox ('sdbx agent serve') and serves compose operations, log
+// streaming and file sync for its project over a REST API; the CLI and the
+// web UI act as the control plane, managing several named agents with the
+// Client.
+//
+// The protocol is JSON over HTTP(S) under APIPrefix. Requests carry one of
+// the project's API tokens ('sdbx token create') as "Authorization: Bearer
+// <token>": read tokens may list services and read logs, write tokens may
+// also run compose actions and list, fetch and write files, which hold the
+// credentials of services. Errors are returned as an ErrorResponse with a
+// non-2xx status.
+//
+//	GET  /v1/info                    Info
+//	GET  /v1/services                []docker.Service, stopped ones included
+//	POST /v1/compose/{action}        ComposeRequest, see Actions
+//	GET  /v1/logs/{service}          Plain text; ?tail=N, ?follow=true streams
+//	GET  /v1/files?dir=configs       []FileInfo, recursively
+//	GET  /v1/files/{path...}         Raw content
+//	PUT  /v1/files/{path...}         Raw content, written atomically
+package agent
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/maiko/sdbx/internal/auth"
+	"github.com/maiko/sdbx/internal/events"
+)
+
+// APIPrefix starts every path of the agent API; it changes with
+// incompatible versions of the protocol
+const APIPrefix = "/v1"
+
+// DefaultPort is the port agents listen on unless told otherwise
+const DefaultPort = 7070
+
+// maxFileSize bounds the files written through the API
+const maxFileSize = 16 << 20
+
+// Compose actions
+const (
+	ActionUp      = "up"      // Create and start services, all without a list
+	ActionDown    = "down"    // Remove the project; stops the listed services only
+	ActionStart   = "start"   // Start stopped services
+	ActionStop    = "stop"    // Stop services
+	ActionRestart = "restart" // Restart services
+	ActionPull    = "pull"    // Pull images, all without a list
+)
+
+// Actions are the compose actions an agent runs
+var Actions = []string{ActionUp, ActionDown, ActionStart, ActionStop, ActionRestart, ActionPull}
+
+// Info describes an agent and its project
+type Info struct {
+	Name       string `json:"name"`
+	Version    string `json:"version"` // sdbx version of the agent
+	Hostname   string `json:"hostname"`
+	ProjectDir string `json:"project_dir"`
+	Domain     string `json:"domain,omitempty"`
+	Offline    bool   `json:"offline,omitempty"`
+}
+
+// ComposeRequest is the body of a compose action
+type ComposeRequest struct {
+	Services []string `json:"services,omitempty"`
+}
+
+// ComposeResponse reports a compose action that succeeded
+type ComposeResponse struct {
+	Action   string   `json:"action"`
+	Services []string `json:"services,omitempty"`
+}
+
+// FileInfo describes a file of the project, for sync
+type FileInfo struct {
+	Path     string    `json:"path"` // Relative to the project, slash-separated
+	Size     int64     `json:"size"`
+	SHA256   string    `json:"sha256"`
+	Modified time.Time `json:"modified"`
+}
+
+// ErrorResponse is the body of failed requests
+type ErrorResponse struct {
+	Error string `json:"error"`
+}
+
+// privateFiles are project files never served nor written through the
+// API: secret values and the credentials of the web UI and the agent stay
+// on the box
+var privateFiles = []string{"secrets", ".git", auth.TokensFile, auth.SharesFile, events.LogFile}
+
+// SyncPaths are the project files 'sdbx agent sync' pushes by default: the
+// configuration and what generation produced from it
+var SyncPaths = []string{".sdbx.yaml", ".sdbx.lock", ".env", "compose.yaml", "configs"}
+
+// ProjectPath checks a path of the API and returns it relative to the
+// project, in the form of the OS
+func ProjectPath(path string) (string, error) {
+	rel := filepath.FromSlash(strings.TrimPrefix(path, "/"))
+	if rel == "" || !filepath.IsLocal(rel) {
+		return "", fmt.Errorf("invalid path %q: must be inside the project", path)
+	}
+	// Cleaned first, so ./secrets and configs/../secrets are private too
+	rel = filepath.Clean(rel)
+	first, _, _ := strings.Cut(filepath.ToSlash(rel), "/")
+	if slices.Contains(privateFiles, first) || strings.HasPrefix(first, events.LogFile) {
+		return "", fmt.Errorf("%s is private to the agent", path)
+	}
+	return rel, nil
+}
@@ -0,0 +1,467 @@
+package agent
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/maiko/sdbx/internal/auth"
+	"github.com/maiko/sdbx/internal/config"
+	"github.com/maiko/sdbx/internal/docker"
+	"github.com/maiko/sdbx/internal/events"
+	"github.com/maiko/sdbx/internal/registry"
+)
+
+const (
+	// defaultLogLines is the number of log lines returned without ?tail
+	defaultLogLines = 100
+
+	// readHeaderTimeout bounds slow clients; there is no write timeout, as
+	// log streams and compose actions run for long
+	readHeaderTimeout = 10 * time.Second
+	shutdownTimeout   = 10 * time.Second
+)
+
+// requestError is a request the agent refuses, answered with 400
+type requestError struct{ msg string }
+
+func (e *requestError) Error() string { return e.msg }
+
+// Server is the agent API of a project
+type Server struct {
+	Name       string
+	Version    string
+	ProjectDir string
+	Compose    *docker.Compose
+	Tokens     *auth.TokenStore
+	Events     *events.Log
+}
+
+// NewServer creates the agent of a project
+func NewServer(name, projectDir, version string) *Server {
+	compose := docker.NewCompose(projectDir)
+	compose.Offline = registry.Offline()
+	return &Server{
+		Name:       name,
+		Version:    version,
+		ProjectDir: projectDir,
+		Compose:    compose,
+		Tokens:     auth.NewTokenStore(projectDir),
+		Events:     events.NewLog(projectDir),
+	}
+}
+
+// Handler returns the HTTP handler of the API
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET "+APIPrefix+"/info", s.handleInfo)
+	mux.HandleFunc("GET "+APIPrefix+"/services", s.handleServices)
+	mux.HandleFunc("POST "+APIPrefix+"/compose/{action}", s.handleCompose)
+	mux.HandleFunc("GET "+APIPrefix+"/logs/{service}", s.handleLogs)
+	mux.HandleFunc("GET "+APIPrefix+"/files", s.handleListFiles)
+	mux.HandleFunc("GET "+APIPrefix+"/files/{path...}", s.handleGetFile)
+	mux.HandleFunc("PUT "+APIPrefix+"/files/{path...}", s.handlePutFile)
+	return s.authenticate(mux)
+}
+
+// ListenAndServe serves the API on addr until ctx is done, over TLS when
+// certFile and keyFile are set
+func (s *Server) ListenAndServe(ctx context.Context, addr, certFile, keyFile string) error {
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           s.Handler(),
+		ReadHeaderTimeout: readHeaderTimeout,
+		BaseContext:       func(net.Listener) context.Context { return ctx },
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		var err error
+		if certFile != "" {
+			err = srv.ListenAndServeTLS(certFile, keyFile)
+		} else {
+			err = srv.ListenAndServe()
+		}
+		if !errors.Is(err, http.ErrServerClosed) {
+			errCh <- err
+		}
+	}()
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		return srv.Shutdown(shutdownCtx)
+	}
+}
+
+// authenticate checks the API token of every request against its scope,
+// and records the changes made with it in the events log
+func (s *Server) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		scheme, secret, _ := strings.Cut(r.Header.Get("Authorization"), " ")
+		if !strings.EqualFold(scheme, "Bearer") || secret == "" {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="sdbx-agent"`)
+			writeError(w, http.StatusUnauthorized, errors.New("missing API token"))
+			return
+		}
+		token, err := s.Tokens.Verify(secret, time.Now())
+		if err != nil {
+			w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+			writeError(w, http.StatusUnauthorized, err)
+			return
+		}
+		if !token.AllowsMethod(r.Method) {
+			writeError(w, http.StatusForbidden, fmt.Errorf("token %s is read-only", token.Name))
+			return
+		}
+		// Project files hold configuration with credentials of the services
+		// (API keys, passwords in configs/), so reading them needs write scope
+		if token.Scope != auth.ScopeWrite && isFilesPath(r.URL.Path) {
+			writeError(w, http.StatusForbidden, fmt.Errorf("token %s is read-only and cannot access project files", token.Name))
+			return
+		}
+
+		if r.Method == http.MethodGet || r.Method == http.MethodHead {
+			next.ServeHTTP(w, r)
+			return
+		}
+		rw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rw, r)
+		err = s.Events.Record(events.Event{
+			Source: events.SourceAgent,
+			User:   "token:" + token.Name,
+			Action: r.Method + " " + r.URL.Path,
+			Status: rw.status,
+		})
+		if err != nil {
+			log.Printf("Warning [audit]: %v", err)
+		}
+	})
+}
+
+func (s *Server) handleInfo(w http.ResponseWriter, _ *http.Request) {
+	hostname, _ := os.Hostname()
+	info := Info{
+		Name:       s.Name,
+		Version:    s.Version,
+		Hostname:   hostname,
+		ProjectDir: s.ProjectDir,
+		Offline:    s.Compose.Offline,
+	}
+	if data, err := os.ReadFile(filepath.Join(s.ProjectDir, ".sdbx.yaml")); err == nil {
+		if cfg, err := config.Parse(data); err == nil {
+			info.Domain = cfg.Domain
+		}
+	}
+	writeJSON(w, http.StatusOK, info)
+}
+
+func (s *Server) handleServices(w http.ResponseWriter, r *http.Request) {
+	services, err := s.Compose.PSAll(r.Context())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if services == nil {
+		services = []docker.Service{}
+	}
+	writeJSON(w, http.StatusOK, services)
+}
+
+func (s *Server) handleCompose(w http.ResponseWriter, r *http.Request) {
+	action := r.PathValue("action")
+	var req ComposeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid request: %w", err))
+		return
+	}
+
+	if err := s.runCompose(r.Context(), action, req.Services); err != nil {
+		status := http.StatusInternalServerError
+		var reqErr *requestError
+		if errors.As(err, &reqErr) {
+			status = http.StatusBadRequest
+		}
+		writeError(w, status, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, ComposeResponse{Action: action, Services: req.Services})
+}
+
+// runCompose runs a compose action on services, or the whole project for
+// the actions allowing it
+func (s *Server) runCompose(ctx context.Context, action string, services []string) error {
+	each := func(fn func(context.Context, string) error) error {
+		if len(services) == 0 {
+			return &requestError{fmt.Sprintf("%s needs services", action)}
+		}
+		for _, svc := range services {
+			if err := fn(ctx, svc); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	switch action {
+	case ActionUp:
+		if len(services) == 0 {
+			return s.Compose.Up(ctx)
+		}
+		return each(s.Compose.UpService)
+	case ActionDown:
+		if len(services) == 0 {
+			return s.Compose.Down(ctx)
+		}
+		return each(s.Compose.Stop)
+	case ActionStart:
+		return each(s.Compose.Start)
+	case ActionStop:
+		return each(s.Compose.Stop)
+	case ActionRestart:
+		return each(s.Compose.Restart)
+	case ActionPull:
+		return s.Compose.Pull(ctx, services...)
+	}
+	return &requestError{fmt.Sprintf("unknown action %q (must be one of %s)", action, strings.Join(Actions, ", "))}
+}
+
+func (s *Server) handleLogs(w http.ResponseWriter, r *http.Request) {
+	service := r.PathValue("service")
+	lines := defaultLogLines
+	if tail := r.URL.Query().Get("tail"); tail != "" {
+		n, err := strconv.Atoi(tail)
+		if err != nil || n < 0 {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid tail %q", tail))
+			return
+		}
+		lines = n
+	}
+
+	if r.URL.Query().Get("follow") != "true" {
+		output, err := s.Compose.Logs(r.Context(), service, lines, false)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		_, _ = io.WriteString(w, output)
+		return
+	}
+
+	// Streamed until the client goes away, which cancels the command
+	cmd, err := s.Compose.LogsStream(r.Context(), service, lines)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	out := &flushWriter{w: w, flusher: http.NewResponseController(w)}
+	cmd.Stdout, cmd.Stderr = out, out
+	if err := cmd.Run(); err != nil && r.Context().Err() == nil {
+		log.Printf("Warning [logs %s]: %v", service, err)
+	}
+}
+
+// isFilesPath reports whether a request path is under the files API
+func isFilesPath(path string) bool {
+	return path == APIPrefix+"/files" || strings.HasPrefix(path, APIPrefix+"/files/")
+}
+
+func (s *Server) handleListFiles(w http.ResponseWriter, r *http.Request) {
+	root := s.ProjectDir
+	if dir := r.URL.Query().Get("dir"); dir != "" {
+		rel, err := ProjectPath(dir)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		root = filepath.Join(s.ProjectDir, rel)
+	}
+
+	files, err := ListFiles(s.ProjectDir, root)
+	if errors.Is(err, fs.ErrNotExist) {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, files)
+}
+
+func (s *Server) handleGetFile(w http.ResponseWriter, r *http.Request) {
+	rel, err := ProjectPath(r.PathValue("path"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	f, err := os.Open(filepath.Join(s.ProjectDir, rel))
+	if errors.Is(err, fs.ErrNotExist) {
+		writeError(w, http.StatusNotFound, fmt.Errorf("%s not found", r.PathValue("path")))
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil || !info.Mode().IsRegular() {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("%s is not a file", r.PathValue("path")))
+		return
+	}
+	w.Header().Set("Content-Type", "application/octet-stream")
+	http.ServeContent(w, r, info.Name(), info.ModTime(), f)
+}
+
+func (s *Server) handlePutFile(w http.ResponseWriter, r *http.Request) {
+	rel, err := ProjectPath(r.PathValue("path"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxFileSize))
+	if err != nil {
+		writeError(w, http.StatusRequestEntityTooLarge, err)
+		return
+	}
+
+	info, err := writeFile(filepath.Join(s.ProjectDir, rel), data)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	info.Path = filepath.ToSlash(rel)
+	writeJSON(w, http.StatusOK, info)
+}
+
+// ListFiles describes the files under root, a directory or file of the
+// project, leaving out those private to the agent
+func ListFiles(projectDir, root string) ([]FileInfo, error) {
+	files := []FileInfo{}
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(projectDir, path)
+		if err != nil {
+			return err
+		}
+		if rel != "." {
+			if _, err := ProjectPath(filepath.ToSlash(rel)); err != nil {
+				if d.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := fileInfo(path)
+		if err != nil {
+			return err
+		}
+		info.Path = filepath.ToSlash(rel)
+		files = append(files, info)
+		return nil
+	})
+	return files, err
+}
+
+// fileInfo returns the size, hash and modification time of a file
+func fileInfo(path string) (FileInfo, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return FileInfo{}, err
+	}
+	defer f.Close()
+	h := sha256.New()
+	size, err := io.Copy(h, f)
+	if err != nil {
+		return FileInfo{}, err
+	}
+	stat, err := f.Stat()
+	if err != nil {
+		return FileInfo{}, err
+	}
+	return FileInfo{Size: size, SHA256: hex.EncodeToString(h.Sum(nil)), Modified: stat.ModTime().UTC()}, nil
+}
+
+// writeFile replaces a file with data, keeping its permissions, through a
+// rename so services never read a partial write
+func writeFile(path string, data []byte) (FileInfo, error) {
+	mode := os.FileMode(0o644)
+	if stat, err := os.Stat(path); err == nil {
+		if !stat.Mode().IsRegular() {
+			return FileInfo{}, fmt.Errorf("%s is not a file", path)
+		}
+		mode = stat.Mode().Perm()
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return FileInfo{}, err
+	}
+	tmp := path + ".sdbx-tmp"
+	if err := os.WriteFile(tmp, data, mode); err != nil {
+		return FileInfo{}, err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		_ = os.Remove(tmp)
+		return FileInfo{}, err
+	}
+	return fileInfo(path)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, ErrorResponse{Error: err.Error()})
+}
+
+// statusWriter records the status of a response
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusWriter) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// flushWriter sends every write of a log stream to the client at once
+type flushWriter struct {
+	w       io.Writer
+	flusher *http.ResponseController
+}
+
+func (f *flushWriter) Write(p []byte) (int, error) {
+	n, err := f.w.Write(p)
+	if err == nil {
+		_ = f.flusher.Flush()
+	}
+	return n, err
+}
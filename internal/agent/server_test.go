@@ -0,0 +1,227 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/maiko/sdbx/internal/auth"
+	"github.com/maiko/sdbx/internal/events"
+)
+
+// newTestAgent serves the agent of a new project, returning clients with a
+// write and a read token
+func newTestAgent(t *testing.T) (string, *Client, *Client) {
+	t.Helper()
+	projectDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(projectDir, ".sdbx.yaml"), []byte("domain: box.example.com\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	tokens := auth.NewTokenStore(projectDir)
+	write, _, err := tokens.Create("control", auth.ScopeWrite, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	read, _, err := tokens.Create("monitor", auth.ScopeRead, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := httptest.NewServer(NewServer("box", projectDir, "1.2.3").Handler())
+	t.Cleanup(server.Close)
+	return projectDir, NewClient(Agent{Name: "box", URL: server.URL, Token: write}), NewClient(Agent{Name: "box", URL: server.URL, Token: read})
+}
+
+func TestServerAuth(t *testing.T) {
+	_, writer, reader := newTestAgent(t)
+	ctx := context.Background()
+
+	info, err := reader.Info(ctx)
+	if err != nil {
+		t.Fatalf("Info() error: %v", err)
+	}
+	if info.Name != "box" || info.Version != "1.2.3" || info.Domain != "box.example.com" {
+		t.Errorf("Info() = %+v", info)
+	}
+
+	if _, err := reader.PutFile(ctx, "configs/app.yaml", []byte("x")); err == nil || !strings.Contains(err.Error(), "read-only") {
+		t.Errorf("read token PutFile() error = %v", err)
+	}
+	stranger := NewClient(Agent{Name: "box", URL: writer.Agent().URL, Token: "sdbx_unknown"})
+	if _, err := stranger.Info(ctx); err == nil {
+		t.Error("unknown token should be refused")
+	}
+	resp, err := http.Get(writer.Agent().URL + APIPrefix + "/info")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("no token status = %d", resp.StatusCode)
+	}
+}
+
+func TestServerCompose(t *testing.T) {
+	_, writer, _ := newTestAgent(t)
+	ctx := context.Background()
+
+	// Refused before docker is called
+	if err := writer.Compose(ctx, "explode"); err == nil || !strings.Contains(err.Error(), "unknown action") {
+		t.Errorf("unknown action error = %v", err)
+	}
+	if err := writer.Compose(ctx, ActionRestart); err == nil || !strings.Contains(err.Error(), "needs services") {
+		t.Errorf("restart without services error = %v", err)
+	}
+}
+
+func TestServerFiles(t *testing.T) {
+	projectDir, writer, reader := newTestAgent(t)
+	ctx := context.Background()
+
+	info, err := writer.PutFile(ctx, "configs/traefik/dynamic.yml", []byte("http: {}\n"))
+	if err != nil {
+		t.Fatalf("PutFile() error: %v", err)
+	}
+	if info.Path != "configs/traefik/dynamic.yml" || info.Size != 9 {
+		t.Errorf("PutFile() = %+v", info)
+	}
+	data, err := writer.GetFile(ctx, "configs/traefik/dynamic.yml")
+	if err != nil || string(data) != "http: {}\n" {
+		t.Errorf("GetFile() = %q, %v", data, err)
+	}
+	if _, err := writer.GetFile(ctx, "configs/missing.yml"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetFile() missing error = %v", err)
+	}
+
+	// Secrets and credentials stay on the agent
+	if err := os.MkdirAll(filepath.Join(projectDir, "secrets"), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(projectDir, "secrets", "vpn_password.txt"), []byte("hunter2"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{"secrets/vpn_password.txt", auth.TokensFile, "configs/../../etc/passwd"} {
+		if _, err := writer.GetFile(ctx, path); err == nil {
+			t.Errorf("GetFile(%s) should be refused", path)
+		}
+	}
+	files, err := writer.ListFiles(ctx, "")
+	if err != nil {
+		t.Fatalf("ListFiles() error: %v", err)
+	}
+	var paths []string
+	for _, f := range files {
+		paths = append(paths, f.Path)
+	}
+	if got := strings.Join(paths, ","); got != ".sdbx.yaml,configs/traefik/dynamic.yml" {
+		t.Errorf("ListFiles() = %s", got)
+	}
+
+	// Configuration files hold credentials, so read tokens cannot see them
+	if _, err := reader.GetFile(ctx, "configs/traefik/dynamic.yml"); err == nil || !strings.Contains(err.Error(), "project files") {
+		t.Errorf("read token GetFile() error = %v, want refused", err)
+	}
+	if _, err := reader.ListFiles(ctx, ""); err == nil || !strings.Contains(err.Error(), "project files") {
+		t.Errorf("read token ListFiles() error = %v, want refused", err)
+	}
+
+	// Writes are recorded with their token
+	recorded, err := events.NewLog(projectDir).Read(events.Filter{Source: events.SourceAgent})
+	if err != nil || len(recorded) != 1 || recorded[0].User != "token:control" || recorded[0].Action != "PUT /v1/files/configs/traefik/dynamic.yml" {
+		t.Errorf("events = %+v, %v", recorded, err)
+	}
+}
+
+// TestServerPrivatePaths verifies private files stay out of reach through
+// paths the mux does not clean, such as encoded slashes
+func TestServerPrivatePaths(t *testing.T) {
+	projectDir, writer, _ := newTestAgent(t)
+	if err := os.MkdirAll(filepath.Join(projectDir, "secrets"), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(projectDir, "secrets", "vpn_password.txt"), []byte("hunter2"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	tokens, err := os.ReadFile(filepath.Join(projectDir, auth.TokensFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, path := range []string{
+		"./secrets/vpn_password.txt",
+		"configs/../secrets/vpn_password.txt",
+		".%2Fsecrets%2Fvpn_password.txt",
+		"configs%2F..%2Fsecrets%2Fvpn_password.txt",
+		".%2F" + auth.TokensFile,
+	} {
+		for _, method := range []string{http.MethodGet, http.MethodPut} {
+			req, err := http.NewRequest(method, writer.Agent().URL+APIPrefix+"/files/"+path, strings.NewReader("tokens: []\n"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.Header.Set("Authorization", "Bearer "+writer.Agent().Token)
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			body, _ := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusBadRequest || strings.Contains(string(body), "hunter2") {
+				t.Errorf("%s %s = %d %q, want refused", method, path, resp.StatusCode, body)
+			}
+		}
+	}
+	if got, _ := os.ReadFile(filepath.Join(projectDir, auth.TokensFile)); string(got) != string(tokens) {
+		t.Error("tokens file overwritten through the API")
+	}
+}
+
+func TestClientSync(t *testing.T) {
+	remoteDir, writer, _ := newTestAgent(t)
+	ctx := context.Background()
+
+	local := t.TempDir()
+	for file, content := range map[string]string{
+		".sdbx.yaml":              "domain: box.example.com\n", // Same as the agent's
+		"compose.yaml":            "services: {}\n",
+		"configs/homepage/a.yaml": "a\n",
+		"secrets/admin_password":  "never synced\n",
+		"configs/homepage/b.yaml": "b\n",
+		"notes/not-in-paths.txt":  "left out\n",
+	} {
+		path := filepath.Join(local, file)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	changed, err := writer.Sync(ctx, local, SyncPaths, true)
+	if err != nil || len(changed) != 3 {
+		t.Fatalf("Sync(dry run) = %+v, %v", changed, err)
+	}
+	if _, err := os.Stat(filepath.Join(remoteDir, "compose.yaml")); err == nil {
+		t.Error("dry run wrote files")
+	}
+
+	if changed, err = writer.Sync(ctx, local, SyncPaths, false); err != nil || len(changed) != 3 {
+		t.Fatalf("Sync() = %+v, %v", changed, err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(remoteDir, "configs/homepage/b.yaml")); string(data) != "b\n" {
+		t.Errorf("synced b.yaml = %q", data)
+	}
+	if changed, err = writer.Sync(ctx, local, SyncPaths, false); err != nil || len(changed) != 0 {
+		t.Errorf("second Sync() = %+v, %v", changed, err)
+	}
+	if _, err := writer.Sync(ctx, local, []string{"secrets"}, false); err == nil {
+		t.Error("Sync(secrets) should be refused")
+	}
+}
@@ -0,0 +1,272 @@
+// Package alert evaluates the alert rules of .sdbx.yaml against health
+// history, disk usage and backups, and notifies on changes.
+package alert
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"path/filepath"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/maiko/sdbx/internal/backup"
+	"github.com/maiko/sdbx/internal/certs"
+	"github.com/maiko/sdbx/internal/config"
+	"github.com/maiko/sdbx/internal/health"
+	"github.com/maiko/sdbx/internal/notify"
+)
+
+// vpnService is the container whose health tells whether the VPN is up
+const vpnService = "gluetun"
+
+// historySlack is loaded on top of the longest rule duration so a failure
+// that started just before the window is still seen as one
+const historySlack = time.Hour
+
+// Alert is a firing rule, per subject (a service, a path, ...)
+type Alert struct {
+	ID       string    `json:"id" yaml:"-"` // <rule>/<subject>
+	Rule     string    `json:"rule" yaml:"rule"`
+	Type     string    `json:"type" yaml:"type"`
+	Subject  string    `json:"subject" yaml:"subject"`
+	Message  string    `json:"message" yaml:"message"`
+	Severity string    `json:"severity" yaml:"severity"`
+	Since    time.Time `json:"since" yaml:"since"`
+}
+
+// Engine evaluates alert rules for a project
+type Engine struct {
+	ProjectDir string
+	Config     *config.Config
+
+	// Overridable for tests
+	Now       func() time.Time
+	DiskUsage func(path string) (float64, error)
+}
+
+// NewEngine creates an engine evaluating cfg's alert rules
+func NewEngine(projectDir string, cfg *config.Config) *Engine {
+	return &Engine{
+		ProjectDir: projectDir,
+		Config:     cfg,
+		Now:        time.Now,
+		DiskUsage:  diskUsage,
+	}
+}
+
+// Evaluate returns the alerts currently firing
+func (e *Engine) Evaluate(ctx context.Context) ([]Alert, error) {
+	now := e.Now()
+
+	stats, err := e.healthStats(now)
+	if err != nil {
+		return nil, err
+	}
+
+	var alerts []Alert
+	for _, rule := range e.Config.Alerts.Rules {
+		var fired []Alert
+		switch rule.Type {
+		case config.AlertContainerDown:
+			fired = containerDown(rule, stats, now)
+		case config.AlertVPNDisconnected:
+			if e.Config.VPNEnabled {
+				fired = vpnDisconnected(rule, stats, now)
+			}
+		case config.AlertDiskUsage:
+			fired, err = e.diskUsage(rule, now)
+		case config.AlertBackupAge:
+			fired, err = e.backupAge(ctx, rule, now)
+		case config.AlertCertExpiry:
+			fired, err = e.certExpiry(rule, now)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("alert rule %s: %w", rule.Name, err)
+		}
+		for _, a := range fired {
+			a.Rule, a.Type = rule.Name, rule.Type
+			a.ID = rule.Name + "/" + a.Subject
+			alerts = append(alerts, a)
+		}
+	}
+	return alerts, nil
+}
+
+// healthStats summarizes the recent health history of the services sampled
+// in the latest round, so services removed since then do not keep firing
+func (e *Engine) healthStats(now time.Time) (map[string]health.Stats, error) {
+	var window time.Duration
+	for _, rule := range e.Config.Alerts.Rules {
+		if rule.Type == config.AlertContainerDown || rule.Type == config.AlertVPNDisconnected {
+			window = max(window, rule.Duration()+historySlack)
+		}
+	}
+	if window == 0 || !health.Exists(e.ProjectDir) {
+		return nil, nil
+	}
+
+	store, err := health.OpenReadOnly(e.ProjectDir)
+	if err != nil {
+		return nil, err
+	}
+	defer store.Close()
+	history, err := store.History(now.Add(-window))
+	if err != nil {
+		return nil, err
+	}
+
+	var latest time.Time
+	all := health.SummarizeAll(history)
+	for _, s := range all {
+		if s.LastSeen.After(latest) {
+			latest = s.LastSeen
+		}
+	}
+	stats := make(map[string]health.Stats, len(all))
+	for _, s := range all {
+		if s.LastSeen.Equal(latest) {
+			stats[s.Service] = s
+		}
+	}
+	return stats, nil
+}
+
+// downFor reports whether a service has been failing for at least d
+func downFor(s health.Stats, d time.Duration, now time.Time) bool {
+	return s.DownSince != nil && now.Sub(*s.DownSince) >= d
+}
+
+func containerDown(rule config.AlertRule, stats map[string]health.Stats, now time.Time) []Alert {
+	var alerts []Alert
+	for _, name := range slices.Sorted(maps.Keys(stats)) {
+		s := stats[name]
+		if len(rule.Services) > 0 && !slices.Contains(rule.Services, name) {
+			continue
+		}
+		if !downFor(s, rule.Duration(), now) {
+			continue
+		}
+		alerts = append(alerts, Alert{
+			Subject:  name,
+			Message:  fmt.Sprintf("%s has been down since %s", name, s.DownSince.Local().Format("2006-01-02 15:04")),
+			Severity: notify.SeverityCritical,
+			Since:    *s.DownSince,
+		})
+	}
+	return alerts
+}
+
+func vpnDisconnected(rule config.AlertRule, stats map[string]health.Stats, now time.Time) []Alert {
+	s, ok := stats[vpnService]
+	if !ok || !downFor(s, rule.Duration(), now) {
+		return nil
+	}
+	return []Alert{{
+		Subject:  vpnService,
+		Message:  fmt.Sprintf("VPN disconnected since %s: downloads routed through gluetun are stalled", s.DownSince.Local().Format("2006-01-02 15:04")),
+		Severity: notify.SeverityCritical,
+		Since:    *s.DownSince,
+	}}
+}
+
+func (e *Engine) diskUsage(rule config.AlertRule, now time.Time) ([]Alert, error) {
+	path := rule.Path
+	if path == "" {
+		path = e.Config.MediaPath
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(e.ProjectDir, path)
+	}
+
+	used, err := e.DiskUsage(path)
+	if err != nil {
+		return nil, err
+	}
+	if used < float64(rule.Threshold) {
+		return nil, nil
+	}
+	return []Alert{{
+		Subject:  path,
+		Message:  fmt.Sprintf("%s is %.0f%% full (threshold %d%%)", path, used, rule.Threshold),
+		Severity: notify.SeverityWarning,
+		Since:    now,
+	}}, nil
+}
+
+func (e *Engine) backupAge(ctx context.Context, rule config.AlertRule, now time.Time) ([]Alert, error) {
+	backups, err := backup.NewManager(e.ProjectDir).List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	maxAge := time.Duration(rule.Days) * 24 * time.Hour
+	for _, b := range backups {
+		// Newest first; skip archives of removed addons
+		if !strings.HasPrefix(b.Name, "sdbx-backup-") {
+			continue
+		}
+		if now.Sub(b.Metadata.Timestamp) < maxAge {
+			return nil, nil
+		}
+		return []Alert{{
+			Subject:  "backups",
+			Message:  fmt.Sprintf("Newest backup was taken %s (%s), limit is %d days - run 'sdbx backup create'", backup.FormatAge(b.Metadata.Timestamp), b.Name, rule.Days),
+			Severity: notify.SeverityWarning,
+			Since:    b.Metadata.Timestamp.Add(maxAge),
+		}}, nil
+	}
+	return []Alert{{
+		Subject:  "backups",
+		Message:  "No backup found - run 'sdbx backup create'",
+		Severity: notify.SeverityWarning,
+		Since:    now,
+	}}, nil
+}
+
+func (e *Engine) certExpiry(rule config.AlertRule, now time.Time) ([]Alert, error) {
+	all, err := certs.Read(e.ProjectDir)
+	if err != nil {
+		return nil, err
+	}
+	days := rule.Days
+	if days == 0 {
+		days = certs.DefaultWarnDays
+	}
+
+	var alerts []Alert
+	for _, c := range all {
+		status := c.Status(now, days)
+		if status != certs.StatusExpiring && status != certs.StatusExpired {
+			continue
+		}
+		a := Alert{
+			Subject:  c.Domain,
+			Message:  fmt.Sprintf("Certificate of %s expires in %d days (%s) and was not renewed - run 'sdbx cert status'", c.Domain, c.DaysLeft(now), c.NotAfter.Local().Format("2006-01-02")),
+			Severity: notify.SeverityWarning,
+			Since:    c.NotAfter.Add(-time.Duration(days) * 24 * time.Hour),
+		}
+		if status == certs.StatusExpired {
+			a.Message = fmt.Sprintf("Certificate of %s expired on %s - run 'sdbx cert status'", c.Domain, c.NotAfter.Local().Format("2006-01-02"))
+			a.Severity = notify.SeverityCritical
+		}
+		alerts = append(alerts, a)
+	}
+	return alerts, nil
+}
+
+// diskUsage returns the used percentage of the filesystem holding path, as df does
+func diskUsage(path string) (float64, error) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(path, &stat); err != nil {
+		return 0, fmt.Errorf("failed to check disk usage of %s: %w", path, err)
+	}
+	used := float64(stat.Blocks - stat.Bfree)
+	total := used + float64(stat.Bavail)
+	if total == 0 {
+		return 0, nil
+	}
+	return used * 100 / total, nil
+}
@@ -0,0 +1,279 @@
+package alert
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/maiko/sdbx/internal/backup"
+	"github.com/maiko/sdbx/internal/certs"
+	"github.com/maiko/sdbx/internal/config"
+	"github.com/maiko/sdbx/internal/docker"
+	"github.com/maiko/sdbx/internal/health"
+	"github.com/maiko/sdbx/internal/notify"
+)
+
+// recordHealth writes one sample per minute ending at end; states maps each
+// service to its health per sample
+func recordHealth(t *testing.T, projectDir string, end time.Time, states map[string][]bool) {
+	t.Helper()
+	store, err := health.Open(projectDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	var rounds int
+	for _, s := range states {
+		rounds = max(rounds, len(s))
+	}
+	for i := range rounds {
+		var services []docker.Service
+		for name, s := range states {
+			if i < len(s) {
+				services = append(services, docker.Service{Service: name, Running: s[i], Status: "running"})
+			}
+		}
+		at := end.Add(-time.Duration(rounds-1-i) * time.Minute)
+		if err := store.Record(at, services); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	tmpDir := t.TempDir()
+	now := time.Now().Truncate(time.Minute)
+
+	// plex down for the last 6 samples, sonarr for 2, lidarr gone since
+	recordHealth(t, tmpDir, now, map[string][]bool{
+		"plex":    {true, true, false, false, false, false, false, false},
+		"sonarr":  {true, true, true, true, true, true, false, false},
+		"gluetun": {true, true, true, false, false, false, false, false},
+		"lidarr":  {false, false, false, false, false, false, false},
+	})
+
+	cfg := config.DefaultConfig()
+	cfg.VPNEnabled = true
+	cfg.Alerts.Rules = []config.AlertRule{
+		{Name: "down", Type: config.AlertContainerDown, For: "5m"},
+		{Name: "vpn", Type: config.AlertVPNDisconnected, For: "2m"},
+		{Name: "disk", Type: config.AlertDiskUsage, Threshold: 90},
+		{Name: "backup", Type: config.AlertBackupAge, Days: 7},
+	}
+
+	engine := NewEngine(tmpDir, cfg)
+	engine.Now = func() time.Time { return now }
+	var checked string
+	engine.DiskUsage = func(path string) (float64, error) {
+		checked = path
+		return 93.5, nil
+	}
+
+	alerts, err := engine.Evaluate(context.Background())
+	if err != nil {
+		t.Fatalf("Evaluate failed: %v", err)
+	}
+
+	ids := make(map[string]Alert)
+	for _, a := range alerts {
+		ids[a.ID] = a
+	}
+	media := filepath.Join(tmpDir, cfg.MediaPath)
+	for _, want := range []string{"down/plex", "vpn/gluetun", "disk/" + media, "backup/backups"} {
+		if _, ok := ids[want]; !ok {
+			t.Errorf("expected alert %s, got %+v", want, alerts)
+		}
+	}
+	// sonarr and gluetun are not down for 5 minutes yet, lidarr is no longer sampled
+	if len(alerts) != 4 {
+		t.Errorf("expected 4 alerts, got %d: %+v", len(alerts), alerts)
+	}
+	if checked != media {
+		t.Errorf("disk usage should default to media_path, checked %s", checked)
+	}
+	if a := ids["down/plex"]; !a.Since.Equal(now.Add(-5*time.Minute)) || a.Rule != "down" || a.Type != config.AlertContainerDown {
+		t.Errorf("unexpected plex alert: %+v", a)
+	}
+	if a := ids["disk/"+media]; !strings.Contains(a.Message, "94% full") {
+		t.Errorf("unexpected disk alert message: %q", a.Message)
+	}
+
+	// A recent backup clears the backup alert; VPN rules are ignored without VPN
+	if err := os.WriteFile(filepath.Join(tmpDir, ".sdbx.yaml"), []byte("domain: example.com\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := backup.NewManager(tmpDir).Create(context.Background()); err != nil {
+		t.Fatalf("backup failed: %v", err)
+	}
+	cfg.VPNEnabled = false
+	alerts, err = engine.Evaluate(context.Background())
+	if err != nil {
+		t.Fatalf("Evaluate failed: %v", err)
+	}
+	for _, a := range alerts {
+		if a.ID == "backup/backups" || a.ID == "vpn/gluetun" {
+			t.Errorf("unexpected alert %s", a.ID)
+		}
+	}
+}
+
+func TestCertExpiry(t *testing.T) {
+	tmpDir := t.TempDir()
+	now := time.Now().Truncate(time.Minute)
+	writeACME(t, tmpDir, map[string]time.Time{
+		"sonarr.example.com": now.Add(60 * 24 * time.Hour),
+		"auth.example.com":   now.Add(5 * 24 * time.Hour),
+		"old.example.com":    now.Add(-time.Hour),
+	})
+
+	cfg := config.DefaultConfig()
+	cfg.Alerts.Rules = []config.AlertRule{{Name: "certs", Type: config.AlertCertExpiry}}
+	engine := NewEngine(tmpDir, cfg)
+	engine.Now = func() time.Time { return now }
+	alerts, err := engine.Evaluate(context.Background())
+	if err != nil {
+		t.Fatalf("Evaluate failed: %v", err)
+	}
+	if len(alerts) != 2 || alerts[0].ID != "certs/old.example.com" || alerts[1].ID != "certs/auth.example.com" {
+		t.Fatalf("expected the expired and expiring certificates, got %+v", alerts)
+	}
+	if alerts[0].Severity != notify.SeverityCritical || !strings.Contains(alerts[1].Message, "expires in 5 days") {
+		t.Errorf("unexpected alerts: %+v", alerts)
+	}
+}
+
+// writeACME writes an acme.json holding a self-signed certificate expiring
+// at the given time for each domain
+func writeACME(t *testing.T, projectDir string, expiries map[string]time.Time) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var entries []map[string]interface{}
+	for domain, notAfter := range expiries {
+		template := &x509.Certificate{SerialNumber: big.NewInt(1), Subject: pkix.Name{CommonName: domain}, NotBefore: notAfter.Add(-90 * 24 * time.Hour), NotAfter: notAfter}
+		der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		chain := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+		entries = append(entries, map[string]interface{}{
+			"domain":      map[string]string{"main": domain},
+			"certificate": base64.StdEncoding.EncodeToString(chain),
+		})
+	}
+	data, err := json.Marshal(map[string]interface{}{"letsencrypt": map[string]interface{}{"Certificates": entries}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(projectDir, certs.ACMEFile)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReconcile(t *testing.T) {
+	start := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	plex := Alert{ID: "down/plex", Rule: "down", Subject: "plex", Message: "plex is down", Severity: "critical", Since: start}
+
+	// New alert is notified
+	state, notices := reconcile(map[string]firing{}, []Alert{plex}, start, time.Hour)
+	if len(notices) != 1 || notices[0].msg.Title != "down: plex" || notices[0].msg.Source != "alert:down" {
+		t.Fatalf("new alert should be notified once: %+v", notices)
+	}
+
+	// Still firing within the repeat interval: deduplicated, start kept
+	later := plex
+	later.Since = start.Add(10 * time.Minute)
+	state, notices = reconcile(state, []Alert{later}, start.Add(10*time.Minute), time.Hour)
+	if len(notices) != 0 {
+		t.Errorf("firing alert should not be notified again: %+v", notices)
+	}
+	if !state["down/plex"].Since.Equal(start) {
+		t.Errorf("Since should stay at the first firing, got %v", state["down/plex"].Since)
+	}
+
+	// Repeat interval elapsed
+	state, notices = reconcile(state, []Alert{plex}, start.Add(time.Hour), time.Hour)
+	if len(notices) != 1 || !strings.Contains(notices[0].msg.Title, "still firing") {
+		t.Errorf("alert should repeat after the interval: %+v", notices)
+	}
+
+	// Without repeat, never notified again
+	if _, notices := reconcile(state, []Alert{plex}, start.Add(48*time.Hour), 0); len(notices) != 0 {
+		t.Errorf("alert should not repeat without a repeat interval: %+v", notices)
+	}
+
+	// Cleared: resolved notification and dropped from state
+	state, notices = reconcile(state, nil, start.Add(90*time.Minute), time.Hour)
+	if len(notices) != 1 || notices[0].msg.Severity != "resolved" || !strings.Contains(notices[0].msg.Body, "1h30m") {
+		t.Errorf("cleared alert should be notified as resolved: %+v", notices)
+	}
+	if len(state) != 0 {
+		t.Errorf("resolved alert should be dropped: %+v", state)
+	}
+}
+
+func TestRunDeduplicates(t *testing.T) {
+	var sent []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		sent = append(sent, r.Header.Get("Title")+"|"+string(body))
+	}))
+	defer server.Close()
+
+	tmpDir := t.TempDir()
+	cfg := config.DefaultConfig()
+	cfg.Alerts.Rules = []config.AlertRule{{Name: "disk", Type: config.AlertDiskUsage, Threshold: 90, Path: "/srv"}}
+	cfg.Notifications.Channels = []config.NotificationChannel{{Name: "phone", Type: config.NotifyNtfy, URL: server.URL}}
+
+	usage := 95.0
+	engine := NewEngine(tmpDir, cfg)
+	engine.DiskUsage = func(string) (float64, error) { return usage, nil }
+	notifier := notify.New(tmpDir, cfg)
+
+	for range 3 {
+		if _, err := engine.Run(context.Background(), notifier); err != nil {
+			t.Fatalf("Run failed: %v", err)
+		}
+	}
+	if len(sent) != 1 || !strings.HasPrefix(sent[0], "disk: /srv|/srv is 95% full") {
+		t.Fatalf("alert should be sent once, got %q", sent)
+	}
+
+	firing, err := Firing(tmpDir)
+	if err != nil || len(firing) != 1 || firing[0].ID != "disk//srv" {
+		t.Errorf("Firing = %+v, %v", firing, err)
+	}
+
+	usage = 50
+	if _, err := engine.Run(context.Background(), notifier); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if len(sent) != 2 || !strings.HasPrefix(sent[1], "disk: /srv resolved|") {
+		t.Errorf("resolution should be sent, got %q", sent)
+	}
+	if firing, _ := Firing(tmpDir); len(firing) != 0 {
+		t.Errorf("no alert should be firing: %+v", firing)
+	}
+}
@@ -0,0 +1,192 @@
+package alert
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/maiko/sdbx/internal/config"
+	"github.com/maiko/sdbx/internal/notify"
+	"github.com/maiko/sdbx/internal/scheduler"
+)
+
+// StateFile records the firing alerts and when they were last notified, so
+// an alert is only sent when it starts, repeats or resolves
+const StateFile = ".sdbx.alerts.yaml"
+
+// firing is a recorded alert
+type firing struct {
+	Alert    `yaml:",inline"`
+	Notified time.Time `yaml:"notified,omitempty"`
+}
+
+// alertState is the content of StateFile
+type alertState struct {
+	Firing map[string]firing `yaml:"firing"`
+}
+
+// notice is a notification due for an alert
+type notice struct {
+	id  string
+	msg notify.Message
+}
+
+// Run evaluates the rules, notifies alerts that started, are due for a repeat
+// or resolved, and records the firing alerts. It returns the firing alerts.
+func (e *Engine) Run(ctx context.Context, notifier *notify.Notifier) ([]Alert, error) {
+	alerts, err := e.Evaluate(ctx)
+	if err != nil {
+		return nil, err
+	}
+	prev, err := loadState(e.ProjectDir)
+	if err != nil {
+		return nil, err
+	}
+
+	next, notices := reconcile(prev, alerts, e.Now(), e.Config.Alerts.RepeatInterval())
+
+	var errs []error
+	for _, n := range notices {
+		if err := notifier.Send(ctx, n.msg); err != nil {
+			errs = append(errs, fmt.Errorf("failed to notify %s: %w", n.id, err))
+			// Retried on the next run
+			if f, ok := next[n.id]; ok {
+				f.Notified = prev[n.id].Notified
+				next[n.id] = f
+			}
+		}
+	}
+
+	if len(next) > 0 || len(prev) > 0 {
+		if err := saveState(e.ProjectDir, next); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return alerts, errors.Join(errs...)
+}
+
+// reconcile compares the firing alerts with the recorded ones. It returns the
+// new state and the notifications due: alerts that started, alerts still
+// firing after the repeat interval (0 never repeats) and resolved alerts.
+func reconcile(prev map[string]firing, alerts []Alert, now time.Time, repeat time.Duration) (map[string]firing, []notice) {
+	next := make(map[string]firing, len(alerts))
+	var notices []notice
+
+	for _, a := range alerts {
+		f, seen := prev[a.ID]
+		if seen {
+			// Keep when the alert started; refresh the message
+			a.Since = f.Since
+		}
+		f.Alert = a
+
+		if !seen || (repeat > 0 && now.Sub(f.Notified) >= repeat) {
+			f.Notified = now
+			title := a.Rule + ": " + a.Subject
+			if seen {
+				title += " (still firing)"
+			}
+			notices = append(notices, notice{a.ID, notify.Message{
+				Title:    title,
+				Body:     a.Message,
+				Severity: a.Severity,
+				Source:   "alert:" + a.Rule,
+			}})
+		}
+		next[a.ID] = f
+	}
+
+	for _, id := range slices.Sorted(maps.Keys(prev)) {
+		if _, ok := next[id]; ok {
+			continue
+		}
+		f := prev[id]
+		notices = append(notices, notice{id, notify.Message{
+			Title:    f.Rule + ": " + f.Subject + " resolved",
+			Body:     fmt.Sprintf("Resolved after %s: %s", now.Sub(f.Since).Round(time.Minute), f.Message),
+			Severity: notify.SeverityResolved,
+			Source:   "alert:" + f.Rule,
+		}})
+	}
+
+	return next, notices
+}
+
+// Firing returns the alerts recorded as firing by the last run, sorted by ID
+func Firing(projectDir string) ([]Alert, error) {
+	state, err := loadState(projectDir)
+	if err != nil {
+		return nil, err
+	}
+	alerts := make([]Alert, 0, len(state))
+	for _, id := range slices.Sorted(maps.Keys(state)) {
+		a := state[id].Alert
+		a.ID = id
+		alerts = append(alerts, a)
+	}
+	return alerts, nil
+}
+
+// Job returns a scheduler job running the alert rules every interval. The
+// configuration is reloaded on each run so rule edits apply without restart.
+func Job(projectDir string, interval time.Duration) scheduler.Job {
+	return scheduler.Job{
+		Name:     "alerts",
+		Interval: interval,
+		Run: func(ctx context.Context) error {
+			cfg, err := config.Load()
+			if err != nil {
+				return fmt.Errorf("failed to load config: %w", err)
+			}
+			if len(cfg.Alerts.Rules) == 0 {
+				return nil
+			}
+			_, err = NewEngine(projectDir, cfg).Run(ctx, notify.New(projectDir, cfg))
+			return err
+		},
+	}
+}
+
+// loadState reads the recorded alerts; a missing file means none
+func loadState(projectDir string) (map[string]firing, error) {
+	data, err := os.ReadFile(filepath.Join(projectDir, StateFile))
+	if errors.Is(err, fs.ErrNotExist) {
+		return map[string]firing{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", StateFile, err)
+	}
+
+	var state alertState
+	if err := yaml.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", StateFile, err)
+	}
+	if state.Firing == nil {
+		state.Firing = map[string]firing{}
+	}
+	for id, f := range state.Firing {
+		f.ID = id
+		state.Firing[id] = f
+	}
+	return state.Firing, nil
+}
+
+// saveState writes the recorded alerts
+func saveState(projectDir string, firing map[string]firing) error {
+	data, err := yaml.Marshal(alertState{Firing: firing})
+	if err != nil {
+		return fmt.Errorf("failed to encode %s: %w", StateFile, err)
+	}
+	if err := os.WriteFile(filepath.Join(projectDir, StateFile), data, 0o644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", StateFile, err)
+	}
+	return nil
+}
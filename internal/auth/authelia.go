@@ -0,0 +1,148 @@
+package auth
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+
+	"golang.org/x/crypto/argon2"
+	"gopkg.in/yaml.v3"
+)
+
+// Argon2id parameters matching the Authelia configuration sdbx generates
+const (
+	argon2Time    = 3
+	argon2Memory  = 64 * 1024 // 64 MB
+	argon2Threads = 4
+	argon2KeyLen  = 32
+	argon2SaltLen = 16
+)
+
+// autheliaStore edits Authelia's file authentication backend
+type autheliaStore struct {
+	path   string
+	domain string // Default email domain for new users
+}
+
+// autheliaUsers is the users_database.yml layout
+type autheliaUsers struct {
+	Users map[string]autheliaUser `yaml:"users"`
+}
+
+type autheliaUser struct {
+	DisplayName string   `yaml:"displayname"`
+	Password    string   `yaml:"password"`
+	Email       string   `yaml:"email,omitempty"`
+	Groups      []string `yaml:"groups,omitempty"`
+	Disabled    bool     `yaml:"disabled,omitempty"`
+}
+
+func (s *autheliaStore) Path() string    { return s.path }
+func (s *autheliaStore) Service() string { return "authelia" }
+
+func (s *autheliaStore) List() ([]User, error) {
+	db, err := s.read()
+	if err != nil {
+		return nil, err
+	}
+	users := make([]User, 0, len(db.Users))
+	for _, name := range slices.Sorted(maps.Keys(db.Users)) {
+		u := db.Users[name]
+		users = append(users, User{Name: name, DisplayName: u.DisplayName, Email: u.Email, Groups: u.Groups})
+	}
+	return users, nil
+}
+
+func (s *autheliaStore) SetPassword(name, password string) (bool, error) {
+	if err := checkNewPassword(name, password); err != nil {
+		return false, err
+	}
+	db, err := s.read()
+	if err != nil && !os.IsNotExist(err) {
+		return false, err
+	}
+	if db.Users == nil {
+		db.Users = make(map[string]autheliaUser)
+	}
+	hash, err := hashArgon2(password)
+	if err != nil {
+		return false, fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	user, exists := db.Users[name]
+	if !exists {
+		user = autheliaUser{DisplayName: name, Email: name + "@" + s.domain, Groups: []string{"users"}}
+	}
+	user.Password = hash
+	db.Users[name] = user
+	return !exists, s.write(db)
+}
+
+func (s *autheliaStore) SetGroups(name string, groups []string) error {
+	db, err := s.read()
+	if err != nil {
+		return err
+	}
+	user, ok := db.Users[name]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUserNotFound, name)
+	}
+	user.Groups = groups
+	db.Users[name] = user
+	return s.write(db)
+}
+
+func (s *autheliaStore) Remove(name string) error {
+	db, err := s.read()
+	if err != nil {
+		return err
+	}
+	if _, ok := db.Users[name]; !ok {
+		return fmt.Errorf("%w: %s", ErrUserNotFound, name)
+	}
+	if len(db.Users) == 1 {
+		return errLastUser
+	}
+	delete(db.Users, name)
+	return s.write(db)
+}
+
+// read parses the users database
+func (s *autheliaStore) read() (autheliaUsers, error) {
+	var db autheliaUsers
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		return db, err
+	}
+	if err := yaml.Unmarshal(data, &db); err != nil {
+		return db, fmt.Errorf("failed to parse %s: %w", s.path, err)
+	}
+	return db, nil
+}
+
+// write replaces the users database
+func (s *autheliaStore) write(db autheliaUsers) error {
+	data, err := yaml.Marshal(db)
+	if err != nil {
+		return err
+	}
+	data = append([]byte("# Authelia Users Database\n# Managed by sdbx user\n\n"), data...)
+	if err := os.WriteFile(s.path, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write %s: %w", s.path, err)
+	}
+	return nil
+}
+
+// hashArgon2 hashes a password in the PHC format Authelia expects
+func hashArgon2(password string) (string, error) {
+	salt := make([]byte, argon2SaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	hash := argon2.IDKey([]byte(password), salt, argon2Time, argon2Memory, argon2Threads, argon2KeyLen)
+	return fmt.Sprintf("$argon2id$v=19$m=%d,t=%d,p=%d$%s$%s", argon2Memory, argon2Time, argon2Threads,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(hash)), nil
+}
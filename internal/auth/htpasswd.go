@@ -0,0 +1,115 @@
+package auth
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// htpasswdStore keeps users as user:bcrypt lines, read by Traefik's basicAuth
+// middleware
+type htpasswdStore struct {
+	path string
+}
+
+// htpasswdEntry is one user:hash line
+type htpasswdEntry struct {
+	name, hash string
+}
+
+func (s *htpasswdStore) Path() string    { return s.path }
+func (s *htpasswdStore) Service() string { return "traefik" }
+
+func (s *htpasswdStore) List() ([]User, error) {
+	entries, err := s.read()
+	if err != nil {
+		return nil, err
+	}
+	users := make([]User, 0, len(entries))
+	for _, e := range entries {
+		users = append(users, User{Name: e.name})
+	}
+	return users, nil
+}
+
+func (s *htpasswdStore) SetPassword(name, password string) (bool, error) {
+	if err := checkNewPassword(name, password); err != nil {
+		return false, err
+	}
+	entries, err := s.read()
+	if err != nil && !os.IsNotExist(err) {
+		return false, err
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return false, fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	i := slices.IndexFunc(entries, func(e htpasswdEntry) bool { return e.name == name })
+	if i >= 0 {
+		entries[i].hash = string(hash)
+	} else {
+		entries = append(entries, htpasswdEntry{name, string(hash)})
+	}
+	if err := s.write(entries); err != nil {
+		return false, err
+	}
+	// The generated first user's password is kept beside the file; it is stale now
+	if i == 0 {
+		_ = os.Remove(strings.TrimSuffix(s.path, ".txt") + ".password")
+	}
+	return i < 0, nil
+}
+
+func (s *htpasswdStore) SetGroups(string, []string) error { return ErrNoGroups }
+
+func (s *htpasswdStore) Remove(name string) error {
+	entries, err := s.read()
+	if err != nil {
+		return err
+	}
+	i := slices.IndexFunc(entries, func(e htpasswdEntry) bool { return e.name == name })
+	if i < 0 {
+		return fmt.Errorf("%w: %s", ErrUserNotFound, name)
+	}
+	if len(entries) == 1 {
+		return errLastUser
+	}
+	return s.write(slices.Delete(entries, i, i+1))
+}
+
+// read parses the htpasswd file, skipping blank lines and comments
+func (s *htpasswdStore) read() ([]htpasswdEntry, error) {
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		return nil, err
+	}
+	var entries []htpasswdEntry
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		name, hash, ok := strings.Cut(line, ":")
+		if !ok {
+			return nil, fmt.Errorf("malformed line in %s: missing ':'", s.path)
+		}
+		entries = append(entries, htpasswdEntry{name, hash})
+	}
+	return entries, nil
+}
+
+// write replaces the htpasswd file
+func (s *htpasswdStore) write(entries []htpasswdEntry) error {
+	var b strings.Builder
+	for _, e := range entries {
+		b.WriteString(e.name + ":" + e.hash + "\n")
+	}
+	if err := os.WriteFile(s.path, []byte(b.String()), 0o600); err != nil {
+		return fmt.Errorf("failed to write %s: %w", s.path, err)
+	}
+	return nil
+}
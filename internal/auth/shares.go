@@ -0,0 +1,248 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// SharesFile holds the share links of a project
+	SharesFile = ".sdbx.shares.yaml"
+
+	// ShareKeyFile is the key signing share links
+	ShareKeyFile = "secrets/share_key.txt"
+
+	// SharePrefix starts every share link token
+	SharePrefix = "sdbxs_"
+
+	// ShareCookiePrefix names the cookie holding the token of a service's
+	// share link (sdbx_share_<service>), so Traefik can route on it
+	ShareCookiePrefix = "sdbx_share_"
+
+	// SharePath is where a share link opens on the service's URL
+	SharePath = "/.sdbx-share/"
+)
+
+// ErrShareNotFound is returned when revoking a share link that does not exist
+var ErrShareNotFound = errors.New("share link not found")
+
+// Share is a time-limited link to a service, bypassing the login. Tokens
+// are signed, not stored; revoking a share deletes its record.
+type Share struct {
+	ID      string    `yaml:"id" json:"id"`
+	Service string    `yaml:"service" json:"service"`
+	Created time.Time `yaml:"created" json:"created"`
+	Expires time.Time `yaml:"expires" json:"expires"`
+}
+
+// Expired reports whether the share has expired at now
+func (s Share) Expired(now time.Time) bool {
+	return !now.Before(s.Expires)
+}
+
+// CookieName returns the cookie holding the token of the share's service
+func (s Share) CookieName() string {
+	return ShareCookiePrefix + s.Service
+}
+
+// sharesFile is the SharesFile layout
+type sharesFile struct {
+	Shares []Share `yaml:"shares"`
+}
+
+// ShareStore reads and writes the share links of a project
+type ShareStore struct {
+	path    string
+	keyPath string
+}
+
+// NewShareStore returns the share store of a project
+func NewShareStore(projectDir string) *ShareStore {
+	return &ShareStore{
+		path:    filepath.Join(projectDir, SharesFile),
+		keyPath: filepath.Join(projectDir, ShareKeyFile),
+	}
+}
+
+// List returns the share links, oldest first
+func (s *ShareStore) List() ([]Share, error) {
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return []Share{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", SharesFile, err)
+	}
+
+	var file sharesFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", SharesFile, err)
+	}
+	if file.Shares == nil {
+		return []Share{}, nil
+	}
+	return file.Shares, nil
+}
+
+// Active returns the share links not expired at now
+func (s *ShareStore) Active(now time.Time) ([]Share, error) {
+	shares, err := s.List()
+	if err != nil {
+		return nil, err
+	}
+	return slices.DeleteFunc(shares, func(sh Share) bool { return sh.Expired(now) }), nil
+}
+
+// Create adds a share link to a service and returns its token. Expired
+// links are dropped.
+func (s *ShareStore) Create(service string, expiry time.Duration) (string, Share, error) {
+	if expiry <= 0 {
+		return "", Share{}, fmt.Errorf("expiry must be positive")
+	}
+	key, err := s.key(true)
+	if err != nil {
+		return "", Share{}, err
+	}
+	now := time.Now().UTC().Truncate(time.Second)
+	shares, err := s.Active(now)
+	if err != nil {
+		return "", Share{}, err
+	}
+
+	id := make([]byte, 6)
+	if _, err := rand.Read(id); err != nil {
+		return "", Share{}, fmt.Errorf("failed to generate share link: %w", err)
+	}
+	share := Share{ID: hex.EncodeToString(id), Service: service, Created: now, Expires: now.Add(expiry)}
+	if err := s.write(append(shares, share)); err != nil {
+		return "", Share{}, err
+	}
+	return signShare(key, share), share, nil
+}
+
+// Revoke deletes a share link by ID
+func (s *ShareStore) Revoke(id string) error {
+	shares, err := s.List()
+	if err != nil {
+		return err
+	}
+	i := slices.IndexFunc(shares, func(sh Share) bool { return sh.ID == id })
+	if i < 0 {
+		return ErrShareNotFound
+	}
+	return s.write(slices.Delete(shares, i, i+1))
+}
+
+// Verify returns the share of a token for service, unless its signature is
+// wrong, it expired at now or it was revoked
+func (s *ShareStore) Verify(token, service string, now time.Time) (Share, error) {
+	id, expires, sig, ok := parseShareToken(token)
+	if !ok {
+		return Share{}, ErrInvalidToken
+	}
+	key, err := s.key(false)
+	if err != nil {
+		return Share{}, ErrInvalidToken
+	}
+	claimed := Share{ID: id, Service: service, Expires: expires}
+	if !hmac.Equal([]byte(sig), []byte(shareSignature(key, claimed))) || claimed.Expired(now) {
+		return Share{}, ErrInvalidToken
+	}
+
+	shares, err := s.List()
+	if err != nil {
+		return Share{}, err
+	}
+	for _, sh := range shares {
+		if sh.ID == id && sh.Service == service {
+			return sh, nil
+		}
+	}
+	return Share{}, ErrInvalidToken
+}
+
+// key reads the signing key, creating it when create is set
+func (s *ShareStore) key(create bool) ([]byte, error) {
+	data, err := os.ReadFile(s.keyPath)
+	if err == nil {
+		return []byte(strings.TrimSpace(string(data))), nil
+	}
+	if !errors.Is(err, fs.ErrNotExist) || !create {
+		return nil, fmt.Errorf("failed to read %s: %w", ShareKeyFile, err)
+	}
+
+	b := make([]byte, tokenBytes)
+	if _, err := rand.Read(b); err != nil {
+		return nil, fmt.Errorf("failed to generate share key: %w", err)
+	}
+	key := hex.EncodeToString(b)
+	if err := os.MkdirAll(filepath.Dir(s.keyPath), 0o700); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(s.keyPath, []byte(key+"\n"), 0o600); err != nil {
+		return nil, fmt.Errorf("failed to write %s: %w", ShareKeyFile, err)
+	}
+	return []byte(key), nil
+}
+
+// write replaces the shares file
+func (s *ShareStore) write(shares []Share) error {
+	data, err := yaml.Marshal(sharesFile{Shares: shares})
+	if err != nil {
+		return fmt.Errorf("failed to encode %s: %w", SharesFile, err)
+	}
+
+	// Rename over the file so the web UI never reads a partial write
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write %s: %w", SharesFile, err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		_ = os.Remove(tmp)
+		return fmt.Errorf("failed to write %s: %w", SharesFile, err)
+	}
+	return nil
+}
+
+// signShare returns the token of a share: its ID, expiry and signature
+func signShare(key []byte, share Share) string {
+	return fmt.Sprintf("%s%s.%d.%s", SharePrefix, share.ID, share.Expires.Unix(), shareSignature(key, share))
+}
+
+// shareSignature signs the ID, service and expiry of a share
+func shareSignature(key []byte, share Share) string {
+	mac := hmac.New(sha256.New, key)
+	fmt.Fprintf(mac, "%s.%s.%d", share.ID, share.Service, share.Expires.Unix())
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// parseShareToken splits a token into its ID, expiry and signature
+func parseShareToken(token string) (string, time.Time, string, bool) {
+	rest, ok := strings.CutPrefix(token, SharePrefix)
+	if !ok {
+		return "", time.Time{}, "", false
+	}
+	parts := strings.Split(rest, ".")
+	if len(parts) != 3 || parts[0] == "" || parts[2] == "" {
+		return "", time.Time{}, "", false
+	}
+	expires, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return "", time.Time{}, "", false
+	}
+	return parts[0], time.Unix(expires, 0).UTC(), parts[2], true
+}
@@ -0,0 +1,78 @@
+package auth
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestShareStore(t *testing.T) {
+	dir := t.TempDir()
+	store := NewShareStore(dir)
+
+	token, share, err := store.Create("overseerr", 72*time.Hour)
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if !strings.HasPrefix(token, SharePrefix+share.ID+".") || share.Expires.Sub(share.Created) != 72*time.Hour {
+		t.Errorf("Create() = %q, %+v", token, share)
+	}
+	if info, err := os.Stat(filepath.Join(dir, ShareKeyFile)); err != nil || info.Mode().Perm() != 0o600 {
+		t.Errorf("share key not created with mode 0600: %v", err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, SharesFile)); strings.Contains(string(data), token) {
+		t.Error("shares file contains the token")
+	}
+
+	now := time.Now()
+	if got, err := store.Verify(token, "overseerr", now); err != nil || got.ID != share.ID {
+		t.Errorf("Verify() = %+v, %v", got, err)
+	}
+	if _, err := store.Verify(token, "sonarr", now); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("Verify() for another service = %v", err)
+	}
+	if _, err := store.Verify(token, "overseerr", share.Expires); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("Verify() after expiry = %v", err)
+	}
+	// A later expiry invalidates the signature
+	id, _, sig, _ := parseShareToken(token)
+	forged := SharePrefix + id + "." + "9999999999" + "." + sig
+	if _, err := store.Verify(forged, "overseerr", now); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("Verify() of an extended token = %v", err)
+	}
+	for _, bad := range []string{"", "sdbx_abc", SharePrefix + "a.b.c", SharePrefix + id} {
+		if _, err := store.Verify(bad, "overseerr", now); !errors.Is(err, ErrInvalidToken) {
+			t.Errorf("Verify(%q) = %v", bad, err)
+		}
+	}
+
+	if _, _, err := store.Create("plex", 0); err == nil {
+		t.Error("expected error for a share link that never expires")
+	}
+	if err := store.Revoke("nope"); !errors.Is(err, ErrShareNotFound) {
+		t.Errorf("Revoke(nope) = %v, want ErrShareNotFound", err)
+	}
+	if err := store.Revoke(share.ID); err != nil {
+		t.Fatalf("Revoke failed: %v", err)
+	}
+	if _, err := store.Verify(token, "overseerr", now); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("Verify() after revoke = %v", err)
+	}
+}
+
+func TestShareStoreActive(t *testing.T) {
+	store := NewShareStore(t.TempDir())
+	if _, _, err := store.Create("plex", time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := store.Create("overseerr", 48*time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	active, err := store.Active(time.Now().Add(2 * time.Hour))
+	if err != nil || len(active) != 1 || active[0].Service != "overseerr" {
+		t.Errorf("Active() = %+v, %v", active, err)
+	}
+}
@@ -0,0 +1,221 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// TokensFile holds the API tokens of a project, hashed
+const TokensFile = ".sdbx.tokens.yaml"
+
+// TokenPrefix starts every API token, so they are recognizable in headers
+// and secret scanners
+const TokenPrefix = "sdbx_"
+
+// API token scopes
+const (
+	ScopeRead  = "read"  // GET and HEAD requests only
+	ScopeWrite = "write" // Every request
+)
+
+// tokenBytes is the entropy of an API token (256 bits)
+const tokenBytes = 32
+
+var (
+	// ErrTokenNotFound is returned when revoking a token that does not exist
+	ErrTokenNotFound = errors.New("token not found")
+	// ErrInvalidToken is returned for unknown, malformed or expired tokens
+	ErrInvalidToken = errors.New("invalid or expired token")
+)
+
+// Token is an API token. Only the hash of its secret is stored.
+type Token struct {
+	Name    string    `yaml:"name" json:"name"`
+	Scope   string    `yaml:"scope" json:"scope"`
+	Hash    string    `yaml:"hash" json:"-"`                             // SHA-256 of the secret, hex
+	Created time.Time `yaml:"created" json:"created"`                    // When the token was created
+	Expires time.Time `yaml:"expires,omitempty" json:"expires,omitzero"` // Zero never expires
+}
+
+// Expired reports whether the token has expired at now
+func (t Token) Expired(now time.Time) bool {
+	return !t.Expires.IsZero() && !now.Before(t.Expires)
+}
+
+// AllowsMethod reports whether the token's scope allows an HTTP method
+func (t Token) AllowsMethod(method string) bool {
+	if t.Scope == ScopeWrite {
+		return true
+	}
+	return method == "GET" || method == "HEAD" || method == "OPTIONS"
+}
+
+// tokensFile is the TokensFile layout
+type tokensFile struct {
+	Tokens []Token `yaml:"tokens"`
+}
+
+// TokenStore reads and writes the API tokens of a project
+type TokenStore struct {
+	path string
+}
+
+// NewTokenStore returns the token store of a project
+func NewTokenStore(projectDir string) *TokenStore {
+	return &TokenStore{path: filepath.Join(projectDir, TokensFile)}
+}
+
+// Path is the file holding the tokens
+func (s *TokenStore) Path() string { return s.path }
+
+// List returns the tokens, oldest first
+func (s *TokenStore) List() ([]Token, error) {
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return []Token{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", TokensFile, err)
+	}
+
+	var file tokensFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", TokensFile, err)
+	}
+	if file.Tokens == nil {
+		return []Token{}, nil
+	}
+	return file.Tokens, nil
+}
+
+// Create adds a token and returns its secret, which is not stored and
+// cannot be shown again. expiry 0 never expires.
+func (s *TokenStore) Create(name, scope string, expiry time.Duration) (string, Token, error) {
+	if err := ValidateUsername(name); err != nil {
+		return "", Token{}, fmt.Errorf("invalid token name %q (letters, digits, dots, dashes and underscores)", name)
+	}
+	if scope != ScopeRead && scope != ScopeWrite {
+		return "", Token{}, fmt.Errorf("invalid scope %q (must be %s or %s)", scope, ScopeRead, ScopeWrite)
+	}
+	if expiry < 0 {
+		return "", Token{}, fmt.Errorf("expiry must not be negative")
+	}
+
+	tokens, err := s.List()
+	if err != nil {
+		return "", Token{}, err
+	}
+	if slices.ContainsFunc(tokens, func(t Token) bool { return t.Name == name }) {
+		return "", Token{}, fmt.Errorf("token %s already exists", name)
+	}
+
+	b := make([]byte, tokenBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", Token{}, fmt.Errorf("failed to generate token: %w", err)
+	}
+	secret := TokenPrefix + base64.RawURLEncoding.EncodeToString(b)
+
+	token := Token{Name: name, Scope: scope, Hash: hashToken(secret), Created: time.Now().UTC().Truncate(time.Second)}
+	if expiry > 0 {
+		token.Expires = token.Created.Add(expiry)
+	}
+	if err := s.write(append(tokens, token)); err != nil {
+		return "", Token{}, err
+	}
+	return secret, token, nil
+}
+
+// Revoke deletes a token by name
+func (s *TokenStore) Revoke(name string) error {
+	tokens, err := s.List()
+	if err != nil {
+		return err
+	}
+	i := slices.IndexFunc(tokens, func(t Token) bool { return t.Name == name })
+	if i < 0 {
+		return ErrTokenNotFound
+	}
+	return s.write(slices.Delete(tokens, i, i+1))
+}
+
+// Verify returns the token matching secret, unless it expired at now
+func (s *TokenStore) Verify(secret string, now time.Time) (Token, error) {
+	if !strings.HasPrefix(secret, TokenPrefix) {
+		return Token{}, ErrInvalidToken
+	}
+	tokens, err := s.List()
+	if err != nil {
+		return Token{}, err
+	}
+
+	hash := []byte(hashToken(secret))
+	for _, t := range tokens {
+		if subtle.ConstantTimeCompare(hash, []byte(t.Hash)) == 1 {
+			if t.Expired(now) {
+				return Token{}, ErrInvalidToken
+			}
+			return t, nil
+		}
+	}
+	return Token{}, ErrInvalidToken
+}
+
+// write replaces the tokens file; it is only readable by its owner
+func (s *TokenStore) write(tokens []Token) error {
+	data, err := yaml.Marshal(tokensFile{Tokens: tokens})
+	if err != nil {
+		return fmt.Errorf("failed to encode %s: %w", TokensFile, err)
+	}
+
+	// Rename over the file so the web UI never reads a partial write
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write %s: %w", TokensFile, err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		_ = os.Remove(tmp)
+		return fmt.Errorf("failed to write %s: %w", TokensFile, err)
+	}
+	return nil
+}
+
+// hashToken returns the stored form of a token secret. Secrets are random,
+// so a fast hash is enough.
+func hashToken(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// ParseExpiry parses a token lifetime: a Go duration (e.g. 12h), a number
+// of days (e.g. 30d), or "never" (0)
+func ParseExpiry(s string) (time.Duration, error) {
+	if s == "never" || s == "0" {
+		return 0, nil
+	}
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n <= 0 {
+			return 0, fmt.Errorf("invalid expiry %q (e.g. 30d, 12h or never)", s)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid expiry %q (e.g. 30d, 12h or never)", s)
+	}
+	return d, nil
+}
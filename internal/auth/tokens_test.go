@@ -0,0 +1,81 @@
+package auth
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTokenStore(t *testing.T) {
+	store := NewTokenStore(t.TempDir())
+
+	secret, token, err := store.Create("homepage", ScopeRead, 30*24*time.Hour)
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if !strings.HasPrefix(secret, TokenPrefix) || token.Hash == "" || strings.Contains(token.Hash, secret) {
+		t.Errorf("Create() = %q, %+v", secret, token)
+	}
+	if token.Expires.Sub(token.Created) != 30*24*time.Hour {
+		t.Errorf("expires = %v, want 30 days after %v", token.Expires, token.Created)
+	}
+
+	// Only the hash is stored, readable by the owner only
+	data, _ := os.ReadFile(store.Path())
+	if strings.Contains(string(data), secret) {
+		t.Error("tokens file contains the secret")
+	}
+	if info, _ := os.Stat(store.Path()); info.Mode().Perm() != 0o600 {
+		t.Errorf("tokens file mode = %v, want 0600", info.Mode().Perm())
+	}
+
+	if _, _, err := store.Create("homepage", ScopeWrite, 0); err == nil {
+		t.Error("expected error for a duplicate name")
+	}
+	if _, _, err := store.Create("ci", "admin", 0); err == nil {
+		t.Error("expected error for an unknown scope")
+	}
+
+	got, err := store.Verify(secret, time.Now())
+	if err != nil || got.Name != "homepage" || got.AllowsMethod("POST") || !got.AllowsMethod("GET") {
+		t.Errorf("Verify() = %+v, %v", got, err)
+	}
+	if _, err := store.Verify(secret, token.Expires); err != ErrInvalidToken {
+		t.Errorf("Verify() after expiry error = %v, want ErrInvalidToken", err)
+	}
+	if _, err := store.Verify(TokenPrefix+"wrong", time.Now()); err != ErrInvalidToken {
+		t.Errorf("Verify(wrong) error = %v, want ErrInvalidToken", err)
+	}
+
+	if err := store.Revoke("homepage"); err != nil {
+		t.Fatalf("Revoke failed: %v", err)
+	}
+	if _, err := store.Verify(secret, time.Now()); err != ErrInvalidToken {
+		t.Errorf("Verify() after revoke error = %v, want ErrInvalidToken", err)
+	}
+	if err := store.Revoke("homepage"); err != ErrTokenNotFound {
+		t.Errorf("Revoke(missing) error = %v, want ErrTokenNotFound", err)
+	}
+}
+
+func TestParseExpiry(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    time.Duration
+		wantErr bool
+	}{
+		{"30d", 30 * 24 * time.Hour, false},
+		{"12h", 12 * time.Hour, false},
+		{"never", 0, false},
+		{"0d", 0, true},
+		{"-1h", 0, true},
+		{"soon", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseExpiry(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseExpiry(%q) = %v, %v", tt.in, got, err)
+		}
+	}
+}
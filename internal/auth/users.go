@@ -0,0 +1,90 @@
+// Package auth manages the users allowed through the authentication layer in
+// front of services: the Authelia users database, or the htpasswd file
+// Traefik checks in basic auth mode. It also keeps the API tokens scripts use
+// to call the web UI API without a browser session.
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"regexp"
+
+	"github.com/maiko/sdbx/internal/config"
+)
+
+// BasicAuthSecret is the htpasswd secret (secrets/<name>.txt) Traefik reads in
+// basic auth mode
+const BasicAuthSecret = "basic_auth_users"
+
+// MinPasswordLength is the shortest password accepted for a user
+const MinPasswordLength = 8
+
+// ErrUserNotFound is returned when changing a user that does not exist
+var ErrUserNotFound = errors.New("user not found")
+
+// ErrNoGroups is returned when setting groups in basic auth mode
+var ErrNoGroups = errors.New("basic auth has no groups - use auth.mode: authelia")
+
+// User is an account of the authentication backend
+type User struct {
+	Name        string   `json:"name"`
+	DisplayName string   `json:"display_name,omitempty"`
+	Email       string   `json:"email,omitempty"`
+	Groups      []string `json:"groups,omitempty"`
+}
+
+// Store reads and writes the users of one authentication backend
+type Store interface {
+	// Path is the file holding the users
+	Path() string
+	// Service is the container to restart so changes take effect
+	Service() string
+	List() ([]User, error)
+	// SetPassword creates the user or changes its password
+	SetPassword(name, password string) (created bool, err error)
+	// SetGroups replaces the groups of an existing user
+	SetGroups(name string, groups []string) error
+	Remove(name string) error
+}
+
+// NewStore returns the user store of the configured auth mode
+func NewStore(projectDir string, cfg *config.Config) Store {
+	if cfg.IsBasicAuth() {
+		return &htpasswdStore{path: filepath.Join(projectDir, "secrets", BasicAuthSecret+".txt")}
+	}
+	return &autheliaStore{
+		path:   filepath.Join(projectDir, "configs", "authelia", "users_database.yml"),
+		domain: cfg.Domain,
+	}
+}
+
+// usernameRegex matches names valid in both htpasswd and Authelia files
+var usernameRegex = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9._-]*$`)
+
+// ValidateUsername checks a user name
+func ValidateUsername(name string) error {
+	if !usernameRegex.MatchString(name) {
+		return fmt.Errorf("invalid user name %q (letters, digits, dots, dashes and underscores)", name)
+	}
+	return nil
+}
+
+// ValidatePassword checks a new password
+func ValidatePassword(password string) error {
+	if len(password) < MinPasswordLength {
+		return fmt.Errorf("password must be at least %d characters", MinPasswordLength)
+	}
+	return nil
+}
+
+// checkNewPassword validates the user name and password before a change
+func checkNewPassword(name, password string) error {
+	if err := ValidateUsername(name); err != nil {
+		return err
+	}
+	return ValidatePassword(password)
+}
+
+// errLastUser refuses to lock everyone out
+var errLastUser = errors.New("cannot remove the last user")
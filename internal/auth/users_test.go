@@ -0,0 +1,147 @@
+package auth
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+	"gopkg.in/yaml.v3"
+
+	"github.com/maiko/sdbx/internal/config"
+	"github.com/maiko/sdbx/internal/secrets"
+)
+
+func TestNewStore(t *testing.T) {
+	cfg := config.DefaultConfig()
+	if store := NewStore("/srv", cfg); store.Service() != "authelia" || store.Path() != "/srv/configs/authelia/users_database.yml" {
+		t.Errorf("default store = %s %s", store.Service(), store.Path())
+	}
+	cfg.Auth.Mode = config.AuthModeBasic
+	if store := NewStore("/srv", cfg); store.Service() != "traefik" || store.Path() != "/srv/secrets/basic_auth_users.txt" {
+		t.Errorf("basic store = %s %s", store.Service(), store.Path())
+	}
+}
+
+func TestHtpasswdStore(t *testing.T) {
+	dir := t.TempDir()
+	spec := secrets.Spec{Name: BasicAuthSecret, Type: secrets.TypeHtpasswd}
+	if _, err := secrets.EnsureSecrets(dir, []secrets.Spec{spec}); err != nil {
+		t.Fatalf("EnsureSecrets failed: %v", err)
+	}
+	store := &htpasswdStore{path: filepath.Join(dir, BasicAuthSecret+".txt")}
+
+	created, err := store.SetPassword("alice", "correct horse")
+	if err != nil || !created {
+		t.Fatalf("SetPassword(alice) = %v, %v", created, err)
+	}
+	if _, err := store.SetPassword("admin", "battery staple"); err != nil {
+		t.Fatalf("SetPassword(admin) failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, BasicAuthSecret+".password")); !os.IsNotExist(err) {
+		t.Error("generated password should be removed once changed")
+	}
+
+	users, err := store.List()
+	if err != nil || len(users) != 2 || users[0].Name != "admin" || users[1].Name != "alice" {
+		t.Fatalf("List() = %v, %v", users, err)
+	}
+
+	// The file stays a valid secret and the hashes check out
+	data, _ := os.ReadFile(store.path)
+	if err := secrets.Validate(spec, string(data)); err != nil {
+		t.Errorf("htpasswd file no longer validates: %v", err)
+	}
+	entries, _ := store.read()
+	if bcrypt.CompareHashAndPassword([]byte(entries[1].hash), []byte("correct horse")) != nil {
+		t.Error("alice's hash does not match her password")
+	}
+
+	if _, err := store.SetPassword("bob", "short"); err == nil {
+		t.Error("expected short password to be rejected")
+	}
+	if err := store.SetGroups("alice", []string{"friends"}); !errors.Is(err, ErrNoGroups) {
+		t.Errorf("SetGroups() = %v, want ErrNoGroups", err)
+	}
+	if err := store.Remove("bob"); !errors.Is(err, ErrUserNotFound) {
+		t.Errorf("Remove(bob) = %v, want ErrUserNotFound", err)
+	}
+	if err := store.Remove("alice"); err != nil {
+		t.Fatalf("Remove(alice) failed: %v", err)
+	}
+	if err := store.Remove("admin"); err == nil {
+		t.Error("expected removing the last user to fail")
+	}
+}
+
+func TestAutheliaStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "users_database.yml")
+	existing := `users:
+  admin:
+    displayname: "Admin"
+    password: "$argon2id$v=19$m=65536,t=3,p=4$c2FsdA$aGFzaA"
+    email: admin@example.com
+    groups:
+      - admins
+      - users
+`
+	if err := os.WriteFile(path, []byte(existing), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	store := &autheliaStore{path: path, domain: "example.com"}
+
+	if created, err := store.SetPassword("alice", "correct horse"); err != nil || !created {
+		t.Fatalf("SetPassword(alice) = %v, %v", created, err)
+	}
+	users, err := store.List()
+	if err != nil || len(users) != 2 {
+		t.Fatalf("List() = %v, %v", users, err)
+	}
+	if users[0].Name != "admin" || strings.Join(users[0].Groups, ",") != "admins,users" {
+		t.Errorf("admin changed: %+v", users[0])
+	}
+	if users[1].Email != "alice@example.com" || strings.Join(users[1].Groups, ",") != "users" {
+		t.Errorf("unexpected new user: %+v", users[1])
+	}
+
+	var db autheliaUsers
+	data, _ := os.ReadFile(path)
+	if err := yaml.Unmarshal(data, &db); err != nil {
+		t.Fatalf("users database no longer parses: %v", err)
+	}
+	if !strings.HasPrefix(db.Users["alice"].Password, "$argon2id$v=19$m=65536,t=3,p=4$") {
+		t.Errorf("alice's password is not an Authelia argon2id hash: %s", db.Users["alice"].Password)
+	}
+
+	if err := store.SetGroups("alice", []string{"friends"}); err != nil {
+		t.Fatalf("SetGroups(alice) failed: %v", err)
+	}
+	if users, _ := store.List(); strings.Join(users[1].Groups, ",") != "friends" || users[1].Email != "alice@example.com" {
+		t.Errorf("alice after SetGroups = %+v", users[1])
+	}
+	if err := store.SetGroups("bob", nil); !errors.Is(err, ErrUserNotFound) {
+		t.Errorf("SetGroups(bob) = %v, want ErrUserNotFound", err)
+	}
+
+	if err := store.Remove("admin"); err != nil {
+		t.Fatalf("Remove(admin) failed: %v", err)
+	}
+	if err := store.Remove("alice"); err == nil {
+		t.Error("expected removing the last user to fail")
+	}
+}
+
+func TestValidateUsername(t *testing.T) {
+	for _, name := range []string{"admin", "alice.smith", "bob_2"} {
+		if err := ValidateUsername(name); err != nil {
+			t.Errorf("ValidateUsername(%q) = %v", name, err)
+		}
+	}
+	for _, name := range []string{"", "al:ice", "-bob", "a b"} {
+		if err := ValidateUsername(name); err == nil {
+			t.Errorf("ValidateUsername(%q) should fail", name)
+		}
+	}
+}
@@ -34,6 +34,9 @@ type Backup struct {
 type Manager struct {
 	projectDir string
 	backupDir  string
+
+	// Progress, when set, is called with each step of Create and Restore
+	Progress func(step string)
 }
 
 // NewManager creates a new backup manager
@@ -46,6 +49,23 @@ func NewManager(projectDir string) *Manager {
 
 // Create creates a new backup
 func (m *Manager) Create(ctx context.Context) (*Backup, error) {
+	// Files to backup
+	filesToBackup := []string{
+		".sdbx.yaml",
+		".sdbx.lock",
+		".sdbx.lock.sha256",
+		".sdbx.checklist.yaml",
+		"compose.yaml",
+		"secrets/",
+		"configs/",
+	}
+
+	return m.Archive(ctx, "backup", filesToBackup)
+}
+
+// Archive creates a restorable backup of selected project paths, named
+// sdbx-<label>-<timestamp>.tar.gz (e.g. the config of a removed addon)
+func (m *Manager) Archive(ctx context.Context, label string, files []string) (*Backup, error) {
 	// Ensure backup directory exists
 	if err := os.MkdirAll(m.backupDir, 0750); err != nil {
 		return nil, fmt.Errorf("failed to create backup directory: %w", err)
@@ -53,32 +73,23 @@ func (m *Manager) Create(ctx context.Context) (*Backup, error) {
 
 	// Generate backup name
 	timestamp := time.Now()
-	name := fmt.Sprintf("sdbx-backup-%s.tar.gz", timestamp.Format("2006-01-02-150405"))
+	name := fmt.Sprintf("sdbx-%s-%s.tar.gz", label, timestamp.Format("2006-01-02-150405"))
 	backupPath := filepath.Join(m.backupDir, name)
 
 	// Get hostname
 	hostname, _ := os.Hostname()
 
-	// Files to backup
-	filesToBackup := []string{
-		".sdbx.yaml",
-		".sdbx.lock",
-		"compose.yaml",
-		"secrets/",
-		"configs/",
-	}
-
 	// Create metadata
 	metadata := Metadata{
 		Version:   "1.0.0",
 		Timestamp: timestamp,
 		Hostname:  hostname,
 		ProjectID: filepath.Base(m.projectDir),
-		Files:     filesToBackup,
+		Files:     files,
 	}
 
 	// Create tar.gz archive
-	if err := m.createArchive(ctx, backupPath, filesToBackup, metadata); err != nil {
+	if err := m.createArchive(ctx, backupPath, files, metadata); err != nil {
 		return nil, fmt.Errorf("failed to create archive: %w", err)
 	}
 
@@ -125,6 +136,7 @@ func (m *Manager) createArchive(ctx context.Context, archivePath string, files [
 			// Skip if doesn't exist
 			continue
 		}
+		m.progress("Archiving " + file)
 
 		// Add to archive
 		if err := m.addToArchive(ctx, tarWriter, fullPath, file); err != nil {
@@ -361,6 +373,7 @@ func (m *Manager) Restore(ctx context.Context, backupName string) error {
 	}
 
 	// Create safety backup before restoring
+	m.progress("Creating safety backup")
 	safetyBackup, safetyErr := m.Create(ctx)
 	if safetyErr != nil {
 		fmt.Fprintf(os.Stderr, "Warning: could not create safety backup before restore: %v\n", safetyErr)
@@ -392,6 +405,7 @@ func (m *Manager) Restore(ctx context.Context, backupName string) error {
 	}
 
 	// Extract all files
+	m.progress("Extracting " + backupName)
 	for {
 		header, err := tarReader.Next()
 		if err == io.EOF {
@@ -470,6 +484,137 @@ func (m *Manager) Delete(ctx context.Context, backupName string) error {
 	return nil
 }
 
+// Entry is a file stored in a backup archive
+type Entry struct {
+	Path string `json:"path"`
+	Size int64  `json:"size"`
+}
+
+// Contents lists the files of a backup, without its metadata
+func (m *Manager) Contents(backupName string) ([]Entry, error) {
+	if err := ValidateBackupName(backupName); err != nil {
+		return nil, fmt.Errorf("invalid backup name: %w", err)
+	}
+
+	f, err := os.Open(filepath.Join(m.backupDir, backupName)) //nolint:gosec // G304 - validated backupName within backupDir
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("backup not found: %s", backupName)
+		}
+		return nil, fmt.Errorf("failed to open backup: %w", err)
+	}
+	defer f.Close()
+
+	gzReader, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create gzip reader: %w", err)
+	}
+	defer gzReader.Close()
+
+	entries := []Entry{}
+	tarReader := tar.NewReader(gzReader)
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read tar header: %w", err)
+		}
+		if header.Name == "metadata.json" || header.Typeflag != tar.TypeReg {
+			continue
+		}
+		entries = append(entries, Entry{Path: header.Name, Size: header.Size})
+	}
+	return entries, nil
+}
+
+// Import stores an archive read from r as backupName, such as one
+// downloaded from another host. The archive must carry sdbx metadata and
+// must not replace an existing backup.
+func (m *Manager) Import(backupName string, r io.Reader) (*Backup, error) {
+	if err := ValidateBackupName(backupName); err != nil {
+		return nil, fmt.Errorf("invalid backup name: %w", err)
+	}
+	if !strings.HasSuffix(backupName, ".tar.gz") {
+		return nil, fmt.Errorf("backup name must end in .tar.gz")
+	}
+	if err := os.MkdirAll(m.backupDir, 0750); err != nil {
+		return nil, fmt.Errorf("failed to create backup directory: %w", err)
+	}
+
+	backupPath := filepath.Join(m.backupDir, backupName)
+	if _, err := os.Stat(backupPath); err == nil {
+		return nil, fmt.Errorf("backup already exists: %s", backupName)
+	}
+
+	// Write next to the final path so a partial upload is never listed
+	tmp, err := os.CreateTemp(m.backupDir, ".import-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, r); err != nil {
+		_ = tmp.Close()
+		return nil, fmt.Errorf("failed to write backup: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return nil, fmt.Errorf("failed to close file: %w", err)
+	}
+
+	metadata, err := m.readMetadata(tmp.Name())
+	if err != nil {
+		return nil, fmt.Errorf("not an sdbx backup: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), backupPath); err != nil {
+		return nil, fmt.Errorf("failed to store backup: %w", err)
+	}
+
+	return &Backup{
+		Name:     backupName,
+		Path:     backupPath,
+		Metadata: metadata,
+	}, nil
+}
+
+// Prune deletes the oldest sdbx-backup-* archives beyond the newest keep,
+// and returns the names deleted. Archives of removed addons are never
+// pruned. keep <= 0 keeps everything.
+func (m *Manager) Prune(ctx context.Context, keep int) ([]string, error) {
+	if keep <= 0 {
+		return nil, nil
+	}
+	backups, err := m.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var deleted []string
+	kept := 0
+	for _, b := range backups {
+		if !strings.HasPrefix(b.Name, "sdbx-backup-") {
+			continue
+		}
+		if kept < keep {
+			kept++
+			continue
+		}
+		if err := m.Delete(ctx, b.Name); err != nil {
+			return deleted, err
+		}
+		deleted = append(deleted, b.Name)
+	}
+	return deleted, nil
+}
+
+// progress reports a step to the Progress callback, if any
+func (m *Manager) progress(step string) {
+	if m.Progress != nil {
+		m.Progress(step)
+	}
+}
+
 // GetSize returns the size of a backup file in bytes
 func (b *Backup) GetSize() (int64, error) {
 	info, err := os.Stat(b.Path)
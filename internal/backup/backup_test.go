@@ -9,6 +9,8 @@ import (
 	"strings"
 	"testing"
 	"time"
+
+	"github.com/maiko/sdbx/internal/config"
 )
 
 // TestNewManager verifies manager construction
@@ -752,3 +754,139 @@ func TestBackupSkipsMissingFiles(t *testing.T) {
 		t.Error("backup should not be nil")
 	}
 }
+
+// writeTestArchive writes a backup archive with the given timestamp
+func writeTestArchive(t *testing.T, m *Manager, name string, ts time.Time) {
+	t.Helper()
+	if err := os.MkdirAll(m.backupDir, 0750); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.createArchive(context.Background(), filepath.Join(m.backupDir, name), nil, Metadata{Version: "1.0.0", Timestamp: ts}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestBackupContents(t *testing.T) {
+	tmpDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(tmpDir, ".sdbx.yaml"), []byte("domain: test.local"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(tmpDir, "secrets"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(tmpDir, "secrets", "jwt.txt"), []byte("secret123"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var steps []string
+	manager := NewManager(tmpDir)
+	manager.Progress = func(step string) { steps = append(steps, step) }
+	backup, err := manager.Create(context.Background())
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if len(steps) != 2 {
+		t.Errorf("progress steps = %v, want one per archived path", steps)
+	}
+
+	entries, err := manager.Contents(backup.Name)
+	if err != nil {
+		t.Fatalf("Contents failed: %v", err)
+	}
+	want := []Entry{{Path: ".sdbx.yaml", Size: 18}, {Path: filepath.Join("secrets", "jwt.txt"), Size: 9}}
+	if len(entries) != len(want) || entries[0] != want[0] || entries[1] != want[1] {
+		t.Errorf("Contents() = %v, want %v", entries, want)
+	}
+
+	if _, err := manager.Contents("../etc/passwd"); err == nil {
+		t.Error("expected error for traversal name")
+	}
+}
+
+func TestImportBackup(t *testing.T) {
+	src := NewManager(t.TempDir())
+	writeTestArchive(t, src, "sdbx-backup-2026-01-02-030405.tar.gz", time.Now())
+	data, err := os.ReadFile(filepath.Join(src.backupDir, "sdbx-backup-2026-01-02-030405.tar.gz"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	manager := NewManager(t.TempDir())
+	backup, err := manager.Import("sdbx-backup-2026-01-02-030405.tar.gz", strings.NewReader(string(data)))
+	if err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+	if backup.Metadata.Version != "1.0.0" {
+		t.Errorf("imported metadata = %+v", backup.Metadata)
+	}
+
+	if _, err := manager.Import(backup.Name, strings.NewReader(string(data))); err == nil {
+		t.Error("expected error when replacing an existing backup")
+	}
+	if _, err := manager.Import("notes.tar.gz", strings.NewReader("not an archive")); err == nil {
+		t.Error("expected error for an archive without metadata")
+	}
+	if _, err := manager.Import("backup.zip", strings.NewReader(string(data))); err == nil {
+		t.Error("expected error for a name without .tar.gz")
+	}
+
+	backups, err := manager.List(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(backups) != 1 {
+		t.Errorf("expected only the imported backup, got %d", len(backups))
+	}
+}
+
+func TestPruneBackups(t *testing.T) {
+	manager := NewManager(t.TempDir())
+	now := time.Now()
+	writeTestArchive(t, manager, "sdbx-backup-3.tar.gz", now.Add(-3*time.Hour))
+	writeTestArchive(t, manager, "sdbx-backup-2.tar.gz", now.Add(-2*time.Hour))
+	writeTestArchive(t, manager, "sdbx-backup-1.tar.gz", now.Add(-1*time.Hour))
+	writeTestArchive(t, manager, "sdbx-addon-sonarr-0.tar.gz", now.Add(-4*time.Hour))
+
+	deleted, err := manager.Prune(context.Background(), 2)
+	if err != nil {
+		t.Fatalf("Prune failed: %v", err)
+	}
+	if len(deleted) != 1 || deleted[0] != "sdbx-backup-3.tar.gz" {
+		t.Errorf("Prune() deleted %v, want the oldest sdbx-backup only", deleted)
+	}
+
+	backups, _ := manager.List(context.Background())
+	if len(backups) != 3 {
+		t.Errorf("expected 3 backups left, got %d", len(backups))
+	}
+}
+
+func TestPushDirectoryAndDue(t *testing.T) {
+	manager := NewManager(t.TempDir())
+	now := time.Now()
+
+	due, err := manager.Due(context.Background(), time.Hour, now)
+	if err != nil || !due {
+		t.Errorf("Due() = %v, %v, want due without backups", due, err)
+	}
+
+	writeTestArchive(t, manager, "sdbx-backup-1.tar.gz", now.Add(-30*time.Minute))
+	if due, _ := manager.Due(context.Background(), time.Hour, now); due {
+		t.Error("Due() = true with a recent backup")
+	}
+	if due, _ := manager.Due(context.Background(), 10*time.Minute, now); !due {
+		t.Error("Due() = false with a backup older than the schedule")
+	}
+
+	target := filepath.Join(t.TempDir(), "nas")
+	remote := config.BackupRemote{Name: "nas", Type: config.BackupRemoteDirectory, Target: target}
+	if err := manager.Push(context.Background(), "sdbx-backup-1.tar.gz", remote); err != nil {
+		t.Fatalf("Push failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(target, "sdbx-backup-1.tar.gz")); err != nil {
+		t.Errorf("backup not copied to remote: %v", err)
+	}
+	if err := manager.Push(context.Background(), "missing.tar.gz", remote); err == nil {
+		t.Error("expected error for a missing backup")
+	}
+}
@@ -0,0 +1,155 @@
+package backup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/maiko/sdbx/internal/config"
+	"github.com/maiko/sdbx/internal/notify"
+	"github.com/maiko/sdbx/internal/scheduler"
+)
+
+// ScheduleCheckInterval is how often the schedule job looks for a due backup
+const ScheduleCheckInterval = 15 * time.Minute
+
+// Push copies a backup to a remote. rclone remotes run the rclone binary,
+// which must be installed where the push runs.
+func (m *Manager) Push(ctx context.Context, backupName string, remote config.BackupRemote) error {
+	if err := ValidateBackupName(backupName); err != nil {
+		return fmt.Errorf("invalid backup name: %w", err)
+	}
+	backupPath := filepath.Join(m.backupDir, backupName)
+	if _, err := os.Stat(backupPath); os.IsNotExist(err) {
+		return fmt.Errorf("backup not found: %s", backupName)
+	}
+
+	switch remote.Type {
+	case config.BackupRemoteDirectory:
+		return copyFile(backupPath, filepath.Join(remote.Target, backupName))
+	case config.BackupRemoteRclone:
+		dst := strings.TrimSuffix(remote.Target, "/") + "/" + backupName
+		if strings.HasSuffix(remote.Target, ":") {
+			dst = remote.Target + backupName
+		}
+		conf := remote.ConfigFile()
+		if !filepath.IsAbs(conf) {
+			conf = filepath.Join(m.projectDir, conf)
+		}
+		cmd := exec.CommandContext(ctx, "rclone", "copyto", backupPath, dst, "--config", conf) //nolint:gosec // G204 - target from validated config
+		if out, err := cmd.CombinedOutput(); err != nil {
+			return fmt.Errorf("rclone copyto failed: %w: %s", err, strings.TrimSpace(string(out)))
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown remote type: %s", remote.Type)
+	}
+}
+
+// copyFile copies src to dst through a temporary file, so an interrupted
+// copy never looks like a complete backup
+func copyFile(src, dst string) error {
+	if err := os.MkdirAll(filepath.Dir(dst), 0750); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+	in, err := os.Open(src) //nolint:gosec // G304 - src within backupDir
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	tmp := dst + ".part"
+	out, err := os.Create(tmp) //nolint:gosec // G304 - dst from validated config
+	if err != nil {
+		return fmt.Errorf("failed to create file: %w", err)
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		_ = out.Close()
+		_ = os.Remove(tmp)
+		return fmt.Errorf("failed to copy backup: %w", err)
+	}
+	if err := out.Close(); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, dst)
+}
+
+// Due reports whether a scheduled backup should be taken: the newest
+// sdbx-backup-* archive is older than interval, or there is none
+func (m *Manager) Due(ctx context.Context, interval time.Duration, now time.Time) (bool, error) {
+	backups, err := m.List(ctx)
+	if err != nil {
+		return false, err
+	}
+	for _, b := range backups {
+		if strings.HasPrefix(b.Name, "sdbx-backup-") {
+			return now.Sub(b.Metadata.Timestamp) >= interval, nil
+		}
+	}
+	return true, nil
+}
+
+// RunScheduled creates a backup, copies it to every remote and prunes old
+// backups. A failed remote does not stop the others.
+func (m *Manager) RunScheduled(ctx context.Context, cfg config.BackupConfig) (*Backup, error) {
+	b, err := m.Create(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create backup: %w", err)
+	}
+
+	var errs []error
+	for _, remote := range cfg.Remotes {
+		m.progress("Copying to " + remote.Name)
+		if err := m.Push(ctx, b.Name, remote); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", remote.Name, err))
+		}
+	}
+	if _, err := m.Prune(ctx, cfg.Keep); err != nil {
+		errs = append(errs, fmt.Errorf("failed to prune backups: %w", err))
+	}
+	return b, errors.Join(errs...)
+}
+
+// Job returns a scheduler job taking the backups of .sdbx.yaml's backup
+// schedule, and notifying when one fails
+func Job(projectDir string) scheduler.Job {
+	return scheduler.Job{
+		Name:     "backup",
+		Interval: ScheduleCheckInterval,
+		Run: func(ctx context.Context) error {
+			cfg, err := config.Load()
+			if err != nil {
+				return fmt.Errorf("failed to load config: %w", err)
+			}
+			interval := cfg.Backup.Interval()
+			if interval == 0 {
+				return nil
+			}
+
+			m := NewManager(projectDir)
+			due, err := m.Due(ctx, interval, time.Now())
+			if err != nil || !due {
+				return err
+			}
+			if _, err = m.RunScheduled(ctx, cfg.Backup); err != nil {
+				msg := notify.Message{
+					Title:    "Scheduled backup failed",
+					Body:     err.Error(),
+					Severity: notify.SeverityWarning,
+					Source:   "backup",
+				}
+				if nerr := notify.New(projectDir, cfg).Send(ctx, msg); nerr != nil {
+					err = errors.Join(err, fmt.Errorf("failed to notify: %w", nerr))
+				}
+			}
+			return err
+		},
+	}
+}
@@ -0,0 +1,234 @@
+// Package certs reads the certificates Traefik obtained from Let's Encrypt
+// (configs/traefik/acme.json) and the renewal errors of its logs.
+package certs
+
+import (
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// ACMEFile is Traefik's ACME storage, relative to the project directory
+	ACMEFile = "configs/traefik/acme.json"
+
+	// RenewalWindow is how long before expiry Traefik renews a certificate,
+	// so a certificate closer to expiry has failed to renew
+	RenewalWindow = 30 * 24 * time.Hour
+
+	// DefaultWarnDays is how many days before expiry a certificate is
+	// reported by sdbx doctor and cert_expiry alerts
+	DefaultWarnDays = 14
+)
+
+// Certificate statuses
+const (
+	StatusValid    = "valid"    // Outside the renewal window
+	StatusRenewing = "renewing" // In the renewal window: Traefik is renewing it, or failing to
+	StatusExpiring = "expiring" // Fewer than the warning days left
+	StatusExpired  = "expired"
+)
+
+// Certificate is a certificate of acme.json
+type Certificate struct {
+	Resolver string    `json:"resolver"`
+	Domain   string    `json:"domain"`
+	SANs     []string  `json:"sans,omitempty"`
+	Issuer   string    `json:"issuer"`
+	NotAfter time.Time `json:"notAfter"`
+}
+
+// DaysLeft returns the whole days left before expiry, negative once expired
+func (c Certificate) DaysLeft(now time.Time) int {
+	left := c.NotAfter.Sub(now)
+	if left < 0 {
+		return -int((-left).Hours()/24) - 1
+	}
+	return int(left.Hours() / 24)
+}
+
+// Status classifies the certificate at now, warning warnDays before expiry
+func (c Certificate) Status(now time.Time, warnDays int) string {
+	switch {
+	case !now.Before(c.NotAfter):
+		return StatusExpired
+	case c.DaysLeft(now) < warnDays:
+		return StatusExpiring
+	case c.NotAfter.Sub(now) < RenewalWindow:
+		return StatusRenewing
+	}
+	return StatusValid
+}
+
+// Names returns the main domain and the SANs of the certificate
+func (c Certificate) Names() []string {
+	return append([]string{c.Domain}, c.SANs...)
+}
+
+// acmeStore is the layout of acme.json: the account and certificates of
+// each certificate resolver
+type acmeStore map[string]struct {
+	Certificates []struct {
+		Domain struct {
+			Main string   `json:"main"`
+			SANs []string `json:"sans"`
+		} `json:"domain"`
+		Certificate string `json:"certificate"` // Base64 of the PEM chain
+	} `json:"Certificates"`
+}
+
+// Read returns the certificates of the project's acme.json, soonest expiry
+// first. A missing or empty file has none.
+func Read(projectDir string) ([]Certificate, error) {
+	data, err := os.ReadFile(filepath.Join(projectDir, ACMEFile))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", ACMEFile, err)
+	}
+	return Parse(data)
+}
+
+// Parse reads the certificates of acme.json data, soonest expiry first
+func Parse(data []byte) ([]Certificate, error) {
+	if len(strings.TrimSpace(string(data))) == 0 {
+		return nil, nil
+	}
+	var store acmeStore
+	if err := json.Unmarshal(data, &store); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", ACMEFile, err)
+	}
+
+	var certs []Certificate
+	for resolver, entry := range store {
+		for _, c := range entry.Certificates {
+			parsed, err := parseChain(c.Certificate)
+			if err != nil {
+				return nil, fmt.Errorf("%s: certificate of %s: %w", ACMEFile, c.Domain.Main, err)
+			}
+			certs = append(certs, Certificate{
+				Resolver: resolver,
+				Domain:   c.Domain.Main,
+				SANs:     c.Domain.SANs,
+				Issuer:   parsed.Issuer.CommonName,
+				NotAfter: parsed.NotAfter,
+			})
+		}
+	}
+	slices.SortFunc(certs, func(a, b Certificate) int {
+		if c := a.NotAfter.Compare(b.NotAfter); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Domain, b.Domain)
+	})
+	return certs, nil
+}
+
+// parseChain returns the leaf of a base64 encoded PEM chain
+func parseChain(encoded string) (*x509.Certificate, error) {
+	chain, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("invalid base64: %w", err)
+	}
+	block, _ := pem.Decode(chain)
+	if block == nil || block.Type != "CERTIFICATE" {
+		return nil, errors.New("no PEM certificate")
+	}
+	return x509.ParseCertificate(block.Bytes)
+}
+
+// RenewalFailure is the last error Traefik logged obtaining or renewing the
+// certificate of some domains
+type RenewalFailure struct {
+	Time    time.Time `json:"time,omitzero"`
+	Domains []string  `json:"domains"`
+	Reason  string    `json:"reason"`
+}
+
+var (
+	logTime  = regexp.MustCompile(`time="([^"]+)"`)
+	logMsg   = regexp.MustCompile(`msg="((?:[^"\\]|\\.)*)"`)
+	logError = regexp.MustCompile(`error="((?:[^"\\]|\\.)*)"`)
+
+	// obtainFailed is the message of a failed issuance or renewal:
+	// `Unable to obtain ACME certificate for domains "a.example.com,b.example.com": <reason>`
+	obtainFailed = regexp.MustCompile(`^Unable to obtain ACME certificate for domains "([^"]*)"(?::\s*(.*))?`)
+
+	// renewFailed is the message of a failed renewal, the reason in the
+	// error field: `Error renewing certificate from LE: {a.example.com [b.example.com]}`
+	renewFailed = regexp.MustCompile(`^Error renewing certificate from LE: \{(\S+) \[([^\]]*)\]\}`)
+)
+
+// RenewalFailures returns the last failure of each set of domains in
+// Traefik's logs, in order of first appearance
+func RenewalFailures(logs string) []RenewalFailure {
+	var failures []RenewalFailure
+	index := make(map[string]int)
+	for _, line := range strings.Split(logs, "\n") {
+		if !strings.Contains(line, "level=error") {
+			continue
+		}
+		failure, ok := parseFailure(line)
+		if !ok {
+			continue
+		}
+		key := strings.Join(failure.Domains, ",")
+		if i, seen := index[key]; seen {
+			failures[i] = failure
+			continue
+		}
+		index[key] = len(failures)
+		failures = append(failures, failure)
+	}
+	return failures
+}
+
+// parseFailure reads a Traefik log line reporting an ACME failure
+func parseFailure(line string) (RenewalFailure, bool) {
+	msg, ok := logField(logMsg, line)
+	if !ok {
+		return RenewalFailure{}, false
+	}
+	var failure RenewalFailure
+	if m := obtainFailed.FindStringSubmatch(msg); m != nil {
+		failure.Domains = strings.Split(m[1], ",")
+		failure.Reason = m[2]
+	} else if m := renewFailed.FindStringSubmatch(msg); m != nil {
+		failure.Domains = append([]string{m[1]}, strings.Fields(m[2])...)
+	} else {
+		return RenewalFailure{}, false
+	}
+	if reason, ok := logField(logError, line); ok {
+		failure.Reason = reason
+	}
+	failure.Reason = strings.Join(strings.Fields(failure.Reason), " ")
+	if value, ok := logField(logTime, line); ok {
+		failure.Time, _ = time.Parse(time.RFC3339, value)
+	}
+	return failure, true
+}
+
+// logField returns the unquoted value of a key="value" field of a log line
+func logField(field *regexp.Regexp, line string) (string, bool) {
+	m := field.FindStringSubmatch(line)
+	if m == nil {
+		return "", false
+	}
+	value, err := strconv.Unquote(`"` + m[1] + `"`)
+	if err != nil {
+		return m[1], true
+	}
+	return value, true
+}
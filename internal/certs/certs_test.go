@@ -0,0 +1,126 @@
+package certs
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// acmeCertificate returns a base64 PEM certificate as acme.json stores it,
+// self-signed so its issuer is its subject
+func acmeCertificate(t *testing.T, domain string, notAfter time.Time) string {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "R11"},
+		NotBefore:    notAfter.Add(-90 * 24 * time.Hour),
+		NotAfter:     notAfter,
+		DNSNames:     []string{domain},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return base64.StdEncoding.EncodeToString(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+}
+
+func TestRead(t *testing.T) {
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	dir := t.TempDir()
+	if certs, err := Read(dir); err != nil || certs != nil {
+		t.Errorf("Read() without acme.json = %v, %v, want none", certs, err)
+	}
+
+	store := map[string]interface{}{
+		"letsencrypt": map[string]interface{}{
+			"Account": map[string]interface{}{"Email": "admin@example.com"},
+			"Certificates": []map[string]interface{}{
+				{"domain": map[string]interface{}{"main": "sonarr.example.com"}, "certificate": acmeCertificate(t, "sonarr.example.com", now.Add(60*24*time.Hour)), "Store": "default"},
+				{"domain": map[string]interface{}{"main": "auth.example.com", "sans": []string{"www.example.com"}}, "certificate": acmeCertificate(t, "auth.example.com", now.Add(10*24*time.Hour)), "Store": "default"},
+			},
+		},
+	}
+	data, err := json.Marshal(store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(dir, filepath.Dir(ACMEFile)), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, ACMEFile), data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	certs, err := Read(dir)
+	if err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+	if len(certs) != 2 || certs[0].Domain != "auth.example.com" || certs[1].Domain != "sonarr.example.com" {
+		t.Fatalf("Read() = %+v, want auth then sonarr", certs)
+	}
+	if certs[0].Resolver != "letsencrypt" || certs[0].Issuer != "R11" || strings.Join(certs[0].Names(), ",") != "auth.example.com,www.example.com" {
+		t.Errorf("certificate = %+v", certs[0])
+	}
+	if got := certs[0].DaysLeft(now); got != 10 {
+		t.Errorf("DaysLeft() = %d, want 10", got)
+	}
+
+	tests := []struct {
+		notAfter time.Time
+		want     string
+	}{
+		{now.Add(60 * 24 * time.Hour), StatusValid},
+		{now.Add(20 * 24 * time.Hour), StatusRenewing},
+		{now.Add(10 * 24 * time.Hour), StatusExpiring},
+		{now.Add(-time.Hour), StatusExpired},
+	}
+	for _, tt := range tests {
+		if got := (Certificate{NotAfter: tt.notAfter}).Status(now, DefaultWarnDays); got != tt.want {
+			t.Errorf("Status() expiring %s = %s, want %s", tt.notAfter, got, tt.want)
+		}
+	}
+
+	if _, err := Parse([]byte(`{"letsencrypt": {"Certificates": [{"domain": {"main": "x"}, "certificate": "bm90IGEgY2VydA=="}]}}`)); err == nil {
+		t.Error("Parse() accepted an invalid certificate")
+	}
+}
+
+func TestRenewalFailures(t *testing.T) {
+	logs := strings.Join([]string{
+		`sdbx-traefik  | time="2026-10-14T03:00:00Z" level=info msg="Starting provider *acme.ChallengeTLSALPN"`,
+		`sdbx-traefik  | time="2026-10-14T03:00:05Z" level=error msg="Unable to obtain ACME certificate for domains \"sonarr.example.com\": unable to generate a certificate for the domains [sonarr.example.com]: acme: error: 400 :: urn:ietf:params:acme:error:connection :: Timeout during connect (likely firewall problem)" providerName=letsencrypt.acme routerName=sonarr@docker`,
+		`sdbx-traefik  | time="2026-10-15T03:00:00Z" level=error msg="Error renewing certificate from LE: {auth.example.com [www.example.com]}" providerName=letsencrypt.acme error="error: one or more domains had a problem:\n[auth.example.com] acme: error: 429 :: urn:ietf:params:acme:error:rateLimited :: too many certificates already issued\n"`,
+		`sdbx-traefik  | time="2026-10-16T03:00:05Z" level=error msg="Unable to obtain ACME certificate for domains \"sonarr.example.com\": unable to generate a certificate for the domains [sonarr.example.com]: acme: error: 403 :: urn:ietf:params:acme:error:unauthorized :: Invalid response" providerName=letsencrypt.acme routerName=sonarr@docker`,
+		`sdbx-traefik  | time="2026-10-16T03:00:06Z" level=error msg="Router sonarr cannot be linked automatically with multiple Services"`,
+	}, "\n")
+
+	failures := RenewalFailures(logs)
+	if len(failures) != 2 {
+		t.Fatalf("RenewalFailures() = %+v, want sonarr and auth", failures)
+	}
+	sonarr, auth := failures[0], failures[1]
+	if strings.Join(sonarr.Domains, ",") != "sonarr.example.com" || !strings.Contains(sonarr.Reason, "unauthorized") {
+		t.Errorf("sonarr failure = %+v, want the last one", sonarr)
+	}
+	if !sonarr.Time.Equal(time.Date(2026, 10, 16, 3, 0, 5, 0, time.UTC)) {
+		t.Errorf("sonarr failure time = %s", sonarr.Time)
+	}
+	if strings.Join(auth.Domains, ",") != "auth.example.com,www.example.com" || !strings.Contains(auth.Reason, "rateLimited") || strings.Contains(auth.Reason, "\n") {
+		t.Errorf("auth failure = %+v, want the error field on one line", auth)
+	}
+}
@@ -0,0 +1,90 @@
+package certs
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// VerifyFile checks a certificate file of the custom TLS provider: its key
+// matches, its chain (the intermediates following it in the file)
+// validates against the roots of caBundle, the system roots when empty,
+// and it is for domain or one of its subdomains.
+func VerifyFile(certFile, keyFile, caBundle, domain string, now time.Time) (Certificate, error) {
+	pair, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return Certificate{}, fmt.Errorf("%s: %w", certFile, err)
+	}
+	leaf, err := x509.ParseCertificate(pair.Certificate[0])
+	if err != nil {
+		return Certificate{}, fmt.Errorf("%s: %w", certFile, err)
+	}
+	cert := Certificate{Resolver: "custom", Issuer: leaf.Issuer.CommonName, NotAfter: leaf.NotAfter}
+	if len(leaf.DNSNames) > 0 {
+		cert.Domain, cert.SANs = leaf.DNSNames[0], leaf.DNSNames[1:]
+	} else {
+		cert.Domain = leaf.Subject.CommonName
+	}
+
+	opts := x509.VerifyOptions{Intermediates: x509.NewCertPool(), CurrentTime: now}
+	for _, der := range pair.Certificate[1:] {
+		if c, err := x509.ParseCertificate(der); err == nil {
+			opts.Intermediates.AddCert(c)
+		}
+	}
+	if caBundle != "" {
+		if opts.Roots, err = loadBundle(caBundle, opts.Intermediates); err != nil {
+			return cert, err
+		}
+	}
+	if _, err := leaf.Verify(opts); err != nil {
+		return cert, fmt.Errorf("%s does not validate: %w", certFile, err)
+	}
+
+	for _, name := range cert.Names() {
+		if name == domain || strings.HasSuffix(name, "."+domain) {
+			return cert, nil
+		}
+	}
+	return cert, fmt.Errorf("%s is for %s, not %s or its subdomains", certFile, strings.Join(cert.Names(), ", "), domain)
+}
+
+// Roots returns the root certificates of a CA bundle
+func Roots(caBundle string) (*x509.CertPool, error) {
+	return loadBundle(caBundle, x509.NewCertPool())
+}
+
+// loadBundle reads the roots of a CA bundle. Intermediates bundled with
+// them are added to intermediates.
+func loadBundle(caBundle string, intermediates *x509.CertPool) (*x509.CertPool, error) {
+	data, err := os.ReadFile(caBundle)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the CA bundle: %w", err)
+	}
+	roots := x509.NewCertPool()
+	var found bool
+	for block, rest := pem.Decode(data); block != nil; block, rest = pem.Decode(rest) {
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		c, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", caBundle, err)
+		}
+		if c.IsCA && c.CheckSignatureFrom(c) == nil {
+			roots.AddCert(c)
+			found = true
+		} else {
+			intermediates.AddCert(c)
+		}
+	}
+	if !found {
+		return nil, errors.New(caBundle + " holds no root certificate")
+	}
+	return roots, nil
+}
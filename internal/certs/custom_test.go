@@ -0,0 +1,128 @@
+package certs
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testCA is a certificate authority issuing test certificates
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+func newTestCA(t *testing.T, name string, parent *testCA) *testCA {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(365 * 24 * time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	signer, signerKey := template, key
+	if parent != nil {
+		signer, signerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testCA{cert: cert, key: key}
+}
+
+// issue writes a certificate for names followed by the CA, and its key
+func (ca *testCA) issue(t *testing.T, dir string, names ...string) (certFile, keyFile string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: names[0]},
+		DNSNames:     names,
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(24 * time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	name := strings.ReplaceAll(names[0], "*", "wildcard")
+	certFile, keyFile = filepath.Join(dir, name+".crt"), filepath.Join(dir, name+".key")
+	chain := append(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), ca.pem()...)
+	if err := os.WriteFile(certFile, chain, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return certFile, keyFile
+}
+
+func (ca *testCA) pem() []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ca.cert.Raw})
+}
+
+func TestVerifyFile(t *testing.T) {
+	dir := t.TempDir()
+	root := newTestCA(t, "Homelab Root CA", nil)
+	intermediate := newTestCA(t, "Homelab Intermediate CA", root)
+	bundle := filepath.Join(dir, "root_ca.crt")
+	if err := os.WriteFile(bundle, root.pem(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	certFile, keyFile := intermediate.issue(t, dir, "*.home.internal", "home.internal")
+
+	cert, err := VerifyFile(certFile, keyFile, bundle, "home.internal", time.Now())
+	if err != nil {
+		t.Fatalf("VerifyFile() error = %v", err)
+	}
+	if cert.Domain != "*.home.internal" || cert.Issuer != "Homelab Intermediate CA" {
+		t.Errorf("VerifyFile() = %+v", cert)
+	}
+
+	if _, err := VerifyFile(certFile, keyFile, bundle, "example.com", time.Now()); err == nil || !strings.Contains(err.Error(), "not example.com") {
+		t.Errorf("VerifyFile() for another domain = %v", err)
+	}
+	if _, err := VerifyFile(certFile, keyFile, "", "home.internal", time.Now()); err == nil || !strings.Contains(err.Error(), "does not validate") {
+		t.Errorf("VerifyFile() against the system roots = %v, want an unknown authority", err)
+	}
+	if _, err := VerifyFile(certFile, keyFile, bundle, "home.internal", time.Now().Add(48*time.Hour)); err == nil {
+		t.Error("VerifyFile() accepted an expired certificate")
+	}
+
+	otherCert, otherKey := root.issue(t, dir, "other.home.internal")
+	if _, err := VerifyFile(certFile, otherKey, bundle, "home.internal", time.Now()); err == nil {
+		t.Error("VerifyFile() accepted a key of another certificate")
+	}
+	if _, err := VerifyFile(otherCert, otherKey, bundle, "home.internal", time.Now()); err != nil {
+		t.Errorf("VerifyFile() of a certificate issued by the root = %v", err)
+	}
+}
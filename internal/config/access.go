@@ -0,0 +1,77 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+)
+
+// BuiltinAutheliaGroups are the groups of the users sdbx creates; their
+// members reach every protected service
+var BuiltinAutheliaGroups = []string{"admins", "users"}
+
+// AccessProfileConfig restricts the members of an Authelia group to a subset
+// of the services (e.g. friends reaching only Overseerr and Plex)
+type AccessProfileConfig struct {
+	Name     string   `mapstructure:"name" yaml:"name"`               // Authelia group of the members
+	Services []string `mapstructure:"services" yaml:"services"`       // Services the members can reach
+	Policy   string   `mapstructure:"policy" yaml:"policy,omitempty"` // one_factor or two_factor (default: authelia.policy)
+}
+
+// AccessProfile returns the access profile of an Authelia group
+func (c *Config) AccessProfile(group string) (AccessProfileConfig, bool) {
+	for _, p := range c.AccessProfiles {
+		if p.Name == group {
+			return p, true
+		}
+	}
+	return AccessProfileConfig{}, false
+}
+
+// AccessGroups returns the Authelia groups users can be put in: the built-in
+// ones, then the access profiles
+func (c *Config) AccessGroups() []string {
+	groups := slices.Clone(BuiltinAutheliaGroups)
+	for _, p := range c.AccessProfiles {
+		groups = append(groups, p.Name)
+	}
+	return groups
+}
+
+// accessGroupRegex matches group names Authelia subjects accept unquoted
+var accessGroupRegex = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// validateAccessProfiles checks the access_profiles section
+func validateAccessProfiles(profiles []AccessProfileConfig, basicAuth bool) error {
+	if len(profiles) > 0 && basicAuth {
+		return NewValidationError("access_profiles", "basic auth has no groups - use auth.mode: authelia")
+	}
+	seen := make(map[string]bool)
+	for i, p := range profiles {
+		field := fmt.Sprintf("access_profiles[%d]", i)
+		if !accessGroupRegex.MatchString(p.Name) {
+			return NewValidationError(field+".name",
+				fmt.Sprintf("invalid group name %q - use lowercase letters, digits, dashes and underscores", p.Name))
+		}
+		if slices.Contains(BuiltinAutheliaGroups, p.Name) {
+			return NewValidationError(field+".name", fmt.Sprintf("%q is a built-in group with access to every service", p.Name))
+		}
+		if seen[p.Name] {
+			return NewValidationError(field+".name", fmt.Sprintf("duplicate access profile %q", p.Name))
+		}
+		seen[p.Name] = true
+		if len(p.Services) == 0 {
+			return NewValidationError(field+".services", "at least one service is required")
+		}
+		for j, name := range p.Services {
+			if name == "" {
+				return NewValidationError(fmt.Sprintf("%s.services[%d]", field, j), "service name is required")
+			}
+		}
+		if p.Policy != "" && p.Policy != AutheliaPolicyOneFactor && p.Policy != AutheliaPolicyTwoFactor {
+			return NewValidationError(field+".policy",
+				fmt.Sprintf("must be one of: %s, %s", AutheliaPolicyOneFactor, AutheliaPolicyTwoFactor))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+package config
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestValidateAccessProfiles(t *testing.T) {
+	friends := AccessProfileConfig{Name: "friends", Services: []string{"overseerr", "plex"}}
+	tests := []struct {
+		name      string
+		profiles  []AccessProfileConfig
+		basicAuth bool
+		wantErr   bool
+	}{
+		{"unset", nil, true, false},
+		{"valid", []AccessProfileConfig{friends, {Name: "family", Services: []string{"plex"}, Policy: AutheliaPolicyTwoFactor}}, false, false},
+		{"basic auth", []AccessProfileConfig{friends}, true, true},
+		{"bad name", []AccessProfileConfig{{Name: "Friends & Family", Services: []string{"plex"}}}, false, true},
+		{"builtin group", []AccessProfileConfig{{Name: "admins", Services: []string{"plex"}}}, false, true},
+		{"duplicate", []AccessProfileConfig{friends, friends}, false, true},
+		{"no services", []AccessProfileConfig{{Name: "friends"}}, false, true},
+		{"empty service", []AccessProfileConfig{{Name: "friends", Services: []string{""}}}, false, true},
+		{"bad policy", []AccessProfileConfig{{Name: "friends", Services: []string{"plex"}, Policy: "bypass"}}, false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateAccessProfiles(tt.profiles, tt.basicAuth); (err != nil) != tt.wantErr {
+				t.Errorf("validateAccessProfiles() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestAccessGroups(t *testing.T) {
+	c := &Config{AccessProfiles: []AccessProfileConfig{{Name: "friends", Services: []string{"plex"}}}}
+	if got := c.AccessGroups(); !slices.Equal(got, []string{"admins", "users", "friends"}) {
+		t.Errorf("AccessGroups() = %v", got)
+	}
+	if p, ok := c.AccessProfile("friends"); !ok || p.Services[0] != "plex" {
+		t.Errorf("AccessProfile(friends) = %+v, %v", p, ok)
+	}
+	if _, ok := c.AccessProfile("users"); ok {
+		t.Error("AccessProfile(users) found a profile for a built-in group")
+	}
+}
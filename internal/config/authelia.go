@@ -0,0 +1,130 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Authelia policies of the services behind the login portal
+const (
+	AutheliaPolicyOneFactor = "one_factor"
+	AutheliaPolicyTwoFactor = "two_factor"
+)
+
+// Authelia settings used when the authelia section leaves them unset
+const (
+	DefaultAutheliaSessionExpiration = "1h"
+	DefaultAutheliaSessionInactivity = "5m"
+	DefaultAutheliaRememberMe        = "1M"
+	DefaultAutheliaMaxRetries        = 3
+	DefaultAutheliaFindTime          = "2m"
+	DefaultAutheliaBanTime           = "5m"
+	DefaultAutheliaRedisPort         = 6379
+)
+
+// AutheliaConfig sets the generated Authelia configuration
+// (configs/authelia/configuration.yml). Authelia sends its notifications
+// through the smtp section, if set.
+type AutheliaConfig struct {
+	Policy     string                   `mapstructure:"policy" yaml:"policy,omitempty"` // Policy of protected services: one_factor (default) or two_factor
+	Session    AutheliaSessionConfig    `mapstructure:"session" yaml:"session,omitempty"`
+	Regulation AutheliaRegulationConfig `mapstructure:"regulation" yaml:"regulation,omitempty"`
+	Redis      *AutheliaRedisConfig     `mapstructure:"redis" yaml:"redis,omitempty"` // Session store; sessions are kept in memory without it
+}
+
+// AutheliaSessionConfig sets the session cookie
+type AutheliaSessionConfig struct {
+	Domain     string `mapstructure:"domain" yaml:"domain,omitempty"`           // Cookie domain: the project domain (default) or a parent of it
+	Expiration string `mapstructure:"expiration" yaml:"expiration,omitempty"`   // Default 1h
+	Inactivity string `mapstructure:"inactivity" yaml:"inactivity,omitempty"`   // Default 5m
+	RememberMe string `mapstructure:"remember_me" yaml:"remember_me,omitempty"` // Default 1M
+}
+
+// AutheliaRegulationConfig bans users after failed logins
+type AutheliaRegulationConfig struct {
+	MaxRetries int    `mapstructure:"max_retries" yaml:"max_retries,omitempty"` // Default 3
+	FindTime   string `mapstructure:"find_time" yaml:"find_time,omitempty"`     // Window of the retries (default 2m)
+	BanTime    string `mapstructure:"ban_time" yaml:"ban_time,omitempty"`       // Default 5m
+}
+
+// AutheliaRedisConfig stores sessions in Redis, so they survive restarts
+type AutheliaRedisConfig struct {
+	Host string `mapstructure:"host" yaml:"host"`
+	Port int    `mapstructure:"port" yaml:"port,omitempty"` // Default 6379
+}
+
+// AutheliaSettings returns the authelia section with its defaults applied
+func (c *Config) AutheliaSettings() AutheliaConfig {
+	a := c.Authelia
+	a.Policy = defaultString(a.Policy, AutheliaPolicyOneFactor)
+	a.Session.Domain = defaultString(a.Session.Domain, c.Domain)
+	a.Session.Expiration = defaultString(a.Session.Expiration, DefaultAutheliaSessionExpiration)
+	a.Session.Inactivity = defaultString(a.Session.Inactivity, DefaultAutheliaSessionInactivity)
+	a.Session.RememberMe = defaultString(a.Session.RememberMe, DefaultAutheliaRememberMe)
+	if a.Regulation.MaxRetries == 0 {
+		a.Regulation.MaxRetries = DefaultAutheliaMaxRetries
+	}
+	a.Regulation.FindTime = defaultString(a.Regulation.FindTime, DefaultAutheliaFindTime)
+	a.Regulation.BanTime = defaultString(a.Regulation.BanTime, DefaultAutheliaBanTime)
+	if a.Redis != nil {
+		redis := *a.Redis
+		if redis.Port == 0 {
+			redis.Port = DefaultAutheliaRedisPort
+		}
+		a.Redis = &redis
+	}
+	return a
+}
+
+// IsSet reports whether any Authelia setting differs from the defaults
+func (a AutheliaConfig) IsSet() bool {
+	return a != AutheliaConfig{}
+}
+
+func defaultString(s, def string) string {
+	if s == "" {
+		return def
+	}
+	return s
+}
+
+// autheliaDurationRegex matches Authelia durations: 90s, 5m, 1h30m, 1d, 1w, 1M, 1y
+var autheliaDurationRegex = regexp.MustCompile(`^(\d+(s|m|h|d|w|M|y))+$`)
+
+// validateAuthelia checks the authelia section against what Authelia accepts
+func validateAuthelia(a AutheliaConfig, domain string) error {
+	if a.Policy != "" && a.Policy != AutheliaPolicyOneFactor && a.Policy != AutheliaPolicyTwoFactor {
+		return NewValidationError("authelia.policy",
+			fmt.Sprintf("must be one of: %s, %s", AutheliaPolicyOneFactor, AutheliaPolicyTwoFactor))
+	}
+	// The session cookie must be sent to every service and the portal
+	if d := a.Session.Domain; d != "" && d != domain && !strings.HasSuffix(domain, "."+d) {
+		return NewValidationError("authelia.session.domain",
+			fmt.Sprintf("must be %s or a parent domain of it", domain))
+	}
+	for _, duration := range []struct{ field, value string }{
+		{"authelia.session.expiration", a.Session.Expiration},
+		{"authelia.session.inactivity", a.Session.Inactivity},
+		{"authelia.session.remember_me", a.Session.RememberMe},
+		{"authelia.regulation.find_time", a.Regulation.FindTime},
+		{"authelia.regulation.ban_time", a.Regulation.BanTime},
+	} {
+		if duration.value != "" && !autheliaDurationRegex.MatchString(duration.value) {
+			return NewValidationError(duration.field, fmt.Sprintf("invalid duration %q (e.g. 90s, 5m, 1h, 1d, 1w, 1M or 1y)", duration.value))
+		}
+	}
+	if a.Regulation.MaxRetries < 0 {
+		return NewValidationError("authelia.regulation.max_retries", "must not be negative")
+	}
+
+	if redis := a.Redis; redis != nil {
+		if redis.Host == "" {
+			return NewValidationError("authelia.redis.host", "is required")
+		}
+		if redis.Port < 0 || redis.Port > 65535 {
+			return NewValidationError("authelia.redis.port", "must be between 1 and 65535")
+		}
+	}
+	return nil
+}
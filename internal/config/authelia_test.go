@@ -0,0 +1,55 @@
+package config
+
+import "testing"
+
+func TestAutheliaSettingsDefaults(t *testing.T) {
+	cfg := DefaultConfig()
+	a := cfg.AutheliaSettings()
+	if a.Policy != AutheliaPolicyOneFactor || a.Session.Domain != cfg.Domain || a.Session.Expiration != DefaultAutheliaSessionExpiration {
+		t.Errorf("AutheliaSettings() = %+v, want the defaults", a)
+	}
+	if a.Regulation.MaxRetries != DefaultAutheliaMaxRetries || a.Redis != nil {
+		t.Errorf("AutheliaSettings() = %+v, want the default regulation and no redis", a)
+	}
+	if cfg.Authelia.IsSet() {
+		t.Error("IsSet() = true for the default configuration")
+	}
+
+	cfg.Authelia = AutheliaConfig{
+		Policy: AutheliaPolicyTwoFactor,
+		Redis:  &AutheliaRedisConfig{Host: "redis"},
+	}
+	a = cfg.AutheliaSettings()
+	if a.Policy != AutheliaPolicyTwoFactor || a.Redis.Port != DefaultAutheliaRedisPort {
+		t.Errorf("AutheliaSettings() = %+v, want two_factor with the default Redis port", a)
+	}
+	if cfg.Authelia.Redis.Port != 0 {
+		t.Error("AutheliaSettings() modified the configuration")
+	}
+}
+
+func TestValidateAuthelia(t *testing.T) {
+	tests := []struct {
+		name     string
+		authelia AutheliaConfig
+		wantErr  bool
+	}{
+		{"unset", AutheliaConfig{}, false},
+		{"two factor", AutheliaConfig{Policy: "two_factor"}, false},
+		{"bad policy", AutheliaConfig{Policy: "bypass"}, true},
+		{"parent session domain", AutheliaConfig{Session: AutheliaSessionConfig{Domain: "example.com"}}, false},
+		{"unrelated session domain", AutheliaConfig{Session: AutheliaSessionConfig{Domain: "other.com"}}, true},
+		{"durations", AutheliaConfig{Session: AutheliaSessionConfig{Expiration: "1h30m", RememberMe: "1y"}}, false},
+		{"bad duration", AutheliaConfig{Regulation: AutheliaRegulationConfig{BanTime: "forever"}}, true},
+		{"negative retries", AutheliaConfig{Regulation: AutheliaRegulationConfig{MaxRetries: -1}}, true},
+		{"redis without host", AutheliaConfig{Redis: &AutheliaRedisConfig{Port: 6379}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateAuthelia(tt.authelia, "sdbx.example.com")
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateAuthelia() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
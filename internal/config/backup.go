@@ -0,0 +1,107 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Backup remote types
+const (
+	BackupRemoteRclone    = "rclone"    // rclone remote:path, using the rclone.conf of storage mounts
+	BackupRemoteDirectory = "directory" // Host directory, such as a NAS mount
+)
+
+// MinBackupSchedule is the shortest time allowed between automatic backups
+const MinBackupSchedule = time.Hour
+
+// backupRemoteNameRegex matches a remote name usable in URLs
+var backupRemoteNameRegex = regexp.MustCompile(`^[a-z0-9][a-z0-9-]*$`)
+
+// BackupConfig takes backups automatically and copies them off the host
+type BackupConfig struct {
+	Schedule string         `mapstructure:"schedule" yaml:"schedule,omitempty"` // Time between automatic backups (e.g. 24h), unset disables them
+	Keep     int            `mapstructure:"keep" yaml:"keep,omitempty"`         // Local backups kept, oldest deleted first (0 keeps all)
+	Remotes  []BackupRemote `mapstructure:"remotes" yaml:"remotes,omitempty"`   // Where scheduled backups are copied
+}
+
+// BackupRemote is a destination backups are copied to
+type BackupRemote struct {
+	Name   string `mapstructure:"name" yaml:"name"`               // Identifies the remote (e.g. nas)
+	Type   string `mapstructure:"type" yaml:"type"`               // rclone | directory
+	Target string `mapstructure:"target" yaml:"target"`           // remote:path for rclone, absolute path for directory
+	Config string `mapstructure:"config" yaml:"config,omitempty"` // rclone.conf path (default ./configs/rclone/rclone.conf)
+}
+
+// IsEnabled reports whether any backup setting is configured
+func (b BackupConfig) IsEnabled() bool {
+	return b.Schedule != "" || b.Keep > 0 || len(b.Remotes) > 0
+}
+
+// Interval returns the parsed Schedule, or 0 when backups are not scheduled
+func (b BackupConfig) Interval() time.Duration {
+	if i, err := time.ParseDuration(b.Schedule); err == nil && i > 0 {
+		return i
+	}
+	return 0
+}
+
+// Remote returns the remote with the given name
+func (b BackupConfig) Remote(name string) (BackupRemote, bool) {
+	for _, r := range b.Remotes {
+		if r.Name == name {
+			return r, true
+		}
+	}
+	return BackupRemote{}, false
+}
+
+// ConfigFile returns the rclone.conf path, defaulting to DefaultRcloneConfig
+func (r BackupRemote) ConfigFile() string {
+	if r.Config == "" {
+		return DefaultRcloneConfig
+	}
+	return r.Config
+}
+
+// validateBackup checks the schedule, retention and remotes
+func validateBackup(b BackupConfig) error {
+	if b.Schedule != "" {
+		if i, err := time.ParseDuration(b.Schedule); err != nil || i < MinBackupSchedule {
+			return NewValidationError("backup.schedule", fmt.Sprintf("invalid duration %q (at least 1h, e.g. 24h)", b.Schedule))
+		}
+	}
+	if b.Keep < 0 {
+		return NewValidationError("backup.keep", "must not be negative")
+	}
+
+	seen := make(map[string]bool)
+	for i, r := range b.Remotes {
+		field := fmt.Sprintf("backup.remotes[%d]", i)
+		if !backupRemoteNameRegex.MatchString(r.Name) {
+			return NewValidationError(field+".name", fmt.Sprintf("invalid name %q (lowercase letters, digits and dashes)", r.Name))
+		}
+		if seen[r.Name] {
+			return NewValidationError(field+".name", fmt.Sprintf("duplicate remote %q", r.Name))
+		}
+		seen[r.Name] = true
+
+		switch r.Type {
+		case BackupRemoteRclone:
+			if remote, _, ok := strings.Cut(r.Target, ":"); !ok || remote == "" {
+				return NewValidationError(field+".target", fmt.Sprintf("invalid rclone remote %q (e.g. b2:sdbx-backups)", r.Target))
+			}
+		case BackupRemoteDirectory:
+			if !strings.HasPrefix(r.Target, "/") {
+				return NewValidationError(field+".target", "must be an absolute host path")
+			}
+			if r.Config != "" {
+				return NewValidationError(field+".config", "only applies to rclone remotes")
+			}
+		default:
+			return NewValidationError(field+".type", fmt.Sprintf("must be %q or %q", BackupRemoteRclone, BackupRemoteDirectory))
+		}
+	}
+	return nil
+}
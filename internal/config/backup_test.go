@@ -0,0 +1,46 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+func TestValidateBackup(t *testing.T) {
+	nas := BackupRemote{Name: "nas", Type: BackupRemoteDirectory, Target: "/mnt/nas/sdbx"}
+	b2 := BackupRemote{Name: "b2", Type: BackupRemoteRclone, Target: "b2:sdbx-backups"}
+	tests := []struct {
+		name    string
+		backup  BackupConfig
+		wantErr bool
+	}{
+		{"disabled", BackupConfig{}, false},
+		{"daily", BackupConfig{Schedule: "24h", Keep: 7}, false},
+		{"remotes", BackupConfig{Schedule: "24h", Remotes: []BackupRemote{nas, b2}}, false},
+		{"bad schedule", BackupConfig{Schedule: "daily"}, true},
+		{"short schedule", BackupConfig{Schedule: "10m"}, true},
+		{"negative keep", BackupConfig{Keep: -1}, true},
+		{"bad name", BackupConfig{Remotes: []BackupRemote{{Name: "My NAS", Type: BackupRemoteDirectory, Target: "/mnt/nas"}}}, true},
+		{"duplicate name", BackupConfig{Remotes: []BackupRemote{nas, nas}}, true},
+		{"bad type", BackupConfig{Remotes: []BackupRemote{{Name: "s3", Type: "s3", Target: "bucket"}}}, true},
+		{"bad rclone target", BackupConfig{Remotes: []BackupRemote{{Name: "b2", Type: BackupRemoteRclone, Target: "sdbx-backups"}}}, true},
+		{"relative directory", BackupConfig{Remotes: []BackupRemote{{Name: "nas", Type: BackupRemoteDirectory, Target: "backups"}}}, true},
+		{"directory with rclone config", BackupConfig{Remotes: []BackupRemote{{Name: "nas", Type: BackupRemoteDirectory, Target: "/mnt/nas", Config: "rclone.conf"}}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateBackup(tt.backup)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateBackup() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestBackupInterval(t *testing.T) {
+	if got := (BackupConfig{Schedule: "12h"}).Interval(); got != 12*time.Hour {
+		t.Errorf("Interval() = %v, want 12h", got)
+	}
+	if got := (BackupConfig{}).Interval(); got != 0 {
+		t.Errorf("Interval() = %v, want 0 when unscheduled", got)
+	}
+}
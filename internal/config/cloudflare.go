@@ -0,0 +1,53 @@
+package config
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"regexp"
+	"strings"
+)
+
+var (
+	// accountTagRegex matches a Cloudflare account ID
+	accountTagRegex = regexp.MustCompile(`^[0-9a-f]{32}$`)
+	// tunnelIDRegex matches a tunnel UUID
+	tunnelIDRegex = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$`)
+	// apiTokenRegex matches a Cloudflare API token, often pasted by mistake
+	apiTokenRegex = regexp.MustCompile(`^[A-Za-z0-9_-]{40}$`)
+)
+
+// TunnelToken is the content of a Cloudflare Tunnel token
+type TunnelToken struct {
+	AccountTag string `json:"a"`
+	TunnelID   string `json:"t"`
+	Secret     string `json:"s"`
+}
+
+// ParseTunnelToken decodes a Cloudflare Tunnel token (base64 JSON holding
+// the account, the tunnel ID and the tunnel secret) and checks its fields
+func ParseTunnelToken(token string) (*TunnelToken, error) {
+	token = strings.TrimSpace(token)
+	data, err := base64.StdEncoding.DecodeString(token)
+	if err != nil {
+		data, err = base64.RawStdEncoding.DecodeString(token)
+	}
+	var t TunnelToken
+	if err != nil || json.Unmarshal(data, &t) != nil {
+		if apiTokenRegex.MatchString(token) {
+			return nil, errors.New("this is a Cloudflare API token, not a tunnel token: copy the token shown when installing the tunnel connector (Networks → Tunnels)")
+		}
+		return nil, errors.New("not a tunnel token: copy the whole token shown when installing the tunnel connector (Networks → Tunnels)")
+	}
+
+	if !accountTagRegex.MatchString(t.AccountTag) {
+		return nil, errors.New("tunnel token has no valid account ID")
+	}
+	if !tunnelIDRegex.MatchString(t.TunnelID) {
+		return nil, errors.New("tunnel token has no valid tunnel ID")
+	}
+	if secret, err := base64.StdEncoding.DecodeString(t.Secret); err != nil || len(secret) < 32 {
+		return nil, errors.New("tunnel token has no valid tunnel secret")
+	}
+	return &t, nil
+}
@@ -0,0 +1,45 @@
+package config
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+)
+
+func TestParseTunnelToken(t *testing.T) {
+	secret := base64.StdEncoding.EncodeToString(make([]byte, 32))
+	encode := func(json string) string { return base64.StdEncoding.EncodeToString([]byte(json)) }
+	valid := `{"a":"0123456789abcdef0123456789abcdef","t":"01234567-89ab-cdef-0123-456789abcdef","s":"` + secret + `"}`
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr string
+	}{
+		{"valid", encode(valid), ""},
+		{"valid with whitespace", "  " + encode(valid) + "\n", ""},
+		{"valid unpadded", strings.TrimRight(encode(valid), "="), ""},
+		{"api token", strings.Repeat("aB3_", 10), "API token"},
+		{"garbage", "not-a-token", "not a tunnel token"},
+		{"bad account", encode(`{"a":"acct","t":"01234567-89ab-cdef-0123-456789abcdef","s":"` + secret + `"}`), "account ID"},
+		{"bad tunnel", encode(`{"a":"0123456789abcdef0123456789abcdef","t":"tunnel","s":"` + secret + `"}`), "tunnel ID"},
+		{"short secret", encode(`{"a":"0123456789abcdef0123456789abcdef","t":"01234567-89ab-cdef-0123-456789abcdef","s":"c2hvcnQ="}`), "tunnel secret"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseTunnelToken(tt.token)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("ParseTunnelToken() error = %v", err)
+				}
+				if got.TunnelID != "01234567-89ab-cdef-0123-456789abcdef" {
+					t.Errorf("TunnelID = %q", got.TunnelID)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ParseTunnelToken() error = %v, want containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -2,15 +2,24 @@
 package config
 
 import (
+	"bytes"
 	"fmt"
+	"maps"
+	"net"
+	"net/mail"
+	"net/url"
 	"os"
 	"path/filepath"
 	"regexp"
+	"runtime"
 	"slices"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/spf13/viper"
+	"gopkg.in/yaml.v3"
 )
 
 const (
@@ -22,6 +31,21 @@ const (
 	// Routing strategies
 	RoutingStrategyPath      = "path"
 	RoutingStrategySubdomain = "subdomain"
+
+	// Lock file integrity policies
+	LockIntegrityWarn = "warn"
+	LockIntegrityFail = "fail"
+	LockIntegrityOff  = "off"
+
+	// Secret delivery strategies for secretRef environment variables
+	SecretDeliveryAuto    = "auto"     // file when the image supports it, otherwise env_file
+	SecretDeliveryFile    = "file"     // Docker secret under /run/secrets, passed as a *_FILE variable
+	SecretDeliveryEnvFile = "env_file" // Written to secrets/<service>.env and loaded with env_file
+	SecretDeliveryEnv     = "env"      // Value written into compose.yaml (least secure)
+
+	// Authentication modes in front of protected services
+	AuthModeAuthelia = "authelia" // SSO portal with 2FA (default)
+	AuthModeBasic    = "basic"    // Traefik basic auth from an htpasswd secret, no Authelia
 )
 
 // Config holds the sdbx configuration
@@ -62,15 +86,114 @@ type Config struct {
 	// Addons
 	Addons []string `mapstructure:"addons"`
 
+	// Extra named copies of a service (e.g. sonarr4k: sonarr), each with its
+	// own container, config directory and subdomain
+	Instances map[string]string `mapstructure:"instances"`
+
 	// Media server selection
 	JellyfinEnabled bool `mapstructure:"jellyfin_enabled"`
 
 	// Plex configuration
 	PlexAdvertiseURLs string `mapstructure:"plex_advertise_urls"`
 
+	// Pass /dev/dri to media servers for Intel/AMD hardware transcoding
+	HardwareTranscode bool `mapstructure:"hardware_transcode"`
+
+	// Target host platform (e.g. linux/arm64); empty means the detected host platform
+	Platform string `mapstructure:"platform"`
+
+	// Docker runtime: engine, docker-desktop or orbstack; empty means the detected runtime
+	Runtime string `mapstructure:"runtime"`
+
+	// What sdbx up does when .sdbx.lock fails its checksum: warn, fail or off
+	LockIntegrity string `mapstructure:"lock_integrity"`
+
+	// How secretRef environment variables reach containers: auto, file, env_file or env
+	SecretDelivery string `mapstructure:"secret_delivery"`
+
+	// Authentication in front of protected services
+	Auth AuthConfig `mapstructure:"auth"`
+
+	// Session, notifier and regulation settings of the generated Authelia configuration
+	Authelia AutheliaConfig `mapstructure:"authelia"`
+
+	// Authelia groups whose members only reach some services
+	AccessProfiles []AccessProfileConfig `mapstructure:"access_profiles"`
+
 	// Per-service overrides
 	Services map[string]ServiceOverride `mapstructure:"services"`
 
+	// Traefik access logging and IP allowlisting
+	Traefik TraefikConfig `mapstructure:"traefik"`
+
+	// Container logging driver and rotation (default for every service)
+	Logging LoggingConfig `mapstructure:"logging"`
+
+	// Static sites and custom error pages served alongside services
+	Extras ExtrasConfig `mapstructure:"extras"`
+
+	// StatusPage publishes service up/down at status.<domain> without login
+	StatusPage StatusPageConfig `mapstructure:"status_page"`
+
+	// One-off containers declared inline instead of in a registry source
+	ExtraServices []ExtraServiceConfig `mapstructure:"extra_services"`
+
+	// Where the external endpoints declared by service definitions live
+	// (e.g. an existing NAS share or database), keyed by dependency name
+	ExternalDependencies map[string]ExternalEndpoint `mapstructure:"external_dependencies"`
+
+	// Named volumes shared between services (e.g. an NFS or SMB share)
+	Volumes map[string]VolumeDefinition `mapstructure:"volumes"`
+
+	// Cloud storage mounted with rclone and pooled with local disks by mergerfs
+	Storage StorageConfig `mapstructure:"storage"`
+
+	// Download clients sdbx talks to, and the seeding rules enforced on them
+	DownloadClients DownloadClientsConfig `mapstructure:"download_clients"`
+	Seeding         SeedingConfig         `mapstructure:"seeding"`
+
+	// Pauses downloads when the downloads path runs out of space
+	DiskGuard DiskGuardConfig `mapstructure:"disk_guard"`
+
+	// Scheduled backups and where they are copied
+	Backup BackupConfig `mapstructure:"backup"`
+
+	// Alert rules evaluated by the monitor and the channels they are sent to
+	Alerts        AlertsConfig        `mapstructure:"alerts"`
+	Notifications NotificationsConfig `mapstructure:"notifications"`
+
+	// Mail server of the Authelia notifier, the *arr email notifications and
+	// the email notification channels
+	SMTP SMTPConfig `mapstructure:"smtp"`
+
+	// MaxMind GeoLite databases for the services that require them
+	GeoIP GeoIPConfig `mapstructure:"geoip"`
+
+	// Proxy of the outbound connections of sdbx
+	Proxy ProxyConfig `mapstructure:"proxy"`
+
+	// Air-gapped host: sources are only read from their cache, images are
+	// never pulled and background downloads (GeoIP) are skipped
+	Offline bool `mapstructure:"offline"`
+
+	// How long sdbx waits on Docker commands and service health
+	Timeouts TimeoutsConfig `mapstructure:"timeouts"`
+
+	// Range of host ports allocated to services publishing a taken port
+	HostPorts HostPortsConfig `mapstructure:"host_ports"`
+
+	// Subnets of the compose networks, by network name (proxy, backend...)
+	Networks map[string]NetworkConfig `mapstructure:"networks"`
+
+	// Local DNS server resolving the domain to the host
+	DNS DNSConfig `mapstructure:"dns"`
+
+	// Services advertised as <subdomain>.local in LAN mode
+	MDNS MDNSConfig `mapstructure:"mdns"`
+
+	// Rotation of the logs of the web UI server and its jobs
+	ServerLogs ServerLogsConfig `mapstructure:"server_logs"`
+
 	// Security (Transient, not saved to config)
 	AdminUser         string `mapstructure:"-"`
 	AdminPasswordHash string `mapstructure:"-"`
@@ -80,6 +203,12 @@ type Config struct {
 
 	// Legacy field for backward compatibility (deprecated)
 	ExposeMode string `mapstructure:"expose_mode"`
+
+	// Schema version of .sdbx.yaml (see SchemaVersion)
+	SchemaVersion int `mapstructure:"schema_version"`
+
+	// Migrations applied in memory by Load (Transient, not saved to config)
+	AppliedMigrations []string `mapstructure:"-" yaml:"-"`
 }
 
 // ExposeConfig defines how services are exposed to the network
@@ -90,12 +219,19 @@ type ExposeConfig struct {
 
 // TLSConfig defines TLS/SSL settings for direct mode
 type TLSConfig struct {
-	Provider      string `mapstructure:"provider"`       // "acme" | "custom" | "selfsigned" | "none"
-	Email         string `mapstructure:"email"`          // For ACME (Let's Encrypt)
-	CertFile      string `mapstructure:"cert_file"`      // For custom certificates
-	KeyFile       string `mapstructure:"key_file"`       // For custom certificates
-	ChallengeType string `mapstructure:"challenge_type"` // "http" | "dns" (default: "http")
-	DNSProvider   string `mapstructure:"dns_provider"`   // For DNS challenge (e.g., "cloudflare")
+	Provider      string           `mapstructure:"provider"`       // "acme" | "custom" | "selfsigned" | "none"
+	Email         string           `mapstructure:"email"`          // For ACME (Let's Encrypt)
+	CertFile      string           `mapstructure:"cert_file"`      // For custom certificates: default certificate (e.g. a wildcard)
+	KeyFile       string           `mapstructure:"key_file"`       // For custom certificates
+	Certificates  []TLSCertificate `mapstructure:"certificates"`   // For custom certificates: more, picked by SNI
+	CABundle      string           `mapstructure:"ca_bundle"`      // For custom certificates: PEM roots of the internal CA, checked by sdbx doctor
+	ChallengeType string           `mapstructure:"challenge_type"` // "http" | "dns" (default: "http")
+	DNSProvider   string           `mapstructure:"dns_provider"`   // For DNS challenge (e.g., "cloudflare")
+}
+
+// AuthConfig selects how protected services authenticate users
+type AuthConfig struct {
+	Mode string `mapstructure:"mode" yaml:"mode,omitempty"` // "authelia" (default) | "basic"
 }
 
 // RoutingConfig defines how services are routed (subdomain vs path)
@@ -106,9 +242,260 @@ type RoutingConfig struct {
 
 // ServiceOverride allows per-service routing customization
 type ServiceOverride struct {
-	Routing   string `mapstructure:"routing"`   // "subdomain" | "path" - override global strategy
-	Subdomain string `mapstructure:"subdomain"` // Custom subdomain (e.g., "requests" for overseerr)
-	Path      string `mapstructure:"path"`      // Custom path (e.g., "/movies" for radarr)
+	Routing     string   `mapstructure:"routing" yaml:"routing,omitempty"`           // "subdomain" | "path" - override global strategy
+	Subdomain   string   `mapstructure:"subdomain" yaml:"subdomain,omitempty"`       // Custom subdomain (e.g., "requests" for overseerr)
+	Path        string   `mapstructure:"path" yaml:"path,omitempty"`                 // Custom path (e.g., "/movies" for radarr)
+	IPAllowList []string `mapstructure:"ip_allowlist" yaml:"ip_allowlist,omitempty"` // Source IPs/CIDRs allowed to reach this service
+	Maintenance bool     `mapstructure:"maintenance" yaml:"maintenance,omitempty"`   // Serve a maintenance page instead of the service
+
+	// CountryAllowList replaces traefik.country_allowlist for this service
+	CountryAllowList []string `mapstructure:"country_allowlist" yaml:"country_allowlist,omitempty"`
+
+	// UpdatePolicy controls Watchtower and `sdbx update`: auto, notify-only or pinned
+	UpdatePolicy string `mapstructure:"update_policy" yaml:"update_policy,omitempty"`
+
+	// Pin reads the service definition from a commit of its Git source, or
+	// the commit defining a metadata.version, instead of the source's HEAD
+	Pin string `mapstructure:"pin" yaml:"pin,omitempty"`
+
+	// Logging replaces the global logging settings for this service
+	Logging *LoggingConfig `mapstructure:"logging" yaml:"logging,omitempty"`
+
+	// ComposeExtra is merged into the generated compose service for fields
+	// SDBX does not model (ulimits, logging, extra_hosts, ...)
+	ComposeExtra map[string]interface{} `mapstructure:"compose_extra" yaml:"compose_extra,omitempty"`
+
+	// Platform forces the image platform (e.g. linux/amd64 under emulation)
+	Platform string `mapstructure:"platform" yaml:"platform,omitempty"`
+
+	// Resources caps the CPU and memory the container may use
+	Resources *ResourceLimits `mapstructure:"resources" yaml:"resources,omitempty"`
+
+	// Transcode selects the hardware transcoding of a media server: vaapi,
+	// nvidia or none (default: vaapi when hardware_transcode is on)
+	Transcode string `mapstructure:"transcode" yaml:"transcode,omitempty"`
+
+	// SecretDelivery replaces the global secret_delivery strategy for this service
+	SecretDelivery string `mapstructure:"secret_delivery" yaml:"secret_delivery,omitempty"`
+
+	// Variables sets the instancing variables the service definition declares,
+	// usually per named instance (e.g. quality: 2160p for sonarr4k)
+	Variables map[string]string `mapstructure:"variables" yaml:"variables,omitempty"`
+}
+
+// ResourceLimits defines container CPU and memory limits
+type ResourceLimits struct {
+	CPUs   string `mapstructure:"cpus" yaml:"cpus,omitempty"`     // Fractional CPUs (e.g. "1.5")
+	Memory string `mapstructure:"memory" yaml:"memory,omitempty"` // Memory limit (e.g. "512m", "2g")
+}
+
+// isEmpty reports whether the override changes nothing
+func (o ServiceOverride) isEmpty() bool {
+	return o.Routing == "" && o.Subdomain == "" && o.Path == "" && len(o.IPAllowList) == 0 && len(o.CountryAllowList) == 0 &&
+		!o.Maintenance && o.UpdatePolicy == "" && o.Pin == "" && o.Logging == nil && len(o.ComposeExtra) == 0 &&
+		o.Platform == "" && o.Resources == nil && o.Transcode == "" && o.SecretDelivery == "" && len(o.Variables) == 0
+}
+
+// Update policies for services
+const (
+	UpdatePolicyAuto       = "auto"        // Watchtower and `sdbx update` pull new images
+	UpdatePolicyNotifyOnly = "notify-only" // Watchtower only reports new images; `sdbx update` skips the service
+	UpdatePolicyPinned     = "pinned"      // Never updated automatically
+)
+
+// TraefikConfig defines reverse proxy settings not tied to a single service
+type TraefikConfig struct {
+	AccessLog   AccessLogConfig `mapstructure:"access_log" yaml:"access_log"`
+	IPAllowList []string        `mapstructure:"ip_allowlist" yaml:"ip_allowlist,omitempty"` // Source IPs/CIDRs allowed to reach all routed services
+
+	// CountryAllowList holds the countries (ISO 3166-1 codes such as FR)
+	// allowed to reach all routed services, looked up in the GeoIP databases
+	CountryAllowList []string `mapstructure:"country_allowlist" yaml:"country_allowlist,omitempty"`
+
+	// MiddlewareDefinitions are shared middlewares referenced by name from
+	// routing.traefik.middlewares; they replace a definition's middleware of the same name
+	MiddlewareDefinitions map[string]MiddlewareDefinition `mapstructure:"middleware_definitions" yaml:"middleware_definitions,omitempty"`
+}
+
+// AccessLogConfig defines Traefik access log settings
+type AccessLogConfig struct {
+	Enabled  bool   `mapstructure:"enabled" yaml:"enabled"`
+	Path     string `mapstructure:"path" yaml:"path"`           // Host directory for access.log (mounted into Traefik)
+	MaxSize  int    `mapstructure:"max_size" yaml:"max_size"`   // Rotate once the log exceeds this many MB
+	MaxFiles int    `mapstructure:"max_files" yaml:"max_files"` // Number of rotated files to keep
+}
+
+// Logging drivers supported in .sdbx.yaml
+const (
+	LogDriverJSONFile = "json-file"
+	LogDriverLocal    = "local"
+	LogDriverLoki     = "loki"
+	LogDriverSyslog   = "syslog"
+	LogDriverJournald = "journald"
+	LogDriverNone     = "none"
+)
+
+// LoggingConfig defines the Docker logging driver for containers.
+// Docker's default json-file driver never rotates, which regularly fills seedbox disks.
+type LoggingConfig struct {
+	Driver  string            `mapstructure:"driver" yaml:"driver,omitempty"`     // Empty keeps Docker's default (or inherits the global driver)
+	MaxSize string            `mapstructure:"max_size" yaml:"max_size,omitempty"` // Rotate json-file/local logs at this size (e.g. "10m")
+	MaxFile int               `mapstructure:"max_file" yaml:"max_file,omitempty"` // Number of rotated files to keep
+	Options map[string]string `mapstructure:"options" yaml:"options,omitempty"`   // Driver options (loki-url, syslog-address, ...)
+
+	// Aggregation ships container logs to Loki; global logging block only
+	Aggregation *LogAggregationConfig `mapstructure:"aggregation" yaml:"aggregation,omitempty"`
+}
+
+// Log shipping agents supported by logging.aggregation
+const (
+	LogAgentVector   = "vector"
+	LogAgentPromtail = "promtail"
+)
+
+// DefaultLokiEndpoint is the Loki addon reached over the proxy network
+const DefaultLokiEndpoint = "http://sdbx-loki:3100"
+
+// LogAggregationConfig runs a log shipping agent tailing every SDBX container
+type LogAggregationConfig struct {
+	Agent    string `mapstructure:"agent" yaml:"agent"`                 // vector or promtail
+	Endpoint string `mapstructure:"endpoint" yaml:"endpoint,omitempty"` // Loki base URL (default: the loki addon)
+}
+
+// LokiEndpoint returns the Loki base URL logs are shipped to
+func (a LogAggregationConfig) LokiEndpoint() string {
+	if a.Endpoint == "" {
+		return DefaultLokiEndpoint
+	}
+	return strings.TrimSuffix(a.Endpoint, "/")
+}
+
+// Rotates reports whether the driver supports max-size/max-file rotation
+func (l LoggingConfig) Rotates() bool {
+	return l.Driver == LogDriverJSONFile || l.Driver == LogDriverLocal
+}
+
+// ExtrasConfig defines optional content served by Traefik that is not a registry service
+type ExtrasConfig struct {
+	StaticSites []StaticSiteConfig `mapstructure:"static_sites" yaml:"static_sites,omitempty"`
+	ErrorPages  string             `mapstructure:"error_pages" yaml:"error_pages,omitempty"` // Directory containing 404.html, 503.html, ...
+}
+
+// StaticSiteConfig defines a user-provided directory served as a static website
+type StaticSiteConfig struct {
+	Name      string `mapstructure:"name" yaml:"name"`
+	Directory string `mapstructure:"directory" yaml:"directory"`           // Host directory with index.html
+	Subdomain string `mapstructure:"subdomain" yaml:"subdomain,omitempty"` // Empty serves the apex domain
+	Auth      bool   `mapstructure:"auth" yaml:"auth,omitempty"`           // Require Authelia login
+}
+
+// ExtraServiceConfig defines a user container declared directly in .sdbx.yaml.
+// It is converted into a registry service definition by the resolver.
+type ExtraServiceConfig struct {
+	Name        string   `mapstructure:"name" yaml:"name"`
+	Image       string   `mapstructure:"image" yaml:"image"` // repository[:tag]
+	Description string   `mapstructure:"description" yaml:"description,omitempty"`
+	Command     string   `mapstructure:"command" yaml:"command,omitempty"`
+	Environment []string `mapstructure:"environment" yaml:"environment,omitempty"` // KEY=value
+	Volumes     []string `mapstructure:"volumes" yaml:"volumes,omitempty"`         // host:container[:ro]
+	Ports       []string `mapstructure:"ports" yaml:"ports,omitempty"`             // Published host ports
+	DependsOn   []string `mapstructure:"depends_on" yaml:"depends_on,omitempty"`
+	Port        int      `mapstructure:"port" yaml:"port,omitempty"`           // Container port routed by Traefik (0 = not routed)
+	Subdomain   string   `mapstructure:"subdomain" yaml:"subdomain,omitempty"` // Defaults to the name
+	Public      bool     `mapstructure:"public" yaml:"public,omitempty"`       // Skip Authelia
+}
+
+// ExternalEndpoint locates infrastructure SDBX does not manage. Fields left
+// empty keep the defaults of the service definition.
+type ExternalEndpoint struct {
+	Host string `mapstructure:"host" yaml:"host,omitempty"`
+	Port int    `mapstructure:"port" yaml:"port,omitempty"`
+	URL  string `mapstructure:"url" yaml:"url,omitempty"`   // HTTP endpoints
+	Path string `mapstructure:"path" yaml:"path,omitempty"` // NFS export or SMB share
+}
+
+// Alert rule types
+const (
+	AlertContainerDown   = "container_down"   // A service unhealthy or stopped for longer than For
+	AlertDiskUsage       = "disk_usage"       // A path's filesystem used above Threshold percent
+	AlertVPNDisconnected = "vpn_disconnected" // Gluetun unhealthy or stopped for longer than For
+	AlertBackupAge       = "backup_age"       // Newest backup older than Days
+	AlertCertExpiry      = "cert_expiry"      // A Let's Encrypt certificate expiring within Days (default 14)
+)
+
+// AlertsConfig defines alert rules evaluated against health history and the host
+type AlertsConfig struct {
+	Repeat string      `mapstructure:"repeat" yaml:"repeat,omitempty"` // Re-notify alerts still firing after this long (e.g. 6h); empty notifies once
+	Rules  []AlertRule `mapstructure:"rules" yaml:"rules,omitempty"`
+}
+
+// AlertRule defines one alert condition. Fields apply depending on Type.
+type AlertRule struct {
+	Name      string   `mapstructure:"name" yaml:"name"`
+	Type      string   `mapstructure:"type" yaml:"type"`
+	For       string   `mapstructure:"for" yaml:"for,omitempty"`             // container_down, vpn_disconnected: duration before firing (e.g. 5m)
+	Services  []string `mapstructure:"services" yaml:"services,omitempty"`   // container_down: services to watch (default all)
+	Threshold int      `mapstructure:"threshold" yaml:"threshold,omitempty"` // disk_usage: percent used
+	Path      string   `mapstructure:"path" yaml:"path,omitempty"`           // disk_usage: path to check (default media_path)
+	Days      int      `mapstructure:"days" yaml:"days,omitempty"`           // backup_age: maximum age of the newest backup; cert_expiry: days left before firing
+}
+
+// Duration returns the parsed For duration (0 when unset or invalid)
+func (r AlertRule) Duration() time.Duration {
+	d, _ := time.ParseDuration(r.For)
+	return d
+}
+
+// RepeatInterval returns the parsed Repeat duration (0 when unset or invalid)
+func (a AlertsConfig) RepeatInterval() time.Duration {
+	d, _ := time.ParseDuration(a.Repeat)
+	return d
+}
+
+// Notification channel types
+const (
+	NotifyWebhook = "webhook" // JSON POST to any URL
+	NotifyNtfy    = "ntfy"    // ntfy topic URL (https://ntfy.sh/<topic> or self-hosted)
+	NotifyEmail   = "email"   // mailto: URL of the recipients, sent through the smtp section
+)
+
+// NotificationsConfig defines where alerts and other notifications are delivered
+type NotificationsConfig struct {
+	Channels []NotificationChannel `mapstructure:"channels" yaml:"channels,omitempty"`
+}
+
+// NotificationChannel is one notification destination
+type NotificationChannel struct {
+	Name        string `mapstructure:"name" yaml:"name"`
+	Type        string `mapstructure:"type" yaml:"type"`
+	URL         string `mapstructure:"url" yaml:"url"` // mailto:a@example.com,b@example.com for email
+	TokenSecret string `mapstructure:"token_secret" yaml:"token_secret,omitempty"` // secrets/<name>.txt, sent as a bearer token
+}
+
+// Recipients returns the addresses of an email channel's mailto: URL
+func (ch NotificationChannel) Recipients() []string {
+	u, err := url.Parse(ch.URL)
+	if err != nil || u.Scheme != "mailto" {
+		return nil
+	}
+	var recipients []string
+	for _, addr := range strings.Split(u.Opaque, ",") {
+		if addr = strings.TrimSpace(addr); addr != "" {
+			recipients = append(recipients, addr)
+		}
+	}
+	return recipients
+}
+
+// HasStaticContent reports whether any static sites or error pages are configured
+func (e ExtrasConfig) HasStaticContent() bool {
+	return len(e.StaticSites) > 0 || e.ErrorPages != ""
+}
+
+// NeedsStaticServer reports whether the sdbx-static file server is required,
+// for extras, maintenance pages or the status page
+func (c *Config) NeedsStaticServer() bool {
+	return c.Extras.HasStaticContent() || len(c.MaintenanceServices()) > 0 || c.StatusPage.Enabled
 }
 
 // DefaultConfig returns a new Config with default values
@@ -141,12 +528,33 @@ func DefaultConfig() *Config {
 		Addons:        []string{},
 		PlexAdvertiseURLs: "",
 		Services:      make(map[string]ServiceOverride),
+		Traefik: TraefikConfig{
+			AccessLog: AccessLogConfig{
+				Enabled:  false,
+				Path:     "./logs/traefik",
+				MaxSize:  100,
+				MaxFiles: 5,
+			},
+		},
+		Logging: LoggingConfig{
+			Driver:  LogDriverJSONFile,
+			MaxSize: "10m",
+			MaxFile: 3,
+		},
+		LockIntegrity:  LockIntegrityWarn,
+		SecretDelivery: SecretDeliveryAuto,
+		SchemaVersion:  SchemaVersion,
 	}
 }
 
 // Domain validation regex - matches valid domain names
 var domainRegex = regexp.MustCompile(`^([a-zA-Z0-9]([a-zA-Z0-9\-]{0,61}[a-zA-Z0-9])?\.)+[a-zA-Z]{2,}$`)
 
+// IsValidDomain reports whether domain is accepted by Validate
+func IsValidDomain(domain string) bool {
+	return domainRegex.MatchString(domain)
+}
+
 // Validate checks if the configuration is valid
 func (c *Config) Validate() error {
 	// Required fields
@@ -213,9 +621,559 @@ func (c *Config) Validate() error {
 		return NewValidationError("pgid", "must be between 0 and 65535")
 	}
 
+	// Traefik access log validation
+	if c.Traefik.AccessLog.Enabled {
+		if c.Traefik.AccessLog.Path == "" {
+			return NewValidationError("traefik.access_log.path",
+				"path is required when access logs are enabled")
+		}
+		if c.Traefik.AccessLog.MaxSize < 0 || c.Traefik.AccessLog.MaxFiles < 0 {
+			return NewValidationError("traefik.access_log",
+				"max_size and max_files cannot be negative")
+		}
+	}
+
+	// Logging validation
+	if err := validateLogging("logging", c.Logging); err != nil {
+		return err
+	}
+	if c.Logging.Aggregation != nil {
+		if err := validateLogAggregation("logging.aggregation", *c.Logging.Aggregation); err != nil {
+			return err
+		}
+	}
+
+	// Platform validation
+	if c.Platform != "" && !IsValidPlatform(c.Platform) {
+		return NewValidationError("platform", fmt.Sprintf("invalid platform %q (e.g. linux/arm64)", c.Platform))
+	}
+
+	// Lock integrity validation
+	validLockIntegrity := []string{LockIntegrityWarn, LockIntegrityFail, LockIntegrityOff}
+	if c.LockIntegrity != "" && !slices.Contains(validLockIntegrity, c.LockIntegrity) {
+		return NewValidationError("lock_integrity",
+			fmt.Sprintf("must be one of: %s", strings.Join(validLockIntegrity, ", ")))
+	}
+
+	// Secret delivery validation
+	validSecretDelivery := []string{SecretDeliveryAuto, SecretDeliveryFile, SecretDeliveryEnvFile, SecretDeliveryEnv}
+	if c.SecretDelivery != "" && !slices.Contains(validSecretDelivery, c.SecretDelivery) {
+		return NewValidationError("secret_delivery",
+			fmt.Sprintf("must be one of: %s", strings.Join(validSecretDelivery, ", ")))
+	}
+
+	// Auth mode validation
+	validAuthModes := []string{AuthModeAuthelia, AuthModeBasic}
+	if c.Auth.Mode != "" && !slices.Contains(validAuthModes, c.Auth.Mode) {
+		return NewValidationError("auth.mode",
+			fmt.Sprintf("must be one of: %s", strings.Join(validAuthModes, ", ")))
+	}
+
+	// IP allowlist validation
+	if err := validateIPAllowList("traefik.ip_allowlist", c.Traefik.IPAllowList); err != nil {
+		return err
+	}
+	if err := c.validateCountryAllowList("traefik.country_allowlist", c.Traefik.CountryAllowList); err != nil {
+		return err
+	}
+	for name, mw := range c.Traefik.MiddlewareDefinitions {
+		field := "traefik.middleware_definitions." + name
+		if err := ValidateMiddlewareName(name); err != nil {
+			return NewValidationError(field, err.Error())
+		}
+		if err := mw.Validate(); err != nil {
+			return NewValidationError(field, err.Error())
+		}
+	}
+	for name, override := range c.Services {
+		if err := validateIPAllowList(fmt.Sprintf("services.%s.ip_allowlist", name), override.IPAllowList); err != nil {
+			return err
+		}
+		if err := c.validateCountryAllowList(fmt.Sprintf("services.%s.country_allowlist", name), override.CountryAllowList); err != nil {
+			return err
+		}
+		validPolicies := []string{"", UpdatePolicyAuto, UpdatePolicyNotifyOnly, UpdatePolicyPinned}
+		if !slices.Contains(validPolicies, override.UpdatePolicy) {
+			return NewValidationError(fmt.Sprintf("services.%s.update_policy", name),
+				fmt.Sprintf("must be one of: %s", strings.Join(validPolicies[1:], ", ")))
+		}
+		if override.Pin != "" && !pinRegex.MatchString(override.Pin) {
+			return NewValidationError(fmt.Sprintf("services.%s.pin", name),
+				fmt.Sprintf("invalid pin %q (a definition version such as 1.4.2, or a commit)", override.Pin))
+		}
+		if override.SecretDelivery != "" && !slices.Contains(validSecretDelivery, override.SecretDelivery) {
+			return NewValidationError(fmt.Sprintf("services.%s.secret_delivery", name),
+				fmt.Sprintf("must be one of: %s", strings.Join(validSecretDelivery, ", ")))
+		}
+		if override.Logging != nil {
+			if err := validateLogging(fmt.Sprintf("services.%s.logging", name), *override.Logging); err != nil {
+				return err
+			}
+			if override.Logging.Aggregation != nil {
+				return NewValidationError(fmt.Sprintf("services.%s.logging.aggregation", name),
+					"only supported in the global logging block")
+			}
+		}
+		if err := validateComposeExtra(fmt.Sprintf("services.%s.compose_extra", name), override.ComposeExtra); err != nil {
+			return err
+		}
+		if override.Resources != nil {
+			if err := validateResources(fmt.Sprintf("services.%s.resources", name), *override.Resources); err != nil {
+				return err
+			}
+		}
+		if override.Platform != "" && !IsValidPlatform(override.Platform) {
+			return NewValidationError(fmt.Sprintf("services.%s.platform", name),
+				fmt.Sprintf("invalid platform %q (e.g. linux/amd64)", override.Platform))
+		}
+		if override.Transcode != "" && !slices.Contains(TranscodeMethods, override.Transcode) {
+			return NewValidationError(fmt.Sprintf("services.%s.transcode", name),
+				fmt.Sprintf("must be one of: %s", strings.Join(TranscodeMethods, ", ")))
+		}
+	}
+
+	// Access profiles validation
+	if err := validateAccessProfiles(c.AccessProfiles, c.IsBasicAuth()); err != nil {
+		return err
+	}
+
+	// Static sites validation
+	if err := validateStaticSites(c.Extras.StaticSites); err != nil {
+		return err
+	}
+
+	// Status page validation
+	if err := validateStatusPage(c.StatusPage, c.Extras.StaticSites); err != nil {
+		return err
+	}
+
+	// Inline services validation
+	if err := validateExtraServices(c.ExtraServices); err != nil {
+		return err
+	}
+
+	// External dependency validation
+	for name, ep := range c.ExternalDependencies {
+		field := "external_dependencies." + name
+		if ep.Port < 0 || ep.Port > 65535 {
+			return NewValidationError(field+".port", "must be between 1 and 65535")
+		}
+		if ep.URL != "" && !strings.HasPrefix(ep.URL, "http://") && !strings.HasPrefix(ep.URL, "https://") {
+			return NewValidationError(field+".url", "must be an http:// or https:// URL")
+		}
+	}
+
+	// Shared volume validation
+	for name, vol := range c.Volumes {
+		field := "volumes." + name
+		if err := ValidateVolumeName(name); err != nil {
+			return NewValidationError(field, err.Error())
+		}
+		if err := vol.Validate(); err != nil {
+			return NewValidationError(field, err.Error())
+		}
+	}
+
+	// Storage mount validation
+	if err := validateStorage(c.Storage); err != nil {
+		return err
+	}
+
+	// Runtime validation
+	if err := c.validateRuntime(); err != nil {
+		return err
+	}
+
+	// Named instances validation
+	if err := c.ValidateInstances(); err != nil {
+		return err
+	}
+
+	// Seeding rules validation
+	if err := validateSeeding(c.Seeding, c.DownloadClients); err != nil {
+		return err
+	}
+
+	// Disk guard validation
+	if err := validateDiskGuard(c.DiskGuard); err != nil {
+		return err
+	}
+
+	// Backup schedule validation
+	if err := validateBackup(c.Backup); err != nil {
+		return err
+	}
+
+	// Authelia settings validation
+	if err := validateAuthelia(c.Authelia, c.Domain); err != nil {
+		return err
+	}
+
+	// Timeouts validation
+	if err := validateTimeouts(c.Timeouts); err != nil {
+		return err
+	}
+	if err := validateHostPorts(c.HostPorts); err != nil {
+		return err
+	}
+	if err := validateNetworks(c.Networks); err != nil {
+		return err
+	}
+	if err := validateDNS(c.DNS, c.Networks); err != nil {
+		return err
+	}
+	if err := validateMDNS(c.MDNS, c.Expose.Mode); err != nil {
+		return err
+	}
+	if err := validateServerLogs(c.ServerLogs); err != nil {
+		return err
+	}
+	if c.Expose.Mode == ExposeModeDirect {
+		if err := validateTLS(c.Expose.TLS); err != nil {
+			return err
+		}
+	}
+
+	// Alerting validation
+	if err := validateAlerts(c.Alerts); err != nil {
+		return err
+	}
+	if err := validateNotifications(c.Notifications, c.SMTP); err != nil {
+		return err
+	}
+
+	// Mail server validation
+	if err := validateSMTP(c.SMTP); err != nil {
+		return err
+	}
+
+	// GeoIP validation
+	if err := validateGeoIP(c.GeoIP); err != nil {
+		return err
+	}
+
+	// Proxy validation
+	if err := validateProxy(c.Proxy); err != nil {
+		return err
+	}
+
 	return nil
 }
 
+// platformRegex matches OCI platform strings such as linux/amd64 or linux/arm/v7
+var platformRegex = regexp.MustCompile(`^[a-z0-9]+/[a-z0-9]+(/v[0-9]+)?$`)
+
+// IsValidPlatform reports whether platform is an OCI platform string such
+// as linux/amd64 or linux/arm/v7
+func IsValidPlatform(platform string) bool {
+	return platformRegex.MatchString(platform)
+}
+
+// pinRegex matches service pins: definition versions (1.4.2) and commits
+var pinRegex = regexp.MustCompile(`^[0-9A-Za-z][0-9A-Za-z._+-]*$`)
+
+// staticSiteNameRegex matches names usable as a URL prefix and directory name
+var staticSiteNameRegex = regexp.MustCompile(`^[a-z0-9][a-z0-9-]*$`)
+
+// validateStaticSites checks static site entries for required fields and collisions
+func validateStaticSites(sites []StaticSiteConfig) error {
+	names := make(map[string]bool)
+	hosts := make(map[string]bool)
+	for i, site := range sites {
+		field := fmt.Sprintf("extras.static_sites[%d]", i)
+		if !staticSiteNameRegex.MatchString(site.Name) {
+			return NewValidationError(field+".name",
+				fmt.Sprintf("invalid name %q - use lowercase letters, digits and dashes", site.Name))
+		}
+		if site.Directory == "" {
+			return NewValidationError(field+".directory", "directory is required")
+		}
+		if names[site.Name] {
+			return NewValidationError(field+".name", fmt.Sprintf("duplicate static site %q", site.Name))
+		}
+		if hosts[site.Subdomain] {
+			if site.Subdomain == "" {
+				return NewValidationError(field+".subdomain", "only one static site can serve the apex domain")
+			}
+			return NewValidationError(field+".subdomain", fmt.Sprintf("subdomain %q is already used", site.Subdomain))
+		}
+		names[site.Name] = true
+		hosts[site.Subdomain] = true
+	}
+	return nil
+}
+
+// validateExtraServices checks inline service entries for required fields and collisions
+func validateExtraServices(services []ExtraServiceConfig) error {
+	names := make(map[string]bool)
+	for i, svc := range services {
+		field := fmt.Sprintf("extra_services[%d]", i)
+		if !staticSiteNameRegex.MatchString(svc.Name) {
+			return NewValidationError(field+".name",
+				fmt.Sprintf("invalid name %q - use lowercase letters, digits and dashes", svc.Name))
+		}
+		if names[svc.Name] {
+			return NewValidationError(field+".name", fmt.Sprintf("duplicate service %q", svc.Name))
+		}
+		names[svc.Name] = true
+		if svc.Image == "" {
+			return NewValidationError(field+".image", "image is required")
+		}
+		for _, env := range svc.Environment {
+			if name, value, ok := strings.Cut(env, "="); !ok || name == "" || value == "" {
+				return NewValidationError(field+".environment", fmt.Sprintf("%q must use KEY=value form", env))
+			}
+		}
+		for _, vol := range svc.Volumes {
+			if !strings.Contains(vol, ":") {
+				return NewValidationError(field+".volumes", fmt.Sprintf("%q must use host:container form", vol))
+			}
+		}
+		if svc.Port < 0 || svc.Port > 65535 {
+			return NewValidationError(field+".port", fmt.Sprintf("invalid port %d", svc.Port))
+		}
+	}
+	return nil
+}
+
+// ValidateInstances checks instance names and that instances copy a real
+// service rather than another instance
+func (c *Config) ValidateInstances() error {
+	instances := c.Instances
+	for _, name := range slices.Sorted(maps.Keys(instances)) {
+		field := "instances." + name
+		service := instances[name]
+		if !staticSiteNameRegex.MatchString(name) {
+			return NewValidationError(field, fmt.Sprintf("invalid instance name %q - use lowercase letters, digits and dashes", name))
+		}
+		if service == "" {
+			return NewValidationError(field, "service is required")
+		}
+		if service == name {
+			return NewValidationError(field, "an instance cannot copy itself")
+		}
+		if _, nested := instances[service]; nested {
+			return NewValidationError(field, fmt.Sprintf("%s is itself an instance", service))
+		}
+	}
+	return nil
+}
+
+// validateAlerts checks alert rule names, types and their type-specific fields
+func validateAlerts(alerts AlertsConfig) error {
+	if alerts.Repeat != "" {
+		if d, err := time.ParseDuration(alerts.Repeat); err != nil || d < 0 {
+			return NewValidationError("alerts.repeat", fmt.Sprintf("invalid duration %q (e.g. 6h)", alerts.Repeat))
+		}
+	}
+
+	validTypes := []string{AlertContainerDown, AlertDiskUsage, AlertVPNDisconnected, AlertBackupAge, AlertCertExpiry}
+	names := make(map[string]bool)
+	for i, rule := range alerts.Rules {
+		field := fmt.Sprintf("alerts.rules[%d]", i)
+		if !staticSiteNameRegex.MatchString(rule.Name) {
+			return NewValidationError(field+".name",
+				fmt.Sprintf("invalid name %q - use lowercase letters, digits and dashes", rule.Name))
+		}
+		if names[rule.Name] {
+			return NewValidationError(field+".name", fmt.Sprintf("duplicate alert rule %q", rule.Name))
+		}
+		names[rule.Name] = true
+		if !slices.Contains(validTypes, rule.Type) {
+			return NewValidationError(field+".type",
+				fmt.Sprintf("must be one of: %s", strings.Join(validTypes, ", ")))
+		}
+		if rule.For != "" {
+			if d, err := time.ParseDuration(rule.For); err != nil || d < 0 {
+				return NewValidationError(field+".for", fmt.Sprintf("invalid duration %q (e.g. 5m)", rule.For))
+			}
+		}
+		switch rule.Type {
+		case AlertDiskUsage:
+			if rule.Threshold < 1 || rule.Threshold > 100 {
+				return NewValidationError(field+".threshold", "must be a percentage between 1 and 100")
+			}
+		case AlertBackupAge:
+			if rule.Days < 1 {
+				return NewValidationError(field+".days", "must be at least 1")
+			}
+		case AlertCertExpiry:
+			if rule.Days < 0 {
+				return NewValidationError(field+".days", "must not be negative")
+			}
+		}
+	}
+	return nil
+}
+
+// validateNotifications checks notification channel names, types and URLs.
+// Email channels need the smtp section.
+func validateNotifications(notifications NotificationsConfig, smtp SMTPConfig) error {
+	validTypes := []string{NotifyWebhook, NotifyNtfy, NotifyEmail}
+	names := make(map[string]bool)
+	for i, ch := range notifications.Channels {
+		field := fmt.Sprintf("notifications.channels[%d]", i)
+		if !staticSiteNameRegex.MatchString(ch.Name) {
+			return NewValidationError(field+".name",
+				fmt.Sprintf("invalid name %q - use lowercase letters, digits and dashes", ch.Name))
+		}
+		if names[ch.Name] {
+			return NewValidationError(field+".name", fmt.Sprintf("duplicate channel %q", ch.Name))
+		}
+		names[ch.Name] = true
+		if !slices.Contains(validTypes, ch.Type) {
+			return NewValidationError(field+".type",
+				fmt.Sprintf("must be one of: %s", strings.Join(validTypes, ", ")))
+		}
+		if ch.Type == NotifyEmail {
+			if err := validateEmailChannel(field, ch, smtp); err != nil {
+				return err
+			}
+			continue
+		}
+		if u, err := url.Parse(ch.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return NewValidationError(field+".url", fmt.Sprintf("invalid URL %q - must be http(s)", ch.URL))
+		}
+	}
+	return nil
+}
+
+// validateEmailChannel checks the recipients of an email channel
+func validateEmailChannel(field string, ch NotificationChannel, smtp SMTPConfig) error {
+	recipients := ch.Recipients()
+	if len(recipients) == 0 {
+		return NewValidationError(field+".url",
+			fmt.Sprintf("invalid URL %q - email channels need mailto:<address>[,<address>...]", ch.URL))
+	}
+	for _, addr := range recipients {
+		if _, err := mail.ParseAddress(addr); err != nil {
+			return NewValidationError(field+".url", fmt.Sprintf("invalid address %q", addr))
+		}
+	}
+	if !smtp.IsSet() {
+		return NewValidationError(field+".type", "email channels need a mail server in the smtp section")
+	}
+	return nil
+}
+
+// validateResources checks container CPU and memory limits
+func validateResources(field string, r ResourceLimits) error {
+	if r.CPUs != "" {
+		if cpus, err := strconv.ParseFloat(r.CPUs, 64); err != nil || cpus <= 0 {
+			return NewValidationError(field+".cpus", fmt.Sprintf("invalid CPU count %q (e.g. 1.5)", r.CPUs))
+		}
+	}
+	if r.Memory != "" && !logSizeRegex.MatchString(r.Memory) {
+		return NewValidationError(field+".memory", fmt.Sprintf("invalid memory %q (e.g. 512m)", r.Memory))
+	}
+	return nil
+}
+
+// logSizeRegex matches Docker log size values such as 10m or 1g
+var logSizeRegex = regexp.MustCompile(`^[0-9]+[kmg]?$`)
+
+// validateLogging checks a logging driver and its rotation settings
+func validateLogging(field string, l LoggingConfig) error {
+	validDrivers := []string{LogDriverJSONFile, LogDriverLocal, LogDriverLoki, LogDriverSyslog, LogDriverJournald, LogDriverNone}
+	if l.Driver != "" && !slices.Contains(validDrivers, l.Driver) {
+		return NewValidationError(field+".driver",
+			fmt.Sprintf("must be one of: %s", strings.Join(validDrivers, ", ")))
+	}
+	if l.MaxSize != "" && !logSizeRegex.MatchString(l.MaxSize) {
+		return NewValidationError(field+".max_size", fmt.Sprintf("invalid size %q (e.g. 10m)", l.MaxSize))
+	}
+	if l.MaxFile < 0 {
+		return NewValidationError(field+".max_file", "cannot be negative")
+	}
+	if l.Driver == LogDriverLoki && l.Options["loki-url"] == "" {
+		return NewValidationError(field+".options", "loki-url is required for the loki driver")
+	}
+	return nil
+}
+
+// validateLogAggregation checks the log shipping agent and its Loki endpoint
+func validateLogAggregation(field string, a LogAggregationConfig) error {
+	validAgents := []string{LogAgentVector, LogAgentPromtail}
+	if !slices.Contains(validAgents, a.Agent) {
+		return NewValidationError(field+".agent",
+			fmt.Sprintf("must be one of: %s", strings.Join(validAgents, ", ")))
+	}
+	if a.Endpoint != "" {
+		u, err := url.Parse(a.Endpoint)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return NewValidationError(field+".endpoint", fmt.Sprintf("invalid URL %q (e.g. http://loki:3100)", a.Endpoint))
+		}
+	}
+	return nil
+}
+
+// composeServiceKeys lists the service-level properties of the Compose specification
+var composeServiceKeys = map[string]bool{
+	"annotations": true, "attach": true, "blkio_config": true, "build": true,
+	"cap_add": true, "cap_drop": true, "cgroup": true, "cgroup_parent": true,
+	"command": true, "configs": true, "container_name": true, "cpu_count": true,
+	"cpu_percent": true, "cpu_period": true, "cpu_quota": true, "cpu_rt_period": true,
+	"cpu_rt_runtime": true, "cpu_shares": true, "cpus": true, "cpuset": true,
+	"credential_spec": true, "depends_on": true, "deploy": true, "develop": true,
+	"device_cgroup_rules": true, "devices": true, "dns": true, "dns_opt": true,
+	"dns_search": true, "domainname": true, "entrypoint": true, "env_file": true,
+	"environment": true, "expose": true, "extends": true, "external_links": true,
+	"extra_hosts": true, "gpus": true, "group_add": true, "healthcheck": true,
+	"hostname": true, "image": true, "init": true, "ipc": true,
+	"isolation": true, "labels": true, "label_file": true, "links": true,
+	"logging": true, "mac_address": true, "mem_limit": true, "mem_reservation": true,
+	"mem_swappiness": true, "memswap_limit": true, "network_mode": true, "networks": true,
+	"oom_kill_disable": true, "oom_score_adj": true, "pid": true, "pids_limit": true,
+	"platform": true, "ports": true, "post_start": true, "pre_stop": true,
+	"privileged": true, "profiles": true, "pull_policy": true, "read_only": true,
+	"restart": true, "runtime": true, "scale": true, "secrets": true,
+	"security_opt": true, "shm_size": true, "stdin_open": true, "stop_grace_period": true,
+	"stop_signal": true, "storage_opt": true, "sysctls": true, "tmpfs": true,
+	"tty": true, "ulimits": true, "user": true, "userns_mode": true,
+	"uts": true, "volumes": true, "volumes_from": true, "working_dir": true,
+}
+
+// validateComposeExtra checks that passthrough keys are Compose service properties
+func validateComposeExtra(field string, extra map[string]interface{}) error {
+	for key := range extra {
+		if composeServiceKeys[key] || strings.HasPrefix(key, "x-") {
+			continue
+		}
+		return NewValidationError(field, fmt.Sprintf("%q is not a Compose service property", key))
+	}
+	return nil
+}
+
+// validateIPAllowList checks that every entry is a valid IP address or CIDR range
+func validateIPAllowList(field string, entries []string) error {
+	for _, entry := range entries {
+		if _, _, err := net.ParseCIDR(entry); err == nil {
+			continue
+		}
+		if net.ParseIP(entry) != nil {
+			continue
+		}
+		return NewValidationError(field, fmt.Sprintf("invalid IP or CIDR range %q", entry))
+	}
+	return nil
+}
+
+// countryCodeRegex matches an ISO 3166-1 alpha-2 country code
+var countryCodeRegex = regexp.MustCompile(`^[A-Z]{2}$`)
+
+// validateCountryAllowList checks that every entry is an ISO 3166-1 country
+// code, and that the geoip section downloads a database to look them up in
+func (c *Config) validateCountryAllowList(field string, entries []string) error {
+	for _, entry := range entries {
+		if !countryCodeRegex.MatchString(entry) {
+			return NewValidationError(field, fmt.Sprintf("invalid country code %q (two capital letters, e.g. FR)", entry))
+		}
+	}
+	if len(entries) > 0 && !c.GeoIP.HasCountries() {
+		return NewValidationError(field, "needs the geoip section with a GeoLite2-Country or GeoLite2-City database")
+	}
+	return nil
+}
 
 // Load loads configuration from file and environment
 func Load() (*Config, error) {
@@ -240,6 +1198,14 @@ func Load() (*Config, error) {
 	viper.SetDefault("vpn_country", cfg.VPNCountry)
 	viper.SetDefault("addons", cfg.Addons)
 	viper.SetDefault("plex_advertise_urls", cfg.PlexAdvertiseURLs)
+	viper.SetDefault("traefik.access_log.path", cfg.Traefik.AccessLog.Path)
+	viper.SetDefault("traefik.access_log.max_size", cfg.Traefik.AccessLog.MaxSize)
+	viper.SetDefault("traefik.access_log.max_files", cfg.Traefik.AccessLog.MaxFiles)
+	viper.SetDefault("logging.driver", cfg.Logging.Driver)
+	viper.SetDefault("logging.max_size", cfg.Logging.MaxSize)
+	viper.SetDefault("logging.max_file", cfg.Logging.MaxFile)
+	viper.SetDefault("lock_integrity", cfg.LockIntegrity)
+	viper.SetDefault("secret_delivery", cfg.SecretDelivery)
 
 	// Try to read config file
 	if err := viper.ReadInConfig(); err != nil {
@@ -249,27 +1215,76 @@ func Load() (*Config, error) {
 		}
 	}
 
+	// Upgrade older schemas in memory (e.g. expose_mode → expose.mode)
+	applied, err := migrateViperConfig()
+	if err != nil {
+		return nil, err
+	}
+
 	// Unmarshal into struct
 	if err := viper.Unmarshal(cfg); err != nil {
 		return nil, fmt.Errorf("error unmarshaling config: %w", err)
 	}
 
-	// Legacy migration: if expose_mode is set but expose.mode is not, migrate
-	if cfg.ExposeMode != "" && cfg.Expose.Mode == "" {
-		cfg.Expose.Mode = cfg.ExposeMode
+	cfg.AppliedMigrations = applied
+	finishDecode(viper.GetViper(), cfg)
+
+	return cfg, nil
+}
+
+// Parse decodes .sdbx.yaml content the way Load reads the file, migrating
+// older schemas, without touching the global config
+func Parse(data []byte) (*Config, error) {
+	raw := make(map[string]interface{})
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("invalid YAML: %w", err)
+	}
+	var applied []string
+	if RawSchemaVersion(raw) < SchemaVersion {
+		for _, m := range Migrate(raw) {
+			applied = append(applied, m.Description)
+		}
+	}
+	migrated, err := yaml.Marshal(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	v := viper.New()
+	v.SetConfigType("yaml")
+	if err := v.ReadConfig(bytes.NewReader(migrated)); err != nil {
+		return nil, fmt.Errorf("error reading config: %w", err)
+	}
+	cfg := DefaultConfig()
+	if err := v.Unmarshal(cfg); err != nil {
+		return nil, fmt.Errorf("error unmarshaling config: %w", err)
+	}
+	cfg.AppliedMigrations = applied
+	finishDecode(v, cfg)
+
+	return cfg, nil
+}
+
+// finishDecode fixes up what mapstructure cannot decode on its own
+func finishDecode(v *viper.Viper, cfg *Config) {
+	// An option-less "compress: {}" decodes to nil
+	for name, mw := range cfg.Traefik.MiddlewareDefinitions {
+		if mw.Compress == nil && v.IsSet("traefik.middleware_definitions."+name+".compress") {
+			mw.Compress = &CompressMiddleware{}
+			cfg.Traefik.MiddlewareDefinitions[name] = mw
+		}
 	}
 
 	// Initialize Services map if nil
 	if cfg.Services == nil {
 		cfg.Services = make(map[string]ServiceOverride)
 	}
-
-	return cfg, nil
 }
 
 // Save saves the configuration to a file
 func (c *Config) Save(path string) error {
 	// Set all values in viper
+	viper.Set("schema_version", c.SchemaVersion)
 	viper.Set("domain", c.Domain)
 	viper.Set("timezone", c.Timezone)
 	viper.Set("expose", c.Expose)
@@ -286,6 +1301,7 @@ func (c *Config) Save(path string) error {
 	viper.Set("vpn_type", c.VPNType)
 	viper.Set("vpn_country", c.VPNCountry)
 	viper.Set("jellyfin_enabled", c.JellyfinEnabled)
+	viper.Set("hardware_transcode", c.HardwareTranscode)
 	viper.Set("addons", c.Addons)
 	if c.PlexAdvertiseURLs != "" {
 		viper.Set("plex_advertise_urls", c.PlexAdvertiseURLs)
@@ -293,6 +1309,100 @@ func (c *Config) Save(path string) error {
 	if len(c.Services) > 0 {
 		viper.Set("services", c.Services)
 	}
+	// Also written when emptied so the last removed instance is dropped
+	if len(c.Instances) > 0 || viper.IsSet("instances") {
+		viper.Set("instances", c.Instances)
+	}
+	if c.Platform != "" {
+		viper.Set("platform", c.Platform)
+	}
+	if c.Runtime != "" {
+		viper.Set("runtime", c.Runtime)
+	}
+	if c.LockIntegrity != "" && c.LockIntegrity != LockIntegrityWarn {
+		viper.Set("lock_integrity", c.LockIntegrity)
+	}
+	if c.SecretDelivery != "" && c.SecretDelivery != SecretDeliveryAuto {
+		viper.Set("secret_delivery", c.SecretDelivery)
+	}
+	// Also written when switched back so the default replaces basic
+	if (c.Auth.Mode != "" && c.Auth.Mode != AuthModeAuthelia) || viper.IsSet("auth") {
+		viper.Set("auth", c.Auth)
+	}
+	if c.Authelia.IsSet() || viper.IsSet("authelia") {
+		viper.Set("authelia", c.Authelia)
+	}
+	if len(c.AccessProfiles) > 0 || viper.IsSet("access_profiles") {
+		viper.Set("access_profiles", c.AccessProfiles)
+	}
+	viper.Set("traefik", c.Traefik)
+	viper.Set("logging", c.Logging)
+	if c.Extras.HasStaticContent() {
+		viper.Set("extras", c.Extras)
+	}
+	if c.StatusPage.Enabled || viper.IsSet("status_page") {
+		viper.Set("status_page", c.StatusPage)
+	}
+	if len(c.ExtraServices) > 0 {
+		viper.Set("extra_services", c.ExtraServices)
+	}
+	if len(c.ExternalDependencies) > 0 || viper.IsSet("external_dependencies") {
+		viper.Set("external_dependencies", c.ExternalDependencies)
+	}
+	if len(c.Volumes) > 0 || viper.IsSet("volumes") {
+		viper.Set("volumes", c.Volumes)
+	}
+	if c.Storage.IsEnabled() || viper.IsSet("storage") {
+		viper.Set("storage", c.Storage)
+	}
+	if c.DownloadClients != (DownloadClientsConfig{}) || viper.IsSet("download_clients") {
+		viper.Set("download_clients", c.DownloadClients)
+	}
+	if c.Seeding.IsEnabled() || c.Seeding.Interval != "" || viper.IsSet("seeding") {
+		viper.Set("seeding", c.Seeding)
+	}
+	if c.DiskGuard.IsEnabled() || viper.IsSet("disk_guard") {
+		viper.Set("disk_guard", c.DiskGuard)
+	}
+	if c.Backup.IsEnabled() || viper.IsSet("backup") {
+		viper.Set("backup", c.Backup)
+	}
+	if len(c.Alerts.Rules) > 0 || c.Alerts.Repeat != "" || viper.IsSet("alerts") {
+		viper.Set("alerts", c.Alerts)
+	}
+	if len(c.Notifications.Channels) > 0 || viper.IsSet("notifications") {
+		viper.Set("notifications", c.Notifications)
+	}
+	if c.SMTP.IsSet() || viper.IsSet("smtp") {
+		viper.Set("smtp", c.SMTP)
+	}
+	if c.GeoIP.IsEnabled() || viper.IsSet("geoip") {
+		viper.Set("geoip", c.GeoIP)
+	}
+	if c.Proxy.IsSet() || viper.IsSet("proxy") {
+		viper.Set("proxy", c.Proxy)
+	}
+	if c.Offline || viper.IsSet("offline") {
+		viper.Set("offline", c.Offline)
+	}
+	if c.Timeouts != (TimeoutsConfig{}) || viper.IsSet("timeouts") {
+		viper.Set("timeouts", c.Timeouts)
+	}
+	if c.HostPorts.IsEnabled() || viper.IsSet("host_ports") {
+		viper.Set("host_ports", c.HostPorts)
+	}
+	if len(c.Networks) > 0 || viper.IsSet("networks") {
+		viper.Set("networks", c.Networks)
+	}
+	if c.DNS.IsEnabled() || viper.IsSet("dns") {
+		viper.Set("dns", c.DNS)
+	}
+	if c.MDNS.IsEnabled() || viper.IsSet("mdns") {
+		viper.Set("mdns", c.MDNS)
+	}
+	if c.ServerLogs.IsEnabled() || viper.IsSet("server_logs") {
+		viper.Set("server_logs", c.ServerLogs)
+	}
 
 	return viper.WriteConfigAs(path)
 }
@@ -358,6 +1468,71 @@ func (c *Config) DisableAddon(addon string) {
 	c.Addons = newAddons
 }
 
+// InstanceSeparator joins a service and one of its named instances in a
+// service reference ("sonarr@sonarr4k")
+const InstanceSeparator = "@"
+
+// SplitServiceRef splits a "service@instance" reference. Plain names return
+// an empty instance.
+func SplitServiceRef(ref string) (service, instance string) {
+	service, instance, _ = strings.Cut(ref, InstanceSeparator)
+	return service, instance
+}
+
+// ServiceName returns the name a service reference runs under: plain names
+// are returned as is and "sonarr@sonarr4k" returns sonarr4k once it is
+// confirmed to be an instance of sonarr
+func (c *Config) ServiceName(ref string) (string, error) {
+	service, instance := SplitServiceRef(ref)
+	if instance == "" {
+		return service, nil
+	}
+	if base := c.InstanceOf(instance); base != service {
+		return "", fmt.Errorf("%s is not an instance of %s", instance, service)
+	}
+	return instance, nil
+}
+
+// ServiceRef returns how a service is shown to users: "sonarr@sonarr4k" for
+// named instances and the plain name otherwise
+func (c *Config) ServiceRef(name string) string {
+	if base := c.InstanceOf(name); base != "" {
+		return base + InstanceSeparator + name
+	}
+	return name
+}
+
+// InstanceOf returns the service a named instance copies, or "" if name is
+// not an instance
+func (c *Config) InstanceOf(name string) string {
+	return c.Instances[name]
+}
+
+// InstancesOf returns the sorted names of the instances of a service
+func (c *Config) InstancesOf(service string) []string {
+	var names []string
+	for name, base := range c.Instances {
+		if base == service {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+	return names
+}
+
+// AddInstance declares a named instance of a service
+func (c *Config) AddInstance(name, service string) {
+	if c.Instances == nil {
+		c.Instances = make(map[string]string)
+	}
+	c.Instances[name] = service
+}
+
+// RemoveInstance drops a named instance
+func (c *Config) RemoveInstance(name string) {
+	delete(c.Instances, name)
+}
+
 // GetServiceRoutingStrategy returns the effective routing strategy for a service
 // It checks for per-service overrides first, then falls back to global routing strategy
 func (c *Config) GetServiceRoutingStrategy(service string) string {
@@ -418,7 +1593,120 @@ func (c *Config) IsCloudflared() bool {
 	return c.Expose.Mode == ExposeModeCloudflared
 }
 
+// AuthMode returns the authentication mode, defaulting to Authelia
+func (c *Config) AuthMode() string {
+	if c.Auth.Mode == "" {
+		return AuthModeAuthelia
+	}
+	return c.Auth.Mode
+}
+
+// IsBasicAuth returns true if Traefik basic auth replaces Authelia
+func (c *Config) IsBasicAuth() bool {
+	return c.Auth.Mode == AuthModeBasic
+}
+
 // IsLANMode returns true if in LAN (no-TLS) mode
 func (c *Config) IsLANMode() bool {
 	return c.Expose.Mode == ExposeModeLAN
 }
+
+// GetServiceIPAllowList returns the effective IP allowlist for a service.
+// A per-service allowlist replaces the global one rather than extending it.
+func (c *Config) GetServiceIPAllowList(service string) []string {
+	if override, ok := c.Services[service]; ok && len(override.IPAllowList) > 0 {
+		return override.IPAllowList
+	}
+	return c.Traefik.IPAllowList
+}
+
+// IsInMaintenance returns true if the service is in maintenance mode
+func (c *Config) IsInMaintenance(service string) bool {
+	override, ok := c.Services[service]
+	return ok && override.Maintenance
+}
+
+// SetMaintenance toggles maintenance mode for a service, dropping overrides
+// that no longer carry any setting
+func (c *Config) SetMaintenance(service string, enabled bool) {
+	if c.Services == nil {
+		c.Services = make(map[string]ServiceOverride)
+	}
+	override := c.Services[service]
+	override.Maintenance = enabled
+	if override.isEmpty() {
+		delete(c.Services, service)
+		return
+	}
+	c.Services[service] = override
+}
+
+// ServicePin returns the definition version or commit a service is pinned
+// to. Named instances follow the pin of their base service unless they set
+// their own.
+func (c *Config) ServicePin(service string) string {
+	if pin := c.Services[service].Pin; pin != "" {
+		return pin
+	}
+	if base := c.InstanceOf(service); base != "" {
+		return c.Services[base].Pin
+	}
+	return ""
+}
+
+// UpdatePolicy returns the update policy for a service (auto unless overridden)
+func (c *Config) UpdatePolicy(service string) string {
+	if policy := c.Services[service].UpdatePolicy; policy != "" {
+		return policy
+	}
+	return UpdatePolicyAuto
+}
+
+// ServiceSecretDelivery returns the secret delivery strategy for a service
+// (the global secret_delivery unless overridden, auto when neither is set)
+func (c *Config) ServiceSecretDelivery(service string) string {
+	if strategy := c.Services[service].SecretDelivery; strategy != "" {
+		return strategy
+	}
+	if c.SecretDelivery != "" {
+		return c.SecretDelivery
+	}
+	return SecretDeliveryAuto
+}
+
+// TargetPlatform returns the platform services must run on: the configured
+// platform, or the platform sdbx itself was built for
+func (c *Config) TargetPlatform() string {
+	if c.Platform != "" {
+		return c.Platform
+	}
+	return HostPlatform()
+}
+
+// ServicePlatform returns the platform forced for a service, or "" to let
+// Docker pick the image matching the host
+func (c *Config) ServicePlatform(service string) string {
+	return c.Services[service].Platform
+}
+
+// HostPlatform returns the OCI platform of the running host (e.g. linux/arm64)
+func HostPlatform() string {
+	switch runtime.GOARCH {
+	case "arm":
+		return "linux/arm/v7"
+	default:
+		return "linux/" + runtime.GOARCH
+	}
+}
+
+// MaintenanceServices returns the sorted names of services in maintenance mode
+func (c *Config) MaintenanceServices() []string {
+	var names []string
+	for name, override := range c.Services {
+		if override.Maintenance {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
@@ -550,3 +550,499 @@ func TestDomainValidation(t *testing.T) {
 		})
 	}
 }
+
+func TestIPAllowListValidation(t *testing.T) {
+	tests := []struct {
+		name    string
+		global  []string
+		service []string
+		wantErr bool
+	}{
+		{"empty", nil, nil, false},
+		{"valid CIDR", []string{"192.168.1.0/24"}, nil, false},
+		{"valid IP", []string{"203.0.113.7"}, nil, false},
+		{"valid IPv6", []string{"2001:db8::/32"}, nil, false},
+		{"invalid global", []string{"not-an-ip"}, nil, true},
+		{"invalid per-service", nil, []string{"10.0.0.0/33"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultConfig()
+			cfg.Traefik.IPAllowList = tt.global
+			if tt.service != nil {
+				cfg.Services["sonarr"] = ServiceOverride{IPAllowList: tt.service}
+			}
+			err := cfg.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCountryAllowListValidation(t *testing.T) {
+	geoip := GeoIPConfig{AccountID: "123456"}
+	tests := []struct {
+		name    string
+		geoip   GeoIPConfig
+		global  []string
+		service []string
+		wantErr bool
+	}{
+		{"empty", GeoIPConfig{}, nil, nil, false},
+		{"valid", geoip, []string{"FR", "BE"}, nil, false},
+		{"valid per-service", geoip, nil, []string{"CA"}, false},
+		{"city database", GeoIPConfig{AccountID: "123456", Editions: []string{"GeoLite2-City"}}, []string{"FR"}, nil, false},
+		{"lowercase", geoip, []string{"fr"}, nil, true},
+		{"country name", geoip, nil, []string{"France"}, true},
+		{"no geoip section", GeoIPConfig{}, []string{"FR"}, nil, true},
+		{"no country database", GeoIPConfig{AccountID: "123456", Editions: []string{"GeoLite2-ASN"}}, nil, []string{"FR"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultConfig()
+			cfg.GeoIP = tt.geoip
+			cfg.Traefik.CountryAllowList = tt.global
+			if tt.service != nil {
+				cfg.Services["sonarr"] = ServiceOverride{CountryAllowList: tt.service}
+			}
+			err := cfg.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestGetServiceIPAllowList(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Traefik.IPAllowList = []string{"192.168.0.0/16"}
+	cfg.Services["sonarr"] = ServiceOverride{IPAllowList: []string{"10.0.0.0/8"}}
+
+	if got := cfg.GetServiceIPAllowList("sonarr"); len(got) != 1 || got[0] != "10.0.0.0/8" {
+		t.Errorf("sonarr allowlist = %v, want [10.0.0.0/8]", got)
+	}
+	if got := cfg.GetServiceIPAllowList("radarr"); len(got) != 1 || got[0] != "192.168.0.0/16" {
+		t.Errorf("radarr allowlist = %v, want [192.168.0.0/16]", got)
+	}
+}
+
+func TestAccessLogValidation(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Traefik.AccessLog.Enabled = true
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("default access log config should be valid: %v", err)
+	}
+
+	cfg.Traefik.AccessLog.Path = ""
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error for empty access log path")
+	}
+}
+
+func TestStaticSitesValidation(t *testing.T) {
+	tests := []struct {
+		name    string
+		sites   []StaticSiteConfig
+		wantErr bool
+	}{
+		{"empty", nil, false},
+		{"apex and subdomain", []StaticSiteConfig{
+			{Name: "landing", Directory: "./www"},
+			{Name: "docs", Directory: "./docs", Subdomain: "docs"},
+		}, false},
+		{"invalid name", []StaticSiteConfig{{Name: "My Site", Directory: "./www"}}, true},
+		{"missing directory", []StaticSiteConfig{{Name: "landing"}}, true},
+		{"duplicate name", []StaticSiteConfig{
+			{Name: "landing", Directory: "./a", Subdomain: "a"},
+			{Name: "landing", Directory: "./b", Subdomain: "b"},
+		}, true},
+		{"two apex sites", []StaticSiteConfig{
+			{Name: "a", Directory: "./a"},
+			{Name: "b", Directory: "./b"},
+		}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultConfig()
+			cfg.Extras.StaticSites = tt.sites
+			err := cfg.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSetMaintenance(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Services["radarr"] = ServiceOverride{Subdomain: "movies"}
+
+	cfg.SetMaintenance("sonarr", true)
+	cfg.SetMaintenance("radarr", true)
+	if got := cfg.MaintenanceServices(); len(got) != 2 || got[0] != "radarr" || got[1] != "sonarr" {
+		t.Errorf("MaintenanceServices() = %v, want [radarr sonarr]", got)
+	}
+	if !cfg.NeedsStaticServer() {
+		t.Error("maintenance mode should require the static server")
+	}
+
+	cfg.SetMaintenance("sonarr", false)
+	cfg.SetMaintenance("radarr", false)
+	if _, ok := cfg.Services["sonarr"]; ok {
+		t.Error("empty override for sonarr should be removed")
+	}
+	if cfg.Services["radarr"].Subdomain != "movies" {
+		t.Error("turning maintenance off must keep other overrides")
+	}
+	if cfg.IsInMaintenance("radarr") {
+		t.Error("radarr should be out of maintenance")
+	}
+}
+
+func TestSetMaintenanceKeepsInstanceVariables(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.AddInstance("sonarr4k", "sonarr")
+	cfg.Services["sonarr4k"] = ServiceOverride{Variables: map[string]string{"quality": "2160p"}}
+
+	cfg.SetMaintenance("sonarr4k", true)
+	cfg.SetMaintenance("sonarr4k", false)
+	if got := cfg.Services["sonarr4k"].Variables["quality"]; got != "2160p" {
+		t.Errorf("variables of sonarr4k after maintenance = %v, want quality 2160p", cfg.Services["sonarr4k"].Variables)
+	}
+	if cfg.IsInMaintenance("sonarr4k") {
+		t.Error("sonarr4k should be out of maintenance")
+	}
+}
+
+func TestExtraServicesValidation(t *testing.T) {
+	tests := []struct {
+		name     string
+		services []ExtraServiceConfig
+		wantErr  bool
+	}{
+		{"empty", nil, false},
+		{"valid", []ExtraServiceConfig{
+			{Name: "kuma", Image: "louislam/uptime-kuma:1", Port: 3001, Environment: []string{"TZ=UTC"}},
+		}, false},
+		{"invalid name", []ExtraServiceConfig{{Name: "Uptime Kuma", Image: "x"}}, true},
+		{"missing image", []ExtraServiceConfig{{Name: "kuma"}}, true},
+		{"duplicate name", []ExtraServiceConfig{{Name: "a", Image: "x"}, {Name: "a", Image: "y"}}, true},
+		{"bad environment", []ExtraServiceConfig{{Name: "a", Image: "x", Environment: []string{"TZ"}}}, true},
+		{"bad volume", []ExtraServiceConfig{{Name: "a", Image: "x", Volumes: []string{"/data"}}}, true},
+		{"bad port", []ExtraServiceConfig{{Name: "a", Image: "x", Port: 70000}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultConfig()
+			cfg.ExtraServices = tt.services
+			err := cfg.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestInstancesValidation(t *testing.T) {
+	tests := []struct {
+		name      string
+		instances map[string]string
+		wantErr   bool
+	}{
+		{"empty", nil, false},
+		{"valid", map[string]string{"sonarr4k": "sonarr", "radarr-anime": "radarr"}, false},
+		{"invalid name", map[string]string{"Sonarr 4K": "sonarr"}, true},
+		{"missing service", map[string]string{"sonarr4k": ""}, true},
+		{"copies itself", map[string]string{"sonarr": "sonarr"}, true},
+		{"nested", map[string]string{"sonarr4k": "sonarr", "sonarr8k": "sonarr4k"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultConfig()
+			cfg.Instances = tt.instances
+			err := cfg.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestAlertsValidation(t *testing.T) {
+	tests := []struct {
+		name     string
+		alerts   AlertsConfig
+		channels []NotificationChannel
+		wantErr  bool
+	}{
+		{"empty", AlertsConfig{}, nil, false},
+		{"valid", AlertsConfig{Repeat: "6h", Rules: []AlertRule{
+			{Name: "down", Type: AlertContainerDown, For: "5m"},
+			{Name: "disk", Type: AlertDiskUsage, Threshold: 90},
+			{Name: "vpn", Type: AlertVPNDisconnected},
+			{Name: "backup", Type: AlertBackupAge, Days: 7},
+		}}, []NotificationChannel{{Name: "phone", Type: NotifyNtfy, URL: "https://ntfy.sh/sdbx"}}, false},
+		{"invalid repeat", AlertsConfig{Repeat: "daily"}, nil, true},
+		{"unknown type", AlertsConfig{Rules: []AlertRule{{Name: "cpu", Type: "cpu_usage"}}}, nil, true},
+		{"duplicate rule", AlertsConfig{Rules: []AlertRule{{Name: "down", Type: AlertContainerDown}, {Name: "down", Type: AlertContainerDown}}}, nil, true},
+		{"invalid for", AlertsConfig{Rules: []AlertRule{{Name: "down", Type: AlertContainerDown, For: "5 minutes"}}}, nil, true},
+		{"disk without threshold", AlertsConfig{Rules: []AlertRule{{Name: "disk", Type: AlertDiskUsage}}}, nil, true},
+		{"backup without days", AlertsConfig{Rules: []AlertRule{{Name: "backup", Type: AlertBackupAge}}}, nil, true},
+		{"unknown channel type", AlertsConfig{}, []NotificationChannel{{Name: "mail", Type: "smtp", URL: "https://example.com"}}, true},
+		{"invalid channel url", AlertsConfig{}, []NotificationChannel{{Name: "hook", Type: NotifyWebhook, URL: "example.com/hook"}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultConfig()
+			cfg.Alerts = tt.alerts
+			cfg.Notifications.Channels = tt.channels
+			err := cfg.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestServiceRefs(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.AddInstance("sonarr4k", "sonarr")
+
+	tests := []struct {
+		ref     string
+		want    string
+		wantErr bool
+	}{
+		{"sonarr", "sonarr", false},
+		{"sonarr4k", "sonarr4k", false},
+		{"sonarr@sonarr4k", "sonarr4k", false},
+		{"radarr@sonarr4k", "", true},
+		{"sonarr@missing", "", true},
+	}
+	for _, tt := range tests {
+		got, err := cfg.ServiceName(tt.ref)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ServiceName(%q) = %q, %v; want %q, error %v", tt.ref, got, err, tt.want, tt.wantErr)
+		}
+	}
+
+	if got := cfg.ServiceRef("sonarr4k"); got != "sonarr@sonarr4k" {
+		t.Errorf("ServiceRef(sonarr4k) = %q, want sonarr@sonarr4k", got)
+	}
+	if got := cfg.ServiceRef("sonarr"); got != "sonarr" {
+		t.Errorf("ServiceRef(sonarr) = %q, want sonarr", got)
+	}
+}
+
+func TestComposeExtraValidation(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Services = map[string]ServiceOverride{
+		"sonarr": {ComposeExtra: map[string]interface{}{
+			"ulimits":     map[string]interface{}{"nofile": 65536},
+			"extra_hosts": []interface{}{"host.docker.internal:host-gateway"},
+			"x-note":      "kept",
+		}},
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Validate() error = %v", err)
+	}
+
+	cfg.Services["sonarr"] = ServiceOverride{ComposeExtra: map[string]interface{}{"ulimit": 1}}
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error for unknown compose property")
+	}
+}
+
+func TestLoggingValidation(t *testing.T) {
+	tests := []struct {
+		name    string
+		logging LoggingConfig
+		wantErr bool
+	}{
+		{"default", DefaultConfig().Logging, false},
+		{"docker default", LoggingConfig{}, false},
+		{"local", LoggingConfig{Driver: LogDriverLocal, MaxSize: "1g", MaxFile: 2}, false},
+		{"unknown driver", LoggingConfig{Driver: "fluentd"}, true},
+		{"bad size", LoggingConfig{Driver: LogDriverJSONFile, MaxSize: "10MB"}, true},
+		{"negative files", LoggingConfig{Driver: LogDriverJSONFile, MaxFile: -1}, true},
+		{"loki without url", LoggingConfig{Driver: LogDriverLoki}, true},
+		{"loki", LoggingConfig{Driver: LogDriverLoki, Options: map[string]string{"loki-url": "http://loki:3100"}}, false},
+		{"vector to loki addon", LoggingConfig{Aggregation: &LogAggregationConfig{Agent: LogAgentVector}}, false},
+		{"promtail to external loki", LoggingConfig{Aggregation: &LogAggregationConfig{Agent: LogAgentPromtail, Endpoint: "https://logs.example.com"}}, false},
+		{"unknown agent", LoggingConfig{Aggregation: &LogAggregationConfig{Agent: "fluentbit"}}, true},
+		{"bad endpoint", LoggingConfig{Aggregation: &LogAggregationConfig{Agent: LogAgentVector, Endpoint: "loki:3100"}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultConfig()
+			cfg.Logging = tt.logging
+			err := cfg.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLogAggregationOverrideRejected(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Services = map[string]ServiceOverride{
+		"plex": {Logging: &LoggingConfig{Aggregation: &LogAggregationConfig{Agent: LogAgentVector}}},
+	}
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error for aggregation in a service override")
+	}
+
+	agg := LogAggregationConfig{Agent: LogAgentVector}
+	if got := agg.LokiEndpoint(); got != DefaultLokiEndpoint {
+		t.Errorf("LokiEndpoint() = %q, want the loki addon", got)
+	}
+	agg.Endpoint = "http://loki.lan:3100/"
+	if got := agg.LokiEndpoint(); got != "http://loki.lan:3100" {
+		t.Errorf("LokiEndpoint() = %q", got)
+	}
+}
+
+func TestUpdatePolicy(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Services = map[string]ServiceOverride{"plex": {UpdatePolicy: UpdatePolicyPinned}}
+
+	if got := cfg.UpdatePolicy("plex"); got != UpdatePolicyPinned {
+		t.Errorf("UpdatePolicy(plex) = %q", got)
+	}
+	if got := cfg.UpdatePolicy("sonarr"); got != UpdatePolicyAuto {
+		t.Errorf("UpdatePolicy(sonarr) = %q, want auto by default", got)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Validate() error = %v", err)
+	}
+
+	cfg.Services["plex"] = ServiceOverride{UpdatePolicy: "never"}
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error for unknown update policy")
+	}
+}
+
+func TestPlatform(t *testing.T) {
+	cfg := DefaultConfig()
+	if got := cfg.TargetPlatform(); got != HostPlatform() {
+		t.Errorf("TargetPlatform() = %q, want host platform %q", got, HostPlatform())
+	}
+
+	cfg.Platform = "linux/arm64"
+	cfg.Services = map[string]ServiceOverride{"plex": {Platform: "linux/amd64"}}
+	if got := cfg.TargetPlatform(); got != "linux/arm64" {
+		t.Errorf("TargetPlatform() = %q, want linux/arm64", got)
+	}
+	if got := cfg.ServicePlatform("plex"); got != "linux/amd64" {
+		t.Errorf("ServicePlatform(plex) = %q", got)
+	}
+	if got := cfg.ServicePlatform("sonarr"); got != "" {
+		t.Errorf("ServicePlatform(sonarr) = %q, want empty", got)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Validate() error = %v", err)
+	}
+
+	cfg.Platform = "arm64"
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error for platform without OS")
+	}
+	cfg.Platform = ""
+	cfg.Services["plex"] = ServiceOverride{Platform: "Linux/AMD64"}
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error for malformed service platform")
+	}
+}
+
+func TestResourceLimitsValidation(t *testing.T) {
+	tests := []struct {
+		limits  ResourceLimits
+		wantErr bool
+	}{
+		{ResourceLimits{CPUs: "1.5", Memory: "512m"}, false},
+		{ResourceLimits{Memory: "2g"}, false},
+		{ResourceLimits{CPUs: "0"}, true},
+		{ResourceLimits{CPUs: "two"}, true},
+		{ResourceLimits{Memory: "1GB"}, true},
+	}
+
+	for _, tt := range tests {
+		cfg := DefaultConfig()
+		cfg.Services = map[string]ServiceOverride{"plex": {Resources: &tt.limits}}
+		if err := cfg.Validate(); (err != nil) != tt.wantErr {
+			t.Errorf("Validate(%+v) error = %v, wantErr %v", tt.limits, err, tt.wantErr)
+		}
+	}
+}
+
+func TestLockIntegrityValidation(t *testing.T) {
+	for _, mode := range []string{"", LockIntegrityWarn, LockIntegrityFail, LockIntegrityOff} {
+		cfg := DefaultConfig()
+		cfg.LockIntegrity = mode
+		if err := cfg.Validate(); err != nil {
+			t.Errorf("Validate(lock_integrity=%q) error = %v", mode, err)
+		}
+	}
+
+	cfg := DefaultConfig()
+	cfg.LockIntegrity = "strict"
+	if err := cfg.Validate(); err == nil {
+		t.Error("Validate(lock_integrity=strict) should fail")
+	}
+}
+
+func TestAuthModeValidation(t *testing.T) {
+	for _, mode := range []string{"", AuthModeAuthelia, AuthModeBasic} {
+		cfg := DefaultConfig()
+		cfg.Auth.Mode = mode
+		if err := cfg.Validate(); err != nil {
+			t.Errorf("Validate(auth.mode=%q) error = %v", mode, err)
+		}
+	}
+
+	cfg := DefaultConfig()
+	cfg.Auth.Mode = "ldap"
+	if err := cfg.Validate(); err == nil {
+		t.Error("Validate(auth.mode=ldap) should fail")
+	}
+	if cfg.Auth.Mode = ""; cfg.AuthMode() != AuthModeAuthelia {
+		t.Errorf("AuthMode() = %q, want authelia by default", cfg.AuthMode())
+	}
+}
+
+func TestSecretDelivery(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.SecretDelivery = SecretDeliveryEnvFile
+	cfg.Services = map[string]ServiceOverride{"plex": {SecretDelivery: SecretDeliveryEnv}}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+	if got := cfg.ServiceSecretDelivery("plex"); got != SecretDeliveryEnv {
+		t.Errorf("ServiceSecretDelivery(plex) = %q, want env", got)
+	}
+	if got := cfg.ServiceSecretDelivery("cloudflared"); got != SecretDeliveryEnvFile {
+		t.Errorf("ServiceSecretDelivery(cloudflared) = %q, want env_file", got)
+	}
+	if got := (&Config{}).ServiceSecretDelivery("plex"); got != SecretDeliveryAuto {
+		t.Errorf("ServiceSecretDelivery() without config = %q, want auto", got)
+	}
+
+	cfg.SecretDelivery = "vault"
+	if err := cfg.Validate(); err == nil {
+		t.Error("Validate(secret_delivery=vault) should fail")
+	}
+	cfg.SecretDelivery = ""
+	cfg.Services["plex"] = ServiceOverride{SecretDelivery: "vault"}
+	if err := cfg.Validate(); err == nil {
+		t.Error("Validate(services.plex.secret_delivery=vault) should fail")
+	}
+}
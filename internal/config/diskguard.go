@@ -0,0 +1,93 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultDiskGuardInterval is how often free space is checked when
+// disk_guard.interval is unset
+const DefaultDiskGuardInterval = time.Minute
+
+// DiskGuardConfig pauses the download clients when free space on the
+// downloads path runs low, and resumes them once space is freed
+type DiskGuardConfig struct {
+	MinFree    string `mapstructure:"min_free" yaml:"min_free"`                 // Pause below this much free space (e.g. 20G)
+	ResumeFree string `mapstructure:"resume_free" yaml:"resume_free,omitempty"` // Resume at this much free space (default 10% above min_free)
+	Path       string `mapstructure:"path" yaml:"path,omitempty"`               // Filesystem to watch (default downloads_path)
+	Interval   string `mapstructure:"interval" yaml:"interval,omitempty"`       // Time between checks (default 1m)
+}
+
+// IsEnabled reports whether the disk guard is configured
+func (d DiskGuardConfig) IsEnabled() bool {
+	return d.MinFree != ""
+}
+
+// Thresholds returns the pause and resume free space in bytes
+func (d DiskGuardConfig) Thresholds() (minFree, resumeFree int64) {
+	minFree, _ = ParseByteSize(d.MinFree)
+	resumeFree, err := ParseByteSize(d.ResumeFree)
+	if err != nil || resumeFree < minFree {
+		resumeFree = minFree + minFree/10
+	}
+	return minFree, resumeFree
+}
+
+// CheckInterval returns the parsed Interval, defaulting to DefaultDiskGuardInterval
+func (d DiskGuardConfig) CheckInterval() time.Duration {
+	if i, err := time.ParseDuration(d.Interval); err == nil && i > 0 {
+		return i
+	}
+	return DefaultDiskGuardInterval
+}
+
+// byteSizeRegex matches upper-cased sizes such as 500M, 20G or 1.5TIB
+var byteSizeRegex = regexp.MustCompile(`^([0-9]+(?:\.[0-9]+)?)\s*([KMGT]?)I?B?$`)
+
+// byteSizeUnits are binary multiples, as df -h shows them
+var byteSizeUnits = map[string]float64{"": 1, "K": 1 << 10, "M": 1 << 20, "G": 1 << 30, "T": 1 << 40}
+
+// ParseByteSize converts sizes such as 500M, 20G, 20GB or 1.5TiB to bytes
+func ParseByteSize(s string) (int64, error) {
+	m := byteSizeRegex.FindStringSubmatch(strings.ToUpper(strings.TrimSpace(s)))
+	if m == nil {
+		return 0, fmt.Errorf("invalid size %q (e.g. 20G)", s)
+	}
+	value, err := strconv.ParseFloat(m[1], 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid size %q (e.g. 20G)", s)
+	}
+	return int64(value * byteSizeUnits[m[2]]), nil
+}
+
+// validateDiskGuard checks the disk guard thresholds
+func validateDiskGuard(d DiskGuardConfig) error {
+	if !d.IsEnabled() {
+		if d.ResumeFree != "" || d.Path != "" {
+			return NewValidationError("disk_guard.min_free", "is required")
+		}
+		return nil
+	}
+	minFree, err := ParseByteSize(d.MinFree)
+	if err != nil || minFree <= 0 {
+		return NewValidationError("disk_guard.min_free", fmt.Sprintf("invalid size %q (e.g. 20G)", d.MinFree))
+	}
+	if d.ResumeFree != "" {
+		resumeFree, err := ParseByteSize(d.ResumeFree)
+		if err != nil {
+			return NewValidationError("disk_guard.resume_free", err.Error())
+		}
+		if resumeFree < minFree {
+			return NewValidationError("disk_guard.resume_free", "must not be below min_free")
+		}
+	}
+	if d.Interval != "" {
+		if i, err := time.ParseDuration(d.Interval); err != nil || i < 10*time.Second {
+			return NewValidationError("disk_guard.interval", fmt.Sprintf("invalid duration %q (at least 10s, e.g. 1m)", d.Interval))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package config
+
+import "testing"
+
+func TestParseByteSize(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    int64
+		wantErr bool
+	}{
+		{"500M", 500 << 20, false},
+		{"20G", 20 << 30, false},
+		{"20GB", 20 << 30, false},
+		{"1.5TiB", 3 << 39, false},
+		{"20g", 20 << 30, false},
+		{"1024", 1024, false},
+		{"", 0, true},
+		{"20 gigs", 0, true},
+		{"-5G", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseByteSize(tt.input)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseByteSize(%q) = %d, %v, want %d (error %v)", tt.input, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestValidateDiskGuard(t *testing.T) {
+	tests := []struct {
+		name    string
+		guard   DiskGuardConfig
+		wantErr bool
+	}{
+		{"disabled", DiskGuardConfig{}, false},
+		{"min free", DiskGuardConfig{MinFree: "20G"}, false},
+		{"all fields", DiskGuardConfig{MinFree: "20G", ResumeFree: "50G", Path: "/mnt/downloads", Interval: "30s"}, false},
+		{"bad size", DiskGuardConfig{MinFree: "lots"}, true},
+		{"resume below min", DiskGuardConfig{MinFree: "20G", ResumeFree: "10G"}, true},
+		{"short interval", DiskGuardConfig{MinFree: "20G", Interval: "1s"}, true},
+		{"path without min free", DiskGuardConfig{Path: "/mnt/downloads"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateDiskGuard(tt.guard)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateDiskGuard() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDiskGuardThresholds(t *testing.T) {
+	minFree, resumeFree := DiskGuardConfig{MinFree: "10G"}.Thresholds()
+	if minFree != 10<<30 || resumeFree != 11<<30 {
+		t.Errorf("Thresholds() = %d, %d, want resume 10%% above min", minFree, resumeFree)
+	}
+	if _, resumeFree := (DiskGuardConfig{MinFree: "10G", ResumeFree: "40G"}).Thresholds(); resumeFree != 40<<30 {
+		t.Errorf("resume_free = %d, want 40G", resumeFree)
+	}
+}
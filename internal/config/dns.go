@@ -0,0 +1,90 @@
+package config
+
+import (
+	"fmt"
+	"net/netip"
+	"slices"
+)
+
+// Local DNS servers of the dns section
+const (
+	DNSServerPihole  = "pihole"
+	DNSServerAdGuard = "adguard"
+)
+
+// DNSServers lists the supported dns.server values
+var DNSServers = []string{DNSServerPihole, DNSServerAdGuard}
+
+// DefaultDNSUpstreams are the resolvers the local DNS server forwards to
+// when dns.upstreams is unset
+var DefaultDNSUpstreams = []string{"1.1.1.1", "9.9.9.9"}
+
+// DNSConfig runs a local DNS server resolving the domain and its subdomains
+// to the host, so LAN installs work without public records or /etc/hosts
+// entries. Services of the stack resolve through it too.
+type DNSConfig struct {
+	Server      string   `mapstructure:"server" yaml:"server,omitempty"`             // pihole or adguard; empty disables the DNS server
+	Address     string   `mapstructure:"address" yaml:"address,omitempty"`           // LAN address of the host: *.domain resolves to it, and port 53 is published on it
+	IPv4Address string   `mapstructure:"ipv4_address" yaml:"ipv4_address,omitempty"` // Address of the DNS container on the proxy network; default the last usable of networks.proxy.subnet
+	Upstreams   []string `mapstructure:"upstreams" yaml:"upstreams,omitempty"`       // Resolvers for other names (default 1.1.1.1, 9.9.9.9)
+}
+
+// IsEnabled reports whether a local DNS server runs
+func (d DNSConfig) IsEnabled() bool {
+	return d.Server != ""
+}
+
+// UpstreamServers returns the configured upstream resolvers, or DefaultDNSUpstreams
+func (d DNSConfig) UpstreamServers() []string {
+	if len(d.Upstreams) > 0 {
+		return d.Upstreams
+	}
+	return DefaultDNSUpstreams
+}
+
+// ContainerAddress returns the address of the DNS container on the proxy
+// network: ipv4_address, or the last usable address of the subnet, which
+// Docker hands out to other containers last
+func (d DNSConfig) ContainerAddress(proxy NetworkConfig) (netip.Addr, error) {
+	subnet, err := proxy.Prefix()
+	if err != nil {
+		return netip.Addr{}, err
+	}
+	if d.IPv4Address == "" {
+		return BroadcastAddr(subnet).Prev(), nil
+	}
+	addr, err := netip.ParseAddr(d.IPv4Address)
+	if err != nil || !addr.Is4() {
+		return netip.Addr{}, fmt.Errorf("invalid IPv4 address %q", d.IPv4Address)
+	}
+	if !subnet.Contains(addr) {
+		return netip.Addr{}, fmt.Errorf("%s is outside networks.proxy.subnet %s", addr, subnet)
+	}
+	return addr, nil
+}
+
+// validateDNS checks the DNS server settings against the proxy subnet its
+// container gets a static address in
+func validateDNS(d DNSConfig, networks map[string]NetworkConfig) error {
+	if !d.IsEnabled() {
+		return nil
+	}
+	if !slices.Contains(DNSServers, d.Server) {
+		return NewValidationError("dns.server", fmt.Sprintf("unknown DNS server %q (one of %v)", d.Server, DNSServers))
+	}
+	if addr, err := netip.ParseAddr(d.Address); err != nil || !addr.Is4() {
+		return NewValidationError("dns.address", fmt.Sprintf("the LAN IPv4 address of the host is required, got %q", d.Address))
+	}
+	for _, upstream := range d.Upstreams {
+		if _, err := netip.ParseAddr(upstream); err != nil {
+			return NewValidationError("dns.upstreams", fmt.Sprintf("invalid resolver address %q", upstream))
+		}
+	}
+	if networks["proxy"].Subnet == "" {
+		return NewValidationError("networks.proxy.subnet", "required by dns: the DNS container gets a static address in it")
+	}
+	if _, err := d.ContainerAddress(networks["proxy"]); err != nil {
+		return NewValidationError("dns.ipv4_address", err.Error())
+	}
+	return nil
+}
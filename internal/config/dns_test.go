@@ -0,0 +1,49 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateDNS(t *testing.T) {
+	proxy := map[string]NetworkConfig{"proxy": {Subnet: "172.30.0.0/24"}}
+	tests := []struct {
+		name     string
+		dns      DNSConfig
+		networks map[string]NetworkConfig
+		wantErr  string
+	}{
+		{"disabled", DNSConfig{}, nil, ""},
+		{"pihole", DNSConfig{Server: DNSServerPihole, Address: "192.168.1.10"}, proxy, ""},
+		{"unknown server", DNSConfig{Server: "bind", Address: "192.168.1.10"}, proxy, "unknown DNS server"},
+		{"no address", DNSConfig{Server: DNSServerAdGuard}, proxy, "dns.address"},
+		{"bad upstream", DNSConfig{Server: DNSServerPihole, Address: "192.168.1.10", Upstreams: []string{"dns.google"}}, proxy, "dns.upstreams"},
+		{"no subnet", DNSConfig{Server: DNSServerPihole, Address: "192.168.1.10"}, nil, "networks.proxy.subnet"},
+		{"address outside subnet", DNSConfig{Server: DNSServerPihole, Address: "192.168.1.10", IPv4Address: "172.31.0.53"}, proxy, "outside"},
+	}
+	for _, tt := range tests {
+		err := validateDNS(tt.dns, tt.networks)
+		if tt.wantErr == "" {
+			if err != nil {
+				t.Errorf("%s: validateDNS() = %v", tt.name, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("%s: validateDNS() = %v, want %q", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestDNSContainerAddress(t *testing.T) {
+	proxy := NetworkConfig{Subnet: "172.30.0.0/24"}
+	if addr, err := (DNSConfig{}).ContainerAddress(proxy); err != nil || addr.String() != "172.30.0.254" {
+		t.Errorf("ContainerAddress() = %v, %v, want the last usable address", addr, err)
+	}
+	if addr, err := (DNSConfig{IPv4Address: "172.30.0.53"}).ContainerAddress(proxy); err != nil || addr.String() != "172.30.0.53" {
+		t.Errorf("ContainerAddress() = %v, %v, want ipv4_address", addr, err)
+	}
+	if got := (DNSConfig{}).UpstreamServers(); strings.Join(got, ",") != "1.1.1.1,9.9.9.9" {
+		t.Errorf("UpstreamServers() = %v, want the defaults", got)
+	}
+}
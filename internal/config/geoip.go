@@ -0,0 +1,88 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"time"
+)
+
+// DefaultGeoIPRefresh is how old the GeoIP databases get before they are
+// downloaded again; MaxMind publishes GeoLite updates twice a week
+const DefaultGeoIPRefresh = 72 * time.Hour
+
+// minGeoIPRefresh keeps the refresh within MaxMind's daily download limit
+const minGeoIPRefresh = 24 * time.Hour
+
+// GeoIPLicenseKeySecret is the secret holding the MaxMind license key
+// (secrets/maxmind_license_key.txt), which the user fills in
+const GeoIPLicenseKeySecret = "maxmind_license_key"
+
+// DefaultGeoIPEditions are the databases downloaded when geoip.editions is unset
+var DefaultGeoIPEditions = []string{"GeoLite2-ASN", "GeoLite2-City", "GeoLite2-Country"}
+
+// GeoIPCountryEditions are the databases holding the country of an address,
+// in the order the country allowlists prefer them
+var GeoIPCountryEditions = []string{"GeoIP2-Country", "GeoLite2-Country", "GeoIP2-City", "GeoLite2-City"}
+
+// GeoIPConfig downloads the MaxMind GeoLite databases for the services
+// whose definition declares requires: geoip
+type GeoIPConfig struct {
+	AccountID string   `mapstructure:"account_id" yaml:"account_id"`       // MaxMind account ID; the license key is read from secrets/maxmind_license_key.txt
+	Editions  []string `mapstructure:"editions" yaml:"editions,omitempty"` // Databases to download (default GeoLite2-ASN, GeoLite2-City, GeoLite2-Country)
+	Refresh   string   `mapstructure:"refresh" yaml:"refresh,omitempty"`   // Age at which they are downloaded again (default 72h)
+}
+
+// IsEnabled reports whether GeoIP databases are downloaded
+func (g GeoIPConfig) IsEnabled() bool {
+	return g.AccountID != ""
+}
+
+// EditionIDs returns the databases to download, defaulting to DefaultGeoIPEditions
+func (g GeoIPConfig) EditionIDs() []string {
+	if len(g.Editions) == 0 {
+		return DefaultGeoIPEditions
+	}
+	return g.Editions
+}
+
+// HasCountries reports whether one of the databases downloaded holds countries
+func (g GeoIPConfig) HasCountries() bool {
+	return g.IsEnabled() && slices.ContainsFunc(g.EditionIDs(), func(edition string) bool {
+		return slices.Contains(GeoIPCountryEditions, edition)
+	})
+}
+
+// RefreshInterval returns the parsed Refresh, defaulting to DefaultGeoIPRefresh
+func (g GeoIPConfig) RefreshInterval() time.Duration {
+	if d, err := time.ParseDuration(g.Refresh); err == nil && d > 0 {
+		return d
+	}
+	return DefaultGeoIPRefresh
+}
+
+var (
+	geoIPAccountRegex = regexp.MustCompile(`^[0-9]+$`)
+	geoIPEditionRegex = regexp.MustCompile(`^[A-Za-z0-9-]+$`)
+)
+
+// validateGeoIP checks the geoip section
+func validateGeoIP(g GeoIPConfig) error {
+	if g.AccountID == "" && len(g.Editions) == 0 && g.Refresh == "" {
+		return nil
+	}
+	if !geoIPAccountRegex.MatchString(g.AccountID) {
+		return NewValidationError("geoip.account_id", "must be the numeric ID of your MaxMind account")
+	}
+	for i, edition := range g.Editions {
+		if !geoIPEditionRegex.MatchString(edition) {
+			return NewValidationError(fmt.Sprintf("geoip.editions[%d]", i), fmt.Sprintf("invalid edition %q (e.g. GeoLite2-City)", edition))
+		}
+	}
+	if g.Refresh != "" {
+		if d, err := time.ParseDuration(g.Refresh); err != nil || d < minGeoIPRefresh {
+			return NewValidationError("geoip.refresh", fmt.Sprintf("invalid duration %q - must be at least %s", g.Refresh, minGeoIPRefresh))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,36 @@
+package config
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestValidateGeoIP(t *testing.T) {
+	tests := []struct {
+		name    string
+		geoip   GeoIPConfig
+		wantErr bool
+	}{
+		{"unset", GeoIPConfig{}, false},
+		{"valid", GeoIPConfig{AccountID: "123456", Editions: []string{"GeoLite2-City"}, Refresh: "168h"}, false},
+		{"without account", GeoIPConfig{Editions: []string{"GeoLite2-City"}}, true},
+		{"bad account", GeoIPConfig{AccountID: "me@example.com"}, true},
+		{"bad edition", GeoIPConfig{AccountID: "123456", Editions: []string{"../City"}}, true},
+		{"refresh too short", GeoIPConfig{AccountID: "123456", Refresh: "1h"}, true},
+		{"bad refresh", GeoIPConfig{AccountID: "123456", Refresh: "weekly"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateGeoIP(tt.geoip); (err != nil) != tt.wantErr {
+				t.Errorf("validateGeoIP() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestGeoIPConfigDefaults(t *testing.T) {
+	var g GeoIPConfig
+	if g.IsEnabled() || !slices.Equal(g.EditionIDs(), DefaultGeoIPEditions) || g.RefreshInterval() != DefaultGeoIPRefresh {
+		t.Errorf("%+v: IsEnabled() = %v, EditionIDs() = %v, RefreshInterval() = %s", g, g.IsEnabled(), g.EditionIDs(), g.RefreshInterval())
+	}
+}
@@ -0,0 +1,44 @@
+package config
+
+import (
+	"fmt"
+	"net/netip"
+)
+
+// MDNSDomain is the domain names advertised over multicast DNS end with
+const MDNSDomain = "local"
+
+// MDNSConfig advertises the routed services as <subdomain>.local over
+// multicast DNS, so LAN mode works without DNS records for the domain.
+// Traefik routes the .local names next to the domain ones.
+type MDNSConfig struct {
+	Enabled bool   `mapstructure:"enabled" yaml:"enabled,omitempty"`
+	Address string `mapstructure:"address" yaml:"address,omitempty"` // IPv4 the names resolve to; default the host's address on the interface a query arrives on
+}
+
+// IsEnabled reports whether services are advertised over mDNS
+func (m MDNSConfig) IsEnabled() bool {
+	return m.Enabled
+}
+
+// MDNSHost returns the .local name of a subdomain
+func MDNSHost(subdomain string) string {
+	return subdomain + "." + MDNSDomain
+}
+
+// validateMDNS checks mDNS is only enabled in LAN mode, where Traefik serves
+// plain HTTP that needs no certificate for the .local names
+func validateMDNS(m MDNSConfig, exposeMode string) error {
+	if !m.IsEnabled() {
+		return nil
+	}
+	if exposeMode != ExposeModeLAN {
+		return NewValidationError("mdns.enabled", fmt.Sprintf("mDNS needs expose.mode lan, got %q", exposeMode))
+	}
+	if m.Address != "" {
+		if addr, err := netip.ParseAddr(m.Address); err != nil || !addr.Is4() {
+			return NewValidationError("mdns.address", fmt.Sprintf("invalid IPv4 address %q", m.Address))
+		}
+	}
+	return nil
+}
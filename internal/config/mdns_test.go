@@ -0,0 +1,33 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateMDNS(t *testing.T) {
+	tests := []struct {
+		name    string
+		mdns    MDNSConfig
+		mode    string
+		wantErr string
+	}{
+		{"disabled", MDNSConfig{}, ExposeModeDirect, ""},
+		{"lan", MDNSConfig{Enabled: true}, ExposeModeLAN, ""},
+		{"address", MDNSConfig{Enabled: true, Address: "192.168.1.10"}, ExposeModeLAN, ""},
+		{"not lan", MDNSConfig{Enabled: true}, ExposeModeCloudflared, "expose.mode lan"},
+		{"bad address", MDNSConfig{Enabled: true, Address: "nas.lan"}, ExposeModeLAN, "mdns.address"},
+	}
+	for _, tt := range tests {
+		err := validateMDNS(tt.mdns, tt.mode)
+		if tt.wantErr == "" {
+			if err != nil {
+				t.Errorf("%s: validateMDNS() = %v", tt.name, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("%s: validateMDNS() = %v, want %q", tt.name, err, tt.wantErr)
+		}
+	}
+}
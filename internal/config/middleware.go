@@ -0,0 +1,105 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+)
+
+// MiddlewareDefinition declares a Traefik middleware rendered into the dynamic
+// configuration, so routers can reference it by name. Option names follow
+// Traefik's; exactly one kind is set.
+type MiddlewareDefinition struct {
+	RateLimit      *RateLimitMiddleware      `mapstructure:"rateLimit" yaml:"rateLimit,omitempty"`
+	Headers        *HeadersMiddleware        `mapstructure:"headers" yaml:"headers,omitempty"`
+	Compress       *CompressMiddleware       `mapstructure:"compress" yaml:"compress,omitempty"`
+	RedirectScheme *RedirectSchemeMiddleware `mapstructure:"redirectScheme" yaml:"redirectScheme,omitempty"`
+	RedirectRegex  *RedirectRegexMiddleware  `mapstructure:"redirectRegex" yaml:"redirectRegex,omitempty"`
+}
+
+// RateLimitMiddleware allows Average requests per Period, with bursts up to Burst
+type RateLimitMiddleware struct {
+	Average int    `mapstructure:"average" yaml:"average"`
+	Period  string `mapstructure:"period" yaml:"period,omitempty"` // Defaults to 1s
+	Burst   int    `mapstructure:"burst" yaml:"burst,omitempty"`
+}
+
+// HeadersMiddleware sets request/response headers and common security headers
+type HeadersMiddleware struct {
+	CustomRequestHeaders  map[string]string `mapstructure:"customRequestHeaders" yaml:"customRequestHeaders,omitempty"`
+	CustomResponseHeaders map[string]string `mapstructure:"customResponseHeaders" yaml:"customResponseHeaders,omitempty"`
+	STSSeconds            int               `mapstructure:"stsSeconds" yaml:"stsSeconds,omitempty"`
+	STSIncludeSubdomains  bool              `mapstructure:"stsIncludeSubdomains" yaml:"stsIncludeSubdomains,omitempty"`
+	FrameDeny             bool              `mapstructure:"frameDeny" yaml:"frameDeny,omitempty"`
+	ContentTypeNosniff    bool              `mapstructure:"contentTypeNosniff" yaml:"contentTypeNosniff,omitempty"`
+	ReferrerPolicy        string            `mapstructure:"referrerPolicy" yaml:"referrerPolicy,omitempty"`
+	ContentSecurityPolicy string            `mapstructure:"contentSecurityPolicy" yaml:"contentSecurityPolicy,omitempty"`
+}
+
+// CompressMiddleware compresses responses
+type CompressMiddleware struct {
+	ExcludedContentTypes []string `mapstructure:"excludedContentTypes" yaml:"excludedContentTypes,omitempty"`
+	MinResponseBodyBytes int      `mapstructure:"minResponseBodyBytes" yaml:"minResponseBodyBytes,omitempty"`
+}
+
+// RedirectSchemeMiddleware redirects to another scheme (usually https)
+type RedirectSchemeMiddleware struct {
+	Scheme    string `mapstructure:"scheme" yaml:"scheme"`
+	Port      string `mapstructure:"port" yaml:"port,omitempty"`
+	Permanent bool   `mapstructure:"permanent" yaml:"permanent,omitempty"`
+}
+
+// RedirectRegexMiddleware redirects URLs matching Regex to Replacement
+type RedirectRegexMiddleware struct {
+	Regex       string `mapstructure:"regex" yaml:"regex"`
+	Replacement string `mapstructure:"replacement" yaml:"replacement"`
+	Permanent   bool   `mapstructure:"permanent" yaml:"permanent,omitempty"`
+}
+
+// middlewareNameRegex matches names usable in router labels (no @provider suffix)
+var middlewareNameRegex = regexp.MustCompile(`^[a-z0-9][a-z0-9-]*$`)
+
+// ValidateMiddlewareName checks a middleware definition name
+func ValidateMiddlewareName(name string) error {
+	if !middlewareNameRegex.MatchString(name) {
+		return fmt.Errorf("invalid middleware name %q (lowercase letters, digits and dashes)", name)
+	}
+	return nil
+}
+
+// Validate checks that exactly one kind is set and its options are usable
+func (m MiddlewareDefinition) Validate() error {
+	kinds := 0
+	for _, set := range []bool{m.RateLimit != nil, m.Headers != nil, m.Compress != nil, m.RedirectScheme != nil, m.RedirectRegex != nil} {
+		if set {
+			kinds++
+		}
+	}
+	if kinds != 1 {
+		return fmt.Errorf("exactly one of rateLimit, headers, compress, redirectScheme, redirectRegex must be set")
+	}
+
+	switch {
+	case m.RateLimit != nil:
+		if m.RateLimit.Average <= 0 || m.RateLimit.Burst < 0 {
+			return fmt.Errorf("rateLimit.average must be positive and rateLimit.burst cannot be negative")
+		}
+		if m.RateLimit.Period != "" {
+			if d, err := time.ParseDuration(m.RateLimit.Period); err != nil || d <= 0 {
+				return fmt.Errorf("invalid rateLimit.period %q (e.g. 1s, 1m)", m.RateLimit.Period)
+			}
+		}
+	case m.RedirectScheme != nil:
+		if m.RedirectScheme.Scheme != "http" && m.RedirectScheme.Scheme != "https" {
+			return fmt.Errorf("redirectScheme.scheme must be http or https")
+		}
+	case m.RedirectRegex != nil:
+		if m.RedirectRegex.Replacement == "" {
+			return fmt.Errorf("redirectRegex.replacement is required")
+		}
+		if _, err := regexp.Compile(m.RedirectRegex.Regex); err != nil || m.RedirectRegex.Regex == "" {
+			return fmt.Errorf("invalid redirectRegex.regex %q", m.RedirectRegex.Regex)
+		}
+	}
+	return nil
+}
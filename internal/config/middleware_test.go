@@ -0,0 +1,38 @@
+package config
+
+import "testing"
+
+func TestMiddlewareDefinitionValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		mw      MiddlewareDefinition
+		wantErr bool
+	}{
+		{"rate limit", MiddlewareDefinition{RateLimit: &RateLimitMiddleware{Average: 50, Period: "1m", Burst: 100}}, false},
+		{"headers", MiddlewareDefinition{Headers: &HeadersMiddleware{FrameDeny: true}}, false},
+		{"compress", MiddlewareDefinition{Compress: &CompressMiddleware{}}, false},
+		{"redirect scheme", MiddlewareDefinition{RedirectScheme: &RedirectSchemeMiddleware{Scheme: "https", Permanent: true}}, false},
+		{"redirect regex", MiddlewareDefinition{RedirectRegex: &RedirectRegexMiddleware{Regex: "^/old/(.*)", Replacement: "/new/${1}"}}, false},
+		{"empty", MiddlewareDefinition{}, true},
+		{"two kinds", MiddlewareDefinition{Compress: &CompressMiddleware{}, Headers: &HeadersMiddleware{}}, true},
+		{"zero rate", MiddlewareDefinition{RateLimit: &RateLimitMiddleware{}}, true},
+		{"bad period", MiddlewareDefinition{RateLimit: &RateLimitMiddleware{Average: 10, Period: "minute"}}, true},
+		{"bad scheme", MiddlewareDefinition{RedirectScheme: &RedirectSchemeMiddleware{Scheme: "ftp"}}, true},
+		{"bad regex", MiddlewareDefinition{RedirectRegex: &RedirectRegexMiddleware{Regex: "(", Replacement: "/"}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.mw.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	cfg := DefaultConfig()
+	cfg.Traefik.MiddlewareDefinitions = map[string]MiddlewareDefinition{"Bad_Name": {Compress: &CompressMiddleware{}}}
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error for invalid middleware name")
+	}
+}
@@ -0,0 +1,128 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v3"
+)
+
+// SchemaVersion is the .sdbx.yaml schema version written by this CLI
+const SchemaVersion = 2
+
+// Migration upgrades a raw .sdbx.yaml document to a schema version
+type Migration struct {
+	Version     int    // Schema version the migration produces
+	Description string // Shown by sdbx upgrade-project
+	Apply       func(raw map[string]interface{}) bool
+}
+
+// migrations are applied in order to documents older than their version
+var migrations = []Migration{
+	{
+		Version:     2,
+		Description: "expose_mode moved to expose.mode",
+		Apply:       migrateExposeMode,
+	},
+}
+
+// migrateExposeMode moves the legacy top-level expose_mode key into expose.mode
+func migrateExposeMode(raw map[string]interface{}) bool {
+	mode, ok := raw["expose_mode"]
+	if !ok {
+		return false
+	}
+	delete(raw, "expose_mode")
+
+	expose, _ := raw["expose"].(map[string]interface{})
+	if expose == nil {
+		expose = make(map[string]interface{})
+		raw["expose"] = expose
+	}
+	if _, set := expose["mode"]; !set {
+		expose["mode"] = mode
+	}
+	return true
+}
+
+// RawSchemaVersion returns the schema version of a raw document (1 when unset)
+func RawSchemaVersion(raw map[string]interface{}) int {
+	if v, ok := raw["schema_version"].(int); ok && v > 0 {
+		return v
+	}
+	return 1
+}
+
+// Migrate upgrades a raw document to SchemaVersion and returns the migrations
+// that changed it
+func Migrate(raw map[string]interface{}) []Migration {
+	from := RawSchemaVersion(raw)
+	var applied []Migration
+	for _, m := range migrations {
+		if m.Version > from && m.Apply(raw) {
+			applied = append(applied, m)
+		}
+	}
+	if from < SchemaVersion {
+		raw["schema_version"] = SchemaVersion
+	}
+	return applied
+}
+
+// FileSchemaVersion returns the schema version of a .sdbx.yaml file
+func FileSchemaVersion(path string) (int, error) {
+	raw, err := readRawConfig(path)
+	if err != nil {
+		return 0, err
+	}
+	return RawSchemaVersion(raw), nil
+}
+
+// readRawConfig parses a config file without applying defaults
+func readRawConfig(path string) (map[string]interface{}, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	raw := make(map[string]interface{})
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("invalid YAML in %s: %w", path, err)
+	}
+	return raw, nil
+}
+
+// migrateViperConfig rewrites the loaded viper config in memory when the file
+// uses an older schema; the file itself is only rewritten on the next generate
+func migrateViperConfig() ([]string, error) {
+	path := viper.ConfigFileUsed()
+	if path == "" {
+		return nil, nil
+	}
+	raw, err := readRawConfig(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if RawSchemaVersion(raw) >= SchemaVersion {
+		return nil, nil
+	}
+
+	applied := Migrate(raw)
+	data, err := yaml.Marshal(raw)
+	if err != nil {
+		return nil, err
+	}
+	if err := viper.ReadConfig(bytes.NewReader(data)); err != nil {
+		return nil, fmt.Errorf("failed to load migrated config: %w", err)
+	}
+
+	descriptions := make([]string, 0, len(applied))
+	for _, m := range applied {
+		descriptions = append(descriptions, m.Description)
+	}
+	return descriptions, nil
+}
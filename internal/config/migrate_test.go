@@ -0,0 +1,53 @@
+package config
+
+import "testing"
+
+func TestMigrate(t *testing.T) {
+	raw := map[string]interface{}{
+		"domain":      "example.com",
+		"expose_mode": "cloudflared",
+	}
+
+	applied := Migrate(raw)
+	if len(applied) != 1 || applied[0].Version != 2 {
+		t.Fatalf("applied = %+v, want the v2 expose_mode migration", applied)
+	}
+	if _, ok := raw["expose_mode"]; ok {
+		t.Error("expose_mode should be removed")
+	}
+	expose, _ := raw["expose"].(map[string]interface{})
+	if expose["mode"] != "cloudflared" {
+		t.Errorf("expose.mode = %v, want cloudflared", expose["mode"])
+	}
+	if RawSchemaVersion(raw) != SchemaVersion {
+		t.Errorf("schema_version = %d, want %d", RawSchemaVersion(raw), SchemaVersion)
+	}
+
+	// An explicit expose.mode wins over the legacy key
+	raw = map[string]interface{}{
+		"expose_mode": "direct",
+		"expose":      map[string]interface{}{"mode": "lan"},
+	}
+	Migrate(raw)
+	if raw["expose"].(map[string]interface{})["mode"] != "lan" {
+		t.Errorf("expose.mode = %v, want lan", raw["expose"])
+	}
+
+	// Current documents are left alone
+	raw = map[string]interface{}{"schema_version": SchemaVersion, "expose_mode": "direct"}
+	if applied := Migrate(raw); len(applied) != 0 {
+		t.Errorf("applied = %+v, want none", applied)
+	}
+	if _, ok := raw["expose_mode"]; !ok {
+		t.Error("current document should not be rewritten")
+	}
+}
+
+func TestRawSchemaVersion(t *testing.T) {
+	if v := RawSchemaVersion(map[string]interface{}{}); v != 1 {
+		t.Errorf("unset schema_version = %d, want 1", v)
+	}
+	if v := RawSchemaVersion(map[string]interface{}{"schema_version": 3}); v != 3 {
+		t.Errorf("schema_version = %d, want 3", v)
+	}
+}
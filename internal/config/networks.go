@@ -0,0 +1,70 @@
+package config
+
+import (
+	"fmt"
+	"maps"
+	"net/netip"
+	"slices"
+)
+
+// SubnetNetworks are the compose networks of the stack whose subnet can be set
+var SubnetNetworks = []string{"proxy", "backend", "downloads", "vpn"}
+
+// NetworkConfig pins the address range of a compose network, so services
+// can get a static address in it (networking.ipv4Address)
+type NetworkConfig struct {
+	Subnet string `mapstructure:"subnet" yaml:"subnet,omitempty"` // IPv4 CIDR, e.g. 172.30.0.0/24; Docker picks one when unset
+}
+
+// Prefix returns the parsed subnet
+func (n NetworkConfig) Prefix() (netip.Prefix, error) {
+	prefix, err := netip.ParsePrefix(n.Subnet)
+	if err != nil {
+		return netip.Prefix{}, fmt.Errorf("invalid subnet %q (e.g. 172.30.0.0/24)", n.Subnet)
+	}
+	if !prefix.Addr().Is4() {
+		return netip.Prefix{}, fmt.Errorf("subnet %q must be IPv4", n.Subnet)
+	}
+	if prefix != prefix.Masked() {
+		return netip.Prefix{}, fmt.Errorf("subnet %q has host bits set, use %s", n.Subnet, prefix.Masked())
+	}
+	if prefix.Bits() > 29 {
+		return netip.Prefix{}, fmt.Errorf("subnet %q is too small, use /29 or larger", n.Subnet)
+	}
+	return prefix, nil
+}
+
+// BroadcastAddr returns the last address of an IPv4 subnet
+func BroadcastAddr(subnet netip.Prefix) netip.Addr {
+	b := subnet.Masked().Addr().As4()
+	for i := range b {
+		hostBits := min(max(32-subnet.Bits()-8*(3-i), 0), 8)
+		b[i] |= byte(1<<hostBits - 1)
+	}
+	return netip.AddrFrom4(b)
+}
+
+// validateNetworks checks the subnets of the networks, which must not overlap
+func validateNetworks(networks map[string]NetworkConfig) error {
+	prefixes := make(map[string]netip.Prefix)
+	for _, name := range slices.Sorted(maps.Keys(networks)) {
+		field := "networks." + name
+		if !slices.Contains(SubnetNetworks, name) {
+			return NewValidationError(field, fmt.Sprintf("unknown network (one of %v)", SubnetNetworks))
+		}
+		if networks[name].Subnet == "" {
+			continue
+		}
+		prefix, err := networks[name].Prefix()
+		if err != nil {
+			return NewValidationError(field+".subnet", err.Error())
+		}
+		for _, other := range slices.Sorted(maps.Keys(prefixes)) {
+			if prefixes[other].Overlaps(prefix) {
+				return NewValidationError(field+".subnet", fmt.Sprintf("overlaps networks.%s.subnet %s", other, prefixes[other]))
+			}
+		}
+		prefixes[name] = prefix
+	}
+	return nil
+}
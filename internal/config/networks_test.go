@@ -0,0 +1,35 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateNetworks(t *testing.T) {
+	tests := []struct {
+		name     string
+		networks map[string]NetworkConfig
+		wantErr  string
+	}{
+		{"unset", nil, ""},
+		{"subnets", map[string]NetworkConfig{"proxy": {Subnet: "172.30.0.0/24"}, "backend": {Subnet: "172.30.1.0/24"}}, ""},
+		{"unknown network", map[string]NetworkConfig{"lan": {Subnet: "172.30.0.0/24"}}, "unknown network"},
+		{"invalid", map[string]NetworkConfig{"proxy": {Subnet: "172.30.0.0"}}, "invalid subnet"},
+		{"IPv6", map[string]NetworkConfig{"proxy": {Subnet: "fd00::/64"}}, "IPv4"},
+		{"host bits", map[string]NetworkConfig{"proxy": {Subnet: "172.30.0.1/24"}}, "172.30.0.0/24"},
+		{"too small", map[string]NetworkConfig{"proxy": {Subnet: "172.30.0.0/30"}}, "too small"},
+		{"overlap", map[string]NetworkConfig{"proxy": {Subnet: "172.30.0.0/16"}, "vpn": {Subnet: "172.30.5.0/24"}}, "overlaps networks.proxy"},
+	}
+	for _, tt := range tests {
+		err := validateNetworks(tt.networks)
+		if tt.wantErr == "" {
+			if err != nil {
+				t.Errorf("%s: validateNetworks() = %v", tt.name, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("%s: validateNetworks() = %v, want %q", tt.name, err, tt.wantErr)
+		}
+	}
+}
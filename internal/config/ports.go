@@ -0,0 +1,49 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// HostPortsConfig sets the range host ports are allocated from when two
+// services publish the same host port
+type HostPortsConfig struct {
+	Range string `mapstructure:"range" yaml:"range,omitempty"` // e.g. 20000-20999; conflicts fail generation when unset
+}
+
+// IsEnabled reports whether conflicting host ports are reallocated
+func (h HostPortsConfig) IsEnabled() bool {
+	return h.Range != ""
+}
+
+// Bounds returns the first and last port of the range
+func (h HostPortsConfig) Bounds() (int, int, error) {
+	first, last, ok := strings.Cut(h.Range, "-")
+	if !ok {
+		return 0, 0, fmt.Errorf("invalid range %q (e.g. 20000-20999)", h.Range)
+	}
+	lo, err := strconv.Atoi(strings.TrimSpace(first))
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid range %q (e.g. 20000-20999)", h.Range)
+	}
+	hi, err := strconv.Atoi(strings.TrimSpace(last))
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid range %q (e.g. 20000-20999)", h.Range)
+	}
+	if lo < 1024 || hi > 65535 || lo > hi {
+		return 0, 0, fmt.Errorf("range %q must be within 1024-65535, lowest port first", h.Range)
+	}
+	return lo, hi, nil
+}
+
+// validateHostPorts checks the allocation range
+func validateHostPorts(h HostPortsConfig) error {
+	if !h.IsEnabled() {
+		return nil
+	}
+	if _, _, err := h.Bounds(); err != nil {
+		return NewValidationError("host_ports.range", err.Error())
+	}
+	return nil
+}
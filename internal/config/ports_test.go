@@ -0,0 +1,32 @@
+package config
+
+import "testing"
+
+func TestHostPortsBounds(t *testing.T) {
+	tests := []struct {
+		rng     string
+		lo, hi  int
+		wantErr bool
+	}{
+		{"20000-20999", 20000, 20999, false},
+		{" 30000 - 30000 ", 30000, 30000, false},
+		{"20000", 0, 0, true},
+		{"a-b", 0, 0, true},
+		{"80-90", 0, 0, true},
+		{"30000-20000", 0, 0, true},
+		{"60000-70000", 0, 0, true},
+	}
+	for _, tt := range tests {
+		lo, hi, err := HostPortsConfig{Range: tt.rng}.Bounds()
+		if (err != nil) != tt.wantErr || lo != tt.lo || hi != tt.hi {
+			t.Errorf("Bounds(%q) = (%d, %d, %v), want (%d, %d, err %v)", tt.rng, lo, hi, err, tt.lo, tt.hi, tt.wantErr)
+		}
+	}
+
+	if err := validateHostPorts(HostPortsConfig{}); err != nil {
+		t.Errorf("validateHostPorts() of an unset range = %v", err)
+	}
+	if err := validateHostPorts(HostPortsConfig{Range: "1-2"}); err == nil {
+		t.Error("validateHostPorts() accepted ports below 1024")
+	}
+}
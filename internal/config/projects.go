@@ -0,0 +1,157 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ProjectEnv selects the project of commands, like --project
+const ProjectEnv = "SDBX_PROJECT"
+
+// ErrUnknownProject is returned for project names missing from the workspace
+var ErrUnknownProject = errors.New("unknown project")
+
+// projectNameRegex matches a project name, so it never reads as a path
+var projectNameRegex = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_-]*$`)
+
+// Project is a named sdbx project of the workspace
+type Project struct {
+	Name string `yaml:"name" json:"name"` // e.g. home
+	Path string `yaml:"path" json:"path"` // Absolute project directory
+}
+
+// Workspace is the registry of the user's projects, projects.yaml next to
+// sources.yaml
+type Workspace struct {
+	Projects []Project `yaml:"projects"`
+}
+
+// WorkspacePath returns the path of the user's projects.yaml
+func WorkspacePath() string {
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".config", "sdbx", "projects.yaml")
+}
+
+// LoadWorkspace reads a workspace file; a missing file has no projects
+func LoadWorkspace(path string) (*Workspace, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return &Workspace{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	var w Workspace
+	if err := yaml.Unmarshal(data, &w); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return &w, nil
+}
+
+// Save writes the workspace file
+func (w *Workspace) Save(path string) error {
+	data, err := yaml.Marshal(w)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+// Get returns a project by name
+func (w *Workspace) Get(name string) (Project, error) {
+	for _, p := range w.Projects {
+		if p.Name == name {
+			return p, nil
+		}
+	}
+	return Project{}, fmt.Errorf("%w: %s", ErrUnknownProject, name)
+}
+
+// Add adds a project, or moves the one of the same name. The path must be
+// a project directory.
+func (w *Workspace) Add(name, path string) (Project, error) {
+	if !projectNameRegex.MatchString(name) {
+		return Project{}, fmt.Errorf("invalid project name %q (letters, digits, dashes and underscores)", name)
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return Project{}, err
+	}
+	if !IsProjectDir(abs) {
+		return Project{}, fmt.Errorf("%s is not a sdbx project (no .sdbx.yaml nor compose.yaml)", abs)
+	}
+
+	project := Project{Name: name, Path: abs}
+	if i := slices.IndexFunc(w.Projects, func(p Project) bool { return p.Name == name }); i >= 0 {
+		w.Projects[i] = project
+		return project, nil
+	}
+	w.Projects = append(w.Projects, project)
+	return project, nil
+}
+
+// Remove removes a project by name; its directory is left untouched
+func (w *Workspace) Remove(name string) error {
+	i := slices.IndexFunc(w.Projects, func(p Project) bool { return p.Name == name })
+	if i < 0 {
+		return fmt.Errorf("%w: %s", ErrUnknownProject, name)
+	}
+	w.Projects = slices.Delete(w.Projects, i, i+1)
+	return nil
+}
+
+// Resolve returns the directory of a project given by name or by path.
+// Values with a path separator, or starting with . or ~, are paths; other
+// ones are names of the workspace, or directories when no project has that
+// name.
+func (w *Workspace) Resolve(value string) (string, error) {
+	isPath := strings.ContainsRune(value, filepath.Separator) || strings.Contains(value, "/") ||
+		strings.HasPrefix(value, ".") || strings.HasPrefix(value, "~")
+	if !isPath {
+		if p, err := w.Get(value); err == nil {
+			return p.Path, nil
+		}
+	}
+
+	path := value
+	if rest, ok := strings.CutPrefix(path, "~"); ok {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		path = filepath.Join(home, rest)
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	if info, err := os.Stat(abs); err != nil || !info.IsDir() {
+		if !isPath {
+			return "", fmt.Errorf("%w %q", ErrUnknownProject, value)
+		}
+		return "", fmt.Errorf("project directory %s not found", abs)
+	}
+	return abs, nil
+}
+
+// IsProjectDir reports whether dir holds a sdbx project, the way ProjectDir
+// recognizes one
+func IsProjectDir(dir string) bool {
+	for _, name := range []string{".sdbx.yaml", "compose.yaml"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err == nil {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,85 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWorkspace(t *testing.T) {
+	dir := t.TempDir()
+	home := filepath.Join(dir, "home")
+	if err := os.MkdirAll(home, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(home, ".sdbx.yaml"), []byte("domain: home.lan\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "projects.yaml")
+
+	w, err := LoadWorkspace(path)
+	if err != nil || len(w.Projects) != 0 {
+		t.Fatalf("LoadWorkspace() of a missing file = %v, %v", w, err)
+	}
+	if _, err := w.Add("home", home); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	if _, err := w.Add("empty", dir); err == nil {
+		t.Error("Add() of a directory without a project should fail")
+	}
+	if _, err := w.Add("../home", home); err == nil {
+		t.Error("Add() with a path as name should fail")
+	}
+	if err := w.Save(path); err != nil {
+		t.Fatal(err)
+	}
+
+	w, err = LoadWorkspace(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p, err := w.Get("home"); err != nil || p.Path != home {
+		t.Errorf("Get(home) = %v, %v", p, err)
+	}
+	if err := w.Remove("parents"); !errors.Is(err, ErrUnknownProject) {
+		t.Errorf("Remove(parents) error = %v, want ErrUnknownProject", err)
+	}
+	if err := w.Remove("home"); err != nil || len(w.Projects) != 0 {
+		t.Errorf("Remove(home) = %v, projects %v", err, w.Projects)
+	}
+}
+
+func TestWorkspaceResolve(t *testing.T) {
+	dir := t.TempDir()
+	w := &Workspace{Projects: []Project{{Name: "home", Path: "/srv/home"}}}
+
+	tests := []struct {
+		name    string
+		value   string
+		want    string
+		wantErr error
+	}{
+		{"name", "home", "/srv/home", nil},
+		{"path", dir, dir, nil},
+		{"unknown name", "parents", "", ErrUnknownProject},
+		{"missing path", filepath.Join(dir, "missing"), "", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := w.Resolve(tt.value)
+			if tt.want == "" {
+				if err == nil {
+					t.Fatalf("Resolve(%q) = %q, want an error", tt.value, got)
+				}
+				if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+					t.Errorf("Resolve(%q) error = %v, want %v", tt.value, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("Resolve(%q) = %q, %v, want %q", tt.value, got, err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,84 @@
+package config
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/maiko/sdbx/internal/netproxy"
+)
+
+// Integrations whose outbound connections proxy.integrations configures
+const (
+	ProxyNotify      = "notify"      // Webhook and chat notification channels
+	ProxyGeoIP       = "geoip"       // MaxMind database downloads
+	ProxyQBittorrent = "qbittorrent" // qBittorrent Web API
+	ProxySABnzbd     = "sabnzbd"     // SABnzbd API
+)
+
+// ProxyIntegrations are the integrations proxy.integrations accepts
+var ProxyIntegrations = []string{ProxyGeoIP, ProxyNotify, ProxyQBittorrent, ProxySABnzbd}
+
+// proxyLocalIntegrations reach services of the project, so they only use a
+// proxy set for them in proxy.integrations
+var proxyLocalIntegrations = []string{ProxyQBittorrent, ProxySABnzbd}
+
+// ProxyConfig routes the outbound connections of sdbx through an HTTP or
+// SOCKS proxy. Service definition sources are configured in sources.yaml.
+type ProxyConfig struct {
+	URL          string            `mapstructure:"url" yaml:"url,omitempty"`                   // http(s):// or socks5:// proxy (default: HTTPS_PROXY/HTTP_PROXY)
+	NoProxy      []string          `mapstructure:"no_proxy" yaml:"no_proxy,omitempty"`         // Hosts, domains and CIDRs reached directly, added to NO_PROXY
+	Integrations map[string]string `mapstructure:"integrations" yaml:"integrations,omitempty"` // Proxy URL or "direct" per integration
+}
+
+// IsSet reports whether any proxy setting is configured
+func (p ProxyConfig) IsSet() bool {
+	return p.URL != "" || len(p.NoProxy) > 0 || len(p.Integrations) > 0
+}
+
+// For returns the proxy of an integration (see netproxy.Func): its own
+// proxy, else the URL, or "direct" for integrations reaching services of
+// the project
+func (p ProxyConfig) For(integration string) string {
+	if proxy := p.Integrations[integration]; proxy != "" {
+		return proxy
+	}
+	if slices.Contains(proxyLocalIntegrations, integration) {
+		return netproxy.Direct
+	}
+	return p.URL
+}
+
+// validateProxy checks the proxy section
+func validateProxy(p ProxyConfig) error {
+	if p.URL == netproxy.Direct {
+		return NewValidationError("proxy.url", "remove proxy.url to connect directly")
+	}
+	if err := netproxy.Validate(p.URL); err != nil {
+		return NewValidationError("proxy.url", err.Error())
+	}
+	for i, host := range p.NoProxy {
+		if host == "" || strings.ContainsAny(host, ", ") {
+			return NewValidationError(fmt.Sprintf("proxy.no_proxy[%d]", i), fmt.Sprintf("invalid host %q", host))
+		}
+	}
+	names := make([]string, 0, len(p.Integrations))
+	for name := range p.Integrations {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		field := "proxy.integrations." + name
+		if !slices.Contains(ProxyIntegrations, name) {
+			return NewValidationError(field, fmt.Sprintf("unknown integration - use one of: %s", strings.Join(ProxyIntegrations, ", ")))
+		}
+		if p.Integrations[name] == "" {
+			return NewValidationError(field, `proxy URL or "direct" is required`)
+		}
+		if err := netproxy.Validate(p.Integrations[name]); err != nil {
+			return NewValidationError(field, err.Error())
+		}
+	}
+	return nil
+}
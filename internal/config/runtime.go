@@ -0,0 +1,124 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+)
+
+// Docker runtimes the stack can run on
+const (
+	RuntimeEngine        = "engine"         // Docker Engine on a Linux host (seedbox)
+	RuntimeDockerDesktop = "docker-desktop" // Docker Desktop VM on macOS
+	RuntimeOrbStack      = "orbstack"       // OrbStack VM on macOS
+)
+
+// TargetRuntime returns the Docker runtime the stack runs on: the configured
+// runtime, or the one detected on the running host
+func (c *Config) TargetRuntime() string {
+	if c.Runtime != "" {
+		return c.Runtime
+	}
+	return HostRuntime()
+}
+
+// DesktopRuntime reports whether containers run in the VM of a desktop
+// runtime rather than on the host. Bind mounts then go through file sharing,
+// which maps ownership to the desktop user, and the host network is the VM's.
+func (c *Config) DesktopRuntime() bool {
+	r := c.TargetRuntime()
+	return r == RuntimeDockerDesktop || r == RuntimeOrbStack
+}
+
+// HostNetworkSupported reports whether network_mode: host reaches the host:
+// OrbStack forwards it to macOS, Docker Desktop keeps it inside its VM
+func (c *Config) HostNetworkSupported() bool {
+	return c.TargetRuntime() != RuntimeDockerDesktop
+}
+
+// HostRuntime returns the Docker runtime of the running host: Docker Engine
+// on Linux, otherwise the desktop runtime of the current Docker context
+func HostRuntime() string {
+	if runtime.GOOS == "linux" {
+		return RuntimeEngine
+	}
+	if strings.Contains(os.Getenv("DOCKER_HOST"), "orbstack") || currentDockerContext() == "orbstack" {
+		return RuntimeOrbStack
+	}
+	return RuntimeDockerDesktop
+}
+
+// RuntimeName returns the display name of a runtime
+func RuntimeName(r string) string {
+	switch r {
+	case RuntimeDockerDesktop:
+		return "Docker Desktop"
+	case RuntimeOrbStack:
+		return "OrbStack"
+	default:
+		return "Docker Engine"
+	}
+}
+
+// currentDockerContext returns the Docker context selected by DOCKER_CONTEXT
+// or ~/.docker/config.json, or "" for the default context
+func currentDockerContext() string {
+	if name := os.Getenv("DOCKER_CONTEXT"); name != "" {
+		return name
+	}
+	dir := os.Getenv("DOCKER_CONFIG")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		dir = filepath.Join(home, ".docker")
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "config.json"))
+	if err != nil {
+		return ""
+	}
+	var cfg struct {
+		CurrentContext string `json:"currentContext"`
+	}
+	if json.Unmarshal(data, &cfg) != nil {
+		return ""
+	}
+	return cfg.CurrentContext
+}
+
+// validateRuntime checks the runtime and refuses features that need the
+// Linux host when the stack runs in a desktop VM
+func (c *Config) validateRuntime() error {
+	validRuntimes := []string{RuntimeEngine, RuntimeDockerDesktop, RuntimeOrbStack}
+	if c.Runtime != "" && !slices.Contains(validRuntimes, c.Runtime) {
+		return NewValidationError("runtime",
+			fmt.Sprintf("must be one of: %s", strings.Join(validRuntimes, ", ")))
+	}
+	if !c.DesktopRuntime() {
+		return nil
+	}
+
+	name := RuntimeName(c.TargetRuntime())
+	if c.HardwareTranscode {
+		return NewValidationError("hardware_transcode",
+			fmt.Sprintf("%s has no /dev/dri to pass to media servers; disable it for local testing", name))
+	}
+	if r := c.Storage.Rclone; r != nil {
+		if r.RcloneMode() == RcloneModeSystemd {
+			return NewValidationError("storage.rclone.mode",
+				fmt.Sprintf("systemd mounts need a Linux host, not %s; use mode: container or remove storage for local testing", name))
+		}
+		return NewValidationError("storage.rclone",
+			fmt.Sprintf("%s cannot share FUSE mounts between containers through file sharing; remove storage for local testing", name))
+	}
+	if c.Storage.Mergerfs != nil {
+		return NewValidationError("storage.mergerfs",
+			fmt.Sprintf("mergerfs pools need a Linux host, not %s; remove storage for local testing", name))
+	}
+	return nil
+}
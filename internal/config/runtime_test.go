@@ -0,0 +1,53 @@
+package config
+
+import "testing"
+
+func TestValidateRuntime(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(*Config)
+		wantErr bool
+	}{
+		{"engine", func(c *Config) { c.Runtime = RuntimeEngine; c.HardwareTranscode = true }, false},
+		{"unknown runtime", func(c *Config) { c.Runtime = "podman" }, true},
+		{"docker desktop", func(c *Config) { c.Runtime = RuntimeDockerDesktop }, false},
+		{"hardware transcode on orbstack", func(c *Config) { c.Runtime = RuntimeOrbStack; c.HardwareTranscode = true }, true},
+		{"rclone on docker desktop", func(c *Config) {
+			c.Runtime = RuntimeDockerDesktop
+			c.Storage.Rclone = &RcloneConfig{Remote: "gdrive:", Mount: "/mnt/remote"}
+		}, true},
+		{"mergerfs on orbstack", func(c *Config) {
+			c.Runtime = RuntimeOrbStack
+			c.Storage.Mergerfs = &MergerfsConfig{Mount: "/mnt/merged", Branches: []string{"/mnt/a", "/mnt/b"}}
+		}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultConfig()
+			tt.modify(cfg)
+			err := cfg.validateRuntime()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateRuntime() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDesktopRuntime(t *testing.T) {
+	cfg := DefaultConfig()
+	for _, tt := range []struct {
+		runtime     string
+		desktop     bool
+		hostNetwork bool
+	}{
+		{RuntimeEngine, false, true},
+		{RuntimeDockerDesktop, true, false},
+		{RuntimeOrbStack, true, true},
+	} {
+		cfg.Runtime = tt.runtime
+		if cfg.DesktopRuntime() != tt.desktop || cfg.HostNetworkSupported() != tt.hostNetwork {
+			t.Errorf("%s: DesktopRuntime() = %v, HostNetworkSupported() = %v", tt.runtime, cfg.DesktopRuntime(), cfg.HostNetworkSupported())
+		}
+	}
+}
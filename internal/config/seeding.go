@@ -0,0 +1,169 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Actions applied to torrents that reached their seeding limits
+const (
+	SeedingActionPause          = "pause"            // Stop seeding, keep the torrent (default)
+	SeedingActionRemove         = "remove"           // Remove the torrent, keep its files
+	SeedingActionRemoveWithData = "remove_with_data" // Remove the torrent and its files
+)
+
+// SeedingAnyCategory is the rule category matching torrents whose category
+// has no rule of its own
+const SeedingAnyCategory = "*"
+
+// DefaultSeedingInterval is how often seeding rules are enforced when
+// seeding.interval is unset
+const DefaultSeedingInterval = 15 * time.Minute
+
+// DefaultQBittorrentURL is the qBittorrent Web API as published on the host
+const DefaultQBittorrentURL = "http://localhost:8080"
+
+// DownloadClientsConfig locates the download clients sdbx talks to
+type DownloadClientsConfig struct {
+	QBittorrent QBittorrentConfig `mapstructure:"qbittorrent" yaml:"qbittorrent,omitempty"`
+	SABnzbd     SABnzbdConfig     `mapstructure:"sabnzbd" yaml:"sabnzbd,omitempty"`
+}
+
+// QBittorrentConfig locates the qBittorrent Web API. The generated
+// qBittorrent config does not require a login from the host or the Docker
+// networks, so credentials are only needed for customized setups.
+type QBittorrentConfig struct {
+	URL            string `mapstructure:"url" yaml:"url,omitempty"`                         // Default: localhost:8080 from the host, the container from the web UI
+	Username       string `mapstructure:"username" yaml:"username,omitempty"`               // Web UI login, when required
+	PasswordSecret string `mapstructure:"password_secret" yaml:"password_secret,omitempty"` // secrets/<name>.txt holding the Web UI password
+}
+
+// SABnzbdConfig locates the SABnzbd API. SABnzbd is only used once its
+// API key is configured.
+type SABnzbdConfig struct {
+	URL          string `mapstructure:"url" yaml:"url,omitempty"`                       // Default: the sdbx-sabnzbd container, from the web UI
+	APIKeySecret string `mapstructure:"api_key_secret" yaml:"api_key_secret,omitempty"` // secrets/<name>.txt holding the API key
+}
+
+// IsEnabled reports whether SABnzbd is configured
+func (s SABnzbdConfig) IsEnabled() bool {
+	return s.APIKeySecret != ""
+}
+
+// SeedingConfig defines per-category seeding limits enforced against
+// qBittorrent by the scheduler, instead of share limits set in the client
+type SeedingConfig struct {
+	Interval string        `mapstructure:"interval" yaml:"interval,omitempty"` // Time between enforcements (default 15m)
+	Rules    []SeedingRule `mapstructure:"rules" yaml:"rules,omitempty"`
+}
+
+// SeedingRule limits the seeding of completed torrents in a category. The
+// action applies once either limit is reached.
+type SeedingRule struct {
+	Category string  `mapstructure:"category" yaml:"category"`             // qBittorrent category, or * for torrents without a rule
+	Ratio    float64 `mapstructure:"ratio" yaml:"ratio,omitempty"`         // Share ratio limit (e.g. 2.0)
+	SeedTime string  `mapstructure:"seed_time" yaml:"seed_time,omitempty"` // Seeding time limit (e.g. 168h)
+	Action   string  `mapstructure:"action" yaml:"action,omitempty"`       // pause (default) | remove | remove_with_data
+}
+
+// IsEnabled reports whether any seeding rule is configured
+func (s SeedingConfig) IsEnabled() bool {
+	return len(s.Rules) > 0
+}
+
+// EnforceInterval returns the parsed Interval, defaulting to DefaultSeedingInterval
+func (s SeedingConfig) EnforceInterval() time.Duration {
+	if d, err := time.ParseDuration(s.Interval); err == nil && d > 0 {
+		return d
+	}
+	return DefaultSeedingInterval
+}
+
+// Rule returns the rule applying to a category: its own, else the * rule
+func (s SeedingConfig) Rule(category string) (SeedingRule, bool) {
+	var fallback *SeedingRule
+	for i, rule := range s.Rules {
+		if rule.Category == category {
+			return rule, true
+		}
+		if rule.Category == SeedingAnyCategory {
+			fallback = &s.Rules[i]
+		}
+	}
+	if fallback != nil {
+		return *fallback, true
+	}
+	return SeedingRule{}, false
+}
+
+// SeedTimeLimit returns the parsed SeedTime (0 when unset)
+func (r SeedingRule) SeedTimeLimit() time.Duration {
+	d, _ := time.ParseDuration(r.SeedTime)
+	return d
+}
+
+// SeedingAction returns the action, defaulting to pause
+func (r SeedingRule) SeedingAction() string {
+	if r.Action == "" {
+		return SeedingActionPause
+	}
+	return r.Action
+}
+
+// validateSeeding checks seeding rules and the download client endpoints
+func validateSeeding(s SeedingConfig, clients DownloadClientsConfig) error {
+	if s.Interval != "" {
+		if d, err := time.ParseDuration(s.Interval); err != nil || d < time.Minute {
+			return NewValidationError("seeding.interval", fmt.Sprintf("invalid duration %q (at least 1m, e.g. 15m)", s.Interval))
+		}
+	}
+
+	validActions := []string{SeedingActionPause, SeedingActionRemove, SeedingActionRemoveWithData}
+	categories := make(map[string]bool)
+	for i, rule := range s.Rules {
+		field := fmt.Sprintf("seeding.rules[%d]", i)
+		if rule.Category == "" {
+			return NewValidationError(field+".category", "is required (use * for any category)")
+		}
+		if categories[rule.Category] {
+			return NewValidationError(field+".category", fmt.Sprintf("duplicate rule for category %q", rule.Category))
+		}
+		categories[rule.Category] = true
+		if rule.Ratio < 0 {
+			return NewValidationError(field+".ratio", "must not be negative")
+		}
+		if rule.SeedTime != "" {
+			if d, err := time.ParseDuration(rule.SeedTime); err != nil || d <= 0 {
+				return NewValidationError(field+".seed_time", fmt.Sprintf("invalid duration %q (e.g. 168h)", rule.SeedTime))
+			}
+		}
+		if rule.Ratio == 0 && rule.SeedTime == "" {
+			return NewValidationError(field, "set a ratio, a seed_time or both")
+		}
+		if !slices.Contains(validActions, rule.SeedingAction()) {
+			return NewValidationError(field+".action",
+				fmt.Sprintf("must be one of: %s", strings.Join(validActions, ", ")))
+		}
+	}
+
+	if raw := clients.QBittorrent.URL; raw != "" {
+		if u, err := url.Parse(raw); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return NewValidationError("download_clients.qbittorrent.url", fmt.Sprintf("invalid URL %q - must be http(s)", raw))
+		}
+	}
+	if q := clients.QBittorrent; q.Username != "" && q.PasswordSecret == "" {
+		return NewValidationError("download_clients.qbittorrent.password_secret", "is required with a username")
+	}
+	if raw := clients.SABnzbd.URL; raw != "" {
+		if u, err := url.Parse(raw); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return NewValidationError("download_clients.sabnzbd.url", fmt.Sprintf("invalid URL %q - must be http(s)", raw))
+		}
+		if !clients.SABnzbd.IsEnabled() {
+			return NewValidationError("download_clients.sabnzbd.api_key_secret", "is required with a url")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+func TestValidateSeeding(t *testing.T) {
+	rules := func(r ...SeedingRule) SeedingConfig { return SeedingConfig{Rules: r} }
+	none := DownloadClientsConfig{}
+
+	tests := []struct {
+		name    string
+		seeding SeedingConfig
+		clients DownloadClientsConfig
+		wantErr bool
+	}{
+		{"none", SeedingConfig{}, none, false},
+		{"ratio", rules(SeedingRule{Category: "movies", Ratio: 2}), none, false},
+		{"seed time and action", rules(SeedingRule{Category: "*", SeedTime: "168h", Action: SeedingActionRemoveWithData}), none, false},
+		{"no limit", rules(SeedingRule{Category: "movies"}), none, true},
+		{"no category", rules(SeedingRule{Ratio: 1}), none, true},
+		{"duplicate category", rules(SeedingRule{Category: "tv", Ratio: 1}, SeedingRule{Category: "tv", Ratio: 2}), none, true},
+		{"negative ratio", rules(SeedingRule{Category: "tv", Ratio: -1}), none, true},
+		{"bad seed time", rules(SeedingRule{Category: "tv", SeedTime: "7d"}), none, true},
+		{"bad action", rules(SeedingRule{Category: "tv", Ratio: 1, Action: "delete"}), none, true},
+		{"short interval", SeedingConfig{Interval: "10s"}, none, true},
+		{"client url", SeedingConfig{}, DownloadClientsConfig{QBittorrent: QBittorrentConfig{URL: "http://nas:8080"}}, false},
+		{"bad client url", SeedingConfig{}, DownloadClientsConfig{QBittorrent: QBittorrentConfig{URL: "nas:8080"}}, true},
+		{"username without password", SeedingConfig{}, DownloadClientsConfig{QBittorrent: QBittorrentConfig{Username: "admin"}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateSeeding(tt.seeding, tt.clients)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateSeeding() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSeedingRule(t *testing.T) {
+	s := SeedingConfig{Rules: []SeedingRule{
+		{Category: "*", SeedTime: "168h"},
+		{Category: "movies", Ratio: 2, Action: SeedingActionRemove},
+	}}
+
+	if rule, ok := s.Rule("movies"); !ok || rule.SeedingAction() != SeedingActionRemove {
+		t.Errorf("Rule(movies) = %+v, %v", rule, ok)
+	}
+	if rule, ok := s.Rule("tv"); !ok || rule.SeedTimeLimit() != 168*time.Hour || rule.SeedingAction() != SeedingActionPause {
+		t.Errorf("Rule(tv) should fall back to *, got %+v, %v", rule, ok)
+	}
+	if _, ok := (SeedingConfig{Rules: s.Rules[1:]}).Rule("tv"); ok {
+		t.Error("Rule(tv) without a * rule should not match")
+	}
+	if s.EnforceInterval() != DefaultSeedingInterval {
+		t.Errorf("EnforceInterval() = %v, want default", s.EnforceInterval())
+	}
+}
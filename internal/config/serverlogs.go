@@ -0,0 +1,57 @@
+package config
+
+import "time"
+
+// Defaults of the server_logs section
+const (
+	DefaultServerLogMaxSize  = 10 // MB
+	DefaultServerLogMaxFiles = 5
+)
+
+// ServerLogsConfig sets the rotation and retention of the logs sdbx writes
+// itself in logs/ of the project: the web UI server and its background jobs
+type ServerLogsConfig struct {
+	MaxSize  int `mapstructure:"max_size" yaml:"max_size,omitempty"`   // Rotate once a log exceeds this many MB (default 10)
+	MaxFiles int `mapstructure:"max_files" yaml:"max_files,omitempty"` // Number of rotated files to keep (default 5)
+	MaxAge   int `mapstructure:"max_age" yaml:"max_age,omitempty"`     // Days rotated files are kept, 0 keeps them up to MaxFiles
+}
+
+// IsEnabled reports whether any server log setting is configured
+func (l ServerLogsConfig) IsEnabled() bool {
+	return l.MaxSize > 0 || l.MaxFiles > 0 || l.MaxAge > 0
+}
+
+// MaxBytes returns the size a log is rotated at
+func (l ServerLogsConfig) MaxBytes() int64 {
+	if l.MaxSize <= 0 {
+		return DefaultServerLogMaxSize << 20
+	}
+	return int64(l.MaxSize) << 20
+}
+
+// Files returns the number of rotated files kept
+func (l ServerLogsConfig) Files() int {
+	if l.MaxFiles <= 0 {
+		return DefaultServerLogMaxFiles
+	}
+	return l.MaxFiles
+}
+
+// Retention returns how long rotated files are kept, 0 for no limit
+func (l ServerLogsConfig) Retention() time.Duration {
+	return time.Duration(l.MaxAge) * 24 * time.Hour
+}
+
+// validateServerLogs checks the server log settings are not negative
+func validateServerLogs(l ServerLogsConfig) error {
+	if l.MaxSize < 0 {
+		return NewValidationError("server_logs.max_size", "must not be negative")
+	}
+	if l.MaxFiles < 0 {
+		return NewValidationError("server_logs.max_files", "must not be negative")
+	}
+	if l.MaxAge < 0 {
+		return NewValidationError("server_logs.max_age", "must not be negative")
+	}
+	return nil
+}
@@ -0,0 +1,29 @@
+package config
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestServerLogsDefaults(t *testing.T) {
+	var l ServerLogsConfig
+	if l.IsEnabled() || l.MaxBytes() != 10<<20 || l.Files() != 5 || l.Retention() != 0 {
+		t.Errorf("defaults = (%v, %d, %d, %s)", l.IsEnabled(), l.MaxBytes(), l.Files(), l.Retention())
+	}
+	l = ServerLogsConfig{MaxSize: 2, MaxFiles: 3, MaxAge: 7}
+	if !l.IsEnabled() || l.MaxBytes() != 2<<20 || l.Files() != 3 || l.Retention() != 7*24*time.Hour {
+		t.Errorf("settings = (%v, %d, %d, %s)", l.IsEnabled(), l.MaxBytes(), l.Files(), l.Retention())
+	}
+}
+
+func TestValidateServerLogs(t *testing.T) {
+	if err := validateServerLogs(ServerLogsConfig{MaxSize: 1, MaxFiles: 1, MaxAge: 1}); err != nil {
+		t.Errorf("valid settings: %v", err)
+	}
+	for _, l := range []ServerLogsConfig{{MaxSize: -1}, {MaxFiles: -1}, {MaxAge: -1}} {
+		if err := validateServerLogs(l); err == nil || !strings.Contains(err.Error(), "negative") {
+			t.Errorf("validateServerLogs(%+v) = %v, want negative error", l, err)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package config
+
+import (
+	"fmt"
+	"net/mail"
+)
+
+// SMTP ports: STARTTLS submission (default) and implicit TLS
+const (
+	DefaultSMTPPort = 587
+	SMTPSPort       = 465
+)
+
+// SMTPPasswordSecret is the secret holding the password of the smtp section
+// (secrets/smtp_password.txt), which the user fills in
+const SMTPPasswordSecret = "smtp_password"
+
+// SMTPConfig is the mail server shared by the Authelia notifier, the email
+// notifications of the *arr services and the email notification channels
+type SMTPConfig struct {
+	Host   string `mapstructure:"host" yaml:"host,omitempty"`
+	Port   int    `mapstructure:"port" yaml:"port,omitempty"`     // Default 587 (STARTTLS); 465 is implicit TLS
+	User   string `mapstructure:"user" yaml:"user,omitempty"`     // Login; the password is read from secrets/smtp_password.txt
+	Sender string `mapstructure:"sender" yaml:"sender,omitempty"` // e.g. "SDBX <sdbx@example.com>"
+}
+
+// IsSet reports whether a mail server is configured
+func (s SMTPConfig) IsSet() bool {
+	return s.Host != ""
+}
+
+// Auth reports whether sdbx and the services log in to the mail server, with
+// the password of SMTPPasswordSecret
+func (s SMTPConfig) Auth() bool {
+	return s.IsSet() && s.User != ""
+}
+
+// ServerPort returns the port of the mail server, 587 when unset
+func (s SMTPConfig) ServerPort() int {
+	if s.Port == 0 {
+		return DefaultSMTPPort
+	}
+	return s.Port
+}
+
+// ImplicitTLS reports whether the connection is encrypted from the start
+// (port 465) rather than upgraded with STARTTLS
+func (s SMTPConfig) ImplicitTLS() bool {
+	return s.ServerPort() == SMTPSPort
+}
+
+// Address returns the host:port of the mail server
+func (s SMTPConfig) Address() string {
+	return fmt.Sprintf("%s:%d", s.Host, s.ServerPort())
+}
+
+// validateSMTP checks the smtp section
+func validateSMTP(s SMTPConfig) error {
+	if s == (SMTPConfig{}) {
+		return nil
+	}
+	if s.Host == "" {
+		return NewValidationError("smtp.host", "is required")
+	}
+	if s.Port < 0 || s.Port > 65535 {
+		return NewValidationError("smtp.port", "must be between 1 and 65535")
+	}
+	if s.Sender == "" {
+		return NewValidationError("smtp.sender", "is required")
+	}
+	if _, err := mail.ParseAddress(s.Sender); err != nil {
+		return NewValidationError("smtp.sender",
+			fmt.Sprintf("invalid address %q (e.g. sdbx@example.com or \"SDBX <sdbx@example.com>\")", s.Sender))
+	}
+	return nil
+}
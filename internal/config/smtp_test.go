@@ -0,0 +1,68 @@
+package config
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestValidateSMTP(t *testing.T) {
+	tests := []struct {
+		name    string
+		smtp    SMTPConfig
+		wantErr bool
+	}{
+		{"unset", SMTPConfig{}, false},
+		{"valid", SMTPConfig{Host: "smtp.example.com", User: "sdbx", Sender: "SDBX <sdbx@example.com>"}, false},
+		{"without host", SMTPConfig{Sender: "sdbx@example.com"}, true},
+		{"without sender", SMTPConfig{Host: "smtp.example.com"}, true},
+		{"bad sender", SMTPConfig{Host: "smtp.example.com", Sender: "sdbx"}, true},
+		{"bad port", SMTPConfig{Host: "smtp.example.com", Port: 70000, Sender: "sdbx@example.com"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateSMTP(tt.smtp); (err != nil) != tt.wantErr {
+				t.Errorf("validateSMTP() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSMTPConfig(t *testing.T) {
+	s := SMTPConfig{Host: "smtp.example.com"}
+	if s.Address() != "smtp.example.com:587" || s.ImplicitTLS() || s.Auth() {
+		t.Errorf("%+v: Address() = %s, ImplicitTLS() = %v, Auth() = %v", s, s.Address(), s.ImplicitTLS(), s.Auth())
+	}
+	s.Port, s.User = SMTPSPort, "sdbx"
+	if !s.ImplicitTLS() || !s.Auth() {
+		t.Errorf("%+v: want implicit TLS and authentication", s)
+	}
+}
+
+func TestEmailChannels(t *testing.T) {
+	ch := NotificationChannel{Name: "mail", Type: NotifyEmail, URL: "mailto:alice@example.com, bob@example.com"}
+	if got := ch.Recipients(); !slices.Equal(got, []string{"alice@example.com", "bob@example.com"}) {
+		t.Errorf("Recipients() = %v", got)
+	}
+
+	tests := []struct {
+		name    string
+		url     string
+		smtp    SMTPConfig
+		wantErr bool
+	}{
+		{"valid", "mailto:alice@example.com", SMTPConfig{Host: "smtp.example.com", Sender: "sdbx@example.com"}, false},
+		{"without smtp", "mailto:alice@example.com", SMTPConfig{}, true},
+		{"http url", "https://example.com", SMTPConfig{Host: "smtp.example.com", Sender: "sdbx@example.com"}, true},
+		{"bad address", "mailto:alice", SMTPConfig{Host: "smtp.example.com", Sender: "sdbx@example.com"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultConfig()
+			cfg.SMTP = tt.smtp
+			cfg.Notifications.Channels = []NotificationChannel{{Name: "mail", Type: NotifyEmail, URL: tt.url}}
+			if err := cfg.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
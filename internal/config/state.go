@@ -0,0 +1,120 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// StateFile records how far generation of the project got
+const StateFile = ".sdbx.state.yaml"
+
+// ProjectState is the generation state of a project
+type ProjectState string
+
+// Project states. Generation moves a project to generating, then to ready,
+// or to degraded when it fails. A project left generating was interrupted.
+const (
+	StateUninitialized ProjectState = "uninitialized" // Never generated
+	StateGenerating    ProjectState = "generating"    // Generation in progress, or interrupted
+	StateReady         ProjectState = "ready"         // Last generation succeeded
+	StateDegraded      ProjectState = "degraded"      // Last generation failed
+)
+
+// State is the content of StateFile
+type State struct {
+	State     ProjectState `yaml:"state"`
+	Updated   time.Time    `yaml:"updated"`
+	LastReady time.Time    `yaml:"last_ready,omitempty"` // Last successful generation
+	Error     string       `yaml:"error,omitempty"`      // Why generation failed, when degraded
+
+	// Services left out of the last generation, which still completed
+	Skipped []SkippedService `yaml:"skipped,omitempty"`
+}
+
+// SkippedService is a service generation left out, and why
+type SkippedService struct {
+	Name   string `yaml:"name"`
+	Reason string `yaml:"reason"`
+	Kept   bool   `yaml:"kept,omitempty"` // compose.yaml still has its previous block
+}
+
+// Dropped returns the skipped services compose.yaml has no block for: their
+// containers, if still running, would be removed as orphans
+func (s State) Dropped() []string {
+	var names []string
+	for _, skipped := range s.Skipped {
+		if !skipped.Kept {
+			names = append(names, skipped.Name)
+		}
+	}
+	return names
+}
+
+// NeedsRepair reports whether generation failed or was interrupted, leaving
+// project files partially written
+func (s State) NeedsRepair() bool {
+	return s.State == StateGenerating || s.State == StateDegraded
+}
+
+// Initialized reports whether the project was generated successfully at
+// least once. A project whose first generation failed is not: its setup has
+// to be completed again.
+func (s State) Initialized() bool {
+	return s.State == StateReady || (s.NeedsRepair() && !s.LastReady.IsZero())
+}
+
+// ReadState returns the state of a project. Projects generated before
+// StateFile existed are ready when they have a .sdbx.yaml, since it was
+// last written.
+func ReadState(projectDir string) (State, error) {
+	data, err := os.ReadFile(filepath.Join(projectDir, StateFile))
+	if errors.Is(err, fs.ErrNotExist) {
+		if info, err := os.Stat(filepath.Join(projectDir, ".sdbx.yaml")); err == nil {
+			return State{State: StateReady, Updated: info.ModTime(), LastReady: info.ModTime()}, nil
+		}
+		return State{State: StateUninitialized}, nil
+	}
+	if err != nil {
+		return State{}, fmt.Errorf("failed to read %s: %w", StateFile, err)
+	}
+
+	var state State
+	if err := yaml.Unmarshal(data, &state); err != nil {
+		return State{}, fmt.Errorf("failed to parse %s: %w", StateFile, err)
+	}
+	switch state.State {
+	case StateUninitialized, StateGenerating, StateReady, StateDegraded:
+		return state, nil
+	default:
+		return State{}, fmt.Errorf("invalid state %q in %s", state.State, StateFile)
+	}
+}
+
+// WriteState records the state of a project, with the generation error
+// for StateDegraded and the services a completed generation skipped
+func WriteState(projectDir string, state ProjectState, genErr error, skipped ...SkippedService) error {
+	s := State{State: state, Updated: time.Now().UTC().Truncate(time.Second), Skipped: skipped}
+	if prev, err := ReadState(projectDir); err == nil {
+		s.LastReady = prev.LastReady
+	}
+	if state == StateReady {
+		s.LastReady = s.Updated
+	}
+	if genErr != nil {
+		s.Error = genErr.Error()
+	}
+	data, err := yaml.Marshal(s)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s: %w", StateFile, err)
+	}
+	if err := os.WriteFile(filepath.Join(projectDir, StateFile), data, 0o644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", StateFile, err)
+	}
+	return nil
+}
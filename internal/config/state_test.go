@@ -0,0 +1,79 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReadWriteState(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	state, err := ReadState(tmpDir)
+	if err != nil || state.State != StateUninitialized {
+		t.Fatalf("ReadState() of empty dir = %v, %v; want uninitialized", state, err)
+	}
+
+	// A first generation failing
+	if err := WriteState(tmpDir, StateDegraded, errors.New("failed to resolve services")); err != nil {
+		t.Fatalf("WriteState failed: %v", err)
+	}
+	state, err = ReadState(tmpDir)
+	if err != nil {
+		t.Fatalf("ReadState failed: %v", err)
+	}
+	if state.State != StateDegraded || state.Error != "failed to resolve services" || !state.NeedsRepair() {
+		t.Errorf("ReadState() = %+v, want degraded with error", state)
+	}
+	if state.Initialized() {
+		t.Error("Initialized() = true for a project never generated successfully")
+	}
+
+	// Once ready, a failed regeneration keeps the project initialized
+	if err := WriteState(tmpDir, StateReady, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteState(tmpDir, StateGenerating, nil); err != nil {
+		t.Fatal(err)
+	}
+	if state, _ := ReadState(tmpDir); !state.Initialized() || !state.NeedsRepair() || state.LastReady.IsZero() {
+		t.Errorf("ReadState() after interrupted regeneration = %+v, want initialized needing repair", state)
+	}
+
+	if err := os.WriteFile(filepath.Join(tmpDir, StateFile), []byte("state: broken\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ReadState(tmpDir); err == nil {
+		t.Error("ReadState() accepted an unknown state")
+	}
+}
+
+func TestReadStateWithoutStateFile(t *testing.T) {
+	tmpDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(tmpDir, ".sdbx.yaml"), []byte("domain: example.com\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Projects from before the state file are ready, and stay initialized
+	// when their next generation fails
+	if state, _ := ReadState(tmpDir); state.State != StateReady || state.LastReady.IsZero() {
+		t.Errorf("ReadState() without state file = %+v, want ready", state)
+	}
+	if err := WriteState(tmpDir, StateDegraded, errors.New("boom")); err != nil {
+		t.Fatal(err)
+	}
+	if state, _ := ReadState(tmpDir); !state.Initialized() {
+		t.Errorf("ReadState() = %+v, want initialized", state)
+	}
+}
+
+func TestStateDropped(t *testing.T) {
+	state := State{Skipped: []SkippedService{
+		{Name: "plex", Reason: "generation failed", Kept: true},
+		{Name: "overseerr", Reason: "depends on skipped service plex"},
+	}}
+	if got := state.Dropped(); len(got) != 1 || got[0] != "overseerr" {
+		t.Errorf("Dropped() = %v, want overseerr", got)
+	}
+}
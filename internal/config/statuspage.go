@@ -0,0 +1,64 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// DefaultStatusPageSubdomain is where the public status page is served
+const DefaultStatusPageSubdomain = "status"
+
+// StatusPageConfig publishes an unauthenticated page showing whether the
+// services are up, without any other detail, for sharing with friends
+type StatusPageConfig struct {
+	Enabled   bool     `mapstructure:"enabled" yaml:"enabled"`
+	Subdomain string   `mapstructure:"subdomain" yaml:"subdomain,omitempty"` // Default status
+	Title     string   `mapstructure:"title" yaml:"title,omitempty"`         // Page heading (default: the domain)
+	Services  []string `mapstructure:"services" yaml:"services,omitempty"`   // Services shown (default: the routed ones)
+}
+
+// SubdomainName returns the subdomain of the status page, defaulting to status
+func (s StatusPageConfig) SubdomainName() string {
+	if s.Subdomain == "" {
+		return DefaultStatusPageSubdomain
+	}
+	return s.Subdomain
+}
+
+// Host returns the hostname of the status page
+func (s StatusPageConfig) Host(domain string) string {
+	return s.SubdomainName() + "." + domain
+}
+
+// Heading returns the title of the page, defaulting to the domain
+func (s StatusPageConfig) Heading(domain string) string {
+	if s.Title != "" {
+		return s.Title
+	}
+	return domain
+}
+
+var statusPageSubdomainRegex = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]*[a-z0-9])?$`)
+
+// validateStatusPage checks the status_page section
+func validateStatusPage(s StatusPageConfig, sites []StaticSiteConfig) error {
+	if !s.Enabled {
+		return nil
+	}
+	if s.Subdomain != "" && !statusPageSubdomainRegex.MatchString(s.Subdomain) {
+		return NewValidationError("status_page.subdomain",
+			fmt.Sprintf("invalid subdomain %q - use lowercase letters, digits and dashes", s.Subdomain))
+	}
+	for _, site := range sites {
+		if site.Subdomain == s.SubdomainName() {
+			return NewValidationError("status_page.subdomain",
+				fmt.Sprintf("subdomain %q is already used by static site %q", site.Subdomain, site.Name))
+		}
+	}
+	for i, name := range s.Services {
+		if name == "" {
+			return NewValidationError(fmt.Sprintf("status_page.services[%d]", i), "service name is required")
+		}
+	}
+	return nil
+}
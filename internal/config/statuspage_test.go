@@ -0,0 +1,33 @@
+package config
+
+import "testing"
+
+func TestValidateStatusPage(t *testing.T) {
+	sites := []StaticSiteConfig{{Name: "landing", Directory: "./www", Subdomain: "www"}}
+	tests := []struct {
+		name    string
+		page    StatusPageConfig
+		wantErr bool
+	}{
+		{"disabled", StatusPageConfig{Subdomain: "Not Valid"}, false},
+		{"defaults", StatusPageConfig{Enabled: true}, false},
+		{"custom subdomain", StatusPageConfig{Enabled: true, Subdomain: "up", Services: []string{"plex"}}, false},
+		{"bad subdomain", StatusPageConfig{Enabled: true, Subdomain: "status.page"}, true},
+		{"static site subdomain", StatusPageConfig{Enabled: true, Subdomain: "www"}, true},
+		{"empty service", StatusPageConfig{Enabled: true, Services: []string{""}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateStatusPage(tt.page, sites); (err != nil) != tt.wantErr {
+				t.Errorf("validateStatusPage() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestStatusPageConfigDefaults(t *testing.T) {
+	var s StatusPageConfig
+	if s.Host("example.com") != "status.example.com" || s.Heading("example.com") != "example.com" {
+		t.Errorf("Host() = %s, Heading() = %s", s.Host("example.com"), s.Heading("example.com"))
+	}
+}
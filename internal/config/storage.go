@@ -0,0 +1,156 @@
+package config
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// How the rclone mount runs
+const (
+	RcloneModeContainer = "container" // sdbx-rclone container sharing the mount with the host (default)
+	RcloneModeSystemd   = "systemd"   // Host rclone started by a generated systemd unit
+)
+
+// DefaultRcloneConfig is the rclone.conf used when storage.rclone.config is unset
+const DefaultRcloneConfig = "./configs/rclone/rclone.conf"
+
+// DefaultRcloneOptions are passed to rclone mount before storage.rclone.options
+var DefaultRcloneOptions = []string{
+	"--allow-other",
+	"--vfs-cache-mode=full",
+	"--dir-cache-time=1000h",
+	"--poll-interval=15s",
+}
+
+// DefaultMergerfsOptions are used when storage.mergerfs.options is unset.
+// New files land on the first branch, so local disks come before the remote.
+var DefaultMergerfsOptions = []string{
+	"allow_other",
+	"cache.files=partial",
+	"dropcacheonclose=true",
+	"category.create=ff",
+}
+
+// StorageConfig mounts cloud storage with rclone and pools it with local
+// disks through mergerfs. Media services mount the pool as media_path.
+type StorageConfig struct {
+	Rclone   *RcloneConfig   `mapstructure:"rclone" yaml:"rclone,omitempty"`
+	Mergerfs *MergerfsConfig `mapstructure:"mergerfs" yaml:"mergerfs,omitempty"`
+}
+
+// RcloneConfig mounts an rclone remote on the host
+type RcloneConfig struct {
+	Remote  string   `mapstructure:"remote" yaml:"remote"`             // remote:path from rclone.conf (e.g. gdrive:media)
+	Mount   string   `mapstructure:"mount" yaml:"mount"`               // Host mountpoint
+	Mode    string   `mapstructure:"mode" yaml:"mode,omitempty"`       // container (default) | systemd
+	Config  string   `mapstructure:"config" yaml:"config,omitempty"`   // rclone.conf path (default ./configs/rclone/rclone.conf)
+	Options []string `mapstructure:"options" yaml:"options,omitempty"` // Extra rclone mount flags (e.g. --vfs-cache-max-size=100G)
+}
+
+// MergerfsConfig pools local and remote directories into one tree
+type MergerfsConfig struct {
+	Mount    string   `mapstructure:"mount" yaml:"mount"`               // Host mountpoint of the pool
+	Branches []string `mapstructure:"branches" yaml:"branches"`         // Pooled directories, in create order (e.g. /mnt/local, /mnt/remote=NC)
+	Options  []string `mapstructure:"options" yaml:"options,omitempty"` // Replaces DefaultMergerfsOptions
+}
+
+// IsEnabled reports whether any storage mount is configured
+func (s StorageConfig) IsEnabled() bool {
+	return s.Rclone != nil || s.Mergerfs != nil
+}
+
+// RcloneMode returns the mode, defaulting to container
+func (r RcloneConfig) RcloneMode() string {
+	if r.Mode == "" {
+		return RcloneModeContainer
+	}
+	return r.Mode
+}
+
+// ConfigFile returns the rclone.conf path, defaulting to DefaultRcloneConfig
+func (r RcloneConfig) ConfigFile() string {
+	if r.Config == "" {
+		return DefaultRcloneConfig
+	}
+	return r.Config
+}
+
+// MountOptions returns the rclone mount flags, defaults first
+func (r RcloneConfig) MountOptions() []string {
+	return append(slices.Clone(DefaultRcloneOptions), r.Options...)
+}
+
+// MountOptions returns the mergerfs -o options
+func (m MergerfsConfig) MountOptions() []string {
+	if len(m.Options) == 0 {
+		return DefaultMergerfsOptions
+	}
+	return m.Options
+}
+
+// BranchPath returns a branch directory without its =RW/=RO/=NC mode suffix
+func BranchPath(branch string) string {
+	path, _, _ := strings.Cut(branch, "=")
+	return path
+}
+
+// branchModes are the mergerfs branch modes accepted after '='
+var branchModes = []string{"RW", "RO", "NC"}
+
+// validateStorage checks the rclone and mergerfs mounts
+func validateStorage(s StorageConfig) error {
+	if r := s.Rclone; r != nil {
+		if remote, _, ok := strings.Cut(r.Remote, ":"); !ok || remote == "" {
+			return NewValidationError("storage.rclone.remote", fmt.Sprintf("invalid remote %q (e.g. gdrive:media)", r.Remote))
+		}
+		if !strings.HasPrefix(r.Mount, "/") {
+			return NewValidationError("storage.rclone.mount", "must be an absolute host path")
+		}
+		validModes := []string{RcloneModeContainer, RcloneModeSystemd}
+		if !slices.Contains(validModes, r.RcloneMode()) {
+			return NewValidationError("storage.rclone.mode",
+				fmt.Sprintf("must be one of: %s", strings.Join(validModes, ", ")))
+		}
+		for _, opt := range r.Options {
+			if !strings.HasPrefix(opt, "--") || strings.ContainsAny(opt, " \t") {
+				return NewValidationError("storage.rclone.options",
+					fmt.Sprintf("invalid flag %q (one --flag=value per entry)", opt))
+			}
+		}
+	}
+
+	if m := s.Mergerfs; m != nil {
+		if !strings.HasPrefix(m.Mount, "/") {
+			return NewValidationError("storage.mergerfs.mount", "must be an absolute host path")
+		}
+		if len(m.Branches) < 2 {
+			return NewValidationError("storage.mergerfs.branches", "at least two branches are required")
+		}
+		for _, branch := range m.Branches {
+			path, mode, hasMode := strings.Cut(branch, "=")
+			if !strings.HasPrefix(path, "/") || strings.Contains(path, ":") {
+				return NewValidationError("storage.mergerfs.branches",
+					fmt.Sprintf("invalid branch %q (absolute host path)", branch))
+			}
+			if hasMode && !slices.Contains(branchModes, mode) {
+				return NewValidationError("storage.mergerfs.branches",
+					fmt.Sprintf("invalid branch mode %q (one of: %s)", mode, strings.Join(branchModes, ", ")))
+			}
+			if path == m.Mount {
+				return NewValidationError("storage.mergerfs.branches", "a branch cannot be the pool mount itself")
+			}
+		}
+		for _, opt := range m.Options {
+			if opt == "" || strings.ContainsAny(opt, ", ") {
+				return NewValidationError("storage.mergerfs.options",
+					fmt.Sprintf("invalid option %q (one option per entry, e.g. category.create=mfs)", opt))
+			}
+		}
+	}
+
+	if s.Rclone != nil && s.Mergerfs != nil && s.Rclone.Mount == s.Mergerfs.Mount {
+		return NewValidationError("storage.mergerfs.mount", "must differ from storage.rclone.mount")
+	}
+	return nil
+}
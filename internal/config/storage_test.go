@@ -0,0 +1,60 @@
+package config
+
+import "testing"
+
+func TestValidateStorage(t *testing.T) {
+	rclone := func(r RcloneConfig) StorageConfig { return StorageConfig{Rclone: &r} }
+	mergerfs := func(m MergerfsConfig) StorageConfig { return StorageConfig{Mergerfs: &m} }
+
+	tests := []struct {
+		name    string
+		storage StorageConfig
+		wantErr bool
+	}{
+		{"none", StorageConfig{}, false},
+		{"rclone container", rclone(RcloneConfig{Remote: "gdrive:media", Mount: "/mnt/remote"}), false},
+		{"rclone systemd", rclone(RcloneConfig{Remote: "gdrive:", Mount: "/mnt/remote", Mode: RcloneModeSystemd, Options: []string{"--vfs-cache-max-size=100G"}}), false},
+		{"remote without colon", rclone(RcloneConfig{Remote: "gdrive", Mount: "/mnt/remote"}), true},
+		{"relative rclone mount", rclone(RcloneConfig{Remote: "gdrive:", Mount: "mnt/remote"}), true},
+		{"unknown mode", rclone(RcloneConfig{Remote: "gdrive:", Mount: "/mnt/remote", Mode: "fstab"}), true},
+		{"bare option", rclone(RcloneConfig{Remote: "gdrive:", Mount: "/mnt/remote", Options: []string{"allow-other"}}), true},
+		{"mergerfs", mergerfs(MergerfsConfig{Mount: "/mnt/merged", Branches: []string{"/mnt/local=RW", "/mnt/remote=NC"}}), false},
+		{"single branch", mergerfs(MergerfsConfig{Mount: "/mnt/merged", Branches: []string{"/mnt/local"}}), true},
+		{"relative branch", mergerfs(MergerfsConfig{Mount: "/mnt/merged", Branches: []string{"/mnt/local", "remote"}}), true},
+		{"bad branch mode", mergerfs(MergerfsConfig{Mount: "/mnt/merged", Branches: []string{"/mnt/local", "/mnt/remote=XX"}}), true},
+		{"branch is mount", mergerfs(MergerfsConfig{Mount: "/mnt/merged", Branches: []string{"/mnt/local", "/mnt/merged"}}), true},
+		{"joined options", mergerfs(MergerfsConfig{Mount: "/mnt/merged", Branches: []string{"/a", "/b"}, Options: []string{"allow_other,use_ino"}}), true},
+		{"same mountpoint", StorageConfig{
+			Rclone:   &RcloneConfig{Remote: "gdrive:", Mount: "/mnt/media"},
+			Mergerfs: &MergerfsConfig{Mount: "/mnt/media", Branches: []string{"/mnt/local", "/mnt/remote"}},
+		}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateStorage(tt.storage)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateStorage() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestStorageDefaults(t *testing.T) {
+	r := RcloneConfig{Remote: "gdrive:", Mount: "/mnt/remote", Options: []string{"--vfs-cache-max-size=100G"}}
+	if r.RcloneMode() != RcloneModeContainer || r.ConfigFile() != DefaultRcloneConfig {
+		t.Errorf("unexpected defaults: mode %q, config %q", r.RcloneMode(), r.ConfigFile())
+	}
+	opts := r.MountOptions()
+	if len(opts) != len(DefaultRcloneOptions)+1 || opts[len(opts)-1] != "--vfs-cache-max-size=100G" {
+		t.Errorf("MountOptions() = %v, want defaults then extra flags", opts)
+	}
+
+	m := MergerfsConfig{Options: []string{"category.create=mfs"}}
+	if got := m.MountOptions(); len(got) != 1 {
+		t.Errorf("options should replace the defaults: %v", got)
+	}
+	if got := BranchPath("/mnt/remote=NC"); got != "/mnt/remote" {
+		t.Errorf("BranchPath() = %q", got)
+	}
+}
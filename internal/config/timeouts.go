@@ -0,0 +1,52 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+// Timeouts used when the timeouts section leaves them unset
+const (
+	DefaultComposeTimeout = 30 * time.Minute
+	DefaultHealthTimeout  = 30 * time.Second
+)
+
+// TimeoutsConfig limits how long sdbx waits on Docker. Source fetches are
+// limited by cache.timeout in sources.yaml, shared by every project.
+type TimeoutsConfig struct {
+	Compose string `mapstructure:"compose" yaml:"compose,omitempty"` // Each docker compose command: up, down, restart... (default 30m)
+	Health  string `mapstructure:"health" yaml:"health,omitempty"`   // Wait for a service to become healthy after an update (default 30s)
+}
+
+// ComposeTimeout returns the parsed Compose timeout, defaulting to DefaultComposeTimeout
+func (t TimeoutsConfig) ComposeTimeout() time.Duration {
+	return parseTimeout(t.Compose, DefaultComposeTimeout)
+}
+
+// HealthTimeout returns the parsed Health timeout, defaulting to DefaultHealthTimeout
+func (t TimeoutsConfig) HealthTimeout() time.Duration {
+	return parseTimeout(t.Health, DefaultHealthTimeout)
+}
+
+func parseTimeout(s string, def time.Duration) time.Duration {
+	if d, err := time.ParseDuration(s); err == nil && d > 0 {
+		return d
+	}
+	return def
+}
+
+// validateTimeouts checks the timeouts are positive durations
+func validateTimeouts(t TimeoutsConfig) error {
+	for _, timeout := range []struct{ field, value string }{
+		{"timeouts.compose", t.Compose},
+		{"timeouts.health", t.Health},
+	} {
+		if timeout.value == "" {
+			continue
+		}
+		if d, err := time.ParseDuration(timeout.value); err != nil || d <= 0 {
+			return NewValidationError(timeout.field, fmt.Sprintf("invalid duration %q (e.g. 10m or 45s)", timeout.value))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTimeoutsDefaults(t *testing.T) {
+	var timeouts TimeoutsConfig
+	if got := timeouts.ComposeTimeout(); got != DefaultComposeTimeout {
+		t.Errorf("ComposeTimeout() = %s, want %s", got, DefaultComposeTimeout)
+	}
+	if got := timeouts.HealthTimeout(); got != DefaultHealthTimeout {
+		t.Errorf("HealthTimeout() = %s, want %s", got, DefaultHealthTimeout)
+	}
+
+	timeouts = TimeoutsConfig{Compose: "1h", Health: "2m"}
+	if got := timeouts.ComposeTimeout(); got != time.Hour {
+		t.Errorf("ComposeTimeout() = %s, want 1h", got)
+	}
+	if got := timeouts.HealthTimeout(); got != 2*time.Minute {
+		t.Errorf("HealthTimeout() = %s, want 2m", got)
+	}
+}
+
+func TestValidateTimeouts(t *testing.T) {
+	tests := []struct {
+		name     string
+		timeouts TimeoutsConfig
+		wantErr  bool
+	}{
+		{"unset", TimeoutsConfig{}, false},
+		{"set", TimeoutsConfig{Compose: "45m", Health: "90s"}, false},
+		{"bad compose", TimeoutsConfig{Compose: "forever"}, true},
+		{"zero health", TimeoutsConfig{Health: "0s"}, true},
+		{"negative compose", TimeoutsConfig{Compose: "-1m"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateTimeouts(tt.timeouts)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateTimeouts() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
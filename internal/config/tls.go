@@ -0,0 +1,62 @@
+package config
+
+import "fmt"
+
+// TLS providers of direct mode
+const (
+	TLSProviderACME   = "acme"   // Let's Encrypt through Traefik's certificate resolver
+	TLSProviderCustom = "custom" // Certificates of an internal CA (e.g. step-ca) or bought ones
+)
+
+// TLSCertificate is a certificate and its key, PEM files on the host.
+// Relative paths are relative to the project directory.
+type TLSCertificate struct {
+	CertFile string `mapstructure:"cert_file" yaml:"cert_file"` // Certificate, followed by its intermediates
+	KeyFile  string `mapstructure:"key_file" yaml:"key_file"`
+}
+
+// UsesACME reports whether Traefik obtains the certificates from Let's Encrypt
+func (t TLSConfig) UsesACME() bool {
+	return t.Provider == "" || t.Provider == TLSProviderACME
+}
+
+// CustomCertificates returns the certificates of the custom provider, the
+// default one (cert_file and key_file) first
+func (t TLSConfig) CustomCertificates() []TLSCertificate {
+	if t.Provider != TLSProviderCustom {
+		return nil
+	}
+	var certs []TLSCertificate
+	if t.CertFile != "" || t.KeyFile != "" {
+		certs = append(certs, TLSCertificate{CertFile: t.CertFile, KeyFile: t.KeyFile})
+	}
+	return append(certs, t.Certificates...)
+}
+
+// validateTLS checks the custom provider has certificates, each with its
+// key. The files are checked by sdbx doctor, since the web UI container
+// does not see host paths.
+func validateTLS(t TLSConfig) error {
+	if t.Provider != TLSProviderCustom {
+		if t.CABundle != "" || len(t.Certificates) > 0 {
+			return NewValidationError("expose.tls.provider", "certificates and ca_bundle need provider custom")
+		}
+		return nil
+	}
+	certs := t.CustomCertificates()
+	if len(certs) == 0 {
+		return NewValidationError("expose.tls.cert_file", "the custom provider needs cert_file and key_file, or certificates")
+	}
+	for i, c := range certs {
+		field := "expose.tls"
+		if t.CertFile == "" && t.KeyFile == "" {
+			field = fmt.Sprintf("expose.tls.certificates[%d]", i)
+		} else if i > 0 {
+			field = fmt.Sprintf("expose.tls.certificates[%d]", i-1)
+		}
+		if c.CertFile == "" || c.KeyFile == "" {
+			return NewValidationError(field, "cert_file and key_file are both required")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateTLS(t *testing.T) {
+	tests := []struct {
+		name    string
+		tls     TLSConfig
+		wantErr string
+	}{
+		{"acme", TLSConfig{Provider: TLSProviderACME}, ""},
+		{"wildcard", TLSConfig{Provider: TLSProviderCustom, CertFile: "certs/wildcard.crt", KeyFile: "certs/wildcard.key", CABundle: "certs/root_ca.crt"}, ""},
+		{"per domain", TLSConfig{Provider: TLSProviderCustom, Certificates: []TLSCertificate{{CertFile: "a.crt", KeyFile: "a.key"}}}, ""},
+		{"no certificate", TLSConfig{Provider: TLSProviderCustom}, "expose.tls.cert_file"},
+		{"missing key", TLSConfig{Provider: TLSProviderCustom, CertFile: "a.crt", Certificates: []TLSCertificate{{CertFile: "b.crt", KeyFile: "b.key"}}}, "[expose.tls]"},
+		{"missing key in list", TLSConfig{Provider: TLSProviderCustom, CertFile: "a.crt", KeyFile: "a.key", Certificates: []TLSCertificate{{CertFile: "b.crt"}}}, "certificates[0]"},
+		{"bundle without custom", TLSConfig{Provider: TLSProviderACME, CABundle: "root.crt"}, "need provider custom"},
+	}
+	for _, tt := range tests {
+		err := validateTLS(tt.tls)
+		if tt.wantErr == "" {
+			if err != nil {
+				t.Errorf("%s: validateTLS() = %v", tt.name, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("%s: validateTLS() = %v, want %q", tt.name, err, tt.wantErr)
+		}
+	}
+
+	custom := TLSConfig{Provider: TLSProviderCustom, CertFile: "a.crt", KeyFile: "a.key", Certificates: []TLSCertificate{{CertFile: "b.crt", KeyFile: "b.key"}}}
+	if got := custom.CustomCertificates(); len(got) != 2 || got[0].CertFile != "a.crt" {
+		t.Errorf("CustomCertificates() = %v, want the default certificate first", got)
+	}
+	if (TLSConfig{}).UsesACME() != true || custom.UsesACME() {
+		t.Error("UsesACME() should default to true and be false for custom")
+	}
+}
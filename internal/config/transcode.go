@@ -0,0 +1,24 @@
+package config
+
+// Hardware transcoding methods of services.<name>.transcode
+const (
+	TranscodeVAAPI  = "vaapi"  // Intel or AMD GPU through /dev/dri (VA-API, Quick Sync)
+	TranscodeNVIDIA = "nvidia" // NVIDIA GPU through the NVIDIA container toolkit (NVENC)
+	TranscodeNone   = "none"   // Software transcoding
+)
+
+// TranscodeMethods lists the valid transcode values
+var TranscodeMethods = []string{TranscodeVAAPI, TranscodeNVIDIA, TranscodeNone}
+
+// ServiceTranscode returns the hardware transcoding method of a service
+// whose definition supports it: its transcode override, or vaapi when
+// hardware_transcode is on
+func (c *Config) ServiceTranscode(service string) string {
+	if t := c.Services[service].Transcode; t != "" {
+		return t
+	}
+	if c.HardwareTranscode {
+		return TranscodeVAAPI
+	}
+	return TranscodeNone
+}
@@ -0,0 +1,27 @@
+package config
+
+import "testing"
+
+func TestServiceTranscode(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.HardwareTranscode = false
+	if got := cfg.ServiceTranscode("plex"); got != TranscodeNone {
+		t.Errorf("ServiceTranscode() = %s without hardware_transcode", got)
+	}
+	cfg.HardwareTranscode = true
+	if got := cfg.ServiceTranscode("plex"); got != TranscodeVAAPI {
+		t.Errorf("ServiceTranscode() = %s with hardware_transcode", got)
+	}
+	cfg.Services["plex"] = ServiceOverride{Transcode: TranscodeNVIDIA}
+	if got := cfg.ServiceTranscode("plex"); got != TranscodeNVIDIA {
+		t.Errorf("ServiceTranscode() = %s with an override", got)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Validate() = %v", err)
+	}
+
+	cfg.Services["plex"] = ServiceOverride{Transcode: "qsv"}
+	if err := cfg.Validate(); err == nil {
+		t.Error("Validate() accepted an unknown transcode method")
+	}
+}
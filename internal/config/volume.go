@@ -0,0 +1,85 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Volume types, all mounted by Docker's local volume driver
+const (
+	VolumeTypeLocal = "local" // Docker-managed, or a bind of Path when set
+	VolumeTypeNFS   = "nfs"   // NFS export Path on Server
+	VolumeTypeCIFS  = "cifs"  // SMB share Path on Server
+)
+
+// VolumeTypes lists every supported volume type
+var VolumeTypes = []string{VolumeTypeLocal, VolumeTypeNFS, VolumeTypeCIFS}
+
+// VolumeDefinition declares a named volume rendered as a top-level compose
+// volume, so services can mount remote storage without a host mount.
+type VolumeDefinition struct {
+	Type     string   `mapstructure:"type" yaml:"type,omitempty"`         // Defaults to local
+	Server   string   `mapstructure:"server" yaml:"server,omitempty"`     // NFS or SMB host
+	Path     string   `mapstructure:"path" yaml:"path,omitempty"`         // NFS export, SMB share or local directory
+	Options  []string `mapstructure:"options" yaml:"options,omitempty"`   // Extra mount options (e.g. nfsvers=4, vers=3.0)
+	Username string   `mapstructure:"username" yaml:"username,omitempty"` // SMB user
+	Secret   string   `mapstructure:"secret" yaml:"secret,omitempty"`     // Secret holding the SMB password
+}
+
+// VolumeType returns the volume type, defaulting to local
+func (v VolumeDefinition) VolumeType() string {
+	if v.Type == "" {
+		return VolumeTypeLocal
+	}
+	return v.Type
+}
+
+// volumeNameRegex matches names valid as compose volume keys
+var volumeNameRegex = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// ValidateVolumeName checks a volume definition name
+func ValidateVolumeName(name string) error {
+	if !volumeNameRegex.MatchString(name) {
+		return fmt.Errorf("invalid volume name %q (lowercase letters, digits, dashes and underscores)", name)
+	}
+	return nil
+}
+
+// Validate checks that the fields required by the type are set
+func (v VolumeDefinition) Validate() error {
+	switch v.VolumeType() {
+	case VolumeTypeLocal:
+		if v.Server != "" || v.Username != "" || v.Secret != "" {
+			return fmt.Errorf("server, username and secret only apply to nfs and cifs volumes")
+		}
+		if v.Path != "" && !strings.HasPrefix(v.Path, "/") {
+			return fmt.Errorf("path must be absolute")
+		}
+	case VolumeTypeNFS:
+		if v.Server == "" || v.Path == "" {
+			return fmt.Errorf("nfs volumes require server and path")
+		}
+		if !strings.HasPrefix(v.Path, "/") {
+			return fmt.Errorf("path must be the absolute NFS export (e.g. /volume1/media)")
+		}
+		if v.Username != "" || v.Secret != "" {
+			return fmt.Errorf("username and secret only apply to cifs volumes")
+		}
+	case VolumeTypeCIFS:
+		if v.Server == "" || v.Path == "" {
+			return fmt.Errorf("cifs volumes require server and path")
+		}
+		if v.Secret != "" && v.Username == "" {
+			return fmt.Errorf("secret requires username")
+		}
+	default:
+		return fmt.Errorf("type must be one of: %s", strings.Join(VolumeTypes, ", "))
+	}
+	for _, opt := range v.Options {
+		if opt == "" || strings.ContainsAny(opt, ", ") {
+			return fmt.Errorf("invalid option %q (one option per entry, e.g. nfsvers=4)", opt)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,39 @@
+package config
+
+import "testing"
+
+func TestVolumeDefinitionValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		vol     VolumeDefinition
+		wantErr bool
+	}{
+		{"docker managed", VolumeDefinition{}, false},
+		{"local bind", VolumeDefinition{Path: "/mnt/storage"}, false},
+		{"nfs", VolumeDefinition{Type: VolumeTypeNFS, Server: "nas.lan", Path: "/volume1/media", Options: []string{"nfsvers=4", "rw"}}, false},
+		{"cifs", VolumeDefinition{Type: VolumeTypeCIFS, Server: "nas.lan", Path: "media", Username: "sdbx", Secret: "nas_password"}, false},
+		{"unknown type", VolumeDefinition{Type: "s3"}, true},
+		{"relative local path", VolumeDefinition{Path: "storage"}, true},
+		{"local with server", VolumeDefinition{Server: "nas.lan"}, true},
+		{"nfs without server", VolumeDefinition{Type: VolumeTypeNFS, Path: "/media"}, true},
+		{"nfs relative export", VolumeDefinition{Type: VolumeTypeNFS, Server: "nas.lan", Path: "media"}, true},
+		{"nfs credentials", VolumeDefinition{Type: VolumeTypeNFS, Server: "nas.lan", Path: "/media", Secret: "x"}, true},
+		{"cifs secret without user", VolumeDefinition{Type: VolumeTypeCIFS, Server: "nas.lan", Path: "media", Secret: "x"}, true},
+		{"joined options", VolumeDefinition{Type: VolumeTypeNFS, Server: "nas.lan", Path: "/media", Options: []string{"nfsvers=4,rw"}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.vol.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	cfg := DefaultConfig()
+	cfg.Volumes = map[string]VolumeDefinition{"NAS": {}}
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error for invalid volume name")
+	}
+}
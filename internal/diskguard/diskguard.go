@@ -0,0 +1,319 @@
+// Package diskguard pauses the download clients when free space on the
+// downloads path drops below disk_guard.min_free, and resumes them once
+// disk_guard.resume_free is available again.
+package diskguard
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"syscall"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/maiko/sdbx/internal/backup"
+	"github.com/maiko/sdbx/internal/config"
+	"github.com/maiko/sdbx/internal/notify"
+	"github.com/maiko/sdbx/internal/qbittorrent"
+	"github.com/maiko/sdbx/internal/sabnzbd"
+	"github.com/maiko/sdbx/internal/scheduler"
+)
+
+// StateFile records what the guard paused, so only those are resumed
+const StateFile = ".sdbx.diskguard.yaml"
+
+// State is the content of StateFile
+type State struct {
+	Paused   bool      `json:"paused" yaml:"paused"`
+	Since    time.Time `json:"since,omitempty" yaml:"since,omitempty"`
+	Torrents []string  `json:"torrents,omitempty" yaml:"torrents,omitempty"` // Hashes of the torrents stopped
+	SABnzbd  bool      `json:"sabnzbd,omitempty" yaml:"sabnzbd,omitempty"`   // Whether the SABnzbd queue was paused
+}
+
+// TorrentClient is the part of the qBittorrent client the guard uses
+type TorrentClient interface {
+	Torrents(ctx context.Context) ([]qbittorrent.Torrent, error)
+	Stop(ctx context.Context, hashes ...string) error
+	Start(ctx context.Context, hashes ...string) error
+}
+
+// QueueClient is the part of the SABnzbd client the guard uses
+type QueueClient interface {
+	Paused(ctx context.Context) (bool, error)
+	Pause(ctx context.Context) error
+	Resume(ctx context.Context) error
+}
+
+// Guard watches the free space of a path. Either client may be nil.
+type Guard struct {
+	ProjectDir  string
+	Config      *config.Config
+	QBittorrent TorrentClient
+	SABnzbd     QueueClient
+
+	// Overridable for tests
+	Now       func() time.Time
+	FreeSpace func(path string) (int64, error)
+}
+
+// Result is the outcome of a check
+type Result struct {
+	Path       string `json:"path"`
+	Free       int64  `json:"free"`
+	MinFree    int64  `json:"min_free"`
+	ResumeFree int64  `json:"resume_free"`
+	State      State  `json:"state"`
+	Changed    bool   `json:"changed"` // Downloads were paused or resumed by this check
+}
+
+// NewGuard creates a guard for cfg's disk_guard settings
+func NewGuard(projectDir string, cfg *config.Config, qbt TorrentClient, sab QueueClient) *Guard {
+	return &Guard{
+		ProjectDir:  projectDir,
+		Config:      cfg,
+		QBittorrent: qbt,
+		SABnzbd:     sab,
+		Now:         time.Now,
+		FreeSpace:   freeSpace,
+	}
+}
+
+// Path returns the watched path: disk_guard.path, else downloads_path
+func (g *Guard) Path() string {
+	path := g.Config.DiskGuard.Path
+	if path == "" {
+		path = g.Config.DownloadsPath
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(g.ProjectDir, path)
+	}
+	return path
+}
+
+// Check pauses the clients when free space is below min_free, keeps
+// pausing torrents started meanwhile, and resumes what it paused once free
+// space reaches resume_free. The state is saved even when a client fails,
+// so a failed pause is retried and a failed resume is not lost.
+func (g *Guard) Check(ctx context.Context) (Result, error) {
+	minFree, resumeFree := g.Config.DiskGuard.Thresholds()
+	result := Result{Path: g.Path(), MinFree: minFree, ResumeFree: resumeFree}
+
+	free, err := g.FreeSpace(result.Path)
+	if err != nil {
+		return result, err
+	}
+	result.Free = free
+
+	state, err := LoadState(g.ProjectDir)
+	if err != nil {
+		return result, err
+	}
+	wasPaused := state.Paused
+
+	var errs []error
+	switch {
+	case free < minFree:
+		if !state.Paused {
+			state.Paused, state.Since = true, g.Now()
+		}
+		errs = append(errs, g.pause(ctx, state)...)
+	case state.Paused && free >= resumeFree:
+		errs = append(errs, g.resume(ctx, state)...)
+		if len(errs) == 0 {
+			*state = State{}
+		}
+	}
+
+	result.State = *state
+	result.Changed = state.Paused != wasPaused
+	if state.Paused || wasPaused {
+		if err := saveState(g.ProjectDir, state); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return result, errors.Join(errs...)
+}
+
+// pause stops the incomplete torrents still running and the SABnzbd queue
+func (g *Guard) pause(ctx context.Context, state *State) []error {
+	var errs []error
+	if g.QBittorrent != nil {
+		if err := g.stopTorrents(ctx, state); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if g.SABnzbd != nil && !state.SABnzbd {
+		// A queue paused by hand stays paused on resume
+		paused, err := g.SABnzbd.Paused(ctx)
+		if err == nil && !paused {
+			if err = g.SABnzbd.Pause(ctx); err == nil {
+				state.SABnzbd = true
+			}
+		}
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+// stopTorrents stops the incomplete running torrents and records them.
+// Seeding torrents do not write to disk and keep running.
+func (g *Guard) stopTorrents(ctx context.Context, state *State) error {
+	torrents, err := g.QBittorrent.Torrents(ctx)
+	if err != nil {
+		return err
+	}
+	var hashes []string
+	for _, t := range torrents {
+		if !t.Completed() && !t.Stopped() {
+			hashes = append(hashes, t.Hash)
+		}
+	}
+	if len(hashes) == 0 {
+		return nil
+	}
+	if err := g.QBittorrent.Stop(ctx, hashes...); err != nil {
+		return err
+	}
+	for _, hash := range hashes {
+		if !slices.Contains(state.Torrents, hash) {
+			state.Torrents = append(state.Torrents, hash)
+		}
+	}
+	return nil
+}
+
+// resume starts what pause stopped
+func (g *Guard) resume(ctx context.Context, state *State) []error {
+	var errs []error
+	if g.QBittorrent != nil && len(state.Torrents) > 0 {
+		if err := g.QBittorrent.Start(ctx, state.Torrents...); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if g.SABnzbd != nil && state.SABnzbd {
+		if err := g.SABnzbd.Resume(ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+// Message describes a pause or resume of the downloads
+func Message(r Result) notify.Message {
+	if r.State.Paused {
+		return notify.Message{
+			Title: "Downloads paused: disk almost full",
+			Body: fmt.Sprintf("%s free on %s (minimum %s). Downloads resume at %s free.",
+				backup.FormatBytes(r.Free), r.Path, backup.FormatBytes(r.MinFree), backup.FormatBytes(r.ResumeFree)),
+			Severity: notify.SeverityCritical,
+			Source:   "diskguard",
+		}
+	}
+	return notify.Message{
+		Title:    "Downloads resumed",
+		Body:     fmt.Sprintf("%s free on %s again.", backup.FormatBytes(r.Free), r.Path),
+		Severity: notify.SeverityResolved,
+		Source:   "diskguard",
+	}
+}
+
+// LoadState reads the guard state; a missing file means nothing is paused
+func LoadState(projectDir string) (*State, error) {
+	data, err := os.ReadFile(filepath.Join(projectDir, StateFile))
+	if errors.Is(err, fs.ErrNotExist) {
+		return &State{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", StateFile, err)
+	}
+	var state State
+	if err := yaml.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", StateFile, err)
+	}
+	return &state, nil
+}
+
+// saveState writes the guard state
+func saveState(projectDir string, state *State) error {
+	data, err := yaml.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s: %w", StateFile, err)
+	}
+	if err := os.WriteFile(filepath.Join(projectDir, StateFile), data, 0o644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", StateFile, err)
+	}
+	return nil
+}
+
+// freeSpace returns the bytes available to unprivileged users on the
+// filesystem holding path, as df does
+func freeSpace(path string) (int64, error) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(path, &stat); err != nil {
+		return 0, fmt.Errorf("failed to check free space of %s: %w", path, err)
+	}
+	return int64(stat.Bavail) * int64(stat.Bsize), nil
+}
+
+// Job checks free space on the disk_guard interval, notifying when
+// downloads are paused and resumed. inContainer selects the client URLs
+// used when none is configured, as for seeding.Job.
+func Job(projectDir string, interval time.Duration, inContainer bool) scheduler.Job {
+	return scheduler.Job{
+		Name:     "diskguard",
+		Interval: interval,
+		Run: func(ctx context.Context) error {
+			cfg, err := config.Load()
+			if err != nil {
+				return fmt.Errorf("failed to load config: %w", err)
+			}
+			if !cfg.DiskGuard.IsEnabled() {
+				return nil
+			}
+			guard, err := New(projectDir, cfg, inContainer)
+			if err != nil {
+				return err
+			}
+			result, err := guard.Check(ctx)
+			if result.Changed {
+				if nerr := notify.New(projectDir, cfg).Send(ctx, Message(result)); nerr != nil {
+					err = errors.Join(err, fmt.Errorf("failed to notify: %w", nerr))
+				}
+			}
+			return err
+		},
+	}
+}
+
+// New creates a guard with the qBittorrent client, and the SABnzbd client
+// when its API key is configured
+func New(projectDir string, cfg *config.Config, inContainer bool) (*Guard, error) {
+	qbtURL := qbittorrent.HostURL(projectDir, cfg)
+	if inContainer {
+		qbtURL = qbittorrent.ContainerURL(cfg)
+	}
+	qbt, err := qbittorrent.New(projectDir, cfg, qbtURL)
+	if err != nil {
+		return nil, err
+	}
+	guard := NewGuard(projectDir, cfg, qbt, nil)
+
+	if cfg.DownloadClients.SABnzbd.IsEnabled() {
+		if !inContainer && cfg.DownloadClients.SABnzbd.URL == "" {
+			return nil, fmt.Errorf("download_clients.sabnzbd.url is required outside the web UI")
+		}
+		sab, err := sabnzbd.New(projectDir, cfg, sabnzbd.ContainerURL)
+		if err != nil {
+			return nil, err
+		}
+		guard.SABnzbd = sab
+	}
+	return guard, nil
+}
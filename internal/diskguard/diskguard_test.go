@@ -0,0 +1,134 @@
+package diskguard
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/maiko/sdbx/internal/config"
+	"github.com/maiko/sdbx/internal/notify"
+	"github.com/maiko/sdbx/internal/qbittorrent"
+)
+
+const gib = int64(1 << 30)
+
+type fakeTorrents struct {
+	torrents []qbittorrent.Torrent
+	stopped  []string
+	started  []string
+}
+
+func (f *fakeTorrents) Torrents(context.Context) ([]qbittorrent.Torrent, error) {
+	return f.torrents, nil
+}
+
+func (f *fakeTorrents) Stop(_ context.Context, hashes ...string) error {
+	f.stopped = append(f.stopped, hashes...)
+	for i, t := range f.torrents {
+		if slices.Contains(hashes, t.Hash) {
+			f.torrents[i].State = "stoppedDL"
+		}
+	}
+	return nil
+}
+
+func (f *fakeTorrents) Start(_ context.Context, hashes ...string) error {
+	f.started = append(f.started, hashes...)
+	return nil
+}
+
+type fakeQueue struct {
+	paused  bool
+	pauses  int
+	resumes int
+}
+
+func (f *fakeQueue) Paused(context.Context) (bool, error) { return f.paused, nil }
+func (f *fakeQueue) Pause(context.Context) error          { f.paused = true; f.pauses++; return nil }
+func (f *fakeQueue) Resume(context.Context) error         { f.paused = false; f.resumes++; return nil }
+
+func newTestGuard(t *testing.T, free *int64, qbt *fakeTorrents, sab *fakeQueue) *Guard {
+	t.Helper()
+	cfg := config.DefaultConfig()
+	cfg.DiskGuard = config.DiskGuardConfig{MinFree: "10G", ResumeFree: "20G"}
+	g := NewGuard(t.TempDir(), cfg, nil, nil)
+	if qbt != nil {
+		g.QBittorrent = qbt
+	}
+	if sab != nil {
+		g.SABnzbd = sab
+	}
+	g.FreeSpace = func(string) (int64, error) { return *free, nil }
+	return g
+}
+
+func TestGuardPauseAndResume(t *testing.T) {
+	qbt := &fakeTorrents{torrents: []qbittorrent.Torrent{
+		{Hash: "dl", Progress: 0.5, State: "downloading"},
+		{Hash: "seed", Progress: 1, State: "uploading"},
+		{Hash: "manual", Progress: 0.2, State: "pausedDL"},
+	}}
+	sab := &fakeQueue{}
+	free := 50 * gib
+	g := newTestGuard(t, &free, qbt, sab)
+	ctx := context.Background()
+
+	if r, err := g.Check(ctx); err != nil || r.Changed || r.State.Paused {
+		t.Fatalf("plenty of space: %+v, %v", r, err)
+	}
+
+	free = 5 * gib
+	r, err := g.Check(ctx)
+	if err != nil || !r.Changed || !r.State.Paused {
+		t.Fatalf("low space should pause: %+v, %v", r, err)
+	}
+	if !slices.Equal(qbt.stopped, []string{"dl"}) || sab.pauses != 1 {
+		t.Errorf("stopped %v, SABnzbd pauses %d", qbt.stopped, sab.pauses)
+	}
+	if msg := Message(r); msg.Severity != notify.SeverityCritical || !strings.Contains(msg.Body, "5.0 GB free") {
+		t.Errorf("pause message = %+v", msg)
+	}
+
+	// A torrent added while paused is stopped too, without a new notification
+	qbt.torrents = append(qbt.torrents, qbittorrent.Torrent{Hash: "new", Progress: 0, State: "downloading"})
+	if r, err := g.Check(ctx); err != nil || r.Changed || !slices.Equal(r.State.Torrents, []string{"dl", "new"}) {
+		t.Errorf("still low: %+v, %v", r, err)
+	}
+
+	// Between the thresholds nothing changes
+	free = 15 * gib
+	if r, _ := g.Check(ctx); r.Changed || !r.State.Paused {
+		t.Errorf("between thresholds: %+v", r)
+	}
+
+	free = 25 * gib
+	r, err = g.Check(ctx)
+	if err != nil || !r.Changed || r.State.Paused {
+		t.Fatalf("space freed should resume: %+v, %v", r, err)
+	}
+	if !slices.Equal(qbt.started, []string{"dl", "new"}) || sab.resumes != 1 {
+		t.Errorf("started %v, SABnzbd resumes %d", qbt.started, sab.resumes)
+	}
+	if state, _ := LoadState(g.ProjectDir); state.Paused || len(state.Torrents) > 0 {
+		t.Errorf("state after resume = %+v", state)
+	}
+}
+
+func TestGuardKeepsManualPause(t *testing.T) {
+	sab := &fakeQueue{paused: true}
+	free := 1 * gib
+	g := newTestGuard(t, &free, nil, sab)
+	ctx := context.Background()
+
+	if _, err := g.Check(ctx); err != nil {
+		t.Fatal(err)
+	}
+	free = 30 * gib
+	if _, err := g.Check(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if sab.pauses != 0 || sab.resumes != 0 || !sab.paused {
+		t.Errorf("a queue paused by hand should be left alone: %+v", sab)
+	}
+}
@@ -5,10 +5,16 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"os"
 	"os/exec"
+	"regexp"
 	"strings"
 	"time"
+
+	"github.com/maiko/sdbx/internal/config"
+	"github.com/maiko/sdbx/internal/problem"
 )
 
 const (
@@ -16,9 +22,19 @@ const (
 	healthHealthy = "healthy"
 )
 
+// Ownership labels stamped by the generator on every SDBX service
+const (
+	LabelManaged        = "sdbx.managed"
+	LabelService        = "sdbx.service"
+	LabelDefinitionHash = "sdbx.definition-hash"
+	LabelSource         = "sdbx.source"
+)
+
 // Service represents a Docker Compose service
 type Service struct {
 	Name     string `json:"name"`
+	Service  string `json:"service,omitempty"` // SDBX service name (sdbx.service label, else compose service)
+	Managed  bool   `json:"managed"`           // Carries the sdbx.managed label
 	Status   string `json:"status"`
 	Health   string `json:"health,omitempty"`
 	Ports    string `json:"ports,omitempty"`
@@ -27,11 +43,17 @@ type Service struct {
 	ExitCode int    `json:"exit_code,omitempty"`
 }
 
+// cancelWaitDelay is how long a canceled docker command has to exit after
+// its interrupt before it is killed
+const cancelWaitDelay = 10 * time.Second
+
 // Compose handles Docker Compose operations
 type Compose struct {
 	ProjectDir  string
 	ComposeFile string
 	ProjectName string
+	Timeout     time.Duration // Limit of each compose command, 0 for none
+	Offline     bool          // Never pull images: up only uses local ones and Pull fails
 }
 
 // NewCompose creates a new Compose instance
@@ -43,12 +65,29 @@ func NewCompose(projectDir string) *Compose {
 	}
 }
 
+// command returns a docker command that is interrupted when ctx is done,
+// so compose stops what it started as after Ctrl+C, and killed if it has
+// not exited cancelWaitDelay later
+func command(ctx context.Context, args ...string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, "docker", args...)
+	cmd.Cancel = func() error {
+		return cmd.Process.Signal(os.Interrupt)
+	}
+	cmd.WaitDelay = cancelWaitDelay
+	return cmd
+}
+
 // run executes a docker compose command
 func (c *Compose) run(ctx context.Context, args ...string) (string, error) {
 	cmdArgs := []string{"compose", "-f", c.ComposeFile, "-p", c.ProjectName}
 	cmdArgs = append(cmdArgs, args...)
 
-	cmd := exec.CommandContext(ctx, "docker", cmdArgs...)
+	if c.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.Timeout)
+		defer cancel()
+	}
+	cmd := command(ctx, cmdArgs...)
 	cmd.Dir = c.ProjectDir
 
 	var stdout, stderr bytes.Buffer
@@ -56,18 +95,63 @@ func (c *Compose) run(ctx context.Context, args ...string) (string, error) {
 	cmd.Stderr = &stderr
 
 	if err := cmd.Run(); err != nil {
-		return "", fmt.Errorf("%w: %s", err, stderr.String())
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return "", fmt.Errorf("docker compose %s timed out after %s (timeouts.compose in .sdbx.yaml)", args[0], c.Timeout)
+		}
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
+		return "", commandError(fmt.Errorf("%w: %s", err, stderr.String()), stderr.String(), "docker compose "+args[0])
 	}
 
 	return stdout.String(), nil
 }
 
-// Up starts all services
+// Docker CLI messages revealing the cause of a failed command
+var (
+	daemonErrorRegex = regexp.MustCompile(`(?i)cannot connect to the docker daemon|is the docker daemon running|permission denied while trying to connect to the docker`)
+	portErrorRegex   = regexp.MustCompile(`(?i)port is already allocated|address already in use`)
+)
+
+// commandError gives the error of a failed docker command the problem kind
+// its output reveals, if any
+func commandError(err error, stderr, command string) error {
+	switch {
+	case daemonErrorRegex.MatchString(stderr):
+		return problem.Wrap(problem.ErrDockerDaemon, err, "%s failed", command)
+	case portErrorRegex.MatchString(stderr):
+		return problem.Wrap(problem.ErrPortConflict, err, "%s failed", command)
+	}
+	return err
+}
+
+// Up starts all services. Containers of services no longer in compose.yaml
+// are removed, unless the last generation skipped services it has no block
+// for: their containers may still run from before the failure.
 func (c *Compose) Up(ctx context.Context) error {
-	_, err := c.run(ctx, "up", "-d", "--remove-orphans")
+	var args []string
+	if state, err := config.ReadState(c.ProjectDir); err == nil && len(state.Dropped()) == 0 {
+		args = append(args, "--remove-orphans")
+	}
+	_, err := c.run(ctx, c.upArgs(args...)...)
 	return err
 }
 
+// UpService creates and starts a single service without touching the others
+func (c *Compose) UpService(ctx context.Context, service string) error {
+	_, err := c.run(ctx, c.upArgs(service)...)
+	return err
+}
+
+// upArgs returns the arguments of docker compose up -d
+func (c *Compose) upArgs(args ...string) []string {
+	up := []string{"up", "-d"}
+	if c.Offline {
+		up = append(up, "--pull", "never")
+	}
+	return append(up, args...)
+}
+
 // Down stops all services
 func (c *Compose) Down(ctx context.Context) error {
 	_, err := c.run(ctx, "down")
@@ -104,9 +188,12 @@ func (c *Compose) Restart(ctx context.Context, service string) error {
 	return err
 }
 
-// Pull pulls images for all services
-func (c *Compose) Pull(ctx context.Context) error {
-	_, err := c.run(ctx, "pull")
+// Pull pulls images for the given services, or all services when none are given
+func (c *Compose) Pull(ctx context.Context, services ...string) error {
+	if c.Offline {
+		return problem.Wrap(problem.ErrOffline, nil, "docker compose pull needs network access")
+	}
+	_, err := c.run(ctx, append([]string{"pull"}, services...)...)
 	return err
 }
 
@@ -125,6 +212,19 @@ func (c *Compose) Logs(ctx context.Context, service string, lines int, follow bo
 	return c.run(ctx, args...)
 }
 
+// LogsRange returns the logs of a service between since and until, with
+// their timestamps. Zero times do not bound the range.
+func (c *Compose) LogsRange(ctx context.Context, service string, since, until time.Time) (string, error) {
+	args := []string{"logs", "--no-color", "--timestamps"}
+	if !since.IsZero() {
+		args = append(args, "--since", since.UTC().Format(time.RFC3339))
+	}
+	if !until.IsZero() {
+		args = append(args, "--until", until.UTC().Format(time.RFC3339))
+	}
+	return c.run(ctx, append(args, service)...)
+}
+
 // LogsStream returns a streaming reader for service logs
 func (c *Compose) LogsStream(ctx context.Context, service string, lines int) (*exec.Cmd, error) {
 	cmdArgs := []string{"compose", "-f", c.ComposeFile, "-p", c.ProjectName, "logs"}
@@ -133,15 +233,25 @@ func (c *Compose) LogsStream(ctx context.Context, service string, lines int) (*e
 	}
 	cmdArgs = append(cmdArgs, "-f", service)
 
-	cmd := exec.CommandContext(ctx, "docker", cmdArgs...)
+	cmd := command(ctx, cmdArgs...)
 	cmd.Dir = c.ProjectDir
 
 	return cmd, nil
 }
 
-// PS returns the status of all services
+// PS returns the status of all running services
 func (c *Compose) PS(ctx context.Context) ([]Service, error) {
-	output, err := c.run(ctx, "ps", "--format", "json")
+	return c.ps(ctx)
+}
+
+// PSAll returns the status of all service containers, including stopped ones
+func (c *Compose) PSAll(ctx context.Context) ([]Service, error) {
+	return c.ps(ctx, "--all")
+}
+
+// ps runs docker compose ps with extra arguments and parses its JSON output
+func (c *Compose) ps(ctx context.Context, args ...string) ([]Service, error) {
+	output, err := c.run(ctx, append([]string{"ps", "--format", "json"}, args...)...)
 	if err != nil {
 		return nil, err
 	}
@@ -154,6 +264,8 @@ func (c *Compose) PS(ctx context.Context) ([]Service, error) {
 		}
 		var svc struct {
 			Name     string `json:"Name"`
+			Service  string `json:"Service"`
+			Labels   string `json:"Labels"`
 			State    string `json:"State"`
 			Health   string `json:"Health"`
 			Image    string `json:"Image"`
@@ -163,8 +275,15 @@ func (c *Compose) PS(ctx context.Context) ([]Service, error) {
 		if err := json.Unmarshal([]byte(line), &svc); err != nil {
 			continue
 		}
+		labels := parseLabels(svc.Labels)
+		name := labels[LabelService]
+		if name == "" {
+			name = svc.Service
+		}
 		services = append(services, Service{
 			Name:     svc.Name,
+			Service:  name,
+			Managed:  labels[LabelManaged] == "true",
 			Status:   svc.State,
 			Health:   svc.Health,
 			Image:    svc.Image,
@@ -177,6 +296,21 @@ func (c *Compose) PS(ctx context.Context) ([]Service, error) {
 	return services, nil
 }
 
+// ImageRepoDigests returns the registry digests (repo@sha256:...) of a local image
+func (c *Compose) ImageRepoDigests(ctx context.Context, image string) ([]string, error) {
+	cmd := command(ctx, "image", "inspect", "--format", "{{json .RepoDigests}}", image)
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to inspect image %s: %w", image, err)
+	}
+
+	var digests []string
+	if err := json.Unmarshal(bytes.TrimSpace(output), &digests); err != nil {
+		return nil, fmt.Errorf("failed to parse digests for %s: %w", image, err)
+	}
+	return digests, nil
+}
+
 // Exec executes a command in a running container
 func (c *Compose) Exec(ctx context.Context, service string, cmd ...string) (string, error) {
 	args := []string{"exec", "-T", service}
@@ -184,6 +318,12 @@ func (c *Compose) Exec(ctx context.Context, service string, cmd ...string) (stri
 	return c.run(ctx, args...)
 }
 
+// Healthy reports whether the service is running and, if it has a
+// healthcheck, passing it
+func (s Service) Healthy() bool {
+	return s.Running && (s.Health == "" || s.Health == healthHealthy)
+}
+
 // IsHealthy checks if a service is healthy
 func (c *Compose) IsHealthy(ctx context.Context, service string) (bool, error) {
 	services, err := c.PS(ctx)
@@ -193,7 +333,7 @@ func (c *Compose) IsHealthy(ctx context.Context, service string) (bool, error) {
 
 	for _, svc := range services {
 		if strings.Contains(svc.Name, service) {
-			return svc.Running && (svc.Health == "" || svc.Health == healthHealthy), nil
+			return svc.Healthy(), nil
 		}
 	}
 
@@ -209,7 +349,11 @@ func (c *Compose) WaitHealthy(ctx context.Context, service string, timeout time.
 		if err == nil && healthy {
 			return nil
 		}
-		time.Sleep(2 * time.Second)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(2 * time.Second):
+		}
 	}
 
 	return fmt.Errorf("timeout waiting for %s to become healthy", service)
@@ -3,10 +3,16 @@ package docker
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
 	"strings"
 	"testing"
 	"time"
+
+	"github.com/maiko/sdbx/internal/problem"
 )
 
 func TestNewCompose(t *testing.T) {
@@ -217,8 +223,7 @@ func TestHealthyServiceDetection(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			// Simulate IsHealthy logic
-			isHealthy := tt.service.Running && (tt.service.Health == "" || tt.service.Health == "healthy")
+			isHealthy := tt.service.Healthy()
 			if isHealthy != tt.expected {
 				t.Errorf("IsHealthy = %v, want %v (Running=%v, Health=%s)",
 					isHealthy, tt.expected, tt.service.Running, tt.service.Health)
@@ -352,3 +357,103 @@ func TestLogsCommandArgs(t *testing.T) {
 		})
 	}
 }
+
+// fakeDocker puts a docker script running body first on PATH
+func fakeDocker(t *testing.T, body string) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("shell script")
+	}
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "docker"), []byte("#!/bin/sh\n"+body+"\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+}
+
+func TestComposeRunTimeout(t *testing.T) {
+	fakeDocker(t, "exec sleep 5")
+	compose := NewCompose(t.TempDir())
+	compose.Timeout = 100 * time.Millisecond
+
+	start := time.Now()
+	err := compose.Up(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "timed out after 100ms") {
+		t.Fatalf("Up() error = %v, want a timeout", err)
+	}
+	if elapsed := time.Since(start); elapsed > 3*time.Second {
+		t.Errorf("Up() returned after %s, want the timeout", elapsed)
+	}
+}
+
+func TestComposeOffline(t *testing.T) {
+	args := filepath.Join(t.TempDir(), "args")
+	fakeDocker(t, "echo \"$@\" > "+args)
+	compose := NewCompose(t.TempDir())
+	compose.Offline = true
+
+	if err := compose.UpService(context.Background(), "plex"); err != nil {
+		t.Fatal(err)
+	}
+	got, _ := os.ReadFile(args)
+	if !strings.HasSuffix(strings.TrimSpace(string(got)), "up -d --pull never plex") {
+		t.Errorf("docker args = %q, want up -d --pull never plex", got)
+	}
+	if err := compose.Pull(context.Background()); !errors.Is(err, problem.ErrOffline) {
+		t.Errorf("Pull() error = %v, want ErrOffline", err)
+	}
+}
+
+func TestComposeRunInterruptedOnCancel(t *testing.T) {
+	marker := filepath.Join(t.TempDir(), "interrupted")
+	fakeDocker(t, fmt.Sprintf("trap 'touch %s; kill $!; exit 130' INT\nsleep 5 >/dev/null & wait", marker))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(200*time.Millisecond, cancel)
+	start := time.Now()
+	if err := NewCompose(t.TempDir()).Down(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Down() error = %v, want context.Canceled", err)
+	}
+	if elapsed := time.Since(start); elapsed > 3*time.Second {
+		t.Errorf("Down() returned after %s, want the cancellation", elapsed)
+	}
+	if _, err := os.Stat(marker); err != nil {
+		t.Error("docker was not interrupted")
+	}
+}
+
+func TestWaitHealthyCanceled(t *testing.T) {
+	fakeDocker(t, "echo '[]'")
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	start := time.Now()
+	if err := NewCompose(t.TempDir()).WaitHealthy(ctx, "sonarr", time.Minute); !errors.Is(err, context.Canceled) {
+		t.Fatalf("WaitHealthy() error = %v, want context.Canceled", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("WaitHealthy() returned after %s", elapsed)
+	}
+}
+
+func TestCommandError(t *testing.T) {
+	cause := errors.New("exit status 1")
+	tests := []struct {
+		stderr string
+		want   *problem.Kind
+	}{
+		{"Cannot connect to the Docker daemon at unix:///var/run/docker.sock. Is the docker daemon running?", problem.ErrDockerDaemon},
+		{"permission denied while trying to connect to the Docker daemon socket", problem.ErrDockerDaemon},
+		{"Bind for 0.0.0.0:8080 failed: port is already allocated", problem.ErrPortConflict},
+		{"no such service: sonarr", nil},
+	}
+	for _, tt := range tests {
+		err := commandError(cause, tt.stderr, "docker compose up")
+		if kind := problem.KindOf(err); kind != tt.want {
+			t.Errorf("commandError(%q) kind = %v, want %v", tt.stderr, kind, tt.want)
+		}
+		if !errors.Is(err, cause) {
+			t.Errorf("commandError(%q) lost its cause", tt.stderr)
+		}
+	}
+}
@@ -0,0 +1,265 @@
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// DefaultSocket is the Docker Engine API socket used unless DOCKER_HOST
+// points to another unix socket
+const DefaultSocket = "/var/run/docker.sock"
+
+// Engine talks to the Docker Engine API, for operations whose progress the
+// docker CLI only reports as terminal output (image pulls)
+type Engine struct {
+	Client  *http.Client
+	BaseURL string
+}
+
+// NewEngine creates an Engine for the local Docker daemon socket
+func NewEngine() *Engine {
+	socket := DefaultSocket
+	if host, ok := strings.CutPrefix(os.Getenv("DOCKER_HOST"), "unix://"); ok {
+		socket = host
+	}
+	transport := &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", socket)
+		},
+	}
+	return &Engine{Client: &http.Client{Transport: transport}, BaseURL: "http://docker"}
+}
+
+// Available reports whether the Engine API answers (it does not when
+// DOCKER_HOST is a TCP or SSH host, or the socket is not accessible)
+func (e *Engine) Available(ctx context.Context) bool {
+	resp, err := e.get(ctx, "/_ping")
+	if err != nil {
+		return false
+	}
+	resp.Body.Close()
+	return resp.StatusCode == http.StatusOK
+}
+
+// ImageInfo is the part of an image inspection sdbx uses
+type ImageInfo struct {
+	ID          string   `json:"Id"`
+	RepoDigests []string `json:"RepoDigests"`
+}
+
+// InspectImage returns a local image, or nil when it is not present
+func (e *Engine) InspectImage(ctx context.Context, image string) (*ImageInfo, error) {
+	resp, err := e.get(ctx, "/images/"+image+"/json")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+		var info ImageInfo
+		if err := json.NewDecoder(resp.Body).Decode(&info); err != nil {
+			return nil, fmt.Errorf("inspect %s: %w", image, err)
+		}
+		return &info, nil
+	case http.StatusNotFound:
+		return nil, nil
+	}
+	return nil, fmt.Errorf("inspect %s: %s", image, resp.Status)
+}
+
+// ImageExists reports whether an image is present locally
+func (e *Engine) ImageExists(ctx context.Context, image string) (bool, error) {
+	info, err := e.InspectImage(ctx, image)
+	return info != nil, err
+}
+
+// HasDigest reports whether any repo digest (repo@sha256:...) of an image
+// ends with digest
+func (i *ImageInfo) HasDigest(digest string) bool {
+	for _, d := range i.RepoDigests {
+		if strings.HasSuffix(d, "@"+digest) {
+			return true
+		}
+	}
+	return false
+}
+
+func (e *Engine) get(ctx context.Context, path string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, e.BaseURL+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	return e.Client.Do(req)
+}
+
+// Layer statuses reported while pulling
+const (
+	LayerDownloading      = "Downloading"
+	LayerDownloadComplete = "Download complete"
+	LayerExtracting       = "Extracting"
+	LayerPullComplete     = "Pull complete"
+	LayerAlreadyExists    = "Already exists"
+)
+
+// PullEvent is one progress message of an image pull. Layer is empty for
+// messages about the whole image (e.g. "Digest: sha256:...").
+type PullEvent struct {
+	Image   string `json:"image"`
+	Layer   string `json:"layer,omitempty"`
+	Status  string `json:"status"`
+	Current int64  `json:"current,omitempty"`
+	Total   int64  `json:"total,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// pullMessage is a message of the /images/create JSON stream
+type pullMessage struct {
+	Status         string `json:"status"`
+	ID             string `json:"id"`
+	Error          string `json:"error"`
+	ProgressDetail struct {
+		Current int64 `json:"current"`
+		Total   int64 `json:"total"`
+	} `json:"progressDetail"`
+}
+
+// PullImage pulls an image, calling fn for each progress message. Errors
+// reported in the stream (e.g. manifest unknown) are returned.
+func (e *Engine) PullImage(ctx context.Context, image string, fn func(PullEvent)) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost,
+		e.BaseURL+"/images/create?fromImage="+url.QueryEscape(image), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := e.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		var apiErr struct {
+			Message string `json:"message"`
+		}
+		if json.Unmarshal(body, &apiErr) == nil && apiErr.Message != "" {
+			return fmt.Errorf("pull %s: %s", image, apiErr.Message)
+		}
+		return fmt.Errorf("pull %s: %s", image, resp.Status)
+	}
+
+	decoder := json.NewDecoder(resp.Body)
+	for {
+		var msg pullMessage
+		if err := decoder.Decode(&msg); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return fmt.Errorf("pull %s: %w", image, err)
+		}
+		event := PullEvent{
+			Image:   image,
+			Layer:   msg.ID,
+			Status:  msg.Status,
+			Current: msg.ProgressDetail.Current,
+			Total:   msg.ProgressDetail.Total,
+			Error:   msg.Error,
+		}
+		// Image-level messages carry the tag as their id
+		if event.Layer != "" && strings.HasSuffix(image, ":"+event.Layer) {
+			event.Layer = ""
+		}
+		if fn != nil {
+			fn(event)
+		}
+		if msg.Error != "" {
+			return fmt.Errorf("pull %s: %s", image, msg.Error)
+		}
+	}
+}
+
+// layerProgress is the download state of one layer
+type layerProgress struct {
+	downloaded int64
+	total      int64
+	done       bool
+}
+
+// PullProgress aggregates the layer events of one image pull
+type PullProgress struct {
+	Image  string
+	layers map[string]*layerProgress
+	order  []string
+}
+
+// NewPullProgress creates an empty PullProgress for an image
+func NewPullProgress(image string) *PullProgress {
+	return &PullProgress{Image: image, layers: make(map[string]*layerProgress)}
+}
+
+// Update applies a pull event
+func (p *PullProgress) Update(ev PullEvent) {
+	if ev.Layer == "" {
+		return
+	}
+	layer, ok := p.layers[ev.Layer]
+	if !ok {
+		layer = &layerProgress{}
+		p.layers[ev.Layer] = layer
+		p.order = append(p.order, ev.Layer)
+	}
+	switch ev.Status {
+	case LayerDownloading:
+		layer.downloaded, layer.total = ev.Current, ev.Total
+	case LayerDownloadComplete, LayerExtracting:
+		layer.downloaded = layer.total
+	case LayerPullComplete:
+		layer.downloaded, layer.done = layer.total, true
+	case LayerAlreadyExists:
+		layer.done = true
+	}
+}
+
+// Downloaded returns the bytes downloaded so far and the total size of the
+// layers being downloaded, as far as it is known yet
+func (p *PullProgress) Downloaded() (downloaded, total int64) {
+	for _, layer := range p.layers {
+		downloaded += layer.downloaded
+		total += layer.total
+	}
+	return downloaded, total
+}
+
+// Layers returns how many layers are complete, out of the layers seen
+func (p *PullProgress) Layers() (done, total int) {
+	for _, id := range p.order {
+		if p.layers[id].done {
+			done++
+		}
+	}
+	return done, len(p.order)
+}
+
+// Percent estimates pull completion from completed layers and downloaded bytes
+func (p *PullProgress) Percent() float64 {
+	if len(p.order) == 0 {
+		return 0
+	}
+	var sum float64
+	for _, layer := range p.layers {
+		switch {
+		case layer.done:
+			sum++
+		case layer.total > 0:
+			sum += float64(layer.downloaded) / float64(layer.total)
+		}
+	}
+	return sum / float64(len(p.order)) * 100
+}
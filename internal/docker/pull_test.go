@@ -0,0 +1,112 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func newTestEngine(t *testing.T, handler http.HandlerFunc) *Engine {
+	t.Helper()
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+	return &Engine{Client: srv.Client(), BaseURL: srv.URL}
+}
+
+func TestPullImage(t *testing.T) {
+	stream := []string{
+		`{"status":"Pulling from linuxserver/sonarr","id":"latest"}`,
+		`{"status":"Already exists","id":"aaa"}`,
+		`{"status":"Downloading","id":"bbb","progressDetail":{"current":50,"total":200}}`,
+		`{"status":"Download complete","id":"bbb"}`,
+		`{"status":"Pull complete","id":"bbb"}`,
+		`{"status":"Digest: sha256:abc"}`,
+	}
+	engine := newTestEngine(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/images/create" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		if got := r.URL.Query().Get("fromImage"); got != "linuxserver/sonarr:latest" {
+			t.Errorf("fromImage = %q", got)
+		}
+		fmt.Fprint(w, strings.Join(stream, "\n"))
+	})
+
+	var events []PullEvent
+	err := engine.PullImage(context.Background(), "linuxserver/sonarr:latest", func(ev PullEvent) {
+		events = append(events, ev)
+	})
+	if err != nil {
+		t.Fatalf("PullImage() error = %v", err)
+	}
+	if len(events) != len(stream) {
+		t.Fatalf("got %d events, want %d", len(events), len(stream))
+	}
+	if events[0].Layer != "" {
+		t.Errorf("image-level event has layer %q", events[0].Layer)
+	}
+
+	progress := NewPullProgress("linuxserver/sonarr:latest")
+	for _, ev := range events {
+		progress.Update(ev)
+	}
+	if downloaded, total := progress.Downloaded(); downloaded != 200 || total != 200 {
+		t.Errorf("Downloaded() = %d, %d, want 200, 200", downloaded, total)
+	}
+	if done, total := progress.Layers(); done != 2 || total != 2 {
+		t.Errorf("Layers() = %d/%d, want 2/2", done, total)
+	}
+	if p := progress.Percent(); p != 100 {
+		t.Errorf("Percent() = %v, want 100", p)
+	}
+}
+
+func TestPullImageStreamError(t *testing.T) {
+	engine := newTestEngine(t, func(w http.ResponseWriter, _ *http.Request) {
+		fmt.Fprint(w, `{"error":"manifest unknown"}`)
+	})
+	err := engine.PullImage(context.Background(), "nope:latest", nil)
+	if err == nil || !strings.Contains(err.Error(), "manifest unknown") {
+		t.Errorf("PullImage() error = %v, want manifest unknown", err)
+	}
+}
+
+func TestPullProgressPartial(t *testing.T) {
+	progress := NewPullProgress("img")
+	progress.Update(PullEvent{Layer: "a", Status: LayerDownloading, Current: 25, Total: 100})
+	progress.Update(PullEvent{Layer: "b", Status: LayerPullComplete})
+
+	if p := progress.Percent(); p != 62.5 {
+		t.Errorf("Percent() = %v, want 62.5", p)
+	}
+	if done, total := progress.Layers(); done != 1 || total != 2 {
+		t.Errorf("Layers() = %d/%d, want 1/2", done, total)
+	}
+}
+
+func TestImageExists(t *testing.T) {
+	engine := newTestEngine(t, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.Contains(r.URL.Path, "missing"):
+			w.WriteHeader(http.StatusNotFound)
+		case strings.HasSuffix(r.URL.Path, "/json"):
+			fmt.Fprint(w, `{"Id":"sha256:1","RepoDigests":["present@sha256:abc"]}`)
+		}
+	})
+	if ok, err := engine.ImageExists(context.Background(), "present:1"); err != nil || !ok {
+		t.Errorf("ImageExists(present) = %v, %v", ok, err)
+	}
+	if ok, err := engine.ImageExists(context.Background(), "missing:1"); err != nil || ok {
+		t.Errorf("ImageExists(missing) = %v, %v", ok, err)
+	}
+	info, err := engine.InspectImage(context.Background(), "present:1")
+	if err != nil || !info.HasDigest("sha256:abc") || info.HasDigest("sha256:ab") {
+		t.Errorf("InspectImage() = %+v, %v", info, err)
+	}
+	if !engine.Available(context.Background()) {
+		t.Error("Available() = false")
+	}
+}
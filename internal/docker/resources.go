@@ -0,0 +1,175 @@
+package docker
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Resource kinds
+const (
+	KindContainer = "container"
+	KindNetwork   = "network"
+	KindVolume    = "volume"
+	KindImage     = "image"
+)
+
+// Compose labels set by docker compose on the objects it creates
+const (
+	labelProject = "com.docker.compose.project"
+	labelService = "com.docker.compose.service"
+	labelNetwork = "com.docker.compose.network"
+	labelVolume  = "com.docker.compose.volume"
+)
+
+// Resource is a Docker object belonging to the compose project
+type Resource struct {
+	Kind  string `json:"kind"`
+	ID    string `json:"id"`
+	Name  string `json:"name"`
+	Key   string `json:"key"` // Compose service, network or volume key (image reference for images)
+	Image string `json:"image,omitempty"`
+	Size  int64  `json:"size"` // Bytes on disk, 0 when unknown
+}
+
+// docker runs a plain docker command and returns its output
+func docker(ctx context.Context, args ...string) (string, error) {
+	cmd := command(ctx, args...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", commandError(fmt.Errorf("docker %s failed: %w\n%s", args[0], err, stderr.String()), stderr.String(), "docker "+args[0])
+	}
+	return stdout.String(), nil
+}
+
+// ProjectResources lists the containers, networks and volumes created for the project
+func (c *Compose) ProjectResources(ctx context.Context) ([]Resource, error) {
+	filter := "label=" + labelProject + "=" + c.ProjectName
+
+	containers, err := docker(ctx, "ps", "-a", "-s", "--filter", filter, "--format", "{{json .}}")
+	if err != nil {
+		return nil, err
+	}
+	networks, err := docker(ctx, "network", "ls", "--filter", filter, "--format", "{{json .}}")
+	if err != nil {
+		return nil, err
+	}
+	volumes, err := docker(ctx, "volume", "ls", "--filter", filter, "--format", "{{json .}}")
+	if err != nil {
+		return nil, err
+	}
+
+	var resources []Resource
+	resources = append(resources, parseResources(KindContainer, containers)...)
+	resources = append(resources, parseResources(KindNetwork, networks)...)
+	resources = append(resources, parseResources(KindVolume, volumes)...)
+	return resources, nil
+}
+
+// parseResources parses one-JSON-object-per-line output of docker ls commands
+func parseResources(kind, output string) []Resource {
+	var resources []Resource
+	for _, line := range strings.Split(strings.TrimSpace(output), "\n") {
+		if line == "" {
+			continue
+		}
+		var obj struct {
+			ID     string `json:"ID"`
+			Names  string `json:"Names"`
+			Name   string `json:"Name"`
+			Image  string `json:"Image"`
+			Labels string `json:"Labels"`
+			Size   string `json:"Size"`
+		}
+		if err := json.Unmarshal([]byte(line), &obj); err != nil {
+			continue
+		}
+
+		labels := parseLabels(obj.Labels)
+		r := Resource{Kind: kind, ID: obj.ID, Name: obj.Name}
+		switch kind {
+		case KindContainer:
+			r.Name = obj.Names
+			r.Key = labels[LabelService]
+			if r.Key == "" {
+				r.Key = labels[labelService]
+			}
+			r.Image = obj.Image
+			r.Size = parseSize(obj.Size)
+		case KindNetwork:
+			r.Key = labels[labelNetwork]
+		case KindVolume:
+			r.ID = obj.Name
+			r.Key = labels[labelVolume]
+		}
+		resources = append(resources, r)
+	}
+	return resources
+}
+
+// parseLabels parses docker's "k1=v1,k2=v2" label format
+func parseLabels(s string) map[string]string {
+	labels := make(map[string]string)
+	for _, pair := range strings.Split(s, ",") {
+		if key, value, ok := strings.Cut(pair, "="); ok {
+			labels[key] = value
+		}
+	}
+	return labels
+}
+
+// sizeUnits maps docker's human-readable size suffixes to bytes (decimal units)
+var sizeUnits = []struct {
+	suffix string
+	factor float64
+}{
+	{"TB", 1e12}, {"GB", 1e9}, {"MB", 1e6}, {"kB", 1e3}, {"B", 1},
+}
+
+// parseSize converts sizes like "12.3MB" or "0B (virtual 1.2GB)" to bytes,
+// keeping only the first (writable layer) value
+func parseSize(s string) int64 {
+	s, _, _ = strings.Cut(strings.TrimSpace(s), " ")
+	for _, unit := range sizeUnits {
+		if number, ok := strings.CutSuffix(s, unit.suffix); ok {
+			value, err := strconv.ParseFloat(number, 64)
+			if err != nil {
+				return 0
+			}
+			return int64(value * unit.factor)
+		}
+	}
+	return 0
+}
+
+// ImageSize returns the size in bytes of a local image
+func (c *Compose) ImageSize(ctx context.Context, image string) (int64, error) {
+	output, err := docker(ctx, "image", "inspect", "--format", "{{.Size}}", image)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseInt(strings.TrimSpace(output), 10, 64)
+}
+
+// RemoveResource deletes a Docker object
+func (c *Compose) RemoveResource(ctx context.Context, r Resource) error {
+	var err error
+	switch r.Kind {
+	case KindContainer:
+		_, err = docker(ctx, "rm", "-f", r.ID)
+	case KindNetwork:
+		_, err = docker(ctx, "network", "rm", r.ID)
+	case KindVolume:
+		_, err = docker(ctx, "volume", "rm", r.ID)
+	case KindImage:
+		_, err = docker(ctx, "image", "rm", r.ID)
+	default:
+		err = fmt.Errorf("unknown resource kind %q", r.Kind)
+	}
+	return err
+}
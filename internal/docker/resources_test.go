@@ -0,0 +1,52 @@
+package docker
+
+import "testing"
+
+func TestParseSize(t *testing.T) {
+	tests := []struct {
+		input string
+		want  int64
+	}{
+		{"0B", 0},
+		{"512B", 512},
+		{"12.5kB", 12500},
+		{"1.2MB (virtual 350MB)", 1200000},
+		{"2GB", 2000000000},
+		{"", 0},
+		{"garbage", 0},
+	}
+
+	for _, tt := range tests {
+		if got := parseSize(tt.input); got != tt.want {
+			t.Errorf("parseSize(%q) = %d, want %d", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestParseResources(t *testing.T) {
+	containers := `{"ID":"abc","Names":"sdbx-lidarr","Image":"lscr.io/linuxserver/lidarr:latest","Labels":"com.docker.compose.project=sdbx,com.docker.compose.service=lidarr","Size":"1MB (virtual 200MB)"}
+not json
+{"ID":"def","Names":"sdbx-sonarr","Image":"sonarr","Labels":"com.docker.compose.service=sonarr","Size":"0B"}
+{"ID":"ghi","Names":"custom-name","Image":"radarr","Labels":"com.docker.compose.service=x,sdbx.managed=true,sdbx.service=radarr","Size":"0B"}`
+
+	got := parseResources(KindContainer, containers)
+	if len(got) != 3 {
+		t.Fatalf("got %d containers, want 3", len(got))
+	}
+	if got[0].Name != "sdbx-lidarr" || got[0].Key != "lidarr" || got[0].Size != 1000000 {
+		t.Errorf("unexpected container: %+v", got[0])
+	}
+	if got[2].Key != "radarr" {
+		t.Errorf("sdbx.service label should take precedence, got key %q", got[2].Key)
+	}
+
+	volumes := parseResources(KindVolume, `{"Name":"sdbx_cache","Labels":"com.docker.compose.volume=cache"}`)
+	if len(volumes) != 1 || volumes[0].ID != "sdbx_cache" || volumes[0].Key != "cache" {
+		t.Errorf("unexpected volumes: %+v", volumes)
+	}
+
+	networks := parseResources(KindNetwork, `{"ID":"n1","Name":"sdbx_old","Labels":"com.docker.compose.network=old"}`)
+	if len(networks) != 1 || networks[0].Key != "old" {
+		t.Errorf("unexpected networks: %+v", networks)
+	}
+}
@@ -0,0 +1,114 @@
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Crash loop thresholds: a container restarted at least CrashLoopRestarts
+// times that is restarting now or was started within CrashLoopWindow
+const (
+	CrashLoopRestarts = 3
+	CrashLoopWindow   = 10 * time.Minute
+)
+
+// RestartState is the restart history Docker keeps for a container. The
+// restart count is reset when the container is recreated.
+type RestartState struct {
+	Service      string    `json:"service"`
+	Container    string    `json:"container"`
+	RestartCount int       `json:"restart_count"`
+	Restarting   bool      `json:"restarting"`
+	Running      bool      `json:"running"`
+	ExitCode     int       `json:"exit_code"`
+	OOMKilled    bool      `json:"oom_killed,omitempty"`
+	StartedAt    time.Time `json:"started_at"`
+}
+
+// CrashLooping reports whether the container keeps restarting: a container
+// that restarted a few times but has been up since is not looping
+func (r RestartState) CrashLooping(now time.Time) bool {
+	if r.RestartCount < CrashLoopRestarts {
+		return false
+	}
+	return r.Restarting || !r.Running || now.Sub(r.StartedAt) < CrashLoopWindow
+}
+
+// RestartStates returns the restart state of every project container
+func (c *Compose) RestartStates(ctx context.Context) ([]RestartState, error) {
+	ids, err := docker(ctx, "ps", "-aq", "--filter", "label="+labelProject+"="+c.ProjectName)
+	if err != nil {
+		return nil, err
+	}
+	fields := strings.Fields(ids)
+	if len(fields) == 0 {
+		return nil, nil
+	}
+
+	output, err := docker(ctx, append([]string{"inspect"}, fields...)...)
+	if err != nil {
+		return nil, err
+	}
+	return parseRestartStates(output)
+}
+
+// CrashLoops returns the containers currently in a restart loop, sorted by service
+func (c *Compose) CrashLoops(ctx context.Context) ([]RestartState, error) {
+	states, err := c.RestartStates(ctx)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	var loops []RestartState
+	for _, s := range states {
+		if s.CrashLooping(now) {
+			loops = append(loops, s)
+		}
+	}
+	return loops, nil
+}
+
+// parseRestartStates parses docker inspect output
+func parseRestartStates(output string) ([]RestartState, error) {
+	var containers []struct {
+		Name         string `json:"Name"`
+		RestartCount int    `json:"RestartCount"`
+		State        struct {
+			Running    bool      `json:"Running"`
+			Restarting bool      `json:"Restarting"`
+			OOMKilled  bool      `json:"OOMKilled"`
+			ExitCode   int       `json:"ExitCode"`
+			StartedAt  time.Time `json:"StartedAt"`
+		} `json:"State"`
+		Config struct {
+			Labels map[string]string `json:"Labels"`
+		} `json:"Config"`
+	}
+	if err := json.Unmarshal([]byte(output), &containers); err != nil {
+		return nil, fmt.Errorf("failed to parse docker inspect output: %w", err)
+	}
+
+	states := make([]RestartState, 0, len(containers))
+	for _, ctr := range containers {
+		name := ctr.Config.Labels[LabelService]
+		if name == "" {
+			name = ctr.Config.Labels[labelService]
+		}
+		states = append(states, RestartState{
+			Service:      name,
+			Container:    strings.TrimPrefix(ctr.Name, "/"),
+			RestartCount: ctr.RestartCount,
+			Restarting:   ctr.State.Restarting,
+			Running:      ctr.State.Running,
+			ExitCode:     ctr.State.ExitCode,
+			OOMKilled:    ctr.State.OOMKilled,
+			StartedAt:    ctr.State.StartedAt,
+		})
+	}
+	slices.SortFunc(states, func(a, b RestartState) int { return strings.Compare(a.Service, b.Service) })
+	return states, nil
+}
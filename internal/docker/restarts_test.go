@@ -0,0 +1,71 @@
+package docker
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseRestartStates(t *testing.T) {
+	output := `[
+  {
+    "Name": "/sdbx-sonarr",
+    "RestartCount": 7,
+    "State": {"Running": false, "Restarting": true, "OOMKilled": false, "ExitCode": 1, "StartedAt": "2026-01-01T12:00:00Z"},
+    "Config": {"Labels": {"sdbx.service": "sonarr", "com.docker.compose.service": "sonarr"}}
+  },
+  {
+    "Name": "/sdbx-plex",
+    "RestartCount": 0,
+    "State": {"Running": true, "Restarting": false, "ExitCode": 0, "StartedAt": "2026-01-01T08:00:00Z"},
+    "Config": {"Labels": {"com.docker.compose.service": "plex"}}
+  }
+]`
+
+	states, err := parseRestartStates(output)
+	if err != nil {
+		t.Fatalf("parseRestartStates failed: %v", err)
+	}
+	if len(states) != 2 {
+		t.Fatalf("expected 2 states, got %d", len(states))
+	}
+
+	plex, sonarr := states[0], states[1]
+	if plex.Service != "plex" || plex.Container != "sdbx-plex" || !plex.Running {
+		t.Errorf("unexpected plex state: %+v", plex)
+	}
+	if sonarr.Service != "sonarr" || sonarr.RestartCount != 7 || !sonarr.Restarting || sonarr.ExitCode != 1 {
+		t.Errorf("unexpected sonarr state: %+v", sonarr)
+	}
+	if !sonarr.StartedAt.Equal(time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)) {
+		t.Errorf("StartedAt = %v", sonarr.StartedAt)
+	}
+
+	if _, err := parseRestartStates("not json"); err == nil {
+		t.Error("expected error for invalid output")
+	}
+}
+
+func TestCrashLooping(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name  string
+		state RestartState
+		want  bool
+	}{
+		{"never restarted", RestartState{Running: true, StartedAt: now.Add(-time.Minute)}, false},
+		{"few restarts", RestartState{RestartCount: 2, Restarting: true}, false},
+		{"restarting", RestartState{RestartCount: 5, Restarting: true, StartedAt: now.Add(-time.Hour)}, true},
+		{"just started again", RestartState{RestartCount: 5, Running: true, StartedAt: now.Add(-time.Minute)}, true},
+		{"gave up", RestartState{RestartCount: 5, StartedAt: now.Add(-time.Hour)}, true},
+		{"stable since", RestartState{RestartCount: 5, Running: true, StartedAt: now.Add(-time.Hour)}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.state.CrashLooping(now); got != tt.want {
+				t.Errorf("CrashLooping() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
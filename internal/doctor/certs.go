@@ -0,0 +1,82 @@
+package doctor
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/maiko/sdbx/internal/certs"
+	"github.com/maiko/sdbx/internal/config"
+)
+
+// checkCertificates reports the Let's Encrypt certificates of acme.json
+// that Traefik failed to renew before certs.DefaultWarnDays of expiry, or
+// verifies the certificates of the custom provider
+func (d *Doctor) checkCertificates(_ context.Context) (bool, string) {
+	cfg, err := config.Load()
+	if err != nil || cfg.Expose.Mode != config.ExposeModeDirect {
+		return true, "Skipped (direct mode only)"
+	}
+	if cfg.Expose.TLS.Provider == config.TLSProviderCustom {
+		return d.checkCustomCertificates(cfg)
+	}
+	if !cfg.Expose.TLS.UsesACME() {
+		return true, "Skipped (provider " + cfg.Expose.TLS.Provider + ")"
+	}
+	all, err := certs.Read(d.ProjectDir)
+	if err != nil {
+		return false, err.Error()
+	}
+	if len(all) == 0 {
+		return true, "No certificate issued yet"
+	}
+	return certificatesExpiry(all, time.Now(), "see sdbx cert status")
+}
+
+// certificatesExpiry fails when a certificate expires within
+// certs.DefaultWarnDays, with hint, and reports the next expiry otherwise
+func certificatesExpiry(all []certs.Certificate, now time.Time, hint string) (bool, string) {
+	var expiring []string
+	for _, c := range all {
+		switch c.Status(now, certs.DefaultWarnDays) {
+		case certs.StatusExpired:
+			expiring = append(expiring, c.Domain+" expired")
+		case certs.StatusExpiring:
+			expiring = append(expiring, fmt.Sprintf("%s expires in %d days", c.Domain, c.DaysLeft(now)))
+		}
+	}
+	if len(expiring) > 0 {
+		return false, strings.Join(expiring, ", ") + " - " + hint
+	}
+	next := all[0]
+	return true, fmt.Sprintf("%d certificate(s), next expiry in %d days (%s)", len(all), next.DaysLeft(now), next.Domain)
+}
+
+// checkCustomCertificates verifies each certificate of the custom provider
+// with its key and its chain up to expose.tls.ca_bundle
+func (d *Doctor) checkCustomCertificates(cfg *config.Config) (bool, string) {
+	tls := cfg.Expose.TLS
+	bundle := d.projectPath(tls.CABundle)
+	now := time.Now()
+	var all []certs.Certificate
+	for _, c := range tls.CustomCertificates() {
+		cert, err := certs.VerifyFile(d.projectPath(c.CertFile), d.projectPath(c.KeyFile), bundle, cfg.Domain, now)
+		if err != nil {
+			return false, err.Error()
+		}
+		all = append(all, cert)
+	}
+	slices.SortFunc(all, func(a, b certs.Certificate) int { return a.NotAfter.Compare(b.NotAfter) })
+	return certificatesExpiry(all, now, "renew them with your CA")
+}
+
+// projectPath resolves a path relative to the project directory
+func (d *Doctor) projectPath(path string) string {
+	if path == "" || filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(d.ProjectDir, path)
+}
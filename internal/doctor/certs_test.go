@@ -0,0 +1,22 @@
+package doctor
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/maiko/sdbx/internal/certs"
+)
+
+func TestCertificatesExpiry(t *testing.T) {
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	valid := certs.Certificate{Domain: "sonarr.example.com", NotAfter: now.Add(45 * 24 * time.Hour)}
+	expiring := certs.Certificate{Domain: "auth.example.com", NotAfter: now.Add(9 * 24 * time.Hour)}
+
+	if ok, msg := certificatesExpiry([]certs.Certificate{valid}, now, "see sdbx cert status"); !ok || msg != "1 certificate(s), next expiry in 45 days (sonarr.example.com)" {
+		t.Errorf("certificatesExpiry() = %v, %q", ok, msg)
+	}
+	if ok, msg := certificatesExpiry([]certs.Certificate{expiring, valid}, now, "see sdbx cert status"); ok || !strings.Contains(msg, "auth.example.com expires in 9 days") {
+		t.Errorf("certificatesExpiry() = %v, %q, want the expiring certificate", ok, msg)
+	}
+}
@@ -14,7 +14,9 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/maiko/sdbx/internal/auth"
 	"github.com/maiko/sdbx/internal/config"
+	"github.com/maiko/sdbx/internal/registry"
 )
 
 // Check represents a single diagnostic check
@@ -41,6 +43,13 @@ const (
 type Doctor struct {
 	ProjectDir string
 	Checks     []Check
+	LogLines   int         // Log lines pulled for crash looping services
+	Loops      []CrashLoop // Services found crash looping by the last run
+
+	// External lists the external dependencies of enabled services to check;
+	// ExternalErr is set instead when they could not be resolved
+	External    []registry.ServiceExternalDependency
+	ExternalErr error
 }
 
 // NewDoctor creates a new Doctor instance
@@ -48,6 +57,7 @@ func NewDoctor(projectDir string) *Doctor {
 	return &Doctor{
 		ProjectDir: projectDir,
 		Checks:     make([]Check, 0),
+		LogLines:   DefaultLogLines,
 	}
 }
 
@@ -63,9 +73,16 @@ func (d *Doctor) RunAll(ctx context.Context) []Check {
 		{"File permissions", d.checkPermissions},
 		{"Required ports", d.checkPorts},
 		{"Docker daemon", d.checkDockerDaemon},
+		{"Docker runtime", d.checkRuntime},
 		{"Project files", d.checkProjectFiles},
 		{"Secrets configured", d.checkSecrets},
 		{"VPN connectivity", d.checkVPNIfEnabled},
+		{"Traefik access log", d.checkAccessLog},
+		{"Restart loops", d.checkRestartLoops},
+		{"External dependencies", d.checkExternalDependencies},
+		{"Storage mounts", d.checkStorageMounts},
+		{"Local DNS", d.checkLocalDNS},
+		{"TLS certificates", d.checkCertificates},
 	}
 
 	for _, c := range checks {
@@ -187,6 +204,9 @@ func (d *Doctor) checkPermissions(_ context.Context) (bool, string) {
 	if runtime.GOOS != "windows" {
 		uid := os.Getuid()
 		gid := os.Getgid()
+		if cfg, err := config.Load(); err == nil && cfg.DesktopRuntime() {
+			return true, fmt.Sprintf("UID/GID %d:%d (%s maps PUID/PGID to this user)", uid, gid, config.RuntimeName(cfg.TargetRuntime()))
+		}
 		return true, fmt.Sprintf("UID/GID %d:%d", uid, gid)
 	}
 
@@ -257,8 +277,56 @@ func (d *Doctor) checkDockerDaemon(ctx context.Context) (bool, string) {
 	return true, "Running"
 }
 
-// checkProjectFiles verifies required project files exist
+// checkRuntime verifies the stack is generated for the Docker runtime the
+// daemon reports
+func (d *Doctor) checkRuntime(ctx context.Context) (bool, string) {
+	output, err := exec.CommandContext(ctx, "docker", "info", "--format", "{{.OperatingSystem}}").Output()
+	if err != nil {
+		return false, "Could not query the Docker daemon"
+	}
+	detected := runtimeFromOperatingSystem(strings.TrimSpace(string(output)))
+	name := config.RuntimeName(detected)
+
+	cfg, err := config.Load()
+	if err != nil {
+		return true, name
+	}
+	if target := cfg.TargetRuntime(); target != detected {
+		return false, fmt.Sprintf("%s detected, but the stack targets %s (set runtime: %s in .sdbx.yaml)",
+			name, config.RuntimeName(target), detected)
+	}
+	if cfg.DesktopRuntime() {
+		return true, name + " (local testing, host-only features disabled)"
+	}
+	return true, name
+}
+
+// runtimeFromOperatingSystem maps the OperatingSystem of docker info to a runtime
+func runtimeFromOperatingSystem(os string) string {
+	switch {
+	case strings.Contains(os, "Docker Desktop"):
+		return config.RuntimeDockerDesktop
+	case strings.Contains(os, "OrbStack"):
+		return config.RuntimeOrbStack
+	default:
+		return config.RuntimeEngine
+	}
+}
+
+// checkProjectFiles verifies the last generation completed and required
+// project files exist
 func (d *Doctor) checkProjectFiles(_ context.Context) (bool, string) {
+	state, err := config.ReadState(d.ProjectDir)
+	if err != nil {
+		return false, err.Error()
+	}
+	switch state.State {
+	case config.StateDegraded:
+		return false, fmt.Sprintf("Last generation failed: %s (run: sdbx regenerate)", state.Error)
+	case config.StateGenerating:
+		return false, "Last generation was interrupted (run: sdbx regenerate)"
+	}
+
 	required := []string{"compose.yaml", ".env"}
 	var missing []string
 
@@ -273,6 +341,14 @@ func (d *Doctor) checkProjectFiles(_ context.Context) (bool, string) {
 		return false, fmt.Sprintf("Missing: %s", strings.Join(missing, ", "))
 	}
 
+	if len(state.Skipped) > 0 {
+		names := make([]string, 0, len(state.Skipped))
+		for _, s := range state.Skipped {
+			names = append(names, s.Name)
+		}
+		return false, fmt.Sprintf("Last generation skipped %s (see .sdbx.state.yaml)", strings.Join(names, ", "))
+	}
+
 	return true, "All present"
 }
 
@@ -288,6 +364,9 @@ func (d *Doctor) checkSecrets(_ context.Context) (bool, string) {
 		"authelia_jwt_secret.txt",
 		"authelia_session_secret.txt",
 	}
+	if cfg, err := config.Load(); err == nil && cfg.IsBasicAuth() {
+		required = []string{auth.BasicAuthSecret + ".txt"}
+	}
 
 	var empty []string
 	for _, secret := range required {
@@ -327,3 +406,36 @@ func (d *Doctor) CheckVPN(ctx context.Context) (bool, string) {
 	}
 	return true, fmt.Sprintf("Connected (IP: %s)", ip)
 }
+
+// checkAccessLog verifies the Traefik access log directory is usable when enabled
+func (d *Doctor) checkAccessLog(_ context.Context) (bool, string) {
+	cfg, err := config.Load()
+	if err != nil || !cfg.Traefik.AccessLog.Enabled {
+		return true, "Skipped (access log not enabled)"
+	}
+
+	logDir := cfg.Traefik.AccessLog.Path
+	if !filepath.IsAbs(logDir) {
+		logDir = filepath.Join(d.ProjectDir, logDir)
+	}
+
+	info, err := os.Stat(logDir)
+	if err != nil || !info.IsDir() {
+		return false, fmt.Sprintf("Log directory missing: %s (run 'sdbx regenerate')", cfg.Traefik.AccessLog.Path)
+	}
+
+	// Warn when rotation is clearly not keeping up
+	logFile := filepath.Join(logDir, "access.log")
+	if info, err := os.Stat(logFile); err == nil && cfg.Traefik.AccessLog.MaxSize > 0 {
+		sizeMB := info.Size() / (1024 * 1024)
+		if sizeMB > int64(cfg.Traefik.AccessLog.MaxSize)*2 {
+			return false, fmt.Sprintf("access.log is %d MB (max %d MB) - is logrotate installed?",
+				sizeMB, cfg.Traefik.AccessLog.MaxSize)
+		}
+	}
+
+	if len(cfg.Traefik.IPAllowList) > 0 {
+		return true, fmt.Sprintf("Enabled, IP allowlist active (%d ranges)", len(cfg.Traefik.IPAllowList))
+	}
+	return true, "Enabled"
+}
@@ -2,11 +2,14 @@ package doctor
 
 import (
 	"context"
+	"errors"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
 	"time"
+
+	"github.com/maiko/sdbx/internal/config"
 )
 
 func TestNewDoctor(t *testing.T) {
@@ -61,6 +64,18 @@ func TestCheckPermissions(t *testing.T) {
 	}
 }
 
+func TestRuntimeFromOperatingSystem(t *testing.T) {
+	for os, want := range map[string]string{
+		"Docker Desktop":     config.RuntimeDockerDesktop,
+		"OrbStack":           config.RuntimeOrbStack,
+		"Ubuntu 24.04.1 LTS": config.RuntimeEngine,
+	} {
+		if got := runtimeFromOperatingSystem(os); got != want {
+			t.Errorf("runtimeFromOperatingSystem(%q) = %q, want %q", os, got, want)
+		}
+	}
+}
+
 func TestCheckProjectFiles(t *testing.T) {
 	// Test with no project files
 	tmpDir, err := os.MkdirTemp("", "sdbx-test-*")
@@ -88,6 +103,24 @@ func TestCheckProjectFiles(t *testing.T) {
 	if !passed {
 		t.Errorf("Should pass with project files: %s", msg)
 	}
+
+	// A failed generation fails the check even with the files present
+	if err := config.WriteState(tmpDir, config.StateDegraded, errors.New("failed to write .env")); err != nil {
+		t.Fatal(err)
+	}
+	passed, msg = doc.checkProjectFiles(ctx)
+	if passed || !strings.Contains(msg, "sdbx regenerate") {
+		t.Errorf("checkProjectFiles() after failed generation = %v, %q", passed, msg)
+	}
+
+	// So does a generation that skipped services
+	if err := config.WriteState(tmpDir, config.StateReady, nil, config.SkippedService{Name: "sonarr", Reason: "invalid service definition"}); err != nil {
+		t.Fatal(err)
+	}
+	passed, msg = doc.checkProjectFiles(ctx)
+	if passed || !strings.Contains(msg, "sonarr") {
+		t.Errorf("checkProjectFiles() after skipping services = %v, %q", passed, msg)
+	}
 }
 
 func TestCheckSecrets(t *testing.T) {
@@ -182,3 +215,33 @@ func TestRunAll(t *testing.T) {
 		}
 	}
 }
+
+func TestDiagnoseLogs(t *testing.T) {
+	tests := []struct {
+		name string
+		logs string
+		want []string
+	}{
+		{"clean", "sdbx-sonarr  | Starting Sonarr\nsdbx-sonarr  | Ready", nil},
+		{"permissions", "sdbx-sonarr  | mkdir: cannot create directory '/config/logs': Permission denied", []string{"bad permissions"}},
+		{"port", "sdbx-plex  | listen tcp 0.0.0.0:32400: bind: address already in use", []string{"port in use"}},
+		{"secret", "sdbx-authelia  | open /run/secrets/authelia_jwt_secret: no such file or directory", []string{"missing secret"}},
+		{"several", "exec /init: exec format error\nfatal error: out of memory", []string{"out of memory", "wrong platform"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			causes := DiagnoseLogs(tt.logs)
+			var names []string
+			for _, c := range causes {
+				names = append(names, c.Name)
+				if c.Hint == "" || c.Line == "" {
+					t.Errorf("cause %s should carry its line and a hint: %+v", c.Name, c)
+				}
+			}
+			if strings.Join(names, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("DiagnoseLogs() = %v, want %v", names, tt.want)
+			}
+		})
+	}
+}
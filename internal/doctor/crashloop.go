@@ -0,0 +1,128 @@
+package doctor
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/maiko/sdbx/internal/docker"
+)
+
+// DefaultLogLines is how many log lines of a looping service are pulled
+const DefaultLogLines = 20
+
+// CrashLoop is a service stuck restarting, with its last log lines and the
+// failure causes recognised in them
+type CrashLoop struct {
+	docker.RestartState
+	Logs   []string       `json:"logs,omitempty"`
+	Causes []FailureCause `json:"causes,omitempty"`
+}
+
+// FailureCause is a known failure pattern found in a service's logs
+type FailureCause struct {
+	Name string `json:"name"`
+	Line string `json:"line"` // First log line matching the pattern
+	Hint string `json:"hint"`
+}
+
+// oomHint explains containers killed for exceeding their memory
+const oomHint = "The container ran out of memory: raise services.<name>.resources.memory in .sdbx.yaml"
+
+// failurePatterns are common reasons for containers to exit right after start
+var failurePatterns = []struct {
+	name    string
+	pattern *regexp.Regexp
+	hint    string
+}{
+	{
+		"bad permissions",
+		regexp.MustCompile(`(?i)permission denied|operation not permitted|read-only file system`),
+		"Check ownership of the service's configs/ and data directories against PUID/PGID in .sdbx.yaml (sudo chown -R <uid>:<gid> <dir>)",
+	},
+	{
+		"port in use",
+		regexp.MustCompile(`(?i)address already in use|port is already allocated|bind: address`),
+		"Another process holds the port: find it with 'sudo ss -ltnp' and stop it or change the port",
+	},
+	{
+		"missing secret",
+		regexp.MustCompile(`(?i)(/run/secrets/|secrets?/)\S*:? no such file|secret\S* (not found|is empty|missing)`),
+		"A secret file is missing or empty: run 'sdbx up' to generate declared secrets, or fill manual ones in secrets/",
+	},
+	{
+		"out of memory",
+		regexp.MustCompile(`(?i)out of memory|cannot allocate memory|oomkilled`),
+		oomHint,
+	},
+	{
+		"wrong platform",
+		regexp.MustCompile(`(?i)exec format error`),
+		"The image does not match the host architecture: check platform in .sdbx.yaml",
+	},
+}
+
+// DiagnoseLogs returns the known failure causes found in log output, in
+// pattern order with the first matching line of each
+func DiagnoseLogs(logs string) []FailureCause {
+	lines := strings.Split(logs, "\n")
+	var causes []FailureCause
+	for _, p := range failurePatterns {
+		for _, line := range lines {
+			if p.pattern.MatchString(line) {
+				causes = append(causes, FailureCause{Name: p.name, Line: strings.TrimSpace(line), Hint: p.hint})
+				break
+			}
+		}
+	}
+	return causes
+}
+
+// CrashLoops returns the services in a restart loop with their last log
+// lines and diagnosed failure causes
+func (d *Doctor) CrashLoops(ctx context.Context) ([]CrashLoop, error) {
+	compose := docker.NewCompose(d.ProjectDir)
+	states, err := compose.CrashLoops(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	loops := make([]CrashLoop, 0, len(states))
+	for _, state := range states {
+		loop := CrashLoop{RestartState: state}
+		if logs, err := compose.Logs(ctx, state.Service, d.LogLines, false); err == nil {
+			loop.Logs = strings.Split(strings.TrimRight(logs, "\n"), "\n")
+			loop.Causes = DiagnoseLogs(logs)
+		}
+		oom := func(c FailureCause) bool { return c.Name == "out of memory" }
+		if state.OOMKilled && !slices.ContainsFunc(loop.Causes, oom) {
+			loop.Causes = append(loop.Causes, FailureCause{Name: "out of memory", Line: "OOMKilled", Hint: oomHint})
+		}
+		loops = append(loops, loop)
+	}
+	d.Loops = loops
+	return loops, nil
+}
+
+// checkRestartLoops fails when a service keeps restarting
+func (d *Doctor) checkRestartLoops(ctx context.Context) (bool, string) {
+	loops, err := d.CrashLoops(ctx)
+	if err != nil {
+		return true, "Skipped (container state unavailable)"
+	}
+	if len(loops) == 0 {
+		return true, "None"
+	}
+
+	parts := make([]string, 0, len(loops))
+	for _, loop := range loops {
+		part := fmt.Sprintf("%s (%d restarts", loop.Service, loop.RestartCount)
+		if len(loop.Causes) > 0 {
+			part += ", " + loop.Causes[0].Name
+		}
+		parts = append(parts, part+")")
+	}
+	return false, "Crash looping: " + strings.Join(parts, ", ")
+}
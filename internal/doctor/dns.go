@@ -0,0 +1,46 @@
+package doctor
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"slices"
+
+	"github.com/maiko/sdbx/internal/config"
+)
+
+// CheckDNS resolves a subdomain of domain through the DNS server at server
+// (host:port) and checks it answers want, the address of the host
+func CheckDNS(ctx context.Context, server, domain, want string) (bool, string) {
+	ctx, cancel := context.WithTimeout(ctx, DefaultProbeTimeout)
+	defer cancel()
+
+	resolver := &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, network, server)
+		},
+	}
+	host := "sdbx-check." + domain
+	addrs, err := resolver.LookupHost(ctx, host)
+	if err != nil {
+		return false, fmt.Sprintf("%s does not resolve through %s: %v", host, server, err)
+	}
+	if !slices.Contains(addrs, want) {
+		return false, fmt.Sprintf("%s resolves to %v through %s, want %s", host, addrs, server, want)
+	}
+	return true, fmt.Sprintf("*.%s resolves to %s through %s", domain, want, server)
+}
+
+// checkLocalDNS verifies the DNS server of dns.server resolves the domain to
+// the host, as LAN clients will query it
+func (d *Doctor) checkLocalDNS(ctx context.Context) (bool, string) {
+	cfg, err := config.Load()
+	if err != nil || !cfg.DNS.IsEnabled() {
+		return true, "Skipped (dns not enabled)"
+	}
+	if !d.isSDBXRunning(ctx) {
+		return true, "Skipped (services not running)"
+	}
+	return CheckDNS(ctx, net.JoinHostPort(cfg.DNS.Address, "53"), cfg.Domain, cfg.DNS.Address)
+}
@@ -0,0 +1,67 @@
+package doctor
+
+import (
+	"context"
+	"net"
+	"net/netip"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// serveDNS answers A queries for names under domain with answer, until the
+// test ends
+func serveDNS(t *testing.T, domain string, answer netip.Addr) string {
+	t.Helper()
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	go func() {
+		buf := make([]byte, 512)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			var query dnsmessage.Message
+			if err := query.Unpack(buf[:n]); err != nil || len(query.Questions) != 1 {
+				continue
+			}
+			q := query.Questions[0]
+			resp := dnsmessage.Message{
+				Header:    dnsmessage.Header{ID: query.ID, Response: true, Authoritative: true},
+				Questions: query.Questions,
+			}
+			if !strings.HasSuffix(q.Name.String(), "."+domain+".") {
+				resp.RCode = dnsmessage.RCodeNameError
+			} else if q.Type == dnsmessage.TypeA {
+				resp.Answers = []dnsmessage.Resource{{
+					Header: dnsmessage.ResourceHeader{Name: q.Name, Type: dnsmessage.TypeA, Class: dnsmessage.ClassINET, TTL: 60},
+					Body:   &dnsmessage.AResource{A: answer.As4()},
+				}}
+			}
+			if packed, err := resp.Pack(); err == nil {
+				_, _ = conn.WriteTo(packed, addr)
+			}
+		}
+	}()
+	return conn.LocalAddr().String()
+}
+
+func TestCheckDNS(t *testing.T) {
+	server := serveDNS(t, "example.com", netip.MustParseAddr("192.168.1.10"))
+
+	if ok, msg := CheckDNS(context.Background(), server, "example.com", "192.168.1.10"); !ok {
+		t.Errorf("CheckDNS() = %v, %q, want resolved", ok, msg)
+	}
+	if ok, msg := CheckDNS(context.Background(), server, "example.com", "192.168.1.99"); ok || !strings.Contains(msg, "192.168.1.10") {
+		t.Errorf("CheckDNS() with another address = %v, %q", ok, msg)
+	}
+	if ok, _ := CheckDNS(context.Background(), server, "other.com", "192.168.1.10"); ok {
+		t.Error("CheckDNS() of a domain without rewrite succeeded")
+	}
+}
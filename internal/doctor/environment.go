@@ -0,0 +1,275 @@
+package doctor
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Shell is the environment sdbx runs in, read from the process by NewShell
+type Shell struct {
+	Getenv     func(string) string
+	Home       string
+	Executable string // The running sdbx, symlinks resolved
+
+	// Runs a command and returns its output (replaced in tests)
+	Output func(ctx context.Context, name string, args ...string) (string, error)
+}
+
+// NewShell returns the environment of the current process
+func NewShell() *Shell {
+	home, _ := os.UserHomeDir()
+	exe, _ := os.Executable()
+	if resolved, err := filepath.EvalSymlinks(exe); err == nil {
+		exe = resolved
+	}
+	return &Shell{
+		Getenv:     os.Getenv,
+		Home:       home,
+		Executable: exe,
+		Output: func(ctx context.Context, name string, args ...string) (string, error) {
+			out, err := exec.CommandContext(ctx, name, args...).Output()
+			return strings.TrimSpace(string(out)), err
+		},
+	}
+}
+
+// Check runs the environment checks: other sdbx binaries and aliases, the
+// Docker Compose version, shell completion and the locale
+func (s *Shell) Check(ctx context.Context) []Check {
+	checks := []struct {
+		name string
+		fn   func(context.Context) (CheckStatus, string)
+	}{
+		{"sdbx on PATH", s.checkPath},
+		{"Docker Compose v2", s.checkCompose},
+		{"Shell completion", s.checkCompletion},
+		{"Locale", s.checkLocale},
+	}
+	var results []Check
+	for _, c := range checks {
+		status, message := c.fn(ctx)
+		results = append(results, Check{Name: c.name, Status: status, Message: message})
+	}
+	return results
+}
+
+// Binaries returns the sdbx executables found on PATH, in lookup order
+func (s *Shell) Binaries() []string {
+	var found []string
+	for _, dir := range filepath.SplitList(s.Getenv("PATH")) {
+		path := filepath.Join(dir, "sdbx")
+		info, err := os.Stat(path)
+		if err != nil || info.IsDir() || info.Mode()&0o111 == 0 {
+			continue
+		}
+		if resolved, err := filepath.EvalSymlinks(path); err == nil {
+			path = resolved
+		}
+		if !slices.Contains(found, path) {
+			found = append(found, path)
+		}
+	}
+	return found
+}
+
+// aliasRegex matches shell aliases and functions shadowing sdbx
+var aliasRegex = regexp.MustCompile(`^\s*(alias\s+sdbx=|(function\s+)?sdbx\s*\(\)|function\s+sdbx\b)`)
+
+// Aliases returns the profile lines defining an sdbx alias or function
+func (s *Shell) Aliases() []string {
+	var aliases []string
+	for _, profile := range s.profiles() {
+		f, err := os.Open(profile)
+		if err != nil {
+			continue
+		}
+		scanner := bufio.NewScanner(f)
+		for line := 1; scanner.Scan(); line++ {
+			if aliasRegex.MatchString(scanner.Text()) {
+				aliases = append(aliases, fmt.Sprintf("%s:%d", profile, line))
+			}
+		}
+		f.Close()
+	}
+	return aliases
+}
+
+func (s *Shell) checkPath(_ context.Context) (CheckStatus, string) {
+	if aliases := s.Aliases(); len(aliases) > 0 {
+		return StatusWarning, "sdbx is redefined by an alias or function at " + strings.Join(aliases, ", ")
+	}
+	binaries := s.Binaries()
+	switch {
+	case len(binaries) == 0:
+		return StatusWarning, fmt.Sprintf("sdbx is not on PATH (running %s)", s.Executable)
+	case binaries[0] != s.Executable:
+		return StatusWarning, fmt.Sprintf("'sdbx' runs %s, not this binary (%s)", binaries[0], s.Executable)
+	case len(binaries) > 1:
+		return StatusWarning, fmt.Sprintf("%s shadows %s", binaries[0], strings.Join(binaries[1:], ", "))
+	}
+	return StatusPassed, binaries[0]
+}
+
+func (s *Shell) checkCompose(ctx context.Context) (CheckStatus, string) {
+	v2, v2Err := s.Output(ctx, "docker", "compose", "version", "--short")
+	v1, v1Err := s.Output(ctx, "docker-compose", "version", "--short")
+	switch {
+	case v2Err != nil && v1Err == nil && strings.HasPrefix(strings.TrimPrefix(v1, "v"), "1."):
+		return StatusFailed, fmt.Sprintf("only docker-compose %s (v1) is installed; sdbx needs the docker compose v2 plugin", v1)
+	case v2Err != nil:
+		return StatusFailed, "docker compose v2 plugin not found"
+	case v1Err == nil && strings.HasPrefix(strings.TrimPrefix(v1, "v"), "1."):
+		return StatusWarning, fmt.Sprintf("docker compose %s, but docker-compose on PATH is v1 (%s); use 'docker compose'", v2, v1)
+	}
+	return StatusPassed, "docker compose " + v2
+}
+
+func (s *Shell) checkCompletion(_ context.Context) (CheckStatus, string) {
+	c, err := s.Completion()
+	if err != nil {
+		return StatusWarning, err.Error()
+	}
+	if !c.Installed {
+		return StatusWarning, fmt.Sprintf("not installed for %s (fix: sdbx completion doctor --fix)", c.Shell)
+	}
+	return StatusPassed, fmt.Sprintf("%s (%s)", c.Shell, c.Profile)
+}
+
+func (s *Shell) checkLocale(_ context.Context) (CheckStatus, string) {
+	locale, name := "", "LANG"
+	for _, v := range []string{"LC_ALL", "LC_CTYPE", "LANG"} {
+		if locale = s.Getenv(v); locale != "" {
+			name = v
+			break
+		}
+	}
+	if term := s.Getenv("TERM"); term == "dumb" {
+		return StatusWarning, "TERM=dumb: the TUI is disabled, use --no-tui"
+	}
+	normalized := strings.ToLower(strings.ReplaceAll(locale, "-", ""))
+	if !strings.Contains(normalized, "utf8") {
+		if locale == "" {
+			locale = "unset"
+		}
+		return StatusWarning, fmt.Sprintf("%s=%s is not UTF-8: TUI icons and borders are garbled (fix: export LANG=C.UTF-8)", name, locale)
+	}
+	return StatusPassed, fmt.Sprintf("%s=%s", name, locale)
+}
+
+// Completion is the shell completion setup of the user's shell
+type Completion struct {
+	Shell     string `json:"shell"`   // bash, zsh or fish
+	Profile   string `json:"profile"` // Startup file loading the completion
+	Installed bool   `json:"installed"`
+}
+
+// completionLines are the profile lines loading the completion of each shell
+var completionLines = map[string][]string{
+	"bash": {"source <(sdbx completion bash)"},
+	"zsh":  {"autoload -U compinit && compinit", "source <(sdbx completion zsh)"},
+	"fish": {"sdbx completion fish | source"},
+}
+
+// completionFiles are where packages and users install completion scripts
+var completionFiles = map[string][]string{
+	"bash": {"/usr/share/bash-completion/completions/sdbx", "/etc/bash_completion.d/sdbx", "~/.local/share/bash-completion/completions/sdbx"},
+	"zsh":  {"/usr/share/zsh/site-functions/_sdbx", "/usr/local/share/zsh/site-functions/_sdbx"},
+	"fish": {"/usr/share/fish/vendor_completions.d/sdbx.fish", "~/.config/fish/completions/sdbx.fish"},
+}
+
+// Completion returns the completion setup of the shell in $SHELL
+func (s *Shell) Completion() (Completion, error) {
+	c := Completion{Shell: filepath.Base(s.Getenv("SHELL"))}
+	switch c.Shell {
+	case "bash":
+		c.Profile = filepath.Join(s.Home, ".bashrc")
+	case "zsh":
+		dir := s.Getenv("ZDOTDIR")
+		if dir == "" {
+			dir = s.Home
+		}
+		c.Profile = filepath.Join(dir, ".zshrc")
+	case "fish":
+		dir := s.Getenv("XDG_CONFIG_HOME")
+		if dir == "" {
+			dir = filepath.Join(s.Home, ".config")
+		}
+		c.Profile = filepath.Join(dir, "fish", "config.fish")
+	default:
+		return c, fmt.Errorf("unsupported shell %q (bash, zsh and fish are)", s.Getenv("SHELL"))
+	}
+
+	if data, err := os.ReadFile(c.Profile); err == nil && strings.Contains(string(data), "sdbx completion "+c.Shell) {
+		c.Installed = true
+	}
+	for _, file := range completionFiles[c.Shell] {
+		if _, err := os.Stat(s.expandHome(file)); err == nil {
+			c.Installed = true
+		}
+	}
+	return c, nil
+}
+
+// InstallCompletion appends the lines loading the completion to the shell
+// profile; new shells pick it up
+func (s *Shell) InstallCompletion(c Completion) error {
+	if c.Installed {
+		return nil
+	}
+	existing, err := os.ReadFile(c.Profile)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	var b strings.Builder
+	if len(existing) > 0 && !strings.HasSuffix(string(existing), "\n") {
+		b.WriteString("\n")
+	}
+	b.WriteString("\n# sdbx shell completion\n")
+	for _, line := range completionLines[c.Shell] {
+		// zsh users with a framework already run compinit
+		if strings.Contains(line, "compinit") && strings.Contains(string(existing), "compinit") {
+			continue
+		}
+		b.WriteString(line + "\n")
+	}
+
+	if err := os.MkdirAll(filepath.Dir(c.Profile), 0o755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(c.Profile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.WriteString(b.String()); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// profiles returns the shell startup files that may define aliases
+func (s *Shell) profiles() []string {
+	profiles := []string{".bashrc", ".bash_profile", ".bash_aliases", ".profile", ".zshrc", ".config/fish/config.fish"}
+	for i, p := range profiles {
+		profiles[i] = filepath.Join(s.Home, p)
+	}
+	if dir := s.Getenv("ZDOTDIR"); dir != "" {
+		profiles = append(profiles, filepath.Join(dir, ".zshrc"))
+	}
+	return profiles
+}
+
+// expandHome replaces a leading ~ with the home directory
+func (s *Shell) expandHome(path string) string {
+	if strings.HasPrefix(path, "~/") {
+		return filepath.Join(s.Home, path[2:])
+	}
+	return path
+}
@@ -0,0 +1,128 @@
+package doctor
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// testShell returns a shell with env as environment and home in a temporary directory
+func testShell(t *testing.T, env map[string]string) *Shell {
+	t.Helper()
+	return &Shell{
+		Getenv: func(k string) string { return env[k] },
+		Home:   t.TempDir(),
+		Output: func(context.Context, string, ...string) (string, error) {
+			return "", errors.New("not found")
+		},
+	}
+}
+
+func writeFile(t *testing.T, path, content string, perm os.FileMode) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), perm); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestShellPath(t *testing.T) {
+	dir := t.TempDir()
+	local, system := filepath.Join(dir, "local"), filepath.Join(dir, "system")
+	writeFile(t, filepath.Join(local, "sdbx"), "#!/bin/sh", 0o755)
+	writeFile(t, filepath.Join(system, "sdbx"), "#!/bin/sh", 0o755)
+	writeFile(t, filepath.Join(dir, "other", "sdbx"), "not executable", 0o644)
+
+	s := testShell(t, map[string]string{"PATH": strings.Join([]string{local, filepath.Join(dir, "other"), system}, string(filepath.ListSeparator))})
+	s.Executable = filepath.Join(system, "sdbx")
+	if got := s.Binaries(); len(got) != 2 || got[0] != filepath.Join(local, "sdbx") {
+		t.Errorf("Binaries() = %v", got)
+	}
+	if status, msg := s.checkPath(context.Background()); status != StatusWarning || !strings.Contains(msg, "not this binary") {
+		t.Errorf("checkPath() = %v, %q", status, msg)
+	}
+
+	writeFile(t, filepath.Join(s.Home, ".bashrc"), "export PATH\nalias sdbx='docker run sdbx'\n", 0o644)
+	if status, msg := s.checkPath(context.Background()); status != StatusWarning || !strings.Contains(msg, ".bashrc:2") {
+		t.Errorf("checkPath() = %v, %q; want the alias", status, msg)
+	}
+}
+
+func TestShellCompose(t *testing.T) {
+	s := testShell(t, nil)
+	versions := map[string]string{"docker-compose": "1.29.2"}
+	s.Output = func(_ context.Context, name string, _ ...string) (string, error) {
+		if v, ok := versions[name]; ok {
+			return v, nil
+		}
+		return "", errors.New("not found")
+	}
+	if status, _ := s.checkCompose(context.Background()); status != StatusFailed {
+		t.Errorf("checkCompose() = %v with only v1", status)
+	}
+	versions["docker"] = "2.29.1"
+	if status, _ := s.checkCompose(context.Background()); status != StatusWarning {
+		t.Errorf("checkCompose() = %v with v1 and v2", status)
+	}
+	delete(versions, "docker-compose")
+	if status, msg := s.checkCompose(context.Background()); status != StatusPassed || msg != "docker compose 2.29.1" {
+		t.Errorf("checkCompose() = %v, %q", status, msg)
+	}
+}
+
+func TestShellCompletion(t *testing.T) {
+	s := testShell(t, map[string]string{"SHELL": "/usr/bin/zsh"})
+	writeFile(t, filepath.Join(s.Home, ".zshrc"), "source $ZSH/oh-my-zsh.sh # runs compinit", 0o644)
+
+	c, err := s.Completion()
+	if err != nil || c.Shell != "zsh" || c.Installed || c.Profile != filepath.Join(s.Home, ".zshrc") {
+		t.Fatalf("Completion() = %+v, %v", c, err)
+	}
+	if err := s.InstallCompletion(c); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(c.Profile)
+	if !strings.HasSuffix(string(data), "compinit\n\n# sdbx shell completion\nsource <(sdbx completion zsh)\n") {
+		t.Errorf(".zshrc = %q", data)
+	}
+	if c, _ := s.Completion(); !c.Installed {
+		t.Error("Completion() not installed after InstallCompletion")
+	}
+
+	s = testShell(t, map[string]string{"SHELL": "/usr/bin/fish"})
+	c, _ = s.Completion()
+	if err := s.InstallCompletion(c); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(s.Home, ".config/fish/config.fish")); !strings.Contains(string(data), "sdbx completion fish | source") {
+		t.Errorf("config.fish = %q", data)
+	}
+
+	s = testShell(t, map[string]string{"SHELL": "/bin/tcsh"})
+	if _, err := s.Completion(); err == nil {
+		t.Error("Completion() accepted tcsh")
+	}
+}
+
+func TestShellLocale(t *testing.T) {
+	tests := []struct {
+		env  map[string]string
+		want CheckStatus
+	}{
+		{map[string]string{"LANG": "en_US.UTF-8"}, StatusPassed},
+		{map[string]string{"LANG": "fr_FR.utf8"}, StatusPassed},
+		{map[string]string{"LANG": "en_US.UTF-8", "LC_ALL": "C"}, StatusWarning},
+		{map[string]string{}, StatusWarning},
+		{map[string]string{"LANG": "C.UTF-8", "TERM": "dumb"}, StatusWarning},
+	}
+	for _, tt := range tests {
+		if status, msg := testShell(t, tt.env).checkLocale(context.Background()); status != tt.want {
+			t.Errorf("checkLocale(%v) = %v, %q; want %v", tt.env, status, msg, tt.want)
+		}
+	}
+}
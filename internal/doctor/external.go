@@ -0,0 +1,76 @@
+package doctor
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/maiko/sdbx/internal/registry"
+)
+
+// CheckExternal checks that an external dependency answers: an HTTP response
+// of any status for http dependencies, an accepted TCP connection otherwise
+func CheckExternal(ctx context.Context, dep registry.ServiceExternalDependency) (bool, string) {
+	endpoint := dep.Endpoint()
+	if endpoint == "" {
+		return true, "Not configured (optional)"
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, DefaultProbeTimeout)
+	defer cancel()
+	start := time.Now()
+
+	if dep.Kind == registry.ExternalHTTP {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+		if err != nil {
+			return false, fmt.Sprintf("Invalid URL %s: %v", endpoint, err)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return false, fmt.Sprintf("%s unreachable: %v", endpoint, err)
+		}
+		resp.Body.Close()
+		return true, fmt.Sprintf("%s answered %d in %s", endpoint, resp.StatusCode, time.Since(start).Round(time.Millisecond))
+	}
+
+	conn, err := (&net.Dialer{}).DialContext(ctx, "tcp", endpoint)
+	if err != nil {
+		return false, fmt.Sprintf("%s unreachable: %v", endpoint, err)
+	}
+	conn.Close()
+	return true, fmt.Sprintf("%s reachable in %s", endpoint, time.Since(start).Round(time.Millisecond))
+}
+
+// checkExternalDependencies fails when a required external dependency of an
+// enabled service is unreachable; optional ones are reported only
+func (d *Doctor) checkExternalDependencies(ctx context.Context) (bool, string) {
+	if d.ExternalErr != nil {
+		return false, d.ExternalErr.Error()
+	}
+	if len(d.External) == 0 {
+		return true, "None declared"
+	}
+
+	var failed, optional []string
+	for _, dep := range d.External {
+		if ok, msg := CheckExternal(ctx, dep); !ok {
+			entry := fmt.Sprintf("%s/%s (%s)", dep.Service, dep.Name, msg)
+			if dep.Optional {
+				optional = append(optional, entry)
+			} else {
+				failed = append(failed, entry)
+			}
+		}
+	}
+
+	switch {
+	case len(failed) > 0:
+		return false, "Unreachable: " + strings.Join(append(failed, optional...), ", ")
+	case len(optional) > 0:
+		return true, "Optional unreachable: " + strings.Join(optional, ", ")
+	}
+	return true, fmt.Sprintf("%d reachable", len(d.External))
+}
@@ -0,0 +1,473 @@
+package doctor
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/maiko/sdbx/internal/auth"
+	"github.com/maiko/sdbx/internal/certs"
+	"github.com/maiko/sdbx/internal/config"
+	"github.com/maiko/sdbx/internal/docker"
+	"github.com/maiko/sdbx/internal/qbittorrent"
+	"github.com/maiko/sdbx/internal/secrets"
+)
+
+// OnboardingFile records the last onboarding run of a project
+const OnboardingFile = ".sdbx.onboarding.yaml"
+
+// DefaultOnboardingWait bounds how long onboarding waits for services that
+// just started, e.g. for Authelia to answer or ACME to issue a certificate
+const DefaultOnboardingWait = 2 * time.Minute
+
+// onboardingPollInterval is the delay between two attempts of a waiting step
+const onboardingPollInterval = 5 * time.Second
+
+// traefikDefaultCert is the subject of the certificate Traefik serves until ACME issues one
+const traefikDefaultCert = "TRAEFIK DEFAULT CERT"
+
+// StepStatus is the outcome of an onboarding step
+type StepStatus string
+
+const (
+	StepDone    StepStatus = "done"    // Nothing left to do
+	StepFixed   StepStatus = "fixed"   // Completed by onboarding
+	StepAction  StepStatus = "action"  // Needs the user, see the instructions
+	StepSkipped StepStatus = "skipped" // Does not apply to this project
+)
+
+// OnboardingStep is one first-boot check
+type OnboardingStep struct {
+	ID           string     `yaml:"id" json:"id"`
+	Title        string     `yaml:"title" json:"title"`
+	Status       StepStatus `yaml:"status" json:"status"`
+	Message      string     `yaml:"message" json:"message"`
+	Instructions []string   `yaml:"instructions,omitempty" json:"instructions,omitempty"`
+}
+
+// OnboardingReport is the outcome of an onboarding run
+type OnboardingReport struct {
+	Ran   time.Time        `yaml:"ran" json:"ran"`
+	Steps []OnboardingStep `yaml:"steps" json:"steps"`
+}
+
+// Outstanding returns the steps that still need the user
+func (r *OnboardingReport) Outstanding() []OnboardingStep {
+	var steps []OnboardingStep
+	for _, s := range r.Steps {
+		if s.Status == StepAction {
+			steps = append(steps, s)
+		}
+	}
+	return steps
+}
+
+// ReadOnboarding reads the last onboarding report of a project; it returns
+// nil without error when onboarding never ran
+func ReadOnboarding(projectDir string) (*OnboardingReport, error) {
+	data, err := os.ReadFile(filepath.Join(projectDir, OnboardingFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", OnboardingFile, err)
+	}
+	var report OnboardingReport
+	if err := yaml.Unmarshal(data, &report); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", OnboardingFile, err)
+	}
+	return &report, nil
+}
+
+// WriteOnboarding records an onboarding report in the project
+func WriteOnboarding(projectDir string, report *OnboardingReport) error {
+	data, err := yaml.Marshal(report)
+	if err != nil {
+		return fmt.Errorf("failed to encode onboarding report: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(projectDir, OnboardingFile), data, 0o644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", OnboardingFile, err)
+	}
+	return nil
+}
+
+// Onboarding runs the first-boot checks of a deployed stack: the login in
+// front of the services, the certificate or tunnel, and the setup wizards
+// services show until someone completes them
+type Onboarding struct {
+	Config     *config.Config
+	ProjectDir string
+	Compose    *docker.Compose
+	Prober     *Prober
+
+	// Services lists the enabled services
+	Services []string
+
+	// PortalURL is the Authelia portal; ProtectedURL a service behind the
+	// login, probed to check the login is enforced
+	PortalURL    string
+	ProtectedURL string
+
+	// Fix completes the steps the service APIs allow
+	Fix bool
+
+	// Wait bounds how long a step waits for a service to come up
+	Wait time.Duration
+}
+
+// NewOnboarding creates an Onboarding for the given project
+func NewOnboarding(cfg *config.Config, projectDir string, services []string) *Onboarding {
+	return &Onboarding{
+		Config:     cfg,
+		ProjectDir: projectDir,
+		Compose:    docker.NewCompose(projectDir),
+		Prober:     NewProber(cfg, DefaultProbeTimeout),
+		Services:   services,
+		Wait:       DefaultOnboardingWait,
+	}
+}
+
+// Run executes every onboarding step and returns the report
+func (o *Onboarding) Run(ctx context.Context) *OnboardingReport {
+	report := &OnboardingReport{Ran: time.Now().UTC()}
+	for _, step := range []func(context.Context) OnboardingStep{
+		o.checkLogin,
+		o.checkCertificate,
+		o.checkPlexClaim,
+		o.checkQBittorrentPassword,
+	} {
+		report.Steps = append(report.Steps, step(ctx))
+	}
+	return report
+}
+
+// poll runs check until it succeeds, Wait elapses or ctx is canceled
+func (o *Onboarding) poll(ctx context.Context, check func() bool) bool {
+	deadline := time.Now().Add(o.Wait)
+	for {
+		if check() {
+			return true
+		}
+		if !time.Now().Before(deadline) {
+			return false
+		}
+		select {
+		case <-ctx.Done():
+			return false
+		case <-time.After(onboardingPollInterval):
+		}
+	}
+}
+
+// hasService reports whether service is enabled
+func (o *Onboarding) hasService(service string) bool {
+	return slices.Contains(o.Services, service)
+}
+
+// checkLogin verifies the login answers, protects the services and has users
+func (o *Onboarding) checkLogin(ctx context.Context) OnboardingStep {
+	step := OnboardingStep{ID: "login", Title: "Login"}
+	if o.ProtectedURL == "" {
+		step.Status, step.Message = StepSkipped, "No service behind the login"
+		return step
+	}
+
+	var users []auth.User
+	if list, err := auth.NewStore(o.ProjectDir, o.Config).List(); err == nil {
+		users = list
+	}
+	noUsers := []string{"Add a user: sdbx user add <name>"}
+
+	if o.Config.IsBasicAuth() {
+		var result ProbeResult
+		if !o.poll(ctx, func() bool {
+			result = o.Prober.Probe(ctx, o.ProtectedURL)
+			return result.StatusCode == http.StatusUnauthorized
+		}) {
+			step.Status = StepAction
+			step.Message = fmt.Sprintf("%s is not asking for a password (%s)", o.ProtectedURL, result.Message)
+			step.Instructions = []string{"Check the basic auth middleware: sdbx logs traefik"}
+			return step
+		}
+		if len(users) == 0 {
+			step.Status, step.Message, step.Instructions = StepAction, "Basic auth has no user", noUsers
+			return step
+		}
+		step.Status = StepDone
+		step.Message = fmt.Sprintf("Basic auth protects the services (%d users)", len(users))
+		return step
+	}
+
+	v := &Verifier{Config: o.Config}
+	var portal, protected ProbeResult
+	if !o.poll(ctx, func() bool {
+		portal = o.Prober.Probe(ctx, strings.TrimSuffix(o.PortalURL, "/")+"/api/health")
+		protected = o.Prober.Probe(ctx, o.ProtectedURL)
+		return portal.StatusCode == http.StatusOK && v.isAuthRedirect(protected.Location)
+	}) {
+		step.Status = StepAction
+		if portal.StatusCode != http.StatusOK {
+			step.Message = fmt.Sprintf("Authelia portal is not healthy (%s)", portal.Message)
+			step.Instructions = []string{"Check Authelia: sdbx logs authelia"}
+		} else {
+			step.Message = fmt.Sprintf("%s does not redirect to the login portal (%s)", o.ProtectedURL, protected.Message)
+			step.Instructions = []string{"Check the forward auth middleware: sdbx logs traefik"}
+		}
+		return step
+	}
+	if len(users) == 0 {
+		step.Status, step.Message, step.Instructions = StepAction, "Authelia has no user", noUsers
+		return step
+	}
+	step.Status = StepDone
+	step.Message = fmt.Sprintf("Authelia portal is up at %s (%d users)", o.PortalURL, len(users))
+	return step
+}
+
+// checkCertificate verifies the tunnel is connected, or that Traefik serves
+// a trusted certificate obtained through ACME
+func (o *Onboarding) checkCertificate(ctx context.Context) OnboardingStep {
+	switch o.Config.Expose.Mode {
+	case config.ExposeModeCloudflared:
+		step := OnboardingStep{ID: "tunnel", Title: "Cloudflare tunnel"}
+		v := &Verifier{Config: o.Config, Compose: o.Compose}
+		var message string
+		if !o.poll(ctx, func() bool {
+			var ok bool
+			ok, message = v.checkTunnel(ctx)
+			return ok
+		}) {
+			step.Status, step.Message = StepAction, message
+			step.Instructions = []string{
+				"Check the tunnel token in secrets/cloudflared_tunnel_token.txt",
+				"Check cloudflared: sdbx logs cloudflared",
+			}
+			return step
+		}
+		step.Status, step.Message = StepDone, message
+		return step
+
+	case config.ExposeModeDirect:
+		step := OnboardingStep{ID: "certificate", Title: "TLS certificate"}
+		host := o.certificateHost()
+		var message string
+		if !o.poll(ctx, func() bool {
+			var ok bool
+			ok, message = o.probeCertificate(ctx, host)
+			return ok
+		}) {
+			step.Status, step.Message = StepAction, message
+			if o.Config.Expose.TLS.Provider == config.TLSProviderCustom {
+				step.Instructions = []string{
+					"Check the certificates of expose.tls: sdbx doctor",
+					"Check that Traefik loaded them: sdbx logs traefik",
+				}
+				return step
+			}
+			step.Instructions = []string{
+				fmt.Sprintf("Point the DNS of %s and *.%s to this server", o.Config.Domain, o.Config.Domain),
+				"Open ports 80 and 443 to this server, Let's Encrypt validates through them",
+				"Check the ACME errors: sdbx logs traefik",
+			}
+			return step
+		}
+		step.Status, step.Message = StepDone, message
+		return step
+	}
+	return OnboardingStep{ID: "certificate", Title: "TLS certificate", Status: StepSkipped, Message: "LAN mode serves plain HTTP"}
+}
+
+// certificateHost returns the hostname whose certificate is checked
+func (o *Onboarding) certificateHost() string {
+	for _, raw := range []string{o.PortalURL, o.ProtectedURL} {
+		if u, err := url.Parse(raw); err == nil && u.Hostname() != "" {
+			return u.Hostname()
+		}
+	}
+	return o.Config.Domain
+}
+
+// probeCertificate reads the certificate the local Traefik serves for host.
+// Certificates of the custom provider are verified against its CA bundle.
+func (o *Onboarding) probeCertificate(ctx context.Context, host string) (bool, string) {
+	var roots *x509.CertPool
+	if bundle := o.Config.Expose.TLS.CABundle; bundle != "" {
+		if !filepath.IsAbs(bundle) {
+			bundle = filepath.Join(o.ProjectDir, bundle)
+		}
+		var err error
+		if roots, err = certs.Roots(bundle); err != nil {
+			return false, err.Error()
+		}
+	}
+
+	dialer := &tls.Dialer{
+		NetDialer: &net.Dialer{Timeout: DefaultProbeTimeout},
+		// The chain is verified below, to tell a pending certificate from a wrong one
+		Config: &tls.Config{ServerName: host, InsecureSkipVerify: true}, //nolint:gosec // G402 - verified by classifyCertificate
+	}
+	conn, err := dialer.DialContext(ctx, "tcp", "127.0.0.1:443")
+	if err != nil {
+		return false, "Traefik is not answering on port 443"
+	}
+	defer conn.Close()
+	return classifyCertificate(conn.(*tls.Conn).ConnectionState().PeerCertificates, host, roots, time.Now())
+}
+
+// classifyCertificate tells whether chain is a trusted certificate for host.
+// Nil roots use the system pool.
+func classifyCertificate(chain []*x509.Certificate, host string, roots *x509.CertPool, now time.Time) (bool, string) {
+	if len(chain) == 0 {
+		return false, "No certificate served"
+	}
+	leaf := chain[0]
+	if leaf.Subject.CommonName == traefikDefaultCert {
+		return false, "Traefik serves its default certificate, ACME has not issued one yet"
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, cert := range chain[1:] {
+		intermediates.AddCert(cert)
+	}
+	if _, err := leaf.Verify(x509.VerifyOptions{
+		DNSName:       host,
+		Roots:         roots,
+		Intermediates: intermediates,
+		CurrentTime:   now,
+	}); err != nil {
+		return false, fmt.Sprintf("Certificate for %s is not trusted: %v", host, err)
+	}
+	return true, fmt.Sprintf("Certificate for %s issued by %s, expires %s",
+		host, leaf.Issuer.CommonName, leaf.NotAfter.Format("2006-01-02"))
+}
+
+// plexClaimed matches the claimed attribute of Plex's /identity answer
+var plexClaimed = regexp.MustCompile(`\bclaimed="([01])"`)
+
+// parsePlexClaimed reads whether the server is claimed from Plex's /identity answer
+func parsePlexClaimed(identity string) (claimed, ok bool) {
+	m := plexClaimed.FindStringSubmatch(identity)
+	if m == nil {
+		return false, false
+	}
+	return m[1] == "1", true
+}
+
+// plexIdentity asks the Plex server whether it is claimed
+func (o *Onboarding) plexIdentity(ctx context.Context) (claimed, ok bool) {
+	out, err := o.Compose.Exec(ctx, "plex", "curl", "-fsS", "--max-time", "10", "http://localhost:32400/identity")
+	if err != nil {
+		return false, false
+	}
+	return parsePlexClaimed(out)
+}
+
+// checkPlexClaim verifies the Plex server is claimed by an account; with
+// Fix, it claims it with the token of secrets/plex_claim_token.txt
+func (o *Onboarding) checkPlexClaim(ctx context.Context) OnboardingStep {
+	step := OnboardingStep{ID: "plex-claim", Title: "Plex claim"}
+	if !o.hasService("plex") {
+		step.Status, step.Message = StepSkipped, "Plex not enabled"
+		return step
+	}
+
+	var claimed bool
+	if !o.poll(ctx, func() bool {
+		var ok bool
+		claimed, ok = o.plexIdentity(ctx)
+		return ok
+	}) {
+		step.Status, step.Message = StepAction, "Plex is not answering"
+		step.Instructions = []string{"Check Plex: sdbx logs plex"}
+		return step
+	}
+	if claimed {
+		step.Status, step.Message = StepDone, "Plex server is claimed"
+		return step
+	}
+
+	token, _ := secrets.ReadSecret(filepath.Join(o.ProjectDir, "secrets"), "plex_claim_token.txt")
+	if o.Fix && token != "" {
+		if _, err := o.Compose.Exec(ctx, "plex", "curl", "-fsS", "--max-time", "30", "-X", "POST",
+			"http://localhost:32400/myplex/claim?token="+url.QueryEscape(token)); err == nil {
+			if claimed, _ := o.plexIdentity(ctx); claimed {
+				step.Status, step.Message = StepFixed, "Plex server claimed with the token of secrets/plex_claim_token.txt"
+				return step
+			}
+		}
+	}
+
+	step.Status, step.Message = StepAction, "Plex server is not claimed, its setup wizard is waiting"
+	if token != "" && o.Fix {
+		step.Message = "Plex refused the claim token, it is valid for 4 minutes only"
+	}
+	step.Instructions = []string{
+		"Get a claim token at https://www.plex.tv/claim/ (valid for 4 minutes)",
+		"Save it to secrets/plex_claim_token.txt, then run: sdbx onboard --fix",
+	}
+	return step
+}
+
+// checkQBittorrentPassword verifies the qBittorrent Web UI has a permanent
+// password; with Fix, it replaces the temporary one with the password sdbx
+// manages (see qbittorrent.Bootstrap)
+func (o *Onboarding) checkQBittorrentPassword(ctx context.Context) OnboardingStep {
+	step := OnboardingStep{ID: "qbittorrent-password", Title: "qBittorrent password"}
+	if !o.hasService("qbittorrent") {
+		step.Status, step.Message = StepSkipped, "qBittorrent not enabled"
+		return step
+	}
+	if qbittorrent.HasPassword(o.ProjectDir, o.Config) {
+		step.Status, step.Message = StepDone, "Web UI has a permanent password"
+		return step
+	}
+
+	var temp string
+	if !o.poll(ctx, func() bool {
+		logs, err := o.Compose.Logs(ctx, "qbittorrent", 1000, false)
+		if err != nil {
+			return false
+		}
+		var started bool
+		temp, started = qbittorrent.TempPassword(logs)
+		return started
+	}) {
+		step.Status, step.Message = StepAction, "qBittorrent Web UI has not started"
+		step.Instructions = []string{"Check qBittorrent: sdbx logs qbittorrent"}
+		return step
+	}
+	if temp == "" {
+		step.Status, step.Message = StepDone, "Web UI does not use a temporary password"
+		return step
+	}
+
+	step.Status, step.Message = StepAction, "Web UI uses a temporary password, replaced on every restart"
+	if o.Fix {
+		err := qbittorrent.Bootstrap(ctx, o.ProjectDir, o.Config, temp)
+		if err == nil {
+			step.Status = StepFixed
+			step.Message = fmt.Sprintf("Web UI password set: log in as %s with secrets/%s.txt",
+				o.Config.DownloadClients.QBittorrent.Username, qbittorrent.PasswordSecret)
+			return step
+		}
+		step.Message = err.Error()
+	}
+	step.Instructions = []string{
+		"Set the password sdbx manages: sdbx onboard --fix",
+		"Or log in with the temporary password of 'sdbx logs qbittorrent' and set one in Tools > Options > Web UI",
+	}
+	return step
+}
@@ -0,0 +1,133 @@
+package doctor
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/maiko/sdbx/internal/config"
+)
+
+func TestParsePlexClaimed(t *testing.T) {
+	tests := []struct {
+		identity string
+		claimed  bool
+		ok       bool
+	}{
+		{`<MediaContainer size="0" claimed="1" machineIdentifier="abc" version="1.40.0"> </MediaContainer>`, true, true},
+		{`<MediaContainer size="0" claimed="0" machineIdentifier="abc"> </MediaContainer>`, false, true},
+		{`<html>Starting</html>`, false, false},
+	}
+	for _, tt := range tests {
+		claimed, ok := parsePlexClaimed(tt.identity)
+		if claimed != tt.claimed || ok != tt.ok {
+			t.Errorf("parsePlexClaimed(%q) = (%v, %v), want (%v, %v)", tt.identity, claimed, ok, tt.claimed, tt.ok)
+		}
+	}
+}
+
+func TestClassifyCertificate(t *testing.T) {
+	now := time.Now()
+	caKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Test CA"},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(24 * time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	caDER, _ := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	ca, _ := x509.ParseCertificate(caDER)
+
+	leaf := func(cn string, dnsNames ...string) *x509.Certificate {
+		key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		tmpl := &x509.Certificate{
+			SerialNumber: big.NewInt(2),
+			Subject:      pkix.Name{CommonName: cn},
+			DNSNames:     dnsNames,
+			NotBefore:    now.Add(-time.Hour),
+			NotAfter:     now.Add(12 * time.Hour),
+			ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		}
+		der, err := x509.CreateCertificate(rand.Reader, tmpl, ca, &key.PublicKey, caKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cert, _ := x509.ParseCertificate(der)
+		return cert
+	}
+	roots := x509.NewCertPool()
+	roots.AddCert(ca)
+
+	ok, msg := classifyCertificate([]*x509.Certificate{leaf(traefikDefaultCert)}, "auth.example.com", roots, now)
+	if ok || !strings.Contains(msg, "ACME") {
+		t.Errorf("default certificate = (%v, %q), want pending ACME", ok, msg)
+	}
+
+	issued := leaf("*.example.com", "*.example.com")
+	if ok, msg = classifyCertificate([]*x509.Certificate{issued}, "auth.example.com", roots, now); !ok || !strings.Contains(msg, "Test CA") {
+		t.Errorf("issued certificate = (%v, %q), want trusted", ok, msg)
+	}
+	if ok, _ = classifyCertificate([]*x509.Certificate{issued}, "auth.other.com", roots, now); ok {
+		t.Error("certificate for another domain must not be trusted")
+	}
+	if ok, _ = classifyCertificate([]*x509.Certificate{issued}, "auth.example.com", x509.NewCertPool(), now); ok {
+		t.Error("certificate of an unknown CA must not be trusted")
+	}
+}
+
+func TestOnboardingSkipsDisabledSteps(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Expose.Mode = config.ExposeModeLAN
+
+	o := NewOnboarding(cfg, t.TempDir(), nil)
+	report := o.Run(context.Background())
+	if len(report.Steps) != 4 {
+		t.Fatalf("Run() returned %d steps, want 4", len(report.Steps))
+	}
+	for _, step := range report.Steps {
+		if step.Status != StepSkipped {
+			t.Errorf("step %s = %s (%s), want skipped", step.ID, step.Status, step.Message)
+		}
+	}
+	if len(report.Outstanding()) != 0 {
+		t.Error("skipped steps must not be outstanding")
+	}
+}
+
+func TestOnboardingReport(t *testing.T) {
+	dir := t.TempDir()
+	if report, err := ReadOnboarding(dir); err != nil || report != nil {
+		t.Fatalf("ReadOnboarding() before any run = (%v, %v), want nil", report, err)
+	}
+
+	report := &OnboardingReport{
+		Ran: time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+		Steps: []OnboardingStep{
+			{ID: "login", Title: "Login", Status: StepDone, Message: "ok"},
+			{ID: "plex-claim", Title: "Plex claim", Status: StepAction, Message: "not claimed", Instructions: []string{"claim it"}},
+		},
+	}
+	if err := WriteOnboarding(dir, report); err != nil {
+		t.Fatal(err)
+	}
+	got, err := ReadOnboarding(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.Ran.Equal(report.Ran) || len(got.Steps) != 2 || got.Steps[1].Instructions[0] != "claim it" {
+		t.Errorf("ReadOnboarding() = %+v", got)
+	}
+	if outstanding := got.Outstanding(); len(outstanding) != 1 || outstanding[0].ID != "plex-claim" {
+		t.Errorf("Outstanding() = %+v, want plex-claim", outstanding)
+	}
+}
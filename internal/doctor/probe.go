@@ -0,0 +1,127 @@
+package doctor
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/maiko/sdbx/internal/config"
+)
+
+// DefaultProbeTimeout bounds a single HTTP probe
+const DefaultProbeTimeout = 5 * time.Second
+
+// ProbeResult describes the outcome of an HTTP probe through the reverse proxy
+type ProbeResult struct {
+	URL        string        `json:"url"`
+	StatusCode int           `json:"status_code,omitempty"`
+	Location   string        `json:"location,omitempty"` // Redirect target, if any
+	Latency    time.Duration `json:"latency"`
+	OK         bool          `json:"ok"`
+	Message    string        `json:"message"`
+}
+
+// Prober sends HTTP requests to routed services the way a browser would reach them
+type Prober struct {
+	Client *http.Client
+	Scheme string      // Overrides the URL scheme when set (LAN mode serves plain HTTP)
+	Header http.Header // Extra headers sent with every probe
+}
+
+// NewProber creates a Prober for the configured expose mode.
+// In LAN and direct mode requests go straight to the local Traefik with the
+// service hostname preserved, so probes work without DNS for the domain.
+// In cloudflared mode requests go through the public tunnel.
+func NewProber(cfg *config.Config, timeout time.Duration) *Prober {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	p := &Prober{}
+
+	switch cfg.Expose.Mode {
+	case config.ExposeModeLAN:
+		p.Scheme = "http"
+		transport.DialContext = dialLocal("127.0.0.1:80")
+	case config.ExposeModeDirect:
+		transport.DialContext = dialLocal("127.0.0.1:443")
+		// Certificates may be self-signed or still being issued
+		transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true} //nolint:gosec // G402 - probes only check reachability
+	}
+
+	p.Client = &http.Client{
+		Transport: transport,
+		Timeout:   timeout,
+		// Authelia answers with a redirect to the login portal; that still
+		// proves the route works, so don't follow it
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	return p
+}
+
+// dialLocal returns a DialContext that connects to addr regardless of the requested host
+func dialLocal(addr string) func(ctx context.Context, network, _ string) (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: DefaultProbeTimeout}
+	return func(ctx context.Context, network, _ string) (net.Conn, error) {
+		return dialer.DialContext(ctx, network, addr)
+	}
+}
+
+// Probe performs a GET request against rawURL and classifies the response
+func (p *Prober) Probe(ctx context.Context, rawURL string) ProbeResult {
+	result := ProbeResult{URL: rawURL}
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		result.Message = fmt.Sprintf("invalid URL: %v", err)
+		return result
+	}
+	if p.Scheme != "" {
+		u.Scheme = p.Scheme
+		result.URL = u.String()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		result.Message = fmt.Sprintf("invalid request: %v", err)
+		return result
+	}
+	for name, values := range p.Header {
+		for _, value := range values {
+			req.Header.Add(name, value)
+		}
+	}
+
+	start := time.Now()
+	resp, err := p.Client.Do(req)
+	result.Latency = time.Since(start)
+	if err != nil {
+		result.Message = "unreachable"
+		return result
+	}
+	resp.Body.Close()
+
+	result.StatusCode = resp.StatusCode
+	result.Location = resp.Header.Get("Location")
+	result.OK, result.Message = classifyStatus(resp.StatusCode)
+	return result
+}
+
+// classifyStatus turns an HTTP status code into a pass/fail verdict and a short explanation
+func classifyStatus(code int) (bool, string) {
+	switch {
+	case code < 400:
+		return true, fmt.Sprintf("HTTP %d", code)
+	case code == http.StatusUnauthorized || code == http.StatusForbidden:
+		return true, fmt.Sprintf("HTTP %d (auth required)", code)
+	case code == http.StatusNotFound:
+		return false, "HTTP 404 (no matching route)"
+	case code == http.StatusBadGateway || code == http.StatusServiceUnavailable || code == http.StatusGatewayTimeout:
+		return false, fmt.Sprintf("HTTP %d (proxy cannot reach container)", code)
+	default:
+		return false, fmt.Sprintf("HTTP %d", code)
+	}
+}
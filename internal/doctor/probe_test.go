@@ -0,0 +1,72 @@
+package doctor
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/maiko/sdbx/internal/config"
+)
+
+func TestClassifyStatus(t *testing.T) {
+	tests := []struct {
+		code int
+		ok   bool
+	}{
+		{200, true},
+		{302, true},
+		{401, true},
+		{403, true},
+		{404, false},
+		{500, false},
+		{502, false},
+		{503, false},
+	}
+
+	for _, tt := range tests {
+		ok, msg := classifyStatus(tt.code)
+		if ok != tt.ok {
+			t.Errorf("classifyStatus(%d) = %v (%s), want %v", tt.code, ok, msg, tt.ok)
+		}
+	}
+}
+
+func TestProberProbe(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/login":
+			http.Redirect(w, r, "https://auth.example.com/", http.StatusFound)
+		case "/down":
+			w.WriteHeader(http.StatusBadGateway)
+		default:
+			w.WriteHeader(http.StatusOK)
+		}
+	}))
+	defer server.Close()
+
+	cfg := config.DefaultConfig() // cloudflared mode: no local dialing
+	prober := NewProber(cfg, DefaultProbeTimeout)
+	ctx := context.Background()
+
+	if res := prober.Probe(ctx, server.URL+"/"); !res.OK || res.StatusCode != 200 {
+		t.Errorf("probe / = %+v, want OK 200", res)
+	}
+	// Redirects are not followed
+	if res := prober.Probe(ctx, server.URL+"/login"); !res.OK || res.StatusCode != http.StatusFound {
+		t.Errorf("probe /login = %+v, want OK 302", res)
+	}
+	if res := prober.Probe(ctx, server.URL+"/down"); res.OK || res.StatusCode != http.StatusBadGateway {
+		t.Errorf("probe /down = %+v, want failed 502", res)
+	}
+}
+
+func TestProberLANModeUsesHTTP(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Expose.Mode = config.ExposeModeLAN
+
+	prober := NewProber(cfg, DefaultProbeTimeout)
+	if prober.Scheme != "http" {
+		t.Errorf("Scheme = %q, want http in LAN mode", prober.Scheme)
+	}
+}
@@ -0,0 +1,134 @@
+package doctor
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/maiko/sdbx/internal/config"
+)
+
+// mountsFile lists the mounts visible to sdbx (replaced in tests)
+var mountsFile = "/proc/self/mounts"
+
+// storageTimeout bounds listing a mount, as a dead FUSE mount can hang forever
+const storageTimeout = 5 * time.Second
+
+// Filesystem types of healthy storage mounts
+const (
+	fsTypeRclone   = "fuse.rclone"
+	fsTypeMergerfs = "fuse.mergerfs"
+)
+
+// parseMounts maps each mountpoint of a /proc/mounts listing to its filesystem type
+func parseMounts(r io.Reader) map[string]string {
+	mounts := make(map[string]string)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 3 {
+			continue
+		}
+		// Spaces in mountpoints are escaped as \040
+		mounts[strings.ReplaceAll(fields[1], `\040`, " ")] = fields[2]
+	}
+	return mounts
+}
+
+// Mounts returns the filesystem type of each mountpoint visible to sdbx.
+// It fails where /proc/self/mounts does not exist (macOS).
+func Mounts() (map[string]string, error) {
+	f, err := os.Open(mountsFile)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return parseMounts(f), nil
+}
+
+// MountOf returns the mountpoint holding an absolute path and its
+// filesystem type: the longest mountpoint that is a parent of path
+func MountOf(mounts map[string]string, path string) (mountpoint, fsType string) {
+	path = filepath.Clean(path)
+	for mount, typ := range mounts {
+		if (path == mount || mount == "/" || strings.HasPrefix(path, mount+"/")) && len(mount) > len(mountpoint) {
+			mountpoint, fsType = mount, typ
+		}
+	}
+	return mountpoint, fsType
+}
+
+// listWithTimeout reads a directory, failing when it does not answer in time
+func listWithTimeout(ctx context.Context, dir string) error {
+	ctx, cancel := context.WithTimeout(ctx, storageTimeout)
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := os.ReadDir(dir)
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return fmt.Errorf("no answer after %s", storageTimeout)
+	}
+}
+
+// CheckStorage checks that the rclone and mergerfs mounts of storage are
+// mounted with the expected filesystem and answer, and that every mergerfs
+// branch is readable
+func CheckStorage(ctx context.Context, storage config.StorageConfig, mounts map[string]string) (bool, string) {
+	var problems, healthy []string
+
+	checkMount := func(name, mount, fsType string) {
+		if got, ok := mounts[mount]; !ok {
+			problems = append(problems, fmt.Sprintf("%s not mounted at %s", name, mount))
+		} else if got != fsType {
+			problems = append(problems, fmt.Sprintf("%s is %s, not %s", mount, got, fsType))
+		} else if err := listWithTimeout(ctx, mount); err != nil {
+			problems = append(problems, fmt.Sprintf("%s unreadable (%v)", mount, err))
+		} else {
+			healthy = append(healthy, fmt.Sprintf("%s %s", name, mount))
+		}
+	}
+
+	if r := storage.Rclone; r != nil {
+		checkMount("rclone", r.Mount, fsTypeRclone)
+	}
+	if m := storage.Mergerfs; m != nil {
+		for _, branch := range m.Branches {
+			if err := listWithTimeout(ctx, config.BranchPath(branch)); err != nil {
+				problems = append(problems, fmt.Sprintf("branch %s unreadable (%v)", config.BranchPath(branch), err))
+			}
+		}
+		checkMount("mergerfs", m.Mount, fsTypeMergerfs)
+	}
+
+	if len(problems) > 0 {
+		return false, strings.Join(problems, ", ")
+	}
+	return true, "Mounted: " + strings.Join(healthy, ", ")
+}
+
+// checkStorageMounts fails when a configured storage mount is missing or
+// stale, since media services would then see an empty directory
+func (d *Doctor) checkStorageMounts(ctx context.Context) (bool, string) {
+	cfg, err := config.Load()
+	if err != nil || !cfg.Storage.IsEnabled() {
+		return true, "Skipped (no storage mounts configured)"
+	}
+
+	mounts, err := Mounts()
+	if err != nil {
+		return false, fmt.Sprintf("Could not read mounts: %v", err)
+	}
+
+	return CheckStorage(ctx, cfg.Storage, mounts)
+}
@@ -0,0 +1,69 @@
+package doctor
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/maiko/sdbx/internal/config"
+)
+
+func TestParseMounts(t *testing.T) {
+	listing := `sysfs /sys sysfs rw,nosuid 0 0
+gdrive:media /mnt/remote fuse.rclone rw,nosuid,nodev 0 0
+/mnt/local:/mnt/remote /mnt/my\040media fuse.mergerfs rw 0 0
+`
+	mounts := parseMounts(strings.NewReader(listing))
+	if mounts["/mnt/remote"] != fsTypeRclone || mounts["/mnt/my media"] != fsTypeMergerfs {
+		t.Errorf("unexpected mounts: %v", mounts)
+	}
+}
+
+func TestMountOf(t *testing.T) {
+	mounts := map[string]string{"/": "ext4", "/mnt/data": "xfs", "/mnt/data/nfs": "nfs4", "/mnt/database": "zfs"}
+
+	tests := []struct {
+		path, mountpoint, fsType string
+	}{
+		{"/home/user/sdbx", "/", "ext4"},
+		{"/mnt/data", "/mnt/data", "xfs"},
+		{"/mnt/data/media/tv", "/mnt/data", "xfs"},
+		{"/mnt/data/nfs/movies", "/mnt/data/nfs", "nfs4"},
+		{"/mnt/database/x", "/mnt/database", "zfs"},
+	}
+	for _, tt := range tests {
+		if mountpoint, fsType := MountOf(mounts, tt.path); mountpoint != tt.mountpoint || fsType != tt.fsType {
+			t.Errorf("MountOf(%s) = %s, %s; want %s, %s", tt.path, mountpoint, fsType, tt.mountpoint, tt.fsType)
+		}
+	}
+}
+
+func TestCheckStorage(t *testing.T) {
+	remote, local, merged := t.TempDir(), t.TempDir(), t.TempDir()
+	storage := config.StorageConfig{
+		Rclone:   &config.RcloneConfig{Remote: "gdrive:", Mount: remote},
+		Mergerfs: &config.MergerfsConfig{Mount: merged, Branches: []string{local, remote + "=NC"}},
+	}
+
+	ok, msg := CheckStorage(context.Background(), storage, map[string]string{remote: fsTypeRclone, merged: fsTypeMergerfs})
+	if !ok {
+		t.Errorf("healthy mounts should pass: %s", msg)
+	}
+
+	// The pool is a plain directory: mergerfs is not running
+	ok, msg = CheckStorage(context.Background(), storage, map[string]string{remote: fsTypeRclone, merged: "ext4"})
+	if ok || !strings.Contains(msg, "not "+fsTypeMergerfs) {
+		t.Errorf("wrong filesystem should fail: %s", msg)
+	}
+
+	ok, msg = CheckStorage(context.Background(), storage, map[string]string{merged: fsTypeMergerfs})
+	if ok || !strings.Contains(msg, "rclone not mounted") {
+		t.Errorf("missing rclone mount should fail: %s", msg)
+	}
+
+	storage.Mergerfs.Branches = []string{local, "/nonexistent/branch"}
+	ok, msg = CheckStorage(context.Background(), storage, map[string]string{remote: fsTypeRclone, merged: fsTypeMergerfs})
+	if ok || !strings.Contains(msg, "/nonexistent/branch") {
+		t.Errorf("unreadable branch should fail: %s", msg)
+	}
+}
@@ -0,0 +1,188 @@
+package doctor
+
+import (
+	"context"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/maiko/sdbx/internal/config"
+)
+
+// devDir holds the device nodes looked up for GPUs (replaced in tests)
+var devDir = "/dev"
+
+// dockerRun runs a docker command and returns its combined output (replaced in tests)
+var dockerRun = func(ctx context.Context, args ...string) ([]byte, error) {
+	return exec.CommandContext(ctx, "docker", args...).CombinedOutput()
+}
+
+const (
+	// TranscodeProbeImage ships an ffmpeg build with VA-API, Quick Sync and
+	// NVENC encoders
+	TranscodeProbeImage = "jellyfin/jellyfin:latest"
+
+	transcodeProbeFFmpeg = "/usr/lib/jellyfin-ffmpeg/ffmpeg"
+
+	// transcodeProbeSource is 10 seconds of generated 1080p30 video
+	transcodeProbeSource = "testsrc2=size=1920x1080:rate=30:duration=10"
+
+	// transcodeProbeTimeout bounds a probe, including the first image pull
+	transcodeProbeTimeout = 5 * time.Minute
+)
+
+// transcodeCodecs are the codecs probed on each device
+var transcodeCodecs = []string{"h264", "hevc"}
+
+// TranscodeDevice is a GPU media servers can transcode with
+type TranscodeDevice struct {
+	Method string `json:"method"` // config.TranscodeVAAPI or config.TranscodeNVIDIA
+	Path   string `json:"path"`   // e.g. /dev/dri/renderD128
+}
+
+// TranscodeProbe is the outcome of a hardware encode on a device
+type TranscodeProbe struct {
+	Method  string  `json:"method"`
+	Device  string  `json:"device"`
+	Codec   string  `json:"codec"`
+	Encoder string  `json:"encoder"`
+	OK      bool    `json:"ok"`
+	Speed   float64 `json:"speed,omitempty"` // Multiple of real time encoding 1080p30
+	Error   string  `json:"error,omitempty"`
+	Hint    string  `json:"hint,omitempty"`
+}
+
+// TranscodeReport is the outcome of the hardware transcoding probes
+type TranscodeReport struct {
+	Devices []TranscodeDevice `json:"devices"`
+	Probes  []TranscodeProbe  `json:"probes"`
+	Method  string            `json:"method"`  // Recommended services.<name>.transcode
+	Streams int               `json:"streams"` // Estimated simultaneous 1080p H.264 transcodes with Method
+}
+
+// DetectTranscodeDevices returns the VA-API render nodes and NVIDIA GPUs of
+// the host
+func DetectTranscodeDevices() []TranscodeDevice {
+	var devices []TranscodeDevice
+	render, _ := filepath.Glob(filepath.Join(devDir, "dri", "renderD*"))
+	for _, path := range render {
+		devices = append(devices, TranscodeDevice{Method: config.TranscodeVAAPI, Path: path})
+	}
+	// /dev/nvidia0, /dev/nvidia1, ... but not /dev/nvidiactl or /dev/nvidia-uvm
+	nvidia, _ := filepath.Glob(filepath.Join(devDir, "nvidia[0-9]*"))
+	for _, path := range nvidia {
+		devices = append(devices, TranscodeDevice{Method: config.TranscodeNVIDIA, Path: path})
+	}
+	return devices
+}
+
+// ProbeTranscode encodes a short 1080p clip with each codec on each device
+// inside a throwaway container, and recommends the transcoding method of
+// media servers: NVIDIA, then VA-API, when they can encode H.264
+func ProbeTranscode(ctx context.Context, devices []TranscodeDevice) TranscodeReport {
+	report := TranscodeReport{Devices: devices, Probes: []TranscodeProbe{}, Method: config.TranscodeNone}
+	nvidiaProbed := false
+	for _, dev := range devices {
+		// Containers get every NVIDIA GPU, probing one is enough
+		if dev.Method == config.TranscodeNVIDIA {
+			if nvidiaProbed {
+				continue
+			}
+			nvidiaProbed = true
+		}
+		for _, codec := range transcodeCodecs {
+			report.Probes = append(report.Probes, probeEncoder(ctx, dev, codec))
+		}
+	}
+
+	for _, method := range []string{config.TranscodeNVIDIA, config.TranscodeVAAPI} {
+		for _, p := range report.Probes {
+			if p.Method == method && p.Codec == "h264" && p.OK {
+				report.Method = method
+				report.Streams = max(1, int(p.Speed))
+				return report
+			}
+		}
+	}
+	return report
+}
+
+// probeEncoder runs ffmpeg with the hardware encoder of codec on dev
+func probeEncoder(ctx context.Context, dev TranscodeDevice, codec string) TranscodeProbe {
+	ctx, cancel := context.WithTimeout(ctx, transcodeProbeTimeout)
+	defer cancel()
+
+	probe := TranscodeProbe{Method: dev.Method, Device: dev.Path, Codec: codec}
+	args := []string{"run", "--rm", "--entrypoint", transcodeProbeFFmpeg}
+	var ffmpeg []string
+	switch dev.Method {
+	case config.TranscodeVAAPI:
+		probe.Encoder = codec + "_vaapi"
+		args = append(args, "--device", dev.Path+":"+dev.Path)
+		ffmpeg = []string{
+			"-init_hw_device", "vaapi=va:" + dev.Path, "-filter_hw_device", "va",
+			"-f", "lavfi", "-i", transcodeProbeSource,
+			"-vf", "format=nv12,hwupload", "-c:v", probe.Encoder,
+		}
+	case config.TranscodeNVIDIA:
+		probe.Encoder = codec + "_nvenc"
+		args = append(args, "--gpus", "all", "-e", "NVIDIA_DRIVER_CAPABILITIES=compute,video,utility")
+		ffmpeg = []string{"-f", "lavfi", "-i", transcodeProbeSource, "-c:v", probe.Encoder}
+	}
+	args = append(args, TranscodeProbeImage, "-hide_banner", "-nostdin")
+	args = append(args, ffmpeg...)
+	args = append(args, "-f", "null", "-")
+
+	output, err := dockerRun(ctx, args...)
+	if err != nil {
+		probe.Error = lastLine(string(output))
+		if probe.Error == "" {
+			probe.Error = err.Error()
+		}
+		probe.Hint = transcodeHint(dev.Method, string(output))
+		return probe
+	}
+	probe.OK = true
+	probe.Speed, _ = parseFFmpegSpeed(string(output))
+	return probe
+}
+
+// ffmpegSpeedRegex matches the speed of ffmpeg's progress lines (speed=12.3x)
+var ffmpegSpeedRegex = regexp.MustCompile(`speed=\s*([0-9.]+)x`)
+
+// parseFFmpegSpeed returns the speed of the last progress line of ffmpeg
+func parseFFmpegSpeed(output string) (float64, bool) {
+	matches := ffmpegSpeedRegex.FindAllStringSubmatch(output, -1)
+	if len(matches) == 0 {
+		return 0, false
+	}
+	speed, err := strconv.ParseFloat(matches[len(matches)-1][1], 64)
+	return speed, err == nil
+}
+
+// transcodeHint suggests a fix for a failed probe
+func transcodeHint(method, output string) string {
+	switch {
+	case method == config.TranscodeNVIDIA && strings.Contains(output, "could not select device driver"):
+		return "Install the NVIDIA Container Toolkit and restart Docker"
+	case strings.Contains(output, "Permission denied"):
+		return "Check that the device is readable by the render or video group"
+	case strings.Contains(output, "Unknown encoder"), strings.Contains(output, "No usable encoding profile"):
+		return "The GPU or its driver cannot encode this codec"
+	}
+	return ""
+}
+
+// lastLine returns the last non-empty line of output
+func lastLine(output string) string {
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	lines = slices.DeleteFunc(lines, func(l string) bool { return strings.TrimSpace(l) == "" })
+	if len(lines) == 0 {
+		return ""
+	}
+	return strings.TrimSpace(lines[len(lines)-1])
+}
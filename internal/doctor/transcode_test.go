@@ -0,0 +1,96 @@
+package doctor
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/maiko/sdbx/internal/config"
+)
+
+func TestDetectTranscodeDevices(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"dri/card0", "dri/renderD128", "nvidia0", "nvidiactl", "nvidia-uvm"} {
+		if err := os.MkdirAll(filepath.Dir(filepath.Join(dir, name)), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	old := devDir
+	devDir = dir
+	defer func() { devDir = old }()
+
+	want := []TranscodeDevice{
+		{Method: config.TranscodeVAAPI, Path: filepath.Join(dir, "dri/renderD128")},
+		{Method: config.TranscodeNVIDIA, Path: filepath.Join(dir, "nvidia0")},
+	}
+	if got := DetectTranscodeDevices(); !slices.Equal(got, want) {
+		t.Errorf("DetectTranscodeDevices() = %+v, want %+v", got, want)
+	}
+}
+
+func TestProbeTranscode(t *testing.T) {
+	old := dockerRun
+	defer func() { dockerRun = old }()
+
+	var runs [][]string
+	dockerRun = func(_ context.Context, args ...string) ([]byte, error) {
+		runs = append(runs, args)
+		cmd := strings.Join(args, " ")
+		switch {
+		case strings.Contains(cmd, "--gpus all"):
+			return []byte("docker: Error response from daemon: could not select device driver \"\" with capabilities: [[gpu]].\n"), errors.New("exit status 125")
+		case strings.Contains(cmd, "hevc_vaapi"):
+			return []byte("[hevc_vaapi @ 0x1] No usable encoding profile found.\n"), errors.New("exit status 1")
+		}
+		return []byte("frame=  150 fps=60 speed=1.9x\nframe=  300 fps=92 speed=3.07x\n"), nil
+	}
+
+	devices := []TranscodeDevice{
+		{Method: config.TranscodeVAAPI, Path: "/dev/dri/renderD128"},
+		{Method: config.TranscodeNVIDIA, Path: "/dev/nvidia0"},
+		{Method: config.TranscodeNVIDIA, Path: "/dev/nvidia1"},
+	}
+	report := ProbeTranscode(context.Background(), devices)
+	if len(runs) != 4 {
+		t.Errorf("ran %d probes, want 2 per method", len(runs))
+	}
+	if report.Method != config.TranscodeVAAPI || report.Streams != 3 {
+		t.Errorf("Method = %s, Streams = %d; want vaapi with 3 streams", report.Method, report.Streams)
+	}
+	for _, p := range report.Probes {
+		switch {
+		case p.Encoder == "h264_vaapi":
+			if !p.OK || p.Speed != 3.07 {
+				t.Errorf("h264_vaapi probe = %+v", p)
+			}
+		case p.Method == config.TranscodeNVIDIA:
+			if p.OK || !strings.Contains(p.Hint, "NVIDIA Container Toolkit") {
+				t.Errorf("%s probe = %+v, want the toolkit hint", p.Encoder, p)
+			}
+		case p.Encoder == "hevc_vaapi":
+			if p.OK || p.Error != "[hevc_vaapi @ 0x1] No usable encoding profile found." {
+				t.Errorf("hevc_vaapi probe = %+v", p)
+			}
+		}
+	}
+
+	if report := ProbeTranscode(context.Background(), nil); report.Method != config.TranscodeNone || report.Streams != 0 {
+		t.Errorf("without devices: Method = %s, Streams = %d", report.Method, report.Streams)
+	}
+}
+
+func TestParseFFmpegSpeed(t *testing.T) {
+	if speed, ok := parseFFmpegSpeed("frame=1 speed=0.5x\rframe=300 speed= 12.4x\n"); !ok || speed != 12.4 {
+		t.Errorf("parseFFmpegSpeed() = %v, %v", speed, ok)
+	}
+	if _, ok := parseFFmpegSpeed("no progress"); ok {
+		t.Error("parseFFmpegSpeed() found a speed without progress lines")
+	}
+}
@@ -0,0 +1,328 @@
+package doctor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/maiko/sdbx/internal/config"
+	"github.com/maiko/sdbx/internal/docker"
+	"github.com/maiko/sdbx/internal/registry"
+)
+
+// publicIPURL returns the caller's public address as plain text
+const publicIPURL = "https://api.ipify.org"
+
+// arrApplications maps *arr services to their Prowlarr application implementation
+var arrApplications = map[string]string{
+	"sonarr":  "Sonarr",
+	"radarr":  "Radarr",
+	"lidarr":  "Lidarr",
+	"readarr": "Readarr",
+}
+
+// Verifier runs end-to-end checks against a deployed stack
+type Verifier struct {
+	Config     *config.Config
+	ProjectDir string
+	Compose    *docker.Compose
+	Prober     *Prober
+
+	// Services lists the routed services to probe, in display order
+	Services []string
+
+	// External lists the external dependencies of enabled services
+	External []registry.ServiceExternalDependency
+
+	// Session is an Authelia session cookie value; when set, protected
+	// routes must serve content instead of redirecting to the login portal
+	Session string
+}
+
+// NewVerifier creates a Verifier for the given project
+func NewVerifier(cfg *config.Config, projectDir string, services []string) *Verifier {
+	return &Verifier{
+		Config:     cfg,
+		ProjectDir: projectDir,
+		Compose:    docker.NewCompose(projectDir),
+		Prober:     NewProber(cfg, DefaultProbeTimeout),
+		Services:   services,
+	}
+}
+
+// SetHeader adds a header sent with every route probe (e.g. an Authelia bypass header)
+func (v *Verifier) SetHeader(name, value string) {
+	if v.Prober.Header == nil {
+		v.Prober.Header = make(http.Header)
+	}
+	v.Prober.Header.Add(name, value)
+}
+
+// SetSession authenticates route probes with an Authelia session cookie
+func (v *Verifier) SetSession(session string) {
+	v.Session = session
+	v.SetHeader("Cookie", "authelia_session="+session)
+}
+
+// Run executes all verification checks and returns the report
+func (v *Verifier) Run(ctx context.Context) []Check {
+	var results []Check
+
+	for _, name := range v.Services {
+		results = append(results, runCheck("Route: "+name, func() (bool, string) {
+			return v.checkRoute(ctx, name)
+		}))
+	}
+
+	for _, dep := range v.External {
+		results = append(results, runCheck(fmt.Sprintf("External: %s/%s", dep.Service, dep.Name), func() (bool, string) {
+			ok, msg := CheckExternal(ctx, dep)
+			if !ok && dep.Optional {
+				return true, msg + " (optional)"
+			}
+			return ok, msg
+		}))
+	}
+
+	checks := []struct {
+		name string
+		fn   func(context.Context) (bool, string)
+	}{
+		{"Cloudflare tunnel", v.checkTunnel},
+		{"VPN exit IP", v.checkVPNExit},
+		{"Prowlarr application links", v.checkProwlarrLinks},
+	}
+	for _, c := range checks {
+		results = append(results, runCheck(c.name, func() (bool, string) {
+			return c.fn(ctx)
+		}))
+	}
+
+	return results
+}
+
+// runCheck times a check function and wraps its result
+func runCheck(name string, fn func() (bool, string)) Check {
+	start := time.Now()
+	passed, message := fn()
+	check := Check{
+		Name:     name,
+		Status:   StatusFailed,
+		Message:  message,
+		Duration: time.Since(start),
+	}
+	if passed {
+		check.Status = StatusPassed
+	}
+	return check
+}
+
+// checkRoute probes a service URL, following Authelia semantics
+func (v *Verifier) checkRoute(ctx context.Context, service string) (bool, string) {
+	res := v.Prober.Probe(ctx, v.Config.GetServiceURL(service))
+	if !res.OK {
+		return false, res.Message
+	}
+	if res.Location != "" && v.isAuthRedirect(res.Location) {
+		if v.Session != "" {
+			return false, "Authelia rejected the session (expired or wrong domain?)"
+		}
+		return true, "Redirects to Authelia (pass --session for an authenticated check)"
+	}
+	return true, fmt.Sprintf("%s in %s", res.Message, res.Latency.Round(time.Millisecond))
+}
+
+// isAuthRedirect reports whether a redirect target is the Authelia portal
+func (v *Verifier) isAuthRedirect(location string) bool {
+	if v.Config.Routing.Strategy == config.RoutingStrategyPath {
+		return strings.Contains(location, fmt.Sprintf("%s.%s/auth", v.Config.Routing.BaseDomain, v.Config.Domain))
+	}
+	return strings.Contains(location, "auth."+v.Config.Domain)
+}
+
+// tunnelRegistered matches cloudflared's log line for an established edge connection
+var tunnelRegistered = regexp.MustCompile(`Registered tunnel connection`)
+
+// checkTunnel verifies cloudflared holds at least one edge connection
+func (v *Verifier) checkTunnel(ctx context.Context) (bool, string) {
+	if !v.Config.IsCloudflared() {
+		return true, "Skipped (not in cloudflared mode)"
+	}
+
+	logs, err := v.Compose.Logs(ctx, "cloudflared", 500, false)
+	if err != nil {
+		return false, "Could not read cloudflared logs (is it running?)"
+	}
+
+	connections := len(tunnelRegistered.FindAllString(logs, -1))
+	if connections == 0 {
+		return false, "No tunnel connection registered - check the tunnel token"
+	}
+	return true, fmt.Sprintf("Connected (%d edge connections registered)", connections)
+}
+
+// checkVPNExit verifies downloads leave through the VPN, not the host address
+func (v *Verifier) checkVPNExit(ctx context.Context) (bool, string) {
+	if !v.Config.VPNEnabled {
+		return true, "Skipped (VPN not enabled)"
+	}
+
+	// qBittorrent shares gluetun's network namespace, so this is the torrent exit IP
+	out, err := v.Compose.Exec(ctx, "qbittorrent", "curl", "-fsS", "--max-time", "10", publicIPURL)
+	if err != nil {
+		return false, "qBittorrent cannot reach the internet through the VPN"
+	}
+	vpnIP := strings.TrimSpace(out)
+
+	hostIP, err := PublicIP(ctx)
+	if err != nil {
+		return true, fmt.Sprintf("Exit IP %s (host IP unknown, leak check skipped)", vpnIP)
+	}
+	if vpnIP == hostIP {
+		return false, fmt.Sprintf("Exit IP %s matches the host - traffic is NOT going through the VPN", vpnIP)
+	}
+	return true, fmt.Sprintf("Exit IP %s (host %s)", vpnIP, hostIP)
+}
+
+// PublicIP returns the host's public IP address
+func PublicIP(ctx context.Context) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, publicIPURL, nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 64))
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(body)), nil
+}
+
+// prowlarrApplication is the subset of Prowlarr's /api/v1/applications response we use
+type prowlarrApplication struct {
+	ID             int    `json:"id"`
+	Name           string `json:"name"`
+	Implementation string `json:"implementation"`
+}
+
+// prowlarrTestResult is one entry of Prowlarr's /api/v1/applications/testall response
+type prowlarrTestResult struct {
+	ID      int  `json:"id"`
+	IsValid bool `json:"isValid"`
+}
+
+// checkProwlarrLinks verifies every enabled *arr is registered in Prowlarr and reachable
+func (v *Verifier) checkProwlarrLinks(ctx context.Context) (bool, string) {
+	if !v.Config.IsAddonEnabled("prowlarr") {
+		return true, "Skipped (Prowlarr not enabled)"
+	}
+
+	var expected []string
+	for _, name := range slices.Sorted(maps.Keys(arrApplications)) {
+		if v.Config.IsAddonEnabled(name) {
+			expected = append(expected, name)
+		}
+	}
+	if len(expected) == 0 {
+		return true, "Skipped (no *arr services enabled)"
+	}
+
+	apiKey, err := ReadArrAPIKey(filepath.Join(v.ProjectDir, "configs", "prowlarr", "config.xml"))
+	if err != nil {
+		return false, "Prowlarr API key not found (has Prowlarr started once?)"
+	}
+
+	out, err := v.prowlarrAPI(ctx, apiKey, http.MethodGet, "/api/v1/applications")
+	if err != nil {
+		return false, "Prowlarr API unreachable"
+	}
+	var apps []prowlarrApplication
+	if err := json.Unmarshal([]byte(out), &apps); err != nil {
+		return false, "Unexpected Prowlarr API response"
+	}
+
+	var missing []string
+	for _, name := range expected {
+		if !hasApplication(apps, arrApplications[name]) {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		return false, fmt.Sprintf("Not linked in Prowlarr: %s", strings.Join(missing, ", "))
+	}
+
+	out, err = v.prowlarrAPI(ctx, apiKey, http.MethodPost, "/api/v1/applications/testall")
+	if err != nil {
+		return false, "Prowlarr application test failed to run"
+	}
+	var tests []prowlarrTestResult
+	if err := json.Unmarshal([]byte(out), &tests); err != nil {
+		return false, "Unexpected Prowlarr test response"
+	}
+
+	var broken []string
+	for _, test := range tests {
+		if test.IsValid {
+			continue
+		}
+		for _, app := range apps {
+			if app.ID == test.ID {
+				broken = append(broken, app.Name)
+			}
+		}
+	}
+	if len(broken) > 0 {
+		return false, fmt.Sprintf("Prowlarr cannot reach: %s", strings.Join(broken, ", "))
+	}
+
+	return true, fmt.Sprintf("Linked: %s", strings.Join(expected, ", "))
+}
+
+// prowlarrAPI calls the Prowlarr API from inside its container
+func (v *Verifier) prowlarrAPI(ctx context.Context, apiKey, method, path string) (string, error) {
+	return v.Compose.Exec(ctx, "prowlarr", "curl", "-fsS", "--max-time", "30",
+		"-X", method, "-H", "X-Api-Key: "+apiKey, "http://localhost:9696"+path)
+}
+
+// hasApplication reports whether Prowlarr has an application of the given implementation
+func hasApplication(apps []prowlarrApplication, implementation string) bool {
+	for _, app := range apps {
+		if app.Implementation == implementation {
+			return true
+		}
+	}
+	return false
+}
+
+// apiKeyRegex extracts the API key from an *arr config.xml
+var apiKeyRegex = regexp.MustCompile(`<ApiKey>([^<]+)</ApiKey>`)
+
+// ReadArrAPIKey reads the API key from an *arr config.xml file
+func ReadArrAPIKey(path string) (string, error) {
+	data, err := os.ReadFile(path) //nolint:gosec // G304 - path is built from the project directory
+	if err != nil {
+		return "", err
+	}
+	m := apiKeyRegex.FindSubmatch(data)
+	if m == nil {
+		return "", fmt.Errorf("no ApiKey in %s", path)
+	}
+	return string(m[1]), nil
+}
@@ -0,0 +1,127 @@
+package doctor
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/maiko/sdbx/internal/config"
+	"github.com/maiko/sdbx/internal/registry"
+)
+
+func TestVerifierSkipsDisabledFeatures(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Expose.Mode = config.ExposeModeLAN
+	cfg.VPNEnabled = false
+
+	v := NewVerifier(cfg, t.TempDir(), nil)
+	ctx := context.Background()
+
+	for name, fn := range map[string]func(context.Context) (bool, string){
+		"tunnel":   v.checkTunnel,
+		"vpn":      v.checkVPNExit,
+		"prowlarr": v.checkProwlarrLinks,
+	} {
+		passed, msg := fn(ctx)
+		if !passed || !strings.HasPrefix(msg, "Skipped") {
+			t.Errorf("%s: got (%v, %q), want skipped", name, passed, msg)
+		}
+	}
+}
+
+func TestVerifierIsAuthRedirect(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Domain = "example.com"
+	v := NewVerifier(cfg, ".", nil)
+
+	if !v.isAuthRedirect("https://auth.example.com/?rd=https://sonarr.example.com/") {
+		t.Error("expected subdomain auth portal to be detected")
+	}
+	if v.isAuthRedirect("https://sonarr.example.com/login") {
+		t.Error("service-local redirect must not count as auth redirect")
+	}
+
+	cfg.Routing.Strategy = config.RoutingStrategyPath
+	if !v.isAuthRedirect("https://sdbx.example.com/auth/?rd=x") {
+		t.Error("expected path-mode auth portal to be detected")
+	}
+}
+
+func TestVerifierSetSession(t *testing.T) {
+	v := NewVerifier(config.DefaultConfig(), ".", nil)
+	v.SetSession("abc123")
+
+	if got := v.Prober.Header.Get("Cookie"); got != "authelia_session=abc123" {
+		t.Errorf("Cookie header = %q", got)
+	}
+}
+
+func TestReadArrAPIKey(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.xml")
+	content := "<Config>\n  <Port>9696</Port>\n  <ApiKey>0123456789abcdef</ApiKey>\n</Config>\n"
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	key, err := ReadArrAPIKey(path)
+	if err != nil {
+		t.Fatalf("ReadArrAPIKey() error = %v", err)
+	}
+	if key != "0123456789abcdef" {
+		t.Errorf("key = %q", key)
+	}
+
+	if _, err := ReadArrAPIKey(filepath.Join(dir, "missing.xml")); err == nil {
+		t.Error("expected error for missing file")
+	}
+}
+
+func TestHasApplication(t *testing.T) {
+	apps := []prowlarrApplication{{ID: 1, Name: "Sonarr", Implementation: "Sonarr"}}
+	if !hasApplication(apps, "Sonarr") {
+		t.Error("expected Sonarr application")
+	}
+	if hasApplication(apps, "Radarr") {
+		t.Error("unexpected Radarr application")
+	}
+}
+
+func TestCheckExternal(t *testing.T) {
+	ctx := context.Background()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	addr := listener.Addr().(*net.TCPAddr)
+
+	db := registry.ServiceExternalDependency{Service: "app", ExternalDependency: registry.ExternalDependency{
+		Name: "db", Kind: registry.ExternalTCP, Host: "127.0.0.1", Port: addr.Port,
+	}}
+	if ok, msg := CheckExternal(ctx, db); !ok {
+		t.Errorf("listening port should be reachable: %s", msg)
+	}
+
+	listener.Close()
+	if ok, _ := CheckExternal(ctx, db); ok {
+		t.Error("closed port should be unreachable")
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer server.Close()
+	api := registry.ServiceExternalDependency{Service: "app", ExternalDependency: registry.ExternalDependency{
+		Name: "api", Kind: registry.ExternalHTTP, URL: server.URL,
+	}}
+	if ok, msg := CheckExternal(ctx, api); !ok || !strings.Contains(msg, "401") {
+		t.Errorf("any HTTP answer proves reachability, got (%v, %q)", ok, msg)
+	}
+}
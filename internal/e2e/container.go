@@ -0,0 +1,116 @@
+//go:build e2e
+
+// Package e2e runs ephemeral containers for the end-to-end tests, which are
+// built with the e2e tag and need a Docker daemon:
+//
+//	make e2e    # go test -tags e2e -run '^TestE2E' ./...
+package e2e
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os/exec"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Container is a container removed when its test ends
+type Container struct {
+	ID    string
+	Image string
+	t     testing.TB
+}
+
+// Run starts image in the background with env, publishing ports on random
+// host ports. The container is removed when the test ends.
+func Run(t testing.TB, image string, env map[string]string, ports ...string) *Container {
+	t.Helper()
+	RequireDocker(t)
+
+	args := []string{"run", "-d", "--label", "one.sdbx.e2e=true"}
+	for key, value := range env {
+		args = append(args, "-e", key+"="+value)
+	}
+	for _, port := range ports {
+		args = append(args, "-p", "127.0.0.1::"+port)
+	}
+	args = append(args, image)
+
+	out, err := exec.Command("docker", args...).CombinedOutput()
+	if err != nil {
+		t.Fatalf("failed to start %s: %v\n%s", image, err, out)
+	}
+	c := &Container{ID: strings.TrimSpace(string(out)), Image: image, t: t}
+	t.Cleanup(func() {
+		if t.Failed() {
+			logs, _ := exec.Command("docker", "logs", "--tail", "50", c.ID).CombinedOutput()
+			t.Logf("last logs of %s:\n%s", image, logs)
+		}
+		_ = exec.Command("docker", "rm", "-f", "-v", c.ID).Run()
+	})
+	return c
+}
+
+// RequireDocker fails the test when no Docker daemon is reachable
+func RequireDocker(t testing.TB) {
+	t.Helper()
+	if err := exec.Command("docker", "info").Run(); err != nil {
+		t.Fatalf("end-to-end tests need a running Docker daemon: %v", err)
+	}
+}
+
+// URL returns the http:// URL of a published container port
+func (c *Container) URL(port string) string {
+	c.t.Helper()
+	out, err := exec.Command("docker", "port", c.ID, port).Output()
+	if err != nil {
+		c.t.Fatalf("port %s of %s is not published: %v", port, c.Image, err)
+	}
+	// One line per address family, e.g. 127.0.0.1:49153
+	addr, _, _ := strings.Cut(strings.TrimSpace(string(out)), "\n")
+	return "http://" + addr
+}
+
+// WaitForLog waits until a log line matches re and returns its submatches
+func (c *Container) WaitForLog(re *regexp.Regexp, timeout time.Duration) []string {
+	c.t.Helper()
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		out, _ := exec.Command("docker", "logs", c.ID).CombinedOutput()
+		if m := re.FindStringSubmatch(string(out)); m != nil {
+			return m
+		}
+		time.Sleep(time.Second)
+	}
+	c.t.Fatalf("%s did not log %q within %s", c.Image, re, timeout)
+	return nil
+}
+
+// WaitForHTTP waits until url answers with a status below 500
+func (c *Container) WaitForHTTP(url string, timeout time.Duration) {
+	c.t.Helper()
+	client := &http.Client{Timeout: 5 * time.Second}
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		if resp, err := client.Get(url); err == nil {
+			resp.Body.Close()
+			if resp.StatusCode < 500 {
+				return
+			}
+		}
+		time.Sleep(time.Second)
+	}
+	c.t.Fatalf("%s did not answer on %s within %s", c.Image, url, timeout)
+}
+
+// Exec runs a command in the container and returns its output
+func (c *Container) Exec(ctx context.Context, command ...string) (string, error) {
+	out, err := exec.CommandContext(ctx, "docker", append([]string{"exec", c.ID}, command...)...).CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("%s: %w\n%s", strings.Join(command, " "), err, out)
+	}
+	return string(out), nil
+}
@@ -0,0 +1,146 @@
+// Package events keeps the log of changes made to a project through the
+// web UI, its API and the CLI, and who made them.
+package events
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+)
+
+// LogFile is the events log in the project directory, one JSON event per line
+const LogFile = ".sdbx.events.log"
+
+// maxLogSize is the size at which the log is rotated to LogFile.1, so the
+// log keeps between one and two of these of history
+const maxLogSize = 5 << 20
+
+// Event sources
+const (
+	SourceWeb   = "web"   // Web UI and its API
+	SourceCLI   = "cli"   // sdbx commands
+	SourceAgent = "agent" // API of 'sdbx agent serve'
+)
+
+// Event is a change made to the project
+type Event struct {
+	Time   time.Time `json:"time"`
+	Source string    `json:"source"`           // web | cli | agent
+	User   string    `json:"user,omitempty"`   // Authelia user, token:<name>, or the CLI's OS user
+	Action string    `json:"action"`           // e.g. "POST /api/services/plex/restart" or "sdbx up"
+	Status int       `json:"status,omitempty"` // HTTP status of web events
+}
+
+// Filter selects events to read. Zero fields match every event.
+type Filter struct {
+	Source string
+	User   string
+	Since  time.Time
+	Limit  int // Newest events kept
+}
+
+// Matches reports whether an event is selected by the filter
+func (f Filter) Matches(e Event) bool {
+	return (f.Source == "" || e.Source == f.Source) &&
+		(f.User == "" || e.User == f.User) &&
+		(f.Since.IsZero() || !e.Time.Before(f.Since))
+}
+
+// Log appends events to and reads them from a project's LogFile
+type Log struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewLog returns the events log of a project
+func NewLog(projectDir string) *Log {
+	return &Log{path: filepath.Join(projectDir, LogFile)}
+}
+
+// Path is the file holding the events
+func (l *Log) Path() string { return l.path }
+
+// Record appends an event, rotating the log when it grows past maxLogSize
+func (l *Log) Record(e Event) error {
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	line, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("failed to encode event: %w", err)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if info, err := os.Stat(l.path); err == nil && info.Size() >= maxLogSize {
+		if err := os.Rename(l.path, l.path+".1"); err != nil {
+			return fmt.Errorf("failed to rotate %s: %w", LogFile, err)
+		}
+	}
+
+	f, err := os.OpenFile(l.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", LogFile, err)
+	}
+	// A single write per event keeps lines whole across processes
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("failed to write %s: %w", LogFile, err)
+	}
+	return f.Close()
+}
+
+// Read returns the events matching filter, newest first
+func (l *Log) Read(filter Filter) ([]Event, error) {
+	var all []Event
+	for _, path := range []string{l.path + ".1", l.path} {
+		events, err := readFile(path, filter)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, events...)
+	}
+
+	slices.Reverse(all)
+	if filter.Limit > 0 && len(all) > filter.Limit {
+		all = all[:filter.Limit]
+	}
+	return all, nil
+}
+
+// readFile reads the matching events of one log file, oldest first. Lines
+// that do not parse, such as one cut short by a crash, are skipped.
+func readFile(path string, filter Filter) ([]Event, error) {
+	f, err := os.Open(path) //nolint:gosec // G304 - path within project directory
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", LogFile, err)
+	}
+	defer f.Close()
+
+	var events []Event
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var e Event
+		if json.Unmarshal(scanner.Bytes(), &e) != nil {
+			continue
+		}
+		if filter.Matches(e) {
+			events = append(events, e)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", LogFile, err)
+	}
+	return events, nil
+}
@@ -0,0 +1,92 @@
+package events
+
+import (
+	"bytes"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestLogRecordRead(t *testing.T) {
+	log := NewLog(t.TempDir())
+
+	events, err := log.Read(Filter{})
+	if err != nil || len(events) != 0 {
+		t.Fatalf("Read without log = %v, %v; want no events", events, err)
+	}
+
+	base := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	recorded := []Event{
+		{Time: base, Source: SourceCLI, User: "alice", Action: "sdbx up"},
+		{Time: base.Add(time.Minute), Source: SourceWeb, User: "bob", Action: "POST /api/services/plex/restart", Status: 200},
+		{Time: base.Add(2 * time.Minute), Source: SourceWeb, User: "token:ci", Action: "POST /api/backup/create", Status: 202},
+	}
+	for _, e := range recorded {
+		if err := log.Record(e); err != nil {
+			t.Fatalf("Record failed: %v", err)
+		}
+	}
+
+	events, err = log.Read(Filter{})
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+	if len(events) != 3 || events[0].User != "token:ci" || events[2].Action != "sdbx up" {
+		t.Errorf("Read() = %+v, want all events newest first", events)
+	}
+
+	tests := []struct {
+		name   string
+		filter Filter
+		want   int
+	}{
+		{"web", Filter{Source: SourceWeb}, 2},
+		{"user", Filter{User: "bob"}, 1},
+		{"since", Filter{Since: base.Add(time.Minute)}, 2},
+		{"limit", Filter{Limit: 1}, 1},
+		{"no match", Filter{Source: SourceCLI, User: "bob"}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events, err := log.Read(tt.filter)
+			if err != nil {
+				t.Fatalf("Read failed: %v", err)
+			}
+			if len(events) != tt.want {
+				t.Errorf("Read(%+v) returned %d events, want %d", tt.filter, len(events), tt.want)
+			}
+		})
+	}
+}
+
+func TestLogRotate(t *testing.T) {
+	log := NewLog(t.TempDir())
+
+	if err := log.Record(Event{Source: SourceCLI, Action: "sdbx down"}); err != nil {
+		t.Fatalf("Record failed: %v", err)
+	}
+	f, err := os.OpenFile(log.Path(), os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	padding := append(bytes.Repeat([]byte(" "), 1023), '\n')
+	if _, err := f.Write(bytes.Repeat(padding, maxLogSize/len(padding))); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	if err := log.Record(Event{Source: SourceCLI, Action: "sdbx up"}); err != nil {
+		t.Fatalf("Record failed: %v", err)
+	}
+
+	if _, err := os.Stat(log.Path() + ".1"); err != nil {
+		t.Fatalf("log was not rotated: %v", err)
+	}
+	events, err := log.Read(Filter{})
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+	// Blank padding lines are skipped; both events survive rotation
+	if len(events) != 2 || events[0].Action != "sdbx up" || events[1].Action != "sdbx down" {
+		t.Errorf("Read() after rotation = %+v", events)
+	}
+}
@@ -0,0 +1,78 @@
+package generator
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// autheliaSections are the top-level keys of Authelia's configuration
+var autheliaSections = []string{
+	"access_control", "authentication_backend", "certificates_directory",
+	"default_2fa_method", "default_redirection_url", "definitions", "duo_api",
+	"identity_providers", "identity_validation", "jwt_secret", "log", "notifier",
+	"ntp", "password_policy", "privacy_policy", "regulation", "server", "session",
+	"storage", "telemetry", "theme", "totp", "webauthn",
+}
+
+// autheliaPolicies are the policies of access control rules
+var autheliaPolicies = []string{"bypass", "one_factor", "two_factor", "deny"}
+
+// autheliaConfiguration is the part of Authelia's configuration sdbx checks
+type autheliaConfiguration struct {
+	AccessControl struct {
+		DefaultPolicy string `yaml:"default_policy"`
+		Rules         []struct {
+			Domain      interface{} `yaml:"domain"`
+			DomainRegex interface{} `yaml:"domain_regex"`
+			Policy      string      `yaml:"policy"`
+		} `yaml:"rules"`
+	} `yaml:"access_control"`
+	Session struct {
+		Domain string `yaml:"domain"`
+	} `yaml:"session"`
+	Notifier map[string]interface{} `yaml:"notifier"`
+}
+
+// validateAutheliaConfig checks an Authelia configuration, merged with its
+// user fragment, against the rules Authelia enforces at startup, so a
+// mistake fails generation instead of crash looping the container
+func validateAutheliaConfig(data []byte) error {
+	var sections map[string]interface{}
+	if err := yaml.Unmarshal(data, &sections); err != nil {
+		return err
+	}
+	for key := range sections {
+		if !slices.Contains(autheliaSections, key) {
+			return fmt.Errorf("unknown setting %q", key)
+		}
+	}
+
+	var c autheliaConfiguration
+	if err := yaml.Unmarshal(data, &c); err != nil {
+		return err
+	}
+	if !slices.Contains(autheliaPolicies, c.AccessControl.DefaultPolicy) {
+		return fmt.Errorf("access_control.default_policy: must be one of: %s", strings.Join(autheliaPolicies, ", "))
+	}
+	for i, rule := range c.AccessControl.Rules {
+		if rule.Domain == nil && rule.DomainRegex == nil {
+			return fmt.Errorf("access_control.rules[%d]: domain or domain_regex is required", i)
+		}
+		if !slices.Contains(autheliaPolicies, rule.Policy) {
+			return fmt.Errorf("access_control.rules[%d].policy: must be one of: %s", i, strings.Join(autheliaPolicies, ", "))
+		}
+	}
+	if c.Session.Domain == "" {
+		return fmt.Errorf("session.domain is required")
+	}
+	// Authelia refuses to start with both notifiers, or none
+	_, smtp := c.Notifier["smtp"]
+	_, filesystem := c.Notifier["filesystem"]
+	if smtp == filesystem {
+		return fmt.Errorf("notifier: exactly one of smtp and filesystem must be set (set the smtp section of .sdbx.yaml rather than notifier.smtp)")
+	}
+	return nil
+}
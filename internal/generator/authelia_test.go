@@ -0,0 +1,158 @@
+package generator
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/maiko/sdbx/internal/config"
+	"github.com/maiko/sdbx/internal/registry"
+)
+
+func TestValidateAutheliaConfig(t *testing.T) {
+	valid := `theme: dark
+access_control:
+  default_policy: deny
+  rules:
+    - domain: "auth.example.com"
+      policy: bypass
+    - domain_regex: "^.*\\.example\\.com$"
+      policy: two_factor
+session:
+  domain: example.com
+notifier:
+  filesystem:
+    filename: /data/notification.txt
+`
+	tests := []struct {
+		name    string
+		data    string
+		wantErr string
+	}{
+		{"valid", valid, ""},
+		{"unknown section", valid + "sesion:\n  name: typo\n", `unknown setting "sesion"`},
+		{"bad rule policy", strings.Replace(valid, "policy: bypass", "policy: allow", 1), "rules[0].policy"},
+		{"rule without domain", strings.Replace(valid, `domain: "auth.example.com"`, `resources: ["^/api"]`, 1), "rules[0]: domain"},
+		{"two notifiers", valid + "  smtp:\n    host: smtp.example.com\n", "exactly one of smtp and filesystem"},
+		{"no session domain", strings.Replace(valid, "domain: example.com", "name: authelia_session", 1), "session.domain"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateAutheliaConfig([]byte(tt.data))
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("validateAutheliaConfig() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("validateAutheliaConfig() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestGenerateRejectsInvalidAutheliaFragment(t *testing.T) {
+	tmpDir := t.TempDir()
+	fragment := filepath.Join(tmpDir, "configs/authelia/user/configuration.yml")
+	if err := os.MkdirAll(filepath.Dir(fragment), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(fragment, []byte("notifier:\n  smtp:\n    host: smtp.example.com\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	err := NewGenerator(config.DefaultConfig(), tmpDir).Generate()
+	if err == nil || !strings.Contains(err.Error(), "smtp section") {
+		t.Fatalf("Generate() error = %v, want the notifier conflict", err)
+	}
+	if _, err := os.Stat(filepath.Join(tmpDir, "configs/authelia/configuration.yml")); !os.IsNotExist(err) {
+		t.Error("configuration.yml was written by a failed generation")
+	}
+}
+
+func TestGenerateKeepsAutheliaSettings(t *testing.T) {
+	tmpDir := t.TempDir()
+	cfg := config.DefaultConfig()
+	cfg.Authelia = config.AutheliaConfig{
+		Policy:  config.AutheliaPolicyTwoFactor,
+		Session: config.AutheliaSessionConfig{Expiration: "12h"},
+	}
+	cfg.SMTP = config.SMTPConfig{Host: "smtp.example.com", User: "auth", Sender: "SDBX <auth@example.com>"}
+	cfg.Timeouts = config.TimeoutsConfig{Compose: "45m"}
+	if err := NewGenerator(cfg, tmpDir).Generate(); err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+
+	// .sdbx.yaml is rewritten by generation and must keep the sections
+	data, err := os.ReadFile(filepath.Join(tmpDir, ".sdbx.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := config.Parse(data)
+	if err != nil {
+		t.Fatalf("failed to parse .sdbx.yaml: %v\n%s", err, data)
+	}
+	if parsed.Authelia.Policy != cfg.Authelia.Policy || parsed.Authelia.Session != cfg.Authelia.Session {
+		t.Errorf("authelia = %+v, want %+v", parsed.Authelia, cfg.Authelia)
+	}
+	if parsed.SMTP != cfg.SMTP {
+		t.Errorf("smtp = %+v, want %+v", parsed.SMTP, cfg.SMTP)
+	}
+	if parsed.Timeouts != cfg.Timeouts {
+		t.Errorf("timeouts = %+v, want %+v", parsed.Timeouts, cfg.Timeouts)
+	}
+}
+
+func TestGenerateAutheliaProfileRules(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Domain = "example.com"
+	cfg.Routing.Strategy = config.RoutingStrategyPath
+	cfg.Routing.BaseDomain = "sdbx"
+	cfg.AccessProfiles = []config.AccessProfileConfig{
+		{Name: "friends", Services: []string{"overseerr", "plex", "sonarr"}},
+		{Name: "family", Services: []string{"plex"}, Policy: config.AutheliaPolicyTwoFactor},
+	}
+
+	overseerr := makeResolvedService("overseerr", &registry.ServiceDefinition{
+		Metadata: registry.ServiceMetadata{Name: "overseerr"},
+		Routing:  registry.RoutingConfig{Enabled: true, Path: "/overseerr"},
+	})
+	plex := makeResolvedService("plex", &registry.ServiceDefinition{
+		Metadata: registry.ServiceMetadata{Name: "plex"},
+		Routing:  registry.RoutingConfig{Enabled: true, Subdomain: "plex", ForceSubdomain: true},
+	})
+	rules := NewIntegrationsGenerator(cfg, nil).GenerateAutheliaProfileRules(makeTestGraph(overseerr, plex))
+
+	want := []AutheliaAccessRule{
+		{Domain: "sdbx.example.com", Resources: []string{"^/overseerr([/?].*)?$"}, Subject: []string{"group:friends"}, Policy: "one_factor"},
+		{Domain: "plex.example.com", Subject: []string{"group:friends"}, Policy: "one_factor"},
+		{Domain: "plex.example.com", Subject: []string{"group:family"}, Policy: "two_factor"},
+		{Domain: "example.com", Subject: []string{"group:friends", "group:family"}, Policy: "deny"},
+		{Domain: "*.example.com", Subject: []string{"group:friends", "group:family"}, Policy: "deny"},
+	}
+	if !reflect.DeepEqual(rules, want) {
+		t.Errorf("rules = %+v\nwant %+v", rules, want)
+	}
+
+	// Rendered before the rule granting every service
+	tmpDir := t.TempDir()
+	cfg.Routing.Strategy = config.RoutingStrategySubdomain
+	if err := NewGenerator(cfg, tmpDir).Generate(); err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(tmpDir, "configs/authelia/configuration.yml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := validateAutheliaConfig(data); err != nil {
+		t.Fatalf("invalid configuration.yml: %v", err)
+	}
+	out := string(data)
+	deny, all := strings.Index(out, "policy: deny"), strings.Index(out, `- "*.example.com"`)
+	if deny < 0 || all < 0 || deny > all {
+		t.Errorf("profile rules missing or after the catch-all rule:\n%s", out)
+	}
+}
@@ -2,15 +2,27 @@ package generator
 
 import (
 	"bytes"
+	"cmp"
 	"fmt"
 	"log"
+	"maps"
+	"path"
+	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
 	"text/template"
+	"time"
 
 	"gopkg.in/yaml.v3"
 
+	"github.com/maiko/sdbx/internal/auth"
 	"github.com/maiko/sdbx/internal/config"
+	"github.com/maiko/sdbx/internal/docker"
+	"github.com/maiko/sdbx/internal/geoip"
+	"github.com/maiko/sdbx/internal/problem"
 	"github.com/maiko/sdbx/internal/registry"
+	"github.com/maiko/sdbx/internal/statuspage"
 )
 
 // ComposeGenerator generates Docker Compose files from registry definitions
@@ -19,6 +31,38 @@ type ComposeGenerator struct {
 	Registry *registry.Registry
 	Secrets  map[string]string
 	funcMap  template.FuncMap
+
+	// Previous is the compose file from the last generation, if any.
+	// Blocks of services listed in Unchanged are copied from it verbatim so
+	// regeneration does not churn containers whose inputs did not change.
+	Previous  *ComposeFile
+	Unchanged map[string]bool
+
+	// EnvFiles holds the contents of secrets/<service>.env for services
+	// with secrets delivered through env_file, keyed by service name
+	EnvFiles map[string][]byte
+
+	// Kept lists the skipped services whose block of Previous was kept
+	Kept map[string]bool
+
+	// HostPorts holds the host port of each published port, keyed by
+	// registry.HostPortKey: the previous generation's before Generate, so
+	// allocated ports stay stable, and this generation's after it
+	HostPorts map[string]int
+
+	// portFree reports whether a host port can be bound; nil checks the host
+	portFree func(port int, proto string) bool
+
+	// external holds the configured external dependencies by name, for the
+	// external* template helpers
+	external map[string]registry.ExternalDependency
+
+	// volumes holds the named volumes services may mount, by name
+	volumes map[string]config.VolumeDefinition
+
+	// templates caches parsed template strings by content, with nil for
+	// those that failed to parse, so each is parsed once per generation
+	templates map[string]*template.Template
 }
 
 // NewComposeGenerator creates a new compose generator
@@ -27,6 +71,9 @@ func NewComposeGenerator(cfg *config.Config, reg *registry.Registry, secrets map
 		Config:   cfg,
 		Registry: reg,
 		Secrets:  secrets,
+		EnvFiles: make(map[string][]byte),
+
+		templates: make(map[string]*template.Template),
 	}
 	g.initFuncMap()
 	return g
@@ -38,11 +85,13 @@ type ComposeFile struct {
 	Services map[string]ComposeService   `yaml:"services"`
 	Networks map[string]ComposeNetwork   `yaml:"networks,omitempty"`
 	Secrets  map[string]ComposeSecretDef `yaml:"secrets,omitempty"`
+	Volumes  map[string]ComposeVolume    `yaml:"volumes,omitempty"`
 }
 
 // ComposeService represents a Docker Compose service
 type ComposeService struct {
 	Image         string                        `yaml:"image"`
+	Platform      string                        `yaml:"platform,omitempty"`
 	ContainerName string                        `yaml:"container_name"`
 	Restart       string                        `yaml:"restart,omitempty"`
 	Environment   []string                      `yaml:"environment,omitempty"`
@@ -51,6 +100,7 @@ type ComposeService struct {
 	Ports         []string                      `yaml:"ports,omitempty"`
 	Networks      []string                      `yaml:"networks,omitempty"`
 	NetworkMode   string                        `yaml:"network_mode,omitempty"`
+	DNS           []string                      `yaml:"dns,omitempty"`
 	DependsOn     map[string]DependsOnCondition `yaml:"depends_on,omitempty"`
 	Labels        []string                      `yaml:"labels,omitempty"`
 	HealthCheck   *ComposeHealthCheck           `yaml:"healthcheck,omitempty"`
@@ -61,6 +111,20 @@ type ComposeService struct {
 	ShmSize       string                        `yaml:"shm_size,omitempty"`
 	Sysctls       map[string]string             `yaml:"sysctls,omitempty"`
 	Deploy        *ComposeDeploy                `yaml:"deploy,omitempty"`
+	Logging       *ComposeLogging               `yaml:"logging,omitempty"`
+
+	// Extra holds Compose properties SDBX does not model (services.<name>.compose_extra)
+	Extra map[string]interface{} `yaml:",inline"`
+
+	// NetworkAddresses holds the static IPv4 address of the container on
+	// some of its networks; networks are then written as a mapping
+	NetworkAddresses map[string]string `yaml:"-"`
+}
+
+// ComposeLogging represents Docker Compose logging configuration
+type ComposeLogging struct {
+	Driver  string            `yaml:"driver"`
+	Options map[string]string `yaml:"options,omitempty"`
 }
 
 // ComposeDeploy represents Docker Compose deploy configuration
@@ -70,11 +134,14 @@ type ComposeDeploy struct {
 
 // ComposeResources represents resource reservations
 type ComposeResources struct {
+	Limits       *ComposeResourceSpec `yaml:"limits,omitempty"`
 	Reservations *ComposeResourceSpec `yaml:"reservations,omitempty"`
 }
 
 // ComposeResourceSpec represents a resource specification
 type ComposeResourceSpec struct {
+	CPUs    string              `yaml:"cpus,omitempty"`
+	Memory  string              `yaml:"memory,omitempty"`
 	Devices []ComposeDeviceSpec `yaml:"devices,omitempty"`
 }
 
@@ -101,7 +168,33 @@ type ComposeHealthCheck struct {
 
 // ComposeNetwork represents a Docker Compose network
 type ComposeNetwork struct {
-	Name string `yaml:"name,omitempty"`
+	Name     string       `yaml:"name,omitempty"`
+	Internal bool         `yaml:"internal,omitempty"`
+	IPAM     *ComposeIPAM `yaml:"ipam,omitempty"`
+}
+
+// ComposeIPAM pins the subnets of a network (networks.<name>.subnet)
+type ComposeIPAM struct {
+	Config []ComposeIPAMPool `yaml:"config"`
+}
+
+// ComposeIPAMPool is a subnet of a network
+type ComposeIPAMPool struct {
+	Subnet string `yaml:"subnet"`
+}
+
+// zoneNetworks maps network zones to the compose network that backs them.
+// The frontend zone reuses the existing proxy network so Traefik can reach it.
+var zoneNetworks = map[string]string{
+	registry.ZoneFrontend:  "proxy",
+	registry.ZoneBackend:   "backend",
+	registry.ZoneDownloads: "downloads",
+}
+
+// zoneNetworkDefs holds the definitions for networks only created on demand
+var zoneNetworkDefs = map[string]ComposeNetwork{
+	"backend":   {Name: "sdbx_backend", Internal: true},
+	"downloads": {Name: "sdbx_downloads"},
 }
 
 // ComposeSecretDef represents a Docker Compose secret definition
@@ -109,6 +202,12 @@ type ComposeSecretDef struct {
 	File string `yaml:"file"`
 }
 
+// ComposeVolume represents a Docker Compose named volume
+type ComposeVolume struct {
+	Driver     string            `yaml:"driver,omitempty"`
+	DriverOpts map[string]string `yaml:"driver_opts,omitempty"`
+}
+
 // initFuncMap initializes custom template functions.
 // Go's built-in template functions (eq, ne, not, or, and, etc.) are available by default.
 func (g *ComposeGenerator) initFuncMap() {
@@ -125,14 +224,54 @@ func (g *ComposeGenerator) initFuncMap() {
 			}
 			return val
 		},
+		// External dependencies: {{ external "db" }} is the URL of http
+		// dependencies and host:port otherwise
+		"external":     g.externalField(registry.ExternalDependency.Endpoint),
+		"externalHost": g.externalField(func(d registry.ExternalDependency) string { return d.Host }),
+		"externalPort": g.externalField(func(d registry.ExternalDependency) string { return strconv.Itoa(d.Port) }),
+		"externalURL":  g.externalField(func(d registry.ExternalDependency) string { return d.URL }),
+		"externalPath": g.externalField(func(d registry.ExternalDependency) string { return d.Path }),
+	}
+}
+
+// externalField returns a template helper reading a field of a named external dependency
+func (g *ComposeGenerator) externalField(field func(registry.ExternalDependency) string) func(string) (string, error) {
+	return func(name string) (string, error) {
+		dep, ok := g.external[name]
+		if !ok {
+			return "", fmt.Errorf("undefined external dependency %q", name)
+		}
+		return field(dep), nil
 	}
 }
 
 // TemplateContext provides data for template evaluation
 type TemplateContext struct {
-	Config  *config.Config
-	Secrets map[string]string
-	Name    string
+	Config   *config.Config
+	Secrets  map[string]string
+	Name     string
+	Instance registry.InstanceContext
+	GeoIP    GeoIPContext
+}
+
+// GeoIPContext holds the container paths of the GeoIP databases, mounted
+// read-only into services whose definition declares requires: geoip
+type GeoIPContext struct {
+	Dir     string // e.g. /geoip
+	City    string // e.g. /geoip/GeoLite2-City.mmdb
+	Country string
+	ASN     string
+}
+
+// newGeoIPContext returns the container paths of the GeoIP databases
+func newGeoIPContext() GeoIPContext {
+	db := func(edition string) string { return path.Join(geoip.ContainerDir, geoip.Filename(edition)) }
+	return GeoIPContext{
+		Dir:     geoip.ContainerDir,
+		City:    db("GeoLite2-City"),
+		Country: db("GeoLite2-Country"),
+		ASN:     db("GeoLite2-ASN"),
+	}
 }
 
 // Generate generates a Docker Compose file from resolved services
@@ -147,45 +286,356 @@ func (g *ComposeGenerator) Generate(graph *registry.ResolutionGraph) (*ComposeFi
 		Secrets: make(map[string]ComposeSecretDef),
 	}
 
-	// Generate services in dependency order
+	// Resolve external dependencies for the template helpers
+	external, err := graph.ExternalDependencies(g.Config)
+	if err != nil {
+		return nil, err
+	}
+	g.external = make(map[string]registry.ExternalDependency, len(external))
+	for _, dep := range external {
+		if _, ok := g.external[dep.Name]; !ok {
+			g.external[dep.Name] = dep.ExternalDependency
+		}
+	}
+
+	// Named volumes declared by definitions and .sdbx.yaml
+	if g.volumes, err = graph.VolumeDefinitions(g.Config); err != nil {
+		return nil, err
+	}
+
+	// Generate services in dependency order. A malformed definition skips
+	// its service, the others are still generated.
+	skipped := make(map[string]error)
 	for _, serviceName := range graph.Order {
 		resolved := graph.Services[serviceName]
 		if !resolved.Enabled {
 			continue
 		}
 
-		def := resolved.FinalDefinition
-
-		// Check conditions
-		if !g.evaluateConditions(def.Conditions) {
+		// Generate compose service, when its conditions are met
+		svc, ok, err := g.generateServiceSafely(resolved.FinalDefinition)
+		if err != nil {
+			skipped[serviceName] = err
 			continue
 		}
-
-		// Generate compose service
-		svc := g.generateService(def)
+		if !ok {
+			continue
+		}
+		if extra := g.Config.Services[serviceName].ComposeExtra; len(extra) > 0 {
+			merged, err := mergeComposeExtra(svc, extra)
+			if err != nil {
+				return nil, fmt.Errorf("services.%s.compose_extra: %w", serviceName, err)
+			}
+			svc = merged
+		}
 		compose.Services[serviceName] = svc
 
 		// Collect secrets
-		for _, secret := range def.Secrets {
-			compose.Secrets[secret.Name] = ComposeSecretDef{
-				File: fmt.Sprintf("./secrets/%s.txt", secret.Name),
+		for _, secret := range svc.Secrets {
+			compose.Secrets[secret] = ComposeSecretDef{
+				File: fmt.Sprintf("./secrets/%s.txt", secret),
 			}
 		}
 	}
 
+	// Services left out are dropped from the graph with the services that
+	// need them, so integrations and .env do not reference them either. The
+	// blocks they had in the previous compose.yaml are kept, so up does not
+	// remove their running containers as orphans.
+	skipDependents(compose, skipped)
+	left := maps.Clone(skipped)
+	for _, e := range graph.Skipped() {
+		left[e.Service] = nil
+	}
+	g.Kept = g.keepPreviousBlocks(compose, left)
+	for _, name := range slices.Sorted(maps.Keys(skipped)) {
+		if dep, ok := skipped[name].(dependencySkipped); ok {
+			graph.Skip(name, "depends on skipped service "+string(dep), nil)
+		} else {
+			graph.Skip(name, "invalid service definition", skipped[name])
+		}
+		delete(g.EnvFiles, name)
+	}
+
 	// Transfer labels for services using network_mode: service:X
 	g.transferLabelsForNetworkSharing(compose)
 
+	// Reuse previous blocks for services whose inputs are unchanged
+	g.reuseUnchangedServices(compose)
+
+	// Ownership labels let status, prune and the web UI recognise SDBX containers
+	for name, svc := range compose.Services {
+		if resolved, ok := graph.Services[name]; ok {
+			svc.Labels = ownershipLabels(svc.Labels, name, resolved.DefinitionHash, resolved.Source)
+			compose.Services[name] = svc
+		}
+	}
+
+	// File server for extras.static_sites, extras.error_pages and maintenance pages
+	if g.Config.NeedsStaticServer() {
+		compose.Services["static"] = g.staticService()
+	}
+
+	// Log shipping agent for logging.aggregation
+	if agg := g.Config.Logging.Aggregation; agg != nil {
+		compose.Services[agg.Agent] = g.logShippingService(agg.Agent)
+	}
+
+	// rclone mount sidecar for storage.rclone; services mounting the storage wait for it
+	if r := g.Config.Storage.Rclone; r != nil && r.RcloneMode() == config.RcloneModeContainer {
+		compose.Services["rclone"] = g.rcloneService(*r)
+		g.dependOnStorage(compose, "rclone")
+	}
+
+	// Local DNS server for dns.server; the other services resolve through it
+	if g.Config.DNS.IsEnabled() {
+		compose.Services[g.Config.DNS.Server] = g.dnsService()
+		g.useLocalDNS(compose)
+	}
+
+	// Certificates of the custom TLS provider
+	if g.Config.Expose.Mode == config.ExposeModeDirect && g.Config.Expose.TLS.Provider == config.TLSProviderCustom {
+		g.mountCertificates(compose)
+	}
+
+	// mDNS responder for the .local names of mdns.enabled
+	if g.Config.MDNS.IsEnabled() {
+		compose.Services["mdns"] = g.mdnsService()
+	}
+
+	// Two services cannot bind the same host port: conflicts get a port of
+	// host_ports.range when configured, and fail generation otherwise
+	if err := g.allocateHostPorts(compose); err != nil {
+		return nil, err
+	}
+	if err := checkPortConflicts(compose); err != nil {
+		return nil, err
+	}
+
+	// Static addresses must be free and in the configured subnet
+	if err := g.checkStaticAddresses(compose); err != nil {
+		return nil, err
+	}
+
+	// Declare zone networks that are actually in use, with their subnets
+	addZoneNetworks(compose)
+	g.addSubnets(compose)
+
+	// Declare named volumes that are actually mounted
+	g.addNamedVolumes(compose)
+
 	return compose, nil
 }
 
+// staticService builds the nginx container serving static sites and error pages.
+// Each site is mounted under its own prefix; Traefik routers add the prefix back.
+func (g *ComposeGenerator) staticService() ComposeService {
+	svc := ComposeService{
+		Image:         "nginx:alpine",
+		ContainerName: "sdbx-static",
+		Restart:       "unless-stopped",
+		Networks:      []string{"proxy"},
+		Labels:        ownershipLabels(watchtowerLabels(true, g.Config.UpdatePolicy("static")), "static", "", "sdbx"),
+		Volumes: []string{
+			"./configs/static/default.conf:/etc/nginx/conf.d/default.conf:ro",
+			"./configs/static/maintenance:/usr/share/nginx/html/_maintenance_page:ro",
+		},
+	}
+	for _, site := range g.Config.Extras.StaticSites {
+		svc.Volumes = append(svc.Volumes, fmt.Sprintf("%s:/usr/share/nginx/html/%s:ro", site.Directory, site.Name))
+	}
+	if g.Config.Extras.ErrorPages != "" {
+		svc.Volumes = append(svc.Volumes, fmt.Sprintf("%s:/usr/share/nginx/html/_errors:ro", g.Config.Extras.ErrorPages))
+	}
+	if g.Config.StatusPage.Enabled {
+		svc.Volumes = append(svc.Volumes, fmt.Sprintf("./%s:/usr/share/nginx/html/_status:ro", statuspage.Dir))
+	}
+	svc.Logging = g.buildLogging("static", nil)
+	return svc
+}
+
+// logShippingService builds the vector or promtail container tailing the logs
+// of SDBX containers through the Docker socket
+func (g *ComposeGenerator) logShippingService(agent string) ComposeService {
+	svc := ComposeService{
+		ContainerName: "sdbx-" + agent,
+		Restart:       "unless-stopped",
+		Networks:      []string{"proxy"},
+		Labels:        ownershipLabels(watchtowerLabels(true, g.Config.UpdatePolicy(agent)), agent, "", "sdbx"),
+		Volumes:       []string{"/var/run/docker.sock:/var/run/docker.sock:ro"},
+	}
+	switch agent {
+	case config.LogAgentPromtail:
+		svc.Image = "grafana/promtail:latest"
+		svc.Command = "-config.file=/etc/promtail/config.yml"
+		svc.Volumes = append(svc.Volumes,
+			"./configs/promtail/config.yml:/etc/promtail/config.yml:ro",
+			"./configs/promtail/data:/var/lib/promtail",
+		)
+	default:
+		svc.Image = "timberio/vector:latest-alpine"
+		svc.Volumes = append(svc.Volumes,
+			"./configs/vector/vector.yaml:/etc/vector/vector.yaml:ro",
+			"./configs/vector/data:/var/lib/vector",
+		)
+	}
+	svc.Logging = g.buildLogging(agent, nil)
+	return svc
+}
+
+// rcloneService builds the container mounting storage.rclone.remote on the
+// host. The mount propagates back through an rshared bind, and the health
+// check passes once the FUSE mount answers.
+func (g *ComposeGenerator) rcloneService(r config.RcloneConfig) ComposeService {
+	configFile := r.ConfigFile()
+	args := []string{
+		"mount", r.Remote, "/data",
+		"--config=/config/rclone/" + filepath.Base(configFile),
+		"--cache-dir=/cache",
+		fmt.Sprintf("--uid=%d", g.Config.PUID),
+		fmt.Sprintf("--gid=%d", g.Config.PGID),
+		"--umask=" + g.Config.Umask,
+	}
+	args = append(args, r.MountOptions()...)
+
+	svc := ComposeService{
+		Image:         "rclone/rclone:latest",
+		ContainerName: "sdbx-rclone",
+		Restart:       "unless-stopped",
+		Command:       strings.Join(args, " "),
+		Labels:        ownershipLabels(watchtowerLabels(true, g.Config.UpdatePolicy("rclone")), "rclone", "", "sdbx"),
+		CapAdd:        []string{"SYS_ADMIN"},
+		Devices:       []string{"/dev/fuse:/dev/fuse"},
+		Volumes: []string{
+			filepath.Dir(configFile) + ":/config/rclone",
+			"./data/rclone-cache:/cache",
+			r.Mount + ":/data:rshared",
+		},
+		HealthCheck: &ComposeHealthCheck{
+			Test:        []string{"CMD-SHELL", "grep -q ' /data fuse.rclone ' /proc/mounts && ls /data >/dev/null"},
+			Interval:    "30s",
+			Timeout:     "10s",
+			Retries:     3,
+			StartPeriod: "30s",
+		},
+		// FUSE mounts are blocked by the default AppArmor profile
+		Extra: map[string]interface{}{"security_opt": []string{"apparmor:unconfined"}},
+	}
+	svc.Logging = g.buildLogging("rclone", nil)
+	return svc
+}
+
+// dependOnStorage makes services with a bind mount inside the rclone or
+// mergerfs mount wait until the storage container is healthy, so media
+// services never start on an empty mountpoint
+func (g *ComposeGenerator) dependOnStorage(compose *ComposeFile, storage string) {
+	var roots []string
+	if r := g.Config.Storage.Rclone; r != nil {
+		roots = append(roots, r.Mount)
+	}
+	if m := g.Config.Storage.Mergerfs; m != nil {
+		roots = append(roots, m.Mount)
+	}
+
+	for name, svc := range compose.Services {
+		if name == storage || !mountsUnder(svc.Volumes, roots) {
+			continue
+		}
+		if svc.DependsOn == nil {
+			svc.DependsOn = make(map[string]DependsOnCondition)
+		}
+		svc.DependsOn[storage] = DependsOnCondition{Condition: "service_healthy"}
+		compose.Services[name] = svc
+	}
+}
+
+// mountsUnder reports whether any bind mount source lies inside one of roots
+func mountsUnder(volumes, roots []string) bool {
+	for _, mount := range volumes {
+		source, _, _ := strings.Cut(mount, ":")
+		for _, root := range roots {
+			if source == root || strings.HasPrefix(source, strings.TrimSuffix(root, "/")+"/") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// buildLogging resolves a service's logging: the global settings, then the
+// service definition, then the per-service override in .sdbx.yaml.
+// Changing the driver drops options inherited for the previous driver.
+func (g *ComposeGenerator) buildLogging(name string, spec *registry.LoggingSpec) *ComposeLogging {
+	settings := g.Config.Logging
+	layers := []map[string]string{settings.Options}
+
+	if spec != nil {
+		if spec.Driver != "" && spec.Driver != settings.Driver {
+			settings.Driver, layers = spec.Driver, nil
+		}
+		layers = append(layers, spec.Options)
+	}
+
+	if override := g.Config.Services[name].Logging; override != nil {
+		if override.Driver != "" && override.Driver != settings.Driver {
+			settings.Driver, layers = override.Driver, nil
+		}
+		rotation := make(map[string]string)
+		if override.MaxSize != "" {
+			rotation["max-size"] = override.MaxSize
+		}
+		if override.MaxFile > 0 {
+			rotation["max-file"] = strconv.Itoa(override.MaxFile)
+		}
+		layers = append(layers, rotation, override.Options)
+	}
+
+	if settings.Driver == "" {
+		return nil
+	}
+
+	options := make(map[string]string)
+	if settings.Rotates() {
+		if settings.MaxSize != "" {
+			options["max-size"] = settings.MaxSize
+		}
+		if settings.MaxFile > 0 {
+			options["max-file"] = strconv.Itoa(settings.MaxFile)
+		}
+	}
+	for _, layer := range layers {
+		maps.Copy(options, layer)
+	}
+	if !settings.Rotates() {
+		delete(options, "max-size")
+		delete(options, "max-file")
+	}
+	if len(options) == 0 {
+		options = nil
+	}
+
+	return &ComposeLogging{Driver: settings.Driver, Options: options}
+}
+
+// ContainerName renders the container name of a service definition, as
+// generated into compose.yaml (e.g. sdbx-sonarr4k for a named instance)
+func ContainerName(cfg *config.Config, def *registry.ServiceDefinition) string {
+	g := NewComposeGenerator(cfg, nil, nil)
+	return g.evalTemplate(def.Spec.Container.NameTemplate, TemplateContext{
+		Config:   cfg,
+		Name:     def.Metadata.Name,
+		Instance: registry.NewInstanceContext(def, cfg),
+		GeoIP:    newGeoIPContext(),
+	})
+}
+
+// nvidiaEnvironment exposes the video encoders of NVIDIA GPUs to media
+// servers; compose device reservations only grant compute by default
+var nvidiaEnvironment = []string{"NVIDIA_VISIBLE_DEVICES=all", "NVIDIA_DRIVER_CAPABILITIES=compute,video,utility"}
+
 // generateService generates a single compose service
 func (g *ComposeGenerator) generateService(def *registry.ServiceDefinition) ComposeService {
-	ctx := TemplateContext{
-		Config:  g.Config,
-		Secrets: g.Secrets,
-		Name:    def.Metadata.Name,
-	}
+	ctx := g.templateContext(def)
 
 	svc := ComposeService{
 		Image:         g.resolveImage(def),
@@ -195,10 +645,15 @@ func (g *ComposeGenerator) generateService(def *registry.ServiceDefinition) Comp
 	}
 
 	// Environment variables
-	svc.Environment = g.buildEnvironment(def, ctx)
+	var delivered secretDelivery
+	svc.Environment, delivered = g.buildEnvironment(def, ctx)
 
-	// Env files
+	// Env files, plus the generated file holding env_file-delivered secrets
 	svc.EnvFile = def.Spec.Environment.EnvFile
+	if len(delivered.envFile) > 0 {
+		svc.EnvFile = append(slices.Clone(svc.EnvFile), fmt.Sprintf("./secrets/%s.env", def.Metadata.Name))
+		g.EnvFiles[def.Metadata.Name] = []byte(strings.Join(delivered.envFile, "\n") + "\n")
+	}
 
 	// Volumes
 	svc.Volumes = g.buildVolumes(def, ctx)
@@ -206,8 +661,13 @@ func (g *ComposeGenerator) generateService(def *registry.ServiceDefinition) Comp
 	// Ports
 	svc.Ports = g.buildPorts(def, ctx)
 
-	// Networks
+	// Networks, with the static address on the proxy network
 	svc.Networks, svc.NetworkMode = g.buildNetworking(def, ctx)
+	if def.Spec.Networking.IPv4Address != "" {
+		if addr := strings.TrimSpace(g.evalTemplate(def.Spec.Networking.IPv4Address, ctx)); addr != "" {
+			svc.NetworkAddresses = map[string]string{"proxy": addr}
+		}
+	}
 
 	// Dependencies
 	svc.DependsOn = g.buildDependsOn(def, ctx)
@@ -224,13 +684,26 @@ func (g *ComposeGenerator) generateService(def *registry.ServiceDefinition) Comp
 			Retries:     def.Spec.HealthCheck.Retries,
 			StartPeriod: def.Spec.HealthCheck.StartPeriod,
 		}
+		if g.Config.DesktopRuntime() {
+			svc.HealthCheck.StartPeriod = desktopStartPeriod(svc.HealthCheck.StartPeriod)
+		}
 	}
 
 	// Capabilities
 	svc.CapAdd = def.Spec.Container.Capabilities.Add
 
-	// Devices
+	// Devices, and the GPU of media servers (sdbx doctor transcode picks it)
 	svc.Devices = def.Spec.Container.Devices
+	gpu := def.Spec.Container.GPUEnabled
+	if def.Spec.Container.HardwareTranscode {
+		switch g.Config.ServiceTranscode(def.Metadata.Name) {
+		case config.TranscodeVAAPI:
+			svc.Devices = append(slices.Clone(svc.Devices), "/dev/dri:/dev/dri")
+		case config.TranscodeNVIDIA:
+			gpu = true
+			svc.Environment = append(svc.Environment, nvidiaEnvironment...)
+		}
+	}
 
 	// Shared memory size
 	svc.ShmSize = def.Spec.Container.ShmSize
@@ -239,7 +712,7 @@ func (g *ComposeGenerator) generateService(def *registry.ServiceDefinition) Comp
 	svc.Sysctls = def.Spec.Container.Sysctls
 
 	// GPU support via deploy.resources.reservations
-	if def.Spec.Container.GPUEnabled {
+	if gpu {
 		svc.Deploy = &ComposeDeploy{
 			Resources: &ComposeResources{
 				Reservations: &ComposeResourceSpec{
@@ -255,14 +728,142 @@ func (g *ComposeGenerator) generateService(def *registry.ServiceDefinition) Comp
 		}
 	}
 
-	// Secrets
+	// CPU and memory limits
+	if limits := g.Config.Services[def.Metadata.Name].Resources; limits != nil {
+		if svc.Deploy == nil {
+			svc.Deploy = &ComposeDeploy{Resources: &ComposeResources{}}
+		}
+		svc.Deploy.Resources.Limits = &ComposeResourceSpec{CPUs: limits.CPUs, Memory: limits.Memory}
+	}
+
+	// Secrets, including file-delivered references the service doesn't declare
 	for _, secret := range def.Secrets {
 		svc.Secrets = append(svc.Secrets, secret.Name)
 	}
+	for _, secret := range delivered.mounts {
+		if !slices.Contains(svc.Secrets, secret) {
+			svc.Secrets = append(svc.Secrets, secret)
+		}
+	}
+
+	// Logging driver and rotation
+	svc.Logging = g.buildLogging(def.Metadata.Name, def.Spec.Logging)
+
+	// Forced image platform (emulated images on multi-arch hosts)
+	svc.Platform = g.Config.ServicePlatform(def.Metadata.Name)
 
 	return svc
 }
 
+// templateContext returns the context the templates of a definition are rendered with
+func (g *ComposeGenerator) templateContext(def *registry.ServiceDefinition) TemplateContext {
+	return TemplateContext{
+		Config:   g.Config,
+		Secrets:  g.Secrets,
+		Name:     def.Metadata.Name,
+		Instance: registry.NewInstanceContext(def, g.Config),
+		GeoIP:    newGeoIPContext(),
+	}
+}
+
+// generateServiceSafely generates the compose service of a definition whose
+// conditions are met (ok), returning a panic on a malformed definition as
+// an error
+func (g *ComposeGenerator) generateServiceSafely(def *registry.ServiceDefinition) (svc ComposeService, ok bool, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("generation failed: %v", r)
+		}
+	}()
+	if !g.evaluateConditions(def.Conditions) {
+		return svc, false, nil
+	}
+	if err := g.checkSecretValues(def); err != nil {
+		return svc, false, err
+	}
+	return g.generateService(def), true, nil
+}
+
+// dependencySkipped is the reason of a service skipped because it needs the
+// named skipped service
+type dependencySkipped string
+
+func (d dependencySkipped) Error() string {
+	return "depends on skipped service " + string(d)
+}
+
+// skipDependents removes the skipped services from the compose file, and
+// adds the services that depend on them or share their network to skipped
+func skipDependents(compose *ComposeFile, skipped map[string]error) {
+	for changed := len(skipped) > 0; changed; {
+		changed = false
+		for _, name := range slices.Sorted(maps.Keys(compose.Services)) {
+			svc := compose.Services[name]
+			if _, ok := skipped[name]; ok {
+				delete(compose.Services, name)
+				continue
+			}
+			for dep := range skipped {
+				if _, needed := svc.DependsOn[dep]; needed || svc.NetworkMode == "service:"+dep {
+					skipped[name] = dependencySkipped(dep)
+					delete(compose.Services, name)
+					changed = true
+					break
+				}
+			}
+		}
+	}
+}
+
+// keepPreviousBlocks puts back the blocks of the previous generation of
+// skipped services, when the services they depend on or share the network
+// of are still in the compose file. It returns the services kept.
+func (g *ComposeGenerator) keepPreviousBlocks(compose *ComposeFile, skipped map[string]error) map[string]bool {
+	kept := make(map[string]bool)
+	if g.Previous == nil {
+		return kept
+	}
+	present := func(name string) bool {
+		_, ok := compose.Services[name]
+		return ok
+	}
+	for changed := true; changed; {
+		changed = false
+		for _, name := range slices.Sorted(maps.Keys(skipped)) {
+			prev, ok := g.Previous.Services[name]
+			if !ok || kept[name] {
+				continue
+			}
+			if target, shared := strings.CutPrefix(prev.NetworkMode, "service:"); shared && !present(target) {
+				continue
+			}
+			if slices.ContainsFunc(slices.Collect(maps.Keys(prev.DependsOn)), func(dep string) bool { return !present(dep) }) {
+				continue
+			}
+			compose.Services[name] = prev
+			for _, secret := range prev.Secrets {
+				def, ok := g.Previous.Secrets[secret]
+				if !ok {
+					def = ComposeSecretDef{File: fmt.Sprintf("./secrets/%s.txt", secret)}
+				}
+				compose.Secrets[secret] = def
+			}
+			for _, mount := range prev.Volumes {
+				source, _, _ := strings.Cut(mount, ":")
+				if volume, ok := g.Previous.Volumes[source]; ok {
+					if compose.Volumes == nil {
+						compose.Volumes = make(map[string]ComposeVolume)
+					}
+					compose.Volumes[source] = volume
+				}
+			}
+			kept[name] = true
+			changed = true
+		}
+	}
+	return kept
+}
+
 // resolveImage builds the full image reference
 func (g *ComposeGenerator) resolveImage(def *registry.ServiceDefinition) string {
 	img := def.Spec.Image.Repository
@@ -272,51 +873,130 @@ func (g *ComposeGenerator) resolveImage(def *registry.ServiceDefinition) string
 	return img
 }
 
-// buildEnvironment builds environment variables
-func (g *ComposeGenerator) buildEnvironment(def *registry.ServiceDefinition, ctx TemplateContext) []string {
+// secretDelivery collects the secretRef variables of a service that are not
+// written into compose.yaml
+type secretDelivery struct {
+	envFile []string // NAME=value lines for secrets/<service>.env
+	mounts  []string // Docker secrets read through a *_FILE variable
+}
+
+// buildEnvironment builds environment variables. Variables backed by a
+// secretRef are delivered according to the service's secret_delivery
+// strategy and may end up in the returned secretDelivery instead.
+func (g *ComposeGenerator) buildEnvironment(def *registry.ServiceDefinition, ctx TemplateContext) ([]string, secretDelivery) {
 	var env []string
+	var delivered secretDelivery
 
-	// Static environment variables
-	for _, e := range def.Spec.Environment.Static {
-		value := e.Value
-		if e.ValueFrom != nil && e.ValueFrom.SecretRef != "" {
-			// Secret reference
-			if secret, ok := g.Secrets[e.ValueFrom.SecretRef+".txt"]; ok {
-				value = secret
-			}
+	for _, e := range g.activeEnvironment(def, ctx) {
+		if e.ValueFrom == nil || e.ValueFrom.SecretRef == "" {
+			env = append(env, fmt.Sprintf("%s=%s", e.Name, g.evalTemplate(e.Value, ctx)))
+			continue
+		}
+
+		ref := e.ValueFrom.SecretRef
+		switch g.secretDeliveryFor(def.Metadata.Name, e.ValueFrom) {
+		case config.SecretDeliveryFile:
+			env = append(env, fmt.Sprintf("%s=/run/secrets/%s", e.ValueFrom.FileEnv, ref))
+			delivered.mounts = append(delivered.mounts, ref)
+		case config.SecretDeliveryEnvFile:
+			delivered.envFile = append(delivered.envFile, envFileLine(e.Name, g.secretValue(e, ctx)))
+		default:
+			env = append(env, fmt.Sprintf("%s=%s", e.Name, g.secretValue(e, ctx)))
 		}
-		value = g.evalTemplate(value, ctx)
-		env = append(env, fmt.Sprintf("%s=%s", e.Name, value))
 	}
+	return env, delivered
+}
 
-	// Conditional environment variables
+// activeEnvironment returns the static environment variables of a
+// definition and the conditional ones whose condition is met
+func (g *ComposeGenerator) activeEnvironment(def *registry.ServiceDefinition, ctx TemplateContext) []registry.EnvVar {
+	envs := slices.Clone(def.Spec.Environment.Static)
 	for _, e := range def.Spec.Environment.Conditional {
 		if g.evalCondition(e.When, ctx) {
-			value := e.Value
-			if e.ValueFrom != nil && e.ValueFrom.SecretRef != "" {
-				if secret, ok := g.Secrets[e.ValueFrom.SecretRef+".txt"]; ok {
-					value = secret
-				}
-			}
-			value = g.evalTemplate(value, ctx)
-			env = append(env, fmt.Sprintf("%s=%s", e.Name, value))
+			envs = append(envs, e.EnvVar)
+		}
+	}
+	return envs
+}
+
+// secretValue is the value of a secretRef variable: the secret, or the
+// variable's own value while the secret is not set
+func (g *ComposeGenerator) secretValue(e registry.EnvVar, ctx TemplateContext) string {
+	value := e.Value
+	if secret, ok := g.Secrets[e.ValueFrom.SecretRef+".txt"]; ok {
+		value = secret
+	}
+	return g.evalTemplate(value, ctx)
+}
+
+// envFileEscaper escapes a value for a double-quoted env_file line; "\$"
+// keeps compose from interpolating it
+var envFileEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "$", `\$`)
+
+// envFileLine formats a variable for an env_file, quoted so the value is
+// passed unchanged
+func envFileLine(name, value string) string {
+	return fmt.Sprintf(`%s="%s"`, name, envFileEscaper.Replace(value))
+}
+
+// checkSecretValues refuses secrets spanning several lines for variables
+// delivered as values: env_file has no multi-line syntax compose and every
+// image agree on, so only file delivery passes them unchanged
+func (g *ComposeGenerator) checkSecretValues(def *registry.ServiceDefinition) error {
+	ctx := g.templateContext(def)
+	for _, e := range g.activeEnvironment(def, ctx) {
+		if e.ValueFrom == nil || e.ValueFrom.SecretRef == "" {
+			continue
+		}
+		if g.secretDeliveryFor(def.Metadata.Name, e.ValueFrom) == config.SecretDeliveryFile {
+			continue
+		}
+		if strings.ContainsAny(g.secretValue(e, ctx), "\r\n") {
+			return fmt.Errorf("secret %s of %s spans several lines, which only file delivery supports (fileEnv in the definition and secret_delivery: file or auto)", e.ValueFrom.SecretRef, e.Name)
 		}
 	}
+	return nil
+}
 
-	return env
+// secretDeliveryFor picks how a secretRef variable reaches the container.
+// File delivery needs an image that reads the secret from a file (fileEnv);
+// otherwise auto and file fall back to env_file.
+func (g *ComposeGenerator) secretDeliveryFor(service string, src *registry.ValueSource) string {
+	strategy := g.Config.ServiceSecretDelivery(service)
+	if strategy == config.SecretDeliveryAuto || strategy == config.SecretDeliveryFile {
+		if src.FileEnv != "" {
+			return config.SecretDeliveryFile
+		}
+		return config.SecretDeliveryEnvFile
+	}
+	return strategy
 }
 
 // buildVolumes builds volume mounts
 func (g *ComposeGenerator) buildVolumes(def *registry.ServiceDefinition, ctx TemplateContext) []string {
 	var volumes []string
 	for _, v := range def.Spec.Volumes {
-		hostPath := g.evalTemplate(v.HostPath, ctx)
-		mount := fmt.Sprintf("%s:%s", hostPath, v.ContainerPath)
+		if !g.evalCondition(v.When, ctx) {
+			continue
+		}
+		source := v.Volume
+		if source == "" {
+			source = g.evalTemplate(v.HostPath, ctx)
+		}
+		mount := fmt.Sprintf("%s:%s", source, v.ContainerPath)
 		if v.ReadOnly {
 			mount += ":ro"
 		}
 		volumes = append(volumes, mount)
 	}
+
+	// GeoIP databases, kept fresh by sdbx geoip update
+	if slices.Contains(def.Spec.Requires, registry.RequireGeoIP) {
+		if !g.Config.GeoIP.IsEnabled() {
+			log.Printf("Warning: %s requires GeoIP databases but the geoip section of .sdbx.yaml is not configured", def.Metadata.Name)
+		}
+		volumes = append(volumes, fmt.Sprintf("./%s:%s:ro", geoip.Dir, geoip.ContainerDir))
+	}
 	return volumes
 }
 
@@ -337,6 +1017,42 @@ func (g *ComposeGenerator) buildPorts(def *registry.ServiceDefinition, ctx Templ
 	return ports
 }
 
+// checkPortConflicts fails when two services publish the same host port
+func checkPortConflicts(compose *ComposeFile) error {
+	owners := make(map[string]string)
+	for _, name := range slices.Sorted(maps.Keys(compose.Services)) {
+		for _, port := range compose.Services[name].Ports {
+			binding, ok := hostBinding(port)
+			if !ok {
+				continue
+			}
+			if owner, taken := owners[binding]; taken && owner != name {
+				return problem.Wrap(problem.ErrPortConflict, nil, "services %s and %s both publish host port %s (set host_ports.range to allocate free ports)", owner, name, port)
+			}
+			owners[binding] = name
+		}
+	}
+	return nil
+}
+
+// hostBinding returns the host address, port and protocol a compose port
+// mapping binds (e.g. "0.0.0.0:8080/tcp" for "8080:80"), and false for
+// mappings publishing no fixed host port
+func hostBinding(mapping string) (string, bool) {
+	spec, proto, found := strings.Cut(mapping, "/")
+	if !found {
+		proto = "tcp"
+	}
+	parts := strings.Split(spec, ":")
+	switch len(parts) {
+	case 2:
+		return fmt.Sprintf("0.0.0.0:%s/%s", parts[0], proto), parts[0] != ""
+	case 3:
+		return fmt.Sprintf("%s:%s/%s", cmp.Or(parts[0], "0.0.0.0"), parts[1], proto), parts[1] != ""
+	}
+	return "", false
+}
+
 // buildNetworking builds network configuration
 func (g *ComposeGenerator) buildNetworking(def *registry.ServiceDefinition, ctx TemplateContext) ([]string, string) {
 	var networks []string
@@ -346,14 +1062,27 @@ func (g *ComposeGenerator) buildNetworking(def *registry.ServiceDefinition, ctx
 	if def.Spec.Networking.ModeTemplate != "" {
 		networkMode = g.evalTemplate(def.Spec.Networking.ModeTemplate, ctx)
 	} else if def.Spec.Networking.Mode == "bridge" || def.Spec.Networking.Mode == "" {
-		// Default bridge mode - use networks
+		// Default bridge mode - use networks. With zones, the proxy network
+		// is only joined through the frontend zone, so backend services such
+		// as databases stay off it.
+		zoned := len(def.Spec.Networking.Zones) > 0
 		for _, n := range def.Spec.Networking.Networks {
 			if n.When == "" || g.evalCondition(n.When, ctx) {
 				name := n.Name
 				if name == "" {
 					name = "proxy"
 				}
-				networks = append(networks, name)
+				if zoned && name == "proxy" {
+					continue
+				}
+				networks = appendUnique(networks, name)
+			}
+		}
+
+		// Zones map onto dedicated networks
+		for _, zone := range def.Spec.Networking.Zones {
+			if name, ok := zoneNetworks[zone]; ok {
+				networks = appendUnique(networks, name)
 			}
 		}
 	} else {
@@ -363,6 +1092,108 @@ func (g *ComposeGenerator) buildNetworking(def *registry.ServiceDefinition, ctx
 	return networks, networkMode
 }
 
+// desktopHealthStartPeriod is the minimum health check start period on
+// desktop runtimes, where first starts are slowed down by file sharing
+const desktopHealthStartPeriod = time.Minute
+
+// desktopStartPeriod raises a health check start period to desktopHealthStartPeriod
+func desktopStartPeriod(period string) string {
+	if d, err := time.ParseDuration(period); err == nil && d >= desktopHealthStartPeriod {
+		return period
+	}
+	return desktopHealthStartPeriod.String()
+}
+
+// appendUnique appends value to list if it is not already present
+func appendUnique(list []string, value string) []string {
+	for _, existing := range list {
+		if existing == value {
+			return list
+		}
+	}
+	return append(list, value)
+}
+
+// addZoneNetworks declares the backend/downloads networks when at least one
+// service joins them, so stacks without zones keep their existing layout.
+func addZoneNetworks(compose *ComposeFile) {
+	for _, svc := range compose.Services {
+		for _, network := range svc.Networks {
+			if def, ok := zoneNetworkDefs[network]; ok {
+				compose.Networks[network] = def
+			}
+		}
+	}
+}
+
+// addNamedVolumes declares the named volumes mounted by services, including
+// inline services and compose_extra mounts of shared volumes
+func (g *ComposeGenerator) addNamedVolumes(compose *ComposeFile) {
+	for _, svc := range compose.Services {
+		for _, mount := range svc.Volumes {
+			source, _, _ := strings.Cut(mount, ":")
+			if def, ok := g.volumes[source]; ok {
+				if compose.Volumes == nil {
+					compose.Volumes = make(map[string]ComposeVolume)
+				}
+				compose.Volumes[source] = composeVolume(source, def)
+			}
+		}
+	}
+}
+
+// composeVolume renders a volume definition as local driver options. SMB
+// passwords are interpolated from .env so they stay out of compose.yaml.
+func composeVolume(name string, def config.VolumeDefinition) ComposeVolume {
+	var opts map[string]string
+	switch def.VolumeType() {
+	case config.VolumeTypeNFS:
+		opts = map[string]string{
+			"type":   "nfs",
+			"o":      strings.Join(append([]string{"addr=" + def.Server}, def.Options...), ","),
+			"device": ":" + def.Path,
+		}
+	case config.VolumeTypeCIFS:
+		o := []string{"addr=" + def.Server}
+		if def.Username != "" {
+			o = append(o, "username="+def.Username)
+		}
+		if def.Secret != "" {
+			o = append(o, fmt.Sprintf("password=${%s}", VolumePasswordEnv(name)))
+		}
+		opts = map[string]string{
+			"type":   "cifs",
+			"o":      strings.Join(append(o, def.Options...), ","),
+			"device": "//" + def.Server + "/" + strings.TrimPrefix(def.Path, "/"),
+		}
+	default:
+		if def.Path == "" {
+			return ComposeVolume{}
+		}
+		opts = map[string]string{
+			"type":   "none",
+			"o":      strings.Join(append([]string{"bind"}, def.Options...), ","),
+			"device": def.Path,
+		}
+	}
+	return ComposeVolume{Driver: "local", DriverOpts: opts}
+}
+
+// hasVolumeCredentials reports whether any volume puts a password in .env
+func hasVolumeCredentials(volumes map[string]config.VolumeDefinition) bool {
+	for _, def := range volumes {
+		if def.Secret != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// VolumePasswordEnv is the .env variable holding the password of an SMB volume
+func VolumePasswordEnv(name string) string {
+	return "SDBX_VOLUME_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_")) + "_PASSWORD"
+}
+
 // buildDependsOn builds service dependencies
 func (g *ComposeGenerator) buildDependsOn(def *registry.ServiceDefinition, ctx TemplateContext) map[string]DependsOnCondition {
 	deps := make(map[string]DependsOnCondition)
@@ -391,14 +1222,53 @@ func (g *ComposeGenerator) buildDependsOn(def *registry.ServiceDefinition, ctx T
 	return deps
 }
 
+// ownershipLabels replaces any sdbx.* labels with the current ownership labels
+func ownershipLabels(labels []string, name, definitionHash, source string) []string {
+	result := make([]string, 0, len(labels)+4)
+	for _, label := range labels {
+		if !strings.HasPrefix(label, "sdbx.") {
+			result = append(result, label)
+		}
+	}
+	result = append(result,
+		docker.LabelManaged+"=true",
+		docker.LabelService+"="+name,
+	)
+	if definitionHash != "" {
+		result = append(result, docker.LabelDefinitionHash+"="+definitionHash)
+	}
+	if source != "" {
+		result = append(result, docker.LabelSource+"="+source)
+	}
+	return result
+}
+
+// watchtowerLabels returns the Watchtower labels for an update policy.
+// Pinned services are explicitly excluded so a Watchtower running without
+// label filtering leaves them alone too.
+func watchtowerLabels(enabled bool, policy string) []string {
+	switch {
+	case policy == config.UpdatePolicyPinned:
+		return []string{"com.centurylinklabs.watchtower.enable=false"}
+	case !enabled:
+		return nil
+	case policy == config.UpdatePolicyNotifyOnly:
+		return []string{
+			"com.centurylinklabs.watchtower.enable=true",
+			"com.centurylinklabs.watchtower.monitor-only=true",
+		}
+	default:
+		return []string{"com.centurylinklabs.watchtower.enable=true"}
+	}
+}
+
 // buildLabels builds Docker labels including Traefik configuration
 func (g *ComposeGenerator) buildLabels(def *registry.ServiceDefinition, ctx TemplateContext) []string {
 	var labels []string
 
-	// Watchtower label
-	if def.Integrations.Watchtower != nil && def.Integrations.Watchtower.Enabled {
-		labels = append(labels, "com.centurylinklabs.watchtower.enable=true")
-	}
+	// Watchtower labels follow the per-service update policy
+	watchtower := def.Integrations.Watchtower != nil && def.Integrations.Watchtower.Enabled
+	labels = append(labels, watchtowerLabels(watchtower, g.Config.UpdatePolicy(def.Metadata.Name))...)
 
 	// Traefik labels for routed services
 	if def.Routing.Enabled {
@@ -416,18 +1286,7 @@ func (g *ComposeGenerator) buildTraefikLabels(def *registry.ServiceDefinition, _
 	labels = append(labels, "traefik.enable=true")
 
 	// Router rule
-	var rule string
-	if def.Routing.ForceSubdomain || g.Config.Routing.Strategy == config.RoutingStrategySubdomain {
-		// Subdomain routing
-		subdomain := def.Routing.Subdomain
-		rule = fmt.Sprintf("Host(`%s.%s`)", subdomain, g.Config.Domain)
-	} else {
-		// Path routing
-		path := def.Routing.Path
-		baseDomain := g.Config.Routing.BaseDomain
-		rule = fmt.Sprintf("Host(`%s.%s`) && PathPrefix(`%s`)", baseDomain, g.Config.Domain, path)
-	}
-	labels = append(labels, fmt.Sprintf("traefik.http.routers.%s.rule=%s", name, rule))
+	labels = append(labels, fmt.Sprintf("traefik.http.routers.%s.rule=%s", name, routerRule(g.Config, def)))
 
 	// Entrypoint
 	var entrypoint string
@@ -454,15 +1313,47 @@ func (g *ComposeGenerator) buildTraefikLabels(def *registry.ServiceDefinition, _
 		}
 	}
 
+	// IP and country allowlist middlewares (checked before auth so blocked clients never reach the login)
+	middlewares = append(middlewares, accessMiddlewares(g.Config, name)...)
+
 	// Auth middleware
-	if def.Routing.Auth.Required && !def.Routing.Auth.Bypass {
-		middlewares = append(middlewares, "authelia@file")
+	var tokenMiddlewares []string
+	authRequired := def.Routing.Auth.Required && !def.Routing.Auth.Bypass
+	if authRequired {
+		tokenMiddlewares = slices.Clone(middlewares)
+		middlewares = append(middlewares, authMiddleware(g.Config)+"@file")
+	}
+
+	// Middlewares referenced by the definition; bare names live in the dynamic config
+	for _, ref := range def.Routing.Traefik.Middlewares {
+		if !strings.Contains(ref, "@") {
+			ref += "@file"
+		}
+		middlewares = append(middlewares, ref)
+		tokenMiddlewares = append(tokenMiddlewares, ref)
 	}
 
 	if len(middlewares) > 0 {
 		labels = append(labels, fmt.Sprintf("traefik.http.routers.%s.middlewares=%s", name, strings.Join(middlewares, ",")))
 	}
 
+	// Requests carrying an API token get their own router without the auth
+	// middleware. Its longer rule takes precedence over the main router.
+	if authRequired && def.Routing.Auth.APITokens {
+		api := name + "-api"
+		labels = append(labels,
+			fmt.Sprintf("traefik.http.routers.%s.rule=%s && HeaderRegexp(`Authorization`, `^Bearer %s`)", api, routerRule(g.Config, def), auth.TokenPrefix),
+			fmt.Sprintf("traefik.http.routers.%s.entrypoints=%s", api, entrypoint),
+			fmt.Sprintf("traefik.http.routers.%s.service=%s", api, name),
+		)
+		if g.Config.Expose.Mode == config.ExposeModeDirect {
+			labels = append(labels, fmt.Sprintf("traefik.http.routers.%s.tls=true", api))
+		}
+		if len(tokenMiddlewares) > 0 {
+			labels = append(labels, fmt.Sprintf("traefik.http.routers.%s.middlewares=%s", api, strings.Join(tokenMiddlewares, ",")))
+		}
+	}
+
 	// Service port
 	labels = append(labels, fmt.Sprintf("traefik.http.services.%s.loadbalancer.server.port=%d", name, def.Routing.Port))
 
@@ -479,6 +1370,56 @@ func (g *ComposeGenerator) buildTraefikLabels(def *registry.ServiceDefinition, _
 	return labels
 }
 
+// reuseUnchangedServices replaces freshly generated blocks with the previous
+// ones for unchanged services. A host service is only reused when every
+// service sharing its network is unchanged too, since it carries their
+// transferred labels.
+func (g *ComposeGenerator) reuseUnchangedServices(compose *ComposeFile) {
+	if g.Previous == nil || len(g.Unchanged) == 0 {
+		return
+	}
+
+	for name := range compose.Services {
+		if !g.Unchanged[name] {
+			continue
+		}
+		prev, ok := g.Previous.Services[name]
+		if !ok {
+			continue
+		}
+
+		reusable := true
+		for otherName, other := range compose.Services {
+			if other.NetworkMode == "service:"+name && !g.Unchanged[otherName] {
+				reusable = false
+				break
+			}
+		}
+		if reusable {
+			compose.Services[name] = prev
+		}
+	}
+}
+
+// routerRule returns the Traefik router rule for a routed service
+func routerRule(cfg *config.Config, def *registry.ServiceDefinition) string {
+	if def.Routing.ForceSubdomain || cfg.Routing.Strategy == config.RoutingStrategySubdomain {
+		// Subdomain routing
+		return hostRule(cfg, def.Routing.Subdomain)
+	}
+	// Path routing
+	return fmt.Sprintf("%s && PathPrefix(`%s`)", hostRule(cfg, cfg.Routing.BaseDomain), def.Routing.Path)
+}
+
+// hostRule matches a subdomain of the domain, and its .local name when
+// services are advertised over mDNS
+func hostRule(cfg *config.Config, subdomain string) string {
+	if cfg.MDNS.IsEnabled() {
+		return fmt.Sprintf("Host(`%s.%s`, `%s`)", subdomain, cfg.Domain, config.MDNSHost(subdomain))
+	}
+	return fmt.Sprintf("Host(`%s.%s`)", subdomain, cfg.Domain)
+}
+
 // transferLabelsForNetworkSharing handles routing pass-through for services
 // using network_mode: service:X pattern. Transfers Traefik labels from the
 // network-sharing service to the host service.
@@ -537,9 +1478,16 @@ func (g *ComposeGenerator) evalTemplate(tmpl string, ctx TemplateContext) string
 		return tmpl
 	}
 
-	t, err := template.New("").Funcs(g.funcMap).Parse(tmpl)
-	if err != nil {
-		log.Printf("Warning: template parse failed for %q: %v", tmpl, err)
+	t, cached := g.templates[tmpl]
+	if !cached {
+		var err error
+		if t, err = template.New("").Funcs(g.funcMap).Parse(tmpl); err != nil {
+			log.Printf("Warning: template parse failed for %q: %v", tmpl, err)
+			t = nil
+		}
+		g.templates[tmpl] = t
+	}
+	if t == nil {
 		return tmpl
 	}
 
@@ -552,16 +1500,71 @@ func (g *ComposeGenerator) evalTemplate(tmpl string, ctx TemplateContext) string
 	return buf.String()
 }
 
-// evalCondition evaluates a condition template and returns boolean
+// evalCondition evaluates a condition template or expression and returns boolean
 func (g *ComposeGenerator) evalCondition(condition string, ctx TemplateContext) bool {
 	if condition == "" {
 		return true
 	}
+	if !registry.IsTemplateCondition(condition) {
+		return registry.EvaluateExpression(condition, g.Config)
+	}
 
 	result := g.evalTemplate(condition, ctx)
 	return result == "true"
 }
 
+// mergeComposeExtra merges user-provided Compose properties into a generated
+// service: maps merge recursively, lists are appended and scalars replaced
+func mergeComposeExtra(svc ComposeService, extra map[string]interface{}) (ComposeService, error) {
+	data, err := yaml.Marshal(svc)
+	if err != nil {
+		return svc, err
+	}
+	var base map[string]interface{}
+	if err := yaml.Unmarshal(data, &base); err != nil {
+		return svc, err
+	}
+
+	mergeYAMLMaps(base, extra)
+
+	if data, err = yaml.Marshal(base); err != nil {
+		return svc, err
+	}
+	var merged ComposeService
+	if err := yaml.Unmarshal(data, &merged); err != nil {
+		return svc, fmt.Errorf("incompatible with generated service: %w", err)
+	}
+	return merged, nil
+}
+
+// mergeYAMLMaps deep-merges src into dst
+func mergeYAMLMaps(dst, src map[string]interface{}) {
+	for key, value := range src {
+		switch v := value.(type) {
+		case map[string]interface{}:
+			if existing, ok := dst[key].(map[string]interface{}); ok {
+				mergeYAMLMaps(existing, v)
+				continue
+			}
+		case []interface{}:
+			if existing, ok := dst[key].([]interface{}); ok {
+				dst[key] = append(existing, v...)
+				continue
+			}
+		}
+		dst[key] = value
+	}
+}
+
+// ParseComposeFile parses a previously generated compose file
+func ParseComposeFile(data []byte) (*ComposeFile, error) {
+	var compose ComposeFile
+	if err := yaml.Unmarshal(data, &compose); err != nil {
+		return nil, fmt.Errorf("failed to parse compose file: %w", err)
+	}
+	return &compose, nil
+}
+
 // ToYAML converts the compose file to YAML
 func (c *ComposeFile) ToYAML() ([]byte, error) {
 	var buf bytes.Buffer
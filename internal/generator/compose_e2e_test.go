@@ -0,0 +1,35 @@
+//go:build e2e
+
+package generator
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/maiko/sdbx/internal/e2e"
+)
+
+// TestE2EComposeConfig checks every golden fixture's compose.yaml with the
+// Compose CLI, which validates it against the Compose specification
+func TestE2EComposeConfig(t *testing.T) {
+	e2e.RequireDocker(t)
+	entries, err := os.ReadDir(goldenDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		t.Run(entry.Name(), func(t *testing.T) {
+			out := generateFixture(t, filepath.Join(goldenDir, entry.Name()))
+			cmd := exec.Command("docker", "compose", "-f", "compose.yaml", "config", "--quiet")
+			cmd.Dir = out
+			if output, err := cmd.CombinedOutput(); err != nil {
+				t.Errorf("docker compose config: %v\n%s", err, output)
+			}
+		})
+	}
+}
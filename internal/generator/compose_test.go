@@ -1,10 +1,16 @@
 package generator
 
 import (
+	"errors"
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
 	"strings"
 	"testing"
 
 	"github.com/maiko/sdbx/internal/config"
+	"github.com/maiko/sdbx/internal/problem"
 	"github.com/maiko/sdbx/internal/registry"
 )
 
@@ -371,6 +377,129 @@ func TestMultipleServicesSharing(t *testing.T) {
 }
 
 // TestGenerateServiceExtraProperties verifies ShmSize, Sysctls, and GPU deploy
+func TestGenerateServiceInstanceTemplates(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Domain = "example.com"
+	cfg.AddInstance("sonarr4k", "sonarr")
+	cfg.Services = map[string]config.ServiceOverride{
+		"sonarr4k": {Variables: map[string]string{"quality": "2160p"}},
+	}
+
+	gen := NewComposeGenerator(cfg, nil, nil)
+
+	def := &registry.ServiceDefinition{
+		Metadata: registry.ServiceMetadata{Name: "sonarr4k"},
+		Spec: registry.ServiceSpec{
+			Image:     registry.ImageSpec{Repository: "linuxserver/sonarr", Tag: "latest"},
+			Container: registry.ContainerSpec{NameTemplate: "sdbx-{{ .Instance.Name }}"},
+			Environment: registry.EnvironmentSpec{Static: []registry.EnvVar{
+				{Name: "INSTANCE_OF", Value: "{{ .Instance.Service }}"},
+				{Name: "QUALITY", Value: "{{ .Instance.Vars.quality }}"},
+			}},
+		},
+		Instancing: &registry.Instancing{
+			Allowed:   true,
+			Variables: map[string]string{"quality": "1080p"},
+		},
+	}
+
+	svc := gen.generateService(def)
+
+	if svc.ContainerName != "sdbx-sonarr4k" {
+		t.Errorf("ContainerName = %q, want sdbx-sonarr4k", svc.ContainerName)
+	}
+	for _, want := range []string{"INSTANCE_OF=sonarr", "QUALITY=2160p"} {
+		if !slices.Contains(svc.Environment, want) {
+			t.Errorf("Environment %v should contain %s", svc.Environment, want)
+		}
+	}
+}
+
+func TestExternalDependencyTemplates(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.ExternalDependencies = map[string]config.ExternalEndpoint{"db": {Host: "db.lan"}}
+
+	def := &registry.ServiceDefinition{
+		Metadata: registry.ServiceMetadata{Name: "app"},
+		Spec: registry.ServiceSpec{
+			Image:     registry.ImageSpec{Repository: "example/app", Tag: "latest"},
+			Container: registry.ContainerSpec{NameTemplate: "sdbx-app"},
+			Environment: registry.EnvironmentSpec{Static: []registry.EnvVar{
+				{Name: "DB_ADDR", Value: `{{ external "db" }}`},
+				{Name: "DB_HOST", Value: `{{ externalHost "db" }}`},
+				{Name: "DB_PORT", Value: `{{ externalPort "db" }}`},
+			}},
+			ExternalDependencies: []registry.ExternalDependency{
+				{Name: "db", Kind: registry.ExternalTCP, Port: 5432},
+			},
+		},
+	}
+
+	compose, err := NewComposeGenerator(cfg, nil, nil).Generate(makeTestGraph(makeResolvedService("app", def)))
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	for _, want := range []string{"DB_ADDR=db.lan:5432", "DB_HOST=db.lan", "DB_PORT=5432"} {
+		if !slices.Contains(compose.Services["app"].Environment, want) {
+			t.Errorf("Environment %v should contain %s", compose.Services["app"].Environment, want)
+		}
+	}
+
+	// A required dependency without a configured host fails generation
+	cfg.ExternalDependencies = nil
+	if _, err := NewComposeGenerator(cfg, nil, nil).Generate(makeTestGraph(makeResolvedService("app", def))); err == nil {
+		t.Error("expected an error for an unconfigured external dependency")
+	}
+}
+
+func TestGenerateNamedVolumes(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Volumes = map[string]config.VolumeDefinition{
+		"nas-media": {Type: config.VolumeTypeCIFS, Server: "nas.lan", Path: "/media", Username: "sdbx", Secret: "nas_password", Options: []string{"vers=3.0"}},
+		"unused":    {},
+	}
+	cfg.ExtraServices = []config.ExtraServiceConfig{{Name: "tool", Image: "example/tool", Volumes: []string{"nas-media:/data:ro"}}}
+
+	def := &registry.ServiceDefinition{
+		Metadata: registry.ServiceMetadata{Name: "app"},
+		Spec: registry.ServiceSpec{
+			Image:     registry.ImageSpec{Repository: "example/app", Tag: "latest"},
+			Container: registry.ContainerSpec{NameTemplate: "sdbx-app"},
+			Volumes: []registry.VolumeMount{
+				{HostPath: "./configs/app", ContainerPath: "/config"},
+				{Volume: "downloads", ContainerPath: "/downloads"},
+			},
+			VolumeDefinitions: map[string]config.VolumeDefinition{
+				"downloads": {Type: config.VolumeTypeNFS, Server: "nas.lan", Path: "/volume1/downloads", Options: []string{"nfsvers=4"}},
+			},
+		},
+	}
+	graph := makeTestGraph(makeResolvedService("app", def), makeResolvedService("tool", registry.ExtraServiceDefinition(cfg.ExtraServices[0])))
+
+	compose, err := NewComposeGenerator(cfg, nil, nil).Generate(graph)
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	if !slices.Contains(compose.Services["app"].Volumes, "downloads:/downloads") {
+		t.Errorf("app volumes %v should mount the downloads volume", compose.Services["app"].Volumes)
+	}
+	if !slices.Contains(compose.Services["tool"].Volumes, "nas-media:/data:ro") {
+		t.Errorf("tool volumes %v should mount the nas-media volume", compose.Services["tool"].Volumes)
+	}
+
+	want := map[string]ComposeVolume{
+		"downloads": {Driver: "local", DriverOpts: map[string]string{
+			"type": "nfs", "o": "addr=nas.lan,nfsvers=4", "device": ":/volume1/downloads",
+		}},
+		"nas-media": {Driver: "local", DriverOpts: map[string]string{
+			"type": "cifs", "o": "addr=nas.lan,username=sdbx,password=${SDBX_VOLUME_NAS_MEDIA_PASSWORD},vers=3.0", "device": "//nas.lan/media",
+		}},
+	}
+	if !reflect.DeepEqual(compose.Volumes, want) {
+		t.Errorf("Volumes = %+v, want %+v", compose.Volumes, want)
+	}
+}
+
 func TestGenerateServiceExtraProperties(t *testing.T) {
 	cfg := &config.Config{
 		Domain: "example.com",
@@ -595,6 +724,87 @@ func TestCustomLabelsEmpty(t *testing.T) {
 	}
 }
 
+// TestReferencedMiddlewares verifies definition middlewares follow the built-in ones
+func TestReferencedMiddlewares(t *testing.T) {
+	cfg := config.DefaultConfig()
+	gen := NewComposeGenerator(cfg, nil, nil)
+
+	def := &registry.ServiceDefinition{
+		Metadata: registry.ServiceMetadata{Name: "sonarr"},
+		Routing: registry.RoutingConfig{
+			Enabled:   true,
+			Port:      8989,
+			Subdomain: "sonarr",
+			Auth:      registry.AuthConfig{Required: true},
+			Traefik:   registry.TraefikConfig{Middlewares: []string{"gzip", "crowdsec@docker"}},
+		},
+	}
+
+	labels := gen.buildTraefikLabels(def, TemplateContext{Config: cfg})
+	want := "traefik.http.routers.sonarr.middlewares=authelia@file,gzip@file,crowdsec@docker"
+	if !slices.Contains(labels, want) {
+		t.Errorf("expected %q in %v", want, labels)
+	}
+
+	// Basic auth mode swaps the auth middleware
+	cfg.Auth.Mode = config.AuthModeBasic
+	labels = gen.buildTraefikLabels(def, TemplateContext{Config: cfg})
+	want = "traefik.http.routers.sonarr.middlewares=basic-auth@file,gzip@file,crowdsec@docker"
+	if !slices.Contains(labels, want) {
+		t.Errorf("expected %q in %v", want, labels)
+	}
+
+	// The IP and country allowlists run before auth
+	cfg.Traefik.IPAllowList = []string{"192.168.0.0/16"}
+	cfg.Services["sonarr"] = config.ServiceOverride{CountryAllowList: []string{"FR"}}
+	labels = gen.buildTraefikLabels(def, TemplateContext{Config: cfg})
+	want = "traefik.http.routers.sonarr.middlewares=ip-allowlist@file,countries-sonarr@file,basic-auth@file,gzip@file,crowdsec@docker"
+	if !slices.Contains(labels, want) {
+		t.Errorf("expected %q in %v", want, labels)
+	}
+}
+
+// TestAPITokenRouter verifies services accepting API tokens get a router
+// without the auth middleware for bearer requests
+func TestAPITokenRouter(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Domain = "example.com"
+	cfg.Expose.Mode = config.ExposeModeDirect
+	gen := NewComposeGenerator(cfg, nil, nil)
+
+	def := &registry.ServiceDefinition{
+		Metadata: registry.ServiceMetadata{Name: "sdbx-webui"},
+		Routing: registry.RoutingConfig{
+			Enabled:   true,
+			Port:      3000,
+			Subdomain: "sdbx",
+			Auth:      registry.AuthConfig{Required: true, APITokens: true},
+			Traefik:   registry.TraefikConfig{Middlewares: []string{"gzip"}},
+		},
+	}
+
+	labels := gen.buildTraefikLabels(def, TemplateContext{Config: cfg})
+	for _, want := range []string{
+		"traefik.http.routers.sdbx-webui.middlewares=authelia@file,gzip@file",
+		"traefik.http.routers.sdbx-webui-api.rule=Host(`sdbx.example.com`) && HeaderRegexp(`Authorization`, `^Bearer sdbx_`)",
+		"traefik.http.routers.sdbx-webui-api.service=sdbx-webui",
+		"traefik.http.routers.sdbx-webui-api.tls=true",
+		"traefik.http.routers.sdbx-webui-api.middlewares=gzip@file",
+	} {
+		if !slices.Contains(labels, want) {
+			t.Errorf("expected %q in %v", want, labels)
+		}
+	}
+
+	// Without auth there is nothing to route around
+	def.Routing.Auth.Bypass = true
+	for _, label := range gen.buildTraefikLabels(def, TemplateContext{Config: cfg}) {
+		if strings.Contains(label, "sdbx-webui-api") {
+			t.Errorf("unexpected API router label %q", label)
+		}
+	}
+}
+
 // TestEvalTemplateWarnings verifies evalTemplate returns fallback on bad templates
 func TestEvalTemplateWarnings(t *testing.T) {
 	cfg := &config.Config{}
@@ -619,3 +829,917 @@ func TestEvalTemplateWarnings(t *testing.T) {
 		t.Errorf("expected 'hello-world', got %q", result)
 	}
 }
+
+// TestNetworkZones verifies zones map onto dedicated networks that are only
+// declared when in use
+func TestNetworkZones(t *testing.T) {
+	cfg := &config.Config{
+		Domain: "example.com",
+		Routing: config.RoutingConfig{
+			Strategy:   config.RoutingStrategySubdomain,
+			BaseDomain: "sdbx",
+		},
+		Expose: config.ExposeConfig{
+			Mode: config.ExposeModeCloudflared,
+		},
+	}
+
+	gen := NewComposeGenerator(cfg, nil, nil)
+
+	def := &registry.ServiceDefinition{
+		Metadata: registry.ServiceMetadata{
+			Name: "sonarr",
+		},
+		Spec: registry.ServiceSpec{
+			Image: registry.ImageSpec{
+				Repository: "linuxserver/sonarr",
+				Tag:        "latest",
+			},
+			Container: registry.ContainerSpec{
+				NameTemplate: "sdbx-sonarr",
+			},
+			Networking: registry.NetworkSpec{
+				Networks: []registry.NetworkRef{{Name: "proxy"}},
+				Zones:    []string{registry.ZoneFrontend, registry.ZoneBackend, registry.ZoneDownloads},
+			},
+		},
+	}
+
+	svc := gen.generateService(def)
+
+	want := []string{"proxy", "backend", "downloads"}
+	if strings.Join(svc.Networks, ",") != strings.Join(want, ",") {
+		t.Errorf("Networks = %v, want %v", svc.Networks, want)
+	}
+
+	compose := &ComposeFile{
+		Services: map[string]ComposeService{"sonarr": svc},
+		Networks: map[string]ComposeNetwork{
+			"proxy": {Name: "sdbx_proxy"},
+		},
+	}
+	addZoneNetworks(compose)
+
+	backend, ok := compose.Networks["backend"]
+	if !ok {
+		t.Fatal("backend network should be declared")
+	}
+	if !backend.Internal {
+		t.Error("backend network should be internal")
+	}
+	if _, ok := compose.Networks["downloads"]; !ok {
+		t.Error("downloads network should be declared")
+	}
+
+	// A database in the backend zone leaves the proxy network it lists
+	def.Metadata.Name = "postgres"
+	def.Spec.Networking.Zones = []string{registry.ZoneBackend}
+	if db := gen.generateService(def); !slices.Equal(db.Networks, []string{"backend"}) {
+		t.Errorf("Networks = %v, want only backend", db.Networks)
+	}
+	def.Spec.Networking.Networks = append(def.Spec.Networking.Networks, registry.NetworkRef{Name: "monitoring"})
+	if db := gen.generateService(def); !slices.Equal(db.Networks, []string{"monitoring", "backend"}) {
+		t.Errorf("Networks = %v, want the named network kept with backend", db.Networks)
+	}
+
+	// Without zones, no extra networks are declared
+	flat := &ComposeFile{
+		Services: map[string]ComposeService{
+			"plex": {Networks: []string{"proxy"}},
+		},
+		Networks: map[string]ComposeNetwork{
+			"proxy": {Name: "sdbx_proxy"},
+		},
+	}
+	addZoneNetworks(flat)
+	if len(flat.Networks) != 1 {
+		t.Errorf("expected only proxy network, got %v", flat.Networks)
+	}
+}
+
+// TestReuseUnchangedServices verifies that unchanged services keep their
+// previous compose block, unless a changed service shares their network
+func TestReuseUnchangedServices(t *testing.T) {
+	cfg := config.DefaultConfig()
+	gen := NewComposeGenerator(cfg, nil, nil)
+
+	gen.Previous = &ComposeFile{
+		Services: map[string]ComposeService{
+			"sonarr":  {Image: "sonarr:old", Labels: []string{"b=2", "a=1"}},
+			"radarr":  {Image: "radarr:old"},
+			"gluetun": {Image: "gluetun:old"},
+		},
+	}
+	gen.Unchanged = map[string]bool{"sonarr": true, "gluetun": true}
+
+	compose := &ComposeFile{
+		Services: map[string]ComposeService{
+			"sonarr":      {Image: "sonarr:new", Labels: []string{"a=1", "b=2"}},
+			"radarr":      {Image: "radarr:new"},
+			"gluetun":     {Image: "gluetun:new"},
+			"qbittorrent": {Image: "qbittorrent:new", NetworkMode: "service:gluetun"},
+		},
+	}
+
+	gen.reuseUnchangedServices(compose)
+
+	if compose.Services["sonarr"].Image != "sonarr:old" {
+		t.Error("unchanged sonarr should keep its previous block")
+	}
+	if compose.Services["radarr"].Image != "radarr:new" {
+		t.Error("changed radarr should be regenerated")
+	}
+	if compose.Services["gluetun"].Image != "gluetun:new" {
+		t.Error("gluetun hosts a changed service and must be regenerated")
+	}
+}
+
+func TestMergeComposeExtra(t *testing.T) {
+	svc := ComposeService{
+		Image:   "sonarr:latest",
+		Labels:  []string{"traefik.enable=true"},
+		Sysctls: map[string]string{"net.ipv4.ip_forward": "1"},
+	}
+	extra := map[string]interface{}{
+		"ulimits":     map[string]interface{}{"nofile": 65536},
+		"extra_hosts": []interface{}{"host.docker.internal:host-gateway"},
+		"labels":      []interface{}{"custom=1"},
+		"sysctls":     map[string]interface{}{"net.core.somaxconn": 1024},
+		"image":       "sonarr:develop",
+	}
+
+	merged, err := mergeComposeExtra(svc, extra)
+	if err != nil {
+		t.Fatalf("mergeComposeExtra() error = %v", err)
+	}
+
+	if merged.Image != "sonarr:develop" {
+		t.Errorf("Image = %q, want scalar replaced", merged.Image)
+	}
+	if len(merged.Labels) != 2 {
+		t.Errorf("Labels = %v, want generated and extra labels", merged.Labels)
+	}
+	if merged.Sysctls["net.ipv4.ip_forward"] != "1" || merged.Sysctls["net.core.somaxconn"] != "1024" {
+		t.Errorf("Sysctls = %v, want deep merge", merged.Sysctls)
+	}
+	if _, ok := merged.Extra["ulimits"]; !ok {
+		t.Error("unmodeled ulimits should be kept in Extra")
+	}
+
+	out, err := (&ComposeFile{Services: map[string]ComposeService{"sonarr": merged}}).ToYAML()
+	if err != nil {
+		t.Fatalf("ToYAML() error = %v", err)
+	}
+	if !strings.Contains(string(out), "extra_hosts:") || !strings.Contains(string(out), "nofile: 65536") {
+		t.Errorf("passthrough fields missing from output:\n%s", out)
+	}
+
+	if _, err := mergeComposeExtra(svc, map[string]interface{}{"command": []interface{}{"a", "b"}}); err == nil {
+		t.Error("expected error for a value incompatible with the modeled field")
+	}
+}
+
+func TestBuildLogging(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Services = map[string]config.ServiceOverride{
+		"plex": {Logging: &config.LoggingConfig{MaxSize: "50m"}},
+		"sonarr": {Logging: &config.LoggingConfig{
+			Driver:  config.LogDriverLoki,
+			Options: map[string]string{"loki-url": "http://loki:3100/loki/api/v1/push"},
+		}},
+	}
+	gen := NewComposeGenerator(cfg, nil, nil)
+
+	logging := gen.buildLogging("radarr", nil)
+	if logging.Driver != config.LogDriverJSONFile || logging.Options["max-size"] != "10m" || logging.Options["max-file"] != "3" {
+		t.Errorf("global logging = %+v", logging)
+	}
+
+	logging = gen.buildLogging("qbittorrent", &registry.LoggingSpec{Options: map[string]string{"max-size": "5m"}})
+	if logging.Options["max-size"] != "5m" {
+		t.Errorf("definition options should override global rotation, got %+v", logging)
+	}
+
+	if logging = gen.buildLogging("plex", nil); logging.Options["max-size"] != "50m" || logging.Options["max-file"] != "3" {
+		t.Errorf("override max_size should keep global max_file, got %+v", logging)
+	}
+
+	logging = gen.buildLogging("sonarr", nil)
+	if logging.Driver != config.LogDriverLoki || logging.Options["max-size"] != "" || logging.Options["loki-url"] == "" {
+		t.Errorf("loki override = %+v", logging)
+	}
+
+	cfg.Logging.Driver = ""
+	if logging = gen.buildLogging("radarr", nil); logging != nil {
+		t.Errorf("empty driver should keep Docker defaults, got %+v", logging)
+	}
+}
+
+func TestLogShippingService(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Logging.Aggregation = &config.LogAggregationConfig{Agent: config.LogAgentPromtail}
+
+	compose, err := NewComposeGenerator(cfg, nil, nil).Generate(makeTestGraph())
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	svc, ok := compose.Services["promtail"]
+	if !ok {
+		t.Fatalf("promtail service missing: %v", compose.Services)
+	}
+	if svc.ContainerName != "sdbx-promtail" || !slices.Contains(svc.Volumes, "/var/run/docker.sock:/var/run/docker.sock:ro") ||
+		!slices.Contains(svc.Volumes, "./configs/promtail/config.yml:/etc/promtail/config.yml:ro") {
+		t.Errorf("unexpected promtail service: %+v", svc)
+	}
+	if !slices.Contains(svc.Labels, "sdbx.service=promtail") {
+		t.Errorf("promtail should carry ownership labels: %v", svc.Labels)
+	}
+
+	cfg.Logging.Aggregation = nil
+	compose, err = NewComposeGenerator(cfg, nil, nil).Generate(makeTestGraph())
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	if _, ok := compose.Services["promtail"]; ok {
+		t.Error("no log shipping agent expected without logging.aggregation")
+	}
+}
+
+func TestRcloneService(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.MediaPath = "/mnt/merged/media"
+	cfg.Storage.Rclone = &config.RcloneConfig{Remote: "gdrive:media", Mount: "/mnt/remote"}
+	cfg.Storage.Mergerfs = &config.MergerfsConfig{Mount: "/mnt/merged", Branches: []string{"/mnt/local", "/mnt/remote"}}
+
+	media := &registry.ServiceDefinition{
+		Metadata: registry.ServiceMetadata{Name: "plex"},
+		Spec: registry.ServiceSpec{
+			Image:     registry.ImageSpec{Repository: "example/plex", Tag: "latest"},
+			Container: registry.ContainerSpec{NameTemplate: "sdbx-plex"},
+			Volumes:   []registry.VolumeMount{{HostPath: "{{ .Config.MediaPath }}", ContainerPath: "/media"}},
+		},
+	}
+	other := &registry.ServiceDefinition{
+		Metadata: registry.ServiceMetadata{Name: "app"},
+		Spec: registry.ServiceSpec{
+			Image:     registry.ImageSpec{Repository: "example/app", Tag: "latest"},
+			Container: registry.ContainerSpec{NameTemplate: "sdbx-app"},
+			Volumes:   []registry.VolumeMount{{HostPath: "/mnt/merged-backup", ContainerPath: "/backup"}},
+		},
+	}
+	graph := makeTestGraph(makeResolvedService("plex", media), makeResolvedService("app", other))
+
+	compose, err := NewComposeGenerator(cfg, nil, nil).Generate(graph)
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	svc, ok := compose.Services["rclone"]
+	if !ok {
+		t.Fatalf("rclone service missing: %v", compose.Services)
+	}
+	if !strings.HasPrefix(svc.Command, "mount gdrive:media /data --config=/config/rclone/rclone.conf") ||
+		!strings.Contains(svc.Command, "--uid=1000") || !strings.Contains(svc.Command, "--allow-other") {
+		t.Errorf("unexpected rclone command: %s", svc.Command)
+	}
+	if !slices.Contains(svc.Volumes, "/mnt/remote:/data:rshared") || !slices.Contains(svc.Devices, "/dev/fuse:/dev/fuse") || svc.HealthCheck == nil {
+		t.Errorf("unexpected rclone service: %+v", svc)
+	}
+
+	if got := compose.Services["plex"].DependsOn["rclone"].Condition; got != "service_healthy" {
+		t.Errorf("plex mounts the pool and should wait for rclone, got %q", got)
+	}
+	if _, ok := compose.Services["app"].DependsOn["rclone"]; ok {
+		t.Error("app does not mount the storage and should not wait for rclone")
+	}
+
+	// In systemd mode rclone runs on the host
+	cfg.Storage.Rclone.Mode = config.RcloneModeSystemd
+	compose, err = NewComposeGenerator(cfg, nil, nil).Generate(graph)
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	if _, ok := compose.Services["rclone"]; ok {
+		t.Error("no rclone container expected in systemd mode")
+	}
+}
+
+func TestWatchtowerLabels(t *testing.T) {
+	tests := []struct {
+		enabled bool
+		policy  string
+		want    []string
+	}{
+		{true, config.UpdatePolicyAuto, []string{"com.centurylinklabs.watchtower.enable=true"}},
+		{false, config.UpdatePolicyAuto, nil},
+		{true, config.UpdatePolicyNotifyOnly, []string{
+			"com.centurylinklabs.watchtower.enable=true",
+			"com.centurylinklabs.watchtower.monitor-only=true",
+		}},
+		{true, config.UpdatePolicyPinned, []string{"com.centurylinklabs.watchtower.enable=false"}},
+		{false, config.UpdatePolicyPinned, []string{"com.centurylinklabs.watchtower.enable=false"}},
+	}
+
+	for _, tt := range tests {
+		got := watchtowerLabels(tt.enabled, tt.policy)
+		if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("watchtowerLabels(%v, %q) = %v, want %v", tt.enabled, tt.policy, got, tt.want)
+		}
+	}
+}
+
+func TestOwnershipLabels(t *testing.T) {
+	existing := []string{
+		"traefik.enable=true",
+		"sdbx.service=stale",
+		"sdbx.definition-hash=old",
+	}
+
+	got := ownershipLabels(existing, "sonarr", "abc123", "embedded")
+	want := []string{
+		"traefik.enable=true",
+		"sdbx.managed=true",
+		"sdbx.service=sonarr",
+		"sdbx.definition-hash=abc123",
+		"sdbx.source=embedded",
+	}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("ownershipLabels() = %v, want %v", got, want)
+	}
+
+	got = ownershipLabels(nil, "static", "", "sdbx")
+	want = []string{"sdbx.managed=true", "sdbx.service=static", "sdbx.source=sdbx"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("ownershipLabels() without hash = %v, want %v", got, want)
+	}
+}
+
+func TestCheckPortConflicts(t *testing.T) {
+	compose := &ComposeFile{Services: map[string]ComposeService{
+		"traefik":  {Ports: []string{"80:80", "443:443"}},
+		"plex":     {Ports: []string{"32400:32400", "1900:1900/udp"}},
+		"jellyfin": {Ports: []string{"1900:1900", "127.0.0.1:8096:8096", "7359"}},
+		"emby":     {Ports: []string{"192.168.1.10:8096:8096"}},
+	}}
+	if err := checkPortConflicts(compose); err != nil {
+		t.Fatalf("checkPortConflicts() error = %v, want none (different protocols and addresses)", err)
+	}
+
+	compose.Services["nginx"] = ComposeService{Ports: []string{"8080:80", "0.0.0.0:443:443"}}
+	err := checkPortConflicts(compose)
+	if !errors.Is(err, problem.ErrPortConflict) {
+		t.Fatalf("checkPortConflicts() error = %v, want a port conflict", err)
+	}
+	if !strings.Contains(err.Error(), "services nginx and traefik") {
+		t.Errorf("error = %v, want both services named", err)
+	}
+}
+
+// TestGenerateSkipsFailingServices verifies a service whose generation
+// panics is skipped with the services needing it, and the others generated
+func TestGenerateSkipsFailingServices(t *testing.T) {
+	cfg := &config.Config{Domain: "example.com"}
+	service := func(name string, deps ...string) *registry.ResolvedService {
+		def := &registry.ServiceDefinition{
+			Metadata: registry.ServiceMetadata{Name: name},
+			Spec: registry.ServiceSpec{
+				Image:        registry.ImageSpec{Repository: "example/" + name},
+				Container:    registry.ContainerSpec{NameTemplate: "sdbx-" + name},
+				Dependencies: registry.DependencySpec{Required: deps},
+			},
+			Conditions: registry.Conditions{Always: true},
+		}
+		return &registry.ResolvedService{Name: name, FinalDefinition: def, Dependencies: deps, Enabled: true}
+	}
+	graph := &registry.ResolutionGraph{
+		Services: map[string]*registry.ResolvedService{
+			"broken":    {Name: "broken", Enabled: true}, // No definition: generation panics
+			"dependent": service("dependent", "broken"),
+			"healthy":   service("healthy"),
+		},
+		Order: []string{"broken", "healthy", "dependent"},
+	}
+
+	compose, err := NewComposeGenerator(cfg, nil, nil).Generate(graph)
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	if _, ok := compose.Services["healthy"]; !ok || len(compose.Services) != 1 {
+		t.Errorf("services = %v, want only healthy", slices.Sorted(maps.Keys(compose.Services)))
+	}
+	if len(graph.Services) != 1 || !slices.Equal(graph.Order, []string{"healthy"}) {
+		t.Errorf("graph = %v %v, want the skipped services dropped", slices.Sorted(maps.Keys(graph.Services)), graph.Order)
+	}
+
+	skipped := graph.Skipped()
+	if len(skipped) != 2 || skipped[0].Service != "broken" || skipped[1].Service != "dependent" {
+		t.Fatalf("Skipped() = %v, want broken and dependent", skipped)
+	}
+	if !strings.Contains(skipped[0].Error(), "generation failed") || !strings.Contains(skipped[1].Error(), "depends on skipped service broken") {
+		t.Errorf("Skipped() = %v, want the reasons", skipped)
+	}
+}
+
+func TestGenerateKeepsPreviousBlocksOfSkippedServices(t *testing.T) {
+	cfg := &config.Config{Domain: "example.com"}
+	healthyDef := &registry.ServiceDefinition{
+		Metadata: registry.ServiceMetadata{Name: "healthy"},
+		Spec: registry.ServiceSpec{
+			Image:     registry.ImageSpec{Repository: "example/healthy"},
+			Container: registry.ContainerSpec{NameTemplate: "sdbx-healthy"},
+		},
+		Conditions: registry.Conditions{Always: true},
+	}
+	graph := &registry.ResolutionGraph{
+		Services: map[string]*registry.ResolvedService{
+			"broken":  {Name: "broken", Enabled: true}, // No definition: generation panics
+			"orphan":  {Name: "orphan", Enabled: true},
+			"healthy": {Name: "healthy", FinalDefinition: healthyDef, Enabled: true},
+		},
+		Order: []string{"broken", "orphan", "healthy"},
+	}
+
+	gen := NewComposeGenerator(cfg, nil, nil)
+	gen.Previous = &ComposeFile{
+		Services: map[string]ComposeService{
+			"broken": {
+				Image:     "example/broken:1.0",
+				DependsOn: map[string]DependsOnCondition{"healthy": {Condition: "service_started"}},
+				Secrets:   []string{"broken_token"},
+				Volumes:   []string{"media:/data"},
+			},
+			// Needs a service that is not generated anymore
+			"orphan": {Image: "example/orphan", NetworkMode: "service:gone"},
+		},
+		Secrets: map[string]ComposeSecretDef{"broken_token": {File: "./secrets/broken_token.txt"}},
+		Volumes: map[string]ComposeVolume{"media": {Driver: "local"}},
+	}
+	compose, err := gen.Generate(graph)
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+
+	if got := compose.Services["broken"].Image; got != "example/broken:1.0" {
+		t.Errorf("broken image = %q, want the previous block kept", got)
+	}
+	if _, ok := compose.Services["orphan"]; ok {
+		t.Error("orphan kept although the service it shares the network of is gone")
+	}
+	if _, ok := compose.Secrets["broken_token"]; !ok {
+		t.Errorf("secrets = %v, want broken_token of the kept block", compose.Secrets)
+	}
+	if _, ok := compose.Volumes["media"]; !ok {
+		t.Errorf("volumes = %v, want media of the kept block", compose.Volumes)
+	}
+	if !gen.Kept["broken"] || gen.Kept["orphan"] {
+		t.Errorf("Kept = %v, want only broken", gen.Kept)
+	}
+	if skipped := graph.Skipped(); len(skipped) != 2 {
+		t.Errorf("Skipped() = %v, want broken and orphan still reported", skipped)
+	}
+}
+
+// TestGenerateServiceTranscodeAndLimits verifies /dev/dri passthrough and resource limits
+func TestGenerateServiceTranscodeAndLimits(t *testing.T) {
+	cfg := &config.Config{
+		Domain:            "example.com",
+		HardwareTranscode: true,
+		Services: map[string]config.ServiceOverride{
+			"plex": {Resources: &config.ResourceLimits{CPUs: "2.0", Memory: "1g"}},
+		},
+	}
+	gen := NewComposeGenerator(cfg, nil, nil)
+
+	def := &registry.ServiceDefinition{
+		Metadata: registry.ServiceMetadata{Name: "plex"},
+		Spec: registry.ServiceSpec{
+			Image: registry.ImageSpec{Repository: "linuxserver/plex", Tag: "latest"},
+			Container: registry.ContainerSpec{
+				NameTemplate:      "sdbx-plex",
+				Devices:           []string{"/dev/bus/usb:/dev/bus/usb"},
+				HardwareTranscode: true,
+				GPUEnabled:        true,
+			},
+		},
+	}
+
+	svc := gen.generateService(def)
+	if !slices.Equal(svc.Devices, []string{"/dev/bus/usb:/dev/bus/usb", "/dev/dri:/dev/dri"}) {
+		t.Errorf("Devices = %v", svc.Devices)
+	}
+	if len(def.Spec.Container.Devices) != 1 {
+		t.Error("definition devices must not be modified")
+	}
+	limits := svc.Deploy.Resources.Limits
+	if limits == nil || limits.CPUs != "2.0" || limits.Memory != "1g" {
+		t.Errorf("Limits = %+v", limits)
+	}
+	if svc.Deploy.Resources.Reservations == nil {
+		t.Error("GPU reservations must be kept alongside limits")
+	}
+
+	cfg.HardwareTranscode = false
+	cfg.Services = nil
+	svc = gen.generateService(def)
+	if len(svc.Devices) != 1 || svc.Deploy.Resources.Limits != nil {
+		t.Errorf("unexpected devices %v or limits without config", svc.Devices)
+	}
+
+	// NVIDIA transcoding reserves the GPUs without /dev/dri
+	def.Spec.Container.GPUEnabled = false
+	cfg.Services = map[string]config.ServiceOverride{"plex": {Transcode: config.TranscodeNVIDIA}}
+	svc = gen.generateService(def)
+	if len(svc.Devices) != 1 || svc.Deploy == nil || svc.Deploy.Resources.Reservations == nil {
+		t.Errorf("Devices = %v, Deploy = %+v; want a GPU reservation", svc.Devices, svc.Deploy)
+	}
+	if !slices.Contains(svc.Environment, "NVIDIA_DRIVER_CAPABILITIES=compute,video,utility") {
+		t.Errorf("Environment = %v", svc.Environment)
+	}
+}
+
+// TestGenerateServiceSecretDelivery verifies file, env_file and env delivery of secretRef variables
+func TestGenerateServiceSecretDelivery(t *testing.T) {
+	def := &registry.ServiceDefinition{
+		Metadata: registry.ServiceMetadata{Name: "app"},
+		Spec: registry.ServiceSpec{
+			Image:     registry.ImageSpec{Repository: "example/app", Tag: "latest"},
+			Container: registry.ContainerSpec{NameTemplate: "sdbx-app"},
+			Environment: registry.EnvironmentSpec{
+				Static: []registry.EnvVar{
+					{Name: "TZ", Value: "UTC"},
+					{Name: "DB_PASSWORD", ValueFrom: &registry.ValueSource{SecretRef: "app_db", FileEnv: "DB_PASSWORD_FILE"}},
+					{Name: "API_KEY", ValueFrom: &registry.ValueSource{SecretRef: "sonarr_api_key"}},
+				},
+			},
+		},
+		Secrets: []registry.SecretDef{{Name: "app_db", Type: "password"}},
+	}
+	secrets := map[string]string{"app_db.txt": "hunter2hunter2", "sonarr_api_key.txt": "abc123"}
+
+	tests := []struct {
+		strategy    string
+		wantEnv     []string
+		wantEnvFile string
+		wantSecrets []string
+	}{
+		{
+			strategy:    config.SecretDeliveryAuto,
+			wantEnv:     []string{"TZ=UTC", "DB_PASSWORD_FILE=/run/secrets/app_db"},
+			wantEnvFile: "API_KEY=\"abc123\"\n",
+			wantSecrets: []string{"app_db"},
+		},
+		{
+			strategy:    config.SecretDeliveryEnvFile,
+			wantEnv:     []string{"TZ=UTC"},
+			wantEnvFile: "DB_PASSWORD=\"hunter2hunter2\"\nAPI_KEY=\"abc123\"\n",
+			wantSecrets: []string{"app_db"},
+		},
+		{
+			strategy:    config.SecretDeliveryEnv,
+			wantEnv:     []string{"TZ=UTC", "DB_PASSWORD=hunter2hunter2", "API_KEY=abc123"},
+			wantSecrets: []string{"app_db"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.strategy, func(t *testing.T) {
+			cfg := &config.Config{
+				Domain:   "example.com",
+				Services: map[string]config.ServiceOverride{"app": {SecretDelivery: tt.strategy}},
+			}
+			gen := NewComposeGenerator(cfg, nil, secrets)
+
+			svc := gen.generateService(def)
+			if !slices.Equal(svc.Environment, tt.wantEnv) {
+				t.Errorf("Environment = %v, want %v", svc.Environment, tt.wantEnv)
+			}
+			if got := string(gen.EnvFiles["app"]); got != tt.wantEnvFile {
+				t.Errorf("env file = %q, want %q", got, tt.wantEnvFile)
+			}
+			hasEnvFile := slices.Contains(svc.EnvFile, "./secrets/app.env")
+			if hasEnvFile != (tt.wantEnvFile != "") {
+				t.Errorf("EnvFile = %v", svc.EnvFile)
+			}
+			if !slices.Equal(svc.Secrets, tt.wantSecrets) {
+				t.Errorf("Secrets = %v, want %v", svc.Secrets, tt.wantSecrets)
+			}
+		})
+	}
+}
+
+func TestGenerateServiceSecretValues(t *testing.T) {
+	def := &registry.ServiceDefinition{
+		Metadata: registry.ServiceMetadata{Name: "app"},
+		Spec: registry.ServiceSpec{
+			Image:     registry.ImageSpec{Repository: "example/app", Tag: "latest"},
+			Container: registry.ContainerSpec{NameTemplate: "sdbx-app"},
+			Environment: registry.EnvironmentSpec{
+				Static: []registry.EnvVar{
+					{Name: "API_KEY", ValueFrom: &registry.ValueSource{SecretRef: "app_key"}},
+					{Name: "WEBHOOK", Value: "disabled", ValueFrom: &registry.ValueSource{SecretRef: "app_webhook"}},
+				},
+			},
+		},
+		Conditions: registry.Conditions{Always: true},
+	}
+	cfg := &config.Config{Domain: "example.com"}
+
+	// Quoted and escaped, so compose neither interpolates nor trims them
+	gen := NewComposeGenerator(cfg, nil, map[string]string{"app_key.txt": `a$b"c\d #e`})
+	gen.generateService(def)
+	want := "API_KEY=\"a\\$b\\\"c\\\\d #e\"\nWEBHOOK=\"disabled\"\n"
+	if got := string(gen.EnvFiles["app"]); got != want {
+		t.Errorf("env file = %q, want %q", got, want)
+	}
+
+	// Multi-line secrets only reach the container through file delivery
+	pem := map[string]string{"app_key.txt": "-----BEGIN KEY-----\nabc\n-----END KEY-----"}
+	for _, strategy := range []string{config.SecretDeliveryEnvFile, config.SecretDeliveryEnv} {
+		cfg.Services = map[string]config.ServiceOverride{"app": {SecretDelivery: strategy}}
+		gen := NewComposeGenerator(cfg, nil, pem)
+		if _, _, err := gen.generateServiceSafely(def); err == nil || !strings.Contains(err.Error(), "several lines") {
+			t.Errorf("%s: generateServiceSafely() error = %v, want multi-line secret refused", strategy, err)
+		}
+	}
+	def.Spec.Environment.Static[0].ValueFrom.FileEnv = "API_KEY_FILE"
+	cfg.Services = nil
+	if _, ok, err := NewComposeGenerator(cfg, nil, pem).generateServiceSafely(def); err != nil || !ok {
+		t.Errorf("generateServiceSafely() with file delivery = %v, %v", ok, err)
+	}
+}
+
+func TestEvalTemplateCache(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Timezone = "Europe/Paris"
+	gen := NewComposeGenerator(cfg, nil, nil)
+	ctx := TemplateContext{Config: cfg}
+
+	for range 3 {
+		if got := gen.evalTemplate("TZ={{ .Config.Timezone }}", ctx); got != "TZ=Europe/Paris" {
+			t.Fatalf("evalTemplate() = %q", got)
+		}
+		if got := gen.evalTemplate("{{ .Broken }", ctx); got != "{{ .Broken }" {
+			t.Fatalf("evalTemplate() = %q for a parse error", got)
+		}
+	}
+	if len(gen.templates) != 2 || gen.templates["{{ .Broken }"] != nil {
+		t.Errorf("templates = %v, want one parsed and one failed entry", gen.templates)
+	}
+
+	// The cache is keyed by content, so a changed context is still rendered
+	cfg.Timezone = "UTC"
+	if got := gen.evalTemplate("TZ={{ .Config.Timezone }}", ctx); got != "TZ=UTC" {
+		t.Errorf("evalTemplate() = %q after a context change", got)
+	}
+}
+
+// largeGraph returns a resolution graph of n services with templated
+// environment variables and volumes, as in large multi-source setups
+func largeGraph(n int) *registry.ResolutionGraph {
+	graph := &registry.ResolutionGraph{Services: make(map[string]*registry.ResolvedService, n)}
+	for i := range n {
+		name := fmt.Sprintf("service%03d", i)
+		def := &registry.ServiceDefinition{
+			Metadata: registry.ServiceMetadata{Name: name},
+			Spec: registry.ServiceSpec{
+				Image:     registry.ImageSpec{Repository: "example/" + name, Tag: "latest"},
+				Container: registry.ContainerSpec{NameTemplate: "sdbx-{{ .Name }}"},
+			},
+		}
+		for j := range 20 {
+			def.Spec.Environment.Static = append(def.Spec.Environment.Static, registry.EnvVar{
+				Name:  fmt.Sprintf("VAR_%d", j),
+				Value: fmt.Sprintf("{{ .Config.Domain }}/%d/{{ .Config.Timezone }}", j),
+			})
+		}
+		for _, dir := range []string{"config", "media", "downloads"} {
+			def.Spec.Volumes = append(def.Spec.Volumes, registry.VolumeMount{
+				HostPath:      "{{ .Config.MediaPath }}/" + dir,
+				ContainerPath: "/" + dir,
+			})
+		}
+		graph.Services[name] = &registry.ResolvedService{Name: name, Enabled: true, FinalDefinition: def}
+		graph.Order = append(graph.Order, name)
+	}
+	return graph
+}
+
+// BenchmarkGenerateLargeGraph renders 300 services with 24 templates each
+func BenchmarkGenerateLargeGraph(b *testing.B) {
+	cfg := config.DefaultConfig()
+	graph := largeGraph(300)
+	for b.Loop() {
+		if _, err := NewComposeGenerator(cfg, nil, nil).Generate(graph); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkEvalTemplate compares rendering a template through the cache
+// with parsing it on every call
+func BenchmarkEvalTemplate(b *testing.B) {
+	cfg := config.DefaultConfig()
+	ctx := TemplateContext{Config: cfg}
+	tmpl := "{{ .Config.Domain }}/{{ .Config.Timezone }}"
+
+	b.Run("cached", func(b *testing.B) {
+		gen := NewComposeGenerator(cfg, nil, nil)
+		for b.Loop() {
+			gen.evalTemplate(tmpl, ctx)
+		}
+	})
+	b.Run("uncached", func(b *testing.B) {
+		gen := NewComposeGenerator(cfg, nil, nil)
+		for b.Loop() {
+			clear(gen.templates)
+			gen.evalTemplate(tmpl, ctx)
+		}
+	})
+}
+
+// TestGenerateServiceGeoIP verifies the databases mount and template paths of requires: geoip
+func TestGenerateServiceGeoIP(t *testing.T) {
+	cfg := &config.Config{Domain: "example.com", GeoIP: config.GeoIPConfig{AccountID: "123456"}}
+	gen := NewComposeGenerator(cfg, nil, nil)
+
+	def := &registry.ServiceDefinition{
+		Metadata: registry.ServiceMetadata{Name: "tautulli"},
+		Spec: registry.ServiceSpec{
+			Image:     registry.ImageSpec{Repository: "linuxserver/tautulli", Tag: "latest"},
+			Container: registry.ContainerSpec{NameTemplate: "sdbx-tautulli"},
+			Requires:  []string{registry.RequireGeoIP},
+			Environment: registry.EnvironmentSpec{
+				Static: []registry.EnvVar{{Name: "GEOIP_DB", Value: "{{ .GeoIP.City }}"}},
+			},
+		},
+	}
+
+	svc := gen.generateService(def)
+	if !slices.Contains(svc.Volumes, "./data/geoip:/geoip:ro") {
+		t.Errorf("Volumes = %v, want the GeoIP databases", svc.Volumes)
+	}
+	if !slices.Contains(svc.Environment, "GEOIP_DB=/geoip/GeoLite2-City.mmdb") {
+		t.Errorf("Environment = %v", svc.Environment)
+	}
+
+	def.Spec.Requires = nil
+	if svc := gen.generateService(def); len(svc.Volumes) != 0 {
+		t.Errorf("Volumes = %v without requires: geoip", svc.Volumes)
+	}
+}
+
+func TestAllocateHostPorts(t *testing.T) {
+	newCompose := func() *ComposeFile {
+		return &ComposeFile{Services: map[string]ComposeService{
+			"grafana":  {Ports: []string{"3000:3000"}},
+			"homarr":   {Ports: []string{"3000:7575"}},
+			"uptime":   {Ports: []string{"127.0.0.1:3000:3001", "20000:20000/udp"}},
+			"jellyfin": {Ports: []string{"7359", "6881-6889:6881-6889"}},
+		}}
+	}
+	cfg := &config.Config{HostPorts: config.HostPortsConfig{Range: "20000-20010"}}
+	g := NewComposeGenerator(cfg, nil, nil)
+	g.portFree = func(port int, proto string) bool { return port != 20000 }
+
+	compose := newCompose()
+	if err := g.allocateHostPorts(compose); err != nil {
+		t.Fatalf("allocateHostPorts() error = %v", err)
+	}
+	if err := checkPortConflicts(compose); err != nil {
+		t.Fatalf("conflicts left after allocation: %v", err)
+	}
+	if got := compose.Services["homarr"].Ports[0]; got != "20001:7575" {
+		t.Errorf("homarr port = %s, want the first free port of the range", got)
+	}
+	if got := compose.Services["grafana"].Ports[0]; got != "3000:3000" {
+		t.Errorf("grafana port = %s, want its requested port", got)
+	}
+	if got := compose.Services["uptime"].Ports[0]; got != "127.0.0.1:3000:3001" {
+		t.Errorf("uptime port = %s, want its requested port on another address", got)
+	}
+	if got := g.HostPorts["homarr:7575/tcp"]; got != 20001 {
+		t.Errorf("HostPorts[homarr:7575/tcp] = %d, want 20001", got)
+	}
+
+	// The lock file keeps assignments stable when the requester changes
+	g.HostPorts = map[string]int{"homarr:7575/tcp": 20001, "grafana:3000/tcp": 20002}
+	compose = newCompose()
+	if err := g.allocateHostPorts(compose); err != nil {
+		t.Fatalf("allocateHostPorts() error = %v", err)
+	}
+	if got := compose.Services["grafana"].Ports[0]; got != "20002:3000" {
+		t.Errorf("grafana port = %s, want the recorded 20002", got)
+	}
+	if got := compose.Services["homarr"].Ports[0]; got != "20001:7575" {
+		t.Errorf("homarr port = %s, want the recorded 20001", got)
+	}
+
+	// A recorded port outside the range is dropped once the mapping asks
+	// for another one
+	g.HostPorts = map[string]int{"grafana:3000/tcp": 3100, "uptime:3001/tcp": 3000}
+	compose = newCompose()
+	if err := g.allocateHostPorts(compose); err != nil {
+		t.Fatalf("allocateHostPorts() error = %v", err)
+	}
+	if got := compose.Services["grafana"].Ports[0]; got != "3000:3000" {
+		t.Errorf("grafana port = %s, want the requested 3000 over the recorded 3100", got)
+	}
+	if got := compose.Services["uptime"].Ports[0]; got != "127.0.0.1:3000:3001" {
+		t.Errorf("uptime port = %s, want the recorded port it still asks for", got)
+	}
+
+	// Without a range, conflicts are left to checkPortConflicts
+	g = NewComposeGenerator(&config.Config{}, nil, nil)
+	compose = newCompose()
+	if err := g.allocateHostPorts(compose); err != nil {
+		t.Fatal(err)
+	}
+	if err := checkPortConflicts(compose); !errors.Is(err, problem.ErrPortConflict) {
+		t.Errorf("checkPortConflicts() error = %v, want a port conflict", err)
+	}
+
+	g = NewComposeGenerator(&config.Config{HostPorts: config.HostPortsConfig{Range: "3000-3000"}}, nil, nil)
+	g.portFree = func(int, string) bool { return true }
+	if err := g.allocateHostPorts(newCompose()); !errors.Is(err, problem.ErrPortConflict) {
+		t.Errorf("allocateHostPorts() with an exhausted range error = %v, want a port conflict", err)
+	}
+}
+
+func TestStaticAddress(t *testing.T) {
+	cfg := &config.Config{
+		Domain:   "example.com",
+		Networks: map[string]config.NetworkConfig{"proxy": {Subnet: "172.30.0.0/24"}},
+	}
+	gen := NewComposeGenerator(cfg, nil, nil)
+	def := &registry.ServiceDefinition{
+		Metadata: registry.ServiceMetadata{Name: "adguard"},
+		Spec: registry.ServiceSpec{
+			Image:     registry.ImageSpec{Repository: "adguard/adguardhome", Tag: "latest"},
+			Container: registry.ContainerSpec{NameTemplate: "sdbx-adguard"},
+			Networking: registry.NetworkSpec{
+				Networks:    []registry.NetworkRef{{Name: "proxy"}},
+				Zones:       []string{registry.ZoneFrontend, registry.ZoneBackend},
+				IPv4Address: `{{ if .Config.Domain }}172.30.0.53{{ end }}`,
+			},
+		},
+	}
+	svc := gen.generateService(def)
+	if svc.NetworkAddresses["proxy"] != "172.30.0.53" {
+		t.Fatalf("NetworkAddresses = %v, want the evaluated template", svc.NetworkAddresses)
+	}
+
+	compose := &ComposeFile{
+		Name:     "sdbx",
+		Services: map[string]ComposeService{"adguard": svc, "plex": {Image: "plex", Networks: []string{"proxy"}}},
+		Networks: map[string]ComposeNetwork{"proxy": {Name: "sdbx_proxy"}},
+	}
+	if err := gen.checkStaticAddresses(compose); err != nil {
+		t.Fatalf("checkStaticAddresses() error = %v", err)
+	}
+	addZoneNetworks(compose)
+	gen.addSubnets(compose)
+
+	data, err := compose.ToYAML()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"ipv4_address: 172.30.0.53", "subnet: 172.30.0.0/24", "backend: {}"} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("compose.yaml lacks %q:\n%s", want, data)
+		}
+	}
+	parsed, err := ParseComposeFile(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := parsed.Services["adguard"]; strings.Join(got.Networks, ",") != "proxy,backend" || got.NetworkAddresses["proxy"] != "172.30.0.53" {
+		t.Errorf("parsed adguard networks = %v %v", got.Networks, got.NetworkAddresses)
+	}
+	if got := parsed.Services["plex"].Networks; len(got) != 1 || got[0] != "proxy" {
+		t.Errorf("parsed plex networks = %v, want the list form", got)
+	}
+
+	for address, wantErr := range map[string]string{
+		"172.31.0.10":  "outside networks.proxy.subnet",
+		"172.30.0.1":   "reserved",
+		"172.30.0.255": "reserved",
+		"172.30.0.300": "invalid IPv4 address",
+	} {
+		bad := svc
+		bad.NetworkAddresses = map[string]string{"proxy": address}
+		compose.Services["adguard"] = bad
+		if err := gen.checkStaticAddresses(compose); err == nil || !strings.Contains(err.Error(), wantErr) {
+			t.Errorf("checkStaticAddresses(%s) error = %v, want %q", address, err, wantErr)
+		}
+	}
+	compose.Services["adguard"] = svc
+	compose.Services["pihole"] = ComposeService{Networks: []string{"proxy"}, NetworkAddresses: map[string]string{"proxy": "172.30.0.53"}}
+	if err := gen.checkStaticAddresses(compose); err == nil || !strings.Contains(err.Error(), "both use") {
+		t.Errorf("checkStaticAddresses() with a duplicate address error = %v", err)
+	}
+
+	gen.Config = &config.Config{}
+	delete(compose.Services, "pihole")
+	if err := gen.checkStaticAddresses(compose); err == nil || !strings.Contains(err.Error(), "networks.proxy.subnet") {
+		t.Errorf("checkStaticAddresses() without a subnet error = %v, want a hint", err)
+	}
+}
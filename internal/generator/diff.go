@@ -0,0 +1,145 @@
+package generator
+
+import (
+	"fmt"
+	"strings"
+)
+
+// diffContext is the number of unchanged lines shown around each change
+const diffContext = 3
+
+// diffOp is one line of an edit script
+type diffOp struct {
+	kind byte // ' ', '-' or '+'
+	line string
+}
+
+// UnifiedDiff returns a unified diff between two versions of a file, or ""
+// when they are identical
+func UnifiedDiff(name string, before, after []byte) string {
+	if string(before) == string(after) {
+		return ""
+	}
+	ops := diffLines(splitLines(string(before)), splitLines(string(after)))
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "--- a/%s\n+++ b/%s\n", name, name)
+
+	for start := 0; start < len(ops); {
+		// Find the next change
+		for start < len(ops) && ops[start].kind == ' ' {
+			start++
+		}
+		if start == len(ops) {
+			break
+		}
+
+		// Extend the hunk while changes are closer than two contexts apart
+		first := max(start-diffContext, 0)
+		end := start
+		for end < len(ops) {
+			if ops[end].kind != ' ' {
+				end++
+				continue
+			}
+			next := end
+			for next < len(ops) && ops[next].kind == ' ' {
+				next++
+			}
+			if next == len(ops) || next-end > 2*diffContext {
+				break
+			}
+			end = next
+		}
+		last := min(end+diffContext, len(ops))
+
+		oldStart, newStart := lineNumbers(ops, first)
+		oldCount, newCount := 0, 0
+		for _, op := range ops[first:last] {
+			if op.kind != '+' {
+				oldCount++
+			}
+			if op.kind != '-' {
+				newCount++
+			}
+		}
+		// An empty range starts at the line before it, as in diff(1)
+		if oldCount == 0 {
+			oldStart--
+		}
+		if newCount == 0 {
+			newStart--
+		}
+		fmt.Fprintf(&b, "@@ -%d,%d +%d,%d @@\n", oldStart, oldCount, newStart, newCount)
+		for _, op := range ops[first:last] {
+			b.WriteByte(op.kind)
+			b.WriteString(op.line)
+			b.WriteByte('\n')
+		}
+		start = last
+	}
+	return b.String()
+}
+
+// splitLines splits text into lines without their trailing newline
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
+
+// lineNumbers returns the 1-based old and new line numbers at ops[i]
+func lineNumbers(ops []diffOp, i int) (int, int) {
+	oldLine, newLine := 1, 1
+	for _, op := range ops[:i] {
+		if op.kind != '+' {
+			oldLine++
+		}
+		if op.kind != '-' {
+			newLine++
+		}
+	}
+	return oldLine, newLine
+}
+
+// diffLines computes a line edit script from the longest common subsequence
+func diffLines(a, b []string) []diffOp {
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	ops := make([]diffOp, 0, len(a)+len(b))
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			ops = append(ops, diffOp{' ', a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			ops = append(ops, diffOp{'-', a[i]})
+			i++
+		default:
+			ops = append(ops, diffOp{'+', b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		ops = append(ops, diffOp{'-', a[i]})
+	}
+	for ; j < len(b); j++ {
+		ops = append(ops, diffOp{'+', b[j]})
+	}
+	return ops
+}
@@ -0,0 +1,37 @@
+package generator
+
+import "testing"
+
+func TestUnifiedDiff(t *testing.T) {
+	if got := UnifiedDiff("a.yaml", []byte("x\ny\n"), []byte("x\ny\n")); got != "" {
+		t.Errorf("expected no diff for identical files, got %q", got)
+	}
+
+	before := "a\nb\nc\nd\ne\nf\ng\nh\ni\nj\nl\n"
+	after := "a\nb\nc\nD\ne\nf\ng\nh\ni\nj\nl\nk\n"
+	want := `--- a/f.txt
++++ b/f.txt
+@@ -1,7 +1,7 @@
+ a
+ b
+ c
+-d
++D
+ e
+ f
+ g
+@@ -9,3 +9,4 @@
+ i
+ j
+ l
++k
+`
+	if got := UnifiedDiff("f.txt", []byte(before), []byte(after)); got != want {
+		t.Errorf("UnifiedDiff() =\n%s\nwant\n%s", got, want)
+	}
+
+	created := UnifiedDiff("new.txt", nil, []byte("one\n"))
+	if created != "--- a/new.txt\n+++ b/new.txt\n@@ -0,0 +1,1 @@\n+one\n" {
+		t.Errorf("unexpected diff for new file:\n%s", created)
+	}
+}
@@ -0,0 +1,132 @@
+package generator
+
+import (
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/maiko/sdbx/internal/config"
+	"github.com/maiko/sdbx/internal/registry"
+)
+
+// adGuardConfigPath is AdGuard Home's config, relative to the project directory
+const adGuardConfigPath = "configs/adguard/conf/AdGuardHome.yaml"
+
+// dnsService builds the Pi-hole or AdGuard Home container of dns.server. It
+// gets a static address on the proxy network, publishes port 53 on the
+// host's LAN address, and its admin UI is routed on the dns subdomain
+// behind the login.
+func (g *ComposeGenerator) dnsService() ComposeService {
+	d := g.Config.DNS
+	addr, _ := d.ContainerAddress(g.Config.Networks["proxy"]) // checked by validateDNS
+	svc := ComposeService{
+		ContainerName:    "sdbx-" + d.Server,
+		Restart:          "unless-stopped",
+		Networks:         []string{"proxy"},
+		NetworkAddresses: map[string]string{"proxy": addr.String()},
+		Ports:            []string{d.Address + ":53:53/tcp", d.Address + ":53:53/udp"},
+	}
+	switch d.Server {
+	case config.DNSServerAdGuard:
+		// Listeners, upstreams and rewrites are set in AdGuardHome.yaml
+		svc.Image = "adguard/adguardhome:latest"
+		svc.Volumes = []string{
+			"./configs/adguard/conf:/opt/adguardhome/conf",
+			"./configs/adguard/work:/opt/adguardhome/work",
+		}
+	default:
+		// The admin UI is behind the login, so Pi-hole asks no password
+		svc.Image = "pihole/pihole:latest"
+		svc.Environment = []string{
+			"TZ=" + g.Config.Timezone,
+			"FTLCONF_dns_upstreams=" + strings.Join(d.UpstreamServers(), ";"),
+			"FTLCONF_dns_listeningMode=all",
+			fmt.Sprintf("FTLCONF_misc_dnsmasq_lines=address=/%s/%s", g.Config.Domain, d.Address),
+			"FTLCONF_webserver_api_password=",
+		}
+		svc.Volumes = []string{"./configs/pihole:/etc/pihole"}
+	}
+
+	route := &registry.ServiceDefinition{
+		Metadata: registry.ServiceMetadata{Name: d.Server},
+		Routing: registry.RoutingConfig{
+			Enabled:        true,
+			Port:           80,
+			Subdomain:      "dns",
+			ForceSubdomain: true,
+			Auth:           registry.AuthConfig{Required: true},
+		},
+	}
+	labels := append(watchtowerLabels(true, g.Config.UpdatePolicy(d.Server)), g.buildTraefikLabels(route, TemplateContext{})...)
+	svc.Labels = ownershipLabels(labels, d.Server, "", "sdbx")
+	svc.Logging = g.buildLogging(d.Server, nil)
+	return svc
+}
+
+// useLocalDNS makes the services on bridge networks resolve through the
+// local DNS server. Names of the Docker networks are still answered by
+// Docker, which forwards the others to it.
+func (g *ComposeGenerator) useLocalDNS(compose *ComposeFile) {
+	server := g.Config.DNS.Server
+	addr := compose.Services[server].NetworkAddresses["proxy"]
+	for name, svc := range compose.Services {
+		if name == server || svc.NetworkMode != "" || len(svc.Networks) == 0 {
+			continue
+		}
+		svc.DNS = []string{addr}
+		compose.Services[name] = svc
+	}
+}
+
+// GenerateAdGuardConfig sets the listeners, the upstreams and the rewrites
+// of the dns section in AdGuard Home's config, keeping everything else
+// AdGuard Home saved in it. Rewrites resolve the domain and its subdomains
+// to the host. They live in the filtering section of current configs, and
+// in the dns section of a new one, which AdGuard Home migrates on start.
+func (g *IntegrationsGenerator) GenerateAdGuardConfig(existing []byte) ([]byte, error) {
+	doc := make(map[string]interface{})
+	if err := yaml.Unmarshal(existing, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", adGuardConfigPath, err)
+	}
+	if doc == nil {
+		doc = make(map[string]interface{})
+	}
+
+	childMap(doc, "http")["address"] = "0.0.0.0:80"
+	dnsSection := childMap(doc, "dns")
+	dnsSection["bind_hosts"] = []string{"0.0.0.0"}
+	dnsSection["port"] = 53
+	dnsSection["upstream_dns"] = g.Config.DNS.UpstreamServers()
+
+	section := dnsSection
+	if filtering, ok := doc["filtering"].(map[string]interface{}); ok {
+		section = filtering
+	}
+	managed := map[string]bool{g.Config.Domain: true, "*." + g.Config.Domain: true}
+	var rewrites []interface{}
+	if list, ok := section["rewrites"].([]interface{}); ok {
+		for _, entry := range list {
+			if rewrite, ok := entry.(map[string]interface{}); ok && managed[fmt.Sprint(rewrite["domain"])] {
+				continue
+			}
+			rewrites = append(rewrites, entry)
+		}
+	}
+	for _, domain := range []string{g.Config.Domain, "*." + g.Config.Domain} {
+		rewrites = append(rewrites, map[string]interface{}{"domain": domain, "answer": g.Config.DNS.Address})
+	}
+	section["rewrites"] = rewrites
+
+	return yaml.Marshal(doc)
+}
+
+// childMap returns the mapping under key, creating it when missing
+func childMap(parent map[string]interface{}, key string) map[string]interface{} {
+	child, ok := parent[key].(map[string]interface{})
+	if !ok {
+		child = make(map[string]interface{})
+		parent[key] = child
+	}
+	return child
+}
@@ -0,0 +1,114 @@
+package generator
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/maiko/sdbx/internal/config"
+)
+
+func dnsTestConfig(server string) *config.Config {
+	return &config.Config{
+		Domain:   "example.com",
+		Timezone: "UTC",
+		Expose:   config.ExposeConfig{Mode: config.ExposeModeLAN},
+		Networks: map[string]config.NetworkConfig{"proxy": {Subnet: "172.30.0.0/24"}},
+		DNS:      config.DNSConfig{Server: server, Address: "192.168.1.10"},
+	}
+}
+
+func TestDNSService(t *testing.T) {
+	g := NewComposeGenerator(dnsTestConfig(config.DNSServerPihole), nil, nil)
+	compose := &ComposeFile{Services: map[string]ComposeService{
+		"sonarr":      {Networks: []string{"proxy"}},
+		"qbittorrent": {NetworkMode: "service:gluetun"},
+	}}
+	compose.Services["pihole"] = g.dnsService()
+	g.useLocalDNS(compose)
+
+	pihole := compose.Services["pihole"]
+	if pihole.NetworkAddresses["proxy"] != "172.30.0.254" {
+		t.Errorf("pihole address = %v, want the last usable address of the subnet", pihole.NetworkAddresses)
+	}
+	if !slices.Contains(pihole.Ports, "192.168.1.10:53:53/udp") {
+		t.Errorf("pihole ports = %v, want 53 published on the host address", pihole.Ports)
+	}
+	if !slices.Contains(pihole.Environment, "FTLCONF_misc_dnsmasq_lines=address=/example.com/192.168.1.10") {
+		t.Errorf("pihole environment = %v, want the domain rewrite", pihole.Environment)
+	}
+	if !slices.Contains(pihole.Labels, "traefik.http.routers.pihole.rule=Host(`dns.example.com`)") {
+		t.Errorf("pihole labels = %v, want the admin UI routed", pihole.Labels)
+	}
+	if len(pihole.DNS) != 0 {
+		t.Errorf("pihole dns = %v, want the Docker default", pihole.DNS)
+	}
+	if got := compose.Services["sonarr"].DNS; len(got) != 1 || got[0] != "172.30.0.254" {
+		t.Errorf("sonarr dns = %v, want the local DNS server", got)
+	}
+	if got := compose.Services["qbittorrent"].DNS; got != nil {
+		t.Errorf("qbittorrent dns = %v, want none with a shared network namespace", got)
+	}
+}
+
+func TestGenerateAdGuardConfig(t *testing.T) {
+	g := NewIntegrationsGenerator(dnsTestConfig(config.DNSServerAdGuard), nil)
+
+	fresh, err := g.GenerateAdGuardConfig(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc struct {
+		HTTP struct {
+			Address string `yaml:"address"`
+		} `yaml:"http"`
+		DNS struct {
+			Upstreams []string            `yaml:"upstream_dns"`
+			Rewrites  []map[string]string `yaml:"rewrites"`
+		} `yaml:"dns"`
+		Filtering struct {
+			Rewrites []map[string]string `yaml:"rewrites"`
+		} `yaml:"filtering"`
+		Users []map[string]string `yaml:"users"`
+	}
+	if err := yaml.Unmarshal(fresh, &doc); err != nil {
+		t.Fatal(err)
+	}
+	if doc.HTTP.Address != "0.0.0.0:80" || strings.Join(doc.DNS.Upstreams, ",") != "1.1.1.1,9.9.9.9" || len(doc.DNS.Rewrites) != 2 {
+		t.Errorf("new AdGuard Home config = %s", fresh)
+	}
+
+	// AdGuard Home's own settings and the user's rewrites are kept
+	existing := []byte(`users:
+  - name: admin
+    password: $2y$10$hash
+filtering:
+  rewrites:
+    - domain: nas.lan
+      answer: 192.168.1.20
+    - domain: '*.example.com'
+      answer: 10.0.0.1
+schema_version: 29
+`)
+	updated, err := g.GenerateAdGuardConfig(existing)
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc.DNS.Rewrites = nil
+	if err := yaml.Unmarshal(updated, &doc); err != nil {
+		t.Fatal(err)
+	}
+	if len(doc.Users) != 1 || len(doc.DNS.Rewrites) != 0 || !strings.Contains(string(updated), "schema_version: 29") {
+		t.Errorf("updated AdGuard Home config = %s", updated)
+	}
+	want := []string{"nas.lan=192.168.1.20", "example.com=192.168.1.10", "*.example.com=192.168.1.10"}
+	var got []string
+	for _, r := range doc.Filtering.Rewrites {
+		got = append(got, r["domain"]+"="+r["answer"])
+	}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("rewrites = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,235 @@
+package generator
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/maiko/sdbx/internal/registry"
+)
+
+// DocsDir is the default output directory of 'sdbx docs generate'
+const DocsDir = "docs"
+
+// docsHeader marks generated documentation so edits are not expected to survive
+const docsHeader = "<!-- Generated by 'sdbx docs generate' from the resolved services. Do not edit; regenerate instead. -->\n\n"
+
+// sensitiveEnvKey matches .env keys whose values are redacted in .env.example
+var sensitiveEnvKey = regexp.MustCompile(`(?i)(DOMAIN|EMAIL|TOKEN|PASSWORD|SECRET|KEY)`)
+
+// GenerateDocs writes Markdown documentation of the resolved project (service
+// list with URLs, architecture diagram, required secrets) and a redacted
+// .env.example to dir, relative to the project directory. It returns the
+// paths written.
+func (g *Generator) GenerateDocs(ctx context.Context, dir string) ([]string, error) {
+	if g.Registry == nil {
+		var err error
+		g.Registry, err = registry.NewWithDefaults()
+		if err != nil {
+			return nil, fmt.Errorf("failed to create registry: %w", err)
+		}
+	}
+
+	graph, err := g.Registry.Resolve(ctx, g.Config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve services: %w", err)
+	}
+	deps, err := g.Registry.DependencyGraph(ctx, g.Config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build dependency graph: %w", err)
+	}
+
+	intGen := NewIntegrationsGenerator(g.Config, nil)
+	env, err := intGen.GenerateEnvFile(graph)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate .env: %w", err)
+	}
+
+	files := []struct {
+		name    string
+		content []byte
+	}{
+		{"README.md", g.docsIndex(graph)},
+		{"services.md", g.docsServices(graph, intGen)},
+		{"architecture.md", docsArchitecture(deps.Included())},
+		{"secrets.md", g.docsSecrets(graph)},
+		{".env.example", docsEnvExample(env, graph)},
+	}
+
+	outDir := dir
+	if !filepath.IsAbs(outDir) {
+		outDir = filepath.Join(g.OutputDir, dir)
+	}
+	if err := os.MkdirAll(outDir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create %s: %w", dir, err)
+	}
+
+	var written []string
+	for _, f := range files {
+		if err := writeFileIfChanged(filepath.Join(outDir, f.name), f.content, 0o644); err != nil {
+			return nil, fmt.Errorf("failed to write %s: %w", f.name, err)
+		}
+		written = append(written, filepath.Join(dir, f.name))
+	}
+	return written, nil
+}
+
+// docsIndex renders the overview page linking the other documents
+func (g *Generator) docsIndex(graph *registry.ResolutionGraph) []byte {
+	var b strings.Builder
+	b.WriteString(docsHeader)
+	fmt.Fprintf(&b, "# %s\n\n", g.Config.Domain)
+	fmt.Fprintf(&b, "| Setting | Value |\n|---|---|\n")
+	fmt.Fprintf(&b, "| Exposure | %s |\n", g.Config.Expose.Mode)
+	fmt.Fprintf(&b, "| Routing | %s |\n", g.Config.Routing.Strategy)
+	fmt.Fprintf(&b, "| VPN | %t |\n", g.Config.VPNEnabled)
+	fmt.Fprintf(&b, "| Services | %d |\n\n", len(graph.Order))
+	b.WriteString("- [Services](services.md) — every service with its URL\n")
+	b.WriteString("- [Architecture](architecture.md) — dependencies and networks\n")
+	b.WriteString("- [Secrets](secrets.md) — secrets the services need\n")
+	b.WriteString("- [.env.example](.env.example) — environment with sensitive values redacted\n")
+	return []byte(b.String())
+}
+
+// docsServices renders the service table
+func (g *Generator) docsServices(graph *registry.ResolutionGraph, intGen *IntegrationsGenerator) []byte {
+	var b strings.Builder
+	b.WriteString(docsHeader)
+	b.WriteString("# Services\n\n")
+	b.WriteString("| Service | Category | URL | Auth | Image | Description |\n")
+	b.WriteString("|---|---|---|---|---|---|\n")
+
+	for _, name := range slices.Sorted(slices.Values(graph.Order)) {
+		def := graph.Services[name].FinalDefinition
+
+		url, auth := "—", "—"
+		if def.Routing.Enabled {
+			url = intGen.getServiceURL(def)
+			url = fmt.Sprintf("[%s](%s)", url, url)
+			switch {
+			case def.Routing.Auth.Bypass:
+				auth = "bypass"
+			case def.Routing.Auth.Required:
+				auth = "required"
+			default:
+				auth = "none"
+			}
+		}
+		fmt.Fprintf(&b, "| %s | %s | %s | %s | `%s:%s` | %s |\n",
+			g.Config.ServiceRef(name), def.Metadata.Category, url, auth,
+			def.Spec.Image.Repository, def.Spec.Image.Tag, markdownCell(def.Metadata.Description))
+	}
+	return []byte(b.String())
+}
+
+// docsArchitecture renders the dependency graph as a Mermaid diagram
+func docsArchitecture(deps *registry.DependencyGraph) []byte {
+	var b strings.Builder
+	b.WriteString(docsHeader)
+	b.WriteString("# Architecture\n\n")
+	b.WriteString("Solid arrows are required dependencies, dotted ones optional or conditional. Circles are Docker networks.\n\n")
+	b.WriteString("```mermaid\n")
+	b.WriteString(deps.RenderMermaid())
+	b.WriteString("```\n")
+	return []byte(b.String())
+}
+
+// docsSecrets renders the secrets the resolved services declare. Values are
+// never included, only whether the file has content.
+func (g *Generator) docsSecrets(graph *registry.ResolutionGraph) []byte {
+	type secretRow struct {
+		def      registry.SecretDef
+		services []string
+	}
+	var rows []*secretRow
+	for _, name := range slices.Sorted(slices.Values(graph.Order)) {
+		for _, def := range graph.Services[name].FinalDefinition.Secrets {
+			i := slices.IndexFunc(rows, func(r *secretRow) bool { return r.def.Name == def.Name })
+			if i < 0 {
+				rows = append(rows, &secretRow{def: def})
+				i = len(rows) - 1
+			}
+			rows[i].services = append(rows[i].services, g.Config.ServiceRef(name))
+		}
+	}
+	slices.SortFunc(rows, func(a, b *secretRow) int { return strings.Compare(a.def.Name, b.def.Name) })
+
+	var b strings.Builder
+	b.WriteString(docsHeader)
+	b.WriteString("# Secrets\n\n")
+	if len(rows) == 0 {
+		b.WriteString("No service declares secrets.\n")
+		return []byte(b.String())
+	}
+	b.WriteString("Secrets live in `secrets/` and are never committed. Generated secrets are created by `sdbx init` and `sdbx regenerate`; manual ones must be filled in.\n\n")
+	b.WriteString("| Secret | File | Type | Provided by | Set | Used by | Description |\n")
+	b.WriteString("|---|---|---|---|---|---|---|\n")
+	for _, row := range rows {
+		typ := row.def.Type
+		if typ == "" {
+			typ = "auto"
+		}
+		provider := "generated"
+		if typ == "manual" {
+			provider = "you"
+		}
+		set := "no"
+		if data, err := os.ReadFile(filepath.Join(g.OutputDir, "secrets", row.def.Name+".txt")); err == nil && len(bytes.TrimSpace(data)) > 0 {
+			set = "yes"
+		}
+		fmt.Fprintf(&b, "| %s | `secrets/%s.txt` | %s | %s | %s | %s | %s |\n",
+			row.def.Name, row.def.Name, typ, provider, set, strings.Join(row.services, ", "), markdownCell(row.def.Description))
+	}
+	return []byte(b.String())
+}
+
+// docsEnvExample redacts sensitive values of the generated .env and lists the
+// variables services receive from secret files
+func docsEnvExample(env []byte, graph *registry.ResolutionGraph) []byte {
+	var b strings.Builder
+	b.WriteString("# SDBX environment example - generated by 'sdbx docs generate'\n")
+	b.WriteString("# Sensitive values are redacted; see secrets.md for secret files.\n")
+
+	scanner := bufio.NewScanner(bytes.NewReader(env))
+	for scanner.Scan() {
+		line := scanner.Text()
+		// Drop the header of the real .env
+		if strings.HasPrefix(line, "# SDBX Environment") || strings.HasPrefix(line, "# Generated by") {
+			continue
+		}
+		if key, value, ok := strings.Cut(line, "="); ok && !strings.HasPrefix(line, "#") && value != "" && sensitiveEnvKey.MatchString(key) {
+			line = key + "=<redacted>"
+		}
+		b.WriteString(line + "\n")
+	}
+
+	var secretVars []string
+	for _, name := range slices.Sorted(slices.Values(graph.Order)) {
+		spec := graph.Services[name].FinalDefinition.Spec.Environment
+		vars := slices.Clone(spec.Static)
+		for _, cond := range spec.Conditional {
+			vars = append(vars, cond.EnvVar)
+		}
+		for _, v := range vars {
+			if v.ValueFrom != nil && v.ValueFrom.SecretRef != "" {
+				secretVars = append(secretVars, fmt.Sprintf("# %s: %s=<secrets/%s.txt>", name, v.Name, v.ValueFrom.SecretRef))
+			}
+		}
+	}
+	if len(secretVars) > 0 {
+		b.WriteString("# Service variables read from secret files (not set in .env)\n")
+		b.WriteString(strings.Join(secretVars, "\n") + "\n")
+	}
+	return []byte(b.String())
+}
+
+// markdownCell escapes text for use in a Markdown table cell
+func markdownCell(s string) string {
+	return strings.NewReplacer("|", `\|`, "\n", " ").Replace(s)
+}
@@ -0,0 +1,80 @@
+package generator
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/maiko/sdbx/internal/config"
+)
+
+func TestGenerateDocs(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	cfg := config.DefaultConfig()
+	cfg.Domain = "home.example.com"
+	cfg.Expose.Mode = config.ExposeModeDirect
+	cfg.Expose.TLS.Email = "admin@example.com"
+
+	gen := NewGenerator(cfg, tmpDir)
+	if err := os.MkdirAll(filepath.Join(tmpDir, "secrets"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(tmpDir, "secrets", "authelia_jwt_secret.txt"), []byte("s3cr3t-value"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := gen.GenerateDocs(context.Background(), DocsDir)
+	if err != nil {
+		t.Fatalf("GenerateDocs failed: %v", err)
+	}
+	if len(files) != 5 {
+		t.Errorf("expected 5 files, got %v", files)
+	}
+
+	read := func(name string) string {
+		t.Helper()
+		data, err := os.ReadFile(filepath.Join(tmpDir, DocsDir, name))
+		if err != nil {
+			t.Fatalf("failed to read %s: %v", name, err)
+		}
+		return string(data)
+	}
+
+	services := read("services.md")
+	for _, want := range []string{"| plex |", "https://plex.home.example.com", "| authelia |"} {
+		if !strings.Contains(services, want) {
+			t.Errorf("services.md should contain %q:\n%s", want, services)
+		}
+	}
+
+	if architecture := read("architecture.md"); !strings.Contains(architecture, "```mermaid\nflowchart LR") {
+		t.Errorf("architecture.md should contain a mermaid flowchart:\n%s", architecture)
+	}
+
+	secretsDoc := read("secrets.md")
+	if !strings.Contains(secretsDoc, "| authelia_jwt_secret | `secrets/authelia_jwt_secret.txt` | auto | generated | yes |") {
+		t.Errorf("secrets.md should list authelia_jwt_secret as set:\n%s", secretsDoc)
+	}
+	if !strings.Contains(secretsDoc, "| plex_claim_token | `secrets/plex_claim_token.txt` | manual | you | no |") {
+		t.Errorf("secrets.md should list plex_claim_token as manual and unset:\n%s", secretsDoc)
+	}
+
+	for _, name := range []string{"README.md", "services.md", "architecture.md", "secrets.md", ".env.example"} {
+		if content := read(name); strings.Contains(content, "s3cr3t-value") {
+			t.Errorf("%s leaks a secret value", name)
+		}
+	}
+
+	env := read(".env.example")
+	for _, want := range []string{"SDBX_DOMAIN=<redacted>", "TRAEFIK_ACME_EMAIL=<redacted>", "PUID=1000", "# plex: PLEX_CLAIM=<secrets/plex_claim_token.txt>"} {
+		if !strings.Contains(env, want) {
+			t.Errorf(".env.example should contain %q:\n%s", want, env)
+		}
+	}
+	if strings.Contains(env, "home.example.com") || strings.Contains(env, "admin@example.com") {
+		t.Errorf(".env.example should not contain the domain or email:\n%s", env)
+	}
+}
@@ -0,0 +1,259 @@
+package generator
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/maiko/sdbx/internal/config"
+	"github.com/maiko/sdbx/internal/registry"
+)
+
+// FixturesDir is the directory next to a service.yaml holding its test
+// fixtures, for 'sdbx service test'
+const FixturesDir = "tests"
+
+// Fixture is a test case of a service definition: a .sdbx.yaml snippet and
+// the compose properties the service must render to with it
+type Fixture struct {
+	Description string         `yaml:"description,omitempty"`
+	Config      map[string]any `yaml:"config,omitempty"` // Applied over the default configuration
+	Compose     map[string]any `yaml:"compose"`          // Expected properties of the service's compose block
+}
+
+// FixtureResult is the outcome of a fixture
+type FixtureResult struct {
+	Service     string `json:"service"`
+	Fixture     string `json:"fixture"` // Path of the fixture file
+	Description string `json:"description,omitempty"`
+	Diff        string `json:"diff,omitempty"`    // Expected against rendered properties, when they differ
+	Error       string `json:"error,omitempty"`   // Why the fixture could not run
+	Updated     bool   `json:"updated,omitempty"` // The fixture was rewritten with the rendered properties
+}
+
+// Passed reports whether the service rendered as expected
+func (r FixtureResult) Passed() bool {
+	return r.Diff == "" && r.Error == ""
+}
+
+// fixtureLabels are generated labels left out of the comparison: the
+// definition hash changes with every edit of the definition
+var fixtureLabels = []string{"sdbx.definition-hash="}
+
+// RunFixtures runs the fixtures of the service definitions under path: a
+// service directory, or a source with core/ and addons/ directories.
+// Definitions under path take precedence over the embedded ones their
+// services depend on. With update, failing fixtures are rewritten with the
+// rendered properties.
+func RunFixtures(ctx context.Context, path string, cache registry.CacheConfig, update bool) ([]FixtureResult, error) {
+	loader := registry.NewLoader()
+	var results []FixtureResult
+	err := filepath.WalkDir(path, func(file string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() && strings.HasPrefix(d.Name(), ".") && file != path {
+			return filepath.SkipDir
+		}
+		if d.IsDir() || d.Name() != "service.yaml" {
+			return nil
+		}
+		fixtures, err := filepath.Glob(filepath.Join(filepath.Dir(file), FixturesDir, "*.yaml"))
+		if err != nil || len(fixtures) == 0 {
+			return err
+		}
+
+		def, err := loader.LoadServiceDefinition(file)
+		if err != nil {
+			return err
+		}
+		reg, err := fixtureRegistry(path, filepath.Dir(file), cache)
+		if err != nil {
+			return err
+		}
+		for _, fixture := range fixtures {
+			results = append(results, runFixture(ctx, reg, def, fixture, update))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// fixtureRegistry returns a registry finding definitions in the source
+// under test, then in the service's own directory, then embedded
+func fixtureRegistry(root, serviceDir string, cache registry.CacheConfig) (*registry.Registry, error) {
+	sources := []registry.Source{{Name: "fixtures", Type: "local", Path: root, Priority: 1000, Enabled: true}}
+	if parent := filepath.Dir(serviceDir); parent != filepath.Clean(root) {
+		sources = append(sources, registry.Source{Name: "fixtures-service", Type: "local", Path: parent, Priority: 999, Enabled: true})
+	}
+	return registry.New(&registry.SourceConfig{Sources: sources, Cache: cache})
+}
+
+// runFixture renders the service with the configuration of a fixture and
+// compares the properties the fixture expects
+func runFixture(ctx context.Context, reg *registry.Registry, def *registry.ServiceDefinition, path string, update bool) FixtureResult {
+	name := def.Metadata.Name
+	result := FixtureResult{Service: name, Fixture: path}
+	fail := func(err error) FixtureResult {
+		result.Error = err.Error()
+		return result
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fail(err)
+	}
+	var fixture Fixture
+	if err := yaml.Unmarshal(data, &fixture); err != nil {
+		return fail(fmt.Errorf("invalid fixture: %w", err))
+	}
+	result.Description = fixture.Description
+
+	snippet, err := yaml.Marshal(fixture.Config)
+	if err != nil {
+		return fail(err)
+	}
+	cfg, err := config.Parse(snippet)
+	if err != nil {
+		return fail(fmt.Errorf("invalid config: %w", err))
+	}
+	if def.Conditions.RequireAddon && !cfg.IsAddonEnabled(name) {
+		cfg.Addons = append(cfg.Addons, name)
+	}
+	if err := cfg.Validate(); err != nil {
+		return fail(fmt.Errorf("invalid config: %w", err))
+	}
+
+	rendered, err := renderFixtureService(ctx, reg, cfg, name)
+	if err != nil {
+		return fail(err)
+	}
+
+	// Only the properties the fixture lists are compared
+	actual := make(map[string]any, len(fixture.Compose))
+	for key := range fixture.Compose {
+		if value, ok := rendered[key]; ok {
+			actual[key] = value
+		}
+	}
+	if len(fixture.Compose) == 0 {
+		actual = rendered
+	}
+	want, err := fixtureYAML(fixture.Compose)
+	if err != nil {
+		return fail(err)
+	}
+	got, err := fixtureYAML(actual)
+	if err != nil {
+		return fail(err)
+	}
+	result.Diff = UnifiedDiff(filepath.Base(path), want, got)
+
+	if update && result.Diff != "" {
+		if err := updateFixture(path, data, actual); err != nil {
+			return fail(err)
+		}
+		result.Diff, result.Updated = "", true
+	}
+	return result
+}
+
+// renderFixtureService generates the compose block of a service as a map of
+// its YAML properties
+func renderFixtureService(ctx context.Context, reg *registry.Registry, cfg *config.Config, name string) (map[string]any, error) {
+	graph, err := reg.Resolve(ctx, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve services: %w", err)
+	}
+	for _, e := range graph.Errors {
+		if e.Service == name {
+			return nil, e
+		}
+	}
+
+	compose, err := NewComposeGenerator(cfg, reg, map[string]string{}).Generate(graph)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate compose file: %w", err)
+	}
+	for _, e := range graph.Skipped() {
+		if e.Service == name {
+			return nil, e
+		}
+	}
+	svc, ok := compose.Services[name]
+	if !ok {
+		return nil, errors.New("service not generated: its conditions are not met")
+	}
+	svc.Labels = slices.DeleteFunc(slices.Clone(svc.Labels), func(label string) bool {
+		return slices.ContainsFunc(fixtureLabels, func(prefix string) bool { return strings.HasPrefix(label, prefix) })
+	})
+
+	data, err := yaml.Marshal(svc)
+	if err != nil {
+		return nil, err
+	}
+	var rendered map[string]any
+	if err := yaml.Unmarshal(data, &rendered); err != nil {
+		return nil, err
+	}
+	return rendered, nil
+}
+
+// updateFixture replaces the compose section of a fixture, keeping the rest
+// of the file and its comments
+func updateFixture(path string, data []byte, compose map[string]any) error {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return err
+	}
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return fmt.Errorf("%s: fixture is not a YAML mapping", path)
+	}
+	var value yaml.Node
+	if err := value.Encode(compose); err != nil {
+		return err
+	}
+
+	root := doc.Content[0]
+	replaced := false
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		if root.Content[i].Value == "compose" {
+			root.Content[i+1] = &value
+			replaced = true
+		}
+	}
+	if !replaced {
+		root.Content = append(root.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: "compose"}, &value)
+	}
+
+	out, err := fixtureYAML(&doc)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, out, 0o644)
+}
+
+// fixtureYAML encodes v with the two-space indent of service definitions
+func fixtureYAML(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,80 @@
+package generator
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/maiko/sdbx/internal/registry"
+)
+
+// TestEmbeddedFixtures runs the fixtures shipped with the embedded definitions
+func TestEmbeddedFixtures(t *testing.T) {
+	results, err := RunFixtures(context.Background(), "../registry/services", registry.CacheConfig{Directory: t.TempDir()}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) == 0 {
+		t.Fatal("no fixtures found")
+	}
+	for _, r := range results {
+		if !r.Passed() {
+			t.Errorf("%s: %s%s", r.Fixture, r.Error, r.Diff)
+		}
+	}
+}
+
+func TestRunFixtures(t *testing.T) {
+	def, err := os.ReadFile("testdata/golden/direct-vpn/services/addons/sonarr/service.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	source := t.TempDir()
+	dir := filepath.Join(source, "addons", "sonarr")
+	if err := os.MkdirAll(filepath.Join(dir, FixturesDir), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "service.yaml"), def, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	fixture := filepath.Join(dir, FixturesDir, "default.yaml")
+	content := "# Sonarr with the defaults\ndescription: Defaults\nconfig:\n  domain: example.com\ncompose:\n  container_name: sonarr\n"
+	if err := os.WriteFile(fixture, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cache := registry.CacheConfig{Directory: t.TempDir()}
+
+	// A service directory works as well as the source holding it
+	for _, path := range []string{source, dir} {
+		results, err := RunFixtures(context.Background(), path, cache, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(results) != 1 || results[0].Service != "sonarr" || results[0].Passed() {
+			t.Fatalf("RunFixtures(%s) = %+v, want a failure", path, results)
+		}
+		if !strings.Contains(results[0].Diff, "-container_name: sonarr\n+container_name: sdbx-sonarr") {
+			t.Errorf("Diff = %s", results[0].Diff)
+		}
+	}
+
+	results, err := RunFixtures(context.Background(), source, cache, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !results[0].Updated || !results[0].Passed() {
+		t.Fatalf("RunFixtures(update) = %+v", results)
+	}
+	updated, err := os.ReadFile(fixture)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(updated), "# Sonarr with the defaults\n") || !strings.Contains(string(updated), "container_name: sdbx-sonarr") {
+		t.Errorf("updated fixture:\n%s", updated)
+	}
+	if results, _ := RunFixtures(context.Background(), source, cache, false); !results[0].Passed() {
+		t.Errorf("updated fixture fails: %+v", results[0])
+	}
+}
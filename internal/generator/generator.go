@@ -2,17 +2,30 @@
 package generator
 
 import (
+	"bytes"
 	"context"
 	"embed"
+	"errors"
 	"fmt"
 	"log"
+	"maps"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync"
 	"text/template"
+	"time"
 
+	"gopkg.in/yaml.v3"
+
+	"github.com/maiko/sdbx/internal/auth"
+	"github.com/maiko/sdbx/internal/certs"
 	"github.com/maiko/sdbx/internal/config"
+	"github.com/maiko/sdbx/internal/mdns"
+	"github.com/maiko/sdbx/internal/qbittorrent"
 	"github.com/maiko/sdbx/internal/registry"
 	"github.com/maiko/sdbx/internal/secrets"
+	"github.com/maiko/sdbx/internal/statuspage"
 )
 
 //go:embed templates/*
@@ -23,6 +36,15 @@ type Generator struct {
 	Config    *config.Config
 	OutputDir string
 	Registry  *registry.Registry
+
+	// Skipped lists the services the last generation left out, with why
+	Skipped []registry.ResolutionError
+
+	tx        *transaction      // Files of the generation in progress
+	checksums map[string]string // Files of the previous generation, from the lock file
+	hostPorts map[string]int    // Host ports of the generation in progress
+
+	secretsHash string // Secret values of the generation in progress
 }
 
 // NewGenerator creates a new Generator with default registry
@@ -54,10 +76,87 @@ func NewGeneratorWithRegistry(cfg *config.Config, outputDir string, reg *registr
 type TemplateData struct {
 	Config  *config.Config
 	Secrets map[string]string
+
+	// Authelia rules of access_profiles, before the ones granting everything
+	AccessRules []AutheliaAccessRule
+
+	// Web UI password hash qBittorrent saved in its config, kept across generations
+	QBittorrentPassword string
 }
 
-// Generate creates all project files
+// Generate creates all project files, recording the project state so a
+// failed or interrupted generation is detected (see config.ReadState). The
+// files are written in one transaction: a failed generation leaves the
+// previous ones in place.
 func (g *Generator) Generate() error {
+	return g.GenerateContext(context.Background())
+}
+
+// GenerateContext is Generate with a context: canceling it stops the
+// resolution of services and leaves the previous files in place.
+func (g *Generator) GenerateContext(ctx context.Context) error {
+	if err := os.MkdirAll(g.OutputDir, 0o755); err != nil {
+		return fmt.Errorf("failed to create directory %s: %w", g.OutputDir, err)
+	}
+	// A crash during the previous swap is completed before anything else
+	if err := recoverGeneration(g.OutputDir); err != nil {
+		return err
+	}
+	if err := config.WriteState(g.OutputDir, config.StateGenerating, nil); err != nil {
+		return err
+	}
+
+	// Files are staged, then swapped into the project together
+	g.tx = newTransaction(g.OutputDir)
+	g.Skipped = nil
+	g.checksums = nil
+	g.hostPorts = nil
+	g.secretsHash = ""
+	err := g.generateSafely(ctx)
+	if err == nil {
+		// Nothing is committed once canceled, even when generation completed
+		err = ctx.Err()
+	}
+	if err == nil {
+		var j *journal
+		if j, err = g.tx.commit(); err == nil {
+			recordGeneration(g.OutputDir, j, g.tx.files, g.hostPorts, g.secretsHash)
+		}
+	}
+	g.tx = nil
+
+	state := config.StateReady
+	var skipped []config.SkippedService
+	if err != nil {
+		state = config.StateDegraded
+	} else {
+		for _, e := range g.Skipped {
+			reason := e.Message
+			if e.Cause != nil {
+				reason += ": " + e.Cause.Error()
+			}
+			skipped = append(skipped, config.SkippedService{Name: e.Service, Reason: reason, Kept: e.Kept})
+		}
+	}
+	if serr := config.WriteState(g.OutputDir, state, err, skipped...); serr != nil {
+		return errors.Join(err, serr)
+	}
+	return err
+}
+
+// generateSafely runs generate, returning a panic as an error so the
+// previous files stay in place and the project is marked degraded
+func (g *Generator) generateSafely(ctx context.Context) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("generation failed: %v", r)
+		}
+	}()
+	return g.generate(ctx)
+}
+
+// generate writes the project files
+func (g *Generator) generate(ctx context.Context) error {
 	// Create base directory structure for core infrastructure and templates.
 	// Additional service-specific dirs are created dynamically after resolution.
 	baseDirs := []string{
@@ -111,12 +210,13 @@ func (g *Generator) Generate() error {
 	}
 
 	data := TemplateData{
-		Config:  g.Config,
-		Secrets: secretsMap,
+		Config:              g.Config,
+		Secrets:             secretsMap,
+		QBittorrentPassword: qbittorrent.SavedPassword(g.OutputDir),
 	}
 
 	// Use registry-based generation
-	if err := g.generateFromRegistry(data); err != nil {
+	if err := g.generateFromRegistry(ctx, data); err != nil {
 		return err
 	}
 
@@ -124,9 +224,7 @@ func (g *Generator) Generate() error {
 }
 
 // generateFromRegistry uses the registry-based generators
-func (g *Generator) generateFromRegistry(data TemplateData) error {
-	ctx := context.Background()
-
+func (g *Generator) generateFromRegistry(ctx context.Context, data TemplateData) error {
 	// Ensure we have a registry
 	if g.Registry == nil {
 		var err error
@@ -142,6 +240,38 @@ func (g *Generator) generateFromRegistry(data TemplateData) error {
 		return fmt.Errorf("failed to resolve services: %w", err)
 	}
 
+	// Refuse to drop pinned services whose definition could not be read
+	if errs := registry.CheckPins(graph); len(errs) > 0 {
+		return fmt.Errorf("failed to resolve pinned services: %w\n\n  Try: check services.NAME.pin in .sdbx.yaml", errors.Join(errs...))
+	}
+
+	// Refuse to generate services this CLI may render wrong
+	if errs := registry.CheckCompatibility(graph); len(errs) > 0 {
+		if !registry.IgnoreCompat() {
+			return fmt.Errorf("service definitions require a newer sdbx: %w\n\n  Try: upgrade sdbx, or pass --ignore-compat", errors.Join(errs...))
+		}
+		for _, err := range errs {
+			log.Printf("Warning: %v (ignored with --ignore-compat)", err)
+		}
+	}
+
+	// Refuse to generate services that cannot run on the target platform
+	if errs := registry.CheckPlatforms(graph, g.Config); len(errs) > 0 {
+		return fmt.Errorf("unsupported platform %s: %w", g.Config.TargetPlatform(), errors.Join(errs...))
+	}
+
+	// Refuse to generate services that need the Linux host on a desktop runtime
+	if errs := registry.CheckRuntime(graph, g.Config); len(errs) > 0 {
+		return fmt.Errorf("unsupported on %s: %w\n\n  Try: set runtime: engine in .sdbx.yaml when generating for the seedbox", config.RuntimeName(g.Config.TargetRuntime()), errors.Join(errs...))
+	}
+
+	// Generate the typed secrets declared by resolved services
+	ensured, err := secrets.EnsureSecrets(filepath.Join(g.OutputDir, "secrets"), append(graph.SecretSpecs(), registry.ConfigSecretSpecs(g.Config)...))
+	if err != nil {
+		return fmt.Errorf("failed to generate secrets: %w", err)
+	}
+	maps.Copy(data.Secrets, ensured.Values)
+
 	// Create config directories for all resolved services
 	for name := range graph.Services {
 		configDir := filepath.Join(g.OutputDir, "configs", name)
@@ -152,30 +282,57 @@ func (g *Generator) generateFromRegistry(data TemplateData) error {
 
 	// Generate compose.yaml using ComposeGenerator
 	composeGen := NewComposeGenerator(g.Config, g.Registry, data.Secrets)
+	composePath := filepath.Join(g.OutputDir, "compose.yaml")
+	g.secretsHash = registry.SecretsHash(data.Secrets)
+	composeGen.Previous, composeGen.Unchanged = g.loadPreviousGeneration(composePath, graph)
+	composeGen.HostPorts = g.lockedHostPorts()
 	composeFile, err := composeGen.Generate(graph)
 	if err != nil {
 		return fmt.Errorf("failed to generate compose file: %w", err)
 	}
+	g.hostPorts = composeGen.HostPorts
+	// Services that failed to resolve or generate are left out of every file
+	// but compose.yaml, which keeps their previous block when there is one
+	g.Skipped = graph.Skipped()
+	for i := range g.Skipped {
+		g.Skipped[i].Kept = composeGen.Kept[g.Skipped[i].Service]
+	}
 
 	composeYAML, err := composeFile.ToYAML()
 	if err != nil {
 		return fmt.Errorf("failed to serialize compose file: %w", err)
 	}
 
-	composePath := filepath.Join(g.OutputDir, "compose.yaml")
-	if err := os.WriteFile(composePath, composeYAML, 0o644); err != nil {
+	if err := g.writeFile(composePath, composeYAML, 0o644); err != nil {
 		return fmt.Errorf("failed to write compose.yaml: %w", err)
 	}
 
+	// Secrets delivered through env_file; stale files of resolved services are removed
+	for name := range graph.Services {
+		envPath := filepath.Join(g.OutputDir, "secrets", name+".env")
+		content, ok := composeGen.EnvFiles[name]
+		if !ok {
+			if err := g.removeFile(envPath); err != nil {
+				return fmt.Errorf("failed to remove %s: %w", envPath, err)
+			}
+			continue
+		}
+		if err := g.writeFile(envPath, content, 0o600); err != nil {
+			return fmt.Errorf("failed to write secrets for %s: %w", name, err)
+		}
+	}
+
 	// Generate integration configs
 	intGen := NewIntegrationsGenerator(g.Config, data.Secrets)
+	intGen.HostPorts = composeGen.HostPorts
+	data.AccessRules = intGen.GenerateAutheliaProfileRules(graph)
 
 	// Homepage services
 	homepageServices, err := intGen.GenerateHomepageServices(graph)
 	if err != nil {
 		return fmt.Errorf("failed to generate homepage services: %w", err)
 	}
-	if err := os.WriteFile(filepath.Join(g.OutputDir, "configs/homepage/services.yaml"), homepageServices, 0o644); err != nil {
+	if err := g.writeManaged(filepath.Join(g.OutputDir, "configs/homepage/services.yaml"), homepageServices, 0o644); err != nil {
 		return fmt.Errorf("failed to write homepage services: %w", err)
 	}
 
@@ -184,7 +341,7 @@ func (g *Generator) generateFromRegistry(data TemplateData) error {
 	if err != nil {
 		return fmt.Errorf("failed to generate traefik dynamic: %w", err)
 	}
-	if err := os.WriteFile(filepath.Join(g.OutputDir, "configs/traefik/dynamic/middlewares.yml"), traefikDynamic, 0o644); err != nil {
+	if err := g.writeManaged(filepath.Join(g.OutputDir, "configs/traefik/dynamic/middlewares.yml"), traefikDynamic, 0o644); err != nil {
 		return fmt.Errorf("failed to write traefik middlewares: %w", err)
 	}
 
@@ -194,19 +351,185 @@ func (g *Generator) generateFromRegistry(data TemplateData) error {
 		if err != nil {
 			return fmt.Errorf("failed to generate cloudflared config: %w", err)
 		}
-		if err := os.WriteFile(filepath.Join(g.OutputDir, "configs/cloudflared/config.yml"), cloudflaredConfig, 0o644); err != nil {
+		if err := g.writeFile(filepath.Join(g.OutputDir, "configs/cloudflared/config.yml"), cloudflaredConfig, 0o644); err != nil {
 			return fmt.Errorf("failed to write cloudflared config: %w", err)
 		}
 	}
 
+	// Static sites, custom error pages and maintenance pages (remove stale routers when unconfigured)
+	staticSitesPath := filepath.Join(g.OutputDir, "configs/traefik/dynamic/static-sites.yml")
+	if g.Config.NeedsStaticServer() {
+		staticSites, err := intGen.GenerateTraefikStaticSites(graph)
+		if err != nil {
+			return fmt.Errorf("failed to generate traefik static sites: %w", err)
+		}
+		if err := g.writeFile(staticSitesPath, staticSites, 0o644); err != nil {
+			return fmt.Errorf("failed to write traefik static sites: %w", err)
+		}
+		if err := os.MkdirAll(filepath.Join(g.OutputDir, "configs/static/maintenance"), 0o755); err != nil {
+			return fmt.Errorf("failed to create static server config directory: %w", err)
+		}
+		if err := g.writeFile(filepath.Join(g.OutputDir, "configs/static/default.conf"), intGen.GenerateStaticServerConfig(), 0o644); err != nil {
+			return fmt.Errorf("failed to write static server config: %w", err)
+		}
+		if err := g.generateFile("maintenance.html.tmpl", "configs/static/maintenance/index.html", data); err != nil {
+			return fmt.Errorf("failed to generate maintenance page: %w", err)
+		}
+	} else if err := g.removeFile(staticSitesPath); err != nil {
+		return fmt.Errorf("failed to remove stale traefik static sites: %w", err)
+	}
+
+	// Routers of the active share links (remove stale routers when there are none)
+	sharesPath := filepath.Join(g.OutputDir, "configs/traefik/dynamic/shares.yml")
+	shares, err := auth.NewShareStore(g.OutputDir).Active(time.Now())
+	if err != nil {
+		return err
+	}
+	if len(shares) > 0 {
+		sharesConfig, err := intGen.GenerateTraefikShares(graph, shares)
+		if err != nil {
+			return fmt.Errorf("failed to generate traefik share routers: %w", err)
+		}
+		if err := g.writeFile(sharesPath, sharesConfig, 0o644); err != nil {
+			return fmt.Errorf("failed to write traefik share routers: %w", err)
+		}
+	} else if err := g.removeFile(sharesPath); err != nil {
+		return fmt.Errorf("failed to remove stale traefik share routers: %w", err)
+	}
+
+	// Certificates of the custom TLS provider
+	tlsPath := filepath.Join(g.OutputDir, traefikTLSPath)
+	if g.Config.Expose.Mode == config.ExposeModeDirect && g.Config.Expose.TLS.Provider == config.TLSProviderCustom {
+		tlsConfig, err := intGen.GenerateTraefikTLS()
+		if err != nil {
+			return fmt.Errorf("failed to generate traefik certificates: %w", err)
+		}
+		if err := g.writeFile(tlsPath, tlsConfig, 0o644); err != nil {
+			return fmt.Errorf("failed to write traefik certificates: %w", err)
+		}
+	} else if err := g.removeFile(tlsPath); err != nil {
+		return fmt.Errorf("failed to remove stale traefik certificates: %w", err)
+	}
+
+	// Services shown on the status page, rendered by the statuspage job
+	statusServicesPath := filepath.Join(g.OutputDir, statuspage.ServicesFile)
+	if g.Config.StatusPage.Enabled {
+		services, err := intGen.GenerateStatusPageServices(graph)
+		if err != nil {
+			return fmt.Errorf("failed to generate status page services: %w", err)
+		}
+		if err := os.MkdirAll(filepath.Dir(statusServicesPath), 0o755); err != nil {
+			return fmt.Errorf("failed to create status page config directory: %w", err)
+		}
+		if err := g.writeFile(statusServicesPath, services, 0o644); err != nil {
+			return fmt.Errorf("failed to write status page services: %w", err)
+		}
+		if err := os.MkdirAll(filepath.Join(g.OutputDir, statuspage.Dir), 0o755); err != nil {
+			return fmt.Errorf("failed to create status page directory: %w", err)
+		}
+	} else if err := g.removeFile(statusServicesPath); err != nil {
+		return fmt.Errorf("failed to remove stale status page services: %w", err)
+	}
+
+	// .local names answered by the mDNS responder
+	mdnsNamesPath := filepath.Join(g.OutputDir, mdns.NamesFile)
+	if g.Config.MDNS.IsEnabled() {
+		if err := os.MkdirAll(filepath.Dir(mdnsNamesPath), 0o755); err != nil {
+			return fmt.Errorf("failed to create mDNS config directory: %w", err)
+		}
+		if err := g.writeFile(mdnsNamesPath, mdnsNames(composeFile), 0o644); err != nil {
+			return fmt.Errorf("failed to write mDNS names: %w", err)
+		}
+	} else if err := g.removeFile(mdnsNamesPath); err != nil {
+		return fmt.Errorf("failed to remove stale mDNS names: %w", err)
+	}
+
+	// Traefik access log directory and rotation config (if enabled)
+	if g.Config.Traefik.AccessLog.Enabled {
+		logDir := g.Config.Traefik.AccessLog.Path
+		if !filepath.IsAbs(logDir) {
+			logDir = filepath.Join(g.OutputDir, logDir)
+		}
+		if absDir, err := filepath.Abs(logDir); err == nil {
+			logDir = absDir
+		}
+		if err := os.MkdirAll(logDir, 0o755); err != nil {
+			return fmt.Errorf("failed to create traefik access log directory: %w", err)
+		}
+		if err := g.writeFile(filepath.Join(g.OutputDir, "configs/traefik/logrotate.conf"), intGen.GenerateTraefikLogrotate(logDir), 0o644); err != nil {
+			return fmt.Errorf("failed to write traefik logrotate config: %w", err)
+		}
+	}
+
+	// Traefik's ACME storage is created empty with the permissions Traefik
+	// requires, so it is bind mounted as a file and keeps the certificates
+	// across container recreation. Traefik owns its content.
+	if g.Config.Expose.Mode == config.ExposeModeDirect && g.Config.Expose.TLS.UsesACME() {
+		acmePath := filepath.Join(g.OutputDir, certs.ACMEFile)
+		if _, err := os.Stat(acmePath); os.IsNotExist(err) {
+			if err := os.WriteFile(acmePath, nil, 0o600); err != nil {
+				return fmt.Errorf("failed to create %s: %w", certs.ACMEFile, err)
+			}
+		}
+	}
+
+	// Log shipping agent config (remove the stale one when the agent changes or aggregation is off)
+	for agent, path := range logShippingConfigPaths {
+		path = filepath.Join(g.OutputDir, path)
+		if agg := g.Config.Logging.Aggregation; agg == nil || agg.Agent != agent {
+			if err := g.removeFile(path); err != nil {
+				return fmt.Errorf("failed to remove stale %s config: %w", agent, err)
+			}
+			continue
+		}
+		if err := os.MkdirAll(filepath.Join(filepath.Dir(path), "data"), 0o755); err != nil {
+			return fmt.Errorf("failed to create %s data directory: %w", agent, err)
+		}
+		if err := g.writeFile(path, intGen.GenerateLogShippingConfig(), 0o644); err != nil {
+			return fmt.Errorf("failed to write %s config: %w", agent, err)
+		}
+	}
+
+	// AdGuard Home config with the rewrites of dns.server
+	if g.Config.DNS.Server == config.DNSServerAdGuard {
+		path := filepath.Join(g.OutputDir, adGuardConfigPath)
+		existing, err := os.ReadFile(path)
+		if err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to read AdGuard Home config: %w", err)
+		}
+		adGuardConfig, err := intGen.GenerateAdGuardConfig(existing)
+		if err != nil {
+			return err
+		}
+		if err := g.writeFile(path, adGuardConfig, 0o644); err != nil {
+			return fmt.Errorf("failed to write AdGuard Home config: %w", err)
+		}
+	}
+
+	// Storage mounts: rclone config directory and systemd units for mounts on the host
+	if err := g.generateStorage(intGen); err != nil {
+		return err
+	}
+
 	// .env file
 	envContent, err := intGen.GenerateEnvFile(graph)
 	if err != nil {
 		return fmt.Errorf("failed to generate .env: %w", err)
 	}
-	if err := os.WriteFile(filepath.Join(g.OutputDir, ".env"), envContent, 0o644); err != nil {
+	envPerm := os.FileMode(0o644)
+	if hasVolumeCredentials(composeGen.volumes) {
+		envPerm = 0o600
+	}
+	envPath := filepath.Join(g.OutputDir, ".env")
+	if err := g.writeFile(envPath, envContent, envPerm); err != nil {
 		return fmt.Errorf("failed to write .env: %w", err)
 	}
+	// An unchanged .env keeps its mode unless set
+	if g.tx != nil {
+		g.tx.chmod(envPath, envPerm)
+	} else if err := os.Chmod(envPath, envPerm); err != nil {
+		return fmt.Errorf("failed to set .env permissions: %w", err)
+	}
 
 	// Static config files still use templates
 	staticFiles := []struct {
@@ -226,6 +549,16 @@ func (g *Generator) generateFromRegistry(data TemplateData) error {
 	}
 
 	for _, f := range staticFiles {
+		// Authelia is not deployed in basic auth mode; its files are kept for switching back
+		if strings.HasPrefix(f.template, "authelia-") && g.Config.IsBasicAuth() {
+			continue
+		}
+		// The admin password hash is only known during init; keep the existing users file
+		if f.template == "authelia-users.yml.tmpl" && g.Config.AdminPasswordHash == "" {
+			if _, err := os.Stat(filepath.Join(g.OutputDir, f.output)); err == nil {
+				continue
+			}
+		}
 		if err := g.generateFile(f.template, f.output, data); err != nil {
 			return fmt.Errorf("failed to generate %s: %w", f.output, err)
 		}
@@ -236,32 +569,135 @@ func (g *Generator) generateFromRegistry(data TemplateData) error {
 
 // generateFile renders a template to a file
 func (g *Generator) generateFile(templateName, outputPath string, data TemplateData) error {
-	// Read template
+	tmpl, err := staticTemplate(templateName)
+	if err != nil {
+		return err
+	}
+
+	// Execute template
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return fmt.Errorf("failed to execute template: %w", err)
+	}
+
+	if err := g.writeManaged(filepath.Join(g.OutputDir, outputPath), buf.Bytes(), 0o644); err != nil {
+		return fmt.Errorf("failed to create file: %w", err)
+	}
+
+	return nil
+}
+
+// staticTemplates caches the parsed embedded templates by name; they never
+// change, so they are parsed once per process
+var staticTemplates sync.Map
+
+// staticTemplate returns the parsed embedded template of that name
+func staticTemplate(templateName string) (*template.Template, error) {
+	if t, ok := staticTemplates.Load(templateName); ok {
+		return t.(*template.Template), nil
+	}
+
 	tmplContent, err := TemplatesFS.ReadFile("templates/" + templateName)
 	if err != nil {
-		return fmt.Errorf("template not found: %s: %w", templateName, err)
+		return nil, fmt.Errorf("template not found: %s: %w", templateName, err)
 	}
+	tmpl, err := template.New(templateName).Funcs(staticTemplateFuncs).Parse(string(tmplContent))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse template: %w", err)
+	}
+	staticTemplates.Store(templateName, tmpl)
+	return tmpl, nil
+}
 
-	// Parse template
-	tmpl, err := template.New(templateName).Parse(string(tmplContent))
+// staticTemplateFuncs are the helpers available to static file templates
+var staticTemplateFuncs = template.FuncMap{
+	"yamlBlock":     yamlBlock,
+	"schemaVersion": func() int { return config.SchemaVersion },
+}
+
+// yamlBlock renders v as YAML indented by the given number of spaces
+func yamlBlock(indent int, v interface{}) (string, error) {
+	data, err := yaml.Marshal(v)
 	if err != nil {
-		return fmt.Errorf("failed to parse template: %w", err)
+		return "", err
+	}
+	pad := strings.Repeat(" ", indent)
+	lines := strings.Split(strings.TrimRight(string(data), "\n"), "\n")
+	for i, line := range lines {
+		lines[i] = pad + line
 	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// writeFileIfChanged writes data to path unless the file already has exactly
+// that content, so unchanged artifacts keep their mtime and produce no diff
+func writeFileIfChanged(path string, data []byte, perm os.FileMode) error {
+	if existing, err := os.ReadFile(path); err == nil && bytes.Equal(existing, data) {
+		return nil
+	}
+	return writeFileAtomic(path, data, perm)
+}
+
+// writeFile writes a project file as part of the generation transaction
+func (g *Generator) writeFile(path string, data []byte, perm os.FileMode) error {
+	if g.tx == nil {
+		return writeFileIfChanged(path, data, perm)
+	}
+	return g.tx.write(path, data, perm)
+}
+
+// removeFile removes a stale project file as part of the generation
+// transaction. A missing file is not an error.
+func (g *Generator) removeFile(path string) error {
+	if g.tx == nil {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	return g.tx.remove(path)
+}
 
-	// Create output file
-	outPath := filepath.Join(g.OutputDir, outputPath)
-	f, err := os.Create(outPath)
+// loadPreviousGeneration returns the existing compose file and the services
+// whose inputs match the lock file, enabling incremental regeneration.
+// Everything is regenerated without a lock file or previous compose.yaml, and
+// after an sdbx upgrade or a change of secret values.
+func (g *Generator) loadPreviousGeneration(composePath string, graph *registry.ResolutionGraph) (*ComposeFile, map[string]bool) {
+	if !registry.LockFileExists(g.OutputDir) {
+		return nil, nil
+	}
+	lock, err := registry.NewLoader().LoadLockFile(registry.GetLockFilePath(g.OutputDir))
 	if err != nil {
-		return fmt.Errorf("failed to create file: %w", err)
+		return nil, nil
+	}
+	configHash, err := registry.ConfigHash(g.Config)
+	if err != nil {
+		return nil, nil
 	}
-	defer f.Close()
 
-	// Execute template
-	if err := tmpl.Execute(f, data); err != nil {
-		return fmt.Errorf("failed to execute template: %w", err)
+	data, err := os.ReadFile(composePath)
+	if err != nil {
+		return nil, nil
+	}
+	previous, err := ParseComposeFile(data)
+	if err != nil {
+		return nil, nil
 	}
 
-	return nil
+	return previous, lock.UnchangedServices(graph, configHash, registry.CLIVersion(), g.secretsHash)
+}
+
+// lockedHostPorts returns the host ports recorded by the last generation,
+// so allocated ports do not move
+func (g *Generator) lockedHostPorts() map[string]int {
+	if !registry.LockFileExists(g.OutputDir) {
+		return nil
+	}
+	lock, err := registry.NewLoader().LoadLockFile(registry.GetLockFilePath(g.OutputDir))
+	if err != nil {
+		return nil
+	}
+	return lock.HostPorts
 }
 
 // CreateDataDirs creates the data directory structure
@@ -285,6 +721,11 @@ func (g *Generator) CreateDataDirs() error {
 			log.Printf("Warning: could not set permissions on %s: %v", dir, err)
 		}
 
+		// Desktop runtimes map bind mount ownership to the desktop user
+		if g.Config.DesktopRuntime() {
+			continue
+		}
+
 		// Fix ownership to PUID:PGID (safe - only changes metadata)
 		if err := os.Chown(dir, g.Config.PUID, g.Config.PGID); err != nil {
 			// Non-fatal if running without sudo - warn but continue
@@ -296,3 +737,49 @@ func (g *Generator) CreateDataDirs() error {
 
 	return nil
 }
+
+// generateStorage prepares the rclone config directory and writes the systemd
+// units of storage mounts running on the host, removing units no longer needed
+func (g *Generator) generateStorage(intGen *IntegrationsGenerator) error {
+	storage := g.Config.Storage
+	units := map[string][]byte{}
+
+	if r := storage.Rclone; r != nil {
+		configFile := r.ConfigFile()
+		if !filepath.IsAbs(configFile) {
+			configFile = filepath.Join(g.OutputDir, configFile)
+		}
+		if absFile, err := filepath.Abs(configFile); err == nil {
+			configFile = absFile
+		}
+		if err := os.MkdirAll(filepath.Dir(configFile), 0o755); err != nil {
+			return fmt.Errorf("failed to create rclone config directory: %w", err)
+		}
+		if r.RcloneMode() == config.RcloneModeSystemd {
+			units[rcloneUnitPath] = intGen.GenerateRcloneUnit(configFile)
+		} else if err := os.MkdirAll(filepath.Join(g.OutputDir, "data/rclone-cache"), 0o755); err != nil {
+			return fmt.Errorf("failed to create rclone cache directory: %w", err)
+		}
+	}
+	if storage.Mergerfs != nil {
+		units[mergerfsUnitPath] = intGen.GenerateMergerfsUnit()
+	}
+
+	for _, path := range []string{rcloneUnitPath, mergerfsUnitPath} {
+		content, ok := units[path]
+		path = filepath.Join(g.OutputDir, path)
+		if !ok {
+			if err := g.removeFile(path); err != nil {
+				return fmt.Errorf("failed to remove stale %s: %w", filepath.Base(path), err)
+			}
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return fmt.Errorf("failed to create systemd unit directory: %w", err)
+		}
+		if err := g.writeFile(path, content, 0o644); err != nil {
+			return fmt.Errorf("failed to write %s: %w", filepath.Base(path), err)
+		}
+	}
+	return nil
+}
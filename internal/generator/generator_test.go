@@ -3,9 +3,15 @@ package generator
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
+	"gopkg.in/yaml.v3"
+
+	"github.com/maiko/sdbx/internal/certs"
 	"github.com/maiko/sdbx/internal/config"
+	"github.com/maiko/sdbx/internal/mdns"
 )
 
 func TestNewGenerator(t *testing.T) {
@@ -22,6 +28,36 @@ func TestNewGenerator(t *testing.T) {
 	}
 }
 
+func TestGenerateRecordsState(t *testing.T) {
+	tmpDir := t.TempDir()
+	gen := NewGenerator(config.DefaultConfig(), tmpDir)
+
+	if err := gen.Generate(); err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	if state, err := config.ReadState(tmpDir); err != nil || state.State != config.StateReady {
+		t.Errorf("state after Generate = %+v, %v; want ready", state, err)
+	}
+
+	// A file where a directory belongs fails generation midway
+	if err := os.RemoveAll(filepath.Join(tmpDir, "configs/homepage")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(tmpDir, "configs/homepage"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := gen.Generate(); err == nil {
+		t.Fatal("Generate succeeded, want failure")
+	}
+	state, err := config.ReadState(tmpDir)
+	if err != nil {
+		t.Fatalf("ReadState failed: %v", err)
+	}
+	if state.State != config.StateDegraded || !strings.Contains(state.Error, "configs/homepage") {
+		t.Errorf("state after failed Generate = %+v, want degraded with the error", state)
+	}
+}
+
 func TestGenerateDirectoryStructure(t *testing.T) {
 	// Create temp directory for test
 	tmpDir, err := os.MkdirTemp("", "sdbx-gen-test-*")
@@ -223,6 +259,27 @@ func TestCreateDataDirs(t *testing.T) {
 	}
 }
 
+func TestGenerateRecordsSkippedServices(t *testing.T) {
+	tmpDir := t.TempDir()
+	cfg := config.DefaultConfig()
+	cfg.Addons = []string{"no-such-addon"}
+	gen := NewGenerator(cfg, tmpDir)
+
+	if err := gen.Generate(); err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	if len(gen.Skipped) != 1 || gen.Skipped[0].Service != "no-such-addon" {
+		t.Fatalf("Skipped = %v, want no-such-addon", gen.Skipped)
+	}
+	state, err := config.ReadState(tmpDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state.State != config.StateReady || len(state.Skipped) != 1 || !strings.Contains(state.Skipped[0].Reason, "not found") {
+		t.Errorf("state = %+v, want ready with the skipped addon", state)
+	}
+}
+
 func TestGenerateWithAddons(t *testing.T) {
 	// Create temp directory for test
 	tmpDir, err := os.MkdirTemp("", "sdbx-gen-test-*")
@@ -292,3 +349,302 @@ func TestGenerateFileContent(t *testing.T) {
 		t.Error("compose.yaml file should not be empty")
 	}
 }
+
+func TestWriteFileIfChanged(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "file.txt")
+
+	if err := writeFileIfChanged(path, []byte("one"), 0o644); err != nil {
+		t.Fatalf("initial write failed: %v", err)
+	}
+
+	// Backdate the file so a rewrite would be visible in the mtime
+	old := time.Now().Add(-time.Hour).Truncate(time.Second)
+	if err := os.Chtimes(path, old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := writeFileIfChanged(path, []byte("one"), 0o644); err != nil {
+		t.Fatalf("identical write failed: %v", err)
+	}
+	info, _ := os.Stat(path)
+	if !info.ModTime().Equal(old) {
+		t.Error("identical content should not rewrite the file")
+	}
+
+	if err := writeFileIfChanged(path, []byte("two"), 0o644); err != nil {
+		t.Fatalf("changed write failed: %v", err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "two" {
+		t.Errorf("content = %q, want two", data)
+	}
+}
+
+func TestGenerateComposeExtraAndExtraServices(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	cfg := config.DefaultConfig()
+	cfg.Services = map[string]config.ServiceOverride{
+		"qbittorrent": {ComposeExtra: map[string]interface{}{
+			"ulimits": map[string]interface{}{"nofile": 65536},
+		}},
+	}
+	cfg.ExtraServices = []config.ExtraServiceConfig{
+		{Name: "kuma", Image: "louislam/uptime-kuma:1", Port: 3001, Environment: []string{"TZ=UTC"}},
+	}
+	gen := NewGenerator(cfg, tmpDir)
+
+	if err := gen.Generate(); err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+
+	compose, err := os.ReadFile(filepath.Join(tmpDir, "compose.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"nofile: 65536", "louislam/uptime-kuma:1", "sdbx-kuma"} {
+		if !strings.Contains(string(compose), want) {
+			t.Errorf("compose.yaml missing %q", want)
+		}
+	}
+
+	// The rendered .sdbx.yaml must keep both sections across regenerations
+	data, err := os.ReadFile(filepath.Join(tmpDir, ".sdbx.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var saved struct {
+		Services map[string]struct {
+			ComposeExtra map[string]map[string]int `yaml:"compose_extra"`
+		} `yaml:"services"`
+		ExtraServices []config.ExtraServiceConfig `yaml:"extra_services"`
+	}
+	if err := yaml.Unmarshal(data, &saved); err != nil {
+		t.Fatalf("invalid .sdbx.yaml: %v\n%s", err, data)
+	}
+	if saved.Services["qbittorrent"].ComposeExtra["ulimits"]["nofile"] != 65536 {
+		t.Errorf("compose_extra not preserved:\n%s", data)
+	}
+	if len(saved.ExtraServices) != 1 || saved.ExtraServices[0].Environment[0] != "TZ=UTC" {
+		t.Errorf("extra_services not preserved:\n%s", data)
+	}
+}
+
+func TestGeneratePlatform(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Platform = "linux/arm/v7"
+
+	if err := NewGenerator(cfg, t.TempDir()).Generate(); err == nil || !strings.Contains(err.Error(), "plex") {
+		t.Fatalf("expected plex to be rejected on linux/arm/v7, got %v", err)
+	}
+
+	cfg.Services = map[string]config.ServiceOverride{
+		"plex":       {Platform: "linux/arm64"},
+		"sdbx-webui": {Platform: "linux/arm64"},
+	}
+	tmpDir := t.TempDir()
+	if err := NewGenerator(cfg, tmpDir).Generate(); err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+
+	compose, err := os.ReadFile(filepath.Join(tmpDir, "compose.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(compose), "platform: linux/arm64") {
+		t.Errorf("compose.yaml missing platform override:\n%s", compose)
+	}
+
+	sdbxYAML, err := os.ReadFile(filepath.Join(tmpDir, ".sdbx.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"platform: linux/arm/v7", "platform: linux/arm64"} {
+		if !strings.Contains(string(sdbxYAML), want) {
+			t.Errorf(".sdbx.yaml missing %q", want)
+		}
+	}
+}
+
+func TestGeneratePresetSettingsPreserved(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	cfg := config.DefaultConfig()
+	cfg.HardwareTranscode = true
+	cfg.Services = map[string]config.ServiceOverride{
+		"plex": {Resources: &config.ResourceLimits{CPUs: "2.0", Memory: "1g"}},
+	}
+	if err := NewGenerator(cfg, tmpDir).Generate(); err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+
+	compose, err := os.ReadFile(filepath.Join(tmpDir, "compose.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"/dev/dri:/dev/dri", "memory: 1g"} {
+		if !strings.Contains(string(compose), want) {
+			t.Errorf("compose.yaml missing %q", want)
+		}
+	}
+
+	data, err := os.ReadFile(filepath.Join(tmpDir, ".sdbx.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var saved struct {
+		HardwareTranscode bool `yaml:"hardware_transcode"`
+		Services          map[string]struct {
+			Resources config.ResourceLimits `yaml:"resources"`
+		} `yaml:"services"`
+	}
+	if err := yaml.Unmarshal(data, &saved); err != nil {
+		t.Fatalf("invalid .sdbx.yaml: %v\n%s", err, data)
+	}
+	if !saved.HardwareTranscode || saved.Services["plex"].Resources.CPUs != "2.0" {
+		t.Errorf("preset settings not preserved:\n%s", data)
+	}
+}
+
+func TestGenerateKeepsQBittorrentPassword(t *testing.T) {
+	tmpDir := t.TempDir()
+	gen := NewGenerator(config.DefaultConfig(), tmpDir)
+	if err := gen.Generate(); err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+
+	// qBittorrent saves the hash once a Web UI password is set
+	confPath := filepath.Join(tmpDir, "configs", "qbittorrent", "qBittorrent", "qBittorrent.conf")
+	conf, err := os.ReadFile(confPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const line = `WebUI\Password_PBKDF2="@ByteArray(c2FsdA==:aGFzaA==)"`
+	if strings.Contains(string(conf), "Password_PBKDF2") {
+		t.Fatal("qBittorrent.conf has a password before one is set")
+	}
+	if err := os.WriteFile(confPath, append(conf, line+"\n"...), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := gen.Generate(); err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	conf, err = os.ReadFile(confPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Count(string(conf), line) != 1 {
+		t.Errorf("qBittorrent.conf lost the Web UI password:\n%s", conf)
+	}
+}
+
+func TestGenerateKeepsNetworkSettings(t *testing.T) {
+	tmpDir := t.TempDir()
+	cfg := config.DefaultConfig()
+	cfg.HostPorts.Range = "20000-20999"
+	cfg.Networks = map[string]config.NetworkConfig{"proxy": {Subnet: "172.30.0.0/24"}}
+	cfg.DNS = config.DNSConfig{Server: config.DNSServerAdGuard, Address: "192.168.1.10"}
+	cfg.Expose.Mode = config.ExposeModeLAN
+	cfg.MDNS = config.MDNSConfig{Enabled: true}
+	if err := NewGenerator(cfg, tmpDir).Generate(); err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(tmpDir, ".sdbx.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var saved struct {
+		HostPorts config.HostPortsConfig          `yaml:"host_ports"`
+		Networks  map[string]config.NetworkConfig `yaml:"networks"`
+		DNS       config.DNSConfig                `yaml:"dns"`
+		MDNS      config.MDNSConfig               `yaml:"mdns"`
+	}
+	if err := yaml.Unmarshal(data, &saved); err != nil {
+		t.Fatalf("invalid .sdbx.yaml: %v\n%s", err, data)
+	}
+	if saved.HostPorts.Range != "20000-20999" {
+		t.Errorf("host_ports not preserved:\n%s", data)
+	}
+	if saved.Networks["proxy"].Subnet != "172.30.0.0/24" {
+		t.Errorf("networks not preserved:\n%s", data)
+	}
+	if saved.DNS.Server != config.DNSServerAdGuard || saved.DNS.Address != "192.168.1.10" {
+		t.Errorf("dns not preserved:\n%s", data)
+	}
+	if _, err := os.Stat(filepath.Join(tmpDir, adGuardConfigPath)); err != nil {
+		t.Errorf("AdGuard Home config not generated: %v", err)
+	}
+	if !saved.MDNS.Enabled {
+		t.Errorf("mdns not preserved:\n%s", data)
+	}
+	if names, err := os.ReadFile(filepath.Join(tmpDir, mdns.NamesFile)); err != nil || !strings.Contains(string(names), "dns.local\n") {
+		t.Errorf("mDNS names = %q, %v, want the routed .local names", names, err)
+	}
+}
+
+func TestGenerateKeepsServerLogs(t *testing.T) {
+	tmpDir := t.TempDir()
+	cfg := config.DefaultConfig()
+	cfg.ServerLogs = config.ServerLogsConfig{MaxSize: 20, MaxFiles: 3, MaxAge: 30}
+	if err := NewGenerator(cfg, tmpDir).Generate(); err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(tmpDir, ".sdbx.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var saved struct {
+		ServerLogs config.ServerLogsConfig `yaml:"server_logs"`
+	}
+	if err := yaml.Unmarshal(data, &saved); err != nil {
+		t.Fatalf("invalid .sdbx.yaml: %v\n%s", err, data)
+	}
+	if saved.ServerLogs != cfg.ServerLogs {
+		t.Errorf("server_logs = %+v, want %+v:\n%s", saved.ServerLogs, cfg.ServerLogs, data)
+	}
+}
+
+func TestGenerateKeepsCustomCertificates(t *testing.T) {
+	tmpDir := t.TempDir()
+	cfg := config.DefaultConfig()
+	cfg.Expose.Mode = config.ExposeModeDirect
+	cfg.Expose.TLS = config.TLSConfig{
+		Provider:     config.TLSProviderCustom,
+		CertFile:     "/etc/step/certs/wildcard.crt",
+		KeyFile:      "/etc/step/certs/wildcard.key",
+		Certificates: []config.TLSCertificate{{CertFile: "certs/plex.crt", KeyFile: "certs/plex.key"}},
+		CABundle:     "/etc/step/certs/root_ca.crt",
+	}
+	if err := NewGenerator(cfg, tmpDir).Generate(); err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(tmpDir, ".sdbx.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var saved struct {
+		Expose struct {
+			TLS struct {
+				Provider     string                  `yaml:"provider"`
+				CertFile     string                  `yaml:"cert_file"`
+				KeyFile      string                  `yaml:"key_file"`
+				Certificates []config.TLSCertificate `yaml:"certificates"`
+				CABundle     string                  `yaml:"ca_bundle"`
+			} `yaml:"tls"`
+		} `yaml:"expose"`
+	}
+	if err := yaml.Unmarshal(data, &saved); err != nil {
+		t.Fatalf("invalid .sdbx.yaml: %v\n%s", err, data)
+	}
+	tls := saved.Expose.TLS
+	if tls.Provider != config.TLSProviderCustom || tls.CertFile != cfg.Expose.TLS.CertFile || tls.KeyFile != cfg.Expose.TLS.KeyFile ||
+		len(tls.Certificates) != 1 || tls.Certificates[0] != cfg.Expose.TLS.Certificates[0] || tls.CABundle != cfg.Expose.TLS.CABundle {
+		t.Errorf("custom certificates not preserved:\n%s", data)
+	}
+	if _, err := os.Stat(filepath.Join(tmpDir, certs.ACMEFile)); !os.IsNotExist(err) {
+		t.Errorf("acme.json created for the custom provider: %v", err)
+	}
+}
@@ -0,0 +1,157 @@
+package generator
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/maiko/sdbx/internal/config"
+	"github.com/maiko/sdbx/internal/registry"
+)
+
+// updateGolden rewrites the golden outputs instead of comparing them:
+//
+//	UPDATE_GOLDEN=1 go test ./internal/generator -run TestGolden
+var updateGolden = os.Getenv("UPDATE_GOLDEN") != ""
+
+// goldenDir holds one project fixture per directory (see its README.md)
+const goldenDir = "testdata/golden"
+
+// goldenFiles are the generated files compared with a fixture's want/ tree.
+// A file a fixture does not generate must be absent from want/.
+var goldenFiles = []string{
+	".env",
+	"compose.yaml",
+	"configs/traefik/traefik.yml",
+	"configs/traefik/dynamic/middlewares.yml",
+	"configs/traefik/dynamic/tls.yml",
+	"configs/authelia/configuration.yml",
+	"configs/cloudflared/config.yml",
+	"configs/homepage/services.yaml",
+}
+
+func TestGolden(t *testing.T) {
+	entries, err := os.ReadDir(goldenDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			t.Run(entry.Name(), func(t *testing.T) {
+				runGoldenCase(t, filepath.Join(goldenDir, entry.Name()))
+			})
+		}
+	}
+}
+
+// runGoldenCase generates a fixture and compares the output with its want/ tree
+func runGoldenCase(t *testing.T, dir string) {
+	t.Helper()
+	out := generateFixture(t, dir)
+	mask := goldenMask(t, out)
+
+	for _, name := range goldenFiles {
+		got, err := os.ReadFile(filepath.Join(out, name))
+		generated := err == nil
+		if err != nil && !os.IsNotExist(err) {
+			t.Fatal(err)
+		}
+		got = mask(got)
+		wantPath := filepath.Join(dir, "want", name)
+
+		if updateGolden {
+			if !generated {
+				if err := os.Remove(wantPath); err != nil && !os.IsNotExist(err) {
+					t.Fatal(err)
+				}
+				continue
+			}
+			if err := os.MkdirAll(filepath.Dir(wantPath), 0o755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(wantPath, got, 0o644); err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+
+		want, err := os.ReadFile(wantPath)
+		switch {
+		case !generated && err == nil:
+			t.Errorf("%s was not generated, but want/%s exists", name, name)
+		case generated && os.IsNotExist(err):
+			t.Errorf("%s was generated, but want/%s is missing (run with UPDATE_GOLDEN=1)", name, name)
+		case err != nil && !os.IsNotExist(err):
+			t.Fatal(err)
+		case generated && !bytes.Equal(got, want):
+			t.Errorf("%s differs from the golden file (run with UPDATE_GOLDEN=1 if intended):\n%s",
+				name, UnifiedDiff(name, want, got))
+		}
+	}
+}
+
+// generateFixture generates a fixture with the embedded definitions and its
+// own services/, and returns the project directory
+func generateFixture(t *testing.T, dir string) string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(dir, "sdbx.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := config.Parse(data)
+	if err != nil {
+		t.Fatalf("failed to parse sdbx.yaml: %v", err)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("invalid sdbx.yaml: %v", err)
+	}
+
+	sources := &registry.SourceConfig{Cache: registry.CacheConfig{Directory: t.TempDir()}}
+	if services, err := filepath.Abs(filepath.Join(dir, "services")); err == nil {
+		if _, err := os.Stat(services); err == nil {
+			sources.Sources = append(sources.Sources, registry.Source{
+				Name: "fixture", Type: "local", Path: services, Priority: 100, Enabled: true,
+			})
+		}
+	}
+	reg, err := registry.New(sources)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out := t.TempDir()
+	if err := NewGeneratorWithRegistry(cfg, out, reg).Generate(); err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	return out
+}
+
+// goldenMask returns a function replacing the generated secret values and
+// the project directory, which change on every run, with placeholders
+func goldenMask(t *testing.T, dir string) func([]byte) []byte {
+	t.Helper()
+	replacements := []string{dir, "<project>"}
+	entries, err := os.ReadDir(filepath.Join(dir, "secrets"))
+	if err != nil && !os.IsNotExist(err) {
+		t.Fatal(err)
+	}
+	for _, entry := range entries {
+		data, err := os.ReadFile(filepath.Join(dir, "secrets", entry.Name()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Short values (e.g. empty placeholders) would mask unrelated text
+		if value := strings.TrimSpace(string(data)); len(value) >= 16 {
+			replacements = append(replacements, value, "<secret:"+strings.TrimSuffix(entry.Name(), ".txt")+">")
+		}
+	}
+	replacer := strings.NewReplacer(replacements...)
+	return func(data []byte) []byte {
+		if data == nil {
+			return nil
+		}
+		return []byte(replacer.Replace(string(data)))
+	}
+}
@@ -0,0 +1,251 @@
+package generator
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/maiko/sdbx/internal/config"
+	"github.com/maiko/sdbx/internal/registry"
+)
+
+// Inspection is what generation produces for one service, for 'sdbx
+// inspect'. Secret values are never included.
+type Inspection struct {
+	Service    string                      `yaml:"service"`
+	Source     string                      `yaml:"source"`
+	Enabled    bool                        `yaml:"enabled"`
+	Definition *registry.ServiceDefinition `yaml:"definition"` // After overrides and instance variables
+
+	// Compose is the service's block of compose.yaml, nil when it is not
+	// generated (disabled, or its conditions are not met)
+	Compose *ComposeService `yaml:"compose,omitempty"`
+
+	// Labels are the labels generated for the service. Services sharing
+	// another's network have their Traefik labels on that service (RoutedVia).
+	Labels    []string `yaml:"labels,omitempty"`
+	RoutedVia string   `yaml:"routed_via,omitempty"`
+
+	Secrets      []InspectedSecret     `yaml:"secrets,omitempty"`
+	Integrations InspectedIntegrations `yaml:"integrations"`
+}
+
+// InspectedSecret is a secret a service declares or references
+type InspectedSecret struct {
+	Name     string `yaml:"name"`
+	Env      string `yaml:"env,omitempty"` // Variable receiving it, for secretRef variables
+	Delivery string `yaml:"delivery"`      // file, env_file or env (see config.SecretDelivery*)
+	Set      bool   `yaml:"set"`           // secrets/<name>.txt has content
+}
+
+// InspectedIntegrations are the entries generated for a service in the
+// integration configs
+type InspectedIntegrations struct {
+	Homepage       []map[string][]map[string]any `yaml:"homepage,omitempty"`        // configs/homepage/services.yaml
+	Middlewares    map[string]TraefikMiddleware  `yaml:"middlewares,omitempty"`     // Middlewares of configs/traefik/dynamic its routers use
+	Cloudflared    []CloudflaredRule             `yaml:"cloudflared,omitempty"`     // Tunnel ingress rules
+	Authelia       []AutheliaAccessRule          `yaml:"authelia,omitempty"`        // Access control rules
+	AccessProfiles []string                      `yaml:"access_profiles,omitempty"` // Groups of access_profiles reaching it
+}
+
+// MarshalJSON encodes the inspection with the keys of its YAML form, the
+// only ones the definition and compose types declare
+func (i *Inspection) MarshalJSON() ([]byte, error) {
+	data, err := yaml.Marshal(i)
+	if err != nil {
+		return nil, err
+	}
+	var v any
+	if err := yaml.Unmarshal(data, &v); err != nil {
+		return nil, err
+	}
+	return json.Marshal(v)
+}
+
+// Inspect resolves the project and renders what generation produces for
+// one service, without writing files or creating secrets
+func (g *Generator) Inspect(ctx context.Context, name string) (*Inspection, error) {
+	if g.Registry == nil {
+		var err error
+		g.Registry, err = registry.NewWithDefaults()
+		if err != nil {
+			return nil, fmt.Errorf("failed to create registry: %w", err)
+		}
+	}
+
+	graph, err := g.Registry.Resolve(ctx, g.Config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve services: %w", err)
+	}
+	resolved, ok := graph.Services[name]
+	if !ok {
+		for _, e := range graph.Errors {
+			if e.Service == name {
+				return nil, e
+			}
+		}
+		return nil, fmt.Errorf("service %s is not part of the project", name)
+	}
+
+	def := resolved.FinalDefinition
+	insp := &Inspection{
+		Service:    name,
+		Source:     resolved.Source,
+		Enabled:    resolved.Enabled,
+		Definition: def,
+	}
+	if !resolved.Enabled {
+		return insp, nil
+	}
+
+	// Without secret values, variables delivered in compose.yaml keep their
+	// definition's placeholder
+	composeGen := NewComposeGenerator(g.Config, g.Registry, map[string]string{})
+	composeGen.HostPorts = g.lockedHostPorts()
+	compose, err := composeGen.Generate(graph)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate compose file: %w", err)
+	}
+	for _, e := range graph.Skipped() {
+		if e.Service == name {
+			return nil, e
+		}
+	}
+	svc, ok := compose.Services[name]
+	if !ok {
+		return insp, nil
+	}
+	insp.Compose = &svc
+	insp.Labels = svc.Labels
+	if host, shared := strings.CutPrefix(svc.NetworkMode, "service:"); shared && def.Routing.Enabled {
+		insp.RoutedVia = host
+		insp.Labels = append(slices.Clone(svc.Labels), composeGen.buildTraefikLabels(def, composeGen.templateContext(def))...)
+	}
+
+	insp.Secrets = g.inspectSecrets(composeGen, def)
+	if insp.Integrations, err = g.inspectIntegrations(graph, name, insp.Labels); err != nil {
+		return nil, err
+	}
+	return insp, nil
+}
+
+// inspectSecrets lists the secrets a definition declares and the secretRef
+// variables of its environment, with their delivery
+func (g *Generator) inspectSecrets(composeGen *ComposeGenerator, def *registry.ServiceDefinition) []InspectedSecret {
+	set := func(name string) bool {
+		data, err := os.ReadFile(filepath.Join(g.OutputDir, "secrets", name+".txt"))
+		return err == nil && len(bytes.TrimSpace(data)) > 0
+	}
+
+	var list []InspectedSecret
+	for _, secret := range def.Secrets {
+		list = append(list, InspectedSecret{Name: secret.Name, Delivery: config.SecretDeliveryFile, Set: set(secret.Name)})
+	}
+
+	for _, e := range composeGen.activeEnvironment(def, composeGen.templateContext(def)) {
+		if e.ValueFrom == nil || e.ValueFrom.SecretRef == "" {
+			continue
+		}
+		secret := InspectedSecret{
+			Name:     e.ValueFrom.SecretRef,
+			Env:      e.Name,
+			Delivery: composeGen.secretDeliveryFor(def.Metadata.Name, e.ValueFrom),
+			Set:      set(e.ValueFrom.SecretRef),
+		}
+		// A declared secret is listed once, with the variable receiving it
+		if i := slices.IndexFunc(list, func(s InspectedSecret) bool { return s.Name == secret.Name && s.Env == "" }); i >= 0 {
+			list[i] = secret
+			continue
+		}
+		list = append(list, secret)
+	}
+	return list
+}
+
+// inspectIntegrations renders the integration configs for the service alone,
+// keeping the entries that are its own
+func (g *Generator) inspectIntegrations(graph *registry.ResolutionGraph, name string, labels []string) (InspectedIntegrations, error) {
+	var out InspectedIntegrations
+	intGen := NewIntegrationsGenerator(g.Config, nil)
+	single := &registry.ResolutionGraph{
+		Services: map[string]*registry.ResolvedService{name: graph.Services[name]},
+		Order:    []string{name},
+	}
+
+	homepage, err := intGen.GenerateHomepageServices(single)
+	if err != nil {
+		return out, fmt.Errorf("failed to generate homepage services: %w", err)
+	}
+	if err := yaml.Unmarshal(homepage, &out.Homepage); err != nil {
+		return out, fmt.Errorf("failed to parse homepage services: %w", err)
+	}
+
+	// Middlewares may be declared by other services, so render them all
+	dynamic, err := intGen.GenerateTraefikDynamic(graph)
+	if err != nil {
+		return out, fmt.Errorf("failed to generate traefik dynamic: %w", err)
+	}
+	var traefik TraefikDynamicConfig
+	if err := yaml.Unmarshal(dynamic, &traefik); err != nil {
+		return out, fmt.Errorf("failed to parse traefik dynamic: %w", err)
+	}
+	for _, label := range labels {
+		key, value, _ := strings.Cut(label, "=")
+		if !strings.HasPrefix(key, "traefik.http.routers.") || !strings.HasSuffix(key, ".middlewares") {
+			continue
+		}
+		for _, ref := range strings.Split(value, ",") {
+			mw, ok := strings.CutSuffix(ref, "@file")
+			if _, exists := traefik.HTTP.Middlewares[mw]; !ok || !exists {
+				continue
+			}
+			if out.Middlewares == nil {
+				out.Middlewares = make(map[string]TraefikMiddleware)
+			}
+			out.Middlewares[mw] = traefik.HTTP.Middlewares[mw]
+		}
+	}
+
+	// Ingress rules, less the catch-all and those of static sites and the status page
+	if g.Config.Expose.Mode == config.ExposeModeCloudflared {
+		tunnel, err := intGen.GenerateCloudflaredConfig(single)
+		if err != nil {
+			return out, fmt.Errorf("failed to generate cloudflared config: %w", err)
+		}
+		var cloudflared CloudflaredConfig
+		if err := yaml.Unmarshal(tunnel, &cloudflared); err != nil {
+			return out, fmt.Errorf("failed to parse cloudflared config: %w", err)
+		}
+		others := make(map[string]bool)
+		for _, site := range g.Config.Extras.StaticSites {
+			others[staticSiteHost(g.Config, site)] = true
+		}
+		if g.Config.StatusPage.Enabled {
+			others[g.Config.StatusPage.Host(g.Config.Domain)] = true
+		}
+		for _, rule := range cloudflared.Ingress {
+			if rule.Hostname != "" && !others[rule.Hostname] {
+				out.Cloudflared = append(out.Cloudflared, rule)
+			}
+		}
+	}
+
+	if !g.Config.IsBasicAuth() {
+		if out.Authelia, err = intGen.GenerateAutheliaAccessRules(single); err != nil {
+			return out, fmt.Errorf("failed to generate authelia access rules: %w", err)
+		}
+		for _, profile := range g.Config.AccessProfiles {
+			if slices.Contains(profile.Services, name) {
+				out.AccessProfiles = append(out.AccessProfiles, profile.Name)
+			}
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,96 @@
+package generator
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/maiko/sdbx/internal/config"
+)
+
+func TestInspect(t *testing.T) {
+	tmpDir := t.TempDir()
+	cfg := config.DefaultConfig()
+	cfg.Domain = "example.com"
+	cfg.Expose.Mode = config.ExposeModeCloudflared
+	cfg.VPNEnabled = true
+	cfg.Services = map[string]config.ServiceOverride{"qbittorrent": {IPAllowList: []string{"192.168.1.0/24"}}}
+	cfg.AccessProfiles = []config.AccessProfileConfig{{Name: "friends", Services: []string{"qbittorrent"}}}
+
+	gen := NewGenerator(cfg, tmpDir)
+	insp, err := gen.Inspect(context.Background(), "qbittorrent")
+	if err != nil {
+		t.Fatalf("Inspect failed: %v", err)
+	}
+	if !insp.Enabled || insp.Definition.Metadata.Name != "qbittorrent" || insp.Compose == nil || insp.Compose.ContainerName != "sdbx-qbittorrent" {
+		t.Fatalf("Inspect() = %+v", insp)
+	}
+	// Its Traefik labels live on gluetun, whose network it shares
+	if insp.RoutedVia != "gluetun" || !slices.Contains(insp.Labels, "sdbx.service=qbittorrent") || !slices.Contains(insp.Labels, "traefik.enable=true") {
+		t.Errorf("Labels = %v via %q, want its own and Traefik labels via gluetun", insp.Labels, insp.RoutedVia)
+	}
+	for _, mw := range []string{"allowlist-qbittorrent", "authelia"} {
+		if _, ok := insp.Integrations.Middlewares[mw]; !ok {
+			t.Errorf("Middlewares missing %s: %v", mw, insp.Integrations.Middlewares)
+		}
+	}
+	if len(insp.Integrations.Homepage) != 1 || len(insp.Integrations.Authelia) != 1 || !slices.Equal(insp.Integrations.AccessProfiles, []string{"friends"}) {
+		t.Errorf("Integrations = %+v", insp.Integrations)
+	}
+	if rules := insp.Integrations.Cloudflared; len(rules) != 1 || rules[0].Hostname != "qbt.example.com" {
+		t.Errorf("Cloudflared = %+v, want the service's rule only", rules)
+	}
+	if entries, err := os.ReadDir(tmpDir); err != nil || len(entries) != 0 {
+		t.Errorf("Inspect wrote %v (%v)", entries, err)
+	}
+
+	// JSON uses the YAML keys
+	data, err := json.Marshal(insp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var parsed map[string]any
+	if err := json.Unmarshal(data, &parsed); err != nil {
+		t.Fatal(err)
+	}
+	if compose, _ := parsed["compose"].(map[string]any); compose["container_name"] != "sdbx-qbittorrent" {
+		t.Errorf("JSON compose = %v", parsed["compose"])
+	}
+
+	if _, err := gen.Inspect(context.Background(), "no-such-service"); err == nil {
+		t.Error("Inspect() of an unknown service should fail")
+	}
+}
+
+func TestInspectSecrets(t *testing.T) {
+	tmpDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(tmpDir, "secrets"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(tmpDir, "secrets", "plex_claim_token.txt"), []byte("claim-secret"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cfg := config.DefaultConfig()
+	cfg.Domain = "example.com"
+	cfg.Expose.Mode = config.ExposeModeDirect
+
+	insp, err := NewGenerator(cfg, tmpDir).Inspect(context.Background(), "plex")
+	if err != nil {
+		t.Fatalf("Inspect failed: %v", err)
+	}
+	want := []InspectedSecret{{Name: "plex_claim_token", Env: "PLEX_CLAIM", Delivery: config.SecretDeliveryFile, Set: true}}
+	if !slices.Equal(insp.Secrets, want) {
+		t.Errorf("Secrets = %+v, want %+v", insp.Secrets, want)
+	}
+	data, err := json.Marshal(insp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "claim-secret") {
+		t.Error("inspection shows a secret value")
+	}
+}
@@ -1,19 +1,33 @@
 package generator
 
 import (
+	"encoding/json"
 	"fmt"
+	"log"
+	"maps"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
 	"strings"
 
 	"gopkg.in/yaml.v3"
 
+	"github.com/maiko/sdbx/internal/auth"
 	"github.com/maiko/sdbx/internal/config"
+	"github.com/maiko/sdbx/internal/docker"
 	"github.com/maiko/sdbx/internal/registry"
+	"github.com/maiko/sdbx/internal/statuspage"
 )
 
 // IntegrationsGenerator generates integration configs (homepage, cloudflared, traefik)
 type IntegrationsGenerator struct {
 	Config  *config.Config
 	Secrets map[string]string
+
+	// HostPorts holds the host port of each published port, keyed by
+	// registry.HostPortKey, for links to services Traefik does not route
+	HostPorts map[string]int
 }
 
 // NewIntegrationsGenerator creates a new integrations generator
@@ -74,14 +88,17 @@ func (g *IntegrationsGenerator) GenerateHomepageServices(graph *registry.Resolut
 		}
 
 		svc := HomepageService{
-			Name:        def.Metadata.Name,
+			Name:        g.Config.ServiceRef(def.Metadata.Name),
 			Icon:        homepage.Icon,
 			Description: homepage.Description,
 			Container:   fmt.Sprintf("sdbx-%s", def.Metadata.Name),
 		}
 
-		// Build URL
+		// Build URL; services Traefik does not route are linked on their host port
 		svc.Href = g.getServiceURL(def)
+		if port, ok := g.HostPorts[registry.HostPortKey(def.Metadata.Name, strconv.Itoa(def.Routing.Port), "tcp")]; ok && !def.Routing.Enabled {
+			svc.Href = fmt.Sprintf("http://%s:%d", g.Config.Domain, port)
+		}
 
 		groups[groupName] = append(groups[groupName], svc)
 	}
@@ -184,6 +201,33 @@ func (g *IntegrationsGenerator) GenerateCloudflaredConfig(graph *registry.Resolu
 		})
 	}
 
+	// Static sites share the Traefik ingress
+	for _, site := range g.Config.Extras.StaticSites {
+		hostname := staticSiteHost(g.Config, site)
+		if seenHostnames[hostname] {
+			continue
+		}
+		seenHostnames[hostname] = true
+
+		cfg.Ingress = append(cfg.Ingress, CloudflaredRule{
+			Hostname: hostname,
+			Service:  "http://sdbx-traefik:80",
+		})
+	}
+
+	// The status page too
+	if g.Config.StatusPage.Enabled {
+		if hostname := g.Config.StatusPage.Host(g.Config.Domain); !seenHostnames[hostname] {
+			cfg.Ingress = append(cfg.Ingress, CloudflaredRule{
+				Hostname: hostname,
+				Service:  "http://sdbx-traefik:80",
+			})
+		}
+	}
+
+	// Services resolved in any order keep the file stable across runs
+	slices.SortFunc(cfg.Ingress, func(a, b CloudflaredRule) int { return strings.Compare(a.Hostname, b.Hostname) })
+
 	// Add catch-all rule (required by cloudflared)
 	cfg.Ingress = append(cfg.Ingress, CloudflaredRule{
 		Service: "http_status:404",
@@ -199,13 +243,105 @@ type TraefikDynamicConfig struct {
 
 // TraefikHTTP represents Traefik HTTP configuration
 type TraefikHTTP struct {
+	Routers     map[string]TraefikRouter     `yaml:"routers,omitempty"`
+	Services    map[string]TraefikService    `yaml:"services,omitempty"`
 	Middlewares map[string]TraefikMiddleware `yaml:"middlewares"`
 }
 
+// TraefikRouter represents a Traefik HTTP router
+type TraefikRouter struct {
+	Rule        string            `yaml:"rule"`
+	EntryPoints []string          `yaml:"entryPoints"`
+	Service     string            `yaml:"service"`
+	Middlewares []string          `yaml:"middlewares,omitempty"`
+	Priority    int               `yaml:"priority,omitempty"`
+	TLS         *TraefikRouterTLS `yaml:"tls,omitempty"`
+}
+
+// TraefikRouterTLS enables TLS on a router (certificates come from the entrypoint)
+type TraefikRouterTLS struct{}
+
+// TraefikService represents a Traefik HTTP service
+type TraefikService struct {
+	LoadBalancer TraefikLoadBalancer `yaml:"loadBalancer"`
+}
+
+// TraefikLoadBalancer represents a Traefik load balancer
+type TraefikLoadBalancer struct {
+	Servers []TraefikServer `yaml:"servers"`
+}
+
+// TraefikServer represents a single load balancer backend
+type TraefikServer struct {
+	URL string `yaml:"url"`
+}
+
 // TraefikMiddleware represents a Traefik middleware
 type TraefikMiddleware struct {
 	StripPrefix *StripPrefixMiddleware `yaml:"stripPrefix,omitempty"`
 	ForwardAuth *ForwardAuthMiddleware `yaml:"forwardAuth,omitempty"`
+	BasicAuth   *BasicAuthMiddleware   `yaml:"basicAuth,omitempty"`
+	IPAllowList *IPAllowListMiddleware `yaml:"ipAllowList,omitempty"`
+	AddPrefix   *AddPrefixMiddleware   `yaml:"addPrefix,omitempty"`
+	Errors      *ErrorsMiddleware      `yaml:"errors,omitempty"`
+
+	ReplacePathRegex *ReplacePathRegexMiddleware `yaml:"replacePathRegex,omitempty"`
+
+	// Kinds available to middlewareDefinitions
+	RateLimit      *config.RateLimitMiddleware      `yaml:"rateLimit,omitempty"`
+	Headers        *config.HeadersMiddleware        `yaml:"headers,omitempty"`
+	Compress       *config.CompressMiddleware       `yaml:"compress,omitempty"`
+	RedirectScheme *config.RedirectSchemeMiddleware `yaml:"redirectScheme,omitempty"`
+	RedirectRegex  *config.RedirectRegexMiddleware  `yaml:"redirectRegex,omitempty"`
+}
+
+// BasicAuthMiddleware represents BasicAuth middleware config
+type BasicAuthMiddleware struct {
+	UsersFile    string `yaml:"usersFile"`
+	Realm        string `yaml:"realm,omitempty"`
+	HeaderField  string `yaml:"headerField,omitempty"`
+	RemoveHeader bool   `yaml:"removeHeader,omitempty"`
+}
+
+// htpasswdPath is where Traefik mounts the basic auth users secret
+const htpasswdPath = "/etc/traefik/htpasswd"
+
+// authMiddleware returns the name of the middleware protecting services in
+// the configured auth mode
+func authMiddleware(cfg *config.Config) string {
+	if cfg.IsBasicAuth() {
+		return "basic-auth"
+	}
+	return "authelia"
+}
+
+// AddPrefixMiddleware represents AddPrefix middleware config
+type AddPrefixMiddleware struct {
+	Prefix string `yaml:"prefix"`
+}
+
+// ReplacePathRegexMiddleware represents ReplacePathRegex middleware config
+type ReplacePathRegexMiddleware struct {
+	Regex       string `yaml:"regex"`
+	Replacement string `yaml:"replacement"`
+}
+
+// ErrorsMiddleware represents Errors middleware config
+type ErrorsMiddleware struct {
+	Status  []string `yaml:"status"`
+	Service string   `yaml:"service"`
+	Query   string   `yaml:"query"`
+}
+
+// IPAllowListMiddleware represents IPAllowList middleware config
+type IPAllowListMiddleware struct {
+	SourceRange []string    `yaml:"sourceRange"`
+	IPStrategy  *IPStrategy `yaml:"ipStrategy,omitempty"`
+}
+
+// IPStrategy selects which address Traefik checks against the allowlist
+type IPStrategy struct {
+	Depth int `yaml:"depth,omitempty"`
 }
 
 // StripPrefixMiddleware represents StripPrefix middleware config
@@ -228,27 +364,72 @@ func (g *IntegrationsGenerator) GenerateTraefikDynamic(graph *registry.Resolutio
 		},
 	}
 
-	// Add Authelia forward auth middleware
-	var authAddr string
-	if g.Config.Routing.Strategy == config.RoutingStrategyPath {
-		authAddr = fmt.Sprintf("http://sdbx-authelia:9091/api/verify?rd=https://%s.%s/auth/",
-			g.Config.Routing.BaseDomain, g.Config.Domain)
+	// Add the auth middleware: basic auth from the htpasswd secret, or Authelia forward auth
+	if g.Config.IsBasicAuth() {
+		cfg.HTTP.Middlewares["basic-auth"] = TraefikMiddleware{
+			BasicAuth: &BasicAuthMiddleware{
+				UsersFile:    htpasswdPath,
+				Realm:        "sdbx",
+				HeaderField:  "Remote-User",
+				RemoveHeader: true,
+			},
+		}
 	} else {
-		authAddr = fmt.Sprintf("http://sdbx-authelia:9091/api/verify?rd=https://auth.%s/",
-			g.Config.Domain)
-	}
+		var authAddr string
+		if g.Config.Routing.Strategy == config.RoutingStrategyPath {
+			authAddr = fmt.Sprintf("http://sdbx-authelia:9091/api/verify?rd=https://%s.%s/auth/",
+				g.Config.Routing.BaseDomain, g.Config.Domain)
+		} else {
+			authAddr = fmt.Sprintf("http://sdbx-authelia:9091/api/verify?rd=https://auth.%s/",
+				g.Config.Domain)
+		}
 
-	cfg.HTTP.Middlewares["authelia"] = TraefikMiddleware{
-		ForwardAuth: &ForwardAuthMiddleware{
-			Address:            authAddr,
-			TrustForwardHeader: true,
-			AuthResponseHeaders: []string{
-				"Remote-User",
-				"Remote-Groups",
-				"Remote-Name",
-				"Remote-Email",
+		cfg.HTTP.Middlewares["authelia"] = TraefikMiddleware{
+			ForwardAuth: &ForwardAuthMiddleware{
+				Address:            authAddr,
+				TrustForwardHeader: true,
+				AuthResponseHeaders: []string{
+					"Remote-User",
+					"Remote-Groups",
+					"Remote-Name",
+					"Remote-Email",
+				},
 			},
-		},
+		}
+	}
+
+	// Add global IP allowlist middleware
+	if len(g.Config.Traefik.IPAllowList) > 0 {
+		cfg.HTTP.Middlewares["ip-allowlist"] = g.ipAllowListMiddleware(g.Config.Traefik.IPAllowList)
+	}
+
+	// Add per-service IP allowlist middlewares
+	for _, serviceName := range graph.Order {
+		resolved := graph.Services[serviceName]
+		if !resolved.Enabled || !resolved.FinalDefinition.Routing.Enabled {
+			continue
+		}
+		override, ok := g.Config.Services[serviceName]
+		if !ok || len(override.IPAllowList) == 0 {
+			continue
+		}
+		cfg.HTTP.Middlewares["allowlist-"+serviceName] = g.ipAllowListMiddleware(override.IPAllowList)
+	}
+
+	// Add global and per-service country allowlist middlewares
+	if len(g.Config.Traefik.CountryAllowList) > 0 {
+		cfg.HTTP.Middlewares["country-allowlist"] = g.countryAllowListMiddleware(g.Config.Traefik.CountryAllowList)
+	}
+	for _, serviceName := range graph.Order {
+		resolved := graph.Services[serviceName]
+		if !resolved.Enabled || !resolved.FinalDefinition.Routing.Enabled {
+			continue
+		}
+		override, ok := g.Config.Services[serviceName]
+		if !ok || len(override.CountryAllowList) == 0 {
+			continue
+		}
+		cfg.HTTP.Middlewares["countries-"+serviceName] = g.countryAllowListMiddleware(override.CountryAllowList)
 	}
 
 	// Add strip prefix middlewares for path routing
@@ -284,13 +465,584 @@ func (g *IntegrationsGenerator) GenerateTraefikDynamic(graph *registry.Resolutio
 		}
 	}
 
+	// Shared middlewares from definitions and traefik.middleware_definitions
+	library, err := g.middlewareLibrary(graph)
+	if err != nil {
+		return nil, err
+	}
+	for name, mw := range library {
+		cfg.HTTP.Middlewares[name] = TraefikMiddleware{
+			RateLimit:      mw.RateLimit,
+			Headers:        mw.Headers,
+			Compress:       mw.Compress,
+			RedirectScheme: mw.RedirectScheme,
+			RedirectRegex:  mw.RedirectRegex,
+		}
+	}
+
+	// Bare middleware references must resolve to this file
+	for _, serviceName := range graph.Order {
+		resolved := graph.Services[serviceName]
+		if !resolved.Enabled || !resolved.FinalDefinition.Routing.Enabled {
+			continue
+		}
+		for _, ref := range resolved.FinalDefinition.Routing.Traefik.Middlewares {
+			if _, ok := cfg.HTTP.Middlewares[ref]; !ok && !strings.Contains(ref, "@") {
+				return nil, fmt.Errorf("service %s references undefined middleware %q (declare it under middlewareDefinitions or use name@provider)", serviceName, ref)
+			}
+		}
+	}
+
+	return yaml.Marshal(cfg)
+}
+
+// reservedMiddlewares are generated by sdbx; prefixes are matched with a dash
+var reservedMiddlewares = []string{"authelia", "basic-auth", "ip-allowlist", "country-allowlist", "maintenance", "error-pages", "not-found-page", "not-found-prefix", "allowlist-", "countries-", "strip-", "site-"}
+
+// middlewareLibrary collects the middlewares declared by enabled services and
+// traefik.middleware_definitions. Services declaring the same name must agree
+// (instances of a definition declare identical ones); the config replaces a
+// service's definition of the same name.
+func (g *IntegrationsGenerator) middlewareLibrary(graph *registry.ResolutionGraph) (map[string]config.MiddlewareDefinition, error) {
+	library := make(map[string]config.MiddlewareDefinition)
+	declaredBy := make(map[string]string)
+
+	for _, serviceName := range graph.Order {
+		resolved := graph.Services[serviceName]
+		if !resolved.Enabled || !g.evaluateConditions(resolved.FinalDefinition.Conditions) {
+			continue
+		}
+		defs := resolved.FinalDefinition.Routing.Traefik.MiddlewareDefinitions
+		for _, name := range slices.Sorted(maps.Keys(defs)) {
+			if other, ok := declaredBy[name]; ok && !reflect.DeepEqual(library[name], defs[name]) {
+				return nil, fmt.Errorf("middleware %q is declared differently by %s and %s", name, other, serviceName)
+			}
+			library[name] = defs[name]
+			declaredBy[name] = serviceName
+		}
+	}
+	maps.Copy(library, g.Config.Traefik.MiddlewareDefinitions)
+
+	for name := range library {
+		for _, reserved := range reservedMiddlewares {
+			if name == reserved || (strings.HasSuffix(reserved, "-") && strings.HasPrefix(name, reserved)) {
+				return nil, fmt.Errorf("middleware name %q is reserved by sdbx", name)
+			}
+		}
+	}
+	return library, nil
+}
+
+// ipAllowListMiddleware builds an IPAllowList middleware for the given ranges.
+// Behind Cloudflare Tunnel every request arrives from the cloudflared container,
+// so the client address is taken from X-Forwarded-For instead.
+func (g *IntegrationsGenerator) ipAllowListMiddleware(sourceRange []string) TraefikMiddleware {
+	mw := &IPAllowListMiddleware{SourceRange: sourceRange}
+	if g.Config.IsCloudflared() {
+		mw.IPStrategy = &IPStrategy{Depth: 1}
+	}
+	return TraefikMiddleware{IPAllowList: mw}
+}
+
+// ipAllowListMiddleware returns the name of the IP allowlist middleware that
+// applies to a service, or "" if the service is not restricted.
+func ipAllowListMiddleware(cfg *config.Config, service string) string {
+	if override, ok := cfg.Services[service]; ok && len(override.IPAllowList) > 0 {
+		return "allowlist-" + service
+	}
+	if len(cfg.Traefik.IPAllowList) > 0 {
+		return "ip-allowlist"
+	}
+	return ""
+}
+
+// countryAllowListMiddleware builds a forwardAuth middleware asking the web
+// UI whether the client's country is one of countries. Behind Cloudflare
+// Tunnel the client address cloudflared forwards is kept.
+func (g *IntegrationsGenerator) countryAllowListMiddleware(countries []string) TraefikMiddleware {
+	return TraefikMiddleware{
+		ForwardAuth: &ForwardAuthMiddleware{
+			Address:            fmt.Sprintf("%s/country/verify?allow=%s", webUIServer, strings.Join(countries, ",")),
+			TrustForwardHeader: g.Config.IsCloudflared(),
+		},
+	}
+}
+
+// countryAllowListMiddleware returns the name of the country allowlist
+// middleware that applies to a service, or "" if the service is not restricted.
+func countryAllowListMiddleware(cfg *config.Config, service string) string {
+	if override, ok := cfg.Services[service]; ok && len(override.CountryAllowList) > 0 {
+		return "countries-" + service
+	}
+	if len(cfg.Traefik.CountryAllowList) > 0 {
+		return "country-allowlist"
+	}
+	return ""
+}
+
+// accessMiddlewares returns the IP and country allowlist middlewares of a
+// service ("" for the global ones), which run before auth so blocked
+// clients never reach the login
+func accessMiddlewares(cfg *config.Config, service string) []string {
+	var middlewares []string
+	if allowList := ipAllowListMiddleware(cfg, service); allowList != "" {
+		middlewares = append(middlewares, allowList+"@file")
+	}
+	if countries := countryAllowListMiddleware(cfg, service); countries != "" {
+		middlewares = append(middlewares, countries+"@file")
+	}
+	return middlewares
+}
+
+// staticService is the Traefik service backed by the sdbx-static file server
+const staticService = "static-files"
+
+// maintenancePriority outranks the label-defined routers, whose default
+// priority is the length of their rule
+const maintenancePriority = 10000
+
+// GenerateTraefikStaticSites generates the Traefik routers, services and error
+// page middlewares for extras.static_sites, extras.error_pages and services in
+// maintenance mode. Content is served by the sdbx-static container added to
+// compose.yaml.
+func (g *IntegrationsGenerator) GenerateTraefikStaticSites(graph *registry.ResolutionGraph) ([]byte, error) {
+	cfg := TraefikDynamicConfig{
+		HTTP: TraefikHTTP{
+			Routers: make(map[string]TraefikRouter),
+			Services: map[string]TraefikService{
+				staticService: {
+					LoadBalancer: TraefikLoadBalancer{
+						Servers: []TraefikServer{{URL: "http://sdbx-static:80"}},
+					},
+				},
+			},
+			Middlewares: make(map[string]TraefikMiddleware),
+		},
+	}
+
+	entryPoint := "websecure"
+	if g.Config.Expose.Mode == config.ExposeModeCloudflared || g.Config.Expose.Mode == config.ExposeModeLAN {
+		entryPoint = "web"
+	}
+
+	for _, site := range g.Config.Extras.StaticSites {
+		prefix := "site-" + site.Name
+		cfg.HTTP.Middlewares[prefix] = TraefikMiddleware{
+			AddPrefix: &AddPrefixMiddleware{Prefix: "/" + site.Name},
+		}
+
+		middlewares := append([]string{prefix}, accessMiddlewares(g.Config, "")...)
+		if site.Auth {
+			middlewares = append(middlewares, authMiddleware(g.Config)+"@file")
+		}
+
+		router := TraefikRouter{
+			Rule:        fmt.Sprintf("Host(`%s`)", staticSiteHost(g.Config, site)),
+			EntryPoints: []string{entryPoint},
+			Service:     staticService,
+			Middlewares: middlewares,
+		}
+		if g.Config.Expose.Mode == config.ExposeModeDirect {
+			router.TLS = &TraefikRouterTLS{}
+		}
+		cfg.HTTP.Routers[prefix] = router
+	}
+
+	// Public status page, without the auth middleware
+	if g.Config.StatusPage.Enabled {
+		cfg.HTTP.Middlewares["status-page"] = TraefikMiddleware{
+			AddPrefix: &AddPrefixMiddleware{Prefix: "/_status"},
+		}
+		middlewares := append([]string{"status-page"}, accessMiddlewares(g.Config, "")...)
+		router := TraefikRouter{
+			Rule:        fmt.Sprintf("Host(`%s`)", g.Config.StatusPage.Host(g.Config.Domain)),
+			EntryPoints: []string{entryPoint},
+			Service:     staticService,
+			Middlewares: middlewares,
+		}
+		if g.Config.Expose.Mode == config.ExposeModeDirect {
+			router.TLS = &TraefikRouterTLS{}
+		}
+		cfg.HTTP.Routers["status-page"] = router
+	}
+
+	// Maintenance routers live in the file provider so they survive the
+	// container being stopped, which removes its label-defined router
+	if maintenance := g.Config.MaintenanceServices(); len(maintenance) > 0 {
+		cfg.HTTP.Middlewares["maintenance"] = TraefikMiddleware{
+			AddPrefix: &AddPrefixMiddleware{Prefix: "/_maintenance"},
+		}
+		for _, serviceName := range maintenance {
+			resolved, ok := graph.Services[serviceName]
+			if !ok || !resolved.Enabled || !resolved.FinalDefinition.Routing.Enabled {
+				continue
+			}
+			router := TraefikRouter{
+				Rule:        routerRule(g.Config, resolved.FinalDefinition),
+				EntryPoints: []string{entryPoint},
+				Service:     staticService,
+				Middlewares: []string{"maintenance"},
+				Priority:    maintenancePriority,
+			}
+			if g.Config.Expose.Mode == config.ExposeModeDirect {
+				router.TLS = &TraefikRouterTLS{}
+			}
+			cfg.HTTP.Routers["maintenance-"+serviceName] = router
+		}
+	}
+
+	if g.Config.Extras.ErrorPages != "" {
+		// Attached to the entrypoint, so it covers every router. Only gateway
+		// errors are replaced; 404s from service APIs must reach their clients.
+		cfg.HTTP.Middlewares["error-pages"] = TraefikMiddleware{
+			Errors: &ErrorsMiddleware{
+				Status:  []string{"502-504"},
+				Service: staticService,
+				Query:   "/_errors/{status}.html",
+			},
+		}
+		cfg.HTTP.Middlewares["not-found-page"] = TraefikMiddleware{
+			Errors: &ErrorsMiddleware{
+				Status:  []string{"404"},
+				Service: staticService,
+				Query:   "/_errors/404.html",
+			},
+		}
+		// Requests matching no other router hit a path that never exists on the
+		// file server, which the not-found-page middleware turns into 404.html.
+		cfg.HTTP.Middlewares["not-found-prefix"] = TraefikMiddleware{
+			AddPrefix: &AddPrefixMiddleware{Prefix: "/_errors/_missing"},
+		}
+		router := TraefikRouter{
+			Rule:        "HostRegexp(`{host:.+}`)",
+			EntryPoints: []string{entryPoint},
+			Service:     staticService,
+			Middlewares: []string{"not-found-page", "not-found-prefix"},
+			Priority:    1,
+		}
+		if g.Config.Expose.Mode == config.ExposeModeDirect {
+			router.TLS = &TraefikRouterTLS{}
+		}
+		cfg.HTTP.Routers["not-found"] = router
+	}
+
+	return yaml.Marshal(cfg)
+}
+
+// webUIServer is the web UI, which opens and checks share links and looks
+// up the countries of the country allowlists
+const webUIServer = "http://sdbx-webui:3000"
+
+// GenerateTraefikShares generates the routers of the active share links
+// ('sdbx share'): requests carrying a service's share cookie skip the auth
+// middleware and are checked by the web UI instead, and the link path of the
+// service is sent to the web UI, which sets the cookie.
+func (g *IntegrationsGenerator) GenerateTraefikShares(graph *registry.ResolutionGraph, shares []auth.Share) ([]byte, error) {
+	cfg := TraefikDynamicConfig{
+		HTTP: TraefikHTTP{
+			Routers: make(map[string]TraefikRouter),
+			Services: map[string]TraefikService{
+				"share-open": {
+					LoadBalancer: TraefikLoadBalancer{
+						Servers: []TraefikServer{{URL: webUIServer}},
+					},
+				},
+			},
+			Middlewares: make(map[string]TraefikMiddleware),
+		},
+	}
+
+	entryPoint := "websecure"
+	if g.Config.Expose.Mode == config.ExposeModeCloudflared || g.Config.Expose.Mode == config.ExposeModeLAN {
+		entryPoint = "web"
+	}
+
+	services := make(map[string]bool)
+	for _, share := range shares {
+		services[share.Service] = true
+	}
+	for _, serviceName := range slices.Sorted(maps.Keys(services)) {
+		resolved, ok := graph.Services[serviceName]
+		if !ok || !resolved.Enabled || !resolved.FinalDefinition.Routing.Enabled {
+			continue
+		}
+		def := resolved.FinalDefinition
+		prefix := ""
+		if !def.Routing.ForceSubdomain && g.Config.Routing.Strategy != config.RoutingStrategySubdomain {
+			prefix = def.Routing.Path
+		}
+
+		middlewares := accessMiddlewares(g.Config, serviceName)
+
+		name := "share-" + serviceName
+		cfg.HTTP.Middlewares[name] = TraefikMiddleware{
+			ForwardAuth: &ForwardAuthMiddleware{Address: fmt.Sprintf("%s/share/verify/%s", webUIServer, serviceName)},
+		}
+		cfg.HTTP.Middlewares[name+"-open"] = TraefikMiddleware{
+			ReplacePathRegex: &ReplacePathRegexMiddleware{
+				Regex:       "^.*" + regexp.QuoteMeta(auth.SharePath) + "(.*)$",
+				Replacement: fmt.Sprintf("/share/open/%s/$1", serviceName),
+			},
+		}
+
+		routers := map[string]TraefikRouter{
+			name: {
+				Rule:        fmt.Sprintf("%s && HeadersRegexp(`Cookie`, `%s%s=`)", routerRule(g.Config, def), auth.ShareCookiePrefix, serviceName),
+				Service:     def.Metadata.Name + "@docker",
+				Middlewares: append(slices.Clone(middlewares), name),
+			},
+			name + "-open": {
+				Rule:        fmt.Sprintf("%s && PathPrefix(`%s%s`)", routerRule(g.Config, def), prefix, auth.SharePath),
+				Service:     "share-open",
+				Middlewares: append(slices.Clone(middlewares), name+"-open"),
+			},
+		}
+		for routerName, router := range routers {
+			router.EntryPoints = []string{entryPoint}
+			if g.Config.Expose.Mode == config.ExposeModeDirect {
+				router.TLS = &TraefikRouterTLS{}
+			}
+			cfg.HTTP.Routers[routerName] = router
+		}
+	}
+
 	return yaml.Marshal(cfg)
 }
 
+// GenerateStatusPageServices generates the list of services shown on the
+// status page: status_page.services, or the enabled routed services
+func (g *IntegrationsGenerator) GenerateStatusPageServices(graph *registry.ResolutionGraph) ([]byte, error) {
+	services := []statuspage.Service{}
+	add := func(name string) {
+		svc := statuspage.Service{Name: name}
+		if resolved, ok := graph.Services[name]; ok && resolved.FinalDefinition != nil {
+			def := resolved.FinalDefinition
+			svc.Description = def.Metadata.Description
+			if def.Integrations.Homepage != nil && def.Integrations.Homepage.Description != "" {
+				svc.Description = def.Integrations.Homepage.Description
+			}
+		}
+		services = append(services, svc)
+	}
+
+	if len(g.Config.StatusPage.Services) > 0 {
+		for _, name := range g.Config.StatusPage.Services {
+			add(name)
+		}
+	} else {
+		for _, name := range slices.Sorted(maps.Keys(graph.Services)) {
+			resolved := graph.Services[name]
+			if resolved.Enabled && resolved.FinalDefinition.Routing.Enabled && g.evaluateConditions(resolved.FinalDefinition.Conditions) {
+				add(name)
+			}
+		}
+	}
+	return json.MarshalIndent(services, "", "  ")
+}
+
+// GenerateStaticServerConfig generates the nginx server block for sdbx-static.
+// Anything under /_maintenance answers 503 with the generated maintenance page.
+func (g *IntegrationsGenerator) GenerateStaticServerConfig() []byte {
+	return []byte(`# Generated by sdbx - do not edit
+server {
+    listen 80;
+    root /usr/share/nginx/html;
+    index index.html;
+
+    location /_maintenance {
+        error_page 503 /_maintenance_page/index.html;
+        return 503;
+    }
+
+    location /_maintenance_page/ {
+        internal;
+    }
+}
+`)
+}
+
+// staticSiteHost returns the hostname a static site is served on
+func staticSiteHost(cfg *config.Config, site config.StaticSiteConfig) string {
+	if site.Subdomain == "" {
+		return cfg.Domain
+	}
+	return fmt.Sprintf("%s.%s", site.Subdomain, cfg.Domain)
+}
+
+// GenerateTraefikLogrotate generates a logrotate snippet for the Traefik access log.
+// Traefik reopens its log files on USR1, so rotated files are released immediately.
+func (g *IntegrationsGenerator) GenerateTraefikLogrotate(logDir string) []byte {
+	accessLog := g.Config.Traefik.AccessLog
+	var lines []string
+
+	lines = append(lines, "# Traefik access log rotation")
+	lines = append(lines, "# Generated by sdbx - install with: sudo ln -s $(pwd)/configs/traefik/logrotate.conf /etc/logrotate.d/sdbx-traefik")
+	lines = append(lines, fmt.Sprintf("%s/access.log {", logDir))
+	if accessLog.MaxSize > 0 {
+		lines = append(lines, fmt.Sprintf("    size %dM", accessLog.MaxSize))
+	} else {
+		lines = append(lines, "    daily")
+	}
+	lines = append(lines, fmt.Sprintf("    rotate %d", accessLog.MaxFiles))
+	lines = append(lines, "    compress")
+	lines = append(lines, "    delaycompress")
+	lines = append(lines, "    missingok")
+	lines = append(lines, "    notifempty")
+	lines = append(lines, "    postrotate")
+	lines = append(lines, "        docker kill --signal=USR1 sdbx-traefik >/dev/null 2>&1 || true")
+	lines = append(lines, "    endscript")
+	lines = append(lines, "}")
+
+	return []byte(strings.Join(lines, "\n") + "\n")
+}
+
+// logShippingConfigPaths maps each log shipping agent to its generated config file
+var logShippingConfigPaths = map[string]string{
+	config.LogAgentVector:   "configs/vector/vector.yaml",
+	config.LogAgentPromtail: "configs/promtail/config.yml",
+}
+
+// GenerateLogShippingConfig generates the config of the logging.aggregation agent.
+// Every container carrying the sdbx.managed label is tailed through the Docker
+// socket and shipped to Loki, labelled with its sdbx.service name.
+func (g *IntegrationsGenerator) GenerateLogShippingConfig() []byte {
+	agg := g.Config.Logging.Aggregation
+	endpoint := agg.LokiEndpoint()
+
+	if agg.Agent == config.LogAgentPromtail {
+		return []byte(fmt.Sprintf(`# Generated by sdbx - do not edit
+server:
+  http_listen_port: 9080
+  grpc_listen_port: 0
+
+positions:
+  filename: /var/lib/promtail/positions.yaml
+
+clients:
+  - url: %s/loki/api/v1/push
+
+scrape_configs:
+  - job_name: sdbx
+    docker_sd_configs:
+      - host: unix:///var/run/docker.sock
+        refresh_interval: 15s
+        filters:
+          - name: label
+            values: ["%s=true"]
+    relabel_configs:
+      - target_label: project
+        replacement: sdbx
+      - source_labels: ["__meta_docker_container_label_sdbx_service"]
+        target_label: service
+      - source_labels: ["__meta_docker_container_name"]
+        regex: "/(.*)"
+        target_label: container
+`, endpoint, docker.LabelManaged))
+	}
+
+	return []byte(fmt.Sprintf(`# Generated by sdbx - do not edit
+data_dir: /var/lib/vector
+
+sources:
+  sdbx:
+    type: docker_logs
+    docker_host: unix:///var/run/docker.sock
+    include_labels:
+      - "%s=true"
+
+sinks:
+  loki:
+    type: loki
+    inputs: ["sdbx"]
+    endpoint: %s
+    encoding:
+      codec: text
+    labels:
+      project: sdbx
+      service: '{{ label."%s" }}'
+      container: "{{ container_name }}"
+`, docker.LabelManaged, endpoint, docker.LabelService))
+}
+
+// Generated systemd units for storage mounts running on the host
+const (
+	rcloneUnitPath   = "configs/systemd/sdbx-rclone.service"
+	mergerfsUnitPath = "configs/systemd/sdbx-mergerfs.service"
+)
+
+// GenerateRcloneUnit generates the systemd unit mounting storage.rclone.remote
+// on the host in systemd mode. configFile is the absolute rclone.conf path.
+func (g *IntegrationsGenerator) GenerateRcloneUnit(configFile string) []byte {
+	r := g.Config.Storage.Rclone
+	args := []string{
+		"/usr/bin/rclone", "mount", r.Remote, r.Mount,
+		"--config=" + configFile,
+		fmt.Sprintf("--uid=%d", g.Config.PUID),
+		fmt.Sprintf("--gid=%d", g.Config.PGID),
+		"--umask=" + g.Config.Umask,
+	}
+	args = append(args, r.MountOptions()...)
+
+	return []byte(fmt.Sprintf(`# Generated by sdbx - install with:
+#   sudo ln -s $(pwd)/%s /etc/systemd/system/ && sudo systemctl enable --now sdbx-rclone
+[Unit]
+Description=SDBX rclone mount of %s
+Wants=network-online.target
+After=network-online.target
+Before=docker.service
+
+[Service]
+Type=notify
+ExecStartPre=/bin/mkdir -p %s
+ExecStart=%s
+ExecStop=/bin/fusermount -uz %s
+Restart=on-failure
+RestartSec=10
+
+[Install]
+WantedBy=multi-user.target
+`, rcloneUnitPath, r.Remote, r.Mount, strings.Join(args, " "), r.Mount))
+}
+
+// GenerateMergerfsUnit generates the systemd unit pooling the
+// storage.mergerfs branches. It starts after the rclone mount when rclone
+// runs on the host, and before Docker so containers see the pool.
+func (g *IntegrationsGenerator) GenerateMergerfsUnit() []byte {
+	m := g.Config.Storage.Mergerfs
+	after := "local-fs.target"
+	var wants string
+	if r := g.Config.Storage.Rclone; r != nil && r.RcloneMode() == config.RcloneModeSystemd {
+		after += " sdbx-rclone.service"
+		wants = "Wants=sdbx-rclone.service\n"
+	}
+
+	return []byte(fmt.Sprintf(`# Generated by sdbx - install with:
+#   sudo ln -s $(pwd)/%s /etc/systemd/system/ && sudo systemctl enable --now sdbx-mergerfs
+[Unit]
+Description=SDBX mergerfs pool at %s
+%sAfter=%s
+Before=docker.service
+
+[Service]
+Type=forking
+ExecStartPre=/bin/mkdir -p %s
+ExecStart=/usr/bin/mergerfs -o %s %s %s
+ExecStop=/bin/fusermount -uz %s
+Restart=on-failure
+RestartSec=10
+
+[Install]
+WantedBy=multi-user.target
+`, mergerfsUnitPath, m.Mount, wants, after, m.Mount,
+		strings.Join(m.MountOptions(), ","), strings.Join(m.Branches, ":"), m.Mount, m.Mount))
+}
+
 // AutheliaAccessRule represents an Authelia access control rule
 type AutheliaAccessRule struct {
-	Domain string `yaml:"domain"`
-	Policy string `yaml:"policy"`
+	Domain    string   `yaml:"domain"`
+	Resources []string `yaml:"resources,omitempty"` // Path regexes (path routing)
+	Subject   []string `yaml:"subject,omitempty"`   // e.g. group:friends
+	Policy    string   `yaml:"policy"`
 }
 
 // GenerateAutheliaAccessRules generates Authelia access control rules
@@ -338,6 +1090,53 @@ func (g *IntegrationsGenerator) GenerateAutheliaAccessRules(graph *registry.Reso
 	return rules, nil
 }
 
+// GenerateAutheliaProfileRules generates the Authelia rules of
+// access_profiles: members of a profile's group reach its services, then are
+// denied everything else. They go before the rules granting every service.
+func (g *IntegrationsGenerator) GenerateAutheliaProfileRules(graph *registry.ResolutionGraph) []AutheliaAccessRule {
+	if len(g.Config.AccessProfiles) == 0 {
+		return nil
+	}
+	var rules []AutheliaAccessRule
+	subjects := make([]string, 0, len(g.Config.AccessProfiles))
+	for _, profile := range g.Config.AccessProfiles {
+		subject := "group:" + profile.Name
+		subjects = append(subjects, subject)
+		policy := profile.Policy
+		if policy == "" {
+			policy = g.Config.AutheliaSettings().Policy
+		}
+
+		for _, serviceName := range profile.Services {
+			resolved, ok := graph.Services[serviceName]
+			if !ok || !resolved.Enabled || !resolved.FinalDefinition.Routing.Enabled {
+				log.Printf("Warning: access profile %s: service %s is not enabled or not routed", profile.Name, serviceName)
+				continue
+			}
+			def := resolved.FinalDefinition
+			// Bypassed services are public already
+			if def.Routing.Auth.Bypass {
+				continue
+			}
+			rule := AutheliaAccessRule{Subject: []string{subject}, Policy: policy}
+			if def.Routing.ForceSubdomain || g.Config.Routing.Strategy == config.RoutingStrategySubdomain {
+				rule.Domain = fmt.Sprintf("%s.%s", def.Routing.Subdomain, g.Config.Domain)
+			} else {
+				rule.Domain = fmt.Sprintf("%s.%s", g.Config.Routing.BaseDomain, g.Config.Domain)
+				rule.Resources = []string{fmt.Sprintf("^%s([/?].*)?$", regexp.QuoteMeta(def.Routing.Path))}
+			}
+			rules = append(rules, rule)
+		}
+	}
+
+	// Members of a profile reach the union of their profiles' services, even
+	// when also in admins or users
+	for _, domain := range []string{g.Config.Domain, "*." + g.Config.Domain} {
+		rules = append(rules, AutheliaAccessRule{Domain: domain, Subject: subjects, Policy: "deny"})
+	}
+	return rules
+}
+
 // getServiceURL returns the full URL for a service
 func (g *IntegrationsGenerator) getServiceURL(def *registry.ServiceDefinition) string {
 	var scheme string
@@ -402,6 +1201,23 @@ func (g *IntegrationsGenerator) GenerateEnvFile(graph *registry.ResolutionGraph)
 		lines = append(lines, "")
 	}
 
+	// SMB volume passwords, interpolated into compose.yaml volume options
+	volumes, err := graph.VolumeDefinitions(g.Config)
+	if err != nil {
+		return nil, err
+	}
+	var passwords []string
+	for _, name := range slices.Sorted(maps.Keys(volumes)) {
+		if secret := volumes[name].Secret; secret != "" {
+			passwords = append(passwords, fmt.Sprintf("%s=%s", VolumePasswordEnv(name), g.Secrets[secret+".txt"]))
+		}
+	}
+	if len(passwords) > 0 {
+		lines = append(lines, "# Volume credentials (from secrets/, keep this file private)")
+		lines = append(lines, passwords...)
+		lines = append(lines, "")
+	}
+
 	// Plex claim (now handled via secrets file, prompted during sdbx up)
 	lines = append(lines, "# Plex claim token is now stored in secrets/plex_claim_token.txt")
 	lines = append(lines, "# You'll be prompted for it when running 'sdbx up'")
@@ -1,13 +1,18 @@
 package generator
 
 import (
+	"encoding/json"
+	"maps"
+	"slices"
 	"strings"
 	"testing"
 
 	"gopkg.in/yaml.v3"
 
+	"github.com/maiko/sdbx/internal/auth"
 	"github.com/maiko/sdbx/internal/config"
 	"github.com/maiko/sdbx/internal/registry"
+	"github.com/maiko/sdbx/internal/statuspage"
 )
 
 // makeTestGraph creates a ResolutionGraph with the given services for testing.
@@ -446,6 +451,34 @@ func TestGenerateTraefikDynamicSubdomain(t *testing.T) {
 	}
 }
 
+func TestGenerateTraefikDynamicBasicAuth(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Domain = "example.com"
+	cfg.Auth.Mode = config.AuthModeBasic
+
+	gen := NewIntegrationsGenerator(cfg, nil)
+	data, err := gen.GenerateTraefikDynamic(makeTestGraph())
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+
+	var parsed TraefikDynamicConfig
+	if err := yaml.Unmarshal(data, &parsed); err != nil {
+		t.Fatalf("invalid YAML: %v", err)
+	}
+
+	if _, ok := parsed.HTTP.Middlewares["authelia"]; ok {
+		t.Error("authelia middleware should not be generated in basic auth mode")
+	}
+	basic, ok := parsed.HTTP.Middlewares["basic-auth"]
+	if !ok || basic.BasicAuth == nil {
+		t.Fatal("expected basic-auth middleware")
+	}
+	if basic.BasicAuth.UsersFile != htpasswdPath || basic.BasicAuth.HeaderField != "Remote-User" {
+		t.Errorf("unexpected basicAuth config: %+v", basic.BasicAuth)
+	}
+}
+
 func TestGenerateTraefikDynamicPathRouting(t *testing.T) {
 	cfg := config.DefaultConfig()
 	cfg.Domain = "example.com"
@@ -534,6 +567,263 @@ func TestGenerateTraefikDynamicSkipsForceSubdomain(t *testing.T) {
 	}
 }
 
+func TestGenerateTraefikDynamicIPAllowList(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Domain = "example.com"
+	cfg.Traefik.IPAllowList = []string{"192.168.0.0/16"}
+	cfg.Services["sonarr"] = config.ServiceOverride{IPAllowList: []string{"10.0.0.0/8"}}
+
+	gen := NewIntegrationsGenerator(cfg, nil)
+
+	sonarr := makeResolvedService("sonarr", &registry.ServiceDefinition{
+		Metadata:   registry.ServiceMetadata{Name: "sonarr"},
+		Conditions: registry.Conditions{Always: true},
+		Routing:    registry.RoutingConfig{Enabled: true, Subdomain: "sonarr"},
+	})
+
+	data, err := gen.GenerateTraefikDynamic(makeTestGraph(sonarr))
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+
+	var parsed TraefikDynamicConfig
+	if err := yaml.Unmarshal(data, &parsed); err != nil {
+		t.Fatalf("invalid YAML: %v", err)
+	}
+
+	global, ok := parsed.HTTP.Middlewares["ip-allowlist"]
+	if !ok || global.IPAllowList == nil {
+		t.Fatal("expected global ip-allowlist middleware")
+	}
+	if len(global.IPAllowList.SourceRange) != 1 || global.IPAllowList.SourceRange[0] != "192.168.0.0/16" {
+		t.Errorf("global sourceRange = %v", global.IPAllowList.SourceRange)
+	}
+	// Cloudflared mode (default) must read the client IP from X-Forwarded-For
+	if global.IPAllowList.IPStrategy == nil || global.IPAllowList.IPStrategy.Depth != 1 {
+		t.Error("expected ipStrategy depth 1 in cloudflared mode")
+	}
+
+	perService, ok := parsed.HTTP.Middlewares["allowlist-sonarr"]
+	if !ok || perService.IPAllowList == nil {
+		t.Fatal("expected allowlist-sonarr middleware")
+	}
+	if perService.IPAllowList.SourceRange[0] != "10.0.0.0/8" {
+		t.Errorf("sonarr sourceRange = %v", perService.IPAllowList.SourceRange)
+	}
+
+	if got := ipAllowListMiddleware(cfg, "sonarr"); got != "allowlist-sonarr" {
+		t.Errorf("ipAllowListMiddleware(sonarr) = %q", got)
+	}
+	if got := ipAllowListMiddleware(cfg, "radarr"); got != "ip-allowlist" {
+		t.Errorf("ipAllowListMiddleware(radarr) = %q", got)
+	}
+}
+
+func TestGenerateTraefikDynamicCountryAllowList(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Domain = "example.com"
+	cfg.GeoIP.AccountID = "123456"
+	cfg.Traefik.CountryAllowList = []string{"FR", "BE"}
+	cfg.Services["sonarr"] = config.ServiceOverride{CountryAllowList: []string{"CA"}}
+
+	sonarr := makeResolvedService("sonarr", &registry.ServiceDefinition{
+		Metadata:   registry.ServiceMetadata{Name: "sonarr"},
+		Conditions: registry.Conditions{Always: true},
+		Routing:    registry.RoutingConfig{Enabled: true, Subdomain: "sonarr"},
+	})
+
+	for _, tt := range []struct {
+		mode  string
+		trust bool
+	}{
+		{config.ExposeModeCloudflared, true},
+		{config.ExposeModeDirect, false},
+	} {
+		cfg.Expose.Mode = tt.mode
+		data, err := NewIntegrationsGenerator(cfg, nil).GenerateTraefikDynamic(makeTestGraph(sonarr))
+		if err != nil {
+			t.Fatalf("%s: %v", tt.mode, err)
+		}
+		var parsed TraefikDynamicConfig
+		if err := yaml.Unmarshal(data, &parsed); err != nil {
+			t.Fatalf("invalid YAML: %v", err)
+		}
+
+		// Behind cloudflared the forwarded client address must reach the web UI
+		for name, address := range map[string]string{
+			"country-allowlist": "http://sdbx-webui:3000/country/verify?allow=FR,BE",
+			"countries-sonarr":  "http://sdbx-webui:3000/country/verify?allow=CA",
+		} {
+			mw := parsed.HTTP.Middlewares[name].ForwardAuth
+			if mw == nil || mw.Address != address || mw.TrustForwardHeader != tt.trust {
+				t.Errorf("%s: %s = %+v, want %s (trustForwardHeader %v)", tt.mode, name, mw, address, tt.trust)
+			}
+		}
+	}
+
+	if got := countryAllowListMiddleware(cfg, "sonarr"); got != "countries-sonarr" {
+		t.Errorf("countryAllowListMiddleware(sonarr) = %q", got)
+	}
+	if got := accessMiddlewares(cfg, "radarr"); !slices.Equal(got, []string{"country-allowlist@file"}) {
+		t.Errorf("accessMiddlewares(radarr) = %v", got)
+	}
+}
+
+func TestGenerateTraefikDynamicMiddlewareDefinitions(t *testing.T) {
+	routed := func(name string, refs []string, defs map[string]config.MiddlewareDefinition) *registry.ResolvedService {
+		return makeResolvedService(name, &registry.ServiceDefinition{
+			Metadata:   registry.ServiceMetadata{Name: name},
+			Conditions: registry.Conditions{Always: true},
+			Routing: registry.RoutingConfig{Enabled: true, Subdomain: name, Traefik: registry.TraefikConfig{
+				Middlewares:           refs,
+				MiddlewareDefinitions: defs,
+			}},
+		})
+	}
+	gzip := config.MiddlewareDefinition{Compress: &config.CompressMiddleware{}}
+	slow := config.MiddlewareDefinition{RateLimit: &config.RateLimitMiddleware{Average: 10}}
+	fast := config.MiddlewareDefinition{RateLimit: &config.RateLimitMiddleware{Average: 100}}
+
+	cfg := config.DefaultConfig()
+	cfg.Traefik.MiddlewareDefinitions = map[string]config.MiddlewareDefinition{"api-limit": fast}
+	gen := NewIntegrationsGenerator(cfg, nil)
+
+	// Same middleware declared identically by two services, config replaces api-limit
+	graph := makeTestGraph(
+		routed("sonarr", []string{"gzip", "api-limit", "crowdsec@docker"}, map[string]config.MiddlewareDefinition{"gzip": gzip, "api-limit": slow}),
+		routed("radarr", []string{"gzip"}, map[string]config.MiddlewareDefinition{"gzip": gzip}),
+	)
+	data, err := gen.GenerateTraefikDynamic(graph)
+	if err != nil {
+		t.Fatalf("GenerateTraefikDynamic() error: %v", err)
+	}
+	var parsed TraefikDynamicConfig
+	if err := yaml.Unmarshal(data, &parsed); err != nil {
+		t.Fatalf("invalid YAML: %v", err)
+	}
+	if parsed.HTTP.Middlewares["gzip"].Compress == nil {
+		t.Error("expected gzip compress middleware")
+	}
+	if rl := parsed.HTTP.Middlewares["api-limit"].RateLimit; rl == nil || rl.Average != 100 {
+		t.Errorf("config should replace the definition's api-limit, got %+v", rl)
+	}
+
+	// Conflicting declarations across services
+	graph = makeTestGraph(
+		routed("sonarr", nil, map[string]config.MiddlewareDefinition{"limit": slow}),
+		routed("radarr", nil, map[string]config.MiddlewareDefinition{"limit": fast}),
+	)
+	if _, err := gen.GenerateTraefikDynamic(graph); err == nil || !strings.Contains(err.Error(), "declared differently by sonarr and radarr") {
+		t.Errorf("expected collision error, got %v", err)
+	}
+
+	// Names generated by sdbx are reserved
+	graph = makeTestGraph(routed("sonarr", nil, map[string]config.MiddlewareDefinition{"strip-radarr": gzip}))
+	if _, err := gen.GenerateTraefikDynamic(graph); err == nil || !strings.Contains(err.Error(), "reserved") {
+		t.Errorf("expected reserved name error, got %v", err)
+	}
+
+	// Bare references must be defined
+	graph = makeTestGraph(routed("sonarr", []string{"missing"}, nil))
+	if _, err := gen.GenerateTraefikDynamic(graph); err == nil || !strings.Contains(err.Error(), `undefined middleware "missing"`) {
+		t.Errorf("expected undefined middleware error, got %v", err)
+	}
+}
+
+func TestGenerateTraefikLogrotate(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Traefik.AccessLog.Enabled = true
+	cfg.Traefik.AccessLog.MaxSize = 50
+	cfg.Traefik.AccessLog.MaxFiles = 3
+
+	gen := NewIntegrationsGenerator(cfg, nil)
+	content := string(gen.GenerateTraefikLogrotate("/srv/sdbx/logs/traefik"))
+
+	for _, want := range []string{
+		"/srv/sdbx/logs/traefik/access.log {",
+		"size 50M",
+		"rotate 3",
+		"--signal=USR1 sdbx-traefik",
+	} {
+		if !strings.Contains(content, want) {
+			t.Errorf("logrotate config missing %q:\n%s", want, content)
+		}
+	}
+}
+
+func TestGenerateLogShippingConfig(t *testing.T) {
+	tests := []struct {
+		agent    string
+		endpoint string
+		want     []string
+	}{
+		{config.LogAgentVector, "", []string{
+			"type: docker_logs",
+			`- "sdbx.managed=true"`,
+			"endpoint: http://sdbx-loki:3100",
+			`service: '{{ label."sdbx.service" }}'`,
+		}},
+		{config.LogAgentPromtail, "https://logs.example.com/", []string{
+			"url: https://logs.example.com/loki/api/v1/push",
+			`values: ["sdbx.managed=true"]`,
+			"__meta_docker_container_label_sdbx_service",
+			"target_label: service",
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.agent, func(t *testing.T) {
+			cfg := config.DefaultConfig()
+			cfg.Logging.Aggregation = &config.LogAggregationConfig{Agent: tt.agent, Endpoint: tt.endpoint}
+
+			content := string(NewIntegrationsGenerator(cfg, nil).GenerateLogShippingConfig())
+			for _, want := range tt.want {
+				if !strings.Contains(content, want) {
+					t.Errorf("%s config missing %q:\n%s", tt.agent, want, content)
+				}
+			}
+			var parsed map[string]interface{}
+			if err := yaml.Unmarshal([]byte(content), &parsed); err != nil {
+				t.Errorf("%s config is not valid YAML: %v", tt.agent, err)
+			}
+		})
+	}
+}
+
+func TestGenerateStorageUnits(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Storage.Rclone = &config.RcloneConfig{Remote: "gdrive:media", Mount: "/mnt/remote", Mode: config.RcloneModeSystemd}
+	cfg.Storage.Mergerfs = &config.MergerfsConfig{Mount: "/mnt/merged", Branches: []string{"/mnt/local", "/mnt/remote=NC"}}
+	gen := NewIntegrationsGenerator(cfg, nil)
+
+	rclone := string(gen.GenerateRcloneUnit("/opt/sdbx/configs/rclone/rclone.conf"))
+	for _, want := range []string{
+		"ExecStart=/usr/bin/rclone mount gdrive:media /mnt/remote --config=/opt/sdbx/configs/rclone/rclone.conf",
+		"ExecStop=/bin/fusermount -uz /mnt/remote",
+		"Before=docker.service",
+	} {
+		if !strings.Contains(rclone, want) {
+			t.Errorf("rclone unit missing %q:\n%s", want, rclone)
+		}
+	}
+
+	mergerfs := string(gen.GenerateMergerfsUnit())
+	for _, want := range []string{
+		"ExecStart=/usr/bin/mergerfs -o allow_other,cache.files=partial,dropcacheonclose=true,category.create=ff /mnt/local:/mnt/remote=NC /mnt/merged",
+		"Wants=sdbx-rclone.service\nAfter=local-fs.target sdbx-rclone.service",
+	} {
+		if !strings.Contains(mergerfs, want) {
+			t.Errorf("mergerfs unit missing %q:\n%s", want, mergerfs)
+		}
+	}
+
+	// With rclone in a container, mergerfs does not wait for a host unit
+	cfg.Storage.Rclone.Mode = config.RcloneModeContainer
+	if mergerfs := string(gen.GenerateMergerfsUnit()); strings.Contains(mergerfs, "sdbx-rclone.service") {
+		t.Errorf("mergerfs unit should not reference the rclone unit:\n%s", mergerfs)
+	}
+}
+
 // --- GenerateAutheliaAccessRules ---
 
 func TestGenerateAutheliaAccessRulesEmpty(t *testing.T) {
@@ -784,3 +1074,238 @@ func TestGenerateEnvFileWithAddons(t *testing.T) {
 		t.Error("env should list enabled addons")
 	}
 }
+
+func TestGenerateEnvFileVolumeCredentials(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Volumes = map[string]config.VolumeDefinition{
+		"nas-media": {Type: config.VolumeTypeCIFS, Server: "nas.lan", Path: "media", Username: "sdbx", Secret: "nas_password"},
+	}
+
+	gen := NewIntegrationsGenerator(cfg, map[string]string{"nas_password.txt": "hunter22"})
+	data, err := gen.GenerateEnvFile(makeTestGraph())
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	if !strings.Contains(string(data), "SDBX_VOLUME_NAS_MEDIA_PASSWORD=hunter22") {
+		t.Errorf("env should hold the SMB password, got:\n%s", data)
+	}
+}
+
+func TestGenerateTraefikStaticSites(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Domain = "example.com"
+	cfg.Expose.Mode = config.ExposeModeDirect
+	cfg.Extras.StaticSites = []config.StaticSiteConfig{
+		{Name: "landing", Directory: "./www"},
+		{Name: "docs", Directory: "./docs", Subdomain: "docs", Auth: true},
+	}
+	cfg.Extras.ErrorPages = "./errors"
+
+	gen := NewIntegrationsGenerator(cfg, nil)
+	data, err := gen.GenerateTraefikStaticSites(makeTestGraph())
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+
+	var parsed TraefikDynamicConfig
+	if err := yaml.Unmarshal(data, &parsed); err != nil {
+		t.Fatalf("invalid YAML: %v", err)
+	}
+
+	landing, ok := parsed.HTTP.Routers["site-landing"]
+	if !ok {
+		t.Fatal("expected site-landing router")
+	}
+	if landing.Rule != "Host(`example.com`)" {
+		t.Errorf("landing rule = %q, want apex domain", landing.Rule)
+	}
+	if landing.TLS == nil || landing.EntryPoints[0] != "websecure" {
+		t.Error("expected TLS on websecure in direct mode")
+	}
+
+	docs := parsed.HTTP.Routers["site-docs"]
+	if docs.Rule != "Host(`docs.example.com`)" {
+		t.Errorf("docs rule = %q", docs.Rule)
+	}
+	if len(docs.Middlewares) != 2 || docs.Middlewares[1] != "authelia@file" {
+		t.Errorf("docs middlewares = %v, want prefix + authelia", docs.Middlewares)
+	}
+	if mw := parsed.HTTP.Middlewares["site-docs"]; mw.AddPrefix == nil || mw.AddPrefix.Prefix != "/docs" {
+		t.Error("expected /docs addPrefix middleware")
+	}
+
+	errorPages, ok := parsed.HTTP.Middlewares["error-pages"]
+	if !ok || errorPages.Errors == nil || errorPages.Errors.Service != staticService {
+		t.Fatal("expected error-pages middleware backed by the static file server")
+	}
+	if notFound, ok := parsed.HTTP.Routers["not-found"]; !ok || notFound.Priority != 1 {
+		t.Error("expected lowest-priority not-found catch-all router")
+	}
+	if len(parsed.HTTP.Services[staticService].LoadBalancer.Servers) != 1 {
+		t.Error("expected static file server service")
+	}
+}
+
+func TestGenerateTraefikStaticSitesStatusPage(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Domain = "example.com"
+	cfg.Expose.Mode = config.ExposeModeCloudflared
+	cfg.StatusPage = config.StatusPageConfig{Enabled: true}
+
+	gen := NewIntegrationsGenerator(cfg, nil)
+	data, err := gen.GenerateTraefikStaticSites(makeTestGraph())
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+
+	var parsed TraefikDynamicConfig
+	if err := yaml.Unmarshal(data, &parsed); err != nil {
+		t.Fatalf("invalid YAML: %v", err)
+	}
+
+	router, ok := parsed.HTTP.Routers["status-page"]
+	if !ok {
+		t.Fatal("expected status-page router")
+	}
+	if router.Rule != "Host(`status.example.com`)" || router.EntryPoints[0] != "web" {
+		t.Errorf("router = %+v", router)
+	}
+	if len(router.Middlewares) != 1 || router.Middlewares[0] != "status-page" {
+		t.Errorf("middlewares = %v, want only the prefix (public page)", router.Middlewares)
+	}
+	if mw := parsed.HTTP.Middlewares["status-page"]; mw.AddPrefix == nil || mw.AddPrefix.Prefix != "/_status" {
+		t.Error("expected /_status addPrefix middleware")
+	}
+
+	cloudflared, err := gen.GenerateCloudflaredConfig(makeTestGraph())
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	if !strings.Contains(string(cloudflared), "hostname: status.example.com") {
+		t.Errorf("cloudflared ingress missing the status page:\n%s", cloudflared)
+	}
+}
+
+func TestGenerateTraefikShares(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Domain = "example.com"
+	cfg.Expose.Mode = config.ExposeModeDirect
+	cfg.Routing.Strategy = config.RoutingStrategyPath
+	cfg.Routing.BaseDomain = "sdbx"
+
+	overseerr := makeResolvedService("overseerr", &registry.ServiceDefinition{
+		Metadata: registry.ServiceMetadata{Name: "overseerr"},
+		Routing:  registry.RoutingConfig{Enabled: true, Path: "/overseerr", Auth: registry.AuthConfig{Required: true}},
+	})
+	shares := []auth.Share{{ID: "a", Service: "overseerr"}, {ID: "b", Service: "overseerr"}, {ID: "c", Service: "bazarr"}}
+
+	gen := NewIntegrationsGenerator(cfg, nil)
+	data, err := gen.GenerateTraefikShares(makeTestGraph(overseerr), shares)
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	var parsed TraefikDynamicConfig
+	if err := yaml.Unmarshal(data, &parsed); err != nil {
+		t.Fatalf("invalid YAML: %v", err)
+	}
+
+	if len(parsed.HTTP.Routers) != 2 {
+		t.Errorf("routers = %v, want 2 for overseerr only", slices.Sorted(maps.Keys(parsed.HTTP.Routers)))
+	}
+	router := parsed.HTTP.Routers["share-overseerr"]
+	if router.Rule != "Host(`sdbx.example.com`) && PathPrefix(`/overseerr`) && HeadersRegexp(`Cookie`, `sdbx_share_overseerr=`)" ||
+		router.Service != "overseerr@docker" || router.TLS == nil || !slices.Equal(router.Middlewares, []string{"share-overseerr"}) {
+		t.Errorf("share-overseerr = %+v", router)
+	}
+	if mw := parsed.HTTP.Middlewares["share-overseerr"]; mw.ForwardAuth == nil || mw.ForwardAuth.Address != "http://sdbx-webui:3000/share/verify/overseerr" {
+		t.Errorf("share-overseerr middleware = %+v", mw)
+	}
+	open := parsed.HTTP.Routers["share-overseerr-open"]
+	if !strings.HasSuffix(open.Rule, "&& PathPrefix(`/overseerr/.sdbx-share/`)") || open.Service != "share-open" {
+		t.Errorf("share-overseerr-open = %+v", open)
+	}
+	if mw := parsed.HTTP.Middlewares["share-overseerr-open"]; mw.ReplacePathRegex == nil || mw.ReplacePathRegex.Replacement != "/share/open/overseerr/$1" {
+		t.Errorf("share-overseerr-open middleware = %+v", mw)
+	}
+}
+
+func TestGenerateStatusPageServices(t *testing.T) {
+	sonarr := makeResolvedService("sonarr", &registry.ServiceDefinition{
+		Metadata:     registry.ServiceMetadata{Name: "sonarr", Description: "TV shows"},
+		Routing:      registry.RoutingConfig{Enabled: true, Subdomain: "sonarr"},
+		Integrations: registry.Integrations{Homepage: &registry.HomepageIntegration{Enabled: true, Description: "Series"}},
+	})
+	plex := makeResolvedService("plex", &registry.ServiceDefinition{
+		Metadata: registry.ServiceMetadata{Name: "plex", Description: "Media server"},
+		Routing:  registry.RoutingConfig{Enabled: true, Subdomain: "plex"},
+	})
+	redis := makeResolvedService("redis", &registry.ServiceDefinition{
+		Metadata: registry.ServiceMetadata{Name: "redis"},
+	})
+	graph := makeTestGraph(sonarr, plex, redis)
+
+	cfg := config.DefaultConfig()
+	gen := NewIntegrationsGenerator(cfg, nil)
+	data, err := gen.GenerateStatusPageServices(graph)
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	var services []statuspage.Service
+	if err := json.Unmarshal(data, &services); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	want := []statuspage.Service{{Name: "plex", Description: "Media server"}, {Name: "sonarr", Description: "Series"}}
+	if !slices.Equal(services, want) {
+		t.Errorf("services = %+v, want %+v (routed services only)", services, want)
+	}
+
+	cfg.StatusPage.Services = []string{"redis", "plex"}
+	data, _ = gen.GenerateStatusPageServices(graph)
+	if err := json.Unmarshal(data, &services); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if len(services) != 2 || services[0].Name != "redis" || services[1].Name != "plex" {
+		t.Errorf("services = %+v, want status_page.services in order", services)
+	}
+}
+
+func TestGenerateTraefikStaticSitesMaintenance(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Domain = "example.com"
+	cfg.SetMaintenance("sonarr", true)
+	cfg.SetMaintenance("bazarr", true) // not resolved, must be skipped
+
+	gen := NewIntegrationsGenerator(cfg, nil)
+
+	sonarr := makeResolvedService("sonarr", &registry.ServiceDefinition{
+		Metadata: registry.ServiceMetadata{Name: "sonarr"},
+		Routing:  registry.RoutingConfig{Enabled: true, Subdomain: "sonarr"},
+	})
+
+	data, err := gen.GenerateTraefikStaticSites(makeTestGraph(sonarr))
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+
+	var parsed TraefikDynamicConfig
+	if err := yaml.Unmarshal(data, &parsed); err != nil {
+		t.Fatalf("invalid YAML: %v", err)
+	}
+
+	router, ok := parsed.HTTP.Routers["maintenance-sonarr"]
+	if !ok {
+		t.Fatal("expected maintenance-sonarr router")
+	}
+	if router.Rule != "Host(`sonarr.example.com`)" {
+		t.Errorf("rule = %q, want the service's own rule", router.Rule)
+	}
+	if router.Priority != maintenancePriority {
+		t.Errorf("priority = %d, want %d", router.Priority, maintenancePriority)
+	}
+	if _, ok := parsed.HTTP.Routers["maintenance-bazarr"]; ok {
+		t.Error("unresolved services must not get a maintenance router")
+	}
+	if mw := parsed.HTTP.Middlewares["maintenance"]; mw.AddPrefix == nil || mw.AddPrefix.Prefix != "/_maintenance" {
+		t.Error("expected /_maintenance addPrefix middleware")
+	}
+}
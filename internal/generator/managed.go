@@ -0,0 +1,247 @@
+package generator
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/maiko/sdbx/internal/registry"
+)
+
+// managedFile is a generated config file that users customize through a
+// user-managed fragment merged into it on every generation, rather than by
+// editing it
+type managedFile struct {
+	Path     string   // Generated file, relative to the project directory
+	Fragment string   // User-managed fragment, relative to the project directory
+	Prepend  []string // Dotted paths of lists whose fragment entries go first
+
+	// Validate checks the merged file, if set
+	Validate func(data []byte) error
+}
+
+// managedFiles are the generated files merged with a user fragment. The
+// fragments live outside configs/traefik/dynamic, which Traefik loads whole.
+var managedFiles = []managedFile{
+	{Path: "configs/traefik/dynamic/middlewares.yml", Fragment: "configs/traefik/user/middlewares.yml"},
+	// Authelia applies the first matching rule: user rules must come first
+	{Path: "configs/authelia/configuration.yml", Fragment: "configs/authelia/user/configuration.yml", Prepend: []string{"access_control.rules"}, Validate: validateAutheliaConfig},
+	{Path: "configs/homepage/services.yaml", Fragment: "configs/homepage/user/services.yaml"},
+	{Path: "configs/homepage/bookmarks.yaml", Fragment: "configs/homepage/user/bookmarks.yaml"},
+	{Path: "configs/homepage/settings.yaml", Fragment: "configs/homepage/user/settings.yaml"},
+}
+
+// ManagedFragments returns the user-managed fragments, relative to the
+// project directory
+func ManagedFragments() []string {
+	fragments := make([]string, 0, len(managedFiles))
+	for _, m := range managedFiles {
+		fragments = append(fragments, m.Fragment)
+	}
+	return fragments
+}
+
+// lookupManaged returns the managed file generated at rel, if any
+func lookupManaged(rel string) (managedFile, bool) {
+	i := slices.IndexFunc(managedFiles, func(m managedFile) bool { return m.Path == rel })
+	if i < 0 {
+		return managedFile{}, false
+	}
+	return managedFiles[i], true
+}
+
+// managedHeader marks a file as owned by sdbx and points to its fragment
+func managedHeader(m managedFile) []byte {
+	return []byte(fmt.Sprintf("# Managed by sdbx: this file is overwritten on every generation.\n"+
+		"# Put your changes in %s, merged into it.\n", m.Fragment))
+}
+
+// writeManaged writes a generated file. A managed file is merged with its
+// user fragment and marked as managed; hand edits made to it since the last
+// generation are saved next to the fragment instead of being lost.
+func (g *Generator) writeManaged(path string, data []byte, perm os.FileMode) error {
+	rel, err := filepath.Rel(g.OutputDir, path)
+	if err != nil {
+		return g.writeFile(path, data, perm)
+	}
+	m, ok := lookupManaged(filepath.ToSlash(rel))
+	if !ok {
+		return g.writeFile(path, data, perm)
+	}
+
+	fragment, err := os.ReadFile(filepath.Join(g.OutputDir, m.Fragment))
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read %s: %w", m.Fragment, err)
+	}
+	if len(bytes.TrimSpace(fragment)) > 0 {
+		if data, err = mergeFragment(data, fragment, m.Prepend); err != nil {
+			return fmt.Errorf("failed to merge %s: %w", m.Fragment, err)
+		}
+	}
+	if m.Validate != nil {
+		if err := m.Validate(data); err != nil {
+			return fmt.Errorf("invalid %s: %w", m.Path, err)
+		}
+	}
+	data = append(managedHeader(m), data...)
+
+	if err := g.preserveEdits(m, data); err != nil {
+		return err
+	}
+	return g.writeFile(path, data, perm)
+}
+
+// preserveEdits saves a managed file edited by hand since the last
+// generation as <fragment>.edited, so the edits can be moved to the fragment
+func (g *Generator) preserveEdits(m managedFile, data []byte) error {
+	recorded, ok := g.generatedChecksums()[m.Path]
+	if !ok {
+		return nil
+	}
+	existing, err := os.ReadFile(filepath.Join(g.OutputDir, m.Path))
+	if err != nil || bytes.Equal(existing, data) {
+		return nil
+	}
+	sum := sha256.Sum256(existing)
+	if hex.EncodeToString(sum[:]) == recorded {
+		return nil
+	}
+
+	saved := m.Fragment + ".edited"
+	if err := os.MkdirAll(filepath.Dir(filepath.Join(g.OutputDir, saved)), 0o755); err != nil {
+		return fmt.Errorf("failed to create directory for %s: %w", saved, err)
+	}
+	if err := g.writeFile(filepath.Join(g.OutputDir, saved), existing, 0o644); err != nil {
+		return fmt.Errorf("failed to save edits of %s: %w", m.Path, err)
+	}
+	log.Printf("Warning: %s was edited since the last generation; the edited file is saved as %s, move your changes to %s to keep them", m.Path, saved, m.Fragment)
+	return nil
+}
+
+// generatedChecksums returns the checksums of the files of the last
+// generation recorded in the lock file, none without one
+func (g *Generator) generatedChecksums() map[string]string {
+	if g.checksums != nil {
+		return g.checksums
+	}
+	g.checksums = map[string]string{}
+	if !registry.LockFileExists(g.OutputDir) {
+		return g.checksums
+	}
+	lock, err := registry.NewLoader().LoadLockFile(registry.GetLockFilePath(g.OutputDir))
+	if err == nil && lock.GeneratedFiles != nil {
+		g.checksums = lock.GeneratedFiles
+	}
+	return g.checksums
+}
+
+// mergeFragment merges a user fragment into a generated YAML document: maps
+// merge recursively, scalars are replaced and lists appended, or prepended at
+// the prepend paths. List entries that are maps with a single key (Homepage
+// groups and services) merge with the generated entry of the same key.
+// The generated document keeps its key order and comments.
+func mergeFragment(generated, fragment []byte, prepend []string) ([]byte, error) {
+	var dst, src yaml.Node
+	if err := yaml.Unmarshal(generated, &dst); err != nil {
+		return nil, fmt.Errorf("failed to parse generated file: %w", err)
+	}
+	if err := yaml.Unmarshal(fragment, &src); err != nil {
+		return nil, fmt.Errorf("invalid YAML: %w", err)
+	}
+	if len(dst.Content) == 0 || len(src.Content) == 0 {
+		return generated, nil
+	}
+	root, user := dst.Content[0], src.Content[0]
+	if root.Kind != user.Kind {
+		return nil, fmt.Errorf("expected a %s like the generated file, got a %s", nodeKindName(root.Kind), nodeKindName(user.Kind))
+	}
+	mergeNodes(root, user, "", prepend)
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(&dst); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// mergeNodes merges src into dst, at the dotted path of dst
+func mergeNodes(dst, src *yaml.Node, path string, prepend []string) {
+	switch {
+	case dst.Kind == yaml.MappingNode && src.Kind == yaml.MappingNode:
+		for i := 0; i+1 < len(src.Content); i += 2 {
+			key, value := src.Content[i], src.Content[i+1]
+			child := key.Value
+			if path != "" {
+				child = path + "." + key.Value
+			}
+			if existing := mappingValue(dst, key.Value); existing != nil {
+				if existing.Kind == value.Kind && value.Kind != yaml.ScalarNode && value.Kind != yaml.AliasNode {
+					mergeNodes(existing, value, child, prepend)
+				} else {
+					*existing = *value
+				}
+				continue
+			}
+			dst.Content = append(dst.Content, key, value)
+		}
+	case dst.Kind == yaml.SequenceNode && src.Kind == yaml.SequenceNode:
+		var added []*yaml.Node
+		for _, item := range src.Content {
+			if name := singleKey(item); name != "" {
+				if i := slices.IndexFunc(dst.Content, func(n *yaml.Node) bool { return singleKey(n) == name }); i >= 0 {
+					mergeNodes(dst.Content[i], item, path, prepend)
+					continue
+				}
+			}
+			added = append(added, item)
+		}
+		if slices.Contains(prepend, path) {
+			dst.Content = append(added, dst.Content...)
+		} else {
+			dst.Content = append(dst.Content, added...)
+		}
+	default:
+		*dst = *src
+	}
+}
+
+// mappingValue returns the value of key in a mapping node, or nil
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// singleKey returns the key of a mapping node with a single key, or ""
+func singleKey(node *yaml.Node) string {
+	if node.Kind != yaml.MappingNode || len(node.Content) != 2 {
+		return ""
+	}
+	return node.Content[0].Value
+}
+
+func nodeKindName(kind yaml.Kind) string {
+	switch kind {
+	case yaml.MappingNode:
+		return "map"
+	case yaml.SequenceNode:
+		return "list"
+	default:
+		return "value"
+	}
+}
@@ -0,0 +1,206 @@
+package generator
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/maiko/sdbx/internal/config"
+	"github.com/maiko/sdbx/internal/registry"
+)
+
+func TestMergeFragment(t *testing.T) {
+	tests := []struct {
+		name      string
+		generated string
+		fragment  string
+		prepend   []string
+		want      string
+		wantErr   bool
+	}{
+		{
+			name: "maps merge and scalars replace",
+			generated: `http:
+  middlewares:
+    security-headers:
+      headers:
+        frameDeny: true
+        stsSeconds: 31536000
+`,
+			fragment: `http:
+  middlewares:
+    security-headers:
+      headers:
+        stsSeconds: 63072000
+    my-redirect:
+      redirectScheme:
+        scheme: https
+`,
+			want: `http:
+  middlewares:
+    security-headers:
+      headers:
+        frameDeny: true
+        stsSeconds: 63072000
+    my-redirect:
+      redirectScheme:
+        scheme: https
+`,
+		},
+		{
+			name: "prepended rules",
+			generated: `# Authelia
+access_control:
+  default_policy: deny
+  rules:
+    - domain: "auth.example.com"
+      policy: bypass
+`,
+			fragment: `access_control:
+  rules:
+    - domain: "nas.example.com"
+      policy: two_factor
+`,
+			prepend: []string{"access_control.rules"},
+			want: `# Authelia
+access_control:
+  default_policy: deny
+  rules:
+    - domain: "nas.example.com"
+      policy: two_factor
+    - domain: "auth.example.com"
+      policy: bypass
+`,
+		},
+		{
+			name: "groups merge by name",
+			generated: `- Media:
+    - plex:
+        href: https://plex.example.com
+- Downloads:
+    - qbittorrent:
+        href: https://qbt.example.com
+`,
+			fragment: `- Media:
+    - plex:
+        href: http://192.168.1.10:32400
+    - nas:
+        href: http://192.168.1.2
+- Home:
+    - router:
+        href: http://192.168.1.1
+`,
+			want: `- Media:
+    - plex:
+        href: http://192.168.1.10:32400
+    - nas:
+        href: http://192.168.1.2
+- Downloads:
+    - qbittorrent:
+        href: https://qbt.example.com
+- Home:
+    - router:
+        href: http://192.168.1.1
+`,
+		},
+		{
+			name:      "kind mismatch",
+			generated: "title: sdbx\n",
+			fragment:  "- title: mine\n",
+			wantErr:   true,
+		},
+		{
+			name:      "invalid fragment",
+			generated: "title: sdbx\n",
+			fragment:  "title: [unclosed\n",
+			wantErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := mergeFragment([]byte(tt.generated), []byte(tt.fragment), tt.prepend)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("mergeFragment() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			var gotDoc, wantDoc interface{}
+			if err := yaml.Unmarshal(got, &gotDoc); err != nil {
+				t.Fatalf("merged file is invalid: %v\n%s", err, got)
+			}
+			if err := yaml.Unmarshal([]byte(tt.want), &wantDoc); err != nil {
+				t.Fatal(err)
+			}
+			if gotYAML, _ := yaml.Marshal(gotDoc); string(gotYAML) != mustMarshal(t, wantDoc) {
+				t.Errorf("mergeFragment() =\n%s\nwant\n%s", got, tt.want)
+			}
+			if strings.HasPrefix(tt.generated, "#") && !strings.HasPrefix(string(got), "# Authelia") {
+				t.Errorf("generated comments were dropped:\n%s", got)
+			}
+		})
+	}
+}
+
+func mustMarshal(t *testing.T, v interface{}) string {
+	t.Helper()
+	data, err := yaml.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestGenerateMergesUserFragments(t *testing.T) {
+	tmpDir := t.TempDir()
+	lock := &registry.LockFile{
+		APIVersion: registry.APIVersion,
+		Kind:       registry.KindLockFile,
+		Metadata:   registry.LockFileMetadata{Version: registry.LockFileVersion},
+	}
+	if err := registry.NewLoader().SaveLockFile(registry.GetLockFilePath(tmpDir), lock); err != nil {
+		t.Fatal(err)
+	}
+	fragment := filepath.Join(tmpDir, "configs/traefik/user/middlewares.yml")
+	if err := os.MkdirAll(filepath.Dir(fragment), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(fragment, []byte("http:\n  middlewares:\n    my-headers:\n      headers:\n        customFrameOptionsValue: SAMEORIGIN\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := config.DefaultConfig()
+	if err := NewGenerator(cfg, tmpDir).Generate(); err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	middlewares := filepath.Join(tmpDir, "configs/traefik/dynamic/middlewares.yml")
+	data, err := os.ReadFile(middlewares)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(data), "# Managed by sdbx") {
+		t.Errorf("middlewares.yml has no managed marker:\n%s", data)
+	}
+	if !strings.Contains(string(data), "my-headers:") || !strings.Contains(string(data), "authelia:") {
+		t.Errorf("middlewares.yml lacks the user or the generated middlewares:\n%s", data)
+	}
+
+	// Edits of the managed file are saved before it is regenerated
+	edited := append(data, []byte("# my edit\n")...)
+	if err := os.WriteFile(middlewares, edited, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := NewGenerator(cfg, tmpDir).Generate(); err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	if saved, err := os.ReadFile(fragment + ".edited"); err != nil || string(saved) != string(edited) {
+		t.Errorf("edited file saved as %q (%v), want the edited content", saved, err)
+	}
+	if data, _ := os.ReadFile(middlewares); strings.Contains(string(data), "# my edit") {
+		t.Error("middlewares.yml kept the hand edit")
+	}
+}
@@ -0,0 +1,63 @@
+package generator
+
+import (
+	"maps"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/maiko/sdbx/internal/mdns"
+)
+
+// mdnsImage runs the responder, sdbx itself as for the web UI
+const mdnsImage = "ghcr.io/maiko/sdbx:latest"
+
+// routerRuleLabel matches the rule labels of Traefik routers
+var routerRuleLabel = regexp.MustCompile(`^traefik\.http\.routers\.[^.]+\.rule=`)
+
+// mdnsHostName matches the .local names of a router rule
+var mdnsHostName = regexp.MustCompile("`([a-z0-9-]+\\.local)`")
+
+// mdnsService builds the responder of mdns.enabled. It runs on the host
+// network, where mDNS multicast reaches the LAN, and answers the names of
+// mdns.NamesFile, which it reads again when regeneration changes it.
+func (g *ComposeGenerator) mdnsService() ComposeService {
+	command := "mdns serve --names /mdns/names"
+	if g.Config.MDNS.Address != "" {
+		command += " --address " + g.Config.MDNS.Address
+	}
+	svc := ComposeService{
+		Image:         mdnsImage,
+		ContainerName: "sdbx-mdns",
+		Restart:       "unless-stopped",
+		NetworkMode:   "host",
+		Command:       command,
+		Volumes:       []string{"./" + filepath.Dir(mdns.NamesFile) + ":/mdns:ro"},
+		Labels:        ownershipLabels(watchtowerLabels(true, g.Config.UpdatePolicy("mdns")), "mdns", "", "sdbx"),
+	}
+	svc.Logging = g.buildLogging("mdns", nil)
+	return svc
+}
+
+// mdnsNames lists the .local names Traefik routes, from the router rules of
+// the services, for mdns.NamesFile
+func mdnsNames(compose *ComposeFile) []byte {
+	names := make(map[string]bool)
+	for _, svc := range compose.Services {
+		for _, label := range svc.Labels {
+			if !routerRuleLabel.MatchString(label) {
+				continue
+			}
+			for _, m := range mdnsHostName.FindAllStringSubmatch(label, -1) {
+				names[m[1]] = true
+			}
+		}
+	}
+	var b strings.Builder
+	b.WriteString("# .local names answered by the mDNS responder, generated by sdbx\n")
+	for _, name := range slices.Sorted(maps.Keys(names)) {
+		b.WriteString(name + "\n")
+	}
+	return []byte(b.String())
+}
@@ -0,0 +1,57 @@
+package generator
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/maiko/sdbx/internal/config"
+	"github.com/maiko/sdbx/internal/registry"
+)
+
+func TestMDNS(t *testing.T) {
+	cfg := &config.Config{
+		Domain:  "sdbx.lan",
+		Expose:  config.ExposeConfig{Mode: config.ExposeModeLAN},
+		Routing: config.RoutingConfig{Strategy: config.RoutingStrategyPath, BaseDomain: "sdbx"},
+		MDNS:    config.MDNSConfig{Enabled: true, Address: "192.168.1.10"},
+	}
+	radarr := &registry.ServiceDefinition{
+		Metadata: registry.ServiceMetadata{Name: "radarr"},
+		Routing:  registry.RoutingConfig{Enabled: true, Port: 7878, Subdomain: "radarr", Path: "/radarr"},
+	}
+	plex := &registry.ServiceDefinition{
+		Metadata: registry.ServiceMetadata{Name: "plex"},
+		Routing:  registry.RoutingConfig{Enabled: true, Port: 32400, Subdomain: "plex", ForceSubdomain: true},
+	}
+	if got, want := routerRule(cfg, radarr), "Host(`sdbx.sdbx.lan`, `sdbx.local`) && PathPrefix(`/radarr`)"; got != want {
+		t.Errorf("routerRule(radarr) = %q, want %q", got, want)
+	}
+	if got, want := routerRule(cfg, plex), "Host(`plex.sdbx.lan`, `plex.local`)"; got != want {
+		t.Errorf("routerRule(plex) = %q, want %q", got, want)
+	}
+
+	g := NewComposeGenerator(cfg, nil, nil)
+	svc := g.mdnsService()
+	if svc.NetworkMode != "host" || svc.Command != "mdns serve --names /mdns/names --address 192.168.1.10" {
+		t.Errorf("mdns service = %+v, want the responder on the host network", svc)
+	}
+	if !slices.Contains(svc.Volumes, "./configs/mdns:/mdns:ro") {
+		t.Errorf("mdns volumes = %v, want the names directory", svc.Volumes)
+	}
+
+	compose := &ComposeFile{Services: map[string]ComposeService{
+		"radarr": {Labels: g.buildTraefikLabels(radarr, TemplateContext{})},
+		"plex":   {Labels: g.buildTraefikLabels(plex, TemplateContext{})},
+		"mdns":   svc,
+	}}
+	names := string(mdnsNames(compose))
+	if !strings.HasSuffix(names, "\nplex.local\nsdbx.local\n") {
+		t.Errorf("mdnsNames() = %q, want plex.local and sdbx.local", names)
+	}
+
+	cfg.MDNS.Enabled = false
+	if got, want := routerRule(cfg, plex), "Host(`plex.sdbx.lan`)"; got != want {
+		t.Errorf("routerRule(plex) without mdns = %q, want %q", got, want)
+	}
+}
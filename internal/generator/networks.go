@@ -0,0 +1,131 @@
+package generator
+
+import (
+	"fmt"
+	"maps"
+	"net/netip"
+	"slices"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/maiko/sdbx/internal/config"
+)
+
+// composeNetworkAttachment is a network of a service in the mapping form
+type composeNetworkAttachment struct {
+	IPv4Address string `yaml:"ipv4_address,omitempty"`
+}
+
+// MarshalYAML writes the networks of a service with a static address as a
+// mapping, and as the short list otherwise
+func (s ComposeService) MarshalYAML() (interface{}, error) {
+	type plain ComposeService
+	if len(s.NetworkAddresses) == 0 {
+		return plain(s), nil
+	}
+
+	var node yaml.Node
+	if err := node.Encode(plain(s)); err != nil {
+		return nil, err
+	}
+	attachments := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	for _, name := range s.Networks {
+		var value yaml.Node
+		if err := value.Encode(composeNetworkAttachment{IPv4Address: s.NetworkAddresses[name]}); err != nil {
+			return nil, err
+		}
+		attachments.Content = append(attachments.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: name}, &value)
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == "networks" {
+			node.Content[i+1] = attachments
+		}
+	}
+	return &node, nil
+}
+
+// UnmarshalYAML reads the networks of a service in both forms
+func (s *ComposeService) UnmarshalYAML(value *yaml.Node) error {
+	type plain ComposeService
+	node := *value
+	var attachments *yaml.Node
+	if value.Kind == yaml.MappingNode {
+		node.Content = nil
+		for i := 0; i+1 < len(value.Content); i += 2 {
+			if value.Content[i].Value == "networks" && value.Content[i+1].Kind == yaml.MappingNode {
+				attachments = value.Content[i+1]
+				continue
+			}
+			node.Content = append(node.Content, value.Content[i], value.Content[i+1])
+		}
+	}
+	if err := node.Decode((*plain)(s)); err != nil {
+		return err
+	}
+	if attachments == nil {
+		return nil
+	}
+
+	for i := 0; i+1 < len(attachments.Content); i += 2 {
+		name := attachments.Content[i].Value
+		var attachment composeNetworkAttachment
+		if err := attachments.Content[i+1].Decode(&attachment); err != nil {
+			return fmt.Errorf("networks.%s: %w", name, err)
+		}
+		s.Networks = append(s.Networks, name)
+		if attachment.IPv4Address != "" {
+			if s.NetworkAddresses == nil {
+				s.NetworkAddresses = make(map[string]string)
+			}
+			s.NetworkAddresses[name] = attachment.IPv4Address
+		}
+	}
+	return nil
+}
+
+// addSubnets pins the subnets configured in the networks section
+func (g *ComposeGenerator) addSubnets(compose *ComposeFile) {
+	for name, network := range compose.Networks {
+		if subnet := g.Config.Networks[name].Subnet; subnet != "" {
+			network.IPAM = &ComposeIPAM{Config: []ComposeIPAMPool{{Subnet: subnet}}}
+			compose.Networks[name] = network
+		}
+	}
+}
+
+// checkStaticAddresses fails when a static address is not in the configured
+// subnet of its network, is reserved by Docker, or is given to two services
+func (g *ComposeGenerator) checkStaticAddresses(compose *ComposeFile) error {
+	owners := make(map[netip.Addr]string)
+	for _, name := range slices.Sorted(maps.Keys(compose.Services)) {
+		svc := compose.Services[name]
+		for _, network := range slices.Sorted(maps.Keys(svc.NetworkAddresses)) {
+			address := svc.NetworkAddresses[network]
+			if !slices.Contains(svc.Networks, network) {
+				return fmt.Errorf("service %s: static address %s is on the %s network, which the service does not join", name, address, network)
+			}
+			addr, err := netip.ParseAddr(address)
+			if err != nil || !addr.Is4() {
+				return fmt.Errorf("service %s: invalid IPv4 address %q", name, address)
+			}
+			if g.Config.Networks[network].Subnet == "" {
+				return fmt.Errorf("service %s: static address %s needs a subnet for the %s network\n\n  Try: set networks.%s.subnet in .sdbx.yaml (e.g. 172.30.0.0/24)", name, address, network, network)
+			}
+			subnet, err := g.Config.Networks[network].Prefix()
+			if err != nil {
+				return fmt.Errorf("networks.%s: %w", network, err)
+			}
+			if !subnet.Contains(addr) {
+				return fmt.Errorf("service %s: static address %s is outside networks.%s.subnet %s", name, address, network, subnet)
+			}
+			if addr == subnet.Addr() || addr == subnet.Addr().Next() || addr == config.BroadcastAddr(subnet) {
+				return fmt.Errorf("service %s: static address %s is reserved in %s (network, gateway or broadcast address)", name, address, subnet)
+			}
+			if owner, taken := owners[addr]; taken {
+				return fmt.Errorf("services %s and %s both use static address %s", owner, name, address)
+			}
+			owners[addr] = name
+		}
+	}
+	return nil
+}
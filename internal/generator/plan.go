@@ -0,0 +1,203 @@
+package generator
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/maiko/sdbx/internal/config"
+	"github.com/maiko/sdbx/internal/registry"
+)
+
+// Plan is what regenerating a project with a config would change
+type Plan struct {
+	Added     []string          `json:"added"`     // Services created
+	Removed   []string          `json:"removed"`   // Services removed
+	Restarted []string          `json:"restarted"` // Services whose definition or config files change
+	Files     map[string]string `json:"files"`     // Unified diff of each changed file
+}
+
+// Empty reports whether the plan changes nothing
+func (p *Plan) Empty() bool {
+	return len(p.Files) == 0
+}
+
+// NewPlan renders the project for cfg and compares it with the files in
+// projectDir. Nothing in projectDir is written.
+func NewPlan(cfg *config.Config, reg *registry.Registry, projectDir string) (*Plan, error) {
+	plan := &Plan{Added: []string{}, Removed: []string{}, Restarted: []string{}}
+	var after map[string]ComposeService
+	err := renderScratch(cfg, reg, projectDir, func(scratch string) error {
+		var err error
+		plan.Files, err = diffScratch(scratch, projectDir)
+		after = composeServices(filepath.Join(scratch, "compose.yaml"))
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	before := composeServices(filepath.Join(projectDir, "compose.yaml"))
+
+	restarted := make(map[string]bool)
+	for name, svc := range after {
+		prev, ok := before[name]
+		switch {
+		case !ok:
+			plan.Added = append(plan.Added, name)
+		case !reflect.DeepEqual(prev, svc):
+			restarted[name] = true
+		}
+	}
+	for name := range before {
+		if _, ok := after[name]; !ok {
+			plan.Removed = append(plan.Removed, name)
+		}
+	}
+
+	// Config files are read at startup, so their service restarts
+	for file := range plan.Files {
+		parts := strings.Split(file, "/")
+		if len(parts) < 3 || parts[0] != "configs" {
+			continue
+		}
+		_, existed := before[parts[1]]
+		_, exists := after[parts[1]]
+		if existed && exists {
+			restarted[parts[1]] = true
+		}
+	}
+	for name := range restarted {
+		plan.Restarted = append(plan.Restarted, name)
+	}
+
+	sort.Strings(plan.Added)
+	sort.Strings(plan.Removed)
+	sort.Strings(plan.Restarted)
+	return plan, nil
+}
+
+// RenderDiff renders the project into a scratch directory and diffs every
+// generated file against the project, keyed by slash-separated path
+func RenderDiff(cfg *config.Config, reg *registry.Registry, projectDir string) (map[string]string, error) {
+	var diffs map[string]string
+	err := renderScratch(cfg, reg, projectDir, func(scratch string) error {
+		var err error
+		diffs, err = diffScratch(scratch, projectDir)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return diffs, nil
+}
+
+// diffScratch diffs every file rendered in scratch, except secrets and the
+// project state, against the project
+func diffScratch(scratch, projectDir string) (map[string]string, error) {
+	diffs := make(map[string]string)
+	err := filepath.WalkDir(scratch, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(scratch, path)
+		if d.IsDir() {
+			if rel == "secrets" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if rel == config.StateFile {
+			return nil
+		}
+		after, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		before, _ := os.ReadFile(filepath.Join(projectDir, rel))
+		if diff := UnifiedDiff(filepath.ToSlash(rel), before, after); diff != "" {
+			diffs[filepath.ToSlash(rel)] = diff
+		}
+		return nil
+	})
+	return diffs, err
+}
+
+// renderScratch renders the project into a scratch directory, calls fn with
+// it, then removes it
+func renderScratch(cfg *config.Config, reg *registry.Registry, projectDir string, fn func(scratch string) error) error {
+	scratch, err := os.MkdirTemp("", "sdbx-render-")
+	if err != nil {
+		return fmt.Errorf("failed to create scratch directory: %w", err)
+	}
+	defer os.RemoveAll(scratch)
+
+	// Existing secrets, users and user fragments keep rendered files comparable
+	preserved := append([]string{"secrets", "configs/authelia/users_database.yml"}, ManagedFragments()...)
+	for _, rel := range preserved {
+		if err := CopyPath(filepath.Join(projectDir, rel), filepath.Join(scratch, rel)); err != nil {
+			return err
+		}
+	}
+
+	if err := NewGeneratorWithRegistry(cfg, scratch, reg).Generate(); err != nil {
+		return fmt.Errorf("failed to render project: %w", err)
+	}
+	if err := fn(scratch); err != nil {
+		return fmt.Errorf("failed to compare rendered files: %w", err)
+	}
+	return nil
+}
+
+// composeServices returns the services of a compose file, or none if it
+// cannot be read
+func composeServices(path string) map[string]ComposeService {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return map[string]ComposeService{}
+	}
+	compose, err := ParseComposeFile(data)
+	if err != nil || compose.Services == nil {
+		return map[string]ComposeService{}
+	}
+	return compose.Services
+}
+
+// CopyPath copies a file or directory tree if it exists
+func CopyPath(src, dst string) error {
+	info, err := os.Stat(src)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if info.IsDir() {
+		entries, err := os.ReadDir(src)
+		if err != nil {
+			return err
+		}
+		if err := os.MkdirAll(dst, info.Mode().Perm()); err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			if err := CopyPath(filepath.Join(src, entry.Name()), filepath.Join(dst, entry.Name())); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	data, err := os.ReadFile(src)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(dst, data, info.Mode().Perm())
+}
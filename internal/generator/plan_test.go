@@ -0,0 +1,78 @@
+package generator
+
+import (
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/maiko/sdbx/internal/config"
+)
+
+func TestNewPlan(t *testing.T) {
+	projectDir := t.TempDir()
+	cfg := config.DefaultConfig()
+	if err := NewGenerator(cfg, projectDir).Generate(); err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+
+	plan, err := NewPlan(cfg, nil, projectDir)
+	if err != nil {
+		t.Fatalf("NewPlan() error = %v", err)
+	}
+	if !plan.Empty() {
+		t.Errorf("unchanged config has file changes: %v", slices.Collect(maps.Keys(plan.Files)))
+	}
+
+	changed := config.DefaultConfig()
+	changed.Expose.Mode = config.ExposeModeLAN
+	changed.VPNEnabled = true
+	changed.VPNProvider = "nordvpn"
+	plan, err = NewPlan(changed, nil, projectDir)
+	if err != nil {
+		t.Fatalf("NewPlan() error = %v", err)
+	}
+	if !slices.Contains(plan.Added, "gluetun") {
+		t.Errorf("Added = %v, want gluetun", plan.Added)
+	}
+	if !slices.Contains(plan.Removed, "cloudflared") {
+		t.Errorf("Removed = %v, want cloudflared", plan.Removed)
+	}
+	if !slices.Contains(plan.Restarted, "qbittorrent") {
+		t.Errorf("Restarted = %v, want qbittorrent", plan.Restarted)
+	}
+	if _, ok := plan.Files["compose.yaml"]; !ok {
+		t.Error("compose.yaml should change")
+	}
+
+	// Planning leaves the project untouched
+	if again, _ := NewPlan(cfg, nil, projectDir); !again.Empty() {
+		t.Errorf("project changed by planning: %v", slices.Collect(maps.Keys(again.Files)))
+	}
+}
+
+func TestCopyPath(t *testing.T) {
+	src := t.TempDir()
+	dst := t.TempDir()
+
+	if err := os.MkdirAll(filepath.Join(src, "secrets"), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(src, "secrets", "key.txt"), []byte("s3cret"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := CopyPath(filepath.Join(src, "secrets"), filepath.Join(dst, "secrets")); err != nil {
+		t.Fatalf("CopyPath() error = %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(dst, "secrets", "key.txt"))
+	if err != nil || string(data) != "s3cret" {
+		t.Errorf("copied file = %q, %v", data, err)
+	}
+
+	// Missing sources are skipped
+	if err := CopyPath(filepath.Join(src, "missing"), filepath.Join(dst, "missing")); err != nil {
+		t.Errorf("CopyPath() on missing source error = %v", err)
+	}
+}
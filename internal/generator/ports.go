@@ -0,0 +1,176 @@
+package generator
+
+import (
+	"fmt"
+	"maps"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/maiko/sdbx/internal/problem"
+	"github.com/maiko/sdbx/internal/registry"
+)
+
+// portMapping is a compose port mapping publishing a single host port,
+// e.g. "127.0.0.1:3000:3000/tcp"
+type portMapping struct {
+	ip        string
+	host      int
+	container string
+	proto     string
+	suffix    string // "/proto" as written, kept when rewriting
+}
+
+// parsePortMapping parses a mapping with a fixed host port, and returns
+// false for the others (container port only, port ranges)
+func parsePortMapping(mapping string) (portMapping, bool) {
+	spec, proto, found := strings.Cut(mapping, "/")
+	m := portMapping{proto: "tcp"}
+	if found {
+		m.proto, m.suffix = proto, "/"+proto
+	}
+	parts := strings.Split(spec, ":")
+	var host string
+	switch len(parts) {
+	case 2:
+		host, m.container = parts[0], parts[1]
+	case 3:
+		m.ip, host, m.container = parts[0], parts[1], parts[2]
+		if m.ip == "" {
+			m.ip = "0.0.0.0"
+		}
+	default:
+		return m, false
+	}
+	port, err := strconv.Atoi(host)
+	if err != nil || strings.Contains(m.container, "-") {
+		return m, false
+	}
+	m.host = port
+	return m, true
+}
+
+// binding is the host address, port and protocol bound, as hostBinding
+func (m portMapping) binding(port int) string {
+	ip := m.ip
+	if ip == "" {
+		ip = "0.0.0.0"
+	}
+	return fmt.Sprintf("%s:%d/%s", ip, port, m.proto)
+}
+
+// String formats the mapping back, on its current host port
+func (m portMapping) String() string {
+	if m.ip != "" {
+		return fmt.Sprintf("%s:%d:%s%s", m.ip, m.host, m.container, m.suffix)
+	}
+	return fmt.Sprintf("%d:%s%s", m.host, m.container, m.suffix)
+}
+
+// hostPortFree reports whether a host port can be bound right now
+func hostPortFree(port int, proto string) bool {
+	addr := fmt.Sprintf(":%d", port)
+	if proto == "udp" {
+		conn, err := net.ListenPacket("udp", addr)
+		if err != nil {
+			return false
+		}
+		return conn.Close() == nil
+	}
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return false
+	}
+	return ln.Close() == nil
+}
+
+// allocateHostPorts resolves host port conflicts from host_ports.range.
+// Ports recorded in HostPorts by the previous generation are kept first
+// when the mapping still asks for them or they came from the range, so a
+// changed port in a definition or override is not ignored; then each mapping keeps the port it asks for while free, and the ones
+// losing a conflict get the lowest port of the range that no service asks
+// for and the host does not use. HostPorts holds every published port on
+// return, for the lock file. Without a range, ports are left as written
+// and conflicts fail in checkPortConflicts.
+func (g *ComposeGenerator) allocateHostPorts(compose *ComposeFile) error {
+	previous := g.HostPorts
+	g.HostPorts = make(map[string]int)
+
+	type published struct {
+		service string
+		index   int
+		mapping portMapping
+	}
+	var all []published
+	requested := make(map[string]bool)
+	for _, name := range slices.Sorted(maps.Keys(compose.Services)) {
+		for i, port := range compose.Services[name].Ports {
+			if m, ok := parsePortMapping(port); ok {
+				all = append(all, published{name, i, m})
+				requested[m.binding(m.host)] = true
+			}
+		}
+	}
+
+	if !g.Config.HostPorts.IsEnabled() {
+		for _, p := range all {
+			g.HostPorts[registry.HostPortKey(p.service, p.mapping.container, p.mapping.proto)] = p.mapping.host
+		}
+		return nil
+	}
+	lo, hi, err := g.Config.HostPorts.Bounds()
+	if err != nil {
+		return err
+	}
+	portFree := g.portFree
+	if portFree == nil {
+		portFree = hostPortFree
+	}
+
+	used := make(map[string]bool)
+	assigned := make([]bool, len(all))
+	assign := func(i, port int) {
+		p := &all[i]
+		p.mapping.host = port
+		used[p.mapping.binding(port)] = true
+		assigned[i] = true
+		g.HostPorts[registry.HostPortKey(p.service, p.mapping.container, p.mapping.proto)] = port
+		compose.Services[p.service].Ports[p.index] = p.mapping.String()
+	}
+
+	// Assignments of the previous generation stay stable, unless the
+	// mapping now asks for another port than the one it was given
+	for i, p := range all {
+		port, ok := previous[registry.HostPortKey(p.service, p.mapping.container, p.mapping.proto)]
+		if !ok || used[p.mapping.binding(port)] {
+			continue
+		}
+		if port == p.mapping.host || (port >= lo && port <= hi) {
+			assign(i, port)
+		}
+	}
+	// Then requested ports, and the range for the ones already taken
+	for i, p := range all {
+		if assigned[i] {
+			continue
+		}
+		if !used[p.mapping.binding(p.mapping.host)] {
+			assign(i, p.mapping.host)
+			continue
+		}
+		port := 0
+		for candidate := lo; candidate <= hi; candidate++ {
+			binding := p.mapping.binding(candidate)
+			if !used[binding] && !requested[binding] && portFree(candidate, p.mapping.proto) {
+				port = candidate
+				break
+			}
+		}
+		if port == 0 {
+			return problem.Wrap(problem.ErrPortConflict, nil, "host port %d of service %s is taken and host_ports.range %s has no free port left", p.mapping.host, p.service, g.Config.HostPorts.Range)
+		}
+		assign(i, port)
+	}
+	return nil
+}
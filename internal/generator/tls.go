@@ -0,0 +1,92 @@
+package generator
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"slices"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/maiko/sdbx/internal/config"
+)
+
+// traefikCertsDir holds the directories of the custom certificates in the
+// Traefik container
+const traefikCertsDir = "/etc/traefik/certs"
+
+// traefikTLSPath is the dynamic config loading the custom certificates
+const traefikTLSPath = "configs/traefik/dynamic/tls.yml"
+
+// TraefikTLSConfig is the tls section of Traefik's dynamic configuration
+type TraefikTLSConfig struct {
+	TLS struct {
+		Certificates []TraefikCertificate               `yaml:"certificates"`
+		Stores       map[string]TraefikCertificateStore `yaml:"stores"`
+	} `yaml:"tls"`
+}
+
+// TraefikCertificate is a certificate file and its key in the container
+type TraefikCertificate struct {
+	CertFile string `yaml:"certFile"`
+	KeyFile  string `yaml:"keyFile"`
+}
+
+// TraefikCertificateStore holds the certificate served without SNI match
+type TraefikCertificateStore struct {
+	DefaultCertificate *TraefikCertificate `yaml:"defaultCertificate,omitempty"`
+}
+
+// customCertificates maps the certificates of the custom TLS provider into
+// the Traefik container. The directories holding the files are mounted
+// rather than the files, so certificates renewed in place (step ca renew)
+// are seen by Traefik.
+func customCertificates(tls config.TLSConfig) (volumes []string, certs []TraefikCertificate) {
+	var dirs []string
+	containerPath := func(file string) string {
+		dir := filepath.Dir(file)
+		if !filepath.IsAbs(dir) {
+			dir = "./" + filepath.ToSlash(filepath.Clean(dir))
+		}
+		i := slices.Index(dirs, dir)
+		if i < 0 {
+			i = len(dirs)
+			dirs = append(dirs, dir)
+			volumes = append(volumes, fmt.Sprintf("%s:%s/%d:ro", dir, traefikCertsDir, i))
+		}
+		return path.Join(traefikCertsDir, fmt.Sprint(i), filepath.Base(file))
+	}
+	for _, c := range tls.CustomCertificates() {
+		certs = append(certs, TraefikCertificate{CertFile: containerPath(c.CertFile), KeyFile: containerPath(c.KeyFile)})
+	}
+	return volumes, certs
+}
+
+// mountCertificates mounts the custom certificates into Traefik
+func (g *ComposeGenerator) mountCertificates(compose *ComposeFile) {
+	traefik, ok := compose.Services["traefik"]
+	if !ok {
+		return
+	}
+	volumes, _ := customCertificates(g.Config.Expose.TLS)
+	for _, volume := range volumes {
+		// A reused block of the previous generation has them already
+		if !slices.Contains(traefik.Volumes, volume) {
+			traefik.Volumes = append(traefik.Volumes, volume)
+		}
+	}
+	compose.Services["traefik"] = traefik
+}
+
+// GenerateTraefikTLS generates the dynamic config loading the custom
+// certificates. Traefik picks one by SNI; the first is served otherwise.
+func (g *IntegrationsGenerator) GenerateTraefikTLS() ([]byte, error) {
+	_, certs := customCertificates(g.Config.Expose.TLS)
+	var cfg TraefikTLSConfig
+	cfg.TLS.Certificates = certs
+	cfg.TLS.Stores = map[string]TraefikCertificateStore{"default": {}}
+	if len(certs) > 0 {
+		cfg.TLS.Stores["default"] = TraefikCertificateStore{DefaultCertificate: &certs[0]}
+	}
+	return yaml.Marshal(cfg)
+}
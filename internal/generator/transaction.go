@@ -0,0 +1,310 @@
+package generator
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/maiko/sdbx/internal/registry"
+)
+
+// StagingDir is where a generation stages its files before swapping them
+// into the project, relative to the project directory
+const StagingDir = ".sdbx.staging"
+
+// journalFile lists the staged files of a committed generation
+const journalFile = "journal.json"
+
+// transaction collects the files written and removed by a generation and
+// swaps them into the project together. Files are staged in StagingDir and
+// fsynced, then the journal is written: the commit point. Each staged file
+// is then renamed into place. A generation interrupted before the commit
+// point leaves the project untouched; one interrupted after it is completed
+// by the next generation (see recoverGeneration).
+type transaction struct {
+	dir     string
+	writes  []stagedWrite
+	removes []string
+	chmods  map[string]os.FileMode
+	files   map[string]string // Generated files and their checksums, relative to dir
+}
+
+type stagedWrite struct {
+	path string // Relative to the project directory
+	data []byte
+	perm os.FileMode
+}
+
+// journal is the record of a committed generation in StagingDir
+type journal struct {
+	ID        string         `json:"id"`
+	CreatedAt time.Time      `json:"createdAt"`
+	Entries   []journalEntry `json:"entries"`
+}
+
+type journalEntry struct {
+	Path   string `json:"path"`             // Relative to the project directory
+	Staged string `json:"staged,omitempty"` // Staged file, or "" to remove Path
+}
+
+func newTransaction(dir string) *transaction {
+	return &transaction{
+		dir:    dir,
+		chmods: make(map[string]os.FileMode),
+		files:  make(map[string]string),
+	}
+}
+
+// write stages data for path, unless the file already has that content.
+// Paths outside the project directory are written directly.
+func (t *transaction) write(path string, data []byte, perm os.FileMode) error {
+	rel, err := filepath.Rel(t.dir, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return writeFileIfChanged(path, data, perm)
+	}
+
+	sum := sha256.Sum256(data)
+	t.files[filepath.ToSlash(rel)] = hex.EncodeToString(sum[:])
+	t.removes = slices.DeleteFunc(t.removes, func(r string) bool { return r == rel })
+	t.writes = slices.DeleteFunc(t.writes, func(w stagedWrite) bool { return w.path == rel })
+
+	if existing, err := os.ReadFile(path); err == nil && bytes.Equal(existing, data) {
+		return nil
+	}
+	t.writes = append(t.writes, stagedWrite{path: rel, data: data, perm: perm})
+	return nil
+}
+
+// remove removes path when the transaction commits, if it exists
+func (t *transaction) remove(path string) error {
+	rel, err := filepath.Rel(t.dir, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+
+	delete(t.files, filepath.ToSlash(rel))
+	t.writes = slices.DeleteFunc(t.writes, func(w stagedWrite) bool { return w.path == rel })
+	if _, err := os.Lstat(path); err == nil && !slices.Contains(t.removes, rel) {
+		t.removes = append(t.removes, rel)
+	}
+	return nil
+}
+
+// chmod sets the mode of path once the transaction committed, including
+// when its content did not change
+func (t *transaction) chmod(path string, perm os.FileMode) {
+	t.chmods[path] = perm
+}
+
+// commit stages and swaps the files of the transaction into the project,
+// returning the journal of the generation, or nil when nothing changed
+func (t *transaction) commit() (*journal, error) {
+	var j *journal
+	if len(t.writes) > 0 || len(t.removes) > 0 {
+		staging := filepath.Join(t.dir, StagingDir)
+		if err := os.RemoveAll(staging); err != nil {
+			return nil, fmt.Errorf("failed to clear %s: %w", StagingDir, err)
+		}
+		if err := os.MkdirAll(staging, 0o700); err != nil {
+			return nil, fmt.Errorf("failed to create %s: %w", StagingDir, err)
+		}
+
+		j = &journal{ID: time.Now().UTC().Format("20060102T150405.000Z"), CreatedAt: time.Now().UTC()}
+		for i, w := range t.writes {
+			name := fmt.Sprintf("%04d", i)
+			if err := writeSynced(filepath.Join(staging, name), w.data, w.perm); err != nil {
+				return nil, fmt.Errorf("failed to stage %s: %w", w.path, err)
+			}
+			j.Entries = append(j.Entries, journalEntry{Path: w.path, Staged: name})
+		}
+		for _, path := range t.removes {
+			j.Entries = append(j.Entries, journalEntry{Path: path})
+		}
+
+		data, err := json.MarshalIndent(j, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		if err := syncDir(staging); err != nil {
+			return nil, err
+		}
+		// Commit point: from here on the generation is completed, even after a crash
+		if err := writeFileAtomic(filepath.Join(staging, journalFile), data, 0o600); err != nil {
+			return nil, fmt.Errorf("failed to write generation journal: %w", err)
+		}
+		if err := j.apply(t.dir); err != nil {
+			return nil, err
+		}
+	}
+
+	for path, perm := range t.chmods {
+		if err := os.Chmod(path, perm); err != nil {
+			return j, fmt.Errorf("failed to set permissions of %s: %w", path, err)
+		}
+	}
+	return j, nil
+}
+
+// apply renames the staged files of a committed journal into place and
+// removes the staging directory. Files already moved are skipped, so an
+// interrupted apply can be repeated.
+func (j *journal) apply(dir string) error {
+	staging := filepath.Join(dir, StagingDir)
+	parents := map[string]bool{}
+
+	for _, e := range j.Entries {
+		target := filepath.Join(dir, filepath.FromSlash(e.Path))
+		if e.Staged == "" {
+			if err := os.Remove(target); err != nil && !os.IsNotExist(err) {
+				return fmt.Errorf("failed to remove %s: %w", e.Path, err)
+			}
+			parents[filepath.Dir(target)] = true
+			continue
+		}
+
+		if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+			return fmt.Errorf("failed to create directory for %s: %w", e.Path, err)
+		}
+		staged := filepath.Join(staging, e.Staged)
+		err := os.Rename(staged, target)
+		if errors.Is(err, os.ErrNotExist) {
+			continue // Moved before an interruption
+		}
+		if err != nil {
+			// Directories on another filesystem cannot be renamed into
+			data, rerr := os.ReadFile(staged)
+			info, serr := os.Stat(staged)
+			if rerr != nil || serr != nil {
+				return fmt.Errorf("failed to move %s into place: %w", e.Path, err)
+			}
+			if err := writeFileAtomic(target, data, info.Mode().Perm()); err != nil {
+				return fmt.Errorf("failed to move %s into place: %w", e.Path, err)
+			}
+		}
+		parents[filepath.Dir(target)] = true
+	}
+
+	for parent := range parents {
+		if err := syncDir(parent); err != nil {
+			return err
+		}
+	}
+	if err := os.RemoveAll(staging); err != nil {
+		return fmt.Errorf("failed to remove %s: %w", StagingDir, err)
+	}
+	return syncDir(dir)
+}
+
+// recoverGeneration completes a generation interrupted after its commit
+// point, and discards one interrupted before it
+func recoverGeneration(dir string) error {
+	staging := filepath.Join(dir, StagingDir)
+	data, err := os.ReadFile(filepath.Join(staging, journalFile))
+	if os.IsNotExist(err) {
+		if err := os.RemoveAll(staging); err != nil {
+			return fmt.Errorf("failed to remove %s: %w", StagingDir, err)
+		}
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read generation journal: %w", err)
+	}
+
+	var j journal
+	if err := json.Unmarshal(data, &j); err != nil {
+		return fmt.Errorf("failed to parse generation journal: %w", err)
+	}
+	log.Printf("Completing interrupted generation %s (%d files)", j.ID, len(j.Entries))
+	if err := j.apply(dir); err != nil {
+		return fmt.Errorf("failed to complete interrupted generation %s: %w", j.ID, err)
+	}
+	return nil
+}
+
+// recordGeneration records a committed generation with the CLI version and
+// secret values it ran with, the checksums of the generated files and the
+// host ports in the project's lock file, if it has one
+func recordGeneration(dir string, j *journal, files map[string]string, hostPorts map[string]int, secretsHash string) {
+	if j == nil || !registry.LockFileExists(dir) {
+		return
+	}
+	loader := registry.NewLoader()
+	path := registry.GetLockFilePath(dir)
+	lock, err := loader.LoadLockFile(path)
+	if err != nil {
+		log.Printf("Warning: generation %s not recorded in the lock file: %v", j.ID, err)
+		return
+	}
+
+	lock.Metadata.Generation = &registry.GenerationRecord{
+		ID:          j.ID,
+		CommittedAt: j.CreatedAt,
+		Changed:     len(j.Entries),
+		CLIVersion:  registry.CLIVersion(),
+		SecretsHash: secretsHash,
+	}
+	lock.GeneratedFiles = files
+	lock.HostPorts = hostPorts
+	if err := loader.SaveLockFile(path, lock); err != nil {
+		log.Printf("Warning: generation %s not recorded in the lock file: %v", j.ID, err)
+	}
+}
+
+// writeSynced writes a new file and flushes it to disk
+func writeSynced(path string, data []byte, perm os.FileMode) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// writeFileAtomic replaces path with data through a synced temporary file
+// in the same directory, so readers see the old or the new content
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	tmp := filepath.Join(filepath.Dir(path), "."+filepath.Base(path)+".tmp")
+	if err := writeSynced(tmp, data, perm); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return syncDir(filepath.Dir(path))
+}
+
+// syncDir flushes a directory's entries, making renames in it durable
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	// Some filesystems do not support syncing directories
+	if err := d.Sync(); err != nil && !errors.Is(err, errors.ErrUnsupported) && !errors.Is(err, syscall.EINVAL) {
+		return fmt.Errorf("failed to sync %s: %w", dir, err)
+	}
+	return nil
+}
@@ -0,0 +1,242 @@
+package generator
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/maiko/sdbx/internal/config"
+	"github.com/maiko/sdbx/internal/registry"
+)
+
+func TestTransactionCommit(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{"same.txt": "same", "changed.txt": "old", "stale.txt": "stale"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	old := time.Now().Add(-time.Hour).Truncate(time.Second)
+	if err := os.Chtimes(filepath.Join(dir, "same.txt"), old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	tx := newTransaction(dir)
+	for _, err := range []error{
+		tx.write(filepath.Join(dir, "same.txt"), []byte("same"), 0o644),
+		tx.write(filepath.Join(dir, "changed.txt"), []byte("new"), 0o644),
+		tx.write(filepath.Join(dir, "configs/new/file.yml"), []byte("created"), 0o600),
+		tx.remove(filepath.Join(dir, "stale.txt")),
+		tx.remove(filepath.Join(dir, "missing.txt")),
+	} {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Nothing is written before the commit
+	if data, _ := os.ReadFile(filepath.Join(dir, "changed.txt")); string(data) != "old" {
+		t.Errorf("changed.txt = %q before commit", data)
+	}
+
+	j, err := tx.commit()
+	if err != nil {
+		t.Fatalf("commit() error = %v", err)
+	}
+	if j == nil || len(j.Entries) != 3 {
+		t.Fatalf("journal = %+v, want 2 writes and 1 removal", j)
+	}
+	for name, want := range map[string]string{"changed.txt": "new", "configs/new/file.yml": "created"} {
+		if data, _ := os.ReadFile(filepath.Join(dir, name)); string(data) != want {
+			t.Errorf("%s = %q, want %q", name, data, want)
+		}
+	}
+	if info, _ := os.Stat(filepath.Join(dir, "configs/new/file.yml")); info.Mode().Perm() != 0o600 {
+		t.Errorf("file.yml mode = %v, want 0600", info.Mode().Perm())
+	}
+	if info, _ := os.Stat(filepath.Join(dir, "same.txt")); !info.ModTime().Equal(old) {
+		t.Error("unchanged file was rewritten")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "stale.txt")); !os.IsNotExist(err) {
+		t.Error("stale.txt was not removed")
+	}
+	if _, err := os.Stat(filepath.Join(dir, StagingDir)); !os.IsNotExist(err) {
+		t.Error("staging directory was left behind")
+	}
+	if len(tx.files) != 3 || tx.files["configs/new/file.yml"] == "" {
+		t.Errorf("files = %v, want the 3 written files", tx.files)
+	}
+
+	if j, err := newTransaction(dir).commit(); j != nil || err != nil {
+		t.Errorf("empty commit() = %+v, %v", j, err)
+	}
+}
+
+func TestRecoverGeneration(t *testing.T) {
+	staged := func(t *testing.T, dir string, journal bool) {
+		t.Helper()
+		tx := newTransaction(dir)
+		for name, content := range map[string]string{"compose.yaml": "new compose", ".env": "NEW=1"} {
+			if err := tx.write(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if _, err := tx.commit(); err != nil {
+			t.Fatal(err)
+		}
+
+		// Recreate the staging directory as a crash would have left it
+		for name, content := range map[string]string{"compose.yaml": "old compose", ".env": "OLD=1"} {
+			if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		staging := filepath.Join(dir, StagingDir)
+		if err := os.MkdirAll(staging, 0o700); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(staging, "0000"), []byte("new compose"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if journal {
+			// .env (0001) was already moved into place
+			if err := os.WriteFile(filepath.Join(dir, ".env"), []byte("NEW=1"), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			data := `{"id":"interrupted","entries":[{"path":"compose.yaml","staged":"0000"},{"path":".env","staged":"0001"}]}`
+			if err := os.WriteFile(filepath.Join(staging, journalFile), []byte(data), 0o600); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	t.Run("before commit point", func(t *testing.T) {
+		dir := t.TempDir()
+		staged(t, dir, false)
+		if err := recoverGeneration(dir); err != nil {
+			t.Fatal(err)
+		}
+		if data, _ := os.ReadFile(filepath.Join(dir, "compose.yaml")); string(data) != "old compose" {
+			t.Errorf("compose.yaml = %q, want the previous generation", data)
+		}
+		if _, err := os.Stat(filepath.Join(dir, StagingDir)); !os.IsNotExist(err) {
+			t.Error("staging directory was not discarded")
+		}
+	})
+
+	t.Run("after commit point", func(t *testing.T) {
+		dir := t.TempDir()
+		staged(t, dir, true)
+		if err := recoverGeneration(dir); err != nil {
+			t.Fatal(err)
+		}
+		for name, want := range map[string]string{"compose.yaml": "new compose", ".env": "NEW=1"} {
+			if data, _ := os.ReadFile(filepath.Join(dir, name)); string(data) != want {
+				t.Errorf("%s = %q, want %q", name, data, want)
+			}
+		}
+		if _, err := os.Stat(filepath.Join(dir, StagingDir)); !os.IsNotExist(err) {
+			t.Error("staging directory was left behind")
+		}
+	})
+}
+
+func TestGenerateRecordsTransactionInLock(t *testing.T) {
+	tmpDir := t.TempDir()
+	lockPath := registry.GetLockFilePath(tmpDir)
+	lock := &registry.LockFile{
+		APIVersion: registry.APIVersion,
+		Kind:       registry.KindLockFile,
+		Metadata:   registry.LockFileMetadata{Version: registry.LockFileVersion},
+	}
+	if err := registry.NewLoader().SaveLockFile(lockPath, lock); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := NewGenerator(config.DefaultConfig(), tmpDir).Generate(); err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+
+	lock, err := registry.NewLoader().LoadLockFile(lockPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lock.Metadata.Generation == nil || lock.Metadata.Generation.ID == "" || lock.Metadata.Generation.Changed == 0 {
+		t.Errorf("generation = %+v, want the committed transaction", lock.Metadata.Generation)
+	}
+	if lock.Metadata.Generation.CLIVersion != registry.CLIVersion() || lock.Metadata.Generation.SecretsHash == "" {
+		t.Errorf("generation = %+v, want the CLI version and secrets hash", lock.Metadata.Generation)
+	}
+	if lock.GeneratedFiles["compose.yaml"] == "" || lock.GeneratedFiles[".env"] == "" {
+		t.Errorf("generatedFiles = %v, want compose.yaml and .env", lock.GeneratedFiles)
+	}
+}
+
+func TestGenerateContextCanceled(t *testing.T) {
+	tmpDir := t.TempDir()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if err := NewGenerator(config.DefaultConfig(), tmpDir).GenerateContext(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("GenerateContext() error = %v, want context.Canceled", err)
+	}
+	if _, err := os.Stat(filepath.Join(tmpDir, "compose.yaml")); !os.IsNotExist(err) {
+		t.Error("compose.yaml was written by a canceled generation")
+	}
+	if _, err := os.Stat(filepath.Join(tmpDir, StagingDir)); !os.IsNotExist(err) {
+		t.Error("staging directory was left behind")
+	}
+}
+
+func TestGenerateWithEditedLockKeepsHostPorts(t *testing.T) {
+	tmpDir := t.TempDir()
+	lockPath := registry.GetLockFilePath(tmpDir)
+	lock := &registry.LockFile{
+		APIVersion: registry.APIVersion,
+		Kind:       registry.KindLockFile,
+		Metadata:   registry.LockFileMetadata{Version: registry.LockFileVersion},
+	}
+	if err := registry.NewLoader().SaveLockFile(lockPath, lock); err != nil {
+		t.Fatal(err)
+	}
+	cfg := config.DefaultConfig()
+	cfg.HostPorts.Range = "20000-20999"
+	if err := NewGenerator(cfg, tmpDir).Generate(); err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	first, err := registry.NewLoader().LoadLockFile(lockPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(first.HostPorts) == 0 {
+		t.Fatal("first generation recorded no host ports")
+	}
+
+	// A hand edit breaks the checksum but not what generation reads
+	data, _ := os.ReadFile(lockPath)
+	if err := os.WriteFile(lockPath, append(data, []byte("# edited\n")...), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := registry.VerifyLockChecksum(lockPath); !errors.Is(err, registry.ErrLockChecksumMismatch) {
+		t.Fatalf("VerifyLockChecksum() = %v, want a mismatch", err)
+	}
+
+	cfg.Timezone = "America/New_York"
+	if err := NewGenerator(cfg, tmpDir).Generate(); err != nil {
+		t.Fatalf("Generate with an edited lock failed: %v", err)
+	}
+	second, err := registry.NewLoader().LoadLockFile(lockPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !maps.Equal(first.HostPorts, second.HostPorts) {
+		t.Errorf("hostPorts = %v, want %v kept", second.HostPorts, first.HostPorts)
+	}
+	if second.Metadata.Generation == nil || second.Metadata.Generation.ID == first.Metadata.Generation.ID {
+		t.Errorf("generation = %+v, want the new transaction recorded", second.Metadata.Generation)
+	}
+}
@@ -0,0 +1,83 @@
+package generator
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/maiko/sdbx/internal/registry"
+)
+
+// AuthNone marks services Traefik serves without authentication
+const AuthNone = "none"
+
+// ServiceURL is how a routed service is reached, for 'sdbx urls'
+type ServiceURL struct {
+	Service   string   `json:"service"`
+	Category  string   `json:"category"`
+	URL       string   `json:"url"`                  // Through Traefik
+	Auth      string   `json:"auth"`                 // authelia, basic-auth or none
+	APITokens bool     `json:"api_tokens,omitempty"` // sdbx API tokens skip the auth
+	Internal  string   `json:"internal"`             // On the Docker network, e.g. http://sdbx-sonarr:8989
+	Port      int      `json:"port"`                 // Container port Traefik routes to
+	Published []string `json:"published,omitempty"`  // Ports published on the host
+}
+
+// URLs resolves the project and returns its routed services, sorted by
+// name. Services generation skips are left out.
+func (g *Generator) URLs(ctx context.Context) ([]ServiceURL, error) {
+	if g.Registry == nil {
+		var err error
+		g.Registry, err = registry.NewWithDefaults()
+		if err != nil {
+			return nil, fmt.Errorf("failed to create registry: %w", err)
+		}
+	}
+
+	graph, err := g.Registry.Resolve(ctx, g.Config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve services: %w", err)
+	}
+	composeGen := NewComposeGenerator(g.Config, g.Registry, map[string]string{})
+	composeGen.HostPorts = g.lockedHostPorts()
+	compose, err := composeGen.Generate(graph)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate compose file: %w", err)
+	}
+	intGen := NewIntegrationsGenerator(g.Config, nil)
+	intGen.HostPorts = composeGen.HostPorts
+
+	var urls []ServiceURL
+	for _, name := range slices.Sorted(slices.Values(graph.Order)) {
+		def := graph.Services[name].FinalDefinition
+		svc, ok := compose.Services[name]
+		if !ok || !def.Routing.Enabled {
+			continue
+		}
+
+		// Services sharing another's network are reached through it
+		host := svc.ContainerName
+		if shared, ok := strings.CutPrefix(svc.NetworkMode, "service:"); ok {
+			if via, ok := compose.Services[shared]; ok {
+				host = via.ContainerName
+			}
+		}
+
+		u := ServiceURL{
+			Service:   g.Config.ServiceRef(name),
+			Category:  string(def.Metadata.Category),
+			URL:       intGen.getServiceURL(def),
+			Auth:      AuthNone,
+			Internal:  fmt.Sprintf("http://%s:%d", host, def.Routing.Port),
+			Port:      def.Routing.Port,
+			Published: svc.Ports,
+		}
+		if def.Routing.Auth.Required && !def.Routing.Auth.Bypass {
+			u.Auth = authMiddleware(g.Config)
+			u.APITokens = def.Routing.Auth.APITokens
+		}
+		urls = append(urls, u)
+	}
+	return urls, nil
+}
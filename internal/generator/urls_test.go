@@ -0,0 +1,54 @@
+package generator
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/maiko/sdbx/internal/config"
+)
+
+func TestURLs(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Domain = "example.com"
+	cfg.Expose.Mode = config.ExposeModeDirect
+	cfg.VPNEnabled = true
+
+	urls, err := NewGenerator(cfg, t.TempDir()).URLs(context.Background())
+	if err != nil {
+		t.Fatalf("URLs failed: %v", err)
+	}
+	byName := make(map[string]ServiceURL)
+	for _, u := range urls {
+		byName[u.Service] = u
+	}
+	if !slices.IsSortedFunc(urls, func(a, b ServiceURL) int { return strings.Compare(a.Service, b.Service) }) {
+		t.Error("URLs should be sorted by service")
+	}
+
+	plex, ok := byName["plex"]
+	if !ok {
+		t.Fatalf("URLs() missing plex: %+v", urls)
+	}
+	if plex.URL != "https://plex.example.com" || plex.Internal != "http://sdbx-plex:32400" || plex.Port != 32400 {
+		t.Errorf("plex = %+v", plex)
+	}
+
+	// qBittorrent shares gluetun's network and sits behind the auth
+	qbt := byName["qbittorrent"]
+	if qbt.Auth != "authelia" || qbt.Internal != "http://sdbx-gluetun:8080" {
+		t.Errorf("qbittorrent = %+v, want authelia and reached through gluetun", qbt)
+	}
+
+	cfg.Auth.Mode = config.AuthModeBasic
+	urls, err = NewGenerator(cfg, t.TempDir()).URLs(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, u := range urls {
+		if u.Service == "qbittorrent" && u.Auth != "basic-auth" {
+			t.Errorf("qbittorrent auth = %q with basic auth", u.Auth)
+		}
+	}
+}
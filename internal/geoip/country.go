@@ -0,0 +1,78 @@
+package geoip
+
+import (
+	"errors"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/maiko/sdbx/internal/config"
+)
+
+// ErrNoCountryDatabase is returned while no database holding countries has
+// been downloaded
+var ErrNoCountryDatabase = errors.New("no GeoIP country database downloaded (run 'sdbx geoip update')")
+
+// CountryDatabase returns the downloaded database the country allowlists
+// read, relative to the project directory, or "" when none is downloaded
+func CountryDatabase(projectDir string) string {
+	for _, edition := range config.GeoIPCountryEditions {
+		path := filepath.Join(Dir, Filename(edition))
+		if _, err := os.Stat(filepath.Join(projectDir, path)); err == nil {
+			return path
+		}
+	}
+	return ""
+}
+
+// Countries looks up the country of addresses in the downloaded database,
+// reading it again once the updater replaces it
+type Countries struct {
+	ProjectDir string
+
+	mu      sync.Mutex
+	path    string
+	modTime time.Time
+	reader  *Reader
+}
+
+// NewCountries creates a country lookup on the databases of a project
+func NewCountries(projectDir string) *Countries {
+	return &Countries{ProjectDir: projectDir}
+}
+
+// Country returns the ISO 3166-1 code of the country of an address, or ""
+// when the database does not know it
+func (c *Countries) Country(addr netip.Addr) (string, error) {
+	reader, err := c.open()
+	if err != nil {
+		return "", err
+	}
+	return reader.Country(addr)
+}
+
+// open returns the reader of the current database
+func (c *Countries) open() (*Reader, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	path := CountryDatabase(c.ProjectDir)
+	if path == "" {
+		return nil, ErrNoCountryDatabase
+	}
+	info, err := os.Stat(filepath.Join(c.ProjectDir, path))
+	if err != nil {
+		return nil, err
+	}
+	if c.reader != nil && path == c.path && info.ModTime().Equal(c.modTime) {
+		return c.reader, nil
+	}
+	reader, err := Open(filepath.Join(c.ProjectDir, path))
+	if err != nil {
+		return nil, err
+	}
+	c.path, c.modTime, c.reader = path, info.ModTime(), reader
+	return reader, nil
+}
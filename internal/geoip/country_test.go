@@ -0,0 +1,53 @@
+package geoip
+
+import (
+	"errors"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCountries(t *testing.T) {
+	dir := t.TempDir()
+	countries := NewCountries(dir)
+	addr := netip.MustParseAddr("81.2.69.142")
+	if _, err := countries.Country(addr); !errors.Is(err, ErrNoCountryDatabase) {
+		t.Fatalf("without a database: err = %v", err)
+	}
+
+	if err := os.MkdirAll(filepath.Join(dir, Dir), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	write := func(edition, gb string, modTime time.Time) {
+		t.Helper()
+		path := filepath.Join(dir, Dir, Filename(edition))
+		if err := os.WriteFile(path, buildDatabase(t, 24, testNetworks(gb)...), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The city database serves until a country database is downloaded
+	start := time.Now().Add(-time.Hour)
+	write("GeoLite2-City", "81.2.69.0/24", start)
+	if got := CountryDatabase(dir); got != filepath.Join(Dir, "GeoLite2-City.mmdb") {
+		t.Errorf("CountryDatabase = %q", got)
+	}
+	if got, err := countries.Country(addr); err != nil || got != "GB" {
+		t.Errorf("Country = %q, %v, want GB", got, err)
+	}
+	write("GeoLite2-Country", "81.2.70.0/24", start)
+	if got, err := countries.Country(addr); err != nil || got != "" {
+		t.Errorf("Country from the country database = %q, %v, want none", got, err)
+	}
+
+	// A database replaced by the updater is read again
+	write("GeoLite2-Country", "81.2.69.0/24", start.Add(time.Minute))
+	if got, err := countries.Country(addr); err != nil || got != "GB" {
+		t.Errorf("Country after an update = %q, %v, want GB", got, err)
+	}
+}
@@ -0,0 +1,233 @@
+// Package geoip downloads the MaxMind GeoLite databases of the geoip section
+// of .sdbx.yaml and keeps them fresh, for the services whose definition
+// declares requires: geoip.
+package geoip
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/maiko/sdbx/internal/config"
+	"github.com/maiko/sdbx/internal/netproxy"
+	"github.com/maiko/sdbx/internal/notify"
+	"github.com/maiko/sdbx/internal/scheduler"
+	"github.com/maiko/sdbx/internal/secrets"
+)
+
+const (
+	// Dir holds the databases, relative to the project directory
+	Dir = "data/geoip"
+
+	// ContainerDir is where services requiring geoip find the databases
+	ContainerDir = "/geoip"
+
+	// DefaultBaseURL is MaxMind's download server
+	DefaultBaseURL = "https://download.maxmind.com"
+
+	// CheckInterval is how often the scheduler looks for stale databases
+	CheckInterval = time.Hour
+
+	// downloadTimeout bounds the download of a single database
+	downloadTimeout = 5 * time.Minute
+)
+
+// Database is a downloaded GeoIP database
+type Database struct {
+	Edition string    `json:"edition"`
+	Path    string    `json:"path"` // Relative to the project directory
+	Updated time.Time `json:"updated,omitempty"`
+	Size    int64     `json:"size,omitempty"`
+	Error   string    `json:"error,omitempty"` // Why the last download failed
+}
+
+// Filename returns the database file of an edition
+func Filename(edition string) string {
+	return edition + ".mmdb"
+}
+
+// Updater downloads the databases of a geoip section
+type Updater struct {
+	ProjectDir string
+	Config     config.GeoIPConfig
+	Client     *http.Client
+	BaseURL    string
+
+	// Overridable for tests
+	Now func() time.Time
+}
+
+// NewUpdater creates an updater for cfg's geoip section
+func NewUpdater(projectDir string, cfg *config.Config) *Updater {
+	return &Updater{
+		ProjectDir: projectDir,
+		Config:     cfg.GeoIP,
+		Client:     netproxy.Client(cfg.Proxy.For(config.ProxyGeoIP), cfg.Proxy.NoProxy, downloadTimeout),
+		BaseURL:    DefaultBaseURL,
+		Now:        time.Now,
+	}
+}
+
+// Status returns the databases of the configured editions, with the time
+// they were downloaded; Updated is zero for a database not downloaded yet
+func (u *Updater) Status() []Database {
+	var databases []Database
+	for _, edition := range u.Config.EditionIDs() {
+		db := Database{Edition: edition, Path: path.Join(Dir, Filename(edition))}
+		if info, err := os.Stat(filepath.Join(u.ProjectDir, db.Path)); err == nil {
+			db.Updated, db.Size = info.ModTime(), info.Size()
+		}
+		databases = append(databases, db)
+	}
+	return databases
+}
+
+// Update downloads the databases that are missing or older than the refresh
+// interval, or all of them with force. A failed download keeps the previous
+// database and does not stop the others; the returned error joins them.
+func (u *Updater) Update(ctx context.Context, force bool) ([]Database, error) {
+	if !u.Config.IsEnabled() {
+		return nil, fmt.Errorf("no MaxMind account configured in the geoip section")
+	}
+	licenseKey, err := u.licenseKey()
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Join(u.ProjectDir, Dir), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create %s: %w", Dir, err)
+	}
+
+	var updated []Database
+	var errs []error
+	for _, db := range u.Status() {
+		if !force && !db.Updated.IsZero() && u.Now().Sub(db.Updated) < u.Config.RefreshInterval() {
+			continue
+		}
+		if err := u.download(ctx, db, licenseKey); err != nil {
+			db.Error = err.Error()
+			errs = append(errs, fmt.Errorf("%s: %w", db.Edition, err))
+		} else if info, err := os.Stat(filepath.Join(u.ProjectDir, db.Path)); err == nil {
+			db.Updated, db.Size = info.ModTime(), info.Size()
+		}
+		updated = append(updated, db)
+	}
+	return updated, errors.Join(errs...)
+}
+
+// licenseKey reads the MaxMind license key from its secret
+func (u *Updater) licenseKey() (string, error) {
+	filename := config.GeoIPLicenseKeySecret + ".txt"
+	key, err := secrets.ReadSecret(filepath.Join(u.ProjectDir, "secrets"), filename)
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", &secrets.SecretNotConfiguredError{Filename: filename}
+	}
+	return key, err
+}
+
+// download fetches the tar.gz archive of an edition and writes the database
+// it contains in place of the previous one
+func (u *Updater) download(ctx context.Context, db Database, licenseKey string) error {
+	url := fmt.Sprintf("%s/geoip/databases/%s/download?suffix=tar.gz", strings.TrimSuffix(u.BaseURL, "/"), db.Edition)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.SetBasicAuth(u.Config.AccountID, licenseKey)
+
+	resp, err := u.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized:
+		return fmt.Errorf("MaxMind rejected the account ID or license key")
+	case resp.StatusCode >= 300:
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	target := filepath.Join(u.ProjectDir, db.Path)
+	tmp := target + ".tmp"
+	if err := extractDatabase(resp.Body, Filename(db.Edition), tmp); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, target)
+}
+
+// extractDatabase writes the file named name of a tar.gz archive to dst
+func extractDatabase(r io.Reader, name, dst string) error {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return fmt.Errorf("invalid archive: %w", err)
+	}
+	defer gz.Close()
+
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return fmt.Errorf("archive has no %s", name)
+		}
+		if err != nil {
+			return fmt.Errorf("invalid archive: %w", err)
+		}
+		// Archives hold <edition>_<date>/<edition>.mmdb
+		if hdr.Typeflag != tar.TypeReg || path.Base(hdr.Name) != name {
+			continue
+		}
+		f, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(f, tr); err != nil {
+			f.Close()
+			return err
+		}
+		return f.Close()
+	}
+}
+
+// Job returns a scheduler job downloading the stale databases of the geoip
+// section, and notifying when a download fails. Failed downloads are retried
+// every CheckInterval but notified at most once per refresh interval.
+func Job(projectDir string) scheduler.Job {
+	var notified time.Time
+	return scheduler.Job{
+		Name:     "geoip",
+		Interval: CheckInterval,
+		Run: func(ctx context.Context) error {
+			cfg, err := config.Load()
+			if err != nil {
+				return fmt.Errorf("failed to load config: %w", err)
+			}
+			if !cfg.GeoIP.IsEnabled() || cfg.Offline {
+				return nil
+			}
+			_, err = NewUpdater(projectDir, cfg).Update(ctx, false)
+			if err != nil && time.Since(notified) >= cfg.GeoIP.RefreshInterval() {
+				notified = time.Now()
+				msg := notify.Message{
+					Title:    "GeoIP database update failed",
+					Body:     err.Error(),
+					Severity: notify.SeverityWarning,
+					Source:   "geoip",
+				}
+				if nerr := notify.New(projectDir, cfg).Send(ctx, msg); nerr != nil {
+					err = errors.Join(err, fmt.Errorf("failed to notify: %w", nerr))
+				}
+			}
+			return err
+		},
+	}
+}
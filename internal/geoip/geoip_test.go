@@ -0,0 +1,120 @@
+package geoip
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/maiko/sdbx/internal/config"
+	"github.com/maiko/sdbx/internal/problem"
+)
+
+// archive builds a MaxMind-style tar.gz holding <edition>_<date>/<edition>.mmdb
+func archive(t *testing.T, edition, content string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for name, body := range map[string]string{
+		edition + "_20261013/LICENSE.txt":          "license",
+		edition + "_20261013/" + edition + ".mmdb": content,
+	} {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0o644, Size: int64(len(body)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(body)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestUpdate(t *testing.T) {
+	var downloads []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, key, ok := r.BasicAuth(); !ok || user != "123456" || key != "license" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		edition := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/geoip/databases/"), "/download")
+		downloads = append(downloads, edition)
+		if edition == "GeoLite2-Broken" {
+			_, _ = w.Write([]byte("not an archive"))
+			return
+		}
+		_, _ = w.Write(archive(t, edition, "db of "+edition))
+	}))
+	defer server.Close()
+
+	tmpDir := t.TempDir()
+	cfg := config.DefaultConfig()
+	cfg.GeoIP = config.GeoIPConfig{AccountID: "123456", Editions: []string{"GeoLite2-City", "GeoLite2-Broken"}}
+	u := NewUpdater(tmpDir, cfg)
+	u.BaseURL = server.URL
+
+	// The license key is a manual secret
+	if _, err := u.Update(context.Background(), false); !errors.Is(err, problem.ErrSecretMissing) {
+		t.Fatalf("Update error = %v, want a missing secret", err)
+	}
+	if err := os.MkdirAll(filepath.Join(tmpDir, "secrets"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(tmpDir, "secrets", "maxmind_license_key.txt"), []byte("license\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	updated, err := u.Update(context.Background(), false)
+	if err == nil || !strings.Contains(err.Error(), "GeoLite2-Broken: invalid archive") {
+		t.Errorf("Update error = %v, want the broken edition to fail", err)
+	}
+	if len(updated) != 2 || updated[0].Updated.IsZero() || updated[1].Error == "" {
+		t.Errorf("Update() = %+v", updated)
+	}
+	data, err := os.ReadFile(filepath.Join(tmpDir, Dir, "GeoLite2-City.mmdb"))
+	if err != nil || string(data) != "db of GeoLite2-City" {
+		t.Errorf("GeoLite2-City.mmdb = %q (%v)", data, err)
+	}
+	if _, err := os.Stat(filepath.Join(tmpDir, Dir, "GeoLite2-Broken.mmdb.tmp")); !os.IsNotExist(err) {
+		t.Error("the failed download left a temporary file")
+	}
+
+	// Fresh databases are kept, unless forced
+	cfg.GeoIP.Editions = []string{"GeoLite2-City"}
+	u = NewUpdater(tmpDir, cfg)
+	u.BaseURL = server.URL
+	downloads = nil
+	if updated, err := u.Update(context.Background(), false); err != nil || len(updated) != 0 || len(downloads) != 0 {
+		t.Errorf("Update() = %+v, %v with a fresh database, downloads %v", updated, err, downloads)
+	}
+	u.Now = func() time.Time { return time.Now().Add(config.DefaultGeoIPRefresh) }
+	if updated, err := u.Update(context.Background(), false); err != nil || len(updated) != 1 {
+		t.Errorf("Update() = %+v, %v with a stale database", updated, err)
+	}
+	u.Now = time.Now
+	if updated, err := u.Update(context.Background(), true); err != nil || len(updated) != 1 {
+		t.Errorf("Update(force) = %+v, %v", updated, err)
+	}
+
+	// A rejected license key is reported as such
+	cfg.GeoIP.AccountID = "654321"
+	u = NewUpdater(tmpDir, cfg)
+	u.BaseURL = server.URL
+	if _, err := u.Update(context.Background(), true); err == nil || !strings.Contains(err.Error(), "rejected") {
+		t.Errorf("Update error = %v, want the credentials rejected", err)
+	}
+}
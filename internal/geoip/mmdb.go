@@ -0,0 +1,312 @@
+package geoip
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"net/netip"
+	"os"
+)
+
+// metadataMarker precedes the metadata map at the end of a MaxMind DB file
+var metadataMarker = []byte("\xAB\xCD\xEFMaxMind.com")
+
+// dataSectionSeparator is the block of zeros between the search tree and
+// the data section
+const dataSectionSeparator = 16
+
+// maxDecodeDepth bounds nested maps, arrays and pointers, so a corrupt
+// file cannot recurse forever
+const maxDecodeDepth = 32
+
+// Reader looks up addresses in a MaxMind DB file (.mmdb), the format of
+// the GeoLite databases: a binary search tree on the address bits whose
+// leaves point into a data section of typed values
+type Reader struct {
+	tree       []byte
+	data       decoder
+	nodeCount  uint
+	recordSize uint
+	ipVersion  uint
+	ipv4Start  uint // Node reached after the 96 zero bits of ::a.b.c.d
+}
+
+// Open reads a database file
+func Open(path string) (*Reader, error) {
+	buf, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return NewReader(buf)
+}
+
+// NewReader parses a database held in memory
+func NewReader(buf []byte) (*Reader, error) {
+	i := bytes.LastIndex(buf, metadataMarker)
+	if i < 0 {
+		return nil, errors.New("not a MaxMind database: no metadata")
+	}
+	value, _, err := decoder{buf: buf[i+len(metadataMarker):]}.decode(0, 0)
+	if err != nil {
+		return nil, fmt.Errorf("invalid metadata: %w", err)
+	}
+	metadata, ok := value.(map[string]any)
+	if !ok {
+		return nil, errors.New("invalid metadata: not a map")
+	}
+	field := func(name string) uint {
+		n, _ := metadata[name].(uint64)
+		return uint(n)
+	}
+
+	r := &Reader{nodeCount: field("node_count"), recordSize: field("record_size"), ipVersion: field("ip_version")}
+	if r.recordSize != 24 && r.recordSize != 28 && r.recordSize != 32 {
+		return nil, fmt.Errorf("unsupported record size %d", r.recordSize)
+	}
+	if r.ipVersion != 4 && r.ipVersion != 6 {
+		return nil, fmt.Errorf("unsupported IP version %d", r.ipVersion)
+	}
+	treeSize := r.nodeCount * r.recordSize / 4
+	if treeSize+dataSectionSeparator > uint(i) {
+		return nil, errors.New("invalid database: search tree exceeds the file")
+	}
+	r.tree = buf[:treeSize]
+	r.data = decoder{buf: buf[treeSize+dataSectionSeparator : i]}
+
+	if r.ipVersion == 6 {
+		for bit := 0; bit < 96 && r.ipv4Start < r.nodeCount; bit++ {
+			r.ipv4Start = r.record(r.ipv4Start, 0)
+		}
+	}
+	return r, nil
+}
+
+// Lookup returns the record of an address, nil when the database has none
+func (r *Reader) Lookup(addr netip.Addr) (any, error) {
+	addr = addr.Unmap()
+	var ip []byte
+	node := uint(0)
+	switch {
+	case addr.Is4():
+		a := addr.As4()
+		ip = a[:]
+		if r.ipVersion == 6 {
+			node = r.ipv4Start
+		}
+	case r.ipVersion == 4:
+		return nil, nil
+	default:
+		a := addr.As16()
+		ip = a[:]
+	}
+
+	for i := 0; i < len(ip)*8 && node < r.nodeCount; i++ {
+		node = r.record(node, uint(ip[i/8]>>(7-i%8))&1)
+	}
+	switch {
+	case node == r.nodeCount:
+		return nil, nil
+	case node < r.nodeCount:
+		return nil, errors.New("invalid database: address shorter than the search tree")
+	}
+	value, _, err := r.data.decode(node-r.nodeCount-dataSectionSeparator, 0)
+	return value, err
+}
+
+// Country returns the ISO 3166-1 code of the country of an address, or ""
+// when the database does not know it. Addresses without a country (anycast,
+// satellite providers) get the country they are registered in.
+func (r *Reader) Country(addr netip.Addr) (string, error) {
+	value, err := r.Lookup(addr)
+	if err != nil {
+		return "", err
+	}
+	record, _ := value.(map[string]any)
+	for _, key := range []string{"country", "registered_country"} {
+		country, _ := record[key].(map[string]any)
+		if code, _ := country["iso_code"].(string); code != "" {
+			return code, nil
+		}
+	}
+	return "", nil
+}
+
+// record returns the left (bit 0) or right (bit 1) record of a tree node
+func (r *Reader) record(node, bit uint) uint {
+	size := r.recordSize / 4
+	b := r.tree[node*size : (node+1)*size]
+	switch r.recordSize {
+	case 24:
+		b = b[bit*3:]
+		return uint(b[0])<<16 | uint(b[1])<<8 | uint(b[2])
+	case 28:
+		// The middle byte holds the high nibble of both records
+		if bit == 0 {
+			return uint(b[3]&0xF0)<<20 | uint(b[0])<<16 | uint(b[1])<<8 | uint(b[2])
+		}
+		return uint(b[3]&0x0F)<<24 | uint(b[4])<<16 | uint(b[5])<<8 | uint(b[6])
+	default:
+		return uint(binary.BigEndian.Uint32(b[bit*4:]))
+	}
+}
+
+// decoder reads the typed values of a data section
+type decoder struct {
+	buf []byte
+}
+
+// Data section value types
+const (
+	typePointer = 1
+	typeString  = 2
+	typeDouble  = 3
+	typeBytes   = 4
+	typeUint16  = 5
+	typeUint32  = 6
+	typeMap     = 7
+	typeInt32   = 8
+	typeUint64  = 9
+	typeUint128 = 10
+	typeArray   = 11
+	typeBool    = 14
+	typeFloat   = 15
+)
+
+// decode returns the value at offset and the offset following it. Maps
+// decode to map[string]any, unsigned integers to uint64 (uint128 to its
+// bytes), int32 to int64 and floats to float64.
+func (d decoder) decode(offset uint, depth int) (any, uint, error) {
+	if depth > maxDecodeDepth {
+		return nil, 0, errors.New("values nested too deep")
+	}
+	ctrl, err := d.bytes(offset, 1)
+	if err != nil {
+		return nil, 0, err
+	}
+	offset++
+	typ := uint(ctrl[0] >> 5)
+
+	if typ == typePointer {
+		ss := uint(ctrl[0]>>3) & 3
+		b, err := d.bytes(offset, ss+1)
+		if err != nil {
+			return nil, 0, err
+		}
+		var target uint
+		switch ss {
+		case 0:
+			target = uint(ctrl[0]&7)<<8 | uint(b[0])
+		case 1:
+			target = (uint(ctrl[0]&7)<<16 | uint(b[0])<<8 | uint(b[1])) + 2048
+		case 2:
+			target = (uint(ctrl[0]&7)<<24 | uint(b[0])<<16 | uint(b[1])<<8 | uint(b[2])) + 526336
+		default:
+			target = uint(binary.BigEndian.Uint32(b))
+		}
+		value, _, err := d.decode(target, depth+1)
+		return value, offset + ss + 1, err
+	}
+
+	if typ == 0 {
+		ext, err := d.bytes(offset, 1)
+		if err != nil {
+			return nil, 0, err
+		}
+		typ = 7 + uint(ext[0])
+		offset++
+	}
+
+	size := uint(ctrl[0] & 0x1F)
+	if size >= 29 {
+		n := size - 28
+		b, err := d.bytes(offset, n)
+		if err != nil {
+			return nil, 0, err
+		}
+		offset += n
+		switch n {
+		case 1:
+			size = 29 + uint(b[0])
+		case 2:
+			size = 285 + (uint(b[0])<<8 | uint(b[1]))
+		default:
+			size = 65821 + (uint(b[0])<<16 | uint(b[1])<<8 | uint(b[2]))
+		}
+	}
+
+	switch typ {
+	case typeMap:
+		m := make(map[string]any, min(size, 1024))
+		for range size {
+			key, next, err := d.decode(offset, depth+1)
+			if err != nil {
+				return nil, 0, err
+			}
+			name, ok := key.(string)
+			if !ok {
+				return nil, 0, errors.New("map key is not a string")
+			}
+			if m[name], offset, err = d.decode(next, depth+1); err != nil {
+				return nil, 0, err
+			}
+		}
+		return m, offset, nil
+	case typeArray:
+		a := make([]any, 0, min(size, 1024))
+		for range size {
+			var value any
+			if value, offset, err = d.decode(offset, depth+1); err != nil {
+				return nil, 0, err
+			}
+			a = append(a, value)
+		}
+		return a, offset, nil
+	case typeBool:
+		return size != 0, offset, nil
+	}
+
+	b, err := d.bytes(offset, size)
+	if err != nil {
+		return nil, 0, err
+	}
+	offset += size
+	switch typ {
+	case typeString:
+		return string(b), offset, nil
+	case typeBytes, typeUint128:
+		return bytes.Clone(b), offset, nil
+	case typeDouble:
+		if size != 8 {
+			return nil, 0, fmt.Errorf("invalid double size %d", size)
+		}
+		return math.Float64frombits(binary.BigEndian.Uint64(b)), offset, nil
+	case typeFloat:
+		if size != 4 {
+			return nil, 0, fmt.Errorf("invalid float size %d", size)
+		}
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(b))), offset, nil
+	case typeUint16, typeUint32, typeUint64, typeInt32:
+		if size > 8 {
+			return nil, 0, fmt.Errorf("invalid integer size %d", size)
+		}
+		var n uint64
+		for _, c := range b {
+			n = n<<8 | uint64(c)
+		}
+		if typ == typeInt32 {
+			return int64(int32(uint32(n))), offset, nil
+		}
+		return n, offset, nil
+	}
+	return nil, 0, fmt.Errorf("unsupported data type %d", typ)
+}
+
+// bytes returns n bytes at offset
+func (d decoder) bytes(offset, n uint) ([]byte, error) {
+	if offset > uint(len(d.buf)) || n > uint(len(d.buf))-offset {
+		return nil, errors.New("invalid database: value past the end of the data")
+	}
+	return d.buf[offset : offset+n], nil
+}
@@ -0,0 +1,182 @@
+package geoip
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net/netip"
+	"testing"
+)
+
+// Data section encoders of the test databases
+func encodeString(s string) []byte {
+	return append([]byte{typeString<<5 | byte(len(s))}, s...)
+}
+
+func encodeMap(pairs ...[]byte) []byte {
+	b := []byte{typeMap<<5 | byte(len(pairs)/2)}
+	for _, p := range pairs {
+		b = append(b, p...)
+	}
+	return b
+}
+
+func encodeUint(typ byte, n uint64, size int) []byte {
+	b := []byte{typ<<5 | byte(size)}
+	for i := size - 1; i >= 0; i-- {
+		b = append(b, byte(n>>(8*i)))
+	}
+	return b
+}
+
+func encodePointer(offset int) []byte {
+	return []byte{typePointer<<5 | byte(offset>>8)&7, byte(offset)}
+}
+
+// countryRecord is the record of GeoLite2-Country for an address
+func countryRecord(key, code string) []byte {
+	return encodeMap(encodeString(key), encodeMap(encodeString("iso_code"), encodeString(code)))
+}
+
+// testNetwork is a network of a test database with its data section record
+type testNetwork struct {
+	prefix string
+	record []byte
+}
+
+// buildDatabase writes an IPv6 MaxMind database mapping networks to their
+// records, which are stored in order in the data section
+func buildDatabase(t *testing.T, recordSize int, networks ...testNetwork) []byte {
+	t.Helper()
+	const empty, dataRef = -1, 1 << 30
+	nodes := [][2]int{{empty, empty}}
+	var data []byte
+	for _, network := range networks {
+		prefix := netip.MustParsePrefix(network.prefix)
+		ip, bits := prefix.Addr().As16(), prefix.Bits()
+		if prefix.Addr().Is4() {
+			// IPv4 networks live under ::/96
+			ip = [16]byte{}
+			v4 := prefix.Addr().As4()
+			copy(ip[12:], v4[:])
+			bits += 96
+		}
+		node := 0
+		for i := range bits {
+			bit := ip[i/8] >> (7 - i%8) & 1
+			if i == bits-1 {
+				nodes[node][bit] = dataRef + len(data)
+				break
+			}
+			if nodes[node][bit] == empty {
+				nodes = append(nodes, [2]int{empty, empty})
+				nodes[node][bit] = len(nodes) - 1
+			}
+			node = nodes[node][bit]
+		}
+		data = append(data, network.record...)
+	}
+
+	count := len(nodes)
+	value := func(record int) uint32 {
+		switch {
+		case record == empty:
+			return uint32(count)
+		case record >= dataRef:
+			return uint32(count + dataSectionSeparator + record - dataRef)
+		}
+		return uint32(record)
+	}
+	var buf []byte
+	for _, node := range nodes {
+		left, right := value(node[0]), value(node[1])
+		switch recordSize {
+		case 24:
+			buf = append(buf, byte(left>>16), byte(left>>8), byte(left), byte(right>>16), byte(right>>8), byte(right))
+		case 28:
+			buf = append(buf, byte(left>>16), byte(left>>8), byte(left), byte(left>>24)<<4|byte(right>>24), byte(right>>16), byte(right>>8), byte(right))
+		default:
+			buf = binary.BigEndian.AppendUint32(buf, left)
+			buf = binary.BigEndian.AppendUint32(buf, right)
+		}
+	}
+	buf = append(buf, make([]byte, dataSectionSeparator)...)
+	buf = append(buf, data...)
+	buf = append(buf, metadataMarker...)
+	return append(buf, encodeMap(
+		encodeString("node_count"), encodeUint(typeUint32, uint64(count), 4),
+		encodeString("record_size"), encodeUint(typeUint16, uint64(recordSize), 2),
+		encodeString("ip_version"), encodeUint(typeUint16, 6, 2),
+		encodeString("database_type"), encodeString("GeoLite2-Country"),
+	)...)
+}
+
+// testNetworks places a network in GB, one in FR through a pointer to
+// another record's country, and one with only a registered country
+func testNetworks(gb string) []testNetwork {
+	fr := countryRecord("country", "FR")
+	// The country map of fr starts after its control byte and key
+	frCountry := 1 + len(encodeString("country"))
+	return []testNetwork{
+		{"2001:db8:1::/48", fr},
+		{gb, countryRecord("country", "GB")},
+		{"2001:db8::/48", encodeMap(encodeString("country"), encodePointer(frCountry))},
+		{"1.1.1.0/24", countryRecord("registered_country", "US")},
+	}
+}
+
+func TestReaderCountry(t *testing.T) {
+	tests := []struct {
+		addr string
+		want string
+	}{
+		{"81.2.69.142", "GB"},
+		{"::ffff:81.2.69.1", "GB"},
+		{"2001:db8::1", "FR"},
+		{"2001:db8:1::1", "FR"},
+		{"1.1.1.1", "US"},
+		{"8.8.8.8", ""},
+		{"2001:db9::1", ""},
+	}
+	for _, recordSize := range []int{24, 28, 32} {
+		reader, err := NewReader(buildDatabase(t, recordSize, testNetworks("81.2.69.0/24")...))
+		if err != nil {
+			t.Fatalf("record size %d: %v", recordSize, err)
+		}
+		for _, tt := range tests {
+			got, err := reader.Country(netip.MustParseAddr(tt.addr))
+			if err != nil || got != tt.want {
+				t.Errorf("record size %d: Country(%s) = %q, %v, want %q", recordSize, tt.addr, got, err, tt.want)
+			}
+		}
+	}
+}
+
+func TestReaderRecord28(t *testing.T) {
+	// The middle byte holds the high nibbles of both records
+	r := &Reader{recordSize: 28, tree: []byte{0x12, 0x34, 0x56, 0xAB, 0x78, 0x9A, 0xBC}}
+	if left, right := r.record(0, 0), r.record(0, 1); left != 0xA123456 || right != 0xB789ABC {
+		t.Errorf("records = %#x, %#x", left, right)
+	}
+}
+
+func TestReaderInvalid(t *testing.T) {
+	db := buildDatabase(t, 24, testNetworks("81.2.69.0/24")...)
+	for name, buf := range map[string][]byte{
+		"not a database": []byte("not a database"),
+		"no search tree": db[bytes.LastIndex(db, metadataMarker):],
+	} {
+		if _, err := NewReader(buf); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+
+	// A record pointing past the data section fails the lookup
+	bad := buildDatabase(t, 24, testNetwork{"81.2.69.0/24", []byte{typeString<<5 | 20, 'G'}})
+	reader, err := NewReader(bad)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := reader.Country(netip.MustParseAddr("81.2.69.1")); err == nil {
+		t.Error("expected an error for a string past the end of the data")
+	}
+}
@@ -0,0 +1,158 @@
+package health
+
+import (
+	"errors"
+	"io/fs"
+	"testing"
+	"time"
+
+	"github.com/maiko/sdbx/internal/docker"
+)
+
+func TestStoreRecordHistoryPrune(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	if _, err := OpenReadOnly(tmpDir); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("OpenReadOnly without history = %v, want fs.ErrNotExist", err)
+	}
+
+	store, err := Open(tmpDir)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+
+	base := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	samples := [][]docker.Service{
+		{
+			{Name: "sdbx-plex", Service: "plex", Status: "running", Running: true},
+			{Name: "sdbx-sonarr", Service: "sonarr", Status: "running", Running: true, Health: "healthy"},
+		},
+		{
+			{Name: "sdbx-plex", Service: "plex", Status: "exited", ExitCode: 1},
+		},
+		{
+			{Name: "sdbx-plex", Service: "plex", Status: "running", Running: true, Health: "unhealthy"},
+		},
+	}
+	for i, services := range samples {
+		if err := store.Record(base.Add(time.Duration(i)*time.Minute), services); err != nil {
+			t.Fatalf("Record failed: %v", err)
+		}
+	}
+
+	history, err := store.History(base)
+	if err != nil {
+		t.Fatalf("History failed: %v", err)
+	}
+	if len(history["plex"]) != 3 || len(history["sonarr"]) != 1 {
+		t.Fatalf("unexpected history: %+v", history)
+	}
+	plex := history["plex"]
+	if !plex[0].Healthy || plex[1].Healthy || plex[2].Healthy {
+		t.Errorf("plex samples have the wrong health: %+v", plex)
+	}
+	if !plex[0].Time.Equal(base) || plex[1].Status != "exited" || plex[2].Health != "unhealthy" {
+		t.Errorf("plex samples are not in time order: %+v", plex)
+	}
+
+	history, err = store.History(base.Add(time.Minute))
+	if err != nil {
+		t.Fatalf("History failed: %v", err)
+	}
+	if len(history["plex"]) != 2 || len(history["sonarr"]) != 0 {
+		t.Errorf("History since should skip older samples: %+v", history)
+	}
+
+	if err := store.Prune(base.Add(time.Minute)); err != nil {
+		t.Fatalf("Prune failed: %v", err)
+	}
+	history, _ = store.History(time.Time{})
+	if len(history["plex"]) != 2 {
+		t.Errorf("Prune should keep recent plex samples: %+v", history)
+	}
+	if _, ok := history["sonarr"]; ok {
+		t.Errorf("Prune should drop services without samples: %+v", history)
+	}
+
+	if err := store.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	readOnly, err := OpenReadOnly(tmpDir)
+	if err != nil {
+		t.Fatalf("OpenReadOnly failed: %v", err)
+	}
+	defer readOnly.Close()
+	if history, _ := readOnly.History(time.Time{}); len(history["plex"]) != 2 {
+		t.Errorf("read-only history lost samples: %+v", history)
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	base := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	series := func(healthy ...bool) []Sample {
+		samples := make([]Sample, len(healthy))
+		for i, h := range healthy {
+			samples[i] = Sample{Time: base.Add(time.Duration(i) * time.Minute), Healthy: h}
+		}
+		return samples
+	}
+
+	tests := []struct {
+		name        string
+		samples     []Sample
+		uptime      float64
+		healthy     bool
+		lastFailure int // Index of the last failing sample, -1 for none
+		downSince   int // Index of the first sample of the current failure, -1 for none
+		flapping    bool
+	}{
+		{"no samples", nil, 0, false, -1, -1, false},
+		{"always up", series(true, true, true, true), 100, true, -1, -1, false},
+		{"one failure", series(true, false, true, true), 75, true, 1, -1, false},
+		{"down now", series(true, true, false, false), 50, false, 3, 2, false},
+		{"never up", series(false, false), 0, false, 1, 0, false},
+		{"flapping", series(true, false, true, false, true, true), 66.66666666666667, true, 3, -1, true},
+		{"flapped long ago", series(true, false, true, false, true, true, true, true, true, true, true, true, true, true), 85.71428571428571, true, 3, -1, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stats := Summarize("plex", tt.samples)
+			if stats.Samples != len(tt.samples) {
+				t.Errorf("Samples = %d, want %d", stats.Samples, len(tt.samples))
+			}
+			if stats.Uptime != tt.uptime {
+				t.Errorf("Uptime = %v, want %v", stats.Uptime, tt.uptime)
+			}
+			if stats.Healthy != tt.healthy {
+				t.Errorf("Healthy = %v, want %v", stats.Healthy, tt.healthy)
+			}
+			switch {
+			case tt.lastFailure < 0 && stats.LastFailure != nil:
+				t.Errorf("LastFailure = %v, want none", stats.LastFailure)
+			case tt.lastFailure >= 0 && (stats.LastFailure == nil || !stats.LastFailure.Equal(tt.samples[tt.lastFailure].Time)):
+				t.Errorf("LastFailure = %v, want %v", stats.LastFailure, tt.samples[tt.lastFailure].Time)
+			}
+			switch {
+			case tt.downSince < 0 && stats.DownSince != nil:
+				t.Errorf("DownSince = %v, want none", stats.DownSince)
+			case tt.downSince >= 0 && (stats.DownSince == nil || !stats.DownSince.Equal(tt.samples[tt.downSince].Time)):
+				t.Errorf("DownSince = %v, want %v", stats.DownSince, tt.samples[tt.downSince].Time)
+			}
+			if stats.Flapping != tt.flapping {
+				t.Errorf("Flapping = %v (changes %d), want %v", stats.Flapping, stats.Changes, tt.flapping)
+			}
+		})
+	}
+}
+
+func TestSummarizeAll(t *testing.T) {
+	stats := SummarizeAll(map[string][]Sample{
+		"sonarr": {{Healthy: true}},
+		"plex":   {{Healthy: false}},
+	})
+	if len(stats) != 2 || stats[0].Service != "plex" || stats[1].Service != "sonarr" {
+		t.Errorf("SummarizeAll should sort by service: %+v", stats)
+	}
+}
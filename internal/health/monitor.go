@@ -0,0 +1,72 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/maiko/sdbx/internal/docker"
+	"github.com/maiko/sdbx/internal/scheduler"
+)
+
+// Monitor defaults
+const (
+	DefaultInterval  = time.Minute
+	DefaultRetention = 7 * 24 * time.Hour
+)
+
+// Monitor periodically samples the project's containers into the health
+// history
+type Monitor struct {
+	ProjectDir string
+	Compose    *docker.Compose
+	Interval   time.Duration
+	Retention  time.Duration
+}
+
+// NewMonitor creates a monitor with the default interval and retention
+func NewMonitor(projectDir string) *Monitor {
+	return &Monitor{
+		ProjectDir: projectDir,
+		Compose:    docker.NewCompose(projectDir),
+		Interval:   DefaultInterval,
+		Retention:  DefaultRetention,
+	}
+}
+
+// Sample records the current state of every container and prunes samples
+// older than the retention. The database is only held open while writing so
+// 'sdbx status --history' can read it meanwhile.
+func (m *Monitor) Sample(ctx context.Context) ([]docker.Service, error) {
+	services, err := m.Compose.PSAll(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get service status: %w", err)
+	}
+
+	store, err := Open(m.ProjectDir)
+	if err != nil {
+		return nil, err
+	}
+	defer store.Close()
+
+	now := time.Now()
+	if err := store.Record(now, services); err != nil {
+		return nil, err
+	}
+	if err := store.Prune(now.Add(-m.Retention)); err != nil {
+		return nil, err
+	}
+	return services, nil
+}
+
+// Job returns the scheduler job sampling every interval
+func (m *Monitor) Job() scheduler.Job {
+	return scheduler.Job{
+		Name:     "health",
+		Interval: m.Interval,
+		Run: func(ctx context.Context) error {
+			_, err := m.Sample(ctx)
+			return err
+		},
+	}
+}
@@ -0,0 +1,71 @@
+package health
+
+import (
+	"maps"
+	"slices"
+	"time"
+)
+
+// Flapping detection: a service is flapping when its health changed at least
+// FlapThreshold times over its last FlapWindow samples
+const (
+	FlapWindow    = 10
+	FlapThreshold = 4
+)
+
+// Stats summarizes the samples of one service
+type Stats struct {
+	Service     string     `json:"service"`
+	Samples     int        `json:"samples"`
+	Uptime      float64    `json:"uptime"` // Percentage of healthy samples
+	Healthy     bool       `json:"healthy"`
+	LastSeen    time.Time  `json:"last_seen"`
+	LastFailure *time.Time `json:"last_failure,omitempty"`
+	DownSince   *time.Time `json:"down_since,omitempty"` // First sample of the current failure, if failing
+	Changes     int        `json:"changes"`              // Health changes over the last FlapWindow samples
+	Flapping    bool       `json:"flapping"`
+}
+
+// Summarize computes the uptime, last failure and flapping state of a service
+// from its samples, oldest first
+func Summarize(service string, samples []Sample) Stats {
+	stats := Stats{Service: service, Samples: len(samples)}
+	if len(samples) == 0 {
+		return stats
+	}
+
+	healthy := 0
+	for i, sample := range samples {
+		if sample.Healthy {
+			healthy++
+		} else {
+			stats.LastFailure = &samples[i].Time
+		}
+	}
+	latest := samples[len(samples)-1]
+	stats.Uptime = float64(healthy) * 100 / float64(len(samples))
+	stats.Healthy = latest.Healthy
+	stats.LastSeen = latest.Time
+	for i := len(samples) - 1; i >= 0 && !samples[i].Healthy; i-- {
+		stats.DownSince = &samples[i].Time
+	}
+
+	window := samples[max(0, len(samples)-FlapWindow):]
+	for i := 1; i < len(window); i++ {
+		if window[i].Healthy != window[i-1].Healthy {
+			stats.Changes++
+		}
+	}
+	stats.Flapping = stats.Changes >= FlapThreshold
+
+	return stats
+}
+
+// SummarizeAll summarizes every service of a history, sorted by name
+func SummarizeAll(history map[string][]Sample) []Stats {
+	stats := make([]Stats, 0, len(history))
+	for _, service := range slices.Sorted(maps.Keys(history)) {
+		stats = append(stats, Summarize(service, history[service]))
+	}
+	return stats
+}
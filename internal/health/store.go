@@ -0,0 +1,164 @@
+// Package health records periodic container health samples and derives
+// uptime statistics from them.
+package health
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/maiko/sdbx/internal/docker"
+)
+
+// DBFile is the health history database in the project directory
+const DBFile = ".sdbx.health.db"
+
+// openTimeout bounds how long Open waits for another process holding the
+// database (the monitor only keeps it open while writing a sample)
+const openTimeout = 5 * time.Second
+
+// Sample is the state of one service container at a point in time
+type Sample struct {
+	Time    time.Time `json:"time"`
+	Status  string    `json:"status"`
+	Health  string    `json:"health,omitempty"`
+	Healthy bool      `json:"healthy"`
+}
+
+// Store persists samples in a bbolt database, one bucket per service keyed
+// by sample time
+type Store struct {
+	db *bolt.DB
+}
+
+// DBPath returns the path of the health database of a project
+func DBPath(projectDir string) string {
+	return filepath.Join(projectDir, DBFile)
+}
+
+// Exists reports whether the project has recorded any health history
+func Exists(projectDir string) bool {
+	_, err := os.Stat(DBPath(projectDir))
+	return err == nil
+}
+
+// Open opens the health database of a project, creating it if needed
+func Open(projectDir string) (*Store, error) {
+	return open(projectDir, false)
+}
+
+// OpenReadOnly opens an existing health database for reading
+func OpenReadOnly(projectDir string) (*Store, error) {
+	if !Exists(projectDir) {
+		return nil, fmt.Errorf("no health history in %s: %w", projectDir, fs.ErrNotExist)
+	}
+	return open(projectDir, true)
+}
+
+func open(projectDir string, readOnly bool) (*Store, error) {
+	db, err := bolt.Open(DBPath(projectDir), 0o600, &bolt.Options{Timeout: openTimeout, ReadOnly: readOnly})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open health history: %w", err)
+	}
+	return &Store{db: db}, nil
+}
+
+// Close closes the database
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// Record stores a sample of every service container taken at the given time
+func (s *Store) Record(at time.Time, services []docker.Service) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		for _, svc := range services {
+			name := svc.Service
+			if name == "" {
+				name = svc.Name
+			}
+			bucket, err := tx.CreateBucketIfNotExists([]byte(name))
+			if err != nil {
+				return fmt.Errorf("failed to create bucket for %s: %w", name, err)
+			}
+			value, err := json.Marshal(Sample{Time: at, Status: svc.Status, Health: svc.Health, Healthy: svc.Healthy()})
+			if err != nil {
+				return err
+			}
+			if err := bucket.Put(timeKey(at), value); err != nil {
+				return fmt.Errorf("failed to record sample for %s: %w", name, err)
+			}
+		}
+		return nil
+	})
+}
+
+// Prune deletes samples older than before, and services left without samples
+func (s *Store) Prune(before time.Time) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		var empty [][]byte
+		err := tx.ForEach(func(name []byte, bucket *bolt.Bucket) error {
+			cutoff := timeKey(before)
+			c := bucket.Cursor()
+			for k, _ := c.First(); k != nil && bytes.Compare(k, cutoff) < 0; k, _ = c.First() {
+				if err := c.Delete(); err != nil {
+					return err
+				}
+			}
+			if k, _ := c.First(); k == nil {
+				empty = append(empty, name)
+			}
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("failed to prune health history: %w", err)
+		}
+		for _, name := range empty {
+			if err := tx.DeleteBucket(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// History returns the samples of every service taken since the given time
+// (all of them for the zero time), oldest first
+func (s *Store) History(since time.Time) (map[string][]Sample, error) {
+	var from []byte
+	if !since.IsZero() {
+		from = timeKey(since)
+	}
+
+	history := make(map[string][]Sample)
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.ForEach(func(name []byte, bucket *bolt.Bucket) error {
+			c := bucket.Cursor()
+			for k, v := c.Seek(from); k != nil; k, v = c.Next() {
+				var sample Sample
+				if err := json.Unmarshal(v, &sample); err != nil {
+					return fmt.Errorf("corrupt sample for %s: %w", name, err)
+				}
+				history[string(name)] = append(history[string(name)], sample)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return history, nil
+}
+
+// timeKey encodes a time as a big-endian key so bucket order is time order
+func timeKey(t time.Time) []byte {
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, uint64(t.UnixNano()))
+	return key
+}
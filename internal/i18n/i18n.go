@@ -0,0 +1,142 @@
+// Package i18n translates the strings shown by the CLI and the web UI.
+// Messages are written in English in the code and looked up by their English
+// text in the catalog of the selected language, falling back to English.
+package i18n
+
+import (
+	"embed"
+	"fmt"
+	"os"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed locales/*.yaml
+var locales embed.FS
+
+// DefaultLanguage is the language of the messages in the code
+const DefaultLanguage = "en"
+
+// Languages lists the supported languages, DefaultLanguage first
+var Languages = []string{DefaultLanguage, "fr", "de"}
+
+var (
+	mu       sync.RWMutex
+	language = DefaultLanguage
+	catalogs map[string]map[string]string
+	loadOnce sync.Once
+	loadErr  error
+)
+
+// Parse returns the supported language of a language tag or locale name
+// (fr, fr-FR, fr_FR.UTF-8, de_DE@euro), or "" when it is not supported
+func Parse(tag string) string {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	if i := strings.IndexAny(tag, "-_.@"); i >= 0 {
+		tag = tag[:i]
+	}
+	if slices.Contains(Languages, tag) {
+		return tag
+	}
+	return ""
+}
+
+// Detect returns the language of the environment: the first supported one
+// of LANGUAGE, LC_ALL, LC_MESSAGES and LANG, in the order gettext reads them,
+// or DefaultLanguage. The C and POSIX locales are English.
+func Detect() string {
+	for _, env := range []string{"LANGUAGE", "LC_ALL", "LC_MESSAGES", "LANG"} {
+		value := os.Getenv(env)
+		if value == "" {
+			continue
+		}
+		// LANGUAGE is a priority list (fr:de:en)
+		for _, tag := range strings.Split(value, ":") {
+			if lang := Parse(tag); lang != "" {
+				return lang
+			}
+		}
+		if env != "LANGUAGE" {
+			// The first set locale variable decides, even when unsupported
+			return DefaultLanguage
+		}
+	}
+	return DefaultLanguage
+}
+
+// SetLanguage selects the language of T
+func SetLanguage(lang string) error {
+	parsed := Parse(lang)
+	if parsed == "" {
+		return fmt.Errorf("unsupported language %q (supported: %s)", lang, strings.Join(Languages, ", "))
+	}
+	mu.Lock()
+	language = parsed
+	mu.Unlock()
+	return nil
+}
+
+// Language returns the selected language
+func Language() string {
+	mu.RLock()
+	defer mu.RUnlock()
+	return language
+}
+
+// T translates a message into the selected language. With arguments, the
+// translation is a format string for them.
+func T(msg string, args ...any) string {
+	return Translate(Language(), msg, args...)
+}
+
+// Translate translates a message into a language, falling back to the
+// English message when the language has no translation for it
+func Translate(lang, msg string, args ...any) string {
+	if lang != DefaultLanguage {
+		if translated, ok := catalog(lang)[msg]; ok && translated != "" {
+			msg = translated
+		}
+	}
+	if len(args) == 0 {
+		return msg
+	}
+	return fmt.Sprintf(msg, args...)
+}
+
+// Catalog returns the translations of a language, keyed by English message
+func Catalog(lang string) (map[string]string, error) {
+	loadOnce.Do(loadCatalogs)
+	if loadErr != nil {
+		return nil, loadErr
+	}
+	return catalogs[lang], nil
+}
+
+// catalog returns the translations of a language, none when the catalogs
+// fail to load (a build error caught by the tests)
+func catalog(lang string) map[string]string {
+	c, _ := Catalog(lang)
+	return c
+}
+
+// loadCatalogs reads the embedded catalog of every supported language
+func loadCatalogs() {
+	catalogs = make(map[string]map[string]string, len(Languages))
+	for _, lang := range Languages[1:] {
+		data, err := locales.ReadFile(path.Join("locales", lang+".yaml"))
+		if err != nil {
+			loadErr = fmt.Errorf("failed to read %s catalog: %w", lang, err)
+			return
+		}
+		messages := make(map[string]string)
+		if err := yaml.Unmarshal(data, &messages); err != nil {
+			loadErr = fmt.Errorf("failed to parse %s catalog: %w", lang, err)
+			return
+		}
+		catalogs[lang] = messages
+	}
+}
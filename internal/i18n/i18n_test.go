@@ -0,0 +1,247 @@
+package i18n
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := map[string]string{
+		"fr":          "fr",
+		"fr-FR":       "fr",
+		"fr_FR.UTF-8": "fr",
+		"de_DE@euro":  "de",
+		"DE":          "de",
+		"en_US.UTF-8": "en",
+		"C":           "",
+		"es_ES":       "",
+		"":            "",
+	}
+	for tag, want := range tests {
+		if got := Parse(tag); got != want {
+			t.Errorf("Parse(%q) = %q, want %q", tag, got, want)
+		}
+	}
+}
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		name                                 string
+		language, lcAll, lcMessages, langVar string
+		want                                 string
+	}{
+		{"nothing set", "", "", "", "", "en"},
+		{"LANG", "", "", "", "fr_FR.UTF-8", "fr"},
+		{"LC_ALL over LANG", "", "de_DE.UTF-8", "", "fr_FR.UTF-8", "de"},
+		{"LC_MESSAGES over LANG", "", "", "de_DE", "fr_FR", "de"},
+		{"LANGUAGE priority list", "es:de:fr", "", "", "fr_FR", "de"},
+		{"unsupported LANGUAGE falls through", "es", "", "", "fr_FR", "fr"},
+		{"unsupported locale", "", "es_ES.UTF-8", "", "fr_FR", "en"},
+		{"C locale", "", "", "", "C", "en"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("LANGUAGE", tt.language)
+			t.Setenv("LC_ALL", tt.lcAll)
+			t.Setenv("LC_MESSAGES", tt.lcMessages)
+			t.Setenv("LANG", tt.langVar)
+			if got := Detect(); got != tt.want {
+				t.Errorf("Detect() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSetLanguage(t *testing.T) {
+	defer SetLanguage(DefaultLanguage)
+
+	if err := SetLanguage("de_DE.UTF-8"); err != nil {
+		t.Fatalf("SetLanguage() error = %v", err)
+	}
+	if got := Language(); got != "de" {
+		t.Errorf("Language() = %q, want de", got)
+	}
+	if got := T("Cancel"); got != "Abbrechen" {
+		t.Errorf("T(Cancel) = %q, want Abbrechen", got)
+	}
+
+	if err := SetLanguage("es"); err == nil {
+		t.Error("SetLanguage(es) succeeded, want an unsupported language error")
+	}
+	if got := Language(); got != "de" {
+		t.Errorf("Language() = %q after a failed SetLanguage, want de", got)
+	}
+}
+
+func TestTranslate(t *testing.T) {
+	tests := []struct {
+		lang, msg string
+		args      []any
+		want      string
+	}{
+		{"en", "Cancel", nil, "Cancel"},
+		{"fr", "Cancel", nil, "Annuler"},
+		{"fr", "All %d services running", []any{3}, "Les 3 services sont en cours d'exécution"},
+		{"de", "Stop %s?", []any{"Radarr"}, "Radarr stoppen?"},
+		// Messages without a translation fall back to English
+		{"fr", "Not translated %d%%", []any{5}, "Not translated 5%"},
+		// Without arguments, the message is not a format string
+		{"en", "100%", nil, "100%"},
+	}
+	for _, tt := range tests {
+		if got := Translate(tt.lang, tt.msg, tt.args...); got != tt.want {
+			t.Errorf("Translate(%q, %q) = %q, want %q", tt.lang, tt.msg, got, tt.want)
+		}
+	}
+}
+
+var verbRe = regexp.MustCompile(`%[-+# 0]*\d*(?:\.\d+)?[a-zA-Z%]`)
+
+func TestCatalogs(t *testing.T) {
+	fr, err := Catalog("fr")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, lang := range Languages[1:] {
+		catalog, err := Catalog(lang)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for msg, translated := range catalog {
+			if translated == "" {
+				t.Errorf("%s: %q has an empty translation", lang, msg)
+			}
+			if want, got := verbRe.FindAllString(msg, -1), verbRe.FindAllString(translated, -1); !slices.Equal(want, got) {
+				t.Errorf("%s: %q has verbs %v, want %v", lang, msg, got, want)
+			}
+			if _, ok := fr[msg]; !ok {
+				t.Errorf("%s: %q is missing from the fr catalog", lang, msg)
+			}
+		}
+		if len(catalog) != len(fr) {
+			t.Errorf("%s has %d messages, fr has %d", lang, len(catalog), len(fr))
+		}
+	}
+}
+
+// TestMessagesTranslated checks every message the CLI and the web templates
+// translate is in every catalog
+func TestMessagesTranslated(t *testing.T) {
+	messages := cliMessages(t)
+	messages = append(messages, templateMessages(t)...)
+	if len(messages) == 0 {
+		t.Fatal("found no translated messages")
+	}
+	for _, lang := range Languages[1:] {
+		catalog, err := Catalog(lang)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, msg := range messages {
+			if _, ok := catalog[msg]; !ok {
+				t.Errorf("%s: no translation for %q", lang, msg)
+			}
+		}
+	}
+}
+
+// cliMessages returns the literal messages passed to i18n.T by the commands
+func cliMessages(t *testing.T) []string {
+	t.Helper()
+	fset := token.NewFileSet()
+	files, err := filepath.Glob("../../cmd/sdbx/cmd/*.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var messages []string
+	for _, path := range files {
+		file, err := parser.ParseFile(fset, path, nil, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ast.Inspect(file, func(n ast.Node) bool {
+			call, ok := n.(*ast.CallExpr)
+			if !ok || len(call.Args) == 0 {
+				return true
+			}
+			sel, ok := call.Fun.(*ast.SelectorExpr)
+			if !ok || sel.Sel.Name != "T" {
+				return true
+			}
+			if pkg, ok := sel.X.(*ast.Ident); !ok || pkg.Name != "i18n" {
+				return true
+			}
+			if msg, ok := stringConstant(call.Args[0]); ok {
+				messages = append(messages, msg)
+			} else {
+				t.Errorf("%s: i18n.T called with a non-literal message", fset.Position(call.Pos()))
+			}
+			return true
+		})
+	}
+	return messages
+}
+
+// stringConstant returns the value of a string literal or a concatenation
+// of string literals
+func stringConstant(expr ast.Expr) (string, bool) {
+	switch e := expr.(type) {
+	case *ast.BasicLit:
+		if e.Kind != token.STRING {
+			return "", false
+		}
+		s, err := strconv.Unquote(e.Value)
+		return s, err == nil
+	case *ast.BinaryExpr:
+		if e.Op != token.ADD {
+			return "", false
+		}
+		x, ok := stringConstant(e.X)
+		if !ok {
+			return "", false
+		}
+		y, ok := stringConstant(e.Y)
+		return x + y, ok
+	case *ast.ParenExpr:
+		return stringConstant(e.X)
+	}
+	return "", false
+}
+
+var templateMessageRe = regexp.MustCompile(`\{\{-?\s*t\s+("(?:[^"\\]|\\.)*")`)
+
+// templateMessages returns the messages translated by {{t "..."}} in the
+// web templates
+func templateMessages(t *testing.T) []string {
+	t.Helper()
+	var messages []string
+	err := filepath.WalkDir("../web/templates", func(path string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() || filepath.Ext(path) != ".html" {
+			return err
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		for _, match := range templateMessageRe.FindAllStringSubmatch(string(data), -1) {
+			msg, err := strconv.Unquote(match[1])
+			if err != nil {
+				t.Errorf("%s: invalid message %s", path, match[1])
+				continue
+			}
+			messages = append(messages, msg)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return messages
+}
@@ -0,0 +1,241 @@
+// Package mdns answers multicast DNS queries for the .local names of the
+// routed services (the mdns section of .sdbx.yaml), so LAN clients reach
+// them without DNS records for the domain.
+package mdns
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"net/netip"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+	"golang.org/x/net/ipv4"
+)
+
+const (
+	// NamesFile lists the names answered, one per line, written by generation
+	NamesFile = "configs/mdns/names"
+
+	// ttl of the answers, RFC 6762's recommendation for host records
+	ttl = 120
+
+	// legacyTTL caps the answers to one-shot queries from a port other than
+	// 5353, which are plain DNS resolvers (RFC 6762 section 6.7)
+	legacyTTL = 10
+
+	// classFlag is the top bit of the class: unicast response in questions,
+	// cache flush in answers
+	classFlag = 0x8000
+)
+
+// group is the mDNS multicast group and port
+var group = &net.UDPAddr{IP: net.IPv4(224, 0, 0, 251), Port: 5353}
+
+// Responder answers A queries for the names of NamesPath. The file is read
+// again when it changes, so regenerating the project needs no restart.
+type Responder struct {
+	NamesPath string
+	Address   netip.Addr // Address answered; zero for the host's address on the interface a query arrives on
+
+	mu      sync.Mutex
+	names   map[string]bool
+	modTime time.Time
+}
+
+// ParseNames reads a names file: one name per line, blank lines and #
+// comments ignored. Names are lowercased, without a trailing dot.
+func ParseNames(data []byte) map[string]bool {
+	names := make(map[string]bool)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		names[strings.ToLower(strings.TrimSuffix(line, "."))] = true
+	}
+	return names
+}
+
+// Names returns the names answered, reading NamesPath again when it changed.
+// A missing file answers no name.
+func (r *Responder) Names() map[string]bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	info, err := os.Stat(r.NamesPath)
+	if err != nil {
+		r.names, r.modTime = nil, time.Time{}
+		return nil
+	}
+	if r.names != nil && info.ModTime().Equal(r.modTime) {
+		return r.names
+	}
+	data, err := os.ReadFile(r.NamesPath)
+	if err != nil {
+		return r.names
+	}
+	r.names, r.modTime = ParseNames(data), info.ModTime()
+	return r.names
+}
+
+// Answer builds the response to an mDNS query: an A record of addr for each
+// question about one of names. It returns nil when no question is about
+// them. A legacy response, to a one-shot query, repeats the query ID and
+// questions and uses a short TTL.
+func Answer(query []byte, names map[string]bool, addr netip.Addr, legacy bool) ([]byte, error) {
+	var p dnsmessage.Parser
+	header, err := p.Start(query)
+	if err != nil {
+		return nil, err
+	}
+	if header.Response || header.OpCode != 0 {
+		return nil, nil
+	}
+	questions, err := p.AllQuestions()
+	if err != nil {
+		return nil, err
+	}
+
+	var matched []dnsmessage.Question
+	for _, q := range questions {
+		class := q.Class &^ classFlag
+		if class != dnsmessage.ClassINET && class != dnsmessage.ClassANY {
+			continue
+		}
+		if q.Type != dnsmessage.TypeA && q.Type != dnsmessage.TypeALL {
+			continue
+		}
+		if names[strings.ToLower(strings.TrimSuffix(q.Name.String(), "."))] {
+			matched = append(matched, q)
+		}
+	}
+	if len(matched) == 0 {
+		return nil, nil
+	}
+
+	resp := dnsmessage.Header{Response: true, Authoritative: true}
+	answer := dnsmessage.ResourceHeader{Class: dnsmessage.ClassINET | classFlag, TTL: ttl}
+	if legacy {
+		resp.ID = header.ID
+		answer = dnsmessage.ResourceHeader{Class: dnsmessage.ClassINET, TTL: legacyTTL}
+	}
+	b := dnsmessage.NewBuilder(nil, resp)
+	b.EnableCompression()
+	if legacy {
+		if err := b.StartQuestions(); err != nil {
+			return nil, err
+		}
+		for _, q := range questions {
+			if err := b.Question(q); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if err := b.StartAnswers(); err != nil {
+		return nil, err
+	}
+	for _, q := range matched {
+		answer.Name = q.Name
+		if err := b.AResource(answer, dnsmessage.AResource{A: addr.As4()}); err != nil {
+			return nil, err
+		}
+	}
+	return b.Finish()
+}
+
+// Serve answers the queries received on the mDNS group of every multicast
+// interface until ctx is done
+func (r *Responder) Serve(ctx context.Context) error {
+	conn, err := net.ListenMulticastUDP("udp4", nil, group)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", group, err)
+	}
+	defer conn.Close()
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	pc := ipv4.NewPacketConn(conn)
+	if ifaces, err := net.Interfaces(); err == nil {
+		for i := range ifaces {
+			flags := ifaces[i].Flags
+			if flags&net.FlagUp != 0 && flags&net.FlagMulticast != 0 && flags&net.FlagLoopback == 0 {
+				_ = pc.JoinGroup(&ifaces[i], group) // Fails on the default interface, already joined
+			}
+		}
+	}
+	if err := pc.SetControlMessage(ipv4.FlagInterface, true); err != nil {
+		return fmt.Errorf("failed to enable interface info: %w", err)
+	}
+	if err := pc.SetMulticastTTL(255); err != nil {
+		return fmt.Errorf("failed to set multicast TTL: %w", err)
+	}
+
+	buf := make([]byte, 9000)
+	for {
+		n, cm, src, err := pc.ReadFrom(buf)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		r.respond(pc, buf[:n], cm, src)
+	}
+}
+
+// respond answers one query: by multicast, or to the sender for legacy queries
+func (r *Responder) respond(pc *ipv4.PacketConn, query []byte, cm *ipv4.ControlMessage, src net.Addr) {
+	ifIndex := 0
+	if cm != nil {
+		ifIndex = cm.IfIndex
+	}
+	addr := r.Address
+	if !addr.IsValid() {
+		if addr = interfaceAddr(ifIndex); !addr.IsValid() {
+			return
+		}
+	}
+	udp, ok := src.(*net.UDPAddr)
+	if !ok {
+		return
+	}
+	legacy := udp.Port != group.Port
+	resp, err := Answer(query, r.Names(), addr, legacy)
+	if err != nil || resp == nil {
+		return
+	}
+	dst := net.Addr(group)
+	if legacy {
+		dst = src
+	}
+	_, _ = pc.WriteTo(resp, &ipv4.ControlMessage{IfIndex: ifIndex}, dst)
+}
+
+// interfaceAddr returns the first IPv4 address of an interface
+func interfaceAddr(index int) netip.Addr {
+	ifi, err := net.InterfaceByIndex(index)
+	if err != nil {
+		return netip.Addr{}
+	}
+	addrs, err := ifi.Addrs()
+	if err != nil {
+		return netip.Addr{}
+	}
+	for _, a := range addrs {
+		if ipnet, ok := a.(*net.IPNet); ok {
+			if ip4 := ipnet.IP.To4(); ip4 != nil {
+				addr, _ := netip.AddrFromSlice(ip4)
+				return addr
+			}
+		}
+	}
+	return netip.Addr{}
+}
@@ -0,0 +1,94 @@
+package mdns
+
+import (
+	"net/netip"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// query builds an mDNS query for names
+func query(t *testing.T, id uint16, qtype dnsmessage.Type, names ...string) []byte {
+	t.Helper()
+	b := dnsmessage.NewBuilder(nil, dnsmessage.Header{ID: id})
+	if err := b.StartQuestions(); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range names {
+		q := dnsmessage.Question{Name: dnsmessage.MustNewName(name), Type: qtype, Class: dnsmessage.ClassINET}
+		if err := b.Question(q); err != nil {
+			t.Fatal(err)
+		}
+	}
+	msg, err := b.Finish()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return msg
+}
+
+func TestAnswer(t *testing.T) {
+	names := ParseNames([]byte("# routed services\nradarr.local\nSonarr.local.\n\n"))
+	addr := netip.MustParseAddr("192.168.1.10")
+
+	resp, err := Answer(query(t, 7, dnsmessage.TypeA, "radarr.local.", "plex.local.", "SONARR.local."), names, addr, false)
+	if err != nil {
+		t.Fatalf("Answer() error = %v", err)
+	}
+	var msg dnsmessage.Message
+	if err := msg.Unpack(resp); err != nil {
+		t.Fatalf("Unpack() error = %v", err)
+	}
+	if !msg.Response || !msg.Authoritative || msg.ID != 0 || len(msg.Questions) != 0 {
+		t.Errorf("header = %+v with %d questions, want an authoritative multicast response", msg.Header, len(msg.Questions))
+	}
+	if len(msg.Answers) != 2 {
+		t.Fatalf("answers = %v, want radarr and sonarr", msg.Answers)
+	}
+	for _, answer := range msg.Answers {
+		a, ok := answer.Body.(*dnsmessage.AResource)
+		if !ok || netip.AddrFrom4(a.A) != addr {
+			t.Errorf("answer %s = %v, want %s", answer.Header.Name, answer.Body, addr)
+		}
+		if answer.Header.Class != dnsmessage.ClassINET|classFlag || answer.Header.TTL != ttl {
+			t.Errorf("answer %s class %v ttl %d, want cache flush and %d", answer.Header.Name, answer.Header.Class, answer.Header.TTL, ttl)
+		}
+	}
+
+	// One-shot resolvers get their ID and questions back
+	resp, err = Answer(query(t, 7, dnsmessage.TypeA, "radarr.local."), names, addr, true)
+	if err != nil {
+		t.Fatalf("Answer() error = %v", err)
+	}
+	if err := msg.Unpack(resp); err != nil {
+		t.Fatalf("Unpack() error = %v", err)
+	}
+	if msg.ID != 7 || len(msg.Questions) != 1 || len(msg.Answers) != 1 || msg.Answers[0].Header.TTL != legacyTTL {
+		t.Errorf("legacy response = %+v, want the ID, the question and a short TTL", msg)
+	}
+
+	for name, q := range map[string][]byte{
+		"other name": query(t, 0, dnsmessage.TypeA, "plex.local."),
+		"AAAA":       query(t, 0, dnsmessage.TypeAAAA, "radarr.local."),
+	} {
+		if resp, err := Answer(q, names, addr, false); err != nil || resp != nil {
+			t.Errorf("%s: Answer() = %v, %v, want no response", name, resp, err)
+		}
+	}
+}
+
+func TestResponderNames(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "names")
+	r := &Responder{NamesPath: path}
+	if names := r.Names(); len(names) != 0 {
+		t.Errorf("Names() = %v without a file, want none", names)
+	}
+	if err := os.WriteFile(path, []byte("radarr.local\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if names := r.Names(); !names["radarr.local"] {
+		t.Errorf("Names() = %v, want radarr.local", names)
+	}
+}
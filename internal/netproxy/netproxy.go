@@ -0,0 +1,92 @@
+// Package netproxy routes the outbound connections of sdbx (source
+// downloads, notifications, GeoIP downloads) through HTTP or SOCKS proxies.
+// Without a configured proxy, HTTPS_PROXY, HTTP_PROXY and NO_PROXY from the
+// environment apply.
+package netproxy
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"golang.org/x/net/http/httpproxy"
+)
+
+// Direct disables the proxy, including the one of the environment
+const Direct = "direct"
+
+// schemes are the proxy URL schemes Go's HTTP transport and git support
+var schemes = []string{"http", "https", "socks5", "socks5h"}
+
+// Parse checks a proxy URL (e.g. http://proxy:3128 or socks5://10.0.0.1:1080)
+func Parse(raw string) (*url.URL, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy URL %q: %w", raw, err)
+	}
+	if !slices.Contains(schemes, u.Scheme) || u.Host == "" {
+		return nil, fmt.Errorf("invalid proxy URL %q - use %s://host:port", raw, strings.Join(schemes, "|"))
+	}
+	return u, nil
+}
+
+// Validate checks a proxy setting: a proxy URL, Direct, or "" for the proxy
+// of the environment
+func Validate(proxy string) error {
+	if proxy == "" || proxy == Direct {
+		return nil
+	}
+	_, err := Parse(proxy)
+	return err
+}
+
+// Func returns the proxy function of an HTTP transport. proxy is a proxy URL,
+// Direct, or "" for the proxy of the environment. Hosts in noProxy (names,
+// domains and CIDRs, as in NO_PROXY) and loopback addresses are reached
+// directly.
+func Func(proxy string, noProxy []string) func(*http.Request) (*url.URL, error) {
+	switch proxy {
+	case Direct:
+		return nil
+	case "":
+		if len(noProxy) == 0 {
+			return http.ProxyFromEnvironment
+		}
+	}
+
+	cfg := httpproxy.FromEnvironment()
+	if proxy != "" {
+		if _, err := Parse(proxy); err != nil {
+			return func(*http.Request) (*url.URL, error) { return nil, err }
+		}
+		cfg.HTTPProxy, cfg.HTTPSProxy = proxy, proxy
+	}
+	cfg.NoProxy = strings.Join(append([]string{cfg.NoProxy}, noProxy...), ",")
+	proxyFunc := cfg.ProxyFunc()
+	return func(req *http.Request) (*url.URL, error) {
+		return proxyFunc(req.URL)
+	}
+}
+
+// Client returns an HTTP client connecting through proxy (see Func)
+func Client(proxy string, noProxy []string, timeout time.Duration) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = Func(proxy, noProxy)
+	return &http.Client{Transport: transport, Timeout: timeout}
+}
+
+// GitEnv returns the variables to add to the environment of a git command
+// fetching through proxy, none for the proxy of the environment. They apply
+// to http(s) remotes; SSH remotes need a ProxyCommand in ~/.ssh/config.
+func GitEnv(proxy string) []string {
+	switch proxy {
+	case "":
+		return nil
+	case Direct:
+		return []string{"NO_PROXY=*", "no_proxy=*"}
+	}
+	return []string{"GIT_CONFIG_COUNT=1", "GIT_CONFIG_KEY_0=http.proxy", "GIT_CONFIG_VALUE_0=" + proxy}
+}
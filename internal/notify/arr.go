@@ -0,0 +1,164 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"slices"
+
+	"github.com/maiko/sdbx/internal/config"
+	"github.com/maiko/sdbx/internal/docker"
+	"github.com/maiko/sdbx/internal/doctor"
+)
+
+// ArrConnectionName is the name of the email connection sdbx registers in
+// the *arr services
+const ArrConnectionName = "sdbx"
+
+// arrAPI locates the API of an *arr service inside its container
+type arrAPI struct {
+	Port    int
+	Version string
+}
+
+// arrServices are the *arr services whose email connections sdbx manages
+var arrServices = map[string]arrAPI{
+	"sonarr":   {8989, "v3"},
+	"radarr":   {7878, "v3"},
+	"lidarr":   {8686, "v1"},
+	"readarr":  {8787, "v1"},
+	"prowlarr": {9696, "v1"},
+}
+
+// ArrServices returns the enabled *arr services, sorted
+func ArrServices(cfg *config.Config) []string {
+	var services []string
+	for name := range arrServices {
+		if cfg.IsAddonEnabled(name) {
+			services = append(services, name)
+		}
+	}
+	slices.Sort(services)
+	return services
+}
+
+// Recipients returns the addresses of every email channel, once each
+func (n *Notifier) Recipients() []string {
+	var recipients []string
+	for _, ch := range n.Channels {
+		if ch.Type != config.NotifyEmail {
+			continue
+		}
+		for _, addr := range ch.Recipients() {
+			if !slices.Contains(recipients, addr) {
+				recipients = append(recipients, addr)
+			}
+		}
+	}
+	return recipients
+}
+
+// arrConnection is the part of an *arr connection (/api/<version>/notification) sdbx reads
+type arrConnection struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+// ConfigureArr creates or updates the sdbx email connection of an *arr
+// service, sending its grab, import and health notifications to the email
+// channels' recipients through the smtp section's mail server
+func (n *Notifier) ConfigureArr(ctx context.Context, compose *docker.Compose, service string) error {
+	api, ok := arrServices[service]
+	if !ok {
+		return fmt.Errorf("%s has no email notifications sdbx can configure", service)
+	}
+	if !n.SMTP.IsSet() {
+		return fmt.Errorf("no mail server configured in the smtp section")
+	}
+	to := n.Recipients()
+	if len(to) == 0 {
+		return fmt.Errorf("no email channel in notifications.channels")
+	}
+	password := ""
+	if n.SMTP.Auth() {
+		var err error
+		if password, err = n.SMTPPassword(); err != nil {
+			return err
+		}
+	}
+	apiKey, err := doctor.ReadArrAPIKey(filepath.Join(n.ProjectDir, "configs", service, "config.xml"))
+	if err != nil {
+		return fmt.Errorf("API key not found (has %s started once?): %w", service, err)
+	}
+
+	call := func(method, path, body string) (string, error) {
+		args := []string{"curl", "-fsS", "--max-time", "30", "-X", method,
+			"-H", "X-Api-Key: " + apiKey, "-H", "Content-Type: application/json"}
+		if body != "" {
+			args = append(args, "--data", body)
+		}
+		args = append(args, fmt.Sprintf("http://localhost:%d/api/%s%s", api.Port, api.Version, path))
+		return compose.Exec(ctx, service, args...)
+	}
+
+	out, err := call(http.MethodGet, "/notification", "")
+	if err != nil {
+		return fmt.Errorf("%s API unreachable: %w", service, err)
+	}
+	var existing []arrConnection
+	if err := json.Unmarshal([]byte(out), &existing); err != nil {
+		return fmt.Errorf("unexpected %s API response: %w", service, err)
+	}
+
+	connection := arrEmailConnection(n.SMTP, password, to)
+	method, path := http.MethodPost, "/notification"
+	if i := slices.IndexFunc(existing, func(c arrConnection) bool { return c.Name == ArrConnectionName }); i >= 0 {
+		connection["id"] = existing[i].ID
+		method, path = http.MethodPut, fmt.Sprintf("/notification/%d", existing[i].ID)
+	}
+	body, err := json.Marshal(connection)
+	if err != nil {
+		return err
+	}
+	if _, err := call(method, path, string(body)); err != nil {
+		return fmt.Errorf("failed to save the %s connection of %s: %w", ArrConnectionName, service, err)
+	}
+	return nil
+}
+
+// arrEmailConnection builds the Email connection of an *arr service. Events
+// a service does not have (e.g. onGrab in Prowlarr) are ignored by it.
+func arrEmailConnection(s config.SMTPConfig, password string, to []string) map[string]interface{} {
+	// useEncryption: 0 prefers STARTTLS, 1 requires TLS from the start
+	encryption := 0
+	if s.ImplicitTLS() {
+		encryption = 1
+	}
+	field := func(name string, value interface{}) map[string]interface{} {
+		return map[string]interface{}{"name": name, "value": value}
+	}
+	return map[string]interface{}{
+		"name":                  ArrConnectionName,
+		"implementation":        "Email",
+		"configContract":        "EmailSettings",
+		"onGrab":                true,
+		"onDownload":            true,
+		"onUpgrade":             true,
+		"onHealthIssue":         true,
+		"includeHealthWarnings": false,
+		"tags":                  []int{},
+		"fields": []map[string]interface{}{
+			field("server", s.Host),
+			field("port", s.ServerPort()),
+			field("useEncryption", encryption),
+			field("username", s.User),
+			field("password", password),
+			field("from", s.Sender),
+			field("to", to),
+			field("cc", []string{}),
+			field("bcc", []string{}),
+		},
+	}
+}
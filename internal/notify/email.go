@@ -0,0 +1,139 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io/fs"
+	"mime"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"path/filepath"
+	"time"
+
+	"github.com/maiko/sdbx/internal/config"
+	"github.com/maiko/sdbx/internal/secrets"
+)
+
+// sendEmail delivers msg to the recipients of an email channel through the
+// smtp section's mail server
+func (n *Notifier) sendEmail(ctx context.Context, ch config.NotificationChannel, msg Message) error {
+	if !n.SMTP.IsSet() {
+		return fmt.Errorf("no mail server configured in the smtp section")
+	}
+	var auth smtp.Auth
+	if n.SMTP.Auth() {
+		password, err := n.SMTPPassword()
+		if err != nil {
+			return err
+		}
+		auth = smtp.PlainAuth("", n.SMTP.User, password, n.SMTP.Host)
+	}
+	to := ch.Recipients()
+	body, err := emailMessage(n.SMTP.Sender, to, msg, time.Now())
+	if err != nil {
+		return err
+	}
+	return n.SendMail(ctx, n.SMTP, auth, to, body)
+}
+
+// SMTPPassword reads the password of the smtp section from
+// secrets/smtp_password.txt
+func (n *Notifier) SMTPPassword() (string, error) {
+	filename := config.SMTPPasswordSecret + ".txt"
+	password, err := secrets.ReadSecret(filepath.Join(n.ProjectDir, "secrets"), filename)
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", &secrets.SecretNotConfiguredError{Filename: filename}
+	}
+	return password, err
+}
+
+// emailMessage formats msg as an RFC 5322 message
+func emailMessage(sender string, to []string, msg Message, date time.Time) ([]byte, error) {
+	from, err := mail.ParseAddress(sender)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sender %q: %w", sender, err)
+	}
+	subject := msg.Title
+	if msg.Severity != "" && msg.Severity != SeverityInfo {
+		subject = fmt.Sprintf("[%s] %s", msg.Severity, msg.Title)
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", from)
+	for _, addr := range to {
+		fmt.Fprintf(&buf, "To: %s\r\n", addr)
+	}
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", date.Format(time.RFC1123Z))
+	buf.WriteString("MIME-Version: 1.0\r\nContent-Type: text/plain; charset=utf-8\r\n\r\n")
+	buf.WriteString(msg.Body)
+	buf.WriteString("\r\n")
+	return buf.Bytes(), nil
+}
+
+// sendMail delivers a message like smtp.SendMail, bounded by ctx and
+// sendTimeout, over implicit TLS on port 465 and STARTTLS otherwise
+func sendMail(ctx context.Context, s config.SMTPConfig, auth smtp.Auth, to []string, msg []byte) error {
+	ctx, cancel := context.WithTimeout(ctx, sendTimeout)
+	defer cancel()
+
+	var conn net.Conn
+	var err error
+	if s.ImplicitTLS() {
+		dialer := &tls.Dialer{Config: &tls.Config{ServerName: s.Host}}
+		conn, err = dialer.DialContext(ctx, "tcp", s.Address())
+	} else {
+		var dialer net.Dialer
+		conn, err = dialer.DialContext(ctx, "tcp", s.Address())
+	}
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	client, err := smtp.NewClient(conn, s.Host)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	if ok, _ := client.Extension("STARTTLS"); ok && !s.ImplicitTLS() {
+		if err := client.StartTLS(&tls.Config{ServerName: s.Host}); err != nil {
+			return err
+		}
+	}
+	if auth != nil {
+		if err := client.Auth(auth); err != nil {
+			return err
+		}
+	}
+	from, err := mail.ParseAddress(s.Sender)
+	if err != nil {
+		return err
+	}
+	if err := client.Mail(from.Address); err != nil {
+		return err
+	}
+	for _, addr := range to {
+		if err := client.Rcpt(addr); err != nil {
+			return err
+		}
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
@@ -0,0 +1,134 @@
+// Package notify delivers messages to the notification channels configured
+// in .sdbx.yaml.
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/maiko/sdbx/internal/config"
+	"github.com/maiko/sdbx/internal/netproxy"
+)
+
+// sendTimeout bounds a single delivery
+const sendTimeout = 10 * time.Second
+
+// Message severities
+const (
+	SeverityInfo     = "info"
+	SeverityWarning  = "warning"
+	SeverityCritical = "critical"
+	SeverityResolved = "resolved"
+)
+
+// Message is a notification sent to every channel
+type Message struct {
+	Title    string `json:"title"`
+	Body     string `json:"message"`
+	Severity string `json:"severity"`
+	Source   string `json:"source,omitempty"` // What emitted the message (e.g. alert:disk-full)
+}
+
+// Notifier sends messages to channels. Tokens and the SMTP password are
+// read from the project's secrets directory.
+type Notifier struct {
+	ProjectDir string
+	Channels   []config.NotificationChannel
+	SMTP       config.SMTPConfig
+	Client     *http.Client
+
+	// SendMail delivers the email channels' messages through the mail server
+	SendMail func(ctx context.Context, s config.SMTPConfig, auth smtp.Auth, to []string, msg []byte) error
+}
+
+// New creates a notifier for the channels configured in cfg
+func New(projectDir string, cfg *config.Config) *Notifier {
+	return &Notifier{
+		ProjectDir: projectDir,
+		Channels:   cfg.Notifications.Channels,
+		SMTP:       cfg.SMTP,
+		Client:     netproxy.Client(cfg.Proxy.For(config.ProxyNotify), cfg.Proxy.NoProxy, sendTimeout),
+		SendMail:   sendMail,
+	}
+}
+
+// Send delivers msg to every channel and returns the joined errors of the
+// channels that failed
+func (n *Notifier) Send(ctx context.Context, msg Message) error {
+	var errs []error
+	for _, ch := range n.Channels {
+		if err := n.SendTo(ctx, ch, msg); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", ch.Name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// SendTo delivers msg to a single channel
+func (n *Notifier) SendTo(ctx context.Context, ch config.NotificationChannel, msg Message) error {
+	if ch.Type == config.NotifyEmail {
+		return n.sendEmail(ctx, ch, msg)
+	}
+
+	var req *http.Request
+	var err error
+	switch ch.Type {
+	case config.NotifyNtfy:
+		req, err = http.NewRequestWithContext(ctx, http.MethodPost, ch.URL, strings.NewReader(msg.Body))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Title", msg.Title)
+		req.Header.Set("Tags", ntfyTags[msg.Severity])
+		if msg.Severity == SeverityCritical {
+			req.Header.Set("Priority", "high")
+		}
+	case config.NotifyWebhook:
+		body, err := json.Marshal(msg)
+		if err != nil {
+			return err
+		}
+		req, err = http.NewRequestWithContext(ctx, http.MethodPost, ch.URL, bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/json")
+	default:
+		return fmt.Errorf("unsupported channel type %q", ch.Type)
+	}
+
+	if ch.TokenSecret != "" {
+		token, err := os.ReadFile(filepath.Join(n.ProjectDir, "secrets", ch.TokenSecret+".txt"))
+		if err != nil {
+			return fmt.Errorf("failed to read token secret: %w", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+strings.TrimSpace(string(token)))
+	}
+
+	resp, err := n.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return nil
+}
+
+// ntfyTags maps severities to ntfy emoji tags
+var ntfyTags = map[string]string{
+	SeverityInfo:     "information_source",
+	SeverityWarning:  "warning",
+	SeverityCritical: "rotating_light",
+	SeverityResolved: "white_check_mark",
+}
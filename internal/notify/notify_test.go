@@ -0,0 +1,149 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/maiko/sdbx/internal/config"
+	"github.com/maiko/sdbx/internal/problem"
+)
+
+func TestNotifierSend(t *testing.T) {
+	type request struct {
+		path, auth, title, body string
+	}
+	var got []request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		got = append(got, request{r.URL.Path, r.Header.Get("Authorization"), r.Header.Get("Title"), string(body)})
+		if r.URL.Path == "/broken" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	tmpDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(tmpDir, "secrets"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(tmpDir, "secrets", "ntfy_token.txt"), []byte("tk_123\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := config.DefaultConfig()
+	cfg.Notifications.Channels = []config.NotificationChannel{
+		{Name: "phone", Type: config.NotifyNtfy, URL: server.URL + "/sdbx", TokenSecret: "ntfy_token"},
+		{Name: "hook", Type: config.NotifyWebhook, URL: server.URL + "/hook"},
+		{Name: "broken", Type: config.NotifyWebhook, URL: server.URL + "/broken"},
+	}
+	n := New(tmpDir, cfg)
+
+	err := n.Send(context.Background(), Message{Title: "plex is down", Body: "plex has been down for 5m", Severity: SeverityCritical})
+	if err == nil || !strings.Contains(err.Error(), "broken: unexpected status 500") {
+		t.Errorf("Send error = %v, want the broken channel to fail", err)
+	}
+	if strings.Contains(err.Error(), "phone") || strings.Contains(err.Error(), "hook:") {
+		t.Errorf("only the broken channel should fail: %v", err)
+	}
+
+	if len(got) != 3 {
+		t.Fatalf("expected 3 deliveries, got %+v", got)
+	}
+	if got[0].path != "/sdbx" || got[0].auth != "Bearer tk_123" || got[0].title != "plex is down" || got[0].body != "plex has been down for 5m" {
+		t.Errorf("unexpected ntfy request: %+v", got[0])
+	}
+
+	var hook Message
+	if err := json.Unmarshal([]byte(got[1].body), &hook); err != nil {
+		t.Fatalf("webhook body is not JSON: %v", err)
+	}
+	if hook.Title != "plex is down" || hook.Severity != SeverityCritical || got[1].auth != "" {
+		t.Errorf("unexpected webhook request: %+v (%+v)", got[1], hook)
+	}
+}
+
+func TestNotifierSendEmail(t *testing.T) {
+	tmpDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(tmpDir, "secrets"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := config.DefaultConfig()
+	cfg.SMTP = config.SMTPConfig{Host: "smtp.example.com", User: "sdbx", Sender: "SDBX <sdbx@example.com>"}
+	cfg.Notifications.Channels = []config.NotificationChannel{
+		{Name: "mail", Type: config.NotifyEmail, URL: "mailto:alice@example.com,bob@example.com"},
+	}
+	n := New(tmpDir, cfg)
+	var sent struct {
+		auth smtp.Auth
+		to   []string
+		msg  string
+	}
+	n.SendMail = func(_ context.Context, s config.SMTPConfig, auth smtp.Auth, to []string, msg []byte) error {
+		sent.auth, sent.to, sent.msg = auth, to, string(msg)
+		return nil
+	}
+	msg := Message{Title: "plex is down", Body: "plex has been down for 5m", Severity: SeverityCritical}
+
+	// The password is required to log in
+	if err := n.Send(context.Background(), msg); !errors.Is(err, problem.ErrSecretMissing) {
+		t.Fatalf("Send error = %v, want a missing secret", err)
+	}
+	if err := os.WriteFile(filepath.Join(tmpDir, "secrets", "smtp_password.txt"), []byte("hunter2\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := n.Send(context.Background(), msg); err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	if sent.auth == nil || !slices.Equal(sent.to, []string{"alice@example.com", "bob@example.com"}) {
+		t.Errorf("sent to %v (auth %v)", sent.to, sent.auth)
+	}
+	for _, want := range []string{"From: \"SDBX\" <sdbx@example.com>\r\n", "To: alice@example.com\r\n", "Subject: [critical] plex is down\r\n", "\r\n\r\nplex has been down for 5m\r\n"} {
+		if !strings.Contains(sent.msg, want) {
+			t.Errorf("message lacks %q:\n%s", want, sent.msg)
+		}
+	}
+}
+
+func TestArrEmailConnection(t *testing.T) {
+	s := config.SMTPConfig{Host: "smtp.example.com", Port: config.SMTPSPort, User: "sdbx", Sender: "sdbx@example.com"}
+	data, err := json.Marshal(arrEmailConnection(s, "hunter2", []string{"alice@example.com"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var connection struct {
+		Name           string `json:"name"`
+		Implementation string `json:"implementation"`
+		Fields         []struct {
+			Name  string      `json:"name"`
+			Value interface{} `json:"value"`
+		} `json:"fields"`
+	}
+	if err := json.Unmarshal(data, &connection); err != nil {
+		t.Fatal(err)
+	}
+	if connection.Name != ArrConnectionName || connection.Implementation != "Email" {
+		t.Errorf("connection = %s", data)
+	}
+	fields := map[string]string{}
+	for _, f := range connection.Fields {
+		fields[f.Name] = fmt.Sprint(f.Value)
+	}
+	want := map[string]string{"server": "smtp.example.com", "port": "465", "useEncryption": "1", "password": "hunter2", "to": "[alice@example.com]"}
+	for name, value := range want {
+		if fields[name] != value {
+			t.Errorf("field %s = %q, want %q", name, fields[name], value)
+		}
+	}
+}
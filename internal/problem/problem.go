@@ -0,0 +1,119 @@
+// Package problem defines the failures sdbx explains to users: each kind has
+// a remediation hint and a documentation link, shown by the CLI error handler
+// and returned as problem+hint JSON by the web API.
+package problem
+
+import (
+	"errors"
+	"fmt"
+)
+
+// DocsURL is the error reference the kinds link to
+const DocsURL = "https://github.com/maiko/sdbx/blob/main/docs/troubleshooting.md"
+
+// Kind is a class of failure with a known remedy. Kinds are sentinel errors:
+// errors.Is(err, problem.ErrDockerDaemon) reports whether err is of a kind.
+type Kind struct {
+	Code  string // Stable identifier, also the anchor in DocsURL
+	Title string
+	Hint  string // What to do about it
+}
+
+func (k *Kind) Error() string {
+	return k.Title
+}
+
+// Doc returns the documentation link of the kind
+func (k *Kind) Doc() string {
+	return DocsURL + "#" + k.Code
+}
+
+// Error kinds
+var (
+	ErrPortConflict = &Kind{
+		Code:  "port-conflict",
+		Title: "port already in use",
+		Hint:  "Another process or service publishes the same host port: find it with 'sudo ss -ltnp', then stop it or change the port in .sdbx.yaml",
+	}
+	ErrSecretMissing = &Kind{
+		Code:  "secret-missing",
+		Title: "secret not configured",
+		Hint:  "Run 'sdbx up' to generate the declared secrets, and fill the manual ones in secrets/<name>.txt",
+	}
+	ErrSourceUnreachable = &Kind{
+		Code:  "source-unreachable",
+		Title: "service source unreachable",
+		Hint:  "Check the network and the source URL with 'sdbx source list'; cached definitions are used until the source is reachable again",
+	}
+	ErrOffline = &Kind{
+		Code:  "offline",
+		Title: "offline mode",
+		Hint:  "Run the command without --offline (and with offline: false in .sdbx.yaml) on a host with network access, or copy the cache it needs",
+	}
+	ErrDockerDaemon = &Kind{
+		Code:  "docker-daemon",
+		Title: "Docker daemon not reachable",
+		Hint:  "Start Docker (sudo systemctl start docker) and check that your user may use it (groups | grep docker), then run 'sdbx doctor'",
+	}
+)
+
+// Kinds lists every kind, in the order errors are matched against them
+var Kinds = []*Kind{ErrDockerDaemon, ErrPortConflict, ErrSecretMissing, ErrSourceUnreachable, ErrOffline}
+
+// Error is a failure of a known kind with its cause
+type Error struct {
+	Kind   *Kind
+	Detail string // What failed, e.g. "failed to clone official"
+	Err    error  // Underlying error, may be nil
+}
+
+// Wrap returns an error of the given kind, describing what failed and why
+func Wrap(kind *Kind, err error, format string, args ...any) *Error {
+	return &Error{Kind: kind, Detail: fmt.Sprintf(format, args...), Err: err}
+}
+
+func (e *Error) Error() string {
+	if e.Err == nil {
+		return fmt.Sprintf("%s: %s", e.Detail, e.Kind.Title)
+	}
+	return fmt.Sprintf("%s: %s: %v", e.Detail, e.Kind.Title, e.Err)
+}
+
+// Unwrap makes the kind and the cause visible to errors.Is and errors.As
+func (e *Error) Unwrap() []error {
+	if e.Err == nil {
+		return []error{e.Kind}
+	}
+	return []error{e.Kind, e.Err}
+}
+
+// KindOf returns the kind of err, or nil when it is of no known kind
+func KindOf(err error) *Kind {
+	for _, kind := range Kinds {
+		if errors.Is(err, kind) {
+			return kind
+		}
+	}
+	return nil
+}
+
+// Problem is the JSON form of an error: the fields of an RFC 9457 problem
+// detail, plus the code and remediation hint of its kind
+type Problem struct {
+	Type   string `json:"type"`             // Documentation link, "about:blank" for unknown kinds
+	Title  string `json:"title"`            // Summary of the kind
+	Status int    `json:"status,omitempty"` // HTTP status
+	Detail string `json:"detail,omitempty"` // What failed
+	Code   string `json:"code,omitempty"`
+	Hint   string `json:"hint,omitempty"`
+}
+
+// From returns the problem describing err. Detail is the error message,
+// which callers hide from untrusted clients.
+func From(err error, status int) Problem {
+	p := Problem{Type: "about:blank", Title: "error", Status: status, Detail: err.Error()}
+	if kind := KindOf(err); kind != nil {
+		p.Type, p.Title, p.Code, p.Hint = kind.Doc(), kind.Title, kind.Code, kind.Hint
+	}
+	return p
+}
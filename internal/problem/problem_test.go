@@ -0,0 +1,59 @@
+package problem
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestWrap(t *testing.T) {
+	cause := errors.New("exit status 128")
+	err := fmt.Errorf("failed to load sources: %w", Wrap(ErrSourceUnreachable, cause, "git clone of %s failed", "official"))
+
+	if !errors.Is(err, ErrSourceUnreachable) || !errors.Is(err, cause) {
+		t.Errorf("errors.Is() does not see the kind and the cause of %v", err)
+	}
+	if errors.Is(err, ErrDockerDaemon) {
+		t.Error("error matches another kind")
+	}
+	var perr *Error
+	if !errors.As(err, &perr) || perr.Detail != "git clone of official failed" {
+		t.Errorf("errors.As() = %+v", perr)
+	}
+	if want := "git clone of official failed: service source unreachable: exit status 128"; perr.Error() != want {
+		t.Errorf("Error() = %q, want %q", perr.Error(), want)
+	}
+	if got := Wrap(ErrPortConflict, nil, "port 80").Error(); got != "port 80: port already in use" {
+		t.Errorf("Error() without cause = %q", got)
+	}
+}
+
+func TestKindOf(t *testing.T) {
+	if kind := KindOf(fmt.Errorf("up: %w", ErrDockerDaemon)); kind != ErrDockerDaemon {
+		t.Errorf("KindOf() = %v, want ErrDockerDaemon", kind)
+	}
+	if kind := KindOf(errors.New("boom")); kind != nil {
+		t.Errorf("KindOf() = %v, want nil", kind)
+	}
+	for _, kind := range Kinds {
+		if kind.Code == "" || kind.Hint == "" || !strings.HasSuffix(kind.Doc(), "#"+kind.Code) {
+			t.Errorf("kind %+v is incomplete", kind)
+		}
+	}
+}
+
+func TestFrom(t *testing.T) {
+	p := From(Wrap(ErrSecretMissing, nil, "secret vpn_password"), 500)
+	if p.Code != "secret-missing" || p.Hint != ErrSecretMissing.Hint || p.Type != ErrSecretMissing.Doc() || p.Status != 500 {
+		t.Errorf("From() = %+v", p)
+	}
+	if p.Detail != "secret vpn_password: secret not configured" {
+		t.Errorf("Detail = %q", p.Detail)
+	}
+
+	p = From(errors.New("boom"), 0)
+	if p.Type != "about:blank" || p.Code != "" || p.Hint != "" || p.Detail != "boom" {
+		t.Errorf("From() of an unknown error = %+v", p)
+	}
+}
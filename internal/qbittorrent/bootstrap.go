@@ -0,0 +1,132 @@
+package qbittorrent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/maiko/sdbx/internal/config"
+	"github.com/maiko/sdbx/internal/secrets"
+)
+
+// PasswordSecret is the secret holding the Web UI password sdbx manages
+const PasswordSecret = "qbittorrent_password"
+
+// DefaultUsername is the Web UI user of a fresh qBittorrent
+const DefaultUsername = "admin"
+
+// ConfigFile is qBittorrent's config, relative to the project directory
+const ConfigFile = "configs/qbittorrent/qBittorrent/qBittorrent.conf"
+
+var (
+	// webUIAnnounced matches the log line qBittorrent prints with the Web UI
+	// login on every start ("Web UI" before 4.6)
+	webUIAnnounced = regexp.MustCompile(`The Web ?UI administrator username is`)
+
+	// tempPassword matches the password qBittorrent generates for a session
+	// when no Web UI password is set (4.6.1 and later)
+	tempPassword = regexp.MustCompile(`A temporary password is provided for this session: (\S+)`)
+
+	// savedPassword matches the Web UI password hash in qBittorrent.conf
+	savedPassword = regexp.MustCompile(`(?m)^WebUI\\Password_PBKDF2=(.+)$`)
+)
+
+// TempPassword reads the temporary Web UI password of the last start from
+// qBittorrent's logs. started is false until qBittorrent announced its Web
+// UI login; password is empty when that start did not need one.
+func TempPassword(logs string) (password string, started bool) {
+	starts := webUIAnnounced.FindAllStringIndex(logs, -1)
+	if len(starts) == 0 {
+		return "", false
+	}
+	last := logs[starts[len(starts)-1][0]:]
+	if m := tempPassword.FindStringSubmatch(last); m != nil {
+		return m[1], true
+	}
+	return "", true
+}
+
+// SavedPassword returns the Web UI password hash qBittorrent saved in its
+// config, or "" when no password is set
+func SavedPassword(projectDir string) string {
+	data, err := os.ReadFile(filepath.Join(projectDir, ConfigFile))
+	if err != nil {
+		return ""
+	}
+	if m := savedPassword.FindSubmatch(data); m != nil {
+		return strings.TrimSpace(string(m[1]))
+	}
+	return ""
+}
+
+// HasPassword reports whether the Web UI has a permanent password: saved
+// by qBittorrent, or set by Bootstrap before qBittorrent saved it
+func HasPassword(projectDir string, cfg *config.Config) bool {
+	if SavedPassword(projectDir) != "" {
+		return true
+	}
+	if cfg.DownloadClients.QBittorrent.PasswordSecret != PasswordSecret {
+		return false
+	}
+	_, err := os.Stat(filepath.Join(projectDir, "secrets", PasswordSecret+".txt"))
+	return err == nil
+}
+
+// SetCredentials changes the Web UI login
+func (c *Client) SetCredentials(ctx context.Context, username, password string) error {
+	if err := c.login(ctx); err != nil {
+		return err
+	}
+	prefs, err := json.Marshal(map[string]string{"web_ui_username": username, "web_ui_password": password})
+	if err != nil {
+		return err
+	}
+	if _, err := c.do(ctx, http.MethodPost, "/api/v2/app/setPreferences", url.Values{"json": {string(prefs)}}); err != nil {
+		return err
+	}
+	c.Username, c.Password = username, password
+	return nil
+}
+
+// Bootstrap replaces the temporary Web UI password of a fresh qBittorrent
+// with the password sdbx manages, in the PasswordSecret secret (generated
+// when missing). It logs in with tempPassword, sets the password through
+// the Web API and records the credentials in download_clients.qbittorrent,
+// so the integrations log in with them.
+func Bootstrap(ctx context.Context, projectDir string, cfg *config.Config, tempPassword string) error {
+	result, err := secrets.EnsureSecrets(filepath.Join(projectDir, "secrets"), []secrets.Spec{
+		{Name: PasswordSecret, Type: secrets.TypePassword, Length: 24},
+	})
+	if err != nil {
+		return err
+	}
+	password := result.Values[PasswordSecret+".txt"]
+
+	q := cfg.DownloadClients.QBittorrent
+	if q.Username == "" {
+		q.Username = DefaultUsername
+	}
+	client, err := New(projectDir, cfg, HostURL(projectDir, cfg))
+	if err != nil {
+		return err
+	}
+	client.Username, client.Password = q.Username, tempPassword
+	if err := client.SetCredentials(ctx, q.Username, password); err != nil {
+		return fmt.Errorf("failed to set the qBittorrent Web UI password: %w", err)
+	}
+
+	if q.PasswordSecret != PasswordSecret || cfg.DownloadClients.QBittorrent.Username == "" {
+		q.PasswordSecret = PasswordSecret
+		cfg.DownloadClients.QBittorrent = q
+		if err := cfg.Save(filepath.Join(projectDir, ".sdbx.yaml")); err != nil {
+			return fmt.Errorf("failed to record the qBittorrent credentials: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+package qbittorrent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/maiko/sdbx/internal/config"
+)
+
+func TestTempPassword(t *testing.T) {
+	first := "The WebUI administrator username is: admin\n" +
+		"The WebUI administrator password was not set. A temporary password is provided for this session: Ab3dE9xYz\n"
+	tests := []struct {
+		name     string
+		logs     string
+		password string
+		started  bool
+	}{
+		{"starting", "[migrations] started\n", "", false},
+		{"temporary", first, "Ab3dE9xYz", true},
+		{"restarted", first + "The WebUI administrator username is: admin\nThe WebUI administrator password was not set. A temporary password is provided for this session: Q7rT2\n", "Q7rT2", true},
+		{"password set since", first + "The WebUI administrator username is: admin\nWebUI will be started shortly after internal preparations. Please wait...\n", "", true},
+		{"qBittorrent 4.5", "The Web UI administrator username is: admin\nThe Web UI administrator password has been changed from the default one.\n", "", true},
+	}
+	for _, tt := range tests {
+		password, started := TempPassword(tt.logs)
+		if password != tt.password || started != tt.started {
+			t.Errorf("%s: TempPassword() = (%q, %v), want (%q, %v)", tt.name, password, started, tt.password, tt.started)
+		}
+	}
+}
+
+func TestHasPassword(t *testing.T) {
+	projectDir := t.TempDir()
+	cfg := config.DefaultConfig()
+	if HasPassword(projectDir, cfg) {
+		t.Error("HasPassword() of a fresh project = true")
+	}
+
+	conf := filepath.Join(projectDir, ConfigFile)
+	if err := os.MkdirAll(filepath.Dir(conf), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(conf, []byte("[Preferences]\nWebUI\\LocalHostAuth=false\nWebUI\\Password_PBKDF2=\"@ByteArray(a:b)\"\r\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if got := SavedPassword(projectDir); got != `"@ByteArray(a:b)"` {
+		t.Errorf("SavedPassword() = %q", got)
+	}
+	if !HasPassword(projectDir, cfg) {
+		t.Error("HasPassword() = false with a saved password")
+	}
+}
+
+func TestBootstrap(t *testing.T) {
+	var prefs map[string]string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		switch r.URL.Path {
+		case "/api/v2/auth/login":
+			if r.Form.Get("username") != "admin" || r.Form.Get("password") != "temp123" {
+				fmt.Fprint(w, "Fails.")
+				return
+			}
+			http.SetCookie(w, &http.Cookie{Name: "SID", Value: "abc", Path: "/"})
+			fmt.Fprint(w, "Ok.")
+		case "/api/v2/app/setPreferences":
+			if c, err := r.Cookie("SID"); err != nil || c.Value != "abc" {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			_ = json.Unmarshal([]byte(r.Form.Get("json")), &prefs)
+		}
+	}))
+	defer srv.Close()
+
+	projectDir := t.TempDir()
+	cfg := config.DefaultConfig()
+	cfg.DownloadClients.QBittorrent.URL = srv.URL
+
+	if err := Bootstrap(context.Background(), projectDir, cfg, "wrong"); err == nil {
+		t.Fatal("Bootstrap() with a wrong temporary password succeeded")
+	}
+	if err := Bootstrap(context.Background(), projectDir, cfg, "temp123"); err != nil {
+		t.Fatalf("Bootstrap() error = %v", err)
+	}
+
+	secret, err := os.ReadFile(filepath.Join(projectDir, "secrets", PasswordSecret+".txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if prefs["web_ui_username"] != "admin" || prefs["web_ui_password"] != strings.TrimSpace(string(secret)) || len(secret) < 24 {
+		t.Errorf("preferences set = %v, want admin and the password of the secret", prefs)
+	}
+	if q := cfg.DownloadClients.QBittorrent; q.Username != "admin" || q.PasswordSecret != PasswordSecret {
+		t.Errorf("download_clients.qbittorrent = %+v, want the managed credentials", q)
+	}
+	if !HasPassword(projectDir, cfg) {
+		t.Error("HasPassword() = false after Bootstrap")
+	}
+	saved, err := os.ReadFile(filepath.Join(projectDir, ".sdbx.yaml"))
+	if err != nil || !strings.Contains(string(saved), PasswordSecret) {
+		t.Errorf(".sdbx.yaml does not record the credentials: %v\n%s", err, saved)
+	}
+}
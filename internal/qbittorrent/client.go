@@ -0,0 +1,230 @@
+// Package qbittorrent is a minimal client for the qBittorrent Web API (v2).
+package qbittorrent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/maiko/sdbx/internal/config"
+	"github.com/maiko/sdbx/internal/netproxy"
+	"github.com/maiko/sdbx/internal/registry"
+)
+
+// requestTimeout bounds a single API call
+const requestTimeout = 15 * time.Second
+
+// Torrent is the part of /api/v2/torrents/info sdbx uses
+type Torrent struct {
+	Hash        string  `json:"hash"`
+	Name        string  `json:"name"`
+	Category    string  `json:"category"`
+	State       string  `json:"state"`
+	Progress    float64 `json:"progress"`     // 0..1
+	Ratio       float64 `json:"ratio"`        // Share ratio
+	SeedingTime int64   `json:"seeding_time"` // Seconds spent seeding
+	Size        int64   `json:"size"`
+}
+
+// Completed reports whether the torrent is fully downloaded
+func (t Torrent) Completed() bool {
+	return t.Progress >= 1
+}
+
+// Stopped reports whether the torrent is paused (stopped in qBittorrent 5)
+func (t Torrent) Stopped() bool {
+	return strings.HasPrefix(t.State, "paused") || strings.HasPrefix(t.State, "stopped")
+}
+
+// Seeded returns the time spent seeding
+func (t Torrent) Seeded() time.Duration {
+	return time.Duration(t.SeedingTime) * time.Second
+}
+
+// Client talks to one qBittorrent instance
+type Client struct {
+	BaseURL  string
+	Username string
+	Password string
+	HTTP     *http.Client
+
+	loggedIn bool
+}
+
+// New creates a client from download_clients.qbittorrent, reading the
+// password from the project's secrets. baseURL is used when no URL is
+// configured.
+func New(projectDir string, cfg *config.Config, baseURL string) (*Client, error) {
+	q := cfg.DownloadClients.QBittorrent
+	if q.URL != "" {
+		baseURL = q.URL
+	}
+	jar, _ := cookiejar.New(nil)
+	client := netproxy.Client(cfg.Proxy.For(config.ProxyQBittorrent), cfg.Proxy.NoProxy, requestTimeout)
+	client.Jar = jar
+	c := &Client{
+		BaseURL:  strings.TrimSuffix(baseURL, "/"),
+		Username: q.Username,
+		HTTP:     client,
+	}
+	if q.PasswordSecret != "" {
+		password, err := os.ReadFile(filepath.Join(projectDir, "secrets", q.PasswordSecret+".txt"))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read qBittorrent password: %w", err)
+		}
+		c.Password = strings.TrimSpace(string(password))
+	}
+	return c, nil
+}
+
+// ContainerURL is the qBittorrent Web API as seen from the Docker networks:
+// behind gluetun when the VPN is enabled
+func ContainerURL(cfg *config.Config) string {
+	if cfg.VPNEnabled {
+		return "http://sdbx-gluetun:8080"
+	}
+	return "http://sdbx-qbittorrent:8080"
+}
+
+// HostURL is the qBittorrent Web API as published on the host: on the host
+// port the last generation recorded in the lock file, when it moved
+func HostURL(projectDir string, cfg *config.Config) string {
+	service := "qbittorrent"
+	if cfg.VPNEnabled {
+		service = "gluetun"
+	}
+	lock, err := registry.NewLoader().LoadLockFile(registry.GetLockFilePath(projectDir))
+	if err != nil {
+		return config.DefaultQBittorrentURL
+	}
+	if port, ok := lock.HostPorts[registry.HostPortKey(service, "8080", "tcp")]; ok {
+		return fmt.Sprintf("http://localhost:%d", port)
+	}
+	return config.DefaultQBittorrentURL
+}
+
+// login opens a session when credentials are configured
+func (c *Client) login(ctx context.Context) error {
+	if c.Username == "" || c.loggedIn {
+		return nil
+	}
+	form := url.Values{"username": {c.Username}, "password": {c.Password}}
+	body, err := c.do(ctx, http.MethodPost, "/api/v2/auth/login", form)
+	if err != nil {
+		return err
+	}
+	if strings.TrimSpace(string(body)) != "Ok." {
+		return fmt.Errorf("qBittorrent login rejected for %s", c.Username)
+	}
+	c.loggedIn = true
+	return nil
+}
+
+// Torrents lists all torrents
+func (c *Client) Torrents(ctx context.Context) ([]Torrent, error) {
+	if err := c.login(ctx); err != nil {
+		return nil, err
+	}
+	body, err := c.do(ctx, http.MethodGet, "/api/v2/torrents/info", nil)
+	if err != nil {
+		return nil, err
+	}
+	var torrents []Torrent
+	if err := json.Unmarshal(body, &torrents); err != nil {
+		return nil, fmt.Errorf("failed to parse torrent list: %w", err)
+	}
+	return torrents, nil
+}
+
+// Stop pauses torrents ("all" for every torrent). qBittorrent 5 renamed
+// pause to stop; the old endpoint is used when the new one is missing.
+func (c *Client) Stop(ctx context.Context, hashes ...string) error {
+	return c.action(ctx, "stop", "pause", hashes)
+}
+
+// Start resumes torrents ("all" for every torrent)
+func (c *Client) Start(ctx context.Context, hashes ...string) error {
+	return c.action(ctx, "start", "resume", hashes)
+}
+
+// Delete removes torrents, and their downloaded files with deleteFiles
+func (c *Client) Delete(ctx context.Context, deleteFiles bool, hashes ...string) error {
+	if err := c.login(ctx); err != nil {
+		return err
+	}
+	form := url.Values{"hashes": {strings.Join(hashes, "|")}, "deleteFiles": {fmt.Sprint(deleteFiles)}}
+	_, err := c.do(ctx, http.MethodPost, "/api/v2/torrents/delete", form)
+	return err
+}
+
+// action posts hashes to a torrents endpoint, falling back to its
+// qBittorrent 4 name
+func (c *Client) action(ctx context.Context, endpoint, legacy string, hashes []string) error {
+	if err := c.login(ctx); err != nil {
+		return err
+	}
+	form := url.Values{"hashes": {strings.Join(hashes, "|")}}
+	_, err := c.do(ctx, http.MethodPost, "/api/v2/torrents/"+endpoint, form)
+	if isNotFound(err) {
+		_, err = c.do(ctx, http.MethodPost, "/api/v2/torrents/"+legacy, form)
+	}
+	return err
+}
+
+// statusError is a non-2xx API response
+type statusError struct {
+	path string
+	code int
+}
+
+func (e *statusError) Error() string {
+	if e.code == http.StatusForbidden {
+		return fmt.Sprintf("qBittorrent %s: forbidden (set download_clients.qbittorrent.username and password_secret)", e.path)
+	}
+	return fmt.Sprintf("qBittorrent %s: %d %s", e.path, e.code, http.StatusText(e.code))
+}
+
+func isNotFound(err error) bool {
+	var se *statusError
+	return errors.As(err, &se) && se.code == http.StatusNotFound
+}
+
+// do sends a request, form-encoded when form is set, and returns the body
+func (c *Client) do(ctx context.Context, method, path string, form url.Values) ([]byte, error) {
+	var body io.Reader
+	if form != nil {
+		body = strings.NewReader(form.Encode())
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.BaseURL+path, body)
+	if err != nil {
+		return nil, err
+	}
+	if form != nil {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+	// qBittorrent rejects requests whose Referer/Origin does not match its host
+	req.Header.Set("Referer", c.BaseURL)
+
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("qBittorrent unreachable at %s: %w", c.BaseURL, err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 32<<20))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 300 {
+		return nil, &statusError{path: path, code: resp.StatusCode}
+	}
+	return data, nil
+}
@@ -0,0 +1,61 @@
+//go:build e2e
+
+package qbittorrent
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/maiko/sdbx/internal/config"
+	"github.com/maiko/sdbx/internal/e2e"
+)
+
+// temporaryPassword matches the Web UI password qBittorrent logs on first start
+var temporaryPassword = regexp.MustCompile(`temporary password is provided for this session: (\S+)`)
+
+func TestE2EClient(t *testing.T) {
+	qbt := e2e.Run(t, "lscr.io/linuxserver/qbittorrent:latest", map[string]string{"WEBUI_PORT": "8080"}, "8080")
+	password := qbt.WaitForLog(temporaryPassword, 2*time.Minute)[1]
+	baseURL := qbt.URL("8080")
+	qbt.WaitForHTTP(baseURL, time.Minute)
+
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "secrets"), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "secrets", "qbittorrent_password.txt"), []byte(password+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cfg := config.DefaultConfig()
+	cfg.DownloadClients.QBittorrent = config.QBittorrentConfig{Username: "admin", PasswordSecret: "qbittorrent_password"}
+
+	client, err := New(dir, cfg, baseURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	torrents, err := client.Torrents(ctx)
+	if err != nil {
+		t.Fatalf("Torrents() error = %v", err)
+	}
+	if len(torrents) != 0 {
+		t.Errorf("Torrents() = %+v, want none on a fresh instance", torrents)
+	}
+	// Pause/resume endpoints exist under one of their names
+	if err := client.Stop(ctx, "all"); err != nil {
+		t.Errorf("Stop() error = %v", err)
+	}
+	if err := client.Start(ctx, "all"); err != nil {
+		t.Errorf("Start() error = %v", err)
+	}
+
+	// A wrong password is rejected by the real login
+	client.Password, client.loggedIn = "wrong", false
+	if _, err := client.Torrents(ctx); err == nil {
+		t.Error("Torrents() succeeded with a wrong password")
+	}
+}
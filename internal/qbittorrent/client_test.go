@@ -0,0 +1,122 @@
+package qbittorrent
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/maiko/sdbx/internal/config"
+	"github.com/maiko/sdbx/internal/registry"
+)
+
+func TestClient(t *testing.T) {
+	var calls []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		calls = append(calls, r.URL.Path+" "+r.Form.Get("hashes")+r.Form.Get("deleteFiles"))
+		switch r.URL.Path {
+		case "/api/v2/auth/login":
+			if r.Form.Get("password") != "s3cret" {
+				fmt.Fprint(w, "Fails.")
+				return
+			}
+			http.SetCookie(w, &http.Cookie{Name: "SID", Value: "abc", Path: "/"})
+			fmt.Fprint(w, "Ok.")
+		case "/api/v2/torrents/info":
+			if c, err := r.Cookie("SID"); err != nil || c.Value != "abc" {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			fmt.Fprint(w, `[{"hash":"h1","name":"Movie","category":"movies","state":"stalledUP","progress":1,"ratio":2.5,"seeding_time":3600}]`)
+		case "/api/v2/torrents/stop":
+			// qBittorrent 4 has no stop endpoint
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	projectDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(projectDir, "secrets"), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(projectDir, "secrets", "qbt.txt"), []byte("s3cret\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cfg := config.DefaultConfig()
+	cfg.DownloadClients.QBittorrent = config.QBittorrentConfig{URL: srv.URL, Username: "admin", PasswordSecret: "qbt"}
+
+	client, err := New(projectDir, cfg, "http://unused")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	torrents, err := client.Torrents(ctx)
+	if err != nil {
+		t.Fatalf("Torrents() error = %v", err)
+	}
+	if len(torrents) != 1 || !torrents[0].Completed() || torrents[0].Stopped() || torrents[0].Seeded().Hours() != 1 {
+		t.Errorf("Torrents() = %+v", torrents)
+	}
+
+	if err := client.Stop(ctx, "h1", "h2"); err != nil {
+		t.Errorf("Stop() error = %v", err)
+	}
+	if err := client.Delete(ctx, true, "h1"); err != nil {
+		t.Errorf("Delete() error = %v", err)
+	}
+
+	want := []string{
+		"/api/v2/auth/login ",
+		"/api/v2/torrents/info ",
+		"/api/v2/torrents/stop h1|h2",
+		"/api/v2/torrents/pause h1|h2",
+		"/api/v2/torrents/delete h1true",
+	}
+	if strings.Join(calls, "\n") != strings.Join(want, "\n") {
+		t.Errorf("calls =\n%s\nwant\n%s", strings.Join(calls, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestClientForbidden(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer srv.Close()
+
+	client, err := New(t.TempDir(), config.DefaultConfig(), srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = client.Torrents(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "password_secret") {
+		t.Errorf("Torrents() error = %v, want a credentials hint", err)
+	}
+}
+
+func TestHostURL(t *testing.T) {
+	projectDir := t.TempDir()
+	cfg := config.DefaultConfig()
+	if got := HostURL(projectDir, cfg); got != config.DefaultQBittorrentURL {
+		t.Errorf("HostURL() without a lock file = %s", got)
+	}
+
+	lock := &registry.LockFile{APIVersion: registry.APIVersion, Kind: registry.KindLockFile, HostPorts: map[string]int{
+		registry.HostPortKey("qbittorrent", "8080", "tcp"): 20003,
+	}}
+	if err := registry.NewLoader().SaveLockFile(registry.GetLockFilePath(projectDir), lock); err != nil {
+		t.Fatal(err)
+	}
+	if got := HostURL(projectDir, cfg); got != "http://localhost:20003" {
+		t.Errorf("HostURL() = %s, want the allocated port", got)
+	}
+	cfg.VPNEnabled = true
+	if got := HostURL(projectDir, cfg); got != config.DefaultQBittorrentURL {
+		t.Errorf("HostURL() behind the VPN = %s, want gluetun's port", got)
+	}
+}
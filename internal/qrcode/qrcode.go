@@ -0,0 +1,517 @@
+// Package qrcode encodes text as QR codes (ISO/IEC 18004) and renders them
+// in the terminal. It covers what URLs need: byte mode, error correction
+// level M and versions 1 to 10, up to 213 bytes.
+package qrcode
+
+import (
+	"errors"
+	"strings"
+)
+
+// MaxLength is the longest text, in bytes, a code can hold
+const MaxLength = 213
+
+// ErrTooLong is returned for text longer than MaxLength bytes
+var ErrTooLong = errors.New("text too long for a QR code")
+
+// quietZone is the light border around codes, in modules
+const quietZone = 2
+
+// block layout of a version at level M: ecPerBlock error correction
+// codewords for each block, blocks1 blocks of data1 data codewords, then
+// blocks2 blocks of data1+1
+type versionInfo struct {
+	ecPerBlock int
+	blocks1    int
+	data1      int
+	blocks2    int
+	alignment  []int // Centers of the alignment patterns
+}
+
+var versions = [...]versionInfo{
+	1:  {10, 1, 16, 0, nil},
+	2:  {16, 1, 28, 0, []int{6, 18}},
+	3:  {26, 1, 44, 0, []int{6, 22}},
+	4:  {18, 2, 32, 0, []int{6, 26}},
+	5:  {24, 2, 43, 0, []int{6, 30}},
+	6:  {16, 4, 27, 0, []int{6, 34}},
+	7:  {18, 4, 31, 0, []int{6, 22, 38}},
+	8:  {22, 2, 38, 2, []int{6, 24, 42}},
+	9:  {22, 3, 36, 2, []int{6, 26, 46}},
+	10: {26, 4, 43, 1, []int{6, 28, 50}},
+}
+
+// dataCodewords returns the number of data codewords of the version
+func (v versionInfo) dataCodewords() int {
+	return v.blocks1*v.data1 + v.blocks2*(v.data1+1)
+}
+
+// Code is an encoded QR code
+type Code struct {
+	Version int
+	Size    int // Modules per side
+	Mask    int
+
+	modules    [][]bool // Dark modules, by row then column
+	isFunction [][]bool // Finder, timing, alignment and format modules
+}
+
+// Encode encodes text in the smallest version holding it
+func Encode(text string) (*Code, error) {
+	data := []byte(text)
+	version := 0
+	for v := 1; v < len(versions); v++ {
+		countBits := 8
+		if v >= 10 {
+			countBits = 16
+		}
+		if 4+countBits+8*len(data) <= 8*versions[v].dataCodewords() {
+			version = v
+			break
+		}
+	}
+	if version == 0 {
+		return nil, ErrTooLong
+	}
+
+	size := 4*version + 17
+	c := &Code{Version: version, Size: size}
+	c.modules = make([][]bool, size)
+	c.isFunction = make([][]bool, size)
+	for i := range size {
+		c.modules[i] = make([]bool, size)
+		c.isFunction[i] = make([]bool, size)
+	}
+
+	c.drawFunctionPatterns()
+	c.drawCodewords(addErrorCorrection(encodeData(data, version), versions[version]))
+
+	// Keep the mask of lowest penalty
+	best, bestPenalty := 0, -1
+	for mask := range 8 {
+		c.applyMask(mask)
+		c.drawFormat(mask)
+		if p := c.penalty(); bestPenalty < 0 || p < bestPenalty {
+			best, bestPenalty = mask, p
+		}
+		c.applyMask(mask) // XOR again to undo it
+	}
+	c.Mask = best
+	c.applyMask(best)
+	c.drawFormat(best)
+	return c, nil
+}
+
+// Dark reports whether the module at column x and row y is dark
+func (c *Code) Dark(x, y int) bool {
+	return c.modules[y][x]
+}
+
+// Terminal renders the code with half blocks, two rows per line, inked
+// where modules are light: terminals are mostly dark, and the light
+// modules and quiet zone must be the bright ones for the code to scan
+func (c *Code) Terminal() string {
+	light := func(x, y int) bool {
+		if x < 0 || y < 0 || x >= c.Size || y >= c.Size {
+			return true
+		}
+		return !c.modules[y][x]
+	}
+
+	var b strings.Builder
+	for y := -quietZone; y < c.Size+quietZone; y += 2 {
+		for x := -quietZone; x < c.Size+quietZone; x++ {
+			top, bottom := light(x, y), light(x, y+1)
+			switch {
+			case top && bottom:
+				b.WriteString("█")
+			case top:
+				b.WriteString("▀")
+			case bottom:
+				b.WriteString("▄")
+			default:
+				b.WriteString(" ")
+			}
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// encodeData returns the data codewords of text in byte mode: mode, count,
+// bytes, terminator and padding
+func encodeData(data []byte, version int) []byte {
+	var bits bitBuffer
+	bits.append(0b0100, 4)
+	if version >= 10 {
+		bits.append(len(data), 16)
+	} else {
+		bits.append(len(data), 8)
+	}
+	for _, b := range data {
+		bits.append(int(b), 8)
+	}
+
+	capacity := 8 * versions[version].dataCodewords()
+	bits.append(0, min(4, capacity-len(bits)))
+	bits.append(0, (8-len(bits)%8)%8)
+	for pad := 0xEC; len(bits) < capacity; pad ^= 0xEC ^ 0x11 {
+		bits.append(pad, 8)
+	}
+
+	codewords := make([]byte, len(bits)/8)
+	for i, bit := range bits {
+		if bit {
+			codewords[i/8] |= 1 << (7 - i%8)
+		}
+	}
+	return codewords
+}
+
+// addErrorCorrection splits data into blocks, computes their error
+// correction codewords and interleaves them all
+func addErrorCorrection(data []byte, v versionInfo) []byte {
+	divisor := reedSolomonDivisor(v.ecPerBlock)
+	var blocks, ecBlocks [][]byte
+	for i, offset := 0, 0; i < v.blocks1+v.blocks2; i++ {
+		n := v.data1
+		if i >= v.blocks1 {
+			n++
+		}
+		block := data[offset : offset+n]
+		offset += n
+		blocks = append(blocks, block)
+		ecBlocks = append(ecBlocks, reedSolomonRemainder(block, divisor))
+	}
+
+	var result []byte
+	for i := 0; i <= v.data1; i++ {
+		for _, block := range blocks {
+			if i < len(block) {
+				result = append(result, block[i])
+			}
+		}
+	}
+	for i := range v.ecPerBlock {
+		for _, block := range ecBlocks {
+			result = append(result, block[i])
+		}
+	}
+	return result
+}
+
+// drawFunctionPatterns draws the finder, timing and alignment patterns and
+// the version information, and reserves the format areas
+func (c *Code) drawFunctionPatterns() {
+	for i := range c.Size {
+		c.setFunction(6, i, i%2 == 0)
+		c.setFunction(i, 6, i%2 == 0)
+	}
+
+	c.drawFinder(3, 3)
+	c.drawFinder(c.Size-4, 3)
+	c.drawFinder(3, c.Size-4)
+
+	centers := versions[c.Version].alignment
+	for i, x := range centers {
+		for j, y := range centers {
+			// Skip the three corners holding finders
+			last := len(centers) - 1
+			if (i == 0 && j == 0) || (i == 0 && j == last) || (i == last && j == 0) {
+				continue
+			}
+			c.drawAlignment(x, y)
+		}
+	}
+
+	c.drawFormat(0) // Reserved, drawn again once the mask is known
+	c.drawVersion()
+}
+
+// drawFinder draws a finder pattern and its separator around center x, y
+func (c *Code) drawFinder(x, y int) {
+	for dy := -4; dy <= 4; dy++ {
+		for dx := -4; dx <= 4; dx++ {
+			xx, yy := x+dx, y+dy
+			if xx < 0 || yy < 0 || xx >= c.Size || yy >= c.Size {
+				continue
+			}
+			dist := max(abs(dx), abs(dy))
+			c.setFunction(xx, yy, dist != 2 && dist != 4)
+		}
+	}
+}
+
+// drawAlignment draws an alignment pattern around center x, y
+func (c *Code) drawAlignment(x, y int) {
+	for dy := -2; dy <= 2; dy++ {
+		for dx := -2; dx <= 2; dx++ {
+			c.setFunction(x+dx, y+dy, max(abs(dx), abs(dy)) != 1)
+		}
+	}
+}
+
+// drawFormat draws both copies of the format information of level M and
+// mask, and the dark module
+func (c *Code) drawFormat(mask int) {
+	bits := formatBits(mask)
+	bit := func(i int) bool { return bits>>i&1 != 0 }
+
+	for i := range 6 {
+		c.setFunction(8, i, bit(i))
+	}
+	c.setFunction(8, 7, bit(6))
+	c.setFunction(8, 8, bit(7))
+	c.setFunction(7, 8, bit(8))
+	for i := 9; i < 15; i++ {
+		c.setFunction(14-i, 8, bit(i))
+	}
+
+	for i := range 8 {
+		c.setFunction(c.Size-1-i, 8, bit(i))
+	}
+	for i := 8; i < 15; i++ {
+		c.setFunction(8, c.Size-15+i, bit(i))
+	}
+	c.setFunction(8, c.Size-8, true)
+}
+
+// formatBits returns the 15 format bits of level M and mask, BCH encoded
+// and masked
+func formatBits(mask int) int {
+	const levelM = 0b00
+	data := levelM<<3 | mask
+	rem := data
+	for range 10 {
+		rem = rem<<1 ^ (rem>>9)*0x537
+	}
+	return (data<<10 | rem) ^ 0x5412
+}
+
+// drawVersion draws both copies of the version information, for versions
+// 7 and up
+func (c *Code) drawVersion() {
+	if c.Version < 7 {
+		return
+	}
+	bits := versionBits(c.Version)
+	for i := range 18 {
+		dark := bits>>i&1 != 0
+		a, b := c.Size-11+i%3, i/3
+		c.setFunction(a, b, dark)
+		c.setFunction(b, a, dark)
+	}
+}
+
+// versionBits returns the 18 version bits, BCH encoded
+func versionBits(version int) int {
+	rem := version
+	for range 12 {
+		rem = rem<<1 ^ (rem>>11)*0x1F25
+	}
+	return version<<12 | rem
+}
+
+// drawCodewords places the codewords in the zigzag order of the standard,
+// in pairs of columns from the bottom right, skipping the vertical timing
+// pattern
+func (c *Code) drawCodewords(codewords []byte) {
+	i := 0
+	for right := c.Size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			right = 5
+		}
+		upward := (right+1)&2 == 0
+		for vert := range c.Size {
+			y := vert
+			if upward {
+				y = c.Size - 1 - vert
+			}
+			for j := range 2 {
+				x := right - j
+				if c.isFunction[y][x] || i >= len(codewords)*8 {
+					continue
+				}
+				c.modules[y][x] = codewords[i/8]>>(7-i%8)&1 != 0
+				i++
+			}
+		}
+	}
+}
+
+// applyMask flips the data modules selected by mask; applying it twice
+// restores them
+func (c *Code) applyMask(mask int) {
+	for y := range c.Size {
+		for x := range c.Size {
+			if c.isFunction[y][x] {
+				continue
+			}
+			var flip bool
+			switch mask {
+			case 0:
+				flip = (x+y)%2 == 0
+			case 1:
+				flip = y%2 == 0
+			case 2:
+				flip = x%3 == 0
+			case 3:
+				flip = (x+y)%3 == 0
+			case 4:
+				flip = (x/3+y/2)%2 == 0
+			case 5:
+				flip = x*y%2+x*y%3 == 0
+			case 6:
+				flip = (x*y%2+x*y%3)%2 == 0
+			case 7:
+				flip = ((x+y)%2+x*y%3)%2 == 0
+			}
+			if flip {
+				c.modules[y][x] = !c.modules[y][x]
+			}
+		}
+	}
+}
+
+// penalty scores the code with the four rules of the standard: runs of
+// five or more modules, 2x2 blocks, finder-like patterns and imbalance
+// between dark and light modules
+func (c *Code) penalty() int {
+	score := 0
+	line := make([]bool, c.Size)
+	for _, horizontal := range []bool{true, false} {
+		for i := range c.Size {
+			for j := range c.Size {
+				if horizontal {
+					line[j] = c.modules[i][j]
+				} else {
+					line[j] = c.modules[j][i]
+				}
+			}
+			score += linePenalty(line)
+		}
+	}
+
+	dark := 0
+	for y := range c.Size {
+		for x := range c.Size {
+			if c.modules[y][x] {
+				dark++
+			}
+			if x > 0 && y > 0 {
+				m := c.modules[y][x]
+				if c.modules[y-1][x] == m && c.modules[y][x-1] == m && c.modules[y-1][x-1] == m {
+					score += 3
+				}
+			}
+		}
+	}
+
+	total := c.Size * c.Size
+	// Steps of 5% away from half dark, rounded up
+	k := (abs(dark*20-total*10)+total-1)/total - 1
+	score += max(k, 0) * 10
+	return score
+}
+
+// finderLike are the patterns of rule 3: 1:1:3:1:1 with four light modules
+// on one side
+var finderLike = [][]bool{
+	{true, false, true, true, true, false, true, false, false, false, false},
+	{false, false, false, false, true, false, true, true, true, false, true},
+}
+
+// linePenalty scores the runs and finder-like patterns of a row or column
+func linePenalty(line []bool) int {
+	score := 0
+	run := 1
+	for i := 1; i <= len(line); i++ {
+		if i < len(line) && line[i] == line[i-1] {
+			run++
+			continue
+		}
+		if run >= 5 {
+			score += run - 2
+		}
+		run = 1
+	}
+
+	for i := 0; i+11 <= len(line); i++ {
+		for _, pattern := range finderLike {
+			match := true
+			for j, dark := range pattern {
+				if line[i+j] != dark {
+					match = false
+					break
+				}
+			}
+			if match {
+				score += 40
+			}
+		}
+	}
+	return score
+}
+
+func (c *Code) setFunction(x, y int, dark bool) {
+	c.modules[y][x] = dark
+	c.isFunction[y][x] = true
+}
+
+// bitBuffer is a sequence of bits, most significant first
+type bitBuffer []bool
+
+func (b *bitBuffer) append(value, n int) {
+	for i := n - 1; i >= 0; i-- {
+		*b = append(*b, value>>i&1 != 0)
+	}
+}
+
+// reedSolomonDivisor returns the generator polynomial of degree, highest
+// coefficient first and the leading 1 omitted
+func reedSolomonDivisor(degree int) []byte {
+	result := make([]byte, degree)
+	result[degree-1] = 1
+	root := byte(1)
+	for range degree {
+		for j := range result {
+			result[j] = gfMultiply(result[j], root)
+			if j+1 < len(result) {
+				result[j] ^= result[j+1]
+			}
+		}
+		root = gfMultiply(root, 0x02)
+	}
+	return result
+}
+
+// reedSolomonRemainder returns the error correction codewords of data
+func reedSolomonRemainder(data, divisor []byte) []byte {
+	result := make([]byte, len(divisor))
+	for _, b := range data {
+		factor := b ^ result[0]
+		copy(result, result[1:])
+		result[len(result)-1] = 0
+		for i, d := range divisor {
+			result[i] ^= gfMultiply(d, factor)
+		}
+	}
+	return result
+}
+
+// gfMultiply multiplies in GF(2^8) modulo x^8 + x^4 + x^3 + x^2 + 1
+func gfMultiply(x, y byte) byte {
+	z := 0
+	for i := 7; i >= 0; i-- {
+		z = z<<1 ^ (z>>7)*0x11D
+		z ^= int(y>>i&1) * int(x)
+	}
+	return byte(z)
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
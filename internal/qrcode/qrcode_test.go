@@ -0,0 +1,182 @@
+package qrcode
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestReedSolomon(t *testing.T) {
+	// Version 1-M codewords of "HELLO WORLD" from the standard's example
+	data := []byte{32, 91, 11, 120, 209, 114, 220, 77, 67, 64, 236, 17, 236, 17, 236, 17}
+	want := []byte{196, 35, 39, 119, 235, 215, 231, 226, 93, 23}
+	if got := reedSolomonRemainder(data, reedSolomonDivisor(10)); !bytes.Equal(got, want) {
+		t.Errorf("reedSolomonRemainder() = %v, want %v", got, want)
+	}
+}
+
+func TestFormatAndVersionBits(t *testing.T) {
+	formats := map[int]int{0: 0x5412, 1: 0x5125, 2: 0x5E7C, 3: 0x5B4B, 4: 0x45F9}
+	for mask, want := range formats {
+		if got := formatBits(mask); got != want {
+			t.Errorf("formatBits(%d) = %#x, want %#x", mask, got, want)
+		}
+	}
+	versionsBits := map[int]int{7: 0x07C94, 8: 0x085BC, 9: 0x09A99, 10: 0x0A4D3}
+	for version, want := range versionsBits {
+		if got := versionBits(version); got != want {
+			t.Errorf("versionBits(%d) = %#x, want %#x", version, got, want)
+		}
+	}
+}
+
+func TestEncode(t *testing.T) {
+	tests := []struct {
+		text    string
+		version int
+	}{
+		{"http://box.lan", 1},
+		{"https://sdbx.one", 2},
+		{"https://sonarr.box.example.com", 3},
+		{"https://box.example.com/" + strings.Repeat("a", 100), 8},
+		{strings.Repeat("a", MaxLength), 10},
+	}
+	for _, tt := range tests {
+		c, err := Encode(tt.text)
+		if err != nil {
+			t.Fatalf("Encode(%q) error = %v", tt.text, err)
+		}
+		if c.Version != tt.version || c.Size != 4*tt.version+17 {
+			t.Errorf("Encode(%q) version %d size %d, want version %d", tt.text, c.Version, c.Size, tt.version)
+		}
+		if got := decode(t, c); got != tt.text {
+			t.Errorf("decode(Encode(%q)) = %q", tt.text, got)
+		}
+	}
+
+	if _, err := Encode(strings.Repeat("a", MaxLength+1)); !errors.Is(err, ErrTooLong) {
+		t.Errorf("Encode() of %d bytes error = %v, want ErrTooLong", MaxLength+1, err)
+	}
+}
+
+func TestTerminal(t *testing.T) {
+	c, err := Encode("https://sdbx.one")
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSuffix(c.Terminal(), "\n"), "\n")
+	width := c.Size + 2*quietZone
+	if len(lines) != (width+1)/2 {
+		t.Errorf("Terminal() has %d lines, want %d", len(lines), (width+1)/2)
+	}
+	for _, line := range lines {
+		if n := len([]rune(line)); n != width {
+			t.Fatalf("Terminal() line of %d columns, want %d", n, width)
+		}
+	}
+	// The first line is quiet zone, light everywhere
+	if lines[0] != strings.Repeat("█", width) {
+		t.Errorf("Terminal() first line = %q, want the quiet zone", lines[0])
+	}
+}
+
+// decode reads a code back: format, unmasked codewords in placement order,
+// error correction of each block, then the byte mode segment
+func decode(t *testing.T, c *Code) string {
+	t.Helper()
+
+	format := 0
+	for i := range 6 {
+		format |= bit(c.Dark(8, i)) << i
+	}
+	format |= bit(c.Dark(8, 7))<<6 | bit(c.Dark(8, 8))<<7 | bit(c.Dark(7, 8))<<8
+	for i := 9; i < 15; i++ {
+		format |= bit(c.Dark(14-i, 8)) << i
+	}
+	if format != formatBits(c.Mask) {
+		t.Fatalf("format bits %#x, want those of mask %d", format, c.Mask)
+	}
+	if !c.Dark(8, c.Size-8) {
+		t.Error("the dark module is light")
+	}
+
+	c.applyMask(c.Mask)
+	defer c.applyMask(c.Mask)
+	var bits bitBuffer
+	for right := c.Size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			right = 5
+		}
+		for vert := range c.Size {
+			y := vert
+			if (right+1)&2 == 0 {
+				y = c.Size - 1 - vert
+			}
+			for j := range 2 {
+				if !c.isFunction[y][right-j] {
+					bits = append(bits, c.modules[y][right-j])
+				}
+			}
+		}
+	}
+	codewords := make([]byte, len(bits)/8)
+	for i := range codewords {
+		for j := range 8 {
+			codewords[i] = codewords[i]<<1 | byte(bit(bits[8*i+j]))
+		}
+	}
+
+	// De-interleave the blocks and check their error correction
+	v := versions[c.Version]
+	n := v.blocks1 + v.blocks2
+	blocks := make([][]byte, n)
+	i := 0
+	for k := 0; k <= v.data1; k++ {
+		for b := range n {
+			if k < v.data1 || b >= v.blocks1 {
+				blocks[b] = append(blocks[b], codewords[i])
+				i++
+			}
+		}
+	}
+	var data []byte
+	for b, block := range blocks {
+		ec := make([]byte, v.ecPerBlock)
+		for k := range ec {
+			ec[k] = codewords[i+k*n+b]
+		}
+		if want := reedSolomonRemainder(block, reedSolomonDivisor(v.ecPerBlock)); !bytes.Equal(ec, want) {
+			t.Fatalf("block %d error correction %v, want %v", b, ec, want)
+		}
+		data = append(data, block...)
+	}
+
+	if data[0]>>4 != 0b0100 {
+		t.Fatalf("mode %04b, want byte mode", data[0]>>4)
+	}
+	read := func(offset, n int) int {
+		value := 0
+		for k := offset; k < offset+n; k++ {
+			value = value<<1 | int(data[k/8]>>(7-k%8)&1)
+		}
+		return value
+	}
+	countBits := 8
+	if c.Version >= 10 {
+		countBits = 16
+	}
+	length := read(4, countBits)
+	text := make([]byte, length)
+	for k := range text {
+		text[k] = byte(read(4+countBits+8*k, 8))
+	}
+	return string(text)
+}
+
+func bit(dark bool) int {
+	if dark {
+		return 1
+	}
+	return 0
+}
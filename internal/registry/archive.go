@@ -0,0 +1,423 @@
+package registry
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/maiko/sdbx/internal/netproxy"
+	"github.com/maiko/sdbx/internal/problem"
+)
+
+const (
+	maxArchiveSize     = 64 << 20 // Compressed archive
+	maxArchiveFileSize = 4 << 20  // Each extracted file
+)
+
+// ArchiveSource implements SourceProvider for service definitions published
+// as a .tar.gz or .zip archive, downloaded over HTTP(S) or read from a local
+// file. The extracted archive is cached like a Git clone.
+type ArchiveSource struct {
+	BaseSource
+	url     string
+	sha256  string // Checksum the archive must match, if pinned
+	proxy   string
+	subPath string
+	cache   *Cache
+}
+
+// NewArchiveSource creates a new archive source
+func NewArchiveSource(src Source, cache *Cache) *ArchiveSource {
+	return &ArchiveSource{
+		BaseSource: BaseSource{
+			name:     src.Name,
+			srcType:  "archive",
+			priority: src.Priority,
+			enabled:  src.Enabled,
+			loader:   NewLoader(),
+		},
+		url:     src.URL,
+		sha256:  strings.ToLower(src.SHA256),
+		proxy:   src.Proxy,
+		subPath: src.Path,
+		cache:   cache,
+	}
+}
+
+// IsArchiveURL reports whether a source URL or path names a .tar.gz, .tgz
+// or .zip archive rather than a Git repository
+func IsArchiveURL(url string) bool {
+	url, _, _ = strings.Cut(strings.ToLower(url), "?")
+	for _, ext := range []string{".tar.gz", ".tgz", ".zip"} {
+		if strings.HasSuffix(url, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// Load loads all service definitions from the archive
+func (s *ArchiveSource) Load(ctx context.Context) ([]*ServiceDefinition, error) {
+	if err := s.ensureExtracted(ctx); err != nil {
+		return nil, err
+	}
+
+	return s.loader.LoadServicesFromDir(s.getServicesPath())
+}
+
+// LoadService loads a specific service definition
+func (s *ArchiveSource) LoadService(ctx context.Context, name string) (*ServiceDefinition, error) {
+	if err := s.ensureExtracted(ctx); err != nil {
+		return nil, err
+	}
+
+	path := s.GetServicePath(name)
+	if _, err := os.Stat(path); err != nil {
+		return nil, fmt.Errorf("service %s not found in source %s", name, s.name)
+	}
+	return s.loader.LoadServiceDefinition(path)
+}
+
+// ListServices returns names of all available services
+func (s *ArchiveSource) ListServices(ctx context.Context) ([]string, error) {
+	if err := s.ensureExtracted(ctx); err != nil {
+		return nil, err
+	}
+
+	return s.loader.DiscoverServices(s.getServicesPath())
+}
+
+// GetServicePath returns the path to a service definition
+func (s *ArchiveSource) GetServicePath(name string) string {
+	servicesPath := s.getServicesPath()
+
+	// Check direct path, then core/ and addons/
+	for _, dir := range []string{"", "core", "addons"} {
+		path := filepath.Join(servicesPath, dir, name, "service.yaml")
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+
+	return filepath.Join(servicesPath, name, "service.yaml")
+}
+
+// Update downloads the archive again and replaces the extracted copy when
+// its content changed. HTTP(S) archives are revalidated with the ETag of the
+// previous download.
+func (s *ArchiveSource) Update(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, s.cache.Timeout())
+	defer cancel()
+
+	data, etag, err := s.fetch(ctx)
+	if err != nil {
+		return err
+	}
+	if data == nil {
+		// Not modified since the last download
+		s.cache.MarkUpdated(s.name)
+		return nil
+	}
+
+	sum := sha256.Sum256(data)
+	checksum := hex.EncodeToString(sum[:])
+	if s.sha256 != "" && checksum != s.sha256 {
+		return fmt.Errorf("archive of source %s checksum mismatch: got %s, want %s", s.name, checksum, s.sha256)
+	}
+
+	if !s.isExtracted() || s.GetCommit() != "sha256:"+checksum {
+		if err := s.install(data); err != nil {
+			return err
+		}
+		s.cache.SetCommit(s.name, "sha256:"+checksum)
+	}
+	s.cache.SetETag(s.name, etag)
+	s.cache.MarkUpdated(s.name)
+	return nil
+}
+
+// GetCommit returns "sha256:" and the checksum of the extracted archive, or
+// "" if it was never downloaded
+func (s *ArchiveSource) GetCommit() string {
+	return s.cache.GetCommit(s.name)
+}
+
+// GetURL returns the URL or path of the archive
+func (s *ArchiveSource) GetURL() string {
+	return s.url
+}
+
+// GetSHA256 returns the checksum the archive is pinned to, or "" if any
+// archive is accepted
+func (s *ArchiveSource) GetSHA256() string {
+	return s.sha256
+}
+
+// GetLastUpdated returns when the archive was last downloaded or revalidated
+func (s *ArchiveSource) GetLastUpdated() time.Time {
+	return s.cache.GetLastUpdated(s.name)
+}
+
+// ensureExtracted downloads the archive if it is not cached, and refreshes
+// it when the cache expired. A cached copy is kept when the refresh fails.
+func (s *ArchiveSource) ensureExtracted(ctx context.Context) error {
+	if !s.isExtracted() {
+		return s.Update(ctx)
+	}
+	if s.cache.NeedsUpdate(s.name) && !offline {
+		if err := s.Update(ctx); err != nil {
+			log.Printf("Warning: using cached archive of source %s: %v", s.name, err)
+		}
+	}
+	return nil
+}
+
+// isExtracted checks if the archive is extracted in the cache and matches
+// the pinned checksum
+func (s *ArchiveSource) isExtracted() bool {
+	commit := s.GetCommit()
+	if !strings.HasPrefix(commit, "sha256:") {
+		return false
+	}
+	if s.sha256 != "" && commit != "sha256:"+s.sha256 {
+		return false
+	}
+	_, err := os.Stat(s.cache.GetRepoPath(s.name))
+	return err == nil
+}
+
+// getServicesPath returns the path to the services directory
+func (s *ArchiveSource) getServicesPath() string {
+	repoPath := s.cache.GetRepoPath(s.name)
+	if s.subPath != "" {
+		return filepath.Join(repoPath, s.subPath)
+	}
+	return repoPath
+}
+
+// fetch reads the archive from its URL or local path. It returns nil data
+// when the server reports that the cached archive is still current.
+func (s *ArchiveSource) fetch(ctx context.Context) ([]byte, string, error) {
+	if !strings.HasPrefix(s.url, "http://") && !strings.HasPrefix(s.url, "https://") {
+		path := strings.TrimPrefix(s.url, "file://")
+		if len(path) > 0 && path[0] == '~' {
+			home, _ := os.UserHomeDir()
+			path = filepath.Join(home, path[1:])
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to read archive: %w", err)
+		}
+		if info.Size() > maxArchiveSize {
+			return nil, "", fmt.Errorf("%s is larger than %d MB", path, maxArchiveSize>>20)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to read archive: %w", err)
+		}
+		return data, "", nil
+	}
+
+	if err := RequireNetwork("download of " + s.name); err != nil {
+		return nil, "", err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.url, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	etag := s.cache.GetETag(s.name)
+	if etag != "" && s.isExtracted() {
+		req.Header.Set("If-None-Match", etag)
+	}
+	resp, err := netproxy.Client(s.proxy, nil, 0).Do(req)
+	if err != nil {
+		return nil, "", problem.Wrap(problem.ErrSourceUnreachable, err, "failed to download %s", s.url)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotModified && etag != "" {
+		return nil, etag, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", problem.Wrap(problem.ErrSourceUnreachable, errors.New(resp.Status), "failed to download %s", s.url)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxArchiveSize+1))
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to download %s: %w", s.url, err)
+	}
+	if len(data) > maxArchiveSize {
+		return nil, "", fmt.Errorf("%s is larger than %d MB", s.url, maxArchiveSize>>20)
+	}
+	return data, resp.Header.Get("ETag"), nil
+}
+
+// install extracts an archive and replaces the cached copy once every
+// definition in it parses. A single top-level directory, as in archives of
+// a release or CI artifact, is the root of the source.
+func (s *ArchiveSource) install(data []byte) error {
+	repoPath := s.cache.GetRepoPath(s.name)
+	if err := os.MkdirAll(filepath.Dir(repoPath), 0o755); err != nil {
+		return fmt.Errorf("failed to create cache directory: %w", err)
+	}
+	tmpDir, err := os.MkdirTemp(filepath.Dir(repoPath), "."+s.name+"-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	// Service discovery skips hidden directories such as tmpDir
+	root := filepath.Join(tmpDir, "archive")
+	if err := extractArchive(data, root); err != nil {
+		return fmt.Errorf("archive of source %s: %w", s.name, err)
+	}
+	if entries, err := os.ReadDir(root); err == nil && len(entries) == 1 && entries[0].IsDir() {
+		root = filepath.Join(root, entries[0].Name())
+	}
+
+	servicesPath := root
+	if s.subPath != "" {
+		servicesPath = filepath.Join(root, s.subPath)
+	}
+	defs, err := s.loader.LoadServicesFromDir(servicesPath)
+	if err != nil {
+		return fmt.Errorf("archive of source %s: %w", s.name, err)
+	}
+	if len(defs) == 0 {
+		return fmt.Errorf("archive of source %s contains no service definitions", s.name)
+	}
+
+	if err := os.RemoveAll(repoPath); err != nil {
+		return fmt.Errorf("failed to remove previous archive: %w", err)
+	}
+	if err := os.Rename(root, repoPath); err != nil {
+		return fmt.Errorf("failed to install archive: %w", err)
+	}
+	return nil
+}
+
+// extractArchive extracts a .tar.gz or .zip archive into dir, rejecting
+// entries outside of it. Links and special files are skipped.
+func extractArchive(data []byte, dir string) error {
+	switch {
+	case bytes.HasPrefix(data, []byte{0x1f, 0x8b}):
+		return extractTarGz(data, dir)
+	case bytes.HasPrefix(data, []byte("PK\x03\x04")):
+		return extractZip(data, dir)
+	default:
+		return fmt.Errorf("not a .tar.gz or .zip archive")
+	}
+}
+
+func extractTarGz(data []byte, dir string) error {
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("failed to read archive: %w", err)
+	}
+	defer gz.Close()
+
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read archive: %w", err)
+		}
+
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			target, err := archiveTarget(dir, hdr.Name)
+			if err != nil {
+				return err
+			}
+			if err := os.MkdirAll(target, 0o755); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if hdr.Size > maxArchiveFileSize {
+				return fmt.Errorf("%s is too large", hdr.Name)
+			}
+			if err := writeArchiveFile(dir, hdr.Name, tr); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func extractZip(data []byte, dir string) error {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return fmt.Errorf("failed to read archive: %w", err)
+	}
+
+	for _, f := range zr.File {
+		switch {
+		case f.FileInfo().IsDir():
+			target, err := archiveTarget(dir, f.Name)
+			if err != nil {
+				return err
+			}
+			if err := os.MkdirAll(target, 0o755); err != nil {
+				return err
+			}
+		case f.Mode().IsRegular():
+			if f.UncompressedSize64 > maxArchiveFileSize {
+				return fmt.Errorf("%s is too large", f.Name)
+			}
+			rc, err := f.Open()
+			if err != nil {
+				return fmt.Errorf("failed to read %s: %w", f.Name, err)
+			}
+			err = writeArchiveFile(dir, f.Name, rc)
+			rc.Close()
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// writeArchiveFile writes an archive entry below dir
+func writeArchiveFile(dir, name string, r io.Reader) error {
+	target, err := archiveTarget(dir, name)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return err
+	}
+	data, err := io.ReadAll(io.LimitReader(r, maxArchiveFileSize+1))
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", name, err)
+	}
+	if len(data) > maxArchiveFileSize {
+		return fmt.Errorf("%s is too large", name)
+	}
+	return os.WriteFile(target, data, 0o644)
+}
+
+// archiveTarget returns the path of an archive entry below dir, rejecting
+// absolute names and names escaping it
+func archiveTarget(dir, name string) (string, error) {
+	clean := path.Clean(strings.ReplaceAll(name, "\\", "/"))
+	if path.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, "../") {
+		return "", fmt.Errorf("unsafe entry %q in archive", name)
+	}
+	return filepath.Join(dir, filepath.FromSlash(clean)), nil
+}
@@ -0,0 +1,203 @@
+package registry
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/maiko/sdbx/internal/problem"
+)
+
+const testArchiveDefinition = `apiVersion: sdbx.one/v1
+kind: Service
+metadata:
+  name: NAME
+  version: 1.0.0
+  category: utility
+  description: Test service
+spec:
+  image:
+    repository: test/NAME
+    tag: latest
+  container:
+    name_template: "sdbx-NAME"
+routing:
+  enabled: false
+conditions:
+  requireAddon: true
+`
+
+func testTarGz(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for name, content := range files {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0o644, Size: int64(len(content)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestArchiveSourceHTTP(t *testing.T) {
+	archive := testTarGz(t, map[string]string{
+		"services-1.0/addons/sonarr/service.yaml": strings.ReplaceAll(testArchiveDefinition, "NAME", "sonarr"),
+	})
+	sum := sha256.Sum256(archive)
+	checksum := hex.EncodeToString(sum[:])
+
+	var downloads, revalidations int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			revalidations++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		downloads++
+		w.Header().Set("ETag", `"v1"`)
+		_, _ = w.Write(archive)
+	}))
+	defer server.Close()
+
+	cache := NewCache(t.TempDir())
+	ctx := context.Background()
+	src := NewArchiveSource(Source{Name: "ci", Type: "archive", URL: server.URL + "/services.tar.gz", SHA256: checksum, Enabled: true}, cache)
+
+	// The single top-level directory is the root of the source
+	names, err := src.ListServices(ctx)
+	if err != nil || len(names) != 1 || names[0] != "sonarr" {
+		t.Fatalf("ListServices() = %v, %v; want [sonarr]", names, err)
+	}
+	if src.GetCommit() != "sha256:"+checksum {
+		t.Errorf("GetCommit() = %q", src.GetCommit())
+	}
+
+	if err := src.Update(ctx); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if downloads != 1 || revalidations != 1 {
+		t.Errorf("downloads = %d, revalidations = %d; want 1 and 1", downloads, revalidations)
+	}
+
+	pinned := NewArchiveSource(Source{Name: "ci-pinned", Type: "archive", URL: server.URL + "/services.tar.gz", SHA256: strings.Repeat("0", 64), Enabled: true}, cache)
+	if err := pinned.Update(ctx); err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Errorf("Update() error = %v, want checksum mismatch", err)
+	}
+	if _, err := os.Stat(cache.GetRepoPath("ci-pinned")); err == nil {
+		t.Error("archive with the wrong checksum was extracted")
+	}
+}
+
+func TestArchiveSourceOffline(t *testing.T) {
+	archive := testTarGz(t, map[string]string{
+		"services/addons/sonarr/service.yaml": strings.ReplaceAll(testArchiveDefinition, "NAME", "sonarr"),
+	})
+	var downloads int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		downloads++
+		_, _ = w.Write(archive)
+	}))
+	defer server.Close()
+
+	cache := NewCache(t.TempDir())
+	ctx := context.Background()
+	cached := NewArchiveSource(Source{Name: "cached", Type: "archive", URL: server.URL + "/services.tar.gz", Enabled: true}, cache)
+	if err := cached.Update(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	SetOffline(true)
+	defer SetOffline(false)
+
+	if names, err := cached.ListServices(ctx); err != nil || len(names) != 1 {
+		t.Errorf("ListServices() of a cached source = %v, %v", names, err)
+	}
+	uncached := NewArchiveSource(Source{Name: "uncached", Type: "archive", URL: server.URL + "/services.tar.gz", Enabled: true}, cache)
+	if _, err := uncached.ListServices(ctx); !errors.Is(err, problem.ErrOffline) {
+		t.Errorf("ListServices() of an uncached source = %v, want ErrOffline", err)
+	}
+	if err := cached.Update(ctx); !errors.Is(err, problem.ErrOffline) {
+		t.Errorf("Update() = %v, want ErrOffline", err)
+	}
+	if downloads != 1 {
+		t.Errorf("downloads = %d, want 1", downloads)
+	}
+}
+
+func TestArchiveSourceLocalZip(t *testing.T) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range map[string]string{
+		"radarr/service.yaml": strings.ReplaceAll(testArchiveDefinition, "NAME", "radarr"),
+		"lidarr/service.yaml": strings.ReplaceAll(testArchiveDefinition, "NAME", "lidarr"),
+	} {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "services.zip")
+	if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	src := NewArchiveSource(Source{Name: "offline", Type: "archive", URL: path, Enabled: true}, NewCache(t.TempDir()))
+	def, err := src.LoadService(context.Background(), "radarr")
+	if err != nil || def.Metadata.Name != "radarr" {
+		t.Fatalf("LoadService() = %v, %v", def, err)
+	}
+}
+
+func TestArchiveSourceRejectsUnsafeEntries(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "services.tar.gz")
+	archive := testTarGz(t, map[string]string{"../escape/service.yaml": "x"})
+	if err := os.WriteFile(path, archive, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	src := NewArchiveSource(Source{Name: "evil", Type: "archive", URL: path, Enabled: true}, NewCache(t.TempDir()))
+	if err := src.Update(context.Background()); err == nil || !strings.Contains(err.Error(), "unsafe entry") {
+		t.Errorf("Update() error = %v, want unsafe entry", err)
+	}
+}
+
+func TestIsArchiveURL(t *testing.T) {
+	for url, want := range map[string]bool{
+		"https://ci.example.com/services.tar.gz":        true,
+		"https://ci.example.com/services.ZIP?token=abc": true,
+		"./services.tgz": true,
+		"https://github.com/maiko/sdbx-services.git": false,
+		"git@github.com:maiko/sdbx-services":         false,
+	} {
+		if got := IsArchiveURL(url); got != want {
+			t.Errorf("IsArchiveURL(%q) = %v, want %v", url, got, want)
+		}
+	}
+}
@@ -0,0 +1,267 @@
+package registry
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/maiko/sdbx/internal/netproxy"
+)
+
+// Release assets the embedded service definitions are refreshed from
+const (
+	DefaultReleaseURL   = "https://github.com/maiko/SDBX/releases/download"
+	ServicesBundleAsset = "sdbx-services.tar.gz"
+	ChecksumsAsset      = "checksums.txt"
+)
+
+const (
+	bundleMetaFile      = "bundle.json"
+	maxBundleSize       = 64 << 20 // Compressed archive
+	maxBundleFileSize   = 4 << 20  // Each extracted file
+	bundleDownloadLimit = 5 * time.Minute
+)
+
+// EmbeddedBundle is a copy of the embedded service definitions downloaded
+// from the release of a CLI version, preferred over the compiled-in copy
+type EmbeddedBundle struct {
+	Version    string    `json:"version"`
+	SHA256     string    `json:"sha256"`
+	Downloaded time.Time `json:"downloaded"`
+	Services   int       `json:"services"`
+	Dir        string    `json:"-"` // Contains services/
+}
+
+// embeddedBundleRoot is the directory of refreshed bundles in a cache
+func embeddedBundleRoot(cacheDir string) string {
+	return filepath.Join(cacheDir, "embedded")
+}
+
+// releaseVersion returns the version of a CLI release, or "" for
+// development builds which have no release assets
+func releaseVersion(version string) string {
+	version = strings.TrimPrefix(version, "v")
+	if version == "" || version == "dev" || strings.Contains(version, "-next") {
+		return ""
+	}
+	return version
+}
+
+// LoadEmbeddedBundle returns the refreshed bundle of a CLI version in a
+// cache, or nil if it was never refreshed
+func LoadEmbeddedBundle(cacheDir, version string) (*EmbeddedBundle, error) {
+	version = releaseVersion(version)
+	if version == "" {
+		return nil, nil
+	}
+	dir := filepath.Join(embeddedBundleRoot(cacheDir), version)
+	data, err := os.ReadFile(filepath.Join(dir, bundleMetaFile))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read embedded bundle: %w", err)
+	}
+
+	var bundle EmbeddedBundle
+	if err := json.Unmarshal(data, &bundle); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", filepath.Join(dir, bundleMetaFile), err)
+	}
+	if bundle.Version != version {
+		return nil, nil
+	}
+	bundle.Dir = dir
+	return &bundle, nil
+}
+
+// RefreshEmbeddedBundle downloads the service definitions released with a
+// CLI version into the cache. The archive is verified against the
+// release's checksums.txt, and every definition in it must parse, before it
+// replaces a previous bundle. Bundles of other versions are removed.
+// Downloads go through proxy (see netproxy.Func).
+func RefreshEmbeddedBundle(ctx context.Context, cacheDir, releaseURL, version, proxy string) (*EmbeddedBundle, error) {
+	release := releaseVersion(version)
+	if release == "" {
+		return nil, fmt.Errorf("development build %q has no release assets", version)
+	}
+	if err := RequireNetwork("refreshing the embedded source"); err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, bundleDownloadLimit)
+	defer cancel()
+
+	client := netproxy.Client(proxy, nil, 0)
+	base := strings.TrimSuffix(releaseURL, "/") + "/v" + release + "/"
+	checksums, err := downloadAsset(ctx, client, base+ChecksumsAsset)
+	if err != nil {
+		return nil, err
+	}
+	want, err := assetChecksum(checksums, ServicesBundleAsset)
+	if err != nil {
+		return nil, err
+	}
+	archive, err := downloadAsset(ctx, client, base+ServicesBundleAsset)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(archive)
+	got := hex.EncodeToString(sum[:])
+	if got != want {
+		return nil, fmt.Errorf("%s checksum mismatch: got %s, want %s", ServicesBundleAsset, got, want)
+	}
+
+	root := embeddedBundleRoot(cacheDir)
+	if err := os.MkdirAll(root, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create %s: %w", root, err)
+	}
+	tmpDir, err := os.MkdirTemp(root, ".refresh-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	if err := extractBundle(archive, tmpDir); err != nil {
+		return nil, err
+	}
+	src := newBundleSource(tmpDir)
+	if err := src.ensureLoaded(); err != nil {
+		return nil, err
+	}
+	if len(src.services) == 0 {
+		return nil, fmt.Errorf("%s contains no service definitions", ServicesBundleAsset)
+	}
+
+	bundle := &EmbeddedBundle{
+		Version:    release,
+		SHA256:     got,
+		Downloaded: time.Now().UTC().Truncate(time.Second),
+		Services:   len(src.services),
+	}
+	data, err := json.MarshalIndent(bundle, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(filepath.Join(tmpDir, bundleMetaFile), data, 0o644); err != nil {
+		return nil, fmt.Errorf("failed to write %s: %w", bundleMetaFile, err)
+	}
+
+	bundle.Dir = filepath.Join(root, release)
+	if err := os.RemoveAll(bundle.Dir); err != nil {
+		return nil, fmt.Errorf("failed to remove previous bundle: %w", err)
+	}
+	if err := os.Rename(tmpDir, bundle.Dir); err != nil {
+		return nil, fmt.Errorf("failed to install bundle: %w", err)
+	}
+
+	// Only the bundle of the running version is ever used
+	entries, _ := os.ReadDir(root)
+	for _, e := range entries {
+		if e.IsDir() && e.Name() != release && !strings.HasPrefix(e.Name(), ".") {
+			_ = os.RemoveAll(filepath.Join(root, e.Name()))
+		}
+	}
+	return bundle, nil
+}
+
+// downloadAsset fetches a release asset into memory
+func downloadAsset(ctx context.Context, client *http.Client, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to download %s: %s", url, resp.Status)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxBundleSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s: %w", url, err)
+	}
+	if len(data) > maxBundleSize {
+		return nil, fmt.Errorf("%s is larger than %d MB", url, maxBundleSize>>20)
+	}
+	return data, nil
+}
+
+// assetChecksum finds the SHA-256 of an asset in a checksums.txt file
+// ("<sha256>  <name>" lines)
+func assetChecksum(checksums []byte, asset string) (string, error) {
+	scanner := bufio.NewScanner(bytes.NewReader(checksums))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && strings.TrimPrefix(fields[1], "*") == asset {
+			return strings.ToLower(fields[0]), nil
+		}
+	}
+	return "", fmt.Errorf("%s not listed in %s", asset, ChecksumsAsset)
+}
+
+// extractBundle extracts the services/ tree of a bundle archive into dir,
+// rejecting entries outside of it
+func extractBundle(archive []byte, dir string) error {
+	gz, err := gzip.NewReader(bytes.NewReader(archive))
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", ServicesBundleAsset, err)
+	}
+	defer gz.Close()
+
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", ServicesBundleAsset, err)
+		}
+
+		name := path.Clean(strings.TrimPrefix(hdr.Name, "./"))
+		if name != "services" && !strings.HasPrefix(name, "services/") {
+			return fmt.Errorf("unexpected entry %q in %s", hdr.Name, ServicesBundleAsset)
+		}
+		target := filepath.Join(dir, filepath.FromSlash(name))
+
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0o755); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if hdr.Size > maxBundleFileSize {
+				return fmt.Errorf("%s is too large in %s", hdr.Name, ServicesBundleAsset)
+			}
+			if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+				return err
+			}
+			data, err := io.ReadAll(tr)
+			if err != nil {
+				return fmt.Errorf("failed to read %s: %w", hdr.Name, err)
+			}
+			if err := os.WriteFile(target, data, 0o644); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("unsupported entry %q in %s", hdr.Name, ServicesBundleAsset)
+		}
+	}
+}
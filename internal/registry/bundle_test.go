@@ -0,0 +1,144 @@
+package registry
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// bundleArchive builds a services bundle from files keyed by tar path
+func bundleArchive(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for name, content := range files {
+		hdr := &tar.Header{Name: name, Mode: 0o644, Size: int64(len(content)), Typeflag: tar.TypeReg}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// releaseServer serves a v1.2.0 release with an archive and its checksum
+func releaseServer(t *testing.T, archive []byte, checksum string) *httptest.Server {
+	t.Helper()
+	if checksum == "" {
+		sum := sha256.Sum256(archive)
+		checksum = hex.EncodeToString(sum[:])
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1.2.0/"+ChecksumsAsset, func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte("0000  sdbx_1.2.0_linux_amd64.tar.gz\n" + checksum + "  " + ServicesBundleAsset + "\n"))
+	})
+	mux.HandleFunc("/v1.2.0/"+ServicesBundleAsset, func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write(archive)
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestRefreshEmbeddedBundle(t *testing.T) {
+	definition, err := fs.ReadFile(embeddedServices, "services/core/traefik/service.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	refreshed := strings.Replace(string(definition), "description:", "description: Refreshed.", 1)
+	archive := bundleArchive(t, map[string]string{
+		"services/core/traefik/service.yaml": refreshed,
+		"services/core/traefik/README.md":    "# Traefik\n",
+	})
+	srv := releaseServer(t, archive, "")
+	cacheDir := t.TempDir()
+
+	// A bundle of another version is replaced
+	stale := filepath.Join(cacheDir, "embedded", "1.1.0")
+	if err := os.MkdirAll(stale, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	bundle, err := RefreshEmbeddedBundle(context.Background(), cacheDir, srv.URL, "v1.2.0", "")
+	if err != nil {
+		t.Fatalf("RefreshEmbeddedBundle() error = %v", err)
+	}
+	if bundle.Version != "1.2.0" || bundle.Services != 1 {
+		t.Errorf("bundle = %+v, want version 1.2.0 with 1 service", bundle)
+	}
+	if _, err := os.Stat(stale); !os.IsNotExist(err) {
+		t.Error("bundle of another version was not removed")
+	}
+
+	// Only the running version uses the bundle
+	if b, err := LoadEmbeddedBundle(cacheDir, "1.3.0"); err != nil || b != nil {
+		t.Errorf("LoadEmbeddedBundle(1.3.0) = %v, %v; want nil", b, err)
+	}
+	defer SetCLIVersion(cliVersion)
+	SetCLIVersion("1.2.0")
+
+	src := newCachedEmbeddedSource(cacheDir)
+	if src.Bundle() == nil || src.GetCommit() != "embedded@v1.2.0" {
+		t.Fatalf("source serves bundle %+v, commit %q", src.Bundle(), src.GetCommit())
+	}
+	def, err := src.LoadService(context.Background(), "traefik")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(def.Metadata.Description, "Refreshed.") {
+		t.Errorf("description = %q, want the refreshed definition", def.Metadata.Description)
+	}
+	if readme, err := readServiceReadme(src.GetServicePath("traefik")); err != nil || readme != "# Traefik\n" {
+		t.Errorf("README = %q, %v", readme, err)
+	}
+}
+
+func TestRefreshEmbeddedBundleRejected(t *testing.T) {
+	valid := bundleArchive(t, map[string]string{"services/core/x/README.md": "x"})
+
+	tests := []struct {
+		name     string
+		version  string
+		archive  []byte
+		checksum string
+		wantErr  string
+	}{
+		{"development build", "dev", valid, "", "development build"},
+		{"checksum mismatch", "1.2.0", valid, strings.Repeat("ab", 32), "checksum mismatch"},
+		{"path traversal", "1.2.0", bundleArchive(t, map[string]string{"services/../../evil": "x"}), "", "unexpected entry"},
+		{"invalid definition", "1.2.0", bundleArchive(t, map[string]string{"services/core/x/service.yaml": ": ["}), "", "failed to parse"},
+		{"no definitions", "1.2.0", valid, "", "no service definitions"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := releaseServer(t, tt.archive, tt.checksum)
+			cacheDir := t.TempDir()
+			_, err := RefreshEmbeddedBundle(context.Background(), cacheDir, srv.URL, tt.version, "")
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("RefreshEmbeddedBundle() error = %v, want %q", err, tt.wantErr)
+			}
+			if b, _ := LoadEmbeddedBundle(cacheDir, tt.version); b != nil {
+				t.Error("a rejected bundle was installed")
+			}
+		})
+	}
+}
@@ -2,17 +2,25 @@ package registry
 
 import (
 	"encoding/json"
+	"fmt"
 	"log"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
 	"sync"
 	"time"
 )
 
+// DefaultFetchTimeout limits a clone, update or download of a source when
+// cache.timeout is unset
+const DefaultFetchTimeout = 5 * time.Minute
+
 // Cache manages caching of Git sources
 type Cache struct {
 	baseDir  string
 	ttl      time.Duration
+	timeout  time.Duration
 	metadata map[string]CacheMetadata
 	mu       sync.RWMutex
 	metaPath string
@@ -24,6 +32,7 @@ type CacheMetadata struct {
 	URL         string    `json:"url,omitempty"`
 	Branch      string    `json:"branch,omitempty"`
 	Commit      string    `json:"commit,omitempty"`
+	ETag        string    `json:"etag,omitempty"` // Of the last archive downloaded
 	LastUpdated time.Time `json:"last_updated"`
 }
 
@@ -32,6 +41,7 @@ func NewCache(baseDir string) *Cache {
 	c := &Cache{
 		baseDir:  baseDir,
 		ttl:      24 * time.Hour,
+		timeout:  DefaultFetchTimeout,
 		metadata: make(map[string]CacheMetadata),
 		metaPath: filepath.Join(baseDir, "cache.json"),
 	}
@@ -47,6 +57,36 @@ func NewCache(baseDir string) *Cache {
 	return c
 }
 
+// NewCacheFromConfig creates the cache of a source configuration, in its
+// directory (default ~/.cache/sdbx/sources) and with its TTL
+func NewCacheFromConfig(cfg CacheConfig) (*Cache, error) {
+	dir := cfg.Directory
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get home directory: %w", err)
+		}
+		dir = filepath.Join(home, ".cache", "sdbx", "sources")
+	}
+	c := NewCache(dir)
+
+	if cfg.TTL != "" {
+		ttl, err := time.ParseDuration(cfg.TTL)
+		if err != nil || ttl < 0 {
+			return nil, fmt.Errorf("invalid cache ttl %q: must be a duration such as 24h or 30m", cfg.TTL)
+		}
+		c.SetTTL(ttl)
+	}
+	if cfg.Timeout != "" {
+		timeout, err := time.ParseDuration(cfg.Timeout)
+		if err != nil || timeout <= 0 {
+			return nil, fmt.Errorf("invalid cache timeout %q: must be a duration such as 5m or 30s", cfg.Timeout)
+		}
+		c.SetTimeout(timeout)
+	}
+	return c, nil
+}
+
 // SetTTL sets the cache TTL
 func (c *Cache) SetTTL(ttl time.Duration) {
 	c.mu.Lock()
@@ -54,6 +94,32 @@ func (c *Cache) SetTTL(ttl time.Duration) {
 	c.ttl = ttl
 }
 
+// TTL returns how long a source is used before it is updated again
+func (c *Cache) TTL() time.Duration {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.ttl
+}
+
+// SetTimeout sets how long fetching a source may take
+func (c *Cache) SetTimeout(timeout time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.timeout = timeout
+}
+
+// Timeout returns how long a clone, update or download of a source may take
+func (c *Cache) Timeout() time.Duration {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.timeout
+}
+
+// Dir returns the cache directory
+func (c *Cache) Dir() string {
+	return c.baseDir
+}
+
 // GetRepoPath returns the path where a repo should be cached
 func (c *Cache) GetRepoPath(sourceName string) string {
 	return filepath.Join(c.baseDir, sourceName)
@@ -105,6 +171,26 @@ func (c *Cache) GetCommit(sourceName string) string {
 	return c.metadata[sourceName].Commit
 }
 
+// SetETag stores the ETag of the archive downloaded for a source
+func (c *Cache) SetETag(sourceName, etag string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	meta := c.metadata[sourceName]
+	meta.ETag = etag
+	c.metadata[sourceName] = meta
+
+	c.saveMetadata()
+}
+
+// GetETag returns the ETag of the archive downloaded for a source
+func (c *Cache) GetETag(sourceName string) string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.metadata[sourceName].ETag
+}
+
 // GetLastUpdated returns when a source was last updated
 func (c *Cache) GetLastUpdated(sourceName string) time.Time {
 	c.mu.RLock()
@@ -169,9 +255,98 @@ func (c *Cache) ClearAll() error {
 
 // GetSize returns the total size of the cache in bytes
 func (c *Cache) GetSize() (int64, error) {
+	return dirSize(c.baseDir)
+}
+
+// CacheEntry describes a directory of the cache
+type CacheEntry struct {
+	Name        string    `json:"name"`
+	Path        string    `json:"path"`
+	Size        int64     `json:"size"`
+	LastUpdated time.Time `json:"last_updated,omitzero"`
+	Expired     bool      `json:"expired"`  // Older than the TTL: updated on next use
+	Orphaned    bool      `json:"orphaned"` // Not a configured source, or left by an interrupted download
+}
+
+// Entries lists the directories of the cache. Directories of sources not
+// among sources, and temporary directories, are orphaned. The refreshed
+// embedded bundles always belong to the cache.
+func (c *Cache) Entries(sources []string) ([]CacheEntry, error) {
+	dirs, err := os.ReadDir(c.baseDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read cache directory: %w", err)
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	var entries []CacheEntry
+	for _, d := range dirs {
+		if !d.IsDir() {
+			continue
+		}
+		entry := CacheEntry{
+			Name: d.Name(),
+			Path: filepath.Join(c.baseDir, d.Name()),
+		}
+		if size, err := dirSize(entry.Path); err == nil {
+			entry.Size = size
+		}
+		if meta, ok := c.metadata[d.Name()]; ok {
+			entry.LastUpdated = meta.LastUpdated
+			entry.Expired = time.Since(meta.LastUpdated) > c.ttl
+		}
+		entry.Orphaned = strings.HasPrefix(d.Name(), ".") ||
+			(d.Name() != filepath.Base(embeddedBundleRoot(c.baseDir)) && !slices.Contains(sources, d.Name()))
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// GC removes the orphaned directories of the cache, and the metadata of
+// sources no longer among sources. It returns the entries removed, or that
+// would be removed when dryRun is set.
+func (c *Cache) GC(sources []string, dryRun bool) ([]CacheEntry, error) {
+	entries, err := c.Entries(sources)
+	if err != nil {
+		return nil, err
+	}
+
+	var removed []CacheEntry
+	for _, entry := range entries {
+		if !entry.Orphaned {
+			continue
+		}
+		if !dryRun {
+			if err := os.RemoveAll(entry.Path); err != nil {
+				return removed, fmt.Errorf("failed to remove %s: %w", entry.Path, err)
+			}
+		}
+		removed = append(removed, entry)
+	}
+	if dryRun {
+		return removed, nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for name := range c.metadata {
+		if !slices.Contains(sources, name) {
+			delete(c.metadata, name)
+		}
+	}
+	c.saveMetadata()
+	return removed, nil
+}
+
+// dirSize returns the total size of the files below dir
+func dirSize(dir string) (int64, error) {
 	var size int64
 
-	err := filepath.WalkDir(c.baseDir, func(path string, d os.DirEntry, err error) error {
+	err := filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
 		if err != nil {
 			return err
 		}
@@ -403,3 +403,90 @@ func TestCacheLoadMetadataMissing(t *testing.T) {
 		t.Error("missing metadata should result in empty metadata map")
 	}
 }
+
+func TestNewCacheFromConfig(t *testing.T) {
+	dir := t.TempDir()
+	cache, err := NewCacheFromConfig(CacheConfig{Directory: dir, TTL: "90m"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cache.Dir() != dir || cache.TTL() != 90*time.Minute {
+		t.Errorf("cache = %s, %v; want %s, 90m", cache.Dir(), cache.TTL(), dir)
+	}
+
+	// The configured TTL decides whether a source is updated
+	cache.MarkUpdated("official")
+	if cache.NeedsUpdate("official") {
+		t.Error("source updated now needs an update")
+	}
+	cache, _ = NewCacheFromConfig(CacheConfig{Directory: dir, TTL: "0s"})
+	if !cache.NeedsUpdate("official") {
+		t.Error("source does not need an update with a zero TTL")
+	}
+
+	for _, ttl := range []string{"1 day", "-1h"} {
+		if _, err := NewCacheFromConfig(CacheConfig{Directory: dir, TTL: ttl}); err == nil {
+			t.Errorf("NewCacheFromConfig() accepted ttl %q", ttl)
+		}
+	}
+
+	if cache.Timeout() != DefaultFetchTimeout {
+		t.Errorf("Timeout() = %v, want %v", cache.Timeout(), DefaultFetchTimeout)
+	}
+	cache, err = NewCacheFromConfig(CacheConfig{Directory: dir, Timeout: "30s"})
+	if err != nil || cache.Timeout() != 30*time.Second {
+		t.Errorf("Timeout() = %v, %v; want 30s", cache.Timeout(), err)
+	}
+	for _, timeout := range []string{"soon", "0s"} {
+		if _, err := NewCacheFromConfig(CacheConfig{Directory: dir, Timeout: timeout}); err == nil {
+			t.Errorf("NewCacheFromConfig() accepted timeout %q", timeout)
+		}
+	}
+}
+
+func TestCacheEntriesAndGC(t *testing.T) {
+	cache := NewCache(t.TempDir())
+	for _, name := range []string{"official", "removed", "embedded", ".ci-123"} {
+		if err := os.MkdirAll(filepath.Join(cache.GetRepoPath(name), "sub"), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(cache.GetRepoPath(name), "sub", "service.yaml"), []byte("1234"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cache.MarkUpdated("official")
+	cache.MarkUpdated("removed")
+	sources := []string{"official", "local"}
+
+	entries, err := cache.Entries(sources)
+	if err != nil {
+		t.Fatal(err)
+	}
+	orphaned := map[string]bool{}
+	for _, e := range entries {
+		if e.Size != 4 {
+			t.Errorf("%s size = %d, want 4", e.Name, e.Size)
+		}
+		orphaned[e.Name] = e.Orphaned
+	}
+	want := map[string]bool{"official": false, "removed": true, "embedded": false, ".ci-123": true}
+	for name, o := range want {
+		if got, ok := orphaned[name]; !ok || got != o {
+			t.Errorf("%s orphaned = %v (listed %v), want %v", name, got, ok, o)
+		}
+	}
+
+	if removed, _ := cache.GC(sources, true); len(removed) != 2 || !cache.Exists("removed") {
+		t.Errorf("dry run removed %v", removed)
+	}
+	removed, err := cache.GC(sources, false)
+	if err != nil || len(removed) != 2 {
+		t.Fatalf("GC() = %v, %v", removed, err)
+	}
+	if cache.Exists("removed") || cache.Exists(".ci-123") || !cache.Exists("official") || !cache.Exists("embedded") {
+		t.Error("GC() removed the wrong directories")
+	}
+	if _, ok := cache.GetMetadata()["removed"]; ok {
+		t.Error("GC() kept the metadata of a removed source")
+	}
+}
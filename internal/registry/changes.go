@@ -0,0 +1,79 @@
+package registry
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// DefinitionChange describes how the directory of a service definition
+// changed between two commits of a Git source
+type DefinitionChange struct {
+	Service   string   `json:"service"`
+	Changelog []string `json:"changelog,omitempty"` // Lines added to the service's CHANGELOG.md
+	Commits   []string `json:"commits,omitempty"`   // Subjects of the commits touching the service, newest first
+}
+
+// HeadCommit returns the commit the cached clone is at, or "" if the source
+// was never cloned
+func (s *GitSource) HeadCommit(ctx context.Context) string {
+	if !s.isCloned() {
+		return ""
+	}
+	if err := s.updateCommitHash(ctx); err != nil {
+		return ""
+	}
+	return s.commit
+}
+
+// DefinitionChanges returns the services, among names, whose directory
+// changed between two commits, with the lines added to their CHANGELOG.md
+// and the commits touching them. Both commits must be in the clone.
+func (s *GitSource) DefinitionChanges(ctx context.Context, from, to string, names []string) ([]DefinitionChange, error) {
+	repoPath := s.cache.GetRepoPath(s.name)
+	for _, commit := range []string{from, to} {
+		if err := s.gitCommand(ctx, repoPath, "cat-file", "-e", commit+"^{commit}").Run(); err != nil {
+			return nil, fmt.Errorf("commit %s is not in the clone of %s", commit, s.name)
+		}
+	}
+
+	var changes []DefinitionChange
+	for _, name := range names {
+		dir, err := filepath.Rel(repoPath, filepath.Dir(s.GetServicePath(name)))
+		if err != nil {
+			return nil, err
+		}
+		dir = filepath.ToSlash(dir)
+
+		log, err := s.gitOutput(ctx, repoPath, "log", "--format=%s", from+".."+to, "--", dir)
+		if err != nil {
+			return nil, err
+		}
+		if log == "" {
+			continue
+		}
+
+		change := DefinitionChange{Service: name, Commits: strings.Split(log, "\n")}
+		diff, err := s.gitOutput(ctx, repoPath, "diff", "--unified=0", from, to, "--", dir+"/CHANGELOG.md")
+		if err != nil {
+			return nil, err
+		}
+		for _, line := range strings.Split(diff, "\n") {
+			if strings.HasPrefix(line, "+") && !strings.HasPrefix(line, "+++") {
+				change.Changelog = append(change.Changelog, line[1:])
+			}
+		}
+		changes = append(changes, change)
+	}
+	return changes, nil
+}
+
+// gitOutput runs a git command in dir and returns its trimmed output
+func (s *GitSource) gitOutput(ctx context.Context, dir string, args ...string) (string, error) {
+	output, err := s.gitCommand(ctx, dir, args...).Output()
+	if err != nil {
+		return "", fmt.Errorf("git %s failed: %w", args[0], err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
@@ -0,0 +1,71 @@
+package registry
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestGitSourceDefinitionChanges(t *testing.T) {
+	remoteDir := t.TempDir()
+	initTestGitRepo(t, remoteDir, map[string]string{
+		"addons/sonarr/service.yaml":  "version: 1\n",
+		"addons/sonarr/CHANGELOG.md":  "## 1.0.0\n- First release\n",
+		"addons/radarr/service.yaml":  "version: 1\n",
+		"addons/lidarr/service.yaml":  "version: 1\n",
+		"addons/lidarr/CHANGELOG.md":  "## 1.0.0\n",
+		"addons/readarr/service.yaml": "version: 1\n",
+	})
+	gs := NewGitSource(Source{Name: "test-changes", Type: "git", URL: remoteDir, Branch: "master", Enabled: true}, NewCache(t.TempDir()))
+	ctx := context.Background()
+	if err := gs.clone(ctx); err != nil {
+		t.Fatal(err)
+	}
+	from := gs.HeadCommit(ctx)
+
+	commit := func(message string, files map[string]string) {
+		t.Helper()
+		for path, content := range files {
+			if err := os.WriteFile(filepath.Join(remoteDir, path), []byte(content), 0o644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		cmd := exec.Command("git", "commit", "-am", message)
+		cmd.Dir = remoteDir
+		if output, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git commit failed: %s: %v", output, err)
+		}
+	}
+	commit("sonarr: bump image to v4", map[string]string{
+		"addons/sonarr/service.yaml": "version: 2\n",
+		"addons/sonarr/CHANGELOG.md": "## 2.0.0\n- Sonarr v4\n## 1.0.0\n- First release\n",
+	})
+	commit("radarr: add healthcheck", map[string]string{"addons/radarr/service.yaml": "version: 2\n"})
+	commit("readarr: fix port", map[string]string{"addons/readarr/service.yaml": "version: 2\n"})
+
+	if err := gs.Update(ctx); err != nil {
+		t.Fatal(err)
+	}
+	changes, err := gs.DefinitionChanges(ctx, from, gs.GetCommit(), []string{"lidarr", "radarr", "sonarr"})
+	if err != nil {
+		t.Fatalf("DefinitionChanges() error = %v", err)
+	}
+
+	// lidarr did not change and readarr is not enabled
+	if len(changes) != 2 {
+		t.Fatalf("changes = %+v, want radarr and sonarr", changes)
+	}
+	if changes[0].Service != "radarr" || !slices.Equal(changes[0].Commits, []string{"radarr: add healthcheck"}) || changes[0].Changelog != nil {
+		t.Errorf("radarr change = %+v", changes[0])
+	}
+	if changes[1].Service != "sonarr" || !slices.Equal(changes[1].Changelog, []string{"## 2.0.0", "- Sonarr v4"}) {
+		t.Errorf("sonarr change = %+v", changes[1])
+	}
+
+	if _, err := gs.DefinitionChanges(ctx, "0123456789abcdef0123456789abcdef01234567", gs.GetCommit(), []string{"sonarr"}); err == nil {
+		t.Error("DefinitionChanges() accepted a commit missing from the clone")
+	}
+}
@@ -0,0 +1,159 @@
+package registry
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// cliVersion is the version of the running CLI, which selects the refreshed
+// embedded bundle and is checked against minCliVersion requirements
+var cliVersion = "dev"
+
+// ignoreCompat lets generation proceed with definitions requiring a newer CLI
+var ignoreCompat bool
+
+// SetCLIVersion sets the version of the running CLI. Registries only use a
+// refreshed embedded bundle downloaded for this version.
+func SetCLIVersion(version string) {
+	cliVersion = version
+}
+
+// CLIVersion returns the version of the running CLI
+func CLIVersion() string {
+	return cliVersion
+}
+
+// SetIgnoreCompat sets whether definitions requiring a newer CLI are only
+// warned about instead of refused (--ignore-compat)
+func SetIgnoreCompat(ignore bool) {
+	ignoreCompat = ignore
+}
+
+// IgnoreCompat reports whether minCliVersion requirements are ignored
+func IgnoreCompat() bool {
+	return ignoreCompat
+}
+
+// versionRegex matches the versions minCliVersion accepts (1, 1.2, v1.2.3, 1.2.3-rc.1)
+var versionRegex = regexp.MustCompile(`^v?(\d+)(?:\.(\d+))?(?:\.(\d+))?(?:-([0-9A-Za-z.-]+))?$`)
+
+// parseVersion splits a version into its major, minor and patch numbers and
+// its pre-release suffix
+func parseVersion(version string) ([3]int, string, error) {
+	var parts [3]int
+	m := versionRegex.FindStringSubmatch(strings.TrimSpace(version))
+	if m == nil {
+		return parts, "", fmt.Errorf("invalid version %q", version)
+	}
+	for i := range parts {
+		if m[i+1] != "" {
+			parts[i], _ = strconv.Atoi(m[i+1])
+		}
+	}
+	return parts, m[4], nil
+}
+
+// CompareVersions compares two versions, returning -1, 0 or 1. A
+// pre-release (1.2.0-rc.1) is older than its release.
+func CompareVersions(a, b string) (int, error) {
+	pa, preA, err := parseVersion(a)
+	if err != nil {
+		return 0, err
+	}
+	pb, preB, err := parseVersion(b)
+	if err != nil {
+		return 0, err
+	}
+	for i := range pa {
+		if pa[i] != pb[i] {
+			if pa[i] < pb[i] {
+				return -1, nil
+			}
+			return 1, nil
+		}
+	}
+	switch {
+	case preA == preB:
+		return 0, nil
+	case preA == "":
+		return 1, nil
+	case preB == "":
+		return -1, nil
+	}
+	return strings.Compare(preA, preB), nil
+}
+
+// CompatError reports a service whose definition, or the source providing
+// it, requires a newer CLI than the one running
+type CompatError struct {
+	Service  string
+	Source   string
+	Required string // minCliVersion
+	Current  string
+	FromRepo bool // Required by the source's sources.yaml rather than the definition
+}
+
+func (e CompatError) Error() string {
+	if e.FromRepo {
+		return fmt.Sprintf("%s: source %s requires sdbx >= %s (running %s)", e.Service, e.Source, e.Required, e.Current)
+	}
+	return fmt.Sprintf("%s: definition requires sdbx >= %s (running %s)", e.Service, e.Required, e.Current)
+}
+
+// supportsCLIVersion reports whether the running CLI satisfies a
+// minCliVersion. Development builds satisfy every requirement.
+func supportsCLIVersion(required string) (bool, error) {
+	current := releaseVersion(cliVersion)
+	if required == "" || current == "" {
+		return true, nil
+	}
+	cmp, err := CompareVersions(current, required)
+	if err != nil {
+		return false, fmt.Errorf("minCliVersion: %w", err)
+	}
+	return cmp >= 0, nil
+}
+
+// checkCompat returns the minCliVersion requirements of a service's source
+// and definition that the running CLI does not satisfy
+func (r *Resolver) checkCompat(ctx context.Context, serviceName, source string, def *ServiceDefinition) []CompatError {
+	var incompatible []CompatError
+
+	if provider, err := r.registry.GetSource(source); err == nil {
+		if git, ok := provider.(*GitSource); ok {
+			if meta, err := git.GetRepoMetadata(ctx); err == nil {
+				if ok, err := supportsCLIVersion(meta.MinCLIVersion); err == nil && !ok {
+					incompatible = append(incompatible, CompatError{
+						Service: serviceName, Source: source, Required: meta.MinCLIVersion, Current: cliVersion, FromRepo: true,
+					})
+				}
+			}
+		}
+	}
+
+	// Invalid versions are reported by the validator
+	if ok, err := supportsCLIVersion(def.Metadata.MinCLIVersion); err == nil && !ok {
+		incompatible = append(incompatible, CompatError{
+			Service: serviceName, Source: source, Required: def.Metadata.MinCLIVersion, Current: cliVersion,
+		})
+	}
+	return incompatible
+}
+
+// CheckCompatibility returns an error for every resolved service requiring
+// a newer CLI, which may render it wrong. Generation refuses them unless
+// IgnoreCompat is set.
+func CheckCompatibility(graph *ResolutionGraph) []error {
+	incompatible := append([]CompatError(nil), graph.Incompatible...)
+	sort.SliceStable(incompatible, func(i, j int) bool { return incompatible[i].Service < incompatible[j].Service })
+
+	errs := make([]error, 0, len(incompatible))
+	for _, e := range incompatible {
+		errs = append(errs, e)
+	}
+	return errs
+}
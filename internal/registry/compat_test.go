@@ -0,0 +1,95 @@
+package registry
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/maiko/sdbx/internal/config"
+)
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"1.2.3", "1.2.3", 0},
+		{"v1.2.3", "1.2.3", 0},
+		{"1.2", "1.2.0", 0},
+		{"1.10.0", "1.9.0", 1},
+		{"1.2.3", "2", -1},
+		{"1.2.0-rc.1", "1.2.0", -1},
+		{"1.2.0-rc.2", "1.2.0-rc.1", 1},
+	}
+	for _, tt := range tests {
+		got, err := CompareVersions(tt.a, tt.b)
+		if err != nil || got != tt.want {
+			t.Errorf("CompareVersions(%q, %q) = %d, %v; want %d", tt.a, tt.b, got, err, tt.want)
+		}
+	}
+
+	if _, err := CompareVersions("1.2.x", "1.2.0"); err == nil {
+		t.Error("CompareVersions() accepted an invalid version")
+	}
+}
+
+func TestResolveIncompatibleService(t *testing.T) {
+	tmpDir := t.TempDir()
+	dir := filepath.Join(tmpDir, "core", "svc-new")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	definition := `apiVersion: sdbx.one/v1
+kind: Service
+metadata:
+  name: svc-new
+  version: 1.0.0
+  category: utility
+  description: Uses features of sdbx 2.1
+  minCliVersion: 2.1.0
+spec:
+  image:
+    repository: test/svc-new
+    tag: latest
+  container:
+    name_template: "sdbx-svc-new"
+routing:
+  enabled: false
+conditions:
+  always: true
+`
+	if err := os.WriteFile(filepath.Join(dir, "service.yaml"), []byte(definition), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	reg := newTestRegistryWithLocal(t, tmpDir)
+	cfg := config.DefaultConfig()
+	cfg.Domain = "test.local"
+
+	defer SetCLIVersion(cliVersion)
+	for _, tt := range []struct {
+		version      string
+		incompatible bool
+	}{
+		{"2.0.5", true},
+		{"2.1.0", false},
+		{"dev", false}, // Development builds satisfy every requirement
+	} {
+		SetCLIVersion(tt.version)
+		graph, err := reg.Resolve(context.Background(), cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := graph.Services["svc-new"]; !ok {
+			t.Fatalf("%s: svc-new was not resolved", tt.version)
+		}
+		errs := CheckCompatibility(graph)
+		if (len(errs) > 0) != tt.incompatible {
+			t.Errorf("%s: CheckCompatibility() = %v, want incompatible %v", tt.version, errs, tt.incompatible)
+		}
+		if tt.incompatible && !strings.Contains(errs[0].Error(), "svc-new: definition requires sdbx >= 2.1.0 (running 2.0.5)") {
+			t.Errorf("error = %q", errs[0])
+		}
+	}
+}
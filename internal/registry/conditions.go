@@ -1,6 +1,10 @@
 package registry
 
-import "github.com/maiko/sdbx/internal/config"
+import (
+	"log"
+
+	"github.com/maiko/sdbx/internal/config"
+)
 
 // EvaluateConditions checks whether a service's conditions are met given the
 // current configuration. It returns true if the service should be included.
@@ -26,8 +30,28 @@ func EvaluateConditions(cond Conditions, cfg *config.Config) bool {
 			if cfg.Expose.Mode != config.ExposeModeCloudflared {
 				return false
 			}
+		case "authelia":
+			if cfg.IsBasicAuth() {
+				return false
+			}
 		}
 	}
 
+	// Expression-based condition
+	if cond.Expr != "" && !EvaluateExpression(cond.Expr, cfg) {
+		return false
+	}
+
 	return true
 }
+
+// EvaluateExpression evaluates a condition expression, treating invalid
+// expressions as false so a typo never enables a service by accident
+func EvaluateExpression(expr string, cfg *config.Config) bool {
+	result, err := EvalExpression(expr, cfg)
+	if err != nil {
+		log.Printf("Warning: invalid condition expression %q: %v", expr, err)
+		return false
+	}
+	return result
+}
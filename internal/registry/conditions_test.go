@@ -61,6 +61,19 @@ func TestEvaluateConditionsCloudflared(t *testing.T) {
 	}
 }
 
+func TestEvaluateConditionsAuthelia(t *testing.T) {
+	cfg := &config.Config{}
+	cond := Conditions{RequireConfig: "authelia"}
+
+	if !EvaluateConditions(cond, cfg) {
+		t.Error("authelia should be deployed by default")
+	}
+	cfg.Auth.Mode = config.AuthModeBasic
+	if EvaluateConditions(cond, cfg) {
+		t.Error("authelia should not be deployed in basic auth mode")
+	}
+}
+
 func TestEvaluateConditionsNoConditions(t *testing.T) {
 	cfg := &config.Config{}
 	cond := Conditions{}
@@ -3,8 +3,12 @@ package registry
 import (
 	"context"
 	"embed"
+	"errors"
 	"fmt"
 	"io/fs"
+	"log"
+	"os"
+	"path"
 	"path/filepath"
 	"strings"
 )
@@ -15,7 +19,8 @@ var embeddedServices embed.FS
 // EmbeddedSource implements SourceProvider for embedded service definitions
 type EmbeddedSource struct {
 	BaseSource
-	fs       embed.FS
+	fs       fs.FS
+	bundle   *EmbeddedBundle // Refreshed copy served instead of the compiled-in one
 	services map[string]*ServiceDefinition
 	loaded   bool
 }
@@ -35,6 +40,39 @@ func NewEmbeddedSource() *EmbeddedSource {
 	}
 }
 
+// newCachedEmbeddedSource creates the embedded source, serving the bundle
+// refreshed for the running CLI version if the cache has one
+func newCachedEmbeddedSource(cacheDir string) *EmbeddedSource {
+	bundle, err := LoadEmbeddedBundle(cacheDir, cliVersion)
+	if err != nil {
+		log.Printf("Warning: using compiled-in service definitions: %v", err)
+	}
+	if bundle == nil {
+		return NewEmbeddedSource()
+	}
+	s := newBundleSource(bundle.Dir)
+	s.bundle = bundle
+	return s
+}
+
+// newBundleSource creates an embedded source reading the services/ tree
+// of a directory
+func newBundleSource(dir string) *EmbeddedSource {
+	s := NewEmbeddedSource()
+	s.fs = os.DirFS(dir)
+	s.bundle = &EmbeddedBundle{Dir: dir}
+	return s
+}
+
+// Bundle returns the refreshed bundle served by the source, or nil when it
+// serves the compiled-in definitions
+func (s *EmbeddedSource) Bundle() *EmbeddedBundle {
+	if s.bundle == nil || s.bundle.Version == "" {
+		return nil
+	}
+	return s.bundle
+}
+
 // Load loads all service definitions from embedded filesystem
 func (s *EmbeddedSource) Load(ctx context.Context) ([]*ServiceDefinition, error) {
 	if err := s.ensureLoaded(); err != nil {
@@ -77,13 +115,38 @@ func (s *EmbeddedSource) ListServices(ctx context.Context) ([]string, error) {
 // GetServicePath returns the embedded path to a service definition
 func (s *EmbeddedSource) GetServicePath(name string) string {
 	// Check core first, then addons
-	corePath := filepath.Join("services", "core", name, "service.yaml")
-	if _, err := s.fs.Open(corePath); err == nil {
-		return "embedded://" + corePath
+	servicePath := path.Join("services", "core", name, "service.yaml")
+	if _, err := fs.Stat(s.fs, servicePath); err != nil {
+		servicePath = path.Join("services", "addons", name, "service.yaml")
 	}
 
-	addonPath := filepath.Join("services", "addons", name, "service.yaml")
-	return "embedded://" + addonPath
+	// Files of a refreshed bundle are read from disk
+	if s.bundle != nil {
+		return filepath.Join(s.bundle.Dir, filepath.FromSlash(servicePath))
+	}
+	return "embedded://" + servicePath
+}
+
+// readServiceReadme reads README.md from the directory of a service.yaml
+// path returned by GetServicePath. A missing README is not an error.
+func readServiceReadme(servicePath string) (string, error) {
+	data, err := readServiceFile(servicePath, "README.md")
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read README: %w", err)
+	}
+	return string(data), nil
+}
+
+// readServiceFile reads a file relative to the directory of a service.yaml
+// path returned by GetServicePath
+func readServiceFile(servicePath, name string) ([]byte, error) {
+	if embeddedPath, ok := strings.CutPrefix(servicePath, "embedded://"); ok {
+		return embeddedServices.ReadFile(path.Join(path.Dir(embeddedPath), name))
+	}
+	return os.ReadFile(filepath.Join(filepath.Dir(servicePath), filepath.FromSlash(name)))
 }
 
 // Update is a no-op for embedded sources
@@ -91,8 +154,11 @@ func (s *EmbeddedSource) Update(ctx context.Context) error {
 	return nil
 }
 
-// GetCommit returns empty string for embedded sources
+// GetCommit returns "embedded", with the release version of a refreshed bundle
 func (s *EmbeddedSource) GetCommit() string {
+	if b := s.Bundle(); b != nil {
+		return "embedded@v" + b.Version
+	}
 	return "embedded"
 }
 
@@ -119,7 +185,7 @@ func (s *EmbeddedSource) ensureLoaded() error {
 		}
 
 		// Read and parse the service definition
-		data, err := s.fs.ReadFile(path)
+		data, err := fs.ReadFile(s.fs, path)
 		if err != nil {
 			return fmt.Errorf("failed to read %s: %w", path, err)
 		}
@@ -241,7 +307,7 @@ func NewDefaultRegistry() (*Registry, error) {
 	}
 
 	// Add embedded source as fallback
-	embedded := NewEmbeddedSource()
+	embedded := newCachedEmbeddedSource(cfg.Cache.Directory)
 	r.sources = append(r.sources, embedded)
 
 	return r, nil
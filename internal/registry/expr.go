@@ -0,0 +1,353 @@
+package registry
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/maiko/sdbx/internal/config"
+)
+
+// Condition expressions are a small boolean language used by `conditions.expr`
+// and by any `when:` field that is not a Go template:
+//
+//	config.vpn_enabled && addon("overseerr")
+//	config.expose.mode == "cloudflared" || !config.jellyfin_enabled
+//
+// Operators: ||, &&, !, ==, != and parentheses. Literals: true, false,
+// "strings" and numbers (compared as strings). Strings are truthy when non-empty.
+//
+// Variables (see exprVariables):
+//
+//	config.domain                    config.expose.mode
+//	config.timezone                  config.expose.tls.provider
+//	config.puid, config.pgid         config.routing.strategy
+//	config.vpn_enabled               config.routing.base_domain
+//	config.vpn_provider              config.traefik.access_log.enabled
+//	config.vpn_type                  config.jellyfin_enabled
+//	config.hardware_transcode        config.auth.mode
+//	config.runtime
+//
+// Functions: addon("name") is true when the addon is enabled,
+// maintenance("name") when the service is in maintenance mode.
+
+// exprVariables maps expression variables to their value in a config
+var exprVariables = map[string]func(*config.Config) interface{}{
+	"config.domain":                     func(c *config.Config) interface{} { return c.Domain },
+	"config.timezone":                   func(c *config.Config) interface{} { return c.Timezone },
+	"config.puid":                       func(c *config.Config) interface{} { return strconv.Itoa(c.PUID) },
+	"config.pgid":                       func(c *config.Config) interface{} { return strconv.Itoa(c.PGID) },
+	"config.vpn_enabled":                func(c *config.Config) interface{} { return c.VPNEnabled },
+	"config.vpn_provider":               func(c *config.Config) interface{} { return c.VPNProvider },
+	"config.vpn_type":                   func(c *config.Config) interface{} { return c.VPNType },
+	"config.jellyfin_enabled":           func(c *config.Config) interface{} { return c.JellyfinEnabled },
+	"config.hardware_transcode":         func(c *config.Config) interface{} { return c.HardwareTranscode },
+	"config.expose.mode":                func(c *config.Config) interface{} { return c.Expose.Mode },
+	"config.expose.tls.provider":        func(c *config.Config) interface{} { return c.Expose.TLS.Provider },
+	"config.routing.strategy":           func(c *config.Config) interface{} { return c.Routing.Strategy },
+	"config.routing.base_domain":        func(c *config.Config) interface{} { return c.Routing.BaseDomain },
+	"config.traefik.access_log.enabled": func(c *config.Config) interface{} { return c.Traefik.AccessLog.Enabled },
+	"config.auth.mode":                  func(c *config.Config) interface{} { return c.AuthMode() },
+	"config.runtime":                    func(c *config.Config) interface{} { return c.TargetRuntime() },
+}
+
+// exprFunctions maps expression functions to their implementation
+var exprFunctions = map[string]func(*config.Config, string) bool{
+	"addon":       func(c *config.Config, name string) bool { return c.IsAddonEnabled(name) },
+	"maintenance": func(c *config.Config, name string) bool { return c.IsInMaintenance(name) },
+}
+
+// IsTemplateCondition reports whether a when: string uses Go template syntax
+func IsTemplateCondition(condition string) bool {
+	return strings.Contains(condition, "{{")
+}
+
+// ValidateExpression checks an expression's syntax, variables and functions
+func ValidateExpression(expr string) error {
+	_, err := parseExpression(expr)
+	return err
+}
+
+// EvalExpression evaluates a condition expression against a config
+func EvalExpression(expr string, cfg *config.Config) (bool, error) {
+	node, err := parseExpression(expr)
+	if err != nil {
+		return false, err
+	}
+	return truthy(node.eval(cfg)), nil
+}
+
+// exprNode is a parsed expression
+type exprNode interface {
+	eval(cfg *config.Config) interface{}
+}
+
+type literalNode struct{ value interface{} }
+
+func (n literalNode) eval(*config.Config) interface{} { return n.value }
+
+type variableNode struct{ name string }
+
+func (n variableNode) eval(cfg *config.Config) interface{} { return exprVariables[n.name](cfg) }
+
+type callNode struct{ fn, arg string }
+
+func (n callNode) eval(cfg *config.Config) interface{} { return exprFunctions[n.fn](cfg, n.arg) }
+
+type notNode struct{ operand exprNode }
+
+func (n notNode) eval(cfg *config.Config) interface{} { return !truthy(n.operand.eval(cfg)) }
+
+type binaryNode struct {
+	op          string
+	left, right exprNode
+}
+
+func (n binaryNode) eval(cfg *config.Config) interface{} {
+	switch n.op {
+	case "&&":
+		return truthy(n.left.eval(cfg)) && truthy(n.right.eval(cfg))
+	case "||":
+		return truthy(n.left.eval(cfg)) || truthy(n.right.eval(cfg))
+	case "==":
+		return fmt.Sprint(n.left.eval(cfg)) == fmt.Sprint(n.right.eval(cfg))
+	default: // "!="
+		return fmt.Sprint(n.left.eval(cfg)) != fmt.Sprint(n.right.eval(cfg))
+	}
+}
+
+// truthy converts an expression value to a boolean
+func truthy(v interface{}) bool {
+	switch val := v.(type) {
+	case bool:
+		return val
+	case string:
+		return val != ""
+	default:
+		return false
+	}
+}
+
+// token kinds
+const (
+	tokEOF = iota
+	tokIdent
+	tokString
+	tokNumber
+	tokOp
+)
+
+type token struct {
+	kind  int
+	value string
+	pos   int
+}
+
+// tokenize splits an expression into tokens
+func tokenize(expr string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(expr); {
+		c := rune(expr[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case c == '"':
+			end := strings.IndexByte(expr[i+1:], '"')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated string at position %d", i)
+			}
+			tokens = append(tokens, token{tokString, expr[i+1 : i+1+end], i})
+			i += end + 2
+		case unicode.IsDigit(c):
+			start := i
+			for i < len(expr) && unicode.IsDigit(rune(expr[i])) {
+				i++
+			}
+			tokens = append(tokens, token{tokNumber, expr[start:i], start})
+		case unicode.IsLetter(c) || c == '_':
+			start := i
+			for i < len(expr) && (unicode.IsLetter(rune(expr[i])) || unicode.IsDigit(rune(expr[i])) || expr[i] == '_' || expr[i] == '.') {
+				i++
+			}
+			tokens = append(tokens, token{tokIdent, expr[start:i], start})
+		default:
+			two := ""
+			if i+1 < len(expr) {
+				two = expr[i : i+2]
+			}
+			switch {
+			case two == "&&" || two == "||" || two == "==" || two == "!=":
+				tokens = append(tokens, token{tokOp, two, i})
+				i += 2
+			case c == '!' || c == '(' || c == ')' || c == ',':
+				tokens = append(tokens, token{tokOp, string(c), i})
+				i++
+			default:
+				return nil, fmt.Errorf("unexpected character %q at position %d", c, i)
+			}
+		}
+	}
+	return append(tokens, token{tokEOF, "", len(expr)}), nil
+}
+
+// exprParser is a recursive-descent parser over tokens
+type exprParser struct {
+	tokens []token
+	pos    int
+}
+
+// parseExpression parses an expression into an evaluable tree
+func parseExpression(expr string) (exprNode, error) {
+	if strings.TrimSpace(expr) == "" {
+		return nil, fmt.Errorf("empty expression")
+	}
+	tokens, err := tokenize(expr)
+	if err != nil {
+		return nil, err
+	}
+	p := &exprParser{tokens: tokens}
+	node, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokEOF {
+		return nil, fmt.Errorf("unexpected %q at position %d", tok.value, tok.pos)
+	}
+	return node, nil
+}
+
+func (p *exprParser) peek() token { return p.tokens[p.pos] }
+
+func (p *exprParser) next() token {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokEOF {
+		p.pos++
+	}
+	return tok
+}
+
+func (p *exprParser) isOp(value string) bool {
+	tok := p.peek()
+	return tok.kind == tokOp && tok.value == value
+}
+
+func (p *exprParser) expectOp(value string) error {
+	if !p.isOp(value) {
+		tok := p.peek()
+		return fmt.Errorf("expected %q at position %d", value, tok.pos)
+	}
+	p.next()
+	return nil
+}
+
+func (p *exprParser) parseOr() (exprNode, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOp("||") {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = binaryNode{"||", left, right}
+	}
+	return left, nil
+}
+
+func (p *exprParser) parseAnd() (exprNode, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOp("&&") {
+		p.next()
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = binaryNode{"&&", left, right}
+	}
+	return left, nil
+}
+
+func (p *exprParser) parseUnary() (exprNode, error) {
+	if p.isOp("!") {
+		p.next()
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{operand}, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *exprParser) parseComparison() (exprNode, error) {
+	left, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	if p.isOp("==") || p.isOp("!=") {
+		op := p.next().value
+		right, err := p.parsePrimary()
+		if err != nil {
+			return nil, err
+		}
+		return binaryNode{op, left, right}, nil
+	}
+	return left, nil
+}
+
+func (p *exprParser) parsePrimary() (exprNode, error) {
+	tok := p.next()
+	switch tok.kind {
+	case tokString, tokNumber:
+		return literalNode{tok.value}, nil
+	case tokOp:
+		if tok.value != "(" {
+			return nil, fmt.Errorf("unexpected %q at position %d", tok.value, tok.pos)
+		}
+		node, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expectOp(")"); err != nil {
+			return nil, err
+		}
+		return node, nil
+	case tokIdent:
+		switch tok.value {
+		case "true":
+			return literalNode{true}, nil
+		case "false":
+			return literalNode{false}, nil
+		}
+		if p.isOp("(") {
+			return p.parseCall(tok)
+		}
+		if _, ok := exprVariables[tok.value]; !ok {
+			return nil, fmt.Errorf("unknown variable %q", tok.value)
+		}
+		return variableNode{tok.value}, nil
+	default:
+		return nil, fmt.Errorf("unexpected end of expression")
+	}
+}
+
+func (p *exprParser) parseCall(name token) (exprNode, error) {
+	if _, ok := exprFunctions[name.value]; !ok {
+		return nil, fmt.Errorf("unknown function %q", name.value)
+	}
+	p.next() // (
+	arg := p.next()
+	if arg.kind != tokString {
+		return nil, fmt.Errorf("%s() expects a string argument at position %d", name.value, arg.pos)
+	}
+	if err := p.expectOp(")"); err != nil {
+		return nil, err
+	}
+	return callNode{name.value, arg.value}, nil
+}
@@ -0,0 +1,92 @@
+package registry
+
+import (
+	"testing"
+
+	"github.com/maiko/sdbx/internal/config"
+)
+
+func TestEvalExpression(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.VPNEnabled = true
+	cfg.VPNProvider = "mullvad"
+	cfg.Expose.Mode = config.ExposeModeCloudflared
+	cfg.Addons = []string{"overseerr"}
+
+	tests := []struct {
+		expr string
+		want bool
+	}{
+		{`true`, true},
+		{`false`, false},
+		{`config.vpn_enabled`, true},
+		{`!config.vpn_enabled`, false},
+		{`config.vpn_enabled && addon("overseerr")`, true},
+		{`config.vpn_enabled && addon("lidarr")`, false},
+		{`addon("lidarr") || config.vpn_provider == "mullvad"`, true},
+		{`config.expose.mode != "cloudflared"`, false},
+		{`!(config.jellyfin_enabled || addon("lidarr"))`, true},
+		{`config.puid == 1000`, cfg.PUID == 1000},
+		{`config.domain`, cfg.Domain != ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			got, err := EvalExpression(tt.expr, cfg)
+			if err != nil {
+				t.Fatalf("EvalExpression(%q) error = %v", tt.expr, err)
+			}
+			if got != tt.want {
+				t.Errorf("EvalExpression(%q) = %v, want %v", tt.expr, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateExpressionErrors(t *testing.T) {
+	invalid := []string{
+		``,
+		`config.vpn_enabed`,
+		`addons("overseerr")`,
+		`addon(overseerr)`,
+		`config.vpn_enabled &&`,
+		`(config.vpn_enabled`,
+		`config.domain == "unterminated`,
+		`config.vpn_enabled & addon("x")`,
+	}
+
+	for _, expr := range invalid {
+		if err := ValidateExpression(expr); err == nil {
+			t.Errorf("ValidateExpression(%q) expected error", expr)
+		}
+	}
+}
+
+func TestEvaluateConditionsExpr(t *testing.T) {
+	cfg := &config.Config{VPNEnabled: true, Addons: []string{"overseerr"}}
+
+	if !EvaluateConditions(Conditions{Expr: `config.vpn_enabled && addon("overseerr")`}, cfg) {
+		t.Error("expected expression to be satisfied")
+	}
+	if EvaluateConditions(Conditions{Expr: `addon("lidarr")`}, cfg) {
+		t.Error("expected expression to fail for disabled addon")
+	}
+	if EvaluateConditions(Conditions{Expr: `not valid`}, cfg) {
+		t.Error("invalid expression should evaluate to false")
+	}
+}
+
+func TestResolverConditionStringExpression(t *testing.T) {
+	r := &Resolver{}
+	cfg := &config.Config{VPNEnabled: true}
+
+	if !r.evaluateConditionString(`config.vpn_enabled`, cfg) {
+		t.Error("expected expression condition to be true")
+	}
+	if r.evaluateConditionString(`!config.vpn_enabled`, cfg) {
+		t.Error("expected negated expression condition to be false")
+	}
+	if !r.evaluateConditionString(`{{ .Config.VPNEnabled }}`, cfg) {
+		t.Error("template conditions must keep working")
+	}
+}
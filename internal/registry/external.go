@@ -0,0 +1,106 @@
+package registry
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+
+	"github.com/maiko/sdbx/internal/config"
+)
+
+// External dependency kinds
+const (
+	ExternalTCP  = "tcp"  // Any TCP service (database, MQTT, ...)
+	ExternalHTTP = "http" // HTTP(S) API, checked with a request to URL
+	ExternalNFS  = "nfs"  // NFS server (port 2049)
+	ExternalSMB  = "smb"  // SMB/CIFS server (port 445)
+)
+
+// externalDefaultPorts are used when neither the definition nor the config sets a port
+var externalDefaultPorts = map[string]int{
+	ExternalNFS: 2049,
+	ExternalSMB: 445,
+}
+
+// ExternalDependency declares infrastructure a service uses but SDBX does not
+// run. Host, port, URL and path are defaults that external_dependencies.<name>
+// in .sdbx.yaml overrides.
+type ExternalDependency struct {
+	Name        string `yaml:"name"`
+	Kind        string `yaml:"kind"`
+	Description string `yaml:"description,omitempty"`
+	Host        string `yaml:"host,omitempty"`
+	Port        int    `yaml:"port,omitempty"`
+	URL         string `yaml:"url,omitempty"`  // http only
+	Path        string `yaml:"path,omitempty"` // NFS export or SMB share
+	Optional    bool   `yaml:"optional,omitempty"`
+}
+
+// ServiceExternalDependency is an external dependency of a resolved service
+type ServiceExternalDependency struct {
+	Service string `json:"service"`
+	ExternalDependency
+}
+
+// Configure applies the .sdbx.yaml settings and kind defaults
+func (d ExternalDependency) Configure(cfg *config.Config) ExternalDependency {
+	if ep, ok := cfg.ExternalDependencies[d.Name]; ok {
+		if ep.Host != "" {
+			d.Host = ep.Host
+		}
+		if ep.Port != 0 {
+			d.Port = ep.Port
+		}
+		if ep.URL != "" {
+			d.URL = ep.URL
+		}
+		if ep.Path != "" {
+			d.Path = ep.Path
+		}
+	}
+	if d.Port == 0 {
+		d.Port = externalDefaultPorts[d.Kind]
+	}
+	return d
+}
+
+// Address returns host:port, or "" when the host or port is unknown
+func (d ExternalDependency) Address() string {
+	if d.Host == "" || d.Port == 0 {
+		return ""
+	}
+	return net.JoinHostPort(d.Host, strconv.Itoa(d.Port))
+}
+
+// Endpoint returns the URL of HTTP dependencies and host:port otherwise
+func (d ExternalDependency) Endpoint() string {
+	if d.Kind == ExternalHTTP {
+		return d.URL
+	}
+	return d.Address()
+}
+
+// ExternalDependencies returns the configured external dependencies of every
+// enabled service, in resolution order. A required dependency whose endpoint
+// is unknown is an error naming the .sdbx.yaml key to set.
+func (g *ResolutionGraph) ExternalDependencies(cfg *config.Config) ([]ServiceExternalDependency, error) {
+	var deps []ServiceExternalDependency
+	for _, name := range g.Order {
+		svc, ok := g.Services[name]
+		if !ok || !svc.Enabled || svc.FinalDefinition == nil {
+			continue
+		}
+		for _, dep := range svc.FinalDefinition.Spec.ExternalDependencies {
+			dep = dep.Configure(cfg)
+			if dep.Endpoint() == "" && !dep.Optional {
+				key := "host"
+				if dep.Kind == ExternalHTTP {
+					key = "url"
+				}
+				return nil, fmt.Errorf("service %s needs external dependency %s: set external_dependencies.%s.%s in .sdbx.yaml", name, dep.Name, dep.Name, key)
+			}
+			deps = append(deps, ServiceExternalDependency{Service: name, ExternalDependency: dep})
+		}
+	}
+	return deps, nil
+}
@@ -0,0 +1,61 @@
+package registry
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/maiko/sdbx/internal/config"
+)
+
+func TestExternalDependencyConfigure(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.ExternalDependencies = map[string]config.ExternalEndpoint{
+		"nas": {Host: "nas.lan", Path: "/export/media"},
+	}
+
+	nas := ExternalDependency{Name: "nas", Kind: ExternalNFS, Host: "192.168.1.10", Path: "/media"}.Configure(cfg)
+	if nas.Host != "nas.lan" || nas.Path != "/export/media" || nas.Port != 2049 {
+		t.Errorf("unexpected configured dependency: %+v", nas)
+	}
+	if nas.Endpoint() != "nas.lan:2049" {
+		t.Errorf("Endpoint() = %q, want nas.lan:2049", nas.Endpoint())
+	}
+
+	api := ExternalDependency{Name: "api", Kind: ExternalHTTP, URL: "http://api.lan/health"}.Configure(cfg)
+	if api.Endpoint() != "http://api.lan/health" {
+		t.Errorf("Endpoint() = %q, want the URL", api.Endpoint())
+	}
+
+	if db := (ExternalDependency{Name: "db", Kind: ExternalTCP, Host: "db.lan"}).Configure(cfg); db.Endpoint() != "" {
+		t.Errorf("tcp dependency without a port should have no endpoint, got %q", db.Endpoint())
+	}
+}
+
+func TestResolutionGraphExternalDependencies(t *testing.T) {
+	def := &ServiceDefinition{Spec: ServiceSpec{ExternalDependencies: []ExternalDependency{
+		{Name: "db", Kind: ExternalTCP, Port: 5432},
+		{Name: "cache", Kind: ExternalTCP, Port: 6379, Optional: true},
+	}}}
+	graph := &ResolutionGraph{
+		Services: map[string]*ResolvedService{
+			"app":      {Name: "app", Enabled: true, FinalDefinition: def},
+			"disabled": {Name: "disabled", FinalDefinition: def},
+		},
+		Order: []string{"app", "disabled"},
+	}
+
+	cfg := config.DefaultConfig()
+	_, err := graph.ExternalDependencies(cfg)
+	if err == nil || !strings.Contains(err.Error(), "external_dependencies.db.host") {
+		t.Fatalf("expected an error naming external_dependencies.db.host, got %v", err)
+	}
+
+	cfg.ExternalDependencies = map[string]config.ExternalEndpoint{"db": {Host: "db.lan"}}
+	deps, err := graph.ExternalDependencies(cfg)
+	if err != nil {
+		t.Fatalf("ExternalDependencies() error = %v", err)
+	}
+	if len(deps) != 2 || deps[0].Service != "app" || deps[0].Endpoint() != "db.lan:5432" || deps[1].Endpoint() != "" {
+		t.Errorf("unexpected dependencies: %+v", deps)
+	}
+}
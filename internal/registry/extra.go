@@ -0,0 +1,121 @@
+package registry
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/maiko/sdbx/internal/config"
+)
+
+// ExtraServiceSource is the source name reported for services declared in .sdbx.yaml
+const ExtraServiceSource = "sdbx.yaml"
+
+// ExtraServiceDefinition converts an inline .sdbx.yaml service into a service definition
+func ExtraServiceDefinition(svc config.ExtraServiceConfig) *ServiceDefinition {
+	imageRegistry, repository, tag := splitImage(svc.Image)
+
+	def := &ServiceDefinition{
+		APIVersion: APIVersion,
+		Kind:       KindService,
+		Metadata: ServiceMetadata{
+			Name:        svc.Name,
+			Version:     "0.0.0",
+			Category:    CategoryUtility,
+			Description: svc.Description,
+			Maintainer:  ExtraServiceSource,
+		},
+		Spec: ServiceSpec{
+			Image: ImageSpec{
+				Repository: repository,
+				Tag:        tag,
+				Registry:   imageRegistry,
+			},
+			Container: ContainerSpec{
+				NameTemplate: "sdbx-{{ .Name }}",
+				Restart:      "unless-stopped",
+				Command:      svc.Command,
+			},
+			Ports: PortSpec{Static: svc.Ports},
+			Networking: NetworkSpec{
+				Networks: []NetworkRef{{Name: "proxy"}},
+			},
+			Dependencies: DependencySpec{Required: svc.DependsOn},
+		},
+		Integrations: Integrations{
+			Watchtower: &WatchtowerIntegration{Enabled: true},
+		},
+		Conditions: Conditions{Always: true},
+	}
+
+	for _, env := range svc.Environment {
+		name, value, _ := strings.Cut(env, "=")
+		def.Spec.Environment.Static = append(def.Spec.Environment.Static, EnvVar{Name: name, Value: value})
+	}
+
+	for _, vol := range svc.Volumes {
+		parts := strings.Split(vol, ":")
+		mount := VolumeMount{HostPath: parts[0]}
+		if config.ValidateVolumeName(parts[0]) == nil {
+			// A bare name is a shared volume, as in compose
+			mount = VolumeMount{Volume: parts[0]}
+		}
+		if len(parts) > 1 {
+			mount.ContainerPath = parts[1]
+		}
+		mount.ReadOnly = len(parts) > 2 && parts[2] == "ro"
+		def.Spec.Volumes = append(def.Spec.Volumes, mount)
+	}
+
+	if svc.Port > 0 {
+		subdomain := svc.Subdomain
+		if subdomain == "" {
+			subdomain = svc.Name
+		}
+		def.Routing = RoutingConfig{
+			Enabled:   true,
+			Port:      svc.Port,
+			Subdomain: subdomain,
+			Path:      "/" + svc.Name,
+			Auth: AuthConfig{
+				Required: !svc.Public,
+				Bypass:   svc.Public,
+			},
+		}
+		def.Integrations.Cloudflared = &CloudflaredIntegration{Enabled: true}
+	}
+
+	return def
+}
+
+// splitImage splits an image reference into registry, repository and tag.
+// A first path segment containing a dot or colon is a registry host; it stays
+// part of the repository since compose pulls the repository as written.
+func splitImage(image string) (imageRegistry, repository, tag string) {
+	imageRegistry = "docker.io"
+	if first, _, found := strings.Cut(image, "/"); found && strings.ContainsAny(first, ".:") {
+		imageRegistry = first
+	}
+
+	repository, tag = image, "latest"
+	if i := strings.LastIndex(image, ":"); i > strings.LastIndex(image, "/") {
+		repository, tag = image[:i], image[i+1:]
+	}
+	return imageRegistry, repository, tag
+}
+
+// extraServiceDefinition looks up and validates an inline service by name
+func extraServiceDefinition(cfg *config.Config, name string) (*ServiceDefinition, bool, error) {
+	for _, svc := range cfg.ExtraServices {
+		if svc.Name != name {
+			continue
+		}
+		def := ExtraServiceDefinition(svc)
+		for _, verr := range NewValidator().Validate(def) {
+			if verr.Severity == "error" {
+				return nil, true, fmt.Errorf("invalid extra service %s: %w", name, verr)
+			}
+		}
+		return def, true, nil
+	}
+	return nil, false, nil
+}
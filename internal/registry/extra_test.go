@@ -0,0 +1,92 @@
+package registry
+
+import (
+	"context"
+	"testing"
+
+	"github.com/maiko/sdbx/internal/config"
+)
+
+func TestSplitImage(t *testing.T) {
+	tests := []struct {
+		image, registry, repository, tag string
+	}{
+		{"nginx", "docker.io", "nginx", "latest"},
+		{"louislam/uptime-kuma:1", "docker.io", "louislam/uptime-kuma", "1"},
+		{"ghcr.io/foo/bar:v2", "ghcr.io", "ghcr.io/foo/bar", "v2"},
+		{"localhost:5000/app", "localhost:5000", "localhost:5000/app", "latest"},
+	}
+
+	for _, tt := range tests {
+		reg, repo, tag := splitImage(tt.image)
+		if reg != tt.registry || repo != tt.repository || tag != tt.tag {
+			t.Errorf("splitImage(%q) = (%q, %q, %q), want (%q, %q, %q)",
+				tt.image, reg, repo, tag, tt.registry, tt.repository, tt.tag)
+		}
+	}
+}
+
+func TestExtraServiceDefinition(t *testing.T) {
+	def := ExtraServiceDefinition(config.ExtraServiceConfig{
+		Name:        "kuma",
+		Image:       "louislam/uptime-kuma:1",
+		Environment: []string{"TZ=UTC", "URL=http://a/?b=c"},
+		Volumes:     []string{"./configs/kuma:/app/data", "/srv/share:/share:ro"},
+		Port:        3001,
+	})
+
+	if errs := NewValidator().Validate(def); HasErrors(errs) {
+		t.Fatalf("generated definition is invalid: %v", errs)
+	}
+	if def.Spec.Environment.Static[1].Name != "URL" || def.Spec.Environment.Static[1].Value != "http://a/?b=c" {
+		t.Errorf("unexpected env var: %+v", def.Spec.Environment.Static[1])
+	}
+	if !def.Spec.Volumes[1].ReadOnly || def.Spec.Volumes[0].ReadOnly {
+		t.Errorf("unexpected read-only flags: %+v", def.Spec.Volumes)
+	}
+	if !def.Routing.Enabled || def.Routing.Subdomain != "kuma" || !def.Routing.Auth.Required {
+		t.Errorf("unexpected routing: %+v", def.Routing)
+	}
+
+	public := ExtraServiceDefinition(config.ExtraServiceConfig{Name: "worker", Image: "busybox"})
+	if public.Routing.Enabled || public.Integrations.Cloudflared != nil {
+		t.Error("service without port must not be routed")
+	}
+}
+
+func TestResolveExtraServices(t *testing.T) {
+	reg := newTestRegistry(t)
+	resolver := NewResolver(reg)
+
+	cfg := config.DefaultConfig()
+	cfg.ExtraServices = []config.ExtraServiceConfig{
+		{Name: "kuma", Image: "louislam/uptime-kuma:1", Port: 3001, DependsOn: []string{"traefik"}},
+		{Name: "traefik", Image: "nginx"},
+	}
+
+	graph, err := resolver.Resolve(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("Resolve() error: %v", err)
+	}
+
+	kuma, ok := graph.Services["kuma"]
+	if !ok {
+		t.Fatal("expected extra service kuma to be resolved")
+	}
+	if kuma.Source != ExtraServiceSource {
+		t.Errorf("Source = %q, want %q", kuma.Source, ExtraServiceSource)
+	}
+	if graph.Services["traefik"].Source == ExtraServiceSource {
+		t.Error("extra service must not shadow the registry traefik")
+	}
+
+	conflict := false
+	for _, e := range graph.Errors {
+		if e.Service == "traefik" {
+			conflict = true
+		}
+	}
+	if !conflict {
+		t.Error("expected a resolution error for the conflicting extra service")
+	}
+}
@@ -2,6 +2,7 @@ package registry
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"os"
@@ -9,6 +10,9 @@ import (
 	"path/filepath"
 	"strings"
 	"time"
+
+	"github.com/maiko/sdbx/internal/netproxy"
+	"github.com/maiko/sdbx/internal/problem"
 )
 
 // GitSource implements SourceProvider for Git repository sources
@@ -16,7 +20,9 @@ type GitSource struct {
 	BaseSource
 	url      string
 	branch   string
+	ref      string // Tag or commit, checked out detached instead of following branch
 	sshKey   string
+	proxy    string
 	subPath  string
 	cache    *Cache
 	commit   string
@@ -35,7 +41,9 @@ func NewGitSource(src Source, cache *Cache) *GitSource {
 		},
 		url:      src.URL,
 		branch:   src.Branch,
+		ref:      src.Ref,
 		sshKey:   src.SSHKey,
+		proxy:    src.Proxy,
 		subPath:  src.Path,
 		cache:    cache,
 		verified: src.Verified,
@@ -95,14 +103,15 @@ func (s *GitSource) ListServices(ctx context.Context) ([]string, error) {
 	return s.loader.DiscoverServices(servicesPath)
 }
 
-// checkMinCLIVersion warns if the source requires a newer CLI version
+// checkMinCLIVersion warns if the source requires a newer CLI version.
+// Generation refuses its services unless compatibility checks are ignored.
 func (s *GitSource) checkMinCLIVersion(ctx context.Context) {
 	meta, err := s.GetRepoMetadata(ctx)
 	if err != nil {
 		return // silently skip if metadata unavailable
 	}
-	if meta.MinCLIVersion != "" {
-		log.Printf("Source %q requires CLI version >= %s", s.name, meta.MinCLIVersion)
+	if ok, err := supportsCLIVersion(meta.MinCLIVersion); err != nil || !ok {
+		log.Printf("Warning: source %q requires sdbx >= %s (running %s)", s.name, meta.MinCLIVersion, cliVersion)
 	}
 }
 
@@ -133,17 +142,28 @@ func (s *GitSource) GetServicePath(name string) string {
 
 // Update updates the Git repository
 func (s *GitSource) Update(ctx context.Context) error {
+	return s.withTimeout(ctx, s.update)
+}
+
+func (s *GitSource) update(ctx context.Context) error {
 	repoPath := s.cache.GetRepoPath(s.name)
 
 	if !s.isCloned() {
 		return s.clone(ctx)
 	}
+	if s.ref != "" {
+		return s.checkoutRef(ctx)
+	}
+	if err := RequireNetwork("git pull of " + s.name); err != nil {
+		return err
+	}
 
 	// Git pull
 	cmd := s.gitCommand(ctx, repoPath, "pull", "origin", s.branch)
 	if output, err := cmd.CombinedOutput(); err != nil {
-		return fmt.Errorf("git pull failed: %s: %w", string(output), err)
+		return problem.Wrap(problem.ErrSourceUnreachable, gitError(output, err), "git pull of %s failed", s.name)
 	}
+	s.cache.MarkUpdated(s.name)
 
 	// Update commit hash
 	return s.updateCommitHash(ctx)
@@ -169,17 +189,38 @@ func (s *GitSource) GetBranch() string {
 	return s.branch
 }
 
+// GetRef returns the tag or commit the source is pinned to, or "" when it
+// follows its branch
+func (s *GitSource) GetRef() string {
+	return s.ref
+}
+
 // ensureCloned ensures the repository is cloned and up to date
 func (s *GitSource) ensureCloned(ctx context.Context) error {
-	if s.isCloned() {
-		// Check if we need to update
-		if s.cache.NeedsUpdate(s.name) {
-			return s.Update(ctx)
+	return s.withTimeout(ctx, func(ctx context.Context) error {
+		if s.isCloned() {
+			// Check if we need to update
+			if s.cache.NeedsUpdate(s.name) && !offline {
+				return s.update(ctx)
+			}
+			return s.updateCommitHash(ctx)
 		}
-		return s.updateCommitHash(ctx)
-	}
+		return s.clone(ctx)
+	})
+}
 
-	return s.clone(ctx)
+// withTimeout runs a clone or update bounded by the cache timeout, reporting
+// when the timeout stopped it
+func (s *GitSource) withTimeout(ctx context.Context, fn func(context.Context) error) error {
+	timeout := s.cache.Timeout()
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	err := fn(ctx)
+	if err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return problem.Wrap(problem.ErrSourceUnreachable, nil, "git timed out after %s updating source %s (cache.timeout in sources.yaml)", timeout, s.name)
+	}
+	return err
 }
 
 // isCloned checks if the repository is already cloned
@@ -193,6 +234,9 @@ func (s *GitSource) isCloned() bool {
 // clone clones the Git repository
 func (s *GitSource) clone(ctx context.Context) error {
 	repoPath := s.cache.GetRepoPath(s.name)
+	if err := RequireNetwork("git clone of " + s.name); err != nil {
+		return err
+	}
 
 	// Ensure parent directory exists
 	if err := os.MkdirAll(filepath.Dir(repoPath), 0o755); err != nil {
@@ -202,11 +246,20 @@ func (s *GitSource) clone(ctx context.Context) error {
 	// Remove existing directory if it exists
 	os.RemoveAll(repoPath)
 
+	// Tags and commits need the full history to be checked out
+	if s.ref != "" {
+		cmd := s.gitCommand(ctx, "", "clone", "--no-checkout", s.url, repoPath)
+		if output, err := cmd.CombinedOutput(); err != nil {
+			return problem.Wrap(problem.ErrSourceUnreachable, gitError(output, err), "git clone of %s failed", s.name)
+		}
+		return s.checkoutRef(ctx)
+	}
+
 	// Clone
 	args := []string{"clone", "--branch", s.branch, "--single-branch", "--depth", "1", s.url, repoPath}
 	cmd := s.gitCommand(ctx, "", args...)
 	if output, err := cmd.CombinedOutput(); err != nil {
-		return fmt.Errorf("git clone failed: %s: %w", string(output), err)
+		return problem.Wrap(problem.ErrSourceUnreachable, gitError(output, err), "git clone of %s failed", s.name)
 	}
 
 	// Update cache timestamp
@@ -215,6 +268,73 @@ func (s *GitSource) clone(ctx context.Context) error {
 	return s.updateCommitHash(ctx)
 }
 
+// checkoutRef fetches every branch and tag of the clone, which may be a
+// shallow single-branch clone, and checks out the source's ref detached
+func (s *GitSource) checkoutRef(ctx context.Context) error {
+	repoPath := s.cache.GetRepoPath(s.name)
+	if err := RequireNetwork("git fetch of " + s.name); err != nil {
+		return err
+	}
+
+	args := []string{"fetch", "--tags", "--force", "origin", "+refs/heads/*:refs/remotes/origin/*"}
+	if shallow, _ := s.gitOutput(ctx, repoPath, "rev-parse", "--is-shallow-repository"); shallow == "true" {
+		args = append(args, "--unshallow")
+	}
+	if output, err := s.gitCommand(ctx, repoPath, args...).CombinedOutput(); err != nil {
+		return problem.Wrap(problem.ErrSourceUnreachable, gitError(output, err), "git fetch of %s failed", s.name)
+	}
+
+	cmd := s.gitCommand(ctx, repoPath, "checkout", "--quiet", "--detach", s.ref+"^{commit}")
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("ref %s not found in %s: %s: %w", s.ref, s.name, strings.TrimSpace(string(output)), err)
+	}
+
+	s.cache.MarkUpdated(s.name)
+	return s.updateCommitHash(ctx)
+}
+
+// Checkout switches the source to a tag or commit, or back to following a
+// branch of the remote, and returns whether ref is a branch. The clone is
+// updated; the caller saves the source configuration.
+func (s *GitSource) Checkout(ctx context.Context, ref string) (bool, error) {
+	var isBranch bool
+	err := s.withTimeout(ctx, func(ctx context.Context) error {
+		var err error
+		isBranch, err = s.checkout(ctx, ref)
+		return err
+	})
+	return isBranch, err
+}
+
+func (s *GitSource) checkout(ctx context.Context, ref string) (bool, error) {
+	if !s.isCloned() {
+		if err := s.clone(ctx); err != nil {
+			return false, err
+		}
+	}
+	repoPath := s.cache.GetRepoPath(s.name)
+	if err := RequireNetwork("git ls-remote of " + s.name); err != nil {
+		return false, err
+	}
+
+	output, err := s.gitCommand(ctx, repoPath, "ls-remote", "--heads", "origin", ref).Output()
+	if err != nil {
+		return false, problem.Wrap(problem.ErrSourceUnreachable, err, "git ls-remote of %s failed", s.name)
+	}
+	if strings.TrimSpace(string(output)) != "" {
+		s.branch, s.ref = ref, ""
+		return true, s.clone(ctx)
+	}
+
+	previous := s.ref
+	s.ref = ref
+	if err := s.checkoutRef(ctx); err != nil {
+		s.ref = previous
+		return false, err
+	}
+	return false, nil
+}
+
 // updateCommitHash gets and stores the current commit hash
 func (s *GitSource) updateCommitHash(ctx context.Context) error {
 	repoPath := s.cache.GetRepoPath(s.name)
@@ -232,9 +352,17 @@ func (s *GitSource) updateCommitHash(ctx context.Context) error {
 // gitCommand creates a git command with optional SSH key
 func (s *GitSource) gitCommand(ctx context.Context, dir string, args ...string) *exec.Cmd {
 	cmd := exec.CommandContext(ctx, "git", args...)
+	// Interrupted rather than killed, so git removes its lock files
+	cmd.Cancel = func() error {
+		return cmd.Process.Signal(os.Interrupt)
+	}
+	cmd.WaitDelay = 10 * time.Second
 	if dir != "" {
 		cmd.Dir = dir
 	}
+	if env := netproxy.GitEnv(s.proxy); env != nil {
+		cmd.Env = append(cmd.Environ(), env...)
+	}
 
 	// Set up SSH key if provided
 	if s.sshKey != "" {
@@ -252,7 +380,7 @@ func (s *GitSource) gitCommand(ctx context.Context, dir string, args ...string)
 			return cmd
 		}
 
-		env := os.Environ()
+		env := cmd.Environ()
 		// Use StrictHostKeyChecking=accept-new to accept new keys but reject changed ones
 		// This provides protection against MITM attacks while still working for new hosts
 		// Quote the path to handle spaces safely
@@ -263,6 +391,11 @@ func (s *GitSource) gitCommand(ctx context.Context, dir string, args ...string)
 	return cmd
 }
 
+// gitError joins the output of a failed git command to its error
+func gitError(output []byte, err error) error {
+	return fmt.Errorf("%s: %w", strings.TrimSpace(string(output)), err)
+}
+
 // isValidSSHKeyPath validates that the SSH key path doesn't contain shell metacharacters
 func isValidSSHKeyPath(path string) bool {
 	// Reject paths with characters that could be used for shell injection
@@ -308,14 +441,25 @@ func (s *GitSource) GetRepoMetadata(ctx context.Context) (*SourceRepository, err
 
 // Fetch fetches updates without merging
 func (s *GitSource) Fetch(ctx context.Context) error {
+	return s.withTimeout(ctx, s.fetch)
+}
+
+func (s *GitSource) fetch(ctx context.Context) error {
 	if !s.isCloned() {
 		return s.clone(ctx)
 	}
 
 	repoPath := s.cache.GetRepoPath(s.name)
-	cmd := s.gitCommand(ctx, repoPath, "fetch", "origin", s.branch)
+	if err := RequireNetwork("git fetch of " + s.name); err != nil {
+		return err
+	}
+	ref := s.branch
+	if s.ref != "" {
+		ref = "--tags"
+	}
+	cmd := s.gitCommand(ctx, repoPath, "fetch", "origin", ref)
 	if output, err := cmd.CombinedOutput(); err != nil {
-		return fmt.Errorf("git fetch failed: %s: %w", string(output), err)
+		return problem.Wrap(problem.ErrSourceUnreachable, gitError(output, err), "git fetch of %s failed", s.name)
 	}
 
 	return nil
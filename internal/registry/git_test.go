@@ -2,11 +2,15 @@ package registry
 
 import (
 	"context"
+	"errors"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
+
+	"github.com/maiko/sdbx/internal/problem"
 )
 
 func TestIsValidSSHKeyPath(t *testing.T) {
@@ -543,6 +547,25 @@ func TestGitSourceUpdateCommitHash(t *testing.T) {
 	}
 }
 
+func TestGitSourceUpdateTimeout(t *testing.T) {
+	remoteDir := t.TempDir()
+	initTestGitRepo(t, remoteDir, map[string]string{
+		"README.md": "test",
+	})
+
+	cache := NewCache(t.TempDir())
+	cache.SetTimeout(time.Nanosecond)
+	gs := NewGitSource(Source{Name: "slow", Type: "git", URL: remoteDir, Branch: "master", Enabled: true}, cache)
+
+	err := gs.Update(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Fatalf("Update() error = %v, want a timeout", err)
+	}
+	if gs.isCloned() {
+		t.Error("clone completed after its timeout")
+	}
+}
+
 func TestGitSourceLoadServiceNotFound(t *testing.T) {
 	remoteDir := t.TempDir()
 	initTestGitRepo(t, remoteDir, map[string]string{
@@ -630,3 +653,30 @@ conditions:
 		t.Errorf("expected name 'sub-svc', got %q", def.Metadata.Name)
 	}
 }
+
+func TestGitSourceOffline(t *testing.T) {
+	cache := NewCache(t.TempDir())
+	gs := NewGitSource(Source{Name: "official", Type: "git", URL: "https://github.com/test/repo.git", Branch: "main"}, cache)
+
+	// A previous partial clone is kept, not replaced
+	marker := filepath.Join(cache.GetRepoPath("official"), "keep")
+	if err := os.MkdirAll(filepath.Dir(marker), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(marker, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	SetOffline(true)
+	defer SetOffline(false)
+
+	if _, err := gs.ListServices(context.Background()); !errors.Is(err, problem.ErrOffline) {
+		t.Errorf("ListServices() of an uncached source = %v, want ErrOffline", err)
+	}
+	if _, err := gs.Checkout(context.Background(), "v1.0.0"); !errors.Is(err, problem.ErrOffline) {
+		t.Errorf("Checkout() = %v, want ErrOffline", err)
+	}
+	if _, err := os.Stat(marker); err != nil {
+		t.Errorf("cache directory removed: %v", err)
+	}
+}
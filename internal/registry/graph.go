@@ -0,0 +1,407 @@
+package registry
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/maiko/sdbx/internal/config"
+)
+
+// Dependency edge kinds
+const (
+	EdgeRequired    = "required"
+	EdgeOptional    = "optional"
+	EdgeConditional = "conditional"
+)
+
+// DependencyGraph explains which services the resolver included and why
+type DependencyGraph struct {
+	Nodes []GraphNode `json:"nodes"`
+	Edges []GraphEdge `json:"edges"`
+}
+
+// GraphNode is a service in a DependencyGraph
+type GraphNode struct {
+	Name        string         `json:"name"`
+	Source      string         `json:"source,omitempty"`
+	Included    bool           `json:"included"`
+	Reason      string         `json:"reason"`
+	Conditions  string         `json:"conditions,omitempty"`
+	Networks    []GraphNetwork `json:"networks,omitempty"`
+	NetworkMode string         `json:"network_mode,omitempty"`
+}
+
+// GraphNetwork is a network a service joins
+type GraphNetwork struct {
+	Name   string `json:"name"`
+	When   string `json:"when,omitempty"`
+	Active bool   `json:"active"`
+}
+
+// GraphEdge is a dependency between two services
+type GraphEdge struct {
+	From   string `json:"from"`
+	To     string `json:"to"`
+	Kind   string `json:"kind"` // "required", "optional", "conditional"
+	When   string `json:"when,omitempty"`
+	Active bool   `json:"active"` // Whether the dependency applies to this configuration
+}
+
+// DependencyGraph resolves the configuration and describes every known
+// service, including those left out and the reason they were
+func (r *Registry) DependencyGraph(ctx context.Context, cfg *config.Config) (*DependencyGraph, error) {
+	resolved, err := r.Resolve(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+	services, err := r.ListServices(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list services: %w", err)
+	}
+
+	graph := &DependencyGraph{}
+	nodes := make(map[string]*GraphNode)
+	addons := make(map[string]bool)
+
+	for _, svc := range services {
+		addons[svc.Name] = svc.IsAddon
+		node := &GraphNode{Name: svc.Name, Source: svc.Source}
+		if _, ok := resolved.Services[svc.Name]; !ok {
+			def, _, err := r.GetService(ctx, svc.Name)
+			switch {
+			case err != nil:
+				node.Reason = "failed to load definition"
+			case svc.IsAddon && !cfg.IsAddonEnabled(svc.Name):
+				node.Reason = "addon not enabled"
+				node.Conditions = describeConditions(def.Conditions)
+			default:
+				node.Reason = "conditions not met"
+				node.Conditions = describeConditions(def.Conditions)
+			}
+		}
+		nodes[svc.Name] = node
+	}
+
+	// Edges come from the final definitions of included services
+	for name, svc := range resolved.Services {
+		node, ok := nodes[name]
+		if !ok {
+			node = &GraphNode{Name: name}
+			nodes[name] = node
+		}
+		def := svc.FinalDefinition
+		node.Source = svc.Source
+		node.Included = true
+		node.Conditions = describeConditions(def.Conditions)
+		node.Networks, node.NetworkMode = r.graphNetworks(def, cfg)
+
+		deps := def.Spec.Dependencies
+		for _, dep := range deps.Required {
+			graph.Edges = append(graph.Edges, GraphEdge{From: name, To: dep, Kind: EdgeRequired, Active: true})
+		}
+		for _, dep := range deps.Optional {
+			_, present := resolved.Services[dep]
+			graph.Edges = append(graph.Edges, GraphEdge{From: name, To: dep, Kind: EdgeOptional, Active: present})
+		}
+		for _, dep := range deps.Conditional {
+			graph.Edges = append(graph.Edges, GraphEdge{
+				From:   name,
+				To:     dep.Name,
+				Kind:   EdgeConditional,
+				When:   dep.When,
+				Active: r.resolver.evaluateConditionString(dep.When, cfg),
+			})
+		}
+	}
+
+	// Explain why included services are present
+	for name, node := range nodes {
+		if !node.Included {
+			continue
+		}
+		switch {
+		case node.Source == ExtraServiceSource:
+			node.Reason = "declared in .sdbx.yaml"
+		case cfg.InstanceOf(name) != "":
+			node.Reason = "instance of " + cfg.InstanceOf(name)
+		case addons[name] && cfg.IsAddonEnabled(name):
+			node.Reason = "addon enabled"
+		case !addons[name]:
+			node.Reason = "core service"
+		default:
+			node.Reason = "dependency of " + strings.Join(dependents(graph.Edges, name), ", ")
+		}
+	}
+
+	// Dependencies that no source provides
+	for _, edge := range graph.Edges {
+		if _, ok := nodes[edge.To]; !ok {
+			nodes[edge.To] = &GraphNode{Name: edge.To, Reason: "not found in any source"}
+		}
+	}
+
+	for _, node := range nodes {
+		graph.Nodes = append(graph.Nodes, *node)
+	}
+	sort.Slice(graph.Nodes, func(i, j int) bool { return graph.Nodes[i].Name < graph.Nodes[j].Name })
+	sort.Slice(graph.Edges, func(i, j int) bool {
+		if graph.Edges[i].From != graph.Edges[j].From {
+			return graph.Edges[i].From < graph.Edges[j].From
+		}
+		return graph.Edges[i].To < graph.Edges[j].To
+	})
+
+	return graph, nil
+}
+
+// Included returns the graph restricted to included services and the
+// dependencies between them
+func (g *DependencyGraph) Included() *DependencyGraph {
+	included := &DependencyGraph{}
+	for _, node := range g.Nodes {
+		if node.Included {
+			included.Nodes = append(included.Nodes, node)
+		}
+	}
+	isIncluded := func(name string) bool {
+		return slices.ContainsFunc(included.Nodes, func(n GraphNode) bool { return n.Name == name })
+	}
+	for _, edge := range g.Edges {
+		if isIncluded(edge.From) && isIncluded(edge.To) {
+			included.Edges = append(included.Edges, edge)
+		}
+	}
+	return included
+}
+
+// dependents returns the services with an active dependency on name
+func dependents(edges []GraphEdge, name string) []string {
+	var from []string
+	for _, edge := range edges {
+		if edge.To == name && edge.Active && !slices.Contains(from, edge.From) {
+			from = append(from, edge.From)
+		}
+	}
+	sort.Strings(from)
+	return from
+}
+
+// graphNetworks lists the networks and network mode a service declares
+func (r *Registry) graphNetworks(def *ServiceDefinition, cfg *config.Config) ([]GraphNetwork, string) {
+	net := def.Spec.Networking
+	if net.ModeTemplate != "" {
+		mode := net.ModeTemplate
+		if tmpl, err := template.New("mode").Parse(net.ModeTemplate); err == nil {
+			var buf bytes.Buffer
+			if tmpl.Execute(&buf, map[string]interface{}{"Config": cfg}) == nil {
+				mode = strings.TrimSpace(buf.String())
+			}
+		}
+		if mode != "bridge" && mode != "" {
+			return nil, mode
+		}
+	} else if net.Mode != "" && net.Mode != "bridge" {
+		return nil, net.Mode
+	}
+
+	var networks []GraphNetwork
+	for _, n := range net.Networks {
+		name := n.Name
+		if name == "" {
+			name = "proxy"
+		}
+		networks = append(networks, GraphNetwork{
+			Name:   name,
+			When:   n.When,
+			Active: r.resolver.evaluateConditionString(n.When, cfg),
+		})
+	}
+	for _, zone := range net.Zones {
+		networks = append(networks, GraphNetwork{Name: "zone:" + zone, Active: true})
+	}
+	return networks, ""
+}
+
+// describeConditions summarises a service's inclusion conditions
+func describeConditions(c Conditions) string {
+	var parts []string
+	if c.Always {
+		parts = append(parts, "always")
+	}
+	if c.RequireAddon {
+		parts = append(parts, "requireAddon")
+	}
+	if c.RequireConfig != "" {
+		parts = append(parts, "requireConfig: "+c.RequireConfig)
+	}
+	if c.RequireFeature != "" {
+		parts = append(parts, "requireFeature: "+c.RequireFeature)
+	}
+	if c.Expr != "" {
+		parts = append(parts, "expr: "+c.Expr)
+	}
+	return strings.Join(parts, "; ")
+}
+
+// RenderDOT renders the graph in Graphviz DOT format. Excluded services are
+// dashed and inactive dependencies dotted.
+func (g *DependencyGraph) RenderDOT() string {
+	var b strings.Builder
+	b.WriteString("digraph sdbx {\n")
+	b.WriteString("  rankdir=LR;\n")
+	b.WriteString("  node [shape=box, fontname=\"Helvetica\"];\n")
+
+	var networks []string
+	for _, node := range g.Nodes {
+		label := node.Name + "\\n" + node.Reason
+		if node.Conditions != "" {
+			label += "\\n[" + node.Conditions + "]"
+		}
+		if node.NetworkMode != "" {
+			label += "\\nnetwork_mode: " + node.NetworkMode
+		}
+		attrs := fmt.Sprintf("label=%s", dotQuote(label))
+		if !node.Included {
+			attrs += ", style=dashed, color=gray50, fontcolor=gray50"
+		}
+		fmt.Fprintf(&b, "  %s [%s];\n", dotQuote(node.Name), attrs)
+		for _, network := range node.Networks {
+			if !slices.Contains(networks, network.Name) {
+				networks = append(networks, network.Name)
+			}
+		}
+	}
+
+	for _, network := range networks {
+		fmt.Fprintf(&b, "  %s [label=%s, shape=ellipse, color=steelblue];\n", dotQuote("net:"+network), dotQuote(network))
+	}
+
+	for _, edge := range g.Edges {
+		attrs := fmt.Sprintf("label=%s", dotQuote(edgeLabel(edge)))
+		switch {
+		case !edge.Active:
+			attrs += ", style=dotted, color=gray50"
+		case edge.Kind != EdgeRequired:
+			attrs += ", style=dashed"
+		}
+		fmt.Fprintf(&b, "  %s -> %s [%s];\n", dotQuote(edge.From), dotQuote(edge.To), attrs)
+	}
+
+	for _, node := range g.Nodes {
+		for _, network := range node.Networks {
+			attrs := "arrowhead=none, color=steelblue"
+			if network.When != "" {
+				attrs += ", label=" + dotQuote(networkLabel(network))
+			}
+			if !network.Active {
+				attrs += ", style=dotted"
+			}
+			fmt.Fprintf(&b, "  %s -> %s [%s];\n", dotQuote(node.Name), dotQuote("net:"+network.Name), attrs)
+		}
+	}
+
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// RenderMermaid renders the graph as a Mermaid flowchart
+func (g *DependencyGraph) RenderMermaid() string {
+	var b strings.Builder
+	b.WriteString("flowchart LR\n")
+
+	var networks []string
+	for _, node := range g.Nodes {
+		label := node.Name + "<br/>" + node.Reason
+		if node.Conditions != "" {
+			label += "<br/>[" + node.Conditions + "]"
+		}
+		if node.NetworkMode != "" {
+			label += "<br/>network_mode: " + node.NetworkMode
+		}
+		fmt.Fprintf(&b, "  %s[%s]", mermaidID(node.Name), mermaidQuote(label))
+		if !node.Included {
+			b.WriteString(":::excluded")
+		}
+		b.WriteString("\n")
+		for _, network := range node.Networks {
+			if !slices.Contains(networks, network.Name) {
+				networks = append(networks, network.Name)
+			}
+		}
+	}
+
+	for _, network := range networks {
+		fmt.Fprintf(&b, "  %s((%s)):::network\n", mermaidID("net:"+network), mermaidQuote(network))
+	}
+
+	for _, edge := range g.Edges {
+		arrow := "-->"
+		if !edge.Active || edge.Kind != EdgeRequired {
+			arrow = "-.->"
+		}
+		fmt.Fprintf(&b, "  %s %s|%s| %s\n", mermaidID(edge.From), arrow, mermaidQuote(edgeLabel(edge)), mermaidID(edge.To))
+	}
+
+	for _, node := range g.Nodes {
+		for _, network := range node.Networks {
+			link := "---"
+			if !network.Active {
+				link = "-.-"
+			}
+			if network.When != "" {
+				link += "|" + mermaidQuote(networkLabel(network)) + "|"
+			}
+			fmt.Fprintf(&b, "  %s %s %s\n", mermaidID(node.Name), link, mermaidID("net:"+network.Name))
+		}
+	}
+
+	b.WriteString("  classDef excluded stroke-dasharray: 5 5,color:#888\n")
+	b.WriteString("  classDef network fill:#e8f0fe,stroke:#4682b4\n")
+	return b.String()
+}
+
+// edgeLabel describes a dependency edge
+func edgeLabel(edge GraphEdge) string {
+	label := edge.Kind
+	if edge.When != "" {
+		label += ": " + edge.When
+	}
+	if !edge.Active {
+		label += " (inactive)"
+	}
+	return label
+}
+
+// networkLabel describes a conditional network membership
+func networkLabel(network GraphNetwork) string {
+	label := "when: " + network.When
+	if !network.Active {
+		label += " (inactive)"
+	}
+	return label
+}
+
+// dotQuote quotes a DOT identifier or label
+func dotQuote(s string) string {
+	return `"` + strings.ReplaceAll(s, `"`, `\"`) + `"`
+}
+
+// mermaidID converts a name into a Mermaid node identifier
+func mermaidID(name string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' {
+			return r
+		}
+		return '_'
+	}, name)
+}
+
+// mermaidQuote quotes a Mermaid label
+func mermaidQuote(s string) string {
+	return `"` + strings.ReplaceAll(s, `"`, "#quot;") + `"`
+}
@@ -0,0 +1,72 @@
+package registry
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/maiko/sdbx/internal/config"
+)
+
+func TestDependencyGraph(t *testing.T) {
+	reg := newTestRegistry(t)
+	cfg := config.DefaultConfig()
+	cfg.VPNEnabled = false
+
+	graph, err := reg.DependencyGraph(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("DependencyGraph() error: %v", err)
+	}
+
+	nodes := make(map[string]GraphNode)
+	for _, node := range graph.Nodes {
+		nodes[node.Name] = node
+	}
+
+	if n := nodes["traefik"]; !n.Included || n.Reason != "core service" {
+		t.Errorf("traefik = %+v, want included core service", n)
+	}
+	if n := nodes["gluetun"]; n.Included || n.Reason != "conditions not met" || n.Conditions != "requireConfig: vpn_enabled" {
+		t.Errorf("gluetun = %+v, want excluded by vpn_enabled", n)
+	}
+
+	var vpnEdge *GraphEdge
+	for i, edge := range graph.Edges {
+		if edge.From == "qbittorrent" && edge.To == "gluetun" {
+			vpnEdge = &graph.Edges[i]
+		}
+	}
+	if vpnEdge == nil || vpnEdge.Kind != EdgeConditional || vpnEdge.Active {
+		t.Errorf("qbittorrent → gluetun edge = %+v, want inactive conditional", vpnEdge)
+	}
+
+	dot := graph.RenderDOT()
+	for _, want := range []string{"digraph sdbx {", `"qbittorrent" -> "gluetun"`, "style=dotted", `"net:proxy"`} {
+		if !strings.Contains(dot, want) {
+			t.Errorf("DOT output missing %q", want)
+		}
+	}
+
+	mermaid := graph.RenderMermaid()
+	for _, want := range []string{"flowchart LR", "sdbx_webui[", "qbittorrent -.->", ":::excluded"} {
+		if !strings.Contains(mermaid, want) {
+			t.Errorf("Mermaid output missing %q", want)
+		}
+	}
+
+	// Enabling the VPN pulls gluetun in and switches qbittorrent to its network
+	cfg.VPNEnabled = true
+	cfg.VPNProvider = "mullvad"
+	graph, err = reg.DependencyGraph(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("DependencyGraph() error: %v", err)
+	}
+	for _, node := range graph.Nodes {
+		if node.Name == "qbittorrent" && node.NetworkMode != "service:gluetun" {
+			t.Errorf("qbittorrent network mode = %q, want service:gluetun", node.NetworkMode)
+		}
+		if node.Name == "gluetun" && !node.Included {
+			t.Error("gluetun should be included with VPN enabled")
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package registry
+
+import (
+	"maps"
+	"strings"
+
+	"github.com/maiko/sdbx/internal/config"
+)
+
+// InstanceContext is the .Instance value available to definition templates.
+// For services that are not named instances, Name and Service are equal.
+type InstanceContext struct {
+	Name    string            // Name the service runs under (sonarr4k)
+	Service string            // Service whose definition it was built from (sonarr)
+	Vars    map[string]string // Instancing variables with services.<name>.variables applied
+}
+
+// NewInstanceContext returns the instance template data of a resolved
+// definition
+func NewInstanceContext(def *ServiceDefinition, cfg *config.Config) InstanceContext {
+	name := def.Metadata.Name
+	ctx := InstanceContext{Name: name, Service: name, Vars: map[string]string{}}
+	if base := cfg.InstanceOf(name); base != "" {
+		ctx.Service = base
+	}
+	if def.Instancing != nil {
+		maps.Copy(ctx.Vars, def.Instancing.Variables)
+	}
+	for key, value := range cfg.Services[name].Variables {
+		if _, declared := ctx.Vars[key]; declared {
+			ctx.Vars[key] = value
+		}
+	}
+	return ctx
+}
+
+// instanceDefinition derives the definition of a named instance from the
+// definition of the service it copies. Everything that must not collide with
+// the original is renamed after the instance: the service name (and with it
+// the container, integrations and per-service settings), project-relative
+// bind mounts such as ./configs/sonarr, and the routing subdomain and path.
+// Host ports are dropped because the original already publishes them.
+func instanceDefinition(base *ServiceDefinition, instance string, loader *Loader) *ServiceDefinition {
+	def := loader.deepCopyServiceDefinition(base)
+	original := def.Metadata.Name
+
+	def.Metadata.Name = instance
+	if def.Spec.Container.NameTemplate != "" && !strings.Contains(def.Spec.Container.NameTemplate, "{{") {
+		def.Spec.Container.NameTemplate = "sdbx-" + instance
+	}
+
+	for i, volume := range def.Spec.Volumes {
+		def.Spec.Volumes[i].HostPath = renameHostPath(volume.HostPath, original, instance)
+	}
+
+	def.Spec.Ports = PortSpec{}
+
+	if def.Routing.Subdomain != "" {
+		def.Routing.Subdomain = instance
+	}
+	if def.Routing.Path != "" {
+		def.Routing.Path = "/" + instance
+	}
+
+	return def
+}
+
+// renameHostPath replaces the original service name in project-relative paths
+// ("./configs/sonarr" becomes "./configs/sonarr4k"). Absolute and templated
+// paths (media, downloads) stay shared between instances.
+func renameHostPath(hostPath, original, instance string) string {
+	if !strings.HasPrefix(hostPath, "./") || strings.Contains(hostPath, "{{") {
+		return hostPath
+	}
+	parts := strings.Split(hostPath, "/")
+	for i, part := range parts {
+		if part == original {
+			parts[i] = instance
+		}
+	}
+	return strings.Join(parts, "/")
+}
@@ -3,8 +3,11 @@ package registry
 import (
 	"fmt"
 	"io"
+	"maps"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
 
 	"gopkg.in/yaml.v3"
 )
@@ -102,7 +105,9 @@ func (l *Loader) ParseSourceConfig(data []byte) (*SourceConfig, error) {
 	return &cfg, nil
 }
 
-// LoadLockFile loads a lock file
+// LoadLockFile loads a lock file. Its checksum is not checked here: callers
+// enforcing lock_integrity use VerifyLockChecksum, so generation keeps the
+// host ports and generated files recorded in a hand-edited lock file.
 func (l *Loader) LoadLockFile(path string) (*LockFile, error) {
 	data, err := os.ReadFile(path)
 	if err != nil {
@@ -161,9 +166,12 @@ func (l *Loader) SaveSourceConfig(path string, cfg *SourceConfig) error {
 	return l.saveYAML(path, cfg)
 }
 
-// SaveLockFile saves a lock file
+// SaveLockFile saves a lock file and its checksum
 func (l *Loader) SaveLockFile(path string, lock *LockFile) error {
-	return l.saveYAML(path, lock)
+	if err := l.saveYAML(path, lock); err != nil {
+		return err
+	}
+	return SignLockFile(path)
 }
 
 // saveYAML saves data as YAML to a file
@@ -296,47 +304,263 @@ func (l *Loader) MergeOverride(base *ServiceDefinition, override *ServiceOverrid
 	merged := l.deepCopyServiceDefinition(base)
 
 	if override.Spec != nil {
-		// Merge image override
-		if override.Spec.Image != nil {
-			if override.Spec.Image.Repository != "" {
-				merged.Spec.Image.Repository = override.Spec.Image.Repository
-			}
-			if override.Spec.Image.Tag != "" {
-				merged.Spec.Image.Tag = override.Spec.Image.Tag
-			}
-			if override.Spec.Image.Registry != "" {
-				merged.Spec.Image.Registry = override.Spec.Image.Registry
+		mergeSpecOverride(&merged.Spec, override.Spec)
+	}
+	if override.Routing != nil {
+		mergeRoutingOverride(&merged.Routing, override.Routing)
+	}
+	if override.Integrations != nil {
+		mergeIntegrations(&merged.Integrations, override.Integrations)
+	}
+
+	return merged
+}
+
+// mergeSpecOverride applies a spec override in place
+func mergeSpecOverride(spec *ServiceSpec, override *ServiceSpecOverride) {
+	// Merge image override
+	if override.Image != nil {
+		if override.Image.Repository != "" {
+			spec.Image.Repository = override.Image.Repository
+		}
+		if override.Image.Tag != "" {
+			spec.Image.Tag = override.Image.Tag
+		}
+		if override.Image.Registry != "" {
+			spec.Image.Registry = override.Image.Registry
+		}
+		if len(override.Image.Platforms) > 0 {
+			spec.Image.Platforms = override.Image.Platforms
+		}
+	}
+
+	if override.Container != nil {
+		mergeContainerOverride(&spec.Container, override.Container)
+	}
+
+	if override.Environment != nil {
+		mergeEnvironmentOverride(&spec.Environment, override.Environment)
+	}
+	if override.Volumes != nil {
+		spec.Volumes = mergeVolumeOverride(spec.Volumes, override.Volumes)
+	}
+	if override.Ports != nil {
+		mergePortOverride(&spec.Ports, override.Ports)
+	}
+
+	if override.Networking != nil {
+		if override.Networking.Mode != nil {
+			spec.Networking.Mode = *override.Networking.Mode
+			spec.Networking.ModeTemplate = ""
+		}
+		spec.Networking.Networks = append(spec.Networking.Networks, override.Networking.Additional...)
+		if override.Networking.Zones != nil {
+			spec.Networking.Zones = override.Networking.Zones
+		}
+		if override.Networking.IPv4Address != nil {
+			spec.Networking.IPv4Address = *override.Networking.IPv4Address
+		}
+	}
+
+	if override.HealthCheck != nil {
+		mergeHealthCheck(spec, override.HealthCheck)
+	}
+}
+
+// mergeEnvironmentOverride removes, replaces and adds environment variables.
+// Removal and replacement also apply to conditional variables; a replaced
+// variable becomes unconditional.
+func mergeEnvironmentOverride(env *EnvironmentSpec, override *EnvironmentOverride) {
+	drop := slices.Clone(override.Remove)
+	for _, v := range override.Replace {
+		drop = append(drop, v.Name)
+	}
+
+	env.Static = slices.DeleteFunc(env.Static, func(v EnvVar) bool {
+		return slices.Contains(drop, v.Name)
+	})
+	env.Conditional = slices.DeleteFunc(env.Conditional, func(v ConditionalEnvVar) bool {
+		return slices.Contains(drop, v.Name)
+	})
+
+	env.Static = append(env.Static, override.Replace...)
+	env.Static = append(env.Static, override.Additional...)
+}
+
+// mergeVolumeOverride removes, replaces and adds volume mounts. Replaced
+// mounts keep their position.
+func mergeVolumeOverride(volumes []VolumeMount, override *VolumeOverride) []VolumeMount {
+	volumes = slices.DeleteFunc(volumes, func(v VolumeMount) bool {
+		return slices.Contains(override.Remove, v.ContainerPath)
+	})
+
+	for _, replacement := range override.Replace {
+		i := slices.IndexFunc(volumes, func(v VolumeMount) bool {
+			return v.ContainerPath == replacement.ContainerPath
+		})
+		if i >= 0 {
+			volumes[i] = replacement
+		} else {
+			volumes = append(volumes, replacement)
+		}
+	}
+
+	return append(volumes, override.Additional...)
+}
+
+// mergePortOverride removes, replaces and adds port mappings. Replaced static
+// mappings keep their position; conditional mappings are only removed.
+func mergePortOverride(ports *PortSpec, override *PortOverride) {
+	removed := func(mapping string) bool {
+		for _, port := range override.Remove {
+			if containerPort(port) == containerPort(mapping) {
+				return true
 			}
 		}
+		return false
+	}
+	ports.Static = slices.DeleteFunc(ports.Static, removed)
+	ports.Conditional = slices.DeleteFunc(ports.Conditional, func(p ConditionalPort) bool {
+		return removed(p.Port)
+	})
 
-		// Merge environment additions
-		if override.Spec.Environment != nil && len(override.Spec.Environment.Additional) > 0 {
-			merged.Spec.Environment.Static = append(
-				merged.Spec.Environment.Static,
-				override.Spec.Environment.Additional...,
-			)
+	for _, replacement := range override.Replace {
+		i := slices.IndexFunc(ports.Static, func(p string) bool {
+			return containerPort(p) == containerPort(replacement)
+		})
+		if i >= 0 {
+			ports.Static[i] = replacement
+		} else {
+			ports.Static = append(ports.Static, replacement)
 		}
+	}
 
-		// Merge volume additions
-		if override.Spec.Volumes != nil && len(override.Spec.Volumes.Additional) > 0 {
-			merged.Spec.Volumes = append(
-				merged.Spec.Volumes,
-				override.Spec.Volumes.Additional...,
-			)
+	ports.Static = append(ports.Static, override.Additional...)
+}
+
+// containerPort returns the container side of a port mapping including its
+// protocol (e.g. "0.0.0.0:8080:80/tcp" → "80/tcp"); tcp is the default
+func containerPort(mapping string) string {
+	port := mapping[strings.LastIndex(mapping, ":")+1:]
+	if !strings.Contains(port, "/") {
+		port += "/tcp"
+	}
+	return port
+}
+
+// mergeContainerOverride applies a container override in place
+func mergeContainerOverride(container *ContainerSpec, override *ContainerOverride) {
+	if override.Restart != nil {
+		container.Restart = *override.Restart
+	}
+	if override.Command != nil {
+		container.Command = *override.Command
+	}
+	if override.Privileged != nil {
+		container.Privileged = *override.Privileged
+	}
+	if override.Capabilities != nil {
+		container.Capabilities.Add = append(container.Capabilities.Add, override.Capabilities.Add...)
+		container.Capabilities.Drop = append(container.Capabilities.Drop, override.Capabilities.Drop...)
+	}
+	container.Devices = append(container.Devices, override.Devices...)
+	if override.ShmSize != nil {
+		container.ShmSize = *override.ShmSize
+	}
+	if len(override.Sysctls) > 0 {
+		if container.Sysctls == nil {
+			container.Sysctls = make(map[string]string)
+		}
+		for k, v := range override.Sysctls {
+			container.Sysctls[k] = v
 		}
 	}
+	if override.GPUEnabled != nil {
+		container.GPUEnabled = *override.GPUEnabled
+	}
+}
 
-	// Merge routing override
-	if override.Routing != nil {
-		if override.Routing.Subdomain != nil {
-			merged.Routing.Subdomain = *override.Routing.Subdomain
+// mergeHealthCheck overlays the fields set in override onto the base healthcheck
+func mergeHealthCheck(spec *ServiceSpec, override *HealthCheck) {
+	if spec.HealthCheck == nil {
+		hc := *override
+		spec.HealthCheck = &hc
+		return
+	}
+	if len(override.Test) > 0 {
+		spec.HealthCheck.Test = override.Test
+	}
+	if override.Interval != "" {
+		spec.HealthCheck.Interval = override.Interval
+	}
+	if override.Timeout != "" {
+		spec.HealthCheck.Timeout = override.Timeout
+	}
+	if override.Retries > 0 {
+		spec.HealthCheck.Retries = override.Retries
+	}
+	if override.StartPeriod != "" {
+		spec.HealthCheck.StartPeriod = override.StartPeriod
+	}
+}
+
+// mergeRoutingOverride applies a routing override in place
+func mergeRoutingOverride(routing *RoutingConfig, override *RoutingConfigOverride) {
+	if override.Enabled != nil {
+		routing.Enabled = *override.Enabled
+	}
+	if override.Port != nil {
+		routing.Port = *override.Port
+	}
+	if override.Subdomain != nil {
+		routing.Subdomain = *override.Subdomain
+	}
+	if override.Path != nil {
+		routing.Path = *override.Path
+	}
+	if override.ForceSubdomain != nil {
+		routing.ForceSubdomain = *override.ForceSubdomain
+	}
+	if override.Auth != nil {
+		routing.Auth = *override.Auth
+	}
+
+	if t := override.Traefik; t != nil {
+		if t.Priority != nil {
+			priority := *t.Priority
+			routing.Traefik.Priority = &priority
 		}
-		if override.Routing.Path != nil {
-			merged.Routing.Path = *override.Routing.Path
+		routing.Traefik.Middlewares = append(routing.Traefik.Middlewares, t.Middlewares...)
+		if len(t.CustomLabels) > 0 {
+			if routing.Traefik.CustomLabels == nil {
+				routing.Traefik.CustomLabels = make(map[string]string)
+			}
+			for k, v := range t.CustomLabels {
+				routing.Traefik.CustomLabels[k] = v
+			}
+		}
+		if routing.Traefik.MiddlewareDefinitions == nil {
+			routing.Traefik.MiddlewareDefinitions = maps.Clone(t.MiddlewareDefinitions)
+		} else {
+			maps.Copy(routing.Traefik.MiddlewareDefinitions, t.MiddlewareDefinitions)
 		}
 	}
+}
 
-	return merged
+// mergeIntegrations replaces each integration block set in override
+func mergeIntegrations(integrations *Integrations, override *Integrations) {
+	if override.Homepage != nil {
+		integrations.Homepage = override.Homepage
+	}
+	if override.Cloudflared != nil {
+		integrations.Cloudflared = override.Cloudflared
+	}
+	if override.Watchtower != nil {
+		integrations.Watchtower = override.Watchtower
+	}
+	if override.Unpackerr != nil {
+		integrations.Unpackerr = override.Unpackerr
+	}
 }
 
 // deepCopyServiceDefinition creates a deep copy of a service definition
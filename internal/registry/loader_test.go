@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"os"
 	"path/filepath"
+	"slices"
 	"testing"
 )
 
@@ -713,6 +714,213 @@ func TestLoaderMergeOverride(t *testing.T) {
 	}
 }
 
+// TestLoaderMergeOverrideFullSpec tests container, port, networking,
+// healthcheck, Traefik and integration overrides parsed from YAML
+func TestLoaderMergeOverrideFullSpec(t *testing.T) {
+	priority := 10
+	base := &ServiceDefinition{
+		Metadata: ServiceMetadata{Name: "svc"},
+		Spec: ServiceSpec{
+			Container: ContainerSpec{
+				Restart:      "unless-stopped",
+				Capabilities: CapabilitiesSpec{Add: []string{"NET_ADMIN"}},
+				Sysctls:      map[string]string{"net.ipv4.ip_forward": "1", "net.core.somaxconn": "128"},
+			},
+			Ports: PortSpec{Static: []string{"8080:8080"}},
+			Networking: NetworkSpec{
+				Networks:     []NetworkRef{{Name: "proxy"}},
+				Zones:        []string{ZoneFrontend},
+				ModeTemplate: "{{ if .Config.VPNEnabled }}service:gluetun{{ else }}bridge{{ end }}",
+			},
+			HealthCheck: &HealthCheck{Test: []string{"CMD", "true"}, Interval: "30s", Retries: 3},
+		},
+		Routing: RoutingConfig{
+			Enabled: true,
+			Port:    8080,
+			Auth:    AuthConfig{Required: true},
+			Traefik: TraefikConfig{
+				Priority:     &priority,
+				Middlewares:  []string{"base"},
+				CustomLabels: map[string]string{"a": "1", "b": "2"},
+			},
+		},
+		Integrations: Integrations{
+			Homepage:   &HomepageIntegration{Enabled: true, Group: "Media"},
+			Watchtower: &WatchtowerIntegration{Enabled: true},
+		},
+	}
+
+	overrideYAML := `apiVersion: sdbx.one/v1
+kind: ServiceOverride
+metadata:
+  name: svc
+spec:
+  container:
+    restart: always
+    privileged: true
+    capabilities:
+      add: [SYS_ADMIN]
+    devices: [/dev/dri:/dev/dri]
+    sysctls:
+      net.core.somaxconn: "1024"
+  ports:
+    additional: ["9090:9090"]
+  networking:
+    mode: bridge
+    additional:
+      - name: backend
+    zones: [downloads]
+  healthcheck:
+    interval: 10s
+routing:
+  port: 9090
+  auth:
+    required: false
+    bypass: true
+  traefik:
+    middlewares: [extra]
+    customLabels:
+      b: "20"
+      c: "3"
+integrations:
+  watchtower:
+    enabled: false
+`
+	loader := NewLoader()
+	override, err := loader.ParseServiceOverride([]byte(overrideYAML))
+	if err != nil {
+		t.Fatalf("ParseServiceOverride failed: %v", err)
+	}
+	merged := loader.MergeOverride(base, override)
+
+	c := merged.Spec.Container
+	if c.Restart != "always" || !c.Privileged || c.Command != "" {
+		t.Errorf("container = %+v, want restart always, privileged, no command", c)
+	}
+	if len(c.Capabilities.Add) != 2 || len(c.Devices) != 1 {
+		t.Errorf("capabilities/devices not appended: %+v %v", c.Capabilities, c.Devices)
+	}
+	if c.Sysctls["net.core.somaxconn"] != "1024" || c.Sysctls["net.ipv4.ip_forward"] != "1" {
+		t.Errorf("sysctls not merged by key: %v", c.Sysctls)
+	}
+
+	if len(merged.Spec.Ports.Static) != 2 {
+		t.Errorf("ports = %v, want 2 entries", merged.Spec.Ports.Static)
+	}
+
+	n := merged.Spec.Networking
+	if n.Mode != "bridge" || n.ModeTemplate != "" {
+		t.Errorf("network mode = %q template %q, want bridge and no template", n.Mode, n.ModeTemplate)
+	}
+	if len(n.Networks) != 2 || len(n.Zones) != 1 || n.Zones[0] != ZoneDownloads {
+		t.Errorf("networking = %+v, want appended networks and replaced zones", n)
+	}
+
+	hc := merged.Spec.HealthCheck
+	if hc.Interval != "10s" || hc.Retries != 3 || len(hc.Test) != 2 {
+		t.Errorf("healthcheck = %+v, want only interval changed", hc)
+	}
+
+	r := merged.Routing
+	if !r.Enabled || r.Port != 9090 || r.Auth.Required || !r.Auth.Bypass {
+		t.Errorf("routing = %+v, want port 9090 and replaced auth", r)
+	}
+	if *r.Traefik.Priority != 10 || len(r.Traefik.Middlewares) != 2 {
+		t.Errorf("traefik = %+v, want priority kept and middlewares appended", r.Traefik)
+	}
+	if r.Traefik.CustomLabels["a"] != "1" || r.Traefik.CustomLabels["b"] != "20" || r.Traefik.CustomLabels["c"] != "3" {
+		t.Errorf("custom labels not merged by key: %v", r.Traefik.CustomLabels)
+	}
+
+	if merged.Integrations.Watchtower.Enabled || merged.Integrations.Homepage == nil {
+		t.Errorf("integrations = %+v, want watchtower replaced and homepage kept", merged.Integrations)
+	}
+
+	// Base maps and slices are left untouched
+	if base.Spec.Container.Sysctls["net.core.somaxconn"] != "128" || len(base.Routing.Traefik.Middlewares) != 1 {
+		t.Error("base should not be modified")
+	}
+}
+
+// TestLoaderMergeOverrideRemoveReplace tests remove/replace semantics for
+// environment variables, volumes and ports
+func TestLoaderMergeOverrideRemoveReplace(t *testing.T) {
+	base := &ServiceDefinition{
+		Metadata: ServiceMetadata{Name: "svc"},
+		Spec: ServiceSpec{
+			Environment: EnvironmentSpec{
+				Static: []EnvVar{{Name: "KEEP", Value: "1"}, {Name: "DROP", Value: "2"}, {Name: "SWAP", Value: "old"}},
+				Conditional: []ConditionalEnvVar{
+					{EnvVar: EnvVar{Name: "DROP", Value: "3"}, When: "{{ .Config.VPNEnabled }}"},
+				},
+			},
+			Volumes: []VolumeMount{
+				{HostPath: "/a", ContainerPath: "/config"},
+				{HostPath: "/b", ContainerPath: "/media"},
+				{HostPath: "/c", ContainerPath: "/cache"},
+			},
+			Ports: PortSpec{
+				Static:      []string{"8096:8096", "1900:1900/udp", "7359:7359/udp"},
+				Conditional: []ConditionalPort{{Port: "8920:8920", When: "true"}},
+			},
+		},
+	}
+
+	overrideYAML := `apiVersion: sdbx.one/v1
+kind: ServiceOverride
+metadata:
+  name: svc
+spec:
+  environment:
+    remove: [DROP]
+    replace:
+      - name: SWAP
+        value: new
+  volumes:
+    remove: [/cache]
+    replace:
+      - hostPath: /b
+        containerPath: /media
+        readOnly: true
+  ports:
+    remove: ["1900/udp", "8920"]
+    replace: ["127.0.0.1:8096:8096"]
+    additional: ["9000:9000"]
+`
+	loader := NewLoader()
+	override, err := loader.ParseServiceOverride([]byte(overrideYAML))
+	if err != nil {
+		t.Fatalf("ParseServiceOverride failed: %v", err)
+	}
+	merged := loader.MergeOverride(base, override)
+
+	env := merged.Spec.Environment
+	if len(env.Static) != 2 || env.Static[0].Name != "KEEP" || env.Static[1].Value != "new" {
+		t.Errorf("static env = %+v, want KEEP and SWAP=new", env.Static)
+	}
+	if len(env.Conditional) != 0 {
+		t.Errorf("conditional env = %+v, want DROP removed", env.Conditional)
+	}
+
+	vols := merged.Spec.Volumes
+	if len(vols) != 2 || vols[1].ContainerPath != "/media" || !vols[1].ReadOnly {
+		t.Errorf("volumes = %+v, want /config and read-only /media", vols)
+	}
+
+	ports := merged.Spec.Ports
+	want := []string{"127.0.0.1:8096:8096", "7359:7359/udp", "9000:9000"}
+	if !slices.Equal(ports.Static, want) {
+		t.Errorf("ports = %v, want %v", ports.Static, want)
+	}
+	if len(ports.Conditional) != 0 {
+		t.Errorf("conditional ports = %+v, want 8920 removed", ports.Conditional)
+	}
+
+	if len(base.Spec.Volumes) != 3 || base.Spec.Environment.Static[2].Value != "old" {
+		t.Error("base should not be modified")
+	}
+}
+
 // TestWriteYAML tests YAML writing to a writer
 func TestWriteYAML(t *testing.T) {
 	var buf bytes.Buffer
@@ -1,11 +1,15 @@
 package registry
 
 import (
+	"bytes"
 	"context"
 	"crypto/sha256"
+	"errors"
 	"fmt"
+	"maps"
 	"os"
 	"path/filepath"
+	"slices"
 	"time"
 
 	"gopkg.in/yaml.v3"
@@ -48,7 +52,7 @@ func (m *LockManager) GenerateLockFile(ctx context.Context, cfg *config.Config,
 		APIVersion: APIVersion,
 		Kind:       KindLockFile,
 		Metadata: LockFileMetadata{
-			Version:     1,
+			Version:     LockFileVersion,
 			GeneratedAt: time.Now().UTC(),
 			CLIVersion:  m.cliVersion,
 			ConfigHash:  configHash,
@@ -66,9 +70,17 @@ func (m *LockManager) GenerateLockFile(ctx context.Context, cfg *config.Config,
 				URL:       gitSrc.GetURL(),
 				Commit:    gitSrc.GetCommit(),
 				Branch:    gitSrc.GetBranch(),
+				Ref:       gitSrc.GetRef(),
 				FetchedAt: gitSrc.GetLastUpdated(),
 			}
 		}
+		if archive, ok := src.(*ArchiveSource); ok {
+			lock.Sources[src.Name()] = LockedSource{
+				URL:       archive.GetURL(),
+				Commit:    archive.GetCommit(),
+				FetchedAt: archive.GetLastUpdated(),
+			}
+		}
 	}
 
 	// Lock services
@@ -85,8 +97,19 @@ func (m *LockManager) GenerateLockFile(ctx context.Context, cfg *config.Config,
 				Repository: def.Spec.Image.Repository,
 				Tag:        def.Spec.Image.Tag,
 			},
-			ResolvedFrom: resolved.SourcePath,
-			Enabled:      resolved.Enabled,
+			ResolvedFrom:   resolved.SourcePath,
+			Enabled:        resolved.Enabled,
+			DefinitionHash: resolved.DefinitionHash,
+			PinnedCommit:   resolved.PinnedCommit,
+		}
+	}
+
+	// The record of the last generation still describes the project files
+	if outputPath != "" {
+		if previous, err := m.loader.LoadLockFile(outputPath); err == nil {
+			lock.Metadata.Generation = previous.Metadata.Generation
+			maps.Copy(lock.GeneratedFiles, previous.GeneratedFiles)
+			lock.HostPorts = previous.HostPorts
 		}
 	}
 
@@ -151,6 +174,18 @@ func (m *LockManager) Verify(ctx context.Context, cfg *config.Config, lock *Lock
 				})
 			}
 		}
+		if archive, ok := src.(*ArchiveSource); ok {
+			if current := archive.GetCommit(); current != locked.Commit {
+				results = append(results, LockVerificationResult{
+					Type:     "source",
+					Name:     sourceName,
+					Status:   "changed",
+					Message:  "Source archive has changed",
+					Expected: locked.Commit,
+					Actual:   current,
+				})
+			}
+		}
 	}
 
 	// Verify services
@@ -304,6 +339,11 @@ func (m *LockManager) Update(ctx context.Context, cfg *config.Config, lock *Lock
 
 // calculateConfigHash calculates a hash of the configuration
 func (m *LockManager) calculateConfigHash(cfg *config.Config) (string, error) {
+	return ConfigHash(cfg)
+}
+
+// ConfigHash calculates the hash of a configuration as stored in lock files
+func ConfigHash(cfg *config.Config) (string, error) {
 	data, err := yaml.Marshal(cfg)
 	if err != nil {
 		return "", err
@@ -313,6 +353,41 @@ func (m *LockManager) calculateConfigHash(cfg *config.Config) (string, error) {
 	return fmt.Sprintf("sha256:%x", hash[:16]), nil
 }
 
+// SecretsHash calculates the hash of the secret values generation renders
+// from, keyed by file name, as recorded in GenerationRecord
+func SecretsHash(values map[string]string) string {
+	hash := sha256.New()
+	for _, name := range slices.Sorted(maps.Keys(values)) {
+		fmt.Fprintf(hash, "%s\x00%s\x00", name, values[name])
+	}
+	return fmt.Sprintf("sha256:%x", hash.Sum(nil)[:16])
+}
+
+// UnchangedServices returns the services whose merged definition and project
+// configuration are identical to when the lock file was generated, and whose
+// last generation ran with the same CLI version and secret values. Their
+// generated output can be reused as-is.
+func (l *LockFile) UnchangedServices(graph *ResolutionGraph, configHash, cliVersion, secretsHash string) map[string]bool {
+	unchanged := make(map[string]bool)
+	if l == nil || l.Metadata.ConfigHash != configHash {
+		return unchanged
+	}
+	if gen := l.Metadata.Generation; gen == nil || gen.CLIVersion != cliVersion || gen.SecretsHash != secretsHash {
+		return unchanged
+	}
+
+	for name, resolved := range graph.Services {
+		locked, ok := l.Services[name]
+		if !ok || locked.DefinitionHash == "" || !resolved.Enabled {
+			continue
+		}
+		if locked.DefinitionHash == resolved.DefinitionHash {
+			unchanged[name] = true
+		}
+	}
+	return unchanged
+}
+
 // LockVerificationResult represents a lock file verification result
 type LockVerificationResult struct {
 	Type     string // "config", "source", "service"
@@ -346,11 +421,70 @@ func (d *LockDiff) IsEmpty() bool {
 	return !d.HasChanges()
 }
 
+// HostPortKey identifies a published port of a service in
+// LockFile.HostPorts, e.g. "homepage:3000/tcp"
+func HostPortKey(service, containerPort, proto string) string {
+	return fmt.Sprintf("%s:%s/%s", service, containerPort, proto)
+}
+
 // GetLockFilePath returns the default lock file path for a project
 func GetLockFilePath(projectDir string) string {
 	return filepath.Join(projectDir, ".sdbx.lock")
 }
 
+// ErrLockChecksumMismatch is returned when a lock file was edited or corrupted
+// after it was generated
+var ErrLockChecksumMismatch = errors.New("lock file does not match its checksum")
+
+// ErrLockChecksumMissing is returned for lock files written before checksums
+// were introduced
+var ErrLockChecksumMissing = errors.New("lock file has no checksum")
+
+// LockChecksumPath returns the path of the checksum stored alongside a lock file
+func LockChecksumPath(lockPath string) string {
+	return lockPath + ".sha256"
+}
+
+// LockChecksum calculates the checksum of lock file contents
+func LockChecksum(data []byte) string {
+	hash := sha256.Sum256(data)
+	return fmt.Sprintf("sha256:%x", hash)
+}
+
+// SignLockFile records the checksum of the lock file at path alongside it
+func SignLockFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read file %s: %w", path, err)
+	}
+	return os.WriteFile(LockChecksumPath(path), []byte(LockChecksum(data)+"\n"), 0o644)
+}
+
+// VerifyLockChecksum checks the lock file at path against its stored checksum
+func VerifyLockChecksum(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read file %s: %w", path, err)
+	}
+	return verifyLockChecksum(path, data)
+}
+
+// verifyLockChecksum compares lock file contents with the checksum stored
+// alongside path
+func verifyLockChecksum(path string, data []byte) error {
+	stored, err := os.ReadFile(LockChecksumPath(path))
+	if os.IsNotExist(err) {
+		return ErrLockChecksumMissing
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read checksum: %w", err)
+	}
+	if string(bytes.TrimSpace(stored)) != LockChecksum(data) {
+		return ErrLockChecksumMismatch
+	}
+	return nil
+}
+
 // LockFileExists checks if a lock file exists
 func LockFileExists(projectDir string) bool {
 	path := GetLockFilePath(projectDir)
@@ -1,10 +1,14 @@
 package registry
 
 import (
+	"context"
+	"errors"
 	"os"
 	"path/filepath"
 	"testing"
 	"time"
+
+	"github.com/maiko/sdbx/internal/config"
 )
 
 // TestLockDiffHasChanges tests LockDiff change detection
@@ -153,6 +157,51 @@ func TestLockFileExists(t *testing.T) {
 	}
 }
 
+// TestLockChecksum tests tamper detection for saved lock files
+func TestLockChecksum(t *testing.T) {
+	path := GetLockFilePath(t.TempDir())
+	lock := &LockFile{APIVersion: APIVersion, Kind: KindLockFile, Metadata: LockFileMetadata{Version: LockFileVersion}}
+
+	loader := NewLoader()
+	if err := loader.SaveLockFile(path, lock); err != nil {
+		t.Fatalf("SaveLockFile failed: %v", err)
+	}
+	if err := VerifyLockChecksum(path); err != nil {
+		t.Fatalf("VerifyLockChecksum after save = %v", err)
+	}
+
+	// Hand edits are detected on verify, and the edited file still loads
+	data, _ := os.ReadFile(path)
+	if err := os.WriteFile(path, append(data, []byte("# edited\n")...), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := VerifyLockChecksum(path); !errors.Is(err, ErrLockChecksumMismatch) {
+		t.Errorf("VerifyLockChecksum after edit = %v, want ErrLockChecksumMismatch", err)
+	}
+	if _, err := loader.LoadLockFile(path); err != nil {
+		t.Errorf("LoadLockFile after edit = %v, want the edited lock file", err)
+	}
+
+	// Re-signing accepts the edit
+	if err := SignLockFile(path); err != nil {
+		t.Fatalf("SignLockFile failed: %v", err)
+	}
+	if _, err := loader.LoadLockFile(path); err != nil {
+		t.Errorf("LoadLockFile after re-sign = %v", err)
+	}
+
+	// Lock files from before checksums still load
+	if err := os.Remove(LockChecksumPath(path)); err != nil {
+		t.Fatal(err)
+	}
+	if err := VerifyLockChecksum(path); !errors.Is(err, ErrLockChecksumMissing) {
+		t.Errorf("VerifyLockChecksum without checksum = %v, want ErrLockChecksumMissing", err)
+	}
+	if _, err := loader.LoadLockFile(path); err != nil {
+		t.Errorf("LoadLockFile without checksum = %v", err)
+	}
+}
+
 // TestLockVerificationResult tests verification result struct
 func TestLockVerificationResult(t *testing.T) {
 	result := LockVerificationResult{
@@ -385,3 +434,127 @@ func TestLockedImageStruct(t *testing.T) {
 		t.Errorf("Tag = %q, want 'alpine'", image.Tag)
 	}
 }
+
+// TestUnchangedServices tests detection of services reusable across regenerations
+func TestUnchangedServices(t *testing.T) {
+	secrets := SecretsHash(map[string]string{"authelia_jwt_secret.txt": "old"})
+	lock := &LockFile{
+		Metadata: LockFileMetadata{
+			ConfigHash: "sha256:cfg",
+			Generation: &GenerationRecord{ID: "1", CLIVersion: "1.2.0", SecretsHash: secrets},
+		},
+		Services: map[string]LockedService{
+			"sonarr": {DefinitionHash: "sha256:aaa", Enabled: true},
+			"radarr": {DefinitionHash: "sha256:bbb", Enabled: true},
+			"plex":   {Enabled: true}, // locked before hashes were recorded
+		},
+	}
+	graph := &ResolutionGraph{
+		Services: map[string]*ResolvedService{
+			"sonarr": {Name: "sonarr", DefinitionHash: "sha256:aaa", Enabled: true},
+			"radarr": {Name: "radarr", DefinitionHash: "sha256:ccc", Enabled: true},
+			"plex":   {Name: "plex", DefinitionHash: "sha256:ddd", Enabled: true},
+			"bazarr": {Name: "bazarr", DefinitionHash: "sha256:eee", Enabled: true},
+		},
+	}
+
+	unchanged := lock.UnchangedServices(graph, "sha256:cfg", "1.2.0", secrets)
+	if len(unchanged) != 1 || !unchanged["sonarr"] {
+		t.Errorf("UnchangedServices() = %v, want only sonarr", unchanged)
+	}
+
+	if got := lock.UnchangedServices(graph, "sha256:other", "1.2.0", secrets); len(got) != 0 {
+		t.Errorf("config change should invalidate all services, got %v", got)
+	}
+
+	// The generator output may differ after an sdbx upgrade
+	if got := lock.UnchangedServices(graph, "sha256:cfg", "1.3.0", secrets); len(got) != 0 {
+		t.Errorf("CLI upgrade should invalidate all services, got %v", got)
+	}
+
+	// Rotated secrets are rendered into environment and env files
+	rotated := SecretsHash(map[string]string{"authelia_jwt_secret.txt": "new"})
+	if rotated == secrets {
+		t.Fatal("SecretsHash() ignores secret values")
+	}
+	if got := lock.UnchangedServices(graph, "sha256:cfg", "1.2.0", rotated); len(got) != 0 {
+		t.Errorf("secret rotation should invalidate all services, got %v", got)
+	}
+
+	// Generations recorded before these inputs regenerate everything once
+	lock.Metadata.Generation = &GenerationRecord{ID: "1"}
+	if got := lock.UnchangedServices(graph, "sha256:cfg", "1.2.0", secrets); len(got) != 0 {
+		t.Errorf("generation without CLI version should invalidate all services, got %v", got)
+	}
+
+	var nilLock *LockFile
+	if got := nilLock.UnchangedServices(graph, "sha256:cfg", "1.2.0", secrets); len(got) != 0 {
+		t.Errorf("nil lock should report nothing unchanged, got %v", got)
+	}
+}
+
+// TestRegistryLockFileRoundTrip tests generating, diffing and updating lock files
+func TestRegistryLockFileRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	reg := newTestRegistry(t)
+	cfg := config.DefaultConfig()
+
+	lock, err := reg.GenerateLockFile(ctx, cfg)
+	if err != nil {
+		t.Fatalf("GenerateLockFile() error: %v", err)
+	}
+	if lock.Metadata.ConfigHash == "" {
+		t.Error("generated lock has no config hash")
+	}
+	traefik, ok := lock.Services["traefik"]
+	if !ok || traefik.DefinitionHash == "" {
+		t.Fatalf("traefik not locked with a definition hash: %+v", traefik)
+	}
+
+	// Edit the lock so the diff has several entries
+	existing, _ := reg.GenerateLockFile(ctx, cfg)
+	existing.Services["traefik"] = LockedService{DefinitionVersion: "0.0.1", Image: traefik.Image}
+	existing.Services["legacy"] = LockedService{DefinitionVersion: "1.0.0"}
+	delete(existing.Services, "authelia")
+
+	diffs := reg.DiffLockFiles(existing, lock)
+	if len(diffs) != 3 {
+		t.Fatalf("DiffLockFiles() = %+v, want 3 differences", diffs)
+	}
+	for i := 1; i < len(diffs); i++ {
+		if diffs[i-1].Description > diffs[i].Description {
+			t.Errorf("diffs not sorted: %q before %q", diffs[i-1].Description, diffs[i].Description)
+		}
+	}
+
+	existing.HostPorts = map[string]int{"qbittorrent:6881/tcp": 20001}
+	existing.GeneratedFiles = map[string]string{"compose.yaml": "sha256:abc"}
+	existing.Metadata.Generation = &GenerationRecord{ID: "20260101T000000Z", Changed: 3}
+
+	updated, err := reg.UpdateLockFile(ctx, cfg, existing, []string{"traefik"})
+	if err != nil {
+		t.Fatalf("UpdateLockFile() error: %v", err)
+	}
+	full, err := reg.UpdateLockFile(ctx, cfg, existing, nil)
+	if err != nil {
+		t.Fatalf("UpdateLockFile() of all services error: %v", err)
+	}
+	for _, lock := range []*LockFile{updated, full} {
+		if lock.HostPorts["qbittorrent:6881/tcp"] != 20001 || lock.GeneratedFiles["compose.yaml"] != "sha256:abc" {
+			t.Errorf("UpdateLockFile() dropped hostPorts or generatedFiles: %v %v", lock.HostPorts, lock.GeneratedFiles)
+		}
+		if lock.Metadata.Generation == nil || lock.Metadata.Generation.ID != "20260101T000000Z" {
+			t.Errorf("UpdateLockFile() generation = %+v, want the existing one", lock.Metadata.Generation)
+		}
+	}
+	if updated.Services["traefik"].DefinitionVersion != traefik.DefinitionVersion {
+		t.Errorf("traefik version = %q, want %q", updated.Services["traefik"].DefinitionVersion, traefik.DefinitionVersion)
+	}
+	if _, ok := updated.Services["legacy"]; !ok {
+		t.Error("services not named in the update should be kept")
+	}
+
+	if _, err := reg.UpdateLockFile(ctx, cfg, existing, []string{"nonexistent"}); err == nil {
+		t.Error("expected error for a service that is neither locked nor enabled")
+	}
+}
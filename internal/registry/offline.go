@@ -0,0 +1,26 @@
+package registry
+
+import "github.com/maiko/sdbx/internal/problem"
+
+// offline keeps sources from reaching the network: Git and archive sources
+// use their cached copies, and fetching anything fails with problem.ErrOffline
+var offline bool
+
+// SetOffline enables or disables offline mode (--offline, offline in .sdbx.yaml)
+func SetOffline(enabled bool) {
+	offline = enabled
+}
+
+// Offline reports whether offline mode is enabled
+func Offline() bool {
+	return offline
+}
+
+// RequireNetwork returns a problem.ErrOffline error when offline mode is
+// enabled. operation says what needs the network, e.g. "git clone of official".
+func RequireNetwork(operation string) error {
+	if offline {
+		return problem.Wrap(problem.ErrOffline, nil, "%s needs network access", operation)
+	}
+	return nil
+}
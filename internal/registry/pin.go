@@ -0,0 +1,142 @@
+package registry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// commitRegex matches abbreviated and full commit hashes
+var commitRegex = regexp.MustCompile(`^[0-9a-f]{7,40}$`)
+
+// LoadServiceAt loads a service definition as of a pin (services.NAME.pin in
+// .sdbx.yaml): a commit of the source, or the newest commit whose definition
+// has that metadata.version. It returns the commit the definition was read
+// from.
+func (s *GitSource) LoadServiceAt(ctx context.Context, name, pin string) (*ServiceDefinition, string, error) {
+	if err := s.ensureCloned(ctx); err != nil {
+		return nil, "", err
+	}
+	repoPath := s.cache.GetRepoPath(s.name)
+
+	// Clones are shallow: pins need the history
+	if shallow, _ := s.gitOutput(ctx, repoPath, "rev-parse", "--is-shallow-repository"); shallow == "true" {
+		if err := RequireNetwork("fetching the history of " + s.name); err != nil {
+			return nil, "", err
+		}
+		if _, err := s.gitOutput(ctx, repoPath, "fetch", "--unshallow", "origin", s.branch); err != nil {
+			return nil, "", fmt.Errorf("failed to fetch the history of %s: %w", s.name, err)
+		}
+	}
+
+	dirs := s.serviceDirs(name)
+	if commitRegex.MatchString(pin) {
+		if commit, err := s.gitOutput(ctx, repoPath, "rev-parse", "--verify", "--quiet", pin+"^{commit}"); err == nil {
+			def, err := s.definitionAt(ctx, commit, dirs)
+			if err != nil {
+				return nil, "", fmt.Errorf("%s at %s: %w", name, pin, err)
+			}
+			return def, commit, nil
+		}
+	}
+
+	// Newest commit touching the service whose definition has the version
+	log, err := s.gitOutput(ctx, repoPath, append([]string{"log", "--format=%H", "--"}, dirs...)...)
+	if err != nil {
+		return nil, "", err
+	}
+	for _, commit := range strings.Fields(log) {
+		if def, err := s.definitionAt(ctx, commit, dirs); err == nil && def.Metadata.Version == pin {
+			return def, commit, nil
+		}
+	}
+	return nil, "", fmt.Errorf("source %s has no commit or definition version %s of %s", s.name, pin, name)
+}
+
+// serviceDirs returns the directories a service definition may live in,
+// relative to the repository, in the order GetServicePath checks them
+func (s *GitSource) serviceDirs(name string) []string {
+	base := path.Clean("/" + strings.ReplaceAll(s.subPath, "\\", "/"))[1:]
+	return []string{
+		path.Join(base, name),
+		path.Join(base, "core", name),
+		path.Join(base, "addons", name),
+	}
+}
+
+// definitionAt parses the service definition of a commit
+func (s *GitSource) definitionAt(ctx context.Context, commit string, dirs []string) (*ServiceDefinition, error) {
+	repoPath := s.cache.GetRepoPath(s.name)
+	for _, dir := range dirs {
+		output, err := s.gitCommand(ctx, repoPath, "show", commit+":"+path.Join(dir, "service.yaml")).Output()
+		if err != nil {
+			continue
+		}
+		return s.loader.ParseServiceDefinition(output)
+	}
+	return nil, fmt.Errorf("no service.yaml in commit %s", commit)
+}
+
+// PinError reports a pinned service whose definition could not be read at
+// its pin
+type PinError struct {
+	Service string
+	Pin     string
+	Err     error
+}
+
+func (e *PinError) Error() string {
+	return fmt.Sprintf("%s pinned to %s: %v", e.Service, e.Pin, e.Err)
+}
+
+func (e *PinError) Unwrap() error {
+	return e.Err
+}
+
+// CheckPins returns the pinned services of a graph that failed to resolve.
+// Generation refuses them rather than dropping the services.
+func CheckPins(graph *ResolutionGraph) []error {
+	var errs []error
+	for _, e := range graph.Errors {
+		var pinErr *PinError
+		if errors.As(e.Cause, &pinErr) && !slices.Contains(errs, error(pinErr)) {
+			errs = append(errs, pinErr)
+		}
+	}
+	return errs
+}
+
+// GetServiceAt returns a service definition pinned to a commit or
+// definition version, from the highest priority source providing the
+// service, with the commit it was read from. Only Git sources can be pinned.
+func (r *Registry) GetServiceAt(ctx context.Context, name, pin string) (*ServiceDefinition, string, string, error) {
+	r.mu.RLock()
+	sources := r.sources
+	r.mu.RUnlock()
+
+	for _, src := range sources {
+		if !src.IsEnabled() {
+			continue
+		}
+		if def, err := src.LoadService(ctx, name); err != nil || def == nil {
+			continue
+		}
+
+		gitSrc, ok := src.(*GitSource)
+		if !ok {
+			return nil, "", "", &PinError{Service: name, Pin: pin,
+				Err: fmt.Errorf("it comes from %s source %s; only Git sources can be pinned", src.Type(), src.Name())}
+		}
+		def, commit, err := gitSrc.LoadServiceAt(ctx, name, pin)
+		if err != nil {
+			return nil, "", "", &PinError{Service: name, Pin: pin, Err: err}
+		}
+		return def, src.Name(), commit, nil
+	}
+
+	return nil, "", "", fmt.Errorf("service %s not found in any source", name)
+}
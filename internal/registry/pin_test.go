@@ -0,0 +1,126 @@
+package registry
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/maiko/sdbx/internal/config"
+)
+
+// pinTestDefinition returns a definition of pinned-svc at a version
+func pinTestDefinition(version string) string {
+	return `apiVersion: sdbx.one/v1
+kind: Service
+metadata:
+  name: pinned-svc
+  version: ` + version + `
+  category: utility
+  description: Pinned service
+spec:
+  image:
+    repository: test/pinned
+    tag: "` + version + `"
+  container:
+    name_template: "sdbx-pinned-svc"
+routing:
+  enabled: false
+conditions:
+  always: true
+`
+}
+
+func TestResolvePinnedService(t *testing.T) {
+	remoteDir := t.TempDir()
+	initTestGitRepo(t, remoteDir, map[string]string{
+		"core/pinned-svc/service.yaml": pinTestDefinition("1.0.0"),
+	})
+	for _, version := range []string{"1.1.0", "1.2.0"} {
+		path := filepath.Join(remoteDir, "core", "pinned-svc", "service.yaml")
+		if err := os.WriteFile(path, []byte(pinTestDefinition(version)), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		cmd := exec.Command("git", "commit", "-am", "pinned-svc "+version)
+		cmd.Dir = remoteDir
+		if output, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git commit failed: %s: %v", output, err)
+		}
+	}
+	cmd := exec.Command("git", "rev-list", "--reverse", "HEAD")
+	cmd.Dir = remoteDir
+	commits, err := cmd.Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	first := strings.Fields(string(commits))[0]
+
+	cache := NewCache(t.TempDir())
+	reg := &Registry{
+		sources: []SourceProvider{NewGitSource(Source{
+			Name: "test-pin", Type: "git", URL: remoteDir, Branch: "master", Enabled: true,
+		}, cache)},
+		cache:     cache,
+		validator: NewValidator(),
+	}
+	reg.resolver = NewResolver(reg)
+	ctx := context.Background()
+
+	tests := []struct {
+		pin         string
+		wantVersion string
+		wantCommit  string
+	}{
+		{"", "1.2.0", ""},
+		{"1.1.0", "1.1.0", ""},
+		{first[:10], "1.0.0", first},
+	}
+	for _, tt := range tests {
+		cfg := config.DefaultConfig()
+		cfg.Services = map[string]config.ServiceOverride{"pinned-svc": {Pin: tt.pin}}
+		graph, err := reg.Resolve(ctx, cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resolved, ok := graph.Services["pinned-svc"]
+		if !ok {
+			t.Fatalf("pin %q: pinned-svc not resolved: %v", tt.pin, graph.Errors)
+		}
+		if v := resolved.FinalDefinition.Metadata.Version; v != tt.wantVersion {
+			t.Errorf("pin %q: version = %s, want %s", tt.pin, v, tt.wantVersion)
+		}
+		if tt.pin == "" && resolved.PinnedCommit != "" {
+			t.Errorf("unpinned service has PinnedCommit %s", resolved.PinnedCommit)
+		}
+		if tt.pin != "" && resolved.PinnedCommit == "" {
+			t.Errorf("pin %q: PinnedCommit not set", tt.pin)
+		}
+		if tt.wantCommit != "" && resolved.PinnedCommit != tt.wantCommit {
+			t.Errorf("pin %q: PinnedCommit = %s, want %s", tt.pin, resolved.PinnedCommit, tt.wantCommit)
+		}
+	}
+
+	// Unknown pins are reported instead of dropping the service
+	cfg := config.DefaultConfig()
+	cfg.Services = map[string]config.ServiceOverride{"pinned-svc": {Pin: "9.9.9"}}
+	graph, err := reg.Resolve(ctx, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	errs := CheckPins(graph)
+	var pinErr *PinError
+	if len(errs) != 1 || !errors.As(errs[0], &pinErr) || pinErr.Service != "pinned-svc" {
+		t.Errorf("CheckPins() = %v, want the pinned-svc pin error", errs)
+	}
+}
+
+func TestGetServiceAtRequiresGitSource(t *testing.T) {
+	reg := newTestRegistry(t)
+	_, _, _, err := reg.GetServiceAt(context.Background(), "traefik", "1.0.0")
+	if err == nil || !strings.Contains(err.Error(), "only Git sources can be pinned") {
+		t.Errorf("GetServiceAt() error = %v, want an embedded source error", err)
+	}
+}
@@ -0,0 +1,94 @@
+package registry
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/maiko/sdbx/internal/config"
+)
+
+// SupportsPlatform reports whether the image is published for a platform.
+// A platform without variant (linux/arm) matches any variant and vice versa.
+func (i ImageSpec) SupportsPlatform(platform string) bool {
+	if len(i.Platforms) == 0 {
+		return true
+	}
+	for _, p := range i.Platforms {
+		if p == platform || strings.HasPrefix(p, platform+"/") || strings.HasPrefix(platform, p+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckPlatforms returns an error for every enabled service whose image is not
+// published for the platform it will run on
+func CheckPlatforms(graph *ResolutionGraph, cfg *config.Config) []error {
+	names := make([]string, 0, len(graph.Services))
+	for name := range graph.Services {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var errs []error
+	for _, name := range names {
+		resolved := graph.Services[name]
+		if !resolved.Enabled || resolved.FinalDefinition == nil {
+			continue
+		}
+
+		image := resolved.FinalDefinition.Spec.Image
+		platform := cfg.ServicePlatform(name)
+		if platform == "" {
+			platform = cfg.TargetPlatform()
+		}
+		if image.SupportsPlatform(platform) {
+			continue
+		}
+
+		hint := "set services." + name + ".platform to run it under emulation"
+		if slices.Contains(cfg.Addons, name) {
+			hint = "disable the addon or " + hint
+		}
+		errs = append(errs, fmt.Errorf("%s: image %s has no %s build (available: %s); %s",
+			name, image.Repository, platform, strings.Join(image.Platforms, ", "), hint))
+	}
+	return errs
+}
+
+// CheckRuntime returns an error for every enabled service that needs the
+// Linux host when the stack runs in a desktop VM (Docker Desktop, OrbStack)
+func CheckRuntime(graph *ResolutionGraph, cfg *config.Config) []error {
+	if !cfg.DesktopRuntime() {
+		return nil
+	}
+	names := make([]string, 0, len(graph.Services))
+	for name := range graph.Services {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	runtime := config.RuntimeName(cfg.TargetRuntime())
+	var errs []error
+	for _, name := range names {
+		resolved := graph.Services[name]
+		if !resolved.Enabled || resolved.FinalDefinition == nil {
+			continue
+		}
+
+		spec := resolved.FinalDefinition.Spec
+		hint := "run it on the seedbox"
+		if slices.Contains(cfg.Addons, name) {
+			hint = "disable the addon for local testing"
+		}
+		if spec.Networking.Mode == "host" && !cfg.HostNetworkSupported() {
+			errs = append(errs, fmt.Errorf("%s: host network mode only reaches the %s VM, not this machine; %s", name, runtime, hint))
+		}
+		if spec.Container.GPUEnabled {
+			errs = append(errs, fmt.Errorf("%s: %s cannot pass an NVIDIA GPU to containers; %s", name, runtime, hint))
+		}
+	}
+	return errs
+}
@@ -0,0 +1,113 @@
+package registry
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/maiko/sdbx/internal/config"
+)
+
+func TestImageSupportsPlatform(t *testing.T) {
+	multiArch := ImageSpec{Repository: "traefik"}
+	if !multiArch.SupportsPlatform("linux/riscv64") {
+		t.Error("image without platforms should support every platform")
+	}
+
+	image := ImageSpec{Repository: "x", Platforms: []string{"linux/amd64", "linux/arm/v7"}}
+	tests := []struct {
+		platform string
+		want     bool
+	}{
+		{"linux/amd64", true},
+		{"linux/arm64", false},
+		{"linux/arm/v7", true},
+		{"linux/arm", true},
+		{"linux/arm/v6", false},
+	}
+	for _, tt := range tests {
+		if got := image.SupportsPlatform(tt.platform); got != tt.want {
+			t.Errorf("SupportsPlatform(%q) = %v, want %v", tt.platform, got, tt.want)
+		}
+	}
+}
+
+func TestCheckPlatforms(t *testing.T) {
+	reg := newTestRegistry(t)
+
+	cfg := config.DefaultConfig()
+	cfg.Platform = "linux/arm/v7"
+
+	graph, err := NewResolver(reg).Resolve(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("Resolve() error: %v", err)
+	}
+
+	errs := CheckPlatforms(graph, cfg)
+	if len(errs) == 0 || !strings.Contains(errs[0].Error(), "plex") {
+		t.Fatalf("expected plex to be rejected on linux/arm/v7, got %v", errs)
+	}
+
+	// Forcing an emulated platform the image is published for is allowed
+	cfg.Services = map[string]config.ServiceOverride{
+		"plex":       {Platform: "linux/arm64"},
+		"sdbx-webui": {Platform: "linux/arm64"},
+	}
+	for _, err := range CheckPlatforms(graph, cfg) {
+		if strings.HasPrefix(err.Error(), "plex") || strings.HasPrefix(err.Error(), "sdbx-webui") {
+			t.Errorf("unexpected error with platform override: %v", err)
+		}
+	}
+
+	cfg.Platform = "linux/arm64"
+	cfg.Services = nil
+	if errs := CheckPlatforms(graph, cfg); len(errs) != 0 {
+		t.Errorf("expected all core services to run on linux/arm64, got %v", errs)
+	}
+}
+
+func TestValidatePlatforms(t *testing.T) {
+	def := ExtraServiceDefinition(config.ExtraServiceConfig{Name: "app", Image: "app"})
+	def.Spec.Image.Platforms = []string{"linux/amd64", "arm64"}
+
+	errs := NewValidator().Validate(def)
+	found := false
+	for _, e := range errs {
+		if e.Field == "spec.image.platforms[1]" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("expected error for malformed platform, got %v", errs)
+	}
+}
+
+func TestCheckRuntime(t *testing.T) {
+	graph := &ResolutionGraph{Services: map[string]*ResolvedService{
+		"plex": {Enabled: true, FinalDefinition: &ServiceDefinition{Spec: ServiceSpec{
+			Networking: NetworkSpec{Mode: "host"},
+		}}},
+		"tdarr": {Enabled: true, FinalDefinition: &ServiceDefinition{Spec: ServiceSpec{
+			Container: ContainerSpec{GPUEnabled: true},
+		}}},
+		"sonarr": {Enabled: true, FinalDefinition: &ServiceDefinition{}},
+	}}
+
+	cfg := config.DefaultConfig()
+	cfg.Runtime = config.RuntimeEngine
+	if errs := CheckRuntime(graph, cfg); len(errs) != 0 {
+		t.Errorf("expected no errors on Docker Engine, got %v", errs)
+	}
+
+	cfg.Runtime = config.RuntimeDockerDesktop
+	errs := CheckRuntime(graph, cfg)
+	if len(errs) != 2 || !strings.HasPrefix(errs[0].Error(), "plex: host network") || !strings.HasPrefix(errs[1].Error(), "tdarr") {
+		t.Errorf("expected plex and tdarr to be rejected on Docker Desktop, got %v", errs)
+	}
+
+	// OrbStack forwards host networking to macOS
+	cfg.Runtime = config.RuntimeOrbStack
+	if errs := CheckRuntime(graph, cfg); len(errs) != 1 || !strings.HasPrefix(errs[0].Error(), "tdarr") {
+		t.Errorf("expected only tdarr to be rejected on OrbStack, got %v", errs)
+	}
+}
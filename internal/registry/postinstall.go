@@ -0,0 +1,184 @@
+package registry
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ChecklistFile is the project file recording completed post-install steps
+const ChecklistFile = ".sdbx.checklist.yaml"
+
+// ChecklistItem is one outstanding or completed post-install task. Steps are
+// marked done by the user; secrets are done once their file has content.
+type ChecklistItem struct {
+	ID          string `json:"id"` // <service>/<step id> or <service>/<secret name>
+	Service     string `json:"service"`
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	URL         string `json:"url,omitempty"`
+	Secret      string `json:"secret,omitempty"`
+	Done        bool   `json:"done"`
+}
+
+// ServiceChecklist groups a service's checklist items and links
+type ServiceChecklist struct {
+	Service string            `json:"service"`
+	Items   []ChecklistItem   `json:"items"`
+	Links   []PostInstallLink `json:"links,omitempty"`
+}
+
+// Checklist is the post-install checklist of every resolved service
+type Checklist struct {
+	Services []ServiceChecklist `json:"services"`
+}
+
+// Outstanding returns the items that are not done yet
+func (c *Checklist) Outstanding() []ChecklistItem {
+	var items []ChecklistItem
+	for _, svc := range c.Services {
+		for _, item := range svc.Items {
+			if !item.Done {
+				items = append(items, item)
+			}
+		}
+	}
+	return items
+}
+
+// Find returns the item with the given ID
+func (c *Checklist) Find(id string) (ChecklistItem, bool) {
+	for _, svc := range c.Services {
+		for _, item := range svc.Items {
+			if item.ID == id {
+				return item, true
+			}
+		}
+	}
+	return ChecklistItem{}, false
+}
+
+// checklistState is the on-disk format of ChecklistFile
+type checklistState struct {
+	Completed []string `yaml:"completed"`
+}
+
+// Checklist builds the post-install checklist for the resolved services,
+// reading completion state from projectDir
+func (g *ResolutionGraph) Checklist(projectDir string) (*Checklist, error) {
+	completed, err := loadChecklistState(projectDir)
+	if err != nil {
+		return nil, err
+	}
+
+	checklist := &Checklist{}
+	for _, name := range slices.Sorted(slices.Values(g.Order)) {
+		svc, ok := g.Services[name]
+		if !ok || svc.FinalDefinition == nil || svc.FinalDefinition.PostInstall == nil {
+			continue
+		}
+		def := svc.FinalDefinition
+		post := def.PostInstall
+
+		entry := ServiceChecklist{Service: name, Links: post.Links}
+		for _, secret := range post.Secrets {
+			item := ChecklistItem{
+				ID:      name + "/" + secret,
+				Service: name,
+				Title:   fmt.Sprintf("Set secrets/%s.txt", secret),
+				Secret:  secret,
+				Done:    secretFilled(projectDir, secret),
+			}
+			if i := slices.IndexFunc(def.Secrets, func(s SecretDef) bool { return s.Name == secret }); i >= 0 {
+				item.Description = def.Secrets[i].Description
+			}
+			entry.Items = append(entry.Items, item)
+		}
+		for _, step := range post.Steps {
+			id := name + "/" + step.ID
+			entry.Items = append(entry.Items, ChecklistItem{
+				ID:          id,
+				Service:     name,
+				Title:       step.Title,
+				Description: step.Description,
+				URL:         step.URL,
+				Done:        slices.Contains(completed, id),
+			})
+		}
+		checklist.Services = append(checklist.Services, entry)
+	}
+	return checklist, nil
+}
+
+// secretFilled reports whether a secret file exists and is not blank
+func secretFilled(projectDir, name string) bool {
+	data, err := os.ReadFile(filepath.Join(projectDir, "secrets", name+".txt"))
+	return err == nil && strings.TrimSpace(string(data)) != ""
+}
+
+// loadChecklistState returns the IDs of completed steps
+func loadChecklistState(projectDir string) ([]string, error) {
+	data, err := os.ReadFile(filepath.Join(projectDir, ChecklistFile))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", ChecklistFile, err)
+	}
+
+	var state checklistState
+	if err := yaml.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", ChecklistFile, err)
+	}
+	return state.Completed, nil
+}
+
+// SetChecklistStep records a post-install step as done or not done
+func SetChecklistStep(projectDir, id string, done bool) error {
+	completed, err := loadChecklistState(projectDir)
+	if err != nil {
+		return err
+	}
+
+	completed = slices.DeleteFunc(completed, func(c string) bool { return c == id })
+	if done {
+		completed = append(completed, id)
+	}
+	return saveChecklistState(projectDir, completed)
+}
+
+// ForgetChecklistService drops the recorded steps of a removed service
+func ForgetChecklistService(projectDir, service string) error {
+	completed, err := loadChecklistState(projectDir)
+	if err != nil || len(completed) == 0 {
+		return err
+	}
+
+	remaining := slices.DeleteFunc(slices.Clone(completed), func(c string) bool {
+		return strings.HasPrefix(c, service+"/")
+	})
+	if len(remaining) == len(completed) {
+		return nil
+	}
+	return saveChecklistState(projectDir, remaining)
+}
+
+// saveChecklistState writes the sorted IDs of completed steps
+func saveChecklistState(projectDir string, completed []string) error {
+	slices.Sort(completed)
+
+	data, err := yaml.Marshal(checklistState{Completed: completed})
+	if err != nil {
+		return fmt.Errorf("failed to encode %s: %w", ChecklistFile, err)
+	}
+	if err := os.WriteFile(filepath.Join(projectDir, ChecklistFile), data, 0o644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", ChecklistFile, err)
+	}
+	return nil
+}
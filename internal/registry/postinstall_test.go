@@ -0,0 +1,78 @@
+package registry
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestChecklist(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "secrets"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	plex := &ServiceDefinition{
+		Secrets: []SecretDef{{Name: "plex_claim_token", Type: "manual", Description: "Claim token"}},
+		PostInstall: &PostInstall{
+			Secrets: []string{"plex_claim_token"},
+			Steps:   []PostInstallStep{{ID: "libraries", Title: "Add libraries"}},
+			Links:   []PostInstallLink{{Title: "Claim", URL: "https://www.plex.tv/claim/"}},
+		},
+	}
+	graph := &ResolutionGraph{
+		Services: map[string]*ResolvedService{
+			"plex":    {Name: "plex", FinalDefinition: plex},
+			"traefik": {Name: "traefik", FinalDefinition: &ServiceDefinition{}},
+		},
+		Order: []string{"traefik", "plex"},
+	}
+
+	checklist, err := graph.Checklist(dir)
+	if err != nil {
+		t.Fatalf("Checklist() error = %v", err)
+	}
+	if len(checklist.Services) != 1 || checklist.Services[0].Service != "plex" {
+		t.Fatalf("Checklist() services = %+v, want only plex", checklist.Services)
+	}
+	if got := len(checklist.Outstanding()); got != 2 {
+		t.Errorf("Outstanding() = %d items, want 2", got)
+	}
+	if item, ok := checklist.Find("plex/plex_claim_token"); !ok || item.Description != "Claim token" {
+		t.Errorf("Find(secret) = %+v, %v", item, ok)
+	}
+
+	// Secrets complete when filled in, steps when marked done
+	if err := os.WriteFile(filepath.Join(dir, "secrets", "plex_claim_token.txt"), []byte("claim-abc\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := SetChecklistStep(dir, "plex/libraries", true); err != nil {
+		t.Fatalf("SetChecklistStep() error = %v", err)
+	}
+	checklist, err = graph.Checklist(dir)
+	if err != nil {
+		t.Fatalf("Checklist() error = %v", err)
+	}
+	if outstanding := checklist.Outstanding(); len(outstanding) != 0 {
+		t.Errorf("Outstanding() = %+v, want none", outstanding)
+	}
+
+	if err := SetChecklistStep(dir, "plex/libraries", false); err != nil {
+		t.Fatalf("SetChecklistStep() error = %v", err)
+	}
+	checklist, _ = graph.Checklist(dir)
+	if outstanding := checklist.Outstanding(); len(outstanding) != 1 || outstanding[0].ID != "plex/libraries" {
+		t.Errorf("Outstanding() after undo = %+v, want plex/libraries", outstanding)
+	}
+
+	// Removing the service forgets its steps
+	if err := SetChecklistStep(dir, "plex/libraries", true); err != nil {
+		t.Fatal(err)
+	}
+	if err := ForgetChecklistService(dir, "plex"); err != nil {
+		t.Fatalf("ForgetChecklistService() error = %v", err)
+	}
+	if completed, _ := loadChecklistState(dir); len(completed) != 0 {
+		t.Errorf("completed after ForgetChecklistService() = %v, want none", completed)
+	}
+}
@@ -0,0 +1,209 @@
+package registry
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"path"
+	"slices"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/maiko/sdbx/internal/config"
+)
+
+//go:embed presets/*.yaml
+var embeddedPresets embed.FS
+
+// PresetDefinition bundles addons and settings for a class of host
+type PresetDefinition struct {
+	APIVersion string         `yaml:"apiVersion"`
+	Kind       string         `yaml:"kind"`
+	Metadata   PresetMetadata `yaml:"metadata"`
+	Spec       PresetSpec     `yaml:"spec"`
+}
+
+// PresetMetadata identifies a preset
+type PresetMetadata struct {
+	Name        string `yaml:"name"`
+	Description string `yaml:"description"`
+}
+
+// PresetSpec defines what a preset changes in the configuration
+type PresetSpec struct {
+	Addons            []string                         `yaml:"addons,omitempty"`
+	Disable           []string                         `yaml:"disable,omitempty"` // Heavy addons removed even if already enabled
+	HardwareTranscode bool                             `yaml:"hardware_transcode,omitempty"`
+	Resources         map[string]config.ResourceLimits `yaml:"resources,omitempty"`
+
+	// Routing and Services are mostly found in presets shared by the
+	// community (see FetchPreset)
+	Routing  PresetRouting                     `yaml:"routing,omitempty"`
+	Services map[string]config.ServiceOverride `yaml:"services,omitempty"` // Per-service overrides
+}
+
+// PresetRouting is the routing strategy a preset sets
+type PresetRouting struct {
+	Strategy   string `yaml:"strategy,omitempty"`    // "subdomain" | "path"
+	BaseDomain string `yaml:"base_domain,omitempty"` // Subdomain of path routing
+}
+
+// Preset is a preset definition resolved against the services in the registry
+type Preset struct {
+	Name              string
+	Description       string
+	Addons            []string // Addons available from the configured sources
+	Missing           []string // Addons not found in any source
+	Disable           []string
+	HardwareTranscode bool
+	Resources         map[string]config.ResourceLimits
+	Routing           PresetRouting
+	Services          map[string]config.ServiceOverride
+}
+
+// ListPresets returns the built-in preset definitions sorted by name
+func ListPresets() ([]*PresetDefinition, error) {
+	entries, err := embeddedPresets.ReadDir("presets")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read presets: %w", err)
+	}
+
+	var presets []*PresetDefinition
+	for _, entry := range entries {
+		data, err := embeddedPresets.ReadFile(path.Join("presets", entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read preset %s: %w", entry.Name(), err)
+		}
+		var def PresetDefinition
+		if err := yaml.Unmarshal(data, &def); err != nil {
+			return nil, fmt.Errorf("failed to parse preset %s: %w", entry.Name(), err)
+		}
+		if def.APIVersion != APIVersion || def.Kind != KindPreset {
+			return nil, fmt.Errorf("preset %s: expected %s %s", entry.Name(), APIVersion, KindPreset)
+		}
+		presets = append(presets, &def)
+	}
+
+	sort.Slice(presets, func(i, j int) bool {
+		return presets[i].Metadata.Name < presets[j].Metadata.Name
+	})
+	return presets, nil
+}
+
+// PresetNames returns the names of the built-in presets
+func PresetNames() []string {
+	presets, err := ListPresets()
+	if err != nil {
+		return nil
+	}
+	names := make([]string, 0, len(presets))
+	for _, p := range presets {
+		names = append(names, p.Metadata.Name)
+	}
+	return names
+}
+
+// ResolvePreset looks up a preset and keeps only the addons that a configured
+// source provides
+func (r *Registry) ResolvePreset(ctx context.Context, name string) (*Preset, error) {
+	presets, err := ListPresets()
+	if err != nil {
+		return nil, err
+	}
+
+	var def *PresetDefinition
+	for _, p := range presets {
+		if p.Metadata.Name == name {
+			def = p
+		}
+	}
+	if def == nil {
+		return nil, fmt.Errorf("unknown preset %q (available: %s)", name, strings.Join(PresetNames(), ", "))
+	}
+	return r.ResolvePresetDefinition(ctx, def)
+}
+
+// ResolvePresetDefinition keeps only the addons of a preset definition that
+// a configured source provides
+func (r *Registry) ResolvePresetDefinition(ctx context.Context, def *PresetDefinition) (*Preset, error) {
+	services, err := r.ListServices(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list services: %w", err)
+	}
+	addons := make(map[string]bool)
+	for _, svc := range services {
+		if svc.IsAddon {
+			addons[svc.Name] = true
+		}
+	}
+
+	preset := def.preset()
+	preset.Addons = nil
+	for _, addon := range def.Spec.Addons {
+		if addons[addon] {
+			preset.Addons = append(preset.Addons, addon)
+		} else {
+			preset.Missing = append(preset.Missing, addon)
+		}
+	}
+	return preset, nil
+}
+
+// preset returns the unresolved preset of a definition, with all its addons
+func (d *PresetDefinition) preset() *Preset {
+	return &Preset{
+		Name:              d.Metadata.Name,
+		Description:       d.Metadata.Description,
+		Addons:            d.Spec.Addons,
+		Disable:           d.Spec.Disable,
+		HardwareTranscode: d.Spec.HardwareTranscode,
+		Resources:         d.Spec.Resources,
+		Routing:           d.Spec.Routing,
+		Services:          d.Spec.Services,
+	}
+}
+
+// Apply merges the preset into a configuration. Existing addons are kept
+// unless the preset disables them; existing service overrides and resource
+// limits are not replaced.
+func (p *Preset) Apply(cfg *config.Config) {
+	addons := slices.Clone(cfg.Addons)
+	for _, addon := range p.Addons {
+		if !slices.Contains(addons, addon) {
+			addons = append(addons, addon)
+		}
+	}
+	cfg.Addons = slices.DeleteFunc(addons, func(addon string) bool {
+		return slices.Contains(p.Disable, addon)
+	})
+
+	cfg.HardwareTranscode = p.HardwareTranscode
+	if p.Routing.Strategy != "" {
+		cfg.Routing.Strategy = p.Routing.Strategy
+	}
+	if p.Routing.BaseDomain != "" {
+		cfg.Routing.BaseDomain = p.Routing.BaseDomain
+	}
+
+	for name, override := range p.Services {
+		if cfg.Services == nil {
+			cfg.Services = make(map[string]config.ServiceOverride)
+		}
+		if _, ok := cfg.Services[name]; !ok {
+			cfg.Services[name] = override
+		}
+	}
+	for name, limits := range p.Resources {
+		if cfg.Services == nil {
+			cfg.Services = make(map[string]config.ServiceOverride)
+		}
+		override := cfg.Services[name]
+		if override.Resources != nil {
+			continue
+		}
+		override.Resources = &limits
+		cfg.Services[name] = override
+	}
+}
@@ -0,0 +1,195 @@
+package registry
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/maiko/sdbx/internal/config"
+	"github.com/maiko/sdbx/internal/netproxy"
+	"github.com/maiko/sdbx/internal/problem"
+)
+
+const (
+	maxPresetSize       = 1 << 20
+	presetDownloadLimit = 30 * time.Second
+
+	// PresetChecksumSuffix is appended to the URL of a shared preset to find
+	// its checksum when none is given ("<sha256>  <name>", as sha256sum)
+	PresetChecksumSuffix = ".sha256"
+)
+
+// RemotePreset is a preset definition downloaded from a URL
+type RemotePreset struct {
+	Definition *PresetDefinition
+	URL        string
+	SHA256     string // Checksum of the downloaded file, verified
+
+	// Pinned is set when the checksum was given rather than read from the
+	// checksum file next to the preset. That file comes from the same
+	// server: it proves the download is intact, not who published it.
+	Pinned bool
+}
+
+// ErrPresetChecksumMissing is returned by FetchPreset when neither a
+// checksum nor a checksum file next to the preset is available
+var ErrPresetChecksumMissing = errors.New("preset has no checksum")
+
+// FetchPreset downloads a preset shared at a URL and verifies it against
+// checksum ("sha256:<hex>" or "<hex>"), or when empty against the checksum
+// file at the URL plus PresetChecksumSuffix. Without either, it fails with
+// ErrPresetChecksumMissing and the checksum of the downloaded file, so it
+// can be reviewed and passed explicitly. The preset must apply to a default
+// configuration without validation errors. Downloads need HTTPS, except on
+// the loopback interface, and go through proxy (see netproxy.Func).
+func FetchPreset(ctx context.Context, rawURL, checksum, proxy string) (*RemotePreset, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("invalid preset URL %q", rawURL)
+	}
+	if u.Scheme != "https" && (u.Scheme != "http" || !isLoopbackHost(u.Hostname())) {
+		return nil, fmt.Errorf("preset URL %s must use https", rawURL)
+	}
+	want := strings.ToLower(strings.TrimPrefix(strings.TrimSpace(checksum), "sha256:"))
+	if want != "" {
+		if _, err := hex.DecodeString(want); err != nil || len(want) != sha256.Size*2 {
+			return nil, fmt.Errorf("invalid checksum %q (sha256:<64 hex digits>)", checksum)
+		}
+	}
+	if err := RequireNetwork("download of preset " + rawURL); err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, presetDownloadLimit)
+	defer cancel()
+
+	client := netproxy.Client(proxy, nil, 0)
+	data, err := downloadPreset(ctx, client, rawURL)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(data)
+	got := hex.EncodeToString(sum[:])
+
+	if want == "" {
+		want, err = fetchPresetChecksum(ctx, client, u)
+		if err != nil {
+			return nil, err
+		}
+		if want == "" {
+			return nil, fmt.Errorf("%w: review %s and pass --preset-checksum sha256:%s", ErrPresetChecksumMissing, rawURL, got)
+		}
+	}
+	if got != want {
+		return nil, fmt.Errorf("preset checksum mismatch: got sha256:%s, want sha256:%s", got, want)
+	}
+
+	def, err := parseRemotePreset(data)
+	if err != nil {
+		return nil, fmt.Errorf("preset %s: %w", rawURL, err)
+	}
+	return &RemotePreset{Definition: def, URL: rawURL, SHA256: got, Pinned: checksum != ""}, nil
+}
+
+// parseRemotePreset parses a shared preset and checks that it applies to a
+// default configuration
+func parseRemotePreset(data []byte) (*PresetDefinition, error) {
+	var def PresetDefinition
+	if err := yaml.Unmarshal(data, &def); err != nil {
+		return nil, fmt.Errorf("failed to parse: %w", err)
+	}
+	if def.APIVersion != APIVersion || def.Kind != KindPreset {
+		return nil, fmt.Errorf("expected %s %s, got %s %s", APIVersion, KindPreset, def.APIVersion, def.Kind)
+	}
+	if def.Metadata.Name == "" {
+		return nil, errors.New("metadata.name is required")
+	}
+	cfg := config.DefaultConfig()
+	def.preset().Apply(cfg)
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return &def, nil
+}
+
+// downloadPreset fetches a preset file into memory
+func downloadPreset(ctx context.Context, client *http.Client, rawURL string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, problem.Wrap(problem.ErrSourceUnreachable, err, "failed to download %s", rawURL)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, problem.Wrap(problem.ErrSourceUnreachable, errors.New(resp.Status), "failed to download %s", rawURL)
+	}
+	return readPresetBody(resp, rawURL)
+}
+
+// fetchPresetChecksum reads the checksum file next to a preset, returning
+// "" when there is none
+func fetchPresetChecksum(ctx context.Context, client *http.Client, u *url.URL) (string, error) {
+	sumURL := *u
+	sumURL.Path += PresetChecksumSuffix
+	sumURL.RawPath = ""
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, sumURL.String(), nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", problem.Wrap(problem.ErrSourceUnreachable, err, "failed to download %s", sumURL.String())
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return "", nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", problem.Wrap(problem.ErrSourceUnreachable, errors.New(resp.Status), "failed to download %s", sumURL.String())
+	}
+	data, err := readPresetBody(resp, sumURL.String())
+	if err != nil {
+		return "", err
+	}
+	fields := strings.Fields(string(data))
+	if len(fields) == 0 || len(fields[0]) != sha256.Size*2 {
+		return "", fmt.Errorf("%s is not a sha256sum checksum file", sumURL.String())
+	}
+	if _, err := hex.DecodeString(fields[0]); err != nil {
+		return "", fmt.Errorf("%s is not a sha256sum checksum file", sumURL.String())
+	}
+	return strings.ToLower(fields[0]), nil
+}
+
+// readPresetBody reads a response body of at most maxPresetSize
+func readPresetBody(resp *http.Response, rawURL string) ([]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxPresetSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s: %w", rawURL, err)
+	}
+	if len(data) > maxPresetSize {
+		return nil, fmt.Errorf("%s is larger than %d MB", rawURL, maxPresetSize>>20)
+	}
+	return data, nil
+}
+
+// isLoopbackHost reports whether a URL host is localhost or a loopback address
+func isLoopbackHost(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
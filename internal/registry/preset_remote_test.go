@@ -0,0 +1,164 @@
+package registry
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/maiko/sdbx/internal/config"
+)
+
+const sharedPreset = `apiVersion: sdbx.one/v1
+kind: Preset
+metadata:
+  name: anime-box
+  description: Sonarr tuned for anime, path routing
+spec:
+  addons:
+    - sonarr
+  routing:
+    strategy: path
+    base_domain: media
+  services:
+    sonarr:
+      subdomain: anime
+      update_policy: notify-only
+`
+
+func presetChecksum(data string) string {
+	sum := sha256.Sum256([]byte(data))
+	return hex.EncodeToString(sum[:])
+}
+
+// servePreset serves a preset at /preset.yaml, and its checksum file when
+// sumFile is not empty
+func servePreset(t *testing.T, preset, sumFile string) string {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/preset.yaml":
+			_, _ = w.Write([]byte(preset))
+		case r.URL.Path == "/preset.yaml.sha256" && sumFile != "":
+			_, _ = w.Write([]byte(sumFile))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server.URL + "/preset.yaml"
+}
+
+func TestFetchPreset(t *testing.T) {
+	ctx := context.Background()
+	sum := presetChecksum(sharedPreset)
+
+	t.Run("explicit checksum", func(t *testing.T) {
+		url := servePreset(t, sharedPreset, "")
+		remote, err := FetchPreset(ctx, url, "sha256:"+sum, "")
+		if err != nil {
+			t.Fatalf("FetchPreset() error: %v", err)
+		}
+		if remote.SHA256 != sum || !remote.Pinned || remote.Definition.Metadata.Name != "anime-box" {
+			t.Errorf("FetchPreset() = %+v", remote)
+		}
+		if remote.Definition.Spec.Routing.Strategy != "path" || remote.Definition.Spec.Services["sonarr"].Subdomain != "anime" {
+			t.Errorf("spec = %+v", remote.Definition.Spec)
+		}
+	})
+
+	t.Run("checksum file", func(t *testing.T) {
+		url := servePreset(t, sharedPreset, sum+"  preset.yaml\n")
+		remote, err := FetchPreset(ctx, url, "", "")
+		if err != nil {
+			t.Fatalf("FetchPreset() error: %v", err)
+		}
+		// The checksum file is served with the preset and proves no publisher
+		if remote.Pinned {
+			t.Error("a checksum read from the checksum file should not pin the preset")
+		}
+	})
+
+	t.Run("no checksum", func(t *testing.T) {
+		url := servePreset(t, sharedPreset, "")
+		_, err := FetchPreset(ctx, url, "", "")
+		if !errors.Is(err, ErrPresetChecksumMissing) {
+			t.Fatalf("FetchPreset() error = %v, want ErrPresetChecksumMissing", err)
+		}
+		if !strings.Contains(err.Error(), sum) {
+			t.Errorf("error %q does not give the checksum of the preset", err)
+		}
+	})
+
+	t.Run("checksum mismatch", func(t *testing.T) {
+		url := servePreset(t, sharedPreset, presetChecksum("other")+"  preset.yaml\n")
+		if _, err := FetchPreset(ctx, url, "", ""); err == nil || !strings.Contains(err.Error(), "mismatch") {
+			t.Errorf("FetchPreset() error = %v, want checksum mismatch", err)
+		}
+	})
+
+	t.Run("invalid checksum", func(t *testing.T) {
+		url := servePreset(t, sharedPreset, "")
+		if _, err := FetchPreset(ctx, url, "sha256:abc", ""); err == nil {
+			t.Error("FetchPreset() with a truncated checksum should fail")
+		}
+	})
+
+	t.Run("wrong kind", func(t *testing.T) {
+		service := strings.Replace(sharedPreset, "kind: Preset", "kind: Service", 1)
+		url := servePreset(t, service, "")
+		if _, err := FetchPreset(ctx, url, presetChecksum(service), ""); err == nil {
+			t.Error("FetchPreset() of a service definition should fail")
+		}
+	})
+
+	t.Run("invalid configuration", func(t *testing.T) {
+		invalid := strings.Replace(sharedPreset, "strategy: path", "strategy: dns", 1)
+		url := servePreset(t, invalid, "")
+		if _, err := FetchPreset(ctx, url, presetChecksum(invalid), ""); err == nil {
+			t.Error("FetchPreset() of a preset with an invalid routing strategy should fail")
+		}
+	})
+
+	t.Run("plain http", func(t *testing.T) {
+		if _, err := FetchPreset(ctx, "http://example.com/preset.yaml", sum, ""); err == nil || !strings.Contains(err.Error(), "https") {
+			t.Errorf("FetchPreset() over http error = %v, want https required", err)
+		}
+	})
+
+	t.Run("offline", func(t *testing.T) {
+		SetOffline(true)
+		defer SetOffline(false)
+		if _, err := FetchPreset(ctx, "https://example.com/preset.yaml", sum, ""); err == nil {
+			t.Error("FetchPreset() in offline mode should fail")
+		}
+	})
+}
+
+func TestPresetApplyOverrides(t *testing.T) {
+	def, err := parseRemotePreset([]byte(sharedPreset))
+	if err != nil {
+		t.Fatalf("parseRemotePreset() error: %v", err)
+	}
+
+	cfg := config.DefaultConfig()
+	cfg.Services = map[string]config.ServiceOverride{"sonarr": {Subdomain: "tv"}}
+	def.preset().Apply(cfg)
+
+	if cfg.Routing.Strategy != "path" || cfg.Routing.BaseDomain != "media" {
+		t.Errorf("routing = %+v, want path on media", cfg.Routing)
+	}
+	if cfg.Services["sonarr"].Subdomain != "tv" {
+		t.Errorf("existing override of sonarr replaced: %+v", cfg.Services["sonarr"])
+	}
+
+	cfg = config.DefaultConfig()
+	def.preset().Apply(cfg)
+	if got := cfg.Services["sonarr"]; got.Subdomain != "anime" || got.UpdatePolicy != "notify-only" {
+		t.Errorf("sonarr override = %+v", got)
+	}
+}
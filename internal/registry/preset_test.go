@@ -0,0 +1,123 @@
+package registry
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/maiko/sdbx/internal/config"
+)
+
+func TestListPresets(t *testing.T) {
+	presets, err := ListPresets()
+	if err != nil {
+		t.Fatalf("ListPresets() error: %v", err)
+	}
+
+	names := PresetNames()
+	for _, want := range []string{"dedicated", "nas", "raspberry-pi"} {
+		if !slices.Contains(names, want) {
+			t.Errorf("missing preset %q in %v", want, names)
+		}
+	}
+
+	cfg := config.DefaultConfig()
+	for _, p := range presets {
+		if p.Metadata.Description == "" {
+			t.Errorf("preset %s has no description", p.Metadata.Name)
+		}
+		for _, addon := range p.Spec.Addons {
+			if slices.Contains(p.Spec.Disable, addon) {
+				t.Errorf("preset %s both enables and disables %s", p.Metadata.Name, addon)
+			}
+		}
+		// Resource limits must pass config validation
+		cfg.Services = make(map[string]config.ServiceOverride)
+		for name, limits := range p.Spec.Resources {
+			cfg.Services[name] = config.ServiceOverride{Resources: &limits}
+		}
+		if err := cfg.Validate(); err != nil {
+			t.Errorf("preset %s has invalid resources: %v", p.Metadata.Name, err)
+		}
+	}
+}
+
+func TestResolvePreset(t *testing.T) {
+	tmpDir := t.TempDir()
+	for _, name := range []string{"sonarr", "radarr"} {
+		dir := filepath.Join(tmpDir, "addons", name)
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		yaml := fmt.Sprintf(`apiVersion: sdbx.one/v1
+kind: Service
+metadata:
+  name: %[1]s
+  version: 1.0.0
+  category: media
+  description: Test addon
+spec:
+  image:
+    repository: test/%[1]s
+    tag: latest
+  container:
+    name_template: "sdbx-%[1]s"
+conditions:
+  requireAddon: true
+`, name)
+		if err := os.WriteFile(filepath.Join(dir, "service.yaml"), []byte(yaml), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	reg := newTestRegistryWithLocal(t, tmpDir)
+
+	preset, err := reg.ResolvePreset(context.Background(), "raspberry-pi")
+	if err != nil {
+		t.Fatalf("ResolvePreset() error: %v", err)
+	}
+	if !slices.Equal(preset.Addons, []string{"sonarr", "radarr"}) {
+		t.Errorf("Addons = %v, want [sonarr radarr]", preset.Addons)
+	}
+	if !slices.Equal(preset.Missing, []string{"prowlarr"}) {
+		t.Errorf("Missing = %v, want [prowlarr]", preset.Missing)
+	}
+
+	if _, err := reg.ResolvePreset(context.Background(), "mainframe"); err == nil {
+		t.Error("expected error for unknown preset")
+	}
+}
+
+func TestPresetApply(t *testing.T) {
+	preset := &Preset{
+		Addons:            []string{"sonarr", "radarr"},
+		Disable:           []string{"flaresolverr"},
+		HardwareTranscode: true,
+		Resources: map[string]config.ResourceLimits{
+			"plex":        {Memory: "1g"},
+			"qbittorrent": {CPUs: "1.0", Memory: "512m"},
+		},
+	}
+
+	cfg := config.DefaultConfig()
+	cfg.Addons = []string{"flaresolverr", "sonarr", "bazarr"}
+	cfg.Services = map[string]config.ServiceOverride{
+		"plex": {Resources: &config.ResourceLimits{Memory: "4g"}},
+	}
+	preset.Apply(cfg)
+
+	if !slices.Equal(cfg.Addons, []string{"sonarr", "bazarr", "radarr"}) {
+		t.Errorf("Addons = %v", cfg.Addons)
+	}
+	if !cfg.HardwareTranscode {
+		t.Error("expected hardware transcoding to be enabled")
+	}
+	if cfg.Services["plex"].Resources.Memory != "4g" {
+		t.Error("existing resource limits must not be replaced")
+	}
+	if r := cfg.Services["qbittorrent"].Resources; r == nil || r.CPUs != "1.0" {
+		t.Errorf("qbittorrent resources = %+v", r)
+	}
+}
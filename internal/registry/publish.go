@@ -0,0 +1,334 @@
+package registry
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/maiko/sdbx/internal/netproxy"
+)
+
+// Publication is a service definition committed to a git source by Publish
+type Publication struct {
+	Service  string `json:"service"`
+	Source   string `json:"source"`
+	Version  string `json:"version"`
+	Previous string `json:"previous,omitempty"` // Version the source had, if any
+	Dir      string `json:"dir"`                // Service directory, relative to the repository
+	Repo     string `json:"repo"`               // Path of the source's checkout
+	Branch   string `json:"branch"`
+	Commit   string `json:"commit"`
+	Pushed   bool   `json:"pushed"`
+}
+
+// Publish copies the service directory dir into the checkout of the source
+// with its version bumped past the source's, and commits it on a new branch,
+// pushed to the source's remote with push. The checkout is switched back
+// afterwards so the source keeps following its branch or ref.
+func (s *GitSource) Publish(ctx context.Context, dir string, push bool) (*Publication, error) {
+	def, err := s.loader.LoadServiceDefinition(filepath.Join(dir, "service.yaml"))
+	if err != nil {
+		return nil, err
+	}
+	name := def.Metadata.Name
+	if err := s.ensureCloned(ctx); err != nil {
+		return nil, err
+	}
+	repoPath := s.cache.GetRepoPath(s.name)
+
+	// Replace the definition where the source keeps it, or add it with the
+	// others of its kind
+	pub := &Publication{Service: name, Source: s.name, Repo: repoPath}
+	for _, candidate := range s.serviceDirs(name) {
+		existing, err := s.loader.LoadServiceDefinition(filepath.Join(repoPath, filepath.FromSlash(candidate), "service.yaml"))
+		if err == nil {
+			pub.Dir, pub.Previous = candidate, existing.Metadata.Version
+			break
+		}
+	}
+	if pub.Dir == "" {
+		kind := "core"
+		if def.Conditions.RequireAddon {
+			kind = "addons"
+		}
+		pub.Dir = path.Join(path.Dir(s.serviceDirs(name)[0]), kind, name)
+	}
+	if pub.Version, err = nextVersion(def.Metadata.Version, pub.Previous); err != nil {
+		return nil, err
+	}
+	pub.Branch = fmt.Sprintf("sdbx/%s-%s", name, pub.Version)
+
+	head, err := s.gitOutput(ctx, repoPath, "rev-parse", "--abbrev-ref", "HEAD")
+	if err == nil && head == "HEAD" {
+		head, err = s.gitOutput(ctx, repoPath, "rev-parse", "HEAD")
+	}
+	if err != nil {
+		return nil, err
+	}
+	git := func(args ...string) error {
+		if output, err := s.gitCommand(ctx, repoPath, args...).CombinedOutput(); err != nil {
+			return fmt.Errorf("git %s failed: %w", args[0], gitError(output, err))
+		}
+		return nil
+	}
+	restore := func() {
+		_ = git("checkout", "--quiet", "--force", head)
+	}
+
+	if err := git("checkout", "--quiet", "-B", pub.Branch); err != nil {
+		return nil, err
+	}
+	if err := s.commitPublication(ctx, dir, pub, git); err != nil {
+		restore()
+		_ = git("clean", "-fdq", "--", pub.Dir)
+		_ = git("branch", "-D", pub.Branch)
+		return nil, err
+	}
+	if pub.Commit, err = s.gitOutput(ctx, repoPath, "rev-parse", "HEAD"); err != nil {
+		restore()
+		return nil, err
+	}
+
+	if push {
+		err := RequireNetwork("git push to " + s.name)
+		if err == nil {
+			err = git("push", "--quiet", "origin", pub.Branch)
+		}
+		if err != nil {
+			restore()
+			return pub, fmt.Errorf("committed on branch %s of %s but the push failed: %w", pub.Branch, repoPath, err)
+		}
+		pub.Pushed = true
+	}
+	restore()
+	return pub, nil
+}
+
+// commitPublication copies the definition into the checkout on the
+// publication branch, sets its version and commits it
+func (s *GitSource) commitPublication(ctx context.Context, dir string, pub *Publication, git func(...string) error) error {
+	target := filepath.Join(pub.Repo, filepath.FromSlash(pub.Dir))
+	if err := os.RemoveAll(target); err != nil {
+		return err
+	}
+	if err := copyServiceDir(dir, target); err != nil {
+		return fmt.Errorf("failed to copy %s: %w", dir, err)
+	}
+
+	defPath := filepath.Join(target, "service.yaml")
+	data, err := os.ReadFile(defPath)
+	if err != nil {
+		return err
+	}
+	if data, err = setDefinitionVersion(data, pub.Version); err != nil {
+		return err
+	}
+	if err := os.WriteFile(defPath, data, 0o644); err != nil {
+		return err
+	}
+	if _, err := s.loader.ParseServiceDefinition(data); err != nil {
+		return err
+	}
+
+	message := fmt.Sprintf("Add %s %s", pub.Service, pub.Version)
+	if pub.Previous != "" {
+		message = fmt.Sprintf("Update %s to %s", pub.Service, pub.Version)
+	}
+	if err := git("add", "--all", "--", pub.Dir); err != nil {
+		return err
+	}
+	if err := git("diff", "--cached", "--quiet"); err == nil {
+		return fmt.Errorf("%s in source %s is already identical", pub.Service, s.name)
+	}
+	return git("commit", "--quiet", "-m", message)
+}
+
+// copyServiceDir copies a service directory, leaving hidden files out
+func copyServiceDir(src, dst string) error {
+	return filepath.WalkDir(src, func(file string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, file)
+		if err != nil {
+			return err
+		}
+		if rel != "." && strings.HasPrefix(d.Name(), ".") {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() {
+			return os.MkdirAll(filepath.Join(dst, rel), 0o755)
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(filepath.Join(dst, rel), data, 0o644)
+	})
+}
+
+// nextVersion returns the version to publish: the local one when it is newer
+// than the source's, otherwise the source's with its patch number bumped
+func nextVersion(local, published string) (string, error) {
+	if published == "" {
+		return local, nil
+	}
+	cmp, err := CompareVersions(local, published)
+	if err != nil {
+		return "", err
+	}
+	if cmp > 0 {
+		return local, nil
+	}
+	parts, _, err := parseVersion(published)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%d.%d.%d", parts[0], parts[1], parts[2]+1), nil
+}
+
+// setDefinitionVersion rewrites metadata.version of a service.yaml, keeping
+// the rest of the file as written
+func setDefinitionVersion(data []byte, version string) ([]byte, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	var node *yaml.Node
+	if len(doc.Content) > 0 {
+		node = mappingValue(mappingValue(doc.Content[0], "metadata"), "version")
+	}
+	if node == nil || node.Kind != yaml.ScalarNode {
+		return nil, errors.New("service.yaml has no metadata.version")
+	}
+
+	lines := bytes.SplitAfter(data, []byte("\n"))
+	line := lines[node.Line-1]
+	start, end := node.Column-1, node.Column-1+len(node.Value)
+	if node.Style&(yaml.DoubleQuotedStyle|yaml.SingleQuotedStyle) != 0 {
+		start, end = start+1, end+1
+	}
+	if end > len(line) || string(line[start:end]) != node.Value {
+		return nil, errors.New("cannot rewrite metadata.version of service.yaml")
+	}
+	lines[node.Line-1] = append(append(append([]byte{}, line[:start]...), version...), line[end:]...)
+	return bytes.Join(lines, nil), nil
+}
+
+// mappingValue returns the value of a key of a mapping node
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// githubAPI is the GitHub REST API, replaced in tests
+var githubAPI = "https://api.github.com"
+
+// githubRepoRegex matches the owner and name of a GitHub repository URL
+var githubRepoRegex = regexp.MustCompile(`^(?:https://github\.com/|git@github\.com:|ssh://git@github\.com/)([\w.-]+)/([\w.-]+?)(?:\.git)?/?$`)
+
+// OpenPullRequest opens a pull request for a branch pushed by Publish. A
+// fork's branch is proposed to the repository it was forked from, other
+// branches to the source's own branch. It returns the pull request's URL.
+func (s *GitSource) OpenPullRequest(ctx context.Context, pub *Publication, token string) (string, error) {
+	m := githubRepoRegex.FindStringSubmatch(s.url)
+	if m == nil {
+		return "", fmt.Errorf("source %s is not a GitHub repository (%s)", s.name, s.url)
+	}
+	if err := RequireNetwork("opening a pull request"); err != nil {
+		return "", err
+	}
+	client := netproxy.Client(s.proxy, nil, 30*time.Second)
+	call := func(method, endpoint string, body, out any) error {
+		var payload io.Reader
+		if body != nil {
+			data, err := json.Marshal(body)
+			if err != nil {
+				return err
+			}
+			payload = bytes.NewReader(data)
+		}
+		req, err := http.NewRequestWithContext(ctx, method, githubAPI+endpoint, payload)
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Accept", "application/vnd.github+json")
+		req.Header.Set("Authorization", "Bearer "+token)
+		resp, err := client.Do(req)
+		if err != nil {
+			return fmt.Errorf("GitHub API request failed: %w", err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode/100 != 2 {
+			var apiErr struct {
+				Message string `json:"message"`
+			}
+			_ = json.NewDecoder(resp.Body).Decode(&apiErr)
+			return fmt.Errorf("GitHub API %s %s: %s: %s", method, endpoint, resp.Status, apiErr.Message)
+		}
+		return json.NewDecoder(resp.Body).Decode(out)
+	}
+
+	owner, repo := m[1], m[2]
+	var info struct {
+		DefaultBranch string `json:"default_branch"`
+		Parent        *struct {
+			FullName      string `json:"full_name"`
+			DefaultBranch string `json:"default_branch"`
+		} `json:"parent"`
+	}
+	if err := call(http.MethodGet, fmt.Sprintf("/repos/%s/%s", owner, repo), nil, &info); err != nil {
+		return "", err
+	}
+	base, baseBranch := owner+"/"+repo, s.branch
+	if info.Parent != nil {
+		base, baseBranch = info.Parent.FullName, info.Parent.DefaultBranch
+	}
+	if baseBranch == "" {
+		baseBranch = info.DefaultBranch
+	}
+
+	title := fmt.Sprintf("Add %s %s", pub.Service, pub.Version)
+	if pub.Previous != "" {
+		title = fmt.Sprintf("Update %s to %s", pub.Service, pub.Version)
+	}
+	var pr struct {
+		HTMLURL string `json:"html_url"`
+	}
+	request := map[string]string{
+		"title": title,
+		"head":  owner + ":" + pub.Branch,
+		"base":  baseBranch,
+		"body":  fmt.Sprintf("Published with `sdbx service publish %s` from a validated local definition.", pub.Service),
+	}
+	if err := call(http.MethodPost, "/repos/"+base+"/pulls", request, &pr); err != nil {
+		return "", err
+	}
+	return pr.HTMLURL, nil
+}
@@ -0,0 +1,157 @@
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGitSourcePublish(t *testing.T) {
+	// Commits in the cache clone need an identity
+	t.Setenv("GIT_AUTHOR_NAME", "Test")
+	t.Setenv("GIT_AUTHOR_EMAIL", "test@test.com")
+	t.Setenv("GIT_COMMITTER_NAME", "Test")
+	t.Setenv("GIT_COMMITTER_EMAIL", "test@test.com")
+
+	remoteDir := t.TempDir()
+	initTestGitRepo(t, remoteDir, map[string]string{
+		"core/pinned-svc/service.yaml": pinTestDefinition("1.0.0"),
+	})
+	gs := NewGitSource(Source{Name: "fork", Type: "git", URL: remoteDir, Branch: "master", Enabled: true}, NewCache(t.TempDir()))
+
+	local := t.TempDir()
+	definition := strings.Replace(pinTestDefinition("1.0.0"), "Pinned service", "Pinned service, improved", 1)
+	for file, content := range map[string]string{
+		"service.yaml":        definition,
+		"tests/default.yaml":  "compose: {}\n",
+		".notes/scratch.yaml": "not published\n",
+	} {
+		if err := os.MkdirAll(filepath.Dir(filepath.Join(local, file)), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(local, file), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	pub, err := gs.Publish(context.Background(), local, true)
+	if err != nil {
+		t.Fatalf("Publish() error: %v", err)
+	}
+	if pub.Version != "1.0.1" || pub.Previous != "1.0.0" || pub.Dir != "core/pinned-svc" || pub.Branch != "sdbx/pinned-svc-1.0.1" || !pub.Pushed {
+		t.Errorf("Publish() = %+v", pub)
+	}
+
+	show := func(ref string) string {
+		t.Helper()
+		output, err := exec.Command("git", "-C", remoteDir, "show", ref).CombinedOutput()
+		if err != nil {
+			t.Fatalf("git show %s: %s", ref, output)
+		}
+		return string(output)
+	}
+	if def := show(pub.Branch + ":core/pinned-svc/service.yaml"); !strings.Contains(def, "version: 1.0.1\n") || !strings.Contains(def, "improved") {
+		t.Errorf("published service.yaml:\n%s", def)
+	}
+	if files := show(pub.Branch + ":core/pinned-svc"); !strings.Contains(files, "tests/") || strings.Contains(files, ".notes") {
+		t.Errorf("published files:\n%s", files)
+	}
+
+	// The checkout follows its branch again
+	repo := gs.cache.GetRepoPath("fork")
+	if head, _ := gs.gitOutput(context.Background(), repo, "rev-parse", "--abbrev-ref", "HEAD"); head != "master" {
+		t.Errorf("checkout left on %q", head)
+	}
+	if def, _ := os.ReadFile(filepath.Join(repo, "core/pinned-svc/service.yaml")); !strings.Contains(string(def), "version: 1.0.0") {
+		t.Errorf("checkout changed:\n%s", def)
+	}
+
+	// Addons the source does not have yet go with the other addons
+	addon := strings.Replace(strings.ReplaceAll(definition, "pinned-svc", "new-svc"), "always: true", "requireAddon: true", 1)
+	if err := os.WriteFile(filepath.Join(local, "service.yaml"), []byte(addon), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	pub, err = gs.Publish(context.Background(), local, false)
+	if err != nil {
+		t.Fatalf("Publish() error: %v", err)
+	}
+	if pub.Version != "1.0.0" || pub.Previous != "" || pub.Dir != "addons/new-svc" || pub.Pushed {
+		t.Errorf("Publish() = %+v", pub)
+	}
+}
+
+func TestNextVersion(t *testing.T) {
+	tests := []struct{ local, published, want string }{
+		{"1.0.0", "", "1.0.0"},
+		{"1.0.0", "1.0.0", "1.0.1"},
+		{"1.2.0", "1.1.4", "1.2.0"},
+		{"1.0.0", "1.3.2", "1.3.3"},
+	}
+	for _, tt := range tests {
+		if got, err := nextVersion(tt.local, tt.published); err != nil || got != tt.want {
+			t.Errorf("nextVersion(%q, %q) = %q, %v; want %q", tt.local, tt.published, got, err, tt.want)
+		}
+	}
+}
+
+func TestSetDefinitionVersion(t *testing.T) {
+	data := "# Sonarr\nmetadata:\n  name: sonarr\n  version: \"1.0.0\" # bumped on publish\nspec:\n  version: 9\n"
+	got, err := setDefinitionVersion([]byte(data), "1.0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := strings.Replace(data, `"1.0.0"`, `"1.0.1"`, 1); string(got) != want {
+		t.Errorf("setDefinitionVersion() =\n%s\nwant\n%s", got, want)
+	}
+	if _, err := setDefinitionVersion([]byte("metadata:\n  name: sonarr\n"), "1.0.1"); err == nil {
+		t.Error("setDefinitionVersion() without a version should fail")
+	}
+}
+
+func TestOpenPullRequest(t *testing.T) {
+	var created map[string]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/repos/me/sdbx-services":
+			_, _ = w.Write([]byte(`{"default_branch":"main","parent":{"full_name":"maiko/SDBX-Services","default_branch":"main"}}`))
+		case r.Method == http.MethodPost && r.URL.Path == "/repos/maiko/SDBX-Services/pulls":
+			_ = json.NewDecoder(r.Body).Decode(&created)
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte(`{"html_url":"https://github.com/maiko/SDBX-Services/pull/7"}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	previous := githubAPI
+	githubAPI = server.URL
+	defer func() { githubAPI = previous }()
+
+	gs := NewGitSource(Source{Name: "fork", URL: "https://github.com/me/sdbx-services.git", Branch: "main"}, NewCache(t.TempDir()))
+	pub := &Publication{Service: "sonarr", Version: "1.0.1", Previous: "1.0.0", Branch: "sdbx/sonarr-1.0.1"}
+	url, err := gs.OpenPullRequest(context.Background(), pub, "secret-token")
+	if err != nil {
+		t.Fatalf("OpenPullRequest() error: %v", err)
+	}
+	if url != "https://github.com/maiko/SDBX-Services/pull/7" {
+		t.Errorf("url = %q", url)
+	}
+	if created["head"] != "me:sdbx/sonarr-1.0.1" || created["base"] != "main" || created["title"] != "Update sonarr to 1.0.1" {
+		t.Errorf("pull request = %v", created)
+	}
+
+	local := NewGitSource(Source{Name: "local", URL: "/srv/services"}, NewCache(t.TempDir()))
+	if _, err := local.OpenPullRequest(context.Background(), pub, "secret-token"); err == nil {
+		t.Error("OpenPullRequest() should fail for a repository not on GitHub")
+	}
+}
@@ -0,0 +1,75 @@
+package registry
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGitSourceRef(t *testing.T) {
+	remoteDir := t.TempDir()
+	initTestGitRepo(t, remoteDir, map[string]string{
+		"addons/sonarr/service.yaml": "version: 1\n",
+	})
+	git := func(args ...string) string {
+		t.Helper()
+		cmd := exec.Command("git", args...)
+		cmd.Dir = remoteDir
+		output, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("git %s failed: %s: %v", args[0], output, err)
+		}
+		return strings.TrimSpace(string(output))
+	}
+	git("tag", "v1.0.0")
+	first := git("rev-parse", "HEAD")
+	if err := os.WriteFile(filepath.Join(remoteDir, "addons/sonarr/service.yaml"), []byte("version: 2\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	git("commit", "-am", "sonarr: v2")
+	head := git("rev-parse", "HEAD")
+
+	cache := NewCache(t.TempDir())
+	ctx := context.Background()
+	definition := func(gs *GitSource) string {
+		t.Helper()
+		data, err := os.ReadFile(gs.GetServicePath("sonarr"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(data)
+	}
+
+	gs := NewGitSource(Source{Name: "test-ref", Type: "git", URL: remoteDir, Branch: "master", Ref: "v1.0.0", Enabled: true}, cache)
+	if err := gs.Update(ctx); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if gs.GetCommit() != first || definition(gs) != "version: 1\n" {
+		t.Errorf("tag checkout: commit = %s, definition = %q", gs.GetCommit(), definition(gs))
+	}
+
+	// Updating keeps the tag rather than following the branch
+	if err := gs.Update(ctx); err != nil || gs.GetCommit() != first {
+		t.Errorf("Update() = %v, commit = %s; want %s", err, gs.GetCommit(), first)
+	}
+
+	isBranch, err := gs.Checkout(ctx, head[:10])
+	if err != nil || isBranch || gs.GetCommit() != head || gs.GetRef() != head[:10] {
+		t.Errorf("Checkout(commit) = %v, %v; commit %s, ref %s", isBranch, err, gs.GetCommit(), gs.GetRef())
+	}
+
+	if _, err := gs.Checkout(ctx, "v9.9.9"); err == nil {
+		t.Error("Checkout() accepted a missing ref")
+	}
+	if gs.GetRef() != head[:10] {
+		t.Errorf("failed Checkout() changed the ref to %q", gs.GetRef())
+	}
+
+	isBranch, err = gs.Checkout(ctx, "master")
+	if err != nil || !isBranch || gs.GetRef() != "" || gs.GetBranch() != "master" || definition(gs) != "version: 2\n" {
+		t.Errorf("Checkout(branch) = %v, %v; ref %q, definition %q", isBranch, err, gs.GetRef(), definition(gs))
+	}
+}
@@ -9,7 +9,6 @@ import (
 	"sort"
 	"strings"
 	"sync"
-	"time"
 
 	"github.com/maiko/sdbx/internal/config"
 )
@@ -28,7 +27,7 @@ type SourceProvider interface {
 	// Name returns the source name
 	Name() string
 
-	// Type returns the source type (local, git)
+	// Type returns the source type (local, git, archive, embedded)
 	Type() string
 
 	// Priority returns the source priority (higher = checked first)
@@ -64,21 +63,18 @@ func New(cfg *SourceConfig) (*Registry, error) {
 	}
 
 	// Initialize cache
-	cacheDir := cfg.Cache.Directory
-	if cacheDir == "" {
-		home, err := os.UserHomeDir()
-		if err != nil {
-			return nil, fmt.Errorf("failed to get home directory: %w", err)
-		}
-		cacheDir = filepath.Join(home, ".cache", "sdbx", "sources")
+	cache, err := NewCacheFromConfig(cfg.Cache)
+	if err != nil {
+		return nil, err
 	}
-	r.cache = NewCache(cacheDir)
+	r.cache = cache
 
 	// Initialize sources
 	for _, src := range cfg.Sources {
 		if !src.Enabled {
 			continue
 		}
+		src.Proxy = cfg.SourceProxy(src)
 
 		provider, err := r.createSourceProvider(src)
 		if err != nil {
@@ -88,7 +84,7 @@ func New(cfg *SourceConfig) (*Registry, error) {
 	}
 
 	// Always add embedded source as a fallback (lowest priority)
-	embeddedSource := NewEmbeddedSource()
+	embeddedSource := newCachedEmbeddedSource(r.cache.Dir())
 	r.sources = append(r.sources, embeddedSource)
 
 	// Sort sources by priority (highest first)
@@ -102,12 +98,22 @@ func New(cfg *SourceConfig) (*Registry, error) {
 	return r, nil
 }
 
-// NewWithDefaults creates a Registry with default configuration
+// NewWithDefaults creates a Registry from the user's sources.yaml, or the
+// default configuration when there is none
 func NewWithDefaults() (*Registry, error) {
-	cfg := DefaultSourceConfig()
+	cfg, err := NewLoader().LoadSourceConfig(SourceConfigPath())
+	if err != nil {
+		cfg = DefaultSourceConfig()
+	}
 	return New(cfg)
 }
 
+// SourceConfigPath returns the path of the user's sources.yaml
+func SourceConfigPath() string {
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".config", "sdbx", "sources.yaml")
+}
+
 // DefaultSourceConfig returns the default source configuration
 func DefaultSourceConfig() *SourceConfig {
 	home, _ := os.UserHomeDir()
@@ -154,8 +160,10 @@ func (r *Registry) createSourceProvider(src Source) (SourceProvider, error) {
 		return NewLocalSource(src), nil
 	case "git":
 		return NewGitSource(src, r.cache), nil
+	case "archive":
+		return NewArchiveSource(src, r.cache), nil
 	case "embedded":
-		return NewEmbeddedSource(), nil
+		return newCachedEmbeddedSource(r.cache.baseDir), nil
 	default:
 		return nil, fmt.Errorf("unknown source type: %s", src.Type)
 	}
@@ -268,6 +276,75 @@ func (r *Registry) GetService(ctx context.Context, name string) (*ServiceDefinit
 	return nil, "", fmt.Errorf("service %s not found in any source", name)
 }
 
+// loadError explains why a service is missing from ListServices: the error
+// loading its definition from the first source listing it, or not found
+func (r *Registry) loadError(ctx context.Context, name string) error {
+	r.mu.RLock()
+	sources := r.sources
+	r.mu.RUnlock()
+
+	for _, src := range sources {
+		if !src.IsEnabled() {
+			continue
+		}
+		names, err := src.ListServices(ctx)
+		if err != nil || !slices.Contains(names, name) {
+			continue
+		}
+		if _, err := src.LoadService(ctx, name); err != nil {
+			return fmt.Errorf("invalid definition in source %s: %w", src.Name(), err)
+		}
+	}
+	return fmt.Errorf("service %s not found in any source", name)
+}
+
+// GetServiceReadme returns the README.md shipped next to the service.yaml of
+// the source that provides the service, or an empty string if it has none
+func (r *Registry) GetServiceReadme(ctx context.Context, name string) (string, error) {
+	r.mu.RLock()
+	sources := r.sources
+	r.mu.RUnlock()
+
+	for _, src := range sources {
+		if !src.IsEnabled() {
+			continue
+		}
+
+		def, err := src.LoadService(ctx, name)
+		if err == nil && def != nil {
+			return readServiceReadme(src.GetServicePath(name))
+		}
+	}
+
+	return "", fmt.Errorf("service %s not found in any source", name)
+}
+
+// GetServiceAsset returns an image declared as the icon or a screenshot of
+// a service, read from the providing source next to its service.yaml
+func (r *Registry) GetServiceAsset(ctx context.Context, name, file string) ([]byte, error) {
+	r.mu.RLock()
+	sources := r.sources
+	r.mu.RUnlock()
+
+	for _, src := range sources {
+		if !src.IsEnabled() {
+			continue
+		}
+
+		def, err := src.LoadService(ctx, name)
+		if err != nil || def == nil {
+			continue
+		}
+		// Only declared assets, so no other file of the source is served
+		if file == "" || (file != def.Metadata.Icon && !slices.Contains(def.Metadata.Screenshots, file)) {
+			return nil, fmt.Errorf("%s is not an asset of %s", file, name)
+		}
+		return readServiceFile(src.GetServicePath(name), file)
+	}
+
+	return nil, fmt.Errorf("service %s not found in any source", name)
+}
+
 // ListServices returns all available services across all sources
 func (r *Registry) ListServices(ctx context.Context) ([]ServiceInfo, error) {
 	r.mu.RLock()
@@ -307,6 +384,11 @@ func (r *Registry) ListServices(ctx context.Context) ([]ServiceInfo, error) {
 				Source:      src.Name(),
 				IsAddon:     def.Conditions.RequireAddon,
 				HasWebUI:    def.Routing.Enabled,
+				Homepage:    def.Metadata.Homepage,
+				Maintainer:  def.Metadata.Maintainer,
+				Tags:        def.Metadata.Tags,
+				Icon:        def.Metadata.Icon,
+				Screenshots: def.Metadata.Screenshots,
 			})
 		}
 	}
@@ -354,27 +436,27 @@ type ServiceInfo struct {
 	Source      string
 	IsAddon     bool
 	HasWebUI    bool
+	Homepage    string
+	Maintainer  string
+	Tags        []string
+	Icon        string
+	Screenshots []string
 }
 
-// matchesQuery checks if a service matches a search query
+// matchesQuery checks if a service matches a search query: every word of
+// the query must appear in its name, description, category, tags or
+// maintainer, case-insensitively
 func matchesQuery(svc ServiceInfo, query string) bool {
-	if query == "" {
-		return true
-	}
+	text := strings.ToLower(strings.Join(append([]string{
+		svc.Name, svc.Description, string(svc.Category), svc.Maintainer,
+	}, svc.Tags...), " "))
 
-	// Simple case-insensitive substring match
-	query = strings.ToLower(query)
-	if strings.Contains(strings.ToLower(svc.Name), query) {
-		return true
-	}
-	if strings.Contains(strings.ToLower(svc.Description), query) {
-		return true
-	}
-	if strings.Contains(strings.ToLower(string(svc.Category)), query) {
-		return true
+	for _, word := range strings.Fields(strings.ToLower(query)) {
+		if !strings.Contains(text, word) {
+			return false
+		}
 	}
-
-	return false
+	return true
 }
 
 // LockFileDiff represents a difference in lock file comparison
@@ -383,59 +465,10 @@ type LockFileDiff struct {
 	Description string
 }
 
-// GenerateLockFile generates a lock file from current configuration
+// GenerateLockFile generates a lock file from current configuration without
+// saving it; the CLI version is left for the caller to stamp
 func (r *Registry) GenerateLockFile(ctx context.Context, cfg *config.Config) (*LockFile, error) {
-	// Resolve all services
-	graph, err := r.Resolve(ctx, cfg)
-	if err != nil {
-		return nil, fmt.Errorf("failed to resolve services: %w", err)
-	}
-
-	// Build lock file
-	lock := &LockFile{
-		APIVersion: APIVersion,
-		Kind:       KindLockFile,
-		Metadata: LockFileMetadata{
-			Version:     1,
-			GeneratedAt: time.Now().UTC(),
-		},
-		Sources:      make(map[string]LockedSource),
-		Services:     make(map[string]LockedService),
-		InstallOrder: graph.Order,
-	}
-
-	// Lock sources
-	for _, src := range r.Sources() {
-		if gitSrc, ok := src.(*GitSource); ok {
-			lock.Sources[src.Name()] = LockedSource{
-				URL:       gitSrc.GetURL(),
-				Commit:    gitSrc.GetCommit(),
-				Branch:    gitSrc.GetBranch(),
-				FetchedAt: gitSrc.GetLastUpdated(),
-			}
-		}
-	}
-
-	// Lock services
-	for name, resolved := range graph.Services {
-		if !resolved.Enabled {
-			continue
-		}
-
-		def := resolved.FinalDefinition
-		lock.Services[name] = LockedService{
-			Source:            resolved.Source,
-			DefinitionVersion: def.Metadata.Version,
-			Image: LockedImage{
-				Repository: def.Spec.Image.Repository,
-				Tag:        def.Spec.Image.Tag,
-			},
-			ResolvedFrom: resolved.SourcePath,
-			Enabled:      resolved.Enabled,
-		}
-	}
-
-	return lock, nil
+	return NewLockManager(r, "").GenerateLockFile(ctx, cfg, "")
 }
 
 // DiffLockFiles compares two lock files and returns differences
@@ -501,6 +534,7 @@ func (r *Registry) DiffLockFiles(existing, current *LockFile) []LockFileDiff {
 		}
 	}
 
+	sort.Slice(diffs, func(i, j int) bool { return diffs[i].Description < diffs[j].Description })
 	return diffs
 }
 
@@ -514,11 +548,24 @@ func (r *Registry) UpdateLockFile(
 		return nil, err
 	}
 
+	// What the last generation recorded stays until the next one
+	current.HostPorts = existing.HostPorts
+	current.GeneratedFiles = existing.GeneratedFiles
+	current.Metadata.Generation = existing.Metadata.Generation
+
 	// If no specific services, return the fully regenerated lock file
 	if len(servicesToUpdate) == 0 {
 		return current, nil
 	}
 
+	for _, name := range servicesToUpdate {
+		_, locked := existing.Services[name]
+		_, enabled := current.Services[name]
+		if !locked && !enabled {
+			return nil, fmt.Errorf("service %q is neither locked nor enabled", name)
+		}
+	}
+
 	// Only update specified services
 	updated := &LockFile{
 		APIVersion:   existing.APIVersion,
@@ -527,6 +574,9 @@ func (r *Registry) UpdateLockFile(
 		Sources:      existing.Sources,
 		Services:     make(map[string]LockedService),
 		InstallOrder: current.InstallOrder,
+
+		GeneratedFiles: current.GeneratedFiles,
+		HostPorts:      current.HostPorts,
 	}
 
 	// Copy existing services, update only specified ones
@@ -6,8 +6,10 @@ import (
 	"crypto/sha256"
 	"fmt"
 	"log"
+	"maps"
 	"os"
 	"path/filepath"
+	"slices"
 	"sort"
 	"strings"
 	"text/template"
@@ -53,13 +55,88 @@ func (r *Resolver) Resolve(ctx context.Context, cfg *config.Config) (*Resolution
 	// Determine which services to include
 	enabledServices := r.determineEnabledServices(ctx, cfg, serviceMap)
 
-	// Resolve each enabled service
+	// Inline services from .sdbx.yaml are always enabled but may not shadow registry services
+	for _, extra := range cfg.ExtraServices {
+		if _, exists := serviceMap[extra.Name]; exists {
+			graph.Errors = append(graph.Errors, ResolutionError{
+				Service: extra.Name,
+				Message: "extra service conflicts with a registry service of the same name",
+				Skipped: true,
+			})
+			continue
+		}
+		enabledServices[extra.Name] = true
+	}
+
+	// Named instances copy a registry service under a new name, as far as
+	// the service's instancing block allows
+	instanceCount := make(map[string]int)
+	for _, name := range slices.Sorted(maps.Keys(cfg.Instances)) {
+		base := cfg.Instances[name]
+		if _, exists := serviceMap[name]; exists {
+			graph.Errors = append(graph.Errors, ResolutionError{
+				Service: name,
+				Message: "instance conflicts with a registry service of the same name",
+				Skipped: true,
+			})
+			continue
+		}
+		if _, exists := serviceMap[base]; !exists {
+			graph.Errors = append(graph.Errors, ResolutionError{
+				Service: name,
+				Message: fmt.Sprintf("instance of unknown service %s", base),
+				Skipped: true,
+			})
+			continue
+		}
+		def, _, err := r.registry.GetService(ctx, base)
+		if err != nil {
+			graph.Errors = append(graph.Errors, ResolutionError{Service: name, Message: "failed to load " + base, Cause: err, Skipped: true})
+			continue
+		}
+		if def.Instancing == nil || !def.Instancing.Allowed {
+			graph.Errors = append(graph.Errors, ResolutionError{
+				Service: name,
+				Message: fmt.Sprintf("%s does not allow named instances", base),
+				Skipped: true,
+			})
+			continue
+		}
+		instanceCount[base]++
+		if limit := def.Instancing.Max; limit > 0 && instanceCount[base] > limit {
+			graph.Errors = append(graph.Errors, ResolutionError{
+				Service: name,
+				Message: fmt.Sprintf("%s allows at most %d instance(s)", base, limit),
+				Skipped: true,
+			})
+			continue
+		}
+		enabledServices[name] = true
+	}
+
+	// Enabled addons missing from the list have a definition that failed to
+	// load, or exist in no source
+	for _, name := range cfg.Addons {
+		isExtra := slices.ContainsFunc(cfg.ExtraServices, func(e config.ExtraServiceConfig) bool { return e.Name == name })
+		if _, exists := serviceMap[name]; !exists && !isExtra {
+			graph.Errors = append(graph.Errors, ResolutionError{
+				Service: name,
+				Message: "enabled addon not available",
+				Cause:   r.registry.loadError(ctx, name),
+				Skipped: true,
+			})
+		}
+	}
+
+	// Resolve each enabled service; one that fails is skipped, the others
+	// still resolve
 	for serviceName := range enabledServices {
 		if err := r.resolveService(ctx, cfg, graph, serviceName); err != nil {
 			graph.Errors = append(graph.Errors, ResolutionError{
 				Service: serviceName,
 				Message: "failed to resolve",
 				Cause:   err,
+				Skipped: true,
 			})
 		}
 	}
@@ -105,8 +182,21 @@ func (r *Resolver) resolveService(ctx context.Context, cfg *config.Config, graph
 		return nil
 	}
 
-	// Get service definition
-	def, source, err := r.registry.GetService(ctx, serviceName)
+	// Named instances are built from the definition of the service they copy
+	definitionName := serviceName
+	if base := cfg.InstanceOf(serviceName); base != "" {
+		definitionName = base
+	}
+
+	// Get service definition, from the pinned commit of its source if any
+	var def *ServiceDefinition
+	var source, pinnedCommit string
+	var err error
+	if pin := cfg.ServicePin(serviceName); pin != "" {
+		def, source, pinnedCommit, err = r.registry.GetServiceAt(ctx, definitionName, pin)
+	} else {
+		def, source, err = r.getDefinition(ctx, cfg, definitionName)
+	}
 	if err != nil {
 		return err
 	}
@@ -116,23 +206,43 @@ func (r *Resolver) resolveService(ctx context.Context, cfg *config.Config, graph
 		return nil // Service doesn't meet conditions
 	}
 
-	// Calculate definition hash
-	hash := r.calculateHash(def)
+	// Definitions requiring a newer CLI still resolve; generation refuses them
+	graph.Incompatible = append(graph.Incompatible, r.checkCompat(ctx, serviceName, source, def)...)
 
 	// Look for overrides (optional)
-	overrides := r.loadOverrides(ctx, serviceName)
+	overrides := r.loadOverrides(ctx, definitionName)
 
 	// Merge overrides to get final definition
 	finalDef := def
 	for _, override := range overrides {
 		finalDef = r.loader.MergeOverride(finalDef, override)
 	}
+	if definitionName != serviceName {
+		finalDef = instanceDefinition(finalDef, serviceName, r.loader)
+	}
+
+	// Variables set in .sdbx.yaml must be declared by the definition
+	var variables map[string]string
+	if finalDef.Instancing != nil {
+		variables = finalDef.Instancing.Variables
+	}
+	for _, key := range slices.Sorted(maps.Keys(cfg.Services[serviceName].Variables)) {
+		if _, declared := variables[key]; !declared {
+			graph.Errors = append(graph.Errors, ResolutionError{
+				Service: serviceName,
+				Message: fmt.Sprintf("services.%s.variables.%s is not an instancing variable of %s", serviceName, key, definitionName),
+			})
+		}
+	}
+
+	// Hash the merged definition so override edits invalidate it too
+	hash := r.calculateHash(finalDef)
 
 	// Get source path
 	sourceProvider, _ := r.registry.GetSource(source)
 	sourcePath := ""
 	if sourceProvider != nil {
-		sourcePath = sourceProvider.GetServicePath(serviceName)
+		sourcePath = sourceProvider.GetServicePath(definitionName)
 	}
 
 	// Create resolved service
@@ -146,6 +256,7 @@ func (r *Resolver) resolveService(ctx context.Context, cfg *config.Config, graph
 		FinalDefinition: finalDef,
 		Dependencies:    r.collectDependencies(finalDef, cfg),
 		Enabled:         true,
+		PinnedCommit:    pinnedCommit,
 	}
 
 	graph.Services[serviceName] = resolved
@@ -153,11 +264,12 @@ func (r *Resolver) resolveService(ctx context.Context, cfg *config.Config, graph
 	// Recursively resolve dependencies
 	for _, depName := range resolved.Dependencies {
 		if err := r.resolveService(ctx, cfg, graph, depName); err != nil {
-			// Dependency failed to resolve, but continue
+			// Dependency failed to resolve, but continue without it
 			graph.Errors = append(graph.Errors, ResolutionError{
-				Service: serviceName,
-				Message: fmt.Sprintf("dependency %s failed", depName),
+				Service: depName,
+				Message: fmt.Sprintf("failed to resolve dependency of %s", serviceName),
 				Cause:   err,
+				Skipped: true,
 			})
 		}
 	}
@@ -165,6 +277,18 @@ func (r *Resolver) resolveService(ctx context.Context, cfg *config.Config, graph
 	return nil
 }
 
+// getDefinition returns a service definition from the registry, falling back
+// to services declared inline in .sdbx.yaml
+func (r *Resolver) getDefinition(ctx context.Context, cfg *config.Config, serviceName string) (*ServiceDefinition, string, error) {
+	def, source, err := r.registry.GetService(ctx, serviceName)
+	if err != nil {
+		if extra, found, extraErr := extraServiceDefinition(cfg, serviceName); found {
+			return extra, ExtraServiceSource, extraErr
+		}
+	}
+	return def, source, err
+}
+
 // evaluateConditions checks if a service's conditions are met
 func (r *Resolver) evaluateConditions(cond Conditions, cfg *config.Config) bool {
 	return EvaluateConditions(cond, cfg)
@@ -176,13 +300,13 @@ func (r *Resolver) collectDependencies(def *ServiceDefinition, cfg *config.Confi
 
 	// Required dependencies
 	for _, dep := range def.Spec.Dependencies.Required {
-		deps[dep] = true
+		deps[dependencyName(dep, cfg)] = true
 	}
 
 	// Conditional dependencies
 	for _, dep := range def.Spec.Dependencies.Conditional {
 		if r.evaluateConditionString(dep.When, cfg) {
-			deps[dep.Name] = true
+			deps[dependencyName(dep.Name, cfg)] = true
 		}
 	}
 
@@ -195,12 +319,25 @@ func (r *Resolver) collectDependencies(def *ServiceDefinition, cfg *config.Confi
 	return result
 }
 
-// evaluateConditionString evaluates a condition template string using text/template.
-// This supports all Go template expressions dynamically, matching the compose generator's behavior.
+// dependencyName maps a "service@instance" dependency to the instance's
+// name. Invalid references are kept so they fail to resolve visibly.
+func dependencyName(ref string, cfg *config.Config) string {
+	if name, err := cfg.ServiceName(ref); err == nil {
+		return name
+	}
+	return ref
+}
+
+// evaluateConditionString evaluates a when: condition. Go templates are executed
+// with text/template, matching the compose generator's behavior; anything else
+// is parsed as a condition expression.
 func (r *Resolver) evaluateConditionString(condition string, cfg *config.Config) bool {
 	if condition == "" {
 		return true
 	}
+	if !IsTemplateCondition(condition) {
+		return EvaluateExpression(condition, cfg)
+	}
 
 	tmpl, err := template.New("cond").Parse(condition)
 	if err != nil {
@@ -305,13 +442,17 @@ func (r *Resolver) topologicalSort(graph *ResolutionGraph) ([]string, error) {
 		}
 	}
 
-	// Kahn's algorithm
+	// Kahn's algorithm, with ties broken by name so the order is stable
 	var queue []string
 	for name, degree := range inDegree {
 		if degree == 0 {
 			queue = append(queue, name)
 		}
 	}
+	sort.Strings(queue)
+	for _, dependents := range adjList {
+		sort.Strings(dependents)
+	}
 
 	var order []string
 	for len(queue) > 0 {
@@ -371,3 +512,25 @@ func GetEnabledServices(graph *ResolutionGraph) map[string]*ResolvedService {
 	}
 	return enabled
 }
+
+// Skip drops a service from the graph, recording why it was left out
+func (g *ResolutionGraph) Skip(name, message string, cause error) {
+	delete(g.Services, name)
+	g.Order = slices.DeleteFunc(slices.Clone(g.Order), func(s string) bool { return s == name })
+	g.Errors = append(g.Errors, ResolutionError{Service: name, Message: message, Cause: cause, Skipped: true})
+}
+
+// Skipped returns why each skipped service was left out of the graph, the
+// first reason recorded for a service, ordered by service name
+func (g *ResolutionGraph) Skipped() []ResolutionError {
+	var skipped []ResolutionError
+	seen := make(map[string]bool)
+	for _, e := range g.Errors {
+		if e.Skipped && !seen[e.Service] {
+			seen[e.Service] = true
+			skipped = append(skipped, e)
+		}
+	}
+	slices.SortStableFunc(skipped, func(a, b ResolutionError) int { return strings.Compare(a.Service, b.Service) })
+	return skipped
+}
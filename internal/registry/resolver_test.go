@@ -4,6 +4,7 @@ import (
 	"context"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/maiko/sdbx/internal/config"
@@ -882,6 +883,60 @@ conditions:
 	}
 }
 
+func TestResolveSkipsInvalidAddon(t *testing.T) {
+	tmpDir := t.TempDir()
+	for name, content := range map[string]string{
+		"good-addon": `apiVersion: sdbx.one/v1
+kind: Service
+metadata:
+  name: good-addon
+  version: 1.0.0
+  category: media
+  description: Test addon
+spec:
+  image:
+    repository: test/addon
+    tag: latest
+  container:
+    name_template: "sdbx-good-addon"
+conditions:
+  requireAddon: true
+`,
+		"bad-addon": "apiVersion: sdbx.one/v1\nkind: Service\nmetadata: [not, a, map\n",
+	} {
+		dir := filepath.Join(tmpDir, "addons", name)
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "service.yaml"), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cfg := config.DefaultConfig()
+	cfg.Domain = "test.local"
+	cfg.Addons = []string{"bad-addon", "good-addon", "missing-addon"}
+
+	graph, err := NewResolver(newTestRegistryWithLocal(t, tmpDir)).Resolve(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("Resolve() error: %v", err)
+	}
+	if _, exists := graph.Services["good-addon"]; !exists {
+		t.Error("good-addon should still be resolved")
+	}
+
+	skipped := graph.Skipped()
+	if len(skipped) != 2 || skipped[0].Service != "bad-addon" || skipped[1].Service != "missing-addon" {
+		t.Fatalf("Skipped() = %v, want bad-addon and missing-addon", skipped)
+	}
+	if !strings.Contains(skipped[0].Error(), "invalid definition in source") {
+		t.Errorf("bad-addon reason = %v, want its load error", skipped[0])
+	}
+	if !strings.Contains(skipped[1].Error(), "not found in any source") {
+		t.Errorf("missing-addon reason = %v, want not found", skipped[1])
+	}
+}
+
 func TestEvaluateConditionStringTemplate(t *testing.T) {
 	reg := newTestRegistry(t)
 	resolver := NewResolver(reg)
@@ -922,3 +977,131 @@ func TestEvaluateConditionStringTemplate(t *testing.T) {
 		})
 	}
 }
+
+func TestResolveNamedInstance(t *testing.T) {
+	tmpDir := t.TempDir()
+	svcDir := filepath.Join(tmpDir, "addons", "sonarr")
+	if err := os.MkdirAll(svcDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	svcYAML := `apiVersion: sdbx.one/v1
+kind: Service
+metadata:
+  name: sonarr
+  version: 1.0.0
+  category: media
+  description: TV automation
+spec:
+  image:
+    repository: test/sonarr
+    tag: latest
+  container:
+    name_template: "sdbx-sonarr"
+  ports:
+    static:
+      - "8989:8989"
+  volumes:
+    - hostPath: "./configs/sonarr"
+      containerPath: /config
+    - hostPath: "{{ .Config.MediaPath }}"
+      containerPath: /media
+routing:
+  enabled: true
+  port: 8989
+  subdomain: sonarr
+  path: /sonarr
+conditions:
+  requireAddon: true
+instancing:
+  allowed: true
+  max: 1
+  variables:
+    quality: 1080p
+`
+	if err := os.WriteFile(filepath.Join(svcDir, "service.yaml"), []byte(svcYAML), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	reg := newTestRegistryWithLocal(t, tmpDir)
+	cfg := config.DefaultConfig()
+	cfg.EnableAddon("sonarr")
+	cfg.AddInstance("sonarr4k", "sonarr")
+	cfg.AddInstance("sonarr8k", "sonarr") // Beyond instancing.max
+	cfg.Services = map[string]config.ServiceOverride{
+		"sonarr4k": {Variables: map[string]string{"quality": "2160p"}},
+	}
+
+	graph, err := reg.resolver.Resolve(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("Resolve() error: %v", err)
+	}
+	if len(graph.Errors) != 1 || graph.Errors[0].Service != "sonarr8k" {
+		t.Fatalf("expected only the max error for sonarr8k, got %v", graph.Errors)
+	}
+	if _, ok := graph.Services["sonarr8k"]; ok {
+		t.Error("instances beyond instancing.max should not be resolved")
+	}
+
+	original, ok := graph.Services["sonarr"]
+	if !ok {
+		t.Fatal("expected sonarr to be resolved")
+	}
+	instance, ok := graph.Services["sonarr4k"]
+	if !ok {
+		t.Fatal("expected sonarr4k to be resolved")
+	}
+
+	def := instance.FinalDefinition
+	if def.Metadata.Name != "sonarr4k" {
+		t.Errorf("instance name = %q, want sonarr4k", def.Metadata.Name)
+	}
+	if def.Spec.Container.NameTemplate != "sdbx-sonarr4k" {
+		t.Errorf("instance container = %q, want sdbx-sonarr4k", def.Spec.Container.NameTemplate)
+	}
+	if got := def.Spec.Volumes[0].HostPath; got != "./configs/sonarr4k" {
+		t.Errorf("instance config path = %q, want ./configs/sonarr4k", got)
+	}
+	if got := def.Spec.Volumes[1].HostPath; got != "{{ .Config.MediaPath }}" {
+		t.Errorf("instance media path = %q, should stay shared", got)
+	}
+	if len(def.Spec.Ports.Static) != 0 {
+		t.Errorf("instance should not publish host ports, got %v", def.Spec.Ports.Static)
+	}
+	if def.Routing.Subdomain != "sonarr4k" || def.Routing.Path != "/sonarr4k" {
+		t.Errorf("instance routing = %s %s, want sonarr4k /sonarr4k", def.Routing.Subdomain, def.Routing.Path)
+	}
+	inst := NewInstanceContext(def, cfg)
+	if inst.Name != "sonarr4k" || inst.Service != "sonarr" || inst.Vars["quality"] != "2160p" {
+		t.Errorf("instance context = %+v, want sonarr4k of sonarr with quality 2160p", inst)
+	}
+	if got := NewInstanceContext(original.FinalDefinition, cfg).Vars["quality"]; got != "1080p" {
+		t.Errorf("original quality = %q, want the 1080p default", got)
+	}
+
+	// The original definition must be left untouched
+	if got := original.FinalDefinition.Spec.Volumes[0].HostPath; got != "./configs/sonarr" {
+		t.Errorf("original config path = %q, want ./configs/sonarr", got)
+	}
+	if len(original.FinalDefinition.Spec.Ports.Static) != 1 {
+		t.Error("original should keep its host ports")
+	}
+}
+
+func TestResolveNamedInstanceErrors(t *testing.T) {
+	reg := newTestRegistry(t)
+	cfg := config.DefaultConfig()
+	cfg.AddInstance("plex", "jellyfin")
+	cfg.AddInstance("radarr4k", "radarr")
+	cfg.AddInstance("traefik2", "traefik") // No instancing block
+
+	graph, err := reg.resolver.Resolve(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("Resolve() error: %v", err)
+	}
+	if len(graph.Errors) != 3 {
+		t.Fatalf("expected 3 resolution errors, got %v", graph.Errors)
+	}
+	if _, ok := graph.Services["radarr4k"]; ok {
+		t.Error("instance of an unknown service should not be resolved")
+	}
+}
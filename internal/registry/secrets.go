@@ -0,0 +1,71 @@
+package registry
+
+import (
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/maiko/sdbx/internal/auth"
+	"github.com/maiko/sdbx/internal/config"
+	"github.com/maiko/sdbx/internal/secrets"
+)
+
+// secretSpec converts a secret declaration into a generator spec
+func secretSpec(def SecretDef) secrets.Spec {
+	return secrets.Spec{
+		Name:     def.Name,
+		Type:     def.Type,
+		Length:   def.Length,
+		Charset:  def.Charset,
+		Username: def.Username,
+	}
+}
+
+// SecretSpecs returns the secrets declared by every resolved service, sorted
+// by name. A secret declared by several services is returned once.
+func (g *ResolutionGraph) SecretSpecs() []secrets.Spec {
+	seen := make(map[string]bool)
+	var specs []secrets.Spec
+	for _, name := range g.Order {
+		svc, ok := g.Services[name]
+		if !ok || svc.FinalDefinition == nil {
+			continue
+		}
+		for _, def := range svc.FinalDefinition.Secrets {
+			if seen[def.Name] {
+				continue
+			}
+			seen[def.Name] = true
+			specs = append(specs, secretSpec(def))
+		}
+	}
+	slices.SortFunc(specs, func(a, b secrets.Spec) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return specs
+}
+
+// ConfigSecretSpecs returns the secrets required by the configuration rather
+// than by a service: the htpasswd users file in basic auth mode, and the
+// SMTP password, the MaxMind license key and the passwords of shared SMB
+// volumes, which the user fills in
+func ConfigSecretSpecs(cfg *config.Config) []secrets.Spec {
+	var specs []secrets.Spec
+	if cfg.IsBasicAuth() {
+		specs = append(specs, secrets.Spec{Name: auth.BasicAuthSecret, Type: secrets.TypeHtpasswd, Username: cfg.AdminUser})
+	}
+	if cfg.SMTP.Auth() {
+		specs = append(specs, secrets.Spec{Name: config.SMTPPasswordSecret, Type: secrets.TypeManual})
+	}
+	if cfg.GeoIP.IsEnabled() {
+		specs = append(specs, secrets.Spec{Name: config.GeoIPLicenseKeySecret, Type: secrets.TypeManual})
+	}
+	seen := make(map[string]bool)
+	for _, name := range slices.Sorted(maps.Keys(cfg.Volumes)) {
+		if secret := cfg.Volumes[name].Secret; secret != "" && !seen[secret] {
+			seen[secret] = true
+			specs = append(specs, secrets.Spec{Name: secret, Type: secrets.TypeManual})
+		}
+	}
+	return specs
+}
@@ -536,3 +536,141 @@ func TestLocalSourceSaveService(t *testing.T) {
 		t.Errorf("loaded name = %q, want 'new-service'", loaded.Metadata.Name)
 	}
 }
+
+// TestGetServiceReadme verifies README.md next to service.yaml is returned from the providing source
+func TestGetServiceReadme(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	for _, name := range []string{"documented", "undocumented"} {
+		serviceDir := filepath.Join(tmpDir, "addons", name)
+		if err := os.MkdirAll(serviceDir, 0o755); err != nil {
+			t.Fatalf("failed to create service dir: %v", err)
+		}
+		serviceYAML := `apiVersion: sdbx.one/v1
+kind: Service
+metadata:
+  name: ` + name + `
+  version: 1.0.0
+  category: utility
+spec:
+  image:
+    repository: nginx
+    tag: latest
+`
+		if err := os.WriteFile(filepath.Join(serviceDir, "service.yaml"), []byte(serviceYAML), 0o644); err != nil {
+			t.Fatalf("failed to write service.yaml: %v", err)
+		}
+	}
+	readme := "# Documented\n\nGet your claim token first.\n"
+	if err := os.WriteFile(filepath.Join(tmpDir, "addons", "documented", "README.md"), []byte(readme), 0o644); err != nil {
+		t.Fatalf("failed to write README.md: %v", err)
+	}
+
+	reg := newTestRegistryWithLocal(t, tmpDir)
+	ctx := context.Background()
+
+	got, err := reg.GetServiceReadme(ctx, "documented")
+	if err != nil || got != readme {
+		t.Errorf("GetServiceReadme(documented) = %q, %v; want %q", got, err, readme)
+	}
+
+	got, err = reg.GetServiceReadme(ctx, "undocumented")
+	if err != nil || got != "" {
+		t.Errorf("GetServiceReadme(undocumented) = %q, %v; want empty", got, err)
+	}
+
+	if _, err := reg.GetServiceReadme(ctx, "missing"); err == nil {
+		t.Error("GetServiceReadme(missing) should fail")
+	}
+}
+
+// TestGetServiceAsset verifies only the declared icon and screenshots are served
+func TestGetServiceAsset(t *testing.T) {
+	tmpDir := t.TempDir()
+	serviceDir := filepath.Join(tmpDir, "addons", "pretty")
+	if err := os.MkdirAll(filepath.Join(serviceDir, "shots"), 0o755); err != nil {
+		t.Fatalf("failed to create service dir: %v", err)
+	}
+	serviceYAML := `apiVersion: sdbx.one/v1
+kind: Service
+metadata:
+  name: pretty
+  version: 1.0.0
+  category: utility
+  icon: icon.png
+  screenshots:
+    - shots/home.png
+spec:
+  image:
+    repository: nginx
+    tag: latest
+`
+	files := map[string]string{
+		"service.yaml":   serviceYAML,
+		"icon.png":       "icon",
+		"shots/home.png": "home",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(serviceDir, name), []byte(content), 0o644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+
+	reg := newTestRegistryWithLocal(t, tmpDir)
+	ctx := context.Background()
+
+	for file, want := range map[string]string{"icon.png": "icon", "shots/home.png": "home"} {
+		got, err := reg.GetServiceAsset(ctx, "pretty", file)
+		if err != nil || string(got) != want {
+			t.Errorf("GetServiceAsset(%s) = %q, %v; want %q", file, got, err, want)
+		}
+	}
+
+	for _, file := range []string{"service.yaml", "", "../pretty/icon.png"} {
+		if _, err := reg.GetServiceAsset(ctx, "pretty", file); err == nil {
+			t.Errorf("GetServiceAsset(%q) should fail for an undeclared file", file)
+		}
+	}
+
+	services, err := reg.SearchServices(ctx, "", "")
+	if err != nil {
+		t.Fatalf("SearchServices() error = %v", err)
+	}
+	if len(services) != 1 || services[0].Icon != "icon.png" || len(services[0].Screenshots) != 1 {
+		t.Errorf("SearchServices() = %+v, want icon and screenshot metadata", services)
+	}
+}
+
+// TestMatchesQuery verifies every query word must match a searchable field
+func TestMatchesQuery(t *testing.T) {
+	svc := ServiceInfo{
+		Name:        "tautulli",
+		Description: "Plex monitoring",
+		Category:    CategoryMedia,
+		Maintainer:  "linuxserver",
+		Tags:        []string{"statistics", "plex"},
+	}
+
+	tests := []struct {
+		query string
+		want  bool
+	}{
+		{"", true},
+		{"taut", true},
+		{"MONITORING", true},
+		{"media", true},
+		{"statistics", true},
+		{"linuxserver", true},
+		{"plex statistics", true},
+		{"plex sonarr", false},
+		{"downloads", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			if got := matchesQuery(svc, tt.query); got != tt.want {
+				t.Errorf("matchesQuery(%q) = %v, want %v", tt.query, got, tt.want)
+			}
+		})
+	}
+}
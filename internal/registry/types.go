@@ -3,7 +3,11 @@
 // and version pinning through lock files.
 package registry
 
-import "time"
+import (
+	"time"
+
+	"github.com/maiko/sdbx/internal/config"
+)
 
 // API version for service definitions
 const (
@@ -13,8 +17,12 @@ const (
 	KindSourceRepository = "SourceRepository"
 	KindSourceConfig     = "SourceConfig"
 	KindLockFile         = "LockFile"
+	KindPreset           = "Preset"
 )
 
+// LockFileVersion is the lock file schema version written by this CLI
+const LockFileVersion = 1
+
 // ServiceCategory defines the category of a service
 type ServiceCategory string
 
@@ -37,6 +45,8 @@ type ServiceDefinition struct {
 	Secrets      []SecretDef     `yaml:"secrets,omitempty"`
 	Integrations Integrations    `yaml:"integrations,omitempty"`
 	Conditions   Conditions      `yaml:"conditions,omitempty"`
+	PostInstall  *PostInstall    `yaml:"postInstall,omitempty"`
+	Instancing   *Instancing     `yaml:"instancing,omitempty"`
 }
 
 // ServiceMetadata contains service identification and descriptive information
@@ -49,6 +59,9 @@ type ServiceMetadata struct {
 	Documentation string          `yaml:"documentation,omitempty"`
 	Maintainer    string          `yaml:"maintainer,omitempty"`
 	Tags          []string        `yaml:"tags,omitempty"`
+	Icon          string          `yaml:"icon,omitempty"`          // Image next to service.yaml (e.g. icon.png)
+	Screenshots   []string        `yaml:"screenshots,omitempty"`   // Images next to service.yaml, shown on the addon page
+	MinCLIVersion string          `yaml:"minCliVersion,omitempty"` // Oldest sdbx rendering the definition correctly
 }
 
 // ServiceSpec defines the container and runtime configuration
@@ -61,13 +74,41 @@ type ServiceSpec struct {
 	Networking   NetworkSpec     `yaml:"networking,omitempty"`
 	HealthCheck  *HealthCheck    `yaml:"healthcheck,omitempty"`
 	Dependencies DependencySpec  `yaml:"dependencies,omitempty"`
+	Logging      *LoggingSpec    `yaml:"logging,omitempty"`
+
+	// ExternalDependencies are endpoints SDBX does not manage (an existing
+	// NAS share, database, ...), checked by doctor/verify and available to
+	// templates through the external* helpers
+	ExternalDependencies []ExternalDependency `yaml:"externalDependencies,omitempty"`
+
+	// VolumeDefinitions declare named volumes (NFS, SMB or local) mounted
+	// through VolumeMount.Volume. Shared volumes of the same name in
+	// .sdbx.yaml replace them.
+	VolumeDefinitions map[string]config.VolumeDefinition `yaml:"volumeDefinitions,omitempty"`
+
+	// Requires lists the resources sdbx provisions for the service (see
+	// Requirements), e.g. geoip to mount the MaxMind GeoLite databases
+	Requires []string `yaml:"requires,omitempty"`
 }
 
+// Requirements of service definitions
+const (
+	// RequireGeoIP mounts the databases of the geoip section read-only at
+	// /geoip; templates get their paths from {{ .GeoIP }}
+	RequireGeoIP = "geoip"
+)
+
+// Requirements lists the known values of spec.requires
+var Requirements = []string{RequireGeoIP}
+
 // ImageSpec defines the container image configuration
 type ImageSpec struct {
 	Repository string `yaml:"repository"`
 	Tag        string `yaml:"tag"`
 	Registry   string `yaml:"registry,omitempty"`
+	// Platforms lists the platforms the image is published for (e.g.
+	// linux/amd64); empty means the image is multi-arch
+	Platforms []string `yaml:"platforms,omitempty"`
 }
 
 // ContainerSpec defines container runtime settings
@@ -81,6 +122,8 @@ type ContainerSpec struct {
 	ShmSize      string            `yaml:"shm_size,omitempty"`
 	Sysctls      map[string]string `yaml:"sysctls,omitempty"`
 	GPUEnabled   bool              `yaml:"gpu_enabled,omitempty"`
+	// HardwareTranscode mounts /dev/dri when config hardware_transcode is on
+	HardwareTranscode bool `yaml:"hardware_transcode,omitempty"`
 }
 
 // CapabilitiesSpec defines Linux capabilities to add or drop
@@ -113,14 +156,21 @@ type ConditionalEnvVar struct {
 type ValueSource struct {
 	SecretRef string `yaml:"secretRef,omitempty"`
 	ConfigRef string `yaml:"configRef,omitempty"`
+	// FileEnv names the variable through which the image reads the secret
+	// from a file (e.g. POSTGRES_PASSWORD_FILE). Setting it allows file
+	// delivery; without it secrets fall back to env_file delivery.
+	FileEnv string `yaml:"fileEnv,omitempty"`
 }
 
-// VolumeMount defines a volume mount for the container
+// VolumeMount defines a volume mount for the container: a bind mount of
+// HostPath, or the named volume Volume
 type VolumeMount struct {
 	Name          string `yaml:"name,omitempty"`
-	HostPath      string `yaml:"hostPath"`
+	HostPath      string `yaml:"hostPath,omitempty"`
+	Volume        string `yaml:"volume,omitempty"`
 	ContainerPath string `yaml:"containerPath"`
 	ReadOnly      bool   `yaml:"readOnly,omitempty"`
+	When          string `yaml:"when,omitempty"`
 }
 
 // PortSpec defines port mappings
@@ -135,11 +185,23 @@ type ConditionalPort struct {
 	When string `yaml:"when"`
 }
 
+// Network zones used for per-service isolation
+const (
+	ZoneFrontend  = "frontend"  // Shared with Traefik (sdbx_proxy)
+	ZoneBackend   = "backend"   // Internal-only, no outbound access (databases, caches)
+	ZoneDownloads = "downloads" // Download clients and the services that talk to them
+)
+
 // NetworkSpec defines network configuration
 type NetworkSpec struct {
 	Networks     []NetworkRef `yaml:"networks,omitempty"`
+	Zones        []string     `yaml:"zones,omitempty"`
 	Mode         string       `yaml:"mode,omitempty"`
 	ModeTemplate string       `yaml:"modeTemplate,omitempty"`
+
+	// IPv4Address is the static address of the container on the proxy
+	// network (template); empty lets Docker pick one
+	IPv4Address string `yaml:"ipv4Address,omitempty"`
 }
 
 // NetworkRef is a network reference with optional condition
@@ -148,6 +210,13 @@ type NetworkRef struct {
 	When string `yaml:"when,omitempty"`
 }
 
+// LoggingSpec overrides the global logging settings for a service
+// (e.g. a smaller max-size for a chatty container)
+type LoggingSpec struct {
+	Driver  string            `yaml:"driver,omitempty"`
+	Options map[string]string `yaml:"options,omitempty"`
+}
+
 // HealthCheck defines container health check configuration
 type HealthCheck struct {
 	Test        []string `yaml:"test"`
@@ -193,23 +262,66 @@ type PathRoutingConfig struct {
 type AuthConfig struct {
 	Required bool `yaml:"required"`
 	Bypass   bool `yaml:"bypass,omitempty"`
+	// APITokens routes requests carrying an sdbx API token around the auth
+	// middleware; the service checks the token itself
+	APITokens bool `yaml:"apiTokens,omitempty"`
 }
 
 // TraefikConfig defines Traefik-specific labels
 type TraefikConfig struct {
 	Priority     *int              `yaml:"priority,omitempty"`
-	Middlewares  []string          `yaml:"middlewares,omitempty"`
+	Middlewares  []string          `yaml:"middlewares,omitempty"` // Bare names resolve to @file, others are passed as-is
 	CustomLabels map[string]string `yaml:"customLabels,omitempty"`
+
+	// MiddlewareDefinitions are rendered into the Traefik dynamic config.
+	// Services declaring the same name must declare the same middleware.
+	MiddlewareDefinitions map[string]config.MiddlewareDefinition `yaml:"middlewareDefinitions,omitempty"`
 }
 
-// SecretDef defines a secret required by the service
+// SecretDef defines a secret required by the service. Type selects the
+// generator (see secrets.Types); manual secrets are left for the user.
 type SecretDef struct {
 	Name        string `yaml:"name"`
 	Type        string `yaml:"type"`
 	Length      int    `yaml:"length,omitempty"`
+	Charset     string `yaml:"charset,omitempty"`  // password/htpasswd only
+	Username    string `yaml:"username,omitempty"` // htpasswd only
 	Description string `yaml:"description,omitempty"`
 }
 
+// Instancing declares whether named instances of the service can be created
+// (sdbx addon enable sonarr --instance sonarr4k) and what may vary between them
+type Instancing struct {
+	Allowed bool `yaml:"allowed"`
+	Max     int  `yaml:"max,omitempty"` // Maximum number of named instances, 0 for no limit
+
+	// Variables available to templates as {{ .Instance.Vars.<name> }}, with
+	// their defaults. Set per instance under services.<instance>.variables.
+	Variables map[string]string `yaml:"variables,omitempty"`
+}
+
+// PostInstall lists what the user has to do after the service first starts.
+// sdbx aggregates it into the project checklist (see Checklist).
+type PostInstall struct {
+	Steps   []PostInstallStep `yaml:"steps,omitempty"`
+	Secrets []string          `yaml:"secrets,omitempty"` // Manual secrets the user must fill in
+	Links   []PostInstallLink `yaml:"links,omitempty"`
+}
+
+// PostInstallStep is a manual step the user marks as done
+type PostInstallStep struct {
+	ID          string `yaml:"id"`
+	Title       string `yaml:"title"`
+	Description string `yaml:"description,omitempty"`
+	URL         string `yaml:"url,omitempty"`
+}
+
+// PostInstallLink is a reference shown next to the service's checklist
+type PostInstallLink struct {
+	Title string `yaml:"title"`
+	URL   string `yaml:"url"`
+}
+
 // Integrations defines how the service integrates with other components
 type Integrations struct {
 	Homepage    *HomepageIntegration    `yaml:"homepage,omitempty"`
@@ -257,15 +369,22 @@ type Conditions struct {
 	RequireAddon   bool   `yaml:"requireAddon,omitempty"`
 	RequireConfig  string `yaml:"requireConfig,omitempty"`
 	RequireFeature string `yaml:"requireFeature,omitempty"`
+	Expr           string `yaml:"expr,omitempty"` // Condition expression, see expr.go
 }
 
-// ServiceOverride allows partial overrides of service definitions
+// ServiceOverride allows partial overrides of service definitions.
+//
+// Merge semantics: scalar fields replace the base value when set, lists named
+// "additional" are appended, maps are merged key by key (override wins), the
+// healthcheck is merged field by field and each integration block replaces
+// the base one.
 type ServiceOverride struct {
-	APIVersion string                 `yaml:"apiVersion"`
-	Kind       string                 `yaml:"kind"`
-	Metadata   OverrideMetadata       `yaml:"metadata"`
-	Spec       *ServiceSpecOverride   `yaml:"spec,omitempty"`
-	Routing    *RoutingConfigOverride `yaml:"routing,omitempty"`
+	APIVersion   string                 `yaml:"apiVersion"`
+	Kind         string                 `yaml:"kind"`
+	Metadata     OverrideMetadata       `yaml:"metadata"`
+	Spec         *ServiceSpecOverride   `yaml:"spec,omitempty"`
+	Routing      *RoutingConfigOverride `yaml:"routing,omitempty"`
+	Integrations *Integrations          `yaml:"integrations,omitempty"`
 }
 
 // OverrideMetadata identifies the service being overridden
@@ -276,24 +395,76 @@ type OverrideMetadata struct {
 // ServiceSpecOverride allows partial spec overrides
 type ServiceSpecOverride struct {
 	Image       *ImageSpec           `yaml:"image,omitempty"`
+	Container   *ContainerOverride   `yaml:"container,omitempty"`
 	Environment *EnvironmentOverride `yaml:"environment,omitempty"`
 	Volumes     *VolumeOverride      `yaml:"volumes,omitempty"`
+	Ports       *PortOverride        `yaml:"ports,omitempty"`
+	Networking  *NetworkOverride     `yaml:"networking,omitempty"`
+	HealthCheck *HealthCheck         `yaml:"healthcheck,omitempty"`
+}
+
+// ContainerOverride allows overriding container runtime settings
+type ContainerOverride struct {
+	Restart      *string           `yaml:"restart,omitempty"`
+	Command      *string           `yaml:"command,omitempty"`
+	Privileged   *bool             `yaml:"privileged,omitempty"`
+	Capabilities *CapabilitiesSpec `yaml:"capabilities,omitempty"` // Appended to the base lists
+	Devices      []string          `yaml:"devices,omitempty"`      // Appended
+	ShmSize      *string           `yaml:"shm_size,omitempty"`
+	Sysctls      map[string]string `yaml:"sysctls,omitempty"` // Merged by key
+	GPUEnabled   *bool             `yaml:"gpu_enabled,omitempty"`
 }
 
-// EnvironmentOverride allows adding environment variables
+// PortOverride allows removing, replacing and adding port mappings, applied
+// in that order. Mappings are matched by container port (e.g. "8096/udp").
+type PortOverride struct {
+	Remove     []string `yaml:"remove,omitempty"`
+	Replace    []string `yaml:"replace,omitempty"`
+	Additional []string `yaml:"additional,omitempty"`
+}
+
+// NetworkOverride allows changing the networks a service joins
+type NetworkOverride struct {
+	Mode        *string      `yaml:"mode,omitempty"` // Also clears the base modeTemplate
+	Additional  []NetworkRef `yaml:"additional,omitempty"`
+	Zones       []string     `yaml:"zones,omitempty"` // Replaces the base zones
+	IPv4Address *string      `yaml:"ipv4Address,omitempty"`
+}
+
+// EnvironmentOverride allows removing, replacing and adding environment
+// variables, applied in that order. Variables are matched by name.
 type EnvironmentOverride struct {
+	Remove     []string `yaml:"remove,omitempty"`
+	Replace    []EnvVar `yaml:"replace,omitempty"`
 	Additional []EnvVar `yaml:"additional,omitempty"`
 }
 
-// VolumeOverride allows adding volume mounts
+// VolumeOverride allows removing, replacing and adding volume mounts, applied
+// in that order. Mounts are matched by container path.
 type VolumeOverride struct {
+	Remove     []string      `yaml:"remove,omitempty"`
+	Replace    []VolumeMount `yaml:"replace,omitempty"`
 	Additional []VolumeMount `yaml:"additional,omitempty"`
 }
 
 // RoutingConfigOverride allows overriding routing settings
 type RoutingConfigOverride struct {
-	Subdomain *string `yaml:"subdomain,omitempty"`
-	Path      *string `yaml:"path,omitempty"`
+	Enabled        *bool            `yaml:"enabled,omitempty"`
+	Port           *int             `yaml:"port,omitempty"`
+	Subdomain      *string          `yaml:"subdomain,omitempty"`
+	Path           *string          `yaml:"path,omitempty"`
+	ForceSubdomain *bool            `yaml:"forceSubdomain,omitempty"`
+	Auth           *AuthConfig      `yaml:"auth,omitempty"` // Replaces the base auth block
+	Traefik        *TraefikOverride `yaml:"traefik,omitempty"`
+}
+
+// TraefikOverride allows overriding Traefik router settings
+type TraefikOverride struct {
+	Priority     *int              `yaml:"priority,omitempty"`
+	Middlewares  []string          `yaml:"middlewares,omitempty"`  // Appended
+	CustomLabels map[string]string `yaml:"customLabels,omitempty"` // Merged by key
+
+	MiddlewareDefinitions map[string]config.MiddlewareDefinition `yaml:"middlewareDefinitions,omitempty"` // Merged by name
 }
 
 // SourceConfig defines the user's source configuration
@@ -304,6 +475,16 @@ type SourceConfig struct {
 	Sources    []Source             `yaml:"sources"`
 	Cache      CacheConfig          `yaml:"cache,omitempty"`
 	Security   SecurityConfig       `yaml:"security,omitempty"`
+	Proxy      string               `yaml:"proxy,omitempty"` // Proxy URL of every source and of embedded refreshes, or "direct"
+}
+
+// SourceProxy returns the proxy a source fetches through: its own, else
+// the configuration's ("" for the proxy of the environment)
+func (c *SourceConfig) SourceProxy(src Source) string {
+	if src.Proxy != "" {
+		return src.Proxy
+	}
+	return c.Proxy
 }
 
 // SourceConfigMetadata contains version info
@@ -318,7 +499,10 @@ type Source struct {
 	URL      string `yaml:"url,omitempty"`
 	Path     string `yaml:"path,omitempty"`
 	Branch   string `yaml:"branch,omitempty"`
+	Ref      string `yaml:"ref,omitempty"`    // Tag or commit checked out instead of following Branch
+	SHA256   string `yaml:"sha256,omitempty"` // Checksum the archive of an archive source must match
 	SSHKey   string `yaml:"ssh_key,omitempty"`
+	Proxy    string `yaml:"proxy,omitempty"` // Proxy URL for http(s) fetches, or "direct"
 	Priority int    `yaml:"priority"`
 	Enabled  bool   `yaml:"enabled"`
 	Verified bool   `yaml:"verified,omitempty"`
@@ -328,6 +512,7 @@ type Source struct {
 type CacheConfig struct {
 	Directory string `yaml:"directory,omitempty"`
 	TTL       string `yaml:"ttl,omitempty"`
+	Timeout   string `yaml:"timeout,omitempty"` // Limit of a clone, update or download of a source
 }
 
 // SecurityConfig defines security settings for sources
@@ -379,6 +564,10 @@ type LockFile struct {
 	Services       map[string]LockedService `yaml:"services"`
 	InstallOrder   []string                 `yaml:"installOrder,omitempty"`
 	GeneratedFiles map[string]string        `yaml:"generatedFiles,omitempty"`
+
+	// HostPorts is the host port of each published port, keyed by
+	// HostPortKey, so allocated ports stay the same across generations
+	HostPorts map[string]int `yaml:"hostPorts,omitempty"`
 }
 
 // LockFileMetadata contains lock file version info
@@ -387,6 +576,21 @@ type LockFileMetadata struct {
 	GeneratedAt time.Time `yaml:"generatedAt"`
 	CLIVersion  string    `yaml:"cliVersion"`
 	ConfigHash  string    `yaml:"configHash"`
+
+	// Last generation swapped into the project
+	Generation *GenerationRecord `yaml:"generation,omitempty"`
+}
+
+// GenerationRecord identifies the generation transaction that last wrote
+// the project files
+type GenerationRecord struct {
+	ID          string    `yaml:"id"`
+	CommittedAt time.Time `yaml:"committedAt"`
+	Changed     int       `yaml:"changed"` // Files written or removed
+
+	// Inputs besides definitions and configuration that shape the output
+	CLIVersion  string `yaml:"cliVersion,omitempty"`
+	SecretsHash string `yaml:"secretsHash,omitempty"` // See SecretsHash
 }
 
 // LockedSource represents a pinned source
@@ -394,6 +598,7 @@ type LockedSource struct {
 	URL       string    `yaml:"url"`
 	Commit    string    `yaml:"commit"`
 	Branch    string    `yaml:"branch,omitempty"`
+	Ref       string    `yaml:"ref,omitempty"`
 	FetchedAt time.Time `yaml:"fetchedAt"`
 }
 
@@ -404,6 +609,8 @@ type LockedService struct {
 	Image             LockedImage `yaml:"image"`
 	ResolvedFrom      string      `yaml:"resolvedFrom"`
 	Enabled           bool        `yaml:"enabled"`
+	DefinitionHash    string      `yaml:"definitionHash,omitempty"` // Hash of the merged definition, used for incremental regeneration
+	PinnedCommit      string      `yaml:"pinnedCommit,omitempty"`   // Source commit of a pinned definition
 }
 
 // LockedImage represents a pinned container image
@@ -424,13 +631,15 @@ type ResolvedService struct {
 	FinalDefinition *ServiceDefinition
 	Dependencies    []string
 	Enabled         bool
+	PinnedCommit    string // Source commit of the definition when services.NAME.pin is set
 }
 
 // ResolutionGraph represents the resolved dependency graph of services
 type ResolutionGraph struct {
-	Services map[string]*ResolvedService
-	Order    []string
-	Errors   []ResolutionError
+	Services     map[string]*ResolvedService
+	Order        []string
+	Errors       []ResolutionError
+	Incompatible []CompatError // Services requiring a newer CLI (minCliVersion)
 }
 
 // ResolutionError represents an error during service resolution
@@ -438,6 +647,8 @@ type ResolutionError struct {
 	Service string
 	Message string
 	Cause   error
+	Skipped bool // The service was left out of the graph, and of generated files
+	Kept    bool // Its compose.yaml block of the previous generation was kept
 }
 
 func (e ResolutionError) Error() string {
@@ -2,8 +2,15 @@ package registry
 
 import (
 	"fmt"
+	"maps"
+	"net/netip"
+	"path"
 	"regexp"
+	"slices"
 	"strings"
+
+	"github.com/maiko/sdbx/internal/config"
+	"github.com/maiko/sdbx/internal/secrets"
 )
 
 // Validator validates service definitions
@@ -50,6 +57,263 @@ func (v *Validator) Validate(def *ServiceDefinition) []ValidationError {
 	// Validate security
 	errors = append(errors, v.validateSecurity(def)...)
 
+	// Validate condition expressions
+	errors = append(errors, v.validateConditions(def)...)
+
+	// Validate secret declarations and references
+	errors = append(errors, v.validateSecrets(def)...)
+
+	// Validate the post-install checklist
+	errors = append(errors, v.validatePostInstall(def)...)
+
+	// Validate the instancing block
+	errors = append(errors, v.validateInstancing(def)...)
+
+	// Validate external dependencies
+	errors = append(errors, v.validateExternalDependencies(def)...)
+
+	// Validate named volumes
+	errors = append(errors, v.validateVolumeDefinitions(def)...)
+
+	return errors
+}
+
+// validateVolumeDefinitions checks named volume declarations and that their
+// password secrets are declared by the service
+func (v *Validator) validateVolumeDefinitions(def *ServiceDefinition) []ValidationError {
+	var errors []ValidationError
+	add := func(field, message string) {
+		errors = append(errors, ValidationError{Field: field, Message: message, Severity: "error"})
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(def.Spec.VolumeDefinitions)) {
+		vol := def.Spec.VolumeDefinitions[name]
+		field := "spec.volumeDefinitions." + name
+		if err := config.ValidateVolumeName(name); err != nil {
+			add(field, err.Error())
+		}
+		if err := vol.Validate(); err != nil {
+			add(field, err.Error())
+		}
+		if vol.Secret != "" && !slices.ContainsFunc(def.Secrets, func(s SecretDef) bool { return s.Name == vol.Secret }) {
+			add(field+".secret", fmt.Sprintf("secret %s is not declared in secrets", vol.Secret))
+		}
+	}
+
+	return errors
+}
+
+// validateExternalDependencies checks names, kinds and default endpoints
+func (v *Validator) validateExternalDependencies(def *ServiceDefinition) []ValidationError {
+	var errors []ValidationError
+	add := func(field, message string) {
+		errors = append(errors, ValidationError{Field: field, Message: message, Severity: "error"})
+	}
+
+	seen := make(map[string]bool)
+	validKinds := []string{ExternalTCP, ExternalHTTP, ExternalNFS, ExternalSMB}
+	for i, dep := range def.Spec.ExternalDependencies {
+		field := fmt.Sprintf("spec.externalDependencies[%d]", i)
+		if !isValidSecretName(dep.Name) {
+			add(field+".name", fmt.Sprintf("invalid name %q (lowercase letters, digits and underscores)", dep.Name))
+		}
+		if seen[dep.Name] {
+			add(field+".name", fmt.Sprintf("duplicate external dependency %s", dep.Name))
+		}
+		seen[dep.Name] = true
+		if !slices.Contains(validKinds, dep.Kind) {
+			add(field+".kind", fmt.Sprintf("must be one of: %s", strings.Join(validKinds, ", ")))
+		}
+		if dep.Port < 0 || dep.Port > 65535 {
+			add(field+".port", "must be between 1 and 65535")
+		}
+		if dep.URL != "" && (dep.Kind != ExternalHTTP || (!strings.HasPrefix(dep.URL, "http://") && !strings.HasPrefix(dep.URL, "https://"))) {
+			add(field+".url", "url is only valid for http dependencies and must be http(s)")
+		}
+	}
+
+	return errors
+}
+
+// validateInstancing checks the instance limit and variable names
+func (v *Validator) validateInstancing(def *ServiceDefinition) []ValidationError {
+	inst := def.Instancing
+	if inst == nil {
+		return nil
+	}
+	var errors []ValidationError
+	add := func(field, message string) {
+		errors = append(errors, ValidationError{Field: field, Message: message, Severity: "error"})
+	}
+
+	if inst.Max < 0 {
+		add("instancing.max", "max cannot be negative")
+	}
+	if !inst.Allowed && (inst.Max != 0 || len(inst.Variables) > 0) {
+		add("instancing.allowed", "max and variables require allowed: true")
+	}
+	for _, name := range slices.Sorted(maps.Keys(inst.Variables)) {
+		if !isValidVariableName(name) {
+			add("instancing.variables."+name, fmt.Sprintf("invalid variable name %q (must start with a lowercase letter; letters, digits and underscores)", name))
+		}
+	}
+
+	return errors
+}
+
+// validatePostInstall checks step IDs, that listed secrets are declared and
+// that links are usable
+func (v *Validator) validatePostInstall(def *ServiceDefinition) []ValidationError {
+	post := def.PostInstall
+	if post == nil {
+		return nil
+	}
+	var errors []ValidationError
+	add := func(field, message string) {
+		errors = append(errors, ValidationError{Field: field, Message: message, Severity: "error"})
+	}
+	checkURL := func(field, rawURL string) {
+		if !strings.HasPrefix(rawURL, "https://") && !strings.HasPrefix(rawURL, "http://") {
+			add(field, fmt.Sprintf("invalid URL %q (must start with http:// or https://)", rawURL))
+		}
+	}
+
+	ids := make(map[string]bool)
+	for i, secret := range post.Secrets {
+		field := fmt.Sprintf("postInstall.secrets[%d]", i)
+		if !slices.ContainsFunc(def.Secrets, func(s SecretDef) bool { return s.Name == secret }) {
+			add(field, fmt.Sprintf("secret %s is not declared in secrets", secret))
+		}
+		ids[secret] = true
+	}
+	for i, step := range post.Steps {
+		field := fmt.Sprintf("postInstall.steps[%d]", i)
+		switch {
+		case !isValidServiceName(step.ID):
+			add(field+".id", fmt.Sprintf("invalid step id %q (lowercase letters, digits and hyphens)", step.ID))
+		case ids[step.ID]:
+			add(field+".id", fmt.Sprintf("duplicate step id %s", step.ID))
+		}
+		ids[step.ID] = true
+		if step.Title == "" {
+			add(field+".title", "title is required")
+		}
+		if step.URL != "" {
+			checkURL(field+".url", step.URL)
+		}
+	}
+	for i, link := range post.Links {
+		field := fmt.Sprintf("postInstall.links[%d]", i)
+		if link.Title == "" {
+			add(field+".title", "title is required")
+		}
+		checkURL(field+".url", link.URL)
+	}
+
+	return errors
+}
+
+// validateSecrets checks secret declarations and that every secretRef names
+// a secret the service declares or one sdbx manages globally
+func (v *Validator) validateSecrets(def *ServiceDefinition) []ValidationError {
+	var errors []ValidationError
+
+	declared := make(map[string]bool)
+	for i, secret := range def.Secrets {
+		field := fmt.Sprintf("secrets[%d]", i)
+		if !isValidSecretName(secret.Name) {
+			errors = append(errors, ValidationError{
+				Field:    field + ".name",
+				Message:  fmt.Sprintf("invalid secret name %q (lowercase letters, digits and underscores)", secret.Name),
+				Severity: "error",
+			})
+		}
+		if declared[secret.Name] {
+			errors = append(errors, ValidationError{
+				Field:    field + ".name",
+				Message:  fmt.Sprintf("duplicate secret %s", secret.Name),
+				Severity: "error",
+			})
+		}
+		declared[secret.Name] = true
+
+		if err := secrets.ValidateSpec(secretSpec(secret)); err != nil {
+			errors = append(errors, ValidationError{
+				Field:    field,
+				Message:  err.Error(),
+				Severity: "error",
+			})
+		}
+	}
+
+	check := func(field string, env EnvVar) {
+		if env.ValueFrom == nil {
+			return
+		}
+		if env.ValueFrom.SecretRef == "" {
+			if env.ValueFrom.FileEnv != "" {
+				errors = append(errors, ValidationError{
+					Field:    strings.TrimSuffix(field, "secretRef") + "fileEnv",
+					Message:  "fileEnv requires a secretRef",
+					Severity: "error",
+				})
+			}
+			return
+		}
+		ref := env.ValueFrom.SecretRef
+		if _, global := secrets.SecretFiles[ref+".txt"]; !declared[ref] && !global {
+			errors = append(errors, ValidationError{
+				Field:    field,
+				Message:  fmt.Sprintf("secretRef %s is not declared in secrets", ref),
+				Severity: "error",
+			})
+		}
+	}
+	for i, env := range def.Spec.Environment.Static {
+		check(fmt.Sprintf("spec.environment.static[%d].valueFrom.secretRef", i), env)
+	}
+	for i, env := range def.Spec.Environment.Conditional {
+		check(fmt.Sprintf("spec.environment.conditional[%d].valueFrom.secretRef", i), env.EnvVar)
+	}
+
+	return errors
+}
+
+// validateConditions checks the syntax of conditions.expr and of every
+// when: field that uses the expression language instead of a Go template
+func (v *Validator) validateConditions(def *ServiceDefinition) []ValidationError {
+	var errors []ValidationError
+
+	check := func(field, expr string) {
+		if expr == "" || IsTemplateCondition(expr) {
+			return
+		}
+		if err := ValidateExpression(expr); err != nil {
+			errors = append(errors, ValidationError{
+				Field:    field,
+				Message:  fmt.Sprintf("invalid condition expression: %v", err),
+				Severity: "error",
+			})
+		}
+	}
+
+	check("conditions.expr", def.Conditions.Expr)
+	for i, env := range def.Spec.Environment.Conditional {
+		check(fmt.Sprintf("spec.environment.conditional[%d].when", i), env.When)
+	}
+	for i, vol := range def.Spec.Volumes {
+		check(fmt.Sprintf("spec.volumes[%d].when", i), vol.When)
+	}
+	for i, port := range def.Spec.Ports.Conditional {
+		check(fmt.Sprintf("spec.ports.conditional[%d].when", i), port.When)
+	}
+	for i, network := range def.Spec.Networking.Networks {
+		check(fmt.Sprintf("spec.networking.networks[%d].when", i), network.When)
+	}
+	for i, dep := range def.Spec.Dependencies.Conditional {
+		check(fmt.Sprintf("spec.dependencies.conditional[%d].when", i), dep.When)
+	}
+
 	return errors
 }
 
@@ -79,6 +343,16 @@ func (v *Validator) validateMetadata(def *ServiceDefinition) []ValidationError {
 		})
 	}
 
+	if def.Metadata.MinCLIVersion != "" {
+		if _, _, err := parseVersion(def.Metadata.MinCLIVersion); err != nil {
+			errors = append(errors, ValidationError{
+				Field:    "metadata.minCliVersion",
+				Message:  err.Error(),
+				Severity: "error",
+			})
+		}
+	}
+
 	if def.Metadata.Category == "" {
 		errors = append(errors, ValidationError{
 			Field:    "metadata.category",
@@ -101,6 +375,23 @@ func (v *Validator) validateMetadata(def *ServiceDefinition) []ValidationError {
 		})
 	}
 
+	if def.Metadata.Icon != "" && !isValidAssetPath(def.Metadata.Icon) {
+		errors = append(errors, ValidationError{
+			Field:    "metadata.icon",
+			Message:  fmt.Sprintf("invalid image %q: must be a .png, .jpg, .gif or .webp file next to service.yaml", def.Metadata.Icon),
+			Severity: "error",
+		})
+	}
+	for i, shot := range def.Metadata.Screenshots {
+		if !isValidAssetPath(shot) {
+			errors = append(errors, ValidationError{
+				Field:    fmt.Sprintf("metadata.screenshots[%d]", i),
+				Message:  fmt.Sprintf("invalid image %q: must be a .png, .jpg, .gif or .webp file next to service.yaml", shot),
+				Severity: "error",
+			})
+		}
+	}
+
 	return errors
 }
 
@@ -117,6 +408,16 @@ func (v *Validator) validateSpec(def *ServiceDefinition) []ValidationError {
 		})
 	}
 
+	for i, platform := range def.Spec.Image.Platforms {
+		if !config.IsValidPlatform(platform) {
+			errors = append(errors, ValidationError{
+				Field:    fmt.Sprintf("spec.image.platforms[%d]", i),
+				Message:  fmt.Sprintf("invalid platform %q (e.g. linux/arm64)", platform),
+				Severity: "error",
+			})
+		}
+	}
+
 	// Validate container name template
 	if def.Spec.Container.NameTemplate == "" {
 		errors = append(errors, ValidationError{
@@ -134,10 +435,10 @@ func (v *Validator) validateSpec(def *ServiceDefinition) []ValidationError {
 
 	// Validate volumes
 	for i, vol := range def.Spec.Volumes {
-		if vol.HostPath == "" {
+		if (vol.HostPath == "") == (vol.Volume == "") {
 			errors = append(errors, ValidationError{
 				Field:    fmt.Sprintf("spec.volumes[%d].hostPath", i),
-				Message:  "hostPath is required",
+				Message:  "exactly one of hostPath or volume is required",
 				Severity: "error",
 			})
 		}
@@ -197,6 +498,60 @@ func (v *Validator) validateSpec(def *ServiceDefinition) []ValidationError {
 		}
 	}
 
+	// Validate network zones
+	for i, zone := range def.Spec.Networking.Zones {
+		if !isValidZone(zone) {
+			errors = append(errors, ValidationError{
+				Field:    fmt.Sprintf("spec.networking.zones[%d]", i),
+				Message:  fmt.Sprintf("invalid zone: %s", zone),
+				Severity: "error",
+			})
+		}
+	}
+	if addr := def.Spec.Networking.IPv4Address; addr != "" && !strings.Contains(addr, "{{") {
+		if ip, err := netip.ParseAddr(addr); err != nil || !ip.Is4() {
+			errors = append(errors, ValidationError{
+				Field:    "spec.networking.ipv4Address",
+				Message:  fmt.Sprintf("invalid IPv4 address: %s", addr),
+				Severity: "error",
+			})
+		}
+	}
+	if def.Spec.Networking.IPv4Address != "" && def.Spec.Networking.Mode != "" && def.Spec.Networking.Mode != "bridge" {
+		errors = append(errors, ValidationError{
+			Field:    "spec.networking.ipv4Address",
+			Message:  fmt.Sprintf("a static address needs bridge networking, not mode %s", def.Spec.Networking.Mode),
+			Severity: "error",
+		})
+	}
+	if len(def.Spec.Networking.Zones) > 0 && def.Routing.Enabled &&
+		!slices.Contains(def.Spec.Networking.Zones, ZoneFrontend) {
+		errors = append(errors, ValidationError{
+			Field:    "spec.networking.zones",
+			Message:  "routed services must join the frontend zone to be reachable by Traefik",
+			Severity: "error",
+		})
+	}
+	if len(def.Spec.Networking.Zones) > 0 && def.Spec.Networking.IPv4Address != "" &&
+		!slices.Contains(def.Spec.Networking.Zones, ZoneFrontend) {
+		errors = append(errors, ValidationError{
+			Field:    "spec.networking.zones",
+			Message:  "a static address on the proxy network needs the frontend zone",
+			Severity: "error",
+		})
+	}
+
+	// Validate requirements
+	for i, req := range def.Spec.Requires {
+		if !slices.Contains(Requirements, req) {
+			errors = append(errors, ValidationError{
+				Field:    fmt.Sprintf("spec.requires[%d]", i),
+				Message:  fmt.Sprintf("unknown requirement %q (known: %s)", req, strings.Join(Requirements, ", ")),
+				Severity: "error",
+			})
+		}
+	}
+
 	// Validate dependencies
 	for i, dep := range def.Spec.Dependencies.Conditional {
 		if dep.Name == "" {
@@ -257,6 +612,20 @@ func (v *Validator) validateRouting(def *ServiceDefinition) []ValidationError {
 		})
 	}
 
+	for _, name := range slices.Sorted(maps.Keys(def.Routing.Traefik.MiddlewareDefinitions)) {
+		err := config.ValidateMiddlewareName(name)
+		if err == nil {
+			err = def.Routing.Traefik.MiddlewareDefinitions[name].Validate()
+		}
+		if err != nil {
+			errors = append(errors, ValidationError{
+				Field:    "routing.traefik.middlewareDefinitions." + name,
+				Message:  err.Error(),
+				Severity: "error",
+			})
+		}
+	}
+
 	return errors
 }
 
@@ -425,6 +794,31 @@ func isValidSubdomain(subdomain string) bool {
 	return matched
 }
 
+// isValidSecretName checks if a name is usable as a secret file name
+func isValidSecretName(name string) bool {
+	matched, _ := regexp.MatchString(`^[a-z][a-z0-9_]*$`, name)
+	return matched
+}
+
+// isValidVariableName checks if a name can be used as {{ .Instance.Vars.<name> }}
+func isValidVariableName(name string) bool {
+	matched, _ := regexp.MatchString(`^[a-z][a-zA-Z0-9_]*$`, name)
+	return matched
+}
+
+// isValidAssetPath checks that an icon or screenshot is a raster image
+// inside the service directory. SVG is refused as it can carry scripts.
+func isValidAssetPath(p string) bool {
+	if p == "" || path.IsAbs(p) || strings.Contains(p, "\\") || path.Clean(p) != p || strings.HasPrefix(p, "../") || p == ".." {
+		return false
+	}
+	switch strings.ToLower(path.Ext(p)) {
+	case ".png", ".jpg", ".jpeg", ".gif", ".webp":
+		return true
+	}
+	return false
+}
+
 // isValidCategory checks if a category is valid
 func isValidCategory(category ServiceCategory) bool {
 	valid := map[ServiceCategory]bool{
@@ -437,3 +831,12 @@ func isValidCategory(category ServiceCategory) bool {
 	}
 	return valid[category]
 }
+
+// isValidZone checks if a network zone name is known
+func isValidZone(zone string) bool {
+	switch zone {
+	case ZoneFrontend, ZoneBackend, ZoneDownloads:
+		return true
+	}
+	return false
+}
@@ -2,6 +2,8 @@ package registry
 
 import (
 	"testing"
+
+	"github.com/maiko/sdbx/internal/config"
 )
 
 // TestNewValidator verifies validator construction
@@ -319,6 +321,109 @@ func TestValidateSpec(t *testing.T) {
 			wantError: true,
 			field:     "spec.healthcheck.test",
 		},
+		{
+			name: "invalid network zone",
+			def: &ServiceDefinition{
+				Metadata: ServiceMetadata{
+					Name:     "test",
+					Version:  "1.0.0",
+					Category: CategoryMedia,
+				},
+				Spec: ServiceSpec{
+					Image:      ImageSpec{Repository: "test/image"},
+					Container:  ContainerSpec{NameTemplate: "{{ .Name }}"},
+					Networking: NetworkSpec{Zones: []string{"dmz"}},
+				},
+			},
+			wantError: true,
+			field:     "spec.networking.zones[0]",
+		},
+		{
+			name: "invalid static address",
+			def: &ServiceDefinition{
+				Metadata: ServiceMetadata{
+					Name:     "test",
+					Version:  "1.0.0",
+					Category: CategoryMedia,
+				},
+				Spec: ServiceSpec{
+					Image:      ImageSpec{Repository: "test/image"},
+					Container:  ContainerSpec{NameTemplate: "{{ .Name }}"},
+					Networking: NetworkSpec{IPv4Address: "fd00::53"},
+				},
+			},
+			wantError: true,
+			field:     "spec.networking.ipv4Address",
+		},
+		{
+			name: "unknown requirement",
+			def: &ServiceDefinition{
+				Metadata: ServiceMetadata{
+					Name:     "test",
+					Version:  "1.0.0",
+					Category: CategoryMedia,
+				},
+				Spec: ServiceSpec{
+					Image:     ImageSpec{Repository: "test/image"},
+					Container: ContainerSpec{NameTemplate: "{{ .Name }}"},
+					Requires:  []string{RequireGeoIP, "gpu"},
+				},
+			},
+			wantError: true,
+			field:     "spec.requires[1]",
+		},
+		{
+			name: "routed service outside frontend zone",
+			def: &ServiceDefinition{
+				Metadata: ServiceMetadata{
+					Name:     "test",
+					Version:  "1.0.0",
+					Category: CategoryMedia,
+				},
+				Spec: ServiceSpec{
+					Image:      ImageSpec{Repository: "test/image"},
+					Container:  ContainerSpec{NameTemplate: "{{ .Name }}"},
+					Networking: NetworkSpec{Zones: []string{ZoneBackend}},
+				},
+				Routing: RoutingConfig{Enabled: true, Port: 8080},
+			},
+			wantError: true,
+			field:     "spec.networking.zones",
+		},
+		{
+			name: "static address outside frontend zone",
+			def: &ServiceDefinition{
+				Metadata: ServiceMetadata{
+					Name:     "test",
+					Version:  "1.0.0",
+					Category: CategoryMedia,
+				},
+				Spec: ServiceSpec{
+					Image:      ImageSpec{Repository: "test/image"},
+					Container:  ContainerSpec{NameTemplate: "{{ .Name }}"},
+					Networking: NetworkSpec{Zones: []string{ZoneBackend}, IPv4Address: "172.30.0.53"},
+				},
+			},
+			wantError: true,
+			field:     "spec.networking.zones",
+		},
+		{
+			name: "valid network zones",
+			def: &ServiceDefinition{
+				Metadata: ServiceMetadata{
+					Name:     "test",
+					Version:  "1.0.0",
+					Category: CategoryMedia,
+				},
+				Spec: ServiceSpec{
+					Image:      ImageSpec{Repository: "test/image"},
+					Container:  ContainerSpec{NameTemplate: "{{ .Name }}"},
+					Networking: NetworkSpec{Zones: []string{ZoneFrontend, ZoneBackend}},
+				},
+				Routing: RoutingConfig{Enabled: true, Port: 8080},
+			},
+			wantError: false,
+		},
 	}
 
 	for _, tt := range tests {
@@ -479,6 +584,31 @@ func TestValidateRouting(t *testing.T) {
 			wantError: true,
 			field:     "routing.pathRouting.strategy",
 		},
+		{
+			name: "invalid middleware definition",
+			def: &ServiceDefinition{
+				Metadata: ServiceMetadata{
+					Name:     "test",
+					Version:  "1.0.0",
+					Category: CategoryMedia,
+				},
+				Spec: ServiceSpec{
+					Image:     ImageSpec{Repository: "test/image"},
+					Container: ContainerSpec{NameTemplate: "{{ .Name }}"},
+				},
+				Routing: RoutingConfig{
+					Enabled: true,
+					Port:    8080,
+					Traefik: TraefikConfig{
+						MiddlewareDefinitions: map[string]config.MiddlewareDefinition{
+							"limit": {RateLimit: &config.RateLimitMiddleware{}},
+						},
+					},
+				},
+			},
+			wantError: true,
+			field:     "routing.traefik.middlewareDefinitions.limit",
+		},
 	}
 
 	for _, tt := range tests {
@@ -621,6 +751,265 @@ func TestValidateSecurity(t *testing.T) {
 	}
 }
 
+func TestValidateSecrets(t *testing.T) {
+	v := NewValidator()
+
+	secretRef := func(ref string) EnvVar {
+		return EnvVar{Name: "TOKEN", ValueFrom: &ValueSource{SecretRef: ref}}
+	}
+
+	tests := []struct {
+		name      string
+		secrets   []SecretDef
+		env       []EnvVar
+		wantField string
+	}{
+		{
+			name:    "typed secrets",
+			secrets: []SecretDef{{Name: "api_key", Type: "hex", Length: 40}, {Name: "signing_key", Type: "ed25519"}},
+			env:     []EnvVar{secretRef("api_key")},
+		},
+		{
+			name: "global secret reference",
+			env:  []EnvVar{secretRef("sonarr_api_key")},
+		},
+		{
+			name:      "unknown type",
+			secrets:   []SecretDef{{Name: "api_key", Type: "random"}},
+			wantField: "secrets[0]",
+		},
+		{
+			name:      "length below minimum",
+			secrets:   []SecretDef{{Name: "signing_key", Type: "rsa", Length: 1024}},
+			wantField: "secrets[0]",
+		},
+		{
+			name:      "charset on non-password type",
+			secrets:   []SecretDef{{Name: "api_key", Type: "hex", Charset: "symbols"}},
+			wantField: "secrets[0]",
+		},
+		{
+			name:      "invalid name",
+			secrets:   []SecretDef{{Name: "API-KEY", Type: "password"}},
+			wantField: "secrets[0].name",
+		},
+		{
+			name:      "duplicate name",
+			secrets:   []SecretDef{{Name: "api_key", Type: "password"}, {Name: "api_key", Type: "hex"}},
+			wantField: "secrets[1].name",
+		},
+		{
+			name:      "fileEnv without secretRef",
+			env:       []EnvVar{{Name: "TOKEN", ValueFrom: &ValueSource{FileEnv: "TOKEN_FILE"}}},
+			wantField: "spec.environment.static[0].valueFrom.fileEnv",
+		},
+		{
+			name:      "undeclared reference",
+			env:       []EnvVar{secretRef("missing_token")},
+			wantField: "spec.environment.static[0].valueFrom.secretRef",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			def := &ServiceDefinition{
+				Spec:    ServiceSpec{Environment: EnvironmentSpec{Static: tt.env}},
+				Secrets: tt.secrets,
+			}
+			errors := v.validateSecrets(def)
+
+			if tt.wantField == "" {
+				if len(errors) > 0 {
+					t.Errorf("expected no errors, got %v", errors)
+				}
+				return
+			}
+			found := false
+			for _, e := range errors {
+				if e.Field == tt.wantField && e.Severity == "error" {
+					found = true
+				}
+			}
+			if !found {
+				t.Errorf("expected error on %s, got %v", tt.wantField, errors)
+			}
+		})
+	}
+}
+
+func TestValidatePostInstall(t *testing.T) {
+	v := NewValidator()
+	secrets := []SecretDef{{Name: "claim_token", Type: "manual"}}
+
+	tests := []struct {
+		name      string
+		post      *PostInstall
+		wantField string
+	}{
+		{
+			name: "valid",
+			post: &PostInstall{
+				Secrets: []string{"claim_token"},
+				Steps:   []PostInstallStep{{ID: "libraries", Title: "Add libraries", URL: "https://example.com"}},
+				Links:   []PostInstallLink{{Title: "Docs", URL: "https://example.com/docs"}},
+			},
+		},
+		{
+			name:      "undeclared secret",
+			post:      &PostInstall{Secrets: []string{"api_key"}},
+			wantField: "postInstall.secrets[0]",
+		},
+		{
+			name:      "invalid step id",
+			post:      &PostInstall{Steps: []PostInstallStep{{ID: "Add Libraries", Title: "Add libraries"}}},
+			wantField: "postInstall.steps[0].id",
+		},
+		{
+			name:      "duplicate step id",
+			post:      &PostInstall{Steps: []PostInstallStep{{ID: "setup", Title: "A"}, {ID: "setup", Title: "B"}}},
+			wantField: "postInstall.steps[1].id",
+		},
+		{
+			name:      "missing title",
+			post:      &PostInstall{Steps: []PostInstallStep{{ID: "setup"}}},
+			wantField: "postInstall.steps[0].title",
+		},
+		{
+			name:      "link without scheme",
+			post:      &PostInstall{Links: []PostInstallLink{{Title: "Docs", URL: "example.com"}}},
+			wantField: "postInstall.links[0].url",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errors := v.validatePostInstall(&ServiceDefinition{Secrets: secrets, PostInstall: tt.post})
+
+			if tt.wantField == "" {
+				if len(errors) > 0 {
+					t.Errorf("expected no errors, got %v", errors)
+				}
+				return
+			}
+			found := false
+			for _, e := range errors {
+				if e.Field == tt.wantField {
+					found = true
+				}
+			}
+			if !found {
+				t.Errorf("expected error on %s, got %v", tt.wantField, errors)
+			}
+		})
+	}
+}
+
+func TestValidateInstancing(t *testing.T) {
+	v := NewValidator()
+
+	tests := []struct {
+		name       string
+		instancing *Instancing
+		wantField  string
+	}{
+		{name: "absent"},
+		{name: "valid", instancing: &Instancing{Allowed: true, Max: 2, Variables: map[string]string{"quality": "1080p"}}},
+		{name: "negative max", instancing: &Instancing{Allowed: true, Max: -1}, wantField: "instancing.max"},
+		{name: "max without allowed", instancing: &Instancing{Max: 2}, wantField: "instancing.allowed"},
+		{
+			name:       "invalid variable",
+			instancing: &Instancing{Allowed: true, Variables: map[string]string{"Quality-Profile": ""}},
+			wantField:  "instancing.variables.Quality-Profile",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errors := v.validateInstancing(&ServiceDefinition{Instancing: tt.instancing})
+
+			if tt.wantField == "" {
+				if len(errors) > 0 {
+					t.Errorf("expected no errors, got %v", errors)
+				}
+				return
+			}
+			if len(errors) != 1 || errors[0].Field != tt.wantField {
+				t.Errorf("expected an error on %s, got %v", tt.wantField, errors)
+			}
+		})
+	}
+}
+
+func TestValidateExternalDependencies(t *testing.T) {
+	v := NewValidator()
+
+	tests := []struct {
+		name      string
+		deps      []ExternalDependency
+		wantField string
+	}{
+		{name: "valid", deps: []ExternalDependency{
+			{Name: "nas", Kind: ExternalNFS, Host: "192.168.1.10", Path: "/volume1/media"},
+			{Name: "auth_api", Kind: ExternalHTTP, URL: "https://id.example.com/health"},
+		}},
+		{name: "bad name", deps: []ExternalDependency{{Name: "My-NAS", Kind: ExternalNFS}}, wantField: "spec.externalDependencies[0].name"},
+		{name: "duplicate", deps: []ExternalDependency{{Name: "db", Kind: ExternalTCP}, {Name: "db", Kind: ExternalTCP}}, wantField: "spec.externalDependencies[1].name"},
+		{name: "unknown kind", deps: []ExternalDependency{{Name: "db", Kind: "postgres"}}, wantField: "spec.externalDependencies[0].kind"},
+		{name: "url on tcp", deps: []ExternalDependency{{Name: "db", Kind: ExternalTCP, URL: "http://db"}}, wantField: "spec.externalDependencies[0].url"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			def := &ServiceDefinition{Spec: ServiceSpec{ExternalDependencies: tt.deps}}
+			errors := v.validateExternalDependencies(def)
+
+			if tt.wantField == "" {
+				if len(errors) > 0 {
+					t.Errorf("expected no errors, got %v", errors)
+				}
+				return
+			}
+			if len(errors) != 1 || errors[0].Field != tt.wantField {
+				t.Errorf("expected an error on %s, got %v", tt.wantField, errors)
+			}
+		})
+	}
+}
+
+func TestValidateVolumeDefinitions(t *testing.T) {
+	v := NewValidator()
+	smb := config.VolumeDefinition{Type: config.VolumeTypeCIFS, Server: "nas.lan", Path: "media", Username: "sdbx", Secret: "nas_password"}
+
+	tests := []struct {
+		name      string
+		volumes   map[string]config.VolumeDefinition
+		secrets   []SecretDef
+		wantField string
+	}{
+		{name: "valid", volumes: map[string]config.VolumeDefinition{"media": smb}, secrets: []SecretDef{{Name: "nas_password", Type: "manual"}}},
+		{name: "bad name", volumes: map[string]config.VolumeDefinition{"Media": {}}, wantField: "spec.volumeDefinitions.Media"},
+		{name: "missing server", volumes: map[string]config.VolumeDefinition{"media": {Type: config.VolumeTypeNFS, Path: "/media"}}, wantField: "spec.volumeDefinitions.media"},
+		{name: "undeclared secret", volumes: map[string]config.VolumeDefinition{"media": smb}, wantField: "spec.volumeDefinitions.media.secret"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			def := &ServiceDefinition{Spec: ServiceSpec{VolumeDefinitions: tt.volumes}, Secrets: tt.secrets}
+			errors := v.validateVolumeDefinitions(def)
+
+			if tt.wantField == "" {
+				if len(errors) > 0 {
+					t.Errorf("expected no errors, got %v", errors)
+				}
+				return
+			}
+			if len(errors) != 1 || errors[0].Field != tt.wantField {
+				t.Errorf("expected an error on %s, got %v", tt.wantField, errors)
+			}
+		})
+	}
+}
+
 // TestValidateWithTrustLevel verifies trust level validation
 func TestValidateWithTrustLevel(t *testing.T) {
 	v := NewValidator()
@@ -957,6 +1346,35 @@ func TestIsValidSubdomain(t *testing.T) {
 	}
 }
 
+// TestIsValidAssetPath verifies icon and screenshot paths stay inside the service directory
+func TestIsValidAssetPath(t *testing.T) {
+	tests := []struct {
+		path  string
+		valid bool
+	}{
+		{"icon.png", true},
+		{"screenshots/queue.jpg", true},
+		{"dashboard.WEBP", true},
+		{"", false},
+		{"icon.svg", false},
+		{"README.md", false},
+		{"/etc/icon.png", false},
+		{"../other/icon.png", false},
+		{"shots/../../icon.png", false},
+		{"./icon.png", false},
+		{"https://example.com/icon.png", false},
+		{"shots\\icon.png", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			if got := isValidAssetPath(tt.path); got != tt.valid {
+				t.Errorf("isValidAssetPath(%q) = %v, want %v", tt.path, got, tt.valid)
+			}
+		})
+	}
+}
+
 // TestIsValidCategory verifies category validation
 func TestIsValidCategory(t *testing.T) {
 	validCategories := []ServiceCategory{
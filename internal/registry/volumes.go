@@ -0,0 +1,39 @@
+package registry
+
+import (
+	"fmt"
+	"maps"
+
+	"github.com/maiko/sdbx/internal/config"
+)
+
+// VolumeDefinitions returns the named volumes available to resolved services:
+// those declared by their definitions, replaced by name by the shared volumes
+// of .sdbx.yaml. A mount referencing a volume declared nowhere is an error.
+func (g *ResolutionGraph) VolumeDefinitions(cfg *config.Config) (map[string]config.VolumeDefinition, error) {
+	volumes := make(map[string]config.VolumeDefinition)
+	for _, name := range g.Order {
+		svc, ok := g.Services[name]
+		if !ok || !svc.Enabled || svc.FinalDefinition == nil {
+			continue
+		}
+		maps.Copy(volumes, svc.FinalDefinition.Spec.VolumeDefinitions)
+	}
+	maps.Copy(volumes, cfg.Volumes)
+
+	for _, name := range g.Order {
+		svc, ok := g.Services[name]
+		if !ok || !svc.Enabled || svc.FinalDefinition == nil {
+			continue
+		}
+		for _, mount := range svc.FinalDefinition.Spec.Volumes {
+			if mount.Volume == "" {
+				continue
+			}
+			if _, ok := volumes[mount.Volume]; !ok {
+				return nil, fmt.Errorf("service %s mounts undefined volume %s: declare it under volumes in .sdbx.yaml", name, mount.Volume)
+			}
+		}
+	}
+	return volumes, nil
+}
@@ -0,0 +1,76 @@
+package registry
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/maiko/sdbx/internal/config"
+)
+
+func TestResolutionGraphVolumeDefinitions(t *testing.T) {
+	def := &ServiceDefinition{Spec: ServiceSpec{
+		Volumes: []VolumeMount{
+			{Volume: "media", ContainerPath: "/media"},
+			{Volume: "backups", ContainerPath: "/backups"},
+		},
+		VolumeDefinitions: map[string]config.VolumeDefinition{
+			"media": {Type: config.VolumeTypeNFS, Server: "192.168.1.10", Path: "/media"},
+		},
+	}}
+	graph := &ResolutionGraph{
+		Services: map[string]*ResolvedService{"app": {Name: "app", Enabled: true, FinalDefinition: def}},
+		Order:    []string{"app"},
+	}
+
+	cfg := config.DefaultConfig()
+	if _, err := graph.VolumeDefinitions(cfg); err == nil || !strings.Contains(err.Error(), "undefined volume backups") {
+		t.Fatalf("expected an error for the undeclared backups volume, got %v", err)
+	}
+
+	cfg.Volumes = map[string]config.VolumeDefinition{
+		"media":   {Type: config.VolumeTypeNFS, Server: "nas.lan", Path: "/volume1/media"},
+		"backups": {},
+	}
+	volumes, err := graph.VolumeDefinitions(cfg)
+	if err != nil {
+		t.Fatalf("VolumeDefinitions() error = %v", err)
+	}
+	if len(volumes) != 2 || volumes["media"].Server != "nas.lan" {
+		t.Errorf("shared volumes should replace definitions by name: %+v", volumes)
+	}
+}
+
+func TestConfigSecretSpecsVolumes(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Volumes = map[string]config.VolumeDefinition{
+		"media": {Type: config.VolumeTypeCIFS, Server: "nas.lan", Path: "media", Username: "sdbx", Secret: "nas_password"},
+		"music": {Type: config.VolumeTypeCIFS, Server: "nas.lan", Path: "music", Username: "sdbx", Secret: "nas_password"},
+	}
+	specs := ConfigSecretSpecs(cfg)
+	if len(specs) != 1 || specs[0].Name != "nas_password" || specs[0].Type != "manual" {
+		t.Errorf("ConfigSecretSpecs() = %+v, want one manual nas_password secret", specs)
+	}
+}
+
+func TestConfigSecretSpecsSMTP(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.SMTP = config.SMTPConfig{Host: "smtp.example.com", Sender: "sdbx@example.com"}
+	if specs := ConfigSecretSpecs(cfg); len(specs) != 0 {
+		t.Errorf("ConfigSecretSpecs() = %+v, want none without an SMTP username", specs)
+	}
+
+	cfg.SMTP.User = "sdbx"
+	specs := ConfigSecretSpecs(cfg)
+	if len(specs) != 1 || specs[0].Name != config.SMTPPasswordSecret || specs[0].Type != "manual" {
+		t.Errorf("ConfigSecretSpecs() = %+v, want one manual %s secret", specs, config.SMTPPasswordSecret)
+	}
+}
+
+func TestConfigSecretSpecsGeoIP(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.GeoIP = config.GeoIPConfig{AccountID: "123456"}
+	specs := ConfigSecretSpecs(cfg)
+	if len(specs) != 1 || specs[0].Name != config.GeoIPLicenseKeySecret || specs[0].Type != "manual" {
+		t.Errorf("ConfigSecretSpecs() = %+v, want one manual %s secret", specs, config.GeoIPLicenseKeySecret)
+	}
+}
@@ -0,0 +1,106 @@
+// Package sabnzbd is a minimal client for the SABnzbd API.
+package sabnzbd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/maiko/sdbx/internal/config"
+	"github.com/maiko/sdbx/internal/netproxy"
+)
+
+// requestTimeout bounds a single API call
+const requestTimeout = 15 * time.Second
+
+// ContainerURL is the SABnzbd API as seen from the Docker networks
+const ContainerURL = "http://sdbx-sabnzbd:8080"
+
+// Client talks to one SABnzbd instance
+type Client struct {
+	BaseURL string
+	APIKey  string
+	HTTP    *http.Client
+}
+
+// New creates a client from download_clients.sabnzbd, reading the API key
+// from the project's secrets. baseURL is used when no URL is configured.
+func New(projectDir string, cfg *config.Config, baseURL string) (*Client, error) {
+	s := cfg.DownloadClients.SABnzbd
+	if s.URL != "" {
+		baseURL = s.URL
+	}
+	key, err := os.ReadFile(filepath.Join(projectDir, "secrets", s.APIKeySecret+".txt"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read SABnzbd API key: %w", err)
+	}
+	return &Client{
+		BaseURL: strings.TrimSuffix(baseURL, "/"),
+		APIKey:  strings.TrimSpace(string(key)),
+		HTTP:    netproxy.Client(cfg.Proxy.For(config.ProxySABnzbd), cfg.Proxy.NoProxy, requestTimeout),
+	}, nil
+}
+
+// Paused reports whether the download queue is paused
+func (c *Client) Paused(ctx context.Context) (bool, error) {
+	var resp struct {
+		Queue struct {
+			Paused bool `json:"paused"`
+		} `json:"queue"`
+	}
+	if err := c.call(ctx, "queue", &resp); err != nil {
+		return false, err
+	}
+	return resp.Queue.Paused, nil
+}
+
+// Pause pauses the download queue
+func (c *Client) Pause(ctx context.Context) error {
+	return c.call(ctx, "pause", nil)
+}
+
+// Resume resumes the download queue
+func (c *Client) Resume(ctx context.Context) error {
+	return c.call(ctx, "resume", nil)
+}
+
+// call runs an API mode and decodes its JSON response into v
+func (c *Client) call(ctx context.Context, mode string, v interface{}) error {
+	query := url.Values{"mode": {mode}, "apikey": {c.APIKey}, "output": {"json"}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.BaseURL+"/api?"+query.Encode(), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		// The request URL holds the API key
+		return fmt.Errorf("SABnzbd unreachable at %s", c.BaseURL)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("SABnzbd %s: %s", mode, resp.Status)
+	}
+
+	var raw json.RawMessage
+	if err := json.NewDecoder(resp.Body).Decode(&raw); err != nil {
+		return fmt.Errorf("SABnzbd %s: invalid response: %w", mode, err)
+	}
+	// Failures are reported as {"status": false, "error": "..."}
+	var status struct {
+		Status *bool  `json:"status"`
+		Error  string `json:"error"`
+	}
+	if json.Unmarshal(raw, &status) == nil && status.Status != nil && !*status.Status {
+		return fmt.Errorf("SABnzbd %s: %s", mode, status.Error)
+	}
+	if v != nil {
+		return json.Unmarshal(raw, v)
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+//go:build e2e
+
+package sabnzbd
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/maiko/sdbx/internal/config"
+	"github.com/maiko/sdbx/internal/e2e"
+)
+
+// apiKeyLine matches the API key SABnzbd writes to sabnzbd.ini on first start
+var apiKeyLine = regexp.MustCompile(`(?m)^api_key = (\w+)`)
+
+func TestE2EClient(t *testing.T) {
+	sab := e2e.Run(t, "lscr.io/linuxserver/sabnzbd:latest", nil, "8080")
+	baseURL := sab.URL("8080")
+	sab.WaitForHTTP(baseURL, 2*time.Minute)
+
+	ctx := context.Background()
+	ini, err := sab.Exec(ctx, "cat", "/config/sabnzbd.ini")
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := apiKeyLine.FindStringSubmatch(ini)
+	if m == nil {
+		t.Fatalf("no api_key in sabnzbd.ini:\n%s", ini)
+	}
+
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "secrets"), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "secrets", "sabnzbd_api_key.txt"), []byte(m[1]), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cfg := config.DefaultConfig()
+	cfg.DownloadClients.SABnzbd = config.SABnzbdConfig{URL: baseURL, APIKeySecret: "sabnzbd_api_key"}
+
+	client, err := New(dir, cfg, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Pause(ctx); err != nil {
+		t.Fatalf("Pause() error = %v", err)
+	}
+	if paused, err := client.Paused(ctx); err != nil || !paused {
+		t.Errorf("Paused() = %v, %v after Pause()", paused, err)
+	}
+	if err := client.Resume(ctx); err != nil {
+		t.Fatalf("Resume() error = %v", err)
+	}
+	if paused, err := client.Paused(ctx); err != nil || paused {
+		t.Errorf("Paused() = %v, %v after Resume()", paused, err)
+	}
+}
@@ -0,0 +1,66 @@
+package sabnzbd
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/maiko/sdbx/internal/config"
+)
+
+func TestClient(t *testing.T) {
+	var modes []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("apikey") != "k3y" {
+			fmt.Fprint(w, `{"status": false, "error": "API Key Incorrect"}`)
+			return
+		}
+		mode := r.URL.Query().Get("mode")
+		modes = append(modes, mode)
+		switch mode {
+		case "queue":
+			fmt.Fprint(w, `{"queue": {"paused": true, "slots": []}}`)
+		default:
+			fmt.Fprint(w, `{"status": true}`)
+		}
+	}))
+	defer srv.Close()
+
+	projectDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(projectDir, "secrets"), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(projectDir, "secrets", "sab.txt"), []byte("k3y\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cfg := config.DefaultConfig()
+	cfg.DownloadClients.SABnzbd = config.SABnzbdConfig{APIKeySecret: "sab"}
+
+	client, err := New(projectDir, cfg, srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	if paused, err := client.Paused(ctx); err != nil || !paused {
+		t.Errorf("Paused() = %v, %v", paused, err)
+	}
+	if err := client.Pause(ctx); err != nil {
+		t.Errorf("Pause() error = %v", err)
+	}
+	if err := client.Resume(ctx); err != nil {
+		t.Errorf("Resume() error = %v", err)
+	}
+	if strings.Join(modes, ",") != "queue,pause,resume" {
+		t.Errorf("modes = %v", modes)
+	}
+
+	client.APIKey = "wrong"
+	if err := client.Pause(ctx); err == nil || !strings.Contains(err.Error(), "API Key Incorrect") {
+		t.Errorf("Pause() with a bad key error = %v", err)
+	}
+}
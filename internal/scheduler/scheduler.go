@@ -0,0 +1,74 @@
+// Package scheduler runs periodic background jobs such as health sampling
+// and alert evaluation.
+package scheduler
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+// Job is a task run immediately and then every Interval
+type Job struct {
+	Name     string
+	Interval time.Duration
+	Run      func(ctx context.Context) error
+}
+
+// Scheduler runs jobs on their own intervals. A job never overlaps with
+// itself; a slow run delays its next tick instead.
+type Scheduler struct {
+	jobs []Job
+
+	// Logger receives job errors, the standard logger when nil
+	Logger *log.Logger
+}
+
+// New creates a scheduler with the given jobs
+func New(jobs ...Job) *Scheduler {
+	return &Scheduler{jobs: jobs}
+}
+
+// Add registers another job. Jobs added after Run has started are ignored.
+func (s *Scheduler) Add(job Job) {
+	s.jobs = append(s.jobs, job)
+}
+
+// Run runs every job until ctx is cancelled and returns once all of them
+// have stopped. Job errors are logged and do not stop the job.
+func (s *Scheduler) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, job := range s.jobs {
+		wg.Add(1)
+		go func(job Job) {
+			defer wg.Done()
+			runJob(ctx, job, s.logger())
+		}(job)
+	}
+	wg.Wait()
+}
+
+// logger returns the logger of job errors
+func (s *Scheduler) logger() *log.Logger {
+	if s.Logger == nil {
+		return log.Default()
+	}
+	return s.Logger
+}
+
+func runJob(ctx context.Context, job Job, logger *log.Logger) {
+	ticker := time.NewTicker(job.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := job.Run(ctx); err != nil && ctx.Err() == nil {
+			logger.Printf("Warning [scheduler.%s]: %v", job.Name, err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSchedulerRun(t *testing.T) {
+	var fast, failing atomic.Int32
+	s := New(Job{
+		Name:     "fast",
+		Interval: 10 * time.Millisecond,
+		Run: func(context.Context) error {
+			fast.Add(1)
+			return nil
+		},
+	})
+	s.Add(Job{
+		Name:     "failing",
+		Interval: time.Hour,
+		Run: func(context.Context) error {
+			failing.Add(1)
+			return errors.New("boom")
+		},
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 55*time.Millisecond)
+	defer cancel()
+
+	done := make(chan struct{})
+	go func() {
+		s.Run(ctx)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Run did not return after the context was cancelled")
+	}
+
+	if n := fast.Load(); n < 3 {
+		t.Errorf("fast job ran %d times, want at least 3", n)
+	}
+	if n := failing.Load(); n != 1 {
+		t.Errorf("failing job ran %d times, want exactly 1 (run immediately, errors do not stop it)", n)
+	}
+}
@@ -1,6 +1,10 @@
 package secrets
 
-import "fmt"
+import (
+	"fmt"
+
+	"github.com/maiko/sdbx/internal/problem"
+)
 
 // SecretNotConfiguredError indicates a secret file is not properly configured
 type SecretNotConfiguredError struct {
@@ -11,6 +15,11 @@ func (e *SecretNotConfiguredError) Error() string {
 	return fmt.Sprintf("secret not configured: %s (file is empty or contains placeholder)", e.Filename)
 }
 
+// Is makes the error a problem.ErrSecretMissing
+func (e *SecretNotConfiguredError) Is(target error) bool {
+	return target == problem.ErrSecretMissing
+}
+
 // IsSecretNotConfigured checks if error is SecretNotConfiguredError
 func IsSecretNotConfigured(err error) bool {
 	_, ok := err.(*SecretNotConfiguredError)
@@ -20,6 +20,8 @@ var SecretFiles = map[string]int{
 	"vpn_password.txt":                    0, // User-provided
 	"cloudflared_tunnel_token.txt":        0, // User-provided
 	"plex_claim_token.txt":                0, // User-provided
+	"smtp_password.txt":                   0, // User-provided (smtp)
+	"maxmind_license_key.txt":             0, // User-provided (geoip)
 	"sonarr_api_key.txt":                  32,
 	"radarr_api_key.txt":                  32,
 }
@@ -71,6 +73,74 @@ func GenerateSecrets(secretsDir string) error {
 	return nil
 }
 
+// EnsureResult reports what EnsureSecrets did
+type EnsureResult struct {
+	Generated []string          // Secrets created by this call
+	Missing   []string          // Manual secrets that are still empty
+	Invalid   map[string]string // Existing secrets that do not match their type
+	Values    map[string]string // Filename (<name>.txt) → value for every configured secret
+}
+
+// EnsureSecrets generates every declared secret that does not exist yet.
+// Existing files are never overwritten; they are validated against their
+// spec instead. Manual secrets get an empty placeholder.
+func EnsureSecrets(secretsDir string, specs []Spec) (*EnsureResult, error) {
+	if err := os.MkdirAll(secretsDir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create secrets directory: %w", err)
+	}
+
+	result := &EnsureResult{
+		Invalid: make(map[string]string),
+		Values:  make(map[string]string),
+	}
+	for _, spec := range specs {
+		filename := spec.Name + ".txt"
+		path := filepath.Join(secretsDir, filename)
+
+		if data, err := os.ReadFile(path); err == nil {
+			value := strings.TrimSpace(string(data))
+			if value == "" {
+				if spec.Type == TypeManual {
+					result.Missing = append(result.Missing, spec.Name)
+					continue
+				}
+			} else {
+				if err := Validate(spec, value); err != nil {
+					result.Invalid[spec.Name] = err.Error()
+				}
+				result.Values[filename] = value
+				continue
+			}
+		}
+
+		if spec.Type == TypeManual {
+			if err := os.WriteFile(path, []byte(""), 0o600); err != nil {
+				return nil, fmt.Errorf("failed to create %s: %w", filename, err)
+			}
+			result.Missing = append(result.Missing, spec.Name)
+			continue
+		}
+
+		generated, err := Generate(spec)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate %s: %w", spec.Name, err)
+		}
+		if err := os.WriteFile(path, []byte(generated.Value), 0o600); err != nil {
+			return nil, fmt.Errorf("failed to write %s: %w", filename, err)
+		}
+		for suffix, content := range generated.Companions {
+			companion := filepath.Join(secretsDir, spec.Name+suffix)
+			if err := os.WriteFile(companion, []byte(content), 0o600); err != nil {
+				return nil, fmt.Errorf("failed to write %s: %w", spec.Name+suffix, err)
+			}
+		}
+		result.Generated = append(result.Generated, spec.Name)
+		result.Values[filename] = strings.TrimSpace(generated.Value)
+	}
+
+	return result, nil
+}
+
 // RotateSecret regenerates a specific secret and creates a backup
 func RotateSecret(secretsDir, name string) (string, error) {
 	length, ok := SecretFiles[name]
@@ -1,11 +1,14 @@
 package secrets
 
 import (
+	"errors"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
 	"time"
+
+	"github.com/maiko/sdbx/internal/problem"
 )
 
 func TestGenerateRandomString(t *testing.T) {
@@ -178,6 +181,9 @@ func TestReadSecretNotConfigured(t *testing.T) {
 	if !IsSecretNotConfigured(err) {
 		t.Errorf("Expected SecretNotConfiguredError, got: %v", err)
 	}
+	if !errors.Is(err, problem.ErrSecretMissing) {
+		t.Errorf("Expected a problem.ErrSecretMissing, got: %v", err)
+	}
 }
 
 func TestRotateSecretBackup(t *testing.T) {
@@ -337,3 +343,58 @@ func TestRotateAllSecrets(t *testing.T) {
 		t.Error("User-provided secret should not be in results")
 	}
 }
+
+func TestEnsureSecrets(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	// An existing secret is kept even when it does not match its type
+	if err := os.WriteFile(filepath.Join(tmpDir, "existing.txt"), []byte("not-hex\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	specs := []Spec{
+		{Name: "api_key", Type: TypeHex, Length: 40},
+		{Name: "signing_key", Type: TypeEd25519},
+		{Name: "existing", Type: TypeHex},
+		{Name: "claim_token", Type: TypeManual},
+	}
+
+	result, err := EnsureSecrets(tmpDir, specs)
+	if err != nil {
+		t.Fatalf("EnsureSecrets failed: %v", err)
+	}
+
+	if strings.Join(result.Generated, ",") != "api_key,signing_key" {
+		t.Errorf("Generated = %v, want [api_key signing_key]", result.Generated)
+	}
+	if strings.Join(result.Missing, ",") != "claim_token" {
+		t.Errorf("Missing = %v, want [claim_token]", result.Missing)
+	}
+	if _, ok := result.Invalid["existing"]; !ok {
+		t.Errorf("Invalid = %v, want existing", result.Invalid)
+	}
+	if result.Values["existing.txt"] != "not-hex" {
+		t.Errorf("existing secret was not preserved: %q", result.Values["existing.txt"])
+	}
+	if len(result.Values["api_key.txt"]) != 40 {
+		t.Errorf("api_key.txt = %q, want 40 hex chars", result.Values["api_key.txt"])
+	}
+	if _, err := os.Stat(filepath.Join(tmpDir, "signing_key.pub")); err != nil {
+		t.Errorf("public key companion not written: %v", err)
+	}
+	if info, err := os.Stat(filepath.Join(tmpDir, "claim_token.txt")); err != nil || info.Size() != 0 {
+		t.Errorf("manual secret placeholder not created: %v", err)
+	}
+
+	// A second run generates nothing and keeps values
+	again, err := EnsureSecrets(tmpDir, specs)
+	if err != nil {
+		t.Fatalf("EnsureSecrets failed: %v", err)
+	}
+	if len(again.Generated) != 0 {
+		t.Errorf("second run generated %v", again.Generated)
+	}
+	if again.Values["api_key.txt"] != result.Values["api_key.txt"] {
+		t.Error("second run changed an existing secret")
+	}
+}
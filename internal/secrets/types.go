@@ -0,0 +1,301 @@
+package secrets
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"maps"
+	"math/big"
+	"regexp"
+	"slices"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+	"golang.org/x/crypto/ssh"
+)
+
+// Secret types a service definition can declare
+const (
+	TypeAuto     = "auto"     // URL-safe random string (legacy default)
+	TypeManual   = "manual"   // Provided by the user
+	TypePassword = "password" // Random string drawn from a charset
+	TypeHex      = "hex"      // Random hex string
+	TypeBase64   = "base64"   // Random bytes, base64 encoded
+	TypeJWTHMAC  = "jwt-hmac" // HMAC signing key, base64url encoded
+	TypeUUID     = "uuid"     // Random (version 4) UUID
+	TypeRSA      = "rsa"      // RSA private key (PEM) with a .pub companion
+	TypeEd25519  = "ed25519"  // Ed25519 private key (PEM) with a .pub companion
+	TypeHtpasswd = "htpasswd" // user:bcrypt line with a .password companion
+)
+
+// Types lists every supported secret type
+var Types = []string{
+	TypeAuto, TypeManual, TypePassword, TypeHex, TypeBase64,
+	TypeJWTHMAC, TypeUUID, TypeRSA, TypeEd25519, TypeHtpasswd,
+}
+
+// Password charsets
+var charsets = map[string]string{
+	"alphanumeric": "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789",
+	"alpha":        "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz",
+	"numeric":      "0123456789",
+	"urlsafe":      "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789-_",
+	"symbols":      "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789!#%+,-.:=?@^_~",
+}
+
+// Charsets lists the supported password charsets
+func Charsets() []string {
+	return slices.Sorted(maps.Keys(charsets))
+}
+
+// Spec describes how a secret is generated and validated
+type Spec struct {
+	Name     string
+	Type     string
+	Length   int    // Characters (auto, password, hex), bytes (base64, jwt-hmac) or bits (rsa)
+	Charset  string // Password charset, default alphanumeric
+	Username string // htpasswd user, default admin
+}
+
+// Default and minimum lengths per type
+var (
+	defaultLengths = map[string]int{
+		TypeAuto: 32, TypePassword: 32, TypeHex: 64, TypeBase64: 32, TypeJWTHMAC: 64, TypeRSA: 3072, TypeHtpasswd: 24,
+	}
+	minLengths = map[string]int{
+		TypePassword: 12, TypeHex: 32, TypeBase64: 16, TypeJWTHMAC: 32, TypeRSA: 2048, TypeHtpasswd: 12,
+	}
+)
+
+// Generated is the material produced for a secret
+type Generated struct {
+	Value string // Written to <name>.txt
+	// Companion files keyed by suffix: ".pub" for keypairs, ".password"
+	// for the plaintext htpasswd password
+	Companions map[string]string
+}
+
+// length returns the configured length or the type's default
+func (s Spec) length() int {
+	if s.Length > 0 {
+		return s.Length
+	}
+	return defaultLengths[s.Type]
+}
+
+// ValidateSpec checks that a secret declaration is usable
+func ValidateSpec(s Spec) error {
+	if !slices.Contains(Types, s.Type) {
+		return fmt.Errorf("unknown secret type %q (must be one of: %s)", s.Type, strings.Join(Types, ", "))
+	}
+	if s.Length < 0 {
+		return fmt.Errorf("length cannot be negative")
+	}
+	if minLength, ok := minLengths[s.Type]; ok && s.Length > 0 && s.Length < minLength {
+		return fmt.Errorf("length %d is below the minimum of %d for %s secrets", s.Length, minLength, s.Type)
+	}
+	if s.Charset != "" {
+		if s.Type != TypePassword && s.Type != TypeHtpasswd {
+			return fmt.Errorf("charset only applies to password and htpasswd secrets")
+		}
+		if _, ok := charsets[s.Charset]; !ok {
+			return fmt.Errorf("unknown charset %q (must be one of: %s)", s.Charset, strings.Join(Charsets(), ", "))
+		}
+	}
+	return nil
+}
+
+// Generate creates a new secret value for spec
+func Generate(s Spec) (*Generated, error) {
+	if err := ValidateSpec(s); err != nil {
+		return nil, err
+	}
+
+	switch s.Type {
+	case TypeManual:
+		return nil, &ManualSecretError{Filename: s.Name}
+	case TypeAuto:
+		value, err := GenerateRandomString(s.length())
+		return &Generated{Value: value}, err
+	case TypePassword:
+		value, err := randomFromCharset(s.length(), s.Charset)
+		return &Generated{Value: value}, err
+	case TypeHex:
+		b, err := randomBytes((s.length() + 1) / 2)
+		if err != nil {
+			return nil, err
+		}
+		return &Generated{Value: hex.EncodeToString(b)[:s.length()]}, nil
+	case TypeBase64:
+		b, err := randomBytes(s.length())
+		return &Generated{Value: base64.StdEncoding.EncodeToString(b)}, err
+	case TypeJWTHMAC:
+		b, err := randomBytes(s.length())
+		return &Generated{Value: base64.RawURLEncoding.EncodeToString(b)}, err
+	case TypeUUID:
+		b, err := randomBytes(16)
+		if err != nil {
+			return nil, err
+		}
+		b[6] = b[6]&0x0f | 0x40 // Version 4
+		b[8] = b[8]&0x3f | 0x80 // RFC 4122 variant
+		return &Generated{Value: fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])}, nil
+	case TypeRSA:
+		key, err := rsa.GenerateKey(rand.Reader, s.length())
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate RSA key: %w", err)
+		}
+		return keypair(key, &key.PublicKey)
+	case TypeEd25519:
+		pub, key, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate Ed25519 key: %w", err)
+		}
+		return keypair(key, pub)
+	case TypeHtpasswd:
+		password, err := randomFromCharset(s.length(), s.Charset)
+		if err != nil {
+			return nil, err
+		}
+		hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+		if err != nil {
+			return nil, fmt.Errorf("failed to hash password: %w", err)
+		}
+		return &Generated{
+			Value:      s.username() + ":" + string(hash),
+			Companions: map[string]string{".password": password},
+		}, nil
+	}
+	return nil, fmt.Errorf("unknown secret type %q", s.Type)
+}
+
+// username returns the htpasswd user
+func (s Spec) username() string {
+	if s.Username != "" {
+		return s.Username
+	}
+	return "admin"
+}
+
+var (
+	hexRegex      = regexp.MustCompile(`^[0-9a-f]+$`)
+	uuidRegex     = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$`)
+	htpasswdRegex = regexp.MustCompile(`^[^:\s]+:\$2[aby]\$\d{2}\$[./A-Za-z0-9]{53}$`)
+)
+
+// Validate checks that an existing secret value matches its spec
+func Validate(s Spec, value string) error {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return &SecretNotConfiguredError{Filename: s.Name}
+	}
+
+	switch s.Type {
+	case TypeAuto, TypePassword:
+		if s.Length > 0 && len(value) < s.Length {
+			return fmt.Errorf("%s is %d characters, expected at least %d", s.Name, len(value), s.Length)
+		}
+		if s.Charset != "" {
+			for _, r := range value {
+				if !strings.ContainsRune(charsets[s.Charset], r) {
+					return fmt.Errorf("%s contains characters outside the %s charset", s.Name, s.Charset)
+				}
+			}
+		}
+	case TypeHex:
+		if !hexRegex.MatchString(value) {
+			return fmt.Errorf("%s is not a lowercase hex string", s.Name)
+		}
+		if s.Length > 0 && len(value) < s.Length {
+			return fmt.Errorf("%s is %d characters, expected at least %d", s.Name, len(value), s.Length)
+		}
+	case TypeBase64:
+		if _, err := base64.StdEncoding.DecodeString(value); err != nil {
+			return fmt.Errorf("%s is not valid base64: %w", s.Name, err)
+		}
+	case TypeJWTHMAC:
+		key, err := base64.RawURLEncoding.DecodeString(value)
+		if err != nil {
+			return fmt.Errorf("%s is not valid base64url: %w", s.Name, err)
+		}
+		if len(key) < minLengths[TypeJWTHMAC] {
+			return fmt.Errorf("%s is %d bytes, expected at least %d", s.Name, len(key), minLengths[TypeJWTHMAC])
+		}
+	case TypeUUID:
+		if !uuidRegex.MatchString(strings.ToLower(value)) {
+			return fmt.Errorf("%s is not a UUID", s.Name)
+		}
+	case TypeRSA, TypeEd25519:
+		block, _ := pem.Decode([]byte(value))
+		if block == nil {
+			return fmt.Errorf("%s is not a PEM encoded key", s.Name)
+		}
+		key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return fmt.Errorf("%s is not a PKCS#8 private key: %w", s.Name, err)
+		}
+		if _, ok := key.(*rsa.PrivateKey); s.Type == TypeRSA && !ok {
+			return fmt.Errorf("%s is not an RSA key", s.Name)
+		}
+		if _, ok := key.(ed25519.PrivateKey); s.Type == TypeEd25519 && !ok {
+			return fmt.Errorf("%s is not an Ed25519 key", s.Name)
+		}
+	case TypeHtpasswd:
+		// One user per line; users can be added to a generated file
+		for _, line := range strings.Split(value, "\n") {
+			if !htpasswdRegex.MatchString(strings.TrimSpace(line)) {
+				return fmt.Errorf("%s is not a list of user:bcrypt htpasswd lines", s.Name)
+			}
+		}
+	}
+	return nil
+}
+
+// randomBytes returns n cryptographically secure random bytes
+func randomBytes(n int) ([]byte, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return nil, fmt.Errorf("failed to generate random bytes: %w", err)
+	}
+	return b, nil
+}
+
+// randomFromCharset draws length characters uniformly from a named charset
+func randomFromCharset(length int, charset string) (string, error) {
+	if charset == "" {
+		charset = "alphanumeric"
+	}
+	chars := charsets[charset]
+	max := big.NewInt(int64(len(chars)))
+
+	out := make([]byte, length)
+	for i := range out {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", fmt.Errorf("failed to generate random bytes: %w", err)
+		}
+		out[i] = chars[n.Int64()]
+	}
+	return string(out), nil
+}
+
+// keypair encodes a private key as PKCS#8 PEM with an OpenSSH public key companion
+func keypair(private, public any) (*Generated, error) {
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode private key: %w", err)
+	}
+	sshPub, err := ssh.NewPublicKey(public)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode public key: %w", err)
+	}
+	return &Generated{
+		Value:      string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})),
+		Companions: map[string]string{".pub": string(ssh.MarshalAuthorizedKey(sshPub))},
+	}, nil
+}
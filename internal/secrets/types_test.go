@@ -0,0 +1,146 @@
+package secrets
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestGenerateTypes(t *testing.T) {
+	tests := []struct {
+		spec      Spec
+		wantLen   int
+		companion string
+	}{
+		{spec: Spec{Name: "auto", Type: TypeAuto, Length: 48}, wantLen: 48},
+		{spec: Spec{Name: "password", Type: TypePassword}, wantLen: 32},
+		{spec: Spec{Name: "pin", Type: TypePassword, Length: 12, Charset: "numeric"}, wantLen: 12},
+		{spec: Spec{Name: "hex", Type: TypeHex, Length: 41}, wantLen: 41},
+		{spec: Spec{Name: "base64", Type: TypeBase64, Length: 24}, wantLen: 32},
+		{spec: Spec{Name: "jwt", Type: TypeJWTHMAC}, wantLen: 86},
+		{spec: Spec{Name: "uuid", Type: TypeUUID}, wantLen: 36},
+		{spec: Spec{Name: "rsa", Type: TypeRSA, Length: 2048}, companion: ".pub"},
+		{spec: Spec{Name: "ed25519", Type: TypeEd25519}, companion: ".pub"},
+		{spec: Spec{Name: "htpasswd", Type: TypeHtpasswd, Username: "sdbx"}, companion: ".password"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec.Type+"/"+tt.spec.Name, func(t *testing.T) {
+			generated, err := Generate(tt.spec)
+			if err != nil {
+				t.Fatalf("Generate() error = %v", err)
+			}
+			if tt.wantLen > 0 && len(generated.Value) != tt.wantLen {
+				t.Errorf("Generate() = %d chars, want %d", len(generated.Value), tt.wantLen)
+			}
+			if tt.companion != "" && generated.Companions[tt.companion] == "" {
+				t.Errorf("Generate() missing %s companion", tt.companion)
+			}
+			if err := Validate(tt.spec, generated.Value); err != nil {
+				t.Errorf("Validate() rejected generated value: %v", err)
+			}
+		})
+	}
+}
+
+func TestGenerateCharset(t *testing.T) {
+	generated, err := Generate(Spec{Name: "pin", Type: TypePassword, Length: 64, Charset: "numeric"})
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	if strings.Trim(generated.Value, "0123456789") != "" {
+		t.Errorf("numeric password contains other characters: %q", generated.Value)
+	}
+}
+
+func TestGenerateHtpasswd(t *testing.T) {
+	generated, err := Generate(Spec{Name: "basic_auth", Type: TypeHtpasswd})
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+
+	user, hash, ok := strings.Cut(generated.Value, ":")
+	if !ok || user != "admin" {
+		t.Fatalf("htpasswd line = %q, want admin:<hash>", generated.Value)
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(generated.Companions[".password"])); err != nil {
+		t.Errorf("hash does not match the companion password: %v", err)
+	}
+}
+
+func TestGenerateManual(t *testing.T) {
+	_, err := Generate(Spec{Name: "token", Type: TypeManual})
+	if !IsManualSecret(err) {
+		t.Errorf("Generate(manual) error = %v, want ManualSecretError", err)
+	}
+}
+
+func TestValidateSpec(t *testing.T) {
+	tests := []struct {
+		name    string
+		spec    Spec
+		wantErr bool
+	}{
+		{"valid password", Spec{Type: TypePassword, Length: 16, Charset: "symbols"}, false},
+		{"default length", Spec{Type: TypeRSA}, false},
+		{"unknown type", Spec{Type: "random"}, true},
+		{"negative length", Spec{Type: TypeHex, Length: -1}, true},
+		{"short rsa key", Spec{Type: TypeRSA, Length: 1024}, true},
+		{"short password", Spec{Type: TypePassword, Length: 8}, true},
+		{"unknown charset", Spec{Type: TypePassword, Charset: "emoji"}, true},
+		{"charset on hex", Spec{Type: TypeHex, Charset: "numeric"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateSpec(tt.spec)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateSpec() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// bcryptHash is a well-formed bcrypt hash
+const bcryptHash = "$2y$05$abcdefghijklmnopqrstuuJ1gV4Kp9y4b1uGBLdUg2b0bR2Oa9a.C"
+
+func TestValidateValue(t *testing.T) {
+	tests := []struct {
+		name    string
+		spec    Spec
+		value   string
+		wantErr bool
+	}{
+		{"empty", Spec{Name: "x", Type: TypePassword}, "  ", true},
+		{"too short", Spec{Name: "x", Type: TypePassword, Length: 16}, "short", true},
+		{"outside charset", Spec{Name: "x", Type: TypePassword, Charset: "numeric"}, "12a4", true},
+		{"uppercase hex", Spec{Name: "x", Type: TypeHex}, "ABCDEF", true},
+		{"bad uuid", Spec{Name: "x", Type: TypeUUID}, "not-a-uuid", true},
+		{"short jwt key", Spec{Name: "x", Type: TypeJWTHMAC}, "c2hvcnQ", true},
+		{"not pem", Spec{Name: "x", Type: TypeRSA}, "ssh-rsa AAAA", true},
+		{"plain htpasswd", Spec{Name: "x", Type: TypeHtpasswd}, "admin:password", true},
+		{"htpasswd users", Spec{Name: "x", Type: TypeHtpasswd}, "admin:" + bcryptHash + "\nbob:" + bcryptHash + "\n", false},
+		{"htpasswd bad second user", Spec{Name: "x", Type: TypeHtpasswd}, "admin:" + bcryptHash + "\nbob:secret", true},
+		{"manual value", Spec{Name: "x", Type: TypeManual}, "anything", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate(tt.spec, tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateWrongKeyType(t *testing.T) {
+	generated, err := Generate(Spec{Name: "key", Type: TypeEd25519})
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	if err := Validate(Spec{Name: "key", Type: TypeRSA}, generated.Value); err == nil {
+		t.Error("Validate(rsa) accepted an Ed25519 key")
+	}
+}
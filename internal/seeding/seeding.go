@@ -0,0 +1,275 @@
+// Package seeding enforces the per-category seeding rules of .sdbx.yaml
+// against qBittorrent and records the torrents it cleaned up.
+package seeding
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/maiko/sdbx/internal/backup"
+	"github.com/maiko/sdbx/internal/config"
+	"github.com/maiko/sdbx/internal/notify"
+	"github.com/maiko/sdbx/internal/qbittorrent"
+	"github.com/maiko/sdbx/internal/scheduler"
+)
+
+// ReportFile records the last enforcement and the torrents cleaned up
+const ReportFile = ".sdbx.seeding.yaml"
+
+// historySize is how many cleaned up torrents ReportFile keeps
+const historySize = 200
+
+// Action is a seeding rule applied to a torrent
+type Action struct {
+	Hash     string        `json:"hash" yaml:"hash"`
+	Name     string        `json:"name" yaml:"name"`
+	Category string        `json:"category" yaml:"category"`
+	Action   string        `json:"action" yaml:"action"`
+	Reason   string        `json:"reason" yaml:"reason"` // Limit reached (e.g. ratio 2.10 ≥ 2.00)
+	Ratio    float64       `json:"ratio" yaml:"ratio"`
+	Seeded   time.Duration `json:"seeded_ns" yaml:"seeded"`
+	Size     int64         `json:"size" yaml:"size"`
+	At       time.Time     `json:"at" yaml:"at"`
+	Error    string        `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+// Report is the content of ReportFile: the last run and the most recent
+// actions, newest first
+type Report struct {
+	LastRun time.Time `json:"last_run" yaml:"last_run"`
+	Actions []Action  `json:"actions" yaml:"actions"`
+}
+
+// Client is the part of the qBittorrent client the enforcer uses
+type Client interface {
+	Torrents(ctx context.Context) ([]qbittorrent.Torrent, error)
+	Stop(ctx context.Context, hashes ...string) error
+	Delete(ctx context.Context, deleteFiles bool, hashes ...string) error
+}
+
+// Enforcer applies seeding rules to the torrents of a qBittorrent instance
+type Enforcer struct {
+	ProjectDir string
+	Config     *config.Config
+	Client     Client
+	Now        func() time.Time // Overridable for tests
+}
+
+// NewEnforcer creates an enforcer for cfg's seeding rules
+func NewEnforcer(projectDir string, cfg *config.Config, client Client) *Enforcer {
+	return &Enforcer{ProjectDir: projectDir, Config: cfg, Client: client, Now: time.Now}
+}
+
+// Plan returns the actions due: completed torrents past a limit of their
+// category's rule. Torrents already stopped are left alone by pause rules.
+func Plan(rules config.SeedingConfig, torrents []qbittorrent.Torrent, now time.Time) []Action {
+	var actions []Action
+	for _, t := range torrents {
+		if !t.Completed() {
+			continue
+		}
+		rule, ok := rules.Rule(t.Category)
+		if !ok {
+			continue
+		}
+		reason := limitReached(rule, t)
+		if reason == "" || (rule.SeedingAction() == config.SeedingActionPause && t.Stopped()) {
+			continue
+		}
+		actions = append(actions, Action{
+			Hash:     t.Hash,
+			Name:     t.Name,
+			Category: t.Category,
+			Action:   rule.SeedingAction(),
+			Reason:   reason,
+			Ratio:    t.Ratio,
+			Seeded:   t.Seeded(),
+			Size:     t.Size,
+			At:       now,
+		})
+	}
+	slices.SortFunc(actions, func(a, b Action) int { return strings.Compare(a.Name, b.Name) })
+	return actions
+}
+
+// limitReached describes the first limit of rule a torrent reached, or
+// returns "" when none is
+func limitReached(rule config.SeedingRule, t qbittorrent.Torrent) string {
+	if rule.Ratio > 0 && t.Ratio >= rule.Ratio {
+		return fmt.Sprintf("ratio %.2f ≥ %.2f", t.Ratio, rule.Ratio)
+	}
+	if limit := rule.SeedTimeLimit(); limit > 0 && t.Seeded() >= limit {
+		return fmt.Sprintf("seeded %s ≥ %s", t.Seeded().Round(time.Minute), limit)
+	}
+	return ""
+}
+
+// Run applies the seeding rules and returns the actions taken. With dryRun
+// nothing is changed or recorded. Failed actions are returned with their
+// error set, and reported again on the next run.
+func (e *Enforcer) Run(ctx context.Context, dryRun bool) ([]Action, error) {
+	torrents, err := e.Client.Torrents(ctx)
+	if err != nil {
+		return nil, err
+	}
+	actions := Plan(e.Config.Seeding, torrents, e.Now())
+	if dryRun {
+		return actions, nil
+	}
+
+	// One call per action type
+	byAction := make(map[string][]string)
+	for _, a := range actions {
+		byAction[a.Action] = append(byAction[a.Action], a.Hash)
+	}
+	failed := make(map[string]error)
+	for action, hashes := range byAction {
+		var err error
+		switch action {
+		case config.SeedingActionPause:
+			err = e.Client.Stop(ctx, hashes...)
+		case config.SeedingActionRemove:
+			err = e.Client.Delete(ctx, false, hashes...)
+		case config.SeedingActionRemoveWithData:
+			err = e.Client.Delete(ctx, true, hashes...)
+		}
+		if err != nil {
+			failed[action] = err
+		}
+	}
+
+	var done []Action
+	var errs []error
+	for i, a := range actions {
+		if err, ok := failed[a.Action]; ok {
+			actions[i].Error = err.Error()
+			continue
+		}
+		done = append(done, a)
+	}
+	for action, err := range failed {
+		errs = append(errs, fmt.Errorf("%s: %w", action, err))
+	}
+
+	if err := e.record(done); err != nil {
+		errs = append(errs, err)
+	}
+	return actions, errors.Join(errs...)
+}
+
+// record prepends the actions taken to the report and updates its last run
+func (e *Enforcer) record(done []Action) error {
+	report, err := LoadReport(e.ProjectDir)
+	if err != nil {
+		return err
+	}
+	report.LastRun = e.Now()
+	report.Actions = append(slices.Clone(done), report.Actions...)
+	if len(report.Actions) > historySize {
+		report.Actions = report.Actions[:historySize]
+	}
+
+	data, err := yaml.Marshal(report)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s: %w", ReportFile, err)
+	}
+	if err := os.WriteFile(filepath.Join(e.ProjectDir, ReportFile), data, 0o644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", ReportFile, err)
+	}
+	return nil
+}
+
+// LoadReport reads the seeding report; a missing file means no run yet
+func LoadReport(projectDir string) (*Report, error) {
+	data, err := os.ReadFile(filepath.Join(projectDir, ReportFile))
+	if errors.Is(err, fs.ErrNotExist) {
+		return &Report{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", ReportFile, err)
+	}
+	var report Report
+	if err := yaml.Unmarshal(data, &report); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", ReportFile, err)
+	}
+	return &report, nil
+}
+
+// Summary describes the actions taken in one notification message
+func Summary(actions []Action) notify.Message {
+	counts := make(map[string]int)
+	var size int64
+	var lines []string
+	for _, a := range actions {
+		counts[a.Action]++
+		if a.Action != config.SeedingActionPause {
+			size += a.Size
+		}
+		lines = append(lines, fmt.Sprintf("%s %s (%s)", a.Action, a.Name, a.Reason))
+	}
+	var parts []string
+	for _, action := range []string{config.SeedingActionPause, config.SeedingActionRemove, config.SeedingActionRemoveWithData} {
+		if counts[action] > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", counts[action], action))
+		}
+	}
+	title := "Seeding rules: " + strings.Join(parts, ", ")
+	if size > 0 {
+		title += fmt.Sprintf(" (%s)", backup.FormatBytes(size))
+	}
+	return notify.Message{
+		Title:    title,
+		Body:     strings.Join(lines, "\n"),
+		Severity: notify.SeverityInfo,
+		Source:   "seeding",
+	}
+}
+
+// Job enforces the seeding rules on their configured interval, notifying
+// the torrents cleaned up. inContainer selects the qBittorrent URL used when
+// none is configured: the container's when running as the sdbx-webui service,
+// the published port otherwise.
+func Job(projectDir string, interval time.Duration, inContainer bool) scheduler.Job {
+	return scheduler.Job{
+		Name:     "seeding",
+		Interval: interval,
+		Run: func(ctx context.Context) error {
+			cfg, err := config.Load()
+			if err != nil {
+				return fmt.Errorf("failed to load config: %w", err)
+			}
+			if !cfg.Seeding.IsEnabled() {
+				return nil
+			}
+			client, err := NewClient(projectDir, cfg, inContainer)
+			if err != nil {
+				return err
+			}
+			actions, err := NewEnforcer(projectDir, cfg, client).Run(ctx, false)
+			if done := slices.DeleteFunc(slices.Clone(actions), func(a Action) bool { return a.Error != "" }); len(done) > 0 {
+				if nerr := notify.New(projectDir, cfg).Send(ctx, Summary(done)); nerr != nil {
+					err = errors.Join(err, fmt.Errorf("failed to notify: %w", nerr))
+				}
+			}
+			return err
+		},
+	}
+}
+
+// NewClient creates the qBittorrent client for the seeding rules
+func NewClient(projectDir string, cfg *config.Config, inContainer bool) (*qbittorrent.Client, error) {
+	baseURL := qbittorrent.HostURL(projectDir, cfg)
+	if inContainer {
+		baseURL = qbittorrent.ContainerURL(cfg)
+	}
+	return qbittorrent.New(projectDir, cfg, baseURL)
+}
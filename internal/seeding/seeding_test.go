@@ -0,0 +1,141 @@
+package seeding
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/maiko/sdbx/internal/config"
+	"github.com/maiko/sdbx/internal/qbittorrent"
+)
+
+// fakeClient records the calls of the enforcer
+type fakeClient struct {
+	torrents  []qbittorrent.Torrent
+	stopped   []string
+	deleted   []string
+	withFiles []string
+	deleteErr error
+}
+
+func (f *fakeClient) Torrents(context.Context) ([]qbittorrent.Torrent, error) {
+	return f.torrents, nil
+}
+
+func (f *fakeClient) Stop(_ context.Context, hashes ...string) error {
+	f.stopped = append(f.stopped, hashes...)
+	return nil
+}
+
+func (f *fakeClient) Delete(_ context.Context, deleteFiles bool, hashes ...string) error {
+	if f.deleteErr != nil {
+		return f.deleteErr
+	}
+	if deleteFiles {
+		f.withFiles = append(f.withFiles, hashes...)
+	} else {
+		f.deleted = append(f.deleted, hashes...)
+	}
+	return nil
+}
+
+var testRules = config.SeedingConfig{Rules: []config.SeedingRule{
+	{Category: "movies", Ratio: 2, Action: config.SeedingActionRemove},
+	{Category: "tv", SeedTime: "48h", Action: config.SeedingActionRemoveWithData},
+	{Category: "*", Ratio: 1},
+}}
+
+var testTorrents = []qbittorrent.Torrent{
+	{Hash: "m1", Name: "Movie A", Category: "movies", Progress: 1, Ratio: 2.1, Size: 1000},
+	{Hash: "m2", Name: "Movie B", Category: "movies", Progress: 1, Ratio: 1.9},
+	{Hash: "m3", Name: "Movie C", Category: "movies", Progress: 0.5, Ratio: 3},
+	{Hash: "t1", Name: "Show S01", Category: "tv", Progress: 1, SeedingTime: 49 * 3600, Size: 500},
+	{Hash: "o1", Name: "Linux ISO", Category: "", Progress: 1, Ratio: 1.5, State: "stalledUP"},
+	{Hash: "o2", Name: "Old ISO", Category: "misc", Progress: 1, Ratio: 4, State: "pausedUP"},
+}
+
+func TestPlan(t *testing.T) {
+	now := time.Now()
+	actions := Plan(testRules, testTorrents, now)
+
+	var got []string
+	for _, a := range actions {
+		got = append(got, a.Hash+":"+a.Action)
+	}
+	want := []string{"o1:pause", "m1:remove", "t1:remove_with_data"}
+	if !slices.Equal(got, want) {
+		t.Errorf("Plan() = %v, want %v", got, want)
+	}
+	if !strings.Contains(actions[2].Reason, "seeded 49h") {
+		t.Errorf("reason = %q, want the seed time", actions[2].Reason)
+	}
+}
+
+func TestEnforcerRun(t *testing.T) {
+	projectDir := t.TempDir()
+	cfg := config.DefaultConfig()
+	cfg.Seeding = testRules
+	client := &fakeClient{torrents: testTorrents}
+	e := NewEnforcer(projectDir, cfg, client)
+
+	// Dry run changes and records nothing
+	if actions, err := e.Run(context.Background(), true); err != nil || len(actions) != 3 {
+		t.Fatalf("dry run = %d actions, %v", len(actions), err)
+	}
+	if len(client.stopped)+len(client.deleted)+len(client.withFiles) > 0 {
+		t.Error("dry run should not act on torrents")
+	}
+	if report, _ := LoadReport(projectDir); !report.LastRun.IsZero() {
+		t.Error("dry run should not write the report")
+	}
+
+	if _, err := e.Run(context.Background(), false); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if !slices.Equal(client.stopped, []string{"o1"}) || !slices.Equal(client.deleted, []string{"m1"}) || !slices.Equal(client.withFiles, []string{"t1"}) {
+		t.Errorf("stopped %v, deleted %v, deleted with files %v", client.stopped, client.deleted, client.withFiles)
+	}
+
+	report, err := LoadReport(projectDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.LastRun.IsZero() || len(report.Actions) != 3 {
+		t.Errorf("report = %+v", report)
+	}
+
+	msg := Summary(report.Actions)
+	if msg.Title != "Seeding rules: 1 pause, 1 remove, 1 remove_with_data (1.5 KB)" {
+		t.Errorf("Summary().Title = %q", msg.Title)
+	}
+}
+
+func TestEnforcerRunFailure(t *testing.T) {
+	projectDir := t.TempDir()
+	cfg := config.DefaultConfig()
+	cfg.Seeding = testRules
+	client := &fakeClient{torrents: testTorrents, deleteErr: errors.New("forbidden")}
+
+	actions, err := NewEnforcer(projectDir, cfg, client).Run(context.Background(), false)
+	if err == nil {
+		t.Fatal("Run() should return the failed deletions")
+	}
+	var failed int
+	for _, a := range actions {
+		if a.Error != "" {
+			failed++
+		}
+	}
+	if failed != 2 {
+		t.Errorf("%d failed actions, want 2", failed)
+	}
+
+	// Only the pause is recorded
+	report, _ := LoadReport(projectDir)
+	if len(report.Actions) != 1 || report.Actions[0].Hash != "o1" {
+		t.Errorf("report actions = %+v", report.Actions)
+	}
+}
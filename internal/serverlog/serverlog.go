@@ -0,0 +1,234 @@
+// Package serverlog keeps the logs sdbx writes itself, of the web UI server
+// and its background jobs, in rotated files of the project's logs directory.
+package serverlog
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/maiko/sdbx/internal/config"
+)
+
+// Dir holds the logs, relative to the project directory
+const Dir = "logs"
+
+// Logs written by sdbx
+const (
+	Server = "server" // Requests and errors of the web UI (sdbx serve)
+	Jobs   = "jobs"   // Background jobs: scheduler errors, web UI jobs
+)
+
+// Names are the logs sdbx writes
+var Names = []string{Server, Jobs}
+
+// Path returns the file of a log, rotated files adding .1 (newest) to .N
+func Path(projectDir, name string) string {
+	return filepath.Join(projectDir, Dir, name+".log")
+}
+
+// Writer appends to a log, rotating it past the configured size and
+// dropping rotated files beyond the configured count and age
+type Writer struct {
+	path      string
+	mu        sync.Mutex
+	retention config.ServerLogsConfig
+	file      *os.File
+	size      int64
+}
+
+// Open opens a log for appending, creating the logs directory
+func Open(projectDir, name string, retention config.ServerLogsConfig) (*Writer, error) {
+	w := &Writer{path: Path(projectDir, name), retention: retention}
+	if err := os.MkdirAll(filepath.Dir(w.path), 0o750); err != nil {
+		return nil, fmt.Errorf("failed to create %s: %w", Dir, err)
+	}
+	if err := w.open(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// Write appends p, rotating the log first when p would grow it past the
+// maximum size
+func (w *Writer) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.file == nil {
+		return 0, os.ErrClosed
+	}
+	if w.size > 0 && w.size+int64(len(p)) > w.retention.MaxBytes() {
+		if err := w.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := w.file.Write(p)
+	w.size += int64(n)
+	return n, err
+}
+
+// SetRetention changes the rotation settings and drops the rotated files
+// they no longer keep
+func (w *Writer) SetRetention(retention config.ServerLogsConfig) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.retention = retention
+	return w.prune(time.Now())
+}
+
+// Close closes the log
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.file == nil {
+		return nil
+	}
+	err := w.file.Close()
+	w.file = nil
+	return err
+}
+
+// open opens the current file. Must hold w.mu once the writer is shared.
+func (w *Writer) open() error {
+	f, err := os.OpenFile(w.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", filepath.Base(w.path), err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return fmt.Errorf("failed to open %s: %w", filepath.Base(w.path), err)
+	}
+	w.file, w.size = f, info.Size()
+	return nil
+}
+
+// rotate shifts the rotated files by one, moves the current file to .1 and
+// starts a new one. Must hold w.mu.
+func (w *Writer) rotate() error {
+	if err := w.file.Close(); err != nil {
+		return err
+	}
+	w.file = nil
+	files := w.retention.Files()
+	_ = os.Remove(fmt.Sprintf("%s.%d", w.path, files))
+	for i := files - 1; i >= 1; i-- {
+		if err := os.Rename(fmt.Sprintf("%s.%d", w.path, i), fmt.Sprintf("%s.%d", w.path, i+1)); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to rotate %s: %w", filepath.Base(w.path), err)
+		}
+	}
+	if err := os.Rename(w.path, w.path+".1"); err != nil {
+		return fmt.Errorf("failed to rotate %s: %w", filepath.Base(w.path), err)
+	}
+	if err := w.prune(time.Now()); err != nil {
+		return err
+	}
+	return w.open()
+}
+
+// prune drops the rotated files beyond the kept count, and the ones last
+// written before the retention age. Must hold w.mu.
+func (w *Writer) prune(now time.Time) error {
+	rotated, err := filepath.Glob(w.path + ".*")
+	if err != nil {
+		return err
+	}
+	files, age := w.retention.Files(), w.retention.Retention()
+	for _, path := range rotated {
+		var n int
+		if _, err := fmt.Sscanf(strings.TrimPrefix(path, w.path+"."), "%d", &n); err != nil {
+			continue
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			continue
+		}
+		if n > files || (age > 0 && now.Sub(info.ModTime()) > age) {
+			if err := os.Remove(path); err != nil {
+				return fmt.Errorf("failed to remove %s: %w", filepath.Base(path), err)
+			}
+		}
+	}
+	return nil
+}
+
+// Files returns the files of a log, oldest first
+func Files(projectDir, name string) []string {
+	path := Path(projectDir, name)
+	rotated, _ := filepath.Glob(path + ".*")
+	index := func(p string) int {
+		var n int
+		_, _ = fmt.Sscanf(strings.TrimPrefix(p, path+"."), "%d", &n)
+		return n
+	}
+	rotated = slices.DeleteFunc(rotated, func(p string) bool { return index(p) == 0 })
+	slices.SortFunc(rotated, func(a, b string) int { return index(b) - index(a) })
+	if _, err := os.Stat(path); err == nil {
+		rotated = append(rotated, path)
+	}
+	return rotated
+}
+
+// lineTime reads the time a log line starts with, written by the standard
+// logger ("2006/01/02 15:04:05") or log/slog's text handler ("time=...")
+func lineTime(line string) (time.Time, bool) {
+	if len(line) >= 19 {
+		if t, err := time.ParseInLocation("2006/01/02 15:04:05", line[:19], time.Local); err == nil {
+			return t, true
+		}
+	}
+	if rest, ok := strings.CutPrefix(line, "time="); ok {
+		value, _, _ := strings.Cut(rest, " ")
+		if t, err := time.Parse(time.RFC3339Nano, value); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// Copy writes the lines of a log between since and until to out, oldest
+// first. Zero times do not bound the range. Lines without a time, such as
+// the rest of a multi-line message, follow the line before them.
+func Copy(out io.Writer, projectDir, name string, since, until time.Time) error {
+	for _, path := range Files(projectDir, name) {
+		if err := copyFile(out, path, since, until); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// copyFile writes the lines of one log file within the range to out
+func copyFile(out io.Writer, path string, since, until time.Time) error {
+	f, err := os.Open(path) //nolint:gosec // G304 - file of the logs directory
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	defer f.Close()
+
+	include := true
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if t, ok := lineTime(line); ok {
+			include = (since.IsZero() || !t.Before(since)) && (until.IsZero() || !t.After(until))
+		}
+		if include {
+			if _, err := fmt.Fprintln(out, line); err != nil {
+				return err
+			}
+		}
+	}
+	return scanner.Err()
+}
@@ -0,0 +1,103 @@
+package serverlog
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/maiko/sdbx/internal/config"
+)
+
+func TestWriterRotates(t *testing.T) {
+	dir := t.TempDir()
+	w, err := Open(dir, Server, config.ServerLogsConfig{MaxSize: 1, MaxFiles: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	line := strings.Repeat("x", 600<<10) + "\n"
+	for i := 0; i < 5; i++ {
+		if _, err := w.Write([]byte(line)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	files := Files(dir, Server)
+	path := Path(dir, Server)
+	want := []string{path + ".2", path + ".1", path}
+	if fmt.Sprint(files) != fmt.Sprint(want) {
+		t.Errorf("Files() = %v, want %v", files, want)
+	}
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Errorf("%s.3 kept beyond max_files", path)
+	}
+}
+
+func TestSetRetentionPrunes(t *testing.T) {
+	dir := t.TempDir()
+	w, err := Open(dir, Jobs, config.ServerLogsConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	path := Path(dir, Jobs)
+	old := time.Now().Add(-10 * 24 * time.Hour)
+	for i := 1; i <= 3; i++ {
+		name := fmt.Sprintf("%s.%d", path, i)
+		if err := os.WriteFile(name, []byte("line\n"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if i == 2 {
+			if err := os.Chtimes(name, old, old); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	if err := w.SetRetention(config.ServerLogsConfig{MaxFiles: 2, MaxAge: 7}); err != nil {
+		t.Fatal(err)
+	}
+	if files := Files(dir, Jobs); fmt.Sprint(files) != fmt.Sprint([]string{path + ".1", path}) {
+		t.Errorf("Files() = %v, want %s.1 and %s", files, path, path)
+	}
+}
+
+func TestCopyTimeRange(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(dir+"/"+Dir, 0o750); err != nil {
+		t.Fatal(err)
+	}
+	path := Path(dir, Server)
+	if err := os.WriteFile(path+".1", []byte("2026/10/01 10:00:00 old\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	lines := "2026/10/02 10:00:00 kept\n  continued\n" +
+		"time=2026-10-02T11:00:00Z level=INFO msg=request\n" +
+		"2026/10/03 10:00:00 late\n  dropped too\n"
+	if err := os.WriteFile(path, []byte(lines), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	var out strings.Builder
+	since := time.Date(2026, 10, 2, 0, 0, 0, 0, time.Local)
+	until := time.Date(2026, 10, 2, 23, 0, 0, 0, time.Local)
+	if err := Copy(&out, dir, Server, since, until); err != nil {
+		t.Fatal(err)
+	}
+	want := "2026/10/02 10:00:00 kept\n  continued\ntime=2026-10-02T11:00:00Z level=INFO msg=request\n"
+	if out.String() != want {
+		t.Errorf("Copy() = %q, want %q", out.String(), want)
+	}
+
+	out.Reset()
+	if err := Copy(&out, dir, Server, time.Time{}, time.Time{}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(out.String(), "2026/10/01 10:00:00 old\n") || !strings.HasSuffix(out.String(), "dropped too\n") {
+		t.Errorf("Copy() without range = %q, want every line oldest first", out.String())
+	}
+}
@@ -0,0 +1,168 @@
+// Package statuspage renders the public status page of the status_page
+// section of .sdbx.yaml: whether each shown service is up, from the health
+// history, and nothing else.
+package statuspage
+
+import (
+	"bytes"
+	"context"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/maiko/sdbx/internal/config"
+	"github.com/maiko/sdbx/internal/health"
+	"github.com/maiko/sdbx/internal/scheduler"
+)
+
+const (
+	// ServicesFile lists the services shown, written by generation
+	ServicesFile = "configs/status/services.json"
+
+	// Dir holds the rendered page, served by sdbx-static
+	Dir = "data/status"
+
+	// Window is the period the uptime is computed over
+	Window = 24 * time.Hour
+)
+
+// Service states shown on the page
+const (
+	StateUp      = "up"
+	StateDown    = "down"
+	StateUnknown = "unknown" // Not sampled within Window
+)
+
+//go:embed page.html.tmpl
+var pageTemplate string
+
+var pageTmpl = template.Must(template.New("status").Parse(pageTemplate))
+
+// Service is a service shown on the status page
+type Service struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+}
+
+// Entry is the state of a service on the page
+type Entry struct {
+	Service
+	State   string  `json:"state"`
+	Uptime  float64 `json:"uptime"` // Percentage of healthy samples within Window
+	Samples int     `json:"-"`
+}
+
+// Page is the content of the status page
+type Page struct {
+	Title    string    `json:"title"`
+	Updated  time.Time `json:"updated"`
+	AllUp    bool      `json:"all_up"`
+	Services []Entry   `json:"services"`
+}
+
+// ReadServices returns the services generation chose to show
+func ReadServices(projectDir string) ([]Service, error) {
+	data, err := os.ReadFile(filepath.Join(projectDir, ServicesFile))
+	if err != nil {
+		return nil, err
+	}
+	var services []Service
+	if err := json.Unmarshal(data, &services); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", ServicesFile, err)
+	}
+	return services, nil
+}
+
+// Build computes the page of services from their health history
+func Build(title string, services []Service, history map[string][]health.Sample, now time.Time) *Page {
+	page := &Page{Title: title, Updated: now, AllUp: true, Services: []Entry{}}
+	for _, svc := range services {
+		stats := health.Summarize(svc.Name, history[svc.Name])
+		entry := Entry{Service: svc, State: StateUnknown, Uptime: stats.Uptime, Samples: stats.Samples}
+		switch {
+		case stats.Samples == 0:
+		case stats.Healthy:
+			entry.State = StateUp
+		default:
+			entry.State = StateDown
+		}
+		if entry.State != StateUp {
+			page.AllUp = false
+		}
+		page.Services = append(page.Services, entry)
+	}
+	return page
+}
+
+// Render writes the HTML of a page
+func Render(page *Page) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := pageTmpl.Execute(&buf, page); err != nil {
+		return nil, fmt.Errorf("failed to render status page: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// Update renders the status page of projectDir into Dir/index.html
+func Update(projectDir string, cfg *config.Config) error {
+	services, err := ReadServices(projectDir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("%s not found - run sdbx regenerate", ServicesFile)
+	}
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	var history map[string][]health.Sample
+	if health.Exists(projectDir) {
+		store, err := health.OpenReadOnly(projectDir)
+		if err != nil {
+			return err
+		}
+		history, err = store.History(now.Add(-Window))
+		store.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	html, err := Render(Build(cfg.StatusPage.Heading(cfg.Domain), services, history, now))
+	if err != nil {
+		return err
+	}
+	dir := filepath.Join(projectDir, Dir)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", Dir, err)
+	}
+	// Replaced atomically so nginx never serves a partial page
+	tmp := filepath.Join(dir, ".index.html.tmp")
+	if err := os.WriteFile(tmp, html, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filepath.Join(dir, "index.html"))
+}
+
+// Job returns a scheduler job rendering the status page every health sample
+func Job(projectDir string) scheduler.Job {
+	return scheduler.Job{
+		Name:     "statuspage",
+		Interval: health.DefaultInterval,
+		Run: func(_ context.Context) error {
+			cfg, err := config.Load()
+			if err != nil {
+				return fmt.Errorf("failed to load config: %w", err)
+			}
+			if !cfg.StatusPage.Enabled {
+				return nil
+			}
+			return Update(projectDir, cfg)
+		},
+	}
+}
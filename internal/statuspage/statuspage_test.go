@@ -0,0 +1,103 @@
+package statuspage
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/maiko/sdbx/internal/config"
+	"github.com/maiko/sdbx/internal/docker"
+	"github.com/maiko/sdbx/internal/health"
+)
+
+func TestBuild(t *testing.T) {
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	history := map[string][]health.Sample{
+		"plex":   {{Time: now.Add(-2 * time.Minute), Healthy: false}, {Time: now.Add(-time.Minute), Healthy: true}},
+		"sonarr": {{Time: now.Add(-time.Minute), Status: "exited", Healthy: false}},
+	}
+	services := []Service{{Name: "plex", Description: "Media server"}, {Name: "sonarr"}, {Name: "radarr"}}
+
+	page := Build("example.com", services, history, now)
+	if page.AllUp {
+		t.Error("AllUp with a service down")
+	}
+	want := map[string]string{"plex": StateUp, "sonarr": StateDown, "radarr": StateUnknown}
+	for _, entry := range page.Services {
+		if entry.State != want[entry.Name] {
+			t.Errorf("%s: State = %s, want %s", entry.Name, entry.State, want[entry.Name])
+		}
+	}
+	if plex := page.Services[0]; plex.Uptime != 50 || plex.Description != "Media server" {
+		t.Errorf("plex = %+v", plex)
+	}
+
+	if page := Build("example.com", services[:1], history, now); !page.AllUp {
+		t.Error("AllUp = false with every service up")
+	}
+}
+
+func TestRender(t *testing.T) {
+	page := &Page{
+		Title:   "<script>alert(1)</script>",
+		Updated: time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC),
+		Services: []Entry{
+			{Service: Service{Name: "plex"}, State: StateUp, Uptime: 99.5, Samples: 10},
+			{Service: Service{Name: "sonarr"}, State: StateUnknown},
+		},
+	}
+	html, err := Render(page)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := string(html)
+	if strings.Contains(out, "<script>alert") {
+		t.Error("title not escaped")
+	}
+	for _, want := range []string{"Some services are unavailable", "99.5% over 24h", `class="state unknown"`, "Updated 2026-10-16 12:00 UTC"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("page missing %q", want)
+		}
+	}
+	if strings.Count(out, "% over 24h") != 1 {
+		t.Error("uptime shown for a service without samples")
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	dir := t.TempDir()
+	cfg := &config.Config{Domain: "example.com", StatusPage: config.StatusPageConfig{Enabled: true, Title: "Home"}}
+
+	if err := Update(dir, cfg); err == nil || !strings.Contains(err.Error(), "sdbx regenerate") {
+		t.Errorf("Update() without %s = %v", ServicesFile, err)
+	}
+
+	if err := os.MkdirAll(filepath.Join(dir, filepath.Dir(ServicesFile)), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, ServicesFile), []byte(`[{"name":"plex"}]`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	store, err := health.Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = store.Record(time.Now(), []docker.Service{{Name: "sdbx-plex", Service: "plex", Status: "running", Running: true}})
+	store.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := Update(dir, cfg); err != nil {
+		t.Fatal(err)
+	}
+	html, err := os.ReadFile(filepath.Join(dir, Dir, "index.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(html), "<h1>Home</h1>") || !strings.Contains(string(html), "All services are operational") {
+		t.Errorf("index.html = %s", html)
+	}
+}
@@ -0,0 +1,97 @@
+package support
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Redacted replaces the values scrubbed from the bundle
+const Redacted = "<redacted>"
+
+// RedactedDomain replaces the domain of the project in the bundle
+const RedactedDomain = "domain.example"
+
+// minSecretLength is the length below which secret file values are not
+// searched for, as they would match ordinary text
+const minSecretLength = 8
+
+var (
+	// sensitiveKey matches the keys of YAML, env and logfmt lines whose
+	// values are redacted
+	sensitiveKey = regexp.MustCompile(`(?i)(password|passwd|secret|token|api_?key|private_?key|license_?key|credential|salt|cookie|authorization)`)
+
+	// referenceKey matches sensitive looking keys that name a file or a
+	// secret rather than hold a value, such as PASSWORD_FILE
+	referenceKey = regexp.MustCompile(`(?i)(_file|_secret|_secrets|_ref|secretref)$`)
+
+	// keyValue matches "key: value" and "key=value", in lists and quoted
+	keyValue = regexp.MustCompile(`^(\s*(?:-\s+)?["']?([A-Za-z0-9_.-]+)["']?\s*[:=]\s*)(\S.*)$`)
+
+	// urlCredentials matches the user and password of a URL
+	urlCredentials = regexp.MustCompile(`://[^/\s:@]+:[^/\s@]+@`)
+
+	// emailAddress matches e-mail addresses
+	emailAddress = regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`)
+)
+
+// Scrubber removes secrets and identifying values from the files of a
+// bundle: the values of the project's secret files wherever they appear,
+// values of sensitive keys, URL credentials, e-mail addresses and the domain
+type Scrubber struct {
+	secrets []string
+	domain  string
+}
+
+// NewScrubber reads the secret values of a project to scrub
+func NewScrubber(projectDir, domain string) *Scrubber {
+	s := &Scrubber{domain: domain}
+	paths, _ := filepath.Glob(filepath.Join(projectDir, "secrets", "*"))
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil || !info.Mode().IsRegular() || info.Size() > 64<<10 {
+			continue
+		}
+		data, err := os.ReadFile(path) //nolint:gosec // G304 - file of the secrets directory
+		if err != nil {
+			continue
+		}
+		for _, line := range strings.Split(string(data), "\n") {
+			if value := strings.TrimSpace(line); len(value) >= minSecretLength {
+				s.secrets = append(s.secrets, value)
+			}
+		}
+	}
+	// Longest first, so a secret containing another is replaced whole
+	slices.SortFunc(s.secrets, func(a, b string) int { return len(b) - len(a) })
+	return s
+}
+
+// Scrub returns data with secrets and identifying values replaced
+func (s *Scrubber) Scrub(data []byte) []byte {
+	lines := strings.Split(string(data), "\n")
+	for i, line := range lines {
+		lines[i] = s.scrubLine(line)
+	}
+	return []byte(strings.Join(lines, "\n"))
+}
+
+// scrubLine scrubs a single line
+func (s *Scrubber) scrubLine(line string) string {
+	for _, secret := range s.secrets {
+		line = strings.ReplaceAll(line, secret, Redacted)
+	}
+	line = urlCredentials.ReplaceAllString(line, "://"+Redacted+"@")
+	line = emailAddress.ReplaceAllString(line, Redacted)
+	if m := keyValue.FindStringSubmatch(line); m != nil && sensitiveKey.MatchString(m[2]) && !referenceKey.MatchString(m[2]) {
+		if value := strings.TrimSpace(m[3]); !slices.Contains([]string{`""`, "''", "{}", "[]", "null", "~", "|", ">"}, value) {
+			line = m[1] + Redacted
+		}
+	}
+	if s.domain != "" {
+		line = strings.ReplaceAll(line, s.domain, RedactedDomain)
+	}
+	return line
+}
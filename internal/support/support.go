@@ -0,0 +1,249 @@
+// Package support builds the diagnostics bundle of a project (sdbx
+// support-bundle): its redacted configuration and generated files, the
+// doctor checks, container states and recent logs in one archive to attach
+// to bug reports.
+package support
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/maiko/sdbx/internal/config"
+	"github.com/maiko/sdbx/internal/docker"
+	"github.com/maiko/sdbx/internal/doctor"
+	"github.com/maiko/sdbx/internal/serverlog"
+)
+
+// DefaultLogLines is how many of the last log lines of each service are collected
+const DefaultLogLines = 500
+
+// serverLogWindow is how far back the logs sdbx writes itself are collected
+const serverLogWindow = 24 * time.Hour
+
+// projectFiles are the configuration and generated files collected, when
+// present. Secret files, the users database, acme.json and backups never are.
+var projectFiles = []string{
+	".sdbx.yaml",
+	".sdbx.lock",
+	config.StateFile,
+	".env",
+	"compose.yaml",
+	"configs/traefik/traefik.yml",
+	"configs/authelia/configuration.yml",
+}
+
+// projectGlobs are generated files collected by pattern
+var projectGlobs = []string{"configs/traefik/dynamic/*.yml"}
+
+// Bundle collects the diagnostics of a project
+type Bundle struct {
+	ProjectDir string
+	Version    string          // sdbx version recorded in the bundle
+	LogLines   int             // Last log lines of each service, 0 skips container logs
+	Compose    *docker.Compose // Container states and logs; nil skips them
+	Doctor     *doctor.Doctor  // Checks run for the bundle; nil skips them
+	Scrubber   *Scrubber       // Default: the secrets of the project, keeping the domain
+}
+
+// Report lists what a bundle holds
+type Report struct {
+	Path    string   `json:"path"`
+	Files   []string `json:"files"`
+	Skipped []string `json:"skipped,omitempty"` // Parts not collected, with why
+}
+
+// entry is a file of the bundle
+type entry struct {
+	name string
+	data []byte
+}
+
+// Write collects the diagnostics and writes them, scrubbed, to a tar.gz
+// archive at path. A part that cannot be collected, such as the container
+// states without Docker, is listed as skipped instead of failing.
+func (b *Bundle) Write(ctx context.Context, path string) (*Report, error) {
+	scrubber := b.Scrubber
+	if scrubber == nil {
+		scrubber = NewScrubber(b.ProjectDir, "")
+	}
+	report := &Report{Path: path}
+	var entries []entry
+	add := func(name string, data []byte) {
+		entries = append(entries, entry{name, scrubber.Scrub(data)})
+		report.Files = append(report.Files, name)
+	}
+	skip := func(part string, err error) {
+		report.Skipped = append(report.Skipped, strings.TrimSuffix(fmt.Sprintf("%s: %v", part, strings.TrimSpace(err.Error())), ":"))
+	}
+
+	add("system.txt", b.system())
+	b.collectFiles(add, skip)
+	if b.Doctor != nil {
+		add("doctor.txt", formatChecks(b.Doctor.RunAll(ctx), b.Doctor.Loops))
+	}
+	if b.Compose != nil {
+		b.collectContainers(ctx, add, skip)
+	}
+	since := time.Now().Add(-serverLogWindow)
+	for _, name := range serverlog.Names {
+		var buf bytes.Buffer
+		if err := serverlog.Copy(&buf, b.ProjectDir, name, since, time.Time{}); err != nil {
+			skip("sdbx "+name+" log", err)
+			continue
+		}
+		if buf.Len() > 0 {
+			add("logs/sdbx-"+name+".log", buf.Bytes())
+		}
+	}
+
+	readme := b.readme(report)
+	if err := writeArchive(path, append([]entry{{"README.txt", readme}}, entries...)); err != nil {
+		return nil, err
+	}
+	report.Files = append([]string{"README.txt"}, report.Files...)
+	return report, nil
+}
+
+// system describes the sdbx build and host
+func (b *Bundle) system() []byte {
+	return []byte(fmt.Sprintf("sdbx %s\nGo %s\nOS %s/%s\nCollected %s\n",
+		b.Version, runtime.Version(), runtime.GOOS, runtime.GOARCH, time.Now().UTC().Format(time.RFC3339)))
+}
+
+// collectFiles adds the configuration and generated files of the project
+func (b *Bundle) collectFiles(add func(string, []byte), skip func(string, error)) {
+	files := append([]string{}, projectFiles...)
+	for _, pattern := range projectGlobs {
+		matches, _ := filepath.Glob(filepath.Join(b.ProjectDir, pattern))
+		for _, match := range matches {
+			if rel, err := filepath.Rel(b.ProjectDir, match); err == nil {
+				files = append(files, filepath.ToSlash(rel))
+			}
+		}
+	}
+	for _, file := range files {
+		data, err := os.ReadFile(filepath.Join(b.ProjectDir, file))
+		if err != nil {
+			if !os.IsNotExist(err) {
+				skip(file, err)
+			}
+			continue
+		}
+		add("project/"+file, data)
+	}
+}
+
+// collectContainers adds the state of every container and their last logs
+func (b *Bundle) collectContainers(ctx context.Context, add func(string, []byte), skip func(string, error)) {
+	services, err := b.Compose.PSAll(ctx)
+	if err != nil {
+		skip("containers", err)
+		return
+	}
+	data, err := json.MarshalIndent(services, "", "  ")
+	if err != nil {
+		skip("containers", err)
+		return
+	}
+	add("containers.json", data)
+
+	if b.LogLines <= 0 {
+		return
+	}
+	for _, svc := range services {
+		name := svc.Service
+		if name == "" {
+			name = svc.Name
+		}
+		logs, err := b.Compose.Logs(ctx, name, b.LogLines, false)
+		if err != nil {
+			skip("logs of "+name, err)
+			continue
+		}
+		add("logs/"+name+".log", []byte(logs))
+	}
+}
+
+// readme explains the bundle: what it holds, what was not collected and
+// how it was scrubbed
+func (b *Bundle) readme(report *Report) []byte {
+	var s strings.Builder
+	s.WriteString("SDBX support bundle\n\n")
+	s.WriteString("Secret values, passwords, tokens, keys, URL credentials and e-mail\n")
+	s.WriteString(fmt.Sprintf("addresses are replaced with %s, and the domain with %s.\n", Redacted, RedactedDomain))
+	s.WriteString("Secret files, the users database, acme.json and backups are not included.\n")
+	s.WriteString("Review the files before sharing them.\n\nFiles:\n")
+	for _, file := range report.Files {
+		s.WriteString("  " + file + "\n")
+	}
+	if len(report.Skipped) > 0 {
+		s.WriteString("\nNot collected:\n")
+		for _, skipped := range report.Skipped {
+			s.WriteString("  " + skipped + "\n")
+		}
+	}
+	return []byte(s.String())
+}
+
+// formatChecks writes the doctor checks and crash looping services as text
+func formatChecks(checks []doctor.Check, loops []doctor.CrashLoop) []byte {
+	var s strings.Builder
+	for _, check := range checks {
+		status := "PASS"
+		switch check.Status {
+		case doctor.StatusWarning:
+			status = "WARN"
+		case doctor.StatusFailed:
+			status = "FAIL"
+		case doctor.StatusPending, doctor.StatusRunning:
+			status = "SKIP"
+		}
+		fmt.Fprintf(&s, "[%s] %s: %s\n", status, check.Name, check.Message)
+	}
+	for _, loop := range loops {
+		fmt.Fprintf(&s, "\nRestart loop: %s\n", loop.Service)
+		for _, line := range loop.Logs {
+			s.WriteString("  " + line + "\n")
+		}
+	}
+	return []byte(s.String())
+}
+
+// writeArchive writes the entries to a tar.gz archive, readable by its owner only
+func writeArchive(path string, entries []entry) error {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600) //nolint:gosec // G304 - path chosen by the user
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", path, err)
+	}
+	defer f.Close()
+
+	gz := gzip.NewWriter(f)
+	tw := tar.NewWriter(gz)
+	root := strings.TrimSuffix(strings.TrimSuffix(filepath.Base(path), ".gz"), ".tar")
+	now := time.Now()
+	for _, e := range entries {
+		header := &tar.Header{Name: root + "/" + e.name, Mode: 0o600, Size: int64(len(e.data)), ModTime: now}
+		if err := tw.WriteHeader(header); err != nil {
+			return fmt.Errorf("failed to write %s: %w", e.name, err)
+		}
+		if _, err := tw.Write(e.data); err != nil {
+			return fmt.Errorf("failed to write %s: %w", e.name, err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		return err
+	}
+	return f.Close()
+}
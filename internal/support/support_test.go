@@ -0,0 +1,91 @@
+package support
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// readArchive returns the files of a bundle by name, without the root directory
+func readArchive(t *testing.T, path string) map[string]string {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := make(map[string]string)
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return files
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, _ := io.ReadAll(tr)
+		_, name, _ := strings.Cut(header.Name, "/")
+		files[name] = string(data)
+	}
+}
+
+func TestBundleWrite(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) {
+		t.Helper()
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(".sdbx.yaml", "domain: box.example.net\nsmtp:\n  password: hunter2hunter2\n")
+	write("compose.yaml", "services: {}\n")
+	write("configs/traefik/dynamic/middlewares.yml", "http: {}\n")
+	write("secrets/cloudflared_tunnel_token.txt", "tunnel-token-value\n")
+	write("configs/traefik/acme.json", "{}")
+	write("logs/jobs.log", "")
+
+	b := &Bundle{ProjectDir: dir, Version: "1.2.3", Scrubber: NewScrubber(dir, "box.example.net")}
+	path := filepath.Join(t.TempDir(), "sdbx-support-test.tar.gz")
+	report, err := b.Write(context.Background(), path)
+	if err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	files := readArchive(t, path)
+	for _, name := range []string{"README.txt", "system.txt", "project/.sdbx.yaml", "project/compose.yaml", "project/configs/traefik/dynamic/middlewares.yml"} {
+		if _, ok := files[name]; !ok {
+			t.Errorf("bundle misses %s, has %v", name, report.Files)
+		}
+	}
+	for name, content := range files {
+		if strings.Contains(name, "secrets") || strings.Contains(name, "acme.json") {
+			t.Errorf("bundle includes %s", name)
+		}
+		if strings.Contains(content, "hunter2hunter2") || strings.Contains(content, "box.example.net") {
+			t.Errorf("%s is not scrubbed:\n%s", name, content)
+		}
+	}
+	if !strings.Contains(files["system.txt"], "sdbx 1.2.3") {
+		t.Errorf("system.txt = %q", files["system.txt"])
+	}
+	if !strings.Contains(files["README.txt"], "project/.sdbx.yaml") {
+		t.Errorf("README.txt does not list the files:\n%s", files["README.txt"])
+	}
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0o600 {
+		t.Errorf("bundle mode = %v, %v, want 0600", info.Mode(), err)
+	}
+}
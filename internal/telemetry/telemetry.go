@@ -0,0 +1,302 @@
+// Package telemetry records anonymous usage reports once the user opts in
+// with 'sdbx telemetry on': the command run, how long it took, the class
+// of its error and the number of enabled addons. Nothing is recorded while
+// telemetry is off. Reports are kept locally, shown by 'sdbx telemetry
+// show' exactly as they are sent, and sent in batches to the endpoint
+// chosen when opting in.
+package telemetry
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/maiko/sdbx/internal/config"
+	"github.com/maiko/sdbx/internal/problem"
+)
+
+// Environment variables turning telemetry off whatever the settings say
+const (
+	DisableEnv    = "SDBX_TELEMETRY" // "off" disables
+	DoNotTrackEnv = "DO_NOT_TRACK"   // Any value but "" or "0" disables (consoledonottrack.com)
+)
+
+const (
+	// sendInterval is the least time between two sends
+	sendInterval = 24 * time.Hour
+	// sendTimeout bounds a send, so a command never waits long on it
+	sendTimeout = 3 * time.Second
+	// maxPending is how many reports are kept while they cannot be sent,
+	// oldest dropped first
+	maxPending = 500
+)
+
+// Error classes of reports, next to the codes of problem kinds
+const (
+	ErrorCanceled   = "canceled"
+	ErrorValidation = "validation"
+	ErrorNoProject  = "no-project"
+	ErrorOther      = "other"
+)
+
+// Settings is the user's telemetry choice, in telemetry.yaml next to
+// projects.yaml
+type Settings struct {
+	Enabled  bool      `yaml:"enabled" json:"enabled"`
+	ID       string    `yaml:"id,omitempty" json:"id,omitempty"`             // Random installation ID, new on each opt-in
+	Endpoint string    `yaml:"endpoint,omitempty" json:"endpoint,omitempty"` // Where reports are sent; none keeps them local
+	LastSent time.Time `yaml:"last_sent,omitempty" json:"lastSent,omitempty"`
+}
+
+// Report is what is recorded of one command. It holds no arguments, paths,
+// names, domains or error messages.
+type Report struct {
+	ID         string `json:"id"`
+	Version    string `json:"version"`
+	OS         string `json:"os"`
+	Arch       string `json:"arch"`
+	Date       string `json:"date"`             // Day of the run, UTC
+	Command    string `json:"command"`          // Command path, e.g. "addon enable"
+	DurationMS int64  `json:"duration_ms"`      // Run time in milliseconds
+	Error      string `json:"error,omitempty"`  // Error class, see ErrorClass
+	Addons     *int   `json:"addons,omitempty"` // Enabled addons, when run in a project
+}
+
+// Store holds the settings and the reports not sent yet
+type Store struct {
+	SettingsPath string
+	PendingPath  string
+	Client       *http.Client // Default: a client with sendTimeout
+}
+
+// NewStore returns the store of the user: ~/.config/sdbx/telemetry.yaml and
+// ~/.cache/sdbx/telemetry.jsonl
+func NewStore() *Store {
+	home, _ := os.UserHomeDir()
+	return &Store{
+		SettingsPath: filepath.Join(home, ".config", "sdbx", "telemetry.yaml"),
+		PendingPath:  filepath.Join(home, ".cache", "sdbx", "telemetry.jsonl"),
+	}
+}
+
+// DisabledByEnv reports whether the environment turns telemetry off
+func DisabledByEnv() bool {
+	if v := os.Getenv(DoNotTrackEnv); v != "" && v != "0" {
+		return true
+	}
+	return os.Getenv(DisableEnv) == "off"
+}
+
+// Settings reads the settings; a missing file is telemetry off
+func (s *Store) Settings() (Settings, error) {
+	data, err := os.ReadFile(s.SettingsPath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return Settings{}, nil
+	}
+	if err != nil {
+		return Settings{}, fmt.Errorf("failed to read %s: %w", s.SettingsPath, err)
+	}
+	var settings Settings
+	if err := yaml.Unmarshal(data, &settings); err != nil {
+		return Settings{}, fmt.Errorf("failed to parse %s: %w", s.SettingsPath, err)
+	}
+	return settings, nil
+}
+
+// Save writes the settings
+func (s *Store) Save(settings Settings) error {
+	data, err := yaml.Marshal(settings)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.SettingsPath), 0o750); err != nil {
+		return err
+	}
+	return os.WriteFile(s.SettingsPath, data, 0o600)
+}
+
+// Enable opts in with a new installation ID. An empty endpoint keeps the
+// previous one.
+func (s *Store) Enable(endpoint string) (Settings, error) {
+	settings, err := s.Settings()
+	if err != nil {
+		return settings, err
+	}
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return settings, err
+	}
+	settings.Enabled, settings.ID = true, hex.EncodeToString(id)
+	if endpoint != "" {
+		settings.Endpoint = endpoint
+	}
+	return settings, s.Save(settings)
+}
+
+// Disable opts out: the installation ID and the reports not sent are deleted
+func (s *Store) Disable() error {
+	settings, err := s.Settings()
+	if err != nil {
+		return err
+	}
+	settings.Enabled, settings.ID = false, ""
+	if err := s.Save(settings); err != nil {
+		return err
+	}
+	if err := os.Remove(s.PendingPath); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// NewReport describes a command run
+func NewReport(settings Settings, version, command string, duration time.Duration, err error, addons *int) Report {
+	return Report{
+		ID:         settings.ID,
+		Version:    version,
+		OS:         runtime.GOOS,
+		Arch:       runtime.GOARCH,
+		Date:       time.Now().UTC().Format("2006-01-02"),
+		Command:    command,
+		DurationMS: duration.Milliseconds(),
+		Error:      ErrorClass(err),
+		Addons:     addons,
+	}
+}
+
+// ErrorClass returns the class of an error, never its message: the code
+// of its problem kind, one of the Error* classes, or "" without error
+func ErrorClass(err error) string {
+	var validation *config.ValidationError
+	switch {
+	case err == nil:
+		return ""
+	case errors.Is(err, context.Canceled):
+		return ErrorCanceled
+	case problem.KindOf(err) != nil:
+		return problem.KindOf(err).Code
+	case errors.As(err, &validation):
+		return ErrorValidation
+	case config.IsProjectNotFoundError(err):
+		return ErrorNoProject
+	}
+	return ErrorOther
+}
+
+// Record appends a report to the pending ones
+func (s *Store) Record(report Report) error {
+	pending, err := s.Pending()
+	if err != nil {
+		return err
+	}
+	pending = append(pending, report)
+	if len(pending) > maxPending {
+		pending = pending[len(pending)-maxPending:]
+	}
+	return s.writePending(pending)
+}
+
+// Pending returns the reports not sent yet, oldest first
+func (s *Store) Pending() ([]Report, error) {
+	f, err := os.Open(s.PendingPath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var reports []Report
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var r Report
+		// Lines that do not parse, such as one cut short by a crash, are skipped
+		if json.Unmarshal(scanner.Bytes(), &r) == nil {
+			reports = append(reports, r)
+		}
+	}
+	return reports, scanner.Err()
+}
+
+// writePending replaces the pending reports
+func (s *Store) writePending(reports []Report) error {
+	var buf bytes.Buffer
+	for _, r := range reports {
+		line, err := json.Marshal(r)
+		if err != nil {
+			return err
+		}
+		buf.Write(append(line, '\n'))
+	}
+	if err := os.MkdirAll(filepath.Dir(s.PendingPath), 0o750); err != nil {
+		return err
+	}
+	return os.WriteFile(s.PendingPath, buf.Bytes(), 0o600)
+}
+
+// Payload is the body sent for the pending reports, as shown by 'sdbx
+// telemetry show'
+func Payload(reports []Report) ([]byte, error) {
+	if reports == nil {
+		reports = []Report{}
+	}
+	return json.MarshalIndent(reports, "", "  ")
+}
+
+// SendDue sends the pending reports when telemetry is on, an endpoint is
+// set and the last send is older than a day. Sent reports are deleted;
+// failed sends keep them for the next try.
+func (s *Store) SendDue(ctx context.Context, now time.Time) error {
+	settings, err := s.Settings()
+	if err != nil || !settings.Enabled || settings.Endpoint == "" || now.Sub(settings.LastSent) < sendInterval {
+		return err
+	}
+	pending, err := s.Pending()
+	if err != nil || len(pending) == 0 {
+		return err
+	}
+	body, err := Payload(pending)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, sendTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, settings.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	client := s.Client
+	if client == nil {
+		client = &http.Client{Timeout: sendTimeout}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("telemetry endpoint answered %s", resp.Status)
+	}
+
+	settings.LastSent = now
+	if err := s.Save(settings); err != nil {
+		return err
+	}
+	return os.Remove(s.PendingPath)
+}
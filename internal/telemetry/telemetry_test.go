@@ -0,0 +1,152 @@
+package telemetry
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/maiko/sdbx/internal/config"
+	"github.com/maiko/sdbx/internal/problem"
+)
+
+func testStore(t *testing.T) *Store {
+	t.Helper()
+	dir := t.TempDir()
+	return &Store{
+		SettingsPath: filepath.Join(dir, "config", "telemetry.yaml"),
+		PendingPath:  filepath.Join(dir, "cache", "telemetry.jsonl"),
+	}
+}
+
+func TestEnableDisable(t *testing.T) {
+	s := testStore(t)
+	if settings, err := s.Settings(); err != nil || settings.Enabled {
+		t.Fatalf("Settings() without a file = %+v, %v, want off", settings, err)
+	}
+
+	first, err := s.Enable("https://telemetry.example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !first.Enabled || len(first.ID) != 32 || first.Endpoint != "https://telemetry.example.com" {
+		t.Errorf("Enable() = %+v", first)
+	}
+	second, err := s.Enable("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second.ID == first.ID || second.Endpoint != first.Endpoint {
+		t.Errorf("Enable() again = %+v, want a new ID and the same endpoint", second)
+	}
+
+	if err := s.Record(Report{ID: second.ID, Command: "up"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Disable(); err != nil {
+		t.Fatal(err)
+	}
+	settings, _ := s.Settings()
+	if settings.Enabled || settings.ID != "" {
+		t.Errorf("Settings() after Disable() = %+v", settings)
+	}
+	if _, err := os.Stat(s.PendingPath); !os.IsNotExist(err) {
+		t.Errorf("pending reports kept after Disable(): %v", err)
+	}
+}
+
+func TestErrorClass(t *testing.T) {
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{nil, ""},
+		{fmt.Errorf("up: %w", context.Canceled), ErrorCanceled},
+		{problem.Wrap(problem.ErrPortConflict, nil, "port 80 of traefik"), "port-conflict"},
+		{fmt.Errorf("failed: %w", config.NewValidationError("domain", "required")), ErrorValidation},
+		{&config.ProjectNotFoundError{StartPath: "/home/alice"}, ErrorNoProject},
+		{errors.New("something at /home/alice/sdbx"), ErrorOther},
+	}
+	for _, tt := range tests {
+		if got := ErrorClass(tt.err); got != tt.want {
+			t.Errorf("ErrorClass(%v) = %q, want %q", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestRecordKeepsNoDetails(t *testing.T) {
+	s := testStore(t)
+	addons := 3
+	report := NewReport(Settings{ID: "abc"}, "1.2.3", "addon enable", 1500*time.Millisecond, errors.New("addon overseerr at /srv/media failed"), &addons)
+	if err := s.Record(report); err != nil {
+		t.Fatal(err)
+	}
+
+	pending, err := s.Pending()
+	if err != nil || len(pending) != 1 {
+		t.Fatalf("Pending() = %v, %v", pending, err)
+	}
+	payload, err := Payload(pending)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(payload), "overseerr") || strings.Contains(string(payload), "/srv") {
+		t.Errorf("payload leaks the error message:\n%s", payload)
+	}
+	if pending[0].Error != ErrorOther || pending[0].DurationMS != 1500 || *pending[0].Addons != 3 {
+		t.Errorf("report = %+v", pending[0])
+	}
+	if empty, _ := Payload(nil); string(empty) != "[]" {
+		t.Errorf("Payload(nil) = %s, want []", empty)
+	}
+}
+
+func TestSendDue(t *testing.T) {
+	var received []Report
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if err := json.Unmarshal(body, &received); err != nil {
+			t.Errorf("invalid payload: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	s := testStore(t)
+	settings, err := s.Enable(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, command := range []string{"up", "status"} {
+		if err := s.Record(NewReport(settings, "dev", command, time.Second, nil, nil)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	now := time.Now()
+	if err := s.SendDue(context.Background(), now); err != nil {
+		t.Fatalf("SendDue() error = %v", err)
+	}
+	if len(received) != 2 || received[0].Command != "up" {
+		t.Errorf("received = %+v, want both reports", received)
+	}
+	if pending, _ := s.Pending(); len(pending) != 0 {
+		t.Errorf("Pending() after send = %v", pending)
+	}
+
+	// Not due again within a day
+	received = nil
+	if err := s.Record(NewReport(settings, "dev", "down", time.Second, nil, nil)); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SendDue(context.Background(), now.Add(time.Hour)); err != nil || received != nil {
+		t.Errorf("SendDue() within a day = %v, sent %v", err, received)
+	}
+}
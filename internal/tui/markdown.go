@@ -0,0 +1,20 @@
+package tui
+
+import (
+	"github.com/charmbracelet/glamour"
+)
+
+// RenderMarkdown renders Markdown for the terminal, wrapped at width.
+// Without styling (pipes, --no-tui) the plain "notty" style is used.
+func RenderMarkdown(markdown string, width int, styled bool) (string, error) {
+	style := glamour.WithStandardStyle("notty")
+	if styled {
+		style = glamour.WithAutoStyle()
+	}
+
+	renderer, err := glamour.NewTermRenderer(style, glamour.WithWordWrap(width))
+	if err != nil {
+		return "", err
+	}
+	return renderer.Render(markdown)
+}
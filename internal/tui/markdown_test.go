@@ -0,0 +1,24 @@
+package tui
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestRenderMarkdown(t *testing.T) {
+	markdown := "# Setup\n\nGet your **claim token** first. " + strings.Repeat("Then restart the service. ", 10) + "\n"
+
+	out, err := RenderMarkdown(markdown, 60, false)
+	if err != nil {
+		t.Fatalf("RenderMarkdown() error = %v", err)
+	}
+	if !strings.Contains(out, "Setup") || !strings.Contains(out, "claim token") {
+		t.Errorf("RenderMarkdown() = %q", out)
+	}
+	for _, line := range strings.Split(out, "\n") {
+		if n := utf8.RuneCountInString(strings.TrimRight(line, " ")); n > 60 {
+			t.Errorf("RenderMarkdown() line is %d characters, want <= 60: %q", n, line)
+		}
+	}
+}
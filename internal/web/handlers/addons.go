@@ -1,28 +1,42 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"html/template"
 	"log"
 	"net/http"
+	"path"
 	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
 
 	"github.com/maiko/sdbx/internal/config"
+	"github.com/maiko/sdbx/internal/docker"
+	"github.com/maiko/sdbx/internal/generator"
 	"github.com/maiko/sdbx/internal/registry"
 )
 
 // AddonsHandler handles addon management routes
 type AddonsHandler struct {
 	registry   *registry.Registry
+	compose    *docker.Compose
+	jobs       *JobsHandler
 	projectDir string
 	templates  *template.Template
+
+	// installMu serializes installs, which rewrite the config and project
+	installMu sync.Mutex
 }
 
-// NewAddonsHandler creates a new addons handler
-func NewAddonsHandler(reg *registry.Registry, projectDir string, tmpl *template.Template) *AddonsHandler {
+// NewAddonsHandler creates a new addons handler. Installs run on jobs.
+func NewAddonsHandler(reg *registry.Registry, compose *docker.Compose, jobs *JobsHandler, projectDir string, tmpl *template.Template) *AddonsHandler {
 	return &AddonsHandler{
 		registry:   reg,
+		compose:    compose,
+		jobs:       jobs,
 		projectDir: projectDir,
 		templates:  tmpl,
 	}
@@ -38,6 +52,19 @@ type AddonDisplay struct {
 	Source      string
 	Enabled     bool
 	HasWebUI    bool
+	Homepage    string
+	Maintainer  string
+	Tags        []string
+	Icon        string   // Asset URL, empty without icon
+	Screenshots []string // Asset URLs
+	Hidden      bool     // Filtered out by the current search
+}
+
+// AddonCategory is a category filter of the addons page
+type AddonCategory struct {
+	Name   string
+	Count  int
+	Active bool
 }
 
 // AddonResponse represents API response for addon operations
@@ -46,11 +73,44 @@ type AddonResponse struct {
 	Message        string `json:"message"`
 	Addon          string `json:"addon,omitempty"`
 	PendingRestart bool   `json:"pendingRestart,omitempty"`
+	JobID          string `json:"jobId,omitempty"`
 }
 
-// HandleAddonsPage handles the addons catalog page
+// newAddonDisplay formats a registry service for display
+func newAddonDisplay(svc registry.ServiceInfo, cfg *config.Config) AddonDisplay {
+	addon := AddonDisplay{
+		Name:        svc.Name,
+		DisplayName: formatServiceName(svc.Name),
+		Description: svc.Description,
+		Category:    string(svc.Category),
+		Version:     svc.Version,
+		Source:      svc.Source,
+		Enabled:     cfg.IsAddonEnabled(svc.Name),
+		HasWebUI:    svc.HasWebUI,
+		Homepage:    svc.Homepage,
+		Maintainer:  svc.Maintainer,
+		Tags:        svc.Tags,
+	}
+	if svc.Icon != "" {
+		addon.Icon = addonAssetURL(svc.Name, svc.Icon)
+	}
+	for _, shot := range svc.Screenshots {
+		addon.Screenshots = append(addon.Screenshots, addonAssetURL(svc.Name, shot))
+	}
+	return addon
+}
+
+// addonAssetURL returns the URL HandleAddonAsset serves an image at
+func addonAssetURL(addon, file string) string {
+	return "/addons/" + addon + "/assets/" + file
+}
+
+// HandleAddonsPage handles the addons catalog page. The q and category
+// query parameters filter the addons shown, as the page's search does.
 func (h *AddonsHandler) HandleAddonsPage(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
+	query := r.URL.Query().Get("q")
+	category := r.URL.Query().Get("category")
 
 	// Load config to check enabled addons
 	cfg, err := config.Load()
@@ -65,21 +125,24 @@ func (h *AddonsHandler) HandleAddonsPage(w http.ResponseWriter, r *http.Request)
 		http.Error(w, "Failed to load addons", http.StatusInternalServerError)
 		return
 	}
+	matches, err := h.registry.SearchServices(ctx, query, registry.ServiceCategory(category))
+	if err != nil {
+		http.Error(w, "Failed to search addons", http.StatusInternalServerError)
+		return
+	}
+	matched := make(map[string]bool)
+	for _, svc := range matches {
+		matched[svc.Name] = true
+	}
 
-	// Filter and format addons
+	// Filter and format addons; all are rendered so the search can
+	// show them again without reloading
 	var addons []AddonDisplay
 	for _, svc := range services {
 		if svc.IsAddon {
-			addons = append(addons, AddonDisplay{
-				Name:        svc.Name,
-				DisplayName: formatServiceName(svc.Name),
-				Description: svc.Description,
-				Category:    string(svc.Category),
-				Version:     svc.Version,
-				Source:      svc.Source,
-				Enabled:     cfg.IsAddonEnabled(svc.Name),
-				HasWebUI:    svc.HasWebUI,
-			})
+			addon := newAddonDisplay(svc, cfg)
+			addon.Hidden = !matched[svc.Name]
+			addons = append(addons, addon)
 		}
 	}
 
@@ -95,6 +158,9 @@ func (h *AddonsHandler) HandleAddonsPage(w http.ResponseWriter, r *http.Request)
 
 	data := map[string]interface{}{
 		"AddonsByCategory": addonsByCategory,
+		"Categories":       addonCategories(addonsByCategory, category),
+		"Query":            query,
+		"Category":         category,
 		"TotalAddons":      len(addons),
 		"EnabledAddons":    countEnabledAddons(addons),
 	}
@@ -102,7 +168,100 @@ func (h *AddonsHandler) HandleAddonsPage(w http.ResponseWriter, r *http.Request)
 	h.renderTemplate(w, "pages/addons.html", data)
 }
 
-// HandleSearchAddons handles addon search
+// addonCategories returns the category filters, sorted by name
+func addonCategories(addonsByCategory map[string][]AddonDisplay, active string) []AddonCategory {
+	categories := make([]AddonCategory, 0, len(addonsByCategory))
+	for name, addons := range addonsByCategory {
+		categories = append(categories, AddonCategory{Name: name, Count: len(addons), Active: name == active})
+	}
+	sort.Slice(categories, func(i, j int) bool {
+		return categories[i].Name < categories[j].Name
+	})
+	return categories
+}
+
+// HandleAddonDetailPage handles GET /addons/{addon}, showing the addon's
+// details and its rendered README
+func (h *AddonsHandler) HandleAddonDetailPage(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	addonName := r.PathValue("addon")
+
+	def, source, err := h.registry.GetService(ctx, addonName)
+	if err != nil || !def.Conditions.RequireAddon {
+		http.NotFound(w, r)
+		return
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		log.Printf("Warning [addons.detail]: config.Load failed, using defaults: %v", err)
+		cfg = config.DefaultConfig()
+	}
+
+	readme, err := h.registry.GetServiceReadme(ctx, addonName)
+	if err != nil {
+		httpError(w, "addons.readme", err, http.StatusInternalServerError)
+		return
+	}
+	readmeHTML, err := renderMarkdown(readme)
+	if err != nil {
+		httpError(w, "addons.readme render", err, http.StatusInternalServerError)
+		return
+	}
+
+	addon := newAddonDisplay(registry.ServiceInfo{
+		Name:        def.Metadata.Name,
+		Description: def.Metadata.Description,
+		Category:    def.Metadata.Category,
+		Version:     def.Metadata.Version,
+		Source:      source,
+		IsAddon:     true,
+		HasWebUI:    def.Routing.Enabled,
+		Homepage:    def.Metadata.Homepage,
+		Maintainer:  def.Metadata.Maintainer,
+		Tags:        def.Metadata.Tags,
+		Icon:        def.Metadata.Icon,
+		Screenshots: def.Metadata.Screenshots,
+	}, cfg)
+
+	data := map[string]interface{}{
+		"Addon":         addon,
+		"Image":         def.Spec.Image.Repository + ":" + def.Spec.Image.Tag,
+		"Homepage":      def.Metadata.Homepage,
+		"Documentation": def.Metadata.Documentation,
+		"Readme":        readmeHTML,
+	}
+
+	h.renderTemplate(w, "pages/addon_detail.html", data)
+}
+
+// HandleAddonAsset handles GET /addons/{addon}/assets/{file...}, serving
+// the icon and screenshots declared in the addon's metadata
+func (h *AddonsHandler) HandleAddonAsset(w http.ResponseWriter, r *http.Request) {
+	data, err := h.registry.GetServiceAsset(r.Context(), r.PathValue("addon"), r.PathValue("file"))
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	contentType := "application/octet-stream"
+	switch strings.ToLower(path.Ext(r.PathValue("file"))) {
+	case ".png":
+		contentType = "image/png"
+	case ".jpg", ".jpeg":
+		contentType = "image/jpeg"
+	case ".gif":
+		contentType = "image/gif"
+	case ".webp":
+		contentType = "image/webp"
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Cache-Control", "public, max-age=3600")
+	w.Write(data)
+}
+
+// HandleSearchAddons handles GET /api/addons/search, matching every word
+// of q against the addons' name, description, category, tags and maintainer
 func (h *AddonsHandler) HandleSearchAddons(w http.ResponseWriter, r *http.Request) {
 	query := r.URL.Query().Get("q")
 	category := r.URL.Query().Get("category")
@@ -129,19 +288,10 @@ func (h *AddonsHandler) HandleSearchAddons(w http.ResponseWriter, r *http.Reques
 	}
 
 	// Filter to addons only
-	var addons []AddonDisplay
+	addons := []AddonDisplay{}
 	for _, svc := range results {
 		if svc.IsAddon {
-			addons = append(addons, AddonDisplay{
-				Name:        svc.Name,
-				DisplayName: formatServiceName(svc.Name),
-				Description: svc.Description,
-				Category:    string(svc.Category),
-				Version:     svc.Version,
-				Source:      svc.Source,
-				Enabled:     cfg.IsAddonEnabled(svc.Name),
-				HasWebUI:    svc.HasWebUI,
-			})
+			addons = append(addons, newAddonDisplay(svc, cfg))
 		}
 	}
 
@@ -217,6 +367,91 @@ func (h *AddonsHandler) HandleEnableAddon(w http.ResponseWriter, r *http.Request
 	})
 }
 
+// HandleInstallAddon handles POST /api/addons/{addon}/install: a job
+// enables the addon, regenerates the project and starts the addon's
+// container. The response carries the job ID to poll.
+func (h *AddonsHandler) HandleInstallAddon(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	addonName := r.PathValue("addon")
+	if addonName == "" {
+		h.respondJSON(w, http.StatusBadRequest, AddonResponse{
+			Success: false,
+			Message: "Addon name is required",
+		})
+		return
+	}
+
+	def, _, err := h.registry.GetService(r.Context(), addonName)
+	if err != nil || !def.Conditions.RequireAddon {
+		h.respondJSON(w, http.StatusNotFound, AddonResponse{
+			Success: false,
+			Message: fmt.Sprintf("Addon '%s' not found", addonName),
+			Addon:   addonName,
+		})
+		return
+	}
+	if h.compose == nil || h.jobs == nil {
+		h.respondJSON(w, http.StatusServiceUnavailable, AddonResponse{
+			Success: false,
+			Message: "Docker Compose is not available. Use 'sdbx addon enable' and 'sdbx up'.",
+			Addon:   addonName,
+		})
+		return
+	}
+
+	job, err := h.jobs.Start("install "+addonName, func(ctx context.Context, step func(string)) error {
+		return h.install(ctx, addonName, step)
+	})
+	if err != nil {
+		jsonError(w, "Failed to start install", "addons.Install.Start", err, http.StatusInternalServerError)
+		return
+	}
+
+	h.respondJSON(w, http.StatusAccepted, AddonResponse{
+		Success: true,
+		Message: fmt.Sprintf("Installing '%s'", addonName),
+		Addon:   addonName,
+		JobID:   job.ID,
+	})
+}
+
+// install enables an addon, regenerates the project and starts the addon
+func (h *AddonsHandler) install(ctx context.Context, addonName string, step func(string)) error {
+	h.installMu.Lock()
+	defer h.installMu.Unlock()
+
+	// Must not fall back to defaults before saving
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	if !cfg.IsAddonEnabled(addonName) {
+		step("Enabling " + addonName)
+		cfg.EnableAddon(addonName)
+		if err := cfg.Save(filepath.Join(h.projectDir, ".sdbx.yaml")); err != nil {
+			return fmt.Errorf("failed to save config: %w", err)
+		}
+	}
+
+	step("Regenerating project files")
+	if err := generator.NewGeneratorWithRegistry(cfg, h.projectDir, h.registry).Generate(); err != nil {
+		return fmt.Errorf("failed to regenerate project: %w", err)
+	}
+
+	step("Starting " + addonName)
+	if err := h.compose.UpService(ctx, addonName); err != nil {
+		return fmt.Errorf("failed to start %s: %w", addonName, err)
+	}
+
+	step(addonName + " is running")
+	return nil
+}
+
 // HandleDisableAddon handles POST /api/addons/{addon}/disable
 func (h *AddonsHandler) HandleDisableAddon(w http.ResponseWriter, r *http.Request) {
 	addonName := r.PathValue("addon")
@@ -5,6 +5,9 @@ import (
 	"net/http/httptest"
 	"strings"
 	"testing"
+
+	"github.com/maiko/sdbx/internal/config"
+	"github.com/maiko/sdbx/internal/registry"
 )
 
 // TestCountEnabledAddons verifies addon counting
@@ -85,7 +88,7 @@ func TestAddonDisplayStruct(t *testing.T) {
 
 // TestHandleEnableAddonMissingName verifies enable requires addon name
 func TestHandleEnableAddonMissingName(t *testing.T) {
-	handler := NewAddonsHandler(nil, "", nil)
+	handler := NewAddonsHandler(nil, nil, nil, "", nil)
 
 	req := httptest.NewRequest(http.MethodPost, "/api/addons//enable", nil)
 	w := httptest.NewRecorder()
@@ -104,7 +107,7 @@ func TestHandleEnableAddonMissingName(t *testing.T) {
 
 // TestHandleDisableAddonMissingName verifies disable requires addon name
 func TestHandleDisableAddonMissingName(t *testing.T) {
-	handler := NewAddonsHandler(nil, "", nil)
+	handler := NewAddonsHandler(nil, nil, nil, "", nil)
 
 	req := httptest.NewRequest(http.MethodPost, "/api/addons//disable", nil)
 	w := httptest.NewRecorder()
@@ -121,6 +124,64 @@ func TestHandleDisableAddonMissingName(t *testing.T) {
 	}
 }
 
+// TestHandleInstallAddonRequiresPost verifies installs are only started by POST
+func TestHandleInstallAddonRequiresPost(t *testing.T) {
+	handler := NewAddonsHandler(nil, nil, nil, "", nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/addons/sonarr/install", nil)
+	req.SetPathValue("addon", "sonarr")
+	w := httptest.NewRecorder()
+
+	handler.HandleInstallAddon(w, req)
+
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusMethodNotAllowed)
+	}
+}
+
+// TestNewAddonDisplayAssets verifies icon and screenshots are served by the addon asset route
+func TestNewAddonDisplayAssets(t *testing.T) {
+	addon := newAddonDisplay(registry.ServiceInfo{
+		Name:        "tautulli",
+		Category:    registry.CategoryMedia,
+		Icon:        "icon.png",
+		Screenshots: []string{"shots/home.png"},
+		Tags:        []string{"plex"},
+	}, config.DefaultConfig())
+
+	if addon.Icon != "/addons/tautulli/assets/icon.png" {
+		t.Errorf("Icon = %q", addon.Icon)
+	}
+	if len(addon.Screenshots) != 1 || addon.Screenshots[0] != "/addons/tautulli/assets/shots/home.png" {
+		t.Errorf("Screenshots = %v", addon.Screenshots)
+	}
+	if addon.DisplayName != "Tautulli" || len(addon.Tags) != 1 {
+		t.Errorf("newAddonDisplay() = %+v", addon)
+	}
+
+	if plain := newAddonDisplay(registry.ServiceInfo{Name: "plain"}, config.DefaultConfig()); plain.Icon != "" {
+		t.Errorf("Icon = %q, want empty without icon", plain.Icon)
+	}
+}
+
+// TestAddonCategories verifies category filters are sorted and mark the active one
+func TestAddonCategories(t *testing.T) {
+	got := addonCategories(map[string][]AddonDisplay{
+		"utility": {{Name: "a"}},
+		"media":   {{Name: "b"}, {Name: "c"}},
+	}, "utility")
+
+	want := []AddonCategory{{Name: "media", Count: 2}, {Name: "utility", Count: 1, Active: true}}
+	if len(got) != len(want) {
+		t.Fatalf("addonCategories() = %+v, want %+v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("addonCategories()[%d] = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
 // TestAddonResponseStruct verifies addon response struct
 func TestAddonResponseStruct(t *testing.T) {
 	resp := AddonResponse{
@@ -194,3 +255,24 @@ func TestValidLogServiceName(t *testing.T) {
 		})
 	}
 }
+
+// TestRenderMarkdownSanitizes verifies README HTML from sources is sanitized
+func TestRenderMarkdownSanitizes(t *testing.T) {
+	src := "# Setup\n\nGet a [claim token](https://plex.tv/claim).\n\n" +
+		"<script>alert(1)</script>\n\n[click](javascript:alert(1))\n\n<img src=x onerror=alert(1)>\n"
+
+	got, err := renderMarkdown(src)
+	if err != nil {
+		t.Fatalf("renderMarkdown() error = %v", err)
+	}
+	html := string(got)
+
+	if !strings.Contains(html, "<h1") || !strings.Contains(html, `href="https://plex.tv/claim"`) {
+		t.Errorf("renderMarkdown() dropped Markdown content: %s", html)
+	}
+	for _, unsafe := range []string{"<script", "javascript:", "onerror"} {
+		if strings.Contains(html, unsafe) {
+			t.Errorf("renderMarkdown() kept %q: %s", unsafe, html)
+		}
+	}
+}
@@ -0,0 +1,164 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"html/template"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/maiko/sdbx/internal/agent"
+	"github.com/maiko/sdbx/internal/docker"
+)
+
+const (
+	agentQueryTimeout   = 10 * time.Second
+	agentComposeTimeout = 10 * time.Minute
+)
+
+// AgentsHandler handles the control plane routes: the remote agents of
+// agents.yaml and their services
+type AgentsHandler struct {
+	path      string
+	templates *template.Template
+}
+
+// NewAgentsHandler creates a new agents handler
+func NewAgentsHandler(tmpl *template.Template) *AgentsHandler {
+	return &AgentsHandler{
+		path:      agent.AgentsPath(),
+		templates: tmpl,
+	}
+}
+
+// AgentDisplay is an agent with its state, for the agents page and API
+type AgentDisplay struct {
+	Name     string           `json:"name"`
+	URL      string           `json:"url"`
+	Info     *agent.Info      `json:"info,omitempty"`
+	Services []docker.Service `json:"services,omitempty"`
+	Error    string           `json:"error,omitempty"` // Why the agent could not be queried
+}
+
+// AgentResponse reports a compose action run on an agent
+type AgentResponse struct {
+	Success bool   `json:"success"`
+	Message string `json:"message"`
+	Agent   string `json:"agent"`
+}
+
+// HandleAgentsPage handles the agents page
+func (h *AgentsHandler) HandleAgentsPage(w http.ResponseWriter, r *http.Request) {
+	displays, err := h.queryAgents(r.Context())
+	if err != nil {
+		httpError(w, "agents.Load", err, http.StatusInternalServerError)
+		return
+	}
+	h.renderTemplate(w, "pages/agents.html", map[string]interface{}{
+		"Agents": displays,
+	})
+}
+
+// HandleGetAgents handles GET /api/agents
+func (h *AgentsHandler) HandleGetAgents(w http.ResponseWriter, r *http.Request) {
+	displays, err := h.queryAgents(r.Context())
+	if err != nil {
+		jsonError(w, "Failed to load agents", "agents.Load", err, http.StatusInternalServerError)
+		return
+	}
+	respondJSON(w, http.StatusOK, displays)
+}
+
+// HandleAgentCompose handles POST /api/agents/{agent}/compose/{action},
+// with the services in the service form values (none for the whole project)
+func (h *AgentsHandler) HandleAgentCompose(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		respondJSON(w, http.StatusMethodNotAllowed, AgentResponse{Message: "Method not allowed"})
+		return
+	}
+	name, action := r.PathValue("agent"), r.PathValue("action")
+	if !slices.Contains(agent.Actions, action) {
+		respondJSON(w, http.StatusBadRequest, AgentResponse{Message: "Unknown action " + action, Agent: name})
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		respondJSON(w, http.StatusBadRequest, AgentResponse{Message: "Invalid form", Agent: name})
+		return
+	}
+	services := r.Form["service"]
+	for _, s := range services {
+		if !validateServiceName(s) {
+			respondJSON(w, http.StatusBadRequest, AgentResponse{Message: "Invalid service name", Agent: name})
+			return
+		}
+	}
+
+	agents, err := agent.LoadAgents(h.path)
+	if err != nil {
+		jsonError(w, "Failed to load agents", "agents.Load", err, http.StatusInternalServerError)
+		return
+	}
+	a, err := agents.Get(name)
+	if err != nil {
+		respondJSON(w, http.StatusNotFound, AgentResponse{Message: "Agent not found", Agent: name})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), agentComposeTimeout)
+	defer cancel()
+	if err := agent.NewClient(a).Compose(ctx, action, services...); err != nil {
+		jsonError(w, fmt.Sprintf("Failed to %s on agent %s", action, name), "agents.Compose", err, http.StatusBadGateway)
+		return
+	}
+
+	if r.Header.Get("HX-Request") == "true" {
+		h.renderTemplate(w, "agent-card", h.queryAgent(r.Context(), a))
+		return
+	}
+	respondJSON(w, http.StatusOK, AgentResponse{
+		Success: true,
+		Message: fmt.Sprintf("Ran %s on agent %s", action, name),
+		Agent:   name,
+	})
+}
+
+// queryAgents returns every agent with its state, querying them
+// concurrently
+func (h *AgentsHandler) queryAgents(ctx context.Context) ([]AgentDisplay, error) {
+	agents, err := agent.LoadAgents(h.path)
+	if err != nil {
+		return nil, err
+	}
+	displays := make([]AgentDisplay, len(agents.Agents))
+	var wg sync.WaitGroup
+	for i, a := range agents.Agents {
+		wg.Go(func() { displays[i] = h.queryAgent(ctx, a) })
+	}
+	wg.Wait()
+	return displays, nil
+}
+
+// queryAgent returns an agent with its info and services
+func (h *AgentsHandler) queryAgent(ctx context.Context, a agent.Agent) AgentDisplay {
+	ctx, cancel := context.WithTimeout(ctx, agentQueryTimeout)
+	defer cancel()
+
+	display := AgentDisplay{Name: a.Name, URL: a.URL}
+	client := agent.NewClient(a)
+	info, err := client.Info(ctx)
+	if err != nil {
+		display.Error = err.Error()
+		return display
+	}
+	display.Info = info
+	if display.Services, err = client.Services(ctx); err != nil {
+		display.Error = err.Error()
+	}
+	return display
+}
+
+func (h *AgentsHandler) renderTemplate(w http.ResponseWriter, name string, data interface{}) {
+	renderTemplate(h.templates, w, name, "agents", data)
+}
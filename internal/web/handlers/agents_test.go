@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/maiko/sdbx/internal/agent"
+	"github.com/maiko/sdbx/internal/auth"
+)
+
+func TestAgentsHandler(t *testing.T) {
+	projectDir := t.TempDir()
+	token, _, err := auth.NewTokenStore(projectDir).Create("control", auth.ScopeWrite, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(agent.NewServer("box", projectDir, "1.2.3").Handler())
+	defer server.Close()
+
+	agents := &agent.Agents{}
+	for _, a := range []agent.Agent{
+		{Name: "box", URL: server.URL, Token: token},
+		{Name: "gone", URL: "http://127.0.0.1:1", Token: token},
+	} {
+		if err := agents.Add(a); err != nil {
+			t.Fatal(err)
+		}
+	}
+	h := &AgentsHandler{path: filepath.Join(t.TempDir(), "agents.yaml")}
+	if err := agents.Save(h.path); err != nil {
+		t.Fatal(err)
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/agents", h.HandleGetAgents)
+	mux.HandleFunc("/api/agents/{agent}/compose/{action}", h.HandleAgentCompose)
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/agents", nil))
+	var displays []AgentDisplay
+	if err := json.NewDecoder(w.Body).Decode(&displays); err != nil {
+		t.Fatal(err)
+	}
+	if len(displays) != 2 || displays[0].Info == nil || displays[0].Info.Version != "1.2.3" || displays[1].Info != nil || displays[1].Error == "" {
+		t.Errorf("agents = %+v", displays)
+	}
+
+	tests := []struct {
+		path string
+		want int
+	}{
+		{"/api/agents/box/compose/explode", http.StatusBadRequest},
+		{"/api/agents/box/compose/restart?service=../etc", http.StatusBadRequest},
+		{"/api/agents/nobody/compose/restart?service=sonarr", http.StatusNotFound},
+		{"/api/agents/box/compose/restart", http.StatusBadGateway}, // The agent needs services
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(http.MethodPost, tt.path, nil))
+		if w.Code != tt.want {
+			t.Errorf("POST %s = %d, want %d", tt.path, w.Code, tt.want)
+		}
+	}
+}
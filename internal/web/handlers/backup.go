@@ -2,11 +2,17 @@ package handlers
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"html/template"
 	"net/http"
+	"os"
+	"path/filepath"
 	"time"
 
 	"github.com/maiko/sdbx/internal/backup"
+	"github.com/maiko/sdbx/internal/config"
 )
 
 const (
@@ -14,18 +20,27 @@ const (
 	backupCreateTimeout  = 2 * time.Minute
 	backupRestoreTimeout = 2 * time.Minute
 	backupDeleteTimeout  = 30 * time.Second
+	backupPushTimeout    = 10 * time.Minute
+
+	// backupTransferTimeout bounds an archive download or upload
+	backupTransferTimeout = 10 * time.Minute
+	// maxBackupUploadSize is the largest archive accepted from the browser
+	maxBackupUploadSize = 1 << 30
 )
 
 // BackupHandler handles backup and restore operations
 type BackupHandler struct {
 	projectDir string
+	jobs       *JobsHandler
 	templates  *template.Template
 }
 
-// NewBackupHandler creates a new backup handler
-func NewBackupHandler(projectDir string, tmpl *template.Template) *BackupHandler {
+// NewBackupHandler creates a new backup handler; create, restore and push
+// run as jobs
+func NewBackupHandler(projectDir string, jobs *JobsHandler, tmpl *template.Template) *BackupHandler {
 	return &BackupHandler{
 		projectDir: projectDir,
+		jobs:       jobs,
 		templates:  tmpl,
 	}
 }
@@ -41,18 +56,32 @@ type BackupDisplay struct {
 	Hostname  string    `json:"hostname"`
 }
 
+// BackupEntryDisplay represents a file of a backup for display
+type BackupEntryDisplay struct {
+	Path      string `json:"path"`
+	Size      int64  `json:"size"`
+	SizeHuman string `json:"sizeHuman"`
+}
+
 // BackupResponse represents API response for backup operations
 type BackupResponse struct {
-	Success bool            `json:"success"`
-	Message string          `json:"message"`
-	Backup  *BackupDisplay  `json:"backup,omitempty"`
-	Backups []BackupDisplay `json:"backups,omitempty"`
+	Success  bool                 `json:"success"`
+	Message  string               `json:"message"`
+	Backup   *BackupDisplay       `json:"backup,omitempty"`
+	Backups  []BackupDisplay      `json:"backups,omitempty"`
+	Contents []BackupEntryDisplay `json:"contents,omitempty"`
+	JobID    string               `json:"jobId,omitempty"`
 }
 
 // HandleBackupPage handles the backup management page
 func (h *BackupHandler) HandleBackupPage(w http.ResponseWriter, r *http.Request) {
 	data := map[string]interface{}{
 		"ProjectDir": h.projectDir,
+		"Settings":   config.BackupConfig{},
+		"NewRemote":  config.BackupRemote{Type: config.BackupRemoteDirectory},
+	}
+	if cfg, err := config.Load(); err == nil {
+		data["Settings"] = cfg.Backup
 	}
 
 	h.renderTemplate(w, "pages/backup.html", data)
@@ -74,16 +103,7 @@ func (h *BackupHandler) HandleListBackups(w http.ResponseWriter, r *http.Request
 	// Convert to display format
 	displayBackups := make([]BackupDisplay, 0, len(backups))
 	for _, b := range backups {
-		size, _ := b.GetSize()
-		displayBackups = append(displayBackups, BackupDisplay{
-			Name:      b.Name,
-			Path:      b.Path,
-			Size:      size,
-			SizeHuman: backup.FormatBytes(size),
-			Timestamp: b.Metadata.Timestamp,
-			Age:       backup.FormatAge(b.Metadata.Timestamp),
-			Hostname:  b.Metadata.Hostname,
-		})
+		displayBackups = append(displayBackups, *newBackupDisplay(b))
 	}
 
 	h.respondJSON(w, http.StatusOK, BackupResponse{
@@ -92,47 +112,154 @@ func (h *BackupHandler) HandleListBackups(w http.ResponseWriter, r *http.Request
 	})
 }
 
-// HandleCreateBackup handles POST /api/backup/create
+// HandleCreateBackup handles POST /api/backup/create. The backup is
+// created by a job polled at /api/jobs/{id}.
 func (h *BackupHandler) HandleCreateBackup(w http.ResponseWriter, r *http.Request) {
-	manager := backup.NewManager(h.projectDir)
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
 
-	ctx, cancel := context.WithTimeout(r.Context(), backupCreateTimeout)
-	defer cancel()
+	job, err := h.jobs.Start("create backup", func(ctx context.Context, step func(string)) error {
+		ctx, cancel := context.WithTimeout(ctx, backupCreateTimeout)
+		defer cancel()
+
+		manager := backup.NewManager(h.projectDir)
+		manager.Progress = step
+		b, err := manager.Create(ctx)
+		if err != nil {
+			return err
+		}
+		step("Created " + b.Name)
+		return nil
+	})
+	if err != nil {
+		jsonError(w, "Failed to start backup", "backup.Create.Start", err, http.StatusInternalServerError)
+		return
+	}
 
-	b, err := manager.Create(ctx)
+	h.respondJSON(w, http.StatusAccepted, BackupResponse{
+		Success: true,
+		Message: "Creating backup",
+		JobID:   job.ID,
+	})
+}
+
+// HandleRestoreBackup handles POST /api/backup/restore/{name}. The restore
+// runs as a job polled at /api/jobs/{id}.
+func (h *BackupHandler) HandleRestoreBackup(w http.ResponseWriter, r *http.Request) {
+	backupName, ok := h.backupName(w, r)
+	if !ok {
+		return
+	}
+
+	job, err := h.jobs.Start("restore "+backupName, func(ctx context.Context, step func(string)) error {
+		ctx, cancel := context.WithTimeout(ctx, backupRestoreTimeout)
+		defer cancel()
+
+		manager := backup.NewManager(h.projectDir)
+		manager.Progress = step
+		if err := manager.Restore(ctx, backupName); err != nil {
+			return err
+		}
+		step("Restored " + backupName + ". Run 'sdbx down && sdbx up' to apply changes.")
+		return nil
+	})
 	if err != nil {
-		jsonError(w, "Failed to create backup", "backup.Create", err, http.StatusInternalServerError)
+		jsonError(w, "Failed to start restore", "backup.Restore.Start", err, http.StatusInternalServerError)
 		return
 	}
 
-	size, _ := b.GetSize()
+	h.respondJSON(w, http.StatusAccepted, BackupResponse{
+		Success: true,
+		Message: fmt.Sprintf("Restoring %s", backupName),
+		JobID:   job.ID,
+	})
+}
+
+// HandleBackupContents handles GET /api/backup/contents/{name}
+func (h *BackupHandler) HandleBackupContents(w http.ResponseWriter, r *http.Request) {
+	backupName, ok := h.backupName(w, r)
+	if !ok {
+		return
+	}
+
+	entries, err := backup.NewManager(h.projectDir).Contents(backupName)
+	if err != nil {
+		jsonError(w, "Failed to read backup", "backup.Contents", err, http.StatusInternalServerError)
+		return
+	}
+
+	contents := make([]BackupEntryDisplay, 0, len(entries))
+	for _, e := range entries {
+		contents = append(contents, BackupEntryDisplay{
+			Path:      filepath.ToSlash(e.Path),
+			Size:      e.Size,
+			SizeHuman: backup.FormatBytes(e.Size),
+		})
+	}
 
 	h.respondJSON(w, http.StatusOK, BackupResponse{
-		Success: true,
-		Message: "Backup created successfully",
-		Backup: &BackupDisplay{
-			Name:      b.Name,
-			Path:      b.Path,
-			Size:      size,
-			SizeHuman: backup.FormatBytes(size),
-			Timestamp: b.Metadata.Timestamp,
-			Age:       backup.FormatAge(b.Metadata.Timestamp),
-			Hostname:  b.Metadata.Hostname,
-		},
+		Success:  true,
+		Contents: contents,
 	})
 }
 
-// HandleRestoreBackup handles POST /api/backup/restore
-func (h *BackupHandler) HandleRestoreBackup(w http.ResponseWriter, r *http.Request) {
-	backupName := r.PathValue("name")
-	if backupName == "" {
-		h.respondJSON(w, http.StatusBadRequest, BackupResponse{
+// HandleDownloadBackup handles GET /api/backup/download/{name}
+func (h *BackupHandler) HandleDownloadBackup(w http.ResponseWriter, r *http.Request) {
+	backupName, ok := h.backupName(w, r)
+	if !ok {
+		return
+	}
+
+	f, err := os.Open(filepath.Join(h.projectDir, "backups", backupName)) //nolint:gosec // G304 - validated backupName within backups/
+	if err != nil {
+		jsonError(w, "Failed to open backup", "backup.Download", err, http.StatusInternalServerError)
+		return
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		jsonError(w, "Failed to open backup", "backup.Download.Stat", err, http.StatusInternalServerError)
+		return
+	}
+
+	// Archives outlast the server's write timeout
+	_ = http.NewResponseController(w).SetWriteDeadline(time.Now().Add(backupTransferTimeout))
+
+	w.Header().Set("Content-Type", "application/gzip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", backupName))
+	http.ServeContent(w, r, backupName, info.ModTime(), f)
+}
+
+// HandleUploadBackup handles POST /api/backup/upload with the archive in
+// the multipart "file" field
+func (h *BackupHandler) HandleUploadBackup(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Archives outlast the server's read timeout
+	_ = http.NewResponseController(w).SetReadDeadline(time.Now().Add(backupTransferTimeout))
+	r.Body = http.MaxBytesReader(w, r.Body, maxBackupUploadSize)
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		status := http.StatusBadRequest
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			status = http.StatusRequestEntityTooLarge
+		}
+		h.respondJSON(w, status, BackupResponse{
 			Success: false,
-			Message: "Backup name is required",
+			Message: "A backup archive is required (at most 1 GB)",
 		})
 		return
 	}
+	defer file.Close()
 
+	backupName := filepath.Base(header.Filename)
 	if err := backup.ValidateBackupName(backupName); err != nil {
 		h.respondJSON(w, http.StatusBadRequest, BackupResponse{
 			Success: false,
@@ -141,19 +268,111 @@ func (h *BackupHandler) HandleRestoreBackup(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	manager := backup.NewManager(h.projectDir)
+	b, err := backup.NewManager(h.projectDir).Import(backupName, file)
+	if err != nil {
+		h.respondJSON(w, http.StatusBadRequest, BackupResponse{
+			Success: false,
+			Message: fmt.Sprintf("Failed to upload backup: %v", err),
+		})
+		return
+	}
 
-	ctx, cancel := context.WithTimeout(r.Context(), backupRestoreTimeout)
-	defer cancel()
+	h.respondJSON(w, http.StatusOK, BackupResponse{
+		Success: true,
+		Message: "Backup uploaded successfully",
+		Backup:  newBackupDisplay(b),
+	})
+}
 
-	if err := manager.Restore(ctx, backupName); err != nil {
-		jsonError(w, "Failed to restore backup", "backup.Restore", err, http.StatusInternalServerError)
+// HandlePushBackup handles POST /api/backup/push/{name}?remote=<name>,
+// copying a backup to a configured remote as a job
+func (h *BackupHandler) HandlePushBackup(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	backupName, ok := h.backupName(w, r)
+	if !ok {
+		return
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		jsonError(w, "Failed to load configuration", "backup.Push.Load", err, http.StatusInternalServerError)
+		return
+	}
+	remote, ok := cfg.Backup.Remote(r.URL.Query().Get("remote"))
+	if !ok {
+		h.respondJSON(w, http.StatusBadRequest, BackupResponse{
+			Success: false,
+			Message: "Unknown backup remote",
+		})
 		return
 	}
 
+	job, err := h.jobs.Start("push "+backupName, func(ctx context.Context, step func(string)) error {
+		ctx, cancel := context.WithTimeout(ctx, backupPushTimeout)
+		defer cancel()
+
+		step(fmt.Sprintf("Copying %s to %s", backupName, remote.Name))
+		return backup.NewManager(h.projectDir).Push(ctx, backupName, remote)
+	})
+	if err != nil {
+		jsonError(w, "Failed to start copy", "backup.Push.Start", err, http.StatusInternalServerError)
+		return
+	}
+
+	h.respondJSON(w, http.StatusAccepted, BackupResponse{
+		Success: true,
+		Message: fmt.Sprintf("Copying %s to %s", backupName, remote.Name),
+		JobID:   job.ID,
+	})
+}
+
+// HandleSaveSettings handles POST /api/backup/settings with the backup
+// schedule, retention and remotes as JSON
+func (h *BackupHandler) HandleSaveSettings(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var settings config.BackupConfig
+	if err := json.NewDecoder(r.Body).Decode(&settings); err != nil {
+		h.respondJSON(w, http.StatusBadRequest, BackupResponse{
+			Success: false,
+			Message: "Invalid backup settings",
+		})
+		return
+	}
+
+	// Must not fall back to defaults before saving
+	cfg, err := config.Load()
+	if err != nil {
+		jsonError(w, "Failed to load configuration", "backup.Settings.Load", err, http.StatusInternalServerError)
+		return
+	}
+	cfg.Backup = settings
+	if err := cfg.Validate(); err != nil {
+		h.respondJSON(w, http.StatusBadRequest, BackupResponse{
+			Success: false,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if err := cfg.Save(filepath.Join(h.projectDir, ".sdbx.yaml")); err != nil {
+		jsonError(w, "Failed to save backup settings", "backup.Settings.Save", err, http.StatusInternalServerError)
+		return
+	}
+
+	message := "Backup settings saved. Scheduled backups are off."
+	if interval := settings.Interval(); interval > 0 {
+		message = fmt.Sprintf("Backup settings saved. A backup is taken every %s by the web UI or 'sdbx monitor'.", interval)
+	}
 	h.respondJSON(w, http.StatusOK, BackupResponse{
 		Success: true,
-		Message: "Backup restored successfully. Run 'sdbx down && sdbx up' to apply changes.",
+		Message: message,
 	})
 }
 
@@ -192,6 +411,49 @@ func (h *BackupHandler) HandleDeleteBackup(w http.ResponseWriter, r *http.Reques
 	})
 }
 
+// backupName returns the {name} path value of an existing backup, or
+// responds with an error
+func (h *BackupHandler) backupName(w http.ResponseWriter, r *http.Request) (string, bool) {
+	backupName := r.PathValue("name")
+	if backupName == "" {
+		h.respondJSON(w, http.StatusBadRequest, BackupResponse{
+			Success: false,
+			Message: "Backup name is required",
+		})
+		return "", false
+	}
+
+	if err := backup.ValidateBackupName(backupName); err != nil {
+		h.respondJSON(w, http.StatusBadRequest, BackupResponse{
+			Success: false,
+			Message: "Invalid backup name",
+		})
+		return "", false
+	}
+
+	if _, err := os.Stat(filepath.Join(h.projectDir, "backups", backupName)); err != nil {
+		h.respondJSON(w, http.StatusNotFound, BackupResponse{
+			Success: false,
+			Message: "Backup not found",
+		})
+		return "", false
+	}
+	return backupName, true
+}
+
+// newBackupDisplay converts a backup to its display format
+func newBackupDisplay(b *backup.Backup) *BackupDisplay {
+	size, _ := b.GetSize()
+	return &BackupDisplay{
+		Name:      b.Name,
+		Path:      b.Path,
+		Size:      size,
+		SizeHuman: backup.FormatBytes(size),
+		Timestamp: b.Metadata.Timestamp,
+		Age:       backup.FormatAge(b.Metadata.Timestamp),
+		Hostname:  b.Metadata.Hostname,
+	}
+}
 
 func (h *BackupHandler) respondJSON(w http.ResponseWriter, statusCode int, data interface{}) {
 	respondJSON(w, statusCode, data)
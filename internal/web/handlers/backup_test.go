@@ -1,6 +1,11 @@
 package handlers
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
 	"os"
@@ -75,7 +80,7 @@ func TestBackupHandlerListBackups(t *testing.T) {
 		t.Fatalf("failed to create backup dir: %v", err)
 	}
 
-	handler := NewBackupHandler(tmpDir, nil)
+	handler := NewBackupHandler(tmpDir, NewJobsHandler(context.Background()), nil)
 
 	req := httptest.NewRequest(http.MethodGet, "/api/backup/list", nil)
 	w := httptest.NewRecorder()
@@ -92,7 +97,7 @@ func TestBackupHandlerListBackups(t *testing.T) {
 	}
 }
 
-// TestBackupHandlerCreateBackup verifies backup creation
+// TestBackupHandlerCreateBackup verifies backup creation runs as a job
 func TestBackupHandlerCreateBackup(t *testing.T) {
 	tmpDir := t.TempDir()
 
@@ -102,30 +107,43 @@ func TestBackupHandlerCreateBackup(t *testing.T) {
 		t.Fatalf("failed to create config: %v", err)
 	}
 
-	handler := NewBackupHandler(tmpDir, nil)
+	jobs := NewJobsHandler(context.Background())
+	handler := NewBackupHandler(tmpDir, jobs, nil)
 
 	req := httptest.NewRequest(http.MethodPost, "/api/backup/create", nil)
 	w := httptest.NewRecorder()
 
 	handler.HandleCreateBackup(w, req)
 
-	if w.Code != http.StatusOK {
-		t.Errorf("status = %d, want %d, body = %s", w.Code, http.StatusOK, w.Body.String())
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("status = %d, want %d, body = %s", w.Code, http.StatusAccepted, w.Body.String())
 	}
 
-	body := w.Body.String()
-	if !strings.Contains(body, "success\":true") {
-		t.Errorf("response should indicate success, got %s", body)
+	var resp BackupResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if !resp.Success || resp.JobID == "" {
+		t.Fatalf("response = %+v, want a job", resp)
+	}
+
+	job := waitJob(t, jobs, resp.JobID)
+	if job.Status != JobSucceeded {
+		t.Fatalf("job status = %s, error = %s", job.Status, job.Error)
+	}
+	if len(job.Steps) == 0 || !strings.HasPrefix(job.Steps[len(job.Steps)-1], "Created sdbx-backup-") {
+		t.Errorf("job steps = %v, want the created backup last", job.Steps)
 	}
 
-	if !strings.Contains(body, "Backup created successfully") {
-		t.Errorf("response should contain success message")
+	backups, err := backup.NewManager(tmpDir).List(context.Background())
+	if err != nil || len(backups) != 1 {
+		t.Errorf("expected one backup, got %d (%v)", len(backups), err)
 	}
 }
 
 // TestBackupHandlerDeleteBackupMissingName verifies delete requires name
 func TestBackupHandlerDeleteBackupMissingName(t *testing.T) {
-	handler := NewBackupHandler("", nil)
+	handler := NewBackupHandler("", NewJobsHandler(context.Background()), nil)
 
 	req := httptest.NewRequest(http.MethodDelete, "/api/backup/delete/", nil)
 	w := httptest.NewRecorder()
@@ -144,7 +162,7 @@ func TestBackupHandlerDeleteBackupMissingName(t *testing.T) {
 
 // TestBackupHandlerRestoreBackupMissingName verifies restore requires name
 func TestBackupHandlerRestoreBackupMissingName(t *testing.T) {
-	handler := NewBackupHandler("", nil)
+	handler := NewBackupHandler("", NewJobsHandler(context.Background()), nil)
 
 	req := httptest.NewRequest(http.MethodPost, "/api/backup/restore/", nil)
 	w := httptest.NewRecorder()
@@ -164,7 +182,7 @@ func TestBackupHandlerRestoreBackupMissingName(t *testing.T) {
 // TestBackupHandlerDeleteNonexistentBackup verifies delete fails for nonexistent
 func TestBackupHandlerDeleteNonexistentBackup(t *testing.T) {
 	tmpDir := t.TempDir()
-	handler := NewBackupHandler(tmpDir, nil)
+	handler := NewBackupHandler(tmpDir, NewJobsHandler(context.Background()), nil)
 
 	req := httptest.NewRequest(http.MethodDelete, "/api/backup/delete/{name}", nil)
 	req.SetPathValue("name", "nonexistent-backup.tar.gz")
@@ -180,7 +198,7 @@ func TestBackupHandlerDeleteNonexistentBackup(t *testing.T) {
 // TestBackupHandlerRestoreNonexistentBackup verifies restore fails for nonexistent
 func TestBackupHandlerRestoreNonexistentBackup(t *testing.T) {
 	tmpDir := t.TempDir()
-	handler := NewBackupHandler(tmpDir, nil)
+	handler := NewBackupHandler(tmpDir, NewJobsHandler(context.Background()), nil)
 
 	req := httptest.NewRequest(http.MethodPost, "/api/backup/restore/{name}", nil)
 	req.SetPathValue("name", "nonexistent-backup.tar.gz")
@@ -188,8 +206,8 @@ func TestBackupHandlerRestoreNonexistentBackup(t *testing.T) {
 
 	handler.HandleRestoreBackup(w, req)
 
-	if w.Code != http.StatusInternalServerError {
-		t.Errorf("status = %d, want %d", w.Code, http.StatusInternalServerError)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusNotFound)
 	}
 }
 
@@ -231,7 +249,7 @@ func TestBackupDisplayStruct(t *testing.T) {
 
 // TestBackupHandlerDeleteInvalidName verifies path traversal rejection in delete
 func TestBackupHandlerDeleteInvalidName(t *testing.T) {
-	handler := NewBackupHandler(t.TempDir(), nil)
+	handler := NewBackupHandler(t.TempDir(), NewJobsHandler(context.Background()), nil)
 
 	invalidNames := []string{"../../../etc/passwd", "/absolute/path", "with/../traversal"}
 	for _, name := range invalidNames {
@@ -254,7 +272,7 @@ func TestBackupHandlerDeleteInvalidName(t *testing.T) {
 
 // TestBackupHandlerRestoreInvalidName verifies path traversal rejection in restore
 func TestBackupHandlerRestoreInvalidName(t *testing.T) {
-	handler := NewBackupHandler(t.TempDir(), nil)
+	handler := NewBackupHandler(t.TempDir(), NewJobsHandler(context.Background()), nil)
 
 	invalidNames := []string{"../../../etc/passwd", "/absolute/path", "with/../traversal"}
 	for _, name := range invalidNames {
@@ -302,3 +320,96 @@ func TestBackupResponseStruct(t *testing.T) {
 		t.Error("should have 2 backups")
 	}
 }
+
+// createTestBackup creates a backup of a project holding .sdbx.yaml
+func createTestBackup(t *testing.T, projectDir string) *backup.Backup {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(projectDir, ".sdbx.yaml"), []byte("domain: test.local"), 0644); err != nil {
+		t.Fatalf("failed to create config: %v", err)
+	}
+	b, err := backup.NewManager(projectDir).Create(context.Background())
+	if err != nil {
+		t.Fatalf("failed to create backup: %v", err)
+	}
+	return b
+}
+
+// TestBackupHandlerContents verifies the files of a backup are listed with sizes
+func TestBackupHandlerContents(t *testing.T) {
+	tmpDir := t.TempDir()
+	b := createTestBackup(t, tmpDir)
+	handler := NewBackupHandler(tmpDir, NewJobsHandler(context.Background()), nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/backup/contents/{name}", nil)
+	req.SetPathValue("name", b.Name)
+	w := httptest.NewRecorder()
+	handler.HandleBackupContents(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", w.Code, w.Body.String())
+	}
+	var resp BackupResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	want := BackupEntryDisplay{Path: ".sdbx.yaml", Size: 18, SizeHuman: "18 B"}
+	if len(resp.Contents) != 1 || resp.Contents[0] != want {
+		t.Errorf("contents = %+v, want %+v", resp.Contents, want)
+	}
+}
+
+// TestBackupHandlerDownloadAndUpload verifies an archive downloaded from one
+// project can be uploaded to another
+func TestBackupHandlerDownloadAndUpload(t *testing.T) {
+	srcDir := t.TempDir()
+	b := createTestBackup(t, srcDir)
+	src := NewBackupHandler(srcDir, NewJobsHandler(context.Background()), nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/backup/download/{name}", nil)
+	req.SetPathValue("name", b.Name)
+	w := httptest.NewRecorder()
+	src.HandleDownloadBackup(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("download status = %d, body = %s", w.Code, w.Body.String())
+	}
+	if got := w.Header().Get("Content-Disposition"); !strings.Contains(got, b.Name) {
+		t.Errorf("Content-Disposition = %q, want the backup name", got)
+	}
+	archive := w.Body.Bytes()
+
+	upload := func(h *BackupHandler, name string, data []byte) *httptest.ResponseRecorder {
+		var body bytes.Buffer
+		mw := multipart.NewWriter(&body)
+		part, err := mw.CreateFormFile("file", name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := io.Copy(part, bytes.NewReader(data)); err != nil {
+			t.Fatal(err)
+		}
+		mw.Close()
+		req := httptest.NewRequest(http.MethodPost, "/api/backup/upload", &body)
+		req.Header.Set("Content-Type", mw.FormDataContentType())
+		w := httptest.NewRecorder()
+		h.HandleUploadBackup(w, req)
+		return w
+	}
+
+	dstDir := t.TempDir()
+	dst := NewBackupHandler(dstDir, NewJobsHandler(context.Background()), nil)
+	if w := upload(dst, b.Name, archive); w.Code != http.StatusOK {
+		t.Fatalf("upload status = %d, body = %s", w.Code, w.Body.String())
+	}
+	backups, err := backup.NewManager(dstDir).List(context.Background())
+	if err != nil || len(backups) != 1 || backups[0].Name != b.Name {
+		t.Errorf("uploaded backups = %v (%v), want %s", backups, err, b.Name)
+	}
+
+	if w := upload(dst, b.Name, archive); w.Code != http.StatusBadRequest {
+		t.Errorf("re-upload status = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+	if w := upload(dst, "notes.tar.gz", []byte("not an archive")); w.Code != http.StatusBadRequest {
+		t.Errorf("invalid archive status = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+}
@@ -11,6 +11,7 @@ import (
 
 	"github.com/maiko/sdbx/internal/config"
 	"github.com/maiko/sdbx/internal/docker"
+	"github.com/maiko/sdbx/internal/problem"
 	"github.com/maiko/sdbx/internal/registry"
 )
 
@@ -34,14 +35,21 @@ func httpError(w http.ResponseWriter, context string, err error, statusCode int)
 
 // jsonError logs the full error internally and returns a generic JSON error to the client.
 // The userMessage is safe to show to clients; the err is only logged server-side.
+// Errors of a known problem kind also carry its type, code and hint.
 func jsonError(w http.ResponseWriter, userMessage string, context string, err error, statusCode int) {
 	log.Printf("Error [%s]: %v", context, err)
+	p := problem.From(err, statusCode)
+	p.Detail = "" // Logged only, it may expose internals
+	if p.Code == "" {
+		p.Title = userMessage
+	}
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(statusCode)
-	json.NewEncoder(w).Encode(map[string]interface{}{
-		"success": false,
-		"message": userMessage,
-	})
+	json.NewEncoder(w).Encode(struct {
+		Success bool   `json:"success"`
+		Message string `json:"message"`
+		problem.Problem
+	}{false, userMessage, p})
 }
 
 // respondJSON sends a JSON response with the given status code and data.
@@ -73,6 +81,7 @@ type ServiceInfo struct {
 	Description string
 	URL         string
 	HasWebUI    bool
+	Maintenance bool
 }
 
 // buildServiceInfoMap creates a service map from registry metadata and Docker status.
@@ -105,12 +114,16 @@ func buildServiceInfoMap(compose *docker.Compose, reg *registry.Registry, ctx co
 		}
 		if cfg != nil && regSvc.HasWebUI {
 			info.URL = cfg.GetServiceURL(regSvc.Name)
+			info.Maintenance = cfg.IsInMaintenance(regSvc.Name)
 		}
 		serviceMap[regSvc.Name] = info
 	}
 
 	for _, dockerSvc := range dockerServices {
-		serviceName := strings.TrimPrefix(dockerSvc.Name, "sdbx-")
+		serviceName := dockerSvc.Service
+		if serviceName == "" {
+			serviceName = strings.TrimPrefix(dockerSvc.Name, "sdbx-")
+		}
 		if info, exists := serviceMap[serviceName]; exists {
 			info.Status = dockerSvc.Status
 			info.Health = dockerSvc.Health
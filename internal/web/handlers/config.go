@@ -1,27 +1,42 @@
 package handlers
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"html/template"
 	"net/http"
 	"os"
 	"path/filepath"
 	"slices"
+	"strings"
+	"sync"
 
 	"gopkg.in/yaml.v3"
 
 	"github.com/maiko/sdbx/internal/config"
+	"github.com/maiko/sdbx/internal/docker"
+	"github.com/maiko/sdbx/internal/generator"
+	"github.com/maiko/sdbx/internal/registry"
 )
 
 // ConfigHandler handles configuration editing routes
 type ConfigHandler struct {
+	registry   *registry.Registry
+	compose    *docker.Compose
+	jobs       *JobsHandler
 	projectDir string
 	templates  *template.Template
+	saveMu     sync.Mutex // Serializes saves and the regeneration that follows
 }
 
 // NewConfigHandler creates a new config handler
-func NewConfigHandler(projectDir string, tmpl *template.Template) *ConfigHandler {
+func NewConfigHandler(reg *registry.Registry, compose *docker.Compose, jobs *JobsHandler, projectDir string, tmpl *template.Template) *ConfigHandler {
 	return &ConfigHandler{
+		registry:   reg,
+		compose:    compose,
+		jobs:       jobs,
 		projectDir: projectDir,
 		templates:  tmpl,
 	}
@@ -32,6 +47,12 @@ type ConfigResponse struct {
 	Success bool     `json:"success"`
 	Message string   `json:"message"`
 	Errors  []string `json:"errors,omitempty"`
+
+	// Set when saving needs confirmation: what saving would change, and the
+	// revision of the file the plan was computed against
+	Plan     *generator.Plan `json:"plan,omitempty"`
+	Revision string          `json:"revision,omitempty"`
+	JobID    string          `json:"jobId,omitempty"` // Apply job, when requested
 }
 
 // HandleConfigPage handles the config editor page
@@ -126,8 +147,16 @@ func (h *ConfigHandler) HandleValidateConfig(w http.ResponseWriter, r *http.Requ
 	})
 }
 
-// HandleSaveConfig handles POST /api/config/save
+// HandleSaveConfig handles POST /api/config/save. Without confirm=true
+// nothing is written: the response carries the plan of what saving would
+// change. With confirm=true and the plan's revision the config is saved and
+// the project regenerated; apply=true also starts a job recreating the
+// changed services.
 func (h *ConfigHandler) HandleSaveConfig(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
 	if err := r.ParseForm(); err != nil {
 		h.respondJSON(w, http.StatusBadRequest, ConfigResponse{
 			Success: false,
@@ -145,9 +174,9 @@ func (h *ConfigHandler) HandleSaveConfig(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	// Validate before saving
-	var cfg config.Config
-	if err := yaml.Unmarshal([]byte(content), &cfg); err != nil {
+	// Validate before saving, reading the content as the CLI would
+	cfg, err := config.Parse([]byte(content))
+	if err != nil {
 		h.respondJSON(w, http.StatusBadRequest, ConfigResponse{
 			Success: false,
 			Message: "Invalid YAML syntax",
@@ -157,7 +186,10 @@ func (h *ConfigHandler) HandleSaveConfig(w http.ResponseWriter, r *http.Request)
 	}
 
 	// Additional validation
-	errors := h.validateConfig(&cfg)
+	errors := h.validateConfig(cfg)
+	if err := cfg.Validate(); err != nil {
+		errors = append(errors, err.Error())
+	}
 	if len(errors) > 0 {
 		h.respondJSON(w, http.StatusBadRequest, ConfigResponse{
 			Success: false,
@@ -167,8 +199,61 @@ func (h *ConfigHandler) HandleSaveConfig(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	// Backup existing config
+	h.saveMu.Lock()
+	defer h.saveMu.Unlock()
+
 	configPath := filepath.Join(h.projectDir, ".sdbx.yaml")
+	revision, err := fileRevision(configPath)
+	if err != nil {
+		jsonError(w, "Failed to read config", "config.Revision", err, http.StatusInternalServerError)
+		return
+	}
+
+	if r.FormValue("confirm") != "true" {
+		plan, err := generator.NewPlan(cfg, h.registry, h.projectDir)
+		if err != nil {
+			h.respondJSON(w, http.StatusBadRequest, ConfigResponse{
+				Success: false,
+				Message: "Config cannot be applied",
+				Errors:  []string{err.Error()},
+			})
+			return
+		}
+		redactDiffs(plan.Files)
+		h.respondJSON(w, http.StatusOK, ConfigResponse{
+			Success:  true,
+			Message:  "Review the changes before saving",
+			Plan:     plan,
+			Revision: revision,
+		})
+		return
+	}
+
+	// The plan was reviewed against this revision of the file
+	if r.FormValue("revision") != revision {
+		h.respondJSON(w, http.StatusConflict, ConfigResponse{
+			Success: false,
+			Message: "The config file changed since the changes were reviewed. Reload and review again.",
+		})
+		return
+	}
+
+	// Services to restart once applied, known only before the files change
+	var restart []string
+	if r.FormValue("apply") == "true" {
+		plan, err := generator.NewPlan(cfg, h.registry, h.projectDir)
+		if err != nil {
+			h.respondJSON(w, http.StatusBadRequest, ConfigResponse{
+				Success: false,
+				Message: "Config cannot be applied",
+				Errors:  []string{err.Error()},
+			})
+			return
+		}
+		restart = plan.Restarted
+	}
+
+	// Backup existing config
 	backupPath := configPath + ".backup"
 
 	if _, err := os.Stat(configPath); err == nil {
@@ -190,12 +275,78 @@ func (h *ConfigHandler) HandleSaveConfig(w http.ResponseWriter, r *http.Request)
 	// Remove backup on success
 	os.Remove(backupPath)
 
-	h.respondJSON(w, http.StatusOK, ConfigResponse{
+	// Keep the project files in sync with the saved config
+	if err := generator.NewGeneratorWithRegistry(cfg, h.projectDir, h.registry).Generate(); err != nil {
+		jsonError(w, "Config saved, but regenerating the project failed. Run 'sdbx regenerate'.", "config.Generate", err, http.StatusInternalServerError)
+		return
+	}
+
+	if r.FormValue("apply") != "true" {
+		h.respondJSON(w, http.StatusOK, ConfigResponse{
+			Success: true,
+			Message: "Config saved and project regenerated. Run 'sdbx up' to apply changes.",
+		})
+		return
+	}
+	if h.compose == nil || h.jobs == nil {
+		h.respondJSON(w, http.StatusOK, ConfigResponse{
+			Success: true,
+			Message: "Config saved and project regenerated. Docker Compose is not available: run 'sdbx up' to apply changes.",
+		})
+		return
+	}
+
+	job, err := h.jobs.Start("apply config", func(ctx context.Context, step func(string)) error {
+		step("Creating and removing services")
+		if err := h.compose.Up(ctx); err != nil {
+			return fmt.Errorf("failed to start services: %w", err)
+		}
+		// Up only recreates services whose definition changed; config
+		// files are read at startup
+		for _, service := range restart {
+			step(fmt.Sprintf("Restarting %s", service))
+			if err := h.compose.Restart(ctx, service); err != nil {
+				return fmt.Errorf("failed to restart %s: %w", service, err)
+			}
+		}
+		step("Services are up to date")
+		return nil
+	})
+	if err != nil {
+		jsonError(w, "Config saved, but applying failed to start. Run 'sdbx up'.", "config.Apply.Start", err, http.StatusInternalServerError)
+		return
+	}
+	h.respondJSON(w, http.StatusAccepted, ConfigResponse{
 		Success: true,
-		Message: "Config saved successfully. Run 'sdbx down && sdbx up' to apply changes.",
+		Message: "Config saved, applying changes",
+		JobID:   job.ID,
 	})
 }
 
+// fileRevision returns a hash identifying the content of path, or "" if it
+// does not exist
+func fileRevision(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// redactDiffs hides the content of changed env files, which hold secrets
+// such as VPN credentials
+func redactDiffs(files map[string]string) {
+	for name := range files {
+		if strings.HasSuffix(name, ".env") {
+			files[name] = ""
+		}
+	}
+}
+
 // validateConfig performs additional validation on config
 func (h *ConfigHandler) validateConfig(cfg *config.Config) []string {
 	var errors []string
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
@@ -14,7 +15,7 @@ import (
 
 // TestConfigHandlerValidateConfig tests the validate endpoint
 func TestConfigHandlerValidateConfig(t *testing.T) {
-	handler := NewConfigHandler("", nil)
+	handler := NewConfigHandler(nil, nil, nil, "", nil)
 
 	tests := []struct {
 		name       string
@@ -112,7 +113,7 @@ timezone: America/New_York`
 		t.Fatalf("failed to create test config: %v", err)
 	}
 
-	handler := NewConfigHandler(tmpDir, nil)
+	handler := NewConfigHandler(nil, nil, nil, tmpDir, nil)
 
 	req := httptest.NewRequest(http.MethodGet, "/api/config", nil)
 	w := httptest.NewRecorder()
@@ -138,7 +139,7 @@ timezone: America/New_York`
 // TestConfigHandlerGetConfigNotFound tests get config when file doesn't exist
 func TestConfigHandlerGetConfigNotFound(t *testing.T) {
 	tmpDir := t.TempDir()
-	handler := NewConfigHandler(tmpDir, nil)
+	handler := NewConfigHandler(nil, nil, nil, tmpDir, nil)
 
 	req := httptest.NewRequest(http.MethodGet, "/api/config", nil)
 	w := httptest.NewRecorder()
@@ -166,7 +167,7 @@ func TestConfigHandlerSaveConfig(t *testing.T) {
 		t.Fatalf("failed to create existing config: %v", err)
 	}
 
-	handler := NewConfigHandler(tmpDir, nil)
+	handler := NewConfigHandler(nil, nil, nil, tmpDir, nil)
 
 	newConfig := `domain: new.example.com
 timezone: UTC
@@ -178,27 +179,56 @@ mediapath: ./data/media
 downloadspath: ./data/downloads
 configpath: ./configs`
 
-	form := url.Values{}
-	form.Set("content", newConfig)
+	save := func(values url.Values) (*httptest.ResponseRecorder, ConfigResponse) {
+		req := httptest.NewRequest(http.MethodPost, "/api/config/save", strings.NewReader(values.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		w := httptest.NewRecorder()
+		handler.HandleSaveConfig(w, req)
+
+		var resp ConfigResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("invalid response %q: %v", w.Body.String(), err)
+		}
+		return w, resp
+	}
 
-	req := httptest.NewRequest(http.MethodPost, "/api/config/save", strings.NewReader(form.Encode()))
-	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-	w := httptest.NewRecorder()
+	// Without confirmation the plan is returned and nothing is written
+	w, resp := save(url.Values{"content": {newConfig}})
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d, body = %s", w.Code, http.StatusOK, w.Body.String())
+	}
+	if resp.Plan == nil || resp.Revision == "" {
+		t.Fatalf("response should carry a plan and revision: %s", w.Body.String())
+	}
+	if _, ok := resp.Plan.Files["compose.yaml"]; !ok {
+		t.Errorf("plan should create compose.yaml, got %v", resp.Plan.Files)
+	}
+	if content, _ := os.ReadFile(configPath); string(content) != existingConfig {
+		t.Errorf("config written before confirmation: %q", content)
+	}
 
-	handler.HandleSaveConfig(w, req)
+	// A stale revision is refused
+	w, _ = save(url.Values{"content": {newConfig}, "confirm": {"true"}, "revision": {"stale"}})
+	if w.Code != http.StatusConflict {
+		t.Errorf("stale revision status = %d, want %d", w.Code, http.StatusConflict)
+	}
 
+	w, _ = save(url.Values{"content": {newConfig}, "confirm": {"true"}, "revision": {resp.Revision}})
 	if w.Code != http.StatusOK {
 		t.Errorf("status = %d, want %d, body = %s", w.Code, http.StatusOK, w.Body.String())
 	}
 
-	// Verify file was updated
+	// Verify file was updated and the project regenerated
 	content, err := os.ReadFile(configPath)
 	if err != nil {
 		t.Fatalf("failed to read saved config: %v", err)
 	}
 
-	if string(content) != newConfig {
-		t.Errorf("saved config = %q, want %q", string(content), newConfig)
+	if !strings.Contains(string(content), "domain: new.example.com") {
+		t.Errorf("saved config = %q, want domain new.example.com", string(content))
+	}
+	if _, err := os.Stat(filepath.Join(tmpDir, "compose.yaml")); err != nil {
+		t.Errorf("project should be regenerated: %v", err)
 	}
 
 	// Verify backup was cleaned up
@@ -208,9 +238,20 @@ configpath: ./configs`
 	}
 }
 
+func TestRedactDiffs(t *testing.T) {
+	files := map[string]string{".env": "-A=1\n", "configs/gluetun/gluetun.env": "-KEY=x\n", "compose.yaml": "+x\n"}
+	redactDiffs(files)
+	if files[".env"] != "" || files["configs/gluetun/gluetun.env"] != "" {
+		t.Errorf("env file diffs should be hidden: %v", files)
+	}
+	if files["compose.yaml"] == "" {
+		t.Error("other diffs should be kept")
+	}
+}
+
 // TestConfigHandlerSaveConfigEmpty tests save with empty content
 func TestConfigHandlerSaveConfigEmpty(t *testing.T) {
-	handler := NewConfigHandler("", nil)
+	handler := NewConfigHandler(nil, nil, nil, "", nil)
 
 	form := url.Values{}
 	form.Set("content", "")
@@ -228,7 +269,7 @@ func TestConfigHandlerSaveConfigEmpty(t *testing.T) {
 
 // TestConfigHandlerSaveConfigInvalidYAML tests save with invalid YAML
 func TestConfigHandlerSaveConfigInvalidYAML(t *testing.T) {
-	handler := NewConfigHandler("", nil)
+	handler := NewConfigHandler(nil, nil, nil, "", nil)
 
 	form := url.Values{}
 	form.Set("content", "invalid: yaml: :")
@@ -251,7 +292,7 @@ func TestConfigHandlerSaveConfigInvalidYAML(t *testing.T) {
 
 // TestValidateConfig tests the internal validation function
 func TestValidateConfig(t *testing.T) {
-	handler := NewConfigHandler("", nil)
+	handler := NewConfigHandler(nil, nil, nil, "", nil)
 
 	tests := []struct {
 		name       string
@@ -367,7 +408,7 @@ func TestValidateConfig(t *testing.T) {
 
 // TestRespondJSON tests JSON response helper
 func TestRespondJSON(t *testing.T) {
-	handler := NewConfigHandler("", nil)
+	handler := NewConfigHandler(nil, nil, nil, "", nil)
 
 	w := httptest.NewRecorder()
 	data := ConfigResponse{
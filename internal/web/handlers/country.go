@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/netip"
+	"slices"
+	"strings"
+
+	"github.com/maiko/sdbx/internal/geoip"
+)
+
+// sharedAddressSpace is the carrier-grade NAT range (RFC 6598), which
+// Tailscale also uses for its devices
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+// CountryHandler answers Traefik's forwardAuth check of the country
+// allowlists (traefik.country_allowlist and services.<name>.country_allowlist),
+// looking up clients in the downloaded GeoIP database
+type CountryHandler struct {
+	countries interface {
+		Country(addr netip.Addr) (string, error)
+	}
+}
+
+// NewCountryHandler creates a new country handler
+func NewCountryHandler(projectDir string) *CountryHandler {
+	return &CountryHandler{countries: geoip.NewCountries(projectDir)}
+}
+
+// HandleVerify answers 200 when the client's country is one of the allow
+// query parameter. Clients on the local network always pass; public
+// addresses the database cannot place are refused.
+func (h *CountryHandler) HandleVerify(w http.ResponseWriter, r *http.Request) {
+	addr, public := clientAddr(r)
+	if !public {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	country, err := h.countries.Country(addr)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, geoip.ErrNoCountryDatabase) {
+			status = http.StatusServiceUnavailable
+		}
+		httpError(w, "country.verify", err, status)
+		return
+	}
+	if country != "" && slices.Contains(strings.Split(r.URL.Query().Get("allow"), ","), country) {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	http.Error(w, "Access from your country is not allowed", http.StatusForbidden)
+}
+
+// clientAddr returns the address of the client Traefik checks, and whether
+// it is a public one. Traefik sends it last in X-Forwarded-For, after the
+// addresses cloudflared forwarded when it trusts them; proxies on the
+// local network (cloudflared, Traefik) are skipped from the right.
+func clientAddr(r *http.Request) (netip.Addr, bool) {
+	var hops []string
+	for _, header := range r.Header.Values("X-Forwarded-For") {
+		hops = append(hops, strings.Split(header, ",")...)
+	}
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil && len(hops) == 0 {
+		hops = append(hops, host)
+	}
+	for _, hop := range slices.Backward(hops) {
+		addr, err := netip.ParseAddr(strings.TrimSpace(hop))
+		if err != nil {
+			continue
+		}
+		addr = addr.Unmap()
+		if addr.IsPrivate() || addr.IsLoopback() || addr.IsLinkLocalUnicast() || addr.IsUnspecified() || sharedAddressSpace.Contains(addr) {
+			continue
+		}
+		return addr, true
+	}
+	return netip.Addr{}, false
+}
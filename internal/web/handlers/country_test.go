@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+
+	"github.com/maiko/sdbx/internal/geoip"
+)
+
+// fakeCountries places addresses from a map
+type fakeCountries map[string]string
+
+func (f fakeCountries) Country(addr netip.Addr) (string, error) {
+	if addr.String() == "198.51.100.9" {
+		return "", geoip.ErrNoCountryDatabase
+	}
+	return f[addr.String()], nil
+}
+
+func TestCountryHandler(t *testing.T) {
+	h := &CountryHandler{countries: fakeCountries{"81.2.69.142": "GB", "2a01:e0a::1": "FR"}}
+	tests := []struct {
+		name      string
+		forwarded []string
+		remote    string
+		allow     string
+		want      int
+	}{
+		{"allowed country", []string{"81.2.69.142"}, "172.18.0.2:4242", "FR,GB", http.StatusOK},
+		{"other country", []string{"81.2.69.142"}, "172.18.0.2:4242", "FR", http.StatusForbidden},
+		{"IPv6", []string{"2a01:e0a::1"}, "172.18.0.2:4242", "FR", http.StatusOK},
+		{"unknown address", []string{"203.0.113.7"}, "172.18.0.2:4242", "FR,GB", http.StatusForbidden},
+		{"local network", []string{"192.168.1.20"}, "172.18.0.2:4242", "FR", http.StatusOK},
+		{"tailscale", []string{"100.101.102.103"}, "172.18.0.2:4242", "FR", http.StatusOK},
+		{"no database", []string{"198.51.100.9"}, "172.18.0.2:4242", "FR", http.StatusServiceUnavailable},
+		// Behind cloudflared, Traefik appends the tunnel to the client and
+		// whatever the client claimed before it
+		{"cloudflared", []string{"2a01:e0a::1, 81.2.69.142", "172.18.0.5"}, "172.18.0.2:4242", "GB", http.StatusOK},
+		{"spoofed hop", []string{"2a01:e0a::1, 81.2.69.142, 172.18.0.5"}, "172.18.0.2:4242", "FR", http.StatusForbidden},
+		{"no header", nil, "81.2.69.142:4242", "GB", http.StatusOK},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/country/verify?allow="+tt.allow, nil)
+		req.RemoteAddr = tt.remote
+		for _, hop := range tt.forwarded {
+			req.Header.Add("X-Forwarded-For", hop)
+		}
+		w := httptest.NewRecorder()
+		h.HandleVerify(w, req)
+		if w.Code != tt.want {
+			t.Errorf("%s: verify = %d, want %d", tt.name, w.Code, tt.want)
+		}
+	}
+}
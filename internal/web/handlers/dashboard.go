@@ -1,26 +1,37 @@
 package handlers
 
 import (
+	"context"
+	"fmt"
 	"html/template"
+	"log"
 	"net/http"
+	"time"
 
+	"github.com/maiko/sdbx/internal/config"
 	"github.com/maiko/sdbx/internal/docker"
+	"github.com/maiko/sdbx/internal/health"
 	"github.com/maiko/sdbx/internal/registry"
 )
 
+// historyWindow is the health history shown on the dashboard
+const historyWindow = 24 * time.Hour
+
 // DashboardHandler handles dashboard routes
 type DashboardHandler struct {
-	compose   *docker.Compose
-	registry  *registry.Registry
-	templates *template.Template
+	compose    *docker.Compose
+	registry   *registry.Registry
+	projectDir string
+	templates  *template.Template
 }
 
 // NewDashboardHandler creates a new dashboard handler
-func NewDashboardHandler(compose *docker.Compose, reg *registry.Registry, tmpl *template.Template) *DashboardHandler {
+func NewDashboardHandler(compose *docker.Compose, reg *registry.Registry, projectDir string, tmpl *template.Template) *DashboardHandler {
 	return &DashboardHandler{
-		compose:   compose,
-		registry:  reg,
-		templates: tmpl,
+		compose:    compose,
+		registry:   reg,
+		projectDir: projectDir,
+		templates:  tmpl,
 	}
 }
 
@@ -31,6 +42,12 @@ func (h *DashboardHandler) HandleDashboard(w http.ResponseWriter, r *http.Reques
 		httpError(w, "dashboard.buildData", err, http.StatusInternalServerError)
 		return
 	}
+	data["History"] = h.healthHistory()
+	if state, err := config.ReadState(h.projectDir); err != nil {
+		log.Printf("Warning [dashboard.state]: %v", err)
+	} else if state.NeedsRepair() {
+		data["ProjectState"] = state
+	}
 	h.renderTemplate(w, "pages/dashboard.html", data)
 }
 
@@ -65,10 +82,84 @@ func (h *DashboardHandler) buildDashboardData(r *http.Request) (map[string]inter
 		"TotalServices":      len(serviceMap),
 		"RunningServices":    countRunningServices(serviceMap),
 		"QuickAccess":        quickAccess,
+		"Checklist":          h.outstandingChecklist(ctx),
 	}
 	return data, nil
 }
 
+// HandleChecklistDone handles POST /api/checklist/{service}/{step}/done and
+// returns the refreshed checklist fragment
+func (h *DashboardHandler) HandleChecklistDone(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	id := r.PathValue("service") + "/" + r.PathValue("step")
+	checklist, err := h.checklist(r.Context())
+	if err != nil {
+		httpError(w, "dashboard.checklistDone", err, http.StatusInternalServerError)
+		return
+	}
+	if item, ok := checklist.Find(id); !ok || item.Secret != "" {
+		http.Error(w, "Checklist step not found", http.StatusNotFound)
+		return
+	}
+
+	if err := registry.SetChecklistStep(h.projectDir, id, true); err != nil {
+		httpError(w, "dashboard.checklistDone", err, http.StatusInternalServerError)
+		return
+	}
+
+	data := map[string]interface{}{"Checklist": h.outstandingChecklist(r.Context())}
+	renderTemplate(h.templates, w, "checklist-fragment", "dashboard.checklist", data)
+}
+
+// checklist builds the post-install checklist of the enabled services
+func (h *DashboardHandler) checklist(ctx context.Context) (*registry.Checklist, error) {
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config: %w", err)
+	}
+	graph, err := h.registry.Resolve(ctx, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve services: %w", err)
+	}
+	return graph.Checklist(h.projectDir)
+}
+
+// outstandingChecklist returns the post-install items left to do. Errors are
+// logged only: the checklist must not break the dashboard.
+func (h *DashboardHandler) outstandingChecklist(ctx context.Context) []registry.ChecklistItem {
+	checklist, err := h.checklist(ctx)
+	if err != nil {
+		log.Printf("Warning [dashboard.checklist]: %v", err)
+		return nil
+	}
+	return checklist.Outstanding()
+}
+
+// healthHistory summarizes the last day of health history, if any was
+// recorded. Errors are logged only: history must not break the dashboard.
+func (h *DashboardHandler) healthHistory() []health.Stats {
+	if !health.Exists(h.projectDir) {
+		return nil
+	}
+	store, err := health.OpenReadOnly(h.projectDir)
+	if err != nil {
+		log.Printf("Warning [dashboard.history]: %v", err)
+		return nil
+	}
+	defer store.Close()
+
+	history, err := store.History(time.Now().Add(-historyWindow))
+	if err != nil {
+		log.Printf("Warning [dashboard.history]: %v", err)
+		return nil
+	}
+	return health.SummarizeAll(history)
+}
+
 func (h *DashboardHandler) renderTemplate(w http.ResponseWriter, name string, data interface{}) {
 	renderTemplate(h.templates, w, name, "dashboard", data)
 }
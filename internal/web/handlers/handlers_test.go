@@ -4,11 +4,15 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
+	"io"
 	"log"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+
+	"github.com/maiko/sdbx/internal/problem"
 )
 
 // TestFormatServiceName verifies service name formatting
@@ -144,9 +148,33 @@ func TestJsonErrorHidesInternalDetails(t *testing.T) {
 	}
 }
 
+// TestJsonErrorProblem verifies errors of a known kind carry its code and hint
+func TestJsonErrorProblem(t *testing.T) {
+	log.SetOutput(io.Discard)
+	defer log.SetOutput(nil)
+
+	w := httptest.NewRecorder()
+	err := problem.Wrap(problem.ErrSourceUnreachable, errors.New("dial tcp 10.0.0.1:443: timeout"), "git clone of private failed")
+	jsonError(w, "Failed to update sources", "sources.Update", err, http.StatusBadGateway)
+
+	var body map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if body["code"] != "source-unreachable" || body["hint"] != problem.ErrSourceUnreachable.Hint || body["type"] != problem.ErrSourceUnreachable.Doc() {
+		t.Errorf("body = %v, want the source-unreachable problem", body)
+	}
+	if body["message"] != "Failed to update sources" || body["status"] != float64(http.StatusBadGateway) {
+		t.Errorf("body = %v, want the user message and status", body)
+	}
+	if strings.Contains(w.Body.String(), "10.0.0.1") {
+		t.Errorf("response must not contain internal error details, got: %s", w.Body.String())
+	}
+}
+
 // TestDashboardHandlerConstruction verifies dashboard handler can be created
 func TestDashboardHandlerConstruction(t *testing.T) {
-	handler := NewDashboardHandler(nil, nil, nil)
+	handler := NewDashboardHandler(nil, nil, "", nil)
 
 	if handler == nil {
 		t.Error("NewDashboardHandler should return non-nil handler")
@@ -173,7 +201,7 @@ func TestLogsHandlerConstruction(t *testing.T) {
 
 // TestAddonsHandlerConstruction verifies addons handler can be created
 func TestAddonsHandlerConstruction(t *testing.T) {
-	handler := NewAddonsHandler(nil, "", nil)
+	handler := NewAddonsHandler(nil, nil, nil, "", nil)
 
 	if handler == nil {
 		t.Error("NewAddonsHandler should return non-nil handler")
@@ -182,7 +210,7 @@ func TestAddonsHandlerConstruction(t *testing.T) {
 
 // TestConfigHandlerConstruction verifies config handler can be created
 func TestConfigHandlerConstruction(t *testing.T) {
-	handler := NewConfigHandler("", nil)
+	handler := NewConfigHandler(nil, nil, nil, "", nil)
 
 	if handler == nil {
 		t.Error("NewConfigHandler should return non-nil handler")
@@ -191,7 +219,7 @@ func TestConfigHandlerConstruction(t *testing.T) {
 
 // TestBackupHandlerConstruction verifies backup handler can be created
 func TestBackupHandlerConstruction(t *testing.T) {
-	handler := NewBackupHandler("", nil)
+	handler := NewBackupHandler("", nil, nil)
 
 	if handler == nil {
 		t.Error("NewBackupHandler should return non-nil handler")
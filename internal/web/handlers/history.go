@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"fmt"
+	"html/template"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/maiko/sdbx/internal/events"
+)
+
+const (
+	// defaultHistoryLimit is the number of events shown unless asked otherwise
+	defaultHistoryLimit = 200
+	// maxHistoryLimit bounds the events returned by one request
+	maxHistoryLimit = 1000
+)
+
+// HistoryHandler handles the events log routes
+type HistoryHandler struct {
+	log       *events.Log
+	templates *template.Template
+}
+
+// NewHistoryHandler creates a new history handler
+func NewHistoryHandler(projectDir string, tmpl *template.Template) *HistoryHandler {
+	return &HistoryHandler{
+		log:       events.NewLog(projectDir),
+		templates: tmpl,
+	}
+}
+
+// HistoryEventDisplay is an event formatted for the history page
+type HistoryEventDisplay struct {
+	events.Event
+	TimeFormatted string
+	Failed        bool
+}
+
+// HandleHistoryPage handles the history page, filtered by the user, source
+// and since query parameters
+func (h *HistoryHandler) HandleHistoryPage(w http.ResponseWriter, r *http.Request) {
+	filter, err := historyFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	recorded, err := h.log.Read(filter)
+	if err != nil {
+		httpError(w, "history.Read", err, http.StatusInternalServerError)
+		return
+	}
+
+	display := make([]HistoryEventDisplay, 0, len(recorded))
+	for _, e := range recorded {
+		display = append(display, HistoryEventDisplay{
+			Event:         e,
+			TimeFormatted: e.Time.Local().Format("2006-01-02 15:04:05"),
+			Failed:        e.Status >= http.StatusBadRequest,
+		})
+	}
+
+	// Users to filter by come from the whole log, not the filtered events
+	all, err := h.log.Read(events.Filter{})
+	if err != nil {
+		httpError(w, "history.Read", err, http.StatusInternalServerError)
+		return
+	}
+	var users []string
+	for _, e := range all {
+		if e.User != "" && !slices.Contains(users, e.User) {
+			users = append(users, e.User)
+		}
+	}
+	slices.Sort(users)
+
+	data := map[string]interface{}{
+		"Events": display,
+		"Users":  users,
+		"User":   filter.User,
+		"Source": filter.Source,
+		"Since":  r.URL.Query().Get("since"),
+		"Limit":  filter.Limit,
+	}
+	h.renderTemplate(w, "pages/history.html", data)
+}
+
+// HandleGetHistory handles GET /api/history, with the page's filters
+func (h *HistoryHandler) HandleGetHistory(w http.ResponseWriter, r *http.Request) {
+	filter, err := historyFilter(r)
+	if err != nil {
+		respondJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	recorded, err := h.log.Read(filter)
+	if err != nil {
+		jsonError(w, "Failed to read history", "history.Read", err, http.StatusInternalServerError)
+		return
+	}
+	respondJSON(w, http.StatusOK, recorded)
+}
+
+// historyFilter parses the user, source, since (a duration such as 24h) and
+// limit query parameters
+func historyFilter(r *http.Request) (events.Filter, error) {
+	q := r.URL.Query()
+	filter := events.Filter{User: q.Get("user"), Source: q.Get("source"), Limit: defaultHistoryLimit}
+
+	switch filter.Source {
+	case "", events.SourceWeb, events.SourceCLI, events.SourceAgent:
+	default:
+		return filter, fmt.Errorf("invalid source %q (must be %s, %s or %s)", filter.Source, events.SourceWeb, events.SourceCLI, events.SourceAgent)
+	}
+	if since := q.Get("since"); since != "" {
+		d, err := time.ParseDuration(since)
+		if err != nil || d <= 0 {
+			return filter, fmt.Errorf("invalid since %q (e.g. 24h)", since)
+		}
+		filter.Since = time.Now().Add(-d)
+	}
+	if limit := q.Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n <= 0 {
+			return filter, fmt.Errorf("invalid limit %q", limit)
+		}
+		filter.Limit = min(n, maxHistoryLimit)
+	}
+	return filter, nil
+}
+
+func (h *HistoryHandler) renderTemplate(w http.ResponseWriter, name string, data interface{}) {
+	renderTemplate(h.templates, w, name, "history", data)
+}
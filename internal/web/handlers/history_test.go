@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/maiko/sdbx/internal/events"
+)
+
+func TestHistoryFilter(t *testing.T) {
+	tests := []struct {
+		query     string
+		wantErr   bool
+		wantLimit int
+	}{
+		{"", false, defaultHistoryLimit},
+		{"user=alice&source=web&since=24h", false, defaultHistoryLimit},
+		{"limit=5000", false, maxHistoryLimit},
+		{"source=api", true, 0},
+		{"since=yesterday", true, 0},
+		{"limit=-1", true, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			filter, err := historyFilter(httptest.NewRequest(http.MethodGet, "/history?"+tt.query, nil))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("historyFilter() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && filter.Limit != tt.wantLimit {
+				t.Errorf("Limit = %d, want %d", filter.Limit, tt.wantLimit)
+			}
+		})
+	}
+}
+
+func TestHandleGetHistory(t *testing.T) {
+	tmpDir := t.TempDir()
+	log := events.NewLog(tmpDir)
+	for _, e := range []events.Event{
+		{Source: events.SourceCLI, User: "alice", Action: "sdbx up"},
+		{Source: events.SourceWeb, User: "bob", Action: "POST /api/backup/create", Status: http.StatusAccepted},
+	} {
+		if err := log.Record(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	h := NewHistoryHandler(tmpDir, nil)
+	w := httptest.NewRecorder()
+	h.HandleGetHistory(w, httptest.NewRequest(http.MethodGet, "/api/history?source=web", nil))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200 (%s)", w.Code, w.Body.String())
+	}
+	var got []events.Event
+	if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0].User != "bob" {
+		t.Errorf("history = %+v, want bob's web event only", got)
+	}
+}
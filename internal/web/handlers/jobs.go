@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/maiko/sdbx/internal/problem"
+)
+
+// Job states
+const (
+	JobRunning   = "running"
+	JobSucceeded = "succeeded"
+	JobFailed    = "failed"
+)
+
+const (
+	// jobTimeout bounds a single job
+	jobTimeout = 15 * time.Minute
+	// maxJobs is how many jobs are kept for polling, oldest dropped first
+	maxJobs = 50
+)
+
+// Job is a long-running operation started from the web UI. Pages poll
+// GET /api/jobs/{id} until it is no longer running.
+type Job struct {
+	ID       string           `json:"id"`
+	Name     string           `json:"name"`
+	Status   string           `json:"status"`
+	Steps    []string         `json:"steps"` // Progress messages, oldest first
+	Error    string           `json:"error,omitempty"`
+	Problem  *problem.Problem `json:"problem,omitempty"` // Set when the error is of a known kind
+	Started  time.Time        `json:"started"`
+	Finished time.Time        `json:"finished"`
+}
+
+// JobFunc is the work of a job; step reports progress
+type JobFunc func(ctx context.Context, step func(msg string)) error
+
+// JobsHandler runs jobs in the background and serves their progress
+type JobsHandler struct {
+	ctx   context.Context
+	mu    sync.Mutex
+	jobs  map[string]*Job
+	order []string
+
+	logger *log.Logger // Jobs log; nil logs nothing
+}
+
+// NewJobsHandler creates a job runner; jobs are cancelled with ctx
+func NewJobsHandler(ctx context.Context) *JobsHandler {
+	return &JobsHandler{ctx: ctx, jobs: make(map[string]*Job)}
+}
+
+// SetLogger records the start, steps and outcome of jobs to logger
+func (h *JobsHandler) SetLogger(logger *log.Logger) {
+	h.logger = logger
+}
+
+// logf writes to the jobs log when one is set
+func (h *JobsHandler) logf(format string, args ...interface{}) {
+	if h.logger != nil {
+		h.logger.Printf(format, args...)
+	}
+}
+
+// Start runs fn in the background and returns the job as started
+func (h *JobsHandler) Start(name string, fn JobFunc) (Job, error) {
+	id, err := generateSessionID()
+	if err != nil {
+		return Job{}, err
+	}
+	job := &Job{ID: id, Name: name, Status: JobRunning, Steps: []string{}, Started: time.Now()}
+
+	h.mu.Lock()
+	h.jobs[id] = job
+	h.order = append(h.order, id)
+	h.prune()
+	snapshot := job.copy()
+	h.mu.Unlock()
+	h.logf("Job %s [%s] started", name, id)
+
+	go func() {
+		ctx, cancel := context.WithTimeout(h.ctx, jobTimeout)
+		defer cancel()
+
+		err := fn(ctx, func(msg string) {
+			h.mu.Lock()
+			job.Steps = append(job.Steps, msg)
+			h.mu.Unlock()
+			h.logf("Job %s [%s]: %s", name, id, msg)
+		})
+		if err != nil {
+			h.logf("Job %s [%s] failed: %v", name, id, err)
+		} else {
+			h.logf("Job %s [%s] succeeded", name, id)
+		}
+
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		job.Finished = time.Now()
+		job.Status = JobSucceeded
+		if err != nil {
+			job.Status = JobFailed
+			job.Error = err.Error()
+			if problem.KindOf(err) != nil {
+				p := problem.From(err, 0)
+				job.Problem = &p
+			}
+		}
+	}()
+
+	return snapshot, nil
+}
+
+// Get returns a job by ID
+func (h *JobsHandler) Get(id string) (Job, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	job, ok := h.jobs[id]
+	if !ok {
+		return Job{}, false
+	}
+	return job.copy(), true
+}
+
+// HandleGetJob handles GET /api/jobs/{id}
+func (h *JobsHandler) HandleGetJob(w http.ResponseWriter, r *http.Request) {
+	job, ok := h.Get(r.PathValue("id"))
+	if !ok {
+		respondJSON(w, http.StatusNotFound, map[string]string{"error": "Job not found"})
+		return
+	}
+	respondJSON(w, http.StatusOK, job)
+}
+
+// prune drops the oldest finished jobs beyond maxJobs. Must hold h.mu.
+func (h *JobsHandler) prune() {
+	for i := 0; len(h.order) > maxJobs && i < len(h.order); {
+		id := h.order[i]
+		if h.jobs[id].Status == JobRunning {
+			i++
+			continue
+		}
+		delete(h.jobs, id)
+		h.order = append(h.order[:i], h.order[i+1:]...)
+	}
+}
+
+// copy returns a snapshot of the job safe to use without the lock
+func (j *Job) copy() Job {
+	c := *j
+	c.Steps = append([]string{}, j.Steps...)
+	return c
+}
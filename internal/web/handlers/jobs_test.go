@@ -0,0 +1,140 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// waitJob polls a job until it is no longer running
+func waitJob(t *testing.T, h *JobsHandler, id string) Job {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		job, ok := h.Get(id)
+		if !ok {
+			t.Fatalf("job %s not found", id)
+		}
+		if job.Status != JobRunning {
+			return job
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("job %s still running", id)
+	return Job{}
+}
+
+// TestJobsHandlerRunsJobs verifies jobs record their steps and outcome
+func TestJobsHandlerRunsJobs(t *testing.T) {
+	h := NewJobsHandler(context.Background())
+
+	ok, err := h.Start("works", func(_ context.Context, step func(string)) error {
+		step("first")
+		step("second")
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	if ok.Status != JobRunning || ok.ID == "" {
+		t.Errorf("Start() = %+v, want a running job with an ID", ok)
+	}
+
+	failed, err := h.Start("fails", func(_ context.Context, step func(string)) error {
+		step("trying")
+		return errors.New("boom")
+	})
+	if err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+
+	if job := waitJob(t, h, ok.ID); job.Status != JobSucceeded || len(job.Steps) != 2 || job.Finished.IsZero() {
+		t.Errorf("succeeded job = %+v", job)
+	}
+	if job := waitJob(t, h, failed.ID); job.Status != JobFailed || job.Error != "boom" {
+		t.Errorf("failed job = %+v", job)
+	}
+}
+
+// TestJobsHandlerLogsJobs verifies jobs are recorded to the jobs log
+func TestJobsHandlerLogsJobs(t *testing.T) {
+	var out strings.Builder
+	h := NewJobsHandler(context.Background())
+	h.SetLogger(log.New(&out, "", 0))
+
+	job, err := h.Start("update", func(_ context.Context, step func(string)) error {
+		step("pulling")
+		return errors.New("boom")
+	})
+	if err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	waitJob(t, h, job.ID)
+
+	for _, want := range []string{"Job update [" + job.ID + "] started", "]: pulling", "] failed: boom"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("jobs log = %q, want %q", out.String(), want)
+		}
+	}
+}
+
+// TestJobsHandlerPrunesFinishedJobs verifies only the most recent jobs are kept
+func TestJobsHandlerPrunesFinishedJobs(t *testing.T) {
+	h := NewJobsHandler(context.Background())
+
+	var first string
+	for i := 0; i < maxJobs+5; i++ {
+		job, err := h.Start("noop", func(context.Context, func(string)) error { return nil })
+		if err != nil {
+			t.Fatalf("Start() error = %v", err)
+		}
+		if i == 0 {
+			first = job.ID
+		}
+		waitJob(t, h, job.ID)
+	}
+
+	if _, ok := h.Get(first); ok {
+		t.Error("oldest finished job should be pruned")
+	}
+	if len(h.order) != maxJobs {
+		t.Errorf("kept %d jobs, want %d", len(h.order), maxJobs)
+	}
+}
+
+// TestHandleGetJob verifies the job endpoint returns jobs as JSON
+func TestHandleGetJob(t *testing.T) {
+	h := NewJobsHandler(context.Background())
+	job, err := h.Start("noop", func(context.Context, func(string)) error { return nil })
+	if err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	waitJob(t, h, job.ID)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/jobs/"+job.ID, nil)
+	req.SetPathValue("id", job.ID)
+	w := httptest.NewRecorder()
+	h.HandleGetJob(w, req)
+
+	var got Job
+	if err := json.NewDecoder(w.Body).Decode(&got); err != nil || w.Code != http.StatusOK {
+		t.Fatalf("HandleGetJob() = %d, %v", w.Code, err)
+	}
+	if got.ID != job.ID || got.Status != JobSucceeded {
+		t.Errorf("HandleGetJob() = %+v", got)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/jobs/missing", nil)
+	req.SetPathValue("id", "missing")
+	w = httptest.NewRecorder()
+	h.HandleGetJob(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("HandleGetJob(missing) status = %d, want %d", w.Code, http.StatusNotFound)
+	}
+}
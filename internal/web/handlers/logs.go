@@ -16,6 +16,7 @@ import (
 
 	"github.com/maiko/sdbx/internal/docker"
 	"github.com/maiko/sdbx/internal/registry"
+	"github.com/maiko/sdbx/internal/serverlog"
 )
 
 // validLogServiceName matches valid service names for log requests.
@@ -36,6 +37,10 @@ type LogsHandler struct {
 	registry  *registry.Registry
 	templates *template.Template
 	upgrader  websocket.Upgrader
+
+	// Logs sdbx writes itself, see SetServerLogs
+	projectDir string
+	serverLogs []*serverlog.Writer
 }
 
 // NewLogsHandler creates a new logs handler
@@ -52,6 +57,13 @@ func NewLogsHandler(compose *docker.Compose, reg *registry.Registry, tmpl *templ
 	}
 }
 
+// SetServerLogs serves the logs sdbx writes in projectDir, and applies
+// retention changes to its open writers
+func (h *LogsHandler) SetServerLogs(projectDir string, writers ...*serverlog.Writer) {
+	h.projectDir = projectDir
+	h.serverLogs = writers
+}
+
 // LogMessage represents a log message sent via WebSocket
 type LogMessage struct {
 	Timestamp string `json:"timestamp"`
@@ -0,0 +1,171 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/maiko/sdbx/internal/config"
+	"github.com/maiko/sdbx/internal/serverlog"
+)
+
+// logDownloadTimeout bounds reading the logs of a download, longer than the
+// server's write timeout
+const logDownloadTimeout = 2 * time.Minute
+
+// parseLogTime reads a bound of a log time range: a duration before now
+// ("2h"), a date ("2006-01-02") or an RFC 3339 time. Empty is no bound.
+func parseLogTime(value string, now time.Time) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if d, err := time.ParseDuration(value); err == nil && d > 0 {
+		return now.Add(-d), nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", value, time.Local); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q (e.g. 2h, 2006-01-02 or 2006-01-02T15:04:05Z)", value)
+}
+
+// logRange reads the since and until query parameters of a download
+func logRange(r *http.Request) (since, until time.Time, err error) {
+	now := time.Now()
+	if since, err = parseLogTime(r.URL.Query().Get("since"), now); err != nil {
+		return since, until, err
+	}
+	if until, err = parseLogTime(r.URL.Query().Get("until"), now); err != nil {
+		return since, until, err
+	}
+	if !since.IsZero() && !until.IsZero() && until.Before(since) {
+		return since, until, fmt.Errorf("until is before since")
+	}
+	return since, until, nil
+}
+
+// logFileName names a downloaded log, e.g. sdbx-radarr-20261016-153000.log
+func logFileName(name string) string {
+	return fmt.Sprintf("sdbx-%s-%s.log", name, time.Now().Format("20060102-150405"))
+}
+
+// HandleDownloadLogs handles GET /api/logs/{service}/download, the logs of
+// a service as a file. since and until select a time range.
+func (h *LogsHandler) HandleDownloadLogs(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	serviceName := r.PathValue("service")
+	if !validLogServiceName.MatchString(serviceName) {
+		http.Error(w, "Invalid service name", http.StatusBadRequest)
+		return
+	}
+	since, until, err := logRange(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Long ranges outlast the server's write timeout
+	_ = http.NewResponseController(w).SetWriteDeadline(time.Now().Add(logDownloadTimeout))
+	ctx, cancel := context.WithTimeout(r.Context(), logDownloadTimeout)
+	defer cancel()
+
+	logs, err := h.compose.LogsRange(ctx, serviceName, since, until)
+	if err != nil {
+		httpError(w, "logs.Download", err, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", logFileName(serviceName)))
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(logs))
+}
+
+// HandleDownloadServerLog handles GET /api/server-logs/{name}/download, a
+// log sdbx writes itself (server or jobs) as a file. since and until select
+// a time range.
+func (h *LogsHandler) HandleDownloadServerLog(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	name := r.PathValue("name")
+	if !slices.Contains(serverlog.Names, name) {
+		http.Error(w, fmt.Sprintf("Unknown log, one of: %s", strings.Join(serverlog.Names, ", ")), http.StatusNotFound)
+		return
+	}
+	since, until, err := logRange(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	_ = http.NewResponseController(w).SetWriteDeadline(time.Now().Add(logDownloadTimeout))
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", logFileName(name)))
+	if err := serverlog.Copy(w, h.projectDir, name, since, until); err != nil {
+		log.Printf("Error [logs.DownloadServerLog]: %v", err)
+	}
+}
+
+// HandleServerLogSettings handles GET and POST /api/server-logs/settings,
+// the rotation and retention of the logs sdbx writes (server_logs section)
+func (h *LogsHandler) HandleServerLogSettings(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		cfg, err := config.Load()
+		if err != nil {
+			jsonError(w, "Failed to load configuration", "logs.Settings.Load", err, http.StatusInternalServerError)
+			return
+		}
+		respondJSON(w, http.StatusOK, cfg.ServerLogs)
+		return
+	case http.MethodPost:
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var settings config.ServerLogsConfig
+	if err := json.NewDecoder(r.Body).Decode(&settings); err != nil {
+		respondJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "message": "Invalid log settings"})
+		return
+	}
+
+	// Must not fall back to defaults before saving
+	cfg, err := config.Load()
+	if err != nil {
+		jsonError(w, "Failed to load configuration", "logs.Settings.Load", err, http.StatusInternalServerError)
+		return
+	}
+	cfg.ServerLogs = settings
+	if err := cfg.Validate(); err != nil {
+		respondJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "message": err.Error()})
+		return
+	}
+	if err := cfg.Save(filepath.Join(h.projectDir, ".sdbx.yaml")); err != nil {
+		jsonError(w, "Failed to save log settings", "logs.Settings.Save", err, http.StatusInternalServerError)
+		return
+	}
+	for _, writer := range h.serverLogs {
+		if err := writer.SetRetention(settings); err != nil {
+			log.Printf("Warning [logs.Settings.Prune]: %v", err)
+		}
+	}
+
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"message": fmt.Sprintf("Log settings saved. Logs rotate at %d MB and keep %d rotated files.", settings.MaxBytes()>>20, settings.Files()),
+	})
+}
@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/maiko/sdbx/internal/serverlog"
+)
+
+func TestParseLogTime(t *testing.T) {
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		value   string
+		want    time.Time
+		wantErr bool
+	}{
+		{"", time.Time{}, false},
+		{"2h", now.Add(-2 * time.Hour), false},
+		{"2026-10-15", time.Date(2026, 10, 15, 0, 0, 0, 0, time.Local), false},
+		{"2026-10-15T08:30:00Z", time.Date(2026, 10, 15, 8, 30, 0, 0, time.UTC), false},
+		{"yesterday", time.Time{}, true},
+		{"-2h", time.Time{}, true},
+	}
+	for _, tt := range tests {
+		got, err := parseLogTime(tt.value, now)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseLogTime(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			continue
+		}
+		if !got.Equal(tt.want) {
+			t.Errorf("parseLogTime(%q) = %s, want %s", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestHandleDownloadServerLog(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, serverlog.Dir), 0o750); err != nil {
+		t.Fatal(err)
+	}
+	lines := "2026/10/01 10:00:00 old\n2026/10/02 10:00:00 kept\n"
+	if err := os.WriteFile(serverlog.Path(dir, serverlog.Jobs), []byte(lines), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	handler := NewLogsHandler(nil, nil, nil)
+	handler.SetServerLogs(dir)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/server-logs/{name}/download", handler.HandleDownloadServerLog)
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/server-logs/jobs/download?since=2026-10-02", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %s", rec.Code, rec.Body)
+	}
+	if rec.Body.String() != "2026/10/02 10:00:00 kept\n" {
+		t.Errorf("body = %q, want the lines since 2026-10-02", rec.Body)
+	}
+	if !strings.Contains(rec.Header().Get("Content-Disposition"), "sdbx-jobs-") {
+		t.Errorf("Content-Disposition = %q", rec.Header().Get("Content-Disposition"))
+	}
+
+	for path, want := range map[string]int{
+		"/api/server-logs/secrets/download":                http.StatusNotFound,
+		"/api/server-logs/jobs/download?since=someday":     http.StatusBadRequest,
+		"/api/server-logs/jobs/download?since=1h&until=2h": http.StatusBadRequest,
+	} {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		if rec.Code != want {
+			t.Errorf("GET %s = %d, want %d", path, rec.Code, want)
+		}
+	}
+}
@@ -0,0 +1,25 @@
+package handlers
+
+import (
+	"bytes"
+	"html/template"
+
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+)
+
+var (
+	markdown       = goldmark.New(goldmark.WithExtensions(extension.GFM))
+	markdownPolicy = bluemonday.UGCPolicy()
+)
+
+// renderMarkdown converts Markdown from a service source to HTML. Sources
+// are third-party content, so the output is sanitized before it is trusted.
+func renderMarkdown(src string) (template.HTML, error) {
+	var buf bytes.Buffer
+	if err := markdown.Convert([]byte(src), &buf); err != nil {
+		return "", err
+	}
+	return template.HTML(markdownPolicy.SanitizeBytes(buf.Bytes())), nil
+}
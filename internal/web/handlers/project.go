@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/maiko/sdbx/internal/config"
+	"github.com/maiko/sdbx/internal/generator"
+	"github.com/maiko/sdbx/internal/registry"
+)
+
+// ProjectHandler reports the generation state of the project and repairs it
+// when generation failed or was interrupted
+type ProjectHandler struct {
+	registry   *registry.Registry
+	jobs       *JobsHandler
+	projectDir string
+}
+
+// NewProjectHandler creates a new project handler
+func NewProjectHandler(reg *registry.Registry, jobs *JobsHandler, projectDir string) *ProjectHandler {
+	return &ProjectHandler{
+		registry:   reg,
+		jobs:       jobs,
+		projectDir: projectDir,
+	}
+}
+
+// ProjectResponse is the JSON response of project endpoints
+type ProjectResponse struct {
+	Success bool   `json:"success"`
+	Message string `json:"message"`
+	JobID   string `json:"jobId,omitempty"`
+}
+
+// HandleGetState handles GET /api/project/state
+func (h *ProjectHandler) HandleGetState(w http.ResponseWriter, r *http.Request) {
+	state, err := config.ReadState(h.projectDir)
+	if err != nil {
+		jsonError(w, "Failed to read project state", "project.ReadState", err, http.StatusInternalServerError)
+		return
+	}
+	respondJSON(w, http.StatusOK, state)
+}
+
+// HandleRepair handles POST /api/project/repair: the project files are
+// regenerated from .sdbx.yaml by a job polled at /api/jobs/{id}
+func (h *ProjectHandler) HandleRepair(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	job, err := h.jobs.Start("repair project", func(_ context.Context, step func(string)) error {
+		step("Loading .sdbx.yaml")
+		cfg, err := config.Load()
+		if err != nil {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+		if err := cfg.Validate(); err != nil {
+			return fmt.Errorf("invalid config, fix it on the Config page: %w", err)
+		}
+
+		step("Regenerating project files")
+		gen := generator.NewGeneratorWithRegistry(cfg, h.projectDir, h.registry)
+		if err := gen.Generate(); err != nil {
+			return err
+		}
+		for _, e := range gen.Skipped {
+			step("Skipped " + e.Error())
+		}
+		step("Project files regenerated")
+		return nil
+	})
+	if err != nil {
+		jsonError(w, "Failed to start repair", "project.Repair.Start", err, http.StatusInternalServerError)
+		return
+	}
+
+	respondJSON(w, http.StatusAccepted, ProjectResponse{
+		Success: true,
+		Message: "Regenerating project files",
+		JobID:   job.ID,
+	})
+}
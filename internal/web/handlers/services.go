@@ -9,6 +9,7 @@ import (
 	"regexp"
 	"time"
 
+	"github.com/maiko/sdbx/internal/config"
 	"github.com/maiko/sdbx/internal/docker"
 	"github.com/maiko/sdbx/internal/registry"
 )
@@ -227,6 +228,9 @@ func (h *ServicesHandler) renderServiceCard(w http.ResponseWriter, r *http.Reque
 		info.Description = svcInfo.Metadata.Description
 		info.HasWebUI = svcInfo.Routing.Enabled
 	}
+	if cfg, err := config.Load(); err == nil {
+		info.Maintenance = cfg.IsInMaintenance(serviceName)
+	}
 
 	renderTemplate(h.templates, w, "service-card", "services.card", info)
 }
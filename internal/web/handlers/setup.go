@@ -7,15 +7,18 @@ import (
 	"fmt"
 	"html/template"
 	"log"
+	"net"
 	"net/http"
 	"os"
 	"path/filepath"
+	"strings"
 	"sync"
 	"time"
 
 	"golang.org/x/crypto/argon2"
 
 	"github.com/maiko/sdbx/internal/config"
+	"github.com/maiko/sdbx/internal/doctor"
 	"github.com/maiko/sdbx/internal/generator"
 	"github.com/maiko/sdbx/internal/registry"
 )
@@ -46,6 +49,10 @@ type SetupHandler struct {
 	templates  *template.Template
 	sessions   map[string]*WizardSession
 	mu         sync.RWMutex
+
+	// Lookups used by the validation endpoints, replaced in tests
+	lookupHost func(ctx context.Context, host string) ([]string, error)
+	publicIP   func(ctx context.Context) (string, error)
 }
 
 // WizardSession holds the state of a setup wizard session
@@ -64,6 +71,8 @@ func NewSetupHandler(ctx context.Context, reg *registry.Registry, projectDir str
 		projectDir: projectDir,
 		templates:  tmpl,
 		sessions:   make(map[string]*WizardSession),
+		lookupHost: net.DefaultResolver.LookupHost,
+		publicIP:   doctor.PublicIP,
 	}
 	go h.cleanupExpiredSessions(ctx)
 	return h
@@ -406,6 +415,15 @@ func (h *SetupHandler) HandleVPN(w http.ResponseWriter, r *http.Request) {
 		if vpnEnabled {
 			session.Config.VPNProvider = vpnProvider
 			session.Config.VPNCountry = vpnCountry
+			if vpnType := r.FormValue("vpn_type"); vpnType != "" {
+				session.Config.VPNType = vpnType
+			}
+			// Credentials are written to gluetun.env on completion
+			session.Config.VPNUsername = strings.TrimSpace(r.FormValue("vpn_username"))
+			session.Config.VPNPassword = r.FormValue("vpn_password")
+			session.Config.VPNToken = strings.TrimSpace(r.FormValue("vpn_token"))
+			session.Config.VPNWireguardKey = strings.TrimSpace(r.FormValue("vpn_wireguard_key"))
+			session.Config.VPNWireguardAddr = strings.TrimSpace(r.FormValue("vpn_wireguard_addr"))
 		}
 
 		// Redirect to next step
@@ -415,8 +433,13 @@ func (h *SetupHandler) HandleVPN(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// GET: Show form
+	authTypes := make(map[string]string, len(config.VPNProviders))
+	for id, provider := range config.VPNProviders {
+		authTypes[id] = string(provider.AuthType)
+	}
 	data := map[string]interface{}{
-		"Config": session.Config,
+		"Config":       session.Config,
+		"VPNAuthTypes": authTypes,
 		"VPNProviders": []string{
 			"nordvpn", "mullvad", "pia", "surfshark", "protonvpn",
 			"expressvpn", "windscribe", "ipvanish", "cyberghost", "ivpn",
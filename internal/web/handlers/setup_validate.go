@@ -0,0 +1,394 @@
+package handlers
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/maiko/sdbx/internal/config"
+	"github.com/maiko/sdbx/internal/doctor"
+)
+
+// Outcomes of a wizard field check
+const (
+	CheckOK      = "ok"
+	CheckWarning = "warning" // Setup can go on, but something needs attention
+	CheckError   = "error"   // The step would produce a broken setup
+)
+
+// lookupTimeout bounds the DNS and public IP lookups of a domain check
+const lookupTimeout = 5 * time.Second
+
+// networkFilesystems are filesystem types the *arr SQLite databases
+// corrupt on when used for config_path
+var networkFilesystems = []string{"nfs", "nfs4", "cifs", "smb3", "smbfs", "fuse.sshfs", "fuse.rclone"}
+
+// mullvadAccountRegex matches a Mullvad account number
+var mullvadAccountRegex = regexp.MustCompile(`^[0-9]{16}$`)
+
+// FieldCheck is the outcome of checking one wizard form field
+type FieldCheck struct {
+	Field   string `json:"field"`  // Form field name
+	Status  string `json:"status"` // ok | warning | error
+	Message string `json:"message"`
+}
+
+// ValidationResult is returned by the /setup/validate endpoints
+type ValidationResult struct {
+	Valid  bool         `json:"valid"` // No check is an error
+	Checks []FieldCheck `json:"checks"`
+}
+
+// newValidationResult builds a result from its checks
+func newValidationResult(checks []FieldCheck) ValidationResult {
+	return ValidationResult{
+		Valid:  !slices.ContainsFunc(checks, func(c FieldCheck) bool { return c.Status == CheckError }),
+		Checks: checks,
+	}
+}
+
+// HandleValidateDomain handles POST /setup/validate/domain: checks the
+// domain's syntax and that it resolves as the exposure mode needs
+func (h *SetupHandler) HandleValidateDomain(w http.ResponseWriter, r *http.Request) {
+	if !h.parseValidateForm(w, r) {
+		return
+	}
+	cfg := config.DefaultConfig()
+	cfg.Domain = strings.TrimSpace(r.FormValue("domain"))
+	cfg.Expose.Mode = r.FormValue("expose_mode")
+	cfg.Routing.Strategy = r.FormValue("routing_strategy")
+	cfg.Routing.BaseDomain = strings.TrimSpace(r.FormValue("base_domain"))
+
+	respondJSON(w, http.StatusOK, newValidationResult(h.checkDomain(r.Context(), cfg)))
+}
+
+// HandleValidatePaths handles POST /setup/validate/paths: checks the storage
+// paths exist or can be created, are writable, and sit on filesystems that
+// suit them
+func (h *SetupHandler) HandleValidatePaths(w http.ResponseWriter, r *http.Request) {
+	if !h.parseValidateForm(w, r) {
+		return
+	}
+	paths := map[string]string{
+		"media_path":     r.FormValue("media_path"),
+		"downloads_path": r.FormValue("downloads_path"),
+		"config_path":    r.FormValue("config_path"),
+	}
+	mounts, _ := doctor.Mounts() // No filesystem checks without them
+
+	respondJSON(w, http.StatusOK, newValidationResult(h.checkPaths(paths, mounts)))
+}
+
+// HandleValidateCloudflare handles POST /setup/validate/cloudflare: checks
+// the pasted token is a tunnel token
+func (h *SetupHandler) HandleValidateCloudflare(w http.ResponseWriter, r *http.Request) {
+	if !h.parseValidateForm(w, r) {
+		return
+	}
+	respondJSON(w, http.StatusOK, newValidationResult([]FieldCheck{checkTunnelToken(r.FormValue("cloudflare_token"))}))
+}
+
+// HandleValidateVPN handles POST /setup/validate/vpn: checks the provider
+// supports the protocol and the credentials have the provider's format
+func (h *SetupHandler) HandleValidateVPN(w http.ResponseWriter, r *http.Request) {
+	if !h.parseValidateForm(w, r) {
+		return
+	}
+	cfg := config.DefaultConfig()
+	cfg.VPNEnabled = true
+	cfg.VPNProvider = r.FormValue("vpn_provider")
+	cfg.VPNType = r.FormValue("vpn_type")
+	cfg.VPNUsername = strings.TrimSpace(r.FormValue("vpn_username"))
+	cfg.VPNPassword = r.FormValue("vpn_password")
+	cfg.VPNToken = strings.TrimSpace(r.FormValue("vpn_token"))
+	cfg.VPNWireguardKey = strings.TrimSpace(r.FormValue("vpn_wireguard_key"))
+	cfg.VPNWireguardAddr = strings.TrimSpace(r.FormValue("vpn_wireguard_addr"))
+
+	respondJSON(w, http.StatusOK, newValidationResult(checkVPNCredentials(cfg)))
+}
+
+// parseValidateForm accepts POST requests only, as forms may hold secrets
+func (h *SetupHandler) parseValidateForm(w http.ResponseWriter, r *http.Request) bool {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return false
+	}
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Invalid form data", http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
+// checkDomain checks the domain syntax, then that the web UI's host resolves:
+// to this host in direct mode, anywhere otherwise
+func (h *SetupHandler) checkDomain(ctx context.Context, cfg *config.Config) []FieldCheck {
+	if cfg.Domain == "" {
+		return []FieldCheck{{Field: "domain", Status: CheckError, Message: "Domain is required"}}
+	}
+	if !config.IsValidDomain(cfg.Domain) {
+		return []FieldCheck{{Field: "domain", Status: CheckError, Message: "Not a valid domain name (e.g. box.example.com)"}}
+	}
+	var checks []FieldCheck
+	if cfg.Routing.Strategy == config.RoutingStrategyPath {
+		if cfg.Routing.BaseDomain == "" {
+			return []FieldCheck{{Field: "base_domain", Status: CheckError, Message: "Base subdomain is required with path routing"}}
+		}
+		if !config.IsValidDomain(cfg.Routing.BaseDomain + "." + cfg.Domain) {
+			return []FieldCheck{{Field: "base_domain", Status: CheckError, Message: "Not a valid subdomain (e.g. sdbx)"}}
+		}
+		checks = append(checks, FieldCheck{Field: "base_domain", Status: CheckOK, Message: "Valid subdomain"})
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, lookupTimeout)
+	defer cancel()
+
+	host := h.webUIHost(ctx, cfg)
+	addrs, err := h.lookupHost(ctx, host)
+	if err != nil || len(addrs) == 0 {
+		switch cfg.Expose.Mode {
+		case config.ExposeModeDirect:
+			return append(checks, FieldCheck{Field: "domain", Status: CheckError,
+				Message: fmt.Sprintf("%s does not resolve: point it (or *.%s) at this host so certificates can be issued", host, cfg.Domain)})
+		case config.ExposeModeCloudflared:
+			return append(checks, FieldCheck{Field: "domain", Status: CheckWarning,
+				Message: fmt.Sprintf("%s does not resolve yet: add it as a public hostname of your tunnel", host)})
+		default:
+			return append(checks, FieldCheck{Field: "domain", Status: CheckWarning,
+				Message: fmt.Sprintf("%s does not resolve: add it to your local DNS or hosts file", host)})
+		}
+	}
+
+	if cfg.Expose.Mode == config.ExposeModeDirect {
+		if ip, err := h.publicIP(ctx); err == nil && !slices.Contains(addrs, ip) {
+			return append(checks, FieldCheck{Field: "domain", Status: CheckWarning,
+				Message: fmt.Sprintf("%s resolves to %s, but this host's public IP is %s", host, strings.Join(addrs, ", "), ip)})
+		}
+	}
+	return append(checks, FieldCheck{Field: "domain", Status: CheckOK,
+		Message: fmt.Sprintf("%s resolves to %s", host, strings.Join(addrs, ", "))})
+}
+
+// webUIHost returns the host the web UI will be served on
+func (h *SetupHandler) webUIHost(ctx context.Context, cfg *config.Config) string {
+	if cfg.Routing.Strategy == config.RoutingStrategyPath {
+		return cfg.Routing.BaseDomain + "." + cfg.Domain
+	}
+	subdomain := "sdbx"
+	if h.registry != nil {
+		if def, _, err := h.registry.GetService(ctx, "sdbx-webui"); err == nil && def.Routing.Subdomain != "" {
+			subdomain = def.Routing.Subdomain
+		}
+	}
+	return subdomain + "." + cfg.Domain
+}
+
+// checkPaths checks each storage path, then that media and downloads share
+// a filesystem. Relative paths are relative to the project directory.
+func (h *SetupHandler) checkPaths(paths map[string]string, mounts map[string]string) []FieldCheck {
+	var checks []FieldCheck
+	resolved := make(map[string]string)
+	for _, field := range []string{"media_path", "downloads_path", "config_path"} {
+		path := strings.TrimSpace(paths[field])
+		if path == "" {
+			checks = append(checks, FieldCheck{Field: field, Status: CheckError, Message: "Path is required"})
+			continue
+		}
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(h.projectDir, path)
+		}
+		check := checkPath(field, path)
+		if check.Status != CheckError {
+			resolved[field] = path
+			if field == "config_path" {
+				if _, fsType := doctor.MountOf(mounts, path); slices.Contains(networkFilesystems, fsType) {
+					check = FieldCheck{Field: field, Status: CheckWarning,
+						Message: fmt.Sprintf("%s is on %s: service databases corrupt on network filesystems, use a local disk", path, fsType)}
+				}
+			}
+		}
+		checks = append(checks, check)
+	}
+
+	media, downloads := resolved["media_path"], resolved["downloads_path"]
+	if len(mounts) > 0 && media != "" && downloads != "" {
+		mediaMount, _ := doctor.MountOf(mounts, media)
+		downloadsMount, _ := doctor.MountOf(mounts, downloads)
+		if mediaMount != downloadsMount {
+			for i := range checks {
+				if checks[i].Field == "downloads_path" && checks[i].Status == CheckOK {
+					checks[i] = FieldCheck{Field: "downloads_path", Status: CheckWarning,
+						Message: fmt.Sprintf("Downloads (%s) and media (%s) are on different filesystems: imports are copied instead of hardlinked", downloadsMount, mediaMount)}
+				}
+			}
+		}
+	}
+	return checks
+}
+
+// checkPath checks that path is a writable directory, or that its closest
+// existing parent is so it can be created
+func checkPath(field, path string) FieldCheck {
+	info, err := os.Stat(path)
+	if err == nil {
+		if !info.IsDir() {
+			return FieldCheck{Field: field, Status: CheckError, Message: fmt.Sprintf("%s is a file, not a directory", path)}
+		}
+		if err := checkWritable(path); err != nil {
+			return FieldCheck{Field: field, Status: CheckError, Message: fmt.Sprintf("%s is not writable", path)}
+		}
+		return FieldCheck{Field: field, Status: CheckOK, Message: fmt.Sprintf("%s exists and is writable", path)}
+	}
+	if !os.IsNotExist(err) {
+		return FieldCheck{Field: field, Status: CheckError, Message: fmt.Sprintf("%s cannot be read", path)}
+	}
+
+	parent := filepath.Dir(path)
+	for {
+		info, err := os.Stat(parent)
+		if err == nil {
+			if !info.IsDir() {
+				return FieldCheck{Field: field, Status: CheckError, Message: fmt.Sprintf("%s is a file, not a directory", parent)}
+			}
+			break
+		}
+		if parent == filepath.Dir(parent) {
+			break
+		}
+		parent = filepath.Dir(parent)
+	}
+	if err := checkWritable(parent); err != nil {
+		return FieldCheck{Field: field, Status: CheckError, Message: fmt.Sprintf("%s does not exist and %s is not writable", path, parent)}
+	}
+	return FieldCheck{Field: field, Status: CheckOK, Message: fmt.Sprintf("%s will be created", path)}
+}
+
+// checkWritable creates and removes a file in dir
+func checkWritable(dir string) error {
+	f, err := os.CreateTemp(dir, ".sdbx-write-test-*")
+	if err != nil {
+		return err
+	}
+	f.Close()
+	return os.Remove(f.Name())
+}
+
+// checkTunnelToken checks a Cloudflare Tunnel token; none is a warning as
+// it can be added after setup
+func checkTunnelToken(token string) FieldCheck {
+	if strings.TrimSpace(token) == "" {
+		return FieldCheck{Field: "cloudflare_token", Status: CheckWarning,
+			Message: "No token: cloudflared will not connect until one is added to secrets/cloudflared_tunnel_token.txt"}
+	}
+	t, err := config.ParseTunnelToken(token)
+	if err != nil {
+		return FieldCheck{Field: "cloudflare_token", Status: CheckError, Message: capitalize(err.Error())}
+	}
+	return FieldCheck{Field: "cloudflare_token", Status: CheckOK,
+		Message: fmt.Sprintf("Tunnel %s of account %s", t.TunnelID, t.AccountTag)}
+}
+
+// checkVPNCredentials checks the credentials gluetun.env is generated with
+// for the provider and protocol. Missing credentials are warnings, as they
+// can be set later with 'sdbx vpn configure'.
+func checkVPNCredentials(cfg *config.Config) []FieldCheck {
+	provider, ok := config.GetVPNProvider(cfg.VPNProvider)
+	if !ok {
+		return []FieldCheck{{Field: "vpn_provider", Status: CheckError, Message: "Unknown VPN provider"}}
+	}
+	if provider.AuthType == config.VPNAuthConfig {
+		return []FieldCheck{{Field: "vpn_provider", Status: CheckWarning,
+			Message: "Custom providers are configured after setup in configs/gluetun/gluetun.env"}}
+	}
+
+	switch cfg.VPNType {
+	case "wireguard":
+		if !provider.SupportsWG {
+			return []FieldCheck{{Field: "vpn_type", Status: CheckError, Message: provider.Name + " does not support WireGuard"}}
+		}
+	case "openvpn":
+		if !provider.SupportsOpenVPN {
+			return []FieldCheck{{Field: "vpn_type", Status: CheckError, Message: provider.Name + " does not support OpenVPN"}}
+		}
+	default:
+		return []FieldCheck{{Field: "vpn_type", Status: CheckError, Message: "VPN type must be 'wireguard' or 'openvpn'"}}
+	}
+
+	missing := func(field, label string) FieldCheck {
+		return FieldCheck{Field: field, Status: CheckWarning,
+			Message: fmt.Sprintf("No %s: the VPN will not connect until you run 'sdbx vpn configure'", label)}
+	}
+	label := func(label, fallback string) string {
+		if label == "" {
+			return fallback
+		}
+		return label
+	}
+
+	var checks []FieldCheck
+	switch {
+	case cfg.VPNType == "wireguard":
+		switch {
+		case cfg.VPNWireguardKey == "":
+			checks = append(checks, missing("vpn_wireguard_key", "WireGuard private key"))
+		case !isWireguardKey(cfg.VPNWireguardKey):
+			checks = append(checks, FieldCheck{Field: "vpn_wireguard_key", Status: CheckError,
+				Message: "Not a WireGuard private key (44 base64 characters ending with '=')"})
+		default:
+			checks = append(checks, FieldCheck{Field: "vpn_wireguard_key", Status: CheckOK, Message: "Valid WireGuard key"})
+		}
+		if cfg.VPNWireguardAddr != "" {
+			if _, _, err := net.ParseCIDR(cfg.VPNWireguardAddr); err != nil {
+				checks = append(checks, FieldCheck{Field: "vpn_wireguard_addr", Status: CheckError,
+					Message: "Not an address with prefix (e.g. 10.64.0.2/32)"})
+			}
+		}
+	case provider.AuthType == config.VPNAuthToken:
+		tokenLabel := label(provider.TokenLabel, "account token")
+		switch {
+		case cfg.VPNToken == "":
+			checks = append(checks, missing("vpn_token", tokenLabel))
+		case provider.ID == "mullvad" && !mullvadAccountRegex.MatchString(strings.ReplaceAll(cfg.VPNToken, " ", "")):
+			checks = append(checks, FieldCheck{Field: "vpn_token", Status: CheckError, Message: "Mullvad account numbers have 16 digits"})
+		default:
+			checks = append(checks, FieldCheck{Field: "vpn_token", Status: CheckOK, Message: "Token set"})
+		}
+	default:
+		if cfg.VPNUsername == "" {
+			checks = append(checks, missing("vpn_username", label(provider.UsernameLabel, "username")))
+		} else if strings.Contains(cfg.VPNUsername, "@") {
+			checks = append(checks, FieldCheck{Field: "vpn_username", Status: CheckWarning,
+				Message: "This looks like your account email: most providers need the service (OpenVPN) username instead"})
+		}
+		if cfg.VPNPassword == "" {
+			checks = append(checks, missing("vpn_password", label(provider.PasswordLabel, "password")))
+		}
+		if cfg.VPNUsername != "" && cfg.VPNPassword != "" && len(checks) == 0 {
+			checks = append(checks, FieldCheck{Field: "vpn_username", Status: CheckOK, Message: "Credentials set"})
+		}
+	}
+	return checks
+}
+
+// isWireguardKey reports whether key is a base64 encoded 32-byte key
+func isWireguardKey(key string) bool {
+	data, err := base64.StdEncoding.DecodeString(key)
+	return err == nil && len(data) == 32
+}
+
+// capitalize uppercases the first letter of an error message shown as a
+// field message
+func capitalize(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + s[1:]
+}
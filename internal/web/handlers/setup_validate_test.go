@@ -0,0 +1,168 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/maiko/sdbx/internal/config"
+)
+
+// statusOf returns the status of the check of field, or "" without one
+func statusOf(checks []FieldCheck, field string) string {
+	for _, c := range checks {
+		if c.Field == field {
+			return c.Status
+		}
+	}
+	return ""
+}
+
+func TestCheckDomain(t *testing.T) {
+	lookupHost := func(_ context.Context, host string) ([]string, error) {
+		if host == "sdbx.box.example.com" || host == "media.box.example.com" {
+			return []string{"203.0.113.10"}, nil
+		}
+		return nil, errors.New("no such host")
+	}
+	h := &SetupHandler{
+		lookupHost: lookupHost,
+		publicIP:   func(context.Context) (string, error) { return "203.0.113.10", nil },
+	}
+
+	tests := []struct {
+		name     string
+		domain   string
+		mode     string
+		strategy string
+		base     string
+		want     string
+	}{
+		{"empty", "", config.ExposeModeDirect, config.RoutingStrategySubdomain, "", CheckError},
+		{"invalid", "not a domain", config.ExposeModeDirect, config.RoutingStrategySubdomain, "", CheckError},
+		{"direct resolves here", "box.example.com", config.ExposeModeDirect, config.RoutingStrategySubdomain, "", CheckOK},
+		{"direct unresolved", "other.example.com", config.ExposeModeDirect, config.RoutingStrategySubdomain, "", CheckError},
+		{"tunnel unresolved", "other.example.com", config.ExposeModeCloudflared, config.RoutingStrategySubdomain, "", CheckWarning},
+		{"lan unresolved", "other.example.com", config.ExposeModeLAN, config.RoutingStrategySubdomain, "", CheckWarning},
+		{"path routing", "box.example.com", config.ExposeModeDirect, config.RoutingStrategyPath, "media", CheckOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config.DefaultConfig()
+			cfg.Domain = tt.domain
+			cfg.Expose.Mode = tt.mode
+			cfg.Routing.Strategy = tt.strategy
+			cfg.Routing.BaseDomain = tt.base
+			if got := statusOf(h.checkDomain(context.Background(), cfg), "domain"); got != tt.want {
+				t.Errorf("domain check = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("direct resolves elsewhere", func(t *testing.T) {
+		h := &SetupHandler{
+			lookupHost: lookupHost,
+			publicIP:   func(context.Context) (string, error) { return "198.51.100.1", nil },
+		}
+		cfg := config.DefaultConfig()
+		cfg.Domain = "box.example.com"
+		cfg.Expose.Mode = config.ExposeModeDirect
+		cfg.Routing.Strategy = config.RoutingStrategySubdomain
+		if got := statusOf(h.checkDomain(context.Background(), cfg), "domain"); got != CheckWarning {
+			t.Errorf("domain check = %q, want %q", got, CheckWarning)
+		}
+	})
+}
+
+func TestCheckPaths(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "file")
+	if err := os.WriteFile(file, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	h := &SetupHandler{projectDir: dir}
+
+	checks := h.checkPaths(map[string]string{
+		"media_path":     "./data/media",
+		"downloads_path": dir,
+		"config_path":    file,
+	}, nil)
+	if got := statusOf(checks, "media_path"); got != CheckOK {
+		t.Errorf("missing path with writable parent = %q, want %q", got, CheckOK)
+	}
+	if got := statusOf(checks, "downloads_path"); got != CheckOK {
+		t.Errorf("writable directory = %q, want %q", got, CheckOK)
+	}
+	if got := statusOf(checks, "config_path"); got != CheckError {
+		t.Errorf("file = %q, want %q", got, CheckError)
+	}
+
+	checks = h.checkPaths(map[string]string{"media_path": " "}, nil)
+	if got := statusOf(checks, "media_path"); got != CheckError {
+		t.Errorf("empty path = %q, want %q", got, CheckError)
+	}
+
+	mounts := map[string]string{"/": "ext4", dir: "nfs4"}
+	checks = h.checkPaths(map[string]string{
+		"media_path":     dir,
+		"downloads_path": t.TempDir(),
+		"config_path":    filepath.Join(dir, "config"),
+	}, mounts)
+	if got := statusOf(checks, "config_path"); got != CheckWarning {
+		t.Errorf("config on nfs = %q, want %q", got, CheckWarning)
+	}
+	if got := statusOf(checks, "downloads_path"); got != CheckWarning {
+		t.Errorf("downloads on another filesystem = %q, want %q", got, CheckWarning)
+	}
+}
+
+func TestCheckVPNCredentials(t *testing.T) {
+	key := "yAnz5TF+lXXJte14tji3zlMNq+hd2rYUIgJBgB3fBmk="
+
+	tests := []struct {
+		name   string
+		modify func(cfg *config.Config)
+		field  string
+		want   string
+	}{
+		{"unknown provider", func(c *config.Config) { c.VPNProvider = "nope" }, "vpn_provider", CheckError},
+		{"custom", func(c *config.Config) { c.VPNProvider = "custom" }, "vpn_provider", CheckWarning},
+		{"wireguard key", func(c *config.Config) { c.VPNWireguardKey = key }, "vpn_wireguard_key", CheckOK},
+		{"bad wireguard key", func(c *config.Config) { c.VPNWireguardKey = "abc" }, "vpn_wireguard_key", CheckError},
+		{"no wireguard key", func(c *config.Config) {}, "vpn_wireguard_key", CheckWarning},
+		{"bad wireguard address", func(c *config.Config) { c.VPNWireguardKey = key; c.VPNWireguardAddr = "10.64.0.2" }, "vpn_wireguard_addr", CheckError},
+		{"mullvad token", func(c *config.Config) {
+			c.VPNProvider = "mullvad"
+			c.VPNType = "openvpn"
+			c.VPNToken = "1234 5678 9012 3456"
+		}, "vpn_token", CheckOK},
+		{"bad mullvad token", func(c *config.Config) { c.VPNProvider = "mullvad"; c.VPNType = "openvpn"; c.VPNToken = "1234" }, "vpn_token", CheckError},
+		{"openvpn credentials", func(c *config.Config) { c.VPNType = "openvpn"; c.VPNUsername = "user"; c.VPNPassword = "pass" }, "vpn_username", CheckOK},
+		{"openvpn no password", func(c *config.Config) { c.VPNType = "openvpn"; c.VPNUsername = "user" }, "vpn_password", CheckWarning},
+		{"bad type", func(c *config.Config) { c.VPNType = "ipsec" }, "vpn_type", CheckError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config.DefaultConfig()
+			cfg.VPNProvider = "nordvpn"
+			cfg.VPNType = "wireguard"
+			tt.modify(cfg)
+			if got := statusOf(checkVPNCredentials(cfg), tt.field); got != tt.want {
+				t.Errorf("%s check = %q, want %q", tt.field, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHandleValidateRequiresPost(t *testing.T) {
+	h := &SetupHandler{}
+	rec := httptest.NewRecorder()
+	h.HandleValidatePaths(rec, httptest.NewRequest(http.MethodGet, "/setup/validate/paths", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/maiko/sdbx/internal/auth"
+)
+
+// ShareHandler opens and checks the share links of 'sdbx share'. Traefik
+// sends it the link path of a shared service, and asks it (forwardAuth)
+// whether requests carrying a share cookie may reach the service.
+type ShareHandler struct {
+	shares *auth.ShareStore
+}
+
+// NewShareHandler creates a new share handler
+func NewShareHandler(projectDir string) *ShareHandler {
+	return &ShareHandler{shares: auth.NewShareStore(projectDir)}
+}
+
+// HandleOpen checks the token of a share link, stores it in a cookie on the
+// service's URL and redirects to the service
+func (h *ShareHandler) HandleOpen(w http.ResponseWriter, r *http.Request) {
+	service := r.PathValue("service")
+	share, err := h.shares.Verify(r.PathValue("token"), service, time.Now())
+	if err != nil {
+		http.Error(w, "This share link has expired or was revoked", http.StatusForbidden)
+		return
+	}
+
+	// Traefik keeps the path before replacePathRegex; the service's path
+	// prefix (path routing) is what precedes the share path
+	prefix, _, _ := strings.Cut(r.Header.Get("X-Replaced-Path"), auth.SharePath)
+	http.SetCookie(w, &http.Cookie{
+		Name:     share.CookieName(),
+		Value:    r.PathValue("token"),
+		Path:     prefix + "/",
+		Expires:  share.Expires,
+		HttpOnly: true,
+		Secure:   r.Header.Get("X-Forwarded-Proto") == "https",
+		SameSite: http.SameSiteLaxMode,
+	})
+	w.Header().Set("Referrer-Policy", "no-referrer")
+	http.Redirect(w, r, prefix+"/", http.StatusFound)
+}
+
+// HandleVerify answers Traefik's forwardAuth request for a shared service:
+// 200 when the share cookie holds a valid link to it
+func (h *ShareHandler) HandleVerify(w http.ResponseWriter, r *http.Request) {
+	service := r.PathValue("service")
+	cookie, err := r.Cookie(auth.ShareCookiePrefix + service)
+	if err == nil {
+		if _, err := h.shares.Verify(cookie.Value, service, time.Now()); err == nil {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+	}
+	http.Error(w, "This share link has expired or was revoked", http.StatusForbidden)
+}
@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/maiko/sdbx/internal/auth"
+)
+
+func TestShareHandler(t *testing.T) {
+	dir := t.TempDir()
+	token, share, err := auth.NewShareStore(dir).Create("overseerr", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := NewShareHandler(dir)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/share/open/{service}/{token}", h.HandleOpen)
+	mux.HandleFunc("/share/verify/{service}", h.HandleVerify)
+
+	// Opening the link sets the cookie under the service's path prefix
+	req := httptest.NewRequest(http.MethodGet, "/share/open/overseerr/"+token, nil)
+	req.Header.Set("X-Replaced-Path", "/overseerr"+auth.SharePath+token)
+	req.Header.Set("X-Forwarded-Proto", "https")
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+	if w.Code != http.StatusFound || w.Header().Get("Location") != "/overseerr/" {
+		t.Fatalf("open = %d %s, want a redirect to /overseerr/", w.Code, w.Header().Get("Location"))
+	}
+	cookies := w.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != share.CookieName() || cookies[0].Path != "/overseerr/" || !cookies[0].Secure || !cookies[0].HttpOnly {
+		t.Fatalf("cookies = %+v", cookies)
+	}
+
+	tests := []struct {
+		service string
+		cookie  *http.Cookie
+		want    int
+	}{
+		{"overseerr", cookies[0], http.StatusOK},
+		{"overseerr", nil, http.StatusForbidden},
+		{"sonarr", &http.Cookie{Name: auth.ShareCookiePrefix + "sonarr", Value: token}, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/share/verify/"+tt.service, nil)
+		if tt.cookie != nil {
+			req.AddCookie(tt.cookie)
+		}
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		if w.Code != tt.want {
+			t.Errorf("verify %s with %v = %d, want %d", tt.service, tt.cookie, w.Code, tt.want)
+		}
+	}
+
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/share/open/overseerr/"+strings.Replace(token, ".", "x", 1), nil))
+	if w.Code != http.StatusForbidden {
+		t.Errorf("open with a bad token = %d", w.Code)
+	}
+}
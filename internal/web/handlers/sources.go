@@ -6,6 +6,7 @@ import (
 	"html/template"
 	"net/http"
 	"regexp"
+	"strings"
 	"time"
 
 	"github.com/maiko/sdbx/internal/registry"
@@ -42,11 +43,12 @@ func NewSourcesHandler(reg *registry.Registry, tmpl *template.Template) *Sources
 // SourceDisplay represents a source for display in templates
 type SourceDisplay struct {
 	Name        string
-	Type        string // "embedded", "git", "local"
+	Type        string // "embedded", "git", "archive", "local"
 	URL         string
 	Priority    int
 	Enabled     bool
 	Branch      string
+	Ref         string // Tag or commit checked out instead of Branch
 	LastCommit  string
 	LastUpdated string
 }
@@ -75,6 +77,7 @@ func (h *SourcesHandler) HandleSourcesPage(w http.ResponseWriter, r *http.Reques
 		if gitSrc, ok := src.(*registry.GitSource); ok {
 			display.URL = gitSrc.GetURL()
 			display.Branch = gitSrc.GetBranch()
+			display.Ref = gitSrc.GetRef()
 			commit := gitSrc.GetCommit()
 			if len(commit) > 12 {
 				display.LastCommit = commit[:12]
@@ -86,6 +89,16 @@ func (h *SourcesHandler) HandleSourcesPage(w http.ResponseWriter, r *http.Reques
 				display.LastUpdated = lastUpdated.Format("2006-01-02 15:04:05")
 			}
 		}
+		if archive, ok := src.(*registry.ArchiveSource); ok {
+			display.URL = archive.GetURL()
+			display.LastCommit = strings.TrimPrefix(archive.GetCommit(), "sha256:")
+			if len(display.LastCommit) > 12 {
+				display.LastCommit = display.LastCommit[:12]
+			}
+			if lastUpdated := archive.GetLastUpdated(); !lastUpdated.IsZero() {
+				display.LastUpdated = lastUpdated.Format("2006-01-02 15:04:05")
+			}
+		}
 
 		displays = append(displays, display)
 	}
@@ -0,0 +1,55 @@
+package middleware
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/maiko/sdbx/internal/events"
+)
+
+// Audit middleware records every state-changing request in the events log,
+// with the user the auth middleware authenticated
+type Audit struct {
+	log *events.Log
+}
+
+// NewAudit creates a new audit middleware writing to an events log
+func NewAudit(log *events.Log) *Audit {
+	return &Audit{log: log}
+}
+
+// ChangesState reports whether requests of a method are recorded. Routes
+// changing state are only registered for these methods.
+func ChangesState(method string) bool {
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// Middleware records POST, PUT, PATCH and DELETE requests once served. It
+// must run inside Auth, which puts the user in the request context.
+func (a *Audit) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !ChangesState(r.Method) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		rw := &responseWriter{ResponseWriter: w, statusCode: http.StatusOK}
+		next.ServeHTTP(rw, r)
+
+		user, _ := r.Context().Value(UserContextKey).(string)
+		err := a.log.Record(events.Event{
+			Source: events.SourceWeb,
+			User:   user,
+			Action: r.Method + " " + r.URL.Path,
+			Status: rw.statusCode,
+		})
+		// The change is already made; a full disk must not turn it into an error
+		if err != nil {
+			log.Printf("Warning [audit]: %v", err)
+		}
+	})
+}
@@ -5,11 +5,29 @@ import (
 	"crypto/subtle"
 	"net"
 	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/maiko/sdbx/internal/auth"
 )
 
 const (
 	// setupTokenCookieMaxAge is how long the setup token cookie lasts (1 hour).
 	setupTokenCookieMaxAge = 3600
+
+	// SetupTokenLifetime is how long the setup token is accepted after the
+	// server starts. Restarting the server prints a new one.
+	SetupTokenLifetime = 24 * time.Hour
+
+	// maxSetupTokenFailures is how many wrong setup tokens an IP may send
+	// before it is locked out
+	maxSetupTokenFailures = 5
+	// setupTokenLockout is how long an IP stays locked out, and how long its
+	// failures are remembered
+	setupTokenLockout = 15 * time.Minute
 )
 
 // contextKey is a custom type for context keys to avoid collisions
@@ -18,22 +36,50 @@ type contextKey string
 // UserContextKey is the context key for storing the authenticated user
 const UserContextKey contextKey = "user"
 
+// apiPathPrefix is the only part of the web UI API tokens grant access to
+const apiPathPrefix = "/api/"
+
+// writeScopePaths are read-only API routes that need a write token anyway:
+// backup archives hold secrets/, so reading them exposes every credential
+var writeScopePaths = []string{"/api/backup/download/", "/api/backup/contents/"}
+
+// sharePathPrefix serves the share links, which carry their own signed token
+const sharePathPrefix = "/share/"
+
 // Auth middleware handles authentication based on deployment phase
 type Auth struct {
-	initialized bool
-	dockerMode  bool
-	setupToken  string
+	initialized       bool
+	dockerMode        bool
+	setupToken        string
+	setupTokenExpires time.Time
+
+	// Wrong setup tokens per IP, for lockout
+	failures   map[string]*setupFailures
+	failuresMu sync.Mutex
+
+	// Tokens, when set, accepts API tokens as "Authorization: Bearer" on
+	// /api/ routes after setup
+	Tokens *auth.TokenStore
 }
 
 // NewAuth creates a new auth middleware
 func NewAuth(initialized, dockerMode bool, setupToken string) *Auth {
 	return &Auth{
-		initialized: initialized,
-		dockerMode:  dockerMode,
-		setupToken:  setupToken,
+		initialized:       initialized,
+		dockerMode:        dockerMode,
+		setupToken:        setupToken,
+		setupTokenExpires: time.Now().Add(SetupTokenLifetime),
+		failures:          make(map[string]*setupFailures),
 	}
 }
 
+// setupFailures counts the wrong setup tokens sent from one IP
+type setupFailures struct {
+	count       int
+	last        time.Time
+	lockedUntil time.Time
+}
+
 // Middleware applies authentication logic
 func (a *Auth) Middleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -49,6 +95,18 @@ func (a *Auth) Middleware(next http.Handler) http.Handler {
 			return
 		}
 
+		// Share links and countries are checked by their handlers
+		if a.initialized && (strings.HasPrefix(r.URL.Path, sharePathPrefix) || r.URL.Path == countryVerifyPath) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		// API tokens authenticate scripts in every post-init mode
+		if secret, ok := bearerToken(r); ok && a.initialized && a.Tokens != nil {
+			a.serveToken(w, r, next, secret)
+			return
+		}
+
 		if !a.initialized {
 			// Pre-init: Require setup token
 			if !a.validateSetupToken(w, r) {
@@ -76,18 +134,69 @@ func (a *Auth) Middleware(next http.Handler) http.Handler {
 	})
 }
 
+// serveToken serves an API request authenticated by a token. Read tokens
+// are limited to safe methods, outside writeScopePaths.
+func (a *Auth) serveToken(w http.ResponseWriter, r *http.Request, next http.Handler, secret string) {
+	if !strings.HasPrefix(r.URL.Path, apiPathPrefix) {
+		http.Error(w, "API tokens are only accepted on "+apiPathPrefix, http.StatusForbidden)
+		return
+	}
+	token, err := a.Tokens.Verify(secret, time.Now())
+	if err != nil {
+		w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+		http.Error(w, "Invalid or expired API token", http.StatusUnauthorized)
+		return
+	}
+	if !token.AllowsMethod(r.Method) {
+		http.Error(w, "API token scope does not allow "+r.Method, http.StatusForbidden)
+		return
+	}
+	if token.Scope != auth.ScopeWrite && slices.ContainsFunc(writeScopePaths, func(prefix string) bool {
+		return strings.HasPrefix(r.URL.Path, prefix)
+	}) {
+		http.Error(w, "Backup archives hold secrets and need a write API token", http.StatusForbidden)
+		return
+	}
+
+	ctx := context.WithValue(r.Context(), UserContextKey, "token:"+token.Name)
+	next.ServeHTTP(w, r.WithContext(ctx))
+}
+
+// bearerToken returns the token of an "Authorization: Bearer" header
+func bearerToken(r *http.Request) (string, bool) {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") || token == "" {
+		return "", false
+	}
+	return token, true
+}
+
 // validateSetupToken validates the setup token from query param or cookie.
 // Returns true if the request should proceed, false if the response has been
-// written (either a redirect or an error).
+// written (either a redirect or an error). IPs sending too many wrong tokens
+// are locked out, and the token expires SetupTokenLifetime after startup.
 func (a *Auth) validateSetupToken(w http.ResponseWriter, r *http.Request) bool {
+	ip := extractIP(r)
+	if until, locked := a.lockedOut(ip); locked {
+		w.Header().Set("Retry-After", strconv.Itoa(int(time.Until(until).Seconds())+1))
+		http.Error(w, "Too many invalid setup tokens, try again later", http.StatusTooManyRequests)
+		return false
+	}
+	if !time.Now().Before(a.setupTokenExpires) {
+		http.Error(w, "Setup token expired, restart 'sdbx serve' for a new one", http.StatusUnauthorized)
+		return false
+	}
+
 	// Check query parameter first
 	queryToken := r.URL.Query().Get("token")
 	if queryToken != "" {
 		// Validate query token
 		if subtle.ConstantTimeCompare([]byte(queryToken), []byte(a.setupToken)) != 1 {
+			a.recordFailure(ip)
 			http.Error(w, "Invalid or missing setup token", http.StatusUnauthorized)
 			return false
 		}
+		a.resetFailures(ip)
 
 		// Set cookie and redirect to strip token from URL (prevents exposure
 		// in browser history, bookmarks, referrer headers, and server logs)
@@ -119,6 +228,7 @@ func (a *Auth) validateSetupToken(w http.ResponseWriter, r *http.Request) bool {
 
 	// Validate cookie token
 	if subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(a.setupToken)) != 1 {
+		a.recordFailure(ip)
 		http.Error(w, "Invalid or missing setup token", http.StatusUnauthorized)
 		return false
 	}
@@ -126,6 +236,61 @@ func (a *Auth) validateSetupToken(w http.ResponseWriter, r *http.Request) bool {
 	return true
 }
 
+// lockedOut reports whether an IP is locked out, and until when
+func (a *Auth) lockedOut(ip string) (time.Time, bool) {
+	a.failuresMu.Lock()
+	defer a.failuresMu.Unlock()
+
+	f, ok := a.failures[ip]
+	if !ok {
+		return time.Time{}, false
+	}
+	return f.lockedUntil, time.Now().Before(f.lockedUntil)
+}
+
+// recordFailure counts a wrong setup token from an IP, locking it out after
+// maxSetupTokenFailures within setupTokenLockout of each other
+func (a *Auth) recordFailure(ip string) {
+	a.failuresMu.Lock()
+	defer a.failuresMu.Unlock()
+
+	now := time.Now()
+	f, ok := a.failures[ip]
+	if !ok || now.Sub(f.last) > setupTokenLockout {
+		if len(a.failures) >= maxVisitors {
+			a.pruneFailures(now)
+			if len(a.failures) >= maxVisitors {
+				return // The rate limiter still applies
+			}
+		}
+		f = &setupFailures{}
+		a.failures[ip] = f
+	}
+	f.count++
+	f.last = now
+	if f.count >= maxSetupTokenFailures {
+		f.lockedUntil = now.Add(setupTokenLockout)
+		f.count = 0
+	}
+}
+
+// resetFailures forgets the failures of an IP once it sent the right token
+func (a *Auth) resetFailures(ip string) {
+	a.failuresMu.Lock()
+	defer a.failuresMu.Unlock()
+	delete(a.failures, ip)
+}
+
+// pruneFailures removes IPs neither locked out nor failing recently. The
+// caller holds failuresMu.
+func (a *Auth) pruneFailures(now time.Time) {
+	for ip, f := range a.failures {
+		if now.Sub(f.last) > setupTokenLockout && !now.Before(f.lockedUntil) {
+			delete(a.failures, ip)
+		}
+	}
+}
+
 // isHTTPS returns true if the request was made over HTTPS, either directly
 // (r.TLS != nil) or via a reverse proxy (X-Forwarded-Proto header).
 func isHTTPS(r *http.Request) bool {
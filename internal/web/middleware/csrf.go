@@ -41,6 +41,13 @@ func (c *CSRF) Middleware(next http.Handler) http.Handler {
 			return
 		}
 
+		// API token requests carry no ambient credentials to forge; the
+		// auth middleware rejects them unless the token is valid
+		if _, ok := bearerToken(r); ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+
 		// Validate CSRF token on state-changing methods
 		cookieToken, err := r.Cookie(csrfCookieName)
 		if err != nil || cookieToken.Value == "" {
@@ -10,6 +10,9 @@ import (
 	"time"
 
 	"golang.org/x/time/rate"
+
+	"github.com/maiko/sdbx/internal/auth"
+	"github.com/maiko/sdbx/internal/events"
 )
 
 // TestAuthPreInitValidTokenRedirects verifies that a valid token in query param
@@ -654,6 +657,78 @@ func TestNewRateLimiter(t *testing.T) {
 	}
 }
 
+// TestAuthAPITokens verifies API tokens are accepted on /api/ routes within
+// their scope, without an Authelia session
+func TestAuthAPITokens(t *testing.T) {
+	store := auth.NewTokenStore(t.TempDir())
+	readToken, _, err := store.Create("homepage", auth.ScopeRead, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeToken, _, err := store.Create("ci", auth.ScopeWrite, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mw := NewAuth(true, true, "")
+	mw.Tokens = store
+	handler := mw.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Context().Value(UserContextKey).(string)))
+	}))
+
+	tests := []struct {
+		name     string
+		method   string
+		path     string
+		token    string
+		wantCode int
+		wantUser string
+	}{
+		{"read token reads", http.MethodGet, "/api/services", readToken, http.StatusOK, "token:homepage"},
+		{"read token cannot write", http.MethodPost, "/api/services/sonarr/restart", readToken, http.StatusForbidden, ""},
+		{"write token writes", http.MethodPost, "/api/services/sonarr/restart", writeToken, http.StatusOK, "token:ci"},
+		{"unknown token", http.MethodGet, "/api/services", auth.TokenPrefix + "unknown", http.StatusUnauthorized, ""},
+		{"pages are not API", http.MethodGet, "/config", writeToken, http.StatusForbidden, ""},
+		{"read token cannot download backups", http.MethodGet, "/api/backup/download/sdbx-backup-2026-01-01.tar.gz", readToken, http.StatusForbidden, ""},
+		{"read token cannot list backup contents", http.MethodGet, "/api/backup/contents/sdbx-backup-2026-01-01.tar.gz", readToken, http.StatusForbidden, ""},
+		{"write token downloads backups", http.MethodGet, "/api/backup/download/sdbx-backup-2026-01-01.tar.gz", writeToken, http.StatusOK, "token:ci"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			req.RemoteAddr = "203.0.113.5:12345" // Tokens need no trusted proxy
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+			w := httptest.NewRecorder()
+
+			handler.ServeHTTP(w, req)
+
+			if w.Code != tt.wantCode {
+				t.Errorf("status = %d, want %d (%s)", w.Code, tt.wantCode, w.Body.String())
+			}
+			if tt.wantUser != "" && w.Body.String() != tt.wantUser {
+				t.Errorf("user = %q, want %q", w.Body.String(), tt.wantUser)
+			}
+		})
+	}
+}
+
+// TestCSRFSkipsBearerRequests verifies API token requests need no CSRF token
+func TestCSRFSkipsBearerRequests(t *testing.T) {
+	handler := NewCSRF().Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest(http.MethodPost, "/api/backup/create", nil)
+	req.Header.Set("Authorization", "Bearer sdbx_abc")
+	w := httptest.NewRecorder()
+
+	handler.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("expected status 200, got %d", w.Code)
+	}
+}
+
 // TestCSRFAllowsGETWithoutToken verifies GET requests pass without CSRF token
 func TestCSRFAllowsGETWithoutToken(t *testing.T) {
 	csrf := NewCSRF()
@@ -1222,3 +1297,85 @@ func TestExtractIP(t *testing.T) {
 		}
 	}
 }
+
+// TestAuditRecordsChanges verifies state-changing requests are recorded with
+// the authenticated user, and reads are not
+func TestAuditRecordsChanges(t *testing.T) {
+	eventLog := events.NewLog(t.TempDir())
+	mw := NewAuth(true, true, "")
+	handler := mw.Middleware(NewAudit(eventLog).Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+	})))
+
+	for _, method := range []string{http.MethodGet, http.MethodPost} {
+		req := httptest.NewRequest(method, "/api/backup/create", nil)
+		req.RemoteAddr = "172.18.0.5:12345"
+		req.Header.Set("Remote-User", "alice")
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	recorded, err := eventLog.Read(events.Filter{})
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+	if len(recorded) != 1 {
+		t.Fatalf("recorded %d events, want 1: %+v", len(recorded), recorded)
+	}
+	e := recorded[0]
+	if e.Source != events.SourceWeb || e.User != "alice" || e.Action != "POST /api/backup/create" || e.Status != http.StatusAccepted {
+		t.Errorf("recorded %+v", e)
+	}
+}
+
+// TestAuthPreInitLockout verifies an IP sending too many wrong setup tokens is
+// locked out, even with the right token, while other IPs are not
+func TestAuthPreInitLockout(t *testing.T) {
+	mw := NewAuth(false, false, "test-token-123")
+	handler := mw.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	request := func(ip, token string) int {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = ip + ":12345"
+		req.AddCookie(&http.Cookie{Name: "setup_token", Value: token})
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	for i := range maxSetupTokenFailures {
+		if code := request("203.0.113.7", "wrong"); code != http.StatusUnauthorized {
+			t.Fatalf("attempt %d: status = %d, want 401", i+1, code)
+		}
+	}
+	if code := request("203.0.113.7", "test-token-123"); code != http.StatusTooManyRequests {
+		t.Errorf("locked out IP with right token: status = %d, want 429", code)
+	}
+	if code := request("203.0.113.8", "test-token-123"); code != http.StatusOK {
+		t.Errorf("other IP: status = %d, want 200", code)
+	}
+
+	// The lockout ends
+	mw.failures["203.0.113.7"].lockedUntil = time.Now().Add(-time.Second)
+	if code := request("203.0.113.7", "test-token-123"); code != http.StatusOK {
+		t.Errorf("after lockout: status = %d, want 200", code)
+	}
+}
+
+// TestAuthPreInitTokenExpires verifies the setup token is refused once expired
+func TestAuthPreInitTokenExpires(t *testing.T) {
+	mw := NewAuth(false, false, "test-token-123")
+	mw.setupTokenExpires = time.Now().Add(-time.Minute)
+	handler := mw.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/?token=test-token-123", nil)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	if w.Code != http.StatusUnauthorized || !strings.Contains(w.Body.String(), "expired") {
+		t.Errorf("expired token: status = %d (%s), want 401 expired", w.Code, strings.TrimSpace(w.Body.String()))
+	}
+}
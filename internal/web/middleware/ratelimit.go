@@ -3,6 +3,7 @@ package middleware
 import (
 	"net"
 	"net/http"
+	"strings"
 	"sync"
 	"time"
 
@@ -16,6 +17,10 @@ const (
 	visitorStaleThreshold = 3 * time.Minute
 	// staticPathPrefix is the URL prefix for static assets (skipped by rate limiter).
 	staticPathPrefix = "/static/"
+	// shareVerifyPathPrefix is Traefik's forwardAuth check of share links (skipped by rate limiter).
+	shareVerifyPathPrefix = "/share/verify/"
+	// countryVerifyPath is Traefik's forwardAuth check of the country allowlists (skipped by rate limiter).
+	countryVerifyPath = "/country/verify"
 	// maxVisitors is the upper bound on tracked IPs to prevent memory exhaustion.
 	maxVisitors = 10000
 )
@@ -110,6 +115,13 @@ func (rl *RateLimiter) Middleware(next http.Handler) http.Handler {
 			return
 		}
 
+		// Traefik checks every request to a shared or country-restricted
+		// service, all from its own IP
+		if strings.HasPrefix(r.URL.Path, shareVerifyPathPrefix) || r.URL.Path == countryVerifyPath {
+			next.ServeHTTP(w, r)
+			return
+		}
+
 		ip := extractIP(r)
 		limiter := rl.getVisitor(ip)
 
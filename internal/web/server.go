@@ -6,19 +6,32 @@ import (
 	"encoding/base64"
 	"fmt"
 	"html/template"
+	"io"
 	"io/fs"
 	"log"
 	"net"
 	"net/http"
 	"os"
 	"os/signal"
-	"path/filepath"
 	"strings"
 	"syscall"
 	"time"
 
+	"github.com/maiko/sdbx/internal/alert"
+	"github.com/maiko/sdbx/internal/auth"
+	"github.com/maiko/sdbx/internal/backup"
+	"github.com/maiko/sdbx/internal/config"
+	"github.com/maiko/sdbx/internal/diskguard"
 	"github.com/maiko/sdbx/internal/docker"
+	"github.com/maiko/sdbx/internal/events"
+	"github.com/maiko/sdbx/internal/geoip"
+	"github.com/maiko/sdbx/internal/health"
+	"github.com/maiko/sdbx/internal/i18n"
 	"github.com/maiko/sdbx/internal/registry"
+	"github.com/maiko/sdbx/internal/scheduler"
+	"github.com/maiko/sdbx/internal/seeding"
+	"github.com/maiko/sdbx/internal/serverlog"
+	"github.com/maiko/sdbx/internal/statuspage"
 	"github.com/maiko/sdbx/internal/web/handlers"
 	"github.com/maiko/sdbx/internal/web/middleware"
 )
@@ -39,6 +52,9 @@ const (
 
 	// Maximum request body size (1 MB)
 	maxBodySize = 1 << 20
+
+	// backupUploadPath sets its own, larger body limit
+	backupUploadPath = "/api/backup/upload"
 )
 
 // contextKey is a private type for context keys in this package.
@@ -53,8 +69,13 @@ func IsDevMode(ctx context.Context) bool {
 }
 
 // maxBytesMiddleware limits the request body size for POST, PUT, and PATCH methods.
+// Backup uploads are limited by their handler instead.
 func maxBytesMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == backupUploadPath {
+			next.ServeHTTP(w, r)
+			return
+		}
 		switch r.Method {
 		case http.MethodPost, http.MethodPut, http.MethodPatch:
 			r.Body = http.MaxBytesReader(w, r.Body, maxBodySize)
@@ -73,6 +94,10 @@ type Server struct {
 	setupToken  string
 	initialized bool
 	dockerMode  bool
+
+	// Logs of the server and its jobs in logs/, set up once initialized
+	logs       []*serverlog.Writer
+	jobsLogger *log.Logger
 }
 
 // ServerConfig holds server configuration
@@ -106,6 +131,32 @@ func (s *Server) Start(ctx context.Context) error {
 		return fmt.Errorf("failed to initialize dependencies: %w", err)
 	}
 
+	if s.initialized {
+		s.openLogs()
+	}
+
+	// Record health history, evaluate alerts, enforce seeding rules, guard
+	// disk space, take scheduled backups, refresh the GeoIP databases and
+	// render the status page when running as the sdbx-webui service
+	if s.initialized && s.dockerMode {
+		monitor := health.NewMonitor(s.config.ProjectDir)
+		seedingInterval, guardInterval := config.DefaultSeedingInterval, config.DefaultDiskGuardInterval
+		if cfg, err := config.Load(); err == nil {
+			seedingInterval, guardInterval = cfg.Seeding.EnforceInterval(), cfg.DiskGuard.CheckInterval()
+		}
+		jobs := scheduler.New(
+			monitor.Job(),
+			alert.Job(s.config.ProjectDir, monitor.Interval),
+			seeding.Job(s.config.ProjectDir, seedingInterval, true),
+			diskguard.Job(s.config.ProjectDir, guardInterval, true),
+			backup.Job(s.config.ProjectDir),
+			geoip.Job(s.config.ProjectDir),
+			statuspage.Job(s.config.ProjectDir),
+		)
+		jobs.Logger = s.jobsLogger
+		go jobs.Run(ctx)
+	}
+
 	// Setup routes
 	mux := http.NewServeMux()
 	s.setupRoutes(ctx, mux)
@@ -142,12 +193,39 @@ func (s *Server) Start(ctx context.Context) error {
 	}
 }
 
+// openLogs copies the standard logger to logs/server.log and sends job
+// logs to logs/jobs.log. The server runs without them when they cannot be
+// opened.
+func (s *Server) openLogs() {
+	var retention config.ServerLogsConfig
+	if cfg, err := config.Load(); err == nil {
+		retention = cfg.ServerLogs
+	}
+	server, err := serverlog.Open(s.config.ProjectDir, serverlog.Server, retention)
+	if err != nil {
+		log.Printf("Warning [server.Logs]: %v", err)
+		return
+	}
+	jobs, err := serverlog.Open(s.config.ProjectDir, serverlog.Jobs, retention)
+	if err != nil {
+		_ = server.Close()
+		log.Printf("Warning [server.Logs]: %v", err)
+		return
+	}
+	s.logs = []*serverlog.Writer{server, jobs}
+	log.SetOutput(io.MultiWriter(os.Stderr, server))
+	s.jobsLogger = log.New(io.MultiWriter(os.Stderr, jobs), "", log.LstdFlags)
+}
+
 // checkPhase determines the deployment phase and generates setup token if needed
 func (s *Server) checkPhase() error {
-	// Check for .sdbx.yaml existence
-	configPath := filepath.Join(s.config.ProjectDir, ".sdbx.yaml")
-	_, err := os.Stat(configPath)
-	s.initialized = err == nil
+	// A project whose first generation failed is set up again with the
+	// wizard; a failed regeneration is repaired from the dashboard
+	state, err := config.ReadState(s.config.ProjectDir)
+	if err != nil {
+		return err
+	}
+	s.initialized = state.Initialized()
 
 	// Check if running in Docker
 	s.dockerMode = os.Getenv("SDBX_MODE") == "server"
@@ -200,11 +278,12 @@ func (s *Server) formatServerMessage() string {
 		if s.config.Host == "0.0.0.0" {
 			msg += "Setup wizard available at:\n"
 			msg += fmt.Sprintf("  http://%s:%d?token=%s\n\n", host, s.config.Port, s.setupToken)
-			msg += "Token expires after setup completion or server restart.\n"
+			msg += fmt.Sprintf("Token expires after setup completion, server restart or %.0f hours.\n", middleware.SetupTokenLifetime.Hours())
 			msg += "Access this URL from any device on your network.\n\n"
 		} else {
 			msg += "Setup wizard available at:\n"
 			msg += fmt.Sprintf("  http://%s:%d?token=%s\n\n", s.config.Host, s.config.Port, s.setupToken)
+			msg += fmt.Sprintf("Token expires after setup completion, server restart or %.0f hours.\n\n", middleware.SetupTokenLifetime.Hours())
 		}
 	} else {
 		// Post-init mode
@@ -220,16 +299,23 @@ func (s *Server) formatServerMessage() string {
 	return msg
 }
 
-// loadTemplates loads and parses all HTML templates
-func (s *Server) loadTemplates() error {
-	// Create template with custom functions
-	funcMap := template.FuncMap{
+// templateFuncs returns the custom functions of the HTML templates.
+// {{t "message"}} translates a message into the language of the server
+// (--lang, SDBX_LANG or the locale), and {{lang}} returns that language.
+func templateFuncs() template.FuncMap {
+	return template.FuncMap{
 		"sub": func(a, b int) int {
 			return a - b
 		},
+		"t":    i18n.T,
+		"lang": i18n.Language,
 	}
+}
 
-	tmpl := template.New("").Funcs(funcMap)
+// loadTemplates loads and parses all HTML templates
+func (s *Server) loadTemplates() error {
+	// Create template with custom functions
+	tmpl := template.New("").Funcs(templateFuncs())
 
 	// Walk the embedded filesystem to find all .html files
 	// Note: ParseFS with "**" glob doesn't work in Go - must walk manually
@@ -277,6 +363,7 @@ func (s *Server) initializeDependencies() error {
 	// Initialize Docker Compose (only if initialized)
 	if s.initialized {
 		s.compose = docker.NewCompose(s.config.ProjectDir)
+		s.compose.Offline = registry.Offline()
 	}
 
 	return nil
@@ -303,20 +390,32 @@ func (s *Server) setupRoutes(ctx context.Context, mux *http.ServeMux) {
 		mux.HandleFunc("/setup/addons", setupHandler.HandleAddons)
 		mux.HandleFunc("/setup/summary", setupHandler.HandleSummary)
 		mux.HandleFunc("/setup/complete", setupHandler.HandleComplete)
+		mux.HandleFunc("/setup/validate/domain", setupHandler.HandleValidateDomain)
+		mux.HandleFunc("/setup/validate/paths", setupHandler.HandleValidatePaths)
+		mux.HandleFunc("/setup/validate/cloudflare", setupHandler.HandleValidateCloudflare)
+		mux.HandleFunc("/setup/validate/vpn", setupHandler.HandleValidateVPN)
 	} else {
 		// Post-init routes: Dashboard and management
-		dashboardHandler := handlers.NewDashboardHandler(s.compose, s.registry, s.templates)
+		dashboardHandler := handlers.NewDashboardHandler(s.compose, s.registry, s.config.ProjectDir, s.templates)
 		servicesHandler := handlers.NewServicesHandler(s.compose, s.registry, s.templates)
 		logsHandler := handlers.NewLogsHandler(s.compose, s.registry, s.templates)
-		addonsHandler := handlers.NewAddonsHandler(s.registry, s.config.ProjectDir, s.templates)
-		configHandler := handlers.NewConfigHandler(s.config.ProjectDir, s.templates)
-		backupHandler := handlers.NewBackupHandler(s.config.ProjectDir, s.templates)
+		logsHandler.SetServerLogs(s.config.ProjectDir, s.logs...)
+		jobsHandler := handlers.NewJobsHandler(ctx)
+		jobsHandler.SetLogger(s.jobsLogger)
+		addonsHandler := handlers.NewAddonsHandler(s.registry, s.compose, jobsHandler, s.config.ProjectDir, s.templates)
+		configHandler := handlers.NewConfigHandler(s.registry, s.compose, jobsHandler, s.config.ProjectDir, s.templates)
+		backupHandler := handlers.NewBackupHandler(s.config.ProjectDir, jobsHandler, s.templates)
 		serviceInfoHandler := handlers.NewServiceInfoHandler(s.registry, s.templates)
 		doctorHandler := handlers.NewDoctorHandler(s.config.ProjectDir, s.templates)
 		vpnHandler := handlers.NewVPNHandler(s.config.ProjectDir, s.templates)
 		sourcesHandler := handlers.NewSourcesHandler(s.registry, s.templates)
 		lockHandler := handlers.NewLockHandler(s.registry, s.config.ProjectDir, s.templates)
 		composeHandler := handlers.NewComposeHandler(s.config.ProjectDir, s.templates)
+		historyHandler := handlers.NewHistoryHandler(s.config.ProjectDir, s.templates)
+		projectHandler := handlers.NewProjectHandler(s.registry, jobsHandler, s.config.ProjectDir)
+		shareHandler := handlers.NewShareHandler(s.config.ProjectDir)
+		countryHandler := handlers.NewCountryHandler(s.config.ProjectDir)
+		agentsHandler := handlers.NewAgentsHandler(s.templates)
 
 		// Pages
 		mux.HandleFunc("/", dashboardHandler.HandleDashboard)
@@ -325,6 +424,8 @@ func (s *Server) setupRoutes(ctx context.Context, mux *http.ServeMux) {
 		mux.HandleFunc("/service-info", serviceInfoHandler.HandleServiceInfoPage)
 		mux.HandleFunc("/logs/{service}", logsHandler.HandleLogsPage)
 		mux.HandleFunc("/addons", addonsHandler.HandleAddonsPage)
+		mux.HandleFunc("/addons/{addon}", addonsHandler.HandleAddonDetailPage)
+		mux.HandleFunc("/addons/{addon}/assets/{file...}", addonsHandler.HandleAddonAsset)
 		mux.HandleFunc("/config", configHandler.HandleConfigPage)
 		mux.HandleFunc("/backup", backupHandler.HandleBackupPage)
 		mux.HandleFunc("/doctor", doctorHandler.HandleDoctorPage)
@@ -332,48 +433,109 @@ func (s *Server) setupRoutes(ctx context.Context, mux *http.ServeMux) {
 		mux.HandleFunc("/sources", sourcesHandler.HandleSourcesPage)
 		mux.HandleFunc("/lock", lockHandler.HandleLockPage)
 		mux.HandleFunc("/compose", composeHandler.HandleComposePage)
+		mux.HandleFunc("/history", historyHandler.HandleHistoryPage)
+		mux.HandleFunc("/agents", agentsHandler.HandleAgentsPage)
 
-		// API endpoints
+		// API endpoints. Routes changing state only accept POST (or DELETE),
+		// so read tokens, limited to GET, cannot reach them
 		mux.HandleFunc("/api/services", servicesHandler.HandleGetServices)
-		mux.HandleFunc("/api/services/{service}/start", servicesHandler.HandleStartService)
-		mux.HandleFunc("/api/services/{service}/stop", servicesHandler.HandleStopService)
-		mux.HandleFunc("/api/services/{service}/restart", servicesHandler.HandleRestartService)
+		mux.HandleFunc("POST /api/services/{service}/start", servicesHandler.HandleStartService)
+		mux.HandleFunc("POST /api/services/{service}/stop", servicesHandler.HandleStopService)
+		mux.HandleFunc("POST /api/services/{service}/restart", servicesHandler.HandleRestartService)
 
 		// Log endpoints
 		mux.HandleFunc("/api/logs/{service}", logsHandler.HandleGetLogs)
 		mux.HandleFunc("/api/logs/{service}/stream", logsHandler.HandleLogStream)
+		mux.HandleFunc("/api/logs/{service}/download", logsHandler.HandleDownloadLogs)
+		mux.HandleFunc("GET /api/server-logs/settings", logsHandler.HandleServerLogSettings)
+		mux.HandleFunc("POST /api/server-logs/settings", logsHandler.HandleServerLogSettings)
+		mux.HandleFunc("/api/server-logs/{name}/download", logsHandler.HandleDownloadServerLog)
 
 		// Addon endpoints
 		mux.HandleFunc("/api/addons/search", addonsHandler.HandleSearchAddons)
-		mux.HandleFunc("/api/addons/{addon}/enable", addonsHandler.HandleEnableAddon)
-		mux.HandleFunc("/api/addons/{addon}/disable", addonsHandler.HandleDisableAddon)
+		mux.HandleFunc("POST /api/addons/{addon}/enable", addonsHandler.HandleEnableAddon)
+		mux.HandleFunc("POST /api/addons/{addon}/install", addonsHandler.HandleInstallAddon)
+		mux.HandleFunc("POST /api/addons/{addon}/disable", addonsHandler.HandleDisableAddon)
+
+		// Job endpoints
+		mux.HandleFunc("/api/jobs/{id}", jobsHandler.HandleGetJob)
+
+		// Post-install checklist endpoints
+		mux.HandleFunc("POST /api/checklist/{service}/{step}/done", dashboardHandler.HandleChecklistDone)
 
 		// Config endpoints
 		mux.HandleFunc("/api/config", configHandler.HandleGetConfig)
-		mux.HandleFunc("/api/config/validate", configHandler.HandleValidateConfig)
-		mux.HandleFunc("/api/config/save", configHandler.HandleSaveConfig)
+		mux.HandleFunc("POST /api/config/validate", configHandler.HandleValidateConfig)
+		mux.HandleFunc("POST /api/config/save", configHandler.HandleSaveConfig)
 
 		// Backup endpoints
 		mux.HandleFunc("/api/backup/list", backupHandler.HandleListBackups)
-		mux.HandleFunc("/api/backup/create", backupHandler.HandleCreateBackup)
-		mux.HandleFunc("/api/backup/restore/{name}", backupHandler.HandleRestoreBackup)
-		mux.HandleFunc("/api/backup/delete/{name}", backupHandler.HandleDeleteBackup)
+		mux.HandleFunc("POST /api/backup/create", backupHandler.HandleCreateBackup)
+		mux.HandleFunc("POST /api/backup/restore/{name}", backupHandler.HandleRestoreBackup)
+		mux.HandleFunc("DELETE /api/backup/delete/{name}", backupHandler.HandleDeleteBackup)
+		mux.HandleFunc("/api/backup/contents/{name}", backupHandler.HandleBackupContents)
+		mux.HandleFunc("/api/backup/download/{name}", backupHandler.HandleDownloadBackup)
+		mux.HandleFunc("POST /api/backup/push/{name}", backupHandler.HandlePushBackup)
+		mux.HandleFunc("POST "+backupUploadPath, backupHandler.HandleUploadBackup)
+		mux.HandleFunc("POST /api/backup/settings", backupHandler.HandleSaveSettings)
 
 		// Doctor endpoints
-		mux.HandleFunc("/api/doctor/run", doctorHandler.HandleRunChecks)
+		mux.HandleFunc("POST /api/doctor/run", doctorHandler.HandleRunChecks)
 
 		// VPN endpoints
 		mux.HandleFunc("/api/vpn/providers", vpnHandler.HandleVPNProviders)
-		mux.HandleFunc("/api/vpn/configure", vpnHandler.HandleVPNConfigure)
+		mux.HandleFunc("POST /api/vpn/configure", vpnHandler.HandleVPNConfigure)
 
 		// Source endpoints
-		mux.HandleFunc("/api/sources/add", sourcesHandler.HandleAddSource)
-		mux.HandleFunc("/api/sources/{source}/remove", sourcesHandler.HandleRemoveSource)
-		mux.HandleFunc("/api/sources/{source}/update", sourcesHandler.HandleUpdateSource)
-		mux.HandleFunc("/api/sources/update-all", sourcesHandler.HandleUpdateAllSources)
+		mux.HandleFunc("POST /api/sources/add", sourcesHandler.HandleAddSource)
+		mux.HandleFunc("POST /api/sources/{source}/remove", sourcesHandler.HandleRemoveSource)
+		mux.HandleFunc("POST /api/sources/{source}/update", sourcesHandler.HandleUpdateSource)
+		mux.HandleFunc("POST /api/sources/update-all", sourcesHandler.HandleUpdateAllSources)
 
 		// Lock endpoints
-		mux.HandleFunc("/api/lock/verify", lockHandler.HandleLockVerify)
+		mux.HandleFunc("POST /api/lock/verify", lockHandler.HandleLockVerify)
+
+		// History endpoints
+		mux.HandleFunc("/api/history", historyHandler.HandleGetHistory)
+
+		// Agent endpoints (control plane)
+		mux.HandleFunc("/api/agents", agentsHandler.HandleGetAgents)
+		mux.HandleFunc("POST /api/agents/{agent}/compose/{action}", agentsHandler.HandleAgentCompose)
+
+		// Project state endpoints
+		mux.HandleFunc("/api/project/state", projectHandler.HandleGetState)
+		mux.HandleFunc("POST /api/project/repair", projectHandler.HandleRepair)
+
+		// Share links, reached through Traefik without Authelia
+		mux.HandleFunc("/share/open/{service}/{token}", shareHandler.HandleOpen)
+		mux.HandleFunc("/share/verify/{service}", shareHandler.HandleVerify)
+
+		// Country allowlists, checked by Traefik for every request they cover
+		mux.HandleFunc("GET /country/verify", countryHandler.HandleVerify)
+
+		// Unknown API paths and methods, instead of the dashboard of "/"
+		mux.HandleFunc("/api/", apiFallback(mux))
+	}
+}
+
+// apiFallback answers API requests no route takes: 405 when the path is
+// served for another method, 404 otherwise
+func apiFallback(mux *http.ServeMux) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var allowed []string
+		for _, method := range []string{http.MethodPost, http.MethodDelete} {
+			probe := r.Clone(r.Context())
+			probe.Method = method
+			if _, pattern := mux.Handler(probe); strings.HasPrefix(pattern, method+" ") {
+				allowed = append(allowed, method)
+			}
+		}
+		if len(allowed) == 0 {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Allow", strings.Join(allowed, ", "))
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 	}
 }
 
@@ -394,8 +556,14 @@ func (s *Server) applyMiddleware(handler http.Handler) http.Handler {
 		handler = devModeMiddleware(handler)
 	}
 
+	// Audit middleware (inside auth, which identifies the user)
+	if s.initialized {
+		handler = middleware.NewAudit(events.NewLog(s.config.ProjectDir)).Middleware(handler)
+	}
+
 	// Auth middleware (based on phase)
 	authMiddleware := middleware.NewAuth(s.initialized, s.dockerMode, s.setupToken)
+	authMiddleware.Tokens = auth.NewTokenStore(s.config.ProjectDir)
 	handler = authMiddleware.Middleware(handler)
 
 	// CSRF middleware (after auth, before rate limiting)
@@ -443,6 +611,11 @@ func (s *Server) Shutdown(timeout time.Duration) error {
 		return fmt.Errorf("server shutdown failed: %w", err)
 	}
 
+	log.SetOutput(os.Stderr)
+	for _, w := range s.logs {
+		_ = w.Close()
+	}
+
 	fmt.Println("Server stopped gracefully")
 	return nil
 }
@@ -2,6 +2,8 @@ package web
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"html/template"
 	"io/fs"
 	"net/http"
@@ -10,18 +12,18 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+
+	"github.com/maiko/sdbx/internal/auth"
+	"github.com/maiko/sdbx/internal/config"
+	"github.com/maiko/sdbx/internal/events"
+	"github.com/maiko/sdbx/internal/i18n"
+	"github.com/maiko/sdbx/internal/web/middleware"
 )
 
 // TestTemplateLoading verifies that all templates are loaded correctly
 func TestTemplateLoading(t *testing.T) {
 	// Load templates using the same method as server
-	funcMap := template.FuncMap{
-		"sub": func(a, b int) int {
-			return a - b
-		},
-	}
-
-	tmpl, err := loadAllTemplates(funcMap)
+	tmpl, err := loadAllTemplates(templateFuncs())
 	if err != nil {
 		t.Fatalf("failed to load templates: %v", err)
 	}
@@ -54,6 +56,7 @@ func TestTemplateLoading(t *testing.T) {
 		"pages/sources.html",
 		"pages/lock.html",
 		"pages/compose.html",
+		"pages/agents.html",
 	}
 
 	for _, name := range requiredTemplates {
@@ -285,6 +288,29 @@ func TestPhaseDetectionPostInit(t *testing.T) {
 	}
 }
 
+// TestPhaseDetectionFailedGeneration verifies a project whose first
+// generation failed goes back to the setup wizard, even with a .sdbx.yaml
+func TestPhaseDetectionFailedGeneration(t *testing.T) {
+	tmpDir := t.TempDir()
+	if err := config.WriteState(tmpDir, config.StateGenerating, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(tmpDir, ".sdbx.yaml"), []byte("domain: test.local"), 0o644); err != nil {
+		t.Fatalf("failed to create config file: %v", err)
+	}
+	if err := config.WriteState(tmpDir, config.StateDegraded, errors.New("failed to write .gitignore")); err != nil {
+		t.Fatal(err)
+	}
+
+	server := NewServer(&ServerConfig{Host: "localhost", Port: 3000, ProjectDir: tmpDir})
+	if err := server.checkPhase(); err != nil {
+		t.Fatalf("checkPhase failed: %v", err)
+	}
+	if server.initialized || server.setupToken == "" {
+		t.Errorf("initialized = %v, setupToken set = %v; want the setup wizard", server.initialized, server.setupToken != "")
+	}
+}
+
 // TestFormatServerMessagePreInit verifies pre-init startup message
 func TestFormatServerMessagePreInit(t *testing.T) {
 	server := NewServer(&ServerConfig{
@@ -453,6 +479,16 @@ func TestMaxBytesMiddleware(t *testing.T) {
 			t.Errorf("oversized PUT should be rejected, got status %d", w.Code)
 		}
 	})
+
+	t.Run("Backup uploads are limited by their handler", func(t *testing.T) {
+		body := strings.NewReader(strings.Repeat("a", (1<<20)+1))
+		req := httptest.NewRequest(http.MethodPost, backupUploadPath, body)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Errorf("backup upload should not be body-limited, got status %d", w.Code)
+		}
+	})
 }
 
 // TestIsDevMode verifies the dev mode context detection
@@ -499,11 +535,7 @@ func TestDevModeMiddleware(t *testing.T) {
 
 // TestTemplateFuncMap verifies template functions work
 func TestTemplateFuncMap(t *testing.T) {
-	funcMap := template.FuncMap{
-		"sub": func(a, b int) int {
-			return a - b
-		},
-	}
+	funcMap := templateFuncs()
 
 	// Test the sub function
 	subFunc := funcMap["sub"].(func(int, int) int)
@@ -511,4 +543,184 @@ func TestTemplateFuncMap(t *testing.T) {
 	if result != 7 {
 		t.Errorf("sub(10, 3) = %d, expected 7", result)
 	}
+
+	// Test the translation functions in the server language
+	if err := i18n.SetLanguage("fr"); err != nil {
+		t.Fatal(err)
+	}
+	defer i18n.SetLanguage(i18n.DefaultLanguage)
+	tmpl := template.Must(template.New("").Funcs(funcMap).Parse(`<html lang="{{lang}}">{{t "Dashboard"}}`))
+	var buf strings.Builder
+	if err := tmpl.Execute(&buf, nil); err != nil {
+		t.Fatal(err)
+	}
+	if got := buf.String(); got != `<html lang="fr">Tableau de bord` {
+		t.Errorf("rendered %q, want the French dashboard title", got)
+	}
+}
+
+// mutatingRoutes are the API routes changing state, with their method
+var mutatingRoutes = []struct{ method, path string }{
+	{http.MethodPost, "/api/services/sonarr/start"},
+	{http.MethodPost, "/api/services/sonarr/stop"},
+	{http.MethodPost, "/api/services/sonarr/restart"},
+	{http.MethodPost, "/api/server-logs/settings"},
+	{http.MethodPost, "/api/addons/sonarr/enable"},
+	{http.MethodPost, "/api/addons/sonarr/install"},
+	{http.MethodPost, "/api/addons/sonarr/disable"},
+	{http.MethodPost, "/api/checklist/plex/claim/done"},
+	{http.MethodPost, "/api/config/save"},
+	{http.MethodPost, "/api/backup/create"},
+	{http.MethodPost, "/api/backup/restore/sdbx-backup-test.tar.gz"},
+	{http.MethodDelete, "/api/backup/delete/sdbx-backup-test.tar.gz"},
+	{http.MethodPost, "/api/backup/push/sdbx-backup-test.tar.gz"},
+	{http.MethodPost, backupUploadPath},
+	{http.MethodPost, "/api/backup/settings"},
+	{http.MethodPost, "/api/doctor/run"},
+	{http.MethodPost, "/api/vpn/configure"},
+	{http.MethodPost, "/api/sources/add"},
+	{http.MethodPost, "/api/sources/official/remove"},
+	{http.MethodPost, "/api/sources/official/update"},
+	{http.MethodPost, "/api/sources/update-all"},
+	{http.MethodPost, "/api/lock/verify"},
+	{http.MethodPost, "/api/agents/box/compose/restart"},
+	{http.MethodPost, "/api/project/repair"},
+}
+
+// newTestHandler serves the web UI of an initialized project, returning it
+// with a read and a write API token
+func newTestHandler(t *testing.T) (http.Handler, string, string, string) {
+	t.Helper()
+	projectDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(projectDir, ".sdbx.yaml"), []byte("domain: test.local\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	tokens := auth.NewTokenStore(projectDir)
+	read, _, err := tokens.Create("homepage", auth.ScopeRead, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	write, _, err := tokens.Create("ci", auth.ScopeWrite, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := NewServer(&ServerConfig{Host: "localhost", Port: 3000, ProjectDir: projectDir})
+	if err := server.checkPhase(); err != nil {
+		t.Fatal(err)
+	}
+	if err := server.loadTemplates(); err != nil {
+		t.Fatal(err)
+	}
+	if err := server.initializeDependencies(); err != nil {
+		t.Fatal(err)
+	}
+	mux := http.NewServeMux()
+	server.setupRoutes(t.Context(), mux)
+	return server.applyMiddleware(mux), projectDir, read, write
+}
+
+// serveToken sends a request with an API token, from its own address so
+// the rate limiter does not interfere
+func serveToken(handler http.Handler, i int, method, path, token string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	req.RemoteAddr = fmt.Sprintf("203.0.113.%d:12345", i%250+1)
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	return w
+}
+
+// TestMutatingRoutesRefuseGET verifies read tokens cannot change state by
+// sending GET to routes that change it
+func TestMutatingRoutesRefuseGET(t *testing.T) {
+	handler, projectDir, read, _ := newTestHandler(t)
+	archive := filepath.Join(projectDir, "backups", "sdbx-backup-test.tar.gz")
+	if err := os.MkdirAll(filepath.Dir(archive), 0o750); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(archive, []byte("archive"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	for i, route := range mutatingRoutes {
+		w := serveToken(handler, i, http.MethodGet, route.path, read)
+		if route.path == "/api/server-logs/settings" {
+			if w.Code != http.StatusOK {
+				t.Errorf("GET %s = %d, want its settings", route.path, w.Code)
+			}
+			continue
+		}
+		if w.Code != http.StatusMethodNotAllowed || w.Header().Get("Allow") != route.method {
+			t.Errorf("GET %s = %d (Allow %q), want 405 allowing %s", route.path, w.Code, w.Header().Get("Allow"), route.method)
+		}
+	}
+	if _, err := os.Stat(archive); err != nil {
+		t.Errorf("backup archive gone after GET requests: %v", err)
+	}
+	if w := serveToken(handler, 0, http.MethodGet, "/api/no-such-route", read); w.Code != http.StatusNotFound {
+		t.Errorf("GET of an unknown API route = %d, want 404", w.Code)
+	}
+}
+
+// TestMutatingRoutesAreAudited verifies every route changing state is
+// recorded in the events log, and refused requests change nothing
+func TestMutatingRoutesAreAudited(t *testing.T) {
+	for _, route := range mutatingRoutes {
+		if !middleware.ChangesState(route.method) {
+			t.Errorf("%s %s is not audited", route.method, route.path)
+		}
+	}
+
+	handler, projectDir, read, write := newTestHandler(t)
+	archive := filepath.Join(projectDir, "backups", "sdbx-backup-test.tar.gz")
+	if err := os.MkdirAll(filepath.Dir(archive), 0o750); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(archive, []byte("archive"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	serveToken(handler, 1, http.MethodGet, "/api/backup/delete/sdbx-backup-test.tar.gz", read)
+	serveToken(handler, 2, http.MethodDelete, "/api/backup/delete/sdbx-backup-test.tar.gz", read)
+	if w := serveToken(handler, 3, http.MethodDelete, "/api/backup/delete/sdbx-backup-test.tar.gz", write); w.Code != http.StatusOK {
+		t.Fatalf("DELETE with a write token = %d: %s", w.Code, w.Body.String())
+	}
+	if _, err := os.Stat(archive); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("archive not deleted: %v", err)
+	}
+
+	recorded, err := events.NewLog(projectDir).Read(events.Filter{Source: events.SourceWeb})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(recorded) != 1 || recorded[0].User != "token:ci" || recorded[0].Action != "DELETE /api/backup/delete/sdbx-backup-test.tar.gz" || recorded[0].Status != http.StatusOK {
+		t.Errorf("events = %+v, want only the deletion by the write token", recorded)
+	}
+}
+
+// TestCountryVerifyRoute verifies Traefik's country checks reach their
+// handler without a login and without the rate limit of its single IP
+func TestCountryVerifyRoute(t *testing.T) {
+	handler, _, _, _ := newTestHandler(t)
+	for i := range 30 {
+		req := httptest.NewRequest(http.MethodGet, "/country/verify?allow=FR", nil)
+		req.RemoteAddr = "172.18.0.2:4242"
+		req.Header.Set("X-Forwarded-For", "192.168.1.20")
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("check %d from the local network = %d, want 200", i, w.Code)
+		}
+	}
+
+	// Public clients are refused until the database is downloaded
+	req := httptest.NewRequest(http.MethodGet, "/country/verify?allow=FR", nil)
+	req.RemoteAddr = "172.18.0.2:4242"
+	req.Header.Set("X-Forwarded-For", "81.2.69.142")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("check without a database = %d, want 503", w.Code)
+	}
 }
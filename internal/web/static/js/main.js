@@ -49,6 +49,33 @@ function showToast(message, type) {
     }, 4000);
 }
 
+// --- Background Jobs ---
+
+// Polls GET /api/jobs/{id} until the job finishes, passing each latest step
+// to onStep. Resolves with the job, rejects with its error.
+function pollJob(jobId, onStep) {
+    return new Promise(function(resolve, reject) {
+        function poll() {
+            fetch('/api/jobs/' + jobId)
+            .then(function(response) { return response.json(); })
+            .then(function(job) {
+                if (job.steps && job.steps.length) onStep(job.steps[job.steps.length - 1]);
+                if (job.status === 'running') {
+                    setTimeout(poll, 1000);
+                } else if (job.status === 'succeeded') {
+                    resolve(job);
+                } else {
+                    var message = job.error || 'job not found';
+                    if (job.problem && job.problem.hint) message += ' — ' + job.problem.hint;
+                    reject(new Error(message));
+                }
+            })
+            .catch(reject);
+        }
+        poll();
+    });
+}
+
 // --- Active Nav Link ---
 
 document.addEventListener('DOMContentLoaded', function() {